@@ -0,0 +1,131 @@
+package crmclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/clients/go/crmclient"
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testserver"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// client returns a client of the test server calling as a user of role
+func client(t *testing.T, api *httptest.Server, id uint, role string) *crmclient.Client {
+	t.Helper()
+	token := testserver.Sign(t, jwt.MapClaims{
+		"user_id": id,
+		"role":    role,
+		"email":   "partner@example.com",
+		"name":    "Partner",
+		"iat":     time.Now().Add(-time.Minute).Unix(),
+	})
+	return crmclient.New(api.URL, token, api.Client())
+}
+
+// TestClientAgainstTheServer drives the generated client through a
+// customer's deal and activity against the service on a test database
+func TestClientAgainstTheServer(t *testing.T) {
+	s := testserver.New(t)
+	api := httptest.NewServer(s.Handler)
+	defer api.Close()
+	ctx := context.Background()
+	crm := client(t, api, 2, models.RoleManager)
+
+	me, err := crm.GetMe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.User.Role != models.RoleManager || len(me.Permissions) == 0 {
+		t.Errorf("me = %+v", me)
+	}
+
+	customer, err := crm.CreateCustomer(ctx, handlers.CustomerCreateRequest{Name: "Nakheel", Email: "info@nakheel.sa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detail, err := crm.GetCustomer(ctx, customer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Name != "Nakheel" || detail.OpenDealsCount != 0 {
+		t.Errorf("customer = %+v", detail)
+	}
+	list, err := crm.ListCustomers(ctx, url.Values{"search": {"Nakheel"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || len(list.Data) != 1 || list.Data[0].ID != customer.ID {
+		t.Errorf("customers = %+v", list)
+	}
+
+	created, err := crm.CreateDeal(ctx, handlers.DealCreateRequest{Title: "Renewal", CustomerID: customer.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.CustomerID != customer.ID {
+		t.Errorf("deal = %+v", created.Deal)
+	}
+	deal, err := crm.MoveDealStage(ctx, created.ID, handlers.DealStageTransitionRequest{Stage: models.DealStageQualification})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deal.Stage != models.DealStageQualification {
+		t.Errorf("stage = %s", deal.Stage)
+	}
+
+	activity, err := crm.CreateActivity(ctx, handlers.ActivityCreateRequest{Title: "Send the proposal", Type: models.ActivityTypeTask, CustomerID: &customer.ID, DealID: &deal.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	completion, err := crm.CompleteActivity(ctx, activity.ID, handlers.ActivityCompleteRequest{Outcome: "Sent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completion.Completed.Status != models.ActivityStatusCompleted {
+		t.Errorf("completed = %+v", completion.Completed)
+	}
+
+	if err := crm.DeleteDeal(ctx, deal.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crm.GetDeal(ctx, deal.ID); !errors.Is(err, crmclient.CodeDealNotFound) {
+		t.Errorf("deleted deal: err = %v", err)
+	}
+}
+
+// TestClientErrors checks that error responses come back as *Error with
+// their status, code and the fields some errors add
+func TestClientErrors(t *testing.T) {
+	s := testserver.New(t)
+	api := httptest.NewServer(s.Handler)
+	defer api.Close()
+	ctx := context.Background()
+	customer := s.Factory.Customer(t)
+	deal := s.Factory.Deal(t, customer)
+
+	_, err := client(t, api, 2, models.RoleManager).MoveDealStage(ctx, deal.ID, handlers.DealStageTransitionRequest{Stage: models.DealStageClosedLost})
+	var apiErr *crmclient.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, crmclient.CodeMissingLostReason) {
+		t.Fatalf("err = %v", err)
+	}
+	if apiErr.Status != http.StatusUnprocessableEntity || apiErr.Message == "" || len(apiErr.Body) == 0 {
+		t.Errorf("error = %+v", apiErr)
+	}
+
+	_, err = client(t, api, 3, models.RoleAgent).CreateTag(ctx, handlers.TagCreateRequest{Name: "vip"})
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusForbidden {
+		t.Errorf("agent creating a tag: err = %v", err)
+	}
+
+	_, err = client(t, api, 1, "ghost").ListCustomers(ctx, nil)
+	if !errors.Is(err, crmclient.CodeUnknownRole) {
+		t.Errorf("unknown role: err = %v", err)
+	}
+}
@@ -0,0 +1,454 @@
+// Code generated by genclient from src/apispec. DO NOT EDIT.
+
+package crmclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// GetMe returns the caller and their permissions.
+//
+//	GET /admin/me
+func (c *Client) GetMe(ctx context.Context) (*models.MeResponse, error) {
+	var out models.MeResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/me", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCapabilities returns the fields the caller can edit per entity.
+//
+//	GET /admin/me/capabilities
+func (c *Client) GetCapabilities(ctx context.Context) (*models.CapabilitiesResponse, error) {
+	var out models.CapabilitiesResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/me/capabilities", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListMyActivities lists the activities assigned to the caller.
+//
+//	GET /admin/me/activities
+func (c *Client) ListMyActivities(ctx context.Context, query url.Values) (*models.ActivityListResponse, error) {
+	var out models.ActivityListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/me/activities", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCustomers lists customers.
+//
+//	GET /admin/customers
+func (c *Client) ListCustomers(ctx context.Context, query url.Values) (*models.CustomerListResponse, error) {
+	var out models.CustomerListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/customers", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCustomer creates a customer.
+//
+//	POST /admin/customers
+func (c *Client) CreateCustomer(ctx context.Context, body handlers.CustomerCreateRequest) (*models.Customer, error) {
+	var out models.Customer
+	if err := c.do(ctx, http.MethodPost, "/admin/customers", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCustomer returns a customer with its related counts.
+//
+//	GET /admin/customers/:id
+func (c *Client) GetCustomer(ctx context.Context, id uint) (*models.CustomerDetailResponse, error) {
+	var out models.CustomerDetailResponse
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/customers/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCustomer updates a customer.
+//
+//	PUT /admin/customers/:id
+func (c *Client) UpdateCustomer(ctx context.Context, id uint, body handlers.CustomerUpdateRequest) (*models.Customer, error) {
+	var out models.Customer
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/customers/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteCustomer deletes a customer with its related records.
+//
+//	DELETE /admin/customers/:id
+func (c *Client) DeleteCustomer(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/customers/%d", id), nil, nil, nil)
+}
+
+// ArchiveCustomer archives a customer.
+//
+//	POST /admin/customers/:id/archive
+func (c *Client) ArchiveCustomer(ctx context.Context, id uint) (*models.Customer, error) {
+	var out models.Customer
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/customers/%d/archive", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UnarchiveCustomer restores an archived customer.
+//
+//	POST /admin/customers/:id/unarchive
+func (c *Client) UnarchiveCustomer(ctx context.Context, id uint) (*models.Customer, error) {
+	var out models.Customer
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/customers/%d/unarchive", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClaimCustomer assigns an unassigned customer to the caller.
+//
+//	POST /admin/customers/:id/claim
+func (c *Client) ClaimCustomer(ctx context.Context, id uint) (*models.Customer, error) {
+	var out models.Customer
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/customers/%d/claim", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AssignTagToCustomer tags a customer.
+//
+//	POST /admin/customers/:id/tags/:tagId
+func (c *Client) AssignTagToCustomer(ctx context.Context, id uint, tagID uint) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/customers/%d/tags/%d", id, tagID), nil, nil, nil)
+}
+
+// RemoveTagFromCustomer removes a tag from a customer.
+//
+//	DELETE /admin/customers/:id/tags/:tagId
+func (c *Client) RemoveTagFromCustomer(ctx context.Context, id uint, tagID uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/customers/%d/tags/%d", id, tagID), nil, nil, nil)
+}
+
+// ListContacts lists a customer's contacts.
+//
+//	GET /admin/customers/:id/contacts
+func (c *Client) ListContacts(ctx context.Context, id uint, query url.Values) (*models.ContactListResponse, error) {
+	var out models.ContactListResponse
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/customers/%d/contacts", id), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateContact adds a contact to a customer.
+//
+//	POST /admin/customers/:id/contacts
+func (c *Client) CreateContact(ctx context.Context, id uint, body handlers.ContactCreateRequest) (*models.Contact, error) {
+	var out models.Contact
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/customers/%d/contacts", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateContact updates a contact.
+//
+//	PUT /admin/contacts/:id
+func (c *Client) UpdateContact(ctx context.Context, id uint, body handlers.ContactUpdateRequest) (*models.Contact, error) {
+	var out models.Contact
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/contacts/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteContact deletes a contact.
+//
+//	DELETE /admin/contacts/:id
+func (c *Client) DeleteContact(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/contacts/%d", id), nil, nil, nil)
+}
+
+// ListDeals lists deals.
+//
+//	GET /admin/deals
+func (c *Client) ListDeals(ctx context.Context, query url.Values) (*models.DealListResponse, error) {
+	var out models.DealListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/deals", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateDeal creates a deal.
+//
+//	POST /admin/deals
+func (c *Client) CreateDeal(ctx context.Context, body handlers.DealCreateRequest) (*handlers.DealCreateResponse, error) {
+	var out handlers.DealCreateResponse
+	if err := c.do(ctx, http.MethodPost, "/admin/deals", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDeal returns a deal with its timeline and checklist.
+//
+//	GET /admin/deals/:id
+func (c *Client) GetDeal(ctx context.Context, id uint) (*models.Deal, error) {
+	var out models.Deal
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/deals/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateDeal updates a deal.
+//
+//	PUT /admin/deals/:id
+func (c *Client) UpdateDeal(ctx context.Context, id uint, body handlers.DealUpdateRequest) (*models.Deal, error) {
+	var out models.Deal
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/deals/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MoveDealStage moves a deal to another stage.
+//
+//	PATCH /admin/deals/:id
+func (c *Client) MoveDealStage(ctx context.Context, id uint, body handlers.DealStageTransitionRequest) (*models.Deal, error) {
+	var out models.Deal
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/admin/deals/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteDeal deletes a deal.
+//
+//	DELETE /admin/deals/:id
+func (c *Client) DeleteDeal(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/deals/%d", id), nil, nil, nil)
+}
+
+// ArchiveDeal archives a deal.
+//
+//	POST /admin/deals/:id/archive
+func (c *Client) ArchiveDeal(ctx context.Context, id uint) (*models.Deal, error) {
+	var out models.Deal
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/deals/%d/archive", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UnarchiveDeal restores an archived deal.
+//
+//	POST /admin/deals/:id/unarchive
+func (c *Client) UnarchiveDeal(ctx context.Context, id uint) (*models.Deal, error) {
+	var out models.Deal
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/deals/%d/unarchive", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListActivities lists activities.
+//
+//	GET /admin/activities
+func (c *Client) ListActivities(ctx context.Context, query url.Values) (*models.ActivityListResponse, error) {
+	var out models.ActivityListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/activities", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateActivity logs or schedules an activity.
+//
+//	POST /admin/activities
+func (c *Client) CreateActivity(ctx context.Context, body handlers.ActivityCreateRequest) (*models.Activity, error) {
+	var out models.Activity
+	if err := c.do(ctx, http.MethodPost, "/admin/activities", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetActivity returns an activity.
+//
+//	GET /admin/activities/:id
+func (c *Client) GetActivity(ctx context.Context, id uint) (*models.Activity, error) {
+	var out models.Activity
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/activities/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateActivity updates an activity.
+//
+//	PUT /admin/activities/:id
+func (c *Client) UpdateActivity(ctx context.Context, id uint, body handlers.ActivityUpdateRequest) (*models.Activity, error) {
+	var out models.Activity
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/activities/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateActivityStatus changes an activity's status.
+//
+//	PATCH /admin/activities/:id
+func (c *Client) UpdateActivityStatus(ctx context.Context, id uint, body handlers.ActivityStatusUpdateRequest) (*models.Activity, error) {
+	var out models.Activity
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/admin/activities/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CompleteActivity completes an activity with its outcome.
+//
+//	POST /admin/activities/:id/complete
+func (c *Client) CompleteActivity(ctx context.Context, id uint, body handlers.ActivityCompleteRequest) (*models.ActivityCompletionResponse, error) {
+	var out models.ActivityCompletionResponse
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/activities/%d/complete", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteActivity deletes an activity.
+//
+//	DELETE /admin/activities/:id
+func (c *Client) DeleteActivity(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/activities/%d", id), nil, nil, nil)
+}
+
+// ListTags lists tags.
+//
+//	GET /admin/tags
+func (c *Client) ListTags(ctx context.Context) (*models.TagListResponse, error) {
+	var out models.TagListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/tags", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateTag creates a tag.
+//
+//	POST /admin/tags
+func (c *Client) CreateTag(ctx context.Context, body handlers.TagCreateRequest) (*models.Tag, error) {
+	var out models.Tag
+	if err := c.do(ctx, http.MethodPost, "/admin/tags", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateTag updates a tag.
+//
+//	PUT /admin/tags/:id
+func (c *Client) UpdateTag(ctx context.Context, id uint, body handlers.TagUpdateRequest) (*models.Tag, error) {
+	var out models.Tag
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/tags/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteTag deletes a tag.
+//
+//	DELETE /admin/tags/:id
+func (c *Client) DeleteTag(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/tags/%d", id), nil, nil, nil)
+}
+
+// ListPipelineStages lists the pipeline stages in board order.
+//
+//	GET /admin/pipeline-stages
+func (c *Client) ListPipelineStages(ctx context.Context) (*models.PipelineStageListResponse, error) {
+	var out models.PipelineStageListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/pipeline-stages", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreatePipelineStage adds a pipeline stage.
+//
+//	POST /admin/pipeline-stages
+func (c *Client) CreatePipelineStage(ctx context.Context, body handlers.PipelineStageCreateRequest) (*models.PipelineStage, error) {
+	var out models.PipelineStage
+	if err := c.do(ctx, http.MethodPost, "/admin/pipeline-stages", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePipelineStage updates a pipeline stage.
+//
+//	PUT /admin/pipeline-stages/:id
+func (c *Client) UpdatePipelineStage(ctx context.Context, id uint, body handlers.PipelineStageUpdateRequest) (*models.PipelineStage, error) {
+	var out models.PipelineStage
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/pipeline-stages/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePipelineStage deletes a pipeline stage no deal is in.
+//
+//	DELETE /admin/pipeline-stages/:id
+func (c *Client) DeletePipelineStage(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/pipeline-stages/%d", id), nil, nil, nil)
+}
+
+// ListJobs lists background jobs.
+//
+//	GET /admin/jobs
+func (c *Client) ListJobs(ctx context.Context, query url.Values) (*models.JobListResponse, error) {
+	var out models.JobListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/jobs", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateExportJob starts an export job.
+//
+//	POST /admin/jobs/exports
+func (c *Client) CreateExportJob(ctx context.Context, body handlers.ExportJobRequest) (*models.Job, error) {
+	var out models.Job
+	if err := c.do(ctx, http.MethodPost, "/admin/jobs/exports", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJob returns a job and its progress.
+//
+//	GET /admin/jobs/:id
+func (c *Client) GetJob(ctx context.Context, id uint) (*models.Job, error) {
+	var out models.Job
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/jobs/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
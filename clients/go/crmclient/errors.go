@@ -0,0 +1,189 @@
+// Code generated by genclient from src/apispec. DO NOT EDIT.
+
+package crmclient
+
+// Error codes the API responds with
+const (
+	CodeActivityAlreadyClosed        ErrorCode = "ACTIVITY_ALREADY_CLOSED"         // Activity is already completed or cancelled
+	CodeActivityNotFound             ErrorCode = "ACTIVITY_NOT_FOUND"              // Activity not found
+	CodeAlertAlreadyAcknowledged     ErrorCode = "ALERT_ALREADY_ACKNOWLEDGED"      // Security alert has already been acknowledged
+	CodeAlreadyClaimed               ErrorCode = "ALREADY_CLAIMED"                 // This record is already assigned
+	CodeAmountWithConversion         ErrorCode = "AMOUNT_WITH_CONVERSION"          // An amount cannot be given when the stored amount is converted
+	CodeAnnotationClosed             ErrorCode = "ANNOTATION_CLOSED"               // Closed annotations cannot be changed
+	CodeAnnotationNotFound           ErrorCode = "ANNOTATION_NOT_FOUND"            // Annotation not found
+	CodeAnonymized                   ErrorCode = "ANONYMIZED"                      // Anonymized customers cannot be changed
+	CodeArchived                     ErrorCode = "ARCHIVED"                        // Archived records must be unarchived before they can be changed
+	CodeArtifactExpired              ErrorCode = "ARTIFACT_EXPIRED"                // The export file has expired, run the export again
+	CodeAssignmentRuleNotFound       ErrorCode = "ASSIGNMENT_RULE_NOT_FOUND"       // Assignment rule not found
+	CodeAuditWriteFailed             ErrorCode = "AUDIT_WRITE_FAILED"              // The change was not saved because its audit entry could not be written
+	CodeBackupSchemaMismatch         ErrorCode = "BACKUP_SCHEMA_MISMATCH"          // The backup does not match the database schema
+	CodeCalendarFeedRequiresUser     ErrorCode = "CALENDAR_FEED_REQUIRES_USER"     // Only users can subscribe to a calendar feed
+	CodeCallAlreadyLogged            ErrorCode = "CALL_ALREADY_LOGGED"             // Call has already been logged as an activity
+	CodeChecklistIncomplete          ErrorCode = "CHECKLIST_INCOMPLETE"            // Complete the close checklist before closing the deal as won
+	CodeChecklistItemNotFound        ErrorCode = "CHECKLIST_ITEM_NOT_FOUND"        // Checklist item not found
+	CodeChecklistKeyExists           ErrorCode = "CHECKLIST_KEY_EXISTS"            // A checklist item with this key already exists
+	CodeChecklistLocked              ErrorCode = "CHECKLIST_LOCKED"                // The checklist of a closed deal cannot be changed; reopen the deal first
+	CodeClaimLimitReached            ErrorCode = "CLAIM_LIMIT_REACHED"             // Daily claim limit reached, try again tomorrow
+	CodeClaimRequiresUser            ErrorCode = "CLAIM_REQUIRES_USER"             // Only users can claim records
+	CodeCompanyNotFound              ErrorCode = "COMPANY_NOT_FOUND"               // No customers found for this domain
+	CodeConflictingDueDate           ErrorCode = "CONFLICTING_DUE_DATE"            // Provide only one of due_date, due_in_days or due_in_business_days
+	CodeConsistencyRunInProgress     ErrorCode = "CONSISTENCY_RUN_IN_PROGRESS"     // A consistency run is already in progress
+	CodeContactNotFound              ErrorCode = "CONTACT_NOT_FOUND"               // Contact not found
+	CodeCurrencyChangeForbidden      ErrorCode = "CURRENCY_CHANGE_FORBIDDEN"       // Currency cannot be changed at this stage without conversion
+	CodeCurrencyImmutable            ErrorCode = "CURRENCY_IMMUTABLE"              // Currency of a closed deal cannot be changed
+	CodeCustomerNotFound             ErrorCode = "CUSTOMER_NOT_FOUND"              // Customer not found
+	CodeCustomerNotLead              ErrorCode = "CUSTOMER_NOT_LEAD"               // Only lead and prospect customers can be converted
+	CodeDatabaseError                ErrorCode = "DATABASE_ERROR"                  // A database error occurred
+	CodeDatabaseNotEmpty             ErrorCode = "DATABASE_NOT_EMPTY"              // The database is not empty; restore into an empty database or pass force=true
+	CodeDeadLetterAlreadyRequeued    ErrorCode = "DEAD_LETTER_ALREADY_REQUEUED"    // Dead letter has already been requeued
+	CodeDeadLetterNotFound           ErrorCode = "DEAD_LETTER_NOT_FOUND"           // Dead letter not found
+	CodeDeadLetterNoRetrier          ErrorCode = "DEAD_LETTER_NO_RETRIER"          // No retry handler is registered for this component
+	CodeDeadLetterRetryFailed        ErrorCode = "DEAD_LETTER_RETRY_FAILED"        // Failed to requeue dead letter
+	CodeDealNotFound                 ErrorCode = "DEAL_NOT_FOUND"                  // Deal not found
+	CodeDealReferencesStage          ErrorCode = "DEAL_REFERENCES_STAGE"           // Deals are still in this stage; move them to another stage first
+	CodeDuplicateExportColumns       ErrorCode = "DUPLICATE_EXPORT_COLUMNS"        // Columns appear more than once
+	CodeEmailExists                  ErrorCode = "EMAIL_EXISTS"                    // A customer with this email already exists
+	CodeEmailInvalid                 ErrorCode = "EMAIL_INVALID"                   // The recipient's email address bounced; update it before sending
+	CodeEmailProviderNotFound        ErrorCode = "EMAIL_PROVIDER_NOT_FOUND"        // Unknown or unconfigured email provider
+	CodeExchangeRateExists           ErrorCode = "EXCHANGE_RATE_EXISTS"            // A rate for this currency pair is already effective from this date
+	CodeExchangeRateNotFound         ErrorCode = "EXCHANGE_RATE_NOT_FOUND"         // No exchange rate found for the requested currencies
+	CodeExchangeRateSameCurrency     ErrorCode = "EXCHANGE_RATE_SAME_CURRENCY"     // The base and quote currencies must differ
+	CodeExportTemplateExists         ErrorCode = "EXPORT_TEMPLATE_EXISTS"          // An export template with this name already exists for this entity
+	CodeExportTemplateMismatch       ErrorCode = "EXPORT_TEMPLATE_MISMATCH"        // The export template is for a different entity
+	CodeExportTemplateNotFound       ErrorCode = "EXPORT_TEMPLATE_NOT_FOUND"       // Export template not found
+	CodeExportTemplateNotSupported   ErrorCode = "EXPORT_TEMPLATE_NOT_SUPPORTED"   // This export type does not support templates
+	CodeExternalIdConflict           ErrorCode = "EXTERNAL_ID_CONFLICT"            // The customer with this email has a different external_id
+	CodeFieldEditForbidden           ErrorCode = "FIELD_EDIT_FORBIDDEN"            // You do not have permission to edit these fields
+	CodeFieldNotNullable             ErrorCode = "FIELD_NOT_NULLABLE"              // These fields cannot be cleared with null
+	CodeHolidayExists                ErrorCode = "HOLIDAY_EXISTS"                  // A holiday already exists on this date for this region
+	CodeHolidayNotFound              ErrorCode = "HOLIDAY_NOT_FOUND"               // Holiday not found
+	CodeInsufficientPermissions      ErrorCode = "INSUFFICIENT_PERMISSIONS"        // You do not have permission to perform this action
+	CodeInsufficientScope            ErrorCode = "INSUFFICIENT_SCOPE"              // Service account token is missing the required scope
+	CodeInternalError                ErrorCode = "INTERNAL_ERROR"                  // An unexpected error occurred
+	CodeInvalidActivityType          ErrorCode = "INVALID_ACTIVITY_TYPE"           // Invalid activity type
+	CodeInvalidAnnotationType        ErrorCode = "INVALID_ANNOTATION_TYPE"         // type must be one of: deploy, import, maintenance
+	CodeInvalidApiKey                ErrorCode = "INVALID_API_KEY"                 // Invalid or missing API key
+	CodeInvalidAssignmentRule        ErrorCode = "INVALID_ASSIGNMENT_RULE"         // Invalid assignment rule
+	CodeInvalidBackup                ErrorCode = "INVALID_BACKUP"                  // Invalid backup archive
+	CodeInvalidChangeReasonRule      ErrorCode = "INVALID_CHANGE_REASON_RULE"      // Invalid change reason rule
+	CodeInvalidChecklistKey          ErrorCode = "INVALID_CHECKLIST_KEY"           // Checklist keys must be lowercase letters, digits or underscores, starting with a letter
+	CodeInvalidConfirmationToken     ErrorCode = "INVALID_CONFIRMATION_TOKEN"      // Confirmation token is invalid or expired; request a new preview
+	CodeInvalidCsv                   ErrorCode = "INVALID_CSV"                     // Invalid CSV file
+	CodeInvalidCursor                ErrorCode = "INVALID_CURSOR"                  // Invalid cursor
+	CodeInvalidDate                  ErrorCode = "INVALID_DATE"                    // Invalid date
+	CodeInvalidDateFormat            ErrorCode = "INVALID_DATE_FORMAT"             // date_format must be one of: date, datetime, eu, rfc3339, us
+	CodeInvalidDateRange             ErrorCode = "INVALID_DATE_RANGE"              // ends_at must be after starts_at
+	CodeInvalidEmail                 ErrorCode = "INVALID_EMAIL"                   // Invalid email format
+	CodeInvalidExportEntity          ErrorCode = "INVALID_EXPORT_ENTITY"           // entity must be one of: customer, deal
+	CodeInvalidExportType            ErrorCode = "INVALID_EXPORT_TYPE"             // Unknown export type
+	CodeInvalidFeatureFlags          ErrorCode = "INVALID_FEATURE_FLAGS"           // Invalid feature flag override
+	CodeInvalidFilterField           ErrorCode = "INVALID_FILTER_FIELD"            // This field cannot be counted
+	CodeInvalidHistoryField          ErrorCode = "INVALID_HISTORY_FIELD"           // This field has no history
+	CodeInvalidId                    ErrorCode = "INVALID_ID"                      // Invalid ID
+	CodeInvalidInterval              ErrorCode = "INVALID_INTERVAL"                // interval must be month or week
+	CodeInvalidLink                  ErrorCode = "INVALID_LINK"                    // Tracked links must be absolute http or https URLs
+	CodeInvalidMergePatch            ErrorCode = "INVALID_MERGE_PATCH"             // Merge patch body must be a JSON object
+	CodeInvalidNeighbor              ErrorCode = "INVALID_NEIGHBOR"                // Neighbor deals must be other deals on the same board in the target stage
+	CodeInvalidOnConflict            ErrorCode = "INVALID_ON_CONFLICT"             // on_conflict must be skip or update
+	CodeInvalidOutcomeCode           ErrorCode = "INVALID_OUTCOME_CODE"            // Invalid outcome code for the activity type
+	CodeInvalidPermission            ErrorCode = "INVALID_PERMISSION"              // Unknown permission
+	CodeInvalidPriority              ErrorCode = "INVALID_PRIORITY"                // Priority must be low, normal or high
+	CodeInvalidRequest               ErrorCode = "INVALID_REQUEST"                 // Invalid request
+	CodeInvalidRole                  ErrorCode = "INVALID_ROLE"                    // Role is not defined; see GET /admin/roles
+	CodeInvalidScope                 ErrorCode = "INVALID_SCOPE"                   // Invalid service account scope
+	CodeInvalidSearchType            ErrorCode = "INVALID_SEARCH_TYPE"             // types must be a list of customer, contact, deal and activity
+	CodeInvalidSelection             ErrorCode = "INVALID_SELECTION"               // Select deals by ids or by at least one filter, not both
+	CodeInvalidStage                 ErrorCode = "INVALID_STAGE"                   // Invalid deal stage
+	CodeInvalidStatus                ErrorCode = "INVALID_STATUS"                  // Invalid status
+	CodeInvalidTimezone              ErrorCode = "INVALID_TIMEZONE"                // X-Timezone must be an IANA time zone such as Asia/Riyadh
+	CodeInvalidToken                 ErrorCode = "INVALID_TOKEN"                   // Invalid token
+	CodeInvalidTokenFormat           ErrorCode = "INVALID_TOKEN_FORMAT"            // Authorization header must be in 'Bearer <token>' format
+	CodeInvalidTrackingToken         ErrorCode = "INVALID_TRACKING_TOKEN"          // This link is invalid
+	CodeInvalidTransition            ErrorCode = "INVALID_TRANSITION"              // This transition is not allowed
+	CodeInvalidWebhookEvent          ErrorCode = "INVALID_WEBHOOK_EVENT"           // Unknown webhook event; see GET /admin/meta/webhooks for the events
+	CodeInvalidWebhookFilter         ErrorCode = "INVALID_WEBHOOK_FILTER"          // Invalid webhook filter
+	CodeInvalidWebhookMode           ErrorCode = "INVALID_WEBHOOK_MODE"            // The webhook mode must be at_least_once or at_most_once
+	CodeInvalidWebhookPayload        ErrorCode = "INVALID_WEBHOOK_PAYLOAD"         // Invalid webhook payload
+	CodeInvalidWebhookSignature      ErrorCode = "INVALID_WEBHOOK_SIGNATURE"       // Invalid webhook signature
+	CodeInvalidWebhookUrl            ErrorCode = "INVALID_WEBHOOK_URL"             // The webhook URL must be an http or https URL
+	CodeInvalidWidget                ErrorCode = "INVALID_WIDGET"                  // Invalid dashboard widget
+	CodeJobNotCompleted              ErrorCode = "JOB_NOT_COMPLETED"               // The job has not produced a file yet
+	CodeJobNotFound                  ErrorCode = "JOB_NOT_FOUND"                   // Job not found
+	CodeJobNotResumable              ErrorCode = "JOB_NOT_RESUMABLE"               // Only failed export jobs can be resumed
+	CodeLineTooLong                  ErrorCode = "LINE_TOO_LONG"                   // A line of the stream is too long
+	CodeLostReasonRequired           ErrorCode = "LOST_REASON_REQUIRED"            // A lost reason is required to close deals as lost
+	CodeMatchKeyRequired             ErrorCode = "MATCH_KEY_REQUIRED"              // Each record needs an external_id or an email
+	CodeMethodNotAllowed             ErrorCode = "METHOD_NOT_ALLOWED"              // This endpoint does not support the request method
+	CodeMissingCallParty             ErrorCode = "MISSING_CALL_PARTY"              // A customer or contact is required to match a call
+	CodeMissingLink                  ErrorCode = "MISSING_LINK"                    // Activity must be linked to a customer or deal
+	CodeMissingLostReason            ErrorCode = "MISSING_LOST_REASON"             // A lost reason is required to close a deal as lost
+	CodeMissingRequiredFields        ErrorCode = "MISSING_REQUIRED_FIELDS"         // Missing required fields
+	CodeMissingRole                  ErrorCode = "MISSING_ROLE"                    // Token must contain a role claim
+	CodeMissingTags                  ErrorCode = "MISSING_TAGS"                    // At least one tag is required
+	CodeMissingToken                 ErrorCode = "MISSING_TOKEN"                   // Authorization header is required
+	CodeNextStepRequired             ErrorCode = "NEXT_STEP_REQUIRED"              // Record a next step before moving the deal forward
+	CodeNotArchived                  ErrorCode = "NOT_ARCHIVED"                    // Record is not archived
+	CodeNotEmailActivity             ErrorCode = "NOT_EMAIL_ACTIVITY"              // Tracking links can only be issued for email activities
+	CodeNoAvailableRep               ErrorCode = "NO_AVAILABLE_REP"                // No rep in this rule is currently available
+	CodeNoUpdates                    ErrorCode = "NO_UPDATES"                      // No fields to update
+	CodeNoUserContext                ErrorCode = "NO_USER_CONTEXT"                 // User context not found
+	CodeOpenDealLimit                ErrorCode = "OPEN_DEAL_LIMIT"                 // Customer has reached the maximum number of open deals
+	CodeOutcomeCodeExists            ErrorCode = "OUTCOME_CODE_EXISTS"             // An outcome with this code already exists for the activity type
+	CodeOutcomeCodeRequired          ErrorCode = "OUTCOME_CODE_REQUIRED"           // An outcome code is required to complete this activity
+	CodeOutcomeNotFound              ErrorCode = "OUTCOME_NOT_FOUND"               // Activity outcome not found
+	CodePipelineStageNotFound        ErrorCode = "PIPELINE_STAGE_NOT_FOUND"        // Pipeline stage not found
+	CodeQuotaExceeded                ErrorCode = "QUOTA_EXCEEDED"                  // Record quota exceeded
+	CodeRateLimited                  ErrorCode = "RATE_LIMITED"                    // Too many requests, please retry later
+	CodeReasonRequired               ErrorCode = "REASON_REQUIRED"                 // Give a reason for changing these fields in change_reason or the X-Change-Reason header
+	CodeReportRollupInProgress       ErrorCode = "REPORT_ROLLUP_IN_PROGRESS"       // A report rollup is already in progress
+	CodeResourceBusy                 ErrorCode = "RESOURCE_BUSY"                   // Too many expensive requests of this kind are running, please retry later
+	CodeReverseConversionForbidden   ErrorCode = "REVERSE_CONVERSION_FORBIDDEN"    // Only managers can move a converted customer back to lead or prospect
+	CodeRoleExists                   ErrorCode = "ROLE_EXISTS"                     // A role with this name already exists
+	CodeRoleInUse                    ErrorCode = "ROLE_IN_USE"                     // The role is still assigned to service accounts
+	CodeRoleNotFound                 ErrorCode = "ROLE_NOT_FOUND"                  // Role not found
+	CodeRoleProtected                ErrorCode = "ROLE_PROTECTED"                  // Built-in roles cannot be deleted and the admin role must keep its core permissions
+	CodeRouteNotFound                ErrorCode = "ROUTE_NOT_FOUND"                 // No endpoint matches this path
+	CodeSandboxUnavailable           ErrorCode = "SANDBOX_UNAVAILABLE"             // The API sandbox is not enabled on this server
+	CodeSandboxUnsupported           ErrorCode = "SANDBOX_UNSUPPORTED"             // This endpoint is not available in the API sandbox
+	CodeSearchEngineNotIndexed       ErrorCode = "SEARCH_ENGINE_NOT_INDEXED"       // Search uses Postgres directly, there is no index to rebuild
+	CodeSearchQueryTooShort          ErrorCode = "SEARCH_QUERY_TOO_SHORT"          // Search query is too short
+	CodeSecurityAlertNotFound        ErrorCode = "SECURITY_ALERT_NOT_FOUND"        // Security alert not found
+	CodeSecurityWebhookNotConfigured ErrorCode = "SECURITY_WEBHOOK_NOT_CONFIGURED" // The security alert webhook is not configured
+	CodeServiceAccountExists         ErrorCode = "SERVICE_ACCOUNT_EXISTS"          // A service account with this name already exists
+	CodeServiceAccountNotFound       ErrorCode = "SERVICE_ACCOUNT_NOT_FOUND"       // Service account not found
+	CodeSlowQueryLogDisabled         ErrorCode = "SLOW_QUERY_LOG_DISABLED"         // Slow query capture is disabled
+	CodeSmokeTestDisabled            ErrorCode = "SMOKE_TEST_DISABLED"             // Smoke tests are disabled in production unless SMOKE_TEST_ENABLED is set
+	CodeStageExists                  ErrorCode = "STAGE_EXISTS"                    // A pipeline stage with this name already exists
+	CodeStageReserved                ErrorCode = "STAGE_RESERVED"                  // The prospecting, closed_won and closed_lost stages cannot be renamed, deactivated or deleted
+	CodeStageUnchanged               ErrorCode = "STAGE_UNCHANGED"                 // Deal is already in the target stage
+	CodeStreamCancelled              ErrorCode = "STREAM_CANCELLED"                // The stream was cancelled
+	CodeStreamReadFailed             ErrorCode = "STREAM_READ_FAILED"              // Failed to read the stream
+	CodeStreamTimeout                ErrorCode = "STREAM_TIMEOUT"                  // The stream exceeded the time limit of a connection
+	CodeStreamTooLarge               ErrorCode = "STREAM_TOO_LARGE"                // The stream exceeds the size limit of a connection
+	CodeTagExists                    ErrorCode = "TAG_EXISTS"                      // A tag with this name already exists
+	CodeTagGroupConflict             ErrorCode = "TAG_GROUP_CONFLICT"              // Some customers carry more than one tag of this group
+	CodeTagGroupExclusive            ErrorCode = "TAG_GROUP_EXCLUSIVE"             // The customer already has a tag from this exclusive group
+	CodeTagGroupExists               ErrorCode = "TAG_GROUP_EXISTS"                // A tag group with this name already exists
+	CodeTagGroupNotFound             ErrorCode = "TAG_GROUP_NOT_FOUND"             // Tag group not found
+	CodeTagNotFound                  ErrorCode = "TAG_NOT_FOUND"                   // Tag not found
+	CodeTelephonyCallNotFound        ErrorCode = "TELEPHONY_CALL_NOT_FOUND"        // Call not found
+	CodeTelephonyNotConfigured       ErrorCode = "TELEPHONY_NOT_CONFIGURED"        // The telephony webhook is not configured
+	CodeTextTooLong                  ErrorCode = "TEXT_TOO_LONG"                   // Text fields are longer than the allowed size
+	CodeTitleRequired                ErrorCode = "TITLE_REQUIRED"                  // Title is required
+	CodeTokenRevoked                 ErrorCode = "TOKEN_REVOKED"                   // Token has been revoked, please sign in again
+	CodeTooManyBuckets               ErrorCode = "TOO_MANY_BUCKETS"                // The period spans too many buckets; shorten it or use a longer interval
+	CodeTooManyDeals                 ErrorCode = "TOO_MANY_DEALS"                  // Too many deals selected; narrow the selection
+	CodeTooManyRecords               ErrorCode = "TOO_MANY_RECORDS"                // Send between 1 and 500 records
+	CodeTooManyTags                  ErrorCode = "TOO_MANY_TAGS"                   // Too many tags requested
+	CodeTooManyWidgets               ErrorCode = "TOO_MANY_WIDGETS"                // The dashboard has too many widgets
+	CodeTrackingTokenExpired         ErrorCode = "TRACKING_TOKEN_EXPIRED"          // This link has expired
+	CodeUnavailabilityNotFound       ErrorCode = "UNAVAILABILITY_NOT_FOUND"        // Unavailability window not found
+	CodeUnknownDateRange             ErrorCode = "UNKNOWN_DATE_RANGE"              // Unknown date range
+	CodeUnknownEntity                ErrorCode = "UNKNOWN_ENTITY"                  // Unknown entity
+	CodeUnknownExportColumns         ErrorCode = "UNKNOWN_EXPORT_COLUMNS"          // Unknown columns
+	CodeUnknownFields                ErrorCode = "UNKNOWN_FIELDS"                  // The patch contains fields that cannot be updated
+	CodeUnknownRole                  ErrorCode = "UNKNOWN_ROLE"                    // Your role is not defined in this CRM
+	CodeUnsubscribed                 ErrorCode = "UNSUBSCRIBED"                    // You have been unsubscribed
+	CodeWebhookNotFound              ErrorCode = "WEBHOOK_NOT_FOUND"               // Webhook subscription not found
+	CodeWebhookPayloadTooLarge       ErrorCode = "WEBHOOK_PAYLOAD_TOO_LARGE"       // Webhook payload is too large
+)
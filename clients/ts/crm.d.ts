@@ -0,0 +1,916 @@
+// Code generated by genclient from src/apispec. DO NOT EDIT.
+
+/** Error codes the API responds with */
+export type ErrorCode =
+  | "ACTIVITY_ALREADY_CLOSED"
+  | "ACTIVITY_NOT_FOUND"
+  | "ALERT_ALREADY_ACKNOWLEDGED"
+  | "ALREADY_CLAIMED"
+  | "AMOUNT_WITH_CONVERSION"
+  | "ANNOTATION_CLOSED"
+  | "ANNOTATION_NOT_FOUND"
+  | "ANONYMIZED"
+  | "ARCHIVED"
+  | "ARTIFACT_EXPIRED"
+  | "ASSIGNMENT_RULE_NOT_FOUND"
+  | "AUDIT_WRITE_FAILED"
+  | "BACKUP_SCHEMA_MISMATCH"
+  | "CALENDAR_FEED_REQUIRES_USER"
+  | "CALL_ALREADY_LOGGED"
+  | "CHECKLIST_INCOMPLETE"
+  | "CHECKLIST_ITEM_NOT_FOUND"
+  | "CHECKLIST_KEY_EXISTS"
+  | "CHECKLIST_LOCKED"
+  | "CLAIM_LIMIT_REACHED"
+  | "CLAIM_REQUIRES_USER"
+  | "COMPANY_NOT_FOUND"
+  | "CONFLICTING_DUE_DATE"
+  | "CONSISTENCY_RUN_IN_PROGRESS"
+  | "CONTACT_NOT_FOUND"
+  | "CURRENCY_CHANGE_FORBIDDEN"
+  | "CURRENCY_IMMUTABLE"
+  | "CUSTOMER_NOT_FOUND"
+  | "CUSTOMER_NOT_LEAD"
+  | "DATABASE_ERROR"
+  | "DATABASE_NOT_EMPTY"
+  | "DEAD_LETTER_ALREADY_REQUEUED"
+  | "DEAD_LETTER_NOT_FOUND"
+  | "DEAD_LETTER_NO_RETRIER"
+  | "DEAD_LETTER_RETRY_FAILED"
+  | "DEAL_NOT_FOUND"
+  | "DEAL_REFERENCES_STAGE"
+  | "DUPLICATE_EXPORT_COLUMNS"
+  | "EMAIL_EXISTS"
+  | "EMAIL_INVALID"
+  | "EMAIL_PROVIDER_NOT_FOUND"
+  | "EXCHANGE_RATE_EXISTS"
+  | "EXCHANGE_RATE_NOT_FOUND"
+  | "EXCHANGE_RATE_SAME_CURRENCY"
+  | "EXPORT_TEMPLATE_EXISTS"
+  | "EXPORT_TEMPLATE_MISMATCH"
+  | "EXPORT_TEMPLATE_NOT_FOUND"
+  | "EXPORT_TEMPLATE_NOT_SUPPORTED"
+  | "EXTERNAL_ID_CONFLICT"
+  | "FIELD_EDIT_FORBIDDEN"
+  | "FIELD_NOT_NULLABLE"
+  | "HOLIDAY_EXISTS"
+  | "HOLIDAY_NOT_FOUND"
+  | "INSUFFICIENT_PERMISSIONS"
+  | "INSUFFICIENT_SCOPE"
+  | "INTERNAL_ERROR"
+  | "INVALID_ACTIVITY_TYPE"
+  | "INVALID_ANNOTATION_TYPE"
+  | "INVALID_API_KEY"
+  | "INVALID_ASSIGNMENT_RULE"
+  | "INVALID_BACKUP"
+  | "INVALID_CHANGE_REASON_RULE"
+  | "INVALID_CHECKLIST_KEY"
+  | "INVALID_CONFIRMATION_TOKEN"
+  | "INVALID_CSV"
+  | "INVALID_CURSOR"
+  | "INVALID_DATE"
+  | "INVALID_DATE_FORMAT"
+  | "INVALID_DATE_RANGE"
+  | "INVALID_EMAIL"
+  | "INVALID_EXPORT_ENTITY"
+  | "INVALID_EXPORT_TYPE"
+  | "INVALID_FEATURE_FLAGS"
+  | "INVALID_FILTER_FIELD"
+  | "INVALID_HISTORY_FIELD"
+  | "INVALID_ID"
+  | "INVALID_INTERVAL"
+  | "INVALID_LINK"
+  | "INVALID_MERGE_PATCH"
+  | "INVALID_NEIGHBOR"
+  | "INVALID_ON_CONFLICT"
+  | "INVALID_OUTCOME_CODE"
+  | "INVALID_PERMISSION"
+  | "INVALID_PRIORITY"
+  | "INVALID_REQUEST"
+  | "INVALID_ROLE"
+  | "INVALID_SCOPE"
+  | "INVALID_SEARCH_TYPE"
+  | "INVALID_SELECTION"
+  | "INVALID_STAGE"
+  | "INVALID_STATUS"
+  | "INVALID_TIMEZONE"
+  | "INVALID_TOKEN"
+  | "INVALID_TOKEN_FORMAT"
+  | "INVALID_TRACKING_TOKEN"
+  | "INVALID_TRANSITION"
+  | "INVALID_WEBHOOK_EVENT"
+  | "INVALID_WEBHOOK_FILTER"
+  | "INVALID_WEBHOOK_MODE"
+  | "INVALID_WEBHOOK_PAYLOAD"
+  | "INVALID_WEBHOOK_SIGNATURE"
+  | "INVALID_WEBHOOK_URL"
+  | "INVALID_WIDGET"
+  | "JOB_NOT_COMPLETED"
+  | "JOB_NOT_FOUND"
+  | "JOB_NOT_RESUMABLE"
+  | "LINE_TOO_LONG"
+  | "LOST_REASON_REQUIRED"
+  | "MATCH_KEY_REQUIRED"
+  | "METHOD_NOT_ALLOWED"
+  | "MISSING_CALL_PARTY"
+  | "MISSING_LINK"
+  | "MISSING_LOST_REASON"
+  | "MISSING_REQUIRED_FIELDS"
+  | "MISSING_ROLE"
+  | "MISSING_TAGS"
+  | "MISSING_TOKEN"
+  | "NEXT_STEP_REQUIRED"
+  | "NOT_ARCHIVED"
+  | "NOT_EMAIL_ACTIVITY"
+  | "NO_AVAILABLE_REP"
+  | "NO_UPDATES"
+  | "NO_USER_CONTEXT"
+  | "OPEN_DEAL_LIMIT"
+  | "OUTCOME_CODE_EXISTS"
+  | "OUTCOME_CODE_REQUIRED"
+  | "OUTCOME_NOT_FOUND"
+  | "PIPELINE_STAGE_NOT_FOUND"
+  | "QUOTA_EXCEEDED"
+  | "RATE_LIMITED"
+  | "REASON_REQUIRED"
+  | "REPORT_ROLLUP_IN_PROGRESS"
+  | "RESOURCE_BUSY"
+  | "REVERSE_CONVERSION_FORBIDDEN"
+  | "ROLE_EXISTS"
+  | "ROLE_IN_USE"
+  | "ROLE_NOT_FOUND"
+  | "ROLE_PROTECTED"
+  | "ROUTE_NOT_FOUND"
+  | "SANDBOX_UNAVAILABLE"
+  | "SANDBOX_UNSUPPORTED"
+  | "SEARCH_ENGINE_NOT_INDEXED"
+  | "SEARCH_QUERY_TOO_SHORT"
+  | "SECURITY_ALERT_NOT_FOUND"
+  | "SECURITY_WEBHOOK_NOT_CONFIGURED"
+  | "SERVICE_ACCOUNT_EXISTS"
+  | "SERVICE_ACCOUNT_NOT_FOUND"
+  | "SLOW_QUERY_LOG_DISABLED"
+  | "SMOKE_TEST_DISABLED"
+  | "STAGE_EXISTS"
+  | "STAGE_RESERVED"
+  | "STAGE_UNCHANGED"
+  | "STREAM_CANCELLED"
+  | "STREAM_READ_FAILED"
+  | "STREAM_TIMEOUT"
+  | "STREAM_TOO_LARGE"
+  | "TAG_EXISTS"
+  | "TAG_GROUP_CONFLICT"
+  | "TAG_GROUP_EXCLUSIVE"
+  | "TAG_GROUP_EXISTS"
+  | "TAG_GROUP_NOT_FOUND"
+  | "TAG_NOT_FOUND"
+  | "TELEPHONY_CALL_NOT_FOUND"
+  | "TELEPHONY_NOT_CONFIGURED"
+  | "TEXT_TOO_LONG"
+  | "TITLE_REQUIRED"
+  | "TOKEN_REVOKED"
+  | "TOO_MANY_BUCKETS"
+  | "TOO_MANY_DEALS"
+  | "TOO_MANY_RECORDS"
+  | "TOO_MANY_TAGS"
+  | "TOO_MANY_WIDGETS"
+  | "TRACKING_TOKEN_EXPIRED"
+  | "UNAVAILABILITY_NOT_FOUND"
+  | "UNKNOWN_DATE_RANGE"
+  | "UNKNOWN_ENTITY"
+  | "UNKNOWN_EXPORT_COLUMNS"
+  | "UNKNOWN_FIELDS"
+  | "UNKNOWN_ROLE"
+  | "UNSUBSCRIBED"
+  | "WEBHOOK_NOT_FOUND"
+  | "WEBHOOK_PAYLOAD_TOO_LARGE";
+
+/** Error response of the API; some errors add fields of their own */
+export interface ApiError {
+  status: number;
+  error: string;
+  code: ErrorCode;
+  message: string;
+  [field: string]: unknown;
+}
+
+/** List query parameters such as page, page_size and filters */
+export type Query = Record<string, string | string[]>;
+
+export interface Activity {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  title: string;
+  description?: string;
+  type: string;
+  status: string;
+  customer_id?: number;
+  deal_id?: number;
+  contact_id?: number;
+  assigned_to?: number;
+  due_date?: string;
+  completed_at?: string;
+  duration?: number;
+  outcome?: string;
+  outcome_code?: string;
+  priority: string;
+  template?: string;
+  message_id?: string;
+  delivery_status?: string;
+  delivery_status_at?: string;
+  previous_activity_id?: number;
+  effective_status?: string;
+  engagement?: EmailEngagement;
+  call?: TelephonyCall;
+  customer?: Customer;
+  deal?: Deal;
+  contact?: Contact;
+}
+
+export interface ActivityCompleteRequest {
+  outcome: string;
+  outcome_code?: string;
+  duration?: number;
+  next_activity?: NextActivityRequest;
+  deal_next_step?: DealNextStepUpdate;
+}
+
+export interface ActivityCompletionResponse {
+  completed: Activity;
+  next_activity?: Activity;
+}
+
+export interface ActivityCreateRequest {
+  title: string;
+  description?: string;
+  type: string;
+  status?: string;
+  customer_id?: number;
+  deal_id?: number;
+  contact_id?: number;
+  assigned_to?: number;
+  due_date?: string;
+  duration?: number;
+  outcome?: string;
+  outcome_code?: string;
+  priority?: string;
+  template?: string;
+}
+
+export interface ActivityListResponse {
+  data: Activity[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+  next_cursor?: string;
+  filters?: Record<string, string>;
+}
+
+export interface ActivityPolicyCapabilities {
+  required: Record<string, Record<string, string[]>>;
+  strict: boolean;
+  effective_from?: string;
+}
+
+export interface ActivityStatusUpdateRequest {
+  status: string;
+  outcome?: string;
+  outcome_code?: string;
+  reopen?: boolean;
+}
+
+export interface ActivityUpdateRequest {
+  title?: string;
+  description?: string;
+  type?: string;
+  status?: string;
+  customer_id?: number;
+  deal_id?: number;
+  contact_id?: number;
+  assigned_to?: number;
+  due_date?: string;
+  completed_at?: string;
+  duration?: number;
+  outcome?: string;
+  outcome_code?: string;
+  priority?: string;
+  template?: string;
+  reopen?: boolean;
+}
+
+export interface CapabilitiesResponse {
+  role: string;
+  permissions: string[];
+  entities: Record<string, EntityCapabilities>;
+  activity_policy: ActivityPolicyCapabilities;
+  open_deal_limit: OpenDealLimitCapabilities;
+  change_reasons: ChangeReasonRule[];
+}
+
+export interface ChangeReasonRule {
+  id: number;
+  entity: string;
+  field: string;
+  closed_only: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CompanyMember {
+  id: number;
+  name: string;
+  email: string;
+  company?: string;
+}
+
+export interface Contact {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  customer_id: number;
+  first_name: string;
+  last_name?: string;
+  email?: string;
+  phone?: string;
+  position?: string;
+  is_primary: boolean;
+  notes?: string;
+  email_opt_out_at?: string;
+  email_invalid_at?: string;
+  customer?: Customer;
+}
+
+export interface ContactCreateRequest {
+  first_name: string;
+  last_name?: string;
+  email?: string;
+  phone?: string;
+  position?: string;
+  is_primary?: boolean;
+  notes?: string;
+}
+
+export interface ContactListResponse {
+  data: Contact[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+  filters?: Record<string, string>;
+}
+
+export interface ContactUpdateRequest {
+  first_name?: string;
+  last_name?: string;
+  email?: string;
+  phone?: string;
+  position?: string;
+  is_primary?: boolean;
+  notes?: string;
+}
+
+export interface Customer {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  name: string;
+  email: string;
+  phone?: string;
+  company?: string;
+  role?: string;
+  status: string;
+  assigned_to?: number;
+  contacted: boolean;
+  next_follow_up_at?: string;
+  notes?: string;
+  archived_at?: string;
+  email_opt_out_at?: string;
+  email_invalid_at?: string;
+  email_domain?: string;
+  anonymized_at?: string;
+  external_id?: string;
+  converted_at?: string;
+  converted_by?: number;
+  redacted_fields?: string[];
+  contacts?: Contact[];
+  deals?: Deal[];
+  activities?: Activity[];
+  tags?: Tag[];
+}
+
+export interface CustomerCreateRequest {
+  name: string;
+  email: string;
+  phone?: string;
+  company?: string;
+  role?: string;
+  status?: string;
+  assigned_to?: number;
+  notes?: string;
+  next_follow_up_at?: string;
+}
+
+export interface CustomerDetailResponse {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  name: string;
+  email: string;
+  phone?: string;
+  company?: string;
+  role?: string;
+  status: string;
+  assigned_to?: number;
+  contacted: boolean;
+  next_follow_up_at?: string;
+  notes?: string;
+  archived_at?: string;
+  email_opt_out_at?: string;
+  email_invalid_at?: string;
+  email_domain?: string;
+  anonymized_at?: string;
+  external_id?: string;
+  converted_at?: string;
+  converted_by?: number;
+  redacted_fields?: string[];
+  contacts?: Contact[];
+  deals?: Deal[];
+  activities?: Activity[];
+  tags?: Tag[];
+  contacts_count: number;
+  open_deals_count: number;
+  upcoming_activities_count: number;
+  recent_activities?: Activity[];
+  domain_mates_count: number;
+  domain_mates?: CompanyMember[];
+}
+
+export interface CustomerListResponse {
+  data: Customer[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+  next_page_token?: string;
+  next_cursor?: string;
+  filters?: Record<string, string>;
+}
+
+export interface CustomerUpdateRequest {
+  name: string;
+  email: string;
+  phone?: string;
+  company?: string;
+  role?: string;
+  status?: string;
+  assigned_to?: number;
+  contacted?: boolean;
+  notes?: string;
+  next_follow_up_at?: string;
+  change_reason?: string;
+}
+
+export interface Deal {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  title: string;
+  description?: string;
+  customer_id: number;
+  contact_id?: number;
+  stage: string;
+  amount: number;
+  currency: string;
+  probability: number;
+  expected_close_date?: string;
+  actual_close_date?: string;
+  owner_id?: number;
+  lost_reason?: string;
+  external_id?: string;
+  archived_at?: string;
+  board_position: number;
+  next_step?: string;
+  next_step_due?: string;
+  next_step_set_at?: string;
+  next_step_nudged_at?: string;
+  redacted_fields?: string[];
+  checklist?: DealChecklist;
+  customer?: Customer;
+  contact?: Contact;
+  activities?: Activity[];
+  notes?: Note[];
+}
+
+export interface DealChecklist {
+  items: DealChecklistEntry[];
+  missing: string[];
+  complete: boolean;
+}
+
+export interface DealChecklistEntry {
+  key: string;
+  label: string;
+  required: boolean;
+  checked: boolean;
+  checked_by?: number;
+  checked_by_name?: string;
+  checked_at?: string;
+}
+
+export interface DealCreateMeta {
+  defaulted: Record<string, string>;
+}
+
+export interface DealCreateRequest {
+  title: string;
+  description?: string;
+  customer_id: number;
+  contact_id?: number;
+  stage?: string;
+  amount?: number;
+  currency?: string;
+  probability?: number;
+  expected_close_date?: string;
+  owner_id?: number;
+  external_id?: string;
+  next_step?: string;
+  next_step_due?: string;
+}
+
+export interface DealCreateResponse {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  title: string;
+  description?: string;
+  customer_id: number;
+  contact_id?: number;
+  stage: string;
+  amount: number;
+  currency: string;
+  probability: number;
+  expected_close_date?: string;
+  actual_close_date?: string;
+  owner_id?: number;
+  lost_reason?: string;
+  external_id?: string;
+  archived_at?: string;
+  board_position: number;
+  next_step?: string;
+  next_step_due?: string;
+  next_step_set_at?: string;
+  next_step_nudged_at?: string;
+  redacted_fields?: string[];
+  checklist?: DealChecklist;
+  customer?: Customer;
+  contact?: Contact;
+  activities?: Activity[];
+  notes?: Note[];
+  meta?: DealCreateMeta;
+}
+
+export interface DealListResponse {
+  data: Deal[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+  next_page_token?: string;
+  filters?: Record<string, string>;
+}
+
+export interface DealNextStepUpdate {
+  next_step?: string;
+  next_step_due?: string;
+  clear?: boolean;
+}
+
+export interface DealStageTransitionRequest {
+  stage: string;
+  lost_reason?: string;
+  next_step?: string;
+  next_step_due?: string;
+  change_reason?: string;
+}
+
+export interface DealUpdateRequest {
+  title?: string;
+  description?: string;
+  customer_id?: number;
+  contact_id?: number;
+  stage?: string;
+  amount?: number;
+  currency?: string;
+  probability?: number;
+  expected_close_date?: string;
+  actual_close_date?: string;
+  owner_id?: number;
+  lost_reason?: string;
+  external_id?: string;
+  next_step?: string;
+  next_step_due?: string;
+  change_reason?: string;
+}
+
+export interface EmailEngagement {
+  opens: number;
+  clicks: number;
+  unsubscribed: boolean;
+  first_opened_at?: string;
+  last_opened_at?: string;
+}
+
+export interface EntityCapabilities {
+  fields: Record<string, FieldCapability>;
+}
+
+export interface ExportJobRequest {
+  type: string;
+  params?: unknown;
+  template_id?: number;
+}
+
+export interface FieldCapability {
+  editable: boolean;
+  editable_when_owner: boolean;
+}
+
+export interface Job {
+  id: number;
+  type: string;
+  status: string;
+  params?: string;
+  error?: string;
+  created_by: number;
+  created_by_name?: string;
+  template_id?: number;
+  result?: string;
+  artifact: JobArtifact;
+  checkpoint: JobCheckpoint;
+  resumes: number;
+  started_at?: string;
+  finished_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface JobArtifact {
+  file_name?: string;
+  content_type?: string;
+  rows: number;
+  bytes: number;
+  checksum?: string;
+  resumes: number;
+  expires_at?: string;
+  deleted_at?: string;
+}
+
+export interface JobCheckpoint {
+  rows: number;
+  bytes: number;
+  checksum?: string;
+  at?: string;
+}
+
+export interface JobListResponse {
+  data: Job[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+}
+
+export interface MeResponse {
+  user: User;
+  permissions: string[];
+}
+
+export interface NextActivityRequest {
+  title: string;
+  description?: string;
+  type?: string;
+  customer_id?: number;
+  deal_id?: number;
+  contact_id?: number;
+  assigned_to?: number;
+  due_date?: string;
+  due_in_days?: number;
+  due_in_business_days?: number;
+  priority?: string;
+}
+
+export interface Note {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  content: string;
+  customer_id?: number;
+  deal_id?: number;
+  activity_id?: number;
+  author_id: number;
+  author_name?: string;
+  imported: boolean;
+  customer?: Customer;
+  deal?: Deal;
+}
+
+export interface OpenDealLimitCapabilities {
+  max: number;
+  mode: string;
+}
+
+export interface PipelineStage {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  name: string;
+  display_name: string;
+  order: number;
+  color?: string;
+  is_active: boolean;
+}
+
+export interface PipelineStageCreateRequest {
+  name: string;
+  display_name: string;
+  order?: number;
+  color?: string;
+  is_active?: boolean;
+}
+
+export interface PipelineStageListResponse {
+  data: PipelineStage[];
+}
+
+export interface PipelineStageUpdateRequest {
+  name: string;
+  display_name: string;
+  order: number;
+  color?: string;
+  is_active?: boolean;
+}
+
+export interface Tag {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  name: string;
+  color?: string;
+  group_id?: number;
+  customers?: Customer[];
+  group?: TagGroup;
+}
+
+export interface TagCreateRequest {
+  name: string;
+  color?: string;
+  group_id?: number;
+}
+
+export interface TagGroup {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string | null;
+  name: string;
+  exclusive: boolean;
+  tags?: Tag[];
+}
+
+export interface TagListResponse {
+  data: Tag[];
+  total: number;
+}
+
+export interface TagUpdateRequest {
+  name?: string;
+  color?: string;
+  group_id?: number;
+}
+
+export interface TelephonyCall {
+  id: number;
+  event_id: string;
+  direction: string;
+  from_number: string;
+  to_number: string;
+  external_number: string;
+  extension?: string;
+  duration_seconds: number;
+  recording_url?: string;
+  started_at: string;
+  status: string;
+  activity_id?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface User {
+  id: number;
+  email?: string;
+  name?: string;
+  role: string;
+  org?: string;
+  is_active: boolean;
+}
+
+/** Client of the CRM admin API. Methods reject with an ApiError on error statuses. */
+export interface CrmClient {
+  /** Returns the caller and their permissions. `GET /admin/me` */
+  getMe(): Promise<MeResponse>;
+  /** Returns the fields the caller can edit per entity. `GET /admin/me/capabilities` */
+  getCapabilities(): Promise<CapabilitiesResponse>;
+  /** Lists the activities assigned to the caller. `GET /admin/me/activities` */
+  listMyActivities(query?: Query): Promise<ActivityListResponse>;
+  /** Lists customers. `GET /admin/customers` */
+  listCustomers(query?: Query): Promise<CustomerListResponse>;
+  /** Creates a customer. `POST /admin/customers` */
+  createCustomer(body: CustomerCreateRequest): Promise<Customer>;
+  /** Returns a customer with its related counts. `GET /admin/customers/:id` */
+  getCustomer(id: number): Promise<CustomerDetailResponse>;
+  /** Updates a customer. `PUT /admin/customers/:id` */
+  updateCustomer(id: number, body: CustomerUpdateRequest): Promise<Customer>;
+  /** Deletes a customer with its related records. `DELETE /admin/customers/:id` */
+  deleteCustomer(id: number): Promise<void>;
+  /** Archives a customer. `POST /admin/customers/:id/archive` */
+  archiveCustomer(id: number): Promise<Customer>;
+  /** Restores an archived customer. `POST /admin/customers/:id/unarchive` */
+  unarchiveCustomer(id: number): Promise<Customer>;
+  /** Assigns an unassigned customer to the caller. `POST /admin/customers/:id/claim` */
+  claimCustomer(id: number): Promise<Customer>;
+  /** Tags a customer. `POST /admin/customers/:id/tags/:tagId` */
+  assignTagToCustomer(id: number, tagId: number): Promise<void>;
+  /** Removes a tag from a customer. `DELETE /admin/customers/:id/tags/:tagId` */
+  removeTagFromCustomer(id: number, tagId: number): Promise<void>;
+  /** Lists a customer's contacts. `GET /admin/customers/:id/contacts` */
+  listContacts(id: number, query?: Query): Promise<ContactListResponse>;
+  /** Adds a contact to a customer. `POST /admin/customers/:id/contacts` */
+  createContact(id: number, body: ContactCreateRequest): Promise<Contact>;
+  /** Updates a contact. `PUT /admin/contacts/:id` */
+  updateContact(id: number, body: ContactUpdateRequest): Promise<Contact>;
+  /** Deletes a contact. `DELETE /admin/contacts/:id` */
+  deleteContact(id: number): Promise<void>;
+  /** Lists deals. `GET /admin/deals` */
+  listDeals(query?: Query): Promise<DealListResponse>;
+  /** Creates a deal. `POST /admin/deals` */
+  createDeal(body: DealCreateRequest): Promise<DealCreateResponse>;
+  /** Returns a deal with its timeline and checklist. `GET /admin/deals/:id` */
+  getDeal(id: number): Promise<Deal>;
+  /** Updates a deal. `PUT /admin/deals/:id` */
+  updateDeal(id: number, body: DealUpdateRequest): Promise<Deal>;
+  /** Moves a deal to another stage. `PATCH /admin/deals/:id` */
+  moveDealStage(id: number, body: DealStageTransitionRequest): Promise<Deal>;
+  /** Deletes a deal. `DELETE /admin/deals/:id` */
+  deleteDeal(id: number): Promise<void>;
+  /** Archives a deal. `POST /admin/deals/:id/archive` */
+  archiveDeal(id: number): Promise<Deal>;
+  /** Restores an archived deal. `POST /admin/deals/:id/unarchive` */
+  unarchiveDeal(id: number): Promise<Deal>;
+  /** Lists activities. `GET /admin/activities` */
+  listActivities(query?: Query): Promise<ActivityListResponse>;
+  /** Logs or schedules an activity. `POST /admin/activities` */
+  createActivity(body: ActivityCreateRequest): Promise<Activity>;
+  /** Returns an activity. `GET /admin/activities/:id` */
+  getActivity(id: number): Promise<Activity>;
+  /** Updates an activity. `PUT /admin/activities/:id` */
+  updateActivity(id: number, body: ActivityUpdateRequest): Promise<Activity>;
+  /** Changes an activity's status. `PATCH /admin/activities/:id` */
+  updateActivityStatus(id: number, body: ActivityStatusUpdateRequest): Promise<Activity>;
+  /** Completes an activity with its outcome. `POST /admin/activities/:id/complete` */
+  completeActivity(id: number, body: ActivityCompleteRequest): Promise<ActivityCompletionResponse>;
+  /** Deletes an activity. `DELETE /admin/activities/:id` */
+  deleteActivity(id: number): Promise<void>;
+  /** Lists tags. `GET /admin/tags` */
+  listTags(): Promise<TagListResponse>;
+  /** Creates a tag. `POST /admin/tags` */
+  createTag(body: TagCreateRequest): Promise<Tag>;
+  /** Updates a tag. `PUT /admin/tags/:id` */
+  updateTag(id: number, body: TagUpdateRequest): Promise<Tag>;
+  /** Deletes a tag. `DELETE /admin/tags/:id` */
+  deleteTag(id: number): Promise<void>;
+  /** Lists the pipeline stages in board order. `GET /admin/pipeline-stages` */
+  listPipelineStages(): Promise<PipelineStageListResponse>;
+  /** Adds a pipeline stage. `POST /admin/pipeline-stages` */
+  createPipelineStage(body: PipelineStageCreateRequest): Promise<PipelineStage>;
+  /** Updates a pipeline stage. `PUT /admin/pipeline-stages/:id` */
+  updatePipelineStage(id: number, body: PipelineStageUpdateRequest): Promise<PipelineStage>;
+  /** Deletes a pipeline stage no deal is in. `DELETE /admin/pipeline-stages/:id` */
+  deletePipelineStage(id: number): Promise<void>;
+  /** Lists background jobs. `GET /admin/jobs` */
+  listJobs(query?: Query): Promise<JobListResponse>;
+  /** Starts an export job. `POST /admin/jobs/exports` */
+  createExportJob(body: ExportJobRequest): Promise<Job>;
+  /** Returns a job and its progress. `GET /admin/jobs/:id` */
+  getJob(id: number): Promise<Job>;
+}
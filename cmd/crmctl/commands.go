@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/consistency"
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/smoketest"
+)
+
+// commands lists every subcommand
+var commands = []command{
+	{group: "jobs", name: "list", help: "List async jobs", run: jobsList},
+	{group: "jobs", name: "get", args: "ID", help: "Show a job", run: jobsGet},
+	{group: "jobs", name: "resume", args: "ID", help: "Resume a failed export from its last checkpoint", run: jobsResume},
+	{group: "jobs", name: "wait", args: "[-interval 2s] [-wait 10m] ID", help: "Wait for a job to finish; fails if the job fails", run: jobsWait},
+	{group: "export", name: "customers", args: "[-template ID] [-params JSON] [-wait] [-out FILE]", help: "Start a customers CSV export", run: exportCommand("customers", "customers_csv")},
+	{group: "export", name: "deals", args: "[-template ID] [-params JSON] [-wait] [-out FILE]", help: "Start a deals CSV export", run: exportCommand("deals", "deals_csv")},
+	{group: "maintenance", name: "consistency", help: "Run the consistency checks; check failed if any finding is open", run: maintenanceConsistency},
+	{group: "maintenance", name: "backfill-domains", help: "Recompute customer email domains", run: maintenanceBackfillDomains},
+	{group: "maintenance", name: "classify-outcomes", args: "[-apply]", help: "Review how free-text activity outcomes map onto outcome codes; -apply records the codes", run: maintenanceClassifyOutcomes},
+	{group: "maintenance", name: "slow-queries", help: "Show captured slow queries by fingerprint", run: maintenanceSlowQueries},
+	{group: "maintenance", name: "smoke-test", help: "Run the post-deploy smoke test; check failed if any step fails", run: maintenanceSmokeTest},
+	{group: "auth", name: "list", help: "List service accounts", run: authList},
+	{group: "auth", name: "rotate", args: "[-grace-hours N] ID", help: "Rotate a service account token and print the new token", run: authRotate},
+	{group: "auth", name: "revoke", args: "ID", help: "Revoke a service account and all of its tokens", run: authRevoke},
+	{group: "audit", name: "verify", args: "[-from RFC3339] [-to RFC3339]", help: "Verify the audit log hash chain; check failed if broken", run: auditVerify},
+}
+
+// newFlags creates the flag set of a subcommand
+func newFlags(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("crmctl "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parseID parses the single positional ID argument of a subcommand
+func parseID(fs *flag.FlagSet) (uint64, error) {
+	if fs.NArg() != 1 {
+		return 0, usageError{"expected exactly one ID argument"}
+	}
+	id, err := strconv.ParseUint(fs.Arg(0), 10, 32)
+	if err != nil || id == 0 {
+		return 0, usageError{fmt.Sprintf("invalid ID %q", fs.Arg(0))}
+	}
+	return id, nil
+}
+
+// jobRows renders jobs as table rows
+func jobRows(jobs ...models.Job) ([]string, [][]string) {
+	header := []string{"ID", "TYPE", "STATUS", "ROWS", "CREATED", "FINISHED", "ERROR"}
+	rows := make([][]string, len(jobs))
+	for i, job := range jobs {
+		createdAt := job.CreatedAt
+		rows[i] = []string{
+			strconv.FormatUint(uint64(job.ID), 10),
+			job.Type,
+			string(job.Status),
+			strconv.FormatInt(job.Artifact.Rows, 10),
+			formatTime(&createdAt),
+			formatTime(job.FinishedAt),
+			orDash(job.Error),
+		}
+	}
+	return header, rows
+}
+
+// jobsList lists jobs
+func jobsList(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "jobs list")
+	jobType := fs.String("type", "", "filter by job type")
+	status := fs.String("status", "", "filter by status")
+	page := fs.Int("page", 1, "page number")
+	pageSize := fs.Int("page-size", 20, "jobs per page")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := url.Values{"page": {strconv.Itoa(*page)}, "page_size": {strconv.Itoa(*pageSize)}}
+	if *jobType != "" {
+		q.Set("type", *jobType)
+	}
+	if *status != "" {
+		q.Set("status", *status)
+	}
+
+	var resp models.JobListResponse
+	if err := e.client.Do(ctx, http.MethodGet, "/admin/jobs", q, nil, &resp); err != nil {
+		return err
+	}
+	header, rows := jobRows(resp.Data...)
+	return e.out.Print(resp, header, rows)
+}
+
+// jobsGet shows one job
+func jobsGet(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "jobs get")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	var job models.Job
+	if err := e.client.Do(ctx, http.MethodGet, fmt.Sprintf("/admin/jobs/%d", id), nil, nil, &job); err != nil {
+		return err
+	}
+	header, rows := jobRows(job)
+	return e.out.Print(job, header, rows)
+}
+
+// jobsResume resumes a failed export
+func jobsResume(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "jobs resume")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	var job models.Job
+	if err := e.client.Do(ctx, http.MethodPost, fmt.Sprintf("/admin/jobs/%d/resume", id), nil, nil, &job); err != nil {
+		return err
+	}
+	header, rows := jobRows(job)
+	return e.out.Print(job, header, rows)
+}
+
+// jobsWait polls a job until it finishes
+func jobsWait(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "jobs wait")
+	interval := fs.Duration("interval", 2*time.Second, "polling interval")
+	wait := fs.Duration("wait", 10*time.Minute, "give up after this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	job, err := waitForJob(ctx, e, uint(id), *interval, *wait)
+	if err != nil {
+		return err
+	}
+	header, rows := jobRows(job)
+	if err := e.out.Print(job, header, rows); err != nil {
+		return err
+	}
+	if job.Status == models.JobStatusFailed {
+		return fmt.Errorf("job %d failed: %s", job.ID, job.Error)
+	}
+	return nil
+}
+
+// waitForJob polls a job until it completes or fails
+func waitForJob(ctx context.Context, e *env, id uint, interval, wait time.Duration) (models.Job, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var job models.Job
+		if err := e.client.Do(ctx, http.MethodGet, fmt.Sprintf("/admin/jobs/%d", id), nil, nil, &job); err != nil {
+			return job, err
+		}
+		if job.IsFinished() {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, fmt.Errorf("job %d is still %s: %w", id, job.Status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// exportCommand starts an export job of the given type, optionally waiting
+// for it and downloading the file
+func exportCommand(name, jobType string) func(context.Context, *env, []string) error {
+	return func(ctx context.Context, e *env, args []string) error {
+		fs := newFlags(e, "export "+name)
+		templateID := fs.Uint("template", 0, "export template ID (defaults to the entity's default template)")
+		params := fs.String("params", "", "export params as JSON, e.g. '{\"status\":\"active\"}'")
+		wait := fs.Bool("wait", false, "wait for the export to finish")
+		out := fs.String("out", "", "download the CSV to this file (- for stdout); implies -wait")
+		interval := fs.Duration("interval", 2*time.Second, "polling interval while waiting")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 {
+			return usageError{"unexpected arguments: " + strings.Join(fs.Args(), " ")}
+		}
+
+		req := handlers.ExportJobRequest{Type: jobType}
+		if *params != "" {
+			if !json.Valid([]byte(*params)) {
+				return usageError{"-params is not valid JSON"}
+			}
+			req.Params = json.RawMessage(*params)
+		}
+		if *templateID != 0 {
+			id := *templateID
+			req.TemplateID = &id
+		}
+
+		var job models.Job
+		if err := e.client.Do(ctx, http.MethodPost, "/admin/jobs/exports", nil, req, &job); err != nil {
+			return err
+		}
+
+		if *wait || *out != "" {
+			var err error
+			if job, err = waitForJob(ctx, e, job.ID, *interval, 24*time.Hour); err != nil {
+				return err
+			}
+			if job.Status == models.JobStatusFailed {
+				return fmt.Errorf("export job %d failed: %s", job.ID, job.Error)
+			}
+		}
+
+		if *out != "" {
+			return downloadArtifact(ctx, e, job, *out)
+		}
+		header, rows := jobRows(job)
+		return e.out.Print(job, header, rows)
+	}
+}
+
+// downloadArtifact saves the file of a completed job
+func downloadArtifact(ctx context.Context, e *env, job models.Job, path string) error {
+	var w io.Writer = e.stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	written, err := e.client.Download(ctx, fmt.Sprintf("/admin/jobs/%d/download", job.ID), w)
+	if err != nil {
+		return err
+	}
+	if path != "-" {
+		fmt.Fprintf(e.stderr, "wrote %d rows (%d bytes) to %s\n", job.Artifact.Rows, written, path)
+	}
+	return nil
+}
+
+// maintenanceConsistency runs the consistency checks
+func maintenanceConsistency(ctx context.Context, e *env, args []string) error {
+	if err := newFlags(e, "maintenance consistency").Parse(args); err != nil {
+		return err
+	}
+
+	var summary consistency.RunSummary
+	if err := e.client.Do(ctx, http.MethodPost, "/admin/maintenance/consistency/run", nil, nil, &summary); err != nil {
+		return err
+	}
+
+	open, failed := 0, 0
+	rows := make([][]string, len(summary.Checks))
+	for i, check := range summary.Checks {
+		open += check.Open
+		if check.Error != "" {
+			failed++
+		}
+		rows[i] = []string{check.Name, string(check.Severity), strconv.Itoa(check.Open), strconv.Itoa(check.Resolved), orDash(check.Error)}
+	}
+	if err := e.out.Print(summary, []string{"CHECK", "SEVERITY", "OPEN", "RESOLVED", "ERROR"}, rows); err != nil {
+		return err
+	}
+	if open > 0 || failed > 0 {
+		return checkFailedError{fmt.Sprintf("%d open findings, %d checks failed", open, failed)}
+	}
+	return nil
+}
+
+// maintenanceBackfillDomains recomputes customer email domains
+func maintenanceBackfillDomains(ctx context.Context, e *env, args []string) error {
+	if err := newFlags(e, "maintenance backfill-domains").Parse(args); err != nil {
+		return err
+	}
+
+	var resp handlers.EmailDomainBackfillResponse
+	if err := e.client.Do(ctx, http.MethodPost, "/admin/maintenance/email-domains/backfill", nil, nil, &resp); err != nil {
+		return err
+	}
+	return e.out.Print(resp, []string{"UPDATED"}, [][]string{{strconv.FormatInt(resp.Updated, 10)}})
+}
+
+// maintenanceClassifyOutcomes classifies free-text activity outcomes by the
+// keywords of the outcome taxonomy
+func maintenanceClassifyOutcomes(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "maintenance classify-outcomes")
+	apply := fs.Bool("apply", false, "record the outcome codes of classified activities instead of only reviewing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var resp handlers.OutcomeClassification
+	req := handlers.OutcomeClassificationRequest{Apply: *apply}
+	if err := e.client.Do(ctx, http.MethodPost, "/admin/maintenance/activity-outcomes/classify", nil, req, &resp); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(resp.Groups))
+	for i, group := range resp.Groups {
+		rows[i] = []string{string(group.Type), group.Result, orDash(strings.Join(group.Codes, ",")), strconv.Itoa(group.Count), strings.Join(group.Samples, " | ")}
+	}
+	if err := e.out.Print(resp, []string{"TYPE", "RESULT", "CODES", "COUNT", "SAMPLES"}, rows); err != nil {
+		return err
+	}
+	if resp.Applied {
+		fmt.Fprintf(e.stderr, "recorded outcome codes of %d activities; %d ambiguous and %d unmatched left without a code\n", resp.Classified, resp.Ambiguous, resp.Unmatched)
+	} else {
+		fmt.Fprintf(e.stderr, "%d activities would get an outcome code; run with -apply to record them\n", resp.Classified)
+	}
+	return nil
+}
+
+// maintenanceSmokeTest runs the post-deploy smoke test
+func maintenanceSmokeTest(ctx context.Context, e *env, args []string) error {
+	if err := newFlags(e, "maintenance smoke-test").Parse(args); err != nil {
+		return err
+	}
+
+	var resp handlers.SmokeTestResponse
+	if err := e.client.Do(ctx, http.MethodPost, "/admin/maintenance/smoke-test", nil, nil, &resp); err != nil {
+		return err
+	}
+
+	failed := 0
+	rows := make([][]string, len(resp.Steps))
+	for i, step := range resp.Steps {
+		if step.Result == smoketest.StepFailed {
+			failed++
+		}
+		rows[i] = []string{step.Name, step.Result, strconv.FormatFloat(step.DurationMs, 'f', 1, 64), orDash(step.Error)}
+	}
+	if err := e.out.Print(resp, []string{"STEP", "RESULT", "MS", "ERROR"}, rows); err != nil {
+		return err
+	}
+	if !resp.Passed {
+		return checkFailedError{fmt.Sprintf("%d steps failed, see job %d", failed, resp.JobID)}
+	}
+	return nil
+}
+
+// maintenanceSlowQueries shows captured slow queries
+func maintenanceSlowQueries(ctx context.Context, e *env, args []string) error {
+	if err := newFlags(e, "maintenance slow-queries").Parse(args); err != nil {
+		return err
+	}
+
+	var report handlers.SlowQueryReport
+	if err := e.client.Do(ctx, http.MethodGet, "/admin/maintenance/slow-queries", nil, nil, &report); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(report.Summary))
+	for i, stats := range report.Summary {
+		lastSeen := stats.LastSeenAt
+		rows[i] = []string{
+			strconv.Itoa(stats.Count),
+			strconv.FormatFloat(stats.MaxMs, 'f', 1, 64),
+			strconv.FormatFloat(stats.P95Ms, 'f', 1, 64),
+			formatTime(&lastSeen),
+			stats.Fingerprint,
+		}
+	}
+	return e.out.Print(report, []string{"COUNT", "MAX_MS", "P95_MS", "LAST_SEEN", "FINGERPRINT"}, rows)
+}
+
+// authList lists service accounts
+func authList(ctx context.Context, e *env, args []string) error {
+	if err := newFlags(e, "auth list").Parse(args); err != nil {
+		return err
+	}
+
+	var resp models.ServiceAccountListResponse
+	if err := e.client.Do(ctx, http.MethodGet, "/admin/service-accounts", nil, nil, &resp); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(resp.Data))
+	for i, account := range resp.Data {
+		rows[i] = []string{
+			strconv.FormatUint(uint64(account.ID), 10),
+			account.Name,
+			account.Role,
+			strings.Join(account.Scopes, ","),
+			strconv.Itoa(len(account.Tokens)),
+			formatTime(account.LastUsedAt),
+			formatTime(account.RevokedAt),
+		}
+	}
+	return e.out.Print(resp, []string{"ID", "NAME", "ROLE", "SCOPES", "TOKENS", "LAST_USED", "REVOKED"}, rows)
+}
+
+// authRotate rotates a service account token
+func authRotate(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "auth rotate")
+	graceHours := fs.Int("grace-hours", -1, "hours the previous token stays valid (server default when unset)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	var req handlers.ServiceAccountRotateRequest
+	if *graceHours >= 0 {
+		req.GracePeriodHours = graceHours
+	}
+
+	var resp models.ServiceAccountCreateResponse
+	if err := e.client.Do(ctx, http.MethodPost, fmt.Sprintf("/admin/service-accounts/%d/rotate", id), nil, req, &resp); err != nil {
+		return err
+	}
+	return e.out.Print(resp, []string{"ID", "NAME", "TOKEN"}, [][]string{{
+		strconv.FormatUint(uint64(resp.ServiceAccount.ID), 10), resp.ServiceAccount.Name, resp.Token,
+	}})
+}
+
+// authRevoke revokes a service account
+func authRevoke(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "auth revoke")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if err := e.client.Do(ctx, http.MethodDelete, fmt.Sprintf("/admin/service-accounts/%d", id), nil, nil, &resp); err != nil {
+		return err
+	}
+	return e.out.Print(resp, []string{"ID", "RESULT"}, [][]string{{strconv.FormatUint(id, 10), resp.Message}})
+}
+
+// auditVerify verifies the audit log hash chain
+func auditVerify(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "audit verify")
+	from := fs.String("from", "", "only verify entries created at or after this RFC 3339 time")
+	to := fs.String("to", "", "only verify entries created at or before this RFC 3339 time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	if *from != "" {
+		q.Set("from", *from)
+	}
+	if *to != "" {
+		q.Set("to", *to)
+	}
+
+	var result audittrail.Result
+	if err := e.client.Do(ctx, http.MethodGet, "/admin/audit-logs/verify", q, nil, &result); err != nil {
+		return err
+	}
+
+	broken := "-"
+	if result.Broken != nil {
+		broken = fmt.Sprintf("#%d %s", result.Broken.AuditID, result.Broken.Reason)
+	}
+	row := []string{
+		strconv.FormatBool(result.Valid),
+		strconv.FormatInt(result.Checked, 10),
+		strconv.FormatInt(result.Legacy, 10),
+		strconv.FormatInt(result.Redacted, 10),
+		broken,
+	}
+	if err := e.out.Print(result, []string{"VALID", "CHECKED", "LEGACY", "REDACTED", "BROKEN"}, [][]string{row}); err != nil {
+		return err
+	}
+	if !result.Valid {
+		return checkFailedError{"audit log hash chain is broken at " + broken}
+	}
+	return nil
+}
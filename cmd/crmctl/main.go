@@ -0,0 +1,176 @@
+// Command crmctl runs routine admin tasks against the CRM admin API.
+//
+// It authenticates with the token in CRM_API_TOKEN, normally a
+// service-account token, and talks to the server at CRM_API_URL.
+//
+//	crmctl [-url URL] [-o table|json] [-timeout 60s] <group> <command> [flags]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Exit codes
+const (
+	exitOK          = 0
+	exitFailure     = 1 // Request or job failed
+	exitUsage       = 2 // Invalid command line
+	exitAuth        = 3 // Token missing, invalid or lacking permission
+	exitNotFound    = 4 // Resource not found
+	exitCheckFailed = 5 // A check ran and reported problems
+)
+
+// usageError reports an invalid command line
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+// checkFailedError reports a check that ran and found problems
+type checkFailedError struct{ msg string }
+
+func (e checkFailedError) Error() string { return e.msg }
+
+// env is what commands run with
+type env struct {
+	client *Client
+	out    printer
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command is one subcommand, run as "crmctl <group> <name>"
+type command struct {
+	group string
+	name  string
+	args  string // Positional arguments shown in usage
+	help  string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	global := flag.NewFlagSet("crmctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	baseURL := global.String("url", envOr("CRM_API_URL", "http://localhost:3000"), "CRM service base URL (CRM_API_URL)")
+	output := global.String("o", outputTable, "output format: table or json")
+	timeout := global.Duration("timeout", 60*time.Second, "timeout of each API request")
+	global.Usage = func() { printUsage(global) }
+	if err := global.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *output != outputTable && *output != outputJSON {
+		fmt.Fprintf(stderr, "crmctl: unknown output format %q\n", *output)
+		return exitUsage
+	}
+
+	rest := global.Args()
+	if len(rest) < 2 {
+		printUsage(global)
+		return exitUsage
+	}
+	cmd, ok := findCommand(rest[0], rest[1])
+	if !ok {
+		fmt.Fprintf(stderr, "crmctl: unknown command %q\n", rest[0]+" "+rest[1])
+		printUsage(global)
+		return exitUsage
+	}
+
+	token := os.Getenv("CRM_API_TOKEN")
+	if token == "" {
+		fmt.Fprintln(stderr, "crmctl: CRM_API_TOKEN is not set")
+		return exitAuth
+	}
+
+	e := &env{
+		client: NewClient(*baseURL, token, &http.Client{Timeout: *timeout}),
+		out:    printer{w: stdout, mode: *output},
+		stdout: stdout,
+		stderr: stderr,
+	}
+	if err := cmd.run(ctx, e, rest[2:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "crmctl: %v\n", err)
+		}
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// exitCode maps a command error to the process exit code
+func exitCode(err error) int {
+	var usage usageError
+	var check checkFailedError
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, flag.ErrHelp), errors.As(err, &usage):
+		return exitUsage
+	case errors.As(err, &check):
+		return exitCheckFailed
+	case errors.As(err, &apiErr):
+		switch apiErr.Status {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return exitAuth
+		case http.StatusNotFound:
+			return exitNotFound
+		case http.StatusBadRequest:
+			return exitUsage
+		}
+	}
+	return exitFailure
+}
+
+// findCommand looks up a subcommand by group and name
+func findCommand(group, name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.group == group && cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage lists the global flags and subcommands
+func printUsage(global *flag.FlagSet) {
+	w := global.Output()
+	fmt.Fprintln(w, "Usage: crmctl [flags] <group> <command> [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	global.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	sorted := append([]command(nil), commands...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].group < sorted[j].group })
+	for _, cmd := range sorted {
+		usage := strings.TrimSpace(cmd.group + " " + cmd.name + " " + cmd.args)
+		fmt.Fprintf(w, "  %s\n      %s\n", usage, cmd.help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "The API token is read from CRM_API_TOKEN.")
+	fmt.Fprintln(w, "Exit codes: 0 ok, 1 failure, 2 usage, 3 auth, 4 not found, 5 check failed.")
+}
+
+// envOr reads an environment variable or returns a default value
+func envOr(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
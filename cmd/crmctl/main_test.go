@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testserver"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// crmctl runs a command line with token as CRM_API_TOKEN and returns the
+// exit code and output
+func crmctl(t *testing.T, token string, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("CRM_API_TOKEN", token)
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// userToken signs a token for a user of the test server
+func userToken(t *testing.T, id uint, role string) string {
+	return testserver.Sign(t, jwt.MapClaims{
+		"user_id": id,
+		"role":    role,
+		"email":   "ops@example.com",
+		"name":    "Ops",
+		"iat":     time.Now().Add(-time.Minute).Unix(),
+	})
+}
+
+func TestCommandLineErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		token string
+		args  []string
+		want  int
+	}{
+		{"no command", "token", nil, exitUsage},
+		{"unknown command", "token", []string{"jobs", "purge"}, exitUsage},
+		{"unknown output", "token", []string{"-o", "yaml", "jobs", "list"}, exitUsage},
+		{"invalid ID", "token", []string{"jobs", "get", "abc"}, exitUsage},
+		{"invalid params", "token", []string{"export", "customers", "-params", "{"}, exitUsage},
+		{"no token", "", []string{"jobs", "list"}, exitAuth},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"-url", "http://127.0.0.1:1"}, tc.args...)
+			if code, _, stderr := crmctl(t, tc.token, args...); code != tc.want {
+				t.Errorf("exit code = %d, want %d: %s", code, tc.want, stderr)
+			}
+		})
+	}
+}
+
+// TestCommandsAgainstTheServer runs crmctl against the service on a test
+// database with a service account token, as ops would
+func TestCommandsAgainstTheServer(t *testing.T) {
+	s := testserver.New(t, func(cfg *config.Config) {
+		cfg.AuthProviders = auth.ProviderServiceAccount + "," + auth.ProviderHMAC
+	})
+	api := httptest.NewServer(s.Handler)
+	defer api.Close()
+	s.Factory.Customer(t)
+	s.Factory.Customer(t)
+
+	// The service account is created as an admin would create it
+	body, err := json.Marshal(handlers.ServiceAccountCreateRequest{
+		Name:   "ops",
+		Role:   models.RoleAdmin,
+		Scopes: []string{"jobs:write", "customers:read", "service-accounts:write", "audit-logs:read"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, api.URL+"/admin/service-accounts", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+userToken(t, 1, models.RoleAdmin))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var created models.ServiceAccountCreateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create service account: status %d: %v", resp.StatusCode, err)
+	}
+	token := created.Token
+	cli := func(args ...string) (int, string, string) {
+		t.Helper()
+		return crmctl(t, token, append([]string{"-url", api.URL, "-o", "json"}, args...)...)
+	}
+
+	t.Run("auth list", func(t *testing.T) {
+		code, stdout, stderr := cli("auth", "list")
+		if code != exitOK {
+			t.Fatalf("exit code = %d: %s", code, stderr)
+		}
+		var list models.ServiceAccountListResponse
+		if err := json.Unmarshal([]byte(stdout), &list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Data) != 1 || list.Data[0].Name != "ops" {
+			t.Errorf("accounts = %+v", list.Data)
+		}
+	})
+
+	t.Run("export customers", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "customers.csv")
+		if code, _, stderr := cli("export", "customers", "-interval", "50ms", "-out", out); code != exitOK {
+			t.Fatalf("exit code = %d: %s", code, stderr)
+		}
+		csv, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Split(strings.TrimSpace(string(csv)), "\n"); len(lines) != 3 || !strings.Contains(string(csv), "Customer 2") {
+			t.Errorf("export = %q, want a header and both customers", csv)
+		}
+	})
+
+	t.Run("audit verify", func(t *testing.T) {
+		code, stdout, stderr := cli("audit", "verify")
+		if code != exitOK {
+			t.Fatalf("exit code = %d: %s", code, stderr)
+		}
+		if !strings.Contains(stdout, `"valid": true`) {
+			t.Errorf("output = %s", stdout)
+		}
+	})
+
+	t.Run("missing job", func(t *testing.T) {
+		if code, _, stderr := cli("jobs", "get", "999"); code != exitNotFound {
+			t.Errorf("exit code = %d, want %d: %s", code, exitNotFound, stderr)
+		}
+	})
+
+	t.Run("forbidden to agents", func(t *testing.T) {
+		code, _, stderr := crmctl(t, userToken(t, 3, models.RoleAgent), "-url", api.URL, "auth", "list")
+		if code != exitAuth {
+			t.Errorf("exit code = %d, want %d: %s", code, exitAuth, stderr)
+		}
+	})
+
+	t.Run("auth revoke", func(t *testing.T) {
+		id := strconv.FormatUint(uint64(created.ServiceAccount.ID), 10)
+		if code, _, stderr := cli("auth", "revoke", id); code != exitOK {
+			t.Fatalf("exit code = %d: %s", code, stderr)
+		}
+		// The revoked token no longer authenticates
+		if code, _, stderr := cli("auth", "list"); code != exitAuth {
+			t.Errorf("exit code after revoking = %d, want %d: %s", code, exitAuth, stderr)
+		}
+	})
+}
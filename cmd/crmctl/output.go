@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output modes
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer writes command results as a table or as JSON
+type printer struct {
+	w    io.Writer
+	mode string
+}
+
+// Print writes v as indented JSON in JSON mode, otherwise as a table with
+// the given header and rows
+func (p printer) Print(v interface{}, header []string, rows [][]string) error {
+	if p.mode == outputJSON {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// formatTime renders an optional timestamp for a table cell
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// orDash renders an empty table cell as a dash
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/apispec"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+)
+
+// generateGoEndpoints writes a Client method per endpoint. Request and
+// response types are the service's own, referenced from their packages.
+func generateGoEndpoints(endpoints []apispec.Endpoint) ([]byte, error) {
+	imports := map[string]bool{"context": true, "net/http": true}
+	var methods bytes.Buffer
+	for _, endpoint := range endpoints {
+		request, err := goTypeName(endpoint.Request, imports)
+		if err != nil {
+			return nil, fmt.Errorf("%s request: %w", endpoint.Name, err)
+		}
+		response, err := goTypeName(endpoint.Response, imports)
+		if err != nil {
+			return nil, fmt.Errorf("%s response: %w", endpoint.Name, err)
+		}
+
+		params := []string{"ctx context.Context"}
+		pathFormat, args := endpoint.Path, []string{}
+		for _, name := range endpoint.Params() {
+			param := goParamName(name)
+			if apispec.IsIDParam(name) {
+				params = append(params, param+" uint")
+				pathFormat = strings.Replace(pathFormat, ":"+name, "%d", 1)
+				args = append(args, param)
+			} else {
+				params = append(params, param+" string")
+				pathFormat = strings.Replace(pathFormat, ":"+name, "%s", 1)
+				args = append(args, "url.PathEscape("+param+")")
+				imports["net/url"] = true
+			}
+		}
+		path := fmt.Sprintf("%q", endpoint.Path)
+		if len(args) > 0 {
+			path = fmt.Sprintf("fmt.Sprintf(%q, %s)", pathFormat, strings.Join(args, ", "))
+			imports["fmt"] = true
+		}
+		query, body := "nil", "nil"
+		if endpoint.Query {
+			params = append(params, "query url.Values")
+			query = "query"
+			imports["net/url"] = true
+		}
+		if request != "" {
+			params = append(params, "body "+request)
+			body = "body"
+		}
+
+		if endpoint.Summary != "" {
+			fmt.Fprintf(&methods, "// %s %s.\n//\n", endpoint.Name, endpoint.Summary)
+		}
+		fmt.Fprintf(&methods, "//\t%s %s\n", endpoint.Method, endpoint.Path)
+		call := fmt.Sprintf("c.do(ctx, %s, %s, %s, %s", goMethod(endpoint.Method), path, query, body)
+		if response == "" {
+			fmt.Fprintf(&methods, "func (c *Client) %s(%s) error {\n\treturn %s, nil)\n}\n\n",
+				endpoint.Name, strings.Join(params, ", "), call)
+			continue
+		}
+		fmt.Fprintf(&methods, "func (c *Client) %s(%s) (*%s, error) {\n\tvar out %s\n\tif err := %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &out, nil\n}\n\n",
+			endpoint.Name, strings.Join(params, ", "), response, response, call)
+	}
+
+	var file bytes.Buffer
+	file.WriteString(generatedHeader)
+	file.WriteString("\npackage crmclient\n\nimport (\n")
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	// Standard library packages first, as goimports groups them
+	sort.Slice(paths, func(i, j int) bool {
+		if std := isStdPackage(paths[i]); std != isStdPackage(paths[j]) {
+			return std
+		}
+		return paths[i] < paths[j]
+	})
+	for i, path := range paths {
+		if i > 0 && isStdPackage(paths[i-1]) && !isStdPackage(path) {
+			file.WriteString("\n")
+		}
+		fmt.Fprintf(&file, "\t%q\n", path)
+	}
+	file.WriteString(")\n\n")
+	file.Write(methods.Bytes())
+	return format.Source(file.Bytes())
+}
+
+// generateGoErrors writes an ErrorCode constant per registered error code,
+// commented with its English message
+func generateGoErrors(codes []string) ([]byte, error) {
+	var file bytes.Buffer
+	file.WriteString(generatedHeader)
+	file.WriteString("\npackage crmclient\n\n// Error codes the API responds with\nconst (\n")
+	for _, code := range codes {
+		fmt.Fprintf(&file, "\t%s ErrorCode = %q // %s\n", goCodeName(code), code, i18n.MessageFor(i18n.DefaultLocale, code, ""))
+	}
+	file.WriteString(")\n")
+	return format.Source(file.Bytes())
+}
+
+// goTypeName returns the qualified name of a value's named type, e.g.
+// models.Customer, recording its package in imports. A nil value has no
+// type and gives "".
+func goTypeName(value interface{}, imports map[string]bool) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	t := reflect.TypeOf(value)
+	if t.Name() == "" || t.PkgPath() == "" {
+		return "", fmt.Errorf("%s is not a named type", t)
+	}
+	imports[t.PkgPath()] = true
+	return t.String(), nil
+}
+
+// isStdPackage reports whether an import path is of the standard library
+func isStdPackage(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// goMethod returns the net/http constant of an HTTP method
+func goMethod(method string) string {
+	return "http.Method" + method[:1] + strings.ToLower(method[1:])
+}
+
+// goParamName turns a path parameter into a Go identifier, e.g. tagId into
+// tagID
+func goParamName(name string) string {
+	if strings.HasSuffix(name, "Id") {
+		return strings.TrimSuffix(name, "Id") + "ID"
+	}
+	return name
+}
+
+// goCodeName turns an error code into its constant name, e.g.
+// CUSTOMER_NOT_FOUND into CodeCustomerNotFound
+func goCodeName(code string) string {
+	var name strings.Builder
+	name.WriteString("Code")
+	for _, word := range strings.Split(code, "_") {
+		if word == "" {
+			continue
+		}
+		name.WriteString(word[:1])
+		name.WriteString(strings.ToLower(word[1:]))
+	}
+	return name.String()
+}
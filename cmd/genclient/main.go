@@ -0,0 +1,101 @@
+// Command genclient generates the clients of the admin API from the
+// endpoint registry in src/apispec: the endpoint methods and error codes of
+// the Go package clients/go/crmclient, and the TypeScript definitions in
+// clients/ts. Endpoints the registry marks internal are left out.
+//
+// Output is deterministic, so regenerating after a registry change gives a
+// reviewable diff.
+//
+//	genclient [-out clients]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/apispec"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+)
+
+// Generated files, relative to the output directory
+const (
+	goEndpointsFile = "go/crmclient/endpoints.go"
+	goErrorsFile    = "go/crmclient/errors.go"
+	tsFile          = "ts/crm.d.ts"
+)
+
+// generatedHeader marks generated files
+const generatedHeader = "// Code generated by genclient from src/apispec. DO NOT EDIT.\n"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run generates the clients and returns the exit code
+func run(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("genclient", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("out", "clients", "directory the clients are written to")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	files, err := generate(apispec.Endpoints)
+	if err != nil {
+		fmt.Fprintf(stderr, "genclient: %v\n", err)
+		return 1
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(*out, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			fmt.Fprintf(stderr, "genclient: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(path, files[name], 0o644); err != nil {
+			fmt.Fprintf(stderr, "genclient: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// generate returns the generated files of the endpoints' clients by path
+// relative to the output directory
+func generate(endpoints []apispec.Endpoint) (map[string][]byte, error) {
+	if err := i18n.Load(); err != nil {
+		return nil, err
+	}
+	var public []apispec.Endpoint
+	for _, endpoint := range endpoints {
+		if endpoint.Internal {
+			continue
+		}
+		if endpoint.Summary == "" {
+			return nil, fmt.Errorf("%s has no summary", endpoint.Name)
+		}
+		public = append(public, endpoint)
+	}
+
+	goEndpoints, err := generateGoEndpoints(public)
+	if err != nil {
+		return nil, fmt.Errorf("go client: %w", err)
+	}
+	goErrors, err := generateGoErrors(i18n.ErrorCodes())
+	if err != nil {
+		return nil, fmt.Errorf("go error codes: %w", err)
+	}
+	ts, err := generateTypeScript(public, i18n.ErrorCodes())
+	if err != nil {
+		return nil, fmt.Errorf("typescript definitions: %w", err)
+	}
+	return map[string][]byte{goEndpointsFile: goEndpoints, goErrorsFile: goErrors, tsFile: ts}, nil
+}
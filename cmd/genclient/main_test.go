@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/apispec"
+)
+
+// TestClientsAreCurrent checks that the clients in /clients are what the
+// registry generates, so a registry change cannot ship without them
+func TestClientsAreCurrent(t *testing.T) {
+	files, err := generate(apispec.Endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, generated := range files {
+		committed, err := os.ReadFile(filepath.Join("..", "..", "clients", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(committed, generated) {
+			t.Errorf("clients/%s is out of date; run go generate ./clients/...", name)
+		}
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	first, err := generate(apispec.Endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		again, err := generate(apispec.Endpoints)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name := range first {
+			if !bytes.Equal(first[name], again[name]) {
+				t.Fatalf("%s differs between runs", name)
+			}
+		}
+	}
+}
+
+func TestInternalEndpointsLeftOut(t *testing.T) {
+	endpoints := []apispec.Endpoint{
+		{Name: "ListTags", Summary: "lists tags", Method: http.MethodGet, Path: "/admin/tags"},
+		{Name: "ReindexSearch", Method: http.MethodPost, Path: "/admin/maintenance/search/reindex", Internal: true},
+	}
+	files, err := generate(endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{goEndpointsFile, tsFile} {
+		content := string(files[name])
+		if !strings.Contains(content, "istTags(") {
+			t.Errorf("%s: public endpoint missing", name)
+		}
+		if strings.Contains(content, "eindexSearch") || strings.Contains(content, "/admin/maintenance") {
+			t.Errorf("%s: internal endpoint generated", name)
+		}
+	}
+}
+
+func TestGenerateRejects(t *testing.T) {
+	for name, endpoint := range map[string]apispec.Endpoint{
+		"no summary":     {Name: "ListTags", Method: http.MethodGet, Path: "/admin/tags"},
+		"unnamed type":   {Name: "ListTags", Summary: "lists tags", Method: http.MethodGet, Path: "/admin/tags", Response: struct{ Data []string }{}},
+		"unserializable": {Name: "ListTags", Summary: "lists tags", Method: http.MethodGet, Path: "/admin/tags", Response: unserializable{}},
+	} {
+		if _, err := generate([]apispec.Endpoint{endpoint}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// unserializable has a field encoding/json cannot write
+type unserializable struct {
+	Done chan bool `json:"done"`
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/apispec"
+	"gorm.io/gorm"
+)
+
+// tsBuiltins maps types that serialize themselves to their TypeScript type
+var tsBuiltins = map[reflect.Type]string{
+	reflect.TypeOf(time.Time{}):       "string",
+	reflect.TypeOf(gorm.DeletedAt{}):  "string | null",
+	reflect.TypeOf(json.RawMessage{}): "unknown",
+	reflect.TypeOf([]byte{}):          "string",
+}
+
+// tsWriter collects the interfaces of the named struct types the endpoints
+// reach
+type tsWriter struct {
+	interfaces map[string]string       // Interface name to its declaration
+	types      map[string]reflect.Type // Interface name to the type it was made from
+}
+
+// generateTypeScript writes the definitions of the endpoints' types, the
+// error codes and a client interface with a method per endpoint
+func generateTypeScript(endpoints []apispec.Endpoint, codes []string) ([]byte, error) {
+	w := &tsWriter{interfaces: map[string]string{}, types: map[string]reflect.Type{}}
+	var client bytes.Buffer
+	client.WriteString("/** Client of the CRM admin API. Methods reject with an ApiError on error statuses. */\nexport interface CrmClient {\n")
+	for _, endpoint := range endpoints {
+		var params []string
+		for _, name := range endpoint.Params() {
+			if apispec.IsIDParam(name) {
+				params = append(params, name+": number")
+			} else {
+				params = append(params, name+": string")
+			}
+		}
+		if endpoint.Query {
+			params = append(params, "query?: Query")
+		}
+		result := "void"
+		if endpoint.Request != nil {
+			name, err := w.typeOf(reflect.TypeOf(endpoint.Request))
+			if err != nil {
+				return nil, fmt.Errorf("%s request: %w", endpoint.Name, err)
+			}
+			params = append(params, "body: "+name)
+		}
+		if endpoint.Response != nil {
+			name, err := w.typeOf(reflect.TypeOf(endpoint.Response))
+			if err != nil {
+				return nil, fmt.Errorf("%s response: %w", endpoint.Name, err)
+			}
+			result = name
+		}
+		fmt.Fprintf(&client, "  /** %s. `%s %s` */\n  %s(%s): Promise<%s>;\n",
+			strings.ToUpper(endpoint.Summary[:1])+endpoint.Summary[1:], endpoint.Method, endpoint.Path,
+			strings.ToLower(endpoint.Name[:1])+endpoint.Name[1:], strings.Join(params, ", "), result)
+	}
+	client.WriteString("}\n")
+
+	var file bytes.Buffer
+	file.WriteString(generatedHeader)
+	file.WriteString("\n/** Error codes the API responds with */\nexport type ErrorCode =\n")
+	for i, code := range codes {
+		end := ""
+		if i == len(codes)-1 {
+			end = ";"
+		}
+		fmt.Fprintf(&file, "  | %q%s\n", code, end)
+	}
+	file.WriteString("\n/** Error response of the API; some errors add fields of their own */\nexport interface ApiError {\n  status: number;\n  error: string;\n  code: ErrorCode;\n  message: string;\n  [field: string]: unknown;\n}\n")
+	file.WriteString("\n/** List query parameters such as page, page_size and filters */\nexport type Query = Record<string, string | string[]>;\n")
+
+	names := make([]string, 0, len(w.interfaces))
+	for name := range w.interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file.WriteString("\n")
+		file.WriteString(w.interfaces[name])
+	}
+	file.WriteString("\n")
+	file.Write(client.Bytes())
+	return file.Bytes(), nil
+}
+
+// typeOf returns the TypeScript type of a Go type, declaring an interface
+// for each named struct it reaches
+func (w *tsWriter) typeOf(t reflect.Type) (string, error) {
+	if builtin, ok := tsBuiltins[t]; ok {
+		return builtin, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return w.typeOf(t.Elem())
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.Interface:
+		return "unknown", nil
+	case reflect.Slice, reflect.Array:
+		elem, err := w.typeOf(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]", nil
+	case reflect.Map:
+		elem, err := w.typeOf(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "Record<string, " + elem + ">", nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			fields, err := w.fields(t, "  ")
+			if err != nil {
+				return "", err
+			}
+			return "{\n" + fields + "}", nil
+		}
+		return w.declare(t)
+	}
+	return "", fmt.Errorf("%s cannot be serialized", t)
+}
+
+// declare declares the interface of a named struct type and returns its name
+func (w *tsWriter) declare(t reflect.Type) (string, error) {
+	name := t.Name()
+	if declared, ok := w.types[name]; ok {
+		if declared != t {
+			return "", fmt.Errorf("%s and %s would both be declared as %s", declared, t, name)
+		}
+		return name, nil
+	}
+	w.types[name] = t
+	fields, err := w.fields(t, "  ")
+	if err != nil {
+		return "", err
+	}
+	w.interfaces[name] = "export interface " + name + " {\n" + fields + "}\n"
+	return name, nil
+}
+
+// fields writes the JSON fields of a struct in order, with the fields of
+// embedded structs inlined as encoding/json does
+func (w *tsWriter) fields(t reflect.Type, indent string) (string, error) {
+	var out strings.Builder
+	var walk func(t reflect.Type, hidden map[string]bool) error
+	walk = func(t reflect.Type, hidden map[string]bool) error {
+		// Fields of the struct itself hide those of embedded structs
+		own := maps.Clone(hidden)
+		for i := range t.NumField() {
+			if name, ok := jsonName(t.Field(i)); ok {
+				own[name] = true
+			}
+		}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			_, options, _ := strings.Cut(tag, ",")
+			fieldType := field.Type
+			if inner, ok := embeddedStruct(field); ok {
+				if err := walk(inner, own); err != nil {
+					return err
+				}
+				continue
+			}
+			name, ok := jsonName(field)
+			if !ok || hidden[name] {
+				continue
+			}
+
+			tsType, err := w.typeOf(fieldType)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", t, field.Name, err)
+			}
+			if strings.Contains(","+options+",", ",string,") {
+				tsType = "string"
+			}
+			optional := ""
+			switch {
+			case strings.Contains(","+options+",", ",omitempty,"):
+				optional = "?"
+			case fieldType.Kind() == reflect.Pointer:
+				tsType += " | null"
+			}
+			fmt.Fprintf(&out, "%s%s%s: %s;\n", indent, name, optional, strings.ReplaceAll(tsType, "\n", "\n"+indent))
+		}
+		return nil
+	}
+	if err := walk(t, map[string]bool{}); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// jsonName returns the name a struct field is serialized with, false for
+// fields that are not serialized or are embedded structs
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if _, ok := embeddedStruct(field); ok {
+		return "", false
+	}
+	if !field.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// embeddedStruct returns the struct type of an embedded field without a
+// JSON name, whose fields are serialized as the outer struct's own
+func embeddedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous || strings.Split(field.Tag.Get("json"), ",")[0] != "" {
+		return nil, false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct
+}
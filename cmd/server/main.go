@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,10 +10,40 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/SalehAlobaylan/CRM-Service/src/backup"
+	"github.com/SalehAlobaylan/CRM-Service/src/buildinfo"
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
 	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/consistency"
 	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/deletion"
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/fallback"
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/jobs"
 	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/preview"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"github.com/SalehAlobaylan/CRM-Service/src/reportrollup"
+	"github.com/SalehAlobaylan/CRM-Service/src/roles"
 	"github.com/SalehAlobaylan/CRM-Service/src/routes"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/SalehAlobaylan/CRM-Service/src/search"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+	"github.com/SalehAlobaylan/CRM-Service/src/stages"
+	"github.com/SalehAlobaylan/CRM-Service/src/storage"
+	"github.com/SalehAlobaylan/CRM-Service/src/tracking"
+	"github.com/SalehAlobaylan/CRM-Service/src/webhooks"
+	"github.com/SalehAlobaylan/CRM-Service/src/workload"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -25,7 +56,92 @@ func main() {
 	}
 	defer middleware.Logger.Sync()
 
-	middleware.Logger.Info("Starting CRM Service...")
+	build := buildinfo.Get()
+	middleware.Logger.Info("Starting CRM Service...",
+		zap.String("version", build.Version),
+		zap.String("commit", build.Commit),
+		zap.String("build_time", build.BuildTime),
+		zap.String("go_version", build.GoVersion),
+	)
+
+	// Load localized message catalogs
+	if err := i18n.Load(); err != nil {
+		middleware.Logger.Fatal("Failed to load message catalogs: " + err.Error())
+	}
+
+	// Configure field-level edit permissions
+	fieldPermissions, err := models.ParseFieldPermissions(cfg.FieldPermissions)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid FIELD_PERMISSIONS: " + err.Error())
+	}
+	models.FieldPermissions = fieldPermissions
+
+	// Configure field redaction in responses
+	fieldRedactions, err := redaction.ParseRules(cfg.FieldRedactions)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid FIELD_REDACTIONS: " + err.Error())
+	}
+	redaction.Rules = fieldRedactions
+
+	// Configure feature flag overrides
+	featureFlags, err := flags.Parse(cfg.FeatureFlags)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid FEATURE_FLAGS: " + err.Error())
+	}
+	flags.Settings = featureFlags
+
+	// Configure activity required fields
+	activityPolicy, err := models.ParseActivityPolicy(cfg.ActivityRequiredFields)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid ACTIVITY_REQUIRED_FIELDS: " + err.Error())
+	}
+	models.ActivityRequirements = activityPolicy
+	models.ActivityPolicyStrict = cfg.ActivityPolicyStrict
+	if cfg.ActivityPolicyEffectiveFrom != "" {
+		effectiveFrom, err := time.Parse("2006-01-02", cfg.ActivityPolicyEffectiveFrom)
+		if err != nil {
+			middleware.Logger.Fatal("Invalid ACTIVITY_POLICY_EFFECTIVE_FROM: " + err.Error())
+		}
+		models.ActivityPolicyEffectiveFrom = effectiveFrom
+	}
+
+	// Configure whether forward stage moves need a next step
+	models.DealNextStepRequired = cfg.DealNextStepRequired
+
+	// Configure the open deal limit per customer
+	openDealLimitMode, err := models.ParseOpenDealLimitMode(cfg.OpenDealLimitMode)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid OPEN_DEAL_LIMIT_MODE: " + err.Error())
+	}
+	models.MaxOpenDealsPerCustomer = cfg.MaxOpenDealsPerCustomer
+	models.OpenDealLimitPolicy = openDealLimitMode
+
+	// Configure long text limits and list previews
+	if cfg.LongTextSoftLimitBytes < 0 || cfg.LongTextHardLimitBytes < 0 {
+		middleware.Logger.Fatal("LONG_TEXT_SOFT_LIMIT_BYTES and LONG_TEXT_HARD_LIMIT_BYTES must not be negative")
+	}
+	if cfg.LongTextHardLimitBytes > 0 && cfg.LongTextSoftLimitBytes > cfg.LongTextHardLimitBytes {
+		middleware.Logger.Fatal("LONG_TEXT_SOFT_LIMIT_BYTES must not exceed LONG_TEXT_HARD_LIMIT_BYTES")
+	}
+	models.LongTextSoftLimit = cfg.LongTextSoftLimitBytes
+	models.LongTextHardLimit = cfg.LongTextHardLimitBytes
+	previewLengths, err := preview.ParseLengths(cfg.TextPreviewLength, cfg.TextPreviewLengths)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid TEXT_PREVIEW_LENGTHS: " + err.Error())
+	}
+	workloadLimits, err := workload.ParseLimits(cfg.WorkloadLimits)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid WORKLOAD_LIMITS: " + err.Error())
+	}
+	workloads := workload.NewLimiter(workloadLimits)
+	nameCollation, err := query.ParseCollation(cfg.NameCollation)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid NAME_COLLATION: " + err.Error())
+	}
+	auditWritePolicy, err := fallback.ParsePolicy(cfg.AuditWritePolicy)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid AUDIT_WRITE_POLICY: " + err.Error())
+	}
 
 	// Connect to database
 	db, err := database.Connect(cfg)
@@ -36,22 +152,510 @@ func main() {
 
 	middleware.Logger.Info("Connected to database")
 
+	// Sort names with the configured collation when the database has it (ICU
+	// collations need PostgreSQL built with ICU)
+	if nameCollation != "" {
+		if ok, err := database.HasCollation(db, nameCollation); err != nil || !ok {
+			middleware.Logger.Warn("NAME_COLLATION " + nameCollation + " is not available; sorting names with the database default")
+			nameCollation = ""
+		}
+	}
+	query.NameCollation = nameCollation
+
+	// Optional read replica for list reads
+	replica, err := database.ConnectReplica(cfg)
+	if err != nil {
+		middleware.Logger.Fatal("Failed to connect to read replica: " + err.Error())
+	}
+	if replica != nil {
+		defer database.Close(replica)
+		middleware.Logger.Info("Connected to read replica")
+	}
+	readRouter := database.NewReadRouter(db, replica, time.Duration(cfg.ReplicaMaxWaitMs)*time.Millisecond)
+
+	// Optional API sandbox; sandbox requests run on a separate demo database
+	sandboxDB, err := database.ConnectSandbox(cfg, db)
+	if err != nil {
+		middleware.Logger.Fatal("Failed to connect to sandbox database: " + err.Error())
+	}
+	if sandboxDB != nil {
+		defer database.Close(sandboxDB)
+		// One replica migrates and resets the sandbox; the others verify it
+		sandboxInit := database.Initializer{
+			Name: "sandbox",
+			Init: func(conn *gorm.DB) error {
+				if err := database.AutoMigrate(conn); err != nil {
+					return fmt.Errorf("failed to migrate sandbox database: %w", err)
+				}
+				if err := database.ResetSandbox(context.Background(), conn); err != nil {
+					return fmt.Errorf("failed to reset sandbox database: %w", err)
+				}
+				return nil
+			},
+			Verify: database.VerifySeeds,
+			OnWait: func() { middleware.Logger.Info("Waiting for another replica to initialize the sandbox database") },
+		}
+		result, err := sandboxInit.Run(context.Background(), sandboxDB)
+		if err != nil {
+			middleware.Logger.Fatal("Failed to initialize sandbox database: " + err.Error())
+		}
+		logInit(sandboxInit.Name, result)
+		if err := sandbox.Route(db, sandboxDB); err != nil {
+			middleware.Logger.Fatal("Failed to route sandbox database: " + err.Error())
+		}
+		middleware.Logger.Info("Connected to sandbox database")
+	}
+
+	// Side effects deferred until their transaction commits, such as
+	// chaining queued audit entries, run from hooks on the connection pool
+	database.EnableCommitHooks(db)
+
 	// Note: Migrations are handled by golang-migrate tool
 	// Run pipeline stages seeding (idempotent)
 	if cfg.IsDevelopment() {
 		middleware.Logger.Info("Seeding pipeline stages...")
-		if err := database.SeedPipelineStages(db); err != nil {
-			middleware.Logger.Warn("Failed to seed pipeline stages: " + err.Error())
+		seedInit := database.Initializer{
+			Name: "primary",
+			Init: func(conn *gorm.DB) error {
+				if err := database.SeedPipelineStages(conn); err != nil {
+					return err
+				}
+				return database.SeedRoles(conn)
+			},
+			Verify: database.VerifySeeds,
+			OnWait: func() { middleware.Logger.Info("Waiting for another replica to seed the database") },
+		}
+		result, err := seedInit.Run(context.Background(), db)
+		if err != nil {
+			middleware.Logger.Warn("Failed to seed database: " + err.Error())
+		} else {
+			logInit(seedInit.Name, result)
+		}
+	}
+
+	// Role definitions for permission checks; the built-in roles apply until loaded
+	roleService := roles.NewService(db)
+	if err := roleService.Reload(context.Background()); err != nil {
+		middleware.Logger.Warn("Failed to load roles: " + err.Error())
+	}
+	roleRefresher := jobs.NewRoleRefresher(
+		roleService,
+		time.Duration(cfg.RoleRefreshIntervalSeconds)*time.Second,
+		func(err error) {
+			middleware.Logger.Warn("Failed to refresh roles: " + err.Error())
+		},
+	)
+	roleRefresher.Start()
+
+	// Active pipeline stages for deal validation; the built-in stages apply until loaded
+	stageService := stages.NewService(db)
+	if err := stageService.Reload(context.Background()); err != nil {
+		middleware.Logger.Warn("Failed to load pipeline stages: " + err.Error())
+	}
+	stageRefresher := jobs.NewStageRefresher(
+		stageService,
+		time.Duration(cfg.StageRefreshIntervalSeconds)*time.Second,
+		func(err error) {
+			middleware.Logger.Warn("Failed to refresh pipeline stages: " + err.Error())
+		},
+	)
+	stageRefresher.Start()
+
+	// Business calendar for business-day due dates; holidays come from the database
+	workdays, err := businesstime.ParseWorkdays(cfg.BusinessWorkdays)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid BUSINESS_WORKDAYS: " + err.Error())
+	}
+	workStart, workEnd, err := businesstime.ParseHours(cfg.BusinessHours)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid BUSINESS_HOURS: " + err.Error())
+	}
+	location, err := time.LoadLocation(cfg.BusinessTimezone)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid BUSINESS_TIMEZONE: " + err.Error())
+	}
+	baseCalendar, err := businesstime.New(workdays, workStart, workEnd, location)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid business calendar: " + err.Error())
+	}
+	if cfg.FiscalYearStartMonth < 1 || cfg.FiscalYearStartMonth > 12 {
+		middleware.Logger.Fatal("Invalid FISCAL_YEAR_START_MONTH: must be 1-12")
+	}
+	businesstime.FiscalYearStart = time.Month(cfg.FiscalYearStartMonth)
+	calendar := businesstime.NewService(db, baseCalendar, cfg.BusinessRegion)
+	if err := calendar.Reload(context.Background()); err != nil {
+		middleware.Logger.Warn("Failed to load holidays: " + err.Error())
+	}
+
+	// Start side effect fallback (keeps audit entries and activity counts
+	// whose write failed and replays them, first those left by a restart)
+	fallbacks := fallback.NewWriter(
+		db,
+		time.Duration(cfg.SideEffectReplaySeconds)*time.Second,
+		cfg.SideEffectBufferSize,
+		func(err error) {
+			middleware.Logger.Error("Side effect fallback: " + err.Error())
+		},
+	)
+	fallbacks.Register(audittrail.FallbackKind, audittrail.Replay)
+	fallbacks.Register(tracking.ActivityFallbackKind, tracking.ReplayActivity)
+	fallbacks.Register(tracking.SecurityFallbackKind, tracking.ReplaySecurityActivity)
+	fallbacks.Start()
+	audittrail.WritePolicy = auditWritePolicy
+	audittrail.Fallback = fallbacks
+
+	// Start user activity tracker (flushes last-seen data in batches)
+	activityTracker := tracking.NewUserActivityTracker(
+		db,
+		time.Duration(cfg.UserActivityFlushSeconds)*time.Second,
+		cfg.UserActivityRetentionDays,
+		fallbacks,
+		func(err error) {
+			middleware.Logger.Warn("Failed to flush user activity: " + err.Error())
+		},
+	)
+	activityTracker.Start()
+
+	// Start recently viewed recorder (writes views off the request path)
+	recentViews := tracking.NewRecentViewRecorder(
+		db,
+		cfg.RecentViewsEnabled,
+		cfg.RecentViewsRetentionDays,
+		func(err error) {
+			middleware.Logger.Warn("Failed to record recent view: " + err.Error())
+		},
+	)
+	recentViews.Start()
+
+	// Start deal auto-archiver (archives long-closed deals)
+	dealArchiver := jobs.NewDealArchiver(
+		db,
+		cfg.DealAutoArchiveDays,
+		time.Hour,
+		func(err error) {
+			middleware.Logger.Warn("Failed to auto-archive deals: " + err.Error())
+		},
+	)
+	dealArchiver.Start()
+
+	// Start next step nudger (tasks owners about overdue or missing next steps)
+	nextStepNudger := jobs.NewNextStepNudger(
+		db,
+		cfg.DealNextStepMissingDays,
+		time.Duration(cfg.DealNextStepNudgeIntervalMinutes)*time.Minute,
+		func(err error) {
+			middleware.Logger.Warn("Failed to nudge deal next steps: " + err.Error())
+		},
+	)
+	nextStepNudger.Start()
+
+	// Start sandbox resetter (restores the demo data nightly)
+	sandboxResetter := jobs.NewSandboxResetter(
+		sandboxDB,
+		time.Duration(cfg.SandboxResetIntervalHours)*time.Hour,
+		func(err error) {
+			middleware.Logger.Warn("Failed to reset sandbox database: " + err.Error())
+		},
+	)
+	sandboxResetter.Start()
+
+	// Start audit sequencer (chains audit entries left queued after their change committed)
+	auditSequencer := jobs.NewAuditSequencer(
+		db,
+		time.Minute,
+		func(err error) {
+			middleware.Logger.Warn("Failed to sequence audit entries: " + err.Error())
+		},
+	)
+	auditSequencer.Start()
+
+	// Start audit log purger (retention; records a hash chain checkpoint)
+	auditPurger := jobs.NewAuditPurger(
+		db,
+		cfg.AuditRetentionDays,
+		24*time.Hour,
+		func(err error) {
+			middleware.Logger.Warn("Failed to purge audit logs: " + err.Error())
+		},
+	)
+	auditPurger.Start()
+
+	// Start deals board rebalancer (renumbers stages when position gaps get too small)
+	boardRebalancer := jobs.NewBoardRebalancer(
+		db,
+		time.Hour,
+		func(err error) {
+			middleware.Logger.Warn("Failed to rebalance deals board: " + err.Error())
+		},
+	)
+	boardRebalancer.Start()
+
+	// Start consistency sweeper (nightly data integrity checks)
+	consistencyRunner := consistency.NewRunner(db)
+	consistencySweeper := jobs.NewConsistencySweeper(
+		consistencyRunner,
+		time.Duration(cfg.ConsistencyCheckIntervalHours)*time.Hour,
+		func(err error) {
+			middleware.Logger.Warn("Consistency sweep failed: " + err.Error())
+		},
+	)
+	consistencySweeper.Start()
+
+	// Report rollup (daily snapshots read by the funnel, revenue and segments reports)
+	reportRollups := reportrollup.NewRunner(db, location)
+	reportRollup := jobs.NewReportRollup(
+		reportRollups,
+		time.Duration(cfg.ReportRollupIntervalMinutes)*time.Minute,
+		func(err error) {
+			middleware.Logger.Warn("Report rollup failed: " + err.Error())
+		},
+	)
+	reportRollup.Start()
+
+	// Customer deleter (background deletion of customers with many records)
+	customerDeletions := deletion.NewRunner(db, cfg.CustomerDeleteSyncLimit)
+	customerDeleter := jobs.NewCustomerDeleter(
+		customerDeletions,
+		5*time.Minute,
+		func(err error) {
+			middleware.Logger.Warn("Customer deletion failed: " + err.Error())
+		},
+	)
+	customerDeleter.Start()
+
+	// Dead-letter queue for async work whose retries are exhausted. Async
+	// components register a retrier here so items can be requeued.
+	deadLetters := deadletter.NewQueue(db)
+
+	// Export jobs store their files as expiring artifacts
+	exportStorage, err := storage.NewLocal(cfg.ExportStorageDir)
+	if err != nil {
+		middleware.Logger.Fatal("Failed to initialize export storage: " + err.Error())
+	}
+	exportManager := exports.NewManager(
+		db,
+		exportStorage,
+		time.Duration(cfg.ExportArtifactTTLHours)*time.Hour,
+		int64(cfg.ExportMaxBytes),
+		cfg.ExportCheckpointBatches,
+		workloads,
+		func(err error) {
+			middleware.Logger.Warn("Export job failed: " + err.Error())
+		},
+	)
+	exportManager.Register("customers_csv", models.ExportEntityCustomer, "customers.csv", "text/csv; charset=utf-8", exports.CustomersCSV)
+	exportManager.Register("deals_csv", models.ExportEntityDeal, "deals.csv", "text/csv; charset=utf-8", exports.DealsCSV)
+	exportManager.Register("notes_csv", models.ExportEntityNote, "notes.csv", "text/csv; charset=utf-8", exports.NotesCSV)
+	exportManager.RegisterInternal(backup.JobType, workload.ClassMaintenance, "backup.tar.gz", "application/gzip", backup.Export)
+	exportManager.DeadLetterTo(deadLetters)
+
+	// External search engine, kept in sync with writes and rebuilt by
+	// reindex jobs. Postgres answers searches when none is configured.
+	var searchIndexer search.Indexer
+	var searchSync *search.Sync
+	switch cfg.SearchEngine {
+	case search.EnginePostgres:
+	case search.EngineOpenSearch:
+		openSearch, err := search.NewOpenSearchIndexer(search.OpenSearchConfig{
+			URL:      cfg.OpenSearchURL,
+			Index:    cfg.OpenSearchIndex,
+			Username: cfg.OpenSearchUsername,
+			Password: cfg.OpenSearchPassword,
+		}, search.Weights{
+			Text:       cfg.SearchWeightText,
+			Exact:      cfg.SearchWeightExact,
+			Prefix:     cfg.SearchWeightPrefix,
+			Owned:      cfg.SearchWeightOwned,
+			Recent:     cfg.SearchWeightRecent,
+			RecentDays: cfg.SearchRecentDays,
+		})
+		if err != nil {
+			middleware.Logger.Fatal("Invalid OpenSearch configuration: " + err.Error())
+		}
+		if err := openSearch.EnsureIndex(context.Background()); err != nil {
+			middleware.Logger.Warn("Failed to create the search index: " + err.Error())
 		}
+		searchIndexer = openSearch
+		searchSync = search.NewSync(
+			db,
+			openSearch,
+			time.Duration(cfg.SearchSyncIntervalMs)*time.Millisecond,
+			func(err error) {
+				middleware.Logger.Warn("Failed to sync the search index: " + err.Error())
+			},
+		)
+		if err := searchSync.Register(db); err != nil {
+			middleware.Logger.Fatal("Failed to register search index sync: " + err.Error())
+		}
+		searchSync.DeadLetterTo(deadLetters)
+		searchSync.Start()
+		exportManager.RegisterInternalResumable(search.ReindexJobType, workload.ClassMaintenance, "reindex.csv", "text/csv; charset=utf-8", search.Reindex(openSearch))
+	default:
+		middleware.Logger.Fatal("Invalid SEARCH_ENGINE: want postgres or opensearch")
+	}
+	if err := exportManager.Recover(context.Background()); err != nil {
+		middleware.Logger.Warn("Failed to recover interrupted export jobs: " + err.Error())
+	}
+	artifactCleaner := jobs.NewArtifactCleaner(
+		exportManager,
+		time.Hour,
+		func(err error) {
+			middleware.Logger.Warn("Failed to clean expired export artifacts: " + err.Error())
+		},
+	)
+	artifactCleaner.Start()
+
+	// List page prefetch cache, invalidated by every write through db
+	var listPrefetch *query.Prefetcher
+	if cfg.ListPrefetchEnabled {
+		listPrefetch = query.NewPrefetcher(
+			cfg.ListPrefetchMaxEntries,
+			time.Duration(cfg.ListPrefetchTTLSeconds)*time.Second,
+		)
+		if err := listPrefetch.Register(db); err != nil {
+			middleware.Logger.Fatal("Failed to register list prefetch invalidation: " + err.Error())
+		}
+	}
+
+	// Rows loaded for a request are marked with the fields its user may not read
+	if err := redaction.Register(db); err != nil {
+		middleware.Logger.Fatal("Failed to register field redaction: " + err.Error())
 	}
 
+	// Rows loaded for a list are marked with its preview length
+	if err := preview.Register(db); err != nil {
+		middleware.Logger.Fatal("Failed to register text previews: " + err.Error())
+	}
+
+	// Record quotas, counted from writes and reconciled periodically
+	quotas := quota.NewTracker(db, map[string]int64{
+		quota.Customers:  int64(cfg.QuotaCustomers),
+		quota.Contacts:   int64(cfg.QuotaContacts),
+		quota.Deals:      int64(cfg.QuotaDeals),
+		quota.Activities: int64(cfg.QuotaActivities),
+	})
+	if err := quotas.Register(db); err != nil {
+		middleware.Logger.Fatal("Failed to register quota counters: " + err.Error())
+	}
+	if err := quotas.Reconcile(context.Background()); err != nil {
+		middleware.Logger.Warn("Failed to count quota usage: " + err.Error())
+	}
+	quotaReconciler := jobs.NewQuotaReconciler(
+		quotas,
+		time.Duration(cfg.QuotaReconcileIntervalMinutes)*time.Minute,
+		func(err error) {
+			middleware.Logger.Warn("Failed to reconcile quota usage: " + err.Error())
+		},
+	)
+	quotaReconciler.Start()
+
+	// Bearer token providers of the admin API
+	hmacClaims, err := auth.ParseClaimsMapping(cfg.AuthHMACClaims)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid AUTH_HMAC_CLAIMS: " + err.Error())
+	}
+	jwksClaims, err := auth.ParseClaimsMapping(cfg.AuthJWKSClaims)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid AUTH_JWKS_CLAIMS: " + err.Error())
+	}
+	authenticators, err := auth.NewChain(cfg.AuthProviders, auth.Config{
+		HMACSecret: cfg.JWTSecret,
+		HMACClaims: hmacClaims,
+		JWKS: auth.JWKSConfig{
+			Issuer:   cfg.AuthJWKSIssuer,
+			URL:      cfg.AuthJWKSURL,
+			Audience: cfg.AuthJWKSAudience,
+			Claims:   jwksClaims,
+			Refresh:  time.Duration(cfg.AuthJWKSRefreshMinutes) * time.Minute,
+		},
+	}, db)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid AUTH_PROVIDERS: " + err.Error())
+	}
+
+	// Security monitoring: alert rules evaluated against per-user activity,
+	// and token revocations applied by alerts
+	securityRules, err := models.ParseSecurityAlertRules(cfg.SecurityAlertRules)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid SECURITY_ALERT_RULES: " + err.Error())
+	}
+	securityWebhookMode, err := models.ParseSecurityWebhookMode(cfg.SecurityAlertWebhookMode)
+	if err != nil {
+		middleware.Logger.Fatal("Invalid SECURITY_ALERT_WEBHOOK_MODE: " + err.Error())
+	}
+	if err := security.LoadRevocations(context.Background(), db); err != nil {
+		middleware.Logger.Warn("Failed to load token revocations: " + err.Error())
+	}
+	securityMonitor := security.NewMonitor(
+		db,
+		securityRules,
+		cfg.SecurityAutoRevoke,
+		security.NewWebhook(cfg.SecurityAlertWebhookURL, securityWebhookMode),
+		func(alert models.SecurityAlert) {
+			middleware.Logger.Warn(fmt.Sprintf("Security alert: user %d exceeded %s>%d with %d in the hour from %s (tokens revoked: %t)",
+				alert.UserID, alert.Metric, alert.Threshold, alert.Value, alert.BucketStart.Format(time.RFC3339), alert.TokensRevoked))
+		},
+	)
+	securityMonitorJob := jobs.NewSecurityMonitor(
+		securityMonitor,
+		time.Duration(cfg.SecurityMonitorIntervalSeconds)*time.Second,
+		func(err error) {
+			middleware.Logger.Warn("Failed to evaluate security alerts: " + err.Error())
+		},
+	)
+	securityMonitorJob.Start()
+
+	// Webhook subscriptions: deal and customer events queued with the audit
+	// entry of their change and sent in the background
+	webhookDispatcher := webhooks.NewDispatcher(db, func(err error) {
+		middleware.Logger.Warn(err.Error())
+	})
+	if err := webhookDispatcher.Reload(context.Background()); err != nil {
+		middleware.Logger.Warn("Failed to load webhook subscriptions: " + err.Error())
+	}
+	webhookDispatcher.DeadLetterTo(deadLetters)
+	audittrail.Events = webhookDispatcher
+	webhookDispatcherJob := jobs.NewWebhookDispatcher(
+		webhookDispatcher,
+		time.Duration(cfg.WebhookDispatchIntervalSeconds)*time.Second,
+		func(err error) {
+			middleware.Logger.Warn("Failed to send webhook deliveries: " + err.Error())
+		},
+	)
+	webhookDispatcherJob.Start()
+
 	// Setup router
-	router := routes.SetupRouter(db, cfg)
+	router, err := routes.SetupRouter(db, cfg, &routes.Services{
+		Authenticators:  authenticators,
+		ActivityTracker: activityTracker,
+		RecentViews:     recentViews,
+		DeadLetters:     deadLetters,
+		Fallbacks:       fallbacks,
+		SlowQueries:     database.SlowQueries,
+		Consistency:     consistencyRunner,
+		Rollups:         reportRollups,
+		Deletions:       customerDeletions,
+		Exports:         exportManager,
+		ListPrefetch:    listPrefetch,
+		Calendar:        calendar,
+		ReadRouter:      readRouter,
+		Quotas:          quotas,
+		Roles:           roleService,
+		Stages:          stageService,
+		Security:        securityMonitor,
+		Webhooks:        webhookDispatcher,
+		Search:          searchIndexer,
+		Previews:        previewLengths,
+		Workloads:       workloads,
+	})
+	if err != nil {
+		middleware.Logger.Fatal("Failed to setup router: " + err.Error())
+	}
 
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:         ":" + cfg.ServerPort,
-		Handler:      router,
+		Handler:      middleware.NormalizePath(router),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -81,5 +685,47 @@ func main() {
 		middleware.Logger.Fatal("Server forced to shutdown: " + err.Error())
 	}
 
+	// Flush remaining user activity after in-flight requests have finished
+	if err := activityTracker.Stop(); err != nil {
+		middleware.Logger.Warn("Failed to flush user activity on shutdown: " + err.Error())
+	}
+	if err := fallbacks.Stop(); err != nil {
+		middleware.Logger.Error("Failed to store side effects on shutdown: " + err.Error())
+	}
+	if searchSync != nil {
+		if err := searchSync.Stop(); err != nil {
+			middleware.Logger.Warn("Failed to sync the search index: " + err.Error())
+		}
+	}
+	recentViews.Stop()
+	dealArchiver.Stop()
+	nextStepNudger.Stop()
+	sandboxResetter.Stop()
+	auditSequencer.Stop()
+	auditPurger.Stop()
+	boardRebalancer.Stop()
+	consistencySweeper.Stop()
+	reportRollup.Stop()
+	customerDeleter.Stop()
+	exportManager.Stop()
+	artifactCleaner.Stop()
+	quotaReconciler.Stop()
+	roleRefresher.Stop()
+	stageRefresher.Stop()
+	securityMonitorJob.Stop()
+	webhookDispatcherJob.Stop()
+
 	middleware.Logger.Info("Server exited gracefully")
 }
+
+// logInit logs which part a replica took in initializing a database and how
+// long it took
+func logInit(name string, result database.InitResult) {
+	middleware.Logger.Info("Initialized "+name+" database",
+		zap.String("database", name),
+		zap.String("role", result.Role),
+		zap.Duration("waited", result.Waited),
+		zap.Duration("duration", result.Duration),
+		zap.Bool("recovered", result.Recovered),
+	)
+}
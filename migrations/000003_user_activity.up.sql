@@ -0,0 +1,11 @@
+-- Create user_activity table for per-user last-seen tracking
+CREATE TABLE IF NOT EXISTS user_activity (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    bucket_date DATE NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_activity_bucket ON user_activity(user_id, endpoint, bucket_date);
+CREATE INDEX IF NOT EXISTS idx_user_activity_bucket_date ON user_activity(bucket_date);
@@ -0,0 +1,10 @@
+-- Create recent_views table for "recently viewed" lists
+CREATE TABLE IF NOT EXISTS recent_views (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    viewed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recent_views_entity ON recent_views(user_id, entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_recent_views_viewed_at ON recent_views(viewed_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_activities_previous_activity_id;
+ALTER TABLE activities DROP COLUMN IF EXISTS previous_activity_id;
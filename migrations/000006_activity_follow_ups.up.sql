@@ -0,0 +1,3 @@
+-- Link follow-up activities to the activity they were scheduled from
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS previous_activity_id INTEGER REFERENCES activities(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_activities_previous_activity_id ON activities(previous_activity_id);
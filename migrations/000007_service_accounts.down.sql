@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS service_account_tokens CASCADE;
+DROP TABLE IF EXISTS service_accounts CASCADE;
@@ -0,0 +1,30 @@
+-- Create service_accounts and their hashed tokens for integrations
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    role VARCHAR(50) NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    rate_limit_per_minute INTEGER NOT NULL DEFAULT 0,
+    created_by INTEGER,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_accounts_name ON service_accounts(name);
+CREATE INDEX IF NOT EXISTS idx_service_accounts_deleted_at ON service_accounts(deleted_at);
+
+CREATE TABLE IF NOT EXISTS service_account_tokens (
+    id SERIAL PRIMARY KEY,
+    service_account_id INTEGER NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_account_tokens_token_hash ON service_account_tokens(token_hash);
+CREATE INDEX IF NOT EXISTS idx_service_account_tokens_service_account_id ON service_account_tokens(service_account_id);
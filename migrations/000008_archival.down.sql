@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_deals_archived_at;
+DROP INDEX IF EXISTS idx_customers_archived_at;
+ALTER TABLE deals DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,5 @@
+-- Add archival state to customers and deals, distinct from soft deletion
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_customers_archived_at ON customers(archived_at);
+CREATE INDEX IF NOT EXISTS idx_deals_archived_at ON deals(archived_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_unavailability CASCADE;
+DROP TABLE IF EXISTS assignment_rules CASCADE;
@@ -0,0 +1,26 @@
+-- Create assignment_rules for lead assignment strategies
+CREATE TABLE IF NOT EXISTS assignment_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    strategy VARCHAR(50) NOT NULL,
+    members JSONB NOT NULL DEFAULT '[]',
+    priority INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN DEFAULT true,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assignment_rules_name ON assignment_rules(name);
+CREATE INDEX IF NOT EXISTS idx_assignment_rules_deleted_at ON assignment_rules(deleted_at);
+
+-- Create user_unavailability for reps skipped by lead assignment
+CREATE TABLE IF NOT EXISTS user_unavailability (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_unavailability_user_id ON user_unavailability(user_id);
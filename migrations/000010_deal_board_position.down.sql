@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_deals_stage_board_position;
+ALTER TABLE deals DROP COLUMN IF EXISTS board_position;
@@ -0,0 +1,12 @@
+-- Add manual board ordering of deals within a stage
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS board_position DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Seed existing deals in creation order within each stage
+UPDATE deals SET board_position = ranked.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY stage ORDER BY created_at, id) * 1024 AS position
+    FROM deals
+) AS ranked
+WHERE deals.id = ranked.id;
+
+CREATE INDEX IF NOT EXISTS idx_deals_stage_board_position ON deals(stage, board_position);
@@ -0,0 +1,15 @@
+-- Create consistency_findings for the data integrity sweep
+CREATE TABLE IF NOT EXISTS consistency_findings (
+    id SERIAL PRIMARY KEY,
+    check_name VARCHAR(100) NOT NULL,
+    resource_type VARCHAR(100) NOT NULL,
+    resource_id INTEGER NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    message TEXT,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_consistency_findings_key ON consistency_findings(check_name, resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_consistency_findings_severity ON consistency_findings(severity);
+CREATE INDEX IF NOT EXISTS idx_consistency_findings_resolved_at ON consistency_findings(resolved_at);
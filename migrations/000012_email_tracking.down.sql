@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS email_events CASCADE;
+ALTER TABLE contacts DROP COLUMN IF EXISTS email_opt_out_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS email_opt_out_at;
+DROP INDEX IF EXISTS idx_activities_template;
+ALTER TABLE activities DROP COLUMN IF EXISTS template;
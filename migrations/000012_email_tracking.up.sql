@@ -0,0 +1,19 @@
+-- Track engagement (opens, unsubscribes) on outbound email activities
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS template VARCHAR(100);
+CREATE INDEX IF NOT EXISTS idx_activities_template ON activities(template);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_opt_out_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS email_opt_out_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS email_events (
+    id SERIAL PRIMARY KEY,
+    activity_id INTEGER NOT NULL REFERENCES activities(id),
+    type VARCHAR(20) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    ip_address VARCHAR(50),
+    user_agent VARCHAR(500),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- One event per token: replayed links are ignored
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_events_token ON email_events(type, nonce);
+CREATE INDEX IF NOT EXISTS idx_email_events_activity_id ON email_events(activity_id);
+CREATE INDEX IF NOT EXISTS idx_email_events_created_at ON email_events(created_at);
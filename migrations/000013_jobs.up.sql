@@ -0,0 +1,26 @@
+-- Create jobs for async work such as exports, with their file artifacts
+CREATE TABLE IF NOT EXISTS jobs (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    params JSONB,
+    error TEXT,
+    created_by INTEGER NOT NULL,
+    created_by_name VARCHAR(255),
+    artifact_key VARCHAR(255),
+    artifact_file_name VARCHAR(255),
+    artifact_content_type VARCHAR(100),
+    artifact_rows BIGINT DEFAULT 0,
+    artifact_bytes BIGINT DEFAULT 0,
+    artifact_checksum VARCHAR(64),
+    artifact_expires_at TIMESTAMP WITH TIME ZONE,
+    artifact_deleted_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_type ON jobs(type);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+CREATE INDEX IF NOT EXISTS idx_jobs_created_by ON jobs(created_by);
+CREATE INDEX IF NOT EXISTS idx_jobs_artifact_expires_at ON jobs(artifact_expires_at);
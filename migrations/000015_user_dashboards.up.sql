@@ -0,0 +1,9 @@
+-- Create user_dashboards for per-user dashboard layouts
+CREATE TABLE IF NOT EXISTS user_dashboards (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    widgets JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_dashboards_user_id ON user_dashboards(user_id);
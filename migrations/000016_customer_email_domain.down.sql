@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_customers_email_domain;
+ALTER TABLE customers DROP COLUMN IF EXISTS email_domain;
@@ -0,0 +1,14 @@
+-- Store the company email domain of customers for company grouping
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_domain VARCHAR(255) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_customers_email_domain ON customers(email_domain);
+
+-- Backfill using the default free provider list. After changing
+-- FREE_EMAIL_PROVIDERS, run POST /admin/maintenance/email-domains/backfill.
+UPDATE customers
+SET email_domain = LOWER(SPLIT_PART(email, '@', 2))
+WHERE POSITION('@' IN email) > 0
+  AND LOWER(SPLIT_PART(email, '@', 2)) NOT IN (
+    'gmail.com', 'googlemail.com', 'yahoo.com', 'hotmail.com', 'outlook.com',
+    'live.com', 'msn.com', 'icloud.com', 'me.com', 'aol.com', 'proton.me',
+    'protonmail.com', 'gmx.com', 'mail.com', 'yandex.com', 'zoho.com'
+  );
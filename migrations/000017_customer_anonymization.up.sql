@@ -0,0 +1,2 @@
+-- Mark customers whose personal data has been anonymized
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ;
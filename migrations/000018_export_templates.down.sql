@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_jobs_template_id;
+ALTER TABLE jobs DROP COLUMN IF EXISTS template_id;
+DROP TABLE IF EXISTS export_templates CASCADE;
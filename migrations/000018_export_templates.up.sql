@@ -0,0 +1,19 @@
+-- Create export_templates for configurable CSV export columns
+CREATE TABLE IF NOT EXISTS export_templates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    entity VARCHAR(50) NOT NULL,
+    columns JSONB NOT NULL DEFAULT '[]',
+    date_format VARCHAR(20) NOT NULL DEFAULT 'rfc3339',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_export_templates_entity_name ON export_templates(entity, name);
+-- At most one default template per entity
+CREATE UNIQUE INDEX IF NOT EXISTS idx_export_templates_default ON export_templates(entity) WHERE is_default;
+
+-- Record the template used by export jobs
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS template_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_jobs_template_id ON jobs(template_id);
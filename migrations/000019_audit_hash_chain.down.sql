@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP FUNCTION IF EXISTS audit_logs_append_only();
+DROP TABLE IF EXISTS audit_checkpoints CASCADE;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS values_redacted_at;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS values_hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS hash;
@@ -0,0 +1,51 @@
+-- Hash-chain audit log entries. Entries written before this migration keep
+-- empty hashes and are reported as legacy by verification.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS values_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS values_redacted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create audit_checkpoints, recording the last hash of purged history
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id SERIAL PRIMARY KEY,
+    last_audit_id INTEGER NOT NULL,
+    last_hash VARCHAR(64) NOT NULL,
+    purged_count BIGINT NOT NULL,
+    purged_before TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_checkpoints_last_audit_id ON audit_checkpoints(last_audit_id);
+
+-- Make audit_logs append-only. The retention purge and anonymization set
+-- crm.audit_maintenance for their transaction; even then only the values
+-- of an entry may change.
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    IF COALESCE(current_setting('crm.audit_maintenance', true), '') <> 'on' THEN
+        RAISE EXCEPTION 'audit_logs is append-only' USING ERRCODE = 'insufficient_privilege';
+    END IF;
+    IF TG_OP = 'UPDATE' THEN
+        IF (NEW.id, NEW.resource_type, NEW.resource_id, NEW.action, NEW.user_id, NEW.user_name,
+            NEW.user_role, NEW.ip_address, NEW.user_agent, NEW.created_at, NEW.hash, NEW.prev_hash, NEW.values_hash)
+           IS DISTINCT FROM
+           (OLD.id, OLD.resource_type, OLD.resource_id, OLD.action, OLD.user_id, OLD.user_name,
+            OLD.user_role, OLD.ip_address, OLD.user_agent, OLD.created_at, OLD.hash, OLD.prev_hash, OLD.values_hash) THEN
+            RAISE EXCEPTION 'only audit log values can be redacted' USING ERRCODE = 'insufficient_privilege';
+        END IF;
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+CREATE TRIGGER audit_logs_no_truncate
+    BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
@@ -0,0 +1,5 @@
+ALTER TABLE notes DROP COLUMN IF EXISTS imported;
+DROP INDEX IF EXISTS idx_notes_content_hash;
+ALTER TABLE notes DROP COLUMN IF EXISTS content_hash;
+DROP INDEX IF EXISTS idx_deals_external_id;
+ALTER TABLE deals DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,11 @@
+-- Deals migrated from another system keep their original identifier
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS external_id VARCHAR(100);
+CREATE INDEX IF NOT EXISTS idx_deals_external_id ON deals(external_id);
+
+-- Notes record a content hash so imports can skip duplicates
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64) NOT NULL DEFAULT '';
+UPDATE notes SET content_hash = encode(sha256(convert_to(content, 'UTF8')), 'hex') WHERE content_hash = '';
+CREATE INDEX IF NOT EXISTS idx_notes_content_hash ON notes(content_hash);
+
+-- Imported notes are history and never trigger automations
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS imported BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,17 @@
+-- Create roles for configurable permission sets
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    description TEXT,
+    permissions JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name ON roles(name);
+
+-- Seed the built-in roles
+INSERT INTO roles (name, description, permissions) VALUES
+    ('admin', 'Full access', '["read", "write", "delete", "manage_all"]'),
+    ('manager', 'Manages all records', '["read", "write", "delete", "manage_all"]'),
+    ('agent', 'Manages own records', '["read", "write", "manage_own"]')
+ON CONFLICT (name) DO NOTHING;
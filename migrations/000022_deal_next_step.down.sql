@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_deals_next_step_due;
+ALTER TABLE deals DROP COLUMN IF EXISTS next_step_nudged_at;
+ALTER TABLE deals DROP COLUMN IF EXISTS next_step_set_at;
+ALTER TABLE deals DROP COLUMN IF EXISTS next_step_due;
+ALTER TABLE deals DROP COLUMN IF EXISTS next_step;
@@ -0,0 +1,6 @@
+-- Record each deal's next step and when its owner was last nudged about it
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS next_step VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS next_step_due TIMESTAMP WITH TIME ZONE;
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS next_step_set_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS next_step_nudged_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_deals_next_step_due ON deals(next_step_due);
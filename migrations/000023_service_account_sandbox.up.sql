@@ -0,0 +1,2 @@
+-- Service accounts whose requests run against the API sandbox database
+ALTER TABLE service_accounts ADD COLUMN IF NOT EXISTS sandbox BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+ALTER TABLE contacts DROP COLUMN IF EXISTS email_invalid_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS email_invalid_at;
+
+DROP INDEX IF EXISTS idx_email_events_recipient_domain;
+ALTER TABLE email_events DROP COLUMN IF EXISTS recipient_domain;
+ALTER TABLE email_events DROP COLUMN IF EXISTS reason;
+
+DROP INDEX IF EXISTS idx_activities_message_id;
+ALTER TABLE activities DROP COLUMN IF EXISTS delivery_status_at;
+ALTER TABLE activities DROP COLUMN IF EXISTS delivery_status;
+ALTER TABLE activities DROP COLUMN IF EXISTS message_id;
@@ -0,0 +1,14 @@
+-- Provider message IDs and delivery status of email activities
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS message_id VARCHAR(255);
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS delivery_status VARCHAR(20);
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS delivery_status_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_activities_message_id ON activities(message_id);
+
+-- Delivery event details
+ALTER TABLE email_events ADD COLUMN IF NOT EXISTS reason VARCHAR(500);
+ALTER TABLE email_events ADD COLUMN IF NOT EXISTS recipient_domain VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_email_events_recipient_domain ON email_events(recipient_domain);
+
+-- Email addresses that hard-bounced
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_invalid_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS email_invalid_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tags_group_id;
+ALTER TABLE tags DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS tag_groups;
@@ -0,0 +1,14 @@
+-- Create tag_groups, organizing tags into categories such as industry or region
+CREATE TABLE IF NOT EXISTS tag_groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    exclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_tag_groups_deleted_at ON tag_groups(deleted_at);
+
+-- Optional group of each tag
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS group_id INTEGER REFERENCES tag_groups(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_tags_group_id ON tags(group_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_activities_automation_open;
+ALTER TABLE activities DROP COLUMN IF EXISTS automation_fingerprint;
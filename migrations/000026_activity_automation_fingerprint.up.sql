@@ -0,0 +1,6 @@
+-- Fingerprint of activities created by automations
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS automation_fingerprint VARCHAR(64) NOT NULL DEFAULT '';
+
+-- At most one open activity per automation fingerprint
+CREATE UNIQUE INDEX IF NOT EXISTS idx_activities_automation_open ON activities(automation_fingerprint)
+    WHERE automation_fingerprint <> '' AND status <> 'completed' AND status <> 'cancelled' AND deleted_at IS NULL;
@@ -0,0 +1,17 @@
+-- Create annotations, marking deploys, imports and maintenance windows
+CREATE TABLE IF NOT EXISTS annotations (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(20) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    created_by INTEGER,
+    created_by_name VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_annotations_type ON annotations(type);
+CREATE INDEX IF NOT EXISTS idx_annotations_starts_at ON annotations(starts_at);
+CREATE INDEX IF NOT EXISTS idx_annotations_ends_at ON annotations(ends_at);
+CREATE INDEX IF NOT EXISTS idx_annotations_created_by ON annotations(created_by);
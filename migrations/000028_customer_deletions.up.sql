@@ -0,0 +1,28 @@
+-- Create customer_deletions, tracking customers whose dependents are
+-- soft-deleted in the background
+CREATE TABLE IF NOT EXISTS customer_deletions (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    expected_contacts BIGINT NOT NULL DEFAULT 0,
+    expected_deals BIGINT NOT NULL DEFAULT 0,
+    expected_activities BIGINT NOT NULL DEFAULT 0,
+    expected_notes BIGINT NOT NULL DEFAULT 0,
+    deleted_contacts BIGINT NOT NULL DEFAULT 0,
+    deleted_deals BIGINT NOT NULL DEFAULT 0,
+    deleted_activities BIGINT NOT NULL DEFAULT 0,
+    deleted_notes BIGINT NOT NULL DEFAULT 0,
+    requested_by INTEGER NOT NULL,
+    requested_by_name VARCHAR(255),
+    requested_by_role VARCHAR(50),
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(500),
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_deletions_customer_id ON customer_deletions(customer_id);
+CREATE INDEX IF NOT EXISTS idx_customer_deletions_status ON customer_deletions(status);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_customers_email_lower;
+DROP INDEX IF EXISTS idx_customers_external_id;
+ALTER TABLE customers DROP COLUMN IF EXISTS external_id;
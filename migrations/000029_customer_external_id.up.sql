@@ -0,0 +1,8 @@
+-- External reference of customers synced from other systems, the preferred
+-- match key of POST /admin/customers/bulk-upsert
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS external_id VARCHAR(100) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_external_id ON customers(external_id)
+WHERE external_id <> '' AND deleted_at IS NULL;
+
+-- Bulk upserts fall back to matching emails regardless of case
+CREATE INDEX IF NOT EXISTS idx_customers_email_lower ON customers(LOWER(email));
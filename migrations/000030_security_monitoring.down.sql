@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_token_revocations;
+DROP TABLE IF EXISTS security_alerts;
+DROP TABLE IF EXISTS security_activity;
@@ -0,0 +1,46 @@
+-- Create security_activity, hourly per-user operation counts watched for
+-- signs of a compromised token
+CREATE TABLE IF NOT EXISTS security_activity (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    reads BIGINT NOT NULL DEFAULT 0,
+    writes BIGINT NOT NULL DEFAULT 0,
+    deletes BIGINT NOT NULL DEFAULT 0,
+    exports BIGINT NOT NULL DEFAULT 0,
+    records_changed BIGINT NOT NULL DEFAULT 0,
+    records_deleted BIGINT NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_security_activity_bucket ON security_activity(user_id, bucket_start);
+CREATE INDEX IF NOT EXISTS idx_security_activity_bucket_start ON security_activity(bucket_start);
+
+-- Create security_alerts, one per user, hour and exceeded rule
+CREATE TABLE IF NOT EXISTS security_alerts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    metric VARCHAR(30) NOT NULL,
+    threshold BIGINT NOT NULL,
+    value BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    tokens_revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    notified_at TIMESTAMP WITH TIME ZONE,
+    false_positive BOOLEAN NOT NULL DEFAULT FALSE,
+    note TEXT,
+    acknowledged_by INTEGER,
+    acknowledged_by_name VARCHAR(255),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_security_alerts_bucket ON security_alerts(user_id, bucket_start, metric);
+CREATE INDEX IF NOT EXISTS idx_security_alerts_status ON security_alerts(status);
+
+-- Create user_token_revocations; a user's tokens issued up to revoked_at
+-- are rejected
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    user_id INTEGER PRIMARY KEY,
+    revoked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    alert_id INTEGER REFERENCES security_alerts(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
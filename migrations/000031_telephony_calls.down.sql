@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_contacts_phone_key;
+DROP INDEX IF EXISTS idx_customers_phone_key;
+DROP TABLE IF EXISTS telephony_calls;
@@ -0,0 +1,28 @@
+-- Create telephony_calls, the calls reported by the telephony provider's
+-- webhook; unmatched calls wait there to be matched by hand
+CREATE TABLE IF NOT EXISTS telephony_calls (
+    id SERIAL PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL,
+    direction VARCHAR(10) NOT NULL,
+    from_number VARCHAR(50),
+    to_number VARCHAR(50),
+    external_number VARCHAR(50),
+    extension VARCHAR(20),
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    recording_url VARCHAR(1000),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    activity_id INTEGER REFERENCES activities(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telephony_calls_event_id ON telephony_calls(event_id);
+CREATE INDEX IF NOT EXISTS idx_telephony_calls_external_number ON telephony_calls(external_number);
+CREATE INDEX IF NOT EXISTS idx_telephony_calls_started_at ON telephony_calls(started_at);
+CREATE INDEX IF NOT EXISTS idx_telephony_calls_status ON telephony_calls(status);
+CREATE INDEX IF NOT EXISTS idx_telephony_calls_activity_id ON telephony_calls(activity_id);
+
+-- Index the phone keys calls are matched on: the trailing 9 digits of the
+-- number (see models.PhoneKeySQL)
+CREATE INDEX IF NOT EXISTS idx_customers_phone_key ON customers ((RIGHT(REGEXP_REPLACE(phone, '[^0-9]', '', 'g'), 9)));
+CREATE INDEX IF NOT EXISTS idx_contacts_phone_key ON contacts ((RIGHT(REGEXP_REPLACE(phone, '[^0-9]', '', 'g'), 9)));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_contacts_search_text_trgm;
+DROP INDEX IF EXISTS idx_customers_search_text_trgm;
+ALTER TABLE contacts DROP COLUMN IF EXISTS search_text;
+ALTER TABLE customers DROP COLUMN IF EXISTS search_text;
+DROP FUNCTION IF EXISTS crm_fold_text(TEXT);
@@ -0,0 +1,20 @@
+-- Fold text for search: drop Arabic diacritics (harakat, superscript alef)
+-- and tatweel, map the alef variants أ إ آ ٱ to ا, ة to ه, ى and ئ to ي and
+-- ؤ to و, and lowercase, so differently spelled names match each other
+CREATE OR REPLACE FUNCTION crm_fold_text(input TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+    SELECT LOWER(TRANSLATE(REGEXP_REPLACE(input, U&'[\064B-\065F\0670\0640]', '', 'g'),
+        U&'\0623\0625\0622\0671\0629\0649\0626\0624', U&'\0627\0627\0627\0627\0647\064A\064A\0648'))
+$$;
+
+-- Folded search text of customers and contacts, matched by list and global
+-- search
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+    (crm_fold_text(COALESCE(name, '') || ' ' || COALESCE(email, '') || ' ' || COALESCE(company, ''))) STORED;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+    (crm_fold_text(COALESCE(first_name, '') || ' ' || COALESCE(last_name, '') || ' ' || COALESCE(email, ''))) STORED;
+
+-- Trigram indexes so substring searches on the folded text use an index
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_customers_search_text_trgm ON customers USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_contacts_search_text_trgm ON contacts USING GIN (search_text gin_trgm_ops);
@@ -0,0 +1,8 @@
+ALTER TABLE jobs DROP COLUMN IF EXISTS artifact_resumes;
+ALTER TABLE jobs DROP COLUMN IF EXISTS resumes;
+ALTER TABLE jobs DROP COLUMN IF EXISTS checkpoint_at;
+ALTER TABLE jobs DROP COLUMN IF EXISTS checkpoint_cursor;
+ALTER TABLE jobs DROP COLUMN IF EXISTS checkpoint_checksum;
+ALTER TABLE jobs DROP COLUMN IF EXISTS checkpoint_bytes;
+ALTER TABLE jobs DROP COLUMN IF EXISTS checkpoint_rows;
+ALTER TABLE jobs DROP COLUMN IF EXISTS checkpoint_parts;
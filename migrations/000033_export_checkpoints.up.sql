@@ -0,0 +1,12 @@
+-- Checkpoints of resumable export jobs: how many rows and bytes are stored
+-- in parts, their running checksum and the keyset of the last row written
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS checkpoint_parts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS checkpoint_rows BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS checkpoint_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS checkpoint_checksum VARCHAR(64);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS checkpoint_cursor JSONB;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS checkpoint_at TIMESTAMP WITH TIME ZONE;
+
+-- Times a job was resumed; its artifact keeps the count it was produced with
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS resumes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS artifact_resumes INTEGER NOT NULL DEFAULT 0;
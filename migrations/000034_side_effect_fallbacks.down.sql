@@ -0,0 +1 @@
+DROP TABLE IF EXISTS side_effect_fallbacks;
@@ -0,0 +1,13 @@
+-- Side effects whose write failed after their change was saved, kept until
+-- they are replayed
+CREATE TABLE IF NOT EXISTS side_effect_fallbacks (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    first_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_side_effect_fallbacks_kind ON side_effect_fallbacks(kind);
@@ -0,0 +1,3 @@
+-- Outcome of jobs that report a result instead of producing a file, such as
+-- post-deploy smoke tests
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS result JSONB;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_customers_converted_at;
+
+ALTER TABLE customers DROP COLUMN IF EXISTS converted_by;
+ALTER TABLE customers DROP COLUMN IF EXISTS converted_at;
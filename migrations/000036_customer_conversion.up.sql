@@ -0,0 +1,5 @@
+-- When and by whom a lead or prospect was converted to an active customer
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS converted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS converted_by INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_customers_converted_at ON customers(converted_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deal_checklist_items;
+DROP TABLE IF EXISTS deal_checklist_definitions;
@@ -0,0 +1,26 @@
+-- Create deal_checklist_definitions, the items deals must complete before
+-- they can be closed won; keys are unique among items not deleted
+CREATE TABLE IF NOT EXISTS deal_checklist_definitions (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(50) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT TRUE,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deal_checklist_definitions_key ON deal_checklist_definitions(key) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_deal_checklist_definitions_deleted_at ON deal_checklist_definitions(deleted_at);
+
+-- Create deal_checklist_items, the checklist items checked on each deal and
+-- who checked them
+CREATE TABLE IF NOT EXISTS deal_checklist_items (
+    id SERIAL PRIMARY KEY,
+    deal_id INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    checked_by INTEGER NOT NULL DEFAULT 0,
+    checked_by_name VARCHAR(255),
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deal_checklist_items_deal_key ON deal_checklist_items(deal_id, key);
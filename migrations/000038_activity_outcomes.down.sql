@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_activities_outcome_code;
+ALTER TABLE activities DROP COLUMN IF EXISTS outcome_code;
+DROP TABLE IF EXISTS activity_outcomes;
@@ -0,0 +1,20 @@
+-- Create activity_outcomes, the outcomes activities of each type are
+-- completed with; codes are unique per type among outcomes not deleted
+CREATE TABLE IF NOT EXISTS activity_outcomes (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    code VARCHAR(50) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    keywords JSONB NOT NULL DEFAULT '[]',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_activity_outcomes_type_code ON activity_outcomes(type, code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_activity_outcomes_type ON activity_outcomes(type);
+CREATE INDEX IF NOT EXISTS idx_activity_outcomes_deleted_at ON activity_outcomes(deleted_at);
+
+-- Outcome code of activities, alongside the free-text outcome
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS outcome_code VARCHAR(50) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_activities_outcome_code ON activities(outcome_code);
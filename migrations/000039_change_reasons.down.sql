@@ -0,0 +1,2 @@
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS reason;
+DROP TABLE IF EXISTS change_reason_rules;
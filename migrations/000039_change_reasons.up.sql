@@ -0,0 +1,20 @@
+-- Create change_reason_rules, the entity fields whose edits must give a
+-- reason; closed_only limits a deal rule to deals already closed
+CREATE TABLE IF NOT EXISTS change_reason_rules (
+    id SERIAL PRIMARY KEY,
+    entity VARCHAR(50) NOT NULL,
+    field VARCHAR(100) NOT NULL,
+    closed_only BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_change_reason_rules_entity_field ON change_reason_rules(entity, field);
+
+-- Compliance requires a reason for amount edits of closed deals and for
+-- customer reassignments
+INSERT INTO change_reason_rules (entity, field, closed_only)
+VALUES ('deal', 'amount', TRUE),
+    ('customer', 'assigned_to', FALSE) ON CONFLICT (entity, field) DO NOTHING;
+
+-- Reason given for a change, covered by the entry's values hash
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_contacts_phone_digits;
+DROP INDEX IF EXISTS idx_customers_phone_digits;
+DROP INDEX IF EXISTS idx_contacts_email_lower;
+DROP INDEX IF EXISTS idx_activities_search_document;
+DROP INDEX IF EXISTS idx_deals_search_document;
+DROP INDEX IF EXISTS idx_contacts_search_document;
+DROP INDEX IF EXISTS idx_customers_search_document;
+DROP INDEX IF EXISTS idx_activities_search_text_trgm;
+DROP INDEX IF EXISTS idx_deals_search_text_trgm;
+ALTER TABLE activities DROP COLUMN IF EXISTS search_text;
+ALTER TABLE deals DROP COLUMN IF EXISTS search_text;
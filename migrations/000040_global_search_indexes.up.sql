@@ -0,0 +1,25 @@
+-- Folded search text of deal and activity titles, matched by list and
+-- global search like the customer and contact columns of migration 000032
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+    (crm_fold_text(COALESCE(title, ''))) STORED;
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+    (crm_fold_text(COALESCE(title, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_deals_search_text_trgm ON deals USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_activities_search_text_trgm ON activities USING GIN (search_text gin_trgm_ops);
+
+-- Full-text documents of global search. The expressions must match the
+-- ones search.entity builds, or the planner cannot use the indexes.
+CREATE INDEX IF NOT EXISTS idx_customers_search_document ON customers USING GIN
+    (to_tsvector('simple', COALESCE(name, '') || ' ' || COALESCE(email, '') || ' ' || COALESCE(company, '')));
+CREATE INDEX IF NOT EXISTS idx_contacts_search_document ON contacts USING GIN
+    (to_tsvector('simple', COALESCE(first_name, '') || ' ' || COALESCE(last_name, '') || ' ' || COALESCE(email, '')));
+CREATE INDEX IF NOT EXISTS idx_deals_search_document ON deals USING GIN
+    (to_tsvector('simple', COALESCE(title, '') || ' ' || COALESCE(description, '')));
+CREATE INDEX IF NOT EXISTS idx_activities_search_document ON activities USING GIN
+    (to_tsvector('simple', COALESCE(title, '')));
+
+-- Exact email and phone matches
+CREATE INDEX IF NOT EXISTS idx_contacts_email_lower ON contacts (LOWER(email));
+CREATE INDEX IF NOT EXISTS idx_customers_phone_digits ON customers (REGEXP_REPLACE(phone, '[^0-9]', '', 'g'));
+CREATE INDEX IF NOT EXISTS idx_contacts_phone_digits ON contacts (REGEXP_REPLACE(phone, '[^0-9]', '', 'g'));
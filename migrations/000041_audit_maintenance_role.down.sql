@@ -0,0 +1,39 @@
+DROP FUNCTION IF EXISTS crm_audit_truncate();
+DROP FUNCTION IF EXISTS crm_audit_purge(BIGINT);
+DROP FUNCTION IF EXISTS crm_audit_redact(BIGINT, JSONB, JSONB, TEXT, TEXT, TIMESTAMP WITH TIME ZONE);
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    IF COALESCE(current_setting('crm.audit_maintenance', true), '') <> 'on' THEN
+        RAISE EXCEPTION 'audit_logs is append-only' USING ERRCODE = 'insufficient_privilege';
+    END IF;
+    IF TG_OP = 'UPDATE' THEN
+        IF (NEW.id, NEW.resource_type, NEW.resource_id, NEW.action, NEW.user_id, NEW.user_name,
+            NEW.user_role, NEW.ip_address, NEW.user_agent, NEW.created_at, NEW.hash, NEW.prev_hash, NEW.values_hash)
+           IS DISTINCT FROM
+           (OLD.id, OLD.resource_type, OLD.resource_id, OLD.action, OLD.user_id, OLD.user_name,
+            OLD.user_role, OLD.ip_address, OLD.user_agent, OLD.created_at, OLD.hash, OLD.prev_hash, OLD.values_hash) THEN
+            RAISE EXCEPTION 'only audit log values can be redacted' USING ERRCODE = 'insufficient_privilege';
+        END IF;
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS redacted_values_hash;
+
+REVOKE ALL ON SEQUENCE audit_logs_id_seq FROM crm_audit_maintainer;
+REVOKE ALL ON audit_logs FROM crm_audit_maintainer;
+REVOKE USAGE ON SCHEMA public FROM crm_audit_maintainer;
+-- The role is shared by the databases of the server and stays while
+-- another database still grants it privileges
+DO $$
+BEGIN
+    DROP ROLE IF EXISTS crm_audit_maintainer;
+EXCEPTION WHEN dependent_objects_still_exist THEN
+    NULL;
+END
+$$;
@@ -0,0 +1,109 @@
+-- Replace the crm.audit_maintenance setting of migration 000019, which any
+-- connection could set, with a role. Only crm_audit_maintainer may change
+-- audit_logs, and the service acts as it only through the SECURITY DEFINER
+-- functions below, each allowing one kind of change.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'crm_audit_maintainer') THEN
+        CREATE ROLE crm_audit_maintainer NOLOGIN;
+    END IF;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO crm_audit_maintainer;
+GRANT SELECT, UPDATE, DELETE, TRUNCATE ON audit_logs TO crm_audit_maintainer;
+GRANT USAGE, UPDATE ON SEQUENCE audit_logs_id_seq TO crm_audit_maintainer;
+
+-- Hash of the values an entry was redacted to, so redacted values are
+-- verified like the originals
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS redacted_values_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    IF current_user <> 'crm_audit_maintainer' THEN
+        RAISE EXCEPTION 'audit_logs is append-only' USING ERRCODE = 'insufficient_privilege';
+    END IF;
+    IF TG_OP = 'UPDATE' THEN
+        IF (NEW.id, NEW.resource_type, NEW.resource_id, NEW.action, NEW.user_id, NEW.user_name,
+            NEW.user_role, NEW.ip_address, NEW.user_agent, NEW.created_at, NEW.hash, NEW.prev_hash, NEW.values_hash)
+           IS DISTINCT FROM
+           (OLD.id, OLD.resource_type, OLD.resource_id, OLD.action, OLD.user_id, OLD.user_name,
+            OLD.user_role, OLD.ip_address, OLD.user_agent, OLD.created_at, OLD.hash, OLD.prev_hash, OLD.values_hash) THEN
+            RAISE EXCEPTION 'only audit log values can be redacted' USING ERRCODE = 'insufficient_privilege';
+        END IF;
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- crm_audit_redact replaces the values and reason of an entry with their
+-- redacted form and records the hash of the redacted values
+CREATE OR REPLACE FUNCTION crm_audit_redact(p_id BIGINT, p_old_values JSONB, p_new_values JSONB,
+                                            p_reason TEXT, p_redacted_values_hash TEXT,
+                                            p_redacted_at TIMESTAMP WITH TIME ZONE) RETURNS VOID
+    LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, pg_temp AS $$
+BEGIN
+    UPDATE audit_logs
+    SET old_values = p_old_values, new_values = p_new_values, reason = p_reason,
+        redacted_values_hash = p_redacted_values_hash, values_redacted_at = p_redacted_at
+    WHERE id = p_id;
+END;
+$$;
+
+-- crm_audit_purge deletes the entries up to and including p_last_id and
+-- returns how many were deleted
+CREATE OR REPLACE FUNCTION crm_audit_purge(p_last_id BIGINT) RETURNS BIGINT
+    LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, pg_temp AS $$
+DECLARE
+    purged BIGINT;
+BEGIN
+    DELETE FROM audit_logs WHERE id <= p_last_id;
+    GET DIAGNOSTICS purged = ROW_COUNT;
+    RETURN purged;
+END;
+$$;
+
+-- crm_audit_truncate empties audit_logs for a backup restore, which brings
+-- its own audit history
+CREATE OR REPLACE FUNCTION crm_audit_truncate() RETURNS VOID
+    LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, pg_temp AS $$
+BEGIN
+    TRUNCATE audit_logs;
+    PERFORM setval('audit_logs_id_seq', 1, false);
+END;
+$$;
+
+-- Hand the functions to the role. Changing an owner requires membership
+-- of the new owner and its CREATE on the schema, both revoked again so the
+-- migrating user cannot SET ROLE to it later.
+DO $$
+DECLARE
+    granted BOOLEAN := NOT pg_has_role(current_user, 'crm_audit_maintainer', 'MEMBER');
+BEGIN
+    IF granted THEN
+        EXECUTE format('GRANT crm_audit_maintainer TO %I', current_user);
+    END IF;
+    GRANT CREATE ON SCHEMA public TO crm_audit_maintainer;
+    ALTER FUNCTION crm_audit_redact(BIGINT, JSONB, JSONB, TEXT, TEXT, TIMESTAMP WITH TIME ZONE) OWNER TO crm_audit_maintainer;
+    ALTER FUNCTION crm_audit_purge(BIGINT) OWNER TO crm_audit_maintainer;
+    ALTER FUNCTION crm_audit_truncate() OWNER TO crm_audit_maintainer;
+    REVOKE CREATE ON SCHEMA public FROM crm_audit_maintainer;
+    IF granted THEN
+        EXECUTE format('REVOKE crm_audit_maintainer FROM %I', current_user);
+    END IF;
+END
+$$;
+
+REVOKE ALL ON FUNCTION crm_audit_redact(BIGINT, JSONB, JSONB, TEXT, TEXT, TIMESTAMP WITH TIME ZONE) FROM PUBLIC;
+REVOKE ALL ON FUNCTION crm_audit_purge(BIGINT) FROM PUBLIC;
+REVOKE ALL ON FUNCTION crm_audit_truncate() FROM PUBLIC;
+DO $$
+BEGIN
+    EXECUTE format('GRANT EXECUTE ON FUNCTION crm_audit_redact(BIGINT, JSONB, JSONB, TEXT, TEXT, TIMESTAMP WITH TIME ZONE) TO %I', current_user);
+    EXECUTE format('GRANT EXECUTE ON FUNCTION crm_audit_purge(BIGINT) TO %I', current_user);
+    EXECUTE format('GRANT EXECUTE ON FUNCTION crm_audit_truncate() TO %I', current_user);
+END
+$$;
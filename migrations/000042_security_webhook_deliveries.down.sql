@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS security_webhook_deliveries;
+DROP INDEX IF EXISTS idx_security_alerts_event_id;
+ALTER TABLE security_alerts DROP COLUMN IF EXISTS notify_failed_at;
+ALTER TABLE security_alerts DROP COLUMN IF EXISTS event_id;
@@ -0,0 +1,26 @@
+-- Give every security alert the event ID its webhook deliveries carry, and
+-- record when an at-most-once webhook refused it
+ALTER TABLE security_alerts ADD COLUMN IF NOT EXISTS event_id VARCHAR(36);
+UPDATE security_alerts SET event_id = gen_random_uuid()::text WHERE event_id IS NULL;
+ALTER TABLE security_alerts ALTER COLUMN event_id SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_security_alerts_event_id ON security_alerts(event_id);
+ALTER TABLE security_alerts ADD COLUMN IF NOT EXISTS notify_failed_at TIMESTAMP WITH TIME ZONE;
+
+-- Create security_webhook_deliveries, one per attempt to post an alert to
+-- the alert webhook, with the start of the response for diagnosis
+CREATE TABLE IF NOT EXISTS security_webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    delivery_id VARCHAR(36) NOT NULL,
+    event_id VARCHAR(36) NOT NULL,
+    alert_id INTEGER NOT NULL REFERENCES security_alerts(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_security_webhook_deliveries_delivery_id ON security_webhook_deliveries(delivery_id);
+CREATE INDEX IF NOT EXISTS idx_security_webhook_deliveries_event_id ON security_webhook_deliveries(event_id);
+CREATE INDEX IF NOT EXISTS idx_security_webhook_deliveries_alert_id ON security_webhook_deliveries(alert_id);
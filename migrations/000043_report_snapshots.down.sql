@@ -0,0 +1,18 @@
+DROP TRIGGER IF EXISTS report_rollup_customer_tags_truncate ON customer_tags;
+DROP TRIGGER IF EXISTS report_rollup_customers_truncate ON customers;
+DROP TRIGGER IF EXISTS report_rollup_deals_truncate ON deals;
+DROP TRIGGER IF EXISTS report_rollup_customer_tags ON customer_tags;
+DROP TRIGGER IF EXISTS report_rollup_customers ON customers;
+DROP TRIGGER IF EXISTS report_rollup_deals ON deals;
+DROP FUNCTION IF EXISTS report_rollup_reset();
+DROP FUNCTION IF EXISTS report_rollup_customer_tags();
+DROP FUNCTION IF EXISTS report_rollup_customers();
+DROP FUNCTION IF EXISTS report_rollup_deals();
+DROP FUNCTION IF EXISTS report_rollup_queue_won(TEXT, INTEGER);
+DROP FUNCTION IF EXISTS report_rollup_queue(TEXT, TIMESTAMP WITH TIME ZONE[]);
+DROP TABLE IF EXISTS report_segment_days;
+DROP TABLE IF EXISTS report_revenue_days;
+DROP TABLE IF EXISTS report_deal_days;
+DROP TABLE IF EXISTS report_customer_days;
+DROP TABLE IF EXISTS report_rollup_days;
+DROP TABLE IF EXISTS report_rollup_state;
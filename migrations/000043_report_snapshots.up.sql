@@ -0,0 +1,170 @@
+-- Create the daily report snapshots read by the funnel, revenue and
+-- segments reports for days before the last rollup. Days are dates in the
+-- time zone of report_rollup_state.
+CREATE TABLE IF NOT EXISTS report_rollup_state (
+    id INTEGER PRIMARY KEY,
+    timezone VARCHAR(64) NOT NULL,
+    through DATE NOT NULL,
+    rolled_up_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS report_rollup_days (
+    id BIGSERIAL PRIMARY KEY,
+    day DATE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_report_rollup_days_day ON report_rollup_days(day);
+
+CREATE TABLE IF NOT EXISTS report_customer_days (
+    id SERIAL PRIMARY KEY,
+    day DATE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    customers BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_report_customer_days_day ON report_customer_days(day);
+
+CREATE TABLE IF NOT EXISTS report_deal_days (
+    id SERIAL PRIMARY KEY,
+    day DATE NOT NULL,
+    owner_id INTEGER,
+    stage VARCHAR(50) NOT NULL,
+    deals BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_report_deal_days_day ON report_deal_days(day);
+
+CREATE TABLE IF NOT EXISTS report_revenue_days (
+    id SERIAL PRIMARY KEY,
+    day DATE NOT NULL,
+    owner_id INTEGER,
+    currency VARCHAR(3),
+    won_count BIGINT NOT NULL,
+    won_total NUMERIC(20,2) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_report_revenue_days_day ON report_revenue_days(day);
+
+CREATE TABLE IF NOT EXISTS report_segment_days (
+    id SERIAL PRIMARY KEY,
+    day DATE NOT NULL,
+    tag_id INTEGER,
+    won_count BIGINT NOT NULL,
+    won_total NUMERIC(20,2) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_report_segment_days_day ON report_segment_days(day);
+
+-- Queue the snapshot days a change to deals, customers or customer_tags
+-- affects, for the next rollup to recompute. Nothing is queued before the
+-- first rollup. Days are appended rather than upserted: a rollup removes
+-- only the rows its snapshot saw, so a change committed during a rollup
+-- stays queued.
+CREATE OR REPLACE FUNCTION report_rollup_queue(tz TEXT, VARIADIC times TIMESTAMP WITH TIME ZONE[]) RETURNS void AS $$
+    INSERT INTO report_rollup_days (day)
+    SELECT DISTINCT (t AT TIME ZONE tz)::date FROM unnest(times) AS t
+    WHERE t IS NOT NULL;
+$$ LANGUAGE sql;
+
+-- The close days of a customer's won deals, which move between segments
+-- when its tags change or it is deleted
+CREATE OR REPLACE FUNCTION report_rollup_queue_won(tz TEXT, customer INTEGER) RETURNS void AS $$
+    INSERT INTO report_rollup_days (day)
+    SELECT DISTINCT (actual_close_date AT TIME ZONE tz)::date FROM deals
+    WHERE customer_id = customer AND stage = 'closed_won' AND actual_close_date IS NOT NULL;
+$$ LANGUAGE sql;
+
+-- Deals count on the days they were created and closed
+CREATE OR REPLACE FUNCTION report_rollup_deals() RETURNS trigger AS $$
+DECLARE
+    tz TEXT;
+BEGIN
+    SELECT timezone INTO tz FROM report_rollup_state WHERE id = 1;
+    IF tz IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM report_rollup_queue(tz, OLD.created_at, OLD.actual_close_date);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM report_rollup_queue(tz, NEW.created_at, NEW.actual_close_date);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Customers count on the day they were created, and their deletion takes
+-- their won deals out of the segments
+CREATE OR REPLACE FUNCTION report_rollup_customers() RETURNS trigger AS $$
+DECLARE
+    tz TEXT;
+BEGIN
+    SELECT timezone INTO tz FROM report_rollup_state WHERE id = 1;
+    IF tz IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM report_rollup_queue(tz, OLD.created_at);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM report_rollup_queue(tz, NEW.created_at);
+    END IF;
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.deleted_at IS DISTINCT FROM NEW.deleted_at) THEN
+        PERFORM report_rollup_queue_won(tz, OLD.id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Tagging or untagging a customer moves its won deals between segments
+CREATE OR REPLACE FUNCTION report_rollup_customer_tags() RETURNS trigger AS $$
+DECLARE
+    tz TEXT;
+BEGIN
+    SELECT timezone INTO tz FROM report_rollup_state WHERE id = 1;
+    IF tz IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM report_rollup_queue_won(tz, OLD.customer_id);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM report_rollup_queue_won(tz, NEW.customer_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Truncating a source table leaves nothing to queue; dropping the state
+-- makes reports read live until the next rollup rebuilds every day
+CREATE OR REPLACE FUNCTION report_rollup_reset() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM report_rollup_state;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS report_rollup_deals ON deals;
+CREATE TRIGGER report_rollup_deals
+    AFTER INSERT OR UPDATE OR DELETE ON deals
+    FOR EACH ROW EXECUTE FUNCTION report_rollup_deals();
+
+DROP TRIGGER IF EXISTS report_rollup_customers ON customers;
+CREATE TRIGGER report_rollup_customers
+    AFTER INSERT OR UPDATE OR DELETE ON customers
+    FOR EACH ROW EXECUTE FUNCTION report_rollup_customers();
+
+DROP TRIGGER IF EXISTS report_rollup_customer_tags ON customer_tags;
+CREATE TRIGGER report_rollup_customer_tags
+    AFTER INSERT OR UPDATE OR DELETE ON customer_tags
+    FOR EACH ROW EXECUTE FUNCTION report_rollup_customer_tags();
+
+DROP TRIGGER IF EXISTS report_rollup_deals_truncate ON deals;
+CREATE TRIGGER report_rollup_deals_truncate
+    AFTER TRUNCATE ON deals
+    FOR EACH STATEMENT EXECUTE FUNCTION report_rollup_reset();
+
+DROP TRIGGER IF EXISTS report_rollup_customers_truncate ON customers;
+CREATE TRIGGER report_rollup_customers_truncate
+    AFTER TRUNCATE ON customers
+    FOR EACH STATEMENT EXECUTE FUNCTION report_rollup_reset();
+
+DROP TRIGGER IF EXISTS report_rollup_customer_tags_truncate ON customer_tags;
+CREATE TRIGGER report_rollup_customer_tags_truncate
+    AFTER TRUNCATE ON customer_tags
+    FOR EACH STATEMENT EXECUTE FUNCTION report_rollup_reset();
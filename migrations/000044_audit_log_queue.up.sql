@@ -0,0 +1,8 @@
+-- Queue audit entries with their change instead of chaining them in its
+-- transaction, which held the chain lock until the change committed. The
+-- audit trail's sequencer moves committed entries into audit_logs.
+CREATE TABLE IF NOT EXISTS audit_log_queue (
+    id BIGSERIAL PRIMARY KEY,
+    entry JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,10 @@
+-- Keep the old names of renamed tags, so reports and links selecting tags
+-- by name still find them. A live tag's name takes precedence over an
+-- alias of another tag.
+CREATE TABLE IF NOT EXISTS tag_aliases (
+    id SERIAL PRIMARY KEY,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_tag_aliases_tag_id ON tag_aliases(tag_id);
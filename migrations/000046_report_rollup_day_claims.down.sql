@@ -0,0 +1,14 @@
+CREATE OR REPLACE FUNCTION report_rollup_queue(tz TEXT, VARIADIC times TIMESTAMP WITH TIME ZONE[]) RETURNS void AS $$
+    INSERT INTO report_rollup_days (day)
+    SELECT DISTINCT (t AT TIME ZONE tz)::date FROM unnest(times) AS t
+    WHERE t IS NOT NULL;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION report_rollup_queue_won(tz TEXT, customer INTEGER) RETURNS void AS $$
+    INSERT INTO report_rollup_days (day)
+    SELECT DISTINCT (actual_close_date AT TIME ZONE tz)::date FROM deals
+    WHERE customer_id = customer AND stage = 'closed_won' AND actual_close_date IS NOT NULL;
+$$ LANGUAGE sql;
+
+DROP INDEX IF EXISTS idx_report_rollup_days_unclaimed;
+ALTER TABLE report_rollup_days DROP COLUMN IF EXISTS claimed;
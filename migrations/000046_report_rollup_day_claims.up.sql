@@ -0,0 +1,26 @@
+-- Queue each snapshot day once rather than once per change. A rollup first
+-- claims the queued days, waiting for the changes still queueing to commit,
+-- so a change that finds its day already queued is in the rollup's
+-- snapshot. Days queued once a rollup has claimed them are queued anew.
+ALTER TABLE report_rollup_days ADD COLUMN IF NOT EXISTS claimed BOOLEAN NOT NULL DEFAULT FALSE;
+
+DELETE FROM report_rollup_days a USING report_rollup_days b WHERE a.day = b.day AND a.id > b.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_report_rollup_days_unclaimed ON report_rollup_days(day) WHERE NOT claimed;
+
+-- Changes queue their days holding the shared side of the queue lock
+-- (reportrollup's queueLockKey, "CRM_QUEU") until they commit
+CREATE OR REPLACE FUNCTION report_rollup_queue(tz TEXT, VARIADIC times TIMESTAMP WITH TIME ZONE[]) RETURNS void AS $$
+    SELECT pg_advisory_xact_lock_shared(4851024820413220181);
+    INSERT INTO report_rollup_days (day)
+    SELECT DISTINCT (t AT TIME ZONE tz)::date FROM unnest(times) AS t
+    WHERE t IS NOT NULL
+    ON CONFLICT (day) WHERE NOT claimed DO NOTHING;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION report_rollup_queue_won(tz TEXT, customer INTEGER) RETURNS void AS $$
+    SELECT pg_advisory_xact_lock_shared(4851024820413220181);
+    INSERT INTO report_rollup_days (day)
+    SELECT DISTINCT (actual_close_date AT TIME ZONE tz)::date FROM deals
+    WHERE customer_id = customer AND stage = 'closed_won' AND actual_close_date IS NOT NULL
+    ON CONFLICT (day) WHERE NOT claimed DO NOTHING;
+$$ LANGUAGE sql;
@@ -0,0 +1,3 @@
+-- A subscription's delivery mode: at_least_once retries a failed delivery
+-- for a day, at_most_once sends it once and marks it failed when refused
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS mode VARCHAR(20) NOT NULL DEFAULT 'at_least_once';
@@ -0,0 +1,4 @@
+-- Whether a subscription takes the events of records changed by imports
+-- and other bulk operations. Those it does not take are recorded as
+-- suppressed deliveries, and the operation raises one bulk.completed event.
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS bulk_events BOOLEAN NOT NULL DEFAULT FALSE;
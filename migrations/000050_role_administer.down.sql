@@ -0,0 +1 @@
+UPDATE roles SET permissions = permissions - 'administer' WHERE permissions ? 'administer';
@@ -0,0 +1,4 @@
+-- Admin only routes check the administer permission rather than the role
+-- name, so custom roles can be granted them. Only admin holds it.
+UPDATE roles SET permissions = permissions || '["administer"]'::jsonb
+WHERE name = 'admin' AND NOT permissions ? 'administer';
@@ -0,0 +1,113 @@
+// Package adminui renders the server-side debugging UI. Pages are filled
+// from the admin API itself, called in-process with the viewer's token, so
+// they show exactly what the API returns to that user and every filter,
+// permission and audit rule applies unchanged.
+package adminui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+)
+
+// SessionCookie holds the viewer's API token
+const SessionCookie = "crm_admin_ui"
+
+// APIError is an error response of the admin API
+type APIError struct {
+	Status int
+	middleware.ErrorResponse
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+	}
+	return fmt.Sprintf("request failed with status %d", e.Status)
+}
+
+// Client calls the admin API in-process
+type Client struct {
+	api http.Handler
+}
+
+// NewClient creates a Client dispatching to the handler serving the admin
+// API, normally the router the UI is mounted on
+func NewClient(api http.Handler) *Client {
+	return &Client{api: api}
+}
+
+// Do calls the admin API on behalf of the UI request r with the viewer's
+// token and decodes the JSON response into out, which may be nil. The
+// client address, user agent and language of r are passed on so audit
+// entries and messages match a direct API call. Non-2xx responses are
+// returned as *APIError.
+func (c *Client) Do(r *http.Request, token, method, path string, query url.Values, out interface{}) error {
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), method, target, nil)
+	if err != nil {
+		return err
+	}
+	req.RemoteAddr = r.RemoteAddr
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	for _, name := range []string{"User-Agent", "Accept-Language", "X-Forwarded-For", "X-Real-IP"} {
+		if value := r.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	rec := newRecorder()
+	c.api.ServeHTTP(rec, req)
+
+	if rec.status >= 300 {
+		apiErr := &APIError{Status: rec.status}
+		json.Unmarshal(rec.body.Bytes(), &apiErr.ErrorResponse)
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(rec.body.Bytes(), out); err != nil {
+		return fmt.Errorf("decoding %s response: %w", path, err)
+	}
+	return nil
+}
+
+// recorder buffers an in-process API response. Like a server response,
+// the first status written wins.
+type recorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
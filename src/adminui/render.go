@@ -0,0 +1,153 @@
+package adminui
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// maxCellLength truncates long values in table cells
+const maxCellLength = 120
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"cell":    Cell,
+	"rowLink": rowLink,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// Page is the data of a UI page
+type Page struct {
+	Title   string
+	User    string
+	Notice  string
+	Error   string
+	Actions []Action // Buttons posting to the UI
+
+	// Filter form, submitted to FilterPath
+	FilterPath string
+	Filters    []Filter
+
+	Record []Field
+	Tables []Table
+}
+
+// Action is a button posting to a UI path
+type Action struct {
+	Label   string
+	Path    string
+	Confirm string // Confirmation prompt, if any
+}
+
+// Filter is a list filter input
+type Filter struct {
+	Name  string
+	Value string
+}
+
+// Field is one value of a record
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Table lists API rows. Rows link to Link followed by their id when Link is
+// set; RowAction adds a button posting to its Path followed by the row id
+// and Suffix.
+type Table struct {
+	Title      string
+	Columns    []string // Keys into the rows; "customer.name" reads nested objects
+	Rows       []map[string]interface{}
+	Link       string
+	RowAction  *RowAction
+	Total      int64
+	Page       int
+	TotalPages int
+	PrevURL    string
+	NextURL    string
+	MoreURL    string // Full list, for tables showing only the first page
+	Error      string
+}
+
+// RowAction is a per-row button
+type RowAction struct {
+	Label  string
+	Path   string
+	Suffix string
+}
+
+// ListResponse is the shape shared by the admin API's paginated lists
+type ListResponse struct {
+	Data       []map[string]interface{} `json:"data"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	TotalPages int                      `json:"total_pages"`
+}
+
+// Render writes a page using the named template
+func Render(w io.Writer, name string, data interface{}) error {
+	return templates.ExecuteTemplate(w, name, data)
+}
+
+// Fields lists the values of a record, id first and the rest by name
+func Fields(record map[string]interface{}) []Field {
+	names := make([]string, 0, len(record))
+	for name := range record {
+		if name != "id" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := record["id"]; ok {
+		names = append([]string{"id"}, names...)
+	}
+
+	fields := make([]Field, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, Field{Name: name, Value: Cell(record, name)})
+	}
+	return fields
+}
+
+// Cell formats the value under key for display. Dotted keys read nested
+// objects, whole numbers print without exponent, and objects and lists are
+// shown as truncated JSON.
+func Cell(row map[string]interface{}, key string) string {
+	var value interface{} = row
+	for _, part := range strings.Split(key, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = object[part]
+	}
+
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		text = v
+	case bool:
+		text = strconv.FormatBool(v)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, _ := json.Marshal(v)
+		text = string(encoded)
+	}
+	if runes := []rune(text); len(runes) > maxCellLength {
+		text = string(runes[:maxCellLength]) + "…"
+	}
+	return text
+}
+
+// rowLink returns the detail path of a row
+func rowLink(prefix string, row map[string]interface{}) string {
+	return prefix + Cell(row, "id")
+}
@@ -0,0 +1,406 @@
+package anonymize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Redacted replaces personal data found inside free text
+const Redacted = "[redacted]"
+
+// placeholderDomain is a reserved domain (RFC 2606) for anonymized emails
+const placeholderDomain = "anonymized.invalid"
+
+// minScrubLength keeps very short values, such as a two-letter name, from
+// redacting unrelated text
+const minScrubLength = 3
+
+var (
+	// ErrAlreadyAnonymized is returned when a customer was anonymized before
+	ErrAlreadyAnonymized = errors.New("customer is already anonymized")
+
+	// ErrInvalidConfirmation is returned for tampered, mismatched or expired
+	// confirmation tokens
+	ErrInvalidConfirmation = errors.New("invalid or expired confirmation token")
+)
+
+// Anonymizer erases a customer's personal data while keeping their deals,
+// amounts, stages and activity metadata for aggregate reports
+type Anonymizer struct {
+	key []byte
+}
+
+// New creates an Anonymizer. The secret keys placeholder hashes, so they
+// cannot be reversed by hashing guessed values, and signs confirmations.
+func New(secret string) *Anonymizer {
+	return &Anonymizer{key: []byte(secret)}
+}
+
+// ConfirmationToken issues a token confirming that a user previewed the
+// anonymization of a customer
+func (a *Anonymizer) ConfirmationToken(customerID, userID uint, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + a.sign(fmt.Sprintf("confirm:%d:%d:%s", customerID, userID, expiry))
+}
+
+// VerifyConfirmation checks a token issued by ConfirmationToken for the same
+// customer and user
+func (a *Anonymizer) VerifyConfirmation(token string, customerID, userID uint, now time.Time) error {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidConfirmation
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return ErrInvalidConfirmation
+	}
+	expected := a.sign(fmt.Sprintf("confirm:%d:%d:%s", customerID, userID, expiry))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidConfirmation
+	}
+	return nil
+}
+
+// Preview counts the records anonymizing a customer would touch
+func (a *Anonymizer) Preview(ctx context.Context, db *gorm.DB, customerID uint) (models.AnonymizationSummary, error) {
+	var summary models.AnonymizationSummary
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		related, err := loadRelated(tx, customerID)
+		if err != nil {
+			return err
+		}
+		summary = related.summary()
+		return tx.Model(&models.AuditLog{}).Scopes(related.auditScope).Count(&summary.AuditLogs).Error
+	})
+	return summary, err
+}
+
+// Customer anonymizes a customer in one transaction. The customer's and
+// contacts' names, emails, phones and notes are replaced with keyed-hash
+// placeholders or cleared, the same values are scrubbed from notes, deal and
+// activity text and audit log values, and email engagement events lose their
+// IP address and user agent. Deals and activities themselves are kept.
+func (a *Anonymizer) Customer(ctx context.Context, db *gorm.DB, customerID uint, at time.Time) (models.AnonymizationSummary, error) {
+	var summary models.AnonymizationSummary
+	// Entries still queued would be chained unscrubbed after this commits
+	if _, err := audittrail.Sequence(ctx, db); err != nil {
+		return summary, err
+	}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var customer models.Customer
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, customerID).Error; err != nil {
+			return err
+		}
+		if customer.AnonymizedAt != nil {
+			return ErrAlreadyAnonymized
+		}
+
+		related, err := loadRelated(tx, customerID)
+		if err != nil {
+			return err
+		}
+		summary = related.summary()
+
+		s := newScrubber(customer.Name, customer.Email, customer.Phone)
+		for _, contact := range related.contacts {
+			s.add(strings.TrimSpace(contact.FirstName+" "+contact.LastName), contact.Email, contact.Phone)
+		}
+
+		hash := a.placeholder(customer.Email)
+		if err := tx.Unscoped().Model(&customer).Updates(map[string]interface{}{
+			"name":          "Anonymized " + hash[:8],
+			"email":         a.placeholderEmail(customer.Email),
+			"phone":         "",
+			"notes":         "",
+			"email_domain":  "",
+			"anonymized_at": at,
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, contact := range related.contacts {
+			email := ""
+			if contact.Email != "" {
+				email = a.placeholderEmail(contact.Email)
+			}
+			if err := tx.Unscoped().Model(&contact).Updates(map[string]interface{}{
+				"first_name": "Anonymized",
+				"last_name":  "",
+				"email":      email,
+				"phone":      "",
+				"notes":      "",
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := scrubRows(tx, &models.Deal{}, related.dealIDs, s, "title", "description", "lost_reason"); err != nil {
+			return err
+		}
+		if err := scrubRows(tx, &models.Activity{}, related.activityIDs, s, "title", "description", "outcome"); err != nil {
+			return err
+		}
+		if err := scrubRows(tx, &models.Note{}, related.noteIDs, s, "content"); err != nil {
+			return err
+		}
+
+		if len(related.activityIDs) > 0 {
+			result := tx.Model(&models.EmailEvent{}).Where("activity_id IN ?", related.activityIDs).
+				Updates(map[string]interface{}{"ip_address": "", "user_agent": ""})
+			if result.Error != nil {
+				return result.Error
+			}
+			summary.EmailEvents = result.RowsAffected
+		}
+
+		scrubbed, err := scrubAuditLogs(tx, related.auditScope, s, at)
+		summary.AuditLogs = scrubbed
+		return err
+	})
+	return summary, err
+}
+
+// placeholder returns an irreversible keyed hash of a value. Equal values
+// get equal placeholders, so uniqueness constraints still hold.
+func (a *Anonymizer) placeholder(value string) string {
+	return a.sign("value:" + strings.ToLower(strings.TrimSpace(value)))[:24]
+}
+
+// placeholderEmail returns a unique, syntactically valid placeholder email
+func (a *Anonymizer) placeholderEmail(email string) string {
+	return "anon-" + a.placeholder(email) + "@" + placeholderDomain
+}
+
+// sign returns the hex HMAC of a message
+func (a *Anonymizer) sign(message string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// related holds the IDs of records belonging to a customer
+type related struct {
+	customerID  uint
+	contacts    []models.Contact
+	dealIDs     []uint
+	activityIDs []uint
+	noteIDs     []uint
+}
+
+// loadRelated loads the customer's contacts, deals, activities and notes,
+// including soft-deleted ones
+func loadRelated(tx *gorm.DB, customerID uint) (*related, error) {
+	r := &related{customerID: customerID}
+	if err := tx.Unscoped().Where("customer_id = ?", customerID).Find(&r.contacts).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Model(&models.Deal{}).Where("customer_id = ?", customerID).Pluck("id", &r.dealIDs).Error; err != nil {
+		return nil, err
+	}
+
+	activities := tx.Unscoped().Model(&models.Activity{}).Where("customer_id = ?", customerID)
+	if len(r.dealIDs) > 0 {
+		activities = activities.Or("deal_id IN ?", r.dealIDs)
+	}
+	if err := activities.Pluck("id", &r.activityIDs).Error; err != nil {
+		return nil, err
+	}
+
+	notes := tx.Unscoped().Model(&models.Note{}).Where("customer_id = ?", customerID)
+	if len(r.dealIDs) > 0 {
+		notes = notes.Or("deal_id IN ?", r.dealIDs)
+	}
+	if len(r.activityIDs) > 0 {
+		notes = notes.Or("activity_id IN ?", r.activityIDs)
+	}
+	if err := notes.Pluck("id", &r.noteIDs).Error; err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// summary counts the related records
+func (r *related) summary() models.AnonymizationSummary {
+	return models.AnonymizationSummary{
+		Contacts:   int64(len(r.contacts)),
+		Deals:      int64(len(r.dealIDs)),
+		Activities: int64(len(r.activityIDs)),
+		Notes:      int64(len(r.noteIDs)),
+	}
+}
+
+// auditScope selects audit log entries of the customer and its related records
+func (r *related) auditScope(db *gorm.DB) *gorm.DB {
+	contactIDs := make([]uint, len(r.contacts))
+	for i, contact := range r.contacts {
+		contactIDs[i] = contact.ID
+	}
+
+	conditions := db.Session(&gorm.Session{NewDB: true}).
+		Where("resource_type = ? AND resource_id = ?", "customer", r.customerID)
+	for resourceType, ids := range map[string][]uint{
+		"contact":  contactIDs,
+		"deal":     r.dealIDs,
+		"activity": r.activityIDs,
+		"note":     r.noteIDs,
+	} {
+		if len(ids) > 0 {
+			conditions = conditions.Or("resource_type = ? AND resource_id IN ?", resourceType, ids)
+		}
+	}
+	return db.Where(conditions)
+}
+
+// scrubRows redacts personal data from text columns of the given rows
+func scrubRows(tx *gorm.DB, model interface{}, ids []uint, s *scrubber, columns ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var rows []map[string]interface{}
+	if err := tx.Unscoped().Model(model).Select(append([]string{"id"}, columns...)).
+		Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		updates := map[string]interface{}{}
+		for _, column := range columns {
+			text, _ := row[column].(string)
+			if scrubbed := s.scrub(text); scrubbed != text {
+				updates[column] = scrubbed
+			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Unscoped().Model(model).Where("id = ?", row["id"]).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrubAuditLogs redacts personal data from the old and new values and the
+// reasons of the selected audit log entries and returns the number of entries changed.
+// Redacted entries are marked and verified against the hash of their new values.
+func scrubAuditLogs(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB, s *scrubber, at time.Time) (int64, error) {
+	var logs []models.AuditLog
+	if err := tx.Scopes(scope).Find(&logs).Error; err != nil {
+		return 0, err
+	}
+
+	var changed int64
+	for _, log := range logs {
+		oldValues, newValues := s.scrubJSON(log.OldValues), s.scrubJSON(log.NewValues)
+		reason := s.scrub(log.Reason)
+		if oldValues == log.OldValues && newValues == log.NewValues && reason == log.Reason {
+			continue
+		}
+		if err := models.RedactAuditLog(tx, log.ID, oldValues, newValues, reason, at); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// scrubber redacts known personal values from text, ignoring case
+type scrubber struct {
+	pattern *regexp.Regexp
+	values  []string
+}
+
+// newScrubber creates a scrubber for the given values
+func newScrubber(values ...string) *scrubber {
+	s := &scrubber{}
+	s.add(values...)
+	return s
+}
+
+// add registers more values to redact
+func (s *scrubber) add(values ...string) {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); len(value) >= minScrubLength {
+			s.values = append(s.values, regexp.QuoteMeta(value))
+		}
+	}
+	s.pattern = nil
+}
+
+// scrub redacts every registered value in text
+func (s *scrubber) scrub(text string) string {
+	if len(s.values) == 0 || text == "" {
+		return text
+	}
+	if s.pattern == nil {
+		// Longest first, so a full name is redacted whole rather than in part
+		sort.Slice(s.values, func(i, j int) bool { return len(s.values[i]) > len(s.values[j]) })
+		s.pattern = regexp.MustCompile("(?i)" + strings.Join(s.values, "|"))
+	}
+	return s.pattern.ReplaceAllString(text, Redacted)
+}
+
+// scrubJSON redacts registered values from every string in a JSON document.
+// Documents that cannot be parsed are returned unchanged.
+func (s *scrubber) scrubJSON(document string) string {
+	if document == "" {
+		return document
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		return document
+	}
+	scrubbed, changed := s.scrubValue(value)
+	if !changed {
+		return document
+	}
+	encoded, err := json.Marshal(scrubbed)
+	if err != nil {
+		return document
+	}
+	return string(encoded)
+}
+
+// scrubValue redacts strings inside a decoded JSON value
+func (s *scrubber) scrubValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		scrubbed := s.scrub(v)
+		return scrubbed, scrubbed != v
+	case map[string]interface{}:
+		changed := false
+		for key, item := range v {
+			if scrubbed, ok := s.scrubValue(item); ok {
+				v[key] = scrubbed
+				changed = true
+			}
+		}
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i, item := range v {
+			if scrubbed, ok := s.scrubValue(item); ok {
+				v[i] = scrubbed
+				changed = true
+			}
+		}
+		return v, changed
+	default:
+		return value, false
+	}
+}
@@ -0,0 +1,100 @@
+// Package apispec registers the admin API endpoints offered to clients,
+// with the types each one takes and returns. cmd/genclient generates the
+// Go and TypeScript clients in /clients from it, and a test checks that
+// every endpoint is served by the router.
+package apispec
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// Endpoint is one route of the admin API
+type Endpoint struct {
+	Name     string // Client method name
+	Summary  string // What the endpoint does, for client doc comments
+	Method   string
+	Path     string      // Route path as registered with gin, e.g. /admin/customers/:id
+	Query    bool        // Takes list query parameters such as page and filters
+	Request  interface{} // Zero value of the JSON body, nil for none
+	Response interface{} // Zero value of the JSON response, nil when clients ignore it
+	Internal bool        // Operational endpoint left out of generated clients
+}
+
+// Params returns the names of the endpoint's path parameters in order
+func (e Endpoint) Params() []string {
+	var params []string
+	for _, segment := range strings.Split(e.Path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// IsIDParam reports whether a path parameter is a numeric record ID
+func IsIDParam(name string) bool {
+	return name == "id" || strings.HasSuffix(name, "Id")
+}
+
+// Endpoints lists the registered endpoints in the order clients present
+// them
+var Endpoints = []Endpoint{
+	{Name: "GetMe", Summary: "returns the caller and their permissions", Method: http.MethodGet, Path: "/admin/me", Response: models.MeResponse{}},
+	{Name: "GetCapabilities", Summary: "returns the fields the caller can edit per entity", Method: http.MethodGet, Path: "/admin/me/capabilities", Response: models.CapabilitiesResponse{}},
+	{Name: "ListMyActivities", Summary: "lists the activities assigned to the caller", Method: http.MethodGet, Path: "/admin/me/activities", Query: true, Response: models.ActivityListResponse{}},
+
+	{Name: "ListCustomers", Summary: "lists customers", Method: http.MethodGet, Path: "/admin/customers", Query: true, Response: models.CustomerListResponse{}},
+	{Name: "CreateCustomer", Summary: "creates a customer", Method: http.MethodPost, Path: "/admin/customers", Request: handlers.CustomerCreateRequest{}, Response: models.Customer{}},
+	{Name: "GetCustomer", Summary: "returns a customer with its related counts", Method: http.MethodGet, Path: "/admin/customers/:id", Response: models.CustomerDetailResponse{}},
+	{Name: "UpdateCustomer", Summary: "updates a customer", Method: http.MethodPut, Path: "/admin/customers/:id", Request: handlers.CustomerUpdateRequest{}, Response: models.Customer{}},
+	{Name: "DeleteCustomer", Summary: "deletes a customer with its related records", Method: http.MethodDelete, Path: "/admin/customers/:id"},
+	{Name: "ArchiveCustomer", Summary: "archives a customer", Method: http.MethodPost, Path: "/admin/customers/:id/archive", Response: models.Customer{}},
+	{Name: "UnarchiveCustomer", Summary: "restores an archived customer", Method: http.MethodPost, Path: "/admin/customers/:id/unarchive", Response: models.Customer{}},
+	{Name: "ClaimCustomer", Summary: "assigns an unassigned customer to the caller", Method: http.MethodPost, Path: "/admin/customers/:id/claim", Response: models.Customer{}},
+	{Name: "AssignTagToCustomer", Summary: "tags a customer", Method: http.MethodPost, Path: "/admin/customers/:id/tags/:tagId"},
+	{Name: "RemoveTagFromCustomer", Summary: "removes a tag from a customer", Method: http.MethodDelete, Path: "/admin/customers/:id/tags/:tagId"},
+
+	{Name: "ListContacts", Summary: "lists a customer's contacts", Method: http.MethodGet, Path: "/admin/customers/:id/contacts", Query: true, Response: models.ContactListResponse{}},
+	{Name: "CreateContact", Summary: "adds a contact to a customer", Method: http.MethodPost, Path: "/admin/customers/:id/contacts", Request: handlers.ContactCreateRequest{}, Response: models.Contact{}},
+	{Name: "UpdateContact", Summary: "updates a contact", Method: http.MethodPut, Path: "/admin/contacts/:id", Request: handlers.ContactUpdateRequest{}, Response: models.Contact{}},
+	{Name: "DeleteContact", Summary: "deletes a contact", Method: http.MethodDelete, Path: "/admin/contacts/:id"},
+
+	{Name: "ListDeals", Summary: "lists deals", Method: http.MethodGet, Path: "/admin/deals", Query: true, Response: models.DealListResponse{}},
+	{Name: "CreateDeal", Summary: "creates a deal", Method: http.MethodPost, Path: "/admin/deals", Request: handlers.DealCreateRequest{}, Response: handlers.DealCreateResponse{}},
+	{Name: "GetDeal", Summary: "returns a deal with its timeline and checklist", Method: http.MethodGet, Path: "/admin/deals/:id", Response: models.Deal{}},
+	{Name: "UpdateDeal", Summary: "updates a deal", Method: http.MethodPut, Path: "/admin/deals/:id", Request: handlers.DealUpdateRequest{}, Response: models.Deal{}},
+	{Name: "MoveDealStage", Summary: "moves a deal to another stage", Method: http.MethodPatch, Path: "/admin/deals/:id", Request: handlers.DealStageTransitionRequest{}, Response: models.Deal{}},
+	{Name: "DeleteDeal", Summary: "deletes a deal", Method: http.MethodDelete, Path: "/admin/deals/:id"},
+	{Name: "ArchiveDeal", Summary: "archives a deal", Method: http.MethodPost, Path: "/admin/deals/:id/archive", Response: models.Deal{}},
+	{Name: "UnarchiveDeal", Summary: "restores an archived deal", Method: http.MethodPost, Path: "/admin/deals/:id/unarchive", Response: models.Deal{}},
+
+	{Name: "ListActivities", Summary: "lists activities", Method: http.MethodGet, Path: "/admin/activities", Query: true, Response: models.ActivityListResponse{}},
+	{Name: "CreateActivity", Summary: "logs or schedules an activity", Method: http.MethodPost, Path: "/admin/activities", Request: handlers.ActivityCreateRequest{}, Response: models.Activity{}},
+	{Name: "GetActivity", Summary: "returns an activity", Method: http.MethodGet, Path: "/admin/activities/:id", Response: models.Activity{}},
+	{Name: "UpdateActivity", Summary: "updates an activity", Method: http.MethodPut, Path: "/admin/activities/:id", Request: handlers.ActivityUpdateRequest{}, Response: models.Activity{}},
+	{Name: "UpdateActivityStatus", Summary: "changes an activity's status", Method: http.MethodPatch, Path: "/admin/activities/:id", Request: handlers.ActivityStatusUpdateRequest{}, Response: models.Activity{}},
+	{Name: "CompleteActivity", Summary: "completes an activity with its outcome", Method: http.MethodPost, Path: "/admin/activities/:id/complete", Request: handlers.ActivityCompleteRequest{}, Response: models.ActivityCompletionResponse{}},
+	{Name: "DeleteActivity", Summary: "deletes an activity", Method: http.MethodDelete, Path: "/admin/activities/:id"},
+
+	{Name: "ListTags", Summary: "lists tags", Method: http.MethodGet, Path: "/admin/tags", Response: models.TagListResponse{}},
+	{Name: "CreateTag", Summary: "creates a tag", Method: http.MethodPost, Path: "/admin/tags", Request: handlers.TagCreateRequest{}, Response: models.Tag{}},
+	{Name: "UpdateTag", Summary: "updates a tag", Method: http.MethodPut, Path: "/admin/tags/:id", Request: handlers.TagUpdateRequest{}, Response: models.Tag{}},
+	{Name: "DeleteTag", Summary: "deletes a tag", Method: http.MethodDelete, Path: "/admin/tags/:id"},
+
+	{Name: "ListPipelineStages", Summary: "lists the pipeline stages in board order", Method: http.MethodGet, Path: "/admin/pipeline-stages", Response: models.PipelineStageListResponse{}},
+	{Name: "CreatePipelineStage", Summary: "adds a pipeline stage", Method: http.MethodPost, Path: "/admin/pipeline-stages", Request: handlers.PipelineStageCreateRequest{}, Response: models.PipelineStage{}},
+	{Name: "UpdatePipelineStage", Summary: "updates a pipeline stage", Method: http.MethodPut, Path: "/admin/pipeline-stages/:id", Request: handlers.PipelineStageUpdateRequest{}, Response: models.PipelineStage{}},
+	{Name: "DeletePipelineStage", Summary: "deletes a pipeline stage no deal is in", Method: http.MethodDelete, Path: "/admin/pipeline-stages/:id"},
+
+	{Name: "ListJobs", Summary: "lists background jobs", Method: http.MethodGet, Path: "/admin/jobs", Query: true, Response: models.JobListResponse{}},
+	{Name: "CreateExportJob", Summary: "starts an export job", Method: http.MethodPost, Path: "/admin/jobs/exports", Request: handlers.ExportJobRequest{}, Response: models.Job{}},
+	{Name: "GetJob", Summary: "returns a job and its progress", Method: http.MethodGet, Path: "/admin/jobs/:id", Response: models.Job{}},
+
+	{Name: "RunConsistencyChecks", Method: http.MethodPost, Path: "/admin/maintenance/consistency/run", Internal: true},
+	{Name: "ReindexSearch", Method: http.MethodPost, Path: "/admin/maintenance/search/reindex", Internal: true},
+	{Name: "RebuildReportSnapshots", Method: http.MethodPost, Path: "/admin/maintenance/rollup", Internal: true},
+}
@@ -0,0 +1,40 @@
+package apispec_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/apispec"
+	"github.com/SalehAlobaylan/CRM-Service/src/testserver"
+)
+
+func TestEndpointsAreUnique(t *testing.T) {
+	names, routes := map[string]bool{}, map[string]bool{}
+	for _, endpoint := range apispec.Endpoints {
+		route := endpoint.Method + " " + endpoint.Path
+		if names[endpoint.Name] || routes[route] {
+			t.Errorf("%s (%s) registered twice", endpoint.Name, route)
+		}
+		names[endpoint.Name], routes[route] = true, true
+		for _, value := range []interface{}{endpoint.Request, endpoint.Response} {
+			if value != nil && reflect.TypeOf(value).Kind() != reflect.Struct {
+				t.Errorf("%s: %T is not a struct", endpoint.Name, value)
+			}
+		}
+	}
+}
+
+// TestEndpointsAreRouted checks the registry against the router, so it
+// cannot list a route that was renamed or removed
+func TestEndpointsAreRouted(t *testing.T) {
+	s := testserver.New(t)
+	routed := map[string]bool{}
+	for _, route := range s.Router.Routes() {
+		routed[route.Method+" "+route.Path] = true
+	}
+	for _, endpoint := range apispec.Endpoints {
+		if !routed[endpoint.Method+" "+endpoint.Path] {
+			t.Errorf("%s: %s %s is not routed", endpoint.Name, endpoint.Method, endpoint.Path)
+		}
+	}
+}
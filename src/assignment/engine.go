@@ -0,0 +1,208 @@
+package assignment
+
+import (
+	"errors"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoAvailableRep is returned when every member of a rule is unavailable
+var ErrNoAvailableRep = errors.New("no available rep in assignment rule")
+
+// picker holds the state needed to pick reps for one rule
+type picker struct {
+	rule        *models.AssignmentRule
+	sequence    []uint
+	unavailable map[uint]bool
+	openLeads   map[uint]int64
+}
+
+// AssignLead picks a rep for a new lead using the first active rule and
+// advances the rule's state. It must run inside the transaction creating
+// the lead: the rule row is locked so concurrent lead creations are
+// serialized and never pick from stale counts. Returns nil when no rule is
+// active.
+func AssignLead(tx *gorm.DB, at time.Time) (*uint, error) {
+	var rule models.AssignmentRule
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("is_active = ?", true).
+		Order("priority ASC, id ASC").
+		First(&rule).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPicker(tx, &rule, at)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&rule).Update("position", rule.Position).Error; err != nil {
+		return nil, err
+	}
+	return &userID, nil
+}
+
+// Simulate returns the reps the next count leads would be assigned to,
+// without changing any state
+func Simulate(db *gorm.DB, rule models.AssignmentRule, count int, at time.Time) (models.AssignmentSimulation, error) {
+	simulation := models.AssignmentSimulation{
+		RuleID:       rule.ID,
+		Strategy:     rule.Strategy,
+		Count:        count,
+		Assignments:  []uint{},
+		Distribution: make(map[uint]int),
+		Unavailable:  []uint{},
+	}
+
+	p, err := newPicker(db, &rule, at)
+	if err != nil {
+		return simulation, err
+	}
+	for _, m := range rule.Members {
+		simulation.Distribution[m.UserID] = 0
+		if p.unavailable[m.UserID] {
+			simulation.Unavailable = append(simulation.Unavailable, m.UserID)
+		}
+	}
+
+	for i := 0; i < count; i++ {
+		userID, err := p.next()
+		if err != nil {
+			return simulation, err
+		}
+		simulation.Assignments = append(simulation.Assignments, userID)
+		simulation.Distribution[userID]++
+	}
+	return simulation, nil
+}
+
+// newPicker loads availability and, for least_open_leads, current lead counts
+func newPicker(db *gorm.DB, rule *models.AssignmentRule, at time.Time) (*picker, error) {
+	userIDs := make([]uint, 0, len(rule.Members))
+	for _, m := range rule.Members {
+		userIDs = append(userIDs, m.UserID)
+	}
+
+	var unavailableIDs []uint
+	if err := db.Model(&models.UserUnavailability{}).
+		Where("user_id IN ? AND starts_at <= ? AND ends_at > ?", userIDs, at, at).
+		Distinct().Pluck("user_id", &unavailableIDs).Error; err != nil {
+		return nil, err
+	}
+
+	p := &picker{
+		rule:        rule,
+		unavailable: make(map[uint]bool, len(unavailableIDs)),
+	}
+	for _, id := range unavailableIDs {
+		p.unavailable[id] = true
+	}
+
+	switch rule.Strategy {
+	case models.AssignmentStrategyLeastOpenLeads:
+		var rows []struct {
+			AssignedTo uint
+			Count      int64
+		}
+		if err := db.Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
+			Select("assigned_to, COUNT(*) as count").
+			Where("assigned_to IN ? AND status IN ?", userIDs, models.OpenLeadStatuses).
+			Group("assigned_to").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		p.openLeads = make(map[uint]int64, len(rows))
+		for _, row := range rows {
+			p.openLeads[row.AssignedTo] = row.Count
+		}
+	case models.AssignmentStrategyWeightedRoundRobin:
+		p.sequence = weightedSequence(rule.Members)
+	default:
+		p.sequence = weightedSequence(unweighted(rule.Members))
+	}
+
+	return p, nil
+}
+
+// next picks the next available rep and advances the picker state
+func (p *picker) next() (uint, error) {
+	if p.rule.Strategy == models.AssignmentStrategyLeastOpenLeads {
+		var best uint
+		found := false
+		for _, m := range p.rule.Members {
+			if p.unavailable[m.UserID] {
+				continue
+			}
+			if !found || p.openLeads[m.UserID] < p.openLeads[best] {
+				best = m.UserID
+				found = true
+			}
+		}
+		if !found {
+			return 0, ErrNoAvailableRep
+		}
+		p.openLeads[best]++
+		return best, nil
+	}
+
+	n := len(p.sequence)
+	for k := 0; k < n; k++ {
+		idx := (p.rule.Position + k) % n
+		userID := p.sequence[idx]
+		if p.unavailable[userID] {
+			continue
+		}
+		p.rule.Position = (idx + 1) % n
+		return userID, nil
+	}
+	return 0, ErrNoAvailableRep
+}
+
+// weightedSequence expands members into one smooth weighted round-robin
+// cycle, interleaving reps instead of assigning in bursts
+func weightedSequence(members []models.AssignmentMember) []uint {
+	total := 0
+	weights := make([]int, len(members))
+	for i, m := range members {
+		weights[i] = m.Weight
+		if weights[i] <= 0 {
+			weights[i] = 1
+		}
+		total += weights[i]
+	}
+
+	current := make([]int, len(members))
+	sequence := make([]uint, 0, total)
+	for len(sequence) < total {
+		best := 0
+		for i := range members {
+			current[i] += weights[i]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		sequence = append(sequence, members[best].UserID)
+	}
+	return sequence
+}
+
+// unweighted returns members with all weights set to 1
+func unweighted(members []models.AssignmentMember) []models.AssignmentMember {
+	result := make([]models.AssignmentMember, len(members))
+	for i, m := range members {
+		result[i] = models.AssignmentMember{UserID: m.UserID, Weight: 1}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package audittrail_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// TestAuditLogGuard checks on Postgres that audit_logs only changes
+// through the functions of the crm_audit_maintainer role
+func TestAuditLogGuard(t *testing.T) {
+	db := testdb.Open(t)
+	for i := 1; i <= 3; i++ {
+		err := db.Create(&models.AuditLog{ResourceType: "customer", ResourceID: uint(i), Action: models.AuditActionUpdate,
+			UserID: 1, NewValues: `{"email":"huda@nakheel.sa"}`}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, statement := range []string{
+		"UPDATE audit_logs SET new_values = NULL WHERE id = 1",
+		"DELETE FROM audit_logs WHERE id = 1",
+		"TRUNCATE audit_logs",
+	} {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// The setting that used to lift the guard no longer does
+			if err := tx.Exec("SET LOCAL crm.audit_maintenance = 'on'").Error; err != nil {
+				return err
+			}
+			return tx.Exec(statement).Error
+		})
+		if err == nil || !strings.Contains(err.Error(), "append-only") {
+			t.Errorf("%s: err = %v, want the append-only guard", statement, err)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return models.RedactAuditLog(tx, 2, "", `{"email":"[redacted]"}`, "", time.Now())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := audittrail.Verify(context.Background(), db, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Redacted != 1 || result.UnverifiedRedactions != 0 {
+		t.Errorf("after redaction: %+v", result)
+	}
+
+	purged, err := models.PurgeAuditLogs(db, 1)
+	if err != nil || purged != 1 {
+		t.Errorf("purge: %d, %v", purged, err)
+	}
+	if err := models.TruncateAuditLogs(db); err != nil {
+		t.Fatal(err)
+	}
+	var left int64
+	if err := db.Model(&models.AuditLog{}).Count(&left).Error; err != nil || left != 0 {
+		t.Errorf("after truncate: %d entries, %v", left, err)
+	}
+}
@@ -0,0 +1,173 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"github.com/SalehAlobaylan/CRM-Service/src/fallback"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// FallbackKind is the side effect kind of audit entries kept for replay
+const FallbackKind = "audit"
+
+// WritePolicy is how Record handles an audit entry that cannot be written
+var WritePolicy = fallback.PolicyResilient
+
+// Fallback keeps the audit entries Record could not write under the
+// resilient policy; without it every failure is returned
+var Fallback *fallback.Writer
+
+// Publisher raises the events of an audited change, such as the webhook
+// events of a deal or customer change. It is called in the transaction
+// saving the change and must leave it usable: a failure to raise events is
+// the publisher's to report and never fails the change. Changes made by a
+// bulk operation carry it in their context (see BulkOperation), and the
+// operation raises PublishBulk once it completes.
+type Publisher interface {
+	Publish(ctx context.Context, tx *gorm.DB, audit *models.AuditLog)
+	PublishBulk(ctx context.Context, db *gorm.DB, summary models.BulkOperationSummary)
+}
+
+// Events is handed every change Record writes, in its transaction; nil
+// publishes nothing
+var Events Publisher
+
+// BulkContextKey holds the bulk operation, such as
+// models.BulkOperationCustomerImport, making the changes of a request
+const BulkContextKey = "audit_bulk"
+
+// BulkOperation returns the bulk operation making the changes of ctx, if
+// any
+func BulkOperation(ctx context.Context) string {
+	operation, _ := ctx.Value(BulkContextKey).(string)
+	return operation
+}
+
+// Publish hands Events a change that has no audit entry of its own, such as
+// a row of an import audited by one summary entry per batch
+func Publish(ctx context.Context, tx *gorm.DB, audit *models.AuditLog) {
+	if Events != nil {
+		Events.Publish(ctx, tx.WithContext(ctx), audit)
+	}
+}
+
+// PublishBulk hands Events a completed bulk operation
+func PublishBulk(ctx context.Context, db *gorm.DB, summary models.BulkOperationSummary) {
+	if Events != nil {
+		Events.PublishBulk(ctx, db, summary)
+	}
+}
+
+// ReasonContextKey holds the reason given for the changes of a request;
+// Record stores it on entries that have none
+const ReasonContextKey = "audit_reason"
+
+// ErrWriteFailed wraps the error of an audit entry that could not be
+// written under the strict policy
+var ErrWriteFailed = errors.New("audit entry could not be written")
+
+// auditSavepoint guards the audit insert inside the change's transaction
+const auditSavepoint = "audit_entry"
+
+// Record writes the audit entry of a change. It is called with the
+// transaction saving the change, so under the strict policy a failed write
+// is returned wrapping ErrWriteFailed and rolls the change back with the
+// transaction. Under the resilient policy the insert runs in a savepoint;
+// when it fails the savepoint is rolled back and nil is returned, so the
+// change still commits. The entry is handed to Fallback only once the
+// transaction commits, so a change rolled back later replays nothing; a
+// transaction begun without commit hooks (see database.EnableCommitHooks)
+// cannot defer it and fails as under the strict policy.
+//
+// In a transaction with commit hooks the entry is queued rather than
+// chained, so the change does not hold the chain lock until it commits,
+// and Sequence moves it into the log once it does. Entries are stamped when
+// they are chained, so a replayed entry carries the time of its replay.
+//
+// The change is handed to Events first, whatever the policy, so its events
+// do not depend on the audit write, and their failure does not fail it.
+func Record(ctx context.Context, tx *gorm.DB, audit *models.AuditLog) error {
+	if reason, ok := ctx.Value(ReasonContextKey).(string); ok && audit.Reason == "" {
+		audit.Reason = reason
+	}
+	tx = tx.WithContext(ctx)
+
+	if Events != nil {
+		Events.Publish(ctx, tx, audit)
+	}
+
+	if WritePolicy == fallback.PolicyStrict || Fallback == nil {
+		if err := write(tx, audit); err != nil {
+			return fmt.Errorf("%w: %w", ErrWriteFailed, err)
+		}
+		return nil
+	}
+
+	// A failed statement aborts the whole transaction in Postgres
+	_, inTransaction := tx.Statement.ConnPool.(gorm.TxCommitter)
+	if inTransaction {
+		if err := tx.SavePoint(auditSavepoint).Error; err != nil {
+			return err
+		}
+	}
+	err := write(tx, audit)
+	if err == nil {
+		return nil
+	}
+	if inTransaction {
+		if err := tx.RollbackTo(auditSavepoint).Error; err != nil {
+			return err
+		}
+	}
+
+	// The entry is chained again when it is replayed
+	entry := unchained(*audit)
+	if !inTransaction {
+		return Fallback.Add(FallbackKind, entry, err)
+	}
+	fallbacks, cause := Fallback, err
+	if !database.AfterCommit(tx, func(*gorm.DB) { fallbacks.Add(FallbackKind, entry, cause) }) {
+		return fmt.Errorf("%w: %w", ErrWriteFailed, err)
+	}
+	return nil
+}
+
+// write queues the audit entry when tx commits with hooks, registering
+// Sequence to run after the commit, and chains it in place otherwise
+func write(tx *gorm.DB, audit *models.AuditLog) error {
+	if !database.HasCommitHooks(tx) {
+		return tx.Create(audit).Error
+	}
+	payload, err := json.Marshal(unchained(*audit))
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(&models.AuditQueueEntry{Entry: string(payload)}).Error; err != nil {
+		return err
+	}
+	// Entries a failed run leaves queued are moved by the sequencer job
+	database.AfterCommitOnce(tx, sequenceHook, func(db *gorm.DB) { Sequence(db.Statement.Context, db) })
+	return nil
+}
+
+// unchained returns the entry without the ID and hashes it gets when it is
+// chained
+func unchained(audit models.AuditLog) models.AuditLog {
+	audit.ID = 0
+	audit.Hash, audit.PrevHash, audit.ValuesHash = "", "", ""
+	return audit
+}
+
+// Replay writes an audit entry kept by Fallback
+func Replay(ctx context.Context, tx *gorm.DB, payload json.RawMessage) error {
+	var audit models.AuditLog
+	if err := json.Unmarshal(payload, &audit); err != nil {
+		return err
+	}
+	return tx.WithContext(ctx).Create(&audit).Error
+}
@@ -0,0 +1,147 @@
+package audittrail_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAuditWritesGoThroughRecord checks that no code outside this package
+// creates audit entries itself, which would ignore AUDIT_WRITE_POLICY. It
+// looks for AuditLog values, functions returning them and INSERT statements
+// on audit_logs handed to GORM's Create, CreateInBatches and Save.
+func TestAuditWritesGoThroughRecord(t *testing.T) {
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+	files := make(map[string]*ast.File)
+	for _, dir := range []string{"src", "cmd"} {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return err
+			}
+			if filepath.Base(filepath.Dir(path)) == "audittrail" {
+				return nil
+			}
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			files[path] = file
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Functions building audit entries, such as the handlers' auditEntry
+	builders := make(map[string]bool)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Type.Results != nil {
+				for _, result := range fn.Type.Results.List {
+					if isAuditType(result.Type) {
+						builders[fn.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, file := range files {
+		// Variables holding audit entries, by declaration
+		entries := make(map[*ast.Object]bool)
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && id.Obj != nil && i < len(n.Rhs) && isAuditValue(n.Rhs[i], builders) {
+						entries[id.Obj] = true
+					}
+				}
+			case *ast.ValueSpec:
+				for i, name := range n.Names {
+					if isAuditType(n.Type) || (i < len(n.Values) && isAuditValue(n.Values[i], builders)) {
+						entries[name.Obj] = true
+					}
+				}
+			}
+			return true
+		})
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING &&
+				strings.Contains(strings.ToUpper(lit.Value), "INSERT INTO AUDIT_LOGS") {
+				t.Errorf("%s: audit entry inserted with SQL; use audittrail.Record", fset.Position(call.Pos()))
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			switch selector.Sel.Name {
+			case "Create", "CreateInBatches", "Save", "FirstOrCreate":
+			default:
+				return true
+			}
+			arg := call.Args[0]
+			if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+				arg = unary.X
+			}
+			id, isIdent := arg.(*ast.Ident)
+			if isAuditValue(arg, builders) || (isIdent && id.Obj != nil && entries[id.Obj]) {
+				t.Errorf("%s: audit entry written with %s; use audittrail.Record", fset.Position(call.Pos()), selector.Sel.Name)
+			}
+			return true
+		})
+	}
+	if len(files) == 0 {
+		t.Fatal("no source files found")
+	}
+}
+
+// isAuditType reports whether expr names AuditLog, a pointer to it or a
+// slice of it
+func isAuditType(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return isAuditType(e.X)
+	case *ast.ArrayType:
+		return isAuditType(e.Elt)
+	case *ast.SelectorExpr:
+		return e.Sel.Name == "AuditLog"
+	case *ast.Ident:
+		return e.Name == "AuditLog"
+	}
+	return false
+}
+
+// isAuditValue reports whether expr builds an audit entry: an AuditLog
+// literal, its address, a slice made of them or a call to a builder
+func isAuditValue(expr ast.Expr, builders map[string]bool) bool {
+	switch e := expr.(type) {
+	case *ast.UnaryExpr:
+		return e.Op == token.AND && isAuditValue(e.X, builders)
+	case *ast.CompositeLit:
+		return e.Type != nil && isAuditType(e.Type)
+	case *ast.CallExpr:
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			if fun.Name == "make" || fun.Name == "new" {
+				return len(e.Args) > 0 && isAuditType(e.Args[0])
+			}
+			return builders[fun.Name]
+		case *ast.SelectorExpr:
+			return builders[fun.Sel.Name]
+		}
+	}
+	return false
+}
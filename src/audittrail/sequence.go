@@ -0,0 +1,59 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// sequenceHook is the key of the commit hook running Sequence, registered
+// once per transaction however many entries it queues
+const sequenceHook = "audittrail:sequence"
+
+// sequenceBatchSize is the most queued entries chained in one transaction
+const sequenceBatchSize = 500
+
+// Sequence moves the audit entries queued by committed changes into the
+// log in the order they were queued and returns how many it moved. Each
+// batch is chained in a short transaction of its own holding the chain
+// lock, so the lock is never held while a change is being saved, and
+// concurrent runs each move an entry at most once.
+func Sequence(ctx context.Context, db *gorm.DB) (int, error) {
+	var moved int
+	for {
+		var queued []models.AuditQueueEntry
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", models.AuditChainLockKey).Error; err != nil {
+				return err
+			}
+			if err := tx.Order("id").Limit(sequenceBatchSize).Find(&queued).Error; err != nil {
+				return err
+			}
+			if len(queued) == 0 {
+				return nil
+			}
+
+			entries := make([]models.AuditLog, len(queued))
+			ids := make([]uint64, len(queued))
+			for i, entry := range queued {
+				if err := json.Unmarshal([]byte(entry.Entry), &entries[i]); err != nil {
+					return err
+				}
+				ids[i] = entry.ID
+			}
+			if err := tx.Create(&entries).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&models.AuditQueueEntry{}).Error
+		})
+		if err != nil {
+			return moved, err
+		}
+		moved += len(queued)
+		if len(queued) < sequenceBatchSize {
+			return moved, nil
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package audittrail writes audit entries and verifies the hash chain of
+// the audit log.
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// verifyBatchSize is how many entries are loaded per query
+const verifyBatchSize = 1000
+
+// Reasons reported for a broken link
+const (
+	ReasonMissingHash   = "missing_hash"   // Unchained entry after the chain started
+	ReasonBrokenLink    = "broken_link"    // prev_hash is not the previous entry's hash
+	ReasonContentChange = "content_change" // Entry fields no longer match its hash
+	ReasonValuesChange  = "values_change"  // Old/new values no longer match their hash
+)
+
+// BrokenLink describes the first entry failing verification
+type BrokenLink struct {
+	AuditID  uint   `json:"audit_id"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// Result is the outcome of verifying a range of the audit log
+type Result struct {
+	Valid                bool                    `json:"valid"`
+	From                 *time.Time              `json:"from,omitempty"`
+	To                   *time.Time              `json:"to,omitempty"`
+	Checked              int64                   `json:"checked"`
+	Legacy               int64                   `json:"legacy"`                // Entries written before hash chaining
+	Redacted             int64                   `json:"redacted"`              // Entries whose values were anonymized
+	UnverifiedRedactions int64                   `json:"unverified_redactions"` // Anonymized before redacted values were hashed
+	FirstID              uint                    `json:"first_id,omitempty"`
+	LastID               uint                    `json:"last_id,omitempty"`
+	Broken               *BrokenLink             `json:"broken,omitempty"`
+	Checkpoint           *models.AuditCheckpoint `json:"checkpoint,omitempty"` // Purge checkpoint the range links to
+}
+
+// Verify recomputes the hash chain of audit entries created in [from, to],
+// either bound optional, and reports the first broken link. The window is
+// resolved to the IDs of the first and last entry created in it, and every
+// entry between them is checked, whatever its creation time says. The first
+// entry is checked against the entry before it, or against the latest
+// purge checkpoint when that entry was purged.
+func Verify(ctx context.Context, db *gorm.DB, from, to *time.Time) (Result, error) {
+	result := Result{Valid: true, From: from, To: to}
+	db = db.WithContext(ctx)
+
+	window := db.Model(&models.AuditLog{})
+	if from != nil {
+		window = window.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		window = window.Where("created_at <= ?", *to)
+	}
+	var bounds struct {
+		MinID *uint
+		MaxID *uint
+	}
+	if err := window.Select("MIN(id) AS min_id, MAX(id) AS max_id").Scan(&bounds).Error; err != nil {
+		return result, err
+	}
+	if bounds.MinID == nil {
+		return result, nil
+	}
+	query := db.Model(&models.AuditLog{}).Where("id BETWEEN ? AND ?", *bounds.MinID, *bounds.MaxID)
+
+	started := false
+	var expected string
+	var batch []models.AuditLog
+	err := query.FindInBatches(&batch, verifyBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			if result.Checked == 0 {
+				result.FirstID = entry.ID
+				var broken *BrokenLink
+				var err error
+				started, expected, broken, err = chainStateBefore(db, entry.ID, &result)
+				if err != nil {
+					return err
+				}
+				if broken != nil {
+					result.Valid = false
+					result.Broken = broken
+					return errBroken
+				}
+			}
+			result.Checked++
+			result.LastID = entry.ID
+
+			if broken := checkEntry(entry, started, expected, &result); broken != nil {
+				result.Valid = false
+				result.Broken = broken
+				return errBroken
+			}
+			if entry.Hash != "" {
+				started = true
+			}
+			expected = entry.Hash
+		}
+		return nil
+	}).Error
+	if errors.Is(err, errBroken) {
+		err = nil
+	}
+	return result, err
+}
+
+// errBroken ends verification at the first broken link
+var errBroken = errors.New("audit chain broken")
+
+// chainStateBefore returns whether the chain had started before the entry
+// with the given ID and the hash that entry must link to. An unchained
+// entry right before it, after the chain started, is reported as broken.
+func chainStateBefore(db *gorm.DB, id uint, result *Result) (bool, string, *BrokenLink, error) {
+	var previous []models.AuditLog
+	if err := db.Select("id", "hash").Where("id < ?", id).Order("id DESC").Limit(1).Find(&previous).Error; err != nil {
+		return false, "", nil, err
+	}
+	if len(previous) > 0 {
+		if previous[0].Hash != "" {
+			return true, previous[0].Hash, nil, nil
+		}
+		var chained int64
+		if err := db.Model(&models.AuditLog{}).Where("id < ? AND hash <> ''", id).Count(&chained).Error; err != nil {
+			return false, "", nil, err
+		}
+		if chained > 0 {
+			return true, "", &BrokenLink{AuditID: previous[0].ID, Reason: ReasonMissingHash}, nil
+		}
+		return false, "", nil, nil
+	}
+
+	var checkpoints []models.AuditCheckpoint
+	if err := db.Where("last_audit_id < ?", id).Order("id DESC").Limit(1).Find(&checkpoints).Error; err != nil {
+		return false, "", nil, err
+	}
+	if len(checkpoints) == 0 {
+		return false, "", nil, nil
+	}
+	result.Checkpoint = &checkpoints[0]
+	return checkpoints[0].LastHash != "", checkpoints[0].LastHash, nil, nil
+}
+
+// checkEntry verifies one entry against the hash it must link to
+func checkEntry(entry models.AuditLog, started bool, expected string, result *Result) *BrokenLink {
+	if entry.Hash == "" {
+		if started {
+			return &BrokenLink{AuditID: entry.ID, Reason: ReasonMissingHash}
+		}
+		result.Legacy++
+		return nil
+	}
+
+	if entry.PrevHash != expected {
+		return &BrokenLink{AuditID: entry.ID, Reason: ReasonBrokenLink, Expected: expected, Actual: entry.PrevHash}
+	}
+	valuesHash := entry.ValuesHash
+	if entry.ValuesRedactedAt != nil {
+		result.Redacted++
+		valuesHash = entry.RedactedValuesHash
+	}
+	if entry.ValuesRedactedAt != nil && valuesHash == "" {
+		result.UnverifiedRedactions++
+	} else if hash := models.AuditValuesHash(entry.OldValues, entry.NewValues, entry.Reason); hash != valuesHash {
+		return &BrokenLink{AuditID: entry.ID, Reason: ReasonValuesChange, Expected: valuesHash, Actual: hash}
+	}
+	if hash := entry.ChainHash(entry.PrevHash); hash != entry.Hash {
+		return &BrokenLink{AuditID: entry.ID, Reason: ReasonContentChange, Expected: entry.Hash, Actual: hash}
+	}
+	return nil
+}
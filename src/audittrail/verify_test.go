@@ -0,0 +1,136 @@
+package audittrail_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+var epoch = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// writeEntries creates n audit entries an hour apart, starting at epoch
+func writeEntries(t *testing.T, f *testdb.Database, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		f.SetNow(epoch.Add(time.Duration(i) * time.Hour))
+		err := f.DB.Create(&models.AuditLog{
+			ResourceType: "customer",
+			ResourceID:   uint(i + 1),
+			Action:       models.AuditActionUpdate,
+			UserID:       1,
+			OldValues:    `{"email":"huda@example.com"}`,
+			NewValues:    `{"email":"huda@nakheel.sa"}`,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func verify(t *testing.T, db *gorm.DB, from, to *time.Time) audittrail.Result {
+	t.Helper()
+	result, err := audittrail.Verify(context.Background(), db, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// tamper changes audit entries behind the service's back, as the role the
+// append-only guard lets through
+func tamper(t *testing.T, db *gorm.DB, statement string, values ...interface{}) {
+	t.Helper()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL ROLE crm_audit_maintainer").Error; err != nil {
+			return err
+		}
+		return tx.Exec(statement, values...).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func at(hours int) *time.Time {
+	t := epoch.Add(time.Duration(hours) * time.Hour)
+	return &t
+}
+
+func TestEntriesAreStampedWhenWritten(t *testing.T) {
+	f := testdb.New(t, epoch)
+	entry := models.AuditLog{ResourceType: "customer", ResourceID: 1, Action: models.AuditActionCreate, UserID: 1,
+		CreatedAt: epoch.Add(-24 * time.Hour)}
+	if err := f.DB.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !entry.CreatedAt.Equal(epoch) {
+		t.Errorf("created_at = %v, want the time of the write %v", entry.CreatedAt, epoch)
+	}
+}
+
+func TestVerifyChecksEveryEntryOfTheWindow(t *testing.T) {
+	f := testdb.New(t, epoch)
+	writeEntries(t, f, 5)
+
+	if result := verify(t, f.DB, nil, nil); !result.Valid || result.Checked != 5 {
+		t.Fatalf("whole log: %+v", result)
+	}
+	if result := verify(t, f.DB, at(1), at(3)); !result.Valid || result.FirstID != 2 || result.LastID != 4 || result.Checked != 3 {
+		t.Errorf("window: %+v", result)
+	}
+	if result := verify(t, f.DB, at(10), nil); !result.Valid || result.Checked != 0 {
+		t.Errorf("empty window: %+v", result)
+	}
+
+	// An entry moved out of the window by its creation time is still
+	// between the window's first and last entries, and fails its hash
+	tamper(t, f.DB, "UPDATE audit_logs SET created_at = ? WHERE id = 3", epoch.Add(-time.Hour))
+	result := verify(t, f.DB, at(1), at(3))
+	if result.Valid || result.Broken == nil || result.Broken.AuditID != 3 || result.Broken.Reason != audittrail.ReasonContentChange {
+		t.Errorf("backdated entry: %+v", result)
+	}
+}
+
+func TestVerifyChecksRedactedValues(t *testing.T) {
+	f := testdb.New(t, epoch)
+	writeEntries(t, f, 3)
+
+	err := models.RedactAuditLog(f.DB, 2, `{"email":"[redacted]"}`, `{"email":"[redacted]"}`, "", epoch.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := verify(t, f.DB, nil, nil)
+	if !result.Valid || result.Redacted != 1 || result.UnverifiedRedactions != 0 {
+		t.Fatalf("after redaction: %+v", result)
+	}
+
+	// Redacted values are held to the hash of the redaction
+	tamper(t, f.DB, `UPDATE audit_logs SET new_values = '{"email":"huda@nakheel.sa"}' WHERE id = 2`)
+	result = verify(t, f.DB, nil, nil)
+	if result.Valid || result.Broken == nil || result.Broken.AuditID != 2 || result.Broken.Reason != audittrail.ReasonValuesChange {
+		t.Errorf("changed redacted values: %+v", result)
+	}
+
+	// Entries redacted before redactions were hashed are counted apart
+	tamper(t, f.DB, "UPDATE audit_logs SET redacted_values_hash = '' WHERE id = 2")
+	if result := verify(t, f.DB, nil, nil); !result.Valid || result.UnverifiedRedactions != 1 {
+		t.Errorf("unhashed redaction: %+v", result)
+	}
+}
+
+func TestPurgeAuditLogs(t *testing.T) {
+	f := testdb.New(t, epoch)
+	writeEntries(t, f, 4)
+	purged, err := models.PurgeAuditLogs(f.DB, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 || f.Count("audit_logs") != 2 {
+		t.Errorf("purged %d, %d left", purged, f.Count("audit_logs"))
+	}
+}
@@ -0,0 +1,166 @@
+// Package auth verifies the bearer tokens of admin API requests. Each
+// provider, such as HMAC-signed JWTs shared with the CMS, JWTs signed by an
+// identity provider's published keys or service-account tokens, is an
+// Authenticator; the configured providers are tried in order.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// Authentication providers
+const (
+	ProviderHMAC           = "hmac"            // JWTs signed with the shared JWT_SECRET
+	ProviderJWKS           = "jwks"            // RS256 JWTs verified with an issuer's published keys
+	ProviderServiceAccount = "service_account" // Service-account API tokens
+)
+
+// Providers lists the provider names AUTH_PROVIDERS accepts
+var Providers = []string{ProviderHMAC, ProviderJWKS, ProviderServiceAccount}
+
+// Reasons a provider refuses a token, reported alongside INVALID_TOKEN so a
+// misconfigured provider is easy to tell from a bad token
+const (
+	ReasonMalformed        = "MALFORMED"
+	ReasonExpired          = "TOKEN_EXPIRED"
+	ReasonNotYetValid      = "TOKEN_NOT_YET_VALID"
+	ReasonBadSignature     = "BAD_SIGNATURE"
+	ReasonUnknownKey       = "UNKNOWN_KEY"
+	ReasonKeysUnavailable  = "KEYS_UNAVAILABLE"
+	ReasonIssuerMismatch   = "ISSUER_MISMATCH"
+	ReasonAudienceMismatch = "AUDIENCE_MISMATCH"
+	ReasonInvalidClaims    = "INVALID_CLAIMS"
+	ReasonInvalid          = "INVALID"
+	ReasonUnknownToken     = "UNKNOWN_TOKEN"
+	ReasonInactiveToken    = "INACTIVE_TOKEN"
+	ReasonAccountRevoked   = "ACCOUNT_REVOKED"
+	ReasonNoProvider       = "NO_PROVIDER"
+)
+
+// ErrNotApplicable is returned by an Authenticator for tokens it does not
+// issue, e.g. an RS256 JWT given to the HMAC provider, so the next provider
+// is tried
+var ErrNotApplicable = errors.New("token not handled by this provider")
+
+// Error is a token refused by a provider
+type Error struct {
+	Provider string
+	Reason   string // One of the Reason constants
+	Message  string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Provider, e.Reason, e.Err)
+	}
+	return e.Provider + ": " + e.Reason + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Identity is the caller a provider authenticated
+type Identity struct {
+	Provider string
+	UserID   uint
+	Email    string
+	Name     string
+	Role     string
+	Org      string
+	Sandbox  bool                        // Route the request to the API sandbox database
+	IssuedAt *time.Time                  // When the token was issued, for revocation
+	Claims   jwt.MapClaims               // Claims of a JWT, nil for service accounts
+	Account  *models.ServiceAccount      // Set when a service account authenticated
+	Token    *models.ServiceAccountToken // The service account's token
+}
+
+// Authenticator verifies the bearer tokens of one provider
+type Authenticator interface {
+	// Name returns the provider name
+	Name() string
+
+	// Authenticate returns the caller a token identifies. It returns
+	// ErrNotApplicable for tokens of other providers, and an *Error for
+	// tokens of the provider it refuses.
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// Chain tries authenticators in order
+type Chain []Authenticator
+
+// Authenticate returns the identity from the first authenticator accepting
+// the token. When none does it returns the error of the first one that
+// refused it rather than passing it on.
+func (c Chain) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	var refused error
+	for _, authenticator := range c {
+		identity, err := authenticator.Authenticate(ctx, token)
+		if err == nil {
+			identity.Provider = authenticator.Name()
+			return identity, nil
+		}
+		if !errors.Is(err, ErrNotApplicable) && refused == nil {
+			refused = err
+		}
+	}
+	if refused == nil {
+		refused = &Error{Reason: ReasonNoProvider, Message: "No authentication provider accepts this token"}
+	}
+	return nil, refused
+}
+
+// Config holds the settings of every provider
+type Config struct {
+	HMACSecret string
+	HMACClaims ClaimsMapping
+	JWKS       JWKSConfig
+}
+
+// NewChain builds the chain of the comma-separated providers in spec, in
+// order. Every provider may appear once.
+func NewChain(spec string, cfg Config, db *gorm.DB) (Chain, error) {
+	var chain Chain
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if slices.Contains(names, name) {
+			return nil, fmt.Errorf("provider %q is listed twice", name)
+		}
+		names = append(names, name)
+
+		switch name {
+		case ProviderHMAC:
+			if cfg.HMACSecret == "" {
+				return nil, errors.New("the hmac provider needs JWT_SECRET")
+			}
+			chain = append(chain, NewHMACAuthenticator(cfg.HMACSecret, cfg.HMACClaims))
+		case ProviderJWKS:
+			if cfg.JWKS.Issuer == "" && cfg.JWKS.URL == "" {
+				return nil, errors.New("the jwks provider needs AUTH_JWKS_ISSUER or AUTH_JWKS_URL")
+			}
+			chain = append(chain, NewJWKSAuthenticator(cfg.JWKS))
+		case ProviderServiceAccount:
+			chain = append(chain, NewServiceAccountAuthenticator(db))
+		default:
+			return nil, fmt.Errorf("unknown provider %q, want one of %s", name, strings.Join(Providers, ", "))
+		}
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("at least one provider is required")
+	}
+	return chain, nil
+}
@@ -0,0 +1,245 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const secret = "auth-test-secret"
+
+func hmacToken(t *testing.T, method jwt.SigningMethod, key string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// reason returns the reason of a refused token, or "" when err is not an
+// *auth.Error
+func reason(err error) string {
+	var authErr *auth.Error
+	if errors.As(err, &authErr) {
+		return authErr.Reason
+	}
+	return ""
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	now := time.Now()
+	nested, err := auth.ParseClaimsMapping("user_id=sub,role=realm_access.roles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		mapping  auth.ClaimsMapping
+		token    string
+		reason   string
+		userID   uint
+		role     string
+		sandbox  bool
+		notMine  bool
+		issuedAt bool
+	}{
+		{name: "hs256", token: hmacToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"user_id": 7, "role": "manager", "iat": now.Unix()}),
+			userID: 7, role: "manager", issuedAt: true},
+		{name: "hs512 with string id", token: hmacToken(t, jwt.SigningMethodHS512, secret, jwt.MapClaims{"user_id": "12", "role": "agent"}),
+			userID: 12, role: "agent"},
+		{name: "sandbox claim", token: hmacToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"user_id": 7, "role": "agent", "sandbox": true}),
+			userID: 7, role: "agent", sandbox: true},
+		{name: "nested role list", mapping: nested,
+			token:  hmacToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"sub": "9", "realm_access": map[string]interface{}{"roles": []string{"offline_access", "admin"}}}),
+			userID: 9, role: "admin"},
+		{name: "wrong secret", token: hmacToken(t, jwt.SigningMethodHS256, "another-secret", jwt.MapClaims{"user_id": 7}), reason: auth.ReasonBadSignature},
+		{name: "expired", token: hmacToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"user_id": 7, "exp": now.Add(-time.Minute).Unix()}), reason: auth.ReasonExpired},
+		{name: "not yet valid", token: hmacToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"user_id": 7, "nbf": now.Add(time.Hour).Unix()}), reason: auth.ReasonNotYetValid},
+		{name: "invalid user id", token: hmacToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"user_id": "seven"}), reason: auth.ReasonInvalidClaims},
+		{name: "malformed", token: "not.a.jwt", reason: auth.ReasonMalformed},
+		{name: "rsa token", token: rsaToken(t, testKey(t, 0), "key-0", jwt.MapClaims{"user_id": 7}), notMine: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping := tc.mapping
+			if mapping == (auth.ClaimsMapping{}) {
+				mapping = auth.DefaultClaimsMapping
+			}
+			identity, err := auth.NewHMACAuthenticator(secret, mapping).Authenticate(context.Background(), tc.token)
+			switch {
+			case tc.notMine:
+				if !errors.Is(err, auth.ErrNotApplicable) {
+					t.Errorf("err = %v, want ErrNotApplicable", err)
+				}
+			case tc.reason != "":
+				if reason(err) != tc.reason {
+					t.Errorf("err = %v, want reason %s", err, tc.reason)
+				}
+			case err != nil:
+				t.Fatal(err)
+			default:
+				if identity.UserID != tc.userID || identity.Role != tc.role || identity.Sandbox != tc.sandbox || (identity.IssuedAt != nil) != tc.issuedAt {
+					t.Errorf("identity = %+v", identity)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceAccountAuthenticator(t *testing.T) {
+	now := time.Now()
+	f := testdb.New(t, now)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	// account creates a service account with one token and returns the token
+	account := func(name string, revoked *time.Time, token models.ServiceAccountToken) string {
+		t.Helper()
+		sa := models.ServiceAccount{Name: name, Role: "agent", Scopes: []string{"customers:read"}, Sandbox: name == "sandbox", RevokedAt: revoked}
+		if err := f.DB.Create(&sa).Error; err != nil {
+			t.Fatal(err)
+		}
+		plain, hash, err := models.GenerateServiceAccountToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token.ServiceAccountID, token.TokenHash, token.Prefix = sa.ID, hash, plain[:12]
+		if err := f.DB.Create(&token).Error; err != nil {
+			t.Fatal(err)
+		}
+		return plain
+	}
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		reason  string
+		sandbox bool
+		notMine bool
+	}{
+		{name: "active", token: account("etl", nil, models.ServiceAccountToken{})},
+		{name: "rotated within grace", token: account("rotated", nil, models.ServiceAccountToken{ExpiresAt: &future})},
+		{name: "sandbox", token: account("sandbox", nil, models.ServiceAccountToken{}), sandbox: true},
+		{name: "expired token", token: account("expired", nil, models.ServiceAccountToken{ExpiresAt: &past}), reason: auth.ReasonInactiveToken},
+		{name: "revoked token", token: account("revoked-token", nil, models.ServiceAccountToken{RevokedAt: &past}), reason: auth.ReasonInactiveToken},
+		{name: "revoked account", token: account("revoked", &past, models.ServiceAccountToken{}), reason: auth.ReasonAccountRevoked},
+		{name: "unknown token", token: models.ServiceAccountTokenPrefix + "0123456789abcdef", reason: auth.ReasonUnknownToken},
+		{name: "jwt", token: hmacToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"user_id": 7}), notMine: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, err := auth.NewServiceAccountAuthenticator(f.DB).Authenticate(context.Background(), tc.token)
+			switch {
+			case tc.notMine:
+				if !errors.Is(err, auth.ErrNotApplicable) {
+					t.Errorf("err = %v, want ErrNotApplicable", err)
+				}
+			case tc.reason != "":
+				if reason(err) != tc.reason {
+					t.Errorf("err = %v, want reason %s", err, tc.reason)
+				}
+			case err != nil:
+				t.Fatal(err)
+			default:
+				if identity.Account == nil || identity.Name != "service-account:"+identity.Account.Name || identity.Role != "agent" || identity.Sandbox != tc.sandbox {
+					t.Errorf("identity = %+v", identity)
+				}
+			}
+		})
+	}
+}
+
+// stub is an authenticator answering every token the same way
+type stub struct {
+	name string
+	err  error
+}
+
+func (s stub) Name() string { return s.name }
+
+func (s stub) Authenticate(context.Context, string) (*auth.Identity, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &auth.Identity{UserID: 1}, nil
+}
+
+func TestChainOrder(t *testing.T) {
+	refusedA := &auth.Error{Provider: "a", Reason: auth.ReasonExpired}
+	refusedB := &auth.Error{Provider: "b", Reason: auth.ReasonBadSignature}
+	skip := auth.ErrNotApplicable
+
+	for _, tc := range []struct {
+		name     string
+		chain    auth.Chain
+		provider string
+		reason   string
+	}{
+		{"first accepts", auth.Chain{stub{"a", nil}, stub{"b", nil}}, "a", ""},
+		{"skipped to the second", auth.Chain{stub{"a", skip}, stub{"b", nil}}, "b", ""},
+		{"refused then accepted", auth.Chain{stub{"a", refusedA}, stub{"b", nil}}, "b", ""},
+		{"first refusal is reported", auth.Chain{stub{"a", refusedA}, stub{"b", refusedB}}, "", auth.ReasonExpired},
+		{"refusal after a skip", auth.Chain{stub{"a", skip}, stub{"b", refusedB}}, "", auth.ReasonBadSignature},
+		{"nobody handles it", auth.Chain{stub{"a", skip}, stub{"b", skip}}, "", auth.ReasonNoProvider},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, err := tc.chain.Authenticate(context.Background(), "token")
+			if tc.reason != "" {
+				if reason(err) != tc.reason {
+					t.Errorf("err = %v, want reason %s", err, tc.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Provider != tc.provider {
+				t.Errorf("provider = %s, want %s", identity.Provider, tc.provider)
+			}
+		})
+	}
+}
+
+func TestNewChain(t *testing.T) {
+	cfg := auth.Config{HMACSecret: secret, HMACClaims: auth.DefaultClaimsMapping, JWKS: auth.JWKSConfig{URL: "https://idp.example.com/jwks.json"}}
+	for _, tc := range []struct {
+		spec  string
+		names []string
+	}{
+		{"hmac", []string{auth.ProviderHMAC}},
+		{"service_account, jwks ,hmac", []string{auth.ProviderServiceAccount, auth.ProviderJWKS, auth.ProviderHMAC}},
+		{"hmac,hmac", nil},
+		{"hmac,saml", nil},
+		{" , ", nil},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			chain, err := auth.NewChain(tc.spec, cfg, nil)
+			if tc.names == nil {
+				if err == nil {
+					t.Errorf("chain = %v, want an error", chain)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, authenticator := range chain {
+				names = append(names, authenticator.Name())
+			}
+			if len(names) != len(tc.names) {
+				t.Fatalf("providers = %v, want %v", names, tc.names)
+			}
+			for i := range names {
+				if names[i] != tc.names[i] {
+					t.Errorf("providers = %v, want %v", names, tc.names)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Identity fields a claims mapping can set
+const (
+	FieldUserID  = "user_id"
+	FieldRole    = "role"
+	FieldEmail   = "email"
+	FieldName    = "name"
+	FieldOrg     = "org"
+	FieldSandbox = "sandbox"
+)
+
+// claimFields lists the fields a claims mapping can set
+var claimFields = []string{FieldUserID, FieldRole, FieldEmail, FieldName, FieldOrg, FieldSandbox}
+
+// ClaimsMapping names the JWT claim holding each identity field. A name is
+// looked up as a claim first and otherwise as a dot-separated path into
+// nested claims, so both "https://crm.example.com/role" and
+// "realm_access.roles" work.
+type ClaimsMapping struct {
+	UserID  string
+	Role    string
+	Email   string
+	Name    string
+	Org     string
+	Sandbox string
+}
+
+// DefaultClaimsMapping reads each field from the claim of the same name,
+// as in the tokens the CMS issues
+var DefaultClaimsMapping = ClaimsMapping{
+	UserID:  FieldUserID,
+	Role:    FieldRole,
+	Email:   FieldEmail,
+	Name:    FieldName,
+	Org:     FieldOrg,
+	Sandbox: FieldSandbox,
+}
+
+// ParseClaimsMapping parses a comma-separated list of field=claim overrides
+// of DefaultClaimsMapping, e.g. "user_id=crm_user_id,role=realm_access.roles"
+func ParseClaimsMapping(spec string) (ClaimsMapping, error) {
+	mapping := DefaultClaimsMapping
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, claim, ok := strings.Cut(entry, "=")
+		field, claim = strings.TrimSpace(field), strings.TrimSpace(claim)
+		if !ok || claim == "" {
+			return ClaimsMapping{}, fmt.Errorf("invalid claim mapping %q, expected field=claim", entry)
+		}
+
+		switch field {
+		case FieldUserID:
+			mapping.UserID = claim
+		case FieldRole:
+			mapping.Role = claim
+		case FieldEmail:
+			mapping.Email = claim
+		case FieldName:
+			mapping.Name = claim
+		case FieldOrg:
+			mapping.Org = claim
+		case FieldSandbox:
+			mapping.Sandbox = claim
+		default:
+			return ClaimsMapping{}, fmt.Errorf("unknown field %q in claim mapping %q, want one of %s", field, entry, strings.Join(claimFields, ", "))
+		}
+	}
+	return mapping, nil
+}
+
+// Identity reads the identity fields from the claims of a token. The user
+// ID may be a number or a numeric string; a role claim holding a list, as
+// identity providers put roles, gives its first role defined in this CRM.
+func (m ClaimsMapping) Identity(claims jwt.MapClaims) (*Identity, error) {
+	identity := &Identity{Claims: claims}
+
+	var err error
+	if identity.UserID, err = claimUint(lookupClaim(claims, m.UserID)); err != nil {
+		return nil, fmt.Errorf("claim %s: %w", m.UserID, err)
+	}
+	identity.Role = claimRole(lookupClaim(claims, m.Role))
+	identity.Email, _ = lookupClaim(claims, m.Email).(string)
+	identity.Name, _ = lookupClaim(claims, m.Name).(string)
+	identity.Org = claimString(lookupClaim(claims, m.Org))
+	identity.Sandbox, _ = lookupClaim(claims, m.Sandbox).(bool)
+	if issuedAt, err := claims.GetIssuedAt(); err == nil && issuedAt != nil {
+		identity.IssuedAt = &issuedAt.Time
+	}
+	return identity, nil
+}
+
+// lookupClaim returns the named claim, trying the name as a path into
+// nested claims when no claim has it, or nil
+func lookupClaim(claims map[string]interface{}, name string) interface{} {
+	if name == "" {
+		return nil
+	}
+	if value, ok := claims[name]; ok {
+		return value
+	}
+
+	head, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil
+	}
+	nested, _ := claims[head].(map[string]interface{})
+	return lookupClaim(nested, rest)
+}
+
+// claimUint reads a user ID claim, 0 when it is missing
+func claimUint(value interface{}) (uint, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if v < 0 || v != math.Trunc(v) || v > math.MaxUint32 {
+			return 0, errors.New("not a user ID")
+		}
+		return uint(v), nil
+	case string:
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return 0, errors.New("not a user ID")
+		}
+		return uint(id), nil
+	default:
+		return 0, errors.New("not a user ID")
+	}
+}
+
+// claimRole reads a role claim: a role, or a list of roles of which the
+// first one defined in this CRM is used
+func claimRole(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var roles []string
+		for _, item := range v {
+			if role, ok := item.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+		if i := slices.IndexFunc(roles, models.IsKnownRole); i >= 0 {
+			return roles[i]
+		}
+		if len(roles) > 0 {
+			return roles[0]
+		}
+	}
+	return ""
+}
+
+// claimString reads a claim that may be a string or a number
+func claimString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// jwtError converts a JWT parsing error into the *Error of a provider
+func jwtError(provider string, err error) *Error {
+	reason, message := ReasonInvalid, "Invalid token"
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		reason, message = ReasonExpired, "Token has expired"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		reason, message = ReasonMalformed, "Token is malformed"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		reason, message = ReasonNotYetValid, "Token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		reason, message = ReasonBadSignature, "Token signature is invalid"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		reason, message = ReasonIssuerMismatch, "Token was issued by another issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		reason, message = ReasonAudienceMismatch, "Token is meant for another audience"
+	case errors.Is(err, errUnknownKey):
+		reason, message = ReasonUnknownKey, "Token is signed with an unknown key"
+	case errors.Is(err, errKeysUnavailable):
+		reason, message = ReasonKeysUnavailable, "Signing keys of the issuer could not be fetched"
+	}
+	return &Error{Provider: provider, Reason: reason, Message: message, Err: err}
+}
@@ -0,0 +1,11 @@
+package auth
+
+import "time"
+
+// AllowRefetch lets the next token with an unknown key fetch the key set
+// again without waiting for the retry interval
+func AllowRefetch(a *JWKSAuthenticator) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.triedAt = time.Time{}
+}
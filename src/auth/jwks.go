@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// jwksTimeout bounds one discovery or key set request
+const jwksTimeout = 10 * time.Second
+
+// jwksRetryInterval is how long after a fetch the keys are fetched again
+// for a token signed with an unknown key, e.g. after a key rotation
+const jwksRetryInterval = 30 * time.Second
+
+var (
+	errUnknownKey      = errors.New("unknown signing key")
+	errKeysUnavailable = errors.New("signing keys unavailable")
+)
+
+// JWKSConfig configures a JWKSAuthenticator
+type JWKSConfig struct {
+	Issuer   string        // Expected iss claim; its OpenID configuration locates the keys when URL is empty
+	URL      string        // JSON Web Key Set URL
+	Audience string        // Expected aud claim, unchecked when empty
+	Claims   ClaimsMapping // Claims holding the identity fields
+	Refresh  time.Duration // How long fetched keys are used before they are fetched again
+}
+
+// JWKSAuthenticator verifies RS256, RS384 and RS512 JWTs with the keys an
+// identity provider such as Auth0 or Keycloak publishes as a JSON Web Key
+// Set. Keys are fetched on first use and again once Refresh has passed or
+// a token names an unknown key. Concurrent requests share one fetch, which
+// runs without holding the lock, so tokens signed with known keys are
+// verified while it runs.
+type JWKSAuthenticator struct {
+	cfg     JWKSConfig
+	client  *http.Client
+	fetches singleflight.Group
+	url     string // Key set URL, from cfg.URL or discovery; only used by the fetch in flight
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time // Last successful fetch
+	triedAt   time.Time // Last fetch attempt
+}
+
+// NewJWKSAuthenticator creates a JWKSAuthenticator
+func NewJWKSAuthenticator(cfg JWKSConfig) *JWKSAuthenticator {
+	return &JWKSAuthenticator{
+		cfg:    cfg,
+		client: &http.Client{Timeout: jwksTimeout},
+		url:    cfg.URL,
+	}
+}
+
+// Name returns the provider name
+func (a *JWKSAuthenticator) Name() string {
+	return ProviderJWKS
+}
+
+// Authenticate verifies an RSA-signed JWT against the issuer's keys, and
+// its issuer and audience when configured. JWTs with other algorithms are
+// left to other providers.
+func (a *JWKSAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	unverified, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return nil, jwtError(ProviderJWKS, err)
+	}
+	if _, ok := unverified.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, ErrNotApplicable
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}), jwt.WithExpirationRequired()}
+	if a.cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(a.cfg.Issuer))
+	}
+	if a.cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(a.cfg.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.key(ctx, kid)
+	}, options...); err != nil {
+		return nil, jwtError(ProviderJWKS, err)
+	}
+
+	identity, err := a.cfg.Claims.Identity(claims)
+	if err != nil {
+		return nil, &Error{Provider: ProviderJWKS, Reason: ReasonInvalidClaims, Message: "Token claims cannot be mapped", Err: err}
+	}
+	return identity, nil
+}
+
+// key returns the public key with the given ID, fetching the key set when
+// it is stale or lacks the key. A token without a key ID matches the only
+// key of a set holding one.
+func (a *JWKSAuthenticator) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	a.mu.Lock()
+	now := time.Now()
+	key, known := a.lookup(kid)
+	stale := a.keys == nil || (a.cfg.Refresh > 0 && now.Sub(a.fetchedAt) >= a.cfg.Refresh)
+	refetch := stale || (!known && now.Sub(a.triedAt) >= jwksRetryInterval)
+	if refetch {
+		a.triedAt = now
+	}
+	a.mu.Unlock()
+	if !refetch {
+		return knownKey(key, known)
+	}
+
+	_, err, _ := a.fetches.Do("keys", func() (interface{}, error) {
+		keys, err := a.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		a.keys, a.fetchedAt = keys, time.Now()
+		a.mu.Unlock()
+		return nil, nil
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil && a.keys == nil {
+		// Known keys keep being used while the issuer is unreachable
+		return nil, fmt.Errorf("%w: %v", errKeysUnavailable, err)
+	}
+	return knownKey(a.lookup(kid))
+}
+
+// knownKey returns a looked up key, or errUnknownKey when there is none
+func knownKey(key *rsa.PublicKey, known bool) (*rsa.PublicKey, error) {
+	if !known {
+		return nil, errUnknownKey
+	}
+	return key, nil
+}
+
+// lookup finds a key among the fetched keys
+func (a *JWKSAuthenticator) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, true
+		}
+	}
+	key, ok := a.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey is a key of a JSON Web Key Set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetch downloads the RSA signing keys of the key set, discovering its URL
+// from the issuer's OpenID configuration the first time when needed
+func (a *JWKSAuthenticator) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	if a.url == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(ctx, strings.TrimSuffix(a.cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, fmt.Errorf("discovery: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("discovery: the OpenID configuration has no jwks_uri")
+		}
+		a.url = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.getJSON(ctx, a.url, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := rsaPublicKey(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("the key set has no RSA signing keys")
+	}
+	return keys, nil
+}
+
+// getJSON decodes the JSON document at url into v
+func (a *JWKSAuthenticator) getJSON(ctx context.Context, url string, v interface{}) error {
+	// Keys are fetched for the request that needs them, but outlive it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// rsaPublicKey builds the public key of a JSON Web Key
+func rsaPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,287 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	keysOnce sync.Once
+	keys     [2]*rsa.PrivateKey
+)
+
+// testKey returns one of two RSA keys generated once for the package
+func testKey(t *testing.T, i int) *rsa.PrivateKey {
+	t.Helper()
+	keysOnce.Do(func() {
+		for k := range keys {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				panic(err)
+			}
+			keys[k] = key
+		}
+	})
+	return keys[i]
+}
+
+func rsaToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// issuer is an identity provider publishing its OpenID configuration and
+// key set
+type issuer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	failing  bool
+	block    chan struct{} // When set, key set requests wait for it to close
+	requests atomic.Int32  // Key set requests
+}
+
+func newIssuer(t *testing.T, keys map[string]*rsa.PublicKey) *issuer {
+	t.Helper()
+	i := &issuer{keys: keys}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": i.URL, "jwks_uri": i.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		i.requests.Add(1)
+		i.mu.Lock()
+		block, failing := i.block, i.failing
+		set := make([]map[string]string, 0, len(i.keys))
+		for kid, key := range i.keys {
+			set = append(set, map[string]string{
+				"kty": "RSA", "use": "sig", "kid": kid,
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		i.mu.Unlock()
+		if block != nil {
+			<-block
+		}
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": set})
+	})
+	i.Server = httptest.NewServer(mux)
+	t.Cleanup(i.Close)
+	return i
+}
+
+// publish replaces the published keys
+func (i *issuer) publish(keys map[string]*rsa.PublicKey) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys = keys
+}
+
+func (i *issuer) setFailing(failing bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.failing = failing
+}
+
+func (i *issuer) setBlock(block chan struct{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.block = block
+}
+
+func (i *issuer) authenticator() *auth.JWKSAuthenticator {
+	return auth.NewJWKSAuthenticator(auth.JWKSConfig{Issuer: i.URL, Audience: "crm", Claims: auth.DefaultClaimsMapping})
+}
+
+// claims returns valid claims for the issuer, with the given changes
+func (i *issuer) claims(changes jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{"iss": i.URL, "aud": "crm", "user_id": 7, "role": "manager", "exp": time.Now().Add(time.Hour).Unix()}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestJWKSAuthenticator(t *testing.T) {
+	key, other := testKey(t, 0), testKey(t, 1)
+	i := newIssuer(t, map[string]*rsa.PublicKey{"key-0": &key.PublicKey})
+	a := i.authenticator()
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		reason  string
+		notMine bool
+	}{
+		{name: "valid", token: rsaToken(t, key, "key-0", i.claims(nil))},
+		{name: "audience list", token: rsaToken(t, key, "key-0", i.claims(jwt.MapClaims{"aud": []string{"billing", "crm"}}))},
+		{name: "wrong audience", token: rsaToken(t, key, "key-0", i.claims(jwt.MapClaims{"aud": "billing"})), reason: auth.ReasonAudienceMismatch},
+		{name: "wrong issuer", token: rsaToken(t, key, "key-0", i.claims(jwt.MapClaims{"iss": "https://idp.example.com"})), reason: auth.ReasonIssuerMismatch},
+		{name: "unknown key", token: rsaToken(t, other, "key-1", i.claims(nil)), reason: auth.ReasonUnknownKey},
+		{name: "other key with a known id", token: rsaToken(t, other, "key-0", i.claims(nil)), reason: auth.ReasonBadSignature},
+		{name: "expired", token: rsaToken(t, key, "key-0", i.claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})), reason: auth.ReasonExpired},
+		{name: "no expiry", token: rsaToken(t, key, "key-0", i.claims(jwt.MapClaims{"exp": nil})), reason: auth.ReasonInvalid},
+		{name: "hmac token", token: hmacToken(t, jwt.SigningMethodHS256, secret, i.claims(nil)), notMine: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, err := a.Authenticate(context.Background(), tc.token)
+			switch {
+			case tc.notMine:
+				if err != auth.ErrNotApplicable {
+					t.Errorf("err = %v, want ErrNotApplicable", err)
+				}
+			case tc.reason != "":
+				if reason(err) != tc.reason {
+					t.Errorf("err = %v, want reason %s", err, tc.reason)
+				}
+			case err != nil:
+				t.Fatal(err)
+			default:
+				if identity.UserID != 7 || identity.Role != "manager" {
+					t.Errorf("identity = %+v", identity)
+				}
+			}
+		})
+	}
+	if n := i.requests.Load(); n != 1 {
+		t.Errorf("%d key set requests, want the key set fetched once", n)
+	}
+}
+
+func TestJWKSKeyRotation(t *testing.T) {
+	oldKey, newKey := testKey(t, 0), testKey(t, 1)
+	i := newIssuer(t, map[string]*rsa.PublicKey{"key-0": &oldKey.PublicKey})
+	a := i.authenticator()
+	oldToken := rsaToken(t, oldKey, "key-0", i.claims(nil))
+	newToken := rsaToken(t, newKey, "key-1", i.claims(nil))
+
+	if _, err := a.Authenticate(context.Background(), oldToken); err != nil {
+		t.Fatal(err)
+	}
+	i.publish(map[string]*rsa.PublicKey{"key-1": &newKey.PublicKey})
+
+	// The key set was just fetched, so the new key is not looked up yet
+	if _, err := a.Authenticate(context.Background(), newToken); reason(err) != auth.ReasonUnknownKey {
+		t.Fatalf("new key within the retry interval: err = %v, want %s", err, auth.ReasonUnknownKey)
+	}
+	if n := i.requests.Load(); n != 1 {
+		t.Errorf("%d key set requests, want 1", n)
+	}
+
+	// Once the retry interval has passed the rotated set is fetched
+	auth.AllowRefetch(a)
+	if _, err := a.Authenticate(context.Background(), newToken); err != nil {
+		t.Fatalf("new key after the retry interval: %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), oldToken); reason(err) != auth.ReasonUnknownKey {
+		t.Errorf("retired key: err = %v, want %s", err, auth.ReasonUnknownKey)
+	}
+}
+
+func TestJWKSKeysUnavailable(t *testing.T) {
+	key := testKey(t, 0)
+	i := newIssuer(t, map[string]*rsa.PublicKey{"key-0": &key.PublicKey})
+	i.setFailing(true)
+	a := i.authenticator()
+	token := rsaToken(t, key, "key-0", i.claims(nil))
+
+	if _, err := a.Authenticate(context.Background(), token); reason(err) != auth.ReasonKeysUnavailable {
+		t.Fatalf("err = %v, want %s", err, auth.ReasonKeysUnavailable)
+	}
+
+	// Fetched keys are kept when a later fetch fails
+	i.setFailing(false)
+	auth.AllowRefetch(a)
+	if _, err := a.Authenticate(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+	i.setFailing(true)
+	auth.AllowRefetch(a)
+	if _, err := a.Authenticate(context.Background(), rsaToken(t, testKey(t, 1), "key-1", i.claims(nil))); reason(err) != auth.ReasonUnknownKey {
+		t.Errorf("unknown key while the issuer fails: err = %v, want %s", err, auth.ReasonUnknownKey)
+	}
+	if _, err := a.Authenticate(context.Background(), token); err != nil {
+		t.Errorf("known key while the issuer fails: %v", err)
+	}
+}
+
+func TestJWKSConcurrentFetches(t *testing.T) {
+	key := testKey(t, 0)
+	i := newIssuer(t, map[string]*rsa.PublicKey{"key-0": &key.PublicKey})
+	a := i.authenticator()
+	token := rsaToken(t, key, "key-0", i.claims(nil))
+
+	// Concurrent first requests share one fetch
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := a.Authenticate(context.Background(), token)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := i.requests.Load(); n != 1 {
+		t.Errorf("%d key set requests, want 1", n)
+	}
+
+	// A token with a known key does not wait for a fetch in flight
+	block := make(chan struct{})
+	defer close(block)
+	i.setBlock(block)
+	auth.AllowRefetch(a)
+	go a.Authenticate(context.Background(), rsaToken(t, testKey(t, 1), "key-1", i.claims(nil)))
+	for i.requests.Load() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.Authenticate(context.Background(), token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a token with a known key waited for the key set fetch")
+	}
+}
@@ -0,0 +1,504 @@
+// Package backup writes and restores logical backups of the whole database:
+// a gzipped tar archive holding a manifest and one NDJSON file per table.
+// Rows are copied as PostgreSQL renders them to JSON, so every column,
+// including soft-delete markers and audit hashes, round-trips unchanged.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// JobType is the job type of backups
+const JobType = "backup"
+
+// Archive layout
+const (
+	Format        = "crm-backup"
+	FormatVersion = 1
+	ManifestFile  = "manifest.json"
+	tablesDir     = "tables/"
+)
+
+// restoreBatchSize is the number of rows inserted per statement on restore
+const restoreBatchSize = 500
+
+// resettableTables hold rows the service creates on its own, such as seeded
+// stages and roles or the restoring admin's activity. They do not make a
+// database count as non-empty and are replaced by a restore.
+var resettableTables = []string{"pipeline_stages", "roles", "user_activity", "jobs"}
+
+var (
+	// ErrInvalidArchive is returned for archives that are not complete
+	// backups in a supported format
+	ErrInvalidArchive = errors.New("invalid backup archive")
+
+	// ErrSchemaMismatch is returned when a backup was taken at another
+	// migration version than the target database
+	ErrSchemaMismatch = errors.New("backup schema version does not match the database")
+
+	// ErrNotEmpty is returned when restoring into a database with data
+	// without force
+	ErrNotEmpty = errors.New("database is not empty")
+)
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	Format        string          `json:"format"`
+	FormatVersion int             `json:"format_version"`
+	SchemaVersion int64           `json:"schema_version"` // Migration version; 0 when migrations are not tracked
+	CreatedAt     time.Time       `json:"created_at"`
+	Tables        []TableManifest `json:"tables"` // In dependency order, parents first
+}
+
+// TableManifest describes one table of a backup
+type TableManifest struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	Rows int64  `json:"rows"`
+}
+
+// Export writes a backup archive of every table to w. It has the signature
+// of an export job, so backups run, are stored and expire as job
+// artifacts. It returns the number of rows written.
+func Export(ctx context.Context, db *gorm.DB, _ json.RawMessage, w io.Writer) (int64, error) {
+	var manifest Manifest
+	var spools []*os.File
+	defer func() {
+		for _, spool := range spools {
+			spool.Close()
+			os.Remove(spool.Name())
+		}
+	}()
+
+	// Read every table from one snapshot, so the archive is consistent
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tables, err := Tables(tx)
+		if err != nil {
+			return err
+		}
+		version, err := SchemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		manifest = Manifest{
+			Format:        Format,
+			FormatVersion: FormatVersion,
+			SchemaVersion: version,
+			CreatedAt:     time.Now().UTC(),
+		}
+
+		for _, table := range tables {
+			spool, err := os.CreateTemp("", "crm-backup-*.ndjson")
+			if err != nil {
+				return err
+			}
+			spools = append(spools, spool)
+
+			rows, err := dumpTable(tx, table, spool)
+			if err != nil {
+				return fmt.Errorf("backup of %s: %w", table, err)
+			}
+			manifest.Tables = append(manifest.Tables, TableManifest{Name: table, File: tablesDir + table + ".ndjson", Rows: rows})
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+
+	// The manifest comes first so restores can validate before loading
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	encoded, _ := json.MarshalIndent(manifest, "", "  ")
+	if err := writeEntry(archive, ManifestFile, manifest.CreatedAt, int64(len(encoded)), strings.NewReader(string(encoded))); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for i, table := range manifest.Tables {
+		info, err := spools[i].Stat()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := spools[i].Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		if err := writeEntry(archive, table.File, manifest.CreatedAt, info.Size(), spools[i]); err != nil {
+			return 0, err
+		}
+		total += table.Rows
+	}
+
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+	return total, gz.Close()
+}
+
+// Restore loads a backup archive into db. Unless force is set, the
+// database must not hold data yet beyond what the service seeds itself;
+// with force its current data is replaced. Tables are loaded parents first
+// and ID sequences continue after the restored rows. It returns the
+// archive's manifest.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader, force bool) (Manifest, error) {
+	manifest, spools, err := readArchive(r)
+	defer func() {
+		for _, spool := range spools {
+			spool.Close()
+			os.Remove(spool.Name())
+		}
+	}()
+	if err != nil {
+		return manifest, err
+	}
+
+	db = db.WithContext(ctx)
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return manifest, err
+	}
+	if manifest.SchemaVersion != 0 && version != 0 && manifest.SchemaVersion != version {
+		return manifest, fmt.Errorf("%w: backup is at %d, database at %d", ErrSchemaMismatch, manifest.SchemaVersion, version)
+	}
+
+	tables, err := Tables(db)
+	if err != nil {
+		return manifest, err
+	}
+	for _, table := range manifest.Tables {
+		if !slices.Contains(tables, table.Name) {
+			return manifest, fmt.Errorf("%w: table %s does not exist in the database", ErrSchemaMismatch, table.Name)
+		}
+	}
+
+	if !force {
+		var populated []string
+		for _, table := range tables {
+			if slices.Contains(resettableTables, table) {
+				continue
+			}
+			var exists bool
+			if err := db.Raw("SELECT EXISTS (SELECT 1 FROM " + quoteIdent(table) + ")").Scan(&exists).Error; err != nil {
+				return manifest, err
+			}
+			if exists {
+				populated = append(populated, table)
+			}
+		}
+		if len(populated) > 0 {
+			return manifest, fmt.Errorf("%w: %s hold data", ErrNotEmpty, strings.Join(populated, ", "))
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// The backup carries its own audit history, hashes included. The
+		// append-only audit log is only emptied through its maintenance role.
+		quoted := make([]string, 0, len(tables))
+		for _, table := range tables {
+			if table == "audit_logs" {
+				if err := models.TruncateAuditLogs(tx); err != nil {
+					return err
+				}
+				continue
+			}
+			quoted = append(quoted, quoteIdent(table))
+		}
+		if err := tx.Exec("TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+			return err
+		}
+
+		files := make(map[string]string, len(manifest.Tables))
+		for _, table := range manifest.Tables {
+			files[table.Name] = table.File
+		}
+
+		// Load in the target's dependency order
+		for _, table := range tables {
+			spool, ok := spools[files[table]]
+			if !ok {
+				continue
+			}
+			if err := loadTable(tx, table, spool); err != nil {
+				return fmt.Errorf("restore of %s: %w", table, err)
+			}
+			if err := resetSequence(tx, table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return manifest, err
+}
+
+// Tables returns the tables of the current schema, except the migration
+// tool's own, ordered so every table comes after the tables it references
+func Tables(db *gorm.DB) ([]string, error) {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return nil, err
+	}
+	tables = slices.DeleteFunc(tables, func(table string) bool { return table == "schema_migrations" })
+	sort.Strings(tables)
+
+	var references []struct {
+		Child  string
+		Parent string
+	}
+	if err := db.Raw(`SELECT child.relname AS child, parent.relname AS parent
+		FROM pg_constraint c
+		JOIN pg_class child ON child.oid = c.conrelid
+		JOIN pg_class parent ON parent.oid = c.confrelid
+		JOIN pg_namespace n ON n.oid = c.connamespace
+		WHERE c.contype = 'f' AND n.nspname = current_schema()`).Scan(&references).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[string][]string)
+	for _, ref := range references {
+		if ref.Child != ref.Parent {
+			parents[ref.Child] = append(parents[ref.Child], ref.Parent)
+		}
+	}
+
+	// Depth-first topological sort; a reference cycle is broken where it
+	// is found, leaving that table in name order
+	ordered := make([]string, 0, len(tables))
+	state := make(map[string]int) // 1 visiting, 2 done
+	var visit func(table string)
+	visit = func(table string) {
+		if state[table] != 0 {
+			return
+		}
+		state[table] = 1
+		for _, parent := range parents[table] {
+			if slices.Contains(tables, parent) {
+				visit(parent)
+			}
+		}
+		state[table] = 2
+		ordered = append(ordered, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered, nil
+}
+
+// SchemaVersion returns the migration version recorded by golang-migrate,
+// or 0 when the database was not set up through migrations
+func SchemaVersion(db *gorm.DB) (int64, error) {
+	if !db.Migrator().HasTable("schema_migrations") {
+		return 0, nil
+	}
+	var versions []int64
+	if err := db.Raw("SELECT version FROM schema_migrations LIMIT 1").Scan(&versions).Error; err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	return versions[0], nil
+}
+
+// dumpTable writes every row of a table to w as NDJSON, in primary key
+// order when the table has an id, so self-references restore in order
+func dumpTable(tx *gorm.DB, table string, w io.Writer) (int64, error) {
+	statement := "SELECT row_to_json(t)::text FROM " + quoteIdent(table) + " t"
+	if tx.Migrator().HasColumn(table, "id") {
+		statement += " ORDER BY t.id"
+	}
+	rows, err := tx.Raw(statement).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	buffered := bufio.NewWriter(w)
+	var count int64
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return count, err
+		}
+		buffered.WriteString(line)
+		buffered.WriteByte('\n')
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	return count, buffered.Flush()
+}
+
+// loadTable inserts the NDJSON rows of a spooled table file in batches.
+// Generated columns are left out and recomputed from the restored values.
+func loadTable(tx *gorm.DB, table string, spool *os.File) error {
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	columns, err := insertableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	list := strings.Join(columns, ", ")
+	statement := "INSERT INTO " + quoteIdent(table) + " (" + list + ") SELECT " + list + " FROM json_populate_recordset(NULL::" + quoteIdent(table) + ", ?::json)"
+
+	scanner := bufio.NewScanner(spool)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	batch := make([]string, 0, restoreBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := tx.Exec(statement, "["+strings.Join(batch, ",")+"]").Error
+		batch = batch[:0]
+		return err
+	}
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			batch = append(batch, line)
+		}
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// insertableColumns returns the quoted columns of a table in order, except
+// generated columns, which cannot be inserted into
+func insertableColumns(tx *gorm.DB, table string) ([]string, error) {
+	var names []string
+	if err := tx.Raw(`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND is_generated = 'NEVER'
+		ORDER BY ordinal_position`, table).Scan(&names).Error; err != nil {
+		return nil, err
+	}
+	columns := make([]string, len(names))
+	for i, name := range names {
+		columns[i] = quoteIdent(name)
+	}
+	return columns, nil
+}
+
+// resetSequence moves a table's id sequence past the restored rows
+func resetSequence(tx *gorm.DB, table string) error {
+	if !tx.Migrator().HasColumn(table, "id") {
+		return nil
+	}
+	return tx.Exec("SELECT setval(seq, max_id) FROM (SELECT pg_get_serial_sequence(?, 'id') AS seq, MAX(id) AS max_id FROM "+quoteIdent(table)+") s WHERE seq IS NOT NULL AND max_id IS NOT NULL",
+		quoteIdent(table)).Error
+}
+
+// readArchive reads a backup archive, spooling each table file to disk
+// keyed by its name in the archive, and checks it against its manifest
+func readArchive(r io.Reader) (Manifest, map[string]*os.File, error) {
+	var manifest Manifest
+	spools := make(map[string]*os.File)
+	rows := make(map[string]int64)
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, spools, fmt.Errorf("%w: not a gzip file", ErrInvalidArchive)
+	}
+	archive := tar.NewReader(gz)
+	seenManifest := false
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, spools, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+
+		switch {
+		case header.Name == ManifestFile:
+			if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+				return manifest, spools, fmt.Errorf("%w: unreadable manifest", ErrInvalidArchive)
+			}
+			seenManifest = true
+		case strings.HasPrefix(header.Name, tablesDir) && strings.HasSuffix(header.Name, ".ndjson"):
+			spool, err := os.CreateTemp("", "crm-restore-*.ndjson")
+			if err != nil {
+				return manifest, spools, err
+			}
+			spools[header.Name] = spool
+			counter := &lineCounter{}
+			if _, err := io.Copy(io.MultiWriter(spool, counter), archive); err != nil {
+				return manifest, spools, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			rows[header.Name] = counter.lines
+		}
+	}
+
+	if !seenManifest {
+		return manifest, spools, fmt.Errorf("%w: missing %s", ErrInvalidArchive, ManifestFile)
+	}
+	if manifest.Format != Format || manifest.FormatVersion != FormatVersion {
+		return manifest, spools, fmt.Errorf("%w: unsupported format %s version %d", ErrInvalidArchive, manifest.Format, manifest.FormatVersion)
+	}
+
+	for _, table := range manifest.Tables {
+		if _, ok := spools[table.File]; !ok {
+			return manifest, spools, fmt.Errorf("%w: missing %s", ErrInvalidArchive, table.File)
+		}
+		if rows[table.File] != table.Rows {
+			return manifest, spools, fmt.Errorf("%w: %s has %d rows, the manifest lists %d", ErrInvalidArchive, table.File, rows[table.File], table.Rows)
+		}
+	}
+	return manifest, spools, nil
+}
+
+// writeEntry adds a file to a tar archive
+func writeEntry(archive *tar.Writer, name string, modTime time.Time, size int64, r io.Reader) error {
+	if err := archive.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := io.Copy(archive, r)
+	return err
+}
+
+// quoteIdent quotes a table name for use in SQL
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// lineCounter counts the newline-terminated lines written to it
+type lineCounter struct {
+	lines int64
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '\n' {
+			c.lines++
+		}
+	}
+	return len(p), nil
+}
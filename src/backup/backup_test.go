@@ -0,0 +1,107 @@
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/backup"
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// counts returns the number of rows of every table
+func counts(t *testing.T, db *gorm.DB) map[string]int64 {
+	t.Helper()
+	tables, err := backup.Tables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		var n int64
+		if err := db.Raw(`SELECT COUNT(*) FROM "` + table + `"`).Scan(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		rows[table] = n
+	}
+	return rows
+}
+
+func compareCounts(t *testing.T, got, want map[string]int64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%d tables, want %d", len(got), len(want))
+	}
+	for table, n := range want {
+		if got[table] != n {
+			t.Errorf("%s: %d rows, want %d", table, got[table], n)
+		}
+	}
+}
+
+// seed creates related records of every kind the factory builds, with
+// their audit entries
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	f := factory.New(db)
+	tag := f.Tag(t)
+	for i := 0; i < 3; i++ {
+		customer := f.Customer(t)
+		f.Contact(t, customer)
+		f.Deal(t, customer)
+		f.Activity(t, customer)
+		f.Note(t, customer)
+		f.TagCustomer(t, customer, tag)
+		err := db.Create(&models.AuditLog{ResourceType: "customer", ResourceID: customer.ID,
+			Action: models.AuditActionCreate, UserID: 1, NewValues: `{"name":"` + customer.Name + `"}`}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := testdb.Open(t)
+	seed(t, source)
+	want := counts(t, source)
+
+	var archive bytes.Buffer
+	if _, err := backup.Export(ctx, source, nil, &archive); err != nil {
+		t.Fatal(err)
+	}
+
+	// Into an empty database
+	target := testdb.Open(t)
+	if _, err := backup.Restore(ctx, target, bytes.NewReader(archive.Bytes()), false); err != nil {
+		t.Fatal(err)
+	}
+	compareCounts(t, counts(t, target), want)
+
+	// The restored audit chain verifies and continues
+	if result, err := audittrail.Verify(ctx, target, nil, nil); err != nil || !result.Valid || result.Checked != 3 {
+		t.Errorf("restored audit log: %+v, %v", result, err)
+	}
+	customer := factory.New(target).Customer(t, func(c *models.Customer) { c.Email = "after-restore@example.com" })
+	if customer.ID != 4 {
+		t.Errorf("customer created after the restore got ID %d, want 4", customer.ID)
+	}
+
+	// A populated database needs force, which replaces its data
+	_, err := backup.Restore(ctx, target, bytes.NewReader(archive.Bytes()), false)
+	if !errors.Is(err, backup.ErrNotEmpty) {
+		t.Fatalf("restore over data: %v, want ErrNotEmpty", err)
+	}
+	if _, err := backup.Restore(ctx, target, bytes.NewReader(archive.Bytes()), true); err != nil {
+		t.Fatal(err)
+	}
+	compareCounts(t, counts(t, target), want)
+	if result, err := audittrail.Verify(ctx, target, nil, nil); err != nil || !result.Valid {
+		t.Errorf("audit log after the forced restore: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,119 @@
+// Package buildinfo describes the running build. Version, Commit and
+// BuildTime are set at link time:
+//
+//	go build -ldflags "-X github.com/SalehAlobaylan/CRM-Service/src/buildinfo.Version=1.4.0 \
+//	  -X github.com/SalehAlobaylan/CRM-Service/src/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/SalehAlobaylan/CRM-Service/src/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+//
+// Builds without them fall back to the VCS details the Go toolchain embeds.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Set by -ldflags -X
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = "" // RFC 3339
+)
+
+// trackedDependencies are the modules reported with the build, the ones
+// most often behind behavior changes between deploys
+var trackedDependencies = []string{
+	"github.com/gin-gonic/gin",
+	"gorm.io/gorm",
+	"gorm.io/driver/postgres",
+	"github.com/jackc/pgx/v5",
+	"github.com/prometheus/client_golang",
+	"go.uber.org/zap",
+}
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a working tree with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// Dependency is a module linked into the build
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"` // Module path replacing it, when replaced
+}
+
+// buildInfo is the build as read at startup
+var buildInfo = read()
+
+// buildInfoGauge is always 1, labelled with the build, so dashboards can
+// annotate deploys where the labels change
+var buildInfoGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "crm_build_info",
+	Help: "Build of the running service; the value is always 1.",
+}, []string{"version", "commit", "go_version"})
+
+func init() {
+	prometheus.MustRegister(buildInfoGauge)
+	buildInfoGauge.WithLabelValues(buildInfo.Version, buildInfo.Commit, buildInfo.GoVersion).Set(1)
+}
+
+// Get returns the running build
+func Get() Info {
+	return buildInfo
+}
+
+// read combines the link-time variables with the VCS details embedded by
+// the Go toolchain, preferring the former
+func read() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// Dependencies returns the versions of the tracked modules linked into the
+// build, in trackedDependencies order
+func Dependencies() []Dependency {
+	deps := []Dependency{}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return deps
+	}
+	for _, path := range trackedDependencies {
+		for _, module := range build.Deps {
+			if module.Path != path {
+				continue
+			}
+			dep := Dependency{Path: module.Path, Version: module.Version}
+			if module.Replace != nil {
+				dep.Replace = module.Replace.Path
+				dep.Version = module.Replace.Version
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
@@ -0,0 +1,184 @@
+// Package businesstime does business-day and business-hour arithmetic over a
+// configurable workweek, working hours and holiday calendar.
+package businesstime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// Embed the time zone database so the calendar location always loads
+	_ "time/tzdata"
+)
+
+// dateLayout formats the calendar date used to look up holidays
+const dateLayout = "2006-01-02"
+
+// ErrNoWorkdays is returned when a calendar would have no business days
+var ErrNoWorkdays = errors.New("calendar needs at least one workday")
+
+// weekdayNames maps the accepted workday names to weekdays
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Calendar describes the workweek, daily working hours and holidays of one
+// region. Dates are evaluated in the calendar's location. A Calendar is
+// immutable and safe for concurrent use.
+type Calendar struct {
+	workdays [7]bool
+	start    int // minutes after midnight
+	end      int // minutes after midnight
+	location *time.Location
+	holidays map[string]bool
+}
+
+// New creates a calendar working on workdays from start to end (minutes
+// after midnight) in location, without holidays
+func New(workdays []time.Weekday, start, end int, location *time.Location) (*Calendar, error) {
+	if len(workdays) == 0 {
+		return nil, ErrNoWorkdays
+	}
+	if start < 0 || end > 24*60 || start >= end {
+		return nil, fmt.Errorf("invalid working hours %d-%d", start, end)
+	}
+
+	c := &Calendar{start: start, end: end, location: location, holidays: map[string]bool{}}
+	for _, day := range workdays {
+		c.workdays[day] = true
+	}
+	return c, nil
+}
+
+// WithHolidays returns a copy of c that also treats dates as non-business
+// days. Only the calendar date of each value is used, not its location.
+func (c *Calendar) WithHolidays(dates []time.Time) *Calendar {
+	copied := *c
+	copied.holidays = make(map[string]bool, len(c.holidays)+len(dates))
+	for date := range c.holidays {
+		copied.holidays[date] = true
+	}
+	for _, date := range dates {
+		copied.holidays[date.Format(dateLayout)] = true
+	}
+	return &copied
+}
+
+// Location returns the time zone the calendar evaluates dates in
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+// IsBusinessDay reports whether t falls on a workday that is not a holiday
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	local := t.In(c.location)
+	return c.workdays[local.Weekday()] && !c.holidays[local.Format(dateLayout)]
+}
+
+// AddBusinessDays moves t by n business days, keeping its clock time.
+// Negative n moves backwards; zero returns t unchanged even on a non-business
+// day.
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+
+	local := t.In(c.location)
+	for n > 0 {
+		local = local.AddDate(0, 0, step)
+		if c.IsBusinessDay(local) {
+			n--
+		}
+	}
+	return local
+}
+
+// BusinessHoursBetween returns the working time between from and to, counting
+// only working hours on business days. It is zero when to is not after from.
+func (c *Calendar) BusinessHoursBetween(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+
+	var total time.Duration
+	from, to = from.In(c.location), to.In(c.location)
+	year, month, day := from.Date()
+	for date := time.Date(year, month, day, 0, 0, 0, 0, c.location); date.Before(to); date = date.AddDate(0, 0, 1) {
+		if !c.IsBusinessDay(date) {
+			continue
+		}
+
+		y, m, d := date.Date()
+		open := time.Date(y, m, d, 0, c.start, 0, 0, c.location)
+		closed := time.Date(y, m, d, 0, c.end, 0, 0, c.location)
+		if open.Before(from) {
+			open = from
+		}
+		if closed.After(to) {
+			closed = to
+		}
+		if closed.After(open) {
+			total += closed.Sub(open)
+		}
+	}
+	return total
+}
+
+// ParseWorkdays parses a comma-separated list of weekday abbreviations such
+// as "sun,mon,tue,wed,thu"
+func ParseWorkdays(value string) ([]time.Weekday, error) {
+	var workdays []time.Weekday
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		day, ok := weekdayNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown workday %q", name)
+		}
+		workdays = append(workdays, day)
+	}
+	if len(workdays) == 0 {
+		return nil, ErrNoWorkdays
+	}
+	return workdays, nil
+}
+
+// ParseHours parses working hours such as "09:00-17:00" into minutes after
+// midnight
+func ParseHours(value string) (int, int, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid working hours %q", value)
+	}
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// parseClock parses HH:MM into minutes after midnight; 24:00 is allowed
+func parseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
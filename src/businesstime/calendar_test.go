@@ -0,0 +1,185 @@
+package businesstime_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+)
+
+var riyadh, _ = time.LoadLocation("Asia/Riyadh")
+
+// at is a local time in Riyadh
+func at(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, riyadh)
+}
+
+// saudiCalendar works Sunday to Thursday, 09:00-17:00 in Riyadh, with New
+// Year's Day 2025 and Eid al-Fitr 2025 (Sunday 30 March to Wednesday 2 April)
+// as holidays
+func saudiCalendar(t *testing.T) *businesstime.Calendar {
+	t.Helper()
+	workdays, err := businesstime.ParseWorkdays("sun,mon,tue,wed,thu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end, err := businesstime.ParseHours("09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar, err := businesstime.New(workdays, start, end, riyadh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var holidays []time.Time
+	for day := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC); !day.After(time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC)); day = day.AddDate(0, 0, 1) {
+		holidays = append(holidays, day)
+	}
+	return calendar.WithHolidays(append(holidays, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	calendar := saudiCalendar(t)
+	for _, tc := range []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"thursday", at(2025, 1, 30, 12), true},
+		{"friday", at(2025, 1, 31, 12), false},
+		{"saturday", at(2025, 2, 1, 12), false},
+		{"sunday", at(2025, 2, 2, 12), true},
+		{"new year's eve", at(2024, 12, 31, 12), true},
+		{"new year's day", at(2025, 1, 1, 12), false},
+		{"last day of eid, in april", at(2025, 4, 2, 12), false},
+		{"after eid", at(2025, 4, 3, 12), true},
+		// 22:00 UTC on a Thursday is already Friday in Riyadh
+		{"thursday night in UTC", time.Date(2025, 1, 30, 22, 0, 0, 0, time.UTC), false},
+		{"new year's eve night in UTC", time.Date(2024, 12, 31, 22, 0, 0, 0, time.UTC), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := calendar.IsBusinessDay(tc.t); got != tc.want {
+				t.Errorf("IsBusinessDay(%v) = %v, want %v", tc.t, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddBusinessDays(t *testing.T) {
+	calendar := saudiCalendar(t)
+	for _, tc := range []struct {
+		name string
+		from time.Time
+		n    int
+		want time.Time
+	}{
+		{"over the weekend into february", at(2025, 1, 30, 10), 1, at(2025, 2, 2, 10)},
+		{"from a friday", at(2025, 1, 31, 10), 1, at(2025, 2, 2, 10)},
+		{"zero on a friday", at(2025, 1, 31, 10), 0, at(2025, 1, 31, 10)},
+		{"over new year's day", at(2024, 12, 31, 15), 1, at(2025, 1, 2, 15)},
+		{"over new year's day and the weekend", at(2024, 12, 31, 15), 2, at(2025, 1, 5, 15)},
+		{"over eid into april", at(2025, 3, 27, 9), 1, at(2025, 4, 3, 9)},
+		{"a week over eid", at(2025, 3, 27, 9), 5, at(2025, 4, 9, 9)},
+		{"back over the weekend into january", at(2025, 2, 2, 10), -1, at(2025, 1, 30, 10)},
+		{"back over new year's day", at(2025, 1, 5, 15), -2, at(2024, 12, 31, 15)},
+		{"back over eid into march", at(2025, 4, 3, 9), -1, at(2025, 3, 27, 9)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := calendar.AddBusinessDays(tc.from, tc.n); !got.Equal(tc.want) {
+				t.Errorf("AddBusinessDays(%v, %d) = %v, want %v", tc.from, tc.n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBusinessHoursBetween(t *testing.T) {
+	calendar := saudiCalendar(t)
+	for _, tc := range []struct {
+		name     string
+		from, to time.Time
+		want     time.Duration
+	}{
+		{"within a day", at(2025, 1, 29, 10), at(2025, 1, 29, 13), 3 * time.Hour},
+		{"outside working hours", at(2025, 1, 29, 17), at(2025, 1, 30, 9), 0},
+		{"over the weekend into february", at(2025, 1, 30, 16), at(2025, 2, 2, 10), 2 * time.Hour},
+		{"the weekend alone", at(2025, 1, 31, 0), at(2025, 2, 2, 0), 0},
+		{"over new year's day", at(2024, 12, 31, 15), at(2025, 1, 2, 11), 4 * time.Hour},
+		{"over eid into april", at(2025, 3, 27, 9), at(2025, 4, 3, 17), 16 * time.Hour},
+		{"reversed", at(2025, 1, 30, 16), at(2025, 1, 29, 10), 0},
+		// 06:00 to 14:00 UTC is 09:00 to 17:00 in Riyadh
+		{"given in UTC", time.Date(2025, 1, 29, 6, 0, 0, 0, time.UTC), time.Date(2025, 1, 29, 14, 0, 0, 0, time.UTC), 8 * time.Hour},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := calendar.BusinessHoursBetween(tc.from, tc.to); got != tc.want {
+				t.Errorf("BusinessHoursBetween(%v, %v) = %v, want %v", tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServiceRegionHolidays(t *testing.T) {
+	testDB := testdb.New(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	for _, holiday := range []models.Holiday{
+		{Date: time.Date(2025, 9, 23, 0, 0, 0, 0, time.UTC), Name: "Saudi National Day"},
+		{Date: time.Date(2025, 12, 2, 0, 0, 0, 0, time.UTC), Region: "ae", Name: "UAE National Day"},
+	} {
+		if err := testDB.DB.Create(&holiday).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	base, err := businesstime.New([]time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday}, 9*60, 17*60, riyadh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := businesstime.NewService(testDB.DB, base, "sa")
+	if err := service.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both holidays fall on a Tuesday. A holiday without a region applies
+	// everywhere, and a region's holiday only to that region.
+	for _, tc := range []struct {
+		region              string
+		september, december time.Time
+	}{
+		{"", at(2025, 9, 24, 12), at(2025, 12, 2, 12)},
+		{"sa", at(2025, 9, 24, 12), at(2025, 12, 2, 12)},
+		{"ae", at(2025, 9, 24, 12), at(2025, 12, 3, 12)},
+	} {
+		calendar := service.Calendar(tc.region)
+		if got := calendar.AddBusinessDays(at(2025, 9, 22, 12), 1); !got.Equal(tc.september) {
+			t.Errorf("%q: a day after 22 September = %v, want %v", tc.region, got, tc.september)
+		}
+		if got := calendar.AddBusinessDays(at(2025, 12, 1, 12), 1); !got.Equal(tc.december) {
+			t.Errorf("%q: a day after 1 December = %v, want %v", tc.region, got, tc.december)
+		}
+	}
+}
+
+func TestParseWorkdaysAndHours(t *testing.T) {
+	workdays, err := businesstime.ParseWorkdays(" Sun, mon,TUE,wed , thu,")
+	if err != nil || len(workdays) != 5 || workdays[0] != time.Sunday || workdays[4] != time.Thursday {
+		t.Errorf("ParseWorkdays = %v, %v", workdays, err)
+	}
+	for _, value := range []string{"", "sun,funday"} {
+		if _, err := businesstime.ParseWorkdays(value); err == nil {
+			t.Errorf("ParseWorkdays(%q) accepted", value)
+		}
+	}
+
+	start, end, err := businesstime.ParseHours("08:30-24:00")
+	if err != nil || start != 8*60+30 || end != 24*60 {
+		t.Errorf("ParseHours = %d, %d, %v", start, end, err)
+	}
+	for _, value := range []string{"09:00", "9-5", "09:00-25:00"} {
+		if _, _, err := businesstime.ParseHours(value); err == nil {
+			t.Errorf("ParseHours(%q) accepted", value)
+		}
+	}
+	if _, err := businesstime.New(workdays, 17*60, 9*60, riyadh); err == nil {
+		t.Error("New accepted working hours ending before they start")
+	}
+}
@@ -0,0 +1,89 @@
+package businesstime
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FiscalYearStart is the month the fiscal year starts in. Fiscal quarters
+// are the three-month periods from it.
+var FiscalYearStart = time.January
+
+// Range is a date range resolved from a relative token. To is the last
+// instant of the range, so it can be used with inclusive comparisons such
+// as BETWEEN; it is one microsecond, the database's precision, before the
+// midnight that ends the range.
+type Range struct {
+	Token string
+	From  time.Time
+	To    time.Time
+}
+
+// rangeTokens resolves each relative token given the start of today
+var rangeTokens = map[string]func(today time.Time) (time.Time, time.Time){
+	"today":                   func(t time.Time) (time.Time, time.Time) { return t, t.AddDate(0, 0, 1) },
+	"yesterday":               func(t time.Time) (time.Time, time.Time) { return t.AddDate(0, 0, -1), t },
+	"last_7_days":             lastDays(7),
+	"last_30_days":            lastDays(30),
+	"last_90_days":            lastDays(90),
+	"this_month":              period(time.January, 1, 0),
+	"previous_month":          period(time.January, 1, -1),
+	"this_quarter":            period(time.January, 3, 0),
+	"previous_quarter":        period(time.January, 3, -1),
+	"this_year":               period(time.January, 12, 0),
+	"previous_year":           period(time.January, 12, -1),
+	"this_fiscal_quarter":     fiscalPeriod(3, 0),
+	"previous_fiscal_quarter": fiscalPeriod(3, -1),
+	"this_fiscal_year":        fiscalPeriod(12, 0),
+	"previous_fiscal_year":    fiscalPeriod(12, -1),
+}
+
+// RangeTokens returns the relative date tokens ResolveRange accepts, sorted
+func RangeTokens() []string {
+	tokens := make([]string, 0, len(rangeTokens))
+	for token := range rangeTokens {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// ResolveRange resolves a relative date token such as "this_month" or
+// "previous_fiscal_year" to the range it names at now, with days starting
+// at midnight in location. The last N days include today.
+func ResolveRange(token string, now time.Time, location *time.Location) (Range, error) {
+	resolve, ok := rangeTokens[token]
+	if !ok {
+		return Range{}, fmt.Errorf("unknown date range %q", token)
+	}
+	year, month, day := now.In(location).Date()
+	from, end := resolve(time.Date(year, month, day, 0, 0, 0, 0, location))
+	return Range{Token: token, From: from, To: end.Add(-time.Microsecond)}, nil
+}
+
+// lastDays resolves the n days ending with today
+func lastDays(n int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		return today.AddDate(0, 0, 1-n), today.AddDate(0, 0, 1)
+	}
+}
+
+// period resolves the period of months months, counted from the start
+// month of the year, that contains today, shifted by offset periods
+func period(start time.Month, months, offset int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		year, month, _ := today.Date()
+		elapsed := (int(month) - int(start) + 12) % 12 % months
+		from := time.Date(year, month-time.Month(elapsed), 1, 0, 0, 0, 0, today.Location()).AddDate(0, offset*months, 0)
+		return from, from.AddDate(0, months, 0)
+	}
+}
+
+// fiscalPeriod is period counted from FiscalYearStart, read when resolving
+// so it follows the configured fiscal year
+func fiscalPeriod(months, offset int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		return period(FiscalYearStart, months, offset)(today)
+	}
+}
@@ -0,0 +1,119 @@
+package businesstime_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
+)
+
+// withFiscalYearStart sets the fiscal year start for the test
+func withFiscalYearStart(t *testing.T, month time.Month) {
+	t.Helper()
+	previous := businesstime.FiscalYearStart
+	businesstime.FiscalYearStart = month
+	t.Cleanup(func() { businesstime.FiscalYearStart = previous })
+}
+
+func TestResolveRange(t *testing.T) {
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := map[string]*time.Location{"UTC": time.UTC, "Riyadh": riyadh, "Los Angeles": losAngeles}
+	utc := func(value string) time.Time {
+		now, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return now
+	}
+
+	for _, tc := range []struct {
+		name     string
+		now      string
+		zone     string
+		fiscal   time.Month
+		token    string
+		from, to string // First and last local day of the range
+	}{
+		// Midnight starting 1 February in Riyadh is still 31 January in UTC
+		{"riyadh at midnight into february", "2025-01-31T21:00:00Z", "Riyadh", time.January, "this_month", "2025-02-01", "2025-02-28"},
+		{"utc at that instant", "2025-01-31T21:00:00Z", "UTC", time.January, "this_month", "2025-01-01", "2025-01-31"},
+		{"riyadh just before midnight", "2025-01-31T20:59:59.999999Z", "Riyadh", time.January, "this_month", "2025-01-01", "2025-01-31"},
+		{"riyadh today at midnight", "2025-01-31T21:00:00Z", "Riyadh", time.January, "today", "2025-02-01", "2025-02-01"},
+		{"riyadh yesterday at midnight", "2025-01-31T21:00:00Z", "Riyadh", time.January, "yesterday", "2025-01-31", "2025-01-31"},
+		{"previous month at midnight into march", "2025-03-01T00:00:00Z", "UTC", time.January, "previous_month", "2025-02-01", "2025-02-28"},
+		{"los angeles still in february", "2025-03-01T00:00:00Z", "Los Angeles", time.January, "this_month", "2025-02-01", "2025-02-28"},
+		{"previous month in a leap year", "2024-03-01T00:00:00Z", "UTC", time.January, "previous_month", "2024-02-01", "2024-02-29"},
+
+		// New year in Riyadh, still the old year in UTC
+		{"riyadh at midnight into the year", "2024-12-31T21:00:00Z", "Riyadh", time.January, "this_year", "2025-01-01", "2025-12-31"},
+		{"utc at that instant, year", "2024-12-31T21:00:00Z", "UTC", time.January, "this_year", "2024-01-01", "2024-12-31"},
+		{"previous quarter over the year", "2024-12-31T21:00:00Z", "Riyadh", time.January, "previous_quarter", "2024-10-01", "2024-12-31"},
+		{"previous year", "2025-06-15T12:00:00Z", "UTC", time.January, "previous_year", "2024-01-01", "2024-12-31"},
+
+		// The last N days include today, over a daylight saving change
+		{"last 7 days over DST", "2025-03-12T19:00:00Z", "Los Angeles", time.January, "last_7_days", "2025-03-06", "2025-03-12"},
+		{"last 30 days over the month", "2025-03-05T12:00:00Z", "Riyadh", time.January, "last_30_days", "2025-02-04", "2025-03-05"},
+		{"last 90 days over the year", "2025-01-15T12:00:00Z", "UTC", time.January, "last_90_days", "2024-10-18", "2025-01-15"},
+
+		// A fiscal year starting in January is the calendar year
+		{"january fiscal quarter", "2025-05-20T12:00:00Z", "UTC", time.January, "this_fiscal_quarter", "2025-04-01", "2025-06-30"},
+		{"january fiscal year", "2025-05-20T12:00:00Z", "UTC", time.January, "this_fiscal_year", "2025-01-01", "2025-12-31"},
+
+		// A fiscal year starting in April spans two calendar years
+		{"april fiscal year", "2025-02-15T12:00:00Z", "Riyadh", time.April, "this_fiscal_year", "2024-04-01", "2025-03-31"},
+		{"april previous fiscal year", "2025-02-15T12:00:00Z", "Riyadh", time.April, "previous_fiscal_year", "2023-04-01", "2024-03-31"},
+		{"april fiscal quarter", "2025-02-15T12:00:00Z", "Riyadh", time.April, "this_fiscal_quarter", "2025-01-01", "2025-03-31"},
+		{"april previous fiscal quarter", "2025-02-15T12:00:00Z", "Riyadh", time.April, "previous_fiscal_quarter", "2024-10-01", "2024-12-31"},
+		{"april fiscal year on its first day", "2025-04-01T00:00:00Z", "UTC", time.April, "this_fiscal_year", "2025-04-01", "2026-03-31"},
+		{"calendar quarter ignores the fiscal year", "2025-02-15T12:00:00Z", "UTC", time.April, "this_quarter", "2025-01-01", "2025-03-31"},
+
+		// The fiscal year starts at midnight in the requester's time zone
+		{"october fiscal year at midnight in riyadh", "2025-09-30T21:00:00Z", "Riyadh", time.October, "this_fiscal_year", "2025-10-01", "2026-09-30"},
+		{"october fiscal year at that instant in utc", "2025-09-30T21:00:00Z", "UTC", time.October, "this_fiscal_year", "2024-10-01", "2025-09-30"},
+		{"october previous fiscal quarter in riyadh", "2025-09-30T21:00:00Z", "Riyadh", time.October, "previous_fiscal_quarter", "2025-07-01", "2025-09-30"},
+		{"july previous fiscal quarter", "2025-07-01T00:00:00Z", "UTC", time.July, "previous_fiscal_quarter", "2025-04-01", "2025-06-30"},
+		{"july fiscal year in los angeles", "2025-07-01T00:00:00Z", "Los Angeles", time.July, "this_fiscal_year", "2024-07-01", "2025-06-30"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			withFiscalYearStart(t, tc.fiscal)
+			location := zones[tc.zone]
+			got, err := businesstime.ResolveRange(tc.token, utc(tc.now), location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			day := func(value string) time.Time {
+				d, err := time.ParseInLocation(time.DateOnly, value, location)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return d
+			}
+			from, to := day(tc.from), day(tc.to).AddDate(0, 0, 1).Add(-time.Microsecond)
+			if got.Token != tc.token || !got.From.Equal(from) || !got.To.Equal(to) {
+				t.Errorf("%s = %s from %v to %v, want from %v to %v", tc.token, got.Token, got.From, got.To, from, to)
+			}
+		})
+	}
+}
+
+func TestRangeTokens(t *testing.T) {
+	tokens := businesstime.RangeTokens()
+	if !slices.IsSorted(tokens) || !slices.Contains(tokens, "previous_fiscal_year") {
+		t.Errorf("tokens = %v", tokens)
+	}
+	// Every token resolves to a non-empty range ending after it starts
+	now := time.Date(2025, 1, 31, 21, 0, 0, 0, time.UTC)
+	for _, token := range tokens {
+		got, err := businesstime.ResolveRange(token, now, riyadh)
+		if err != nil || !got.To.After(got.From) {
+			t.Errorf("%s = %+v, %v", token, got, err)
+		}
+	}
+	if _, err := businesstime.ResolveRange("next_month", now, riyadh); err == nil {
+		t.Error("unknown token resolved")
+	}
+}
@@ -0,0 +1,81 @@
+package businesstime
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Service serves region calendars built from a base calendar and the
+// holidays stored in the database
+type Service struct {
+	db            *gorm.DB
+	base          *Calendar
+	defaultRegion string
+
+	mu        sync.RWMutex
+	holidays  []models.Holiday
+	calendars map[string]*Calendar
+}
+
+// NewService creates a Service. Call Reload to load holidays.
+func NewService(db *gorm.DB, base *Calendar, defaultRegion string) *Service {
+	return &Service{
+		db:            db,
+		base:          base,
+		defaultRegion: defaultRegion,
+		calendars:     make(map[string]*Calendar),
+	}
+}
+
+// DefaultRegion returns the region used when none is given
+func (s *Service) DefaultRegion() string {
+	return s.defaultRegion
+}
+
+// Reload reads all holidays from the database, replacing cached calendars
+func (s *Service) Reload(ctx context.Context) error {
+	var holidays []models.Holiday
+	if err := s.db.WithContext(ctx).Find(&holidays).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.holidays = holidays
+	s.calendars = make(map[string]*Calendar)
+	return nil
+}
+
+// Calendar returns the calendar of region, which includes holidays without a
+// region and those of the region. An empty region means the default region.
+func (s *Service) Calendar(region string) *Calendar {
+	if region == "" {
+		region = s.defaultRegion
+	}
+
+	s.mu.RLock()
+	calendar, ok := s.calendars[region]
+	s.mu.RUnlock()
+	if ok {
+		return calendar
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if calendar, ok := s.calendars[region]; ok {
+		return calendar
+	}
+	var dates []time.Time
+	for _, holiday := range s.holidays {
+		if holiday.Region == "" || holiday.Region == region {
+			dates = append(dates, holiday.Date)
+		}
+	}
+	calendar = s.base.WithHolidays(dates)
+	s.calendars[region] = calendar
+	return calendar
+}
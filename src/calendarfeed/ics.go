@@ -0,0 +1,148 @@
+package calendarfeed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// ContentType is the media type of the feed
+const ContentType = "text/calendar; charset=utf-8"
+
+// defaultEventDuration is the length of calls and meetings without a
+// duration, so they still show as a block in calendars
+const defaultEventDuration = 30 * time.Minute
+
+// maxLineOctets is the longest content line before folding (RFC 5545 3.1)
+const maxLineOctets = 75
+
+// icsTime is the UTC date-time format of iCalendar
+const icsTime = "20060102T150405Z"
+
+// Write renders activities with a due date as a calendar. Calls and
+// meetings are events spanning their duration; other activities are to-dos
+// due at their due date. Cancelled activities are kept with STATUS:CANCELLED
+// so calendar clients remove them. link returns the URL of an activity.
+func Write(w io.Writer, name string, activities []models.Activity, link func(id uint) string) error {
+	cal := &writer{w: bufio.NewWriter(w)}
+	cal.line("BEGIN", "VCALENDAR")
+	cal.line("VERSION", "2.0")
+	cal.line("PRODID", "-//Turfa//CRM Service//EN")
+	cal.line("CALSCALE", "GREGORIAN")
+	cal.line("METHOD", "PUBLISH")
+	cal.line("X-WR-CALNAME", escape(name))
+	for _, activity := range activities {
+		if activity.DueDate != nil {
+			cal.activity(activity, link(activity.ID))
+		}
+	}
+	cal.line("END", "VCALENDAR")
+	if cal.err != nil {
+		return cal.err
+	}
+	return cal.w.Flush()
+}
+
+// writer writes folded content lines, keeping the first write error
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+// activity writes an activity as a VEVENT or VTODO component
+func (cal *writer) activity(activity models.Activity, url string) {
+	component := "VTODO"
+	if activity.Type == models.ActivityTypeCall || activity.Type == models.ActivityTypeMeeting {
+		component = "VEVENT"
+	}
+	due := activity.DueDate.UTC()
+	duration := time.Duration(activity.Duration) * time.Minute
+
+	cal.line("BEGIN", component)
+	cal.line("UID", fmt.Sprintf("activity-%d@crm-service", activity.ID))
+	cal.line("DTSTAMP", activity.UpdatedAt.UTC().Format(icsTime))
+	cal.line("LAST-MODIFIED", activity.UpdatedAt.UTC().Format(icsTime))
+	cal.line("SUMMARY", escape(activity.Title))
+
+	// Google Calendar ignores URL, so the link is repeated in the description
+	description := url
+	if activity.Description != "" {
+		description = activity.Description + "\n\n" + url
+	}
+	cal.line("DESCRIPTION", escape(description))
+	cal.line("URL", url)
+
+	if component == "VEVENT" {
+		if duration <= 0 {
+			duration = defaultEventDuration
+		}
+		cal.line("DTSTART", due.Format(icsTime))
+		cal.line("DTEND", due.Add(duration).Format(icsTime))
+		if activity.Status == models.ActivityStatusCancelled {
+			cal.line("STATUS", "CANCELLED")
+		} else {
+			cal.line("STATUS", "CONFIRMED")
+		}
+	} else {
+		if duration > 0 {
+			cal.line("DTSTART", due.Add(-duration).Format(icsTime))
+		}
+		cal.line("DUE", due.Format(icsTime))
+		switch activity.Status {
+		case models.ActivityStatusCancelled:
+			cal.line("STATUS", "CANCELLED")
+		case models.ActivityStatusCompleted:
+			cal.line("STATUS", "COMPLETED")
+			if activity.CompletedAt != nil {
+				cal.line("COMPLETED", activity.CompletedAt.UTC().Format(icsTime))
+			}
+		default:
+			cal.line("STATUS", "NEEDS-ACTION")
+		}
+	}
+
+	switch activity.Priority {
+	case "high":
+		cal.line("PRIORITY", "1")
+	case "low":
+		cal.line("PRIORITY", "9")
+	default:
+		cal.line("PRIORITY", "5")
+	}
+	cal.line("END", component)
+}
+
+// line writes a content line, folding it after maxLineOctets octets
+// without splitting a UTF-8 sequence
+func (cal *writer) line(name, value string) {
+	if cal.err != nil {
+		return
+	}
+	content := name + ":" + value
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		if _, cal.err = cal.w.WriteString(content[:cut] + "\r\n "); cal.err != nil {
+			return
+		}
+		content = content[cut:]
+		limit = maxLineOctets - 1 // Continuation lines start with a space
+	}
+	_, cal.err = cal.w.WriteString(content + "\r\n")
+}
+
+// textEscaper escapes TEXT values (RFC 5545 3.3.11)
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+// escape escapes a TEXT value
+func escape(text string) string {
+	return textEscaper.Replace(text)
+}
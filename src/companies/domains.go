@@ -0,0 +1,68 @@
+package companies
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// backfillBatchSize is the number of customers loaded per backfill batch
+const backfillBatchSize = 500
+
+// Domains derives company email domains for customers
+type Domains struct {
+	free map[string]bool
+}
+
+// NewDomains creates a Domains ignoring the given free email providers
+func NewDomains(freeProviders []string) *Domains {
+	free := make(map[string]bool, len(freeProviders))
+	for _, domain := range freeProviders {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			free[domain] = true
+		}
+	}
+	return &Domains{free: free}
+}
+
+// Domain returns the lower-cased domain of an email address, or an empty
+// string for free email providers and addresses without a domain
+func (d *Domains) Domain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[at+1:])), ".")
+	if domain == "" || d.free[domain] {
+		return ""
+	}
+	return domain
+}
+
+// Backfill recomputes the email domain of every customer, including archived
+// and deleted ones, and returns the number of customers updated. Run it after
+// changing the free provider list.
+func (d *Domains) Backfill(ctx context.Context, db *gorm.DB) (int64, error) {
+	var updated int64
+	var customers []models.Customer
+	err := db.WithContext(ctx).Unscoped().Model(&models.Customer{}).
+		Select("id", "email", "email_domain").
+		FindInBatches(&customers, backfillBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, customer := range customers {
+				domain := d.Domain(customer.Email)
+				if domain == customer.EmailDomain {
+					continue
+				}
+				if err := db.WithContext(ctx).Unscoped().Model(&models.Customer{}).
+					Where("id = ?", customer.ID).
+					UpdateColumn("email_domain", domain).Error; err != nil {
+					return err
+				}
+				updated++
+			}
+			return nil
+		}).Error
+	return updated, err
+}
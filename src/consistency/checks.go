@@ -0,0 +1,206 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Issue is a single invariant violation reported by a check
+type Issue struct {
+	ResourceType string
+	ResourceID   uint
+	Message      string
+}
+
+// CheckFunc inspects the database and returns every violation it finds
+type CheckFunc func(ctx context.Context, db *gorm.DB) ([]Issue, error)
+
+// Check is a named invariant with a severity
+type Check struct {
+	Name     string
+	Severity models.FindingSeverity
+	Run      CheckFunc
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Check)
+)
+
+// Register adds a check to the registry. Registering a name twice replaces
+// the earlier check.
+func Register(name string, severity models.FindingSeverity, run CheckFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = Check{Name: name, Severity: severity, Run: run}
+}
+
+// Checks returns all registered checks sorted by name
+func Checks() []Check {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	checks := make([]Check, 0, len(registry))
+	for _, check := range registry {
+		checks = append(checks, check)
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Name < checks[j].Name
+	})
+	return checks
+}
+
+func init() {
+	Register("activities_orphaned_customer", models.FindingSeverityWarning, activitiesOrphanedCustomer)
+	Register("activities_orphaned_deal", models.FindingSeverityWarning, activitiesOrphanedDeal)
+	Register("deals_orphaned_customer", models.FindingSeverityError, dealsOrphanedCustomer)
+	Register("customers_multiple_primary_contacts", models.FindingSeverityError, customersMultiplePrimaryContacts)
+	Register("deals_closed_without_close_date", models.FindingSeverityWarning, dealsClosedWithoutCloseDate)
+	Register("customers_over_open_deal_limit", models.FindingSeverityWarning, customersOverOpenDealLimit)
+	Register("audit_logs_missing_resource", models.FindingSeverityInfo, auditLogsMissingResource)
+}
+
+// idRow is a scanned resource ID
+type idRow struct {
+	ID uint
+}
+
+// scanIssues runs a query returning IDs and turns each row into an issue
+func scanIssues(query *gorm.DB, resourceType, message string) ([]Issue, error) {
+	var rows []idRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, 0, len(rows))
+	for _, row := range rows {
+		issues = append(issues, Issue{ResourceType: resourceType, ResourceID: row.ID, Message: message})
+	}
+	return issues, nil
+}
+
+// activitiesOrphanedCustomer finds live activities linked to a deleted or missing customer
+func activitiesOrphanedCustomer(ctx context.Context, db *gorm.DB) ([]Issue, error) {
+	return scanIssues(db.WithContext(ctx).Table("activities").
+		Select("activities.id").
+		Joins("LEFT JOIN customers ON customers.id = activities.customer_id").
+		Where("activities.deleted_at IS NULL AND activities.customer_id IS NOT NULL").
+		Where("customers.id IS NULL OR customers.deleted_at IS NOT NULL"),
+		"activity", "Activity is linked to a deleted or missing customer")
+}
+
+// activitiesOrphanedDeal finds live activities linked to a deleted or missing deal
+func activitiesOrphanedDeal(ctx context.Context, db *gorm.DB) ([]Issue, error) {
+	return scanIssues(db.WithContext(ctx).Table("activities").
+		Select("activities.id").
+		Joins("LEFT JOIN deals ON deals.id = activities.deal_id").
+		Where("activities.deleted_at IS NULL AND activities.deal_id IS NOT NULL").
+		Where("deals.id IS NULL OR deals.deleted_at IS NOT NULL"),
+		"activity", "Activity is linked to a deleted or missing deal")
+}
+
+// dealsOrphanedCustomer finds live deals whose customer is deleted or missing
+func dealsOrphanedCustomer(ctx context.Context, db *gorm.DB) ([]Issue, error) {
+	return scanIssues(db.WithContext(ctx).Table("deals").
+		Select("deals.id").
+		Joins("LEFT JOIN customers ON customers.id = deals.customer_id").
+		Where("deals.deleted_at IS NULL").
+		Where("customers.id IS NULL OR customers.deleted_at IS NOT NULL"),
+		"deal", "Deal belongs to a deleted or missing customer")
+}
+
+// customersMultiplePrimaryContacts finds customers with more than one primary contact
+func customersMultiplePrimaryContacts(ctx context.Context, db *gorm.DB) ([]Issue, error) {
+	var rows []struct {
+		ID    uint
+		Count int64
+	}
+	if err := db.WithContext(ctx).Table("contacts").
+		Select("customer_id as id, COUNT(*) as count").
+		Where("deleted_at IS NULL AND is_primary = ?", true).
+		Group("customer_id").
+		Having("COUNT(*) > 1").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, 0, len(rows))
+	for _, row := range rows {
+		issues = append(issues, Issue{
+			ResourceType: "customer",
+			ResourceID:   row.ID,
+			Message:      fmt.Sprintf("Customer has %d primary contacts", row.Count),
+		})
+	}
+	return issues, nil
+}
+
+// dealsClosedWithoutCloseDate finds closed deals missing their actual close date
+func dealsClosedWithoutCloseDate(ctx context.Context, db *gorm.DB) ([]Issue, error) {
+	return scanIssues(db.WithContext(ctx).Table("deals").
+		Select("id").
+		Where("deleted_at IS NULL AND actual_close_date IS NULL AND stage IN ?",
+			[]models.DealStage{models.DealStageClosedWon, models.DealStageClosedLost}),
+		"deal", "Closed deal has no actual close date")
+}
+
+// customersOverOpenDealLimit finds customers with more open deals than
+// MaxOpenDealsPerCustomer allows, such as those from before the limit was
+// set or made while it only warned
+func customersOverOpenDealLimit(ctx context.Context, db *gorm.DB) ([]Issue, error) {
+	if models.MaxOpenDealsPerCustomer <= 0 {
+		return nil, nil
+	}
+
+	var rows []struct {
+		ID    uint
+		Count int64
+	}
+	if err := db.WithContext(ctx).Table("deals").Scopes(models.OpenDeals).
+		Select("deals.customer_id as id, COUNT(*) as count").
+		Where("deals.deleted_at IS NULL").
+		Group("deals.customer_id").
+		Having("COUNT(*) > ?", models.MaxOpenDealsPerCustomer).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, 0, len(rows))
+	for _, row := range rows {
+		issues = append(issues, Issue{
+			ResourceType: "customer",
+			ResourceID:   row.ID,
+			Message:      fmt.Sprintf("Customer has %d open deals; the limit is %d", row.Count, models.MaxOpenDealsPerCustomer),
+		})
+	}
+	return issues, nil
+}
+
+// auditedTables maps audited resource types to their tables
+var auditedTables = map[string]string{
+	"customer": "customers",
+	"contact":  "contacts",
+	"deal":     "deals",
+	"activity": "activities",
+	"tag":      "tags",
+}
+
+// auditLogsMissingResource finds audit entries whose resource row never
+// existed or was hard-deleted. Soft-deleted rows still count as existing.
+func auditLogsMissingResource(ctx context.Context, db *gorm.DB) ([]Issue, error) {
+	var issues []Issue
+	for resourceType, table := range auditedTables {
+		found, err := scanIssues(db.WithContext(ctx).Table("audit_logs").
+			Select("DISTINCT audit_logs.resource_id as id").
+			Where("audit_logs.resource_type = ?", resourceType).
+			Where("NOT EXISTS (SELECT 1 FROM "+table+" WHERE "+table+".id = audit_logs.resource_id)"),
+			resourceType, "Audit log references a "+resourceType+" that does not exist")
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+	return issues, nil
+}
@@ -0,0 +1,135 @@
+package consistency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// ErrRunInProgress is returned when a run is requested while one is active
+var ErrRunInProgress = errors.New("consistency run already in progress")
+
+// CheckResult summarizes one check within a run
+type CheckResult struct {
+	Name     string                 `json:"name"`
+	Severity models.FindingSeverity `json:"severity"`
+	Open     int                    `json:"open"`
+	Resolved int                    `json:"resolved"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// RunSummary summarizes a consistency run
+type RunSummary struct {
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Checks     []CheckResult `json:"checks"`
+}
+
+// Runner executes registered checks and stores their findings
+type Runner struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	running bool
+	lastRun *RunSummary
+}
+
+// NewRunner creates a new Runner
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{db: db}
+}
+
+// LastRun returns the summary of the most recent run, if any
+func (r *Runner) LastRun() *RunSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastRun
+}
+
+// Run executes every registered check. A failing check is reported in the
+// summary and its findings are left untouched; other checks still run.
+func (r *Runner) Run(ctx context.Context) (RunSummary, error) {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return RunSummary{}, ErrRunInProgress
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	summary := RunSummary{StartedAt: time.Now()}
+	for _, check := range Checks() {
+		result := CheckResult{Name: check.Name, Severity: check.Severity}
+		issues, err := check.Run(ctx, r.db)
+		if err == nil {
+			result.Open, result.Resolved, err = r.record(ctx, check, issues, summary.StartedAt)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		summary.Checks = append(summary.Checks, result)
+	}
+	summary.FinishedAt = time.Now()
+
+	r.mu.Lock()
+	r.running = false
+	r.lastRun = &summary
+	r.mu.Unlock()
+
+	return summary, nil
+}
+
+// record upserts a check's findings and resolves those no longer reported
+func (r *Runner) record(ctx context.Context, check Check, issues []Issue, now time.Time) (int, int, error) {
+	var resolved int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.ConsistencyFinding
+		if err := tx.Where("check_name = ?", check.Name).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		type key struct {
+			resourceType string
+			resourceID   uint
+		}
+		byKey := make(map[key]models.ConsistencyFinding, len(existing))
+		for _, finding := range existing {
+			byKey[key{finding.ResourceType, finding.ResourceID}] = finding
+		}
+
+		seen := make([]uint, 0, len(issues))
+		for _, issue := range issues {
+			finding, ok := byKey[key{issue.ResourceType, issue.ResourceID}]
+			if !ok {
+				finding = models.ConsistencyFinding{
+					CheckName:    check.Name,
+					ResourceType: issue.ResourceType,
+					ResourceID:   issue.ResourceID,
+					FirstSeenAt:  now,
+				}
+			}
+			finding.Severity = check.Severity
+			finding.Message = issue.Message
+			finding.LastSeenAt = now
+			finding.ResolvedAt = nil
+			if err := tx.Save(&finding).Error; err != nil {
+				return err
+			}
+			seen = append(seen, finding.ID)
+		}
+
+		query := tx.Model(&models.ConsistencyFinding{}).
+			Where("check_name = ? AND resolved_at IS NULL", check.Name)
+		if len(seen) > 0 {
+			query = query.Where("id NOT IN ?", seen)
+		}
+		result := query.Update("resolved_at", now)
+		resolved = result.RowsAffected
+		return result.Error
+	})
+	return len(issues), int(resolved), err
+}
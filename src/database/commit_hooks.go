@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// EnableCommitHooks wraps the connection pool of db so that transactions
+// begun on it run the functions registered with AfterCommit once they
+// commit. Like sandbox routing it works below GORM, so handlers and
+// callbacks need no changes; it wraps whatever pool db has, so it is
+// enabled after the sandbox is routed.
+func EnableCommitHooks(db *gorm.DB) {
+	pool := &hookPool{ConnPool: db.ConnPool}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+}
+
+// HasCommitHooks reports whether tx is in a transaction begun with commit
+// hooks enabled
+func HasCommitHooks(tx *gorm.DB) bool {
+	_, ok := tx.Statement.ConnPool.(*hookTx)
+	return ok
+}
+
+// AfterCommit registers fn to run once the transaction of tx commits. fn is
+// given a session on the pool the transaction was begun on, carrying the
+// values but not the cancellation of tx's context. It is dropped when the
+// transaction rolls back, or when the savepoint it was registered after is
+// rolled back to. It returns false, without registering fn, when tx is not
+// in a transaction begun with commit hooks enabled.
+func AfterCommit(tx *gorm.DB, fn func(db *gorm.DB)) bool {
+	return AfterCommitOnce(tx, "", fn)
+}
+
+// AfterCommitOnce is AfterCommit for hooks a transaction needs to run only
+// once, such as draining a queue: fn is not registered when a hook with the
+// same key already is.
+func AfterCommitOnce(tx *gorm.DB, key string, fn func(db *gorm.DB)) bool {
+	hooked, ok := tx.Statement.ConnPool.(*hookTx)
+	if !ok {
+		return false
+	}
+	db := tx.Session(&gorm.Session{NewDB: true, Context: context.WithoutCancel(tx.Statement.Context)})
+	db.Statement.ConnPool = hooked.pool
+
+	hooked.mu.Lock()
+	defer hooked.mu.Unlock()
+	if key != "" {
+		for _, hook := range hooked.hooks {
+			if hook.key == key {
+				return true
+			}
+		}
+	}
+	hooked.hooks = append(hooked.hooks, commitHook{key: key, fn: func() { fn(db) }})
+	return true
+}
+
+// commitHook is a function to run once a transaction commits
+type commitHook struct {
+	key string // Set for hooks registered once per transaction
+	fn  func()
+}
+
+// hookPool begins transactions that run commit hooks
+type hookPool struct {
+	gorm.ConnPool
+}
+
+// BeginTx begins a transaction on the wrapped pool. hookPool only
+// implements gorm.ConnPoolBeginner, so GORM keeps the returned hookTx as
+// the connection of the transaction.
+func (p *hookPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		sqlTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = sqlTx
+	case gorm.ConnPoolBeginner:
+		poolTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = poolTx
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	committer, ok := tx.(gorm.TxCommitter)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	return &hookTx{ConnPool: tx, committer: committer, pool: p, savepoints: make(map[string]int)}, nil
+}
+
+// GetDBConn returns the *sql.DB of the wrapped pool, for pings and pool
+// settings
+func (p *hookPool) GetDBConn() (*sql.DB, error) {
+	switch pool := p.ConnPool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gorm.GetDBConnector:
+		return pool.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// hookTx is a transaction holding the functions to run once it commits
+type hookTx struct {
+	gorm.ConnPool
+	committer gorm.TxCommitter
+	pool      *hookPool
+
+	mu         sync.Mutex
+	hooks      []commitHook
+	savepoints map[string]int // Hooks registered when each savepoint was set
+}
+
+// ExecContext runs a statement of the transaction, noting savepoints so
+// that rolling back to one drops the hooks registered after it
+func (t *hookTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := t.ConnPool.ExecContext(ctx, query, args...)
+	if err != nil {
+		return result, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if name, ok := strings.CutPrefix(query, "ROLLBACK TO SAVEPOINT "); ok {
+		if mark, ok := t.savepoints[name]; ok {
+			t.hooks = t.hooks[:mark]
+		}
+	} else if name, ok := strings.CutPrefix(query, "SAVEPOINT "); ok {
+		t.savepoints[name] = len(t.hooks)
+	}
+	return result, nil
+}
+
+// Commit commits the transaction and runs its hooks in order
+func (t *hookTx) Commit() error {
+	if err := t.committer.Commit(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	hooks := t.hooks
+	t.hooks = nil
+	t.mu.Unlock()
+	for _, hook := range hooks {
+		hook.fn()
+	}
+	return nil
+}
+
+// Rollback rolls the transaction back and drops its hooks
+func (t *hookTx) Rollback() error {
+	t.mu.Lock()
+	t.hooks = nil
+	t.mu.Unlock()
+	return t.committer.Rollback()
+}
+
+// GetDBConn returns the *sql.DB the transaction was begun on
+func (t *hookTx) GetDBConn() (*sql.DB, error) {
+	return t.pool.GetDBConn()
+}
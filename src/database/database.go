@@ -3,18 +3,24 @@ package database
 import (
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/SalehAlobaylan/CRM-Service/src/config"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// SlowQueries holds captured slow queries; nil when capture is disabled
+var SlowQueries *SlowQueryLog
+
 // Connect establishes connection to the PostgreSQL database
 func Connect(cfg *config.Config) (*gorm.DB, error) {
 	dsn := cfg.GetDSN()
@@ -35,6 +41,16 @@ func Connect(cfg *config.Config) (*gorm.DB, error) {
 		},
 	)
 
+	// Capture slow queries for the maintenance endpoint
+	if cfg.SlowQueryLogEnabled {
+		SlowQueries = NewSlowQueryLog(
+			time.Duration(cfg.SlowQueryThresholdMs)*time.Millisecond,
+			cfg.SlowQueryLogSize,
+			cfg.SlowQuerySampleRate,
+		)
+		gormLogger = newSlowQueryLogger(gormLogger, SlowQueries)
+	}
+
 	// Open connection
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: gormLogger,
@@ -63,46 +79,165 @@ func Connect(cfg *config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
-// AutoMigrate runs GORM AutoMigrate for all models
-// Note: Use golang-migrate for production, AutoMigrate for development only
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+// Models returns the models AutoMigrate creates tables for
+func Models() []interface{} {
+	return []interface{}{
 		&models.Customer{},
 		&models.Contact{},
 		&models.Deal{},
 		&models.PipelineStage{},
+		&models.ChecklistDefinition{},
+		&models.DealChecklistItem{},
 		&models.Activity{},
+		&models.ActivityOutcome{},
+		&models.ChangeReasonRule{},
 		&models.Note{},
+		&models.TagGroup{},
 		&models.Tag{},
+		&models.TagAlias{},
 		&models.AuditLog{},
-	)
+		&models.Annotation{},
+		&models.CustomerDeletion{},
+		&models.ExchangeRate{},
+		&models.UserActivity{},
+		&models.RecentView{},
+		&models.DeadLetter{},
+		&models.ServiceAccount{},
+		&models.ServiceAccountToken{},
+		&models.AssignmentRule{},
+		&models.UserUnavailability{},
+		&models.ConsistencyFinding{},
+		&models.EmailEvent{},
+		&models.Job{},
+		&models.Holiday{},
+		&models.UserDashboard{},
+		&models.ExportTemplate{},
+		&models.AuditCheckpoint{},
+		&models.AuditQueueEntry{},
+		&models.Role{},
+		&models.SideEffectFallback{},
+		&models.SecurityActivity{},
+		&models.SecurityAlert{},
+		&models.UserTokenRevocation{},
+		&models.SecurityWebhookDelivery{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.TelephonyCall{},
+		&models.ReportRollupState{},
+		&models.ReportRollupDay{},
+		&models.ReportCustomerDay{},
+		&models.ReportDealDay{},
+		&models.ReportRevenueDay{},
+		&models.ReportSegmentDay{},
+	}
+}
+
+// AutoMigrate runs GORM AutoMigrate for all models
+// Note: Use golang-migrate for production, AutoMigrate for development only
+func AutoMigrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(Models()...); err != nil {
+		return err
+	}
+	return migrateSearchText(db)
+}
+
+// searchTextDDL creates crm_fold_text and the folded search_text columns
+// that customer, contact, deal and activity search match on, as migrations
+// 000032 and 000040 do
+var searchTextDDL = []string{
+	`CREATE OR REPLACE FUNCTION crm_fold_text(input TEXT) RETURNS TEXT
+		LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+		SELECT LOWER(TRANSLATE(REGEXP_REPLACE(input, U&'[\064B-\065F\0670\0640]', '', 'g'),
+			U&'\0623\0625\0622\0671\0629\0649\0626\0624', U&'\0627\0627\0627\0627\0647\064A\064A\0648'))
+	$$`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+		(crm_fold_text(COALESCE(name, '') || ' ' || COALESCE(email, '') || ' ' || COALESCE(company, ''))) STORED`,
+	`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+		(crm_fold_text(COALESCE(first_name, '') || ' ' || COALESCE(last_name, '') || ' ' || COALESCE(email, ''))) STORED`,
+	`ALTER TABLE deals ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+		(crm_fold_text(COALESCE(title, ''))) STORED`,
+	`ALTER TABLE activities ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS
+		(crm_fold_text(COALESCE(title, ''))) STORED`,
+}
+
+// migrateSearchText adds the folded search columns, which GORM models
+// leave out because the database generates them
+func migrateSearchText(db *gorm.DB) error {
+	for _, ddl := range searchTextDDL {
+		if err := db.Exec(ddl).Error; err != nil {
+			return fmt.Errorf("failed to migrate search text: %w", err)
+		}
+	}
+	return nil
+}
+
+// defaultPipelineStages are the pipeline stages seeded into new databases
+var defaultPipelineStages = []models.PipelineStage{
+	{Name: "prospecting", DisplayName: "Prospecting", Order: 1, Color: "#6366f1", IsActive: true},
+	{Name: "qualification", DisplayName: "Qualification", Order: 2, Color: "#8b5cf6", IsActive: true},
+	{Name: "proposal", DisplayName: "Proposal", Order: 3, Color: "#a855f7", IsActive: true},
+	{Name: "negotiation", DisplayName: "Negotiation", Order: 4, Color: "#f59e0b", IsActive: true},
+	{Name: "closed_won", DisplayName: "Closed Won", Order: 5, Color: "#22c55e", IsActive: true},
+	{Name: "closed_lost", DisplayName: "Closed Lost", Order: 6, Color: "#ef4444", IsActive: true},
 }
 
-// SeedPipelineStages seeds default pipeline stages if not present
+// SeedPipelineStages seeds default pipeline stages if not present. Stages
+// are upserted on name, so replicas seeding at once cannot insert
+// duplicates and a deleted stage stays deleted.
 func SeedPipelineStages(db *gorm.DB) error {
-	stages := []models.PipelineStage{
-		{Name: "prospecting", DisplayName: "Prospecting", Order: 1, Color: "#6366f1", IsActive: true},
-		{Name: "qualification", DisplayName: "Qualification", Order: 2, Color: "#8b5cf6", IsActive: true},
-		{Name: "proposal", DisplayName: "Proposal", Order: 3, Color: "#a855f7", IsActive: true},
-		{Name: "negotiation", DisplayName: "Negotiation", Order: 4, Color: "#f59e0b", IsActive: true},
-		{Name: "closed_won", DisplayName: "Closed Won", Order: 5, Color: "#22c55e", IsActive: true},
-		{Name: "closed_lost", DisplayName: "Closed Lost", Order: 6, Color: "#ef4444", IsActive: true},
-	}
-
-	for _, stage := range stages {
-		// Use FirstOrCreate to avoid duplicates
-		var existing models.PipelineStage
-		result := db.Where("name = ?", stage.Name).First(&existing)
-		if result.Error == gorm.ErrRecordNotFound {
-			if err := db.Create(&stage).Error; err != nil {
-				return fmt.Errorf("failed to seed pipeline stage %s: %w", stage.Name, err)
-			}
+	stages := slices.Clone(defaultPipelineStages)
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&stages).Error; err != nil {
+		return fmt.Errorf("failed to seed pipeline stages: %w", err)
+	}
+
+	return nil
+}
+
+// SeedRoles seeds the built-in roles if not present, in name order, upserting
+// on name like SeedPipelineStages
+func SeedRoles(db *gorm.DB) error {
+	for _, name := range slices.Sorted(maps.Keys(models.DefaultRolePermissions)) {
+		role := models.Role{Name: name, Permissions: models.DefaultRolePermissions[name]}
+		if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&role).Error; err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", name, err)
 		}
 	}
+	return nil
+}
 
+// VerifySeeds checks that the built-in pipeline stages and roles are seeded
+func VerifySeeds(db *gorm.DB) error {
+	names := make([]string, len(defaultPipelineStages))
+	for i, stage := range defaultPipelineStages {
+		names[i] = stage.Name
+	}
+	var stages int64
+	if err := db.Unscoped().Model(&models.PipelineStage{}).Where("name IN ?", names).Count(&stages).Error; err != nil {
+		return err
+	}
+	if int(stages) != len(names) {
+		return fmt.Errorf("%d of %d built-in pipeline stages are seeded", stages, len(names))
+	}
+
+	var roles int64
+	if err := db.Model(&models.Role{}).Where("name IN ?", slices.Collect(maps.Keys(models.DefaultRolePermissions))).Count(&roles).Error; err != nil {
+		return err
+	}
+	if int(roles) != len(models.DefaultRolePermissions) {
+		return fmt.Errorf("%d of %d built-in roles are seeded", roles, len(models.DefaultRolePermissions))
+	}
 	return nil
 }
 
+// HasCollation reports whether the database defines the named collation
+func HasCollation(db *gorm.DB, name string) (bool, error) {
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM pg_collation WHERE collname = ?", name).Scan(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // Close closes the database connection
 func Close(db *gorm.DB) error {
 	sqlDB, err := db.DB()
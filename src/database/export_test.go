@@ -0,0 +1,12 @@
+package database
+
+import "context"
+
+// InitLockKey exposes the initialization lock to tests holding it
+const InitLockKey = initLockKey
+
+// SetReplayed replaces how the router reads the replica's replayed WAL
+// position, to simulate a lagging replica
+func SetReplayed(r *ReadRouter, replayed func(ctx context.Context) (uint64, error)) {
+	r.replayed = replayed
+}
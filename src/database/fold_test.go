@@ -0,0 +1,57 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+)
+
+// TestFoldText checks crm_fold_text, which the folded search columns and
+// search filters match with, on representative names
+func TestFoldText(t *testing.T) {
+	db := testdb.Open(t)
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		// Alef variants fold to bare alef
+		{"alef with hamza above", "أحمد", "احمد"},
+		{"alef with hamza below", "إيمان", "ايمان"},
+		{"alef with madda", "آدم", "ادم"},
+		{"alef wasla", "ٱلرحمن", "الرحمن"},
+		{"bare alef unchanged", "احمد", "احمد"},
+
+		// Ta marbuta folds to ha, alef maqsura and the hamza seats to their letters
+		{"ta marbuta", "فاطمة", "فاطمه"},
+		{"ha unchanged", "فاطمه", "فاطمه"},
+		{"alef maqsura", "مصطفى", "مصطفي"},
+		{"hamza on ya", "هيئة", "هييه"},
+		{"hamza on waw", "مؤمن", "مومن"},
+		{"several variants", "آمنة أسامة", "امنه اسامه"},
+
+		// Diacritics and tatweel are dropped
+		{"fatha, damma and shadda", "مُحَمَّد", "محمد"},
+		{"kasra and sukun", "مَسْجِد", "مسجد"},
+		{"tanween", "شكرًا", "شكرا"},
+		{"superscript alef", "هٰذا", "هذا"},
+		{"tatweel", "محـــمد", "محمد"},
+		{"diacritics on a variant", "أَحْمَد", "احمد"},
+
+		// Other text is only lowercased
+		{"latin", "Sara AL-Qahtani", "sara al-qahtani"},
+		{"mixed scripts", "Huda هُدى", "huda هدي"},
+		{"digits and email", "Omar.2@Nakheel.SA", "omar.2@nakheel.sa"},
+		{"empty", "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			if err := db.Raw("SELECT crm_fold_text(?)", tc.input).Scan(&got).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("crm_fold_text(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// initLockKey is the Postgres advisory lock held while a replica migrates or
+// seeds a database. Advisory locks are scoped to a database, so the primary
+// and sandbox databases are initialized independently.
+const initLockKey int64 = 0x43524d5f494e4954 // "CRM_INIT"
+
+// Roles a replica can take in an initialization
+const (
+	InitRoleLeader   = "leader"   // Won the lock and initialized the database
+	InitRoleFollower = "follower" // Waited for the leader and verified its work
+)
+
+var (
+	initDurationSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "crm_init_duration_seconds",
+			Help: "Time the last startup initialization of each database took, including waiting for the lock",
+		},
+		[]string{"database", "role"},
+	)
+	initLockWaitSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "crm_init_lock_wait_seconds",
+			Help: "Time the last startup initialization of each database waited for another replica",
+		},
+		[]string{"database", "role"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(initDurationSeconds, initLockWaitSeconds)
+}
+
+// Initializer runs the startup migration and seeding of a database so that
+// exactly one replica performs them. The first replica to take the advisory
+// lock runs Init; the others wait for it to finish and run Verify instead.
+// A follower whose Verify fails, because the leader failed or stopped
+// midway, runs Init itself while still holding the lock.
+type Initializer struct {
+	Name   string               // Names the database in logs and metrics
+	Init   func(*gorm.DB) error // Migrates and seeds; must be idempotent
+	Verify func(*gorm.DB) error // Checks the work of Init
+	OnWait func()               // Called when another replica holds the lock; may be nil
+}
+
+// InitResult describes how a replica took part in an initialization
+type InitResult struct {
+	Role      string        // InitRoleLeader or InitRoleFollower
+	Waited    time.Duration // Time spent waiting for the lock
+	Duration  time.Duration // Total time, including the wait
+	Recovered bool          // A follower ran Init because Verify failed
+}
+
+// Run takes the advisory lock on a dedicated connection, since advisory
+// locks belong to a session, and initializes or verifies the database. The
+// lock is released even when ctx is cancelled.
+func (i Initializer) Run(ctx context.Context, db *gorm.DB) (InitResult, error) {
+	start := time.Now()
+	result := InitResult{Role: InitRoleLeader}
+
+	err := db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", initLockKey).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to take %s init lock: %w", i.Name, err)
+		}
+		if !locked {
+			result.Role = InitRoleFollower
+			if i.OnWait != nil {
+				i.OnWait()
+			}
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", initLockKey).Error; err != nil {
+				return fmt.Errorf("failed to wait for %s init lock: %w", i.Name, err)
+			}
+			result.Waited = time.Since(start)
+		}
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(?)", initLockKey)
+
+		if result.Role == InitRoleFollower {
+			err := i.Verify(conn)
+			if err == nil {
+				return nil
+			}
+			result.Recovered = true
+		}
+		return i.Init(conn)
+	})
+
+	result.Duration = time.Since(start)
+	initDurationSeconds.WithLabelValues(i.Name, result.Role).Set(result.Duration.Seconds())
+	initLockWaitSeconds.WithLabelValues(i.Name, result.Role).Set(result.Waited.Seconds())
+	return result, err
+}
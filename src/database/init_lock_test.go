@@ -0,0 +1,222 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+func TestSeedIsIdempotent(t *testing.T) {
+	f := testdb.New(t, time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+	// The migrations seed the stages and roles too; start without them
+	if err := f.DB.Exec("DELETE FROM pipeline_stages; DELETE FROM roles").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.VerifySeeds(f.DB); err == nil {
+		t.Fatal("an empty database verifies")
+	}
+	for i := 0; i < 2; i++ {
+		if err := database.SeedPipelineStages(f.DB); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.SeedRoles(f.DB); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.VerifySeeds(f.DB); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.Count("pipeline_stages"); n != 6 {
+		t.Errorf("%d stages, want 6", n)
+	}
+	if n := f.Count("roles"); n != len(models.DefaultRolePermissions) {
+		t.Errorf("%d roles, want %d", n, len(models.DefaultRolePermissions))
+	}
+
+	// A deleted stage is not seeded again
+	if err := f.DB.Where("name = ?", "proposal").Delete(&models.PipelineStage{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.SeedPipelineStages(f.DB); err != nil {
+		t.Fatal(err)
+	}
+	var active int64
+	if err := f.DB.Model(&models.PipelineStage{}).Count(&active).Error; err != nil {
+		t.Fatal(err)
+	}
+	if active != 5 || f.Count("pipeline_stages") != 6 {
+		t.Errorf("%d active stages of %d, want 5 of 6", active, f.Count("pipeline_stages"))
+	}
+}
+
+// replica connects to the database at dsn as a server replica does
+func replica(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	db, err := database.Connect(&config.Config{DatabaseURL: dsn, Environment: "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close(db) })
+	return db
+}
+
+// startup migrates and seeds an empty database, as the sandbox
+// initialization does
+func startup(conn *gorm.DB) error {
+	if err := database.AutoMigrate(conn); err != nil {
+		return err
+	}
+	if err := database.SeedPipelineStages(conn); err != nil {
+		return err
+	}
+	return database.SeedRoles(conn)
+}
+
+// countSeeds returns the number of pipeline stages, deleted or not, and of
+// roles
+func countSeeds(t *testing.T, db *gorm.DB) (stages, roles int64) {
+	t.Helper()
+	if err := db.Unscoped().Model(&models.PipelineStage{}).Count(&stages).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Role{}).Count(&roles).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stages, roles
+}
+
+// TestConcurrentInitialization checks on Postgres that two replicas
+// connecting and initializing an empty database at once leave exactly the
+// six built-in stages, one of them initializing and the other verifying
+func TestConcurrentInitialization(t *testing.T) {
+	dsn := testdb.URL(t)
+
+	// The leader keeps the lock until the other replica is waiting for it,
+	// so the two always overlap
+	waiting := make(chan struct{})
+	var once sync.Once
+	initializer := database.Initializer{
+		Name: "test",
+		Init: func(conn *gorm.DB) error {
+			select {
+			case <-waiting:
+			case <-time.After(10 * time.Second):
+				return errors.New("the other replica never waited for the lock")
+			}
+			return startup(conn)
+		},
+		Verify: database.VerifySeeds,
+		OnWait: func() { once.Do(func() { close(waiting) }) },
+	}
+
+	var (
+		connectMu sync.Mutex // Connect sets database.DB
+		wg        sync.WaitGroup
+		results   [2]database.InitResult
+		errs      [2]error
+	)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			connectMu.Lock()
+			db, err := database.Connect(&config.Config{DatabaseURL: dsn, Environment: "production"})
+			connectMu.Unlock()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer database.Close(db)
+			results[i], errs[i] = initializer.Run(context.Background(), db)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	leaders, followers := 0, 0
+	for _, result := range results {
+		switch result.Role {
+		case database.InitRoleLeader:
+			leaders++
+		case database.InitRoleFollower:
+			followers++
+			if result.Recovered || result.Waited <= 0 {
+				t.Errorf("follower = %+v, want a wait and no recovery", result)
+			}
+		}
+	}
+	if leaders != 1 || followers != 1 {
+		t.Errorf("results = %+v, want a leader and a follower", results)
+	}
+
+	stages, roles := countSeeds(t, replica(t, dsn))
+	if stages != 6 {
+		t.Errorf("%d stages, want 6", stages)
+	}
+	if int(roles) != len(models.DefaultRolePermissions) {
+		t.Errorf("%d roles, want %d", roles, len(models.DefaultRolePermissions))
+	}
+}
+
+// TestFollowerRecovers checks on Postgres that a follower finishes the
+// initialization when the leader failed midway
+func TestFollowerRecovers(t *testing.T) {
+	dsn := testdb.URL(t)
+	leader, follower := replica(t, dsn), replica(t, dsn)
+
+	// The leader migrates but fails before seeding
+	failed := database.Initializer{
+		Name: "test",
+		Init: func(conn *gorm.DB) error {
+			if err := database.AutoMigrate(conn); err != nil {
+				return err
+			}
+			return errors.New("killed")
+		},
+		Verify: database.VerifySeeds,
+	}
+	if _, err := failed.Run(context.Background(), leader); err == nil {
+		t.Fatal("the failed initialization succeeded")
+	}
+
+	// A replica taking the lock held by nobody is a leader; hold it from
+	// another session so the replica follows
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go leader.Connection(func(conn *gorm.DB) error {
+		conn.Exec("SELECT pg_advisory_lock(?)", database.InitLockKey)
+		close(held)
+		<-release
+		return conn.Exec("SELECT pg_advisory_unlock(?)", database.InitLockKey).Error
+	})
+	<-held
+
+	recovering := database.Initializer{
+		Name:   "test",
+		Init:   startup,
+		Verify: database.VerifySeeds,
+		OnWait: func() { close(release) },
+	}
+	result, err := recovering.Run(context.Background(), follower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Role != database.InitRoleFollower || !result.Recovered {
+		t.Errorf("result = %+v, want a recovering follower", result)
+	}
+	if stages, _ := countSeeds(t, follower); stages != 6 {
+		t.Errorf("%d stages, want 6", stages)
+	}
+}
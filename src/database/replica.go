@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// replicaPollInterval is how often a lagging replica is re-checked while a
+// read waits for it to catch up
+const replicaPollInterval = 20 * time.Millisecond
+
+// ConnectReplica opens the read replica, or returns nil when none is configured
+func ConnectReplica(cfg *config.Config) (*gorm.DB, error) {
+	if cfg.DatabaseReplicaURL == "" {
+		return nil, nil
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseReplicaURL), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to read replica: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping read replica: %w", err)
+	}
+	return db, nil
+}
+
+// ReadRouter sends reads to the read replica unless the client needs to see
+// a write the replica has not replayed yet. Sync tokens are primary WAL
+// positions: mutations hand one out, and reads that present it are served by
+// the replica only once it has replayed that far.
+type ReadRouter struct {
+	primary *gorm.DB
+	replica *gorm.DB
+	maxWait time.Duration
+
+	// replayed returns the replica's replayed WAL position
+	replayed func(ctx context.Context) (uint64, error)
+}
+
+// NewReadRouter creates a ReadRouter. replica may be nil, in which case every
+// read goes to the primary. Reads wait up to maxWait for a lagging replica
+// before falling back to the primary.
+func NewReadRouter(primary, replica *gorm.DB, maxWait time.Duration) *ReadRouter {
+	r := &ReadRouter{primary: primary, replica: replica, maxWait: maxWait}
+	r.replayed = func(ctx context.Context) (uint64, error) {
+		return queryLSN(ctx, r.replica, "SELECT pg_last_wal_replay_lsn()::text")
+	}
+	return r
+}
+
+// Enabled reports whether a read replica is configured
+func (r *ReadRouter) Enabled() bool {
+	return r != nil && r.replica != nil
+}
+
+// SyncToken returns the primary's current WAL position. A read presenting it
+// is guaranteed to see every write committed before it was issued.
+func (r *ReadRouter) SyncToken(ctx context.Context) (string, error) {
+	lsn, err := queryLSN(ctx, r.primary, "SELECT pg_current_wal_lsn()::text")
+	if err != nil {
+		return "", err
+	}
+	return formatLSN(lsn), nil
+}
+
+// Reader returns the database to read from. Without a token the replica is
+// used. With one, the replica is used once it has replayed past the token,
+// waiting up to maxWait; otherwise, or when the token is invalid or the
+// replica cannot be queried, the primary is used.
+func (r *ReadRouter) Reader(ctx context.Context, minToken string) *gorm.DB {
+	if r.replica == nil {
+		return r.primary
+	}
+	if minToken == "" {
+		return r.replica
+	}
+	target, err := parseLSN(minToken)
+	if err != nil {
+		return r.primary
+	}
+
+	deadline := time.Now().Add(r.maxWait)
+	for {
+		replayed, err := r.replayed(ctx)
+		if err != nil {
+			return r.primary
+		}
+		if replayed >= target {
+			return r.replica
+		}
+		if time.Now().Add(replicaPollInterval).After(deadline) {
+			return r.primary
+		}
+		select {
+		case <-ctx.Done():
+			return r.primary
+		case <-time.After(replicaPollInterval):
+		}
+	}
+}
+
+// queryLSN runs a query returning a WAL position as text
+func queryLSN(ctx context.Context, db *gorm.DB, sql string) (uint64, error) {
+	var text *string
+	if err := db.WithContext(ctx).Raw(sql).Scan(&text).Error; err != nil {
+		return 0, err
+	}
+	if text == nil {
+		return 0, fmt.Errorf("WAL position unavailable")
+	}
+	return parseLSN(*text)
+}
+
+// parseLSN parses a PostgreSQL WAL position such as "16/B374D848"
+func parseLSN(text string) (uint64, error) {
+	high, low, ok := strings.Cut(text, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid sync token %q", text)
+	}
+	h, err := strconv.ParseUint(high, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sync token %q", text)
+	}
+	l, err := strconv.ParseUint(low, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sync token %q", text)
+	}
+	return h<<32 | l, nil
+}
+
+// formatLSN formats a WAL position the way PostgreSQL does
+func formatLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", lsn>>32, lsn&0xFFFFFFFF)
+}
@@ -0,0 +1,83 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"gorm.io/gorm"
+)
+
+// TestReaderFallsBackFromAStaleReplica checks which database reads
+// presenting a sync token go to while the replica lags behind it
+func TestReaderFallsBackFromAStaleReplica(t *testing.T) {
+	primary, replica := &gorm.DB{}, &gorm.DB{}
+	const token = "16/B374D848"
+	const target = uint64(0x16B374D848)
+	const maxWait = 200 * time.Millisecond
+
+	for _, tc := range []struct {
+		name     string
+		token    string
+		replayed func(calls int64) (uint64, error)
+		cancel   bool
+		want     *gorm.DB
+		waits    bool // The read waited out maxWait first
+	}{
+		{name: "no token", replayed: func(int64) (uint64, error) { return 0, nil }, want: replica},
+		{name: "caught up", token: token, replayed: func(int64) (uint64, error) { return target, nil }, want: replica},
+		{name: "ahead", token: token, replayed: func(int64) (uint64, error) { return target + 1, nil }, want: replica},
+		{name: "catches up while waiting", token: token, replayed: func(calls int64) (uint64, error) {
+			if calls < 3 {
+				return target - 1, nil
+			}
+			return target, nil
+		}, want: replica},
+		{name: "stale", token: token, replayed: func(int64) (uint64, error) { return target - 1, nil }, want: primary, waits: true},
+		{name: "replay position unavailable", token: token, replayed: func(int64) (uint64, error) { return 0, errors.New("replica down") }, want: primary},
+		{name: "invalid token", token: "not-a-token", replayed: func(int64) (uint64, error) { return target, nil }, want: primary},
+		{name: "request cancelled", token: token, replayed: func(int64) (uint64, error) { return target - 1, nil }, cancel: true, want: primary},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router := database.NewReadRouter(primary, replica, maxWait)
+			var calls atomic.Int64
+			database.SetReplayed(router, func(context.Context) (uint64, error) {
+				return tc.replayed(calls.Add(1))
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+
+			start := time.Now()
+			got := router.Reader(ctx, tc.token)
+			elapsed := time.Since(start)
+			if got != tc.want {
+				t.Errorf("read from the %s", name(got, primary))
+			}
+			if tc.waits && elapsed < maxWait/2 {
+				t.Errorf("fell back after %v, before waiting for the replica", elapsed)
+			}
+			if elapsed > maxWait+time.Second {
+				t.Errorf("read waited %v, past maxWait", elapsed)
+			}
+		})
+	}
+
+	// Without a replica every read goes to the primary
+	if got := database.NewReadRouter(primary, nil, maxWait).Reader(context.Background(), token); got != primary {
+		t.Errorf("read from the %s without a replica", name(got, primary))
+	}
+}
+
+// name names the database a read went to
+func name(db, primary *gorm.DB) string {
+	if db == primary {
+		return "primary"
+	}
+	return "replica"
+}
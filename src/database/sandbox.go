@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ConnectSandbox opens the sandbox database, or returns nil when none is
+// configured. Resets truncate every table of the sandbox, so it is refused
+// when it resolves to the same database as primary.
+func ConnectSandbox(cfg *config.Config, primary *gorm.DB) (*gorm.DB, error) {
+	if cfg.SandboxDatabaseURL == "" {
+		return nil, nil
+	}
+	if cfg.SandboxDatabaseURL == cfg.DatabaseURL {
+		return nil, errors.New("sandbox database must differ from the primary database")
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.SandboxDatabaseURL), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to sandbox database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	sqlDB.SetMaxIdleConns(2)
+	sqlDB.SetMaxOpenConns(10)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping sandbox database: %w", err)
+	}
+
+	primaryID, err := databaseIdentity(primary)
+	if err != nil {
+		return nil, err
+	}
+	sandboxID, err := databaseIdentity(db)
+	if err != nil {
+		return nil, err
+	}
+	if primaryID == sandboxID {
+		return nil, errors.New("sandbox database must differ from the primary database")
+	}
+	return db, nil
+}
+
+// databaseIdentity names the server and database a connection reaches
+func databaseIdentity(db *gorm.DB) (string, error) {
+	var identity string
+	err := db.Raw("SELECT COALESCE(inet_server_addr()::text, '') || ':' || COALESCE(inet_server_port()::text, '') || '/' || current_database()").
+		Scan(&identity).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to identify database: %w", err)
+	}
+	return identity, nil
+}
+
+// ResetSandbox empties every table of the sandbox database, restarting ID
+// sequences, and loads the demo fixtures, so each reset yields the same IDs
+// and records
+func ResetSandbox(ctx context.Context, db *gorm.DB) error {
+	tables, err := db.WithContext(ctx).Migrator().GetTables()
+	if err != nil {
+		return err
+	}
+	tables = slices.DeleteFunc(tables, func(table string) bool { return table == "schema_migrations" })
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(tables) > 0 {
+			quoted := make([]string, len(tables))
+			for i, table := range tables {
+				quoted[i] = `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+			}
+			if err := tx.Exec("TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+				return err
+			}
+		}
+		if err := SeedPipelineStages(tx); err != nil {
+			return err
+		}
+		if err := SeedRoles(tx); err != nil {
+			return err
+		}
+		return seedSandboxFixtures(tx, time.Now().UTC().Truncate(24*time.Hour))
+	})
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Sandbox fixture owners. Sandbox tokens may use these user IDs to see the
+// demo records as their own.
+const (
+	sandboxOwnerAlice uint = 1
+	sandboxOwnerBob   uint = 2
+)
+
+// seedSandboxFixtures loads the sandbox demo records. Tables are empty and
+// their sequences restarted, so records get the same IDs in every reset.
+// Dates are relative to day, so due and overdue items stay meaningful.
+func seedSandboxFixtures(tx *gorm.DB, day time.Time) error {
+	at := func(days int) *time.Time {
+		t := day.AddDate(0, 0, days).Add(10 * time.Hour)
+		return &t
+	}
+	owner := func(id uint) *uint { return &id }
+
+	customers := []models.Customer{
+		{Name: "Dana Whitfield", Email: "dana@acme-robotics.example", Phone: "+1 555 0101", Company: "Acme Robotics", Role: "COO", Status: models.CustomerStatusActive, AssignedTo: owner(sandboxOwnerAlice), Contacted: true, EmailDomain: "acme-robotics.example"},
+		{Name: "Omar Haddad", Email: "omar@blueharbor.example", Phone: "+1 555 0102", Company: "Blue Harbor Logistics", Role: "Head of Operations", Status: models.CustomerStatusProspect, AssignedTo: owner(sandboxOwnerBob), Contacted: true, NextFollowUpAt: at(2), EmailDomain: "blueharbor.example"},
+		{Name: "Mia Lindqvist", Email: "mia@cedarpine.example", Company: "Cedar & Pine Studio", Role: "Founder", Status: models.CustomerStatusLead, AssignedTo: owner(sandboxOwnerAlice), EmailDomain: "cedarpine.example"},
+		{Name: "Layla Nasser", Email: "layla@desertbloom.example", Phone: "+1 555 0104", Company: "Desert Bloom Foods", Role: "Procurement Manager", Status: models.CustomerStatusActive, AssignedTo: owner(sandboxOwnerBob), Contacted: true, EmailDomain: "desertbloom.example"},
+		{Name: "Samuel Osei", Email: "samuel@evergreenclinics.example", Company: "Evergreen Clinics", Role: "IT Director", Status: models.CustomerStatusInactive, Contacted: true, EmailDomain: "evergreenclinics.example"},
+	}
+	if err := tx.Create(&customers).Error; err != nil {
+		return err
+	}
+
+	contacts := []models.Contact{
+		{CustomerID: customers[0].ID, FirstName: "Dana", LastName: "Whitfield", Email: "dana@acme-robotics.example", Position: "COO", IsPrimary: true},
+		{CustomerID: customers[0].ID, FirstName: "Leo", LastName: "Park", Email: "leo@acme-robotics.example", Position: "Plant Manager"},
+		{CustomerID: customers[1].ID, FirstName: "Omar", LastName: "Haddad", Email: "omar@blueharbor.example", Position: "Head of Operations", IsPrimary: true},
+		{CustomerID: customers[3].ID, FirstName: "Layla", LastName: "Nasser", Email: "layla@desertbloom.example", Position: "Procurement Manager", IsPrimary: true},
+		{CustomerID: customers[4].ID, FirstName: "Samuel", LastName: "Osei", Email: "samuel@evergreenclinics.example", Position: "IT Director", IsPrimary: true},
+	}
+	if err := tx.Create(&contacts).Error; err != nil {
+		return err
+	}
+
+	tags := []models.Tag{
+		{Name: "enterprise", Color: "#6366F1"},
+		{Name: "smb", Color: "#22C55E"},
+		{Name: "vip", Color: "#F59E0B"},
+	}
+	if err := tx.Create(&tags).Error; err != nil {
+		return err
+	}
+	customerTags := []models.CustomerTag{
+		{CustomerID: customers[0].ID, TagID: tags[0].ID},
+		{CustomerID: customers[0].ID, TagID: tags[2].ID},
+		{CustomerID: customers[1].ID, TagID: tags[0].ID},
+		{CustomerID: customers[2].ID, TagID: tags[1].ID},
+		{CustomerID: customers[3].ID, TagID: tags[1].ID},
+	}
+	if err := tx.Create(&customerTags).Error; err != nil {
+		return err
+	}
+
+	deals := []models.Deal{
+		{Title: "Acme Robotics - Fleet Expansion", CustomerID: customers[0].ID, ContactID: &contacts[0].ID, Stage: models.DealStageNegotiation, Amount: 120000, Currency: "USD", Probability: 70, ExpectedCloseDate: at(21), OwnerID: owner(sandboxOwnerAlice), BoardPosition: models.BoardPositionStep, NextStep: "Send revised pricing", NextStepDue: at(3), NextStepSetAt: at(-2)},
+		{Title: "Acme Robotics - Support Renewal", CustomerID: customers[0].ID, ContactID: &contacts[1].ID, Stage: models.DealStageClosedWon, Amount: 18000, Currency: "USD", Probability: 100, ActualCloseDate: at(-30), OwnerID: owner(sandboxOwnerAlice), BoardPosition: models.BoardPositionStep},
+		{Title: "Blue Harbor - Route Optimization Pilot", CustomerID: customers[1].ID, ContactID: &contacts[2].ID, Stage: models.DealStageProposal, Amount: 45000, Currency: "USD", Probability: 40, ExpectedCloseDate: at(45), OwnerID: owner(sandboxOwnerBob), BoardPosition: models.BoardPositionStep, NextStep: "Review proposal with operations team", NextStepDue: at(-1), NextStepSetAt: at(-8)},
+		{Title: "Cedar & Pine - Starter Plan", CustomerID: customers[2].ID, Stage: models.DealStageProspecting, Amount: 2400, Currency: "USD", Probability: 10, OwnerID: owner(sandboxOwnerAlice), BoardPosition: models.BoardPositionStep},
+		{Title: "Desert Bloom - Cold Chain Sensors", CustomerID: customers[3].ID, ContactID: &contacts[3].ID, Stage: models.DealStageQualification, Amount: 32000, Currency: "EUR", Probability: 25, ExpectedCloseDate: at(60), OwnerID: owner(sandboxOwnerBob), BoardPosition: models.BoardPositionStep, NextStep: "Schedule site visit", NextStepDue: at(7), NextStepSetAt: at(-1)},
+		{Title: "Evergreen Clinics - Records Migration", CustomerID: customers[4].ID, ContactID: &contacts[4].ID, Stage: models.DealStageClosedLost, Amount: 75000, Currency: "USD", ActualCloseDate: at(-14), OwnerID: owner(sandboxOwnerBob), LostReason: "Budget frozen", BoardPosition: models.BoardPositionStep},
+	}
+	if err := tx.Create(&deals).Error; err != nil {
+		return err
+	}
+
+	activities := []models.Activity{
+		{Title: "Discovery call", Type: models.ActivityTypeCall, Status: models.ActivityStatusCompleted, CustomerID: &customers[0].ID, DealID: &deals[0].ID, ContactID: &contacts[0].ID, AssignedTo: owner(sandboxOwnerAlice), DueDate: at(-10), CompletedAt: at(-10), Duration: 30, Outcome: "Interested in 40 additional units", Priority: "normal"},
+		{Title: "Pricing review meeting", Type: models.ActivityTypeMeeting, Status: models.ActivityStatusScheduled, CustomerID: &customers[0].ID, DealID: &deals[0].ID, ContactID: &contacts[0].ID, AssignedTo: owner(sandboxOwnerAlice), DueDate: at(3), Duration: 60, Priority: "high"},
+		{Title: "Follow up on pilot proposal", Type: models.ActivityTypeTask, Status: models.ActivityStatusScheduled, CustomerID: &customers[1].ID, DealID: &deals[2].ID, AssignedTo: owner(sandboxOwnerBob), DueDate: at(-1), Priority: "high"},
+		{Title: "Intro email", Type: models.ActivityTypeEmail, Status: models.ActivityStatusScheduled, CustomerID: &customers[2].ID, DealID: &deals[3].ID, AssignedTo: owner(sandboxOwnerAlice), DueDate: at(1), Priority: "normal"},
+		{Title: "Qualification call", Type: models.ActivityTypeCall, Status: models.ActivityStatusCompleted, CustomerID: &customers[3].ID, DealID: &deals[4].ID, ContactID: &contacts[3].ID, AssignedTo: owner(sandboxOwnerBob), DueDate: at(-3), CompletedAt: at(-3), Duration: 25, Outcome: "Needs sensors for 12 trucks", Priority: "normal"},
+	}
+	if err := tx.Create(&activities).Error; err != nil {
+		return err
+	}
+
+	notes := []models.Note{
+		{Content: "Prefers quarterly invoicing.", CustomerID: &customers[0].ID, AuthorID: sandboxOwnerAlice, AuthorName: "Alice Demo"},
+		{Content: "Pilot limited to the northern routes.", CustomerID: &customers[1].ID, DealID: &deals[2].ID, AuthorID: sandboxOwnerBob, AuthorName: "Bob Demo"},
+		{Content: "Lost to budget freeze; revisit next fiscal year.", CustomerID: &customers[4].ID, DealID: &deals[5].ID, AuthorID: sandboxOwnerBob, AuthorName: "Bob Demo"},
+	}
+	for i := range notes {
+		notes[i].ContentHash = models.NoteContentHash(notes[i].Content)
+	}
+	return tx.Create(&notes).Error
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// Request context keys read when attributing a slow query to a request.
+// They match the keys set on the gin context by the request middleware.
+const (
+	ContextKeyRequestID = "request_id"
+	ContextKeyRoute     = "route"
+)
+
+var (
+	stringLiteralPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLiteralPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	inListPattern        = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	whitespacePattern    = regexp.MustCompile(`\s+`)
+)
+
+// SlowQuery is a captured query that exceeded the slow query threshold
+type SlowQuery struct {
+	Fingerprint string    `json:"fingerprint"`
+	DurationMs  float64   `json:"duration_ms"`
+	Rows        int64     `json:"rows"`
+	Route       string    `json:"route,omitempty"`
+	RequestID   string    `json:"request_id,omitempty"`
+	CapturedAt  time.Time `json:"captured_at"`
+}
+
+// SlowQueryStats aggregates captured slow queries sharing a fingerprint
+type SlowQueryStats struct {
+	Fingerprint string    `json:"fingerprint"`
+	Count       int       `json:"count"`
+	MaxMs       float64   `json:"max_ms"`
+	P95Ms       float64   `json:"p95_ms"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// SlowQueryLog keeps the most recent slow queries in a fixed-size ring buffer
+type SlowQueryLog struct {
+	threshold  time.Duration
+	sampleRate float64
+
+	mu      sync.Mutex
+	entries []SlowQuery
+	next    int
+	full    bool
+}
+
+// NewSlowQueryLog creates a new SlowQueryLog. sampleRate is the fraction
+// (0-1] of slow queries that are captured.
+func NewSlowQueryLog(threshold time.Duration, capacity int, sampleRate float64) *SlowQueryLog {
+	if capacity <= 0 {
+		capacity = 200
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	return &SlowQueryLog{
+		threshold:  threshold,
+		sampleRate: sampleRate,
+		entries:    make([]SlowQuery, capacity),
+	}
+}
+
+// Threshold returns the duration above which queries are captured
+func (l *SlowQueryLog) Threshold() time.Duration {
+	return l.threshold
+}
+
+// Record captures a query if it exceeded the threshold. The SQL is only
+// rendered for slow, sampled queries, and is stored as a fingerprint with
+// all literal values stripped.
+func (l *SlowQueryLog) Record(ctx context.Context, elapsed time.Duration, fc func() (string, int64)) {
+	if elapsed < l.threshold {
+		return
+	}
+	if l.sampleRate < 1 && rand.Float64() >= l.sampleRate {
+		return
+	}
+
+	sql, rows := fc()
+	entry := SlowQuery{
+		Fingerprint: Fingerprint(sql),
+		DurationMs:  float64(elapsed.Microseconds()) / 1000,
+		Rows:        rows,
+		Route:       contextString(ctx, ContextKeyRoute),
+		RequestID:   contextString(ctx, ContextKeyRequestID),
+		CapturedAt:  time.Now(),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Entries returns the captured slow queries, newest first
+func (l *SlowQueryLog) Entries() []SlowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+
+	entries := make([]SlowQuery, 0, count)
+	for i := 1; i <= count; i++ {
+		idx := (l.next - i + len(l.entries)) % len(l.entries)
+		entries = append(entries, l.entries[idx])
+	}
+	return entries
+}
+
+// Summary aggregates captured slow queries by fingerprint, slowest first
+func (l *SlowQueryLog) Summary() []SlowQueryStats {
+	durations := make(map[string][]float64)
+	lastSeen := make(map[string]time.Time)
+	for _, entry := range l.Entries() {
+		durations[entry.Fingerprint] = append(durations[entry.Fingerprint], entry.DurationMs)
+		if entry.CapturedAt.After(lastSeen[entry.Fingerprint]) {
+			lastSeen[entry.Fingerprint] = entry.CapturedAt
+		}
+	}
+
+	stats := make([]SlowQueryStats, 0, len(durations))
+	for fingerprint, values := range durations {
+		sort.Float64s(values)
+		p95 := values[int(float64(len(values)-1)*0.95)]
+		stats = append(stats, SlowQueryStats{
+			Fingerprint: fingerprint,
+			Count:       len(values),
+			MaxMs:       values[len(values)-1],
+			P95Ms:       p95,
+			LastSeenAt:  lastSeen[fingerprint],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].MaxMs > stats[j].MaxMs
+	})
+	return stats
+}
+
+// Reset clears all captured slow queries
+func (l *SlowQueryLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = make([]SlowQuery, len(l.entries))
+	l.next = 0
+	l.full = false
+}
+
+// Fingerprint normalizes a SQL statement by replacing string and numeric
+// literals with placeholders and collapsing IN lists and whitespace
+func Fingerprint(sql string) string {
+	sql = stringLiteralPattern.ReplaceAllString(sql, "?")
+	sql = numberLiteralPattern.ReplaceAllString(sql, "?")
+	sql = inListPattern.ReplaceAllString(sql, "(?)")
+	sql = whitespacePattern.ReplaceAllString(sql, " ")
+	return strings.TrimSpace(sql)
+}
+
+// contextString reads a string value from a request context
+func contextString(ctx context.Context, key string) string {
+	if ctx == nil {
+		return ""
+	}
+	value, _ := ctx.Value(key).(string)
+	return value
+}
+
+// slowQueryLogger wraps a GORM logger and feeds traced queries to a SlowQueryLog
+type slowQueryLogger struct {
+	logger.Interface
+	slowQueries *SlowQueryLog
+}
+
+// newSlowQueryLogger wraps a GORM logger with slow query capture
+func newSlowQueryLogger(inner logger.Interface, slowQueries *SlowQueryLog) logger.Interface {
+	return &slowQueryLogger{Interface: inner, slowQueries: slowQueries}
+}
+
+// LogMode sets the log level of the wrapped logger
+func (l *slowQueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &slowQueryLogger{Interface: l.Interface.LogMode(level), slowQueries: l.slowQueries}
+}
+
+// Trace logs the query and captures it if it was slow
+func (l *slowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.Interface.Trace(ctx, begin, fc, err)
+	l.slowQueries.Record(ctx, time.Since(begin), fc)
+}
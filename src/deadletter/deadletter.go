@@ -0,0 +1,109 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// ErrNoRetrier is returned when no component has registered a retry handler
+var ErrNoRetrier = errors.New("no retry handler registered for component")
+
+// RetryFunc re-enqueues a dead-lettered payload for its component
+type RetryFunc func(payload json.RawMessage) error
+
+var deadLettersTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "crm_dead_letters_total",
+		Help: "Total number of async work items dead-lettered after exhausting retries",
+	},
+	[]string{"component"},
+)
+
+func init() {
+	prometheus.MustRegister(deadLettersTotal)
+}
+
+// Queue stores failed async work and requeues it through component retriers
+type Queue struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	retriers map[string]RetryFunc
+}
+
+// NewQueue creates a new dead-letter Queue
+func NewQueue(db *gorm.DB) *Queue {
+	return &Queue{
+		db:       db,
+		retriers: make(map[string]RetryFunc),
+	}
+}
+
+// RegisterRetrier registers the function used to requeue a component's work
+func (q *Queue) RegisterRetrier(component string, retry RetryFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retriers[component] = retry
+}
+
+// Add records a work item whose retries were exhausted
+func (q *Queue) Add(component string, payload interface{}, cause error, attempts int) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter payload: %w", err)
+	}
+
+	message := ""
+	if cause != nil {
+		message = cause.Error()
+	}
+
+	now := time.Now()
+	letter := models.DeadLetter{
+		Component:     component,
+		Payload:       string(data),
+		Error:         message,
+		Attempts:      attempts,
+		Status:        models.DeadLetterStatusPending,
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+	}
+	if err := q.db.Create(&letter).Error; err != nil {
+		return fmt.Errorf("failed to store dead letter: %w", err)
+	}
+
+	deadLettersTotal.WithLabelValues(component).Inc()
+	return nil
+}
+
+// Retry requeues a dead letter through its component's retrier and marks
+// it requeued through db, so callers can save it in their transaction. A
+// failed retry keeps the item pending and records the new error.
+func (q *Queue) Retry(db *gorm.DB, letter *models.DeadLetter) error {
+	q.mu.RLock()
+	retry, ok := q.retriers[letter.Component]
+	q.mu.RUnlock()
+	if !ok {
+		return ErrNoRetrier
+	}
+
+	if err := retry(json.RawMessage(letter.Payload)); err != nil {
+		letter.Attempts++
+		letter.LastFailedAt = time.Now()
+		letter.Error = err.Error()
+		q.db.Save(letter)
+		return err
+	}
+
+	now := time.Now()
+	letter.Status = models.DeadLetterStatusRequeued
+	letter.RequeuedAt = &now
+	return db.Save(letter).Error
+}
@@ -0,0 +1,234 @@
+// Package dealdefaults suggests field values for new deals from a
+// customer's deal history, falling back to configured settings.
+package dealdefaults
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Sources a suggested value can come from
+const (
+	SourceHistory  = "history"  // The customer's past deals
+	SourceCustomer = "customer" // The customer record itself
+	SourceSettings = "settings" // Configured defaults
+	SourceNone     = "none"     // No suggestion available
+)
+
+// minClosedDeals is the number of closed deals needed before the customer's
+// win rate is suggested as the probability
+const minClosedDeals = 3
+
+// titleHistorySize is the number of recent deal titles searched for a
+// title pattern
+const titleHistorySize = 5
+
+// Settings are the defaults used when a customer has no usable history
+type Settings struct {
+	Currency     string
+	Stage        models.DealStage
+	Probability  int
+	TitlePattern string // May use {customer}, {company}, {year}, {quarter} and {month}
+}
+
+// Field is a suggested value and where it came from
+type Field[T any] struct {
+	Value  T      `json:"value"`
+	Source string `json:"source"`
+}
+
+// Defaults are the suggested values for a new deal of a customer
+type Defaults struct {
+	CustomerID   uint                    `json:"customer_id"`
+	DealCount    int64                   `json:"deal_count"` // Past deals the suggestions are based on
+	Currency     Field[string]           `json:"currency"`
+	OwnerID      Field[*uint]            `json:"owner_id"`
+	Amount       Field[*float64]         `json:"amount"` // Median amount of the customer's deals in the suggested currency
+	Probability  Field[int]              `json:"probability"`
+	Stage        Field[models.DealStage] `json:"stage"`
+	TitlePattern Field[string]           `json:"title_pattern"`
+	Title        string                  `json:"title"` // TitlePattern rendered for today
+}
+
+// Service computes deal defaults
+type Service struct {
+	db       *gorm.DB
+	settings Settings
+}
+
+// New creates a Service. Invalid settings fall back to USD, the
+// prospecting stage and a probability of 0.
+func New(db *gorm.DB, settings Settings) *Service {
+	settings.Currency = strings.ToUpper(strings.TrimSpace(settings.Currency))
+	if len(settings.Currency) != 3 {
+		settings.Currency = "USD"
+	}
+	if !models.IsValidDealStage(settings.Stage) {
+		settings.Stage = models.DealStageProspecting
+	}
+	if settings.Probability < 0 || settings.Probability > 100 {
+		settings.Probability = 0
+	}
+	if strings.TrimSpace(settings.TitlePattern) == "" {
+		settings.TitlePattern = "{company} - {month} {year}"
+	}
+	return &Service{db: db, settings: settings}
+}
+
+// Settings returns the configured defaults
+func (s *Service) Settings() Settings {
+	return s.settings
+}
+
+// Suggest computes the defaults for a new deal of the customer. Archived
+// deals count as history; deleted ones do not.
+func (s *Service) Suggest(ctx context.Context, customer models.Customer, now time.Time) (Defaults, error) {
+	db := s.db.WithContext(ctx)
+	deals := func() *gorm.DB {
+		return db.Model(&models.Deal{}).Where("customer_id = ?", customer.ID)
+	}
+
+	d := Defaults{
+		CustomerID:   customer.ID,
+		Currency:     Field[string]{Value: s.settings.Currency, Source: SourceSettings},
+		OwnerID:      Field[*uint]{Source: SourceNone},
+		Amount:       Field[*float64]{Source: SourceNone},
+		Probability:  Field[int]{Value: s.settings.Probability, Source: SourceSettings},
+		Stage:        Field[models.DealStage]{Value: s.settings.Stage, Source: SourceSettings},
+		TitlePattern: Field[string]{Value: s.settings.TitlePattern, Source: SourceSettings},
+	}
+
+	if err := deals().Count(&d.DealCount).Error; err != nil {
+		return d, err
+	}
+
+	if customer.AssignedTo != nil {
+		d.OwnerID = Field[*uint]{Value: customer.AssignedTo, Source: SourceCustomer}
+	}
+
+	if d.DealCount > 0 {
+		// Most used currency, the most recently used one on ties
+		var currencies []string
+		if err := deals().Select("currency").Group("currency").
+			Order("COUNT(*) DESC, MAX(created_at) DESC").Limit(1).
+			Pluck("currency", &currencies).Error; err != nil {
+			return d, err
+		}
+		if len(currencies) > 0 && currencies[0] != "" {
+			d.Currency = Field[string]{Value: currencies[0], Source: SourceHistory}
+		}
+
+		var amounts []float64
+		if err := deals().Where("currency = ? AND stage <> ? AND amount > 0", d.Currency.Value, models.DealStageClosedLost).
+			Pluck("percentile_cont(0.5) WITHIN GROUP (ORDER BY amount)", &amounts).Error; err != nil {
+			return d, err
+		}
+		if len(amounts) > 0 && amounts[0] > 0 {
+			amount := math.Round(amounts[0]*100) / 100
+			d.Amount = Field[*float64]{Value: &amount, Source: SourceHistory}
+		}
+
+		var closed struct {
+			Won   int64
+			Total int64
+		}
+		if err := deals().Select("COUNT(*) FILTER (WHERE stage = ?) AS won, COUNT(*) AS total", models.DealStageClosedWon).
+			Where("stage IN ?", []models.DealStage{models.DealStageClosedWon, models.DealStageClosedLost}).
+			Scan(&closed).Error; err != nil {
+			return d, err
+		}
+		if closed.Total >= minClosedDeals {
+			d.Probability = Field[int]{Value: int(math.Round(float64(closed.Won) * 100 / float64(closed.Total))), Source: SourceHistory}
+		}
+
+		if d.OwnerID.Value == nil {
+			var owners []uint
+			if err := deals().Where("owner_id IS NOT NULL").Order("created_at DESC").Limit(1).
+				Pluck("owner_id", &owners).Error; err != nil {
+				return d, err
+			}
+			if len(owners) > 0 {
+				owner := owners[0]
+				d.OwnerID = Field[*uint]{Value: &owner, Source: SourceHistory}
+			}
+		}
+
+		var titles []string
+		if err := deals().Order("created_at DESC").Limit(titleHistorySize).Pluck("title", &titles).Error; err != nil {
+			return d, err
+		}
+		if pattern := TitlePattern(titles, customer); pattern != "" {
+			d.TitlePattern = Field[string]{Value: pattern, Source: SourceHistory}
+		}
+	}
+
+	d.Title = RenderTitle(d.TitlePattern.Value, customer, now)
+	return d, nil
+}
+
+var (
+	yearPattern    = regexp.MustCompile(`\b20\d{2}\b`)
+	quarterPattern = regexp.MustCompile(`\bQ[1-4]\b`)
+	monthPattern   = regexp.MustCompile(`\b(` + monthNames() + `)\b`)
+)
+
+// monthNames returns the English month names as a regexp alternation
+func monthNames() string {
+	names := make([]string, 12)
+	for m := time.January; m <= time.December; m++ {
+		names[m-1] = m.String()
+	}
+	return strings.Join(names, "|")
+}
+
+// TitlePattern derives a title pattern from recent deal titles, newest
+// first, by replacing the customer's company and name, years, quarters and
+// month names with placeholders. The most common pattern with at least one
+// placeholder wins, the newest on ties; it is empty when none has one.
+func TitlePattern(titles []string, customer models.Customer) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, title := range titles {
+		pattern := title
+		if customer.Company != "" {
+			pattern = strings.ReplaceAll(pattern, customer.Company, "{company}")
+		}
+		if customer.Name != "" {
+			pattern = strings.ReplaceAll(pattern, customer.Name, "{customer}")
+		}
+		pattern = yearPattern.ReplaceAllString(pattern, "{year}")
+		pattern = quarterPattern.ReplaceAllString(pattern, "{quarter}")
+		pattern = monthPattern.ReplaceAllString(pattern, "{month}")
+		if pattern == title {
+			continue
+		}
+		counts[pattern]++
+		if best == "" || counts[pattern] > counts[best] {
+			best = pattern
+		}
+	}
+	return best
+}
+
+// RenderTitle fills in the placeholders of a title pattern. {company} falls
+// back to the customer's name when the customer has no company.
+func RenderTitle(pattern string, customer models.Customer, now time.Time) string {
+	company := customer.Company
+	if company == "" {
+		company = customer.Name
+	}
+	return strings.NewReplacer(
+		"{customer}", customer.Name,
+		"{company}", company,
+		"{year}", strconv.Itoa(now.Year()),
+		"{quarter}", "Q"+strconv.Itoa((int(now.Month())+2)/3),
+		"{month}", now.Month().String(),
+	).Replace(pattern)
+}
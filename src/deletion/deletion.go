@@ -0,0 +1,323 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// DefaultBatchSize is the number of records deleted per table and batch
+const DefaultBatchSize = 500
+
+// ErrRunInProgress is returned when a run is requested while one is active
+var ErrRunInProgress = errors.New("customer deletion run already in progress")
+
+// Requester identifies who asked for a deletion, for its audit entry
+type Requester struct {
+	UserID    uint
+	UserName  string
+	UserRole  string
+	IPAddress string
+	UserAgent string
+}
+
+// Runner deletes customers along with their contacts, deals, activities and
+// notes. Customers with more dependents than the sync limit are hidden at
+// once and their dependents are deleted in batches by Run, which resumes
+// unfinished deletions after a restart.
+type Runner struct {
+	db        *gorm.DB
+	syncLimit int64
+	batchSize int
+	wake      chan struct{}
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewRunner creates a new Runner. Customers with more than syncLimit
+// dependent records are deleted in the background.
+func NewRunner(db *gorm.DB, syncLimit int) *Runner {
+	return &Runner{
+		db:        db,
+		syncLimit: int64(syncLimit),
+		batchSize: DefaultBatchSize,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Wakeups signals when a background deletion was requested
+func (r *Runner) Wakeups() <-chan struct{} {
+	return r.wake
+}
+
+// Delete deletes a customer. When its dependents fit within the sync limit
+// they are deleted with it in one transaction and the returned deletion is
+// nil. Otherwise the customer is soft-deleted, hiding it everywhere, and a
+// pending deletion is returned for Run to finish.
+func (r *Runner) Delete(ctx context.Context, customer *models.Customer, by Requester) (*models.CustomerDeletion, models.CustomerDeletionSummary, error) {
+	var pending *models.CustomerDeletion
+	var summary models.CustomerDeletionSummary
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expected, err := Count(tx, customer.ID)
+		if err != nil {
+			return err
+		}
+
+		if expected.Total() <= r.syncLimit {
+			if summary, err = deleteBatch(tx, customer.ID, 0); err != nil {
+				return err
+			}
+			if err := tx.Delete(customer).Error; err != nil {
+				return err
+			}
+			return audittrail.Record(ctx, tx, audit(customer, summary, by))
+		}
+
+		if err := tx.Delete(customer).Error; err != nil {
+			return err
+		}
+		pending = &models.CustomerDeletion{
+			CustomerID:      customer.ID,
+			Status:          models.CustomerDeletionPending,
+			Expected:        expected,
+			RequestedBy:     by.UserID,
+			RequestedByName: by.UserName,
+			RequestedByRole: by.UserRole,
+			IPAddress:       by.IPAddress,
+			UserAgent:       by.UserAgent,
+		}
+		return tx.Create(pending).Error
+	})
+	if err != nil {
+		return nil, summary, err
+	}
+
+	if pending != nil {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+	return pending, summary, nil
+}
+
+// Run works through every unfinished deletion, continuing where an earlier
+// run stopped. A failing deletion is marked failed and retried by the next
+// run; the others still proceed. It returns the number completed.
+func (r *Runner) Run(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return 0, ErrRunInProgress
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+	}()
+
+	var deletions []models.CustomerDeletion
+	if err := r.db.WithContext(ctx).
+		Where("status <> ?", models.CustomerDeletionCompleted).
+		Order("id ASC").
+		Find(&deletions).Error; err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	var errs []error
+	for i := range deletions {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := r.process(ctx, &deletions[i]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		completed++
+	}
+	return completed, errors.Join(errs...)
+}
+
+// process deletes one customer's dependents batch by batch, recording
+// progress after each, and completes the deletion once none are left
+func (r *Runner) process(ctx context.Context, deletion *models.CustomerDeletion) error {
+	db := r.db.WithContext(ctx)
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":   models.CustomerDeletionRunning,
+		"attempts": gorm.Expr("attempts + 1"),
+		"error":    "",
+	}
+	if deletion.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	if err := db.Model(deletion).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	for {
+		var batch models.CustomerDeletionSummary
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if batch, err = deleteBatch(tx, deletion.CustomerID, r.batchSize); err != nil {
+				return err
+			}
+			if batch.Total() == 0 {
+				return nil
+			}
+			deletion.Deleted.Add(batch)
+			return tx.Model(deletion).Select("deleted_contacts", "deleted_deals", "deleted_activities", "deleted_notes").
+				Updates(deletion).Error
+		})
+		if err != nil {
+			// Batches already committed stay deleted; the next run resumes
+			db.Model(deletion).Updates(map[string]interface{}{
+				"status": models.CustomerDeletionFailed,
+				"error":  err.Error(),
+			})
+			return err
+		}
+		if batch.Total() == 0 {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var customer models.Customer
+		if err := tx.Unscoped().First(&customer, deletion.CustomerID).Error; err != nil {
+			return err
+		}
+		finished := time.Now()
+		if err := tx.Model(deletion).Updates(map[string]interface{}{
+			"status":      models.CustomerDeletionCompleted,
+			"finished_at": finished,
+		}).Error; err != nil {
+			return err
+		}
+		return audittrail.Record(ctx, tx, audit(&customer, deletion.Deleted, Requester{
+			UserID:    deletion.RequestedBy,
+			UserName:  deletion.RequestedByName,
+			UserRole:  deletion.RequestedByRole,
+			IPAddress: deletion.IPAddress,
+			UserAgent: deletion.UserAgent,
+		}))
+	})
+}
+
+// Count counts the live contacts, deals, activities and notes of a customer
+func Count(tx *gorm.DB, customerID uint) (models.CustomerDeletionSummary, error) {
+	var summary models.CustomerDeletionSummary
+	counts := []struct {
+		query *gorm.DB
+		total *int64
+	}{
+		{contactsOf(tx, customerID), &summary.Contacts},
+		{dealsOf(tx, customerID), &summary.Deals},
+		{activitiesOf(tx, customerID), &summary.Activities},
+		{notesOf(tx, customerID), &summary.Notes},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.total).Error; err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// deleteBatch soft-deletes up to limit records per table, notes first so
+// that no note outlives its activity or deal. limit <= 0 deletes all.
+// Records are deleted through the models so quota usage and prefetch
+// caches follow.
+func deleteBatch(tx *gorm.DB, customerID uint, limit int) (models.CustomerDeletionSummary, error) {
+	var summary models.CustomerDeletionSummary
+	steps := []struct {
+		query   *gorm.DB
+		model   interface{}
+		deleted *int64
+	}{
+		{notesOf(tx, customerID), &models.Note{}, &summary.Notes},
+		{activitiesOf(tx, customerID), &models.Activity{}, &summary.Activities},
+		{dealsOf(tx, customerID), &models.Deal{}, &summary.Deals},
+		{contactsOf(tx, customerID), &models.Contact{}, &summary.Contacts},
+	}
+	for _, step := range steps {
+		ids := step.query.Select("id")
+		if limit > 0 {
+			ids = ids.Order("id ASC").Limit(limit)
+		}
+		result := tx.Where("id IN (?)", ids).Delete(step.model)
+		if result.Error != nil {
+			return summary, result.Error
+		}
+		*step.deleted = result.RowsAffected
+	}
+	return summary, nil
+}
+
+// contactsOf selects a customer's live contacts
+func contactsOf(tx *gorm.DB, customerID uint) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.Contact{}).
+		Where("customer_id = ?", customerID)
+}
+
+// dealsOf selects a customer's live deals
+func dealsOf(tx *gorm.DB, customerID uint) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.Deal{}).
+		Where("customer_id = ?", customerID)
+}
+
+// activitiesOf selects live activities of a customer or of its deals,
+// including deals deleted by an earlier batch
+func activitiesOf(tx *gorm.DB, customerID uint) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.Activity{}).
+		Where("customer_id = ? OR deal_id IN (?)", customerID, allDealIDs(tx, customerID))
+}
+
+// notesOf selects live notes of a customer or of its deals or activities,
+// including those deleted by an earlier batch
+func notesOf(tx *gorm.DB, customerID uint) *gorm.DB {
+	deals := allDealIDs(tx, customerID)
+	activities := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Activity{}).
+		Select("id").
+		Where("customer_id = ? OR deal_id IN (?)", customerID, deals)
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.Note{}).
+		Where("customer_id = ? OR deal_id IN (?) OR activity_id IN (?)", customerID, deals, activities)
+}
+
+// allDealIDs selects the IDs of a customer's deals, deleted or not
+func allDealIDs(tx *gorm.DB, customerID uint) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Deal{}).
+		Select("id").
+		Where("customer_id = ?", customerID)
+}
+
+// audit builds the delete audit entry of a customer, summarizing what was
+// deleted along with it
+func audit(customer *models.Customer, summary models.CustomerDeletionSummary, by Requester) *models.AuditLog {
+	entry := &models.AuditLog{
+		ResourceType: "customer",
+		ResourceID:   customer.ID,
+		Action:       models.AuditActionDelete,
+		UserID:       by.UserID,
+		UserName:     by.UserName,
+		UserRole:     by.UserRole,
+		IPAddress:    by.IPAddress,
+		UserAgent:    by.UserAgent,
+	}
+	entry.OldValues, _ = models.AuditDiff(customer, nil)
+	_, entry.NewValues = models.AuditDiff(nil, &summary)
+	return entry
+}
@@ -0,0 +1,193 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+var requester = Requester{UserID: 1, UserName: "Admin", UserRole: models.RoleAdmin}
+
+// graph creates a customer with 2 contacts, a deal, 3 activities of which
+// one is on the deal alone, and 3 notes: on the customer, the deal and the
+// deal's activity. It returns the customer and a bystander customer with
+// one of each.
+func graph(t *testing.T, f *factory.Factory) (models.Customer, models.Customer) {
+	t.Helper()
+	customer := f.Customer(t)
+	f.Contact(t, customer)
+	f.Contact(t, customer)
+	deal := f.Deal(t, customer)
+	f.Activity(t, customer)
+	f.Activity(t, customer)
+	dealActivity := f.Activity(t, customer, func(a *models.Activity) { a.CustomerID, a.DealID = nil, &deal.ID })
+	f.Note(t, customer)
+	f.Note(t, customer, func(n *models.Note) { n.CustomerID, n.DealID = nil, &deal.ID })
+	f.Note(t, customer, func(n *models.Note) { n.CustomerID, n.ActivityID = nil, &dealActivity.ID })
+
+	bystander := f.Customer(t)
+	f.Contact(t, bystander)
+	f.Deal(t, bystander)
+	f.Activity(t, bystander)
+	f.Note(t, bystander)
+	return customer, bystander
+}
+
+var whole = models.CustomerDeletionSummary{Contacts: 2, Deals: 1, Activities: 3, Notes: 3}
+
+// live counts the dependents of a customer that are not deleted
+func live(t *testing.T, db *gorm.DB, customerID uint) models.CustomerDeletionSummary {
+	t.Helper()
+	summary, err := Count(db, customerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
+
+func TestDeleteSync(t *testing.T) {
+	testDB := testdb.New(t, factory.Epoch)
+	customer, bystander := graph(t, factory.New(testDB.DB))
+	runner := NewRunner(testDB.DB, 9)
+
+	pending, summary, err := runner.Delete(context.Background(), &customer, requester)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != nil || summary != whole {
+		t.Errorf("pending = %+v, summary = %+v, want %+v deleted at once", pending, summary, whole)
+	}
+	if got := live(t, testDB.DB, customer.ID); got.Total() != 0 {
+		t.Errorf("left = %+v", got)
+	}
+	if got := live(t, testDB.DB, bystander.ID); got.Total() != 4 {
+		t.Errorf("bystander left = %+v, want it untouched", got)
+	}
+	if n := testDB.Count("customer_deletions"); n != 0 {
+		t.Errorf("%d deletions queued", n)
+	}
+	if n := testDB.Count("audit_logs"); n != 1 {
+		t.Errorf("%d audit entries, want the delete", n)
+	}
+}
+
+func TestDeleteInBackground(t *testing.T) {
+	testDB := testdb.New(t, factory.Epoch)
+	customer, bystander := graph(t, factory.New(testDB.DB))
+	runner := NewRunner(testDB.DB, 8)
+	runner.batchSize = 1
+
+	pending, _, err := runner.Delete(context.Background(), &customer, requester)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending == nil || pending.Status != models.CustomerDeletionPending || pending.Expected != whole {
+		t.Fatalf("pending = %+v", pending)
+	}
+	select {
+	case <-runner.Wakeups():
+	default:
+		t.Error("the background job was not woken")
+	}
+
+	// The customer is hidden at once, its dependents are not deleted yet
+	if err := testDB.DB.First(&models.Customer{}, customer.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("customer lookup: %v, want it hidden", err)
+	}
+	if got := live(t, testDB.DB, customer.ID); got != whole {
+		t.Errorf("left = %+v, want everything until the job runs", got)
+	}
+	if n := testDB.Count("audit_logs"); n != 0 {
+		t.Errorf("%d audit entries before the job ran", n)
+	}
+
+	completed, err := runner.Run(context.Background())
+	if err != nil || completed != 1 {
+		t.Fatalf("run: %d, %v", completed, err)
+	}
+	var deletion models.CustomerDeletion
+	if err := testDB.DB.First(&deletion, pending.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deletion.Status != models.CustomerDeletionCompleted || deletion.Deleted != whole || deletion.FinishedAt == nil || deletion.Attempts != 1 {
+		t.Errorf("deletion = %+v", deletion)
+	}
+	if got := live(t, testDB.DB, customer.ID); got.Total() != 0 {
+		t.Errorf("left = %+v", got)
+	}
+	if got := live(t, testDB.DB, bystander.ID); got.Total() != 4 {
+		t.Errorf("bystander left = %+v, want it untouched", got)
+	}
+	var entry models.AuditLog
+	if err := testDB.DB.First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if entry.ResourceID != customer.ID || entry.Action != models.AuditActionDelete || entry.UserID != requester.UserID {
+		t.Errorf("audit entry = %+v", entry)
+	}
+
+	// Nothing is left to do
+	if completed, err := runner.Run(context.Background()); err != nil || completed != 0 {
+		t.Errorf("second run: %d, %v", completed, err)
+	}
+}
+
+// TestDeleteResumes fails a deletion midway, as a crash would, and checks
+// that the next run finishes it counting every record once
+func TestDeleteResumes(t *testing.T) {
+	testDB := testdb.New(t, factory.Epoch)
+	customer, _ := graph(t, factory.New(testDB.DB))
+	runner := NewRunner(testDB.DB, 0)
+	runner.batchSize = 1
+
+	pending, _, err := runner.Delete(context.Background(), &customer, requester)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second batch fails, as a crash would stop it
+	errDown := errors.New("connection reset")
+	activityDeletes := 0
+	err = testDB.DB.Callback().Delete().Before("gorm:delete").Register("test:fail_second_batch", func(tx *gorm.DB) {
+		if tx.Statement.Table == "activities" {
+			if activityDeletes++; activityDeletes == 2 {
+				tx.AddError(errDown)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed, err := runner.Run(context.Background()); !errors.Is(err, errDown) || completed != 0 {
+		t.Fatalf("failing run: %d, %v", completed, err)
+	}
+	var deletion models.CustomerDeletion
+	if err := testDB.DB.First(&deletion, pending.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	first := models.CustomerDeletionSummary{Contacts: 1, Deals: 1, Activities: 1, Notes: 1}
+	if deletion.Status != models.CustomerDeletionFailed || deletion.Error == "" || deletion.Deleted != first {
+		t.Fatalf("failed deletion = %+v, want the first batch recorded", deletion)
+	}
+	if got := live(t, testDB.DB, customer.ID); got != (models.CustomerDeletionSummary{Contacts: 1, Activities: 2, Notes: 2}) {
+		t.Errorf("left after the failure = %+v", got)
+	}
+
+	if completed, err := runner.Run(context.Background()); err != nil || completed != 1 {
+		t.Fatalf("resumed run: %d, %v", completed, err)
+	}
+	if err := testDB.DB.First(&deletion, pending.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deletion.Status != models.CustomerDeletionCompleted || deletion.Deleted != whole || deletion.Attempts != 2 || deletion.Error != "" {
+		t.Errorf("resumed deletion = %+v", deletion)
+	}
+	if n := testDB.Count("audit_logs"); n != 1 {
+		t.Errorf("%d audit entries, want one", n)
+	}
+}
@@ -0,0 +1,64 @@
+// Package emaildelivery verifies and normalizes the delivery status webhooks
+// of email providers, such as deliveries, bounces and spam complaints.
+package emaildelivery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+var (
+	// ErrInvalidSignature is returned when a webhook is not signed by the
+	// provider, or its signature is stale
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrInvalidPayload is returned when a webhook body cannot be parsed
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+)
+
+// Event is a delivery event normalized from a provider payload
+type Event struct {
+	ID         string // Provider event ID, used to ignore redelivered events
+	MessageID  string // Message ID recorded on the email activity when sent
+	ActivityID uint   // Email activity ID, when the provider echoes it back
+	Type       models.EmailEventType
+	Email      string
+	Reason     string
+	OccurredAt time.Time
+}
+
+// Nonce identifies the event among the events of its type from provider.
+// Events without an ID are identified by their content instead.
+func (e Event) Nonce(provider string) string {
+	key := e.ID
+	if key == "" {
+		key = strings.Join([]string{e.MessageID, e.Email, e.OccurredAt.UTC().Format(time.RFC3339Nano), e.Reason}, "|")
+	}
+	sum := sha256.Sum256([]byte(provider + ":" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Domain returns the domain of the event's recipient address
+func (e Event) Domain() string {
+	_, domain, ok := strings.Cut(e.Email, "@")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(domain))
+}
+
+// Provider verifies and parses the webhooks of one email provider
+type Provider interface {
+	// Verify checks that body was signed by the provider
+	Verify(header http.Header, body []byte, now time.Time) error
+
+	// Parse returns the delivery events of a verified body. Event kinds the
+	// CRM does not track are left out.
+	Parse(body []byte) ([]Event, error)
+}
@@ -0,0 +1,104 @@
+package emaildelivery
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// Generic webhook signature headers
+const (
+	GenericSignatureHeader = "X-Webhook-Signature"
+	GenericTimestampHeader = "X-Webhook-Timestamp"
+)
+
+// GenericMaxSkew bounds how old a signed generic webhook may be
+const GenericMaxSkew = 5 * time.Minute
+
+// Generic accepts the CRM's own webhook schema, for providers without an
+// adapter or a relay in front of them:
+//
+//	{"events": [{"id": "evt_1", "message_id": "<abc@mail.example>", "activity_id": 12,
+//	  "type": "bounce", "email": "jane@example.com", "reason": "550 mailbox unavailable",
+//	  "timestamp": "2026-10-16T09:30:00Z"}]}
+//
+// Bodies are signed with a hex HMAC-SHA256 of "<timestamp>.<body>" in
+// X-Webhook-Signature, where timestamp is the Unix time in
+// X-Webhook-Timestamp.
+type Generic struct {
+	secret []byte
+}
+
+// NewGeneric creates a Generic provider signed with secret
+func NewGeneric(secret string) *Generic {
+	return &Generic{secret: []byte(secret)}
+}
+
+// Verify checks the HMAC signature and that it is recent
+func (g *Generic) Verify(header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(GenericTimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > GenericMaxSkew || skew < -GenericMaxSkew {
+		return ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(header.Get(GenericSignatureHeader))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// genericPayload is the body of a generic webhook
+type genericPayload struct {
+	Events []struct {
+		ID         string                `json:"id"`
+		MessageID  string                `json:"message_id"`
+		ActivityID uint                  `json:"activity_id"`
+		Type       models.EmailEventType `json:"type"`
+		Email      string                `json:"email"`
+		Reason     string                `json:"reason"`
+		Timestamp  time.Time             `json:"timestamp"`
+	} `json:"events"`
+}
+
+// Parse returns the events of a generic webhook. Types must be delivery
+// event types; others are left out.
+func (g *Generic) Parse(body []byte) ([]Event, error) {
+	var payload genericPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrInvalidPayload
+	}
+
+	events := make([]Event, 0, len(payload.Events))
+	for _, e := range payload.Events {
+		if !models.IsDeliveryEvent(e.Type) {
+			continue
+		}
+		events = append(events, Event{
+			ID:         e.ID,
+			MessageID:  e.MessageID,
+			ActivityID: e.ActivityID,
+			Type:       e.Type,
+			Email:      e.Email,
+			Reason:     e.Reason,
+			OccurredAt: e.Timestamp,
+		})
+	}
+	return events, nil
+}
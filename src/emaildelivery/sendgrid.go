@@ -0,0 +1,153 @@
+package emaildelivery
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// SendGrid signed event webhook headers
+const (
+	SendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	SendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// SendGrid accepts SendGrid event webhooks. Emails are matched by the
+// X-Message-Id SendGrid returned when the email was sent, or by an
+// activity_id custom argument set on the message.
+type SendGrid struct {
+	key *ecdsa.PublicKey
+}
+
+// NewSendGrid creates a SendGrid provider from the base64 verification key
+// shown in SendGrid's signed event webhook settings
+func NewSendGrid(publicKey string) (*SendGrid, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil {
+		return nil, errors.New("sendgrid: verification key is not base64")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errors.New("sendgrid: invalid verification key")
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("sendgrid: verification key is not an ECDSA key")
+	}
+	return &SendGrid{key: key}, nil
+}
+
+// Verify checks the ECDSA signature over the timestamp and body. SendGrid
+// retries failed deliveries for up to three days, so the timestamp is not
+// checked for age; redelivered events are ignored by their event ID.
+func (s *SendGrid) Verify(header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(SendGridTimestampHeader)
+	signature, err := base64.StdEncoding.DecodeString(header.Get(SendGridSignatureHeader))
+	if timestamp == "" || err != nil {
+		return ErrInvalidSignature
+	}
+
+	digest := sha256.New()
+	digest.Write([]byte(timestamp))
+	digest.Write(body)
+	if !ecdsa.VerifyASN1(s.key, digest.Sum(nil), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// sendGridEvent is one event of a SendGrid webhook
+type sendGridEvent struct {
+	Event      string        `json:"event"`
+	Type       string        `json:"type"` // "bounce" or "blocked", for bounce events
+	Email      string        `json:"email"`
+	Timestamp  int64         `json:"timestamp"`
+	Reason     string        `json:"reason"`
+	Response   string        `json:"response"`
+	EventID    string        `json:"sg_event_id"`
+	MessageID  string        `json:"sg_message_id"`
+	ActivityID customArgUint `json:"activity_id"`
+}
+
+// customArgUint reads an ID custom argument, which SendGrid echoes back as
+// sent, so as a string or a number. Unparseable values read as zero.
+type customArgUint uint
+
+func (u *customArgUint) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseUint(strings.Trim(string(data), `"`), 10, 32)
+	if err == nil {
+		*u = customArgUint(value)
+	}
+	return nil
+}
+
+// Parse returns the delivery events of a SendGrid webhook. Engagement
+// events such as opens and clicks are left out; the CRM tracks those itself.
+func (s *SendGrid) Parse(body []byte) ([]Event, error) {
+	var payload []sendGridEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrInvalidPayload
+	}
+
+	events := make([]Event, 0, len(payload))
+	for _, e := range payload {
+		var eventType models.EmailEventType
+		switch e.Event {
+		case "delivered":
+			eventType = models.EmailEventDelivered
+		case "deferred":
+			eventType = models.EmailEventDeferred
+		case "bounce":
+			// Blocks are temporary rejections; the address itself is valid
+			eventType = models.EmailEventBounce
+			if e.Type == "blocked" {
+				eventType = models.EmailEventSoftBounce
+			}
+		case "dropped":
+			eventType = models.EmailEventDropped
+		case "spamreport":
+			eventType = models.EmailEventComplaint
+		default:
+			continue
+		}
+
+		reason := e.Reason
+		if reason == "" {
+			reason = e.Response
+		}
+		var occurredAt time.Time
+		if e.Timestamp > 0 {
+			occurredAt = time.Unix(e.Timestamp, 0)
+		}
+		events = append(events, Event{
+			ID:         e.EventID,
+			MessageID:  sendGridMessageID(e.MessageID),
+			ActivityID: uint(e.ActivityID),
+			Type:       eventType,
+			Email:      e.Email,
+			Reason:     reason,
+			OccurredAt: occurredAt,
+		})
+	}
+	return events, nil
+}
+
+// sendGridMessageID trims the per-recipient suffix SendGrid appends to the
+// X-Message-Id returned when the email was sent
+func sendGridMessageID(id string) string {
+	for _, marker := range []string{".filter", ".recvd"} {
+		if i := strings.Index(id, marker); i >= 0 {
+			return id[:i]
+		}
+	}
+	return id
+}
@@ -0,0 +1,8 @@
+package emailtracking
+
+import "time"
+
+// SetNow replaces the clock tokens are issued and verified by
+func (s *Signer) SetNow(now func() time.Time) {
+	s.now = now
+}
@@ -0,0 +1,140 @@
+package emailtracking
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Purpose restricts what a tracking token may be used for
+type Purpose string
+
+const (
+	PurposeOpen        Purpose = "open"
+	PurposeClick       Purpose = "click"
+	PurposeUnsubscribe Purpose = "unsubscribe"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed, tampered or wrong-purpose tokens
+	ErrInvalidToken = errors.New("invalid tracking token")
+	// ErrExpiredToken is returned for tokens issued longer ago than the TTL
+	ErrExpiredToken = errors.New("expired tracking token")
+	// ErrInvalidLink is returned when a click token is requested for a link
+	// that is not an absolute http or https URL
+	ErrInvalidLink = errors.New("tracked links must be absolute http or https URLs")
+)
+
+// Token is the signed payload of a tracking token. The nonce makes each
+// issued token unique so replays of it can be recorded idempotently.
+// Click tokens carry the link they redirect to, so only signed links are
+// followed.
+type Token struct {
+	ActivityID uint    `json:"a"`
+	Purpose    Purpose `json:"p"`
+	Nonce      string  `json:"n"`
+	IssuedAt   int64   `json:"t"`
+	URL        string  `json:"u,omitempty"`
+}
+
+// DefaultTokenTTL is how long tokens stay valid when no TTL is set
+const DefaultTokenTTL = 365 * 24 * time.Hour
+
+// Signer issues and verifies HMAC-signed tracking tokens
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSigner creates a new Signer whose tokens expire ttl after they are
+// issued, or DefaultTokenTTL after when ttl is not positive
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &Signer{secret: []byte(secret), ttl: ttl, now: time.Now}
+}
+
+// Issue creates a single-purpose token for an email activity
+func (s *Signer) Issue(activityID uint, purpose Purpose) (string, error) {
+	return s.issue(Token{ActivityID: activityID, Purpose: purpose})
+}
+
+// IssueClick creates a click token redirecting to link
+func (s *Signer) IssueClick(activityID uint, link string) (string, error) {
+	if !validLink(link) {
+		return "", ErrInvalidLink
+	}
+	return s.issue(Token{ActivityID: activityID, Purpose: PurposeClick, URL: link})
+}
+
+// issue stamps a token with a nonce and the issue time and signs it
+func (s *Signer) issue(t Token) (string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	t.Nonce = hex.EncodeToString(nonce)
+	t.IssuedAt = s.now().Unix()
+
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded)), nil
+}
+
+// Verify checks a token's signature, purpose and age and returns its
+// payload. Expired tokens are returned with ErrExpiredToken, so a click
+// can still be redirected without being recorded.
+func (s *Signer) Verify(token string, purpose Purpose) (Token, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Token{}, ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(encoded)) {
+		return Token{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Token{}, ErrInvalidToken
+	}
+
+	var t Token
+	if err := json.Unmarshal(payload, &t); err != nil || t.Purpose != purpose || t.ActivityID == 0 || t.Nonce == "" || t.IssuedAt <= 0 {
+		return Token{}, ErrInvalidToken
+	}
+	if (purpose == PurposeClick) != (t.URL != "") || t.URL != "" && !validLink(t.URL) {
+		return Token{}, ErrInvalidToken
+	}
+	if s.now().After(time.Unix(t.IssuedAt, 0).Add(s.ttl)) {
+		return t, ErrExpiredToken
+	}
+	return t, nil
+}
+
+// validLink reports whether a link is an absolute http or https URL
+func validLink(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// sign returns the HMAC-SHA256 of the encoded payload
+func (s *Signer) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,126 @@
+package emailtracking_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/emailtracking"
+)
+
+func TestVerify(t *testing.T) {
+	signer := emailtracking.NewSigner("secret", 30*24*time.Hour)
+	open, err := signer.Issue(7, emailtracking.PurposeOpen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	click, err := signer.IssueClick(7, "https://nakheel.sa/pricing?plan=pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(click, ".")
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		purpose emailtracking.Purpose
+		err     error
+	}{
+		{"open", open, emailtracking.PurposeOpen, nil},
+		{"click", click, emailtracking.PurposeClick, nil},
+		{"wrong purpose", open, emailtracking.PurposeUnsubscribe, emailtracking.ErrInvalidToken},
+		{"open used as click", open, emailtracking.PurposeClick, emailtracking.ErrInvalidToken},
+		{"other key", mustIssue(t, emailtracking.NewSigner("other", 0), emailtracking.PurposeOpen), emailtracking.PurposeOpen, emailtracking.ErrInvalidToken},
+		{"tampered payload", payload[:len(payload)-2] + "xx." + signature, emailtracking.PurposeClick, emailtracking.ErrInvalidToken},
+		{"no signature", payload, emailtracking.PurposeClick, emailtracking.ErrInvalidToken},
+		{"empty", "", emailtracking.PurposeOpen, emailtracking.ErrInvalidToken},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := signer.Verify(tc.token, tc.purpose)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+			if err == nil && token.ActivityID != 7 {
+				t.Errorf("token = %+v", token)
+			}
+		})
+	}
+
+	token, _ := signer.Verify(click, emailtracking.PurposeClick)
+	if token.URL != "https://nakheel.sa/pricing?plan=pro" {
+		t.Errorf("click URL = %q", token.URL)
+	}
+}
+
+func TestIssueClickLinks(t *testing.T) {
+	signer := emailtracking.NewSigner("secret", 0)
+	for _, link := range []string{
+		"javascript:alert(1)",
+		"/pricing",
+		"//nakheel.sa/pricing",
+		"ftp://nakheel.sa/file",
+		"https://",
+		"",
+	} {
+		if _, err := signer.IssueClick(7, link); !errors.Is(err, emailtracking.ErrInvalidLink) {
+			t.Errorf("%q: err = %v, want ErrInvalidLink", link, err)
+		}
+	}
+	for _, link := range []string{"http://nakheel.sa", "https://nakheel.sa/a?b=c#d"} {
+		if _, err := signer.IssueClick(7, link); err != nil {
+			t.Errorf("%q: %v", link, err)
+		}
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	issued := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	ttl := 30 * 24 * time.Hour
+	signer := emailtracking.NewSigner("secret", ttl)
+	signer.SetNow(func() time.Time { return issued })
+	token, err := signer.IssueClick(7, "https://nakheel.sa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		at   time.Time
+		err  error
+	}{
+		{"just issued", issued, nil},
+		{"at the TTL", issued.Add(ttl), nil},
+		{"past the TTL", issued.Add(ttl + time.Second), emailtracking.ErrExpiredToken},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signer.SetNow(func() time.Time { return tc.at })
+			got, err := signer.Verify(token, emailtracking.PurposeClick)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+			// Expired tokens keep their payload so clicks still redirect
+			if got.URL != "https://nakheel.sa" {
+				t.Errorf("URL = %q", got.URL)
+			}
+		})
+	}
+
+	// Without a TTL tokens expire after the default
+	signer = emailtracking.NewSigner("secret", 0)
+	signer.SetNow(func() time.Time { return issued })
+	token = mustIssue(t, signer, emailtracking.PurposeOpen)
+	signer.SetNow(func() time.Time { return issued.Add(emailtracking.DefaultTokenTTL + time.Second) })
+	if _, err := signer.Verify(token, emailtracking.PurposeOpen); !errors.Is(err, emailtracking.ErrExpiredToken) {
+		t.Errorf("past the default TTL: err = %v", err)
+	}
+}
+
+func mustIssue(t *testing.T, signer *emailtracking.Signer, purpose emailtracking.Purpose) string {
+	t.Helper()
+	token, err := signer.Issue(7, purpose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
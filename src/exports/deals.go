@@ -0,0 +1,40 @@
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"gorm.io/gorm"
+)
+
+// DealsParams filters the deals export
+type DealsParams struct {
+	Stage           string                         `json:"stage,omitempty"`
+	IncludeArchived bool                           `json:"include_archived,omitempty"`
+	Template        *models.ExportTemplateSnapshot `json:"template,omitempty"`
+	Viewer          *redaction.Viewer              `json:"viewer,omitempty"` // Set by the server to the user starting the export
+}
+
+// DealsCSV exports deals as CSV, in ID order
+func DealsCSV(ctx context.Context, db *gorm.DB, params json.RawMessage, w io.Writer, resume Resume) (int64, error) {
+	var p DealsParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return 0, err
+		}
+	}
+
+	filter := func(query *gorm.DB) *gorm.DB {
+		if !p.IncludeArchived {
+			query = query.Scopes(models.NotArchived("deals"))
+		}
+		if p.Stage != "" {
+			query = query.Where("deals.stage = ?", p.Stage)
+		}
+		return query
+	}
+	return writeCSV(ctx, db, models.ExportEntityDeal, filter, "", p.Template, p.Viewer, w, resume)
+}
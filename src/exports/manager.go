@@ -0,0 +1,361 @@
+// Package exports runs async export jobs and stores their files as
+// expiring job artifacts.
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/storage"
+	"github.com/SalehAlobaylan/CRM-Service/src/workload"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownType is returned when enqueuing a job type with no exporter
+var ErrUnknownType = errors.New("unknown export type")
+
+// DeadLetterComponent is the dead-letter component of failed exports
+const DeadLetterComponent = "exports"
+
+// deadLetterPayload identifies a failed export in the dead-letter queue
+type deadLetterPayload struct {
+	JobID uint `json:"job_id"`
+}
+
+// Exporter writes an export to w and returns the number of rows written
+type Exporter func(ctx context.Context, db *gorm.DB, params json.RawMessage, w io.Writer) (int64, error)
+
+// exporter is a registered export type
+type exporter struct {
+	entity      string // Entity of the export templates it accepts, if any
+	class       string // Workload class whose slots its jobs run in
+	fileName    string
+	contentType string
+	internal    bool // Enqueued by the service itself, never through the export API
+	run         Exporter
+	resumable   ResumableExporter // Set instead of run for exports that can resume
+}
+
+// Manager runs export jobs in the background and manages their artifacts
+type Manager struct {
+	db       *gorm.DB
+	store    storage.Storage
+	ttl      time.Duration
+	maxBytes int64
+	onErr    func(error)
+
+	checkpointEvery int // Batches between checkpoints of resumable exports
+
+	mu        sync.RWMutex
+	exporters map[string]exporter
+
+	limiter     *workload.Limiter
+	deadLetters *deadletter.Queue // Receives failed resumable exports, if set
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// NewManager creates a new Manager. Artifacts expire ttl after the job
+// completes; maxBytes > 0 fails exports producing larger files. Resumable
+// exports save a checkpoint every checkpointEvery batches (at least 1).
+// Jobs wait for a slot of their workload class in limiter, shared with the
+// HTTP endpoints of the class; a nil limiter uses workload.DefaultLimits.
+func NewManager(db *gorm.DB, store storage.Storage, ttl time.Duration, maxBytes int64, checkpointEvery int, limiter *workload.Limiter, onErr func(error)) *Manager {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if checkpointEvery < 1 {
+		checkpointEvery = 1
+	}
+	if limiter == nil {
+		limiter = workload.NewLimiter(workload.DefaultLimits)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		db:              db,
+		store:           store,
+		ttl:             ttl,
+		maxBytes:        maxBytes,
+		onErr:           onErr,
+		checkpointEvery: checkpointEvery,
+		exporters:       make(map[string]exporter),
+		limiter:         limiter,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// DeadLetterTo hands exports that fail from now on to queue and lets the
+// queue retry them by resuming the job. Only resumable exports are handed
+// over, and not those interrupted by Stop, which Recover fails on the next
+// start.
+func (m *Manager) DeadLetterTo(queue *deadletter.Queue) {
+	m.mu.Lock()
+	m.deadLetters = queue
+	m.mu.Unlock()
+	queue.RegisterRetrier(DeadLetterComponent, func(payload json.RawMessage) error {
+		var letter deadLetterPayload
+		if err := json.Unmarshal(payload, &letter); err != nil {
+			return err
+		}
+		var job models.Job
+		if err := m.db.First(&job, letter.JobID).Error; err != nil {
+			return err
+		}
+		return m.Resume(context.Background(), &job)
+	})
+}
+
+// Register adds an export type producing a file with the given name.
+// Exports of an entity accept that entity's export templates; pass an empty
+// entity for exports with a fixed layout. Failed exports can be resumed.
+// They run in the exports workload class.
+func (m *Manager) Register(jobType, entity, fileName, contentType string, run ResumableExporter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exporters[jobType] = exporter{entity: entity, class: workload.ClassExports, fileName: fileName, contentType: contentType, resumable: run}
+}
+
+// RegisterInternal adds a job type that runs and stores its file like an
+// export but is only started through EnqueueInternal, such as backups
+// behind their own admin endpoint. Its jobs run in the given workload class.
+func (m *Manager) RegisterInternal(jobType, class, fileName, contentType string, run Exporter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exporters[jobType] = exporter{class: class, fileName: fileName, contentType: contentType, internal: true, run: run}
+}
+
+// RegisterInternalResumable adds an internal job type that can resume
+// from its checkpoint after failing, like the exports added by Register
+func (m *Manager) RegisterInternalResumable(jobType, class, fileName, contentType string, run ResumableExporter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exporters[jobType] = exporter{class: class, fileName: fileName, contentType: contentType, internal: true, resumable: run}
+}
+
+// Entity returns the template entity of an export type, and false when the
+// type is not registered
+func (m *Manager) Entity(jobType string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	exp, ok := m.exporters[jobType]
+	if exp.internal {
+		return "", false
+	}
+	return exp.entity, ok
+}
+
+// Types returns the registered export types, sorted. Internal types are
+// left out.
+func (m *Manager) Types() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	types := make([]string, 0, len(m.exporters))
+	for t, exp := range m.exporters {
+		if exp.internal {
+			continue
+		}
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Enqueue stores a queued job and starts it in the background. Internal
+// types are reported as unknown.
+func (m *Manager) Enqueue(ctx context.Context, job *models.Job) error {
+	return m.enqueue(ctx, job, false)
+}
+
+// EnqueueInternal is Enqueue for job types registered with RegisterInternal
+func (m *Manager) EnqueueInternal(ctx context.Context, job *models.Job) error {
+	return m.enqueue(ctx, job, true)
+}
+
+// enqueue stores a queued job and starts it in the background
+func (m *Manager) enqueue(ctx context.Context, job *models.Job, internal bool) error {
+	m.mu.RLock()
+	exp, ok := m.exporters[job.Type]
+	m.mu.RUnlock()
+	if !ok || exp.internal != internal {
+		return ErrUnknownType
+	}
+
+	job.Status = models.JobStatusQueued
+	if err := m.db.WithContext(ctx).Create(job).Error; err != nil {
+		return err
+	}
+
+	m.start(job, exp)
+	return nil
+}
+
+// start runs a queued job in the background once a slot of its workload
+// class is free
+func (m *Manager) start(job *models.Job, exp exporter) {
+	m.wg.Add(1)
+	go func(job models.Job) {
+		defer m.wg.Done()
+
+		release, err := m.limiter.Acquire(m.ctx, exp.class)
+		if err != nil {
+			m.finish(&job, nil, err)
+			return
+		}
+		defer release()
+		m.run(&job, exp)
+	}(*job)
+}
+
+// Recover fails jobs left queued or running by a previous process, so they
+// can be resumed
+func (m *Manager) Recover(ctx context.Context) error {
+	now := time.Now()
+	return m.db.WithContext(ctx).Model(&models.Job{}).
+		Where("status IN ?", []models.JobStatus{models.JobStatusQueued, models.JobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.JobStatusFailed,
+			"error":       "interrupted by a restart, resume the export or run it again",
+			"finished_at": now,
+		}).Error
+}
+
+// Stop cancels running exports and waits for them to finish
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Open returns a reader for a job's artifact
+func (m *Manager) Open(ctx context.Context, job models.Job) (io.ReadCloser, error) {
+	return m.store.Open(ctx, job.Artifact.Key)
+}
+
+// CleanupExpired deletes artifacts whose expiry has passed and returns how
+// many were removed. The job rows are kept so downloads can report expiry.
+// Checkpoint parts of failed jobs not resumed within the artifact TTL are
+// removed too; resuming such a job starts over.
+func (m *Manager) CleanupExpired(ctx context.Context, now time.Time) (int, error) {
+	var jobs []models.Job
+	if err := m.db.WithContext(ctx).
+		Where("artifact_key <> '' AND artifact_deleted_at IS NULL AND artifact_expires_at <= ?", now).
+		Find(&jobs).Error; err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, job := range jobs {
+		if err := m.store.Delete(ctx, job.Artifact.Key); err != nil {
+			return removed, err
+		}
+		if err := m.db.WithContext(ctx).Model(&job).Update("artifact_deleted_at", now).Error; err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	cleared, err := m.cleanupCheckpoints(ctx, now.Add(-m.ttl))
+	return removed + cleared, err
+}
+
+// run executes one job, streaming the exporter's output into storage
+func (m *Manager) run(job *models.Job, exp exporter) {
+	started := time.Now()
+	job.Status = models.JobStatusRunning
+	job.StartedAt = &started
+	if err := m.db.Model(job).Updates(map[string]interface{}{
+		"status":     job.Status,
+		"started_at": started,
+	}).Error; err != nil {
+		m.onErr(err)
+	}
+	if exp.resumable != nil {
+		m.runResumable(job, exp)
+		return
+	}
+
+	key := fmt.Sprintf("jobs/%d/%s", job.ID, exp.fileName)
+	reader, writer := io.Pipe()
+
+	var rows int64
+	exportErr := make(chan error, 1)
+	go func() {
+		var err error
+		rows, err = exp.run(m.ctx, m.db.WithContext(m.ctx), json.RawMessage(job.Params), writer)
+		writer.CloseWithError(err)
+		exportErr <- err
+	}()
+
+	object, err := m.store.Put(m.ctx, key, reader, m.maxBytes)
+	// Unblock the exporter if storage stopped reading early
+	reader.CloseWithError(io.ErrClosedPipe)
+	if runErr := <-exportErr; err == nil && runErr != nil {
+		err = runErr
+	}
+	if err != nil {
+		m.store.Delete(context.Background(), key)
+		m.finish(job, nil, err)
+		return
+	}
+
+	expires := time.Now().Add(m.ttl)
+	m.finish(job, &models.JobArtifact{
+		Key:         object.Key,
+		FileName:    exp.fileName,
+		ContentType: exp.contentType,
+		Rows:        rows,
+		Bytes:       object.Size,
+		Checksum:    object.Checksum,
+		ExpiresAt:   &expires,
+	}, nil)
+}
+
+// finish records the outcome of a job
+func (m *Manager) finish(job *models.Job, artifact *models.JobArtifact, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = models.JobStatusFailed
+		job.Error = err.Error()
+		if errors.Is(err, storage.ErrTooLarge) {
+			job.Error = fmt.Sprintf("export exceeds the %d byte size limit, narrow it with filters", m.maxBytes)
+		}
+	} else {
+		job.Status = models.JobStatusCompleted
+		job.Artifact = *artifact
+	}
+
+	if err := m.db.Select("status", "error", "finished_at", "artifact_key", "artifact_file_name",
+		"artifact_content_type", "artifact_rows", "artifact_bytes", "artifact_checksum", "artifact_resumes",
+		"artifact_expires_at").
+		Save(job).Error; err != nil {
+		m.onErr(err)
+	}
+	if err != nil {
+		m.deadLetter(job, err)
+	}
+}
+
+// deadLetter hands a failed resumable export to the dead-letter queue.
+// Exports over the size limit would fail again and are left out.
+func (m *Manager) deadLetter(job *models.Job, cause error) {
+	m.mu.RLock()
+	queue, exp := m.deadLetters, m.exporters[job.Type]
+	m.mu.RUnlock()
+	if queue == nil || exp.resumable == nil || m.ctx.Err() != nil || errors.Is(cause, storage.ErrTooLarge) {
+		return
+	}
+	if err := queue.Add(DeadLetterComponent, deadLetterPayload{JobID: job.ID}, cause, job.Resumes+1); err != nil {
+		m.onErr(err)
+	}
+}
@@ -0,0 +1,66 @@
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/storage"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+func TestFailedExportsAreDeadLettered(t *testing.T) {
+	testDB := testdb.New(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(testDB.DB, store, time.Hour, 0, 1, nil, nil)
+	queue := deadletter.NewQueue(testDB.DB)
+	m.DeadLetterTo(queue)
+
+	fail := errors.New("replica went away")
+	m.Register("flaky_csv", "", "flaky.csv", "text/csv", func(_ context.Context, _ *gorm.DB, _ json.RawMessage, w io.Writer, _ Resume) (int64, error) {
+		if fail != nil {
+			return 0, fail
+		}
+		_, err := io.WriteString(w, "id\n1\n")
+		return 1, err
+	})
+
+	job := models.Job{Type: "flaky_csv", CreatedBy: 1}
+	if err := m.Enqueue(context.Background(), &job); err != nil {
+		t.Fatal(err)
+	}
+	m.wg.Wait()
+
+	var letter models.DeadLetter
+	if err := testDB.DB.First(&letter).Error; err != nil {
+		t.Fatalf("no dead letter for the failed export: %v", err)
+	}
+	if letter.Component != DeadLetterComponent || letter.Payload != `{"job_id":1}` || letter.Attempts != 1 || letter.Error != fail.Error() {
+		t.Errorf("dead letter = %+v", letter)
+	}
+
+	// Retrying the letter resumes the job
+	fail = nil
+	if err := queue.Retry(testDB.DB, &letter); err != nil {
+		t.Fatal(err)
+	}
+	m.wg.Wait()
+	if err := testDB.DB.First(&job, job.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != models.JobStatusCompleted || job.Resumes != 1 || job.Artifact.Rows != 1 {
+		t.Errorf("resumed job = %+v", job)
+	}
+	if letter.Status != models.DeadLetterStatusRequeued || testDB.Count("dead_letters") != 1 {
+		t.Errorf("letter after retry = %+v, %d letters", letter, testDB.Count("dead_letters"))
+	}
+}
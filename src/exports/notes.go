@@ -0,0 +1,51 @@
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// NotesParams filters the notes export
+type NotesParams struct {
+	CustomerID  *uint                          `json:"customer_id,omitempty"`
+	DealID      *uint                          `json:"deal_id,omitempty"`
+	Imported    *bool                          `json:"imported,omitempty"`
+	CreatedFrom *time.Time                     `json:"created_from,omitempty"`
+	CreatedTo   *time.Time                     `json:"created_to,omitempty"`
+	Template    *models.ExportTemplateSnapshot `json:"template,omitempty"`
+}
+
+// NotesCSV exports notes as CSV, in ID order
+func NotesCSV(ctx context.Context, db *gorm.DB, params json.RawMessage, w io.Writer, resume Resume) (int64, error) {
+	var p NotesParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return 0, err
+		}
+	}
+
+	filter := func(query *gorm.DB) *gorm.DB {
+		if p.CustomerID != nil {
+			query = query.Where("notes.customer_id = ?", *p.CustomerID)
+		}
+		if p.DealID != nil {
+			query = query.Where("notes.deal_id = ?", *p.DealID)
+		}
+		if p.Imported != nil {
+			query = query.Where("notes.imported = ?", *p.Imported)
+		}
+		if p.CreatedFrom != nil {
+			query = query.Where("notes.created_at >= ?", *p.CreatedFrom)
+		}
+		if p.CreatedTo != nil {
+			query = query.Where("notes.created_at <= ?", *p.CreatedTo)
+		}
+		return query
+	}
+	return writeCSV(ctx, db, models.ExportEntityNote, filter, "", p.Template, nil, w, resume)
+}
@@ -0,0 +1,291 @@
+package exports
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/storage"
+	"gorm.io/gorm"
+)
+
+// ErrNotResumable is returned when resuming a job that has not failed or
+// whose type cannot resume
+var ErrNotResumable = errors.New("job cannot be resumed")
+
+// errCheckpointMismatch is returned when the stored parts of a job no longer
+// match its checkpoint
+var errCheckpointMismatch = errors.New("the export's stored parts do not match its checkpoint, resume it to start over")
+
+// Cursor is the keyset of the last row a resumable export wrote. Rows are
+// exported ordered by Order, when set, and then by ID, so the export
+// continues with the rows after (Key, ID).
+type Cursor struct {
+	Order string `json:"order,omitempty"` // Sort key expression; empty for ID order
+	Key   string `json:"key,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+// Resume tells a resumable exporter where to start and where to report
+// its progress
+type Resume struct {
+	From *Cursor // Last row written before the interruption; nil starts over
+	// Checkpoint, when set, is called after each batch with the last row
+	// written and the rows written so far; its error stops the export
+	Checkpoint func(cursor Cursor, rows int64) error
+}
+
+// ResumableExporter is an Exporter that can continue after a cursor. It
+// writes the header only when starting over.
+type ResumableExporter func(ctx context.Context, db *gorm.DB, params json.RawMessage, w io.Writer, resume Resume) (int64, error)
+
+// partKey is the storage key of one part of a resumable export
+func partKey(job *models.Job, part int) string {
+	return fmt.Sprintf("jobs/%d/parts/%06d", job.ID, part)
+}
+
+// Resume queues a failed export again. It continues from its checkpoint, or
+// starts over when it has none.
+func (m *Manager) Resume(ctx context.Context, job *models.Job) error {
+	m.mu.RLock()
+	exp, ok := m.exporters[job.Type]
+	m.mu.RUnlock()
+	if !ok || exp.resumable == nil || job.Status != models.JobStatusFailed {
+		return ErrNotResumable
+	}
+
+	// Only one request may resume a failed job
+	result := m.db.WithContext(ctx).Model(job).Where("status = ?", models.JobStatusFailed).
+		Updates(map[string]interface{}{
+			"status":      models.JobStatusQueued,
+			"error":       "",
+			"finished_at": nil,
+			"resumes":     gorm.Expr("resumes + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotResumable
+	}
+	if err := m.db.WithContext(ctx).First(job, job.ID).Error; err != nil {
+		return err
+	}
+
+	m.start(job, exp)
+	return nil
+}
+
+// runResumable executes a resumable job. Output is buffered and stored as a
+// new part every checkpointEvery batches, after which the checkpoint is
+// saved; when the export finishes the parts are joined into the artifact.
+func (m *Manager) runResumable(job *models.Job, exp exporter) {
+	checkpoint := job.Checkpoint
+	resumedRows := checkpoint.Rows
+	running := sha256.New()
+	if err := m.verifyParts(job, running); err != nil {
+		m.clearCheckpoint(job)
+		m.finish(job, nil, err)
+		return
+	}
+
+	var from *Cursor
+	if checkpoint.Cursor != "" {
+		from = &Cursor{}
+		if err := json.Unmarshal([]byte(checkpoint.Cursor), from); err != nil {
+			m.clearCheckpoint(job)
+			m.finish(job, nil, err)
+			return
+		}
+	}
+
+	var buffer bytes.Buffer
+	batches := 0
+	save := func(cursor *Cursor, rows int64) error {
+		if m.maxBytes > 0 && checkpoint.Bytes+int64(buffer.Len()) > m.maxBytes {
+			return storage.ErrTooLarge
+		}
+		if buffer.Len() == 0 {
+			return nil
+		}
+		if _, err := m.store.Put(m.ctx, partKey(job, checkpoint.Parts+1), bytes.NewReader(buffer.Bytes()), 0); err != nil {
+			return err
+		}
+		running.Write(buffer.Bytes())
+		checkpoint.Parts++
+		checkpoint.Bytes += int64(buffer.Len())
+		checkpoint.Checksum = hex.EncodeToString(running.Sum(nil))
+		buffer.Reset()
+		if cursor == nil {
+			return nil
+		}
+
+		encoded, _ := json.Marshal(cursor)
+		now := time.Now()
+		checkpoint.Rows = resumedRows + rows
+		checkpoint.Cursor = string(encoded)
+		checkpoint.At = &now
+		return m.db.Model(job).Select("checkpoint_parts", "checkpoint_rows", "checkpoint_bytes",
+			"checkpoint_checksum", "checkpoint_cursor", "checkpoint_at").
+			Updates(&models.Job{Checkpoint: checkpoint}).Error
+	}
+
+	rows, err := exp.resumable(m.ctx, m.db.WithContext(m.ctx), json.RawMessage(job.Params), &buffer, Resume{
+		From: from,
+		Checkpoint: func(cursor Cursor, rows int64) error {
+			if batches++; batches%m.checkpointEvery != 0 {
+				return nil
+			}
+			return save(&cursor, rows)
+		},
+	})
+	if err == nil {
+		// The tail after the last checkpoint is only kept with the artifact
+		err = save(nil, rows)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrTooLarge) {
+			job.Checkpoint.Parts = checkpoint.Parts
+			m.clearCheckpoint(job)
+		}
+		m.finish(job, nil, err)
+		return
+	}
+
+	key := fmt.Sprintf("jobs/%d/%s", job.ID, exp.fileName)
+	parts := m.joinParts(job, checkpoint.Parts)
+	object, err := m.store.Put(m.ctx, key, parts, m.maxBytes)
+	parts.Close()
+	if err == nil && (object.Size != checkpoint.Bytes || object.Checksum != hex.EncodeToString(running.Sum(nil))) {
+		m.store.Delete(context.Background(), key)
+		err = errCheckpointMismatch
+	}
+	if err != nil {
+		m.finish(job, nil, err)
+		return
+	}
+	job.Checkpoint.Parts = checkpoint.Parts
+	m.clearCheckpoint(job)
+
+	expires := time.Now().Add(m.ttl)
+	m.finish(job, &models.JobArtifact{
+		Key:         object.Key,
+		FileName:    exp.fileName,
+		ContentType: exp.contentType,
+		Rows:        resumedRows + rows,
+		Bytes:       object.Size,
+		Checksum:    object.Checksum,
+		Resumes:     job.Resumes,
+		ExpiresAt:   &expires,
+	}, nil)
+}
+
+// verifyParts hashes the parts stored by the job's checkpoint into running
+// and checks them against the checkpoint's size and checksum
+func (m *Manager) verifyParts(job *models.Job, running hash.Hash) error {
+	if job.Checkpoint.Parts == 0 {
+		return nil
+	}
+
+	parts := m.joinParts(job, job.Checkpoint.Parts)
+	defer parts.Close()
+	size, err := io.Copy(running, parts)
+	if errors.Is(err, storage.ErrNotFound) {
+		return errCheckpointMismatch
+	}
+	if err != nil {
+		return err
+	}
+	if size != job.Checkpoint.Bytes || hex.EncodeToString(running.Sum(nil)) != job.Checkpoint.Checksum {
+		return errCheckpointMismatch
+	}
+	return nil
+}
+
+// joinParts streams the first count parts of a job in order. Closing the
+// reader early stops the stream.
+func (m *Manager) joinParts(job *models.Job, count int) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		for part := 1; part <= count; part++ {
+			file, err := m.store.Open(m.ctx, partKey(job, part))
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			_, err = io.Copy(writer, file)
+			file.Close()
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		writer.Close()
+	}()
+	return reader
+}
+
+// clearCheckpoint deletes the parts stored for a job and resets its
+// checkpoint, so resuming it starts over
+func (m *Manager) clearCheckpoint(job *models.Job) {
+	for part := 1; part <= job.Checkpoint.Parts; part++ {
+		if err := m.store.Delete(context.Background(), partKey(job, part)); err != nil {
+			m.onErr(err)
+		}
+	}
+	job.Checkpoint = models.JobCheckpoint{}
+	if err := m.db.Model(job).Updates(checkpointReset()).Error; err != nil {
+		m.onErr(err)
+	}
+}
+
+// checkpointReset is the update clearing a job's checkpoint
+func checkpointReset() map[string]interface{} {
+	return map[string]interface{}{
+		"checkpoint_parts":    0,
+		"checkpoint_rows":     0,
+		"checkpoint_bytes":    0,
+		"checkpoint_checksum": "",
+		"checkpoint_cursor":   nil,
+		"checkpoint_at":       nil,
+	}
+}
+
+// cleanupCheckpoints deletes the stored parts of failed jobs that finished
+// before cutoff and were not resumed, returning how many jobs it cleared
+func (m *Manager) cleanupCheckpoints(ctx context.Context, cutoff time.Time) (int, error) {
+	var jobs []models.Job
+	if err := m.db.WithContext(ctx).
+		Where("status = ? AND checkpoint_parts > 0 AND finished_at <= ?", models.JobStatusFailed, cutoff).
+		Find(&jobs).Error; err != nil {
+		return 0, err
+	}
+
+	cleared := 0
+	for _, job := range jobs {
+		// Skip jobs resumed in the meantime, which still need their parts
+		result := m.db.WithContext(ctx).Model(&job).Where("status = ?", models.JobStatusFailed).
+			Updates(checkpointReset())
+		if result.Error != nil {
+			return cleared, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		for part := 1; part <= job.Checkpoint.Parts; part++ {
+			if err := m.store.Delete(ctx, partKey(&job, part)); err != nil {
+				return cleared, err
+			}
+		}
+		cleared++
+	}
+	return cleared, nil
+}
@@ -0,0 +1,186 @@
+package exports
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/storage"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// testBatchSize is the export batch size of the tests
+const testBatchSize = 10
+
+// exportedCustomers is enough customers for three full batches and a part
+// of a fourth
+const exportedCustomers = 3*testBatchSize + 5
+
+// smallBatches sets the export batch size for a test
+func smallBatches(t *testing.T) {
+	t.Helper()
+	old := exportBatchSize
+	exportBatchSize = testBatchSize
+	t.Cleanup(func() { exportBatchSize = old })
+}
+
+// seedCustomers creates customers whose names are not in ID order
+func seedCustomers(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	customers := make([]models.Customer, exportedCustomers)
+	for i := range customers {
+		n := (i * 7919) % exportedCustomers
+		customers[i] = models.Customer{Name: fmt.Sprintf("Customer %04d", n), Email: fmt.Sprintf("c%04d@example.com", n)}
+	}
+	if err := db.Create(&customers).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exportedIDs reads the id column of a job's artifact
+func exportedIDs(t *testing.T, m *Manager, job models.Job) []string {
+	t.Helper()
+	artifact, err := m.Open(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer artifact.Close()
+	records, err := csv.NewReader(artifact).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || records[0][0] != "id" {
+		t.Fatalf("artifact starts with %v, want the header", records[:min(len(records), 1)])
+	}
+	ids := make([]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		ids = append(ids, record[0])
+	}
+	return ids
+}
+
+// TestResumeAfterKill kills an export midway, as a crash would, and checks
+// that the export resumed by the next process writes every row once
+func TestResumeAfterKill(t *testing.T) {
+	smallBatches(t)
+	for _, tc := range []struct {
+		name   string
+		params CustomersParams
+		// killAt kills the export after the given checkpoint, with the
+		// next batch written but not stored, or with beforeSaved while
+		// the checkpoint is saved, once its part is stored
+		killAt      int
+		beforeSaved bool
+	}{
+		{name: "id order, between checkpoints", killAt: 2},
+		{name: "name order, between checkpoints", params: CustomersParams{SortBy: "name"}, killAt: 1},
+		{name: "id order, before the checkpoint is saved", killAt: 2, beforeSaved: true},
+		{name: "name order, before the checkpoint is saved", params: CustomersParams{SortBy: "name"}, killAt: 1, beforeSaved: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testDB := testdb.New(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+			seedCustomers(t, testDB.DB)
+			store, err := storage.NewLocal(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The first process dies in the export's goroutine, leaving the
+			// job running and the rows written since the last part unsaved
+			first := NewManager(testDB.DB, store, time.Hour, 0, 1, nil, nil)
+			checkpoints := 0
+			first.Register("customers_csv", models.ExportEntityCustomer, "customers.csv", "text/csv", func(ctx context.Context, db *gorm.DB, params json.RawMessage, w io.Writer, resume Resume) (int64, error) {
+				checkpoint := resume.Checkpoint
+				resume.Checkpoint = func(cursor Cursor, rows int64) error {
+					if checkpoints++; checkpoints == tc.killAt+1 && !tc.beforeSaved {
+						runtime.Goexit()
+					}
+					return checkpoint(cursor, rows)
+				}
+				return CustomersCSV(ctx, db, params, w, resume)
+			})
+			if tc.beforeSaved {
+				err := testDB.DB.Callback().Update().Before("gorm:update").Register("test:kill", func(tx *gorm.DB) {
+					if tx.Statement.Table == "jobs" && slices.Contains(tx.Statement.Selects, "checkpoint_cursor") && checkpoints == tc.killAt {
+						runtime.Goexit()
+					}
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			params, _ := json.Marshal(tc.params)
+			job := models.Job{Type: "customers_csv", Params: string(params), CreatedBy: 1}
+			if err := first.Enqueue(context.Background(), &job); err != nil {
+				t.Fatal(err)
+			}
+			first.wg.Wait()
+			if err := testDB.DB.First(&job, job.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			savedRows := int64(tc.killAt) * testBatchSize
+			if tc.beforeSaved {
+				savedRows -= testBatchSize
+			}
+			if job.Status != models.JobStatusRunning || job.Checkpoint.Rows != savedRows {
+				t.Fatalf("killed job = %s with %d rows checkpointed, want running with %d", job.Status, job.Checkpoint.Rows, savedRows)
+			}
+			testDB.DB.Callback().Update().Remove("test:kill")
+
+			// The next process fails the interrupted job and resumes it
+			second := NewManager(testDB.DB, store, time.Hour, 0, 1, nil, nil)
+			second.Register("customers_csv", models.ExportEntityCustomer, "customers.csv", "text/csv", CustomersCSV)
+			if err := second.Recover(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if err := testDB.DB.First(&job, job.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := second.Resume(context.Background(), &job); err != nil {
+				t.Fatal(err)
+			}
+			second.wg.Wait()
+			if err := testDB.DB.First(&job, job.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != models.JobStatusCompleted || job.Artifact.Rows != exportedCustomers || job.Artifact.Resumes != 1 {
+				t.Fatalf("resumed job = %s: %s, artifact %+v", job.Status, job.Error, job.Artifact)
+			}
+
+			// Every customer is exported once, in the export's order
+			var want []string
+			order := "id"
+			if tc.params.SortBy == "name" {
+				order = "name, id"
+			}
+			if err := testDB.DB.Model(&models.Customer{}).Order(order).Pluck("id", &want).Error; err != nil {
+				t.Fatal(err)
+			}
+			got := exportedIDs(t, second, job)
+			if !slices.Equal(got, want) {
+				seen := map[string]int{}
+				for _, id := range got {
+					seen[id]++
+				}
+				var repeated []string
+				for id, n := range seen {
+					if n > 1 {
+						repeated = append(repeated, id)
+					}
+				}
+				sort.Strings(repeated)
+				t.Errorf("exported %d rows of %d, %d distinct; repeated %v", len(got), len(want), len(seen), repeated)
+			}
+		})
+	}
+}
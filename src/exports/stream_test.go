@@ -0,0 +1,105 @@
+package exports
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// streamWriter notes when a stream's first bytes arrive and how many
+// follow, and calls sample on every write
+type streamWriter struct {
+	start  time.Time
+	first  time.Duration
+	bytes  int64
+	sample func()
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.bytes == 0 {
+		w.first = time.Since(w.start)
+	}
+	w.bytes += int64(len(p))
+	if w.sample != nil {
+		w.sample()
+	}
+	return len(p), nil
+}
+
+// TestStreamStartsBeforeTheCursorEnds streams customers from a cursor that
+// takes 20ms a row and checks that the first batch arrives long before the
+// last row is read
+func TestStreamStartsBeforeTheCursorEnds(t *testing.T) {
+	db := testdb.Open(t)
+	smallBatches(t)
+	seedCustomers(t, db)
+
+	slow := func(query *gorm.DB) *gorm.DB {
+		return query.Where("pg_sleep(?)::text = ''", 0.02)
+	}
+	w := &streamWriter{start: time.Now()}
+	written, err := StreamCustomersCSV(context.Background(), db, slow, nil, nil, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := time.Since(w.start)
+	if written != exportedCustomers {
+		t.Fatalf("wrote %d rows, want %d", written, exportedCustomers)
+	}
+	if w.first == 0 || w.first > total/2 {
+		t.Errorf("first bytes after %v of %v, want them with the first batch", w.first, total)
+	}
+}
+
+// TestStreamMemoryStaysBounded streams megabytes of customers and checks
+// that the live heap stays flat meanwhile, rather than growing with the
+// rows written
+func TestStreamMemoryStaysBounded(t *testing.T) {
+	db := testdb.Open(t)
+	padding := strings.Repeat("x", 200)
+	customers := make([]models.Customer, 20000)
+	for i := range customers {
+		customers[i] = models.Customer{
+			Name:    fmt.Sprintf("Customer %05d %s", i, padding),
+			Email:   fmt.Sprintf("c%05d@example.com", i),
+			Company: padding,
+		}
+	}
+	if err := db.CreateInBatches(&customers, 1000).Error; err != nil {
+		t.Fatal(err)
+	}
+	customers = nil
+
+	live := func() uint64 {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		return stats.HeapAlloc
+	}
+	baseline := live()
+	var peak uint64
+	w := &streamWriter{start: time.Now()}
+	w.sample = func() {
+		if heap := live(); heap > peak {
+			peak = heap
+		}
+	}
+	if _, err := StreamCustomersCSV(context.Background(), db, func(query *gorm.DB) *gorm.DB { return query }, nil, nil, w); err != nil {
+		t.Fatal(err)
+	}
+
+	const bound = 4 << 20
+	if w.bytes < 2*bound {
+		t.Fatalf("streamed %d bytes, too few to tell", w.bytes)
+	}
+	if peak > baseline && peak-baseline > bound {
+		t.Errorf("live heap grew by %d bytes while streaming %d", peak-baseline, w.bytes)
+	}
+}
@@ -0,0 +1,306 @@
+package exports
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"gorm.io/gorm"
+)
+
+// Column is a column export templates can reference
+type Column struct {
+	Key   string `json:"key"`
+	Label string `json:"label"` // Header used when a template sets no label
+	expr  string
+}
+
+// entity is an exportable table with its columns. Related columns read
+// from tables joined under the alias before the dot of their key.
+type entity struct {
+	table    string
+	joins    []string
+	columns  []Column
+	defaults []string // Columns exported without a template
+	redact   string   // Entity of the field redaction rules, empty when none apply
+	owner    string   // Owner expression for redaction rules exempting owners
+}
+
+// entities lists the exportable entities by name
+var entities = map[string]entity{
+	models.ExportEntityCustomer: {
+		table: "customers",
+		columns: []Column{
+			{Key: "id", Label: "id", expr: "customers.id"},
+			{Key: "name", Label: "name", expr: "customers.name"},
+			{Key: "email", Label: "email", expr: "customers.email"},
+			{Key: "phone", Label: "phone", expr: "customers.phone"},
+			{Key: "company", Label: "company", expr: "customers.company"},
+			{Key: "role", Label: "role", expr: "customers.role"},
+			{Key: "status", Label: "status", expr: "customers.status"},
+			{Key: "assigned_to", Label: "assigned_to", expr: "customers.assigned_to"},
+			{Key: "contacted", Label: "contacted", expr: "customers.contacted"},
+			{Key: "next_follow_up_at", Label: "next_follow_up_at", expr: "customers.next_follow_up_at"},
+			{Key: "email_domain", Label: "email_domain", expr: "customers.email_domain"},
+			{Key: "notes", Label: "notes", expr: "customers.notes"},
+			{Key: "tags", Label: "tags", expr: "(SELECT STRING_AGG(tags.name, ', ' ORDER BY tags.name) FROM customer_tags JOIN tags ON tags.id = customer_tags.tag_id AND tags.deleted_at IS NULL WHERE customer_tags.customer_id = customers.id)"},
+			{Key: "open_deals_count", Label: "open_deals_count", expr: "(SELECT COUNT(*) FROM deals WHERE deals.customer_id = customers.id AND deals.deleted_at IS NULL AND deals.stage NOT IN ('closed_won', 'closed_lost'))"},
+			{Key: "archived_at", Label: "archived_at", expr: "customers.archived_at"},
+			{Key: "created_at", Label: "created_at", expr: "customers.created_at"},
+			{Key: "updated_at", Label: "updated_at", expr: "customers.updated_at"},
+		},
+		defaults: []string{"id", "name", "email", "phone", "company", "role", "status", "assigned_to", "contacted", "created_at"},
+		redact:   models.EntityCustomer,
+		owner:    "customers.assigned_to",
+	},
+	models.ExportEntityDeal: {
+		table: "deals",
+		joins: []string{
+			"LEFT JOIN customers customer ON customer.id = deals.customer_id",
+			"LEFT JOIN contacts contact ON contact.id = deals.contact_id",
+		},
+		columns: []Column{
+			{Key: "id", Label: "id", expr: "deals.id"},
+			{Key: "title", Label: "title", expr: "deals.title"},
+			{Key: "stage", Label: "stage", expr: "deals.stage"},
+			{Key: "amount", Label: "amount", expr: "deals.amount::text"},
+			{Key: "currency", Label: "currency", expr: "deals.currency"},
+			{Key: "probability", Label: "probability", expr: "deals.probability"},
+			{Key: "expected_close_date", Label: "expected_close_date", expr: "deals.expected_close_date"},
+			{Key: "actual_close_date", Label: "actual_close_date", expr: "deals.actual_close_date"},
+			{Key: "owner_id", Label: "owner_id", expr: "deals.owner_id"},
+			{Key: "lost_reason", Label: "lost_reason", expr: "deals.lost_reason"},
+			{Key: "next_step", Label: "next_step", expr: "deals.next_step"},
+			{Key: "next_step_due", Label: "next_step_due", expr: "deals.next_step_due"},
+			{Key: "external_id", Label: "external_id", expr: "deals.external_id"},
+			{Key: "customer_id", Label: "customer_id", expr: "deals.customer_id"},
+			{Key: "customer.name", Label: "customer_name", expr: "customer.name"},
+			{Key: "customer.email", Label: "customer_email", expr: "customer.email"},
+			{Key: "customer.company", Label: "customer_company", expr: "customer.company"},
+			{Key: "customer.status", Label: "customer_status", expr: "customer.status"},
+			{Key: "contact.name", Label: "contact_name", expr: "TRIM(contact.first_name || ' ' || COALESCE(contact.last_name, ''))"},
+			{Key: "contact.email", Label: "contact_email", expr: "contact.email"},
+			{Key: "archived_at", Label: "archived_at", expr: "deals.archived_at"},
+			{Key: "created_at", Label: "created_at", expr: "deals.created_at"},
+			{Key: "updated_at", Label: "updated_at", expr: "deals.updated_at"},
+		},
+		defaults: []string{"id", "title", "customer_id", "stage", "amount", "currency", "probability", "expected_close_date", "actual_close_date", "created_at"},
+		redact:   models.EntityDeal,
+		owner:    "deals.owner_id",
+	},
+	models.ExportEntityNote: {
+		table: "notes",
+		joins: []string{
+			"LEFT JOIN customers customer ON customer.id = notes.customer_id",
+			"LEFT JOIN deals deal ON deal.id = notes.deal_id",
+		},
+		columns: []Column{
+			{Key: "id", Label: "id", expr: "notes.id"},
+			{Key: "content", Label: "content", expr: "notes.content"},
+			{Key: "customer_id", Label: "customer_id", expr: "notes.customer_id"},
+			{Key: "customer.email", Label: "customer_email", expr: "customer.email"},
+			{Key: "customer.name", Label: "customer_name", expr: "customer.name"},
+			{Key: "deal_id", Label: "deal_id", expr: "notes.deal_id"},
+			{Key: "deal.external_id", Label: "deal_external_id", expr: "NULLIF(deal.external_id, '')"},
+			{Key: "deal.title", Label: "deal_title", expr: "deal.title"},
+			{Key: "activity_id", Label: "activity_id", expr: "notes.activity_id"},
+			{Key: "author_id", Label: "author_id", expr: "notes.author_id"},
+			{Key: "author_name", Label: "author_name", expr: "notes.author_name"},
+			{Key: "imported", Label: "imported", expr: "notes.imported"},
+			{Key: "created_at", Label: "created_at", expr: "notes.created_at"},
+			{Key: "updated_at", Label: "updated_at", expr: "notes.updated_at"},
+		},
+		// Matches the columns POST /admin/notes/import reads
+		defaults: []string{"id", "customer_id", "customer.email", "deal_id", "deal.external_id", "author_name", "imported", "created_at", "content"},
+	},
+}
+
+// Columns returns the columns export templates for an entity can reference
+func Columns(entityName string) ([]Column, bool) {
+	e, ok := entities[entityName]
+	return e.columns, ok
+}
+
+// UnknownColumns returns the keys that are not columns of the entity, sorted
+func UnknownColumns(entityName string, keys []string) []string {
+	e := entities[entityName]
+	var unknown []string
+	for _, key := range keys {
+		if _, ok := e.column(key); !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// column finds a column by key
+func (e entity) column(key string) (Column, bool) {
+	for _, col := range e.columns {
+		if col.Key == key {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+// writeCSV streams the rows of an entity selected by filter as CSV, ordered
+// by the text expression sortKey, when set, and then by ID. The template
+// chooses columns, headers and date format; without one the entity's
+// default columns are written with RFC 3339 dates. Fields hidden from
+// viewer are left empty; a nil viewer sees every field. A resumed export
+// continues after the cursor's row without repeating the header.
+func writeCSV(ctx context.Context, db *gorm.DB, entityName string, filter func(*gorm.DB) *gorm.DB, sortKey string, template *models.ExportTemplateSnapshot, viewer *redaction.Viewer, w io.Writer, resume Resume) (int64, error) {
+	e := entities[entityName]
+
+	var columns []models.ExportColumn
+	layout := models.ExportDateFormats[models.DefaultExportDateFormat]
+	if template != nil {
+		if template.Entity != entityName {
+			return 0, fmt.Errorf("export template %d is for %s, not %s", template.ID, template.Entity, entityName)
+		}
+		columns = template.Columns
+		if format, ok := models.ExportDateFormats[template.DateFormat]; ok {
+			layout = format
+		}
+	} else {
+		for _, key := range e.defaults {
+			columns = append(columns, models.ExportColumn{Key: key})
+		}
+	}
+
+	header := make([]string, len(columns))
+	selects := make([]string, len(columns))
+	for i, c := range columns {
+		col, ok := e.column(c.Key)
+		if !ok {
+			return 0, fmt.Errorf("unknown %s export column %q", entityName, c.Key)
+		}
+		header[i] = c.Label
+		if header[i] == "" {
+			header[i] = col.Label
+		}
+		selects[i] = e.redacted(col, viewer)
+	}
+
+	// The keyset of each row follows its columns, so resumed exports neither
+	// skip nor repeat rows
+	id := e.table + ".id"
+	order := id
+	cursor := Cursor{Order: sortKey}
+	keyset := []interface{}{&cursor.ID}
+	selects = append(selects, id)
+	if sortKey != "" {
+		order = sortKey + ", " + id
+		keyset = append(keyset, &cursor.Key)
+		selects = append(selects, sortKey)
+	}
+
+	query := db.Table(e.table).Select(strings.Join(selects, ", ")).Where(e.table + ".deleted_at IS NULL")
+	for _, join := range e.joins {
+		query = query.Joins(join)
+	}
+	if from := resume.From; from != nil {
+		if from.Order != sortKey {
+			return 0, errors.New("export order changed since the checkpoint, run the export again")
+		}
+		if sortKey == "" {
+			query = query.Where(id+" > ?", from.ID)
+		} else {
+			query = query.Where("("+sortKey+", "+id+") > (?, ?)", from.Key, from.ID)
+		}
+	}
+	rows, err := query.Scopes(filter).Order(order).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	writer := csv.NewWriter(w)
+	if resume.From == nil {
+		writer.Write(header)
+	}
+
+	var written int64
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	pointers = append(pointers, keyset...)
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return written, err
+		}
+		for i, value := range values {
+			record[i] = formatValue(value, layout)
+		}
+		writer.Write(record)
+		written++
+
+		if written%exportBatchSize == 0 {
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return written, err
+			}
+			if err := ctx.Err(); err != nil {
+				return written, err
+			}
+			if resume.Checkpoint != nil {
+				if err := resume.Checkpoint(cursor, written); err != nil {
+					return written, err
+				}
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return written, err
+	}
+
+	writer.Flush()
+	return written, writer.Error()
+}
+
+// redacted returns the select expression of a column, yielding NULL on rows
+// where the viewer may not read it
+func (e entity) redacted(col Column, viewer *redaction.Viewer) string {
+	rule, ok := redaction.Rules[e.redact][col.Key]
+	if viewer == nil || !ok || models.HasPermission(viewer.Role, rule.Permission) {
+		return col.expr
+	}
+	if rule.OwnerExempt {
+		return "CASE WHEN " + e.owner + " = " + strconv.FormatUint(uint64(viewer.UserID), 10) + " THEN " + col.expr + " END"
+	}
+	return "NULL"
+}
+
+// formatValue renders a scanned database value as a CSV field
+func formatValue(value interface{}, layout string) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(layout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
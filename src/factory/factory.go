@@ -0,0 +1,174 @@
+// Package factory builds records for tests. Records are created with
+// readable defaults, overridable per record, and stamped from a fixed
+// clock a minute apart, so on a fresh database they get sequential IDs and
+// timestamps and the responses built from them are the same on every run.
+package factory
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Epoch is the time of the first record a factory creates
+var Epoch = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+// Factory creates records in a database
+type Factory struct {
+	db    *gorm.DB
+	clock time.Time
+	seq   map[string]int
+}
+
+// New returns a factory creating records in db
+func New(db *gorm.DB) *Factory {
+	return &Factory{db: db, clock: Epoch, seq: make(map[string]int)}
+}
+
+// next returns the sequence number of the next record of a table and the
+// time it is created at
+func (f *Factory) next(table string) (int, time.Time) {
+	f.seq[table]++
+	at := f.clock
+	f.clock = f.clock.Add(time.Minute)
+	return f.seq[table], at
+}
+
+// Now returns the time the next record will be created at
+func (f *Factory) Now() time.Time {
+	return f.clock
+}
+
+func (f *Factory) create(t testing.TB, record interface{}) {
+	t.Helper()
+	if err := f.db.Create(record).Error; err != nil {
+		t.Fatalf("factory: create %T: %v", record, err)
+	}
+}
+
+// Customer creates a lead
+func (f *Factory) Customer(t testing.TB, overrides ...func(*models.Customer)) models.Customer {
+	t.Helper()
+	n, at := f.next("customers")
+	customer := models.Customer{
+		Name:    fmt.Sprintf("Customer %d", n),
+		Email:   fmt.Sprintf("customer%d@example.com", n),
+		Phone:   fmt.Sprintf("+96650000%04d", n),
+		Company: fmt.Sprintf("Company %d", n),
+		Status:  models.CustomerStatusLead,
+	}
+	customer.CreatedAt, customer.UpdatedAt = at, at
+	for _, override := range overrides {
+		override(&customer)
+	}
+	if customer.EmailDomain == "" {
+		_, customer.EmailDomain, _ = strings.Cut(customer.Email, "@")
+	}
+	f.create(t, &customer)
+	return customer
+}
+
+// Contact creates a contact of a customer
+func (f *Factory) Contact(t testing.TB, customer models.Customer, overrides ...func(*models.Contact)) models.Contact {
+	t.Helper()
+	n, at := f.next("contacts")
+	contact := models.Contact{
+		CustomerID: customer.ID,
+		FirstName:  "Contact",
+		LastName:   fmt.Sprint(n),
+		Email:      fmt.Sprintf("contact%d@example.com", n),
+		Position:   "Buyer",
+	}
+	contact.CreatedAt, contact.UpdatedAt = at, at
+	for _, override := range overrides {
+		override(&contact)
+	}
+	f.create(t, &contact)
+	return contact
+}
+
+// Deal creates a prospecting deal of a customer
+func (f *Factory) Deal(t testing.TB, customer models.Customer, overrides ...func(*models.Deal)) models.Deal {
+	t.Helper()
+	n, at := f.next("deals")
+	deal := models.Deal{
+		Title:         fmt.Sprintf("Deal %d", n),
+		CustomerID:    customer.ID,
+		Stage:         models.DealStageProspecting,
+		Amount:        float64(1000 * n),
+		Currency:      "USD",
+		Probability:   10,
+		BoardPosition: float64(n),
+	}
+	deal.CreatedAt, deal.UpdatedAt = at, at
+	for _, override := range overrides {
+		override(&deal)
+	}
+	f.create(t, &deal)
+	return deal
+}
+
+// Activity creates a task of a customer, due a day after it is created
+func (f *Factory) Activity(t testing.TB, customer models.Customer, overrides ...func(*models.Activity)) models.Activity {
+	t.Helper()
+	n, at := f.next("activities")
+	due := at.Add(24 * time.Hour)
+	activity := models.Activity{
+		Title:      fmt.Sprintf("Activity %d", n),
+		Type:       models.ActivityTypeTask,
+		Status:     models.ActivityStatusScheduled,
+		CustomerID: &customer.ID,
+		DueDate:    &due,
+		Priority:   "normal",
+	}
+	activity.CreatedAt, activity.UpdatedAt = at, at
+	for _, override := range overrides {
+		override(&activity)
+	}
+	f.create(t, &activity)
+	return activity
+}
+
+// Note creates a note on a customer
+func (f *Factory) Note(t testing.TB, customer models.Customer, overrides ...func(*models.Note)) models.Note {
+	t.Helper()
+	n, at := f.next("notes")
+	note := models.Note{
+		Content:    fmt.Sprintf("Note %d", n),
+		CustomerID: &customer.ID,
+		AuthorID:   1,
+		AuthorName: "Author",
+	}
+	note.CreatedAt, note.UpdatedAt = at, at
+	for _, override := range overrides {
+		override(&note)
+	}
+	f.create(t, &note)
+	return note
+}
+
+// Tag creates a tag
+func (f *Factory) Tag(t testing.TB, overrides ...func(*models.Tag)) models.Tag {
+	t.Helper()
+	n, at := f.next("tags")
+	tag := models.Tag{Name: fmt.Sprintf("tag-%d", n), Color: "#336699"}
+	tag.CreatedAt, tag.UpdatedAt = at, at
+	for _, override := range overrides {
+		override(&tag)
+	}
+	f.create(t, &tag)
+	return tag
+}
+
+// TagCustomer assigns a tag to a customer
+func (f *Factory) TagCustomer(t testing.TB, customer models.Customer, tag models.Tag) {
+	t.Helper()
+	err := f.db.Table("customer_tags").Create(map[string]interface{}{"customer_id": customer.ID, "tag_id": tag.ID}).Error
+	if err != nil {
+		t.Fatalf("factory: tag customer: %v", err)
+	}
+}
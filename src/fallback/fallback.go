@@ -0,0 +1,292 @@
+// Package fallback keeps side effects, such as audit entries and activity
+// counters, whose write failed after the change they belong to was saved,
+// and replays them once the database recovers.
+package fallback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// Policy is how a failed side effect write is handled
+type Policy string
+
+const (
+	PolicyStrict    Policy = "strict"    // Fail the request that caused it
+	PolicyResilient Policy = "resilient" // Keep the change and replay the side effect later
+)
+
+// ParsePolicy parses a side effect policy; "" is resilient
+func ParsePolicy(s string) (Policy, error) {
+	switch policy := Policy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case "":
+		return PolicyResilient, nil
+	case PolicyStrict, PolicyResilient:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown side effect policy %q: want strict or resilient", s)
+	}
+}
+
+// ReplayFunc writes a stored side effect again within tx
+type ReplayFunc func(ctx context.Context, tx *gorm.DB, payload json.RawMessage) error
+
+// replayBatchSize is how many stored side effects are loaded per query
+const replayBatchSize = 100
+
+var (
+	sideEffectFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crm_side_effect_failures_total",
+			Help: "Total number of side effect writes that failed and were kept for replay",
+		},
+		[]string{"kind"},
+	)
+	sideEffectReplaysTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crm_side_effect_replays_total",
+			Help: "Total number of side effect replays by result (replayed, failed, dropped)",
+		},
+		[]string{"kind", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(sideEffectFailuresTotal, sideEffectReplaysTotal)
+}
+
+// Writer buffers failed side effects in memory, stores them in the
+// side_effect_fallbacks table and replays them through the function
+// registered for their kind
+type Writer struct {
+	db        *gorm.DB
+	interval  time.Duration
+	maxBuffer int
+
+	mu        sync.Mutex
+	buffer    []models.SideEffectFallback // Not yet stored
+	replayers map[string]ReplayFunc
+
+	kick   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewWriter creates a new Writer replaying every interval and keeping at
+// most maxBuffer side effects in memory while the database is unavailable
+func NewWriter(db *gorm.DB, interval time.Duration, maxBuffer int, onErr func(error)) *Writer {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Writer{
+		db:        db,
+		interval:  interval,
+		maxBuffer: maxBuffer,
+		replayers: make(map[string]ReplayFunc),
+		kick:      make(chan struct{}, 1),
+		onErr:     onErr,
+	}
+}
+
+// Register registers the function replaying a kind of side effect
+func (w *Writer) Register(kind string, replay ReplayFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.replayers[kind] = replay
+}
+
+// Add keeps a side effect whose write failed. It is buffered in memory and
+// stored by the background loop, which is woken so the buffer is stored
+// as soon as the database accepts it. A full buffer drops its oldest entry.
+func (w *Writer) Add(kind string, payload interface{}, cause error) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s side effect: %w", kind, err)
+	}
+
+	now := time.Now()
+	entry := models.SideEffectFallback{
+		Kind:          kind,
+		Payload:       string(data),
+		Attempts:      1,
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+	}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+
+	var dropped models.SideEffectFallback
+	full := false
+	w.mu.Lock()
+	if w.maxBuffer > 0 && len(w.buffer) >= w.maxBuffer {
+		dropped, full = w.buffer[0], true
+		w.buffer = w.buffer[1:]
+	}
+	w.buffer = append(w.buffer, entry)
+	w.mu.Unlock()
+
+	if full {
+		sideEffectReplaysTotal.WithLabelValues(dropped.Kind, "dropped").Inc()
+		w.onErr(fmt.Errorf("side effect fallback buffer full, dropped a %s side effect from %s",
+			dropped.Kind, dropped.FirstFailedAt.Format(time.RFC3339)))
+	}
+	sideEffectFailuresTotal.WithLabelValues(kind).Inc()
+	w.onErr(fmt.Errorf("%s side effect write failed, kept for replay: %w", kind, cause))
+
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start launches the background loop, which replays stored side effects
+// right away, after a restart, and then every interval
+func (w *Writer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := w.Replay(ctx); err != nil && ctx.Err() == nil {
+				w.onErr(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-w.kick:
+			}
+		}
+	}()
+}
+
+// Stop halts the background loop and stores the buffered side effects
+func (w *Writer) Stop() error {
+	if w.cancel != nil {
+		w.cancel()
+		<-w.done
+	}
+	return w.store()
+}
+
+// Replay stores the buffered side effects and replays the stored ones
+// oldest first, returning how many it replayed. It stops at the first
+// failure, which is recorded on the side effect, so an unavailable
+// database is not retried for every entry.
+func (w *Writer) Replay(ctx context.Context) (int, error) {
+	if err := w.store(); err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	lastID := uint(0)
+	for {
+		var entries []models.SideEffectFallback
+		if err := w.db.WithContext(ctx).Where("id > ?", lastID).Order("id").
+			Limit(replayBatchSize).Find(&entries).Error; err != nil {
+			return replayed, err
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			lastID = entry.ID
+
+			w.mu.Lock()
+			replay, ok := w.replayers[entry.Kind]
+			w.mu.Unlock()
+			if !ok {
+				continue
+			}
+
+			err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := replay(ctx, tx, json.RawMessage(entry.Payload)); err != nil {
+					return err
+				}
+				return tx.Delete(entry).Error
+			})
+			if err != nil {
+				sideEffectReplaysTotal.WithLabelValues(entry.Kind, "failed").Inc()
+				w.db.Model(entry).Updates(map[string]interface{}{
+					"attempts":       gorm.Expr("attempts + 1"),
+					"error":          err.Error(),
+					"last_failed_at": time.Now(),
+				})
+				return replayed, fmt.Errorf("failed to replay %s side effect %d: %w", entry.Kind, entry.ID, err)
+			}
+			sideEffectReplaysTotal.WithLabelValues(entry.Kind, "replayed").Inc()
+			replayed++
+		}
+
+		if len(entries) < replayBatchSize {
+			return replayed, nil
+		}
+	}
+}
+
+// store writes the buffered side effects to the fallback table. On
+// failure they are put back in front of any added meanwhile.
+func (w *Writer) store() error {
+	w.mu.Lock()
+	buffered := w.buffer
+	w.buffer = nil
+	w.mu.Unlock()
+	if len(buffered) == 0 {
+		return nil
+	}
+
+	if err := w.db.CreateInBatches(&buffered, 500).Error; err != nil {
+		for i := range buffered {
+			buffered[i].ID = 0
+		}
+		w.mu.Lock()
+		w.buffer = append(buffered, w.buffer...)
+		w.mu.Unlock()
+		return fmt.Errorf("failed to store side effects for replay: %w", err)
+	}
+	return nil
+}
+
+// Pending counts the side effects waiting to be replayed by kind, both
+// stored and still buffered. The buffered counts are returned along with
+// the error when the table cannot be read.
+func (w *Writer) Pending(ctx context.Context) (map[string]int64, error) {
+	pending := make(map[string]int64)
+	w.mu.Lock()
+	for _, entry := range w.buffer {
+		pending[entry.Kind]++
+	}
+	w.mu.Unlock()
+
+	var counts []struct {
+		Kind  string
+		Count int64
+	}
+	if err := w.db.WithContext(ctx).Model(&models.SideEffectFallback{}).
+		Select("kind, COUNT(*) AS count").Group("kind").Scan(&counts).Error; err != nil {
+		return pending, err
+	}
+	for _, count := range counts {
+		pending[count.Kind] += count.Count
+	}
+	return pending, nil
+}
@@ -0,0 +1,145 @@
+// Package flags defines feature flags for rolling out risky behavior
+// gradually. Flags are declared in code with a default, overridden for the
+// whole service by the FEATURE_FLAGS setting, and per request by the
+// X-Feature-Flags header where the header is honored.
+package flags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Feature flag names
+const (
+	MergePatch   = "merge_patch"
+	ListPrefetch = "list_prefetch"
+)
+
+// Flag is a feature flag declared in code
+type Flag struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+// Definitions lists every feature flag
+var Definitions = []Flag{
+	{Name: MergePatch, Description: "PATCH endpoints apply application/merge-patch+json bodies as RFC 7396 merge patches", Default: true},
+	{Name: ListPrefetch, Description: "Customer and deal lists prime the next page for ?prefetch=true", Default: true},
+}
+
+// Settings holds the service-wide overrides from FEATURE_FLAGS. It is set
+// once at startup.
+var Settings = map[string]bool{}
+
+// Header carries per-request overrides, e.g. "merge_patch=off,list_prefetch"
+const Header = "X-Feature-Flags"
+
+// ContextKey holds the request's accepted header overrides
+const ContextKey = "feature_flags"
+
+// Where a flag's value came from, in increasing precedence
+const (
+	SourceDefault  = "default"
+	SourceSettings = "settings"
+	SourceHeader   = "header"
+)
+
+// State is a flag's value for one request
+type State struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Source      string `json:"source"`
+}
+
+// Parse parses a comma-separated list of overrides. An entry is a flag
+// name, which turns it on, "-name", which turns it off, or name=value with
+// a boolean or on/off value. Unknown flags are rejected.
+func Parse(spec string) (map[string]bool, error) {
+	overrides := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		enabled := true
+		switch {
+		case hasValue:
+			parsed, err := parseValue(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in feature flag %q", entry)
+			}
+			enabled = parsed
+		case strings.HasPrefix(name, "-"):
+			name, enabled = name[1:], false
+		}
+
+		if _, ok := lookup(name); !ok {
+			return nil, fmt.Errorf("unknown feature flag %q", name)
+		}
+		overrides[name] = enabled
+	}
+	return overrides, nil
+}
+
+// parseValue parses a flag value: a boolean, "on" or "off"
+func parseValue(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// Enabled reports whether a flag is on for the request. Unknown flags are
+// off.
+func Enabled(c *gin.Context, name string) bool {
+	flag, ok := lookup(name)
+	if !ok {
+		return false
+	}
+	return state(c, flag).Enabled
+}
+
+// States returns the value of every flag for the request
+func States(c *gin.Context) []State {
+	states := make([]State, 0, len(Definitions))
+	for _, flag := range Definitions {
+		states = append(states, state(c, flag))
+	}
+	return states
+}
+
+// state resolves a flag from its default, the settings and the request's
+// header overrides, the last one set winning
+func state(c *gin.Context, flag Flag) State {
+	s := State{Name: flag.Name, Description: flag.Description, Enabled: flag.Default, Source: SourceDefault}
+	if enabled, ok := Settings[flag.Name]; ok {
+		s.Enabled, s.Source = enabled, SourceSettings
+	}
+	if overrides, ok := c.Value(ContextKey).(map[string]bool); ok {
+		if enabled, ok := overrides[flag.Name]; ok {
+			s.Enabled, s.Source = enabled, SourceHeader
+		}
+	}
+	return s
+}
+
+// lookup finds a flag definition by name
+func lookup(name string) (Flag, bool) {
+	for _, flag := range Definitions {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return Flag{}, false
+}
@@ -0,0 +1,104 @@
+package flags_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/gin-gonic/gin"
+)
+
+// withFlag declares an extra flag for the test, with the service-wide
+// settings reset
+func withFlag(t *testing.T, flag flags.Flag) {
+	t.Helper()
+	definitions, settings := flags.Definitions, flags.Settings
+	flags.Definitions = append(append([]flags.Flag{}, definitions...), flag)
+	t.Cleanup(func() { flags.Definitions, flags.Settings = definitions, settings })
+}
+
+func TestPrecedence(t *testing.T) {
+	const dark = "dark_launch"
+	withFlag(t, flags.Flag{Name: dark, Description: "Off until rolled out"})
+
+	for _, tc := range []struct {
+		name     string
+		settings map[string]bool
+		header   map[string]bool // nil when no header was accepted
+		enabled  bool
+		source   string
+	}{
+		{"default off", nil, nil, false, flags.SourceDefault},
+		{"settings on", map[string]bool{dark: true}, nil, true, flags.SourceSettings},
+		{"settings off", map[string]bool{dark: false}, nil, false, flags.SourceSettings},
+		{"header on over the default", nil, map[string]bool{dark: true}, true, flags.SourceHeader},
+		{"header off over settings on", map[string]bool{dark: true}, map[string]bool{dark: false}, false, flags.SourceHeader},
+		{"header on over settings off", map[string]bool{dark: false}, map[string]bool{dark: true}, true, flags.SourceHeader},
+		{"header for another flag", map[string]bool{dark: true}, map[string]bool{flags.MergePatch: false}, true, flags.SourceSettings},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			flags.Settings = tc.settings
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tc.header != nil {
+				c.Set(flags.ContextKey, tc.header)
+			}
+
+			if got := flags.Enabled(c, dark); got != tc.enabled {
+				t.Errorf("enabled = %v, want %v", got, tc.enabled)
+			}
+			var found bool
+			for _, state := range flags.States(c) {
+				if state.Name == dark {
+					found = true
+					if state.Enabled != tc.enabled || state.Source != tc.source {
+						t.Errorf("state = %+v, want enabled %v from %s", state, tc.enabled, tc.source)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("%s missing from the states", dark)
+			}
+		})
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(flags.ContextKey, map[string]bool{"undeclared": true})
+	if flags.Enabled(c, "undeclared") {
+		t.Error("an undeclared flag is on")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		spec string
+		want map[string]bool
+	}{
+		{"", map[string]bool{}},
+		{"merge_patch", map[string]bool{flags.MergePatch: true}},
+		{"-merge_patch", map[string]bool{flags.MergePatch: false}},
+		{" merge_patch=off , list_prefetch = ON ", map[string]bool{flags.MergePatch: false, flags.ListPrefetch: true}},
+		{"merge_patch=false,list_prefetch=1", map[string]bool{flags.MergePatch: false, flags.ListPrefetch: true}},
+		{"merge_patch=on,-merge_patch", map[string]bool{flags.MergePatch: false}},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := flags.Parse(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("overrides = %v, want %v", got, tc.want)
+			}
+			for name, enabled := range tc.want {
+				if value, ok := got[name]; !ok || value != enabled {
+					t.Errorf("%s = %v, want %v", name, value, enabled)
+				}
+			}
+		})
+	}
+
+	for _, spec := range []string{"no_such_flag", "merge_patch=maybe", "-"} {
+		if overrides, err := flags.Parse(spec); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", spec, overrides)
+		}
+	}
+}
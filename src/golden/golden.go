@@ -0,0 +1,75 @@
+// Package golden compares responses with golden files under a test
+// package's testdata directory. Running the tests with -update rewrites the
+// files from the responses instead, for review in the diff:
+//
+//	go test ./src/routes -run Golden -update
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files from the test output")
+
+// JSON compares a JSON body with testdata/<name>.json. Both are compared
+// canonically, indented with sorted keys, so the files stay readable and
+// byte-stable.
+func JSON(t testing.TB, name string, body []byte) {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("golden %s: response is not JSON: %v\n%s", name, err, body)
+	}
+	got, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatalf("golden %s: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("golden %s: %v (run with -update to create it)", name, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("golden %s: response differs from %s (run with -update to accept):\n%s", name, path, diff(string(want), string(got)))
+	}
+}
+
+// diff renders the first differing lines of two texts with some context
+func diff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	first := 0
+	for first < len(wantLines) && first < len(gotLines) && wantLines[first] == gotLines[first] {
+		first++
+	}
+	start := max(first-3, 0)
+	var b strings.Builder
+	for i := start; i < first; i++ {
+		b.WriteString("  " + wantLines[i] + "\n")
+	}
+	for i := first; i < min(first+5, len(wantLines)); i++ {
+		b.WriteString("- " + wantLines[i] + "\n")
+	}
+	for i := first; i < min(first+5, len(gotLines)); i++ {
+		b.WriteString("+ " + gotLines[i] + "\n")
+	}
+	return b.String()
+}
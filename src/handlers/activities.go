@@ -1,26 +1,34 @@
 package handlers
 
 import (
-	"math"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // ActivityHandler handles activity-related endpoints
 type ActivityHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	calendar *businesstime.Service
+	quotas   *quota.Tracker
 }
 
 // NewActivityHandler creates a new ActivityHandler
-func NewActivityHandler(db *gorm.DB) *ActivityHandler {
-	return &ActivityHandler{db: db}
+func NewActivityHandler(db *gorm.DB, calendar *businesstime.Service, quotas *quota.Tracker) *ActivityHandler {
+	return &ActivityHandler{db: db, calendar: calendar, quotas: quotas}
 }
 
 // ActivityCreateRequest represents the request body for creating an activity
@@ -35,7 +43,10 @@ type ActivityCreateRequest struct {
 	AssignedTo  *uint                `json:"assigned_to,omitempty"`
 	DueDate     *time.Time           `json:"due_date,omitempty"`
 	Duration    int                  `json:"duration,omitempty"`
+	Outcome     string               `json:"outcome,omitempty"`
+	OutcomeCode string               `json:"outcome_code,omitempty" binding:"max=50"`
 	Priority    string               `json:"priority,omitempty"`
+	Template    string               `json:"template,omitempty" binding:"max=100"`
 }
 
 // ActivityUpdateRequest represents the request body for updating an activity
@@ -52,106 +63,159 @@ type ActivityUpdateRequest struct {
 	CompletedAt *time.Time            `json:"completed_at,omitempty"`
 	Duration    *int                  `json:"duration,omitempty"`
 	Outcome     string                `json:"outcome,omitempty"`
+	OutcomeCode string                `json:"outcome_code,omitempty" binding:"max=50"`
 	Priority    string                `json:"priority,omitempty"`
+	Template    string                `json:"template,omitempty" binding:"max=100"`
+	Reopen      bool                  `json:"reopen,omitempty"` // Allow moving a completed or cancelled activity back to an open status
 }
 
 // ActivityStatusUpdateRequest represents a status update request
 type ActivityStatusUpdateRequest struct {
-	Status  models.ActivityStatus `json:"status" binding:"required"`
-	Outcome string                `json:"outcome,omitempty"`
+	Status      models.ActivityStatus `json:"status" binding:"required"`
+	Outcome     string                `json:"outcome,omitempty"`
+	OutcomeCode string                `json:"outcome_code,omitempty" binding:"max=50"`
+	Reopen      bool                  `json:"reopen,omitempty"` // Allow moving a completed or cancelled activity back to an open status
 }
 
-// ListActivities returns a paginated list of activities with filtering
-// GET /admin/activities
-func (h *ActivityHandler) ListActivities(c *gin.Context) {
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
+// ActivityCompleteRequest represents the request body for completing an activity
+type ActivityCompleteRequest struct {
+	Outcome      string               `json:"outcome" binding:"required"`
+	OutcomeCode  string               `json:"outcome_code,omitempty" binding:"max=50"` // Required when the type has outcomes
+	Duration     *int                 `json:"duration,omitempty" binding:"omitempty,min=0"`
+	NextActivity *NextActivityRequest `json:"next_activity,omitempty"`
+	DealNextStep *DealNextStepUpdate  `json:"deal_next_step,omitempty"`
+}
 
-	query := h.db.Model(&models.Activity{})
+// DealNextStepUpdate replaces or clears the next step of the completed
+// activity's deal, typically when the follow-up it scheduled is done
+type DealNextStepUpdate struct {
+	NextStep    string     `json:"next_step,omitempty" binding:"max=255"`
+	NextStepDue *time.Time `json:"next_step_due,omitempty"`
+	Clear       bool       `json:"clear,omitempty"`
+}
 
-	// Filters
-	if activityType := c.Query("type"); activityType != "" {
-		query = query.Where("type = ?", activityType)
-	}
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
-	}
-	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
-		query = query.Where("assigned_to = ?", assignedTo)
-	}
-	if customerID := c.Query("customer_id"); customerID != "" {
-		query = query.Where("customer_id = ?", customerID)
-	}
-	if dealID := c.Query("deal_id"); dealID != "" {
-		query = query.Where("deal_id = ?", dealID)
-	}
-	if search := c.Query("search"); search != "" {
-		searchTerm := "%" + strings.ToLower(search) + "%"
-		query = query.Where("LOWER(title) LIKE ?", searchTerm)
-	}
-	if dueDateFrom := c.Query("due_date_from"); dueDateFrom != "" {
-		if t, err := time.Parse(time.RFC3339, dueDateFrom); err == nil {
-			query = query.Where("due_date >= ?", t)
-		}
+// NextActivityRequest describes the follow-up scheduled when an activity is
+// completed. Links and assignee default to those of the completed activity.
+type NextActivityRequest struct {
+	Title             string              `json:"title" binding:"required,min=1,max=255"`
+	Description       string              `json:"description,omitempty"`
+	Type              models.ActivityType `json:"type,omitempty"`
+	CustomerID        *uint               `json:"customer_id,omitempty"`
+	DealID            *uint               `json:"deal_id,omitempty"`
+	ContactID         *uint               `json:"contact_id,omitempty"`
+	AssignedTo        *uint               `json:"assigned_to,omitempty"`
+	DueDate           *time.Time          `json:"due_date,omitempty"`
+	DueInDays         *int                `json:"due_in_days,omitempty" binding:"omitempty,min=0"`
+	DueInBusinessDays *int                `json:"due_in_business_days,omitempty" binding:"omitempty,min=0"` // Skips weekends and holidays
+	Priority          string              `json:"priority,omitempty"`
+}
+
+// dueDateOptions counts how many ways of setting the due date were given
+func (r *NextActivityRequest) dueDateOptions() int {
+	count := 0
+	if r.DueDate != nil {
+		count++
 	}
-	if dueDateTo := c.Query("due_date_to"); dueDateTo != "" {
-		if t, err := time.Parse(time.RFC3339, dueDateTo); err == nil {
-			query = query.Where("due_date <= ?", t)
-		}
+	if r.DueInDays != nil {
+		count++
 	}
-	if priority := c.Query("priority"); priority != "" {
-		query = query.Where("priority = ?", priority)
+	if r.DueInBusinessDays != nil {
+		count++
 	}
+	return count
+}
 
-	// Sorting
-	sortBy := c.DefaultQuery("sort_by", "due_date")
-	sortOrder := c.DefaultQuery("sort_order", "asc")
-	if sortOrder != "asc" && sortOrder != "desc" {
-		sortOrder = "asc"
-	}
-	allowedSortFields := map[string]bool{
-		"created_at": true, "updated_at": true, "title": true, "due_date": true,
-		"status": true, "type": true, "priority": true,
-	}
-	if !allowedSortFields[sortBy] {
-		sortBy = "due_date"
+// activityListQuery defines the filters and sorting of ListActivities
+var activityListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("type", "type"),
+		query.Equal("status", models.EffectiveStatusSQL),
+		query.Equal("assigned_to", "assigned_to"),
+		query.Equal("customer_id", "customer_id"),
+		query.Equal("deal_id", "deal_id"),
+		query.FoldedSearch("search", "activities.search_text"),
+		query.AtLeast("due_date_from", "due_date", query.KindTime),
+		query.AtMost("due_date_to", "due_date", query.KindTime),
+		query.Equal("priority", "priority"),
+		query.Condition("claimable", "activities.assigned_to IS NULL AND activities.status NOT IN ('completed', 'cancelled')"),
+	},
+	Sort: query.Sort{
+		Fields:       []string{"created_at", "updated_at", "title", "due_date", "status", "type", "priority"},
+		DefaultField: "due_date",
+		DefaultOrder: "asc",
+	},
+}
+
+// ListActivities returns a paginated list of activities with filtering.
+// With cursor, the page after that cursor is listed in the same sort
+// instead of a numbered page.
+// GET /admin/activities
+func (h *ActivityHandler) ListActivities(c *gin.Context) {
+	values := c.Request.URL.Query()
+	page := query.ParsePage(values)
+
+	db := middleware.GetReadDB(c, h.db).WithContext(c).Model(&models.Activity{})
+	db, filters := activityListQuery.Filter(db, values)
+	db = db.Session(&gorm.Session{})
+
+	cursor, err := activityListQuery.ParseCursor(values.Get("cursor"), values)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
 	}
-	query = query.Order(sortBy + " " + sortOrder)
 
 	// Count total
 	var total int64
-	query.Count(&total)
+	db.Count(&total)
 
-	// Get activities
+	rows := db.Scopes(models.WithEffectiveStatus).Preload("Customer").Preload("Deal")
 	var activities []models.Activity
-	offset := (page - 1) * pageSize
-	if err := query.Preload("Customer").Preload("Deal").Offset(offset).Limit(pageSize).Find(&activities).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch activities",
-		})
+	more := false
+	if cursor != nil {
+		page.Page = 0
+		activities, more, err = query.FindAfter[models.Activity](rows, activityListQuery, "activities", values, *cursor, page.PageSize)
+	} else {
+		err = rows.Order(activityListQuery.KeysetOrder("activities", values)).Offset(page.Offset()).Limit(page.PageSize).Find(&activities).Error
+		more = page.Page < page.TotalPages(total)
+	}
+	if errors.Is(err, query.ErrInvalidCursor) {
+		respondInvalidCursor(c)
+		return
+	}
+	if err != nil {
+		respondListFailed(c, "Failed to fetch activities")
 		return
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	var nextCursor string
+	if more && len(activities) > 0 {
+		if nextCursor, err = activityListQuery.NextCursor(c, db, values, &activities[len(activities)-1]); err != nil {
+			respondListFailed(c, "Failed to fetch activities")
+			return
+		}
+	}
 
+	setPageHeaders(c, total, nextCursor)
 	c.JSON(http.StatusOK, models.ActivityListResponse{
 		Data:       activities,
 		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+		NextCursor: nextCursor,
+		Filters:    filters,
 	})
 }
 
+// myActivityListQuery defines the filters and ordering of GetMyActivities;
+// upcoming tasks come first
+var myActivityListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("status", models.EffectiveStatusSQL),
+	},
+	Sort: query.Sort{Fixed: "due_date ASC NULLS LAST"},
+}
+
 // GetMyActivities returns activities assigned to the current user
 // GET /admin/me/activities
 func (h *ActivityHandler) GetMyActivities(c *gin.Context) {
@@ -160,61 +224,46 @@ func (h *ActivityHandler) GetMyActivities(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "unauthorized",
 			"code":    "NO_USER_CONTEXT",
-			"message": "User not found in context",
+			"message": i18n.Message(c, "NO_USER_CONTEXT", "User not found in context"),
 		})
 		return
 	}
 
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
+	page := query.ParsePage(c.Request.URL.Query())
 
-	query := h.db.Model(&models.Activity{}).Where("assigned_to = ?", user.ID)
+	db := h.db.WithContext(c).Model(&models.Activity{}).Where("assigned_to = ?", user.ID)
+	db, filters := myActivityListQuery.Apply(db, c.Request.URL.Query())
 
-	// Filter by status (default to scheduled/overdue for "my tasks")
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
-	} else {
-		// Default: show scheduled and overdue tasks
-		query = query.Where("status IN ?", []string{
+	// Default to scheduled and overdue tasks for "my tasks"
+	if _, ok := filters["status"]; !ok {
+		db = db.Where("status IN ?", []string{
 			string(models.ActivityStatusScheduled),
 			string(models.ActivityStatusOverdue),
 		})
 	}
 
-	// Order by due date ascending (upcoming first)
-	query = query.Order("due_date ASC NULLS LAST")
-
 	// Count total
 	var total int64
-	query.Count(&total)
+	db.Count(&total)
 
-	// Get activities
 	var activities []models.Activity
-	offset := (page - 1) * pageSize
-	if err := query.Preload("Customer").Preload("Deal").Offset(offset).Limit(pageSize).Find(&activities).Error; err != nil {
+	if err := db.Scopes(models.WithEffectiveStatus).Preload("Customer").Preload("Deal").Offset(page.Offset()).Limit(page.PageSize).Find(&activities).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch activities",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activities"),
 		})
 		return
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-
+	setPageHeaders(c, total, "")
 	c.JSON(http.StatusOK, models.ActivityListResponse{
 		Data:       activities,
 		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+		Filters:    filters,
 	})
 }
 
@@ -226,7 +275,7 @@ func (h *ActivityHandler) CreateActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
@@ -236,7 +285,7 @@ func (h *ActivityHandler) CreateActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "MISSING_LINK",
-			"message": "Activity must be linked to a customer or deal",
+			"message": i18n.Message(c, "MISSING_LINK", "Activity must be linked to a customer or deal"),
 		})
 		return
 	}
@@ -262,24 +311,44 @@ func (h *ActivityHandler) CreateActivity(c *gin.Context) {
 		AssignedTo:  req.AssignedTo,
 		DueDate:     req.DueDate,
 		Duration:    req.Duration,
+		Outcome:     req.Outcome,
+		OutcomeCode: req.OutcomeCode,
 		Priority:    priority,
+		Template:    req.Template,
 	}
 
-	if err := h.db.Create(&activity).Error; err != nil {
+	if !checkActivityPolicy(c, &activity, "") {
+		return
+	}
+	if !h.checkActivityOutcome(c, &activity, nil) {
+		return
+	}
+	if !checkLongText(c, &activity, nil, "") {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&activity).Error; err != nil {
+			return err
+		}
+		// Reload with relations
+		if err := tx.Preload("Customer").Preload("Deal").First(&activity, activity.ID).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity", activity.ID, models.AuditActionCreate, nil, &activity)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to create activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create activity"),
 		})
 		return
 	}
 
-	// Reload with relations
-	h.db.Preload("Customer").Preload("Deal").First(&activity, activity.ID)
-
-	// Log audit
-	h.logAudit(c, "activity", activity.ID, models.AuditActionCreate, nil, &activity)
-
 	c.JSON(http.StatusCreated, activity)
 }
 
@@ -291,29 +360,37 @@ func (h *ActivityHandler) GetActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid activity ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid activity ID"),
 		})
 		return
 	}
 
 	var activity models.Activity
-	if err := h.db.Preload("Customer").Preload("Deal").Preload("Contact").First(&activity, id).Error; err != nil {
+	if err := h.db.WithContext(c).Scopes(models.WithEffectiveStatus).Preload("Customer").Preload("Deal").Preload("Contact").First(&activity, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "ACTIVITY_NOT_FOUND",
-				"message": "Activity not found",
+				"message": i18n.Message(c, "ACTIVITY_NOT_FOUND", "Activity not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity"),
 		})
 		return
 	}
 
+	if activity.Type == models.ActivityTypeEmail {
+		engagement := loadEmailEngagement(h.db.WithContext(c), activity.ID)
+		activity.Engagement = &engagement
+	}
+	if activity.Type == models.ActivityTypeCall {
+		activity.Call = loadActivityCall(h.db.WithContext(c), activity.ID)
+	}
+
 	c.JSON(http.StatusOK, activity)
 }
 
@@ -325,25 +402,25 @@ func (h *ActivityHandler) UpdateActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid activity ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid activity ID"),
 		})
 		return
 	}
 
 	var activity models.Activity
-	if err := h.db.First(&activity, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&activity, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "ACTIVITY_NOT_FOUND",
-				"message": "Activity not found",
+				"message": i18n.Message(c, "ACTIVITY_NOT_FOUND", "Activity not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity"),
 		})
 		return
 	}
@@ -355,7 +432,7 @@ func (h *ActivityHandler) UpdateActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
@@ -397,29 +474,58 @@ func (h *ActivityHandler) UpdateActivity(c *gin.Context) {
 	if req.Outcome != "" {
 		activity.Outcome = req.Outcome
 	}
+	if req.OutcomeCode != "" {
+		activity.OutcomeCode = req.OutcomeCode
+	}
 	if req.Priority != "" {
 		activity.Priority = req.Priority
 	}
+	if req.Template != "" {
+		activity.Template = req.Template
+	}
 
-	if err := h.db.Save(&activity).Error; err != nil {
+	if !checkActivityStatus(c, oldActivity.Status, activity.Status, req.Reopen) {
+		return
+	}
+	activity.SyncCompletedAt(oldActivity, time.Now())
+
+	if !checkActivityPolicy(c, &activity, "") {
+		return
+	}
+	if !h.checkActivityOutcome(c, &activity, &oldActivity) {
+		return
+	}
+	if !checkLongText(c, &activity, &oldActivity, "") {
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&activity).Error; err != nil {
+			return err
+		}
+		// Reload with relations
+		if err := tx.Preload("Customer").Preload("Deal").First(&activity, activity.ID).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity", activity.ID, activityUpdateAction(oldActivity, activity), &oldActivity, &activity)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update activity"),
 		})
 		return
 	}
 
-	// Reload with relations
-	h.db.Preload("Customer").Preload("Deal").First(&activity, activity.ID)
-
-	// Log audit
-	h.logAudit(c, "activity", activity.ID, models.AuditActionUpdate, &oldActivity, &activity)
-
 	c.JSON(http.StatusOK, activity)
 }
 
-// PatchActivity handles status updates (complete/cancel)
+// PatchActivity handles status updates (complete/cancel). Closed activities
+// are only reopened when reopen is true.
 // PATCH /admin/activities/:id
 func (h *ActivityHandler) PatchActivity(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -427,29 +533,34 @@ func (h *ActivityHandler) PatchActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid activity ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid activity ID"),
 		})
 		return
 	}
 
 	var activity models.Activity
-	if err := h.db.First(&activity, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&activity, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "ACTIVITY_NOT_FOUND",
-				"message": "Activity not found",
+				"message": i18n.Message(c, "ACTIVITY_NOT_FOUND", "Activity not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity"),
 		})
 		return
 	}
 
+	if isMergePatch(c) {
+		h.mergePatchActivity(c, activity)
+		return
+	}
+
 	oldActivity := activity
 
 	var req ActivityStatusUpdateRequest
@@ -457,42 +568,478 @@ func (h *ActivityHandler) PatchActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
-	// Update status
-	activity.Status = req.Status
-
-	// If completed, set completed_at
-	if req.Status == models.ActivityStatusCompleted {
-		now := time.Now()
-		activity.CompletedAt = &now
+	if !checkActivityStatus(c, activity.Status, req.Status, req.Reopen) {
+		return
 	}
 
+	// Update status, stamping completed_at only when newly completed
+	activity.Status = req.Status
+	activity.SyncCompletedAt(oldActivity, time.Now())
+	completed := activity.Status == models.ActivityStatusCompleted && oldActivity.Status != models.ActivityStatusCompleted
+
 	if req.Outcome != "" {
 		activity.Outcome = req.Outcome
 	}
+	if req.OutcomeCode != "" {
+		activity.OutcomeCode = req.OutcomeCode
+	}
 
-	if err := h.db.Save(&activity).Error; err != nil {
+	if !checkActivityPolicy(c, &activity, "") {
+		return
+	}
+	if !h.checkActivityOutcome(c, &activity, &oldActivity) {
+		return
+	}
+	if !checkLongText(c, &activity, &oldActivity, "") {
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&activity).Error; err != nil {
+			return err
+		}
+		if completed {
+			if err := markCustomerContacted(tx, &activity, nil); err != nil {
+				return err
+			}
+		}
+		// Reload with relations
+		if err := tx.Preload("Customer").Preload("Deal").First(&activity, activity.ID).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity", activity.ID, activityUpdateAction(oldActivity, activity), &oldActivity, &activity)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update activity"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, activity)
+}
+
+// mergePatchActivity applies a JSON Merge Patch to an activity. Validation
+// runs on the patched activity; completing it stamps completed_at unless the
+// patch sets it and marks the customer as contacted. Closed activities are
+// only reopened with ?reopen=true.
+func (h *ActivityHandler) mergePatchActivity(c *gin.Context, activity models.Activity) {
+	oldActivity := activity
+
+	changed, ok := applyMergePatch(c, &activity, activityMergePatchFields)
+	if !ok {
+		return
+	}
+	if len(changed) == 0 {
+		c.JSON(http.StatusOK, activity)
+		return
+	}
+
+	activity.Title = strings.TrimSpace(activity.Title)
+	if activity.Title == "" || len(activity.Title) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "title must be between 1 and 255 characters"),
+		})
+		return
+	}
+	if len(activity.Template) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "template must be at most 100 characters"),
+		})
+		return
+	}
+	if !models.IsValidActivityType(activity.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ACTIVITY_TYPE",
+			"message": i18n.Message(c, "INVALID_ACTIVITY_TYPE", "Invalid activity type"),
+		})
+		return
+	}
+	if patchTouched(changed, "priority") && !slices.Contains(models.ValidActivityPriorities, activity.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_PRIORITY",
+			"message": i18n.Message(c, "INVALID_PRIORITY", "Priority must be low, normal or high"),
+		})
+		return
+	}
+	if activity.Duration < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "duration cannot be negative"),
 		})
 		return
 	}
 
-	// Reload with relations
-	h.db.Preload("Customer").Preload("Deal").First(&activity, activity.ID)
+	if !checkActivityStatus(c, oldActivity.Status, activity.Status, c.Query("reopen") == "true") {
+		return
+	}
+	// completed_at follows the status, so a patch cannot contradict it
+	if patchTouched(changed, "completed_at") {
+		if activity.Status == models.ActivityStatusCompleted && activity.CompletedAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "FIELD_NOT_NULLABLE",
+				"message": i18n.Message(c, "FIELD_NOT_NULLABLE", "completed_at cannot be cleared on a completed activity"),
+				"fields":  []string{"completed_at"},
+			})
+			return
+		}
+		if activity.Status != models.ActivityStatusCompleted && activity.CompletedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_REQUEST",
+				"message": i18n.Message(c, "INVALID_REQUEST", "completed_at is only set on completed activities"),
+			})
+			return
+		}
+	}
+	if activity.SyncCompletedAt(oldActivity, time.Now()) && !patchTouched(changed, "completed_at") {
+		changed = append(changed, "completed_at")
+	}
+	completed := activity.Status == models.ActivityStatusCompleted && oldActivity.Status != models.ActivityStatusCompleted
 
-	// Log audit
-	h.logAudit(c, "activity", activity.ID, models.AuditActionUpdate, &oldActivity, &activity)
+	if !checkActivityPolicy(c, &activity, "") {
+		return
+	}
+	if !h.checkActivityOutcome(c, &activity, &oldActivity) {
+		return
+	}
+	if !checkLongText(c, &activity, &oldActivity, "") {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Select writes cleared fields, which Updates would otherwise skip
+		if err := tx.Model(&activity).Select(changed).Updates(&activity).Error; err != nil {
+			return err
+		}
+		if completed {
+			if err := markCustomerContacted(tx, &activity, nil); err != nil {
+				return err
+			}
+		}
+		// Reload with relations
+		if err := tx.Preload("Customer").Preload("Deal").First(&activity, activity.ID).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity", activity.ID, activityUpdateAction(oldActivity, activity), &oldActivity, &activity)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update activity"),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, activity)
 }
 
+// dealForNextStep loads the deal of a completed activity and applies a
+// next step update to it, writing the error response when the activity has
+// no deal or the deal may not be changed. It returns the updated deal and
+// its previous state.
+func (h *ActivityHandler) dealForNextStep(c *gin.Context, activity *models.Activity, update *DealNextStepUpdate, now time.Time) (*models.Deal, *models.Deal) {
+	nextStep := strings.TrimSpace(update.NextStep)
+	if !update.Clear && nextStep == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "deal_next_step needs a next_step unless clear is true"),
+		})
+		return nil, nil
+	}
+
+	var deal models.Deal
+	if activity.DealID == nil || h.db.WithContext(c).First(&deal, *activity.DealID).Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "DEAL_NOT_FOUND",
+			"message": i18n.Message(c, "DEAL_NOT_FOUND", "Deal not found"),
+		})
+		return nil, nil
+	}
+	if rejectArchived(c, deal.ArchivedAt) {
+		return nil, nil
+	}
+	if !enforceFieldPermissions(c, models.EntityDeal, deal.OwnerID, []string{"next_step", "next_step_due"}) {
+		return nil, nil
+	}
+
+	oldDeal := deal
+	deal.NextStep, deal.NextStepDue = nextStep, update.NextStepDue
+	if update.Clear {
+		deal.NextStep, deal.NextStepDue = "", nil
+	}
+	deal.TrackNextStep(oldDeal, now)
+	return &deal, &oldDeal
+}
+
+// CompleteActivity completes an activity with its outcome and optionally
+// schedules the next activity in the same transaction
+// POST /admin/activities/:id/complete
+func (h *ActivityHandler) CompleteActivity(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid activity ID"),
+		})
+		return
+	}
+
+	var req ActivityCompleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if req.NextActivity != nil && req.NextActivity.dueDateOptions() > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "CONFLICTING_DUE_DATE",
+			"message": i18n.Message(c, "CONFLICTING_DUE_DATE", "Provide only one of due_date, due_in_days or due_in_business_days"),
+		})
+		return
+	}
+
+	if req.NextActivity != nil && !middleware.CheckQuota(c, h.quotas, quota.Activities, 1) {
+		return
+	}
+
+	var activity models.Activity
+	if err := h.db.WithContext(c).First(&activity, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "ACTIVITY_NOT_FOUND",
+				"message": i18n.Message(c, "ACTIVITY_NOT_FOUND", "Activity not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity"),
+		})
+		return
+	}
+
+	if activity.IsClosed() {
+		respondActivityClosed(c)
+		return
+	}
+
+	oldActivity := activity
+
+	now := time.Now()
+	activity.Status = models.ActivityStatusCompleted
+	activity.CompletedAt = &now
+	activity.Outcome = req.Outcome
+	activity.OutcomeCode = req.OutcomeCode
+	if req.Duration != nil {
+		activity.Duration = *req.Duration
+	}
+
+	if !checkActivityPolicy(c, &activity, "") {
+		return
+	}
+	if !h.checkActivityOutcome(c, &activity, &oldActivity) {
+		return
+	}
+	if !checkLongText(c, &activity, &oldActivity, "") {
+		return
+	}
+
+	var next *models.Activity
+	if req.NextActivity != nil {
+		next = buildNextActivity(&activity, req.NextActivity, now, h.calendar.Calendar(""))
+		if !checkActivityPolicy(c, next, "next_activity.") {
+			return
+		}
+		if !checkLongText(c, next, nil, "next_activity.") {
+			return
+		}
+	}
+
+	var deal, oldDeal *models.Deal
+	if req.DealNextStep != nil {
+		if deal, oldDeal = h.dealForNextStep(c, &activity, req.DealNextStep, now); deal == nil {
+			return
+		}
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Complete the activity only while it is still open, so of two
+		// concurrent completions one is refused
+		result := tx.Model(&activity).
+			Where("status NOT IN ?", []models.ActivityStatus{models.ActivityStatusCompleted, models.ActivityStatusCancelled}).
+			Select("status", "completed_at", "outcome", "outcome_code", "duration", "updated_at").
+			Updates(&activity)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errActivityClosed
+		}
+		if next != nil {
+			if err := tx.Create(next).Error; err != nil {
+				return err
+			}
+		}
+		if deal != nil {
+			if err := tx.Model(deal).Select("next_step", "next_step_due", "next_step_set_at", "next_step_nudged_at").Updates(deal).Error; err != nil {
+				return err
+			}
+		}
+		if err := markCustomerContacted(tx, &activity, next); err != nil {
+			return err
+		}
+
+		// Reload with relations
+		if err := tx.Preload("Customer").Preload("Deal").First(&activity, activity.ID).Error; err != nil {
+			return err
+		}
+		if next != nil {
+			if err := tx.Preload("Customer").Preload("Deal").First(next, next.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := logAudit(c, tx, "activity", activity.ID, models.AuditActionUpdate, &oldActivity, &activity); err != nil {
+			return err
+		}
+		if next != nil {
+			if err := logAudit(c, tx, "activity", next.ID, models.AuditActionCreate, nil, next); err != nil {
+				return err
+			}
+		}
+		if deal != nil {
+			return logAudit(c, tx, "deal", deal.ID, models.AuditActionUpdate, oldDeal, deal)
+		}
+		return nil
+	})
+	if errors.Is(err, errActivityClosed) {
+		respondActivityClosed(c)
+		return
+	}
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to complete activity"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ActivityCompletionResponse{
+		Completed:    activity,
+		NextActivity: next,
+	})
+}
+
+// errActivityClosed is returned inside a completion transaction when the
+// activity was completed or cancelled after it was read
+var errActivityClosed = errors.New("activity is already closed")
+
+// respondActivityClosed writes the 409 response for completing an activity
+// that is already completed or cancelled
+func respondActivityClosed(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "conflict",
+		"code":    "ACTIVITY_ALREADY_CLOSED",
+		"message": i18n.Message(c, "ACTIVITY_ALREADY_CLOSED", "Activity is already completed or cancelled"),
+	})
+}
+
+// buildNextActivity creates the follow-up for a completed activity, inheriting
+// its type, links, assignee, and priority unless overridden
+func buildNextActivity(completed *models.Activity, req *NextActivityRequest, now time.Time, calendar *businesstime.Calendar) *models.Activity {
+	next := &models.Activity{
+		Title:              req.Title,
+		Description:        req.Description,
+		Type:               completed.Type,
+		Status:             models.ActivityStatusScheduled,
+		CustomerID:         completed.CustomerID,
+		DealID:             completed.DealID,
+		ContactID:          completed.ContactID,
+		AssignedTo:         completed.AssignedTo,
+		DueDate:            req.DueDate,
+		Priority:           completed.Priority,
+		PreviousActivityID: &completed.ID,
+	}
+	if req.Type != "" {
+		next.Type = req.Type
+	}
+	if req.CustomerID != nil {
+		next.CustomerID = req.CustomerID
+	}
+	if req.DealID != nil {
+		next.DealID = req.DealID
+	}
+	if req.ContactID != nil {
+		next.ContactID = req.ContactID
+	}
+	if req.AssignedTo != nil {
+		next.AssignedTo = req.AssignedTo
+	}
+	if req.DueInDays != nil {
+		due := now.AddDate(0, 0, *req.DueInDays)
+		next.DueDate = &due
+	}
+	if req.DueInBusinessDays != nil {
+		due := calendar.AddBusinessDays(now, *req.DueInBusinessDays)
+		next.DueDate = &due
+	}
+	if req.Priority != "" {
+		next.Priority = req.Priority
+	}
+	return next
+}
+
+// markCustomerContacted marks the customer of a completed activity as
+// contacted and moves its next follow-up to the scheduled next activity
+func markCustomerContacted(tx *gorm.DB, completed *models.Activity, next *models.Activity) error {
+	if completed.CustomerID == nil {
+		return nil
+	}
+
+	updates := map[string]interface{}{"contacted": true}
+	if next != nil && next.DueDate != nil && next.CustomerID != nil && *next.CustomerID == *completed.CustomerID {
+		updates["next_follow_up_at"] = *next.DueDate
+	}
+	return tx.Model(&models.Customer{}).Where("id = ?", *completed.CustomerID).Updates(updates).Error
+}
+
 // DeleteActivity soft-deletes an activity
 // DELETE /admin/activities/:id
 func (h *ActivityHandler) DeleteActivity(c *gin.Context) {
@@ -501,60 +1048,114 @@ func (h *ActivityHandler) DeleteActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid activity ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid activity ID"),
 		})
 		return
 	}
 
 	var activity models.Activity
-	if err := h.db.First(&activity, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&activity, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "ACTIVITY_NOT_FOUND",
-				"message": "Activity not found",
+				"message": i18n.Message(c, "ACTIVITY_NOT_FOUND", "Activity not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity"),
 		})
 		return
 	}
 
-	if err := h.db.Delete(&activity).Error; err != nil {
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&activity).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity", activity.ID, models.AuditActionDelete, &activity, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to delete activity",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete activity"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "activity", activity.ID, models.AuditActionDelete, &activity, nil)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Activity deleted successfully",
 	})
 }
 
-// logAudit creates an audit log entry
-func (h *ActivityHandler) logAudit(c *gin.Context, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) {
-	user, _ := middleware.GetUserFromContext(c)
+// checkActivityStatus checks that an activity may move from one status to
+// another, writing a 422 response listing the allowed statuses otherwise
+func checkActivityStatus(c *gin.Context, from, to models.ActivityStatus, reopen bool) bool {
+	if !models.IsValidActivityStatus(to) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_STATUS",
+			"message": i18n.Message(c, "INVALID_STATUS", "Invalid status"),
+		})
+		return false
+	}
+	if models.CanTransitionActivity(from, to, reopen) {
+		return true
+	}
 
-	audit := models.AuditLog{
-		ResourceType: resourceType,
-		ResourceID:   resourceID,
-		Action:       action,
-		UserID:       user.ID,
-		UserName:     user.Name,
-		UserRole:     user.Role,
-		IPAddress:    c.ClientIP(),
-		UserAgent:    c.Request.UserAgent(),
+	message := "Cannot change an activity from " + string(from) + " to " + string(to)
+	if models.IsActivityReopen(from, to) {
+		message += "; set reopen to true to reopen it"
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":   "validation_error",
+		"code":    "INVALID_TRANSITION",
+		"message": i18n.Message(c, "INVALID_TRANSITION", message),
+		"from":    from,
+		"allowed": models.AllowedActivityTransitions(from, reopen),
+	})
+	return false
+}
+
+// activityUpdateAction is the audit action of an activity update, which is
+// recorded as a reopen when it moved a closed activity back to an open status
+func activityUpdateAction(old, updated models.Activity) models.AuditAction {
+	if models.IsActivityReopen(old.Status, updated.Status) {
+		return models.AuditActionReopen
+	}
+	return models.AuditActionUpdate
+}
+
+// checkActivityPolicy checks that an activity has the fields the activity
+// policy requires for its type and status, writing a 422 response listing
+// the missing ones. Activities created before the policy applied only get a
+// Warning header unless the policy is strict. prefix qualifies the reported
+// field names.
+func checkActivityPolicy(c *gin.Context, activity *models.Activity, prefix string) bool {
+	missing := models.ActivityRequirements.MissingFields(*activity)
+	if len(missing) == 0 {
+		return true
+	}
+	for i := range missing {
+		missing[i] = prefix + missing[i]
 	}
 
-	h.db.Create(&audit)
+	if !models.ActivityPolicyStrict && activity.PredatesActivityPolicy() {
+		c.Writer.Header().Add("Warning", fmt.Sprintf("299 - %q", "Missing required fields: "+strings.Join(missing, ", ")))
+		return true
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":   "validation_error",
+		"code":    "MISSING_REQUIRED_FIELDS",
+		"message": i18n.Message(c, "MISSING_REQUIRED_FIELDS", "Missing fields required for a "+string(activity.Status)+" "+string(activity.Type)+": "+strings.Join(missing, ", ")),
+		"fields":  missing,
+	})
+	return false
 }
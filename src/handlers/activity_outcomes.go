@@ -0,0 +1,501 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// outcomeClassificationBatchSize is the number of activities loaded, and
+// updated, per batch of an outcome classification
+const outcomeClassificationBatchSize = 500
+
+// outcomeClassificationSamples is the number of free-text outcomes shown
+// per group of an outcome classification
+const outcomeClassificationSamples = 5
+
+// Results of classifying a free-text outcome
+const (
+	OutcomeClassified = "classified"
+	OutcomeAmbiguous  = "ambiguous" // Keywords of several outcomes match
+	OutcomeUnmatched  = "unmatched"
+)
+
+// ActivityOutcomeHandler handles the activity outcome taxonomy endpoints
+type ActivityOutcomeHandler struct {
+	db *gorm.DB
+}
+
+// NewActivityOutcomeHandler creates a new ActivityOutcomeHandler
+func NewActivityOutcomeHandler(db *gorm.DB) *ActivityOutcomeHandler {
+	return &ActivityOutcomeHandler{db: db}
+}
+
+// ActivityOutcomeCreateRequest represents the request body for adding an
+// activity outcome. Without a position the outcome goes last among the
+// outcomes of its type.
+type ActivityOutcomeCreateRequest struct {
+	Type     models.ActivityType `json:"type" binding:"required"`
+	Code     string              `json:"code" binding:"required,max=50"`
+	Label    string              `json:"label" binding:"required,max=255"`
+	Keywords []string            `json:"keywords,omitempty" binding:"max=50,dive,max=100"`
+	Position int                 `json:"position,omitempty" binding:"min=0"`
+}
+
+// ActivityOutcomeUpdateRequest represents the request body for amending an
+// activity outcome. The type and code cannot change, since activities
+// record their outcome by code.
+type ActivityOutcomeUpdateRequest struct {
+	Label    string    `json:"label" binding:"required,max=255"`
+	Keywords *[]string `json:"keywords,omitempty" binding:"omitempty,max=50,dive,max=100"` // Unchanged when omitted
+	Position int       `json:"position" binding:"required,min=1"`
+}
+
+// ListActivityOutcomes returns the activity outcomes by type, in order
+// GET /admin/activity-outcomes?type=
+func (h *ActivityOutcomeHandler) ListActivityOutcomes(c *gin.Context) {
+	query := h.db.WithContext(c).Order("type ASC, position ASC, id ASC")
+	if activityType := c.Query("type"); activityType != "" {
+		query = query.Where("type = ?", activityType)
+	}
+
+	var outcomes []models.ActivityOutcome
+	if err := query.Find(&outcomes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity outcomes"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ActivityOutcomeListResponse{Data: outcomes})
+}
+
+// CreateActivityOutcome adds an outcome to the taxonomy of an activity
+// type. Once a type has outcomes, activities of the type are completed
+// with one of their codes.
+// POST /admin/activity-outcomes
+func (h *ActivityOutcomeHandler) CreateActivityOutcome(c *gin.Context) {
+	var req ActivityOutcomeCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if !models.IsValidActivityType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ACTIVITY_TYPE",
+			"message": i18n.Message(c, "INVALID_ACTIVITY_TYPE", "Invalid activity type"),
+		})
+		return
+	}
+	if err := models.ValidateOutcomeCode(req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_OUTCOME_CODE",
+			"message": i18n.Message(c, "INVALID_OUTCOME_CODE", err.Error()),
+		})
+		return
+	}
+
+	var existing int64
+	h.db.WithContext(c).Model(&models.ActivityOutcome{}).Where("type = ? AND code = ?", req.Type, req.Code).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "OUTCOME_CODE_EXISTS",
+			"message": i18n.Message(c, "OUTCOME_CODE_EXISTS", "An outcome with this code already exists for the activity type"),
+		})
+		return
+	}
+
+	outcome := models.ActivityOutcome{
+		Type:     req.Type,
+		Code:     req.Code,
+		Label:    req.Label,
+		Keywords: models.NormalizeOutcomeKeywords(req.Keywords),
+		Position: req.Position,
+	}
+	if outcome.Position == 0 {
+		h.db.WithContext(c).Model(&models.ActivityOutcome{}).Where("type = ?", req.Type).Select("COALESCE(MAX(position), 0) + 1").Scan(&outcome.Position)
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&outcome).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity_outcome", outcome.ID, models.AuditActionCreate, nil, &outcome)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create activity outcome"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, outcome)
+}
+
+// UpdateActivityOutcome amends the label, keywords or position of an
+// activity outcome
+// PUT /admin/activity-outcomes/:id
+func (h *ActivityOutcomeHandler) UpdateActivityOutcome(c *gin.Context) {
+	outcome, ok := h.findOutcome(c)
+	if !ok {
+		return
+	}
+	oldOutcome := *outcome
+
+	var req ActivityOutcomeUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	outcome.Label = req.Label
+	outcome.Position = req.Position
+	if req.Keywords != nil {
+		outcome.Keywords = models.NormalizeOutcomeKeywords(*req.Keywords)
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(outcome).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity_outcome", outcome.ID, models.AuditActionUpdate, &oldOutcome, outcome)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update activity outcome"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, outcome)
+}
+
+// DeleteActivityOutcome removes an activity outcome. Activities keep its
+// code, and are still reported under it; an activity type left without
+// outcomes is completed without a code again.
+// DELETE /admin/activity-outcomes/:id
+func (h *ActivityOutcomeHandler) DeleteActivityOutcome(c *gin.Context) {
+	outcome, ok := h.findOutcome(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(outcome).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "activity_outcome", outcome.ID, models.AuditActionDelete, outcome, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete activity outcome"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Activity outcome deleted successfully",
+	})
+}
+
+// findOutcome loads the activity outcome identified by the :id route
+// parameter, writing the error response when it cannot be found
+func (h *ActivityOutcomeHandler) findOutcome(c *gin.Context) (*models.ActivityOutcome, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid activity outcome ID"),
+		})
+		return nil, false
+	}
+
+	var outcome models.ActivityOutcome
+	if err := h.db.WithContext(c).First(&outcome, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "OUTCOME_NOT_FOUND",
+				"message": i18n.Message(c, "OUTCOME_NOT_FOUND", "Activity outcome not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity outcome"),
+		})
+		return nil, false
+	}
+
+	return &outcome, true
+}
+
+// OutcomeClassificationRequest represents the request body for classifying
+// the free-text outcomes of completed activities
+type OutcomeClassificationRequest struct {
+	Apply bool `json:"apply"` // Review only unless set
+}
+
+// OutcomeClassification is the review report of classifying free-text
+// outcomes by the keywords of the outcome taxonomy. It covers completed
+// activities with a free-text outcome but no outcome code, of the types
+// that have outcomes; only classified activities get a code when applied.
+type OutcomeClassification struct {
+	Applied    bool                         `json:"applied"`
+	Classified int                          `json:"classified"`
+	Ambiguous  int                          `json:"ambiguous"`
+	Unmatched  int                          `json:"unmatched"`
+	Groups     []OutcomeClassificationGroup `json:"groups"`
+}
+
+// OutcomeClassificationGroup counts the activities of a type whose
+// free-text outcomes classify the same way
+type OutcomeClassificationGroup struct {
+	Type    models.ActivityType `json:"type"`
+	Result  string              `json:"result"` // classified, ambiguous or unmatched
+	Codes   []string            `json:"codes"`  // The code assigned, or the codes an ambiguous outcome matches
+	Count   int                 `json:"count"`
+	Samples []string            `json:"samples"` // Distinct outcomes of the group, for review
+
+	ids []uint // Activities to update, for classified groups
+}
+
+// ClassifyActivityOutcomes maps the free-text outcomes of completed
+// activities onto outcome codes by the keywords of the taxonomy. Without
+// apply it only returns the review report; with it, every classified
+// activity gets its code in one transaction, recorded as one audit entry
+// with the counts.
+// POST /admin/maintenance/activity-outcomes/classify
+func (h *ActivityOutcomeHandler) ClassifyActivityOutcomes(c *gin.Context) {
+	var req OutcomeClassificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	finish := func(string) {}
+	if req.Apply {
+		finish = annotateOperation(c, h.db, models.AnnotationTypeMaintenance, "Activity outcome classification")
+	}
+
+	var classification OutcomeClassification
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		taxonomy, err := loadOutcomeTaxonomy(tx)
+		if err != nil {
+			return err
+		}
+		classification, err = planOutcomeClassification(tx, taxonomy)
+		if err != nil || !req.Apply {
+			return err
+		}
+
+		for _, group := range classification.Groups {
+			for ids := range slices.Chunk(group.ids, outcomeClassificationBatchSize) {
+				if err := tx.Model(&models.Activity{}).
+					Where("id IN ? AND outcome_code = ''", ids).
+					UpdateColumn("outcome_code", group.Codes[0]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		classification.Applied = true
+
+		// Log audit
+		summary := classification
+		summary.Groups = nil
+		return logAudit(c, tx, "activity", 0, models.AuditActionClassify, nil, &summary)
+	})
+	if err != nil {
+		finish("Failed: no outcomes were classified")
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to classify activity outcomes"),
+		})
+		return
+	}
+	if classification.Applied {
+		finish("Classified " + strconv.Itoa(classification.Classified) + " activity outcomes")
+	}
+
+	c.JSON(http.StatusOK, classification)
+}
+
+// planOutcomeClassification classifies the free-text outcomes of completed
+// activities without an outcome code, grouped by type and result
+func planOutcomeClassification(tx *gorm.DB, taxonomy models.OutcomeTaxonomy) (OutcomeClassification, error) {
+	classification := OutcomeClassification{Groups: []OutcomeClassificationGroup{}}
+	types := make([]models.ActivityType, 0, len(taxonomy))
+	for activityType := range taxonomy {
+		types = append(types, activityType)
+	}
+	if len(types) == 0 {
+		return classification, nil
+	}
+
+	groups := make(map[string]*OutcomeClassificationGroup)
+	var activities []models.Activity
+	err := tx.Model(&models.Activity{}).
+		Select("id", "type", "outcome").
+		Where("status = ? AND outcome_code = '' AND type IN ? AND TRIM(COALESCE(outcome, '')) <> ''", models.ActivityStatusCompleted, types).
+		FindInBatches(&activities, outcomeClassificationBatchSize, func(*gorm.DB, int) error {
+			for _, activity := range activities {
+				codes := models.ClassifyOutcome(taxonomy[activity.Type], activity.Outcome)
+				result := OutcomeClassified
+				switch {
+				case len(codes) == 0:
+					result = OutcomeUnmatched
+					classification.Unmatched++
+				case len(codes) > 1:
+					result = OutcomeAmbiguous
+					classification.Ambiguous++
+				default:
+					classification.Classified++
+				}
+
+				key := string(activity.Type) + "/" + result + "/" + strings.Join(codes, ",")
+				group := groups[key]
+				if group == nil {
+					group = &OutcomeClassificationGroup{Type: activity.Type, Result: result, Codes: codes, Samples: []string{}}
+					if group.Codes == nil {
+						group.Codes = []string{}
+					}
+					groups[key] = group
+				}
+				group.Count++
+				outcome := strings.TrimSpace(activity.Outcome)
+				if len(group.Samples) < outcomeClassificationSamples && !slices.Contains(group.Samples, outcome) {
+					group.Samples = append(group.Samples, outcome)
+				}
+				if result == OutcomeClassified {
+					group.ids = append(group.ids, activity.ID)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return classification, err
+	}
+
+	// Largest groups first within each type and result
+	results := []string{OutcomeClassified, OutcomeAmbiguous, OutcomeUnmatched}
+	for _, group := range groups {
+		classification.Groups = append(classification.Groups, *group)
+	}
+	slices.SortFunc(classification.Groups, func(a, b OutcomeClassificationGroup) int {
+		switch {
+		case a.Type != b.Type:
+			return strings.Compare(string(a.Type), string(b.Type))
+		case a.Result != b.Result:
+			return slices.Index(results, a.Result) - slices.Index(results, b.Result)
+		case a.Count != b.Count:
+			return b.Count - a.Count
+		}
+		return strings.Compare(strings.Join(a.Codes, ","), strings.Join(b.Codes, ","))
+	})
+	return classification, nil
+}
+
+// loadOutcomeTaxonomy loads the outcomes of every activity type in order
+func loadOutcomeTaxonomy(db *gorm.DB) (models.OutcomeTaxonomy, error) {
+	var outcomes []models.ActivityOutcome
+	if err := db.Order("type ASC, position ASC, id ASC").Find(&outcomes).Error; err != nil {
+		return nil, err
+	}
+	return models.NewOutcomeTaxonomy(outcomes), nil
+}
+
+// outcomeCodeError returns the error code and message of an activity
+// whose outcome code does not fit the taxonomy of its type, or "" when it
+// fits. Completed activities of a type with outcomes need a code. old is
+// the activity before the change, nil for a new one; only a changed code
+// or a newly completed activity is checked, so amending the taxonomy
+// leaves activities already completed editable.
+func outcomeCodeError(taxonomy models.OutcomeTaxonomy, activity, old *models.Activity) (string, string) {
+	completed := activity.Status == models.ActivityStatusCompleted
+	if old != nil && activity.OutcomeCode == old.OutcomeCode && activity.Type == old.Type &&
+		(!completed || old.Status == models.ActivityStatusCompleted) {
+		return "", ""
+	}
+
+	codes := taxonomy.Codes(activity.Type)
+	switch {
+	case activity.OutcomeCode != "" && len(codes) == 0:
+		return "INVALID_OUTCOME_CODE", "Activities of type " + string(activity.Type) + " have no outcome codes"
+	case activity.OutcomeCode != "" && !slices.Contains(codes, activity.OutcomeCode):
+		return "INVALID_OUTCOME_CODE", "outcome_code must be one of " + strings.Join(codes, ", ")
+	case activity.OutcomeCode == "" && completed && len(codes) > 0:
+		return "OUTCOME_CODE_REQUIRED", "A completed " + string(activity.Type) + " needs an outcome_code: one of " + strings.Join(codes, ", ")
+	}
+	return "", ""
+}
+
+// checkActivityOutcome checks the outcome code of an activity against the
+// taxonomy of its type, writing a 422 response with the allowed codes
+// otherwise. old is the activity before the change, nil for a new one.
+func (h *ActivityHandler) checkActivityOutcome(c *gin.Context, activity, old *models.Activity) bool {
+	taxonomy, err := loadOutcomeTaxonomy(h.db.WithContext(c).Where("type = ?", activity.Type))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity outcomes"),
+		})
+		return false
+	}
+
+	code, message := outcomeCodeError(taxonomy, activity, old)
+	if code == "" {
+		return true
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":   "validation_error",
+		"code":    code,
+		"message": i18n.Message(c, code, message),
+		"allowed": taxonomy.Codes(activity.Type),
+	})
+	return false
+}
@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// maxActivityStreamLine is the longest NDJSON line an activity stream
+// accepts
+const maxActivityStreamLine = 1 << 20
+
+// activityStreamWriteGrace is how long past its read deadline an activity
+// stream may still write the results of the records it read
+const activityStreamWriteGrace = 30 * time.Second
+
+// ActivityStreamLimits bounds the activity stream of one connection
+type ActivityStreamLimits struct {
+	BatchSize   int           // Records validated and inserted together
+	MaxBytes    int64         // Largest request body
+	MaxDuration time.Duration // Longest the body may take to arrive
+}
+
+// ActivityStreamHandler ingests activities streamed as NDJSON
+type ActivityStreamHandler struct {
+	db     *gorm.DB
+	quotas *quota.Tracker
+	limits ActivityStreamLimits
+}
+
+// NewActivityStreamHandler creates a new ActivityStreamHandler
+func NewActivityStreamHandler(db *gorm.DB, quotas *quota.Tracker, limits ActivityStreamLimits) *ActivityStreamHandler {
+	return &ActivityStreamHandler{db: db, quotas: quotas, limits: limits}
+}
+
+// ActivityStreamRecord is one line of an activity stream: the fields of
+// CreateActivity, plus the timestamps of an activity logged after the fact
+type ActivityStreamRecord struct {
+	ActivityCreateRequest
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"` // When the activity was logged; defaults to now
+}
+
+// ActivityStreamSummary is the last line of an activity stream response.
+// Code and Message say why the stream stopped before the end of the body;
+// records after the last reported line were not read.
+type ActivityStreamSummary struct {
+	TotalRows int    `json:"total_rows"`
+	Created   int    `json:"created"`
+	Failed    int    `json:"failed"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// activityStreamEntry is a record read from an activity stream and its
+// result
+type activityStreamEntry struct {
+	activity *models.Activity // nil once the record has failed
+	result   ImportRowResult
+}
+
+// activityStream is the state of one activity stream request
+type activityStream struct {
+	encoder *json.Encoder
+	pending []activityStreamEntry // Records read since the last batch
+	valid   int                   // Pending records that passed validation
+	summary ActivityStreamSummary
+}
+
+// StreamActivities creates activities from newline-delimited JSON, one
+// ActivityStreamRecord per line, for integrations logging history in bulk.
+// Records are validated as they are read and inserted every BatchSize
+// valid records, with the customers, deals and contacts of a batch looked
+// up together. The body is only read further once a batch is written, so
+// a client sends at the pace of the database. The response streams one
+// ImportRowResult per record, with the line number as row, as each batch
+// is written, so a client can retry exactly the failed lines, and ends
+// with an ActivityStreamSummary line. A stream stops early when the
+// activity quota runs out or the body exceeds the size or time limits of
+// a connection. Each written batch gets one import audit entry with its
+// summary instead of an entry per activity.
+// POST /admin/activities/stream
+func (h *ActivityStreamHandler) StreamActivities(c *gin.Context) {
+	taxonomy, err := loadOutcomeTaxonomy(h.db.WithContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity outcomes"),
+		})
+		return
+	}
+
+	// The stream's own deadline replaces the server read and write timeouts
+	deadline := time.Now().Add(h.limits.MaxDuration)
+	controller := http.NewResponseController(c.Writer)
+	if err := controller.EnableFullDuplex(); err != nil {
+		middleware.Logger.Debug("Full duplex not supported: " + err.Error())
+	}
+	if err := controller.SetReadDeadline(deadline); err != nil {
+		middleware.Logger.Debug("Read deadline not supported: " + err.Error())
+	}
+	if err := controller.SetWriteDeadline(deadline.Add(activityStreamWriteGrace)); err != nil {
+		middleware.Logger.Debug("Write deadline not supported: " + err.Error())
+	}
+
+	body := &streamBody{reader: http.MaxBytesReader(c.Writer, c.Request.Body, h.limits.MaxBytes)}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxActivityStreamLine)
+	scanner.Split(body.scanLines)
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	finish := annotateOperation(c, h.db, models.AnnotationTypeImport, "Activity stream")
+	stream := &activityStream{encoder: json.NewEncoder(c.Writer)}
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		stream.summary.TotalRows++
+		entry := activityStreamEntry{result: ImportRowResult{Row: line}}
+		activity, errs := parseActivityStreamRecord(c, raw, time.Now(), taxonomy)
+		if len(errs) > 0 {
+			entry.result.Status = ImportRowFailed
+			entry.result.Errors = errs
+		} else {
+			entry.activity = activity
+			stream.valid++
+		}
+		stream.pending = append(stream.pending, entry)
+
+		if stream.valid >= h.limits.BatchSize && !h.writeBatch(c, stream) {
+			break
+		}
+	}
+	// Records read before the body was cut off are still written
+	if stream.summary.Code == "" {
+		h.writeBatch(c, stream)
+	}
+	if err := scanner.Err(); err != nil && stream.summary.Code == "" {
+		code, message := h.readFailure(c, err)
+		stream.stop(c, code, message)
+	}
+
+	outcome := "Created " + strconv.Itoa(stream.summary.Created) + " activities, " + strconv.Itoa(stream.summary.Failed) + " records failed"
+	if stream.summary.Code != "" {
+		outcome += ", stopped with " + stream.summary.Code
+	}
+	finish(outcome)
+	stream.encoder.Encode(gin.H{"summary": stream.summary})
+	c.Writer.Flush()
+}
+
+// streamBody is the body of an activity stream, remembering why reading
+// it failed
+type streamBody struct {
+	reader io.Reader
+	err    error // Read error other than io.EOF
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+	return n, err
+}
+
+// scanLines splits the body into lines like bufio.ScanLines, except that
+// a last line cut off by a read error, such as the size limit, is not
+// returned as a record
+func (b *streamBody) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && b.err != nil && bytes.IndexByte(data, '\n') < 0 {
+		return 0, nil, b.err
+	}
+	return bufio.ScanLines(data, atEOF)
+}
+
+// parseActivityStreamRecord validates a line of an activity stream as
+// CreateActivity would, returning the activity to create or the record's
+// errors
+func parseActivityStreamRecord(c *gin.Context, raw []byte, now time.Time, taxonomy models.OutcomeTaxonomy) (*models.Activity, []string) {
+	var record ActivityStreamRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, []string{"invalid JSON: " + err.Error()}
+	}
+
+	var errs []string
+	if err := binding.Validator.ValidateStruct(&record); err != nil {
+		errs = append(errs, i18n.ValidationMessage(c, err))
+	}
+	if record.CustomerID == nil && record.DealID == nil {
+		errs = append(errs, "Activity must be linked to a customer or deal")
+	}
+	if record.Type != "" && !models.IsValidActivityType(record.Type) {
+		errs = append(errs, "Invalid activity type")
+	}
+	status := record.Status
+	if status == "" {
+		status = models.ActivityStatusScheduled
+	}
+	if !models.IsValidActivityStatus(status) {
+		errs = append(errs, "Invalid activity status")
+	}
+	priority := record.Priority
+	if priority == "" {
+		priority = "normal"
+	}
+	if !slices.Contains(models.ValidActivityPriorities, priority) {
+		errs = append(errs, "priority must be one of "+strings.Join(models.ValidActivityPriorities, ", "))
+	}
+	createdAt := now
+	if record.CreatedAt != nil {
+		if record.CreatedAt.After(now) {
+			errs = append(errs, "created_at cannot be in the future")
+		}
+		createdAt = *record.CreatedAt
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	activity := &models.Activity{
+		Title:       record.Title,
+		Description: record.Description,
+		Type:        record.Type,
+		Status:      status,
+		CustomerID:  record.CustomerID,
+		DealID:      record.DealID,
+		ContactID:   record.ContactID,
+		AssignedTo:  record.AssignedTo,
+		DueDate:     record.DueDate,
+		CompletedAt: record.CompletedAt,
+		Duration:    record.Duration,
+		Outcome:     record.Outcome,
+		OutcomeCode: record.OutcomeCode,
+		Priority:    priority,
+		Template:    record.Template,
+	}
+	activity.CreatedAt = createdAt
+	activity.UpdatedAt = createdAt
+	activity.SyncCompletedAt(models.Activity{}, createdAt)
+
+	if missing := models.ActivityRequirements.MissingFields(*activity); len(missing) > 0 &&
+		(models.ActivityPolicyStrict || !activity.PredatesActivityPolicy()) {
+		errs = append(errs, "Missing fields required for a "+string(activity.Status)+" "+string(activity.Type)+": "+strings.Join(missing, ", "))
+	}
+	if code, message := outcomeCodeError(taxonomy, activity, nil); code != "" {
+		errs = append(errs, message)
+	}
+	errs = append(errs, longTextErrors(activity)...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return activity, nil
+}
+
+// writeBatch inserts the valid pending records of a stream and writes the
+// results of every pending record. It returns false when the stream
+// stops: the quota ran out, the request was cancelled or its response
+// cannot be written.
+func (h *ActivityStreamHandler) writeBatch(c *gin.Context, stream *activityStream) bool {
+	pending := stream.pending
+	stream.pending, stream.valid = nil, 0
+	if len(pending) == 0 {
+		return true
+	}
+
+	proceed := true
+	if err := h.checkLinks(c, pending); err != nil {
+		failEntries(pending, "the links of this record could not be checked")
+		if c.Request.Context().Err() != nil {
+			proceed = stream.stop(c, "STREAM_CANCELLED", "The stream was cancelled")
+		} else {
+			proceed = stream.stop(c, "DATABASE_ERROR", "Failed to fetch the customers, deals and contacts of activities")
+		}
+	}
+
+	var activities []*models.Activity
+	var written []*activityStreamEntry
+	for i := range pending {
+		if entry := &pending[i]; entry.activity != nil {
+			activities = append(activities, entry.activity)
+			written = append(written, entry)
+		}
+	}
+
+	// Only the activities that fit within the quota are created
+	var exceeded *quota.ExceededError
+	if h.quotas != nil && !sandbox.Active(c) && len(activities) > 0 {
+		if err := h.quotas.Check(quota.Activities, int64(len(activities))); errors.As(err, &exceeded) {
+			fit := max(int(exceeded.Limit-exceeded.Usage), 0)
+			for _, entry := range written[fit:] {
+				entry.activity = nil
+				entry.result.Status = ImportRowFailed
+				entry.result.Errors = []string{fmt.Sprintf("activity quota exceeded: %d of %d used", exceeded.Usage, exceeded.Limit)}
+			}
+			activities, written = activities[:fit], written[:fit]
+			proceed = stream.stop(c, "QUOTA_EXCEEDED", "Record quota exceeded")
+		}
+	}
+
+	if len(activities) > 0 {
+		err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			if err := tx.CreateInBatches(&activities, importBatchSize).Error; err != nil {
+				return err
+			}
+
+			// Log audit
+			return h.auditStream(c, tx, ActivityStreamSummary{
+				TotalRows: len(pending),
+				Created:   len(activities),
+				Failed:    len(pending) - len(activities),
+			})
+		})
+		if err != nil {
+			for _, entry := range written {
+				entry.activity = nil
+				entry.result.Status = ImportRowFailed
+				entry.result.Errors = []string{"the batch of lines " + strconv.Itoa(written[0].result.Row) + " to " + strconv.Itoa(written[len(written)-1].result.Row) + " could not be written"}
+			}
+			switch {
+			case errors.Is(err, audittrail.ErrWriteFailed):
+				proceed = stream.stop(c, "AUDIT_WRITE_FAILED", "The change was not saved because its audit entry could not be written")
+			case c.Request.Context().Err() != nil:
+				proceed = stream.stop(c, "STREAM_CANCELLED", "The stream was cancelled")
+			}
+		}
+	}
+
+	for i := range pending {
+		entry := &pending[i]
+		if entry.activity != nil {
+			entry.result.Status = ImportRowCreated
+			entry.result.ID = entry.activity.ID
+			stream.summary.Created++
+		} else {
+			stream.summary.Failed++
+		}
+		if err := stream.encoder.Encode(entry.result); err != nil {
+			proceed = false
+		}
+	}
+	c.Writer.Flush()
+	return proceed
+}
+
+// checkLinks fails the pending records whose customer, deal or contact does
+// not exist, looking each table up once for the whole batch
+func (h *ActivityStreamHandler) checkLinks(c *gin.Context, pending []activityStreamEntry) error {
+	links := []struct {
+		name  string
+		model interface{}
+		id    func(*models.Activity) *uint
+	}{
+		{"customer", &models.Customer{}, func(a *models.Activity) *uint { return a.CustomerID }},
+		{"deal", &models.Deal{}, func(a *models.Activity) *uint { return a.DealID }},
+		{"contact", &models.Contact{}, func(a *models.Activity) *uint { return a.ContactID }},
+	}
+	for _, link := range links {
+		var ids []uint
+		for _, entry := range pending {
+			if entry.activity != nil && link.id(entry.activity) != nil {
+				ids = append(ids, *link.id(entry.activity))
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		var found []uint
+		if err := h.db.WithContext(c).Model(link.model).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+			return err
+		}
+		for i := range pending {
+			entry := &pending[i]
+			if entry.activity == nil || link.id(entry.activity) == nil || slices.Contains(found, *link.id(entry.activity)) {
+				continue
+			}
+			id := *link.id(entry.activity)
+			entry.activity = nil
+			entry.result.Status = ImportRowFailed
+			entry.result.Errors = []string{"no " + link.name + " with ID " + strconv.FormatUint(uint64(id), 10)}
+		}
+	}
+	return nil
+}
+
+// failEntries fails every pending record still valid with the same error
+func failEntries(pending []activityStreamEntry, message string) {
+	for i := range pending {
+		if pending[i].activity != nil {
+			pending[i].activity = nil
+			pending[i].result.Status = ImportRowFailed
+			pending[i].result.Errors = []string{message}
+		}
+	}
+}
+
+// stop records on the summary why the stream stops and returns false
+func (s *activityStream) stop(c *gin.Context, code, message string) bool {
+	s.summary.Code = code
+	s.summary.Message = i18n.Message(c, code, message)
+	return false
+}
+
+// readFailure returns the code and message of an error reading the body
+// of a stream
+func (h *ActivityStreamHandler) readFailure(c *gin.Context, err error) (string, string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return "STREAM_TOO_LARGE", "The stream exceeds " + strconv.FormatInt(tooLarge.Limit, 10) + " bytes"
+	case errors.Is(err, bufio.ErrTooLong):
+		return "LINE_TOO_LONG", "A line exceeds " + strconv.Itoa(maxActivityStreamLine) + " bytes"
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return "STREAM_TIMEOUT", "The stream exceeded its time limit of " + h.limits.MaxDuration.String()
+	case c.Request.Context().Err() != nil:
+		return "STREAM_CANCELLED", "The stream was cancelled"
+	default:
+		return "STREAM_READ_FAILED", "Failed to read the stream: " + err.Error()
+	}
+}
+
+// auditStream writes the import audit entry summarizing a batch of a
+// stream in the transaction creating it
+func (h *ActivityStreamHandler) auditStream(c *gin.Context, tx *gorm.DB, summary ActivityStreamSummary) error {
+	user, _ := middleware.GetUserFromContext(c)
+
+	audit := models.AuditLog{
+		ResourceType: "activity",
+		Action:       models.AuditActionImport,
+		UserID:       user.ID,
+		UserName:     user.Name,
+		UserRole:     user.Role,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+	}
+	audit.OldValues, audit.NewValues = models.AuditDiff(nil, &summary)
+	return audittrail.Record(c, tx, &audit)
+}
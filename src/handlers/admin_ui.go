@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/adminui"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by AdminUIHandler.RequireSession
+const (
+	adminUITokenKey = "admin_ui_token"
+	adminUIUserKey  = "admin_ui_user"
+)
+
+// adminUIRelatedPageSize is the number of related rows shown on detail pages
+const adminUIRelatedPageSize = "10"
+
+// AdminUIHandler serves the read-only debugging UI under /admin/ui. Every
+// page and action calls the admin API in-process with the viewer's token.
+type AdminUIHandler struct {
+	api           *adminui.Client
+	secureCookies bool
+}
+
+// NewAdminUIHandler creates a new AdminUIHandler calling the admin API
+// served by api
+func NewAdminUIHandler(api http.Handler, secureCookies bool) *AdminUIHandler {
+	return &AdminUIHandler{api: adminui.NewClient(api), secureCookies: secureCookies}
+}
+
+// adminUIList describes a list backed by an admin API list endpoint
+type adminUIList struct {
+	title   string
+	path    string // UI list page, empty when there is none
+	api     string
+	filters []string
+	columns []string
+	link    string // UI detail path prefix
+	action  *adminui.RowAction
+}
+
+var (
+	adminUICustomers = adminUIList{
+		title:   "Customers",
+		path:    "/admin/ui/customers",
+		api:     "/admin/customers",
+		filters: []string{"search", "status", "assigned_to", "domain", "tags", "include_archived"},
+		columns: []string{"id", "name", "email", "company", "status", "assigned_to", "created_at"},
+		link:    "/admin/ui/customers/",
+	}
+	adminUIDeals = adminUIList{
+		title:   "Deals",
+		path:    "/admin/ui/deals",
+		api:     "/admin/deals",
+		filters: []string{"search", "stage", "owner_id", "customer_id", "external_id", "include_archived"},
+		columns: []string{"id", "title", "customer_id", "stage", "amount", "currency", "owner_id", "expected_close_date"},
+		link:    "/admin/ui/deals/",
+	}
+	adminUIActivities = adminUIList{
+		title:   "Activities",
+		path:    "/admin/ui/activities",
+		api:     "/admin/activities",
+		filters: []string{"search", "type", "status", "assigned_to", "customer_id", "deal_id"},
+		columns: []string{"id", "title", "type", "effective_status", "customer_id", "deal_id", "assigned_to", "due_date"},
+		link:    "/admin/ui/activities/",
+	}
+	adminUIAuditLogs = adminUIList{
+		title:   "Audit logs",
+		path:    "/admin/ui/audit-logs",
+		api:     "/admin/audit-logs",
+		filters: []string{"resource_type", "resource_id", "action", "user_id", "from", "to"},
+		columns: []string{"id", "created_at", "resource_type", "resource_id", "action", "user_name", "new_values"},
+	}
+	adminUIJobs = adminUIList{
+		title:   "Jobs",
+		path:    "/admin/ui/jobs",
+		api:     "/admin/jobs",
+		filters: []string{"type", "status"},
+		columns: []string{"id", "type", "status", "created_by_name", "artifact.rows", "created_at", "finished_at", "error"},
+		link:    "/admin/ui/jobs/",
+	}
+	adminUIDeadLetters = adminUIList{
+		title:   "Dead letters",
+		path:    "/admin/ui/dead-letters",
+		api:     "/admin/dead-letters",
+		filters: []string{"component", "status"},
+		columns: []string{"id", "component", "status", "attempts", "error", "last_failed_at"},
+		action:  &adminui.RowAction{Label: "Retry", Path: "/admin/ui/dead-letters/", Suffix: "/retry"},
+	}
+	adminUIConsistencyFindings = adminUIList{
+		title:   "Open consistency findings",
+		api:     "/admin/maintenance/consistency",
+		columns: []string{"id", "check_name", "severity", "resource_type", "resource_id", "message", "last_seen_at"},
+	}
+)
+
+// RequireSession loads the viewer from the session cookie and redirects to
+// the sign-in page when there is none or its token is no longer accepted
+func (h *AdminUIHandler) RequireSession(c *gin.Context) {
+	token, err := c.Cookie(adminui.SessionCookie)
+	if err != nil || token == "" {
+		c.Redirect(http.StatusSeeOther, "/admin/ui/login")
+		c.Abort()
+		return
+	}
+
+	// The cookie is SameSite=Strict; also refuse cross-origin posts from
+	// browsers that ignore it
+	if c.Request.Method == http.MethodPost && !sameOrigin(c.Request) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	var me models.MeResponse
+	if err := h.api.Do(c.Request, token, http.MethodGet, "/admin/me", nil, &me); err != nil {
+		h.clearSession(c)
+		c.Redirect(http.StatusSeeOther, "/admin/ui/login?error="+url.QueryEscape(err.Error()))
+		c.Abort()
+		return
+	}
+
+	c.Set(adminUITokenKey, token)
+	c.Set(adminUIUserKey, me.User.Name+" ("+me.User.Role+")")
+	c.Next()
+}
+
+// Login shows the sign-in form
+// GET /admin/ui/login
+func (h *AdminUIHandler) Login(c *gin.Context) {
+	h.render(c, http.StatusOK, "login", adminui.Page{Title: "Sign in", Error: c.Query("error")})
+}
+
+// CreateSession exchanges an API token for a session cookie
+// POST /admin/ui/session
+func (h *AdminUIHandler) CreateSession(c *gin.Context) {
+	token := strings.TrimSpace(c.PostForm("token"))
+	if parts := strings.SplitN(token, " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+		token = strings.TrimSpace(parts[1])
+	}
+	if token == "" {
+		h.render(c, http.StatusBadRequest, "login", adminui.Page{Title: "Sign in", Error: "A token is required"})
+		return
+	}
+
+	if err := h.api.Do(c.Request, token, http.MethodGet, "/admin/me", nil, nil); err != nil {
+		h.render(c, http.StatusUnauthorized, "login", adminui.Page{Title: "Sign in", Error: err.Error()})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(adminui.SessionCookie, token, 0, "/admin/ui", "", h.secureCookies, true)
+	c.Redirect(http.StatusSeeOther, "/admin/ui")
+}
+
+// Logout clears the session cookie
+// POST /admin/ui/logout
+func (h *AdminUIHandler) Logout(c *gin.Context) {
+	h.clearSession(c)
+	c.Redirect(http.StatusSeeOther, "/admin/ui/login")
+}
+
+// Home shows the signed-in user and a customer search
+// GET /admin/ui
+func (h *AdminUIHandler) Home(c *gin.Context) {
+	page := h.page(c, "Home")
+
+	var me map[string]interface{}
+	if err := h.api.Do(c.Request, h.token(c), http.MethodGet, "/admin/me", nil, &me); err != nil {
+		page.Error = err.Error()
+	} else if user, ok := me["user"].(map[string]interface{}); ok {
+		user["permissions"] = me["permissions"]
+		page.Record = adminui.Fields(user)
+	}
+
+	page.FilterPath = adminUICustomers.path
+	page.Filters = []adminui.Filter{{Name: "search"}}
+	h.render(c, http.StatusOK, "page", page)
+}
+
+// ListCustomers lists customers
+// GET /admin/ui/customers
+func (h *AdminUIHandler) ListCustomers(c *gin.Context) {
+	h.list(c, adminUICustomers)
+}
+
+// ListDeals lists deals
+// GET /admin/ui/deals
+func (h *AdminUIHandler) ListDeals(c *gin.Context) {
+	h.list(c, adminUIDeals)
+}
+
+// ListActivities lists activities
+// GET /admin/ui/activities
+func (h *AdminUIHandler) ListActivities(c *gin.Context) {
+	h.list(c, adminUIActivities)
+}
+
+// ListAuditLogs lists audit log entries
+// GET /admin/ui/audit-logs
+func (h *AdminUIHandler) ListAuditLogs(c *gin.Context) {
+	h.list(c, adminUIAuditLogs)
+}
+
+// ListJobs lists jobs
+// GET /admin/ui/jobs
+func (h *AdminUIHandler) ListJobs(c *gin.Context) {
+	h.list(c, adminUIJobs)
+}
+
+// ListDeadLetters lists dead letters with a retry button
+// GET /admin/ui/dead-letters
+func (h *AdminUIHandler) ListDeadLetters(c *gin.Context) {
+	h.list(c, adminUIDeadLetters)
+}
+
+// list renders a list page, passing the request's filters and pagination
+// through to the admin API
+func (h *AdminUIHandler) list(c *gin.Context, list adminUIList) {
+	page := h.page(c, list.title)
+
+	values := c.Request.URL.Query()
+	values.Del("notice")
+	values.Del("error")
+
+	page.FilterPath = list.path
+	for _, name := range list.filters {
+		page.Filters = append(page.Filters, adminui.Filter{Name: name, Value: values.Get(name)})
+	}
+
+	table := h.table(c, list, values)
+	table.Title = ""
+	if table.Page > 1 {
+		table.PrevURL = pageURL(list.path, values, table.Page-1)
+	}
+	if table.Page < table.TotalPages {
+		table.NextURL = pageURL(list.path, values, table.Page+1)
+	}
+	page.Tables = []adminui.Table{table}
+
+	h.render(c, http.StatusOK, "page", page)
+}
+
+// GetCustomer shows a customer with its contacts, deals, activities and
+// audit trail
+// GET /admin/ui/customers/:id
+func (h *AdminUIHandler) GetCustomer(c *gin.Context) {
+	id, ok := h.recordPage(c, "Customer")
+	if !ok {
+		return
+	}
+	contacts := adminUIList{
+		title:   "Contacts",
+		api:     "/admin/customers/" + id + "/contacts",
+		columns: []string{"id", "first_name", "last_name", "email", "phone", "position", "is_primary"},
+	}
+	h.renderRecord(c, "Customer", "/admin/customers/"+id,
+		h.related(c, contacts, nil),
+		h.related(c, adminUIDeals, url.Values{"customer_id": {id}, "include_archived": {"true"}}),
+		h.related(c, adminUIActivities, url.Values{"customer_id": {id}}),
+		h.related(c, adminUIAuditLogs, url.Values{"resource_type": {"customer"}, "resource_id": {id}}),
+	)
+}
+
+// GetDeal shows a deal with its activities and audit trail
+// GET /admin/ui/deals/:id
+func (h *AdminUIHandler) GetDeal(c *gin.Context) {
+	id, ok := h.recordPage(c, "Deal")
+	if !ok {
+		return
+	}
+	h.renderRecord(c, "Deal", "/admin/deals/"+id,
+		h.related(c, adminUIActivities, url.Values{"deal_id": {id}}),
+		h.related(c, adminUIAuditLogs, url.Values{"resource_type": {"deal"}, "resource_id": {id}}),
+	)
+}
+
+// GetActivity shows an activity with its audit trail
+// GET /admin/ui/activities/:id
+func (h *AdminUIHandler) GetActivity(c *gin.Context) {
+	id, ok := h.recordPage(c, "Activity")
+	if !ok {
+		return
+	}
+	h.renderRecord(c, "Activity", "/admin/activities/"+id,
+		h.related(c, adminUIAuditLogs, url.Values{"resource_type": {"activity"}, "resource_id": {id}}),
+	)
+}
+
+// GetJob shows a job and its artifact
+// GET /admin/ui/jobs/:id
+func (h *AdminUIHandler) GetJob(c *gin.Context) {
+	id, ok := h.recordPage(c, "Job")
+	if !ok {
+		return
+	}
+	h.renderRecord(c, "Job", "/admin/jobs/"+id)
+}
+
+// Maintenance shows consistency findings and slow queries with buttons for
+// the on-demand maintenance tasks
+// GET /admin/ui/maintenance
+func (h *AdminUIHandler) Maintenance(c *gin.Context) {
+	page := h.page(c, "Maintenance")
+	page.Actions = []adminui.Action{
+		{Label: "Run consistency checks", Path: "/admin/ui/maintenance/consistency/run"},
+		{Label: "Backfill email domains", Path: "/admin/ui/maintenance/email-domains/backfill", Confirm: "Recompute the email domain of every customer?"},
+	}
+
+	findings := h.related(c, adminUIConsistencyFindings, nil)
+	findings.MoreURL = ""
+
+	slowQueries := adminui.Table{
+		Title:   "Slow queries",
+		Columns: []string{"fingerprint", "count", "max_ms", "p95_ms", "last_seen_at"},
+	}
+	var report struct {
+		Summary []map[string]interface{} `json:"summary"`
+	}
+	if err := h.api.Do(c.Request, h.token(c), http.MethodGet, "/admin/maintenance/slow-queries", nil, &report); err != nil {
+		slowQueries.Error = err.Error()
+	} else {
+		slowQueries.Rows = report.Summary
+	}
+
+	page.Tables = []adminui.Table{findings, slowQueries}
+	h.render(c, http.StatusOK, "page", page)
+}
+
+// RetryDeadLetter requeues a dead letter
+// POST /admin/ui/dead-letters/:id/retry
+func (h *AdminUIHandler) RetryDeadLetter(c *gin.Context) {
+	id, ok := h.idParam(c)
+	if !ok {
+		return
+	}
+	h.action(c, "/admin/dead-letters/"+id+"/retry", adminUIDeadLetters.path, "Dead letter "+id+" requeued")
+}
+
+// RunConsistencyChecks runs the consistency checks now
+// POST /admin/ui/maintenance/consistency/run
+func (h *AdminUIHandler) RunConsistencyChecks(c *gin.Context) {
+	h.action(c, "/admin/maintenance/consistency/run", "/admin/ui/maintenance", "Consistency checks finished")
+}
+
+// BackfillEmailDomains recomputes customer email domains
+// POST /admin/ui/maintenance/email-domains/backfill
+func (h *AdminUIHandler) BackfillEmailDomains(c *gin.Context) {
+	var result EmailDomainBackfillResponse
+	if err := h.api.Do(c.Request, h.token(c), http.MethodPost, "/admin/maintenance/email-domains/backfill", nil, &result); err != nil {
+		redirectWith(c, "/admin/ui/maintenance", "error", err.Error())
+		return
+	}
+	redirectWith(c, "/admin/ui/maintenance", "notice", "Email domains updated on "+strconv.FormatInt(result.Updated, 10)+" customers")
+}
+
+// action posts to the admin API and redirects back with the outcome
+func (h *AdminUIHandler) action(c *gin.Context, api, back, notice string) {
+	if err := h.api.Do(c.Request, h.token(c), http.MethodPost, api, nil, nil); err != nil {
+		redirectWith(c, back, "error", err.Error())
+		return
+	}
+	redirectWith(c, back, "notice", notice)
+}
+
+// recordPage validates the :id parameter of a detail page, rendering an
+// error page when it is invalid
+func (h *AdminUIHandler) recordPage(c *gin.Context, title string) (string, bool) {
+	id, ok := h.idParam(c)
+	if !ok {
+		page := h.page(c, title)
+		page.Error = "Invalid ID"
+		h.render(c, http.StatusBadRequest, "page", page)
+	}
+	return id, ok
+}
+
+// renderRecord renders the record served at api followed by the related
+// tables
+func (h *AdminUIHandler) renderRecord(c *gin.Context, title, api string, tables ...adminui.Table) {
+	page := h.page(c, title+" "+c.Param("id"))
+
+	var record map[string]interface{}
+	if err := h.api.Do(c.Request, h.token(c), http.MethodGet, api, nil, &record); err != nil {
+		page.Error = err.Error()
+		status := http.StatusBadGateway
+		if apiErr, ok := err.(*adminui.APIError); ok {
+			status = apiErr.Status
+		}
+		h.render(c, status, "page", page)
+		return
+	}
+
+	page.Record = adminui.Fields(record)
+	page.Tables = tables
+	h.render(c, http.StatusOK, "page", page)
+}
+
+// related loads the first rows of a list for a detail page, linking to the
+// full list when it has a page
+func (h *AdminUIHandler) related(c *gin.Context, list adminUIList, values url.Values) adminui.Table {
+	if values == nil {
+		values = url.Values{}
+	}
+	query := url.Values{}
+	for name, value := range values {
+		query[name] = value
+	}
+	query.Set("page_size", adminUIRelatedPageSize)
+
+	table := h.table(c, list, query)
+	if list.path != "" && table.TotalPages > 1 {
+		table.MoreURL = list.path + "?" + values.Encode()
+	}
+	return table
+}
+
+// table loads one page of a list from the admin API
+func (h *AdminUIHandler) table(c *gin.Context, list adminUIList, values url.Values) adminui.Table {
+	table := adminui.Table{
+		Title:     list.title,
+		Columns:   list.columns,
+		Link:      list.link,
+		RowAction: list.action,
+	}
+
+	var response adminui.ListResponse
+	if err := h.api.Do(c.Request, h.token(c), http.MethodGet, list.api, values, &response); err != nil {
+		table.Error = err.Error()
+		return table
+	}
+	table.Rows = response.Data
+	table.Total = response.Total
+	table.Page = response.Page
+	table.TotalPages = response.TotalPages
+	return table
+}
+
+// page starts a page for the signed-in viewer, with the outcome of a
+// redirected action
+func (h *AdminUIHandler) page(c *gin.Context, title string) adminui.Page {
+	return adminui.Page{
+		Title:  title,
+		User:   c.GetString(adminUIUserKey),
+		Notice: c.Query("notice"),
+		Error:  c.Query("error"),
+	}
+}
+
+// render writes an HTML page
+func (h *AdminUIHandler) render(c *gin.Context, status int, name string, page adminui.Page) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	if err := adminui.Render(c.Writer, name, page); err != nil {
+		middleware.Logger.Error("Admin UI page " + name + " failed to render: " + err.Error())
+	}
+}
+
+// token returns the viewer's API token
+func (h *AdminUIHandler) token(c *gin.Context) string {
+	return c.GetString(adminUITokenKey)
+}
+
+// idParam validates the :id path parameter
+func (h *AdminUIHandler) idParam(c *gin.Context) (string, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatUint(id, 10), true
+}
+
+// clearSession removes the session cookie
+func (h *AdminUIHandler) clearSession(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(adminui.SessionCookie, "", -1, "/admin/ui", "", h.secureCookies, true)
+}
+
+// redirectWith redirects to path with a notice or error message
+func redirectWith(c *gin.Context, path, kind, message string) {
+	c.Redirect(http.StatusSeeOther, path+"?"+url.Values{kind: {message}}.Encode())
+}
+
+// pageURL links to another page of a list
+func pageURL(path string, values url.Values, page int) string {
+	query := url.Values{}
+	for name, value := range values {
+		query[name] = value
+	}
+	query.Set("page", strconv.Itoa(page))
+	return path + "?" + query.Encode()
+}
+
+// sameOrigin reports whether a request's Origin header, when sent, matches
+// the host it was sent to
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errAnnotationClosed is returned inside an update transaction when the
+// annotation was closed between the read and the write
+var errAnnotationClosed = errors.New("annotation is closed")
+
+// AnnotationHandler handles operational annotation endpoints
+type AnnotationHandler struct {
+	db *gorm.DB
+}
+
+// NewAnnotationHandler creates a new AnnotationHandler
+func NewAnnotationHandler(db *gorm.DB) *AnnotationHandler {
+	return &AnnotationHandler{db: db}
+}
+
+// CreateAnnotationRequest represents the request body for creating an annotation
+type CreateAnnotationRequest struct {
+	Type        models.AnnotationType `json:"type" binding:"required"`
+	Title       string                `json:"title" binding:"required,min=1,max=255"`
+	Description string                `json:"description,omitempty"`
+	StartsAt    *time.Time            `json:"starts_at,omitempty"` // Defaults to now
+	EndsAt      *time.Time            `json:"ends_at,omitempty"`   // Leave unset to open the annotation
+}
+
+// UpdateAnnotationRequest represents the request body for updating an open
+// annotation. Setting ends_at closes it.
+type UpdateAnnotationRequest struct {
+	Title       *string    `json:"title,omitempty" binding:"omitempty,min=1,max=255"`
+	Description *string    `json:"description,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+}
+
+// annotationListQuery defines the filters and sorting of ListAnnotations.
+// from and to select the annotations overlapping the window; open ones
+// overlap every window after their start.
+var annotationListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("type", "type"),
+		{Param: "from", Kind: query.KindTime, Where: "(ends_at IS NULL OR ends_at >= ?)"},
+		query.AtMost("to", "starts_at", query.KindTime),
+		query.Condition("open", "ends_at IS NULL"),
+	},
+	Sort: query.Sort{Fixed: "starts_at DESC, id DESC"},
+}
+
+// ListAnnotations returns annotations, latest first, for dashboard overlays
+// GET /admin/annotations?type=&from=&to=&open=
+func (h *AnnotationHandler) ListAnnotations(c *gin.Context) {
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db, _ := annotationListQuery.Apply(h.db.WithContext(c).Model(&models.Annotation{}), c.Request.URL.Query())
+
+	var total int64
+	db.Count(&total)
+
+	var annotations []models.Annotation
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&annotations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch annotations"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.AnnotationListResponse{
+		Data:       annotations,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
+
+// CreateAnnotation records an operational annotation. Without ends_at it
+// stays open until updated with one.
+// POST /admin/annotations
+func (h *AnnotationHandler) CreateAnnotation(c *gin.Context) {
+	var req CreateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if !models.IsValidAnnotationType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ANNOTATION_TYPE",
+			"message": i18n.Message(c, "INVALID_ANNOTATION_TYPE", "type must be one of: deploy, import, maintenance"),
+		})
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	annotation := models.Annotation{
+		Type:          req.Type,
+		Title:         req.Title,
+		Description:   req.Description,
+		StartsAt:      time.Now(),
+		EndsAt:        req.EndsAt,
+		CreatedBy:     annotationAuthor(user),
+		CreatedByName: user.Name,
+	}
+	if req.StartsAt != nil {
+		annotation.StartsAt = *req.StartsAt
+	}
+	if !checkAnnotationRange(c, annotation) {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&annotation).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "annotation", annotation.ID, models.AuditActionCreate, nil, &annotation)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create annotation"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, annotation)
+}
+
+// UpdateAnnotation changes an open annotation, closing it when ends_at is
+// set. Closed annotations cannot be changed.
+// PATCH /admin/annotations/:id
+func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid annotation ID"),
+		})
+		return
+	}
+
+	var annotation models.Annotation
+	if err := h.db.WithContext(c).First(&annotation, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "ANNOTATION_NOT_FOUND",
+				"message": i18n.Message(c, "ANNOTATION_NOT_FOUND", "Annotation not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch annotation"),
+		})
+		return
+	}
+	if !annotation.IsOpen() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "ANNOTATION_CLOSED",
+			"message": i18n.Message(c, "ANNOTATION_CLOSED", "Closed annotations cannot be changed"),
+		})
+		return
+	}
+	oldAnnotation := annotation
+
+	var req UpdateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if req.Title != nil {
+		annotation.Title = *req.Title
+	}
+	if req.Description != nil {
+		annotation.Description = *req.Description
+	}
+	annotation.EndsAt = req.EndsAt
+	if !checkAnnotationRange(c, annotation) {
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Guard against a concurrent close between the read and the write
+		result := tx.Model(&annotation).Where("ends_at IS NULL").
+			Select("title", "description", "ends_at").Updates(&annotation)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAnnotationClosed
+		}
+		return logAudit(c, tx, "annotation", annotation.ID, models.AuditActionUpdate, &oldAnnotation, &annotation)
+	})
+	if errors.Is(err, errAnnotationClosed) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "ANNOTATION_CLOSED",
+			"message": i18n.Message(c, "ANNOTATION_CLOSED", "Closed annotations cannot be changed"),
+		})
+		return
+	}
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update annotation"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, annotation)
+}
+
+// checkAnnotationRange responds with 400 when an annotation ends before it starts
+func checkAnnotationRange(c *gin.Context, annotation models.Annotation) bool {
+	if annotation.EndsAt != nil && annotation.EndsAt.Before(annotation.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_DATE_RANGE",
+			"message": i18n.Message(c, "INVALID_DATE_RANGE", "ends_at must be after starts_at"),
+		})
+		return false
+	}
+	return true
+}
+
+// annotateOperation opens an annotation for an import or maintenance run
+// on behalf of the request's user and returns a function closing it with
+// the run's outcome. Annotation failures are logged and never fail the run.
+func annotateOperation(c *gin.Context, db *gorm.DB, annotationType models.AnnotationType, title string) func(outcome string) {
+	_, finish := openAnnotation(c, db, annotationType, title)
+	return finish
+}
+
+// openAnnotation is annotateOperation that also returns the annotation's
+// ID, nil when it could not be opened
+func openAnnotation(c *gin.Context, db *gorm.DB, annotationType models.AnnotationType, title string) (*uint, func(outcome string)) {
+	user, _ := middleware.GetUserFromContext(c)
+	annotation, err := models.OpenAnnotation(db.WithContext(c), annotationType, title, annotationAuthor(user), user.Name)
+	if err != nil {
+		middleware.Logger.Warn("Failed to open annotation: " + err.Error())
+		return nil, func(string) {}
+	}
+	return &annotation.ID, func(outcome string) {
+		// Close even when the request was cancelled mid-run
+		if err := models.CloseAnnotation(db, annotation, outcome); err != nil {
+			middleware.Logger.Warn("Failed to close annotation: " + err.Error())
+		}
+	}
+}
+
+// annotationAuthor returns the user ID recorded on an annotation; service
+// accounts have none and are identified by name only
+func annotationAuthor(user models.User) *uint {
+	if user.ID == 0 {
+		return nil
+	}
+	return &user.ID
+}
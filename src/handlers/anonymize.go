@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/anonymize"
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// anonymizationConfirmationTTL is how long a preview's confirmation token
+// stays valid
+const anonymizationConfirmationTTL = 10 * time.Minute
+
+// AnonymizationHandler handles customer anonymization
+type AnonymizationHandler struct {
+	db         *gorm.DB
+	anonymizer *anonymize.Anonymizer
+}
+
+// NewAnonymizationHandler creates a new AnonymizationHandler
+func NewAnonymizationHandler(db *gorm.DB, anonymizer *anonymize.Anonymizer) *AnonymizationHandler {
+	return &AnonymizationHandler{db: db, anonymizer: anonymizer}
+}
+
+// AnonymizeRequest represents the request body for anonymizing a customer
+type AnonymizeRequest struct {
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+// AnonymizeCustomer erases a customer's personal data while keeping their
+// deals for reporting. Without a confirmation token it only previews the
+// affected records and issues a token; repeating the request with that token
+// performs the anonymization.
+// POST /admin/customers/:id/anonymize
+func (h *AnonymizationHandler) AnonymizeCustomer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
+		})
+		return
+	}
+
+	var req AnonymizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	var customer models.Customer
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CUSTOMER_NOT_FOUND",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
+		})
+		return
+	}
+
+	if rejectAnonymized(c, customer.AnonymizedAt) {
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+
+	// Step one: preview what would change and issue a confirmation token
+	if req.ConfirmationToken == "" {
+		affected, err := h.anonymizer.Preview(c, h.db, customer.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to preview anonymization"),
+			})
+			return
+		}
+
+		expiresAt := time.Now().Add(anonymizationConfirmationTTL).Truncate(time.Second)
+		c.JSON(http.StatusOK, models.AnonymizationPreview{
+			CustomerID:        customer.ID,
+			Affected:          affected,
+			ConfirmationToken: h.anonymizer.ConfirmationToken(customer.ID, user.ID, expiresAt),
+			ExpiresAt:         expiresAt,
+		})
+		return
+	}
+
+	// Step two: anonymize once the preview is confirmed
+	if err := h.anonymizer.VerifyConfirmation(req.ConfirmationToken, customer.ID, user.ID, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_CONFIRMATION_TOKEN",
+			"message": i18n.Message(c, "INVALID_CONFIRMATION_TOKEN", "Confirmation token is invalid or expired; request a new preview"),
+		})
+		return
+	}
+
+	// Log audit without values, which would reintroduce the erased data
+	var affected models.AnonymizationSummary
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if affected, err = h.anonymizer.Customer(c, tx, customer.ID, time.Now()); err != nil {
+			return err
+		}
+		return audittrail.Record(c, tx, &models.AuditLog{
+			ResourceType: "customer",
+			ResourceID:   customer.ID,
+			Action:       models.AuditActionAnonymize,
+			UserID:       user.ID,
+			UserName:     user.Name,
+			UserRole:     user.Role,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, anonymize.ErrAlreadyAnonymized) {
+			rejectAnonymized(c, &time.Time{})
+			return
+		}
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to anonymize customer"),
+		})
+		return
+	}
+
+	h.db.WithContext(c).First(&customer, customer.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"customer": customer,
+		"affected": affected,
+	})
+}
+
+// rejectAnonymized responds with 409 ANONYMIZED when a customer has been
+// anonymized. Anonymized customers cannot be edited.
+func rejectAnonymized(c *gin.Context, anonymizedAt *time.Time) bool {
+	if anonymizedAt == nil {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "conflict",
+		"code":    "ANONYMIZED",
+		"message": i18n.Message(c, "ANONYMIZED", "Anonymized customers cannot be changed"),
+	})
+	return true
+}
+
+// rejectAnonymizedCustomer is rejectAnonymized for records that only hold
+// their customer's ID, such as contacts
+func rejectAnonymizedCustomer(c *gin.Context, db *gorm.DB, customerID uint) bool {
+	var customer models.Customer
+	if err := db.WithContext(c).Unscoped().Select("id", "anonymized_at").First(&customer, customerID).Error; err != nil {
+		return false
+	}
+	return rejectAnonymized(c, customer.AnonymizedAt)
+}
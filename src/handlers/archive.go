@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// rejectArchived responds with 409 ARCHIVED when a record is archived.
+// Archived records only accept unarchive.
+func rejectArchived(c *gin.Context, archivedAt *time.Time) bool {
+	if archivedAt == nil {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "conflict",
+		"code":    "ARCHIVED",
+		"message": i18n.Message(c, "ARCHIVED", "Archived records must be unarchived before they can be changed"),
+	})
+	return true
+}
+
+// rejectArchivedCustomer is rejectArchived for records that only hold
+// their customer's ID, such as contacts
+func rejectArchivedCustomer(c *gin.Context, db *gorm.DB, customerID uint) bool {
+	var customer models.Customer
+	if err := db.WithContext(c).Select("id", "archived_at").First(&customer, customerID).Error; err != nil {
+		return false
+	}
+	return rejectArchived(c, customer.ArchivedAt)
+}
+
+// includeArchived reports whether a list request asked for archived records
+func includeArchived(c *gin.Context) bool {
+	return c.Query("include_archived") == "true"
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/assignment"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSimulationCount caps how many assignments a simulation may preview
+const maxSimulationCount = 500
+
+// AssignmentRuleHandler handles lead assignment rule endpoints
+type AssignmentRuleHandler struct {
+	db *gorm.DB
+}
+
+// NewAssignmentRuleHandler creates a new AssignmentRuleHandler
+func NewAssignmentRuleHandler(db *gorm.DB) *AssignmentRuleHandler {
+	return &AssignmentRuleHandler{db: db}
+}
+
+// AssignmentRuleRequest represents the request body for creating or updating a rule
+type AssignmentRuleRequest struct {
+	Name     string                    `json:"name" binding:"required,min=1,max=100"`
+	Strategy models.AssignmentStrategy `json:"strategy" binding:"required"`
+	Members  []models.AssignmentMember `json:"members" binding:"required,min=1"`
+	Priority int                       `json:"priority,omitempty"`
+	IsActive *bool                     `json:"is_active,omitempty"`
+}
+
+// ListAssignmentRules returns all assignment rules in evaluation order
+// GET /admin/assignment-rules
+func (h *AssignmentRuleHandler) ListAssignmentRules(c *gin.Context) {
+	var rules []models.AssignmentRule
+	if err := h.db.WithContext(c).Order("priority ASC, id ASC").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch assignment rules"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// CreateAssignmentRule creates a new assignment rule
+// POST /admin/assignment-rules
+func (h *AssignmentRuleHandler) CreateAssignmentRule(c *gin.Context) {
+	var req AssignmentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	rule := models.AssignmentRule{IsActive: true}
+	applyAssignmentRuleRequest(&rule, req)
+	if !h.validateRule(c, rule) {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rule).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "assignment_rule", rule.ID, models.AuditActionCreate, nil, &rule)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create assignment rule"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateAssignmentRule replaces an assignment rule. The round-robin cursor
+// is reset when members or strategy change.
+// PUT /admin/assignment-rules/:id
+func (h *AssignmentRuleHandler) UpdateAssignmentRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+	oldRule := *rule
+
+	var req AssignmentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	applyAssignmentRuleRequest(rule, req)
+	rule.Position = 0
+	if !h.validateRule(c, *rule) {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(rule).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "assignment_rule", rule.ID, models.AuditActionUpdate, &oldRule, rule)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update assignment rule"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteAssignmentRule deletes an assignment rule
+// DELETE /admin/assignment-rules/:id
+func (h *AssignmentRuleHandler) DeleteAssignmentRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(rule).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "assignment_rule", rule.ID, models.AuditActionDelete, rule, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete assignment rule"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Assignment rule deleted successfully",
+	})
+}
+
+// SimulateAssignmentRule shows how the next leads would be distributed
+// without assigning anything
+// GET /admin/assignment-rules/:id/simulate?count=20
+func (h *AssignmentRuleHandler) SimulateAssignmentRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "20"))
+	if count < 1 || count > maxSimulationCount {
+		count = 20
+	}
+
+	simulation, err := assignment.Simulate(h.db.WithContext(c), *rule, count, time.Now())
+	if err != nil {
+		if errors.Is(err, assignment.ErrNoAvailableRep) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "NO_AVAILABLE_REP",
+				"message": i18n.Message(c, "NO_AVAILABLE_REP", "No rep in this rule is currently available"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to simulate assignment rule"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
+
+// applyAssignmentRuleRequest copies request fields onto a rule
+func applyAssignmentRuleRequest(rule *models.AssignmentRule, req AssignmentRuleRequest) {
+	rule.Name = req.Name
+	rule.Strategy = req.Strategy
+	rule.Members = req.Members
+	rule.Priority = req.Priority
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+}
+
+// validateRule responds with 400 when a rule is invalid
+func (h *AssignmentRuleHandler) validateRule(c *gin.Context, rule models.AssignmentRule) bool {
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ASSIGNMENT_RULE",
+			"message": i18n.Message(c, "INVALID_ASSIGNMENT_RULE", err.Error()),
+		})
+		return false
+	}
+	return true
+}
+
+// findRule loads the assignment rule identified by the :id route parameter,
+// writing the error response when it cannot be found
+func (h *AssignmentRuleHandler) findRule(c *gin.Context) (*models.AssignmentRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid assignment rule ID"),
+		})
+		return nil, false
+	}
+
+	var rule models.AssignmentRule
+	if err := h.db.WithContext(c).First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "ASSIGNMENT_RULE_NOT_FOUND",
+				"message": i18n.Message(c, "ASSIGNMENT_RULE_NOT_FOUND", "Assignment rule not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch assignment rule"),
+		})
+		return nil, false
+	}
+
+	return &rule, true
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AuditLogHandler handles audit log endpoints
+type AuditLogHandler struct {
+	db *gorm.DB
+}
+
+// NewAuditLogHandler creates a new AuditLogHandler
+func NewAuditLogHandler(db *gorm.DB) *AuditLogHandler {
+	return &AuditLogHandler{db: db}
+}
+
+// auditLogListQuery defines the filters and sorting of ListAuditLogs
+var auditLogListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("resource_type", "resource_type"),
+		query.Equal("resource_id", "resource_id"),
+		query.Equal("action", "action"),
+		query.Equal("user_id", "user_id"),
+		query.AtLeast("from", "created_at", query.KindTime),
+		query.AtMost("to", "created_at", query.KindTime),
+	},
+	Sort: query.Sort{Fixed: "id DESC"},
+}
+
+// ListAuditLogs returns audit log entries, newest first
+// GET /admin/audit-logs
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db, _ := auditLogListQuery.Apply(h.db.WithContext(c).Model(&models.AuditLog{}), c.Request.URL.Query())
+
+	var total int64
+	db.Count(&total)
+
+	var logs []models.AuditLog
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch audit logs"),
+		})
+		return
+	}
+
+	annotations, err := h.annotationsFor(c, logs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch annotations"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.AuditLogListResponse{
+		Data:        logs,
+		Annotations: annotations,
+		Total:       total,
+		Page:        page.Page,
+		PageSize:    page.PageSize,
+		TotalPages:  page.TotalPages(total),
+	})
+}
+
+// annotationsFor returns the annotations overlapping the time span of a
+// page of audit log entries, so auditors see the imports and maintenance
+// that explain them
+func (h *AuditLogHandler) annotationsFor(c *gin.Context, logs []models.AuditLog) ([]models.Annotation, error) {
+	annotations := []models.Annotation{}
+	if len(logs) == 0 {
+		return annotations, nil
+	}
+
+	from, to := logs[0].CreatedAt, logs[0].CreatedAt
+	for _, log := range logs[1:] {
+		if log.CreatedAt.Before(from) {
+			from = log.CreatedAt
+		}
+		if log.CreatedAt.After(to) {
+			to = log.CreatedAt
+		}
+	}
+	err := h.db.WithContext(c).
+		Where("starts_at <= ? AND (ends_at IS NULL OR ends_at >= ?)", to, from).
+		Order("starts_at ASC, id ASC").
+		Find(&annotations).Error
+	return annotations, err
+}
+
+// VerifyAuditLogs recomputes the audit log hash chain, optionally over the
+// entries created in an RFC 3339 range, and reports the first broken link
+// GET /admin/audit-logs/verify?from=&to=
+func (h *AuditLogHandler) VerifyAuditLogs(c *gin.Context) {
+	var bounds [2]*time.Time
+	for i, name := range []string{"from", "to"} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_DATE",
+				"message": i18n.Message(c, "INVALID_DATE", name+" must be an RFC 3339 timestamp"),
+			})
+			return
+		}
+		bounds[i] = &t
+	}
+	if bounds[0] != nil && bounds[1] != nil && bounds[1].Before(*bounds[0]) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_DATE_RANGE",
+			"message": i18n.Message(c, "INVALID_DATE_RANGE", "to must be after from"),
+		})
+		return
+	}
+
+	result, err := audittrail.Verify(c, h.db, bounds[0], bounds[1])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to verify audit logs"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondAuditFailure responds that a change was not saved because its
+// audit entry could not be written under the strict audit policy, when err
+// is such a failure, and reports whether it did
+func respondAuditFailure(c *gin.Context, err error) bool {
+	if !errors.Is(err, audittrail.ErrWriteFailed) {
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "internal_error",
+		"code":    "AUDIT_WRITE_FAILED",
+		"message": i18n.Message(c, "AUDIT_WRITE_FAILED", "The change was not saved because its audit entry could not be written"),
+	})
+	return true
+}
+
+// auditEntry builds the audit entry of a change made by the request's user,
+// with the fields the change replaced and the values it set
+func auditEntry(c *gin.Context, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) models.AuditLog {
+	user, _ := middleware.GetUserFromContext(c)
+
+	audit := models.AuditLog{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Action:       action,
+		UserID:       user.ID,
+		UserName:     user.Name,
+		UserRole:     user.Role,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		Reason:       c.GetString(audittrail.ReasonContextKey),
+	}
+	audit.OldValues, audit.NewValues = models.AuditDiff(oldValue, newValue)
+	audit.Snapshot = models.AuditSnapshot(oldValue, newValue)
+	return audit
+}
+
+// logAudit creates an audit log entry in the transaction saving the change
+// (see audittrail.Record)
+func logAudit(c *gin.Context, tx *gorm.DB, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) error {
+	audit := auditEntry(c, resourceType, resourceID, action, oldValue, newValue)
+	return audittrail.Record(c, tx, &audit)
+}
+
+// publishChange raises the events of a change audited only by a summary
+// entry, such as a row of an import
+func publishChange(c *gin.Context, tx *gorm.DB, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) {
+	audit := auditEntry(c, resourceType, resourceID, action, oldValue, newValue)
+	audittrail.Publish(c, tx, &audit)
+}
+
+// beginBulkOperation marks the request's changes as made by a bulk
+// operation, so their events are suppressed for subscriptions that do not
+// take bulk events, and opens its annotation. The returned function closes
+// the annotation with outcome and raises the operation's bulk.completed
+// event with summary, stamped with the annotation and run times.
+func beginBulkOperation(c *gin.Context, db *gorm.DB, operation string, annotationType models.AnnotationType, title string) func(summary models.BulkOperationSummary, outcome string) {
+	started := time.Now()
+	c.Set(audittrail.BulkContextKey, operation)
+	annotationID, finish := openAnnotation(c, db, annotationType, title)
+	return func(summary models.BulkOperationSummary, outcome string) {
+		finish(outcome)
+		summary.Operation, summary.AnnotationID = operation, annotationID
+		summary.StartedAt, summary.CompletedAt = started, time.Now()
+		audittrail.PublishBulk(c, db, summary)
+	}
+}
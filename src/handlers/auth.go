@@ -3,17 +3,21 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AuthHandler handles authentication-related endpoints
-type AuthHandler struct{}
+type AuthHandler struct {
+	db *gorm.DB
+}
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{}
+func NewAuthHandler(db *gorm.DB) *AuthHandler {
+	return &AuthHandler{db: db}
 }
 
 // GetMe returns the current user's information from JWT claims
@@ -24,13 +28,13 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "unauthorized",
 			"code":    "NO_USER_CONTEXT",
-			"message": "User not found in context",
+			"message": i18n.Message(c, "NO_USER_CONTEXT", "User not found in context"),
 		})
 		return
 	}
 
 	// Get permissions for user's role
-	permissions := models.RolePermissions[user.Role]
+	permissions, _ := models.RolePermissions(user.Role)
 	if permissions == nil {
 		permissions = []string{}
 	}
@@ -42,3 +46,43 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetCapabilities returns which fields the current user can edit per entity,
+// which activity fields are required in each status, the open deal limit
+// and which field changes require a reason
+// GET /admin/me/capabilities
+func (h *AuthHandler) GetCapabilities(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "NO_USER_CONTEXT",
+			"message": i18n.Message(c, "NO_USER_CONTEXT", "User not found in context"),
+		})
+		return
+	}
+
+	permissions, _ := models.RolePermissions(user.Role)
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	changeReasons, err := loadChangeReasonPolicy(h.db.WithContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch change reason policy"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CapabilitiesResponse{
+		Role:           user.Role,
+		Permissions:    permissions,
+		Entities:       models.BuildCapabilities(user.Role),
+		ActivityPolicy: models.BuildActivityPolicyCapabilities(),
+		OpenDealLimit:  models.BuildOpenDealLimitCapabilities(),
+		ChangeReasons:  changeReasons,
+	})
+}
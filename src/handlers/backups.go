@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/backup"
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BackupHandler handles logical backup and restore endpoints
+type BackupHandler struct {
+	db      *gorm.DB
+	exports *exports.Manager
+}
+
+// NewBackupHandler creates a new BackupHandler. Backups run as jobs of the
+// export manager.
+func NewBackupHandler(db *gorm.DB, exportManager *exports.Manager) *BackupHandler {
+	return &BackupHandler{db: db, exports: exportManager}
+}
+
+// RestoreResponse is the response of a completed restore
+type RestoreResponse struct {
+	Message  string          `json:"message"`
+	Manifest backup.Manifest `json:"manifest"`
+}
+
+// CreateBackup starts an async backup of every table. The archive is
+// downloaded from the job like an export and expires with it.
+// POST /admin/maintenance/backup
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c)
+	job := models.Job{
+		Type:          backup.JobType,
+		CreatedBy:     user.ID,
+		CreatedByName: user.Name,
+	}
+
+	if err := h.exports.EnqueueInternal(c, &job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create job"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// RestoreBackup loads a backup archive, sent as a multipart "file" or as
+// the raw body. The database must be empty unless force=true, which
+// replaces its data.
+// POST /admin/maintenance/restore?force=
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	force, _ := strconv.ParseBool(c.DefaultQuery("force", "false"))
+
+	var source io.Reader = c.Request.Body
+	if file, _, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		source = file
+	}
+
+	// Log audit, continuing the restored audit chain
+	var manifest backup.Manifest
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if manifest, err = backup.Restore(c, tx, source, force); err != nil {
+			return err
+		}
+		return logAudit(c, tx, "backup", 0, models.AuditActionRestore, nil, &manifest)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, backup.ErrInvalidArchive):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_BACKUP",
+				"message": i18n.Message(c, "INVALID_BACKUP", err.Error()),
+			})
+		case errors.Is(err, backup.ErrSchemaMismatch):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "BACKUP_SCHEMA_MISMATCH",
+				"message": i18n.Message(c, "BACKUP_SCHEMA_MISMATCH", err.Error()),
+			})
+		case errors.Is(err, backup.ErrNotEmpty):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "DATABASE_NOT_EMPTY",
+				"message": i18n.Message(c, "DATABASE_NOT_EMPTY", err.Error()+"; restore into an empty database or pass force=true"),
+			})
+		default:
+			middleware.Logger.Error("Restore failed: " + err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to restore backup"),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, RestoreResponse{
+		Message:  "Backup restored",
+		Manifest: manifest,
+	})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/calendarfeed"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// calendarFeedHistory is how far back the feed lists activities, so recently
+// completed and cancelled ones still update subscribed calendars
+const calendarFeedHistory = 90 * 24 * time.Hour
+
+// calendarFeedLimit caps the activities of one feed
+const calendarFeedLimit = 5000
+
+// CalendarFeedHandler serves users' activities as iCalendar feeds
+type CalendarFeedHandler struct {
+	db            *gorm.DB
+	signer        *calendarfeed.Signer
+	publicBaseURL string
+}
+
+// NewCalendarFeedHandler creates a new CalendarFeedHandler
+func NewCalendarFeedHandler(db *gorm.DB, signer *calendarfeed.Signer, publicBaseURL string) *CalendarFeedHandler {
+	return &CalendarFeedHandler{
+		db:            db,
+		signer:        signer,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// CalendarTokenResponse is the feed token of a user and the URL calendar
+// clients subscribe to
+type CalendarTokenResponse struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// IssueCalendarToken issues a long-lived token for subscribing to the
+// user's activity feed. Earlier tokens stay valid until the user's tokens
+// are revoked.
+// POST /admin/me/calendar-token
+func (h *CalendarFeedHandler) IssueCalendarToken(c *gin.Context) {
+	// Feeds list the activities assigned to a person; service accounts have
+	// no user ID
+	user, _ := middleware.GetUserFromContext(c)
+	if user.ID == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"code":    "CALENDAR_FEED_REQUIRES_USER",
+			"message": i18n.Message(c, "CALENDAR_FEED_REQUIRES_USER", "Only users can subscribe to a calendar feed"),
+		})
+		return
+	}
+
+	token, err := h.signer.Issue(user.ID, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "INTERNAL_ERROR",
+			"message": i18n.Message(c, "INTERNAL_ERROR", "Failed to issue calendar feed token"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CalendarTokenResponse{
+		Token: token,
+		URL:   h.publicBaseURL + "/admin/me/activities.ics?token=" + url.QueryEscape(token),
+	})
+}
+
+// GetMyActivitiesCalendar renders the user's activities with a due date as
+// an iCalendar feed, from calendarFeedHistory ago onward. Cancelled
+// activities are included so calendars drop them.
+// GET /admin/me/activities.ics
+func (h *CalendarFeedHandler) GetMyActivitiesCalendar(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "NO_USER_CONTEXT",
+			"message": i18n.Message(c, "NO_USER_CONTEXT", "User not found in context"),
+		})
+		return
+	}
+
+	var activities []models.Activity
+	if err := h.db.WithContext(c).
+		Where("assigned_to = ? AND due_date IS NOT NULL AND due_date >= ?", user.ID, time.Now().Add(-calendarFeedHistory)).
+		Order("due_date ASC, id ASC").
+		Limit(calendarFeedLimit).
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activities"),
+		})
+		return
+	}
+
+	var feed bytes.Buffer
+	link := func(id uint) string {
+		return h.publicBaseURL + "/admin/activities/" + strconv.FormatUint(uint64(id), 10)
+	}
+	if err := calendarfeed.Write(&feed, "CRM activities", activities, link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "INTERNAL_ERROR",
+			"message": i18n.Message(c, "INTERNAL_ERROR", "Failed to render calendar feed"),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="activities.ics"`)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, calendarfeed.ContentType, feed.Bytes())
+}
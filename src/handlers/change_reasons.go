@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HeaderChangeReason carries the reason for a change, for clients that do
+// not send it as change_reason in the body
+const HeaderChangeReason = "X-Change-Reason"
+
+// mergePatchReasonKey holds the change_reason taken out of a merge patch
+const mergePatchReasonKey = "merge_patch_change_reason"
+
+// ChangeReasonHandler handles the change reason policy settings
+type ChangeReasonHandler struct {
+	db *gorm.DB
+}
+
+// NewChangeReasonHandler creates a new ChangeReasonHandler
+func NewChangeReasonHandler(db *gorm.DB) *ChangeReasonHandler {
+	return &ChangeReasonHandler{db: db}
+}
+
+// ChangeReasonPolicyRequest represents the request body replacing the
+// change reason policy
+type ChangeReasonPolicyRequest struct {
+	Rules []ChangeReasonRuleRequest `json:"rules" binding:"required,dive"`
+}
+
+// ChangeReasonRuleRequest is one rule of a change reason policy
+type ChangeReasonRuleRequest struct {
+	Entity     string `json:"entity" binding:"required"`
+	Field      string `json:"field" binding:"required"`
+	ClosedOnly bool   `json:"closed_only,omitempty"`
+}
+
+// GetChangeReasonPolicy returns the fields whose edits require a reason
+// GET /admin/change-reasons
+func (h *ChangeReasonHandler) GetChangeReasonPolicy(c *gin.Context) {
+	policy, err := loadChangeReasonPolicy(h.db.WithContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch change reason policy"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ChangeReasonPolicyResponse{Data: policy})
+}
+
+// UpdateChangeReasonPolicy replaces the fields whose edits require a reason
+// PUT /admin/change-reasons
+func (h *ChangeReasonHandler) UpdateChangeReasonPolicy(c *gin.Context) {
+	var req ChangeReasonPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	rules := make([]models.ChangeReasonRule, 0, len(req.Rules))
+	seen := make(map[string]bool, len(req.Rules))
+	for _, ruleReq := range req.Rules {
+		rule := models.ChangeReasonRule{Entity: ruleReq.Entity, Field: ruleReq.Field, ClosedOnly: ruleReq.ClosedOnly}
+		if err := rule.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_CHANGE_REASON_RULE",
+				"message": i18n.Message(c, "INVALID_CHANGE_REASON_RULE", err.Error()),
+			})
+			return
+		}
+		if seen[rule.Entity+"."+rule.Field] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_CHANGE_REASON_RULE",
+				"message": i18n.Message(c, "INVALID_CHANGE_REASON_RULE", "Each field may have only one rule: "+rule.Entity+"."+rule.Field),
+			})
+			return
+		}
+		seen[rule.Entity+"."+rule.Field] = true
+		rules = append(rules, rule)
+	}
+
+	var old []models.ChangeReasonRule
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if old, err = loadChangeReasonPolicy(tx); err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&models.ChangeReasonRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) > 0 {
+			if err := tx.Create(&rules).Error; err != nil {
+				return err
+			}
+		}
+		return logAudit(c, tx, "change_reason_policy", 0, models.AuditActionUpdate, models.ChangeReasonPolicyResponse{Data: old}, models.ChangeReasonPolicyResponse{Data: rules})
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update change reason policy"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ChangeReasonPolicyResponse{Data: rules})
+}
+
+// loadChangeReasonPolicy reads the change reason rules by entity and field
+func loadChangeReasonPolicy(db *gorm.DB) (models.ChangeReasonPolicy, error) {
+	policy := models.ChangeReasonPolicy{}
+	err := db.Order("entity ASC, field ASC").Find(&policy).Error
+	return policy, err
+}
+
+// takeChangeReason takes the reason for an edit from the request body, or
+// else the X-Change-Reason header, and records it on the audit entries of
+// the request. It responds with 400 and returns false when the reason is
+// too long.
+func takeChangeReason(c *gin.Context, bodyReason string) (string, bool) {
+	reason := strings.TrimSpace(bodyReason)
+	if reason == "" {
+		reason = strings.TrimSpace(c.GetHeader(HeaderChangeReason))
+	}
+	if len(reason) > models.MaxChangeReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "change_reason must be at most 500 characters"),
+		})
+		return "", false
+	}
+	if reason != "" {
+		c.Set(audittrail.ReasonContextKey, reason)
+	}
+	return reason, true
+}
+
+// requireChangeReason takes the reason for an edit as takeChangeReason
+// does. When the policy requires a reason for a changed field and none was
+// given it responds with 422 REASON_REQUIRED naming the fields and returns
+// false. closed reports whether the record was closed before the edit.
+func requireChangeReason(c *gin.Context, db *gorm.DB, entity string, closed bool, changed []string, bodyReason string) bool {
+	reason, ok := takeChangeReason(c, bodyReason)
+	if !ok {
+		return false
+	}
+	if reason != "" || len(changed) == 0 {
+		return true
+	}
+
+	policy, err := loadChangeReasonPolicy(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch change reason policy"),
+		})
+		return false
+	}
+	if required := policy.RequiredFields(entity, closed, changed); len(required) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "validation_error",
+			"code":    "REASON_REQUIRED",
+			"message": i18n.Message(c, "REASON_REQUIRED", "Give a reason for changing these fields in change_reason or the X-Change-Reason header"),
+			"fields":  required,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ClaimHandler lets agents claim unassigned customers and activities
+type ClaimHandler struct {
+	db         *gorm.DB
+	dailyLimit int
+}
+
+// NewClaimHandler creates a new ClaimHandler. dailyLimit caps the claims
+// each user makes per UTC day; 0 disables the cap.
+func NewClaimHandler(db *gorm.DB, dailyLimit int) *ClaimHandler {
+	return &ClaimHandler{db: db, dailyLimit: dailyLimit}
+}
+
+// ClaimDigest summarizes the claims made on one day, per agent
+type ClaimDigest struct {
+	Date       string             `json:"date"`
+	Total      int                `json:"total"`
+	DailyLimit int                `json:"daily_limit"`
+	Agents     []ClaimDigestAgent `json:"agents"`
+}
+
+// ClaimDigestAgent lists the records one agent claimed
+type ClaimDigestAgent struct {
+	UserID      uint   `json:"user_id"`
+	UserName    string `json:"user_name"`
+	Total       int    `json:"total"`
+	CustomerIDs []uint `json:"customer_ids"`
+	ActivityIDs []uint `json:"activity_ids"`
+}
+
+// ClaimCustomer assigns an unassigned customer to the current user. Only
+// one of several concurrent claims succeeds; the others get 409.
+// POST /admin/customers/:id/claim
+func (h *ClaimHandler) ClaimCustomer(c *gin.Context) {
+	id, ok := h.parseClaim(c, "Invalid customer ID")
+	if !ok {
+		return
+	}
+
+	var customer models.Customer
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CUSTOMER_NOT_FOUND",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
+		})
+		return
+	}
+	if rejectAnonymized(c, customer.AnonymizedAt) {
+		return
+	}
+
+	oldCustomer := customer
+	user, _ := middleware.GetUserFromContext(c)
+	customer.AssignedTo = &user.ID
+	if !h.claim(c, "customer", &models.Customer{}, customer.ID, oldCustomer.AssignedTo, &oldCustomer, &customer) {
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// ClaimActivity assigns an unassigned open activity to the current user.
+// Only one of several concurrent claims succeeds; the others get 409.
+// POST /admin/activities/:id/claim
+func (h *ClaimHandler) ClaimActivity(c *gin.Context) {
+	id, ok := h.parseClaim(c, "Invalid activity ID")
+	if !ok {
+		return
+	}
+
+	var activity models.Activity
+	if err := h.db.WithContext(c).First(&activity, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "ACTIVITY_NOT_FOUND",
+				"message": i18n.Message(c, "ACTIVITY_NOT_FOUND", "Activity not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity"),
+		})
+		return
+	}
+	if activity.IsClosed() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "ACTIVITY_ALREADY_CLOSED",
+			"message": i18n.Message(c, "ACTIVITY_ALREADY_CLOSED", "Activity is already completed or cancelled"),
+		})
+		return
+	}
+
+	oldActivity := activity
+	user, _ := middleware.GetUserFromContext(c)
+	activity.AssignedTo = &user.ID
+	if !h.claim(c, "activity", &models.Activity{}, activity.ID, oldActivity.AssignedTo, &oldActivity, &activity) {
+		return
+	}
+
+	c.JSON(http.StatusOK, activity)
+}
+
+// GetClaimDigest returns the claims made on a UTC day, by agent, so
+// managers can review them once a day. It defaults to yesterday.
+// GET /admin/claims/digest?date=2026-10-15
+func (h *ClaimHandler) GetClaimDigest(c *gin.Context) {
+	day := startOfUTCDay(time.Now()).AddDate(0, 0, -1)
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_DATE",
+				"message": i18n.Message(c, "INVALID_DATE", "date must be formatted as YYYY-MM-DD"),
+			})
+			return
+		}
+		day = parsed
+	}
+
+	var claims []models.AuditLog
+	if err := h.db.WithContext(c).
+		Select("id", "resource_type", "resource_id", "user_id", "user_name").
+		Where("action = ? AND created_at >= ? AND created_at < ?", models.AuditActionClaim, day, day.AddDate(0, 0, 1)).
+		Order("user_id ASC, id ASC").
+		Find(&claims).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch claims"),
+		})
+		return
+	}
+
+	digest := ClaimDigest{
+		Date:       day.Format("2006-01-02"),
+		Total:      len(claims),
+		DailyLimit: h.dailyLimit,
+		Agents:     []ClaimDigestAgent{},
+	}
+	for _, claim := range claims {
+		if n := len(digest.Agents); n == 0 || digest.Agents[n-1].UserID != claim.UserID {
+			digest.Agents = append(digest.Agents, ClaimDigestAgent{
+				UserID:      claim.UserID,
+				UserName:    claim.UserName,
+				CustomerIDs: []uint{},
+				ActivityIDs: []uint{},
+			})
+		}
+		agent := &digest.Agents[len(digest.Agents)-1]
+		agent.Total++
+		switch claim.ResourceType {
+		case "customer":
+			agent.CustomerIDs = append(agent.CustomerIDs, claim.ResourceID)
+		case "activity":
+			agent.ActivityIDs = append(agent.ActivityIDs, claim.ResourceID)
+		}
+	}
+
+	c.JSON(http.StatusOK, digest)
+}
+
+// parseClaim validates the :id parameter and that the current user may
+// claim another record today, writing the error response otherwise
+func (h *ClaimHandler) parseClaim(c *gin.Context, invalidID string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", invalidID),
+		})
+		return 0, false
+	}
+
+	// Records are claimed for a person; service accounts have no user ID
+	user, _ := middleware.GetUserFromContext(c)
+	if user.ID == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"code":    "CLAIM_REQUIRES_USER",
+			"message": i18n.Message(c, "CLAIM_REQUIRES_USER", "Only users can claim records"),
+		})
+		return 0, false
+	}
+
+	if h.dailyLimit > 0 {
+		var claimed int64
+		if err := h.db.WithContext(c).Model(&models.AuditLog{}).
+			Where("action = ? AND user_id = ? AND created_at >= ?", models.AuditActionClaim, user.ID, startOfUTCDay(time.Now())).
+			Count(&claimed).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to count claims"),
+			})
+			return 0, false
+		}
+		if claimed >= int64(h.dailyLimit) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "rate_limited",
+				"code":    "CLAIM_LIMIT_REACHED",
+				"message": i18n.Message(c, "CLAIM_LIMIT_REACHED", "Daily claim limit reached, try again tomorrow"),
+				"limit":   h.dailyLimit,
+			})
+			return 0, false
+		}
+	}
+
+	return uint(id), true
+}
+
+// claim assigns a record to the current user only while it is still
+// unassigned, and audits the claim in the same transaction. It writes the
+// error response and returns false when the record was not claimed.
+func (h *ClaimHandler) claim(c *gin.Context, resourceType string, model interface{}, id uint, assignedTo *uint, oldValue, newValue interface{}) bool {
+	user, _ := middleware.GetUserFromContext(c)
+	if assignedTo != nil {
+		respondAlreadyClaimed(c, *assignedTo)
+		return false
+	}
+
+	claimed := false
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(model).Where("id = ? AND assigned_to IS NULL", id).Update("assigned_to", user.ID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		claimed = true
+
+		audit := models.AuditLog{
+			ResourceType: resourceType,
+			ResourceID:   id,
+			Action:       models.AuditActionClaim,
+			UserID:       user.ID,
+			UserName:     user.Name,
+			UserRole:     user.Role,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+		}
+		audit.OldValues, audit.NewValues = models.AuditDiff(oldValue, newValue)
+		return audittrail.Record(c, tx, &audit)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to claim "+resourceType),
+		})
+		return false
+	}
+	if !claimed {
+		// Another claim or assignment won the race
+		var winner uint
+		h.db.WithContext(c).Model(model).Where("id = ?", id).Select("assigned_to").Scan(&winner)
+		respondAlreadyClaimed(c, winner)
+		return false
+	}
+	return true
+}
+
+// respondAlreadyClaimed responds with 409 naming the record's assignee
+func respondAlreadyClaimed(c *gin.Context, assignedTo uint) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":       "conflict",
+		"code":        "ALREADY_CLAIMED",
+		"message":     i18n.Message(c, "ALREADY_CLAIMED", "This record is already assigned"),
+		"assigned_to": assignedTo,
+	})
+}
+
+// startOfUTCDay returns midnight UTC of t's day
+func startOfUTCDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/companies"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CompanyHandler handles company endpoints. Companies are not stored: they
+// are customers grouped by the domain of their email address.
+type CompanyHandler struct {
+	db      *gorm.DB
+	domains *companies.Domains
+}
+
+// NewCompanyHandler creates a new CompanyHandler
+func NewCompanyHandler(db *gorm.DB, domains *companies.Domains) *CompanyHandler {
+	return &CompanyHandler{db: db, domains: domains}
+}
+
+// openDealTotalsSQL sums open deals per customer
+const openDealTotalsSQL = `LEFT JOIN (
+	SELECT customer_id, COUNT(*) AS open_deals, SUM(amount) AS open_value
+	FROM deals
+	WHERE deleted_at IS NULL AND archived_at IS NULL AND stage NOT IN ('closed_won', 'closed_lost')
+	GROUP BY customer_id
+) open_deals ON open_deals.customer_id = customers.id`
+
+// ListCompanies returns email domains with their customer count, open
+// pipeline and most common company name, largest first
+// GET /admin/companies?search=acme
+func (h *CompanyHandler) ListCompanies(c *gin.Context) {
+	values := c.Request.URL.Query()
+	page := query.ParsePage(values)
+
+	db := middleware.GetReadDB(c, h.db).WithContext(c).Model(&models.Customer{}).
+		Scopes(models.NotArchived("customers")).
+		Where("customers.email_domain <> ''")
+	filters := map[string]string{}
+	if search := strings.TrimSpace(values.Get("search")); search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		db = db.Where("customers.email_domain LIKE ? OR LOWER(customers.company) LIKE ?", like, like)
+		filters["search"] = search
+	}
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Distinct("customers.email_domain").Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch companies"),
+		})
+		return
+	}
+
+	data := []models.Company{}
+	err := db.Select("customers.email_domain AS domain, " +
+		"COALESCE(MODE() WITHIN GROUP (ORDER BY NULLIF(customers.company, '')), '') AS company_name, " +
+		"COUNT(*) AS customers_count, " +
+		"COALESCE(SUM(open_deals.open_deals), 0) AS open_deals_count, " +
+		"COALESCE(SUM(open_deals.open_value), 0) AS pipeline_value").
+		Joins(openDealTotalsSQL).
+		Group("customers.email_domain").
+		Order("customers_count DESC, domain ASC").
+		Offset(page.Offset()).
+		Limit(page.PageSize).
+		Scan(&data).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch companies"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.CompanyListResponse{
+		Data:       data,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+		Filters:    filters,
+	})
+}
+
+// ListCompanyCustomers returns the customers at an email domain
+// GET /admin/companies/:domain/customers
+func (h *CompanyHandler) ListCompanyCustomers(c *gin.Context) {
+	domain := strings.ToLower(c.Param("domain"))
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db := middleware.GetReadDB(c, h.db).WithContext(c).Model(&models.Customer{}).
+		Scopes(models.NotArchived("customers")).
+		Where("email_domain = ?", domain).
+		Session(&gorm.Session{})
+
+	var total int64
+	db.Count(&total)
+	if total == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "COMPANY_NOT_FOUND",
+			"message": i18n.Message(c, "COMPANY_NOT_FOUND", "No customers found for this domain"),
+		})
+		return
+	}
+
+	var customers []models.Customer
+	if err := db.Order("name ASC").Offset(page.Offset()).Limit(page.PageSize).Find(&customers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customers"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.CustomerListResponse{
+		Data:       customers,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
+
+// EmailDomainBackfillResponse is the response for the email domain backfill
+type EmailDomainBackfillResponse struct {
+	Updated int64 `json:"updated"` // Customers whose domain changed
+}
+
+// BackfillEmailDomains recomputes every customer's email domain, for example
+// after FREE_EMAIL_PROVIDERS changes
+// POST /admin/maintenance/email-domains/backfill
+func (h *CompanyHandler) BackfillEmailDomains(c *gin.Context) {
+	finish := annotateOperation(c, h.db, models.AnnotationTypeMaintenance, "Email domain backfill")
+	updated, err := h.domains.Backfill(c, h.db)
+	if err != nil {
+		finish("Failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to backfill email domains"),
+		})
+		return
+	}
+
+	finish("Email domains updated on " + strconv.FormatInt(updated, 10) + " customers")
+
+	c.JSON(http.StatusOK, EmailDomainBackfillResponse{Updated: updated})
+}
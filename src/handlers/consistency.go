@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/consistency"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ConsistencyHandler handles consistency checker endpoints
+type ConsistencyHandler struct {
+	db     *gorm.DB
+	runner *consistency.Runner
+}
+
+// NewConsistencyHandler creates a new ConsistencyHandler
+func NewConsistencyHandler(db *gorm.DB, runner *consistency.Runner) *ConsistencyHandler {
+	return &ConsistencyHandler{db: db, runner: runner}
+}
+
+// ConsistencyReport is the response for the consistency endpoint
+type ConsistencyReport struct {
+	models.ConsistencyFindingListResponse
+	LastRun *consistency.RunSummary `json:"last_run,omitempty"`
+}
+
+// ListFindings returns consistency findings, open ones by default
+// GET /admin/maintenance/consistency
+func (h *ConsistencyHandler) ListFindings(c *gin.Context) {
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	query := h.db.WithContext(c).Model(&models.ConsistencyFinding{})
+
+	// Filters
+	switch c.DefaultQuery("status", "open") {
+	case "open":
+		query = query.Where("resolved_at IS NULL")
+	case "resolved":
+		query = query.Where("resolved_at IS NOT NULL")
+	}
+	if check := c.Query("check"); check != "" {
+		query = query.Where("check_name = ?", check)
+	}
+	if severity := c.Query("severity"); severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+
+	// Count total
+	var total int64
+	query.Count(&total)
+
+	var findings []models.ConsistencyFinding
+	offset := (page - 1) * pageSize
+	if err := query.Order("last_seen_at DESC, id ASC").Offset(offset).Limit(pageSize).Find(&findings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch consistency findings"),
+		})
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, ConsistencyReport{
+		ConsistencyFindingListResponse: models.ConsistencyFindingListResponse{
+			Data:       findings,
+			Total:      total,
+			Page:       page,
+			PageSize:   pageSize,
+			TotalPages: totalPages,
+		},
+		LastRun: h.runner.LastRun(),
+	})
+}
+
+// RunChecks runs all consistency checks now and returns the run summary
+// POST /admin/maintenance/consistency/run
+func (h *ConsistencyHandler) RunChecks(c *gin.Context) {
+	finish := annotateOperation(c, h.db, models.AnnotationTypeMaintenance, "Consistency checks")
+	summary, err := h.runner.Run(c.Request.Context())
+	if err != nil {
+		finish("Failed: " + err.Error())
+		if errors.Is(err, consistency.ErrRunInProgress) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "CONSISTENCY_RUN_IN_PROGRESS",
+				"message": i18n.Message(c, "CONSISTENCY_RUN_IN_PROGRESS", "A consistency run is already in progress"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to run consistency checks"),
+		})
+		return
+	}
+	finish("Ran " + strconv.Itoa(len(summary.Checks)) + " checks")
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -1,24 +1,28 @@
 package handlers
 
 import (
-	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // ContactHandler handles contact-related endpoints
 type ContactHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	quotas *quota.Tracker
 }
 
 // NewContactHandler creates a new ContactHandler
-func NewContactHandler(db *gorm.DB) *ContactHandler {
-	return &ContactHandler{db: db}
+func NewContactHandler(db *gorm.DB, quotas *quota.Tracker) *ContactHandler {
+	return &ContactHandler{db: db, quotas: quotas}
 }
 
 // ContactCreateRequest represents the request body for creating a contact
@@ -43,6 +47,15 @@ type ContactUpdateRequest struct {
 	Notes     string `json:"notes,omitempty"`
 }
 
+// contactListQuery defines the filters and ordering of ListContacts;
+// primary contacts come first
+var contactListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.FoldedSearch("search", "contacts.search_text"),
+	},
+	Sort: query.Sort{Fixed: "is_primary DESC, created_at ASC"},
+}
+
 // ListContacts returns all contacts for a customer
 // GET /admin/customers/:id/contacts
 func (h *ContactHandler) ListContacts(c *gin.Context) {
@@ -51,66 +64,59 @@ func (h *ContactHandler) ListContacts(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
 
+	readDB := middleware.GetReadDB(c, h.db)
+
 	// Verify customer exists
 	var customer models.Customer
-	if err := h.db.First(&customer, customerID).Error; err != nil {
+	if err := readDB.WithContext(c).First(&customer, customerID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db := readDB.WithContext(c).Model(&models.Contact{}).Where("customer_id = ?", customerID)
+	db, filters := contactListQuery.Apply(db, c.Request.URL.Query())
 
-	// Get contacts
+	// Count total
 	var total int64
-	h.db.Model(&models.Contact{}).Where("customer_id = ?", customerID).Count(&total)
+	db.Count(&total)
 
 	var contacts []models.Contact
-	offset := (page - 1) * pageSize
-	if err := h.db.Where("customer_id = ?", customerID).
-		Order("is_primary DESC, created_at ASC").
-		Offset(offset).Limit(pageSize).
-		Find(&contacts).Error; err != nil {
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&contacts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch contacts",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch contacts"),
 		})
 		return
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-
+	setPageHeaders(c, total, "")
 	c.JSON(http.StatusOK, models.ContactListResponse{
 		Data:       contacts,
 		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+		Filters:    filters,
 	})
 }
 
@@ -122,43 +128,51 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
 
 	// Verify customer exists
 	var customer models.Customer
-	if err := h.db.First(&customer, customerID).Error; err != nil {
+	if err := h.db.WithContext(c).First(&customer, customerID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
+	if rejectArchived(c, customer.ArchivedAt) || rejectAnonymized(c, customer.AnonymizedAt) {
+		return
+	}
+
 	var req ContactCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
+	if !checkLongText(c, &models.Contact{Notes: req.Notes}, nil, "") {
+		return
+	}
+
 	// If this is set as primary, unset other primaries
 	if req.IsPrimary {
-		h.db.Model(&models.Contact{}).Where("customer_id = ?", customerID).Update("is_primary", false)
+		h.db.WithContext(c).Model(&models.Contact{}).Where("customer_id = ?", customerID).Update("is_primary", false)
 	}
 
 	contact := models.Contact{
@@ -172,18 +186,24 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 		Notes:      req.Notes,
 	}
 
-	if err := h.db.Create(&contact).Error; err != nil {
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&contact).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "contact", contact.ID, models.AuditActionCreate, nil, &contact)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to create contact",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create contact"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "contact", contact.ID, models.AuditActionCreate, nil, &contact)
-
 	c.JSON(http.StatusCreated, contact)
 }
 
@@ -195,29 +215,33 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid contact ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid contact ID"),
 		})
 		return
 	}
 
 	var contact models.Contact
-	if err := h.db.First(&contact, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&contact, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CONTACT_NOT_FOUND",
-				"message": "Contact not found",
+				"message": i18n.Message(c, "CONTACT_NOT_FOUND", "Contact not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch contact",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch contact"),
 		})
 		return
 	}
 
+	if rejectArchivedCustomer(c, h.db, contact.CustomerID) || rejectAnonymizedCustomer(c, h.db, contact.CustomerID) {
+		return
+	}
+
 	oldContact := contact
 
 	var req ContactUpdateRequest
@@ -225,7 +249,7 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
@@ -238,6 +262,9 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 		contact.LastName = req.LastName
 	}
 	if req.Email != "" {
+		if !strings.EqualFold(contact.Email, req.Email) {
+			contact.EmailInvalidAt = nil
+		}
 		contact.Email = req.Email
 	}
 	if req.Phone != "" {
@@ -249,26 +276,35 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 	if req.Notes != "" {
 		contact.Notes = req.Notes
 	}
+	if !checkLongText(c, &contact, &oldContact, "") {
+		return
+	}
 	if req.IsPrimary != nil {
 		// If setting as primary, unset other primaries
 		if *req.IsPrimary {
-			h.db.Model(&models.Contact{}).Where("customer_id = ? AND id != ?", contact.CustomerID, id).Update("is_primary", false)
+			h.db.WithContext(c).Model(&models.Contact{}).Where("customer_id = ? AND id != ?", contact.CustomerID, id).Update("is_primary", false)
 		}
 		contact.IsPrimary = *req.IsPrimary
 	}
 
-	if err := h.db.Save(&contact).Error; err != nil {
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&contact).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "contact", contact.ID, models.AuditActionUpdate, &oldContact, &contact)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update contact",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update contact"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "contact", contact.ID, models.AuditActionUpdate, &oldContact, &contact)
-
 	c.JSON(http.StatusOK, contact)
 }
 
@@ -280,60 +316,208 @@ func (h *ContactHandler) DeleteContact(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid contact ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid contact ID"),
 		})
 		return
 	}
 
 	var contact models.Contact
-	if err := h.db.First(&contact, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&contact, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CONTACT_NOT_FOUND",
-				"message": "Contact not found",
+				"message": i18n.Message(c, "CONTACT_NOT_FOUND", "Contact not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch contact",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch contact"),
 		})
 		return
 	}
 
-	if err := h.db.Delete(&contact).Error; err != nil {
+	if rejectArchivedCustomer(c, h.db, contact.CustomerID) {
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&contact).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "contact", contact.ID, models.AuditActionDelete, &contact, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to delete contact",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete contact"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "contact", contact.ID, models.AuditActionDelete, &contact, nil)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Contact deleted successfully",
 	})
 }
 
-// logAudit creates an audit log entry
-func (h *ContactHandler) logAudit(c *gin.Context, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) {
-	user, _ := middleware.GetUserFromContext(c)
+// ImportContacts bulk-imports contacts for a customer from CSV
+// POST /admin/customers/:id/contacts/import
+func (h *ContactHandler) ImportContacts(c *gin.Context) {
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
+		})
+		return
+	}
+
+	// Verify customer exists
+	var customer models.Customer
+	if err := h.db.WithContext(c).First(&customer, customerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CUSTOMER_NOT_FOUND",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
+		})
+		return
+	}
+
+	if rejectArchived(c, customer.ArchivedAt) || rejectAnonymized(c, customer.AnonymizedAt) {
+		return
+	}
+
+	records, err := readCSVUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_CSV",
+			"message": i18n.Message(c, "INVALID_CSV", err.Error()),
+		})
+		return
+	}
+
+	// Fail fast when the whole file cannot fit in the quota
+	if !middleware.CheckQuota(c, h.quotas, quota.Contacts, int64(len(records))) {
+		return
+	}
+
+	report := ImportReport{
+		DryRun:    isDryRun(c),
+		TotalRows: len(records),
+		Rows:      make([]ImportRowResult, 0, len(records)),
+	}
+
+	// Validate every row before writing anything
+	var contacts []models.Contact
+	var contactRows []int
+	primaryRow := 0
+	for _, record := range records {
+		result := ImportRowResult{Row: record.Line}
+
+		contact := models.Contact{
+			CustomerID: uint(customerID),
+			FirstName:  record.Get("first_name"),
+			LastName:   record.Get("last_name"),
+			Email:      record.Get("email"),
+			Phone:      record.Get("phone"),
+			Position:   record.Get("position"),
+		}
+
+		if contact.FirstName == "" {
+			result.Errors = append(result.Errors, "first_name is required")
+		} else if len(contact.FirstName) > 100 {
+			result.Errors = append(result.Errors, "first_name must be at most 100 characters")
+		}
+		if contact.Email != "" && !isValidEmail(contact.Email) {
+			result.Errors = append(result.Errors, "Invalid email format")
+		}
+		isPrimary, err := parseCSVBool(record.Get("is_primary"))
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		if isPrimary {
+			if primaryRow != 0 {
+				result.Errors = append(result.Errors, "only one primary contact is allowed (row "+strconv.Itoa(primaryRow)+" is already primary)")
+			} else if len(result.Errors) == 0 {
+				primaryRow = record.Line
+			}
+		}
+		contact.IsPrimary = isPrimary
+
+		if len(result.Errors) > 0 {
+			result.Status = ImportRowFailed
+			report.Failed++
+		} else {
+			result.Status = ImportRowValid
+			contacts = append(contacts, contact)
+			contactRows = append(contactRows, len(report.Rows))
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if report.DryRun || len(contacts) == 0 {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	finish := annotateOperation(c, h.db, models.AnnotationTypeImport, "Contact import for customer "+customer.Name)
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Demote the existing primary once, not per imported row
+		if primaryRow != 0 {
+			if err := tx.Model(&models.Contact{}).
+				Where("customer_id = ? AND is_primary = ?", customerID, true).
+				Update("is_primary", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.CreateInBatches(&contacts, importBatchSize).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		for i := range contacts {
+			if err := logAudit(c, tx, "contact", contacts[i].ID, models.AuditActionCreate, nil, &contacts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		finish("Failed: no contacts were imported")
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to import contacts"),
+		})
+		return
+	}
 
-	audit := models.AuditLog{
-		ResourceType: resourceType,
-		ResourceID:   resourceID,
-		Action:       action,
-		UserID:       user.ID,
-		UserName:     user.Name,
-		UserRole:     user.Role,
-		IPAddress:    c.ClientIP(),
-		UserAgent:    c.Request.UserAgent(),
+	for i := range contacts {
+		row := &report.Rows[contactRows[i]]
+		row.Status = ImportRowCreated
+		row.ID = contacts[i].ID
+		report.Created++
 	}
+	finish("Imported " + strconv.Itoa(report.Created) + " contacts, " + strconv.Itoa(report.Failed) + " rows failed")
 
-	h.db.Create(&audit)
+	c.JSON(http.StatusOK, report)
 }
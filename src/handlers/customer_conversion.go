@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/dealdefaults"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errNotLead is returned inside a conversion transaction when the customer
+// stopped being a lead or prospect before it was locked
+var errNotLead = errors.New("customer is not a lead or prospect")
+
+// ConversionHandler converts leads and prospects to active customers
+type ConversionHandler struct {
+	db       *gorm.DB
+	defaults *dealdefaults.Service
+	quotas   *quota.Tracker
+}
+
+// NewConversionHandler creates a new ConversionHandler. Initial deals get
+// the stage and currency of defaults when the request leaves them out.
+func NewConversionHandler(db *gorm.DB, defaults *dealdefaults.Service, quotas *quota.Tracker) *ConversionHandler {
+	return &ConversionHandler{db: db, defaults: defaults, quotas: quotas}
+}
+
+// CustomerConvertRequest represents the request body for converting a
+// customer, with the initial deal and contact to create along with it
+type CustomerConvertRequest struct {
+	Deal         *ConversionDealRequest `json:"deal,omitempty"`
+	Contact      *ContactCreateRequest  `json:"contact,omitempty"`
+	ChangeReason string                 `json:"change_reason,omitempty"`
+}
+
+// ConversionDealRequest is the initial deal of a conversion. It belongs to
+// the converted customer and, when one is created, to the new contact.
+type ConversionDealRequest struct {
+	Title             string           `json:"title" binding:"required,max=255"`
+	Description       string           `json:"description,omitempty"`
+	Stage             models.DealStage `json:"stage,omitempty"`
+	Amount            *float64         `json:"amount,omitempty"`
+	Currency          string           `json:"currency,omitempty"`
+	Probability       *int             `json:"probability,omitempty"`
+	ExpectedCloseDate *time.Time       `json:"expected_close_date,omitempty"`
+	OwnerID           *uint            `json:"owner_id,omitempty"`
+	NextStep          string           `json:"next_step,omitempty" binding:"max=255"`
+	NextStepDue       *time.Time       `json:"next_step_due,omitempty"`
+}
+
+// CustomerConversion is the converted customer with the deal and contact
+// created along with it
+type CustomerConversion struct {
+	Customer models.Customer `json:"customer"`
+	Deal     *models.Deal    `json:"deal,omitempty"`
+	Contact  *models.Contact `json:"contact,omitempty"`
+}
+
+// ConvertCustomer converts a lead or prospect to an active customer,
+// stamping when and by whom, and creates the optional initial contact and
+// deal in the same transaction. The conversion is recorded as a convert
+// audit entry, the customer.converted event of the audit trail.
+// POST /admin/customers/:id/convert
+func (h *ConversionHandler) ConvertCustomer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
+		})
+		return
+	}
+
+	var req CustomerConvertRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	var customer models.Customer
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CUSTOMER_NOT_FOUND",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
+		})
+		return
+	}
+
+	if rejectArchived(c, customer.ArchivedAt) || rejectAnonymized(c, customer.AnonymizedAt) {
+		return
+	}
+	if !models.IsLeadStatus(customer.Status) {
+		respondNotLead(c)
+		return
+	}
+
+	// Enforce field-level edit permissions and the change reason policy
+	if !enforceFieldPermissions(c, models.EntityCustomer, customer.AssignedTo, []string{"status"}) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityCustomer, false, []string{"status"}, req.ChangeReason) {
+		return
+	}
+
+	var contact *models.Contact
+	if req.Contact != nil {
+		if !middleware.CheckQuota(c, h.quotas, quota.Contacts, 1) ||
+			!checkLongText(c, &models.Contact{Notes: req.Contact.Notes}, nil, "contact.") {
+			return
+		}
+		contact = &models.Contact{
+			CustomerID: customer.ID,
+			FirstName:  req.Contact.FirstName,
+			LastName:   req.Contact.LastName,
+			Email:      req.Contact.Email,
+			Phone:      req.Contact.Phone,
+			Position:   req.Contact.Position,
+			IsPrimary:  req.Contact.IsPrimary,
+			Notes:      req.Contact.Notes,
+		}
+	}
+
+	var deal *models.Deal
+	if req.Deal != nil {
+		if !middleware.CheckQuota(c, h.quotas, quota.Deals, 1) {
+			return
+		}
+		if deal = h.conversionDeal(customer, req.Deal); !models.IsValidDealStage(deal.Stage) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_STAGE",
+				"message": i18n.Message(c, "INVALID_STAGE", "Invalid deal stage"),
+			})
+			return
+		}
+		if !checkLongText(c, deal, nil, "deal.") {
+			return
+		}
+	}
+
+	oldCustomer := customer
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Lock the customer so concurrent conversions cannot both succeed
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, id).Error; err != nil {
+			return err
+		}
+		if !models.IsLeadStatus(customer.Status) {
+			return errNotLead
+		}
+		oldCustomer = customer
+
+		customer.Status = models.CustomerStatusActive
+		changed := append([]string{"status"}, trackConversion(c, &customer, oldCustomer.Status)...)
+		if err := tx.Model(&customer).Select(changed).Updates(&customer).Error; err != nil {
+			return err
+		}
+
+		if contact != nil {
+			if contact.IsPrimary {
+				if err := tx.Model(&models.Contact{}).Where("customer_id = ?", customer.ID).Update("is_primary", false).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(contact).Error; err != nil {
+				return err
+			}
+		}
+
+		if deal != nil {
+			if contact != nil {
+				deal.ContactID = &contact.ID
+			}
+
+			// New deals go to the bottom of their stage on the board
+			position, err := models.BottomBoardPosition(tx, deal.Stage, deal.OwnerID, 0)
+			if err != nil {
+				return err
+			}
+			deal.BoardPosition = position
+
+			if err := guardOpenDealLimit(c, tx, *deal, models.Deal{}); err != nil {
+				return err
+			}
+			if err := tx.Create(deal).Error; err != nil {
+				return err
+			}
+		}
+
+		// Log audit
+		if err := logAudit(c, tx, "customer", customer.ID, models.AuditActionConvert, &oldCustomer, &customer); err != nil {
+			return err
+		}
+		if contact != nil {
+			if err := logAudit(c, tx, "contact", contact.ID, models.AuditActionCreate, nil, contact); err != nil {
+				return err
+			}
+		}
+		if deal != nil {
+			return logAudit(c, tx, "deal", deal.ID, models.AuditActionCreate, nil, deal)
+		}
+		return nil
+	})
+	if errors.Is(err, errNotLead) {
+		respondNotLead(c)
+		return
+	}
+	if respondOpenDealLimit(c, err) || respondAuditFailure(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to convert customer"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, CustomerConversion{Customer: customer, Deal: deal, Contact: contact})
+}
+
+// conversionDeal builds the initial deal of a conversion, filling in the
+// default stage and currency
+func (h *ConversionHandler) conversionDeal(customer models.Customer, req *ConversionDealRequest) *models.Deal {
+	settings := h.defaults.Settings()
+	stage := req.Stage
+	if stage == "" {
+		stage = settings.Stage
+	}
+	currency := req.Currency
+	if currency == "" {
+		currency = settings.Currency
+	}
+	var amount float64
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	var probability int
+	if req.Probability != nil {
+		probability = min(max(*req.Probability, 0), 100)
+	}
+
+	deal := &models.Deal{
+		Title:             strings.TrimSpace(req.Title),
+		Description:       req.Description,
+		CustomerID:        customer.ID,
+		Stage:             stage,
+		Amount:            amount,
+		Currency:          currency,
+		Probability:       probability,
+		ExpectedCloseDate: req.ExpectedCloseDate,
+		OwnerID:           req.OwnerID,
+		NextStep:          strings.TrimSpace(req.NextStep),
+		NextStepDue:       req.NextStepDue,
+	}
+	deal.TrackNextStep(models.Deal{}, time.Now())
+	return deal
+}
+
+// respondNotLead writes the 409 response for converting a customer that is
+// not a lead or prospect
+func respondNotLead(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "conflict",
+		"code":    "CUSTOMER_NOT_LEAD",
+		"message": i18n.Message(c, "CUSTOMER_NOT_LEAD", "Only lead and prospect customers can be converted"),
+	})
+}
+
+// checkStatusTransition rejects moving a converted customer back to lead or
+// prospect unless the user can manage all records, writing a 403
+// REVERSE_CONVERSION_FORBIDDEN response
+func checkStatusTransition(c *gin.Context, from, to models.CustomerStatus) bool {
+	if !models.IsReverseConversion(from, to) {
+		return true
+	}
+	user, _ := middleware.GetUserFromContext(c)
+	if models.CanManageAll(user.Role) {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "forbidden",
+		"code":    "REVERSE_CONVERSION_FORBIDDEN",
+		"message": i18n.Message(c, "REVERSE_CONVERSION_FORBIDDEN", "Only managers can move a converted customer back to lead or prospect"),
+	})
+	return false
+}
+
+// trackConversion keeps the conversion stamps of a customer in step with a
+// status change from previous: a lead or prospect becoming active is
+// stamped as converted now by the current user, and a customer moved back
+// to lead or prospect loses its stamps. It returns the columns it changed.
+func trackConversion(c *gin.Context, customer *models.Customer, previous models.CustomerStatus) []string {
+	switch {
+	case models.IsLeadStatus(previous) && customer.Status == models.CustomerStatusActive:
+		now := time.Now()
+		customer.ConvertedAt, customer.ConvertedBy = &now, nil
+		if userID, ok := middleware.GetUserIDFromContext(c); ok && userID != 0 {
+			customer.ConvertedBy = &userID
+		}
+	case models.IsReverseConversion(previous, customer.Status) && customer.ConvertedAt != nil:
+		customer.ConvertedAt, customer.ConvertedBy = nil, nil
+	default:
+		return nil
+	}
+	return []string{"converted_at", "converted_by"}
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Ways a customer import handles rows whose email already exists
+const (
+	ImportConflictSkip   = "skip"   // Leave the existing customer as it is
+	ImportConflictUpdate = "update" // Set the row's non-empty columns on it
+)
+
+// ImportRowUpdated is the status of an import row that updated an existing
+// record
+const ImportRowUpdated = "updated"
+
+// customerImportColumns lists the CSV columns a customer import reads
+var customerImportColumns = []string{"name", "email", "phone", "company", "status", "notes"}
+
+// CustomerImportReport is the per-row report of a customer import
+type CustomerImportReport struct {
+	ImportReport
+	Updated    int    `json:"updated"`
+	OnConflict string `json:"on_conflict"`
+}
+
+// CustomerImportSummary counts the outcomes of a batch of a customer
+// import, for its audit entry
+type CustomerImportSummary struct {
+	TotalRows int `json:"total_rows"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+}
+
+// customerImportRow is a validated row of a customer import
+type customerImportRow struct {
+	report int // Index of the row in the report
+	email  string
+	patch  map[string]json.RawMessage
+}
+
+// ImportCustomers creates customers from CSV with the columns name, email,
+// phone, company, status and notes. Rows match existing customers by
+// case-insensitive email; with on_conflict=update the match gets the row's
+// non-empty columns, as with a merge patch, otherwise the row is skipped.
+// Rows are written in transactions of 100, each row under its own savepoint
+// so a rejected row does not undo the others, and a failed batch does not
+// undo the batches before it. One import audit entry summarizes the counts.
+// Each row still raises its events, as part of the bulk operation, which
+// raises bulk.completed at the end.
+// POST /admin/customers/import?on_conflict=skip|update&dry_run=true
+func (h *CustomerHandler) ImportCustomers(c *gin.Context) {
+	onConflict := c.DefaultQuery("on_conflict", ImportConflictSkip)
+	if onConflict != ImportConflictSkip && onConflict != ImportConflictUpdate {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ON_CONFLICT",
+			"message": i18n.Message(c, "INVALID_ON_CONFLICT", "on_conflict must be skip or update"),
+		})
+		return
+	}
+
+	records, err := readCSVUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_CSV",
+			"message": i18n.Message(c, "INVALID_CSV", err.Error()),
+		})
+		return
+	}
+
+	report := CustomerImportReport{
+		ImportReport: ImportReport{
+			DryRun:    isDryRun(c),
+			TotalRows: len(records),
+			Rows:      make([]ImportRowResult, 0, len(records)),
+		},
+		OnConflict: onConflict,
+	}
+
+	// Validate every row before writing anything
+	var rows []customerImportRow
+	seen := map[string]int{}
+	for _, record := range records {
+		result := ImportRowResult{Row: record.Line}
+		row, errs := parseCustomerImportRow(record)
+		if len(errs) > 0 {
+			result.Status = ImportRowFailed
+			result.Errors = errs
+		} else if first, ok := seen[row.email]; ok {
+			result.Status = ImportRowSkipped
+			result.Errors = []string{"duplicate of row " + strconv.Itoa(first)}
+		} else {
+			seen[row.email] = record.Line
+			result.Status = ImportRowValid
+			row.report = len(report.Rows)
+			rows = append(rows, row)
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if report.DryRun {
+		if !h.previewCustomerImport(c, rows, &report) {
+			return
+		}
+		report.count()
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	finish := beginBulkOperation(c, h.db, models.BulkOperationCustomerImport, models.AnnotationTypeImport, "Customer import of "+strconv.Itoa(len(records))+" rows")
+	for start := 0; start < len(rows); start += importBatchSize {
+		batch := rows[start:min(start+importBatchSize, len(rows))]
+
+		results := make([]ImportRowResult, len(batch))
+		err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			for i, row := range batch {
+				results[i] = ImportRowResult{Row: report.Rows[row.report].Row}
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					return h.importCustomer(c, tx, row, onConflict, &results[i])
+				})
+				var failure *upsertFailure
+				switch {
+				case errors.As(err, &failure):
+					results[i].Status = ImportRowFailed
+					results[i].Errors = []string{failure.message}
+				case err != nil:
+					return err
+				}
+			}
+
+			// Log audit, one summary entry per batch
+			summary := summarizeImportRows(results)
+			if summary.Created+summary.Updated == 0 {
+				return nil
+			}
+			return logAudit(c, tx, "customer", 0, models.AuditActionImport, nil, &summary)
+		})
+		for i, row := range batch {
+			if err != nil {
+				// The whole batch was rolled back
+				results[i] = ImportRowResult{
+					Row:    report.Rows[row.report].Row,
+					Status: ImportRowFailed,
+					Errors: []string{"the batch of rows " + strconv.Itoa(report.Rows[batch[0].report].Row) + " to " + strconv.Itoa(report.Rows[batch[len(batch)-1].report].Row) + " could not be written"},
+				}
+			}
+			report.Rows[row.report] = results[i]
+		}
+	}
+	report.count()
+	finish(models.BulkOperationSummary{
+		ResourceType: "customer",
+		Total:        report.TotalRows,
+		Created:      report.Created,
+		Updated:      report.Updated,
+		Skipped:      report.Skipped,
+		Failed:       report.Failed,
+	}, "Created "+strconv.Itoa(report.Created)+", updated "+strconv.Itoa(report.Updated)+
+		", skipped "+strconv.Itoa(report.Skipped)+", failed "+strconv.Itoa(report.Failed))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseCustomerImportRow validates a CSV row, returning it as a merge patch
+// of its non-empty columns or the row's errors
+func parseCustomerImportRow(record csvRecord) (customerImportRow, []string) {
+	var errs []string
+	email := strings.ToLower(record.Get("email"))
+	if email == "" {
+		errs = append(errs, "email is required")
+	} else if !isValidEmail(email) {
+		errs = append(errs, "Invalid email format")
+	}
+	if name := record.Get("name"); name == "" {
+		errs = append(errs, "name is required")
+	} else if len(name) > 255 {
+		errs = append(errs, "name must be at most 255 characters")
+	}
+	if len(record.Get("phone")) > 50 {
+		errs = append(errs, "phone must be at most 50 characters")
+	}
+	if len(record.Get("company")) > 255 {
+		errs = append(errs, "company must be at most 255 characters")
+	}
+	if status := record.Get("status"); status != "" && !models.IsValidCustomerStatus(models.CustomerStatus(status)) {
+		errs = append(errs, "Invalid status")
+	}
+	errs = append(errs, longTextErrors(&models.Customer{Notes: record.Get("notes")})...)
+	if len(errs) > 0 {
+		return customerImportRow{}, errs
+	}
+
+	row := customerImportRow{email: email, patch: map[string]json.RawMessage{}}
+	for _, column := range customerImportColumns {
+		value := record.Get(column)
+		if column == "email" {
+			value = email
+		}
+		if value != "" {
+			row.patch[column], _ = json.Marshal(value)
+		}
+	}
+	return row, nil
+}
+
+// importCustomer creates or, with on_conflict=update, updates the customer
+// of one row, filling in its result. A rejected row returns an
+// *upsertFailure.
+func (h *CustomerHandler) importCustomer(c *gin.Context, tx *gorm.DB, row customerImportRow, onConflict string, result *ImportRowResult) error {
+	customer, err := findUpsertMatch(tx, "", row.email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var created *models.Customer
+		if created, err = h.createUpserted(c, tx, row.patch); err != nil {
+			return err
+		}
+		if created != nil {
+			result.Status = ImportRowCreated
+			result.ID = created.ID
+			return nil
+		}
+
+		// A concurrent request created the customer first
+		customer, err = findUpsertMatch(tx, "", row.email)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &upsertFailure{"EMAIL_EXISTS", "A deleted customer holds this email"}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	result.ID = customer.ID
+	if onConflict == ImportConflictSkip {
+		result.Status = ImportRowSkipped
+		result.Errors = []string{"a customer with this email already exists"}
+		return nil
+	}
+	changed, err := h.updateUpserted(c, tx, customer, row.patch)
+	if err != nil {
+		return err
+	}
+	result.Status = ImportRowUpdated
+	if !changed {
+		result.Status = ImportRowSkipped
+		result.Errors = []string{"the customer with this email already has these values"}
+	}
+	return nil
+}
+
+// previewCustomerImport reports what a dry run would do with the valid
+// rows: rows matching an existing customer are skipped or stay valid for an
+// update. It writes a 500 response and returns false when the customers
+// cannot be looked up.
+func (h *CustomerHandler) previewCustomerImport(c *gin.Context, rows []customerImportRow, report *CustomerImportReport) bool {
+	if len(rows) == 0 {
+		return true
+	}
+	emails := make([]string, len(rows))
+	for i, row := range rows {
+		emails[i] = row.email
+	}
+
+	var existing []struct {
+		ID    uint
+		Email string
+	}
+	if err := h.db.WithContext(c).Model(&models.Customer{}).Select("id, LOWER(email) AS email").
+		Where("LOWER(email) IN ?", emails).Scan(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customers"),
+		})
+		return false
+	}
+
+	ids := make(map[string]uint, len(existing))
+	for _, customer := range existing {
+		ids[customer.Email] = customer.ID
+	}
+	for _, row := range rows {
+		id, ok := ids[row.email]
+		if !ok {
+			continue
+		}
+		result := &report.Rows[row.report]
+		result.ID = id
+		if report.OnConflict == ImportConflictSkip {
+			result.Status = ImportRowSkipped
+			result.Errors = []string{"a customer with this email already exists"}
+		}
+	}
+	return true
+}
+
+// count totals the row statuses of the report
+func (r *CustomerImportReport) count() {
+	summary := summarizeImportRows(r.Rows)
+	r.Created, r.Updated, r.Skipped, r.Failed = summary.Created, summary.Updated, summary.Skipped, summary.Failed
+}
+
+// summarizeImportRows totals the statuses of import rows
+func summarizeImportRows(rows []ImportRowResult) CustomerImportSummary {
+	summary := CustomerImportSummary{TotalRows: len(rows)}
+	for _, row := range rows {
+		switch row.Status {
+		case ImportRowCreated:
+			summary.Created++
+		case ImportRowUpdated:
+			summary.Updated++
+		case ImportRowSkipped:
+			summary.Skipped++
+		case ImportRowFailed:
+			summary.Failed++
+		}
+	}
+	return summary
+}
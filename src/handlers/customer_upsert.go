@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/assignment"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxUpsertRecords caps the number of records in one bulk upsert
+const maxUpsertRecords = 500
+
+// Bulk upsert result statuses
+const (
+	UpsertCreated   = "created"
+	UpsertUpdated   = "updated"
+	UpsertUnchanged = "unchanged"
+	UpsertError     = "error"
+)
+
+// customerUpsertFields lists the fields a bulk upsert record may set, mapped
+// to whether null is allowed to clear them
+var customerUpsertFields = func() map[string]bool {
+	fields := map[string]bool{"external_id": false}
+	for field, nullable := range customerMergePatchFields {
+		fields[field] = nullable
+	}
+	return fields
+}()
+
+// CustomerUpsertRequest holds the records of a bulk upsert. Each record is a
+// merge patch of a customer, matched by external_id or else by email.
+type CustomerUpsertRequest struct {
+	Records []json.RawMessage `json:"records" binding:"required"`
+}
+
+// CustomerUpsertResult reports the outcome for a single record
+type CustomerUpsertResult struct {
+	Index      int    `json:"index"`
+	Status     string `json:"status"`
+	ID         uint   `json:"id,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// CustomerUpsertSummary counts the outcomes of a bulk upsert
+type CustomerUpsertSummary struct {
+	Total     int `json:"total"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+// CustomerUpsertReport is the per-record report of a bulk upsert
+type CustomerUpsertReport struct {
+	CustomerUpsertSummary
+	Results []CustomerUpsertResult `json:"results"`
+}
+
+// upsertFailure rejects one record of a bulk upsert
+type upsertFailure struct {
+	code    string
+	message string
+}
+
+func (f *upsertFailure) Error() string {
+	return f.message
+}
+
+// BulkUpsertCustomers creates or updates up to 500 customers. Records match
+// an existing customer by external_id, then by case-insensitive email;
+// matches get only the fields present in the record, as with a merge patch,
+// and the rest are created. Records are written in transactions of 100,
+// each record under its own savepoint so a rejected record does not undo
+// the others. Creations rely on the unique indexes with ON CONFLICT, so
+// concurrent upserts of the same customer update it rather than duplicate
+// it. One summary audit entry is written per transaction instead of one per
+// record. Each record still raises its events, as part of the bulk
+// operation, which raises bulk.completed at the end.
+// POST /admin/customers/bulk-upsert
+func (h *CustomerHandler) BulkUpsertCustomers(c *gin.Context) {
+	var req CustomerUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if len(req.Records) == 0 || len(req.Records) > maxUpsertRecords {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "TOO_MANY_RECORDS",
+			"message": i18n.Message(c, "TOO_MANY_RECORDS", "Send between 1 and "+strconv.Itoa(maxUpsertRecords)+" records"),
+		})
+		return
+	}
+
+	report := CustomerUpsertReport{Results: make([]CustomerUpsertResult, 0, len(req.Records))}
+	finish := beginBulkOperation(c, h.db, models.BulkOperationCustomerUpsert, models.AnnotationTypeImport, "Customer bulk upsert of "+strconv.Itoa(len(req.Records))+" records")
+	for start := 0; start < len(req.Records); start += importBatchSize {
+		end := min(start+importBatchSize, len(req.Records))
+
+		var batch []CustomerUpsertResult
+		err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			batch = batch[:0]
+			for i := start; i < end; i++ {
+				result := CustomerUpsertResult{Index: i}
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					return h.upsertCustomer(c, tx, req.Records[i], &result)
+				})
+				var failure *upsertFailure
+				switch {
+				case errors.As(err, &failure):
+					result.Status = UpsertError
+					result.Code = failure.code
+					result.Message = i18n.Message(c, failure.code, failure.message)
+				case err != nil:
+					return err
+				}
+				batch = append(batch, result)
+			}
+
+			// Log audit
+			summary := summarizeUpserts(batch)
+			if summary.Created+summary.Updated == 0 {
+				return nil
+			}
+			return logAudit(c, tx, "customer", 0, models.AuditActionBulkUpsert, nil, &summary)
+		})
+		if err != nil {
+			batch = batch[:0]
+			for i := start; i < end; i++ {
+				batch = append(batch, CustomerUpsertResult{
+					Index:   i,
+					Status:  UpsertError,
+					Code:    "DATABASE_ERROR",
+					Message: i18n.Message(c, "DATABASE_ERROR", "Failed to upsert customer"),
+				})
+			}
+		}
+		report.Results = append(report.Results, batch...)
+	}
+
+	report.CustomerUpsertSummary = summarizeUpserts(report.Results)
+	finish(models.BulkOperationSummary{
+		ResourceType: "customer",
+		Total:        report.Total,
+		Created:      report.Created,
+		Updated:      report.Updated,
+		Skipped:      report.Unchanged,
+		Failed:       report.Failed,
+	}, "Created "+strconv.Itoa(report.Created)+", updated "+strconv.Itoa(report.Updated)+
+		", unchanged "+strconv.Itoa(report.Unchanged)+", failed "+strconv.Itoa(report.Failed))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// summarizeUpserts totals the outcomes of upsert results
+func summarizeUpserts(results []CustomerUpsertResult) CustomerUpsertSummary {
+	summary := CustomerUpsertSummary{Total: len(results)}
+	for _, result := range results {
+		switch result.Status {
+		case UpsertCreated:
+			summary.Created++
+		case UpsertUpdated:
+			summary.Updated++
+		case UpsertUnchanged:
+			summary.Unchanged++
+		default:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// upsertCustomer creates or updates the customer of one record, filling in
+// its result. A rejected record returns an *upsertFailure.
+func (h *CustomerHandler) upsertCustomer(c *gin.Context, tx *gorm.DB, raw json.RawMessage, result *CustomerUpsertResult) error {
+	var patch map[string]json.RawMessage
+	if trimmed := strings.TrimSpace(string(raw)); !strings.HasPrefix(trimmed, "{") || json.Unmarshal(raw, &patch) != nil {
+		return &upsertFailure{"INVALID_MERGE_PATCH", "Each record must be a JSON object"}
+	}
+
+	externalID, email, err := upsertKeys(patch)
+	if err != nil {
+		return err
+	}
+	result.ExternalID, result.Email = externalID, email
+
+	customer, err := findUpsertMatch(tx, externalID, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created, err := h.createUpserted(c, tx, patch)
+		if err != nil {
+			return err
+		}
+		if created != nil {
+			result.Status = UpsertCreated
+			result.ID = created.ID
+			return nil
+		}
+
+		// A concurrent upsert created the customer first; update it instead
+		customer, err = findUpsertMatch(tx, externalID, email)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &upsertFailure{"EMAIL_EXISTS", "A deleted customer holds this email or external_id"}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	result.ID = customer.ID
+	changed, err := h.updateUpserted(c, tx, customer, patch)
+	if err != nil {
+		return err
+	}
+	result.Status = UpsertUnchanged
+	if changed {
+		result.Status = UpsertUpdated
+	}
+	return nil
+}
+
+// upsertKeys reads the match keys of a record, normalizing them in the
+// patch: external_id is trimmed and email is trimmed and lowercased
+func upsertKeys(patch map[string]json.RawMessage) (string, string, error) {
+	keys := make(map[string]string, 2)
+	for _, field := range []string{"external_id", "email"} {
+		raw, ok := patch[field]
+		if !ok || isJSONNull(raw) {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return "", "", &upsertFailure{"INVALID_REQUEST", "Invalid value for field " + field}
+		}
+		value = strings.TrimSpace(value)
+		if field == "email" {
+			value = strings.ToLower(value)
+		}
+		keys[field] = value
+		patch[field], _ = json.Marshal(value)
+	}
+
+	if len(keys["external_id"]) > 100 {
+		return "", "", &upsertFailure{"INVALID_REQUEST", "external_id must be at most 100 characters"}
+	}
+	if keys["external_id"] == "" && keys["email"] == "" {
+		return "", "", &upsertFailure{"MATCH_KEY_REQUIRED", "Each record needs an external_id or an email"}
+	}
+	return keys["external_id"], keys["email"], nil
+}
+
+// findUpsertMatch locks the live customer with the external ID, or else
+// with the email regardless of case
+func findUpsertMatch(tx *gorm.DB, externalID, email string) (models.Customer, error) {
+	var customer models.Customer
+	err := gorm.ErrRecordNotFound
+	if externalID != "" {
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("external_id = ?", externalID).
+			First(&customer).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) && email != "" {
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("LOWER(email) = ?", email).
+			First(&customer).Error
+		if err == nil && externalID != "" && customer.ExternalID != "" {
+			return customer, &upsertFailure{"EXTERNAL_ID_CONFLICT", "The customer with this email has a different external_id"}
+		}
+	}
+	return customer, err
+}
+
+// createUpserted creates the customer of an unmatched record, assigning
+// leads as CreateCustomer does. It returns nil when a customer with the
+// same email or external ID was created concurrently.
+func (h *CustomerHandler) createUpserted(c *gin.Context, tx *gorm.DB, patch map[string]json.RawMessage) (*models.Customer, error) {
+	customer := models.Customer{Status: models.CustomerStatusLead}
+	if _, patchErr := mergePatch(&customer, patch, customerUpsertFields); patchErr != nil {
+		return nil, &upsertFailure{patchErr.Code, patchErr.Message}
+	}
+	if err := validateUpserted(&customer, true); err != nil {
+		return nil, err
+	}
+	if errs := longTextErrors(&customer); len(errs) > 0 {
+		return nil, &upsertFailure{"TEXT_TOO_LONG", errs[0]}
+	}
+	customer.EmailDomain = h.domains.Domain(customer.Email)
+
+	if h.quotas != nil && !sandbox.Active(c) {
+		if err := h.quotas.Check(quota.Customers, 1); err != nil {
+			return nil, &upsertFailure{"QUOTA_EXCEEDED", "Record quota exceeded"}
+		}
+	}
+
+	if customer.AssignedTo == nil && customer.Status == models.CustomerStatusLead {
+		assignedTo, err := assignment.AssignLead(tx, time.Now())
+		if err != nil && !errors.Is(err, assignment.ErrNoAvailableRep) {
+			return nil, err
+		}
+		customer.AssignedTo = assignedTo
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&customer)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	publishChange(c, tx, "customer", customer.ID, models.AuditActionCreate, nil, &customer)
+	return &customer, nil
+}
+
+// updateUpserted applies a record to its matched customer as a merge patch,
+// checking it as mergePatchCustomer does. It reports whether anything changed.
+func (h *CustomerHandler) updateUpserted(c *gin.Context, tx *gorm.DB, customer models.Customer, patch map[string]json.RawMessage) (bool, error) {
+	// The match already covers the email; only a different address changes it
+	var email string
+	if raw, ok := patch["email"]; ok && json.Unmarshal(raw, &email) == nil && strings.EqualFold(email, customer.Email) {
+		delete(patch, "email")
+	}
+
+	oldCustomer := customer
+	changed, patchErr := mergePatch(&customer, patch, customerUpsertFields)
+	if patchErr != nil {
+		return false, &upsertFailure{patchErr.Code, patchErr.Message}
+	}
+	if len(changed) == 0 {
+		return false, nil
+	}
+
+	switch {
+	case oldCustomer.ArchivedAt != nil:
+		return false, &upsertFailure{"ARCHIVED", "Archived records must be unarchived before they can be changed"}
+	case oldCustomer.AnonymizedAt != nil:
+		return false, &upsertFailure{"ANONYMIZED", "Anonymized customers cannot be changed"}
+	}
+
+	// Enforce field-level edit permissions
+	user, _ := middleware.GetUserFromContext(c)
+	isOwner := oldCustomer.AssignedTo == nil || *oldCustomer.AssignedTo == user.ID
+	if len(models.ForbiddenFields(models.EntityCustomer, user.Role, isOwner, changed)) > 0 {
+		return false, &upsertFailure{"FIELD_EDIT_FORBIDDEN", "You do not have permission to edit these fields"}
+	}
+	if models.IsReverseConversion(oldCustomer.Status, customer.Status) && !models.CanManageAll(user.Role) {
+		return false, &upsertFailure{"REVERSE_CONVERSION_FORBIDDEN", "Only managers can move a converted customer back to lead or prospect"}
+	}
+
+	if err := validateUpserted(&customer, patchTouched(changed, "email")); err != nil {
+		return false, err
+	}
+	if errs := longTextErrors(&customer); len(errs) > 0 && patchTouched(changed, "notes") {
+		return false, &upsertFailure{"TEXT_TOO_LONG", errs[0]}
+	}
+	changed = append(changed, trackConversion(c, &customer, oldCustomer.Status)...)
+	if patchTouched(changed, "email") {
+		customer.EmailDomain = h.domains.Domain(customer.Email)
+		customer.EmailInvalidAt = nil
+		changed = append(changed, "email_domain", "email_invalid_at")
+
+		var existing models.Customer
+		if err := tx.Where("LOWER(email) = ? AND id != ?", customer.Email, customer.ID).First(&existing).Error; err == nil {
+			return false, &upsertFailure{"EMAIL_EXISTS", "A customer with this email already exists"}
+		}
+	}
+
+	// Select writes cleared fields, which Updates would otherwise skip
+	if err := tx.Model(&customer).Select(changed).Updates(&customer).Error; err != nil {
+		return false, err
+	}
+	publishChange(c, tx, "customer", customer.ID, models.AuditActionUpdate, &oldCustomer, &customer)
+	return true, nil
+}
+
+// validateUpserted checks the fields of a patched customer, and its email
+// when it was set
+func validateUpserted(customer *models.Customer, checkEmail bool) error {
+	customer.Name = strings.TrimSpace(customer.Name)
+	if customer.Name == "" || len(customer.Name) > 255 {
+		return &upsertFailure{"INVALID_REQUEST", "name must be between 1 and 255 characters"}
+	}
+	if !models.IsValidCustomerStatus(customer.Status) {
+		return &upsertFailure{"INVALID_STATUS", "Invalid status"}
+	}
+	if checkEmail && !isValidEmail(customer.Email) {
+		return &upsertFailure{"INVALID_EMAIL", "Invalid email format"}
+	}
+	return nil
+}
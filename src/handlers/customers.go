@@ -1,27 +1,50 @@
 package handlers
 
 import (
-	"math"
+	"errors"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/assignment"
+	"github.com/SalehAlobaylan/CRM-Service/src/companies"
+	"github.com/SalehAlobaylan/CRM-Service/src/deletion"
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/preview"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // CustomerHandler handles customer-related endpoints
 type CustomerHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	prefetch *query.Prefetcher
+	domains  *companies.Domains
+	deletion *deletion.Runner
+	quotas   *quota.Tracker
+	timeline int // Preview length of the recent activities on customer details
 }
 
-// NewCustomerHandler creates a new CustomerHandler
-func NewCustomerHandler(db *gorm.DB) *CustomerHandler {
-	return &CustomerHandler{db: db}
+// NewCustomerHandler creates a new CustomerHandler. prefetch may be nil to
+// disable list page caching. The recent activities of customer details are
+// previewed with timelinePreview characters, 0 for in full.
+func NewCustomerHandler(db *gorm.DB, prefetch *query.Prefetcher, domains *companies.Domains, deletions *deletion.Runner, quotas *quota.Tracker, timelinePreview int) *CustomerHandler {
+	return &CustomerHandler{db: db, prefetch: prefetch, domains: domains, deletion: deletions, quotas: quotas, timeline: timelinePreview}
+}
+
+// CustomerDeletionResponse describes a customer whose deletion is still
+// running in the background
+type CustomerDeletionResponse struct {
+	Customer models.Customer         `json:"customer"`
+	Deletion models.CustomerDeletion `json:"deletion"`
 }
 
 // CustomerCreateRequest represents the request body for creating a customer
@@ -49,6 +72,7 @@ type CustomerUpdateRequest struct {
 	Contacted      *bool               `json:"contacted,omitempty"`
 	Notes          string              `json:"notes,omitempty"`
 	NextFollowUpAt *time.Time          `json:"next_follow_up_at,omitempty"`
+	ChangeReason   string              `json:"change_reason,omitempty"` // Also accepted as the X-Change-Reason header
 }
 
 // CustomerPatchRequest represents the request body for patching a customer
@@ -57,90 +81,134 @@ type CustomerPatchRequest struct {
 	AssignedTo     *uint                  `json:"assigned_to,omitempty"`
 	Contacted      *bool                  `json:"contacted,omitempty"`
 	NextFollowUpAt *time.Time             `json:"next_follow_up_at,omitempty"`
+	ChangeReason   string                 `json:"change_reason,omitempty"`
+}
+
+// customerListQuery defines the filters and sorting of ListCustomers
+var customerListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("status", "status"),
+		query.Equal("assigned_to", "assigned_to"),
+		query.Equal("domain", "email_domain"),
+		query.Equal("external_id", "external_id"),
+		query.FoldedSearch("search", "customers.search_text"),
+		query.AtLeast("created_from", "created_at", query.KindTime),
+		query.AtMost("created_to", "created_at", query.KindTime),
+		query.AnyOf("tags", "customer_tags.tag_id IN ?", "JOIN customer_tags ON customer_tags.customer_id = customers.id"),
+		query.AnyOf("tag_group", "customers.id IN ("+customersInTagGroups+")", ""),
+		query.Condition("claimable", "customers.assigned_to IS NULL AND customers.anonymized_at IS NULL"),
+	},
+	Sort: query.Sort{
+		Fields:       []string{"created_at", "updated_at", "name", "email", "status"},
+		DefaultField: "created_at",
+		DefaultOrder: "desc",
+		Collated:     []string{"name"},
+	},
 }
 
-// ListCustomers returns a paginated list of customers with filtering
+// ListCustomers returns a paginated list of customers with filtering. With
+// prefetch=true the next page is primed so following next_page_token is
+// served from cache. With cursor, the page after that cursor is listed in
+// the same sort instead, for paging deep into large lists.
 // GET /admin/customers
 func (h *CustomerHandler) ListCustomers(c *gin.Context) {
-	// Pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
+	values := query.Values(c.Request.URL.Query())
+	page := query.ParsePage(values)
 
-	// Build query
-	query := h.db.Model(&models.Customer{})
-
-	// Apply filters
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
+	db := middleware.GetReadDB(c, h.db).Model(&models.Customer{})
+	if values.Get("include_archived") != "true" {
+		db = db.Scopes(models.NotArchived("customers"))
 	}
-	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
-		query = query.Where("assigned_to = ?", assignedTo)
+	db, filters := customerListQuery.Filter(db, values)
+
+	cursor, err := customerListQuery.ParseCursor(c.Query("cursor"), values)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
 	}
-	if search := c.Query("search"); search != "" {
-		searchTerm := "%" + strings.ToLower(search) + "%"
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ? OR LOWER(company) LIKE ?",
-			searchTerm, searchTerm, searchTerm)
+	if cursor != nil {
+		h.listCustomersAfter(c, db, values, *cursor, page, filters)
+		return
 	}
-	if createdFrom := c.Query("created_from"); createdFrom != "" {
-		if t, err := time.Parse(time.RFC3339, createdFrom); err == nil {
-			query = query.Where("created_at >= ?", t)
-		}
+
+	customers, total, err := query.FindPage[models.Customer](c, h.prefetch, query.PageRequest{
+		Query:    db.Preload("Tags"),
+		Table:    "customers",
+		Depends:  []string{"tags", "customer_tags", "tag_groups"},
+		Scope:    prefetchScope(c),
+		Values:   values,
+		Page:     page,
+		Order:    customerListQuery.KeysetOrder("customers", values),
+		Prefetch: c.Query("prefetch") == "true" && flags.Enabled(c, flags.ListPrefetch),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customers"),
+		})
+		return
 	}
-	if createdTo := c.Query("created_to"); createdTo != "" {
-		if t, err := time.Parse(time.RFC3339, createdTo); err == nil {
-			query = query.Where("created_at <= ?", t)
+
+	// Offset pages hand over to cursor paging from their last row
+	var nextCursor string
+	if page.Page < page.TotalPages(total) && len(customers) > 0 {
+		if nextCursor, err = customerListQuery.NextCursor(c, db, values, &customers[len(customers)-1]); err != nil {
+			respondListFailed(c, "Failed to fetch customers")
+			return
 		}
 	}
-	if tagIDs := c.Query("tags"); tagIDs != "" {
-		ids := strings.Split(tagIDs, ",")
-		query = query.Joins("JOIN customer_tags ON customer_tags.customer_id = customers.id").
-			Where("customer_tags.tag_id IN ?", ids)
-	}
 
-	// Sorting
-	sortBy := c.DefaultQuery("sort_by", "created_at")
-	sortOrder := c.DefaultQuery("sort_order", "desc")
-	if sortOrder != "asc" && sortOrder != "desc" {
-		sortOrder = "desc"
-	}
-	allowedSortFields := map[string]bool{
-		"created_at": true, "updated_at": true, "name": true, "email": true, "status": true,
-	}
-	if !allowedSortFields[sortBy] {
-		sortBy = "created_at"
-	}
-	query = query.Order(sortBy + " " + sortOrder)
+	next := query.NextPageToken(values, page, total)
+	setPageHeaders(c, total, next)
+	c.JSON(http.StatusOK, models.CustomerListResponse{
+		Data:          customers,
+		Total:         total,
+		Page:          page.Page,
+		PageSize:      page.PageSize,
+		TotalPages:    page.TotalPages(total),
+		NextPageToken: next,
+		NextCursor:    nextCursor,
+		Filters:       filters,
+	})
+}
 
-	// Get total count
+// listCustomersAfter responds with the page of customers after cursor.
+// Pages are not numbered in cursor mode, so page is left 0; total still
+// counts all matching customers.
+func (h *CustomerHandler) listCustomersAfter(c *gin.Context, db *gorm.DB, values url.Values, cursor query.Cursor, page query.Page, filters map[string]string) {
 	var total int64
-	query.Count(&total)
+	if err := db.WithContext(c).Count(&total).Error; err != nil {
+		respondListFailed(c, "Failed to fetch customers")
+		return
+	}
 
-	// Apply pagination
-	offset := (page - 1) * pageSize
-	var customers []models.Customer
-	if err := query.Preload("Tags").Offset(offset).Limit(pageSize).Find(&customers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customers",
-		})
+	customers, more, err := query.FindAfter[models.Customer](db.WithContext(c).Preload("Tags"), customerListQuery, "customers", values, cursor, page.PageSize)
+	if errors.Is(err, query.ErrInvalidCursor) {
+		respondInvalidCursor(c)
+		return
+	}
+	if err != nil {
+		respondListFailed(c, "Failed to fetch customers")
 		return
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	var nextCursor string
+	if more {
+		if nextCursor, err = customerListQuery.NextCursor(c, db, values, &customers[len(customers)-1]); err != nil {
+			respondListFailed(c, "Failed to fetch customers")
+			return
+		}
+	}
 
+	setPageHeaders(c, total, nextCursor)
 	c.JSON(http.StatusOK, models.CustomerListResponse{
 		Data:       customers,
 		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+		NextCursor: nextCursor,
+		Filters:    filters,
 	})
 }
 
@@ -152,7 +220,7 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
@@ -162,18 +230,18 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_EMAIL",
-			"message": "Invalid email format",
+			"message": i18n.Message(c, "INVALID_EMAIL", "Invalid email format"),
 		})
 		return
 	}
 
 	// Check email uniqueness
 	var existing models.Customer
-	if err := h.db.Where("email = ?", req.Email).First(&existing).Error; err == nil {
+	if err := h.db.WithContext(c).Where("email = ?", req.Email).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{
 			"error":   "conflict",
 			"code":    "EMAIL_EXISTS",
-			"message": "A customer with this email already exists",
+			"message": i18n.Message(c, "EMAIL_EXISTS", "A customer with this email already exists"),
 		})
 		return
 	}
@@ -187,6 +255,7 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 	customer := models.Customer{
 		Name:           req.Name,
 		Email:          req.Email,
+		EmailDomain:    h.domains.Domain(req.Email),
 		Phone:          req.Phone,
 		Company:        req.Company,
 		Role:           req.Role,
@@ -195,19 +264,37 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		Notes:          req.Notes,
 		NextFollowUpAt: req.NextFollowUpAt,
 	}
+	if !checkLongText(c, &customer, nil, "") {
+		return
+	}
 
-	if err := h.db.Create(&customer).Error; err != nil {
+	// Unassigned leads are assigned by the active assignment rule in the same
+	// transaction, so concurrent creations see each other's assignments
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if customer.AssignedTo == nil && customer.Status == models.CustomerStatusLead {
+			assignedTo, err := assignment.AssignLead(tx, time.Now())
+			if err != nil && !errors.Is(err, assignment.ErrNoAvailableRep) {
+				return err
+			}
+			customer.AssignedTo = assignedTo
+		}
+		if err := tx.Create(&customer).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "customer", customer.ID, models.AuditActionCreate, nil, &customer)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to create customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create customer"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "customer", customer.ID, models.AuditActionCreate, nil, &customer)
-
 	c.JSON(http.StatusCreated, customer)
 }
 
@@ -219,44 +306,48 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
 
 	var customer models.Customer
-	if err := h.db.Preload("Tags").First(&customer, id).Error; err != nil {
+	if err := h.db.WithContext(c).Preload("Tags").First(&customer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			if h.pendingDeletion(c, uint(id)) {
+				return
+			}
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
 	// Get related counts
 	var contactsCount int64
-	h.db.Model(&models.Contact{}).Where("customer_id = ?", id).Count(&contactsCount)
+	h.db.WithContext(c).Model(&models.Contact{}).Where("customer_id = ?", id).Count(&contactsCount)
 
 	var openDealsCount int64
-	h.db.Model(&models.Deal{}).Where("customer_id = ? AND stage NOT IN ?", id,
+	h.db.WithContext(c).Model(&models.Deal{}).Where("customer_id = ? AND stage NOT IN ?", id,
 		[]string{string(models.DealStageClosedWon), string(models.DealStageClosedLost)}).Count(&openDealsCount)
 
 	var upcomingActivitiesCount int64
-	h.db.Model(&models.Activity{}).Where("customer_id = ? AND status = ? AND due_date > ?",
+	h.db.WithContext(c).Model(&models.Activity{}).Where("customer_id = ? AND status = ? AND due_date > ?",
 		id, models.ActivityStatusScheduled, time.Now()).Count(&upcomingActivitiesCount)
 
 	// Get recent activities
 	var recentActivities []models.Activity
-	h.db.Where("customer_id = ?", id).Order("created_at DESC").Limit(5).Find(&recentActivities)
+	h.db.WithContext(c).Where("customer_id = ?", id).Order("created_at DESC").Limit(5).Find(&recentActivities)
+	preview.Mark(recentActivities, h.timeline)
 
 	response := models.CustomerDetailResponse{
 		Customer:                customer,
@@ -266,6 +357,15 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 		RecentActivities:        recentActivities,
 	}
 
+	// Get other customers at the same company domain
+	if customer.EmailDomain != "" {
+		domainMates := h.db.WithContext(c).Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
+			Where("email_domain = ? AND id <> ?", customer.EmailDomain, customer.ID).
+			Session(&gorm.Session{})
+		domainMates.Count(&response.DomainMatesCount)
+		domainMates.Select("id, name, email, company").Order("name ASC").Limit(5).Scan(&response.DomainMates)
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -277,29 +377,33 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
 
 	var customer models.Customer
-	if err := h.db.First(&customer, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
+	if rejectArchived(c, customer.ArchivedAt) || rejectAnonymized(c, customer.AnonymizedAt) {
+		return
+	}
+
 	oldCustomer := customer
 
 	var req CustomerUpdateRequest
@@ -307,32 +411,46 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
+	// Enforce field-level edit permissions and the change reason policy
+	changed := customerUpdateChangedFields(req, customer)
+	if !enforceFieldPermissions(c, models.EntityCustomer, customer.AssignedTo, changed) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityCustomer, false, changed, req.ChangeReason) {
+		return
+	}
+	if req.Status != "" && !checkStatusTransition(c, customer.Status, req.Status) {
+		return
+	}
+
 	// If email is being changed, check uniqueness
 	if req.Email != "" && req.Email != customer.Email {
 		if !isValidEmail(req.Email) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "validation_error",
 				"code":    "INVALID_EMAIL",
-				"message": "Invalid email format",
+				"message": i18n.Message(c, "INVALID_EMAIL", "Invalid email format"),
 			})
 			return
 		}
 
 		var existing models.Customer
-		if err := h.db.Where("email = ? AND id != ?", req.Email, id).First(&existing).Error; err == nil {
+		if err := h.db.WithContext(c).Where("email = ? AND id != ?", req.Email, id).First(&existing).Error; err == nil {
 			c.JSON(http.StatusConflict, gin.H{
 				"error":   "conflict",
 				"code":    "EMAIL_EXISTS",
-				"message": "A customer with this email already exists",
+				"message": i18n.Message(c, "EMAIL_EXISTS", "A customer with this email already exists"),
 			})
 			return
 		}
+		if !strings.EqualFold(customer.Email, req.Email) {
+			customer.EmailInvalidAt = nil
+		}
 		customer.Email = req.Email
+		customer.EmailDomain = h.domains.Domain(req.Email)
 	}
 
 	// Update fields
@@ -350,6 +468,7 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 	}
 	if req.Status != "" {
 		customer.Status = req.Status
+		trackConversion(c, &customer, oldCustomer.Status)
 	}
 	if req.AssignedTo != nil {
 		customer.AssignedTo = req.AssignedTo
@@ -363,19 +482,28 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 	if req.NextFollowUpAt != nil {
 		customer.NextFollowUpAt = req.NextFollowUpAt
 	}
+	if !checkLongText(c, &customer, &oldCustomer, "") {
+		return
+	}
 
-	if err := h.db.Save(&customer).Error; err != nil {
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&customer).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "customer", customer.ID, models.AuditActionUpdate, &oldCustomer, &customer)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update customer"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "customer", customer.ID, models.AuditActionUpdate, &oldCustomer, &customer)
-
 	c.JSON(http.StatusOK, customer)
 }
 
@@ -387,29 +515,38 @@ func (h *CustomerHandler) PatchCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
 
 	var customer models.Customer
-	if err := h.db.First(&customer, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
+	if rejectArchived(c, customer.ArchivedAt) || rejectAnonymized(c, customer.AnonymizedAt) {
+		return
+	}
+
+	if isMergePatch(c) {
+		h.mergePatchCustomer(c, customer)
+		return
+	}
+
 	oldCustomer := customer
 
 	var req CustomerPatchRequest
@@ -417,15 +554,31 @@ func (h *CustomerHandler) PatchCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
+	// Enforce field-level edit permissions and the change reason policy
+	changed := customerPatchChangedFields(req, customer)
+	if !enforceFieldPermissions(c, models.EntityCustomer, customer.AssignedTo, changed) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityCustomer, false, changed, req.ChangeReason) {
+		return
+	}
+	if req.Status != nil && !checkStatusTransition(c, customer.Status, *req.Status) {
+		return
+	}
+
 	// Apply patch updates
 	updates := make(map[string]interface{})
 	if req.Status != nil {
 		updates["status"] = *req.Status
+		patched := customer
+		patched.Status = *req.Status
+		if len(trackConversion(c, &patched, customer.Status)) > 0 {
+			updates["converted_at"] = patched.ConvertedAt
+			updates["converted_by"] = patched.ConvertedBy
+		}
 	}
 	if req.AssignedTo != nil {
 		updates["assigned_to"] = *req.AssignedTo
@@ -441,25 +594,134 @@ func (h *CustomerHandler) PatchCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "NO_UPDATES",
-			"message": "No fields to update",
+			"message": i18n.Message(c, "NO_UPDATES", "No fields to update"),
 		})
 		return
 	}
 
-	if err := h.db.Model(&customer).Updates(updates).Error; err != nil {
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&customer).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Reload customer
+		if err := tx.First(&customer, id).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "customer", customer.ID, models.AuditActionUpdate, &oldCustomer, &customer)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update customer"),
 		})
 		return
 	}
 
-	// Reload customer
-	h.db.First(&customer, id)
+	c.JSON(http.StatusOK, customer)
+}
+
+// mergePatchCustomer applies a JSON Merge Patch to a customer. Validation
+// runs on the patched customer, so clearing and setting fields in the same
+// request is checked as a whole.
+func (h *CustomerHandler) mergePatchCustomer(c *gin.Context, customer models.Customer) {
+	oldCustomer := customer
+
+	changed, ok := applyMergePatch(c, &customer, customerMergePatchFields)
+	if !ok {
+		return
+	}
+	if len(changed) == 0 {
+		c.JSON(http.StatusOK, customer)
+		return
+	}
+
+	// Enforce field-level edit permissions and the change reason policy
+	if !enforceFieldPermissions(c, models.EntityCustomer, oldCustomer.AssignedTo, changed) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityCustomer, false, changed, c.GetString(mergePatchReasonKey)) {
+		return
+	}
+	if !checkStatusTransition(c, oldCustomer.Status, customer.Status) {
+		return
+	}
+
+	customer.Name = strings.TrimSpace(customer.Name)
+	if customer.Name == "" || len(customer.Name) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "name must be between 1 and 255 characters"),
+		})
+		return
+	}
+	if !models.IsValidCustomerStatus(customer.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_STATUS",
+			"message": i18n.Message(c, "INVALID_STATUS", "Invalid status"),
+		})
+		return
+	}
+	changed = append(changed, trackConversion(c, &customer, oldCustomer.Status)...)
+
+	// If email is being changed, check uniqueness
+	if patchTouched(changed, "email") {
+		customer.EmailDomain = h.domains.Domain(customer.Email)
+		changed = append(changed, "email_domain")
+		if !strings.EqualFold(customer.Email, oldCustomer.Email) {
+			customer.EmailInvalidAt = nil
+			changed = append(changed, "email_invalid_at")
+		}
+
+		if !isValidEmail(customer.Email) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_EMAIL",
+				"message": i18n.Message(c, "INVALID_EMAIL", "Invalid email format"),
+			})
+			return
+		}
+
+		var existing models.Customer
+		if err := h.db.WithContext(c).Where("email = ? AND id != ?", customer.Email, customer.ID).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "EMAIL_EXISTS",
+				"message": i18n.Message(c, "EMAIL_EXISTS", "A customer with this email already exists"),
+			})
+			return
+		}
+	}
+
+	if !checkLongText(c, &customer, &oldCustomer, "") {
+		return
+	}
 
-	// Log audit
-	h.logAudit(c, "customer", customer.ID, models.AuditActionUpdate, &oldCustomer, &customer)
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Select writes cleared fields, which Updates would otherwise skip
+		if err := tx.Model(&customer).Select(changed).Updates(&customer).Error; err != nil {
+			return err
+		}
+		// Reload customer
+		if err := tx.First(&customer, customer.ID).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "customer", customer.ID, models.AuditActionUpdate, &oldCustomer, &customer)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update customer"),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, customer)
 }
@@ -472,63 +734,195 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
 
 	var customer models.Customer
-	if err := h.db.First(&customer, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
-	// Soft delete
-	if err := h.db.Delete(&customer).Error; err != nil {
+	if rejectArchived(c, customer.ArchivedAt) {
+		return
+	}
+
+	// Soft delete along with contacts, deals, activities and notes; large
+	// customers are hidden now and finished in the background
+	user, _ := middleware.GetUserFromContext(c)
+	pending, summary, err := h.deletion.Delete(c, &customer, deletion.Requester{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserRole:  user.Role,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to delete customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete customer"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "customer", customer.ID, models.AuditActionDelete, &customer, nil)
+	if pending != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":  "Customer deletion started",
+			"deletion": pending,
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Customer deleted successfully",
+		"deleted": summary,
 	})
 }
 
-// logAudit creates an audit log entry
-func (h *CustomerHandler) logAudit(c *gin.Context, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) {
-	user, _ := middleware.GetUserFromContext(c)
+// pendingDeletion responds with the progress of a customer's background
+// deletion when an admin fetches it. Everyone else gets a 404, as for any
+// deleted customer.
+func (h *CustomerHandler) pendingDeletion(c *gin.Context, id uint) bool {
+	if user, _ := middleware.GetUserFromContext(c); !models.HoldsRole(user.Role, models.RoleAdmin) {
+		return false
+	}
+
+	var response CustomerDeletionResponse
+	err := h.db.WithContext(c).
+		Where("customer_id = ? AND status <> ?", id, models.CustomerDeletionCompleted).
+		First(&response.Deletion).Error
+	if err != nil {
+		return false
+	}
+	if err := h.db.WithContext(c).Unscoped().First(&response.Customer, id).Error; err != nil {
+		return false
+	}
+
+	c.JSON(http.StatusOK, response)
+	return true
+}
+
+// ArchiveCustomer archives a customer, hiding it from default lists and reports
+// POST /admin/customers/:id/archive
+func (h *CustomerHandler) ArchiveCustomer(c *gin.Context) {
+	customer, ok := h.findCustomer(c)
+	if !ok {
+		return
+	}
+
+	if rejectArchived(c, customer.ArchivedAt) {
+		return
+	}
+
+	oldCustomer := *customer
+	now := time.Now()
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(customer).Update("archived_at", now).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "customer", customer.ID, models.AuditActionArchive, &oldCustomer, customer)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to archive customer"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// UnarchiveCustomer restores an archived customer
+// POST /admin/customers/:id/unarchive
+func (h *CustomerHandler) UnarchiveCustomer(c *gin.Context) {
+	customer, ok := h.findCustomer(c)
+	if !ok {
+		return
+	}
+
+	if customer.ArchivedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "NOT_ARCHIVED",
+			"message": i18n.Message(c, "NOT_ARCHIVED", "Customer is not archived"),
+		})
+		return
+	}
+
+	oldCustomer := *customer
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(customer).Update("archived_at", nil).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "customer", customer.ID, models.AuditActionUnarchive, &oldCustomer, customer)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to unarchive customer"),
+		})
+		return
+	}
 
-	audit := models.AuditLog{
-		ResourceType: resourceType,
-		ResourceID:   resourceID,
-		Action:       action,
-		UserID:       user.ID,
-		UserName:     user.Name,
-		UserRole:     user.Role,
-		IPAddress:    c.ClientIP(),
-		UserAgent:    c.Request.UserAgent(),
+	c.JSON(http.StatusOK, customer)
+}
+
+// findCustomer loads the customer identified by the :id route parameter,
+// writing the error response when it cannot be found
+func (h *CustomerHandler) findCustomer(c *gin.Context) (*models.Customer, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
+		})
+		return nil, false
 	}
 
-	h.db.Create(&audit)
+	var customer models.Customer
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CUSTOMER_NOT_FOUND",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
+		})
+		return nil, false
+	}
+
+	return &customer, true
 }
 
 // isValidEmail validates email format
@@ -536,3 +930,10 @@ func isValidEmail(email string) bool {
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	return emailRegex.MatchString(email)
 }
+
+// prefetchScope partitions cached list pages per user. Service accounts
+// share user ID 0 and are told apart by name.
+func prefetchScope(c *gin.Context) string {
+	user, _ := middleware.GetUserFromContext(c)
+	return strconv.FormatUint(uint64(user.ID), 10) + ":" + user.Name
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxDashboardWidgets caps the number of widgets on one dashboard
+const maxDashboardWidgets = 20
+
+// Error codes reported for dashboard widgets that cannot be resolved
+const (
+	widgetErrorUnknown       = "UNKNOWN_WIDGET"
+	widgetErrorInvalidParams = "INVALID_WIDGET_PARAMS"
+	widgetErrorFailed        = "WIDGET_FAILED"
+)
+
+// widgetParam describes an integer widget parameter
+type widgetParam struct {
+	Min     int
+	Max     int
+	Default int
+}
+
+// dashboardWidgetSpec is a widget catalog entry: its parameters and the
+// report function resolving its data
+type dashboardWidgetSpec struct {
+	Params  map[string]widgetParam
+	Resolve func(r *ReportHandler, c *gin.Context, user models.User, params map[string]int) (interface{}, error)
+}
+
+// limitParam is the row limit shared by list widgets
+var limitParam = widgetParam{Min: 1, Max: 50, Default: 5}
+
+// dashboardWidgetCatalog lists the widgets a dashboard may contain. Removing
+// an entry leaves stored dashboards usable: the widget resolves to an
+// UNKNOWN_WIDGET error and the rest of the dashboard still loads.
+var dashboardWidgetCatalog = map[string]dashboardWidgetSpec{
+	"customer_stats": {
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, _ map[string]int) (interface{}, error) {
+			return r.getCustomerStats(c)
+		},
+	},
+	"deal_stats": {
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, _ map[string]int) (interface{}, error) {
+			return r.getDealStats(c)
+		},
+	},
+	"activity_stats": {
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, _ map[string]int) (interface{}, error) {
+			return r.getActivityStats(c)
+		},
+	},
+	"top_customers": {
+		Params: map[string]widgetParam{"limit": limitParam},
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, params map[string]int) (interface{}, error) {
+			return r.getTopCustomers(c, params["limit"])
+		},
+	},
+	"recent_deals": {
+		Params: map[string]widgetParam{"limit": limitParam},
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, params map[string]int) (interface{}, error) {
+			return r.getRecentDeals(c, params["limit"])
+		},
+	},
+	"team_funnel": {
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, _ map[string]int) (interface{}, error) {
+			return r.getPipelineByStage(c, nil), nil
+		},
+	},
+	"lead_conversion": {
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, _ map[string]int) (interface{}, error) {
+			return r.getConversionStats(c)
+		},
+	},
+	"my_pipeline": {
+		Resolve: func(r *ReportHandler, c *gin.Context, user models.User, _ map[string]int) (interface{}, error) {
+			return r.getPipelineByStage(c, &user.ID), nil
+		},
+	},
+	"my_activities": {
+		Params: map[string]widgetParam{"limit": {Min: 1, Max: 50, Default: 10}},
+		Resolve: func(r *ReportHandler, c *gin.Context, user models.User, params map[string]int) (interface{}, error) {
+			return r.getUserActivities(c, user.ID, params["limit"]), nil
+		},
+	},
+	"stuck_deals": {
+		Params: map[string]widgetParam{
+			"days":              {Min: 1, Max: 365, Default: 14},
+			"limit":             {Min: 1, Max: 50, Default: 10},
+			"checklist_blocked": {Min: 0, Max: 1, Default: 0}, // 1 for deals with unchecked required close checklist items
+		},
+		Resolve: func(r *ReportHandler, c *gin.Context, _ models.User, params map[string]int) (interface{}, error) {
+			return r.getStuckDeals(c, params["days"], params["limit"], params["checklist_blocked"] == 1), nil
+		},
+	},
+}
+
+// defaultDashboards is the layout shown to users who have not saved one
+var defaultDashboards = map[string][]models.DashboardWidget{
+	models.RoleAgent: {
+		{Type: "my_activities", Size: models.WidgetSizeLarge},
+		{Type: "my_pipeline", Size: models.WidgetSizeMedium},
+	},
+	models.RoleManager: {
+		{Type: "team_funnel", Size: models.WidgetSizeLarge},
+		{Type: "lead_conversion", Size: models.WidgetSizeSmall},
+		{Type: "stuck_deals", Size: models.WidgetSizeMedium},
+		{Type: "activity_stats", Size: models.WidgetSizeSmall},
+	},
+	models.RoleAdmin: {
+		{Type: "customer_stats", Size: models.WidgetSizeSmall},
+		{Type: "deal_stats", Size: models.WidgetSizeSmall},
+		{Type: "activity_stats", Size: models.WidgetSizeSmall},
+		{Type: "team_funnel", Size: models.WidgetSizeLarge},
+		{Type: "lead_conversion", Size: models.WidgetSizeSmall},
+	},
+}
+
+// DashboardHandler handles per-user dashboard endpoints
+type DashboardHandler struct {
+	db          *gorm.DB
+	reports     *ReportHandler
+	concurrency int
+}
+
+// NewDashboardHandler creates a new DashboardHandler. concurrency bounds how
+// many widgets are resolved in parallel.
+func NewDashboardHandler(db *gorm.DB, concurrency int) *DashboardHandler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	// Widgets take no report periods, so their reports need no calendar
+	return &DashboardHandler{db: db, reports: NewReportHandler(db, nil, 1), concurrency: concurrency}
+}
+
+// DashboardRequest represents the request body for saving a dashboard
+type DashboardRequest struct {
+	Widgets []models.DashboardWidget `json:"widgets" binding:"required"`
+}
+
+// GetMyDashboard returns the current user's dashboard layout, or the default
+// layout for their role when none has been saved
+// GET /admin/me/dashboard
+func (h *DashboardHandler) GetMyDashboard(c *gin.Context) {
+	dashboard, ok := h.loadDashboard(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// UpdateMyDashboard replaces the current user's dashboard layout
+// PUT /admin/me/dashboard
+func (h *DashboardHandler) UpdateMyDashboard(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "NO_USER_CONTEXT",
+			"message": i18n.Message(c, "NO_USER_CONTEXT", "User not found in context"),
+		})
+		return
+	}
+
+	var req DashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if len(req.Widgets) > maxDashboardWidgets {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "TOO_MANY_WIDGETS",
+			"message": i18n.Message(c, "TOO_MANY_WIDGETS", "A dashboard can have at most "+strconv.Itoa(maxDashboardWidgets)+" widgets"),
+		})
+		return
+	}
+
+	widgets := make([]models.DashboardWidget, len(req.Widgets))
+	for i, widget := range req.Widgets {
+		normalized, err := normalizeWidget(widget)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_WIDGET",
+				"message": i18n.Message(c, "INVALID_WIDGET", fmt.Sprintf("Widget %d: %s", i, err.Error())),
+				"widget":  i,
+			})
+			return
+		}
+		widgets[i] = normalized
+	}
+
+	dashboard := models.UserDashboard{UserID: user.ID, Widgets: widgets}
+	err := h.db.WithContext(c).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"widgets", "updated_at"}),
+	}).Create(&dashboard).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to save dashboard"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DashboardResponse{Widgets: widgets, UpdatedAt: &dashboard.UpdatedAt})
+}
+
+// GetMyDashboardData resolves the data of every widget on the current user's
+// dashboard in one call. Widgets that cannot be resolved are returned with an
+// error code instead of failing the whole dashboard.
+// GET /admin/me/dashboard/data
+func (h *DashboardHandler) GetMyDashboardData(c *gin.Context) {
+	dashboard, ok := h.loadDashboard(c)
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetUserFromContext(c)
+
+	results := make([]models.DashboardWidgetData, len(dashboard.Widgets))
+	sem := make(chan struct{}, h.concurrency)
+	var wg sync.WaitGroup
+	for i, widget := range dashboard.Widgets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = h.resolveWidget(c, user, widget)
+		}()
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       results,
+		"is_default": dashboard.IsDefault,
+	})
+}
+
+// resolveWidget loads one widget's data. Widgets removed from the catalog,
+// stored with parameters that are no longer valid, or whose report fails or
+// panics resolve to an error entry.
+func (h *DashboardHandler) resolveWidget(c *gin.Context, user models.User, widget models.DashboardWidget) (result models.DashboardWidgetData) {
+	result = models.DashboardWidgetData{Type: widget.Type, Size: widget.Size}
+
+	spec, ok := dashboardWidgetCatalog[widget.Type]
+	if !ok {
+		result.Error = widgetErrorUnknown
+		return result
+	}
+	params, err := widgetParams(spec, widget.Params)
+	if err != nil {
+		result.Error = widgetErrorInvalidParams
+		return result
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			middleware.Logger.Error(fmt.Sprintf("Dashboard widget %s panicked: %v", widget.Type, r))
+			result.Data = nil
+			result.Error = widgetErrorFailed
+		}
+	}()
+	data, err := spec.Resolve(h.reports, c, user, params)
+	if err != nil {
+		middleware.Logger.Error(fmt.Sprintf("Dashboard widget %s failed: %v", widget.Type, err))
+		result.Error = widgetErrorFailed
+		return result
+	}
+	result.Data = data
+	return result
+}
+
+// loadDashboard returns the current user's saved dashboard or the default
+// layout for their role, writing the error response on failure
+func (h *DashboardHandler) loadDashboard(c *gin.Context) (models.DashboardResponse, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "NO_USER_CONTEXT",
+			"message": i18n.Message(c, "NO_USER_CONTEXT", "User not found in context"),
+		})
+		return models.DashboardResponse{}, false
+	}
+
+	var dashboard models.UserDashboard
+	err := h.db.WithContext(c).Where("user_id = ?", user.ID).First(&dashboard).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		widgets := defaultDashboards[user.Role]
+		if widgets == nil {
+			widgets = defaultDashboards[models.RoleAgent]
+		}
+		return models.DashboardResponse{Widgets: widgets, IsDefault: true}, true
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch dashboard"),
+		})
+		return models.DashboardResponse{}, false
+	}
+
+	return models.DashboardResponse{Widgets: dashboard.Widgets, UpdatedAt: &dashboard.UpdatedAt}, true
+}
+
+// normalizeWidget validates a widget against the catalog, defaulting its size
+// and filling in omitted parameters
+func normalizeWidget(widget models.DashboardWidget) (models.DashboardWidget, error) {
+	spec, ok := dashboardWidgetCatalog[widget.Type]
+	if !ok {
+		return widget, fmt.Errorf("unknown widget type %q", widget.Type)
+	}
+	if widget.Size == "" {
+		widget.Size = models.WidgetSizeMedium
+	}
+	if !slices.Contains(models.ValidWidgetSizes, widget.Size) {
+		return widget, fmt.Errorf("invalid size %q", widget.Size)
+	}
+	params, err := widgetParams(spec, widget.Params)
+	if err != nil {
+		return widget, err
+	}
+	widget.Params = params
+	return widget, nil
+}
+
+// widgetParams checks parameters against a catalog entry and returns them
+// with defaults filled in
+func widgetParams(spec dashboardWidgetSpec, params map[string]int) (map[string]int, error) {
+	var unknown []string
+	for name := range params {
+		if _, ok := spec.Params[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameter %q", unknown[0])
+	}
+
+	if len(spec.Params) == 0 {
+		return nil, nil
+	}
+	resolved := make(map[string]int, len(spec.Params))
+	for name, param := range spec.Params {
+		value, ok := params[name]
+		if !ok {
+			value = param.Default
+		}
+		if value < param.Min || value > param.Max {
+			return nil, fmt.Errorf("%s must be between %d and %d", name, param.Min, param.Max)
+		}
+		resolved[name] = value
+	}
+	return resolved, nil
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DeadLetterHandler handles dead-letter queue endpoints
+type DeadLetterHandler struct {
+	db    *gorm.DB
+	queue *deadletter.Queue
+}
+
+// NewDeadLetterHandler creates a new DeadLetterHandler
+func NewDeadLetterHandler(db *gorm.DB, queue *deadletter.Queue) *DeadLetterHandler {
+	return &DeadLetterHandler{db: db, queue: queue}
+}
+
+// ListDeadLetters returns a paginated list of dead-lettered items
+// GET /admin/dead-letters
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := h.db.WithContext(c).Model(&models.DeadLetter{})
+
+	// Filters
+	if component := c.Query("component"); component != "" {
+		query = query.Where("component = ?", component)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if from := c.Query("from"); from != "" {
+		if t, err := time.Parse(time.RFC3339, from); err == nil {
+			query = query.Where("last_failed_at >= ?", t)
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if t, err := time.Parse(time.RFC3339, to); err == nil {
+			query = query.Where("last_failed_at <= ?", t)
+		}
+	}
+
+	// Count total
+	var total int64
+	query.Count(&total)
+
+	var letters []models.DeadLetter
+	offset := (page - 1) * pageSize
+	if err := query.Order("last_failed_at DESC").Offset(offset).Limit(pageSize).Find(&letters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch dead letters"),
+		})
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.DeadLetterListResponse{
+		Data:       letters,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	})
+}
+
+// RetryDeadLetter requeues a dead-lettered item through its component
+// POST /admin/dead-letters/:id/retry
+func (h *DeadLetterHandler) RetryDeadLetter(c *gin.Context) {
+	letter, ok := h.findDeadLetter(c)
+	if !ok {
+		return
+	}
+
+	if letter.Status == models.DeadLetterStatusRequeued {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "DEAD_LETTER_ALREADY_REQUEUED",
+			"message": i18n.Message(c, "DEAD_LETTER_ALREADY_REQUEUED", "Dead letter has already been requeued"),
+		})
+		return
+	}
+
+	oldLetter := *letter
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := h.queue.Retry(tx, letter); err != nil {
+			return err
+		}
+		return logAudit(c, tx, "dead_letter", letter.ID, models.AuditActionUpdate, &oldLetter, letter)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		if errors.Is(err, deadletter.ErrNoRetrier) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "validation_error",
+				"code":    "DEAD_LETTER_NO_RETRIER",
+				"message": i18n.Message(c, "DEAD_LETTER_NO_RETRIER", "No retry handler is registered for this component"),
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "retry_failed",
+			"code":    "DEAD_LETTER_RETRY_FAILED",
+			"message": i18n.Message(c, "DEAD_LETTER_RETRY_FAILED", "Failed to requeue dead letter"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, letter)
+}
+
+// DeleteDeadLetter discards a dead-lettered item
+// DELETE /admin/dead-letters/:id
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	letter, ok := h.findDeadLetter(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(letter).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "dead_letter", letter.ID, models.AuditActionDelete, letter, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete dead letter"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dead letter discarded successfully",
+	})
+}
+
+// findDeadLetter loads the dead letter identified by the :id route parameter,
+// writing the error response when it cannot be found
+func (h *DeadLetterHandler) findDeadLetter(c *gin.Context) (*models.DeadLetter, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid dead letter ID"),
+		})
+		return nil, false
+	}
+
+	var letter models.DeadLetter
+	if err := h.db.WithContext(c).First(&letter, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "DEAD_LETTER_NOT_FOUND",
+				"message": i18n.Message(c, "DEAD_LETTER_NOT_FOUND", "Dead letter not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch dead letter"),
+		})
+		return nil, false
+	}
+
+	return &letter, true
+}
@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Deals per stage returned by the deals board
+const (
+	defaultBoardLimit = 25
+	maxBoardLimit     = 100
+)
+
+// boardOrder orders the deals of a board stage: soonest expected close
+// first, undated deals last
+const boardOrder = "deals.expected_close_date ASC NULLS LAST, deals.id ASC"
+
+// DealPositionRequest represents a move of a deal on the board. The deal is
+// placed between prev_id (directly above) and next_id (directly below), or at
+// an explicit position. With neither, it goes to the bottom of the stage.
+type DealPositionRequest struct {
+	Stage        models.DealStage `json:"stage" binding:"required"`
+	PrevID       *uint            `json:"prev_id,omitempty"`
+	NextID       *uint            `json:"next_id,omitempty"`
+	Position     *float64         `json:"position,omitempty"`
+	LostReason   string           `json:"lost_reason,omitempty"`
+	NextStep     *string          `json:"next_step,omitempty" binding:"omitempty,max=255"`
+	NextStepDue  *time.Time       `json:"next_step_due,omitempty"`
+	ChangeReason string           `json:"change_reason,omitempty"`
+}
+
+// GetPipeline returns the deals board: open and closed deals grouped by
+// stage in their manual board order
+// GET /admin/deals/pipeline
+func (h *DealHandler) GetPipeline(c *gin.Context) {
+	query := h.db.WithContext(c).Model(&models.Deal{})
+	if !includeArchived(c) {
+		query = query.Scopes(models.NotArchived("deals"))
+	}
+	if ownerID := c.Query("owner_id"); ownerID != "" {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+
+	var deals []models.Deal
+	if err := query.Preload("Customer").Order("board_position ASC, id ASC").Find(&deals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deals"),
+		})
+		return
+	}
+
+	stages := h.boardStages(c)
+	columns := make([]models.PipelineColumn, 0, len(stages))
+	index := make(map[models.DealStage]int, len(stages))
+	for _, stage := range stages {
+		index[models.DealStage(stage.Name)] = len(columns)
+		columns = append(columns, models.PipelineColumn{
+			Stage:       models.DealStage(stage.Name),
+			DisplayName: stage.DisplayName,
+			Color:       stage.Color,
+			Deals:       []models.Deal{},
+		})
+	}
+	for _, deal := range deals {
+		i, ok := index[deal.Stage]
+		if !ok {
+			continue
+		}
+		columns[i].Deals = append(columns[i].Deals, deal)
+		columns[i].Count++
+		columns[i].TotalAmount += deal.Amount
+	}
+
+	c.JSON(http.StatusOK, models.PipelineViewResponse{Stages: columns})
+}
+
+// GetBoard returns the deals board in one response: for every active stage
+// its count and summed amount, and its first deals by expected close date,
+// soonest first and undated last. Each stage holds at most limit deals
+// (default 25, max 100) and a next_cursor when it has more; passing it as
+// cursor, with the same filters, returns the following deals of that stage
+// only. The ListDeals filters apply, and users who cannot manage all
+// records only see their own deals.
+// GET /admin/deals/board?limit=25&cursor=&owner_id=&customer_id=
+func (h *DealHandler) GetBoard(c *gin.Context) {
+	values := c.Request.URL.Query()
+	limit, err := strconv.Atoi(values.Get("limit"))
+	if err != nil || limit < 1 || limit > maxBoardLimit {
+		limit = defaultBoardLimit
+	}
+
+	var after *boardCursor
+	if token := values.Get("cursor"); token != "" {
+		cursor, err := decodeBoardCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_CURSOR",
+				"message": i18n.Message(c, "INVALID_CURSOR", "Invalid cursor"),
+			})
+			return
+		}
+		after = &cursor
+	}
+
+	db := middleware.GetReadDB(c, h.db).WithContext(c).Model(&models.Deal{})
+	if values.Get("include_archived") != "true" {
+		db = db.Scopes(models.NotArchived("deals"))
+	}
+	db, filters := dealListQuery.Filter(db, values)
+	if user, _ := middleware.GetUserFromContext(c); !models.CanManageAll(user.Role) {
+		db = db.Where("deals.owner_id = ?", user.ID)
+	}
+	if after != nil {
+		db = db.Where("deals.stage = ?", after.Stage)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var totals []struct {
+		Stage       models.DealStage
+		Count       int
+		TotalAmount float64
+	}
+	if err := db.Select("deals.stage, COUNT(*) AS count, COALESCE(SUM(deals.amount), 0) AS total_amount").
+		Group("deals.stage").Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to count deals"),
+		})
+		return
+	}
+
+	// One extra deal per stage tells whether the stage has more
+	page := db
+	if after != nil {
+		page = after.apply(page)
+	}
+	ranked := page.Select("deals.id, ROW_NUMBER() OVER (PARTITION BY deals.stage ORDER BY " + boardOrder + ") AS board_rank")
+	var deals []models.Deal
+	if err := middleware.GetReadDB(c, h.db).WithContext(c).Preload("Customer").
+		Where("deals.id IN (?)", h.db.Table("(?) AS ranked", ranked).Select("id").Where("board_rank <= ?", limit+1)).
+		Order(boardOrder).Find(&deals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deals"),
+		})
+		return
+	}
+
+	stages := h.boardStages(c)
+	columns := make([]models.DealBoardColumn, 0, len(stages))
+	index := make(map[models.DealStage]int, len(stages))
+	for _, stage := range stages {
+		if after != nil && models.DealStage(stage.Name) != after.Stage {
+			continue
+		}
+		index[models.DealStage(stage.Name)] = len(columns)
+		columns = append(columns, models.DealBoardColumn{PipelineColumn: models.PipelineColumn{
+			Stage:       models.DealStage(stage.Name),
+			DisplayName: stage.DisplayName,
+			Color:       stage.Color,
+			Deals:       []models.Deal{},
+		}})
+	}
+	for _, total := range totals {
+		if i, ok := index[total.Stage]; ok {
+			columns[i].Count = total.Count
+			columns[i].TotalAmount = total.TotalAmount
+		}
+	}
+	for _, deal := range deals {
+		i, ok := index[deal.Stage]
+		if !ok {
+			continue
+		}
+		column := &columns[i]
+		if len(column.Deals) == limit {
+			last := column.Deals[limit-1]
+			column.NextCursor = boardCursor{Stage: last.Stage, ExpectedCloseDate: last.ExpectedCloseDate, ID: last.ID}.encode()
+			continue
+		}
+		column.Deals = append(column.Deals, deal)
+	}
+
+	c.JSON(http.StatusOK, models.DealBoardResponse{Stages: columns, Filters: filters})
+}
+
+// boardStages returns the active pipeline stages in board order, or the
+// built-in stages when none are configured
+func (h *DealHandler) boardStages(c *gin.Context) []models.PipelineStage {
+	var stages []models.PipelineStage
+	h.db.WithContext(c).Where("is_active = ?", true).Order("\"order\" ASC").Find(&stages)
+	if len(stages) == 0 {
+		for i, stage := range models.DealStages() {
+			stages = append(stages, models.PipelineStage{Name: string(stage), DisplayName: string(stage), Order: i + 1})
+		}
+	}
+	return stages
+}
+
+// boardCursor is the keyset of the last deal returned for a board stage
+type boardCursor struct {
+	Stage             models.DealStage `json:"stage"`
+	ExpectedCloseDate *time.Time       `json:"expected_close_date,omitempty"`
+	ID                uint             `json:"id"`
+}
+
+// decodeBoardCursor reads a cursor made by boardCursor.encode
+func decodeBoardCursor(token string) (boardCursor, error) {
+	var cursor boardCursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, err
+	}
+	if !models.IsValidDealStage(cursor.Stage) {
+		return cursor, errors.New("unknown stage " + string(cursor.Stage))
+	}
+	return cursor, nil
+}
+
+// encode returns the cursor as an opaque token
+func (b boardCursor) encode() string {
+	raw, _ := json.Marshal(b)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// apply restricts db to the deals after the cursor in boardOrder
+func (b boardCursor) apply(db *gorm.DB) *gorm.DB {
+	if b.ExpectedCloseDate == nil {
+		return db.Where("deals.expected_close_date IS NULL AND deals.id > ?", b.ID)
+	}
+	return db.Where("(deals.expected_close_date > ? OR (deals.expected_close_date = ? AND deals.id > ?) OR deals.expected_close_date IS NULL)",
+		*b.ExpectedCloseDate, *b.ExpectedCloseDate, b.ID)
+}
+
+// MoveDeal sets a deal's stage and board position. Moving across stages
+// applies the same stage transition as PatchDeal.
+// PATCH /admin/deals/:id/position
+func (h *DealHandler) MoveDeal(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid deal ID"),
+		})
+		return
+	}
+
+	var deal models.Deal
+	if err := h.db.WithContext(c).First(&deal, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "DEAL_NOT_FOUND",
+				"message": i18n.Message(c, "DEAL_NOT_FOUND", "Deal not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal"),
+		})
+		return
+	}
+
+	if rejectArchived(c, deal.ArchivedAt) {
+		return
+	}
+
+	oldDeal := deal
+
+	var req DealPositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if !models.IsValidDealStage(req.Stage) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_STAGE",
+			"message": i18n.Message(c, "INVALID_STAGE", "Invalid deal stage"),
+		})
+		return
+	}
+
+	// Enforce field-level edit permissions and the change reason policy
+	if req.Stage != deal.Stage && (!enforceFieldPermissions(c, models.EntityDeal, deal.OwnerID, []string{"stage"}) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityDeal, models.IsClosedDealStage(deal.Stage), []string{"stage"}, req.ChangeReason)) {
+		return
+	}
+
+	position, ok := h.boardPosition(c, deal, req)
+	if !ok {
+		return
+	}
+
+	if req.Stage != deal.Stage && !applyStageTransition(c, &deal, oldDeal, req.Stage, req.LostReason) {
+		return
+	}
+	if !applyNextStep(c, &deal, oldDeal, req.NextStep, req.NextStepDue) {
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if position != nil {
+			deal.BoardPosition = *position
+		} else {
+			bottom, err := models.BottomBoardPosition(tx, deal.Stage, deal.OwnerID, deal.ID)
+			if err != nil {
+				return err
+			}
+			deal.BoardPosition = bottom
+		}
+		if err := tx.Save(&deal).Error; err != nil {
+			return err
+		}
+		// Reload with customer
+		if err := tx.Preload("Customer").First(&deal, deal.ID).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "deal", deal.ID, models.AuditActionUpdate, &oldDeal, &deal)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update deal"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, deal)
+}
+
+// boardPosition computes the fractional position for a move within the
+// deal's column of the target stage, writing the error response when a
+// neighbor is invalid. A nil position means the bottom of the column,
+// which is only read once the column is locked.
+func (h *DealHandler) boardPosition(c *gin.Context, deal models.Deal, req DealPositionRequest) (*float64, bool) {
+	if req.PrevID == nil && req.NextID == nil {
+		return req.Position, true
+	}
+
+	neighbor := func(neighborID *uint) (*models.Deal, bool) {
+		if neighborID == nil {
+			return nil, true
+		}
+		var n models.Deal
+		if *neighborID == deal.ID ||
+			models.BoardColumn(h.db.WithContext(c), req.Stage, deal.OwnerID).Where("id = ?", *neighborID).First(&n).Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_NEIGHBOR",
+				"message": i18n.Message(c, "INVALID_NEIGHBOR", "Neighbor deals must be other deals on the same board in the target stage"),
+			})
+			return nil, false
+		}
+		return &n, true
+	}
+
+	prev, ok := neighbor(req.PrevID)
+	if !ok {
+		return nil, false
+	}
+	next, ok := neighbor(req.NextID)
+	if !ok {
+		return nil, false
+	}
+
+	var position float64
+	switch {
+	case prev != nil && next != nil:
+		if prev.BoardPosition >= next.BoardPosition {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_NEIGHBOR",
+				"message": i18n.Message(c, "INVALID_NEIGHBOR", "prev_id must be above next_id on the board"),
+			})
+			return nil, false
+		}
+		position = (prev.BoardPosition + next.BoardPosition) / 2
+	case prev != nil:
+		position = prev.BoardPosition + models.BoardPositionStep
+	default:
+		position = next.BoardPosition - models.BoardPositionStep
+	}
+	return &position, true
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// maxBulkStageDeals caps the number of deals a bulk stage change may select
+const maxBulkStageDeals = 500
+
+// Bulk stage result statuses
+const (
+	BulkStageUpdated = "updated"
+	BulkStageSkipped = "skipped"
+)
+
+// DealBulkStageRequest selects deals by ID or by ListDeals filters and
+// moves them to one stage
+type DealBulkStageRequest struct {
+	IDs            []uint            `json:"ids,omitempty"`
+	Filter         map[string]string `json:"filter,omitempty"`
+	Stage          models.DealStage  `json:"stage" binding:"required"`
+	LostReason     string            `json:"lost_reason,omitempty"`
+	ChangeReason   string            `json:"change_reason,omitempty"`   // Recorded for every moved deal
+	SuppressEvents *bool             `json:"suppress_events,omitempty"` // Defaults to true; see BulkUpdateStage
+}
+
+// BulkStageResult reports the outcome for a single deal
+type BulkStageResult struct {
+	ID      uint   `json:"id"`
+	Status  string `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Warning string `json:"warning,omitempty"` // Set on updated deals that exceed the open deal limit in warn mode
+}
+
+// BulkStageReport is the per-deal report of a bulk stage change
+type BulkStageReport struct {
+	Stage   models.DealStage  `json:"stage"`
+	Total   int               `json:"total"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Results []BulkStageResult `json:"results"`
+}
+
+// bulkStageMove is a deal a bulk stage change moves, with the index of its
+// result in the report
+type bulkStageMove struct {
+	old, deal models.Deal
+	result    int
+}
+
+// BulkUpdateStage moves the selected deals to a stage. Each deal is checked
+// as PatchDeal would check it, including the open deal limit; deals that
+// fail are skipped with a code and the rest are updated in one transaction
+// with their audit entries, so the stage history of every moved deal is
+// recorded. Unless suppress_events is false, the moves are one bulk
+// operation: their webhook events are suppressed for subscriptions that do
+// not take bulk events and a single bulk.completed event is raised.
+// POST /admin/deals/bulk-stage
+func (h *DealHandler) BulkUpdateStage(c *gin.Context) {
+	var req DealBulkStageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if !models.IsValidDealStage(req.Stage) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_STAGE",
+			"message": i18n.Message(c, "INVALID_STAGE", "Invalid deal stage"),
+		})
+		return
+	}
+
+	if req.Stage == models.DealStageClosedLost && req.LostReason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "LOST_REASON_REQUIRED",
+			"message": i18n.Message(c, "LOST_REASON_REQUIRED", "A lost reason is required to close deals as lost"),
+		})
+		return
+	}
+
+	deals, ok := h.bulkStageDeals(c, req)
+	if !ok {
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	report := BulkStageReport{Stage: req.Stage}
+	skip := func(id uint, code, message string) {
+		report.Results = append(report.Results, BulkStageResult{
+			ID:      id,
+			Status:  BulkStageSkipped,
+			Code:    code,
+			Message: i18n.Message(c, code, message),
+		})
+	}
+
+	// Deals closed as won must have completed the close checklist
+	var checklists []models.ChecklistDefinition
+	var checked map[uint][]models.DealChecklistItem
+	if req.Stage == models.DealStageClosedWon {
+		ids := make([]uint, len(deals))
+		for i, deal := range deals {
+			ids[i] = deal.ID
+		}
+		var err error
+		if checklists, checked, err = loadChecklists(h.db.WithContext(c), ids); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal checklists"),
+			})
+			return
+		}
+	}
+
+	// Without a reason, deals whose stage change needs one are skipped
+	reason, ok := takeChangeReason(c, req.ChangeReason)
+	if !ok {
+		return
+	}
+	var policy models.ChangeReasonPolicy
+	if reason == "" {
+		var err error
+		if policy, err = loadChangeReasonPolicy(h.db.WithContext(c)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch change reason policy"),
+			})
+			return
+		}
+	}
+
+	found := make(map[uint]bool, len(deals))
+	var moves []bulkStageMove
+	now := time.Now()
+	for _, deal := range deals {
+		found[deal.ID] = true
+		switch {
+		case deal.ArchivedAt != nil:
+			skip(deal.ID, "ARCHIVED", "Archived records must be unarchived before they can be changed")
+		case deal.Stage == req.Stage:
+			skip(deal.ID, "STAGE_UNCHANGED", "Deal is already in the target stage")
+		case len(models.ForbiddenFields(models.EntityDeal, user.Role, deal.OwnerID == nil || *deal.OwnerID == user.ID, []string{"stage"})) > 0:
+			skip(deal.ID, "FIELD_EDIT_FORBIDDEN", "You do not have permission to edit these fields")
+		case len(policy.RequiredFields(models.EntityDeal, models.IsClosedDealStage(deal.Stage), []string{"stage"})) > 0:
+			skip(deal.ID, "REASON_REQUIRED", "Give a reason for changing these fields in change_reason or the X-Change-Reason header")
+		case models.Deal{Stage: req.Stage, NextStep: deal.NextStep}.NeedsNextStep(deal.Stage):
+			skip(deal.ID, "NEXT_STEP_REQUIRED", "Record a next step before moving the deal forward")
+		case !models.CanTransitionDeal(deal.Stage, req.Stage, models.HoldsRole(user.Role, models.RoleAdmin)):
+			skip(deal.ID, "INVALID_TRANSITION", "Cannot move a deal from "+string(deal.Stage)+" to "+string(req.Stage))
+		case models.Deal{Stage: req.Stage}.NeedsChecklist(deal.Stage, models.NewDealChecklist(models.Deal{}, checklists, checked[deal.ID])):
+			skip(deal.ID, "CHECKLIST_INCOMPLETE", "Complete the close checklist before closing the deal as won")
+		default:
+			oldDeal := deal
+			deal.Stage = req.Stage
+			if req.Stage == models.DealStageClosedLost {
+				deal.LostReason = req.LostReason
+			}
+			deal.SyncCloseFields(oldDeal, now)
+			moves = append(moves, bulkStageMove{old: oldDeal, deal: deal, result: len(report.Results)})
+			report.Results = append(report.Results, BulkStageResult{ID: deal.ID, Status: BulkStageUpdated})
+		}
+	}
+	for _, id := range req.IDs {
+		if !found[id] {
+			found[id] = true
+			skip(id, "DEAL_NOT_FOUND", "Deal not found")
+		}
+	}
+
+	finish := func(models.BulkOperationSummary, string) {}
+	if req.SuppressEvents == nil || *req.SuppressEvents {
+		finish = beginBulkOperation(c, h.db, models.BulkOperationDealStage, models.AnnotationTypeMaintenance,
+			"Bulk stage change of "+strconv.Itoa(len(moves))+" deals to "+string(req.Stage))
+	}
+
+	// Reopened deals are checked against the open deal limit in order, so
+	// earlier moves count toward the limit of later ones
+	var updated int
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		updated = 0
+		for i := range moves {
+			move := &moves[i]
+			limitErr, err := checkOpenDealLimit(tx, move.deal, move.old)
+			if err != nil {
+				return err
+			}
+			result := &report.Results[move.result]
+			*result = BulkStageResult{ID: move.deal.ID, Status: BulkStageUpdated}
+			if limitErr != nil {
+				if models.OpenDealLimitPolicy != models.OpenDealLimitWarn {
+					*result = BulkStageResult{
+						ID:      move.deal.ID,
+						Status:  BulkStageSkipped,
+						Code:    "OPEN_DEAL_LIMIT",
+						Message: i18n.Message(c, "OPEN_DEAL_LIMIT", limitErr.openDealsMessage()),
+					}
+					continue
+				}
+				result.Warning = limitErr.openDealsMessage()
+			}
+
+			if err := tx.Save(&move.deal).Error; err != nil {
+				return err
+			}
+			audit := auditEntry(c, "deal", move.deal.ID, models.AuditActionUpdate, &move.old, &move.deal)
+			if err := audittrail.Record(c, tx, &audit); err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		finish(models.BulkOperationSummary{ResourceType: "deal", Total: len(report.Results), Skipped: len(report.Results) - len(moves), Failed: len(moves)}, "Failed")
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update deals"),
+		})
+		return
+	}
+
+	report.Total = len(report.Results)
+	report.Updated = updated
+	report.Skipped = report.Total - report.Updated
+	finish(models.BulkOperationSummary{ResourceType: "deal", Total: report.Total, Updated: report.Updated, Skipped: report.Skipped},
+		"Updated "+strconv.Itoa(report.Updated)+", skipped "+strconv.Itoa(report.Skipped))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// bulkStageDeals loads the deals selected by a bulk stage request, writing
+// the error response when the selection is empty, too large or fails
+func (h *DealHandler) bulkStageDeals(c *gin.Context, req DealBulkStageRequest) ([]models.Deal, bool) {
+	db := h.db.WithContext(c).Model(&models.Deal{})
+	switch {
+	case len(req.IDs) > 0 && len(req.Filter) > 0:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_SELECTION",
+			"message": i18n.Message(c, "INVALID_SELECTION", "Select deals by ids or by at least one filter, not both"),
+		})
+		return nil, false
+	case len(req.IDs) > 0:
+		if len(req.IDs) > maxBulkStageDeals {
+			h.tooManyDeals(c)
+			return nil, false
+		}
+		db = db.Where("id IN ?", req.IDs)
+	default:
+		values := make(url.Values, len(req.Filter))
+		for param, value := range req.Filter {
+			values.Set(param, value)
+		}
+		var applied map[string]string
+		db, applied = dealListQuery.Filter(db.Scopes(models.NotArchived("deals")), values)
+		if len(applied) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_SELECTION",
+				"message": i18n.Message(c, "INVALID_SELECTION", "Select deals by ids or by at least one filter, not both"),
+			})
+			return nil, false
+		}
+	}
+
+	var deals []models.Deal
+	if err := db.Order("id").Limit(maxBulkStageDeals + 1).Find(&deals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deals"),
+		})
+		return nil, false
+	}
+	if len(deals) > maxBulkStageDeals {
+		h.tooManyDeals(c)
+		return nil, false
+	}
+	return deals, true
+}
+
+// tooManyDeals writes the error response for an oversized bulk selection
+func (h *DealHandler) tooManyDeals(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "validation_error",
+		"code":    "TOO_MANY_DEALS",
+		"message": i18n.Message(c, "TOO_MANY_DEALS", "Too many deals selected; narrow the selection"),
+	})
+}
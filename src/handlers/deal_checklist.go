@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChecklistHandler handles the close checklist definition endpoints
+type ChecklistHandler struct {
+	db *gorm.DB
+}
+
+// NewChecklistHandler creates a new ChecklistHandler
+func NewChecklistHandler(db *gorm.DB) *ChecklistHandler {
+	return &ChecklistHandler{db: db}
+}
+
+// ChecklistCreateRequest represents the request body for adding a close
+// checklist item. Without a position the item goes last.
+type ChecklistCreateRequest struct {
+	Key      string `json:"key" binding:"required,max=50"`
+	Label    string `json:"label" binding:"required,max=255"`
+	Required *bool  `json:"required,omitempty"` // Defaults to true
+	Position int    `json:"position,omitempty" binding:"min=0"`
+}
+
+// ChecklistUpdateRequest represents the request body for amending a close
+// checklist item. The key cannot change, since deals record checked items
+// by key.
+type ChecklistUpdateRequest struct {
+	Label    string `json:"label" binding:"required,max=255"`
+	Required *bool  `json:"required,omitempty"` // Unchanged when omitted
+	Position int    `json:"position" binding:"required,min=1"`
+}
+
+// ListChecklist returns the close checklist items in order
+// GET /admin/deal-checklist
+func (h *ChecklistHandler) ListChecklist(c *gin.Context) {
+	var definitions []models.ChecklistDefinition
+	if err := h.db.WithContext(c).Order("position ASC, id ASC").Find(&definitions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch checklist items"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ChecklistListResponse{Data: definitions})
+}
+
+// CreateChecklistItem adds a close checklist item. A required item applies to
+// open deals at once; deals closed before it was added are not held to it.
+// POST /admin/deal-checklist
+func (h *ChecklistHandler) CreateChecklistItem(c *gin.Context) {
+	var req ChecklistCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if err := models.ValidateChecklistKey(req.Key); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_CHECKLIST_KEY",
+			"message": i18n.Message(c, "INVALID_CHECKLIST_KEY", err.Error()),
+		})
+		return
+	}
+
+	var existing int64
+	h.db.WithContext(c).Model(&models.ChecklistDefinition{}).Where("key = ?", req.Key).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "CHECKLIST_KEY_EXISTS",
+			"message": i18n.Message(c, "CHECKLIST_KEY_EXISTS", "A checklist item with this key already exists"),
+		})
+		return
+	}
+
+	definition := models.ChecklistDefinition{
+		Key:      req.Key,
+		Label:    req.Label,
+		Required: req.Required == nil || *req.Required,
+		Position: req.Position,
+	}
+	if definition.Position == 0 {
+		h.db.WithContext(c).Model(&models.ChecklistDefinition{}).Select("COALESCE(MAX(position), 0) + 1").Scan(&definition.Position)
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&definition).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "deal_checklist_definition", definition.ID, models.AuditActionCreate, nil, &definition)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create checklist item"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, definition)
+}
+
+// UpdateChecklistItem amends the label, required flag or position of a close
+// checklist item. Deals already closed won stay closed.
+// PUT /admin/deal-checklist/:id
+func (h *ChecklistHandler) UpdateChecklistItem(c *gin.Context) {
+	definition, ok := h.findDefinition(c)
+	if !ok {
+		return
+	}
+	oldDefinition := *definition
+
+	var req ChecklistUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	definition.Label = req.Label
+	definition.Position = req.Position
+	if req.Required != nil {
+		definition.Required = *req.Required
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(definition).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "deal_checklist_definition", definition.ID, models.AuditActionUpdate, &oldDefinition, definition)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update checklist item"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// DeleteChecklistItem removes a close checklist item. Deals keep the record
+// of having checked it, which applies again if an item with its key is
+// added back.
+// DELETE /admin/deal-checklist/:id
+func (h *ChecklistHandler) DeleteChecklistItem(c *gin.Context) {
+	definition, ok := h.findDefinition(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(definition).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "deal_checklist_definition", definition.ID, models.AuditActionDelete, definition, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete checklist item"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Checklist item deleted successfully",
+	})
+}
+
+// findDefinition loads the checklist item identified by the :id route
+// parameter, writing the error response when it cannot be found
+func (h *ChecklistHandler) findDefinition(c *gin.Context) (*models.ChecklistDefinition, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid checklist item ID"),
+		})
+		return nil, false
+	}
+
+	var definition models.ChecklistDefinition
+	if err := h.db.WithContext(c).First(&definition, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CHECKLIST_ITEM_NOT_FOUND",
+				"message": i18n.Message(c, "CHECKLIST_ITEM_NOT_FOUND", "Checklist item not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch checklist item"),
+		})
+		return nil, false
+	}
+
+	return &definition, true
+}
+
+// GetDealChecklist returns the close checklist of a deal
+// GET /admin/deals/:id/checklist
+func (h *DealHandler) GetDealChecklist(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	checklist, err := loadDealChecklist(h.db.WithContext(c), *deal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal checklist"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, checklist)
+}
+
+// CheckDealChecklistItem checks a close checklist item on a deal, recording
+// who checked it. Checking a checked item keeps the first check.
+// PUT /admin/deals/:id/checklist/:key
+func (h *DealHandler) CheckDealChecklistItem(c *gin.Context) {
+	deal, ok := h.checklistDeal(c)
+	if !ok {
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	item := models.DealChecklistItem{
+		DealID:        deal.ID,
+		Key:           c.Param("key"),
+		CheckedBy:     user.ID,
+		CheckedByName: user.Name,
+		CheckedAt:     time.Now(),
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&item)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return logAudit(c, tx, "deal_checklist_item", item.ID, models.AuditActionCreate, nil, &item)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		h.checklistFailure(c)
+		return
+	}
+
+	h.respondDealChecklist(c, *deal)
+}
+
+// UncheckDealChecklistItem unchecks a close checklist item on a deal
+// DELETE /admin/deals/:id/checklist/:key
+func (h *DealHandler) UncheckDealChecklistItem(c *gin.Context) {
+	deal, ok := h.checklistDeal(c)
+	if !ok {
+		return
+	}
+
+	var item models.DealChecklistItem
+	err := h.db.WithContext(c).Where("deal_id = ? AND key = ?", deal.ID, c.Param("key")).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		h.respondDealChecklist(c, *deal)
+		return
+	}
+	if err != nil {
+		h.checklistFailure(c)
+		return
+	}
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "deal_checklist_item", item.ID, models.AuditActionDelete, &item, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		h.checklistFailure(c)
+		return
+	}
+
+	h.respondDealChecklist(c, *deal)
+}
+
+// checklistDeal loads the deal whose checklist item named by the :key route
+// parameter is changed, writing the error response when the deal or item
+// cannot be found or the checklist may not be changed. The checklist of a
+// closed deal is kept as it was at closing; reopen the deal to change it.
+func (h *DealHandler) checklistDeal(c *gin.Context) (*models.Deal, bool) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return nil, false
+	}
+	if rejectArchived(c, deal.ArchivedAt) {
+		return nil, false
+	}
+	if !enforceFieldPermissions(c, models.EntityDeal, deal.OwnerID, []string{"checklist"}) {
+		return nil, false
+	}
+	if models.IsClosedDealStage(deal.Stage) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "CHECKLIST_LOCKED",
+			"message": i18n.Message(c, "CHECKLIST_LOCKED", "The checklist of a closed deal cannot be changed; reopen the deal first"),
+		})
+		return nil, false
+	}
+
+	var definitions int64
+	if err := h.db.WithContext(c).Model(&models.ChecklistDefinition{}).Where("key = ?", c.Param("key")).Count(&definitions).Error; err != nil {
+		h.checklistFailure(c)
+		return nil, false
+	}
+	if definitions == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "CHECKLIST_ITEM_NOT_FOUND",
+			"message": i18n.Message(c, "CHECKLIST_ITEM_NOT_FOUND", "Checklist item not found"),
+		})
+		return nil, false
+	}
+
+	return deal, true
+}
+
+// respondDealChecklist responds with the checklist of a deal after a change
+func (h *DealHandler) respondDealChecklist(c *gin.Context, deal models.Deal) {
+	checklist, err := loadDealChecklist(h.db.WithContext(c), deal)
+	if err != nil {
+		h.checklistFailure(c)
+		return
+	}
+	c.JSON(http.StatusOK, checklist)
+}
+
+// checklistFailure writes the error response for a failed checklist query
+func (h *DealHandler) checklistFailure(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "internal_error",
+		"code":    "DATABASE_ERROR",
+		"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update deal checklist"),
+	})
+}
+
+// checkChecklist refuses closing a deal as won from a stage while a
+// required checklist item is unchecked, writing a 422 response listing the
+// missing items. Closing is checked against every current item, whatever
+// close date the request gives.
+func (h *DealHandler) checkChecklist(c *gin.Context, deal models.Deal, from models.DealStage) bool {
+	if deal.Stage != models.DealStageClosedWon || from == models.DealStageClosedWon {
+		return true
+	}
+
+	definitions, checked, err := loadChecklists(h.db.WithContext(c), []uint{deal.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal checklist"),
+		})
+		return false
+	}
+	checklist := models.NewDealChecklist(models.Deal{}, definitions, checked[deal.ID])
+	if !deal.NeedsChecklist(from, checklist) {
+		return true
+	}
+
+	missing := make([]models.DealChecklistEntry, 0, len(checklist.Missing))
+	for _, entry := range checklist.Items {
+		if entry.Required && !entry.Checked {
+			missing = append(missing, entry)
+		}
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":   "validation_error",
+		"code":    "CHECKLIST_INCOMPLETE",
+		"message": i18n.Message(c, "CHECKLIST_INCOMPLETE", "Complete the close checklist before closing the deal as won"),
+		"missing": missing,
+	})
+	return false
+}
+
+// loadDealChecklist builds the close checklist of a deal
+func loadDealChecklist(db *gorm.DB, deal models.Deal) (models.DealChecklist, error) {
+	definitions, checked, err := loadChecklists(db, []uint{deal.ID})
+	if err != nil {
+		return models.DealChecklist{}, err
+	}
+	return models.NewDealChecklist(deal, definitions, checked[deal.ID]), nil
+}
+
+// loadChecklists returns the close checklist items in order and the items
+// checked on each of the deals
+func loadChecklists(db *gorm.DB, dealIDs []uint) ([]models.ChecklistDefinition, map[uint][]models.DealChecklistItem, error) {
+	var definitions []models.ChecklistDefinition
+	if err := db.Order("position ASC, id ASC").Find(&definitions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var items []models.DealChecklistItem
+	if err := db.Where("deal_id IN ?", dealIDs).Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	checked := make(map[uint][]models.DealChecklistItem, len(dealIDs))
+	for _, item := range items {
+		checked[item.DealID] = append(checked[item.DealID], item)
+	}
+	return definitions, checked, nil
+}
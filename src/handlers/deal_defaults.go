@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/dealdefaults"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetDealDefaults returns suggested values for a new deal of a customer,
+// computed from the customer's deal history with fallbacks to settings
+// GET /admin/customers/:id/deal-defaults
+func (h *DealHandler) GetDealDefaults(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
+		})
+		return
+	}
+
+	var customer models.Customer
+	if err := h.db.WithContext(c).First(&customer, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CUSTOMER_NOT_FOUND",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
+		})
+		return
+	}
+
+	defaults, err := h.defaults.Suggest(c, customer, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute deal defaults"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, defaults)
+}
+
+// applyDealDefaults fills the fields a create request leaves unset from
+// the customer's deal defaults. Explicit request values always win; the
+// defaults already prefer customer history over settings. It returns the
+// filled fields with the source of each value.
+func applyDealDefaults(req *DealCreateRequest, defaults dealdefaults.Defaults) map[string]string {
+	defaulted := make(map[string]string)
+
+	if strings.TrimSpace(req.Title) == "" && defaults.Title != "" {
+		req.Title = defaults.Title
+		defaulted["title"] = defaults.TitlePattern.Source
+	}
+	if req.Currency == "" {
+		req.Currency = defaults.Currency.Value
+		defaulted["currency"] = defaults.Currency.Source
+	}
+	if req.OwnerID == nil && defaults.OwnerID.Value != nil {
+		req.OwnerID = defaults.OwnerID.Value
+		defaulted["owner_id"] = defaults.OwnerID.Source
+	}
+	if req.Amount == nil && defaults.Amount.Value != nil {
+		req.Amount = defaults.Amount.Value
+		defaulted["amount"] = defaults.Amount.Source
+	}
+	if req.Probability == nil {
+		probability := defaults.Probability.Value
+		req.Probability = &probability
+		defaulted["probability"] = defaults.Probability.Source
+	}
+	if req.Stage == "" {
+		req.Stage = defaults.Stage.Value
+		defaulted["stage"] = defaults.Stage.Source
+	}
+
+	return defaulted
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OpenDealSummary identifies one of a customer's open deals in an
+// OPEN_DEAL_LIMIT response
+type OpenDealSummary struct {
+	ID    uint             `json:"id"`
+	Title string           `json:"title"`
+	Stage models.DealStage `json:"stage"`
+}
+
+// openDealLimitError refuses a change that would give a customer more open
+// deals than MaxOpenDealsPerCustomer allows
+type openDealLimitError struct {
+	Open []OpenDealSummary // The customer's other open deals
+}
+
+func (e *openDealLimitError) Error() string {
+	return "open deal limit reached"
+}
+
+// checkOpenDealLimit checks within tx whether a change from previous to
+// deal, which is about to be written, takes the deal's customer over the
+// open deal limit, returning the customer's other open deals when it does.
+// The customer row stays locked until tx ends, so two concurrent changes
+// cannot both slip under the limit.
+func checkOpenDealLimit(tx *gorm.DB, deal, previous models.Deal) (*openDealLimitError, error) {
+	if models.MaxOpenDealsPerCustomer <= 0 || !deal.AddsOpenDeal(previous) {
+		return nil, nil
+	}
+
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Limit(1).Find(&customer, deal.CustomerID).Error; err != nil {
+		return nil, err
+	}
+
+	var open []OpenDealSummary
+	if err := tx.Model(&models.Deal{}).Scopes(models.OpenDeals).
+		Where("deals.customer_id = ? AND deals.id <> ?", deal.CustomerID, deal.ID).
+		Order("deals.id").
+		Find(&open).Error; err != nil {
+		return nil, err
+	}
+	if len(open) < models.MaxOpenDealsPerCustomer {
+		return nil, nil
+	}
+	return &openDealLimitError{Open: open}, nil
+}
+
+// guardOpenDealLimit applies the open deal limit to a change within tx. Over
+// the limit it returns an *openDealLimitError, or adds a Warning header when
+// the limit only warns.
+func guardOpenDealLimit(c *gin.Context, tx *gorm.DB, deal, previous models.Deal) error {
+	limitErr, err := checkOpenDealLimit(tx, deal, previous)
+	if err != nil || limitErr == nil {
+		return err
+	}
+	if models.OpenDealLimitPolicy == models.OpenDealLimitWarn {
+		c.Writer.Header().Add("Warning", fmt.Sprintf("299 - %q", limitErr.openDealsMessage()))
+		return nil
+	}
+	return limitErr
+}
+
+// openDealsMessage describes the exceeded limit
+func (e *openDealLimitError) openDealsMessage() string {
+	return fmt.Sprintf("Customer already has %d open deals; the limit is %d", len(e.Open), models.MaxOpenDealsPerCustomer)
+}
+
+// respondOpenDealLimit writes the 422 response listing the customer's open
+// deals when err refused a change for the open deal limit
+func respondOpenDealLimit(c *gin.Context, err error) bool {
+	var limitErr *openDealLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":      "validation_error",
+		"code":       "OPEN_DEAL_LIMIT",
+		"message":    i18n.Message(c, "OPEN_DEAL_LIMIT", limitErr.openDealsMessage()),
+		"limit":      models.MaxOpenDealsPerCustomer,
+		"open_deals": limitErr.Open,
+	})
+	return true
+}
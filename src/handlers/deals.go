@@ -1,40 +1,75 @@
 package handlers
 
 import (
-	"math"
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/dealdefaults"
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/preview"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // DealHandler handles deal-related endpoints
 type DealHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	prefetch *query.Prefetcher
+	defaults *dealdefaults.Service
+	timeline int // Preview length of the activities and notes on deal details
 }
 
-// NewDealHandler creates a new DealHandler
-func NewDealHandler(db *gorm.DB) *DealHandler {
-	return &DealHandler{db: db}
+// NewDealHandler creates a new DealHandler. prefetch may be nil to disable
+// list page caching. The activities and notes of deal details are
+// previewed with timelinePreview characters, 0 for in full.
+func NewDealHandler(db *gorm.DB, prefetch *query.Prefetcher, defaults *dealdefaults.Service, timelinePreview int) *DealHandler {
+	return &DealHandler{db: db, prefetch: prefetch, defaults: defaults, timeline: timelinePreview}
 }
 
-// DealCreateRequest represents the request body for creating a deal
+// DealCreateRequest represents the request body for creating a deal. The
+// title may be omitted when defaults are applied.
 type DealCreateRequest struct {
-	Title             string           `json:"title" binding:"required,min=1,max=255"`
+	Title             string           `json:"title" binding:"max=255"`
 	Description       string           `json:"description,omitempty"`
 	CustomerID        uint             `json:"customer_id" binding:"required"`
 	ContactID         *uint            `json:"contact_id,omitempty"`
 	Stage             models.DealStage `json:"stage,omitempty"`
-	Amount            float64          `json:"amount,omitempty"`
+	Amount            *float64         `json:"amount,omitempty"`
 	Currency          string           `json:"currency,omitempty"`
-	Probability       int              `json:"probability,omitempty"`
+	Probability       *int             `json:"probability,omitempty"`
 	ExpectedCloseDate *time.Time       `json:"expected_close_date,omitempty"`
 	OwnerID           *uint            `json:"owner_id,omitempty"`
+	ExternalID        string           `json:"external_id,omitempty" binding:"max=100"`
+	NextStep          string           `json:"next_step,omitempty" binding:"max=255"`
+	NextStepDue       *time.Time       `json:"next_step_due,omitempty"`
+}
+
+// DealCreateResponse is the created deal, with the fields that were filled
+// from defaults when apply_defaults=true was passed
+type DealCreateResponse struct {
+	models.Deal
+	Meta *DealCreateMeta `json:"meta,omitempty"`
+}
+
+// MarshalJSON serializes the deal followed by meta, which the embedded
+// deal's MarshalJSON would otherwise leave out
+func (r DealCreateResponse) MarshalJSON() ([]byte, error) {
+	return models.MarshalExtended(r.Deal, struct {
+		Meta *DealCreateMeta `json:"meta,omitempty"`
+	}{r.Meta})
+}
+
+// DealCreateMeta describes how a deal was created
+type DealCreateMeta struct {
+	Defaulted map[string]string `json:"defaulted"` // Field name to the source of its value
 }
 
 // DealUpdateRequest represents the request body for updating a deal
@@ -51,107 +86,94 @@ type DealUpdateRequest struct {
 	ActualCloseDate   *time.Time       `json:"actual_close_date,omitempty"`
 	OwnerID           *uint            `json:"owner_id,omitempty"`
 	LostReason        string           `json:"lost_reason,omitempty"`
+	ExternalID        string           `json:"external_id,omitempty" binding:"max=100"`
+	NextStep          *string          `json:"next_step,omitempty" binding:"omitempty,max=255"` // "" clears the next step
+	NextStepDue       *time.Time       `json:"next_step_due,omitempty"`
+	ChangeReason      string           `json:"change_reason,omitempty"` // Also accepted as the X-Change-Reason header
 }
 
-// DealStageTransitionRequest represents a stage transition request
+// DealStageTransitionRequest represents a stage transition request. The
+// next step may be recorded in the same request.
 type DealStageTransitionRequest struct {
-	Stage      models.DealStage `json:"stage" binding:"required"`
-	LostReason string           `json:"lost_reason,omitempty"`
+	Stage        models.DealStage `json:"stage" binding:"required"`
+	LostReason   string           `json:"lost_reason,omitempty"`
+	NextStep     *string          `json:"next_step,omitempty" binding:"omitempty,max=255"`
+	NextStepDue  *time.Time       `json:"next_step_due,omitempty"`
+	ChangeReason string           `json:"change_reason,omitempty"`
 }
 
-// ListDeals returns a paginated list of deals with filtering
+// dealListQuery defines the filters and sorting of ListDeals
+var dealListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("stage", "stage"),
+		query.Equal("owner_id", "owner_id"),
+		query.Equal("customer_id", "customer_id"),
+		query.Equal("external_id", "external_id"),
+		query.FoldedSearch("search", "deals.search_text"),
+		query.AtLeast("amount_min", "amount", query.KindFloat),
+		query.AtMost("amount_max", "amount", query.KindFloat),
+		query.AtLeast("expected_close_from", "expected_close_date", query.KindTime),
+		query.AtMost("expected_close_to", "expected_close_date", query.KindTime),
+		query.AnyOf("tags", "deals.customer_id IN (SELECT customer_id FROM customer_tags WHERE tag_id IN ?)", ""),
+		query.AnyOf("tag_group", "deals.customer_id IN ("+customersInTagGroups+")", ""),
+		query.Condition("missing_next_step", "deals.next_step = ''"),
+	},
+	Sort: query.Sort{
+		Fields:       []string{"created_at", "updated_at", "title", "amount", "expected_close_date", "stage"},
+		DefaultField: "created_at",
+		DefaultOrder: "desc",
+		Collated:     []string{"title"},
+	},
+}
+
+// ListDeals returns a paginated list of deals with filtering. With
+// prefetch=true the next page is primed so following next_page_token is
+// served from cache.
 // GET /admin/deals
 func (h *DealHandler) ListDeals(c *gin.Context) {
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
-
-	query := h.db.Model(&models.Deal{})
-
-	// Filters
-	if stage := c.Query("stage"); stage != "" {
-		query = query.Where("stage = ?", stage)
-	}
-	if ownerID := c.Query("owner_id"); ownerID != "" {
-		query = query.Where("owner_id = ?", ownerID)
-	}
-	if customerID := c.Query("customer_id"); customerID != "" {
-		query = query.Where("customer_id = ?", customerID)
-	}
-	if search := c.Query("search"); search != "" {
-		searchTerm := "%" + strings.ToLower(search) + "%"
-		query = query.Where("LOWER(title) LIKE ?", searchTerm)
-	}
-	if amountMin := c.Query("amount_min"); amountMin != "" {
-		if val, err := strconv.ParseFloat(amountMin, 64); err == nil {
-			query = query.Where("amount >= ?", val)
-		}
-	}
-	if amountMax := c.Query("amount_max"); amountMax != "" {
-		if val, err := strconv.ParseFloat(amountMax, 64); err == nil {
-			query = query.Where("amount <= ?", val)
-		}
-	}
-	if closeDateFrom := c.Query("expected_close_from"); closeDateFrom != "" {
-		if t, err := time.Parse(time.RFC3339, closeDateFrom); err == nil {
-			query = query.Where("expected_close_date >= ?", t)
-		}
-	}
-	if closeDateTo := c.Query("expected_close_to"); closeDateTo != "" {
-		if t, err := time.Parse(time.RFC3339, closeDateTo); err == nil {
-			query = query.Where("expected_close_date <= ?", t)
-		}
-	}
+	values := query.Values(c.Request.URL.Query())
+	page := query.ParsePage(values)
 
-	// Sorting
-	sortBy := c.DefaultQuery("sort_by", "created_at")
-	sortOrder := c.DefaultQuery("sort_order", "desc")
-	if sortOrder != "asc" && sortOrder != "desc" {
-		sortOrder = "desc"
-	}
-	allowedSortFields := map[string]bool{
-		"created_at": true, "updated_at": true, "title": true, "amount": true,
-		"expected_close_date": true, "stage": true,
+	db := middleware.GetReadDB(c, h.db).Model(&models.Deal{})
+	if values.Get("include_archived") != "true" {
+		db = db.Scopes(models.NotArchived("deals"))
 	}
-	if !allowedSortFields[sortBy] {
-		sortBy = "created_at"
-	}
-	query = query.Order(sortBy + " " + sortOrder)
-
-	// Count total
-	var total int64
-	query.Count(&total)
+	db, filters := dealListQuery.Filter(db, values)
 
-	// Get deals
-	var deals []models.Deal
-	offset := (page - 1) * pageSize
-	if err := query.Preload("Customer").Offset(offset).Limit(pageSize).Find(&deals).Error; err != nil {
+	deals, total, err := query.FindPage[models.Deal](c, h.prefetch, query.PageRequest{
+		Query:    db.Preload("Customer"),
+		Table:    "deals",
+		Depends:  []string{"customers", "customer_tags", "tags", "tag_groups"},
+		Scope:    prefetchScope(c),
+		Values:   values,
+		Page:     page,
+		Order:    dealListQuery.Order(values),
+		Prefetch: c.Query("prefetch") == "true" && flags.Enabled(c, flags.ListPrefetch),
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch deals",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deals"),
 		})
 		return
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-
+	next := query.NextPageToken(values, page, total)
+	setPageHeaders(c, total, next)
 	c.JSON(http.StatusOK, models.DealListResponse{
-		Data:       deals,
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+		Data:          deals,
+		Total:         total,
+		Page:          page.Page,
+		PageSize:      page.PageSize,
+		TotalPages:    page.TotalPages(total),
+		NextPageToken: next,
+		Filters:       filters,
 	})
 }
 
-// CreateDeal creates a new deal
+// CreateDeal creates a new deal. With apply_defaults=true, fields the
+// request leaves unset are filled from the customer's deal defaults.
 // POST /admin/deals
 func (h *DealHandler) CreateDeal(c *gin.Context) {
 	var req DealCreateRequest
@@ -159,42 +181,73 @@ func (h *DealHandler) CreateDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
 	// Verify customer exists
 	var customer models.Customer
-	if err := h.db.First(&customer, req.CustomerID).Error; err != nil {
+	if err := h.db.WithContext(c).First(&customer, req.CustomerID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "validation_error",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to verify customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to verify customer"),
+		})
+		return
+	}
+
+	var meta *DealCreateMeta
+	if c.Query("apply_defaults") == "true" {
+		defaults, err := h.defaults.Suggest(c, customer, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute deal defaults"),
+			})
+			return
+		}
+		meta = &DealCreateMeta{Defaulted: applyDealDefaults(&req, defaults)}
+	}
+
+	if strings.TrimSpace(req.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "TITLE_REQUIRED",
+			"message": i18n.Message(c, "TITLE_REQUIRED", "Title is required"),
 		})
 		return
 	}
 
 	// Set defaults
+	settings := h.defaults.Settings()
 	stage := req.Stage
 	if stage == "" {
-		stage = models.DealStageProspecting
+		stage = settings.Stage
 	}
 	currency := req.Currency
 	if currency == "" {
-		currency = "USD"
+		currency = settings.Currency
+	}
+	var amount float64
+	if req.Amount != nil {
+		amount = *req.Amount
 	}
 
 	// Validate probability
-	probability := req.Probability
+	var probability int
+	if req.Probability != nil {
+		probability = *req.Probability
+	}
 	if probability < 0 {
 		probability = 0
 	}
@@ -208,29 +261,56 @@ func (h *DealHandler) CreateDeal(c *gin.Context) {
 		CustomerID:        req.CustomerID,
 		ContactID:         req.ContactID,
 		Stage:             stage,
-		Amount:            req.Amount,
+		Amount:            amount,
 		Currency:          currency,
 		Probability:       probability,
 		ExpectedCloseDate: req.ExpectedCloseDate,
 		OwnerID:           req.OwnerID,
+		ExternalID:        strings.TrimSpace(req.ExternalID),
+		NextStep:          strings.TrimSpace(req.NextStep),
+		NextStepDue:       req.NextStepDue,
+	}
+	deal.TrackNextStep(models.Deal{}, time.Now())
+	if !checkLongText(c, &deal, nil, "") {
+		return
+	}
+	if !h.checkChecklist(c, deal, "") {
+		return
 	}
 
-	if err := h.db.Create(&deal).Error; err != nil {
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := guardOpenDealLimit(c, tx, deal, models.Deal{}); err != nil {
+			return err
+		}
+		// New deals go to the bottom of their stage on the board
+		position, err := models.BottomBoardPosition(tx, deal.Stage, deal.OwnerID, 0)
+		if err != nil {
+			return err
+		}
+		deal.BoardPosition = position
+		if err := tx.Create(&deal).Error; err != nil {
+			return err
+		}
+
+		// Reload with customer
+		tx.Preload("Customer").First(&deal, deal.ID)
+
+		// Log audit
+		return logAudit(c, tx, "deal", deal.ID, models.AuditActionCreate, nil, &deal)
+	})
+	if respondOpenDealLimit(c, err) || respondAuditFailure(c, err) {
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to create deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create deal"),
 		})
 		return
 	}
 
-	// Reload with customer
-	h.db.Preload("Customer").First(&deal, deal.ID)
-
-	// Log audit
-	h.logAudit(c, "deal", deal.ID, models.AuditActionCreate, nil, &deal)
-
-	c.JSON(http.StatusCreated, deal)
+	c.JSON(http.StatusCreated, DealCreateResponse{Deal: deal, Meta: meta})
 }
 
 // GetDeal returns a single deal by ID
@@ -241,28 +321,43 @@ func (h *DealHandler) GetDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid deal ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid deal ID"),
 		})
 		return
 	}
 
 	var deal models.Deal
-	if err := h.db.Preload("Customer").Preload("Contact").Preload("Activities").Preload("Notes").First(&deal, id).Error; err != nil {
+	if err := h.db.WithContext(c).Preload("Customer").Preload("Contact").Preload("Activities").Preload("Notes").First(&deal, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "DEAL_NOT_FOUND",
-				"message": "Deal not found",
+				"message": i18n.Message(c, "DEAL_NOT_FOUND", "Deal not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal"),
+		})
+		return
+	}
+
+	checklist, err := loadDealChecklist(h.db.WithContext(c), deal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal checklist"),
 		})
 		return
 	}
+	deal.Checklist = &checklist
+
+	// The deal is returned in full, its timeline as previews
+	preview.Mark(deal.Activities, h.timeline)
+	preview.Mark(deal.Notes, h.timeline)
 
 	c.JSON(http.StatusOK, deal)
 }
@@ -275,29 +370,33 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid deal ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid deal ID"),
 		})
 		return
 	}
 
 	var deal models.Deal
-	if err := h.db.First(&deal, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&deal, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "DEAL_NOT_FOUND",
-				"message": "Deal not found",
+				"message": i18n.Message(c, "DEAL_NOT_FOUND", "Deal not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal"),
 		})
 		return
 	}
 
+	if rejectArchived(c, deal.ArchivedAt) {
+		return
+	}
+
 	oldDeal := deal
 
 	var req DealUpdateRequest
@@ -305,11 +404,100 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
+	// Enforce field-level edit permissions and the change reason policy
+	changed := dealUpdateChangedFields(req, deal)
+	if !enforceFieldPermissions(c, models.EntityDeal, deal.OwnerID, changed) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityDeal, models.IsClosedDealStage(deal.Stage), changed, req.ChangeReason) {
+		return
+	}
+
+	// Guard currency changes so the amount's value is not silently altered.
+	// The guard applies to the stage the deal is moving to.
+	targetStage := deal.Stage
+	if req.Stage != "" {
+		targetStage = req.Stage
+	}
+	var conversion *currencyConversion
+	if req.Currency != "" && !strings.EqualFold(req.Currency, deal.Currency) {
+		if models.IsClosedDealStage(targetStage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "CURRENCY_IMMUTABLE",
+				"message": i18n.Message(c, "CURRENCY_IMMUTABLE", "Currency of a closed deal cannot be changed"),
+			})
+			return
+		}
+
+		newCurrency := strings.ToUpper(req.Currency)
+		convert, _ := strconv.ParseBool(c.DefaultQuery("convert", "false"))
+		if convert {
+			// The converted amount is computed from the stored one, so an
+			// amount given alongside would contradict it
+			if req.Amount != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error":   "validation_error",
+					"code":    "AMOUNT_WITH_CONVERSION",
+					"message": i18n.Message(c, "AMOUNT_WITH_CONVERSION", "amount cannot be given when convert=true converts the stored amount"),
+				})
+				return
+			}
+			effectiveDate := time.Now()
+			if value := c.Query("effective_date"); value != "" {
+				t, err := time.Parse("2006-01-02", value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{
+						"error":   "validation_error",
+						"code":    "INVALID_DATE",
+						"message": i18n.Message(c, "INVALID_DATE", "effective_date must be in YYYY-MM-DD format"),
+					})
+					return
+				}
+				effectiveDate = t
+			}
+
+			rate, err := h.findExchangeRate(c, deal.Currency, newCurrency, effectiveDate)
+			if err != nil {
+				if err == gorm.ErrRecordNotFound {
+					c.JSON(http.StatusBadRequest, gin.H{
+						"error":   "validation_error",
+						"code":    "EXCHANGE_RATE_NOT_FOUND",
+						"message": i18n.Message(c, "EXCHANGE_RATE_NOT_FOUND", "No exchange rate found for "+deal.Currency+" to "+newCurrency),
+					})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "internal_error",
+					"code":    "DATABASE_ERROR",
+					"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch exchange rate"),
+				})
+				return
+			}
+
+			conversion = &currencyConversion{
+				FromCurrency:  deal.Currency,
+				ToCurrency:    newCurrency,
+				Rate:          rate,
+				EffectiveDate: effectiveDate.Format("2006-01-02"),
+				OldAmount:     deal.Amount,
+				NewAmount:     models.ConvertAmount(deal.Amount, rate),
+			}
+			deal.Amount = conversion.NewAmount
+		} else if models.IsPastQualification(targetStage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "CURRENCY_CHANGE_FORBIDDEN",
+				"message": i18n.Message(c, "CURRENCY_CHANGE_FORBIDDEN", "Currency cannot be changed past qualification unless convert=true is passed"),
+			})
+			return
+		}
+		deal.Currency = newCurrency
+	}
+
 	// Update fields
 	if req.Title != "" {
 		deal.Title = req.Title
@@ -328,18 +516,20 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "validation_error",
 				"code":    "INVALID_STAGE",
-				"message": "Invalid deal stage",
+				"message": i18n.Message(c, "INVALID_STAGE", "Invalid deal stage"),
 			})
 			return
 		}
-		deal.Stage = req.Stage
+		if !applyStageTransition(c, &deal, oldDeal, req.Stage, req.LostReason) {
+			return
+		}
+		if !h.checkChecklist(c, deal, oldDeal.Stage) {
+			return
+		}
 	}
 	if req.Amount != nil {
 		deal.Amount = *req.Amount
 	}
-	if req.Currency != "" {
-		deal.Currency = req.Currency
-	}
 	if req.Probability != nil {
 		prob := *req.Probability
 		if prob < 0 {
@@ -362,22 +552,48 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 	if req.LostReason != "" {
 		deal.LostReason = req.LostReason
 	}
+	if req.ExternalID != "" {
+		deal.ExternalID = strings.TrimSpace(req.ExternalID)
+	}
+	if !applyNextStep(c, &deal, oldDeal, req.NextStep, req.NextStepDue) {
+		return
+	}
+	if !checkLongText(c, &deal, &oldDeal, "") {
+		return
+	}
 
-	if err := h.db.Save(&deal).Error; err != nil {
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := guardOpenDealLimit(c, tx, deal, oldDeal); err != nil {
+			return err
+		}
+		if err := tx.Save(&deal).Error; err != nil {
+			return err
+		}
+
+		// Reload with customer
+		tx.Preload("Customer").First(&deal, deal.ID)
+
+		// Log audit
+		if err := logAudit(c, tx, "deal", deal.ID, models.AuditActionUpdate, &oldDeal, &deal); err != nil {
+			return err
+		}
+		if conversion != nil {
+			return h.logCurrencyConversion(c, tx, deal.ID, conversion)
+		}
+		return nil
+	})
+	if respondOpenDealLimit(c, err) || respondAuditFailure(c, err) {
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update deal"),
 		})
 		return
 	}
 
-	// Reload with customer
-	h.db.Preload("Customer").First(&deal, deal.ID)
-
-	// Log audit
-	h.logAudit(c, "deal", deal.ID, models.AuditActionUpdate, &oldDeal, &deal)
-
 	c.JSON(http.StatusOK, deal)
 }
 
@@ -389,29 +605,38 @@ func (h *DealHandler) PatchDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid deal ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid deal ID"),
 		})
 		return
 	}
 
 	var deal models.Deal
-	if err := h.db.First(&deal, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&deal, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "DEAL_NOT_FOUND",
-				"message": "Deal not found",
+				"message": i18n.Message(c, "DEAL_NOT_FOUND", "Deal not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal"),
 		})
 		return
 	}
 
+	if rejectArchived(c, deal.ArchivedAt) {
+		return
+	}
+
+	if isMergePatch(c) {
+		h.mergePatchDeal(c, deal)
+		return
+	}
+
 	oldDeal := deal
 
 	var req DealStageTransitionRequest
@@ -419,51 +644,310 @@ func (h *DealHandler) PatchDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
+	// Enforce field-level edit permissions and the change reason policy
+	if req.Stage != deal.Stage && (!enforceFieldPermissions(c, models.EntityDeal, deal.OwnerID, []string{"stage"}) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityDeal, models.IsClosedDealStage(deal.Stage), []string{"stage"}, req.ChangeReason)) {
+		return
+	}
+
 	// Validate stage
 	if !models.IsValidDealStage(req.Stage) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_STAGE",
-			"message": "Invalid deal stage",
+			"message": i18n.Message(c, "INVALID_STAGE", "Invalid deal stage"),
 		})
 		return
 	}
 
 	// Update stage
-	deal.Stage = req.Stage
+	if !applyStageTransition(c, &deal, oldDeal, req.Stage, req.LostReason) {
+		return
+	}
+	if !h.checkChecklist(c, deal, oldDeal.Stage) {
+		return
+	}
+	if !applyNextStep(c, &deal, oldDeal, req.NextStep, req.NextStepDue) {
+		return
+	}
+	if !checkLongText(c, &deal, &oldDeal, "") {
+		return
+	}
 
-	// If closed, set actual close date
-	if req.Stage == models.DealStageClosedWon || req.Stage == models.DealStageClosedLost {
-		now := time.Now()
-		deal.ActualCloseDate = &now
-		if req.Stage == models.DealStageClosedLost && req.LostReason != "" {
-			deal.LostReason = req.LostReason
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := guardOpenDealLimit(c, tx, deal, oldDeal); err != nil {
+			return err
 		}
-	}
+		if err := tx.Save(&deal).Error; err != nil {
+			return err
+		}
+
+		// Reload with customer
+		tx.Preload("Customer").First(&deal, deal.ID)
 
-	if err := h.db.Save(&deal).Error; err != nil {
+		// Log audit
+		return logAudit(c, tx, "deal", deal.ID, models.AuditActionUpdate, &oldDeal, &deal)
+	})
+	if respondOpenDealLimit(c, err) || respondAuditFailure(c, err) {
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update deal"),
 		})
 		return
 	}
 
-	// Reload with customer
-	h.db.Preload("Customer").First(&deal, deal.ID)
+	c.JSON(http.StatusOK, deal)
+}
+
+// mergePatchDeal applies a JSON Merge Patch to a deal. Validation runs on the
+// patched deal. Currency changes follow the PUT rules but never convert the
+// amount; use PUT with convert=true for that.
+func (h *DealHandler) mergePatchDeal(c *gin.Context, deal models.Deal) {
+	oldDeal := deal
+
+	changed, ok := applyMergePatch(c, &deal, dealMergePatchFields)
+	if !ok {
+		return
+	}
+	if len(changed) == 0 {
+		c.JSON(http.StatusOK, deal)
+		return
+	}
 
-	// Log audit
-	h.logAudit(c, "deal", deal.ID, models.AuditActionUpdate, &oldDeal, &deal)
+	// Enforce field-level edit permissions and the change reason policy
+	if !enforceFieldPermissions(c, models.EntityDeal, oldDeal.OwnerID, changed) ||
+		!requireChangeReason(c, h.db.WithContext(c), models.EntityDeal, models.IsClosedDealStage(oldDeal.Stage), changed, c.GetString(mergePatchReasonKey)) {
+		return
+	}
+
+	deal.Title = strings.TrimSpace(deal.Title)
+	if deal.Title == "" || len(deal.Title) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "title must be between 1 and 255 characters"),
+		})
+		return
+	}
+	if !models.IsValidDealStage(deal.Stage) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_STAGE",
+			"message": i18n.Message(c, "INVALID_STAGE", "Invalid deal stage"),
+		})
+		return
+	}
+	if deal.Probability < 0 || deal.Probability > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "probability must be between 0 and 100"),
+		})
+		return
+	}
+	deal.ExternalID = strings.TrimSpace(deal.ExternalID)
+	if len(deal.ExternalID) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "external_id must be at most 100 characters"),
+		})
+		return
+	}
+
+	if patchTouched(changed, "currency") {
+		deal.Currency = strings.ToUpper(deal.Currency)
+		if len(deal.Currency) != 3 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_REQUEST",
+				"message": i18n.Message(c, "INVALID_REQUEST", "currency must be a 3-letter ISO code"),
+			})
+			return
+		}
+		// deal.Stage is the stage the patch moves the deal to
+		if models.IsClosedDealStage(deal.Stage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "CURRENCY_IMMUTABLE",
+				"message": i18n.Message(c, "CURRENCY_IMMUTABLE", "Currency of a closed deal cannot be changed"),
+			})
+			return
+		}
+		if models.IsPastQualification(deal.Stage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "CURRENCY_CHANGE_FORBIDDEN",
+				"message": i18n.Message(c, "CURRENCY_CHANGE_FORBIDDEN", "Currency cannot be changed past qualification unless convert=true is passed"),
+			})
+			return
+		}
+	}
+
+	// Verify a new customer exists
+	if patchTouched(changed, "customer_id") {
+		var customer models.Customer
+		if err := h.db.WithContext(c).First(&customer, deal.CustomerID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "validation_error",
+					"code":    "CUSTOMER_NOT_FOUND",
+					"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to verify customer"),
+			})
+			return
+		}
+	}
+
+	// Closing a deal stamps the close date unless the patch sets it, and
+	// reopening it clears the close date and lost reason
+	if patchTouched(changed, "stage") {
+		if !checkStageTransition(c, oldDeal.Stage, deal) || !h.checkChecklist(c, deal, oldDeal.Stage) {
+			return
+		}
+		deal.SyncCloseFields(oldDeal, time.Now())
+		for _, field := range []string{"actual_close_date", "lost_reason"} {
+			if !patchTouched(changed, field) {
+				changed = append(changed, field)
+			}
+		}
+	}
+
+	deal.NextStep = strings.TrimSpace(deal.NextStep)
+	if len(deal.NextStep) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "next_step must be at most 255 characters"),
+		})
+		return
+	}
+	if !applyNextStep(c, &deal, oldDeal, nil, nil) {
+		return
+	}
+	if !checkLongText(c, &deal, &oldDeal, "") {
+		return
+	}
+	if patchTouched(changed, "next_step") || patchTouched(changed, "next_step_due") {
+		changed = append(changed, "next_step_set_at", "next_step_nudged_at")
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := guardOpenDealLimit(c, tx, deal, oldDeal); err != nil {
+			return err
+		}
+		// Select writes cleared fields, which Updates would otherwise skip
+		if err := tx.Model(&deal).Select(changed).Updates(&deal).Error; err != nil {
+			return err
+		}
+
+		// Reload with customer
+		tx.Preload("Customer").First(&deal, deal.ID)
+
+		// Log audit
+		return logAudit(c, tx, "deal", deal.ID, models.AuditActionUpdate, &oldDeal, &deal)
+	})
+	if respondOpenDealLimit(c, err) || respondAuditFailure(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update deal"),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, deal)
 }
 
+// applyStageTransition moves a deal from its previous state to a stage,
+// setting the lost reason of lost deals and syncing the close fields. It
+// writes a 422 response and returns false when the move is refused.
+func applyStageTransition(c *gin.Context, deal *models.Deal, previous models.Deal, stage models.DealStage, lostReason string) bool {
+	deal.Stage = stage
+	if stage == models.DealStageClosedLost && lostReason != "" {
+		deal.LostReason = lostReason
+	}
+	if !checkStageTransition(c, previous.Stage, *deal) {
+		return false
+	}
+
+	deal.SyncCloseFields(previous, time.Now())
+	return true
+}
+
+// checkStageTransition checks that a deal may move from a stage to its
+// current one, writing a 422 response listing the allowed stages otherwise.
+// Only admins may reopen closed deals or move deals back.
+func checkStageTransition(c *gin.Context, from models.DealStage, deal models.Deal) bool {
+	user, _ := middleware.GetUserFromContext(c)
+	reopen := models.HoldsRole(user.Role, models.RoleAdmin)
+	if !models.CanTransitionDeal(from, deal.Stage, reopen) {
+		message := "Cannot move a deal from " + string(from) + " to " + string(deal.Stage)
+		if models.IsDealReopen(from, deal.Stage) {
+			message += "; only admins can reopen deals"
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_TRANSITION",
+			"message": i18n.Message(c, "INVALID_TRANSITION", message),
+			"from":    from,
+			"allowed": models.AllowedDealTransitions(from, reopen),
+		})
+		return false
+	}
+	if deal.NeedsLostReason(from) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "validation_error",
+			"code":    "MISSING_LOST_REASON",
+			"message": i18n.Message(c, "MISSING_LOST_REASON", "A lost reason is required to close a deal as lost"),
+		})
+		return false
+	}
+	return true
+}
+
+// applyNextStep sets the requested next step fields, then refuses a
+// forward stage move that leaves the deal without a next step when next
+// steps are required, writing the error response
+func applyNextStep(c *gin.Context, deal *models.Deal, oldDeal models.Deal, nextStep *string, nextStepDue *time.Time) bool {
+	if nextStep != nil {
+		deal.NextStep = strings.TrimSpace(*nextStep)
+	}
+	if nextStepDue != nil {
+		deal.NextStepDue = nextStepDue
+	}
+
+	if deal.NeedsNextStep(oldDeal.Stage) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "NEXT_STEP_REQUIRED",
+			"message": i18n.Message(c, "NEXT_STEP_REQUIRED", "Record a next step before moving the deal forward"),
+		})
+		return false
+	}
+
+	deal.TrackNextStep(oldDeal, time.Now())
+	return true
+}
+
 // DeleteDeal soft-deletes a deal
 // DELETE /admin/deals/:id
 func (h *DealHandler) DeleteDeal(c *gin.Context) {
@@ -472,60 +956,223 @@ func (h *DealHandler) DeleteDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid deal ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid deal ID"),
 		})
 		return
 	}
 
 	var deal models.Deal
-	if err := h.db.First(&deal, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&deal, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "DEAL_NOT_FOUND",
-				"message": "Deal not found",
+				"message": i18n.Message(c, "DEAL_NOT_FOUND", "Deal not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal"),
 		})
 		return
 	}
 
-	if err := h.db.Delete(&deal).Error; err != nil {
+	if rejectArchived(c, deal.ArchivedAt) {
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&deal).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "deal", deal.ID, models.AuditActionDelete, &deal, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to delete deal",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete deal"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "deal", deal.ID, models.AuditActionDelete, &deal, nil)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Deal deleted successfully",
 	})
 }
 
-// logAudit creates an audit log entry
-func (h *DealHandler) logAudit(c *gin.Context, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) {
+// ArchiveDeal archives a deal, hiding it from default lists and reports
+// POST /admin/deals/:id/archive
+func (h *DealHandler) ArchiveDeal(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	if rejectArchived(c, deal.ArchivedAt) {
+		return
+	}
+
+	oldDeal := *deal
+	now := time.Now()
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(deal).Update("archived_at", now).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "deal", deal.ID, models.AuditActionArchive, &oldDeal, deal)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to archive deal"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, deal)
+}
+
+// UnarchiveDeal restores an archived deal
+// POST /admin/deals/:id/unarchive
+func (h *DealHandler) UnarchiveDeal(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	if deal.ArchivedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "NOT_ARCHIVED",
+			"message": i18n.Message(c, "NOT_ARCHIVED", "Deal is not archived"),
+		})
+		return
+	}
+
+	oldDeal := *deal
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		unarchived := *deal
+		unarchived.ArchivedAt = nil
+		if err := guardOpenDealLimit(c, tx, unarchived, oldDeal); err != nil {
+			return err
+		}
+		if err := tx.Model(deal).Update("archived_at", nil).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "deal", deal.ID, models.AuditActionUnarchive, &oldDeal, deal)
+	})
+	if respondOpenDealLimit(c, err) || respondAuditFailure(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to unarchive deal"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, deal)
+}
+
+// findDeal loads the deal identified by the :id route parameter,
+// writing the error response when it cannot be found
+func (h *DealHandler) findDeal(c *gin.Context) (*models.Deal, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid deal ID"),
+		})
+		return nil, false
+	}
+
+	var deal models.Deal
+	if err := h.db.WithContext(c).First(&deal, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "DEAL_NOT_FOUND",
+				"message": i18n.Message(c, "DEAL_NOT_FOUND", "Deal not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deal"),
+		})
+		return nil, false
+	}
+
+	return &deal, true
+}
+
+// currencyConversion records how a deal amount was converted between currencies
+type currencyConversion struct {
+	FromCurrency  string  `json:"from_currency"`
+	ToCurrency    string  `json:"to_currency"`
+	Rate          float64 `json:"rate"`
+	EffectiveDate string  `json:"effective_date"`
+	OldAmount     float64 `json:"old_amount"`
+	NewAmount     float64 `json:"new_amount"`
+}
+
+// findExchangeRate returns the rate converting from one currency to another
+// that was effective on the given date, falling back to the inverse pair
+func (h *DealHandler) findExchangeRate(c *gin.Context, from, to string, effectiveDate time.Time) (float64, error) {
+	var rate models.ExchangeRate
+	err := h.db.WithContext(c).Where("base_currency = ? AND quote_currency = ? AND effective_date <= ?", from, to, effectiveDate).
+		Order("effective_date DESC").First(&rate).Error
+	if err == nil {
+		return rate.Rate, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	err = h.db.WithContext(c).Where("base_currency = ? AND quote_currency = ? AND effective_date <= ?", to, from, effectiveDate).
+		Order("effective_date DESC").First(&rate).Error
+	if err != nil {
+		return 0, err
+	}
+	if rate.Rate == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return 1 / rate.Rate, nil
+}
+
+// logCurrencyConversion creates an audit log entry describing a currency
+// conversion in the transaction saving it, like logAudit
+func (h *DealHandler) logCurrencyConversion(c *gin.Context, tx *gorm.DB, dealID uint, conversion *currencyConversion) error {
 	user, _ := middleware.GetUserFromContext(c)
 
+	newValues, _ := json.Marshal(gin.H{"currency_conversion": conversion})
+
 	audit := models.AuditLog{
-		ResourceType: resourceType,
-		ResourceID:   resourceID,
-		Action:       action,
+		ResourceType: "deal",
+		ResourceID:   dealID,
+		Action:       models.AuditActionUpdate,
 		UserID:       user.ID,
 		UserName:     user.Name,
 		UserRole:     user.Role,
+		NewValues:    string(newValues),
 		IPAddress:    c.ClientIP(),
 		UserAgent:    c.Request.UserAgent(),
 	}
 
-	h.db.Create(&audit)
+	return audittrail.Record(c, tx, &audit)
 }
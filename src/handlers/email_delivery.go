@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/emaildelivery"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxEmailEventsBody bounds the size of one delivery webhook
+const maxEmailEventsBody = 5 << 20
+
+// EmailDeliveryHandler handles email provider delivery webhooks
+type EmailDeliveryHandler struct {
+	db        *gorm.DB
+	providers map[string]emaildelivery.Provider
+}
+
+// NewEmailDeliveryHandler creates a new EmailDeliveryHandler. providers holds
+// the configured providers by name.
+func NewEmailDeliveryHandler(db *gorm.DB, providers map[string]emaildelivery.Provider) *EmailDeliveryHandler {
+	return &EmailDeliveryHandler{db: db, providers: providers}
+}
+
+// EmailEventsResult summarizes an ingested delivery webhook
+type EmailEventsResult struct {
+	Received   int `json:"received"`
+	Recorded   int `json:"recorded"`
+	Duplicates int `json:"duplicates"`
+	Unmatched  int `json:"unmatched"` // Events for emails the CRM did not send
+}
+
+// IngestEvents records the delivery events of a provider webhook against
+// the email activities they report on. Hard bounces mark the recipient's
+// email invalid. Events for unknown emails are acknowledged and skipped, so
+// providers do not retry them.
+// POST /integrations/email/events?provider=generic|sendgrid
+func (h *EmailDeliveryHandler) IngestEvents(c *gin.Context) {
+	name := c.DefaultQuery("provider", "generic")
+	provider, ok := h.providers[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "EMAIL_PROVIDER_NOT_FOUND",
+			"message": i18n.Message(c, "EMAIL_PROVIDER_NOT_FOUND", "Unknown or unconfigured email provider"),
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxEmailEventsBody+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_WEBHOOK_PAYLOAD",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_PAYLOAD", "Invalid webhook payload"),
+		})
+		return
+	}
+	if len(body) > maxEmailEventsBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "validation_error",
+			"code":    "WEBHOOK_PAYLOAD_TOO_LARGE",
+			"message": i18n.Message(c, "WEBHOOK_PAYLOAD_TOO_LARGE", "Webhook payload is too large"),
+		})
+		return
+	}
+
+	now := time.Now()
+	if err := provider.Verify(c.Request.Header, body, now); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "INVALID_WEBHOOK_SIGNATURE",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_SIGNATURE", "Invalid webhook signature"),
+		})
+		return
+	}
+	events, err := provider.Parse(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_WEBHOOK_PAYLOAD",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_PAYLOAD", "Invalid webhook payload"),
+		})
+		return
+	}
+
+	result := EmailEventsResult{Received: len(events)}
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		for _, event := range events {
+			if event.OccurredAt.IsZero() || event.OccurredAt.After(now) {
+				event.OccurredAt = now
+			}
+
+			activity, err := findDeliveryActivity(tx, event)
+			if err != nil {
+				return err
+			}
+			if activity == nil {
+				result.Unmatched++
+				continue
+			}
+
+			reason := event.Reason
+			if len(reason) > 500 {
+				reason = strings.ToValidUTF8(reason[:500], "")
+			}
+			recorded := models.EmailEvent{
+				ActivityID:      activity.ID,
+				Type:            event.Type,
+				Nonce:           event.Nonce(name),
+				Reason:          reason,
+				RecipientDomain: event.Domain(),
+				CreatedAt:       event.OccurredAt,
+			}
+			create := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&recorded)
+			if create.Error != nil {
+				return create.Error
+			}
+			if create.RowsAffected == 0 {
+				result.Duplicates++
+				continue
+			}
+			result.Recorded++
+
+			// Keep the latest status; providers may deliver events out of order
+			if err := tx.Model(&models.Activity{}).
+				Where("id = ? AND (delivery_status_at IS NULL OR delivery_status_at <= ?)", activity.ID, event.OccurredAt).
+				UpdateColumns(map[string]interface{}{
+					"delivery_status":    event.Type,
+					"delivery_status_at": event.OccurredAt,
+				}).Error; err != nil {
+				return err
+			}
+
+			if event.Type == models.EmailEventBounce {
+				if err := markEmailInvalid(c, tx, *activity, event.Email, now); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to record email events"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// findDeliveryActivity returns the email activity an event reports on, by
+// activity ID when the provider echoes it back or by message ID otherwise,
+// or nil when there is none
+func findDeliveryActivity(tx *gorm.DB, event emaildelivery.Event) (*models.Activity, error) {
+	q := tx.Where("type = ?", models.ActivityTypeEmail)
+	switch {
+	case event.ActivityID != 0:
+		q = q.Where("id = ?", event.ActivityID)
+	case event.MessageID != "":
+		q = q.Where("message_id = ?", event.MessageID)
+	default:
+		return nil, nil
+	}
+
+	var activities []models.Activity
+	if err := q.Order("id DESC").Limit(1).Find(&activities).Error; err != nil {
+		return nil, err
+	}
+	if len(activities) == 0 {
+		return nil, nil
+	}
+	return &activities[0], nil
+}
+
+// markEmailInvalid flags the email of the contact a bounced email went to,
+// or of the customer otherwise. A bounce for an address the recipient no
+// longer has is ignored.
+func markEmailInvalid(ctx context.Context, tx *gorm.DB, activity models.Activity, email string, now time.Time) error {
+	var (
+		resourceType string
+		id           uint
+		target       interface{}
+		old, updated interface{}
+	)
+	switch {
+	case activity.ContactID != nil:
+		var contact models.Contact
+		if err := tx.Limit(1).Find(&contact, *activity.ContactID).Error; err != nil || contact.ID == 0 {
+			return err
+		}
+		if contact.EmailInvalidAt != nil || (email != "" && !strings.EqualFold(contact.Email, email)) {
+			return nil
+		}
+		marked := contact
+		marked.EmailInvalidAt = &now
+		resourceType, id, target, old, updated = "contact", contact.ID, &contact, contact, marked
+	case activity.CustomerID != nil:
+		var customer models.Customer
+		if err := tx.Limit(1).Find(&customer, *activity.CustomerID).Error; err != nil || customer.ID == 0 {
+			return err
+		}
+		if customer.EmailInvalidAt != nil || (email != "" && !strings.EqualFold(customer.Email, email)) {
+			return nil
+		}
+		marked := customer
+		marked.EmailInvalidAt = &now
+		resourceType, id, target, old, updated = "customer", customer.ID, &customer, customer, marked
+	default:
+		return nil
+	}
+
+	if err := tx.Model(target).UpdateColumn("email_invalid_at", now).Error; err != nil {
+		return err
+	}
+
+	audit := models.AuditLog{
+		ResourceType: resourceType,
+		ResourceID:   id,
+		Action:       models.AuditActionUpdate,
+		UserName:     "system:email-events",
+		UserRole:     "system",
+		CreatedAt:    now,
+	}
+	audit.OldValues, audit.NewValues = models.AuditDiff(old, updated)
+	return audittrail.Record(ctx, tx, &audit)
+}
+
+// recipientEmailInvalid reports whether the recipient of an email activity
+// has an email that hard-bounced
+func recipientEmailInvalid(db *gorm.DB, activity models.Activity) (bool, error) {
+	var count int64
+	var err error
+	switch {
+	case activity.ContactID != nil:
+		err = db.Model(&models.Contact{}).Where("id = ? AND email_invalid_at IS NOT NULL", *activity.ContactID).Count(&count).Error
+	case activity.CustomerID != nil:
+		err = db.Model(&models.Customer{}).Where("id = ? AND email_invalid_at IS NOT NULL", *activity.CustomerID).Count(&count).Error
+	default:
+		return false, nil
+	}
+	return count > 0, err
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/emailtracking"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// trackingPixel is a transparent 1x1 GIF
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// EmailTrackingHandler handles email engagement tracking endpoints
+type EmailTrackingHandler struct {
+	db            *gorm.DB
+	signer        *emailtracking.Signer
+	publicBaseURL string
+}
+
+// NewEmailTrackingHandler creates a new EmailTrackingHandler
+func NewEmailTrackingHandler(db *gorm.DB, signer *emailtracking.Signer, publicBaseURL string) *EmailTrackingHandler {
+	return &EmailTrackingHandler{
+		db:            db,
+		signer:        signer,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// IssueTrackingTokensRequest optionally records the provider message ID of
+// the email, which delivery webhooks are matched by, and lists the links of
+// the email to track clicks on
+type IssueTrackingTokensRequest struct {
+	MessageID string   `json:"message_id" binding:"omitempty,max=255"`
+	Links     []string `json:"links" binding:"omitempty,max=50,dive,max=2048"`
+}
+
+// IssueTrackingTokens issues the open pixel and unsubscribe links to embed
+// in an email when it is sent, and a signed click link for each of its
+// links. Emails to a recipient whose address hard-bounced are refused.
+// POST /admin/activities/:id/email-tracking
+func (h *EmailTrackingHandler) IssueTrackingTokens(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid activity ID"),
+		})
+		return
+	}
+
+	var req IssueTrackingTokensRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	var activity models.Activity
+	if err := h.db.WithContext(c).First(&activity, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "ACTIVITY_NOT_FOUND",
+				"message": i18n.Message(c, "ACTIVITY_NOT_FOUND", "Activity not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch activity"),
+		})
+		return
+	}
+
+	if activity.Type != models.ActivityTypeEmail {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "NOT_EMAIL_ACTIVITY",
+			"message": i18n.Message(c, "NOT_EMAIL_ACTIVITY", "Tracking links can only be issued for email activities"),
+		})
+		return
+	}
+
+	invalid, err := recipientEmailInvalid(h.db.WithContext(c), activity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch recipient"),
+		})
+		return
+	}
+	if invalid {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "EMAIL_INVALID",
+			"message": i18n.Message(c, "EMAIL_INVALID", "The recipient's email address bounced; update it before sending"),
+		})
+		return
+	}
+
+	// Links are signed first so an invalid one leaves the activity unchanged
+	links := make([]models.EmailTrackedLink, 0, len(req.Links))
+	for _, link := range req.Links {
+		token, err := h.signer.IssueClick(activity.ID, link)
+		if errors.Is(err, emailtracking.ErrInvalidLink) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_LINK",
+				"message": i18n.Message(c, "INVALID_LINK", "Tracked links must be absolute http or https URLs"),
+				"link":    link,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "INTERNAL_ERROR",
+				"message": i18n.Message(c, "INTERNAL_ERROR", "Failed to issue tracking links"),
+			})
+			return
+		}
+		links = append(links, models.EmailTrackedLink{URL: link, Token: token, TrackingURL: h.publicBaseURL + "/public/email/click/" + token})
+	}
+
+	if req.MessageID != "" && req.MessageID != activity.MessageID {
+		if err := h.db.WithContext(c).Model(&activity).Update("message_id", req.MessageID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to record message ID"),
+			})
+			return
+		}
+	}
+
+	openToken, err := h.signer.Issue(activity.ID, emailtracking.PurposeOpen)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "INTERNAL_ERROR",
+			"message": i18n.Message(c, "INTERNAL_ERROR", "Failed to issue tracking links"),
+		})
+		return
+	}
+	unsubscribeToken, err := h.signer.Issue(activity.ID, emailtracking.PurposeUnsubscribe)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "INTERNAL_ERROR",
+			"message": i18n.Message(c, "INTERNAL_ERROR", "Failed to issue tracking links"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.EmailTrackingTokens{
+		ActivityID:       activity.ID,
+		OpenToken:        openToken,
+		OpenURL:          h.publicBaseURL + "/public/email/open/" + openToken,
+		UnsubscribeToken: unsubscribeToken,
+		UnsubscribeURL:   h.publicBaseURL + "/public/email/unsubscribe/" + unsubscribeToken,
+		Links:            links,
+	})
+}
+
+// TrackOpen records an email open and serves a 1x1 pixel. The pixel is
+// served even for invalid or expired tokens so email clients never show a
+// broken image.
+// GET /public/email/open/:token
+func (h *EmailTrackingHandler) TrackOpen(c *gin.Context) {
+	if token, err := h.signer.Verify(c.Param("token"), emailtracking.PurposeOpen); err == nil {
+		h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			_, err := recordEmailEvent(c, tx, token, models.EmailEventOpen)
+			return err
+		})
+	}
+
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+	c.Data(http.StatusOK, "image/gif", trackingPixel)
+}
+
+// TrackClick records a click on a tracked link and redirects to it. Only
+// links signed into the token are followed, and expired tokens still
+// redirect without recording the click.
+// GET /public/email/click/:token
+func (h *EmailTrackingHandler) TrackClick(c *gin.Context) {
+	token, err := h.signer.Verify(c.Param("token"), emailtracking.PurposeClick)
+	if err != nil && !errors.Is(err, emailtracking.ErrExpiredToken) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_TRACKING_TOKEN",
+			"message": i18n.Message(c, "INVALID_TRACKING_TOKEN", "This link is invalid"),
+		})
+		return
+	}
+	if err == nil {
+		h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			_, err := recordEmailEvent(c, tx, token, models.EmailEventClick)
+			return err
+		})
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, token.URL)
+}
+
+// Unsubscribe records an email opt-out for the recipient of an email
+// GET /public/email/unsubscribe/:token
+func (h *EmailTrackingHandler) Unsubscribe(c *gin.Context) {
+	token, err := h.signer.Verify(c.Param("token"), emailtracking.PurposeUnsubscribe)
+	if errors.Is(err, emailtracking.ErrExpiredToken) {
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "gone",
+			"code":    "TRACKING_TOKEN_EXPIRED",
+			"message": i18n.Message(c, "TRACKING_TOKEN_EXPIRED", "This link has expired"),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_TRACKING_TOKEN",
+			"message": i18n.Message(c, "INVALID_TRACKING_TOKEN", "This link is invalid"),
+		})
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		activity, err := recordEmailEvent(c, tx, token, models.EmailEventUnsubscribe)
+		if err != nil || activity == nil {
+			return err
+		}
+
+		// Opt out the contact the email went to, or the customer otherwise
+		now := time.Now()
+		if activity.ContactID != nil {
+			return tx.Model(&models.Contact{}).
+				Where("id = ? AND email_opt_out_at IS NULL", *activity.ContactID).
+				Update("email_opt_out_at", now).Error
+		}
+		if activity.CustomerID != nil {
+			return tx.Model(&models.Customer{}).
+				Where("id = ? AND email_opt_out_at IS NULL", *activity.CustomerID).
+				Update("email_opt_out_at", now).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to record unsubscribe"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": i18n.Message(c, "UNSUBSCRIBED", "You have been unsubscribed"),
+	})
+}
+
+// recordEmailEvent stores the event for a token once. It returns the email
+// activity when the event was newly recorded, and nil for replays or
+// activities that no longer exist.
+func recordEmailEvent(c *gin.Context, tx *gorm.DB, token emailtracking.Token, eventType models.EmailEventType) (*models.Activity, error) {
+	var activity models.Activity
+	if err := tx.Where("type = ?", models.ActivityTypeEmail).First(&activity, token.ActivityID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	event := models.EmailEvent{
+		ActivityID: activity.ID,
+		Type:       eventType,
+		Nonce:      token.Nonce,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &activity, nil
+}
+
+// loadEmailEngagement aggregates the engagement events of an email activity
+func loadEmailEngagement(db *gorm.DB, activityID uint) models.EmailEngagement {
+	var rows []struct {
+		Type    models.EmailEventType
+		Count   int64
+		FirstAt *time.Time
+		LastAt  *time.Time
+	}
+	db.Model(&models.EmailEvent{}).
+		Select("type, COUNT(*) as count, MIN(created_at) as first_at, MAX(created_at) as last_at").
+		Where("activity_id = ?", activityID).
+		Group("type").
+		Scan(&rows)
+
+	var engagement models.EmailEngagement
+	for _, row := range rows {
+		switch row.Type {
+		case models.EmailEventOpen:
+			engagement.Opens = row.Count
+			engagement.FirstOpenedAt = row.FirstAt
+			engagement.LastOpenedAt = row.LastAt
+		case models.EmailEventClick:
+			engagement.Clicks = row.Count
+		case models.EmailEventUnsubscribe:
+			engagement.Unsubscribed = row.Count > 0
+		}
+	}
+	return engagement
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"gorm.io/gorm"
+)
+
+// EntitySchema describes the fields of an entity so clients can generate
+// forms and list views instead of hardcoding them
+type EntitySchema struct {
+	Entity     string         `json:"entity"`
+	Fields     []FieldSchema  `json:"fields"`
+	Filters    []FilterSchema `json:"filters"`     // Query parameters of the list endpoint
+	SortFields []string       `json:"sort_fields"` // Values of sort_by; empty when the order is fixed
+}
+
+// FieldSchema describes one field of an entity. Editability is computed
+// for the requesting user.
+type FieldSchema struct {
+	Name              string     `json:"name"`
+	Type              string     `json:"type"` // string, integer, number, boolean or datetime
+	Nullable          bool       `json:"nullable"`
+	ReadOnly          bool       `json:"read_only"`
+	WritableOn        []string   `json:"writable_on"` // Operations accepting the field: create, update
+	RequiredOn        []string   `json:"required_on"` // Operations requiring the field
+	Rules             FieldRules `json:"rules"`
+	Filterable        bool       `json:"filterable"`
+	Sortable          bool       `json:"sortable"`
+	Editable          bool       `json:"editable"`            // The user may set it on any record
+	EditableWhenOwner bool       `json:"editable_when_owner"` // The user may set it on records they own
+}
+
+// FieldRules lists the validation rules of a field
+type FieldRules struct {
+	MinLength *int        `json:"min_length,omitempty"`
+	MaxLength *int        `json:"max_length,omitempty"`
+	Min       *float64    `json:"min,omitempty"`
+	Max       *float64    `json:"max,omitempty"`
+	Format    string      `json:"format,omitempty"` // email
+	Enum      []EnumValue `json:"enum,omitempty"`
+}
+
+// EnumValue is one allowed value of an enum field
+type EnumValue struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+}
+
+// FilterSchema describes a query parameter of an entity's list endpoint
+type FilterSchema struct {
+	Param string `json:"param"`
+	Type  string `json:"type"` // string, search, number, datetime, list or boolean
+}
+
+// entityEnum loads the allowed values of an enum field
+type entityEnum func(db *gorm.DB) ([]EnumValue, error)
+
+// entitySchemaSource registers an entity with the metadata endpoint. Field
+// types, validation rules, filters and sorting are read from the model,
+// the request bodies its handlers bind and its list query definition, so
+// the schema follows them as fields are added.
+type entitySchemaSource struct {
+	model       interface{} // Its JSON fields are the entity's fields
+	create      interface{} // Request body bound on create
+	update      interface{} // Request body bound on update
+	list        *query.Definition
+	table       string
+	archivable  bool                  // Lists leave out archived rows unless include_archived=true
+	owner       string                // Column of the record's owner, if any
+	permissions string                // Entity of its field-level edit permissions, if any
+	rules       map[string]FieldRules // Rules checked in handler code rather than binding tags
+	enums       map[string]entityEnum
+}
+
+// entitySchemaSources lists the entities described by GET /admin/meta/entities/:entity
+var entitySchemaSources = map[string]entitySchemaSource{
+	"customers": {
+		model:       models.Customer{},
+		create:      CustomerCreateRequest{},
+		update:      CustomerUpdateRequest{},
+		list:        &customerListQuery,
+		table:       "customers",
+		archivable:  true,
+		owner:       "customers.assigned_to",
+		permissions: models.EntityCustomer,
+		enums: map[string]entityEnum{
+			"status": fixedEnum(models.ValidCustomerStatuses),
+		},
+	},
+	"deals": {
+		model:       models.Deal{},
+		create:      DealCreateRequest{},
+		update:      DealUpdateRequest{},
+		list:        &dealListQuery,
+		table:       "deals",
+		archivable:  true,
+		owner:       "deals.owner_id",
+		permissions: models.EntityDeal,
+		rules: map[string]FieldRules{
+			"probability": {Min: schemaBound(0), Max: schemaBound(100)},
+		},
+		enums: map[string]entityEnum{
+			"stage": pipelineStageEnum,
+		},
+	},
+	"activities": {
+		model:  models.Activity{},
+		create: ActivityCreateRequest{},
+		update: ActivityUpdateRequest{},
+		list:   &activityListQuery,
+		table:  "activities",
+		owner:  "activities.assigned_to",
+		enums: map[string]entityEnum{
+			"type":     fixedEnum(models.ValidActivityTypes),
+			"status":   fixedEnum(models.ValidActivityStatuses),
+			"priority": fixedEnum(models.ValidActivityPriorities),
+		},
+	},
+	"contacts": {
+		model:  models.Contact{},
+		create: ContactCreateRequest{},
+		update: ContactUpdateRequest{},
+		list:   &contactListQuery,
+		table:  "contacts",
+	},
+}
+
+// buildEntitySchema describes an entity for a role
+func buildEntitySchema(db *gorm.DB, entity string, source entitySchemaSource, role string) (EntitySchema, error) {
+	schema := EntitySchema{
+		Entity:     entity,
+		Fields:     []FieldSchema{},
+		Filters:    []FilterSchema{},
+		SortFields: []string{},
+	}
+	if source.list != nil {
+		for _, filter := range source.list.Filters {
+			schema.Filters = append(schema.Filters, FilterSchema{Param: filter.Param, Type: filter.Kind.String()})
+		}
+		if source.list.Sort.Fixed == "" {
+			schema.SortFields = append(schema.SortFields, source.list.Sort.Fields...)
+		}
+	}
+
+	create := schemaFields(reflect.TypeOf(source.create))
+	update := schemaFields(reflect.TypeOf(source.update))
+	canWrite := models.HasPermission(role, models.PermissionWrite)
+
+	fields := schemaFields(reflect.TypeOf(source.model))
+	// Request-only fields, if any, follow the model's
+	for _, requests := range [][]reflect.StructField{create, update} {
+		for _, field := range requests {
+			if !slices.ContainsFunc(fields, func(f reflect.StructField) bool { return jsonName(f) == jsonName(field) }) {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	for _, field := range fields {
+		name := jsonName(field)
+		fieldType, nullable := schemaType(field.Type)
+		described := FieldSchema{
+			Name:       name,
+			Type:       fieldType,
+			Nullable:   nullable,
+			WritableOn: []string{},
+			RequiredOn: []string{},
+			Filterable: schema.hasFilter(name),
+			Sortable:   slices.Contains(schema.SortFields, name),
+		}
+		described.Rules.MaxLength = gormSize(field)
+
+		for _, op := range []struct {
+			name   string
+			fields []reflect.StructField
+		}{{"create", create}, {"update", update}} {
+			index := slices.IndexFunc(op.fields, func(f reflect.StructField) bool { return jsonName(f) == name })
+			if index < 0 {
+				continue
+			}
+			described.WritableOn = append(described.WritableOn, op.name)
+			if applyBindingRules(&described.Rules, op.fields[index]) {
+				described.RequiredOn = append(described.RequiredOn, op.name)
+			}
+		}
+		described.ReadOnly = len(described.WritableOn) == 0
+
+		if rules, ok := source.rules[name]; ok {
+			mergeFieldRules(&described.Rules, rules)
+		}
+		if enum, ok := source.enums[name]; ok {
+			values, err := enum(db)
+			if err != nil {
+				return schema, err
+			}
+			described.Rules.Enum = values
+		}
+
+		if !described.ReadOnly && canWrite {
+			described.Editable = models.CanEditField(source.permissions, name, role, false)
+			described.EditableWhenOwner = models.CanEditField(source.permissions, name, role, true)
+		}
+		schema.Fields = append(schema.Fields, described)
+	}
+	return schema, nil
+}
+
+// hasFilter reports whether the list endpoint filters on a parameter
+func (s EntitySchema) hasFilter(param string) bool {
+	return slices.ContainsFunc(s.Filters, func(f FilterSchema) bool { return f.Param == param })
+}
+
+// schemaFields returns the JSON fields of a struct, flattening embedded
+// structs and leaving out relations and hidden fields
+func schemaFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, schemaFields(field.Type)...)
+			continue
+		}
+		if !field.IsExported() || jsonName(field) == "" {
+			continue
+		}
+		if fieldType, _ := schemaType(field.Type); fieldType == "" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// jsonName returns a field's JSON name, or "" when it is not serialized
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// schemaType maps a Go type to a schema type and whether it is nullable.
+// Relations and other composite types map to "".
+func schemaType(t reflect.Type) (string, bool) {
+	nullable := false
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return "datetime", nullable
+	case t == reflect.TypeOf(gorm.DeletedAt{}):
+		return "datetime", true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", nullable
+	case reflect.Bool:
+		return "boolean", nullable
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nullable
+	case reflect.Float32, reflect.Float64:
+		return "number", nullable
+	default:
+		return "", false
+	}
+}
+
+// gormSize returns the column size of a string field, if it has one
+func gormSize(field reflect.StructField) *int {
+	for _, setting := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if value, ok := strings.CutPrefix(setting, "size:"); ok {
+			if size, err := strconv.Atoi(value); err == nil {
+				return &size
+			}
+		}
+	}
+	return nil
+}
+
+// applyBindingRules adds the rules of a request field's binding tag and
+// reports whether the field is required. min and max bound the length of
+// strings and the value of numbers, as in the validator.
+func applyBindingRules(rules *FieldRules, field reflect.StructField) bool {
+	fieldType, _ := schemaType(field.Type)
+	required := false
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			rules.Format = "email"
+		case "min", "max":
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			if fieldType == "string" {
+				length := int(bound)
+				if name == "min" {
+					rules.MinLength = &length
+				} else if rules.MaxLength == nil || length < *rules.MaxLength {
+					rules.MaxLength = &length
+				}
+			} else if name == "min" {
+				rules.Min = &bound
+			} else {
+				rules.Max = &bound
+			}
+		}
+	}
+	return required
+}
+
+// mergeFieldRules overlays the rules set in extra on rules
+func mergeFieldRules(rules *FieldRules, extra FieldRules) {
+	if extra.MinLength != nil {
+		rules.MinLength = extra.MinLength
+	}
+	if extra.MaxLength != nil {
+		rules.MaxLength = extra.MaxLength
+	}
+	if extra.Min != nil {
+		rules.Min = extra.Min
+	}
+	if extra.Max != nil {
+		rules.Max = extra.Max
+	}
+	if extra.Format != "" {
+		rules.Format = extra.Format
+	}
+}
+
+// schemaBound returns a pointer to a numeric bound
+func schemaBound(value float64) *float64 {
+	return &value
+}
+
+// fixedEnum lists the values of an enum defined in code
+func fixedEnum[T ~string](values []T) entityEnum {
+	return func(*gorm.DB) ([]EnumValue, error) {
+		enum := make([]EnumValue, len(values))
+		for i, value := range values {
+			enum[i] = EnumValue{Value: string(value)}
+		}
+		return enum, nil
+	}
+}
+
+// pipelineStageEnum lists the active pipeline stages in board order, with
+// their display names. Stages the API does not accept are left out, and
+// the built-in stages are listed when none are seeded.
+func pipelineStageEnum(db *gorm.DB) ([]EnumValue, error) {
+	var stages []models.PipelineStage
+	if err := db.Where("is_active = ?", true).Order(`"order" ASC`).Find(&stages).Error; err != nil {
+		return nil, err
+	}
+
+	var enum []EnumValue
+	for _, stage := range stages {
+		if models.IsValidDealStage(models.DealStage(stage.Name)) {
+			enum = append(enum, EnumValue{Value: stage.Name, Label: stage.DisplayName})
+		}
+	}
+	if len(enum) == 0 {
+		return fixedEnum(models.DealStages())(db)
+	}
+	return enum, nil
+}
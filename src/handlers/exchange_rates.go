@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExchangeRateHandler handles the exchange rates deal conversions use
+type ExchangeRateHandler struct {
+	db *gorm.DB
+}
+
+// NewExchangeRateHandler creates a new ExchangeRateHandler
+func NewExchangeRateHandler(db *gorm.DB) *ExchangeRateHandler {
+	return &ExchangeRateHandler{db: db}
+}
+
+// ExchangeRateRequest represents the request body for creating or updating
+// an exchange rate
+type ExchangeRateRequest struct {
+	BaseCurrency  string  `json:"base_currency" binding:"required,len=3,alpha"`
+	QuoteCurrency string  `json:"quote_currency" binding:"required,len=3,alpha"`
+	Rate          float64 `json:"rate" binding:"required,gt=0"` // 1 base = rate quote
+	EffectiveDate string  `json:"effective_date" binding:"required,datetime=2006-01-02"`
+}
+
+// ListExchangeRates returns exchange rates by currency pair, the latest
+// effective first, optionally for one base or quote currency
+// GET /admin/exchange-rates?base=USD&quote=SAR
+func (h *ExchangeRateHandler) ListExchangeRates(c *gin.Context) {
+	db := h.db.WithContext(c).Model(&models.ExchangeRate{})
+	if base := c.Query("base"); base != "" {
+		db = db.Where("base_currency = ?", strings.ToUpper(base))
+	}
+	if quote := c.Query("quote"); quote != "" {
+		db = db.Where("quote_currency = ?", strings.ToUpper(quote))
+	}
+
+	var rates []models.ExchangeRate
+	if err := db.Order("base_currency ASC, quote_currency ASC, effective_date DESC").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch exchange rates"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": rates,
+	})
+}
+
+// CreateExchangeRate adds a rate effective from a date
+// POST /admin/exchange-rates
+func (h *ExchangeRateHandler) CreateExchangeRate(c *gin.Context) {
+	var req ExchangeRateRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	var rate models.ExchangeRate
+	applyExchangeRateRequest(&rate, req)
+	if !h.checkUnique(c, rate) {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rate).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "exchange_rate", rate.ID, models.AuditActionCreate, nil, &rate)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create exchange rate"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// UpdateExchangeRate replaces an exchange rate. Deals already converted
+// keep the amounts they were converted to.
+// PUT /admin/exchange-rates/:id
+func (h *ExchangeRateHandler) UpdateExchangeRate(c *gin.Context) {
+	rate, ok := h.findExchangeRate(c)
+	if !ok {
+		return
+	}
+	oldRate := *rate
+
+	var req ExchangeRateRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	applyExchangeRateRequest(rate, req)
+	if !h.checkUnique(c, *rate) {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(rate).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "exchange_rate", rate.ID, models.AuditActionUpdate, &oldRate, rate)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update exchange rate"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// DeleteExchangeRate removes an exchange rate
+// DELETE /admin/exchange-rates/:id
+func (h *ExchangeRateHandler) DeleteExchangeRate(c *gin.Context) {
+	rate, ok := h.findExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(rate).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "exchange_rate", rate.ID, models.AuditActionDelete, rate, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete exchange rate"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Exchange rate deleted successfully",
+	})
+}
+
+// bind parses the request body, writing the error response when it is
+// invalid or converts a currency to itself
+func (h *ExchangeRateHandler) bind(c *gin.Context, req *ExchangeRateRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return false
+	}
+	if strings.EqualFold(req.BaseCurrency, req.QuoteCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "EXCHANGE_RATE_SAME_CURRENCY",
+			"message": i18n.Message(c, "EXCHANGE_RATE_SAME_CURRENCY", "The base and quote currencies must differ"),
+		})
+		return false
+	}
+	return true
+}
+
+// applyExchangeRateRequest copies request fields onto a rate. The date was
+// validated by binding.
+func applyExchangeRateRequest(rate *models.ExchangeRate, req ExchangeRateRequest) {
+	rate.BaseCurrency = strings.ToUpper(req.BaseCurrency)
+	rate.QuoteCurrency = strings.ToUpper(req.QuoteCurrency)
+	rate.Rate = req.Rate
+	rate.EffectiveDate, _ = time.Parse("2006-01-02", req.EffectiveDate)
+}
+
+// checkUnique responds with 409 when another rate of the same pair is
+// effective from the same date
+func (h *ExchangeRateHandler) checkUnique(c *gin.Context, rate models.ExchangeRate) bool {
+	var count int64
+	h.db.WithContext(c).Model(&models.ExchangeRate{}).
+		Where("base_currency = ? AND quote_currency = ? AND effective_date = ? AND id <> ?",
+			rate.BaseCurrency, rate.QuoteCurrency, rate.EffectiveDate, rate.ID).
+		Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "EXCHANGE_RATE_EXISTS",
+			"message": i18n.Message(c, "EXCHANGE_RATE_EXISTS", "A rate for this currency pair is already effective from this date"),
+		})
+		return false
+	}
+	return true
+}
+
+// findExchangeRate loads the rate identified by the :id route parameter,
+// writing the error response when it cannot be found
+func (h *ExchangeRateHandler) findExchangeRate(c *gin.Context) (*models.ExchangeRate, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid exchange rate ID"),
+		})
+		return nil, false
+	}
+
+	var rate models.ExchangeRate
+	if err := h.db.WithContext(c).First(&rate, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "EXCHANGE_RATE_NOT_FOUND",
+				"message": i18n.Message(c, "EXCHANGE_RATE_NOT_FOUND", "Exchange rate not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch exchange rate"),
+		})
+		return nil, false
+	}
+
+	return &rate, true
+}
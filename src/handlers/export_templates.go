@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportTemplateHandler handles export template endpoints
+type ExportTemplateHandler struct {
+	db *gorm.DB
+}
+
+// NewExportTemplateHandler creates a new ExportTemplateHandler
+func NewExportTemplateHandler(db *gorm.DB) *ExportTemplateHandler {
+	return &ExportTemplateHandler{db: db}
+}
+
+// ExportTemplateRequest represents the request body for creating or
+// replacing an export template
+type ExportTemplateRequest struct {
+	Name       string                `json:"name" binding:"required,min=1,max=100"`
+	Entity     string                `json:"entity" binding:"required"`
+	Columns    []models.ExportColumn `json:"columns" binding:"required,min=1,dive"`
+	DateFormat string                `json:"date_format,omitempty"`
+	IsDefault  bool                  `json:"is_default"`
+}
+
+// ListExportTemplates returns export templates, optionally of one entity
+// GET /admin/export-templates?entity=deal
+func (h *ExportTemplateHandler) ListExportTemplates(c *gin.Context) {
+	db := h.db.WithContext(c).Model(&models.ExportTemplate{})
+	if entity := c.Query("entity"); entity != "" {
+		db = db.Where("entity = ?", entity)
+	}
+
+	templates := []models.ExportTemplate{}
+	if err := db.Order("entity ASC, name ASC").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch export templates"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ExportTemplateListResponse{
+		Data:  templates,
+		Total: int64(len(templates)),
+	})
+}
+
+// ListExportColumns returns the columns templates of an entity can use
+// GET /admin/export-templates/columns?entity=deal
+func (h *ExportTemplateHandler) ListExportColumns(c *gin.Context) {
+	columns, ok := exports.Columns(c.Query("entity"))
+	if !ok {
+		rejectExportEntity(c)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": columns})
+}
+
+// GetExportTemplate returns an export template
+// GET /admin/export-templates/:id
+func (h *ExportTemplateHandler) GetExportTemplate(c *gin.Context) {
+	template, ok := h.findExportTemplate(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// CreateExportTemplate creates an export template
+// POST /admin/export-templates
+func (h *ExportTemplateHandler) CreateExportTemplate(c *gin.Context) {
+	var req ExportTemplateRequest
+	if !h.bindExportTemplate(c, &req, 0) {
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	template := models.ExportTemplate{CreatedBy: user.ID}
+	req.apply(&template)
+
+	if err := h.save(c, &template, nil); err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create export template"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdateExportTemplate replaces an export template. Jobs already started
+// keep the snapshot of the template they ran with.
+// PUT /admin/export-templates/:id
+func (h *ExportTemplateHandler) UpdateExportTemplate(c *gin.Context) {
+	template, ok := h.findExportTemplate(c)
+	if !ok {
+		return
+	}
+	oldTemplate := *template
+
+	var req ExportTemplateRequest
+	if !h.bindExportTemplate(c, &req, template.ID) {
+		return
+	}
+	req.apply(template)
+
+	if err := h.save(c, template, &oldTemplate); err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update export template"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteExportTemplate deletes an export template
+// DELETE /admin/export-templates/:id
+func (h *ExportTemplateHandler) DeleteExportTemplate(c *gin.Context) {
+	template, ok := h.findExportTemplate(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(template).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "export_template", template.ID, models.AuditActionDelete, template, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete export template"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Export template deleted successfully",
+	})
+}
+
+// bindExportTemplate binds and validates a template request, writing the
+// error response when it is invalid. id is the template being replaced, or
+// zero for a new one.
+func (h *ExportTemplateHandler) bindExportTemplate(c *gin.Context, req *ExportTemplateRequest, id uint) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return false
+	}
+
+	if _, ok := exports.Columns(req.Entity); !ok {
+		rejectExportEntity(c)
+		return false
+	}
+
+	if req.DateFormat == "" {
+		req.DateFormat = models.DefaultExportDateFormat
+	}
+	if _, ok := models.ExportDateFormats[req.DateFormat]; !ok {
+		formats := make([]string, 0, len(models.ExportDateFormats))
+		for name := range models.ExportDateFormats {
+			formats = append(formats, name)
+		}
+		slices.Sort(formats)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_DATE_FORMAT",
+			"message": i18n.Message(c, "INVALID_DATE_FORMAT", "date_format must be one of: "+strings.Join(formats, ", ")),
+		})
+		return false
+	}
+
+	keys := make([]string, len(req.Columns))
+	var duplicates []string
+	for i, column := range req.Columns {
+		if slices.Contains(keys[:i], column.Key) && !slices.Contains(duplicates, column.Key) {
+			duplicates = append(duplicates, column.Key)
+		}
+		keys[i] = column.Key
+	}
+	if unknown := exports.UnknownColumns(req.Entity, keys); len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "UNKNOWN_EXPORT_COLUMNS",
+			"message": i18n.Message(c, "UNKNOWN_EXPORT_COLUMNS", "Unknown columns: "+strings.Join(unknown, ", ")),
+			"columns": unknown,
+		})
+		return false
+	}
+	if len(duplicates) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "DUPLICATE_EXPORT_COLUMNS",
+			"message": i18n.Message(c, "DUPLICATE_EXPORT_COLUMNS", "Columns appear more than once: "+strings.Join(duplicates, ", ")),
+			"columns": duplicates,
+		})
+		return false
+	}
+
+	// Check uniqueness
+	var count int64
+	h.db.WithContext(c).Model(&models.ExportTemplate{}).
+		Where("entity = ? AND name = ? AND id <> ?", req.Entity, req.Name, id).
+		Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "EXPORT_TEMPLATE_EXISTS",
+			"message": i18n.Message(c, "EXPORT_TEMPLATE_EXISTS", "An export template with this name already exists for this entity"),
+		})
+		return false
+	}
+	return true
+}
+
+// apply copies the request onto a template, filling empty labels with the
+// column's default header so the template fully describes its output
+func (r ExportTemplateRequest) apply(template *models.ExportTemplate) {
+	columns, _ := exports.Columns(r.Entity)
+	template.Name = r.Name
+	template.Entity = r.Entity
+	template.DateFormat = r.DateFormat
+	template.IsDefault = r.IsDefault
+	template.Columns = make([]models.ExportColumn, len(r.Columns))
+	for i, column := range r.Columns {
+		if column.Label == "" {
+			for _, known := range columns {
+				if known.Key == column.Key {
+					column.Label = known.Label
+				}
+			}
+		}
+		template.Columns[i] = column
+	}
+}
+
+// save stores a template with its audit entry, as a change of oldTemplate
+// or as a new template when it is nil. A new default replaces the entity's
+// previous one.
+func (h *ExportTemplateHandler) save(c *gin.Context, template, oldTemplate *models.ExportTemplate) error {
+	return h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if template.IsDefault {
+			if err := tx.Model(&models.ExportTemplate{}).
+				Where("entity = ? AND is_default AND id <> ?", template.Entity, template.ID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(template).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		if oldTemplate == nil {
+			return logAudit(c, tx, "export_template", template.ID, models.AuditActionCreate, nil, template)
+		}
+		return logAudit(c, tx, "export_template", template.ID, models.AuditActionUpdate, oldTemplate, template)
+	})
+}
+
+// findExportTemplate loads the template identified by the :id route
+// parameter, writing the error response when it cannot be found
+func (h *ExportTemplateHandler) findExportTemplate(c *gin.Context) (*models.ExportTemplate, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid export template ID"),
+		})
+		return nil, false
+	}
+
+	var template models.ExportTemplate
+	if err := h.db.WithContext(c).First(&template, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "EXPORT_TEMPLATE_NOT_FOUND",
+				"message": i18n.Message(c, "EXPORT_TEMPLATE_NOT_FOUND", "Export template not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch export template"),
+		})
+		return nil, false
+	}
+
+	return &template, true
+}
+
+// rejectExportEntity responds with 400 INVALID_EXPORT_ENTITY
+func rejectExportEntity(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "validation_error",
+		"code":    "INVALID_EXPORT_ENTITY",
+		"message": i18n.Message(c, "INVALID_EXPORT_ENTITY", "entity must be one of: "+strings.Join(models.ValidExportEntities, ", ")),
+	})
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Filter option defaults
+const (
+	defaultFilterOptionLimit = 20
+	maxFilterOptionLimit     = 100
+	filterOptionsCacheTTL    = 30 * time.Second
+	maxFilterOptionsCached   = 1000
+)
+
+// FilterOptionsResponse lists the values of filterable fields with counts
+type FilterOptionsResponse struct {
+	Entity    string                  `json:"entity"`
+	Fields    map[string]FilterCounts `json:"fields"`
+	OwnOnly   bool                    `json:"own_only"` // Counts only cover the user's own records
+	CachedAt  time.Time               `json:"cached_at"`
+	ExpiresAt time.Time               `json:"expires_at"`
+}
+
+// FilterCounts lists the most common values of one field
+type FilterCounts struct {
+	Options []FilterOption `json:"options"`
+	Other   int64          `json:"other"` // Rows with values beyond the listed options
+	Total   int64          `json:"total"`
+}
+
+// FilterOption is one value of a field and the number of rows holding it.
+// Value is null for rows without one.
+type FilterOption struct {
+	Value *string `json:"value"`
+	Count int64   `json:"count"`
+}
+
+// filterOptionsCache keeps filter option responses for a short while, so
+// dropdowns opened together or repeatedly do not recount
+type filterOptionsCache struct {
+	mu      sync.Mutex
+	entries map[string]FilterOptionsResponse
+}
+
+// get returns an unexpired cached response
+func (f *filterOptionsCache) get(key string, now time.Time) (FilterOptionsResponse, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	response, ok := f.entries[key]
+	if !ok || !now.Before(response.ExpiresAt) {
+		return FilterOptionsResponse{}, false
+	}
+	return response, true
+}
+
+// put caches a response, dropping expired entries once the cache is full
+func (f *filterOptionsCache) put(key string, response FilterOptionsResponse, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.entries) >= maxFilterOptionsCached {
+		for k, entry := range f.entries {
+			if !now.Before(entry.ExpiresAt) {
+				delete(f.entries, k)
+			}
+		}
+		if len(f.entries) >= maxFilterOptionsCached {
+			return
+		}
+	}
+	f.entries[key] = response
+}
+
+// GetFilterOptions counts the values of an entity's filterable fields under
+// the other filters of the request, for dropdowns showing counts. Each
+// field lists its most common values, up to limit, and an "other" bucket.
+// Users who cannot manage all records only count their own.
+// GET /admin/meta/filter-options?entity=deals&fields=stage,owner_id&limit=
+func (h *MetaHandler) GetFilterOptions(c *gin.Context) {
+	values := c.Request.URL.Query()
+	entity := values.Get("entity")
+	source, ok := entitySchemaSources[entity]
+	if !ok || source.list == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "UNKNOWN_ENTITY",
+			"message": i18n.Message(c, "UNKNOWN_ENTITY", "entity must be one of: customers, deals, activities, contacts"),
+		})
+		return
+	}
+
+	var fields []query.Filter
+	for _, param := range strings.Split(values.Get("fields"), ",") {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		filter, ok := source.list.Lookup(param)
+		if !ok || filter.Column == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_FILTER_FIELD",
+				"message": i18n.Message(c, "INVALID_FILTER_FIELD", param+" cannot be counted; use fields with equality filters"),
+			})
+			return
+		}
+		fields = append(fields, filter)
+	}
+	if len(fields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_FILTER_FIELD",
+			"message": i18n.Message(c, "INVALID_FILTER_FIELD", "fields must list at least one filterable field"),
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(values.Get("limit"))
+	if err != nil || limit < 1 || limit > maxFilterOptionLimit {
+		limit = defaultFilterOptionLimit
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	ownOnly := source.owner != "" && !models.CanManageAll(user.Role)
+
+	// Cached per scope: the sandbox, and the user when counts are their own
+	scope := "role:" + user.Role
+	if ownOnly {
+		scope = "user:" + strconv.FormatUint(uint64(user.ID), 10) + ":" + user.Name
+	}
+	if sandbox.Active(c) {
+		scope = "sandbox:" + scope
+	}
+	values.Set("limit", strconv.Itoa(limit))
+	key := scope + "|" + values.Encode()
+
+	now := time.Now()
+	if cached, ok := h.filterOptions.get(key, now); ok {
+		c.JSON(http.StatusOK, cached)
+		return
+	}
+
+	response := FilterOptionsResponse{
+		Entity:    entity,
+		Fields:    make(map[string]FilterCounts, len(fields)),
+		OwnOnly:   ownOnly,
+		CachedAt:  now,
+		ExpiresAt: now.Add(filterOptionsCacheTTL),
+	}
+	for _, field := range fields {
+		// A field's own filter is left out, so every option stays selectable
+		db := middleware.GetReadDB(c, h.db).WithContext(c).Table(source.table)
+		if source.archivable && values.Get("include_archived") != "true" {
+			db = db.Scopes(models.NotArchived(source.table))
+		}
+		db = db.Where(source.table + ".deleted_at IS NULL")
+		if ownOnly {
+			db = db.Where(source.owner+" = ?", user.ID)
+		}
+		db, _ = source.list.Without(field.Param).Filter(db, values)
+
+		counts, err := countFilterOptions(db, field.Column, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to count filter options"),
+			})
+			return
+		}
+		response.Fields[field.Param] = counts
+	}
+
+	h.filterOptions.put(key, response, now)
+	c.JSON(http.StatusOK, response)
+}
+
+// countFilterOptions counts the rows of a query per value of a column,
+// keeping the limit most common values
+func countFilterOptions(db *gorm.DB, column string, limit int) (FilterCounts, error) {
+	counts := FilterCounts{Options: []FilterOption{}}
+	if err := db.Session(&gorm.Session{}).Count(&counts.Total).Error; err != nil {
+		return counts, err
+	}
+
+	var rows []struct {
+		Value *string
+		Count int64
+	}
+	value := "CAST(" + column + " AS TEXT)"
+	if err := db.Session(&gorm.Session{}).
+		Select(value + " AS value, COUNT(*) AS count").
+		Group(value).
+		Order("count DESC, value ASC").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return counts, err
+	}
+
+	counted := int64(0)
+	for _, row := range rows {
+		counts.Options = append(counts.Options, FilterOption{Value: row.Value, Count: row.Count})
+		counted += row.Count
+	}
+	counts.Other = counts.Total - counted
+	return counts, nil
+}
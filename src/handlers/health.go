@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"runtime"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/buildinfo"
+	"github.com/SalehAlobaylan/CRM-Service/src/fallback"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
@@ -12,28 +18,43 @@ import (
 
 // HealthHandler handles health check and metrics endpoints
 type HealthHandler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	fallbacks *fallback.Writer
 }
 
-// NewHealthHandler creates a new HealthHandler
-func NewHealthHandler(db *gorm.DB) *HealthHandler {
-	return &HealthHandler{db: db}
+// NewHealthHandler creates a new HealthHandler; fallbacks, when set,
+// reports the side effects waiting to be replayed
+func NewHealthHandler(db *gorm.DB, fallbacks *fallback.Writer) *HealthHandler {
+	return &HealthHandler{db: db, fallbacks: fallbacks}
 }
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
 	Status    string            `json:"status"`
 	Version   string            `json:"version"`
+	Commit    string            `json:"commit,omitempty"`
+	BuildTime string            `json:"build_time,omitempty"`
 	Checks    map[string]string `json:"checks"`
+	Details   *HealthDetails    `json:"details,omitempty"`
+}
+
+// HealthDetails reports degraded parts that do not make the service
+// unhealthy
+type HealthDetails struct {
+	// Side effects by kind whose write failed and that wait to be replayed
+	PendingFallbacks map[string]int64 `json:"pending_fallbacks"`
 }
 
 // Health returns the health status of the service
 // GET /health
 func (h *HealthHandler) Health(c *gin.Context) {
+	build := buildinfo.Get()
 	response := HealthResponse{
-		Status:  "healthy",
-		Version: "1.0.0",
-		Checks:  make(map[string]string),
+		Status:    "healthy",
+		Version:   build.Version,
+		Commit:    build.Commit,
+		BuildTime: build.BuildTime,
+		Checks:    make(map[string]string),
 	}
 
 	// Check database connection
@@ -50,6 +71,27 @@ func (h *HealthHandler) Health(c *gin.Context) {
 		}
 	}
 
+	// Side effects kept for replay degrade the service without failing it
+	if h.fallbacks != nil {
+		ctx, cancel := context.WithTimeout(c, 2*time.Second)
+		pending, err := h.fallbacks.Pending(ctx)
+		cancel()
+
+		total := int64(0)
+		for _, count := range pending {
+			total += count
+		}
+		switch {
+		case err != nil:
+			response.Checks["side_effects"] = "error: " + err.Error()
+		case total > 0:
+			response.Checks["side_effects"] = "degraded"
+		default:
+			response.Checks["side_effects"] = "ok"
+		}
+		response.Details = &HealthDetails{PendingFallbacks: pending}
+	}
+
 	// Memory stats
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -85,9 +127,28 @@ func (h *HealthHandler) Metrics() gin.HandlerFunc {
 		[]string{"method", "endpoint"},
 	)
 
+	// Annotations in progress, so dashboards can mute alerts during them
+	activeMaintenance := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "crm_active_maintenance",
+			Help: "Number of operational annotations currently open",
+		},
+		func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			var count int64
+			h.db.WithContext(ctx).Model(&models.Annotation{}).
+				Where("starts_at <= ? AND ends_at IS NULL", time.Now()).
+				Count(&count)
+			return float64(count)
+		},
+	)
+
 	// Register metrics (ignore if already registered)
 	prometheus.Register(httpRequestsTotal)
 	prometheus.Register(httpRequestDuration)
+	prometheus.Register(activeMaintenance)
 
 	return gin.WrapH(promhttp.Handler())
 }
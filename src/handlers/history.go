@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// customerHistoryFields lists customer fields whose history can be queried
+var customerHistoryFields = []string{
+	"name", "email", "phone", "company", "role", "status", "assigned_to",
+	"contacted", "next_follow_up_at", "notes", "archived_at", "email_invalid_at",
+}
+
+// dealHistoryFields lists deal fields whose history can be queried
+var dealHistoryFields = []string{
+	"title", "customer_id", "contact_id", "stage", "amount", "currency", "probability",
+	"expected_close_date", "actual_close_date", "owner_id", "lost_reason", "archived_at",
+	"next_step", "next_step_due",
+}
+
+// fieldChangeRow is a scanned audit entry narrowed to one field
+type fieldChangeRow struct {
+	ID        uint
+	Action    models.AuditAction
+	OldValue  *string
+	NewValue  *string
+	UserID    uint
+	UserName  string
+	UserRole  string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// writeFieldHistory responds with the chronological changes of one field of
+// a resource, read from the old/new values stored on its audit entries
+func writeFieldHistory(c *gin.Context, db *gorm.DB, resourceType string, resourceID uint, allowedFields []string) {
+	field := c.Query("field")
+	if !slices.Contains(allowedFields, field) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_HISTORY_FIELD",
+			"message": i18n.Message(c, "INVALID_HISTORY_FIELD", "field must be one of: "+strings.Join(allowedFields, ", ")),
+		})
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries := db.Model(&models.AuditLog{}).
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID)
+
+	query := entries.Session(&gorm.Session{}).
+		Where("COALESCE(old_values -> ?::text, 'null'::jsonb) <> COALESCE(new_values -> ?::text, 'null'::jsonb)", field, field)
+
+	var total int64
+	query.Count(&total)
+
+	var rows []fieldChangeRow
+	offset := (page - 1) * pageSize
+	if err := query.
+		Select("id, action, (old_values -> ?::text)::text as old_value, (new_values -> ?::text)::text as new_value, user_id, user_name, user_role, reason, created_at", field, field).
+		Order("created_at ASC, id ASC").Offset(offset).Limit(pageSize).
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch history"),
+		})
+		return
+	}
+
+	// Entries written before old/new values were stored carry neither
+	var legacy int64
+	entries.Session(&gorm.Session{}).
+		Where("old_values IS NULL AND new_values IS NULL").
+		Count(&legacy)
+
+	changes := make([]models.FieldChange, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, models.FieldChange{
+			AuditID:   row.ID,
+			Action:    row.Action,
+			OldValue:  rawJSON(row.OldValue),
+			NewValue:  rawJSON(row.NewValue),
+			UserID:    row.UserID,
+			UserName:  row.UserName,
+			UserRole:  row.UserRole,
+			Reason:    row.Reason,
+			ChangedAt: row.CreatedAt,
+		})
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.FieldHistoryResponse{
+		Field:      field,
+		Data:       changes,
+		Truncated:  legacy > 0,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageSize))),
+	})
+}
+
+// rawJSON converts a scanned JSON value, mapping a missing value to null
+func rawJSON(value *string) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(*value)
+}
+
+// GetCustomerHistory returns the change history of one customer field
+// GET /admin/customers/:id/history?field=assigned_to
+func (h *CustomerHandler) GetCustomerHistory(c *gin.Context) {
+	customer, ok := h.findCustomer(c)
+	if !ok {
+		return
+	}
+	writeFieldHistory(c, h.db.WithContext(c), "customer", customer.ID, customerHistoryFields)
+}
+
+// GetDealHistory returns the change history of one deal field
+// GET /admin/deals/:id/history?field=stage
+func (h *DealHandler) GetDealHistory(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+	writeFieldHistory(c, h.db.WithContext(c), "deal", deal.ID, dealHistoryFields)
+}
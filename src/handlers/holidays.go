@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HolidayHandler handles business calendar holiday endpoints
+type HolidayHandler struct {
+	db       *gorm.DB
+	calendar *businesstime.Service
+}
+
+// NewHolidayHandler creates a new HolidayHandler
+func NewHolidayHandler(db *gorm.DB, calendar *businesstime.Service) *HolidayHandler {
+	return &HolidayHandler{db: db, calendar: calendar}
+}
+
+// HolidayRequest represents the request body for creating or updating a holiday
+type HolidayRequest struct {
+	Date   string `json:"date" binding:"required,datetime=2006-01-02"`
+	Name   string `json:"name" binding:"required,min=1,max=255"`
+	Region string `json:"region,omitempty" binding:"max=50"` // empty applies to every region
+}
+
+// ListHolidays returns holidays ordered by date, optionally for one year and
+// region. Holidays without a region are included for every region.
+// GET /admin/holidays?year=2025&region=SA
+func (h *HolidayHandler) ListHolidays(c *gin.Context) {
+	db := h.db.WithContext(c).Model(&models.Holiday{})
+	if year, err := strconv.Atoi(c.Query("year")); err == nil {
+		db = db.Where("date >= ? AND date < ?",
+			time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC))
+	}
+	if region := c.Query("region"); region != "" {
+		db = db.Where("region IN ?", []string{"", region})
+	}
+
+	var holidays []models.Holiday
+	if err := db.Order("date ASC, region ASC").Find(&holidays).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch holidays"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           holidays,
+		"default_region": h.calendar.DefaultRegion(),
+	})
+}
+
+// CreateHoliday adds a holiday to the business calendar
+// POST /admin/holidays
+func (h *HolidayHandler) CreateHoliday(c *gin.Context) {
+	var req HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	var holiday models.Holiday
+	applyHolidayRequest(&holiday, req)
+	if !h.checkUnique(c, holiday) {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&holiday).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "holiday", holiday.ID, models.AuditActionCreate, nil, &holiday)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create holiday"),
+		})
+		return
+	}
+	h.reloadCalendar(c)
+
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// UpdateHoliday replaces a holiday
+// PUT /admin/holidays/:id
+func (h *HolidayHandler) UpdateHoliday(c *gin.Context) {
+	holiday, ok := h.findHoliday(c)
+	if !ok {
+		return
+	}
+	oldHoliday := *holiday
+
+	var req HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	applyHolidayRequest(holiday, req)
+	if !h.checkUnique(c, *holiday) {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(holiday).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "holiday", holiday.ID, models.AuditActionUpdate, &oldHoliday, holiday)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update holiday"),
+		})
+		return
+	}
+	h.reloadCalendar(c)
+
+	c.JSON(http.StatusOK, holiday)
+}
+
+// DeleteHoliday removes a holiday from the business calendar
+// DELETE /admin/holidays/:id
+func (h *HolidayHandler) DeleteHoliday(c *gin.Context) {
+	holiday, ok := h.findHoliday(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(holiday).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "holiday", holiday.ID, models.AuditActionDelete, holiday, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete holiday"),
+		})
+		return
+	}
+	h.reloadCalendar(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Holiday deleted successfully",
+	})
+}
+
+// applyHolidayRequest copies request fields onto a holiday. The date was
+// validated by binding.
+func applyHolidayRequest(holiday *models.Holiday, req HolidayRequest) {
+	holiday.Date, _ = time.Parse("2006-01-02", req.Date)
+	holiday.Name = req.Name
+	holiday.Region = strings.ToUpper(strings.TrimSpace(req.Region))
+}
+
+// checkUnique responds with 409 when another holiday has the same date and region
+func (h *HolidayHandler) checkUnique(c *gin.Context, holiday models.Holiday) bool {
+	var count int64
+	h.db.WithContext(c).Model(&models.Holiday{}).
+		Where("date = ? AND region = ? AND id <> ?", holiday.Date, holiday.Region, holiday.ID).
+		Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "HOLIDAY_EXISTS",
+			"message": i18n.Message(c, "HOLIDAY_EXISTS", "A holiday already exists on this date for this region"),
+		})
+		return false
+	}
+	return true
+}
+
+// reloadCalendar refreshes the cached calendars after a holiday change. A
+// failure leaves the previous holidays in effect until the next change.
+func (h *HolidayHandler) reloadCalendar(c *gin.Context) {
+	if err := h.calendar.Reload(c); err != nil {
+		middleware.Logger.Warn("Failed to reload business calendar: " + err.Error())
+	}
+}
+
+// findHoliday loads the holiday identified by the :id route parameter,
+// writing the error response when it cannot be found
+func (h *HolidayHandler) findHoliday(c *gin.Context) (*models.Holiday, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid holiday ID"),
+		})
+		return nil, false
+	}
+
+	var holiday models.Holiday
+	if err := h.db.WithContext(c).First(&holiday, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "HOLIDAY_NOT_FOUND",
+				"message": i18n.Message(c, "HOLIDAY_NOT_FOUND", "Holiday not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch holiday"),
+		})
+		return nil, false
+	}
+
+	return &holiday, true
+}
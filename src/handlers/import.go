@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importBatchSize is the number of rows inserted per batch during imports
+const importBatchSize = 100
+
+// maxImportRows caps the number of data rows accepted in a single import
+const maxImportRows = 5000
+
+// Import row statuses
+const (
+	ImportRowCreated = "created"
+	ImportRowValid   = "valid"
+	ImportRowFailed  = "failed"
+	ImportRowSkipped = "skipped"
+)
+
+// ImportRowResult reports the outcome of a single CSV row
+type ImportRowResult struct {
+	Row    int      `json:"row"`
+	Status string   `json:"status"`
+	ID     uint     `json:"id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ImportReport is the per-row report returned by CSV import endpoints
+type ImportReport struct {
+	DryRun    bool              `json:"dry_run"`
+	TotalRows int               `json:"total_rows"`
+	Created   int               `json:"created"`
+	Failed    int               `json:"failed"`
+	Skipped   int               `json:"skipped"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// csvRecord is a CSV data row keyed by normalized header name
+type csvRecord struct {
+	Line   int
+	Fields map[string]string
+}
+
+// Get returns the trimmed value of a column, or "" if absent
+func (r csvRecord) Get(column string) string {
+	return strings.TrimSpace(r.Fields[column])
+}
+
+// isDryRun reports whether the request asked for validation without writes
+func isDryRun(c *gin.Context) bool {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	return dryRun
+}
+
+// readCSVUpload reads CSV rows from a multipart "file" field or, failing
+// that, from the raw request body. Header names are lower-cased and spaces
+// replaced with underscores so "First Name" matches "first_name".
+func readCSVUpload(c *gin.Context) ([]csvRecord, error) {
+	var source io.Reader = c.Request.Body
+	if file, _, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		source = file
+	}
+
+	reader := csv.NewReader(source)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("CSV file is empty")
+		}
+		return nil, err
+	}
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		header[i] = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	}
+
+	var records []csvRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(records) >= maxImportRows {
+			return nil, errors.New("CSV file exceeds the maximum of " + strconv.Itoa(maxImportRows) + " rows")
+		}
+
+		fields := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(row) {
+				fields[name] = row[i]
+			}
+		}
+		records = append(records, csvRecord{Line: line, Fields: fields})
+	}
+
+	return records, nil
+}
+
+// parseCSVBool parses common spreadsheet boolean spellings
+func parseCSVBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "0", "false", "no", "n":
+		return false, nil
+	case "1", "true", "yes", "y":
+		return true, nil
+	}
+	return false, errors.New("invalid boolean value: " + value)
+}
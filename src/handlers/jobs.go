@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"github.com/SalehAlobaylan/CRM-Service/src/storage"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// JobHandler handles async job endpoints
+type JobHandler struct {
+	db      *gorm.DB
+	exports *exports.Manager
+}
+
+// NewJobHandler creates a new JobHandler
+func NewJobHandler(db *gorm.DB, exportManager *exports.Manager) *JobHandler {
+	return &JobHandler{db: db, exports: exportManager}
+}
+
+// ExportJobRequest represents the request body for starting an export
+type ExportJobRequest struct {
+	Type       string          `json:"type" binding:"required"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	TemplateID *uint           `json:"template_id,omitempty"` // Defaults to the entity's default template
+}
+
+// jobListQuery defines the filters and sorting of ListJobs
+var jobListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("type", "type"),
+		query.Equal("status", "status"),
+		query.Equal("template_id", "template_id"),
+	},
+	Sort: query.Sort{Fixed: "created_at DESC, id DESC"},
+}
+
+// CreateExportJob starts an async export. Exports of an entity use the
+// requested export template, or the entity's default one; a snapshot of
+// the template is stored in the job's params.
+// POST /admin/jobs/exports
+func (h *JobHandler) CreateExportJob(c *gin.Context) {
+	var req ExportJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	job := models.Job{
+		Type:          req.Type,
+		CreatedBy:     user.ID,
+		CreatedByName: user.Name,
+	}
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		job.Params = string(req.Params)
+	}
+
+	template, ok := h.resolveExportTemplate(c, req)
+	if !ok {
+		return
+	}
+	params := map[string]json.RawMessage{}
+	if job.Params != "" {
+		if err := json.Unmarshal([]byte(job.Params), &params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_REQUEST",
+				"message": i18n.Message(c, "INVALID_REQUEST", "params must be a JSON object"),
+			})
+			return
+		}
+	}
+	if template != nil {
+		params["template"], _ = json.Marshal(template.Snapshot())
+		job.TemplateID = &template.ID
+	}
+	// Exports hide the fields their user may not read
+	params["viewer"], _ = json.Marshal(redaction.Viewer{UserID: user.ID, Role: user.Role})
+	encoded, _ := json.Marshal(params)
+	job.Params = string(encoded)
+
+	if err := h.exports.Enqueue(c, &job); err != nil {
+		if errors.Is(err, exports.ErrUnknownType) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_EXPORT_TYPE",
+				"message": i18n.Message(c, "INVALID_EXPORT_TYPE", "type must be one of: "+strings.Join(h.exports.Types(), ", ")),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create job"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// resolveExportTemplate loads the export template for an export request:
+// the requested one, else the default template of the export's entity, else
+// nil. It writes the error response when the request is invalid.
+func (h *JobHandler) resolveExportTemplate(c *gin.Context, req ExportJobRequest) (*models.ExportTemplate, bool) {
+	entity, registered := h.exports.Entity(req.Type)
+	if !registered {
+		// Enqueue reports the unknown type
+		return nil, true
+	}
+	if entity == "" {
+		if req.TemplateID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "EXPORT_TEMPLATE_NOT_SUPPORTED",
+				"message": i18n.Message(c, "EXPORT_TEMPLATE_NOT_SUPPORTED", "This export type does not support templates"),
+			})
+			return nil, false
+		}
+		return nil, true
+	}
+
+	var template models.ExportTemplate
+	var err error
+	if req.TemplateID != nil {
+		err = h.db.WithContext(c).First(&template, *req.TemplateID).Error
+	} else {
+		err = h.db.WithContext(c).Where("entity = ? AND is_default", entity).First(&template).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, true
+		}
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "EXPORT_TEMPLATE_NOT_FOUND",
+				"message": i18n.Message(c, "EXPORT_TEMPLATE_NOT_FOUND", "Export template not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch export template"),
+		})
+		return nil, false
+	}
+
+	if template.Entity != entity {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "EXPORT_TEMPLATE_MISMATCH",
+			"message": i18n.Message(c, "EXPORT_TEMPLATE_MISMATCH", "The export template is for "+template.Entity+" exports"),
+		})
+		return nil, false
+	}
+	return &template, true
+}
+
+// ListJobs returns the current user's jobs; admins see every job
+// GET /admin/jobs
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db := h.db.WithContext(c).Model(&models.Job{})
+	if user, _ := middleware.GetUserFromContext(c); !models.HoldsRole(user.Role, models.RoleAdmin) {
+		db = db.Where("created_by = ?", user.ID)
+		if user.ID == 0 {
+			db = db.Where("created_by_name = ?", user.Name)
+		}
+	}
+	db, _ = jobListQuery.Apply(db, c.Request.URL.Query())
+
+	var total int64
+	db.Count(&total)
+
+	var jobs []models.Job
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch jobs"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.JobListResponse{
+		Data:       jobs,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
+
+// GetJob returns a job's status and artifact metadata
+// GET /admin/jobs/:id
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.findJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadJobArtifact streams the file produced by a completed job
+// GET /admin/jobs/:id/download
+func (h *JobHandler) DownloadJobArtifact(c *gin.Context) {
+	job, ok := h.findJob(c)
+	if !ok {
+		return
+	}
+
+	if job.Status != models.JobStatusCompleted || job.Artifact.Key == "" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "JOB_NOT_COMPLETED",
+			"message": i18n.Message(c, "JOB_NOT_COMPLETED", "The job has not produced a file yet"),
+			"status":  job.Status,
+		})
+		return
+	}
+
+	expired := job.Artifact.DeletedAt != nil ||
+		(job.Artifact.ExpiresAt != nil && !time.Now().Before(*job.Artifact.ExpiresAt))
+	var file io.ReadCloser
+	if !expired {
+		var err error
+		file, err = h.exports.Open(c, *job)
+		if errors.Is(err, storage.ErrNotFound) {
+			expired = true
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "INTERNAL_ERROR",
+				"message": i18n.Message(c, "INTERNAL_ERROR", "Failed to open the export file"),
+			})
+			return
+		}
+	}
+	if expired {
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "gone",
+			"code":    "ARTIFACT_EXPIRED",
+			"message": i18n.Message(c, "ARTIFACT_EXPIRED", "The export file has expired, run the export again"),
+		})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Disposition", `attachment; filename="`+job.Artifact.FileName+`"`)
+	c.Header("X-Checksum-Sha256", job.Artifact.Checksum)
+	c.DataFromReader(http.StatusOK, job.Artifact.Bytes, job.Artifact.ContentType, file, nil)
+}
+
+// ResumeJob resumes a failed export from its last checkpoint, appending to
+// the rows it already wrote; without a checkpoint the export starts over
+// POST /admin/jobs/:id/resume
+func (h *JobHandler) ResumeJob(c *gin.Context) {
+	job, ok := h.findJob(c)
+	if !ok {
+		return
+	}
+
+	if err := h.exports.Resume(c, job); err != nil {
+		if errors.Is(err, exports.ErrNotResumable) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "JOB_NOT_RESUMABLE",
+				"message": i18n.Message(c, "JOB_NOT_RESUMABLE", "Only failed export jobs can be resumed"),
+				"status":  job.Status,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to resume job"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// findJob loads the job identified by the :id route parameter, writing
+// the error response when it cannot be found or belongs to someone else
+func (h *JobHandler) findJob(c *gin.Context) (*models.Job, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid job ID"),
+		})
+		return nil, false
+	}
+
+	var job models.Job
+	err = h.db.WithContext(c).First(&job, id).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch job"),
+		})
+		return nil, false
+	}
+
+	// Jobs of other users are reported as missing rather than forbidden
+	user, _ := middleware.GetUserFromContext(c)
+	if err == gorm.ErrRecordNotFound || (!models.HoldsRole(user.Role, models.RoleAdmin) && !ownsJob(user, job)) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "JOB_NOT_FOUND",
+			"message": i18n.Message(c, "JOB_NOT_FOUND", "Job not found"),
+		})
+		return nil, false
+	}
+
+	return &job, true
+}
+
+// ownsJob reports whether user created job. Service accounts share user ID
+// 0, so they are told apart by name.
+func ownsJob(user models.User, job models.Job) bool {
+	if job.CreatedBy != user.ID {
+		return false
+	}
+	return user.ID != 0 || job.CreatedByName == user.Name
+}
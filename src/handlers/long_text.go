@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// checkLongText checks the long text fields a write changes from previous,
+// nil for a new record, against the size limits, writing a 413 response
+// listing the fields over the hard limit. Fields over the soft limit only get
+// a Warning header. Unchanged fields are not checked, so records written
+// before the limits can still be edited. prefix qualifies the reported field
+// names.
+func checkLongText(c *gin.Context, model, previous models.LongText, prefix string) bool {
+	if oversized := prefixed(changedFields(models.OversizedFields(model, models.LongTextHardLimit), model, previous), prefix); len(oversized) > 0 {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "validation_error",
+			"code":    "TEXT_TOO_LONG",
+			"message": i18n.Message(c, "TEXT_TOO_LONG", fmt.Sprintf("Fields longer than %d bytes: %s", models.LongTextHardLimit, strings.Join(oversized, ", "))),
+			"fields":  oversized,
+			"limit":   models.LongTextHardLimit,
+		})
+		return false
+	}
+	if long := prefixed(changedFields(models.OversizedFields(model, models.LongTextSoftLimit), model, previous), prefix); len(long) > 0 {
+		c.Writer.Header().Add("Warning", fmt.Sprintf("299 - %q", fmt.Sprintf("Fields longer than %d bytes: %s", models.LongTextSoftLimit, strings.Join(long, ", "))))
+	}
+	return true
+}
+
+// longTextErrors returns a row error for each long text field of a model
+// over the hard limit, for imports that report errors per row
+func longTextErrors(model models.LongText) []string {
+	var errs []string
+	for _, field := range models.OversizedFields(model, models.LongTextHardLimit) {
+		errs = append(errs, fmt.Sprintf("%s must be at most %d bytes", field, models.LongTextHardLimit))
+	}
+	return errs
+}
+
+// changedFields keeps the fields whose value differs from previous
+func changedFields(fields []string, model, previous models.LongText) []string {
+	if previous == nil || len(fields) == 0 {
+		return fields
+	}
+	current, old := model.LongTextFields(), previous.LongTextFields()
+	changed := fields[:0]
+	for _, field := range fields {
+		if current[field] != old[field] {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+// prefixed qualifies field names with prefix
+func prefixed(fields []string, prefix string) []string {
+	for i := range fields {
+		fields[i] = prefix + fields[i]
+	}
+	return fields
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceHandler handles operational maintenance endpoints
+type MaintenanceHandler struct {
+	slowQueries *database.SlowQueryLog
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler. slowQueries may be
+// nil when slow query capture is disabled.
+func NewMaintenanceHandler(slowQueries *database.SlowQueryLog) *MaintenanceHandler {
+	return &MaintenanceHandler{slowQueries: slowQueries}
+}
+
+// SlowQueryReport is the response for the slow query endpoint
+type SlowQueryReport struct {
+	ThresholdMs int64                     `json:"threshold_ms"`
+	Summary     []database.SlowQueryStats `json:"summary"`
+	Queries     []database.SlowQuery      `json:"queries"`
+}
+
+// GetSlowQueries returns captured slow queries aggregated by fingerprint
+// GET /admin/maintenance/slow-queries
+func (h *MaintenanceHandler) GetSlowQueries(c *gin.Context) {
+	if !h.requireSlowQueryLog(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, SlowQueryReport{
+		ThresholdMs: h.slowQueries.Threshold().Milliseconds(),
+		Summary:     h.slowQueries.Summary(),
+		Queries:     h.slowQueries.Entries(),
+	})
+}
+
+// ResetSlowQueries clears captured slow queries
+// DELETE /admin/maintenance/slow-queries
+func (h *MaintenanceHandler) ResetSlowQueries(c *gin.Context) {
+	if !h.requireSlowQueryLog(c) {
+		return
+	}
+
+	h.slowQueries.Reset()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Slow query log cleared",
+	})
+}
+
+// requireSlowQueryLog responds with 404 when slow query capture is disabled
+func (h *MaintenanceHandler) requireSlowQueryLog(c *gin.Context) bool {
+	if h.slowQueries == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "SLOW_QUERY_LOG_DISABLED",
+			"message": i18n.Message(c, "SLOW_QUERY_LOG_DISABLED", "Slow query capture is disabled"),
+		})
+		return false
+	}
+	return true
+}
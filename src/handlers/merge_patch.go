@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// MergePatchContentType selects RFC 7396 JSON Merge Patch semantics on PATCH
+// endpoints. Plain application/json keeps each endpoint's original behavior.
+const MergePatchContentType = "application/merge-patch+json"
+
+// isMergePatch reports whether the request body is a JSON Merge Patch.
+// With the merge_patch flag off, merge patches are read as plain JSON.
+func isMergePatch(c *gin.Context) bool {
+	return c.ContentType() == MergePatchContentType && flags.Enabled(c, flags.MergePatch)
+}
+
+// customerMergePatchFields lists the customer fields a merge patch may set,
+// mapped to whether null is allowed to clear them
+var customerMergePatchFields = map[string]bool{
+	"name":              false,
+	"email":             false,
+	"phone":             true,
+	"company":           true,
+	"role":              true,
+	"status":            false,
+	"assigned_to":       true,
+	"contacted":         false,
+	"notes":             true,
+	"next_follow_up_at": true,
+}
+
+// dealMergePatchFields lists the deal fields a merge patch may set, mapped to
+// whether null is allowed to clear them
+var dealMergePatchFields = map[string]bool{
+	"title":               false,
+	"description":         true,
+	"customer_id":         false,
+	"contact_id":          true,
+	"stage":               false,
+	"amount":              false,
+	"currency":            false,
+	"probability":         false,
+	"expected_close_date": true,
+	"actual_close_date":   true,
+	"owner_id":            true,
+	"lost_reason":         true,
+	"external_id":         true,
+	"next_step":           true,
+	"next_step_due":       true,
+}
+
+// activityMergePatchFields lists the activity fields a merge patch may set,
+// mapped to whether null is allowed to clear them
+var activityMergePatchFields = map[string]bool{
+	"title":        false,
+	"description":  true,
+	"type":         false,
+	"status":       false,
+	"customer_id":  true,
+	"deal_id":      true,
+	"contact_id":   true,
+	"assigned_to":  true,
+	"due_date":     true,
+	"completed_at": true,
+	"duration":     true,
+	"outcome":      true,
+	"outcome_code": true,
+	"priority":     false,
+	"template":     true,
+}
+
+// mergePatchError is a rejected merge patch
+type mergePatchError struct {
+	Code    string
+	Message string
+	Fields  []string
+}
+
+// applyMergePatch applies an RFC 7396 merge patch from the request body to
+// target, a pointer to a model whose JSON names match its columns. Absent
+// keys are left untouched and null resets a field to its zero value. Keys
+// missing from fields, and nulls on fields that cannot be cleared, are
+// rejected with 400. A change_reason key is taken out of the patch for
+// requireChangeReason. It returns the columns whose value actually changed,
+// writing the error response and returning false when the patch is invalid.
+func applyMergePatch(c *gin.Context, target interface{}, fields map[string]bool) ([]string, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.Message(c, "INVALID_REQUEST", "Failed to read request body"),
+		})
+		return nil, false
+	}
+
+	// A patch that is not an object would replace the whole resource
+	var patch map[string]json.RawMessage
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '{' || json.Unmarshal(trimmed, &patch) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_MERGE_PATCH",
+			"message": i18n.Message(c, "INVALID_MERGE_PATCH", "Merge patch body must be a JSON object"),
+		})
+		return nil, false
+	}
+
+	// change_reason is not a field; it is kept for requireChangeReason
+	if raw, ok := patch["change_reason"]; ok {
+		var reason string
+		if err := json.Unmarshal(raw, &reason); err != nil && !isJSONNull(raw) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_REQUEST",
+				"message": i18n.Message(c, "INVALID_REQUEST", "change_reason must be a string"),
+			})
+			return nil, false
+		}
+		c.Set(mergePatchReasonKey, reason)
+		delete(patch, "change_reason")
+	}
+
+	changed, patchErr := mergePatch(target, patch, fields)
+	if patchErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    patchErr.Code,
+			"message": i18n.Message(c, patchErr.Code, patchErr.Message),
+			"fields":  patchErr.Fields,
+		})
+		return nil, false
+	}
+	return changed, true
+}
+
+// mergePatch applies a decoded merge patch object to target as
+// applyMergePatch does, returning the changed columns or the rejection
+func mergePatch(target interface{}, patch map[string]json.RawMessage, fields map[string]bool) ([]string, *mergePatchError) {
+	var unknown, notNullable []string
+	for key, raw := range patch {
+		nullable, ok := fields[key]
+		switch {
+		case !ok:
+			unknown = append(unknown, key)
+		case !nullable && isJSONNull(raw):
+			notNullable = append(notNullable, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, &mergePatchError{Code: "UNKNOWN_FIELDS", Message: "The patch contains fields that cannot be updated", Fields: unknown}
+	}
+	if len(notNullable) > 0 {
+		sort.Strings(notNullable)
+		return nil, &mergePatchError{Code: "FIELD_NOT_NULLABLE", Message: "These fields cannot be cleared with null", Fields: notNullable}
+	}
+
+	value := reflect.ValueOf(target).Elem()
+	var changed []string
+	for key, raw := range patch {
+		field := fieldByJSONName(value, key)
+		if !field.IsValid() {
+			continue
+		}
+
+		before, _ := json.Marshal(field.Interface())
+		if isJSONNull(raw) {
+			field.Set(reflect.Zero(field.Type()))
+		} else {
+			// Decode into a fresh value so a failed decode leaves the field untouched
+			decoded := reflect.New(field.Type())
+			if err := json.Unmarshal(raw, decoded.Interface()); err != nil {
+				return nil, &mergePatchError{Code: "INVALID_REQUEST", Message: "Invalid value for field " + key, Fields: []string{key}}
+			}
+			field.Set(decoded.Elem())
+		}
+		after, _ := json.Marshal(field.Interface())
+		if !bytes.Equal(before, after) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// fieldByJSONName finds the struct field serialized under a JSON name
+func fieldByJSONName(value reflect.Value, name string) reflect.Value {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return value.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// isJSONNull reports whether a raw JSON value is null
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
+
+// patchTouched reports whether a merge patch changed any of the given columns
+func patchTouched(changed []string, columns ...string) bool {
+	for _, column := range columns {
+		if slices.Contains(changed, column) {
+			return true
+		}
+	}
+	return false
+}
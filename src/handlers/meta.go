@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/buildinfo"
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MetaHandler serves information about the service itself
+type MetaHandler struct {
+	db            *gorm.DB
+	monitor       *security.Monitor
+	filterOptions *filterOptionsCache
+}
+
+// NewMetaHandler creates a new MetaHandler
+func NewMetaHandler(db *gorm.DB, monitor *security.Monitor) *MetaHandler {
+	return &MetaHandler{
+		db:            db,
+		monitor:       monitor,
+		filterOptions: &filterOptionsCache{entries: make(map[string]FilterOptionsResponse)},
+	}
+}
+
+// ListFlags returns every feature flag as it applies to this request,
+// with where its value came from
+// GET /admin/meta/flags
+func (h *MetaHandler) ListFlags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": flags.States(c),
+	})
+}
+
+// BuildInfoResponse describes the running build and its key dependencies
+type BuildInfoResponse struct {
+	buildinfo.Info
+	Dependencies []buildinfo.Dependency `json:"dependencies"`
+}
+
+// GetBuildInfo returns the version, commit and build time of the running
+// build, its Go version and the versions of its key dependencies
+// GET /admin/meta/build
+func (h *MetaHandler) GetBuildInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, BuildInfoResponse{
+		Info:         buildinfo.Get(),
+		Dependencies: buildinfo.Dependencies(),
+	})
+}
+
+// ListWebhooks returns the delivery contract of each configured outbound
+// webhook: whether it delivers at least or at most once, the headers that
+// identify events and attempts, and where attempts are listed. The contract
+// of webhook subscriptions, with the events they can subscribe to, is under
+// subscriptions.
+// GET /admin/meta/webhooks
+func (h *MetaHandler) ListWebhooks(c *gin.Context) {
+	webhooks := []SecurityWebhookResponse{}
+	if webhook := h.monitor.Webhook(); webhook != nil {
+		webhooks = append(webhooks, securityWebhookContract(webhook))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":          webhooks,
+		"subscriptions": webhookContract,
+	})
+}
+
+// GetEntitySchema describes an entity's fields, their validation rules and
+// enum values, list filters and sorting, and which fields the current user
+// may edit
+// GET /admin/meta/entities/:entity
+func (h *MetaHandler) GetEntitySchema(c *gin.Context) {
+	entity := c.Param("entity")
+	source, ok := entitySchemaSources[entity]
+	if !ok {
+		entities := make([]string, 0, len(entitySchemaSources))
+		for name := range entitySchemaSources {
+			entities = append(entities, name)
+		}
+		sort.Strings(entities)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "UNKNOWN_ENTITY",
+			"message": i18n.Message(c, "UNKNOWN_ENTITY", "entity must be one of: "+strings.Join(entities, ", ")),
+		})
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	schema, err := buildEntitySchema(h.db.WithContext(c), entity, source, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch entity metadata"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NoteHandler handles bulk note import and export
+type NoteHandler struct {
+	db *gorm.DB
+}
+
+// NewNoteHandler creates a new NoteHandler
+func NewNoteHandler(db *gorm.DB) *NoteHandler {
+	return &NoteHandler{db: db}
+}
+
+// noteCSVTimeLayouts are the created_at formats accepted by the notes import
+var noteCSVTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// parseNoteCSVTime parses a created_at value from a notes import
+func parseNoteCSVTime(value string) (time.Time, bool) {
+	for _, layout := range noteCSVTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// noteParentKey identifies the record a note is attached to, for duplicate
+// detection
+type noteParentKey struct {
+	customerID uint
+	dealID     uint
+	hash       string
+}
+
+// ImportNotes bulk-imports historical notes from CSV. Rows are attached by
+// customer_email and/or deal_external_id and keep their author_name and
+// created_at. Notes whose content already exists on the same parent are
+// skipped.
+// POST /admin/notes/import
+func (h *NoteHandler) ImportNotes(c *gin.Context) {
+	records, err := readCSVUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_CSV",
+			"message": i18n.Message(c, "INVALID_CSV", err.Error()),
+		})
+		return
+	}
+
+	// Resolve every referenced parent up front instead of per row
+	emailSet := map[string]bool{}
+	externalIDSet := map[string]bool{}
+	for _, record := range records {
+		if email := strings.ToLower(record.Get("customer_email")); email != "" {
+			emailSet[email] = true
+		}
+		if externalID := record.Get("deal_external_id"); externalID != "" {
+			externalIDSet[externalID] = true
+		}
+	}
+
+	customersByEmail := map[string][]models.Customer{}
+	if len(emailSet) > 0 {
+		var customers []models.Customer
+		if err := h.db.WithContext(c).Select("id", "email", "anonymized_at").
+			Where("LOWER(email) IN ?", slices.Collect(maps.Keys(emailSet))).Find(&customers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customers"),
+			})
+			return
+		}
+		for _, customer := range customers {
+			email := strings.ToLower(customer.Email)
+			customersByEmail[email] = append(customersByEmail[email], customer)
+		}
+	}
+
+	dealsByExternalID := map[string][]models.Deal{}
+	if len(externalIDSet) > 0 {
+		var deals []models.Deal
+		if err := h.db.WithContext(c).Select("id", "customer_id", "external_id").
+			Where("external_id IN ?", slices.Collect(maps.Keys(externalIDSet))).Find(&deals).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch deals"),
+			})
+			return
+		}
+		for _, deal := range deals {
+			dealsByExternalID[deal.ExternalID] = append(dealsByExternalID[deal.ExternalID], deal)
+		}
+	}
+
+	// Anonymized customers, including those only reached through a deal
+	anonymized := map[uint]bool{}
+	for _, customers := range customersByEmail {
+		for _, customer := range customers {
+			if customer.AnonymizedAt != nil {
+				anonymized[customer.ID] = true
+			}
+		}
+	}
+	var dealCustomerIDs []uint
+	for _, deals := range dealsByExternalID {
+		for _, deal := range deals {
+			dealCustomerIDs = append(dealCustomerIDs, deal.CustomerID)
+		}
+	}
+	if len(dealCustomerIDs) > 0 {
+		var ids []uint
+		if err := h.db.WithContext(c).Model(&models.Customer{}).
+			Where("id IN ? AND anonymized_at IS NOT NULL", dealCustomerIDs).Pluck("id", &ids).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customers"),
+			})
+			return
+		}
+		for _, id := range ids {
+			anonymized[id] = true
+		}
+	}
+
+	report := ImportReport{
+		DryRun:    isDryRun(c),
+		TotalRows: len(records),
+		Rows:      make([]ImportRowResult, 0, len(records)),
+	}
+
+	// Validate every row before writing anything
+	var notes []models.Note
+	var noteRows []int
+	var hashes []string
+	seen := map[noteParentKey]int{}
+	now := time.Now()
+	for _, record := range records {
+		result := ImportRowResult{Row: record.Line}
+
+		content := record.Get("content")
+		if content == "" {
+			result.Errors = append(result.Errors, "content is required")
+		}
+		result.Errors = append(result.Errors, longTextErrors(&models.Note{Content: content})...)
+		authorName := record.Get("author_name")
+		if len(authorName) > 255 {
+			result.Errors = append(result.Errors, "author_name must be at most 255 characters")
+		}
+
+		var createdAt time.Time
+		if value := record.Get("created_at"); value == "" {
+			result.Errors = append(result.Errors, "created_at is required")
+		} else if t, ok := parseNoteCSVTime(value); !ok {
+			result.Errors = append(result.Errors, "created_at must be an RFC 3339 timestamp, YYYY-MM-DD HH:MM:SS or YYYY-MM-DD")
+		} else if t.After(now) {
+			result.Errors = append(result.Errors, "created_at cannot be in the future")
+		} else {
+			createdAt = t
+		}
+
+		var customerID, dealID uint
+		email := record.Get("customer_email")
+		externalID := record.Get("deal_external_id")
+		if email == "" && externalID == "" {
+			result.Errors = append(result.Errors, "customer_email or deal_external_id is required")
+		}
+		if email != "" {
+			switch matches := customersByEmail[strings.ToLower(email)]; len(matches) {
+			case 0:
+				result.Errors = append(result.Errors, "no customer with email "+email)
+			case 1:
+				customerID = matches[0].ID
+			default:
+				result.Errors = append(result.Errors, "several customers have email "+email)
+			}
+		}
+		if externalID != "" {
+			switch matches := dealsByExternalID[externalID]; len(matches) {
+			case 0:
+				result.Errors = append(result.Errors, "no deal with external ID "+externalID)
+			case 1:
+				dealID = matches[0].ID
+				if customerID != 0 && matches[0].CustomerID != customerID {
+					result.Errors = append(result.Errors, "deal "+externalID+" does not belong to customer "+email)
+				}
+				customerID = matches[0].CustomerID
+			default:
+				result.Errors = append(result.Errors, "several deals have external ID "+externalID)
+			}
+		}
+		if customerID != 0 && anonymized[customerID] {
+			result.Errors = append(result.Errors, "customer is anonymized")
+		}
+
+		if len(result.Errors) > 0 {
+			result.Status = ImportRowFailed
+			report.Failed++
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		note := models.Note{
+			Content:     content,
+			ContentHash: models.NoteContentHash(content),
+			CustomerID:  &customerID,
+			AuthorID:    0,
+			AuthorName:  authorName,
+			Imported:    true,
+		}
+		if dealID != 0 {
+			note.DealID = &dealID
+		}
+		note.CreatedAt = createdAt
+		note.UpdatedAt = createdAt
+
+		key := noteParentKey{customerID: customerID, dealID: dealID, hash: note.ContentHash}
+		if row, ok := seen[key]; ok {
+			result.Status = ImportRowSkipped
+			result.Errors = []string{"duplicate of row " + strconv.Itoa(row)}
+			report.Skipped++
+		} else {
+			seen[key] = record.Line
+			result.Status = ImportRowValid
+			notes = append(notes, note)
+			noteRows = append(noteRows, len(report.Rows))
+			hashes = append(hashes, note.ContentHash)
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	// Skip notes whose content already exists on the same parent
+	if len(hashes) > 0 {
+		var existing []models.Note
+		if err := h.db.WithContext(c).Select("id", "customer_id", "deal_id", "content_hash").
+			Where("content_hash IN ?", hashes).Find(&existing).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch notes"),
+			})
+			return
+		}
+		existingIDs := map[noteParentKey]uint{}
+		for _, note := range existing {
+			key := noteParentKey{hash: note.ContentHash}
+			if note.CustomerID != nil {
+				key.customerID = *note.CustomerID
+			}
+			if note.DealID != nil {
+				key.dealID = *note.DealID
+			}
+			existingIDs[key] = note.ID
+		}
+
+		kept := notes[:0]
+		keptRows := noteRows[:0]
+		for i, note := range notes {
+			key := noteParentKey{customerID: *note.CustomerID, hash: note.ContentHash}
+			if note.DealID != nil {
+				key.dealID = *note.DealID
+			}
+			if id, ok := existingIDs[key]; ok {
+				row := &report.Rows[noteRows[i]]
+				row.Status = ImportRowSkipped
+				row.ID = id
+				row.Errors = []string{"duplicate of note " + strconv.FormatUint(uint64(id), 10)}
+				report.Skipped++
+				continue
+			}
+			kept = append(kept, note)
+			keptRows = append(keptRows, noteRows[i])
+		}
+		notes, noteRows = kept, keptRows
+	}
+
+	if report.DryRun || len(notes) == 0 {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	finish := annotateOperation(c, h.db, models.AnnotationTypeImport, "Note import")
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&notes, importBatchSize).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		for i := range notes {
+			if err := logAudit(c, tx, "note", notes[i].ID, models.AuditActionCreate, nil, &notes[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		finish("Failed: no notes were imported")
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to import notes"),
+		})
+		return
+	}
+
+	for i := range notes {
+		row := &report.Rows[noteRows[i]]
+		row.Status = ImportRowCreated
+		row.ID = notes[i].ID
+		report.Created++
+	}
+	finish("Imported " + strconv.Itoa(report.Created) + " notes, " + strconv.Itoa(report.Skipped) + " skipped, " + strconv.Itoa(report.Failed) + " rows failed")
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportNotes streams notes as CSV in the format ImportNotes reads
+// GET /admin/notes/export
+func (h *NoteHandler) ExportNotes(c *gin.Context) {
+	var params exports.NotesParams
+	for name, target := range map[string]**uint{"customer_id": &params.CustomerID, "deal_id": &params.DealID} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_ID",
+				"message": i18n.Message(c, "INVALID_ID", "Invalid "+name),
+			})
+			return
+		}
+		parsed := uint(id)
+		*target = &parsed
+	}
+	if value := c.Query("imported"); value != "" {
+		imported, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_REQUEST",
+				"message": i18n.Message(c, "INVALID_REQUEST", "imported must be true or false"),
+			})
+			return
+		}
+		params.Imported = &imported
+	}
+	for name, target := range map[string]**time.Time{"created_from": &params.CreatedFrom, "created_to": &params.CreatedTo} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_DATE",
+				"message": i18n.Message(c, "INVALID_DATE", name+" must be an RFC 3339 timestamp"),
+			})
+			return
+		}
+		*target = &t
+	}
+
+	encoded, _ := json.Marshal(params)
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="notes.csv"`)
+	c.Status(http.StatusOK)
+
+	// Headers are sent by now, so a failure can only cut the stream short
+	if _, err := exports.NotesCSV(c, h.db, encoded, c.Writer, exports.Resume{}); err != nil {
+		middleware.Logger.Error("Notes export failed: " + err.Error())
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// setPageHeaders mirrors the total and next page token or cursor of a list
+// response in headers, for clients that page without parsing the body
+func setPageHeaders(c *gin.Context, total int64, next string) {
+	c.Header(middleware.HeaderTotalCount, strconv.FormatInt(total, 10))
+	if next != "" {
+		c.Header(middleware.HeaderNextCursor, next)
+	}
+}
+
+// respondInvalidCursor rejects a cursor that is malformed or was made for
+// another sort
+func respondInvalidCursor(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "validation_error",
+		"code":    "INVALID_CURSOR",
+		"message": i18n.Message(c, "INVALID_CURSOR", "Invalid cursor"),
+	})
+}
+
+// respondListFailed responds with a database error while listing
+func respondListFailed(c *gin.Context, message string) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "internal_error",
+		"code":    "DATABASE_ERROR",
+		"message": i18n.Message(c, "DATABASE_ERROR", message),
+	})
+}
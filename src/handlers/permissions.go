@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// enforceFieldPermissions responds with 403 and returns false when the
+// current user changes fields they are not allowed to edit on the record
+func enforceFieldPermissions(c *gin.Context, entity string, ownerID *uint, changed []string) bool {
+	user, _ := middleware.GetUserFromContext(c)
+	isOwner := ownerID == nil || *ownerID == user.ID
+
+	forbidden := models.ForbiddenFields(entity, user.Role, isOwner, changed)
+	if len(forbidden) == 0 {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "forbidden",
+		"code":    "FIELD_EDIT_FORBIDDEN",
+		"message": i18n.Message(c, "FIELD_EDIT_FORBIDDEN", "You do not have permission to edit these fields"),
+		"fields":  forbidden,
+	})
+	return false
+}
+
+// uintChanged reports whether a requested optional ID differs from the current one
+func uintChanged(requested, current *uint) bool {
+	if requested == nil {
+		return false
+	}
+	return current == nil || *requested != *current
+}
+
+// timeChanged reports whether a requested optional time differs from the current one
+func timeChanged(requested, current *time.Time) bool {
+	if requested == nil {
+		return false
+	}
+	return current == nil || !requested.Equal(*current)
+}
+
+// customerUpdateChangedFields lists the fields a PUT request actually changes
+func customerUpdateChangedFields(req CustomerUpdateRequest, customer models.Customer) []string {
+	var changed []string
+	if req.Name != "" && req.Name != customer.Name {
+		changed = append(changed, "name")
+	}
+	if req.Email != "" && req.Email != customer.Email {
+		changed = append(changed, "email")
+	}
+	if req.Phone != "" && req.Phone != customer.Phone {
+		changed = append(changed, "phone")
+	}
+	if req.Company != "" && req.Company != customer.Company {
+		changed = append(changed, "company")
+	}
+	if req.Role != "" && req.Role != customer.Role {
+		changed = append(changed, "role")
+	}
+	if req.Status != "" && req.Status != customer.Status {
+		changed = append(changed, "status")
+	}
+	if uintChanged(req.AssignedTo, customer.AssignedTo) {
+		changed = append(changed, "assigned_to")
+	}
+	if req.Contacted != nil && *req.Contacted != customer.Contacted {
+		changed = append(changed, "contacted")
+	}
+	if req.Notes != "" && req.Notes != customer.Notes {
+		changed = append(changed, "notes")
+	}
+	if timeChanged(req.NextFollowUpAt, customer.NextFollowUpAt) {
+		changed = append(changed, "next_follow_up_at")
+	}
+	return changed
+}
+
+// customerPatchChangedFields lists the fields a PATCH request actually changes
+func customerPatchChangedFields(req CustomerPatchRequest, customer models.Customer) []string {
+	var changed []string
+	if req.Status != nil && *req.Status != customer.Status {
+		changed = append(changed, "status")
+	}
+	if uintChanged(req.AssignedTo, customer.AssignedTo) {
+		changed = append(changed, "assigned_to")
+	}
+	if req.Contacted != nil && *req.Contacted != customer.Contacted {
+		changed = append(changed, "contacted")
+	}
+	if timeChanged(req.NextFollowUpAt, customer.NextFollowUpAt) {
+		changed = append(changed, "next_follow_up_at")
+	}
+	return changed
+}
+
+// dealUpdateChangedFields lists the fields a PUT request actually changes
+func dealUpdateChangedFields(req DealUpdateRequest, deal models.Deal) []string {
+	var changed []string
+	if req.Title != "" && req.Title != deal.Title {
+		changed = append(changed, "title")
+	}
+	if req.Description != "" && req.Description != deal.Description {
+		changed = append(changed, "description")
+	}
+	if req.CustomerID != nil && *req.CustomerID != deal.CustomerID {
+		changed = append(changed, "customer_id")
+	}
+	if uintChanged(req.ContactID, deal.ContactID) {
+		changed = append(changed, "contact_id")
+	}
+	if req.Stage != "" && req.Stage != deal.Stage {
+		changed = append(changed, "stage")
+	}
+	if req.Amount != nil && *req.Amount != deal.Amount {
+		changed = append(changed, "amount")
+	}
+	if req.Currency != "" && req.Currency != deal.Currency {
+		changed = append(changed, "currency")
+	}
+	if req.Probability != nil && *req.Probability != deal.Probability {
+		changed = append(changed, "probability")
+	}
+	if timeChanged(req.ExpectedCloseDate, deal.ExpectedCloseDate) {
+		changed = append(changed, "expected_close_date")
+	}
+	if timeChanged(req.ActualCloseDate, deal.ActualCloseDate) {
+		changed = append(changed, "actual_close_date")
+	}
+	if uintChanged(req.OwnerID, deal.OwnerID) {
+		changed = append(changed, "owner_id")
+	}
+	if req.LostReason != "" && req.LostReason != deal.LostReason {
+		changed = append(changed, "lost_reason")
+	}
+	if req.ExternalID != "" && req.ExternalID != deal.ExternalID {
+		changed = append(changed, "external_id")
+	}
+	if req.NextStep != nil && *req.NextStep != deal.NextStep {
+		changed = append(changed, "next_step")
+	}
+	if timeChanged(req.NextStepDue, deal.NextStepDue) {
+		changed = append(changed, "next_step_due")
+	}
+	return changed
+}
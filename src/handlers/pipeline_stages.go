@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/stages"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PipelineStageHandler handles pipeline stage endpoints
+type PipelineStageHandler struct {
+	db     *gorm.DB
+	stages *stages.Service
+}
+
+// NewPipelineStageHandler creates a new PipelineStageHandler
+func NewPipelineStageHandler(db *gorm.DB, stages *stages.Service) *PipelineStageHandler {
+	return &PipelineStageHandler{db: db, stages: stages}
+}
+
+// PipelineStageCreateRequest represents the request body for creating a
+// pipeline stage. Without an order the stage goes last.
+type PipelineStageCreateRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	DisplayName string `json:"display_name" binding:"required,max=100"`
+	Order       int    `json:"order,omitempty" binding:"min=0"`
+	Color       string `json:"color,omitempty" binding:"omitempty,len=7,hexcolor"`
+	IsActive    *bool  `json:"is_active,omitempty"` // Defaults to true
+}
+
+// PipelineStageUpdateRequest represents the request body for updating a
+// pipeline stage. Changing the name renames the stage on its deals too.
+type PipelineStageUpdateRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	DisplayName string `json:"display_name" binding:"required,max=100"`
+	Order       int    `json:"order" binding:"required,min=1"`
+	Color       string `json:"color,omitempty" binding:"omitempty,len=7,hexcolor"`
+	IsActive    *bool  `json:"is_active,omitempty"` // Unchanged when omitted
+}
+
+// ListPipelineStages returns every pipeline stage, active or not, in board
+// order
+// GET /admin/pipeline-stages
+func (h *PipelineStageHandler) ListPipelineStages(c *gin.Context) {
+	var stages []models.PipelineStage
+	if err := h.db.WithContext(c).Order(`"order" ASC, id ASC`).Find(&stages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch pipeline stages"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PipelineStageListResponse{Data: stages})
+}
+
+// CreatePipelineStage adds a pipeline stage
+// POST /admin/pipeline-stages
+func (h *PipelineStageHandler) CreatePipelineStage(c *gin.Context) {
+	var req PipelineStageCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if !h.validateName(c, req.Name) || !h.nameAvailable(c, req.Name) {
+		return
+	}
+
+	stage := models.PipelineStage{
+		Name:        req.Name,
+		DisplayName: req.DisplayName,
+		Order:       req.Order,
+		Color:       req.Color,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+	}
+	if stage.Order == 0 {
+		h.db.WithContext(c).Model(&models.PipelineStage{}).Select(`COALESCE(MAX("order"), 0) + 1`).Scan(&stage.Order)
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&stage).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "pipeline_stage", stage.ID, models.AuditActionCreate, nil, &stage)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create pipeline stage"),
+		})
+		return
+	}
+	h.reloadStages(c)
+
+	c.JSON(http.StatusCreated, stage)
+}
+
+// UpdatePipelineStage replaces a pipeline stage. Renaming a stage moves its
+// deals, including deleted ones, to the new name in the same transaction.
+// Reserved stages cannot be renamed or deactivated, and a stage with deals
+// cannot be deactivated.
+// PUT /admin/pipeline-stages/:id
+func (h *PipelineStageHandler) UpdatePipelineStage(c *gin.Context) {
+	stage, ok := h.findStage(c)
+	if !ok {
+		return
+	}
+	oldStage := *stage
+
+	var req PipelineStageUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	renamed := req.Name != stage.Name
+	deactivated := stage.IsActive && req.IsActive != nil && !*req.IsActive
+	if (renamed || deactivated) && models.IsReservedDealStage(models.DealStage(stage.Name)) {
+		h.stageReserved(c)
+		return
+	}
+	if renamed && (!h.validateName(c, req.Name) || !h.nameAvailable(c, req.Name)) {
+		return
+	}
+	if deactivated && h.hasDeals(c, stage) {
+		return
+	}
+
+	stage.Name = req.Name
+	stage.DisplayName = req.DisplayName
+	stage.Order = req.Order
+	stage.Color = req.Color
+	if req.IsActive != nil {
+		stage.IsActive = *req.IsActive
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(stage).Error; err != nil {
+			return err
+		}
+		if renamed {
+			if err := tx.Unscoped().Model(&models.Deal{}).Where("stage = ?", oldStage.Name).
+				UpdateColumn("stage", stage.Name).Error; err != nil {
+				return err
+			}
+		}
+
+		// Log audit
+		return logAudit(c, tx, "pipeline_stage", stage.ID, models.AuditActionUpdate, &oldStage, stage)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update pipeline stage"),
+		})
+		return
+	}
+	h.reloadStages(c)
+
+	c.JSON(http.StatusOK, stage)
+}
+
+// DeletePipelineStage removes a pipeline stage. Reserved stages and stages
+// that still have deals cannot be deleted.
+// DELETE /admin/pipeline-stages/:id
+func (h *PipelineStageHandler) DeletePipelineStage(c *gin.Context) {
+	stage, ok := h.findStage(c)
+	if !ok {
+		return
+	}
+
+	if models.IsReservedDealStage(models.DealStage(stage.Name)) {
+		h.stageReserved(c)
+		return
+	}
+	if h.hasDeals(c, stage) {
+		return
+	}
+
+	// Deleted for good so the name can be used again
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(stage).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "pipeline_stage", stage.ID, models.AuditActionDelete, stage, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete pipeline stage"),
+		})
+		return
+	}
+	h.reloadStages(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipeline stage deleted successfully",
+	})
+}
+
+// validateName responds with 400 when a stage name cannot be stored on deals
+func (h *PipelineStageHandler) validateName(c *gin.Context, name string) bool {
+	if err := models.ValidateStageName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_STAGE",
+			"message": i18n.Message(c, "INVALID_STAGE", err.Error()),
+		})
+		return false
+	}
+	return true
+}
+
+// nameAvailable responds with 409 when another stage has the name
+func (h *PipelineStageHandler) nameAvailable(c *gin.Context, name string) bool {
+	var existing int64
+	h.db.WithContext(c).Unscoped().Model(&models.PipelineStage{}).Where("name = ?", name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "STAGE_EXISTS",
+			"message": i18n.Message(c, "STAGE_EXISTS", "A pipeline stage with this name already exists"),
+		})
+		return false
+	}
+	return true
+}
+
+// hasDeals responds with 409 when deals are still in the stage
+func (h *PipelineStageHandler) hasDeals(c *gin.Context, stage *models.PipelineStage) bool {
+	var deals int64
+	if err := h.db.WithContext(c).Model(&models.Deal{}).Where("stage = ?", stage.Name).Count(&deals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to count the stage's deals"),
+		})
+		return true
+	}
+	if deals == 0 {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":      "conflict",
+		"code":       "DEAL_REFERENCES_STAGE",
+		"message":    i18n.Message(c, "DEAL_REFERENCES_STAGE", "Deals are still in this stage; move them to another stage first"),
+		"deal_count": deals,
+	})
+	return true
+}
+
+// stageReserved responds with 409 for changes to the reserved stages
+func (h *PipelineStageHandler) stageReserved(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "conflict",
+		"code":    "STAGE_RESERVED",
+		"message": i18n.Message(c, "STAGE_RESERVED", "The prospecting, closed_won and closed_lost stages cannot be renamed, deactivated or deleted"),
+	})
+}
+
+// reloadStages applies a stage change to deal validation immediately. A
+// failure leaves the previous stages in effect until the next refresh.
+func (h *PipelineStageHandler) reloadStages(c *gin.Context) {
+	if err := h.stages.Reload(c); err != nil {
+		middleware.Logger.Warn("Failed to reload pipeline stages: " + err.Error())
+	}
+}
+
+// findStage loads the stage identified by the :id route parameter, writing
+// the error response when it cannot be found
+func (h *PipelineStageHandler) findStage(c *gin.Context) (*models.PipelineStage, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid pipeline stage ID"),
+		})
+		return nil, false
+	}
+
+	var stage models.PipelineStage
+	if err := h.db.WithContext(c).First(&stage, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "PIPELINE_STAGE_NOT_FOUND",
+				"message": i18n.Message(c, "PIPELINE_STAGE_NOT_FOUND", "Pipeline stage not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch pipeline stage"),
+		})
+		return nil, false
+	}
+
+	return &stage, true
+}
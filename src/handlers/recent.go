@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recentViewsLimit is the number of entries returned by GET /admin/me/recent
+const recentViewsLimit = 20
+
+// RecentViewHandler handles "recently viewed" endpoints
+type RecentViewHandler struct {
+	db *gorm.DB
+}
+
+// NewRecentViewHandler creates a new RecentViewHandler
+func NewRecentViewHandler(db *gorm.DB) *RecentViewHandler {
+	return &RecentViewHandler{db: db}
+}
+
+// GetMyRecent returns the current user's most recently viewed customers and deals
+// GET /admin/me/recent
+func (h *RecentViewHandler) GetMyRecent(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "NO_USER_CONTEXT",
+			"message": i18n.Message(c, "NO_USER_CONTEXT", "User not found in context"),
+		})
+		return
+	}
+
+	var views []models.RecentView
+	if err := h.db.WithContext(c).Where("user_id = ?", user.ID).
+		Order("viewed_at DESC").
+		Limit(recentViewsLimit).
+		Find(&views).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch recent views"),
+		})
+		return
+	}
+
+	var customerIDs, dealIDs []uint
+	for _, view := range views {
+		switch view.EntityType {
+		case models.RecentViewCustomer:
+			customerIDs = append(customerIDs, view.EntityID)
+		case models.RecentViewDeal:
+			dealIDs = append(dealIDs, view.EntityID)
+		}
+	}
+
+	customers := make(map[uint]models.RecentCustomerSummary)
+	if len(customerIDs) > 0 {
+		var rows []models.RecentCustomerSummary
+		h.db.WithContext(c).Model(&models.Customer{}).
+			Select("id, name, email, company, status").
+			Where("id IN ?", customerIDs).
+			Scan(&rows)
+		for _, row := range rows {
+			customers[row.ID] = row
+		}
+	}
+
+	deals := make(map[uint]models.RecentDealSummary)
+	if len(dealIDs) > 0 {
+		var rows []models.RecentDealSummary
+		h.db.WithContext(c).Model(&models.Deal{}).
+			Select("id, title, customer_id, stage, amount, currency, owner_id").
+			Where("id IN ?", dealIDs).
+			Scan(&rows)
+		// Scan skips the query callbacks that redact loaded deals
+		if viewer, ok := redaction.From(c); ok {
+			redaction.Mark(rows, viewer)
+		}
+		for _, row := range rows {
+			deals[row.ID] = row
+		}
+	}
+
+	// Skip entities that have since been deleted
+	items := make([]models.RecentViewItem, 0, len(views))
+	for _, view := range views {
+		item := models.RecentViewItem{
+			EntityType: view.EntityType,
+			EntityID:   view.EntityID,
+			ViewedAt:   view.ViewedAt,
+		}
+		switch view.EntityType {
+		case models.RecentViewCustomer:
+			summary, ok := customers[view.EntityID]
+			if !ok {
+				continue
+			}
+			item.Summary = summary
+		case models.RecentViewDeal:
+			summary, ok := deals[view.EntityID]
+			if !ok {
+				continue
+			}
+			item.Summary = summary
+		default:
+			continue
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, models.RecentViewListResponse{Data: items})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// Report bucket intervals
+const (
+	reportIntervalMonth = "month"
+	reportIntervalWeek  = "week"
+)
+
+// maxReportBuckets caps how many buckets the period of a bucketed report
+// may span, whichever page of them is asked for
+const maxReportBuckets = 520
+
+// BucketPage describes the page of its buckets a bucketed report returns.
+// Pages are numbered from 1 and hold every bucket unless page_size is set.
+type BucketPage struct {
+	Page         int `json:"page"`
+	PageSize     int `json:"page_size"`
+	TotalBuckets int `json:"total_buckets"`
+	TotalPages   int `json:"total_pages"`
+}
+
+// reportBuckets is the page of buckets a bucketed report computes
+type reportBuckets struct {
+	BucketPage
+	starts   []time.Time // Start of every bucket of the page
+	from, to time.Time   // What of the period the page covers, when it has buckets
+}
+
+// isValidReportInterval reports whether interval is a report bucket
+// interval
+func isValidReportInterval(interval string) bool {
+	return interval == reportIntervalMonth || interval == reportIntervalWeek
+}
+
+// reportBucketStart returns the start of the month or week holding t
+func reportBucketStart(t time.Time, interval string) time.Time {
+	year, month, day := t.Date()
+	if interval == reportIntervalWeek {
+		start := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+		return start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	}
+	return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+}
+
+// nextReportBucket returns the start of the bucket after the one starting
+// at start
+func nextReportBucket(start time.Time, interval string) time.Time {
+	if interval == reportIntervalWeek {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// bucketPage returns the page of the buckets from from to to, starting at
+// midnight in location, that the request asks for with ?page=&page_size=.
+// Only that page is computed, so a report's work and response stay bounded
+// by the page size. It writes 422 TOO_MANY_BUCKETS when the period spans
+// more than maxReportBuckets.
+func bucketPage(c *gin.Context, from, to time.Time, interval string, location *time.Location) (reportBuckets, bool) {
+	var starts []time.Time
+	to = to.In(location)
+	for start := reportBucketStart(from.In(location), interval); !start.After(to); start = nextReportBucket(start, interval) {
+		if len(starts) == maxReportBuckets {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "validation_error",
+				"code":    "TOO_MANY_BUCKETS",
+				"message": i18n.Message(c, "TOO_MANY_BUCKETS", fmt.Sprintf("The period spans more than %d buckets; shorten it or use a longer interval", maxReportBuckets)),
+			})
+			return reportBuckets{}, false
+		}
+		starts = append(starts, start)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(maxReportBuckets)))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > maxReportBuckets {
+		pageSize = maxReportBuckets
+	}
+	buckets := reportBuckets{BucketPage: BucketPage{
+		Page:         page,
+		PageSize:     pageSize,
+		TotalBuckets: len(starts),
+		TotalPages:   (len(starts) + pageSize - 1) / pageSize,
+	}}
+	first := min((page-1)*pageSize, len(starts))
+	buckets.starts = starts[first:min(first+pageSize, len(starts))]
+	if len(buckets.starts) > 0 {
+		buckets.from = buckets.starts[0]
+		if buckets.from.Before(from) {
+			buckets.from = from
+		}
+		buckets.to = nextReportBucket(buckets.starts[len(buckets.starts)-1], interval).Add(-time.Nanosecond)
+		if buckets.to.After(to) {
+			buckets.to = to
+		}
+	}
+	setPageHeaders(c, int64(len(starts)), "")
+	return buckets, true
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/reportrollup"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportRollupHandler handles report snapshot maintenance endpoints
+type ReportRollupHandler struct {
+	db     *gorm.DB
+	runner *reportrollup.Runner
+}
+
+// NewReportRollupHandler creates a new ReportRollupHandler
+func NewReportRollupHandler(db *gorm.DB, runner *reportrollup.Runner) *ReportRollupHandler {
+	return &ReportRollupHandler{db: db, runner: runner}
+}
+
+// RebuildSnapshots recomputes every report snapshot day now and returns the
+// rollup summary
+// POST /admin/maintenance/rollup
+func (h *ReportRollupHandler) RebuildSnapshots(c *gin.Context) {
+	finish := annotateOperation(c, h.db, models.AnnotationTypeMaintenance, "Report snapshot rebuild")
+	summary, err := h.runner.Run(c.Request.Context(), true)
+	if err != nil {
+		finish("Failed: " + err.Error())
+		if errors.Is(err, reportrollup.ErrRollupInProgress) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "REPORT_ROLLUP_IN_PROGRESS",
+				"message": i18n.Message(c, "REPORT_ROLLUP_IN_PROGRESS", "A report rollup is already in progress"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to roll up report snapshots"),
+		})
+		return
+	}
+	finish("Rolled up through " + summary.Through)
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -1,21 +1,45 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/reportrollup"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ReportHandler handles reporting endpoints
 type ReportHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	calendar    *businesstime.Service
+	concurrency int
 }
 
-// NewReportHandler creates a new ReportHandler
-func NewReportHandler(db *gorm.DB) *ReportHandler {
-	return &ReportHandler{db: db}
+// NewReportHandler creates a new ReportHandler. concurrency bounds how many
+// sections of a report are computed in parallel; relative report ranges
+// default to the calendar's time zone.
+func NewReportHandler(db *gorm.DB, calendar *businesstime.Service, concurrency int) *ReportHandler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ReportHandler{db: db, calendar: calendar, concurrency: concurrency}
 }
 
 // OverviewReport represents the overview report response
@@ -25,12 +49,24 @@ type OverviewReport struct {
 	Activities    ActivityStats     `json:"activities"`
 	RecentDeals   []models.Deal     `json:"recent_deals"`
 	TopCustomers  []CustomerSummary `json:"top_customers"`
+	PartialErrors []string          `json:"partial_errors,omitempty"` // Sections that failed and were left empty
 }
 
 // CustomerStats represents customer statistics
 type CustomerStats struct {
-	Total    int64            `json:"total"`
-	ByStatus map[string]int64 `json:"by_status"`
+	Total      int64            `json:"total"`
+	ByStatus   map[string]int64 `json:"by_status"`
+	Conversion ConversionStats  `json:"conversion"`
+}
+
+// ConversionStats measures how leads and prospects convert to customers,
+// from the conversion stamps set when a customer becomes active
+type ConversionStats struct {
+	Open                 int64   `json:"open"`                    // Leads and prospects not converted yet
+	Converted            int64   `json:"converted"`               // Customers with a conversion stamp
+	Rate                 float64 `json:"rate"`                    // Converted share of converted and open, 0 to 1
+	AverageDaysToConvert float64 `json:"average_days_to_convert"` // From creation to conversion
+	Unstamped            int64   `json:"unstamped"`               // Other customers without a stamp, created active or before conversions were stamped
 }
 
 // DealStats represents deal statistics
@@ -41,6 +77,7 @@ type DealStats struct {
 	WonCount        int64            `json:"won_count"`
 	LostCount       int64            `json:"lost_count"`
 	OpenCount       int64            `json:"open_count"`
+	MissingNextStep int64            `json:"missing_next_step"` // Open deals without a next step
 	AverageDealSize float64          `json:"average_deal_size"`
 	ByStage         map[string]int64 `json:"by_stage"`
 }
@@ -64,106 +101,216 @@ type CustomerSummary struct {
 	DealsValue float64 `json:"deals_value"`
 }
 
-// GetOverview returns an overview report
+// customerStatuses lists customer statuses in display order
+var customerStatuses = []models.CustomerStatus{
+	models.CustomerStatusLead,
+	models.CustomerStatusProspect,
+	models.CustomerStatusActive,
+	models.CustomerStatusInactive,
+	models.CustomerStatusChurned,
+}
+
+// overviewSection is an independently computed block of the overview
+// report. A failing critical section fails the whole report; other sections
+// are reported in partial_errors.
+type overviewSection struct {
+	name     string
+	critical bool
+	compute  func(h *ReportHandler, ctx context.Context, report *OverviewReport) error
+}
+
+// overviewSections lists the blocks of the overview report. Each writes its
+// own field of the report, so they can run concurrently.
+var overviewSections = []overviewSection{
+	{name: "customers", critical: true, compute: func(h *ReportHandler, ctx context.Context, report *OverviewReport) (err error) {
+		report.Customers, err = h.getCustomerStats(ctx)
+		return err
+	}},
+	{name: "deals", critical: true, compute: func(h *ReportHandler, ctx context.Context, report *OverviewReport) (err error) {
+		report.Deals, err = h.getDealStats(ctx)
+		return err
+	}},
+	{name: "activities", compute: func(h *ReportHandler, ctx context.Context, report *OverviewReport) (err error) {
+		report.Activities, err = h.getActivityStats(ctx)
+		return err
+	}},
+	{name: "recent_deals", compute: func(h *ReportHandler, ctx context.Context, report *OverviewReport) (err error) {
+		report.RecentDeals, err = h.getRecentDeals(ctx, 5)
+		return err
+	}},
+	{name: "top_customers", compute: func(h *ReportHandler, ctx context.Context, report *OverviewReport) (err error) {
+		report.TopCustomers, err = h.getTopCustomers(ctx, 5)
+		return err
+	}},
+}
+
+// GetOverview returns an overview report. Its sections are computed in
+// parallel; sections that fail are left empty and named in partial_errors.
 // GET /admin/reports/overview
 func (h *ReportHandler) GetOverview(c *gin.Context) {
-	report := OverviewReport{
-		Customers:  h.getCustomerStats(),
-		Deals:      h.getDealStats(),
-		Activities: h.getActivityStats(),
-	}
+	var report OverviewReport
+	var mu sync.Mutex
 
-	// Get recent deals
-	var recentDeals []models.Deal
-	h.db.Preload("Customer").Order("created_at DESC").Limit(5).Find(&recentDeals)
-	report.RecentDeals = recentDeals
-
-	// Get top customers by deal value
-	report.TopCustomers = h.getTopCustomers(5)
+	g, ctx := errgroup.WithContext(c.Request.Context())
+	g.SetLimit(h.concurrency)
+	for _, section := range overviewSections {
+		g.Go(func() error {
+			err := section.compute(h, ctx, &report)
+			if err == nil {
+				return nil
+			}
+			if section.critical {
+				return fmt.Errorf("%s: %w", section.name, err)
+			}
+			middleware.Logger.Warn(fmt.Sprintf("Overview report section %s failed: %v", section.name, err))
+			mu.Lock()
+			report.PartialErrors = append(report.PartialErrors, section.name)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		middleware.Logger.Error("Overview report failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute overview report"),
+		})
+		return
+	}
 
+	sort.Strings(report.PartialErrors)
 	c.JSON(http.StatusOK, report)
 }
 
 // getCustomerStats returns customer statistics
-func (h *ReportHandler) getCustomerStats() CustomerStats {
+func (h *ReportHandler) getCustomerStats(ctx context.Context) (CustomerStats, error) {
 	stats := CustomerStats{
 		ByStatus: make(map[string]int64),
 	}
 
-	// Total customers
-	h.db.Model(&models.Customer{}).Count(&stats.Total)
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := h.db.WithContext(ctx).Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return stats, err
+	}
 
-	// By status
-	statuses := []models.CustomerStatus{
-		models.CustomerStatusLead,
-		models.CustomerStatusProspect,
-		models.CustomerStatusActive,
-		models.CustomerStatusInactive,
-		models.CustomerStatusChurned,
+	for _, status := range customerStatuses {
+		stats.ByStatus[string(status)] = 0
+	}
+	for _, row := range rows {
+		stats.Total += row.Count
+		if _, ok := stats.ByStatus[row.Status]; ok {
+			stats.ByStatus[row.Status] = row.Count
+		}
 	}
 
-	for _, status := range statuses {
-		var count int64
-		h.db.Model(&models.Customer{}).Where("status = ?", status).Count(&count)
-		stats.ByStatus[string(status)] = count
+	conversion, err := h.getConversionStats(ctx)
+	if err != nil {
+		return stats, err
 	}
+	stats.Conversion = conversion
 
-	return stats
+	return stats, nil
+}
+
+// getConversionStats returns lead to customer conversion statistics
+func (h *ReportHandler) getConversionStats(ctx context.Context) (ConversionStats, error) {
+	var stats ConversionStats
+	leadStatuses := []models.CustomerStatus{models.CustomerStatusLead, models.CustomerStatusProspect}
+	if err := h.db.WithContext(ctx).Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
+		Select(`COUNT(*) FILTER (WHERE converted_at IS NULL AND status IN ?) AS open,
+			COUNT(*) FILTER (WHERE converted_at IS NOT NULL) AS converted,
+			COALESCE(AVG(EXTRACT(EPOCH FROM converted_at - created_at)) FILTER (WHERE converted_at IS NOT NULL), 0) / 86400 AS average_days_to_convert,
+			COUNT(*) FILTER (WHERE converted_at IS NULL AND status NOT IN ?) AS unstamped`, leadStatuses, leadStatuses).
+		Scan(&stats).Error; err != nil {
+		return stats, err
+	}
+
+	if total := stats.Open + stats.Converted; total > 0 {
+		stats.Rate = float64(stats.Converted) / float64(total)
+	}
+	return stats, nil
 }
 
 // getDealStats returns deal statistics
-func (h *ReportHandler) getDealStats() DealStats {
+func (h *ReportHandler) getDealStats(ctx context.Context) (DealStats, error) {
 	stats := DealStats{
 		ByStage: make(map[string]int64),
 	}
 
-	// Total deals
-	h.db.Model(&models.Deal{}).Count(&stats.Total)
-
-	// Total value
-	h.db.Model(&models.Deal{}).Select("COALESCE(SUM(amount), 0)").Scan(&stats.TotalValue)
-
-	// Won deals
-	h.db.Model(&models.Deal{}).Where("stage = ?", models.DealStageClosedWon).Count(&stats.WonCount)
-	h.db.Model(&models.Deal{}).Where("stage = ?", models.DealStageClosedWon).Select("COALESCE(SUM(amount), 0)").Scan(&stats.WonValue)
-
-	// Lost deals
-	h.db.Model(&models.Deal{}).Where("stage = ?", models.DealStageClosedLost).Count(&stats.LostCount)
+	var rows []struct {
+		Stage           string
+		Count           int64
+		Value           float64
+		MissingNextStep int64
+	}
+	if err := h.db.WithContext(ctx).Model(&models.Deal{}).Scopes(models.NotArchived("deals")).
+		Select("stage, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS value, " +
+			"SUM(CASE WHEN next_step = '' THEN 1 ELSE 0 END) AS missing_next_step").
+		Group("stage").Scan(&rows).Error; err != nil {
+		return stats, err
+	}
 
-	// Open deals
-	h.db.Model(&models.Deal{}).Where("stage NOT IN ?", []string{
-		string(models.DealStageClosedWon),
-		string(models.DealStageClosedLost),
-	}).Count(&stats.OpenCount)
+	for _, stage := range models.DealStages() {
+		stats.ByStage[string(stage)] = 0
+	}
+	for _, row := range rows {
+		stats.Total += row.Count
+		stats.TotalValue += row.Value
+		switch models.DealStage(row.Stage) {
+		case models.DealStageClosedWon:
+			stats.WonCount = row.Count
+			stats.WonValue = row.Value
+		case models.DealStageClosedLost:
+			stats.LostCount = row.Count
+		default:
+			stats.OpenCount += row.Count
+			stats.MissingNextStep += row.MissingNextStep
+		}
+		if _, ok := stats.ByStage[row.Stage]; ok {
+			stats.ByStage[row.Stage] = row.Count
+		}
+	}
 
 	// Average deal size
 	if stats.Total > 0 {
 		stats.AverageDealSize = stats.TotalValue / float64(stats.Total)
 	}
 
-	// By stage
-	for _, stage := range models.ValidDealStages {
-		var count int64
-		h.db.Model(&models.Deal{}).Where("stage = ?", stage).Count(&count)
-		stats.ByStage[string(stage)] = count
-	}
-
-	return stats
+	return stats, nil
 }
 
 // getActivityStats returns activity statistics
-func (h *ReportHandler) getActivityStats() ActivityStats {
+func (h *ReportHandler) getActivityStats(ctx context.Context) (ActivityStats, error) {
 	stats := ActivityStats{
 		ByType: make(map[string]int64),
 	}
 
-	// Total activities
-	h.db.Model(&models.Activity{}).Count(&stats.Total)
-
-	// By status
-	h.db.Model(&models.Activity{}).Where("status = ?", models.ActivityStatusScheduled).Count(&stats.Scheduled)
-	h.db.Model(&models.Activity{}).Where("status = ?", models.ActivityStatusCompleted).Count(&stats.Completed)
-	h.db.Model(&models.Activity{}).Where("status = ?", models.ActivityStatusOverdue).Count(&stats.Overdue)
+	// By effective status, so past-due scheduled activities count as overdue
+	var statusRows []struct {
+		Status string
+		Count  int64
+	}
+	if err := h.db.WithContext(ctx).Model(&models.Activity{}).
+		Select(models.EffectiveStatusSQL + " AS status, COUNT(*) AS count").
+		Group(models.EffectiveStatusSQL).Scan(&statusRows).Error; err != nil {
+		return stats, err
+	}
+	for _, row := range statusRows {
+		stats.Total += row.Count
+		switch models.ActivityStatus(row.Status) {
+		case models.ActivityStatusScheduled:
+			stats.Scheduled = row.Count
+		case models.ActivityStatusCompleted:
+			stats.Completed = row.Count
+		case models.ActivityStatusOverdue:
+			stats.Overdue = row.Count
+		}
+	}
 
 	// By type
 	types := []models.ActivityType{
@@ -173,27 +320,1513 @@ func (h *ReportHandler) getActivityStats() ActivityStats {
 		models.ActivityTypeTask,
 		models.ActivityTypeNote,
 	}
-
 	for _, t := range types {
-		var count int64
-		h.db.Model(&models.Activity{}).Where("type = ?", t).Count(&count)
-		stats.ByType[string(t)] = count
+		stats.ByType[string(t)] = 0
 	}
 
-	return stats
+	var typeRows []struct {
+		Type  string
+		Count int64
+	}
+	if err := h.db.WithContext(ctx).Model(&models.Activity{}).
+		Select("type, COUNT(*) AS count").Group("type").Scan(&typeRows).Error; err != nil {
+		return stats, err
+	}
+	for _, row := range typeRows {
+		if _, ok := stats.ByType[row.Type]; ok {
+			stats.ByType[row.Type] = row.Count
+		}
+	}
+
+	return stats, nil
 }
 
 // getTopCustomers returns top customers by deal value
-func (h *ReportHandler) getTopCustomers(limit int) []CustomerSummary {
+func (h *ReportHandler) getTopCustomers(ctx context.Context, limit int) ([]CustomerSummary, error) {
 	var results []CustomerSummary
 
-	h.db.Model(&models.Customer{}).
+	err := h.db.WithContext(ctx).Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
 		Select("customers.id, customers.name, customers.email, customers.company, COUNT(deals.id) as deals_count, COALESCE(SUM(deals.amount), 0) as deals_value").
-		Joins("LEFT JOIN deals ON deals.customer_id = customers.id AND deals.deleted_at IS NULL").
+		Joins("LEFT JOIN deals ON deals.customer_id = customers.id AND deals.deleted_at IS NULL AND deals.archived_at IS NULL").
 		Group("customers.id, customers.name, customers.email, customers.company").
 		Order("deals_value DESC").
 		Limit(limit).
-		Scan(&results)
+		Scan(&results).Error
+
+	return results, err
+}
+
+// getRecentDeals returns the most recently created deals
+func (h *ReportHandler) getRecentDeals(ctx context.Context, limit int) ([]models.Deal, error) {
+	var deals []models.Deal
+	err := h.db.WithContext(ctx).Preload("Customer").Order("created_at DESC").Limit(limit).Find(&deals).Error
+	return deals, err
+}
+
+// StageSummary represents open pipeline totals for one deal stage
+type StageSummary struct {
+	Stage string  `json:"stage"`
+	Count int64   `json:"count"`
+	Value float64 `json:"value"`
+}
+
+// getPipelineByStage returns deal count and value per stage in pipeline
+// order, limited to one owner's deals when ownerID is set
+func (h *ReportHandler) getPipelineByStage(c *gin.Context, ownerID *uint) []StageSummary {
+	db := h.db.WithContext(c).Model(&models.Deal{}).Scopes(models.NotArchived("deals"))
+	if ownerID != nil {
+		db = db.Where("owner_id = ?", *ownerID)
+	}
+
+	var rows []StageSummary
+	db.Select("stage, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS value").Group("stage").Scan(&rows)
+
+	byStage := make(map[string]StageSummary, len(rows))
+	for _, row := range rows {
+		byStage[row.Stage] = row
+	}
+	stages := models.DealStages()
+	summaries := make([]StageSummary, 0, len(stages))
+	for _, stage := range stages {
+		summary := byStage[string(stage)]
+		summary.Stage = string(stage)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// getUserActivities returns a user's open activities, soonest due first
+func (h *ReportHandler) getUserActivities(c *gin.Context, userID uint, limit int) []models.Activity {
+	var activities []models.Activity
+	h.db.WithContext(c).Model(&models.Activity{}).Scopes(models.WithEffectiveStatus).
+		Where("assigned_to = ? AND status = ?", userID, models.ActivityStatusScheduled).
+		Preload("Customer").
+		Order("due_date ASC NULLS LAST").
+		Limit(limit).
+		Find(&activities)
+	return activities
+}
+
+// getStuckDeals returns open deals that have not been updated for the given
+// number of days, longest untouched first, optionally only those with an
+// unchecked required close checklist item
+func (h *ReportHandler) getStuckDeals(c *gin.Context, days, limit int, checklistBlocked bool) []models.Deal {
+	var deals []models.Deal
+	db := h.db.WithContext(c).Scopes(models.NotArchived("deals"))
+	if checklistBlocked {
+		db = db.Where(models.ChecklistBlockedSQL)
+	}
+	db.Where("stage NOT IN ? AND updated_at < ?", []string{
+		string(models.DealStageClosedWon),
+		string(models.DealStageClosedLost),
+	}, time.Now().AddDate(0, 0, -days)).
+		Preload("Customer").
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&deals)
+	return deals
+}
+
+// maxSegmentTags caps how many tags can be compared in one segments report
+const maxSegmentTags = 10
+
+// SegmentReport represents the tag segmentation report response
+type SegmentReport struct {
+	Segments []SegmentStats    `json:"segments"`
+	Meta     SegmentReportMeta `json:"meta"`
+}
+
+// SegmentStats represents pipeline and customer statistics for one tag
+type SegmentStats struct {
+	Tag               string           `json:"tag"`
+	TagID             *uint            `json:"tag_id,omitempty"`
+	Group             string           `json:"group,omitempty"`
+	GroupExclusive    bool             `json:"group_exclusive,omitempty"`
+	Customers         int64            `json:"customers"`
+	CustomersByStatus map[string]int64 `json:"customers_by_status"`
+	OpenPipelineValue float64          `json:"open_pipeline_value"`
+	WonValue          float64          `json:"won_value"`
+	WonCount          int64            `json:"won_count"`
+	AverageDealSize   float64          `json:"average_deal_size"` // Average won deal amount in the period
+}
+
+// SegmentReportMeta describes how the segments report was computed
+type SegmentReportMeta struct {
+	ReportPeriod
+	Tags         []string                `json:"tags"`
+	TagIDs       []uint                  `json:"tag_ids,omitempty"`
+	TagGroups    []string                `json:"tag_groups,omitempty"`
+	UnknownTags  []string                `json:"unknown_tags,omitempty"`
+	RenamedTags  map[string]string       `json:"renamed_tags,omitempty"` // Former names requested, with the tags' current names
+	NonExclusive bool                    `json:"non_exclusive"`
+	Note         string                  `json:"note"`
+	Snapshot     *reportrollup.Freshness `json:"snapshot"` // Nil when computed live
+}
+
+// segmentStatusRow is a scanned customer count per tag and status
+type segmentStatusRow struct {
+	TagID  uint
+	Status string
+	Count  int64
+}
+
+// segmentDealRow is a scanned deal aggregate per tag
+type segmentDealRow struct {
+	TagID     uint
+	OpenValue float64
+	WonValue  float64
+	WonCount  int64
+}
+
+// GetSegments returns customer and pipeline statistics segmented by tag.
+// Tags are selected by name, by ID, by tag group or any combination; a
+// renamed tag is still found by its former names (see UpdateTag). Segments are
+// exclusive only when all of them are tags of one exclusive group.
+// GET /admin/reports/segments?tags=vip,enterprise&tag_ids=3&tag_group=industry
+func (h *ReportHandler) GetSegments(c *gin.Context) {
+	var tagNames []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(c.Query("tags"), ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			tagNames = append(tagNames, name)
+		}
+	}
+	var requestedIDs []uint
+	seenIDs := make(map[uint]bool)
+	for _, value := range strings.Split(c.Query("tag_ids"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_ID",
+				"message": i18n.Message(c, "INVALID_ID", "Invalid tag ID: "+value),
+			})
+			return
+		}
+		if !seenIDs[uint(id)] {
+			seenIDs[uint(id)] = true
+			requestedIDs = append(requestedIDs, uint(id))
+		}
+	}
+	var groupNames []string
+	seenGroups := make(map[string]bool)
+	for _, name := range strings.Split(c.Query("tag_group"), ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seenGroups[name] {
+			seenGroups[name] = true
+			groupNames = append(groupNames, name)
+		}
+	}
+	if len(groupNames) > 0 {
+		var groupTagIDs []uint
+		if err := h.db.WithContext(c).Model(&models.Tag{}).
+			Joins("JOIN tag_groups ON tag_groups.id = tags.group_id AND tag_groups.deleted_at IS NULL").
+			Where("tag_groups.name IN ?", groupNames).
+			Order("tags.name").
+			Pluck("tags.id", &groupTagIDs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tags"),
+			})
+			return
+		}
+		for _, id := range groupTagIDs {
+			if !seenIDs[id] {
+				seenIDs[id] = true
+				requestedIDs = append(requestedIDs, id)
+			}
+		}
+	}
+	if len(tagNames)+len(requestedIDs)+len(groupNames) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "MISSING_TAGS",
+			"message": i18n.Message(c, "MISSING_TAGS", "At least one tag is required"),
+		})
+		return
+	}
+	if len(tagNames)+len(requestedIDs) > maxSegmentTags {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "TOO_MANY_TAGS",
+			"message": i18n.Message(c, "TOO_MANY_TAGS", "At most "+strconv.Itoa(maxSegmentTags)+" tags can be compared"),
+		})
+		return
+	}
+
+	period, ok := h.reportPeriod(c)
+	if !ok {
+		return
+	}
+	from, to := period.From, period.To
+
+	var tags []models.Tag
+	if err := h.db.WithContext(c).Preload("Group").Where("name IN ? OR id IN ?", tagNames, requestedIDs).Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tags"),
+		})
+		return
+	}
+
+	report := SegmentReport{
+		Meta: SegmentReportMeta{
+			ReportPeriod: period,
+			Tags:         tagNames,
+			TagIDs:       requestedIDs,
+			TagGroups:    groupNames,
+			NonExclusive: true,
+			Note:         "Segments are not exclusive: customers carrying several requested tags are counted in each of them",
+		},
+	}
+
+	tagsByName := make(map[string]models.Tag, len(tags))
+	tagsByID := make(map[uint]models.Tag, len(tags))
+	tagIDs := make([]uint, 0, len(tags))
+	for _, tag := range tags {
+		tagsByName[tag.Name] = tag
+		tagsByID[tag.ID] = tag
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	segments := make(map[uint]*SegmentStats, len(tags))
+	addSegment := func(tag models.Tag) {
+		if segments[tag.ID] != nil {
+			// Requested both by name and by ID
+			return
+		}
+		tagID := tag.ID
+		stats := newSegmentStats(tag.Name, &tagID)
+		if tag.Group != nil {
+			stats.Group = tag.Group.Name
+			stats.GroupExclusive = tag.Group.Exclusive
+		}
+		segments[tag.ID] = stats
+		report.Segments = append(report.Segments, *stats)
+	}
+	var formerNames []string
+	for _, name := range tagNames {
+		if _, ok := tagsByName[name]; !ok {
+			formerNames = append(formerNames, name)
+		}
+	}
+	if len(formerNames) > 0 {
+		var aliases []models.TagAlias
+		if err := h.db.WithContext(c).Where("name IN ?", formerNames).Find(&aliases).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tags"),
+			})
+			return
+		}
+		aliasIDs := make([]uint, 0, len(aliases))
+		for _, alias := range aliases {
+			aliasIDs = append(aliasIDs, alias.TagID)
+		}
+		var renamed []models.Tag
+		if err := h.db.WithContext(c).Preload("Group").Where("id IN ?", aliasIDs).Find(&renamed).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tags"),
+			})
+			return
+		}
+		for _, tag := range renamed {
+			if _, ok := tagsByID[tag.ID]; !ok {
+				tagsByID[tag.ID] = tag
+				tagIDs = append(tagIDs, tag.ID)
+			}
+		}
+		for _, alias := range aliases {
+			if tag, ok := tagsByID[alias.TagID]; ok {
+				tagsByName[alias.Name] = tag
+				if report.Meta.RenamedTags == nil {
+					report.Meta.RenamedTags = make(map[string]string)
+				}
+				report.Meta.RenamedTags[alias.Name] = tag.Name
+			}
+		}
+	}
+	for _, name := range tagNames {
+		tag, ok := tagsByName[name]
+		if !ok {
+			report.Meta.UnknownTags = append(report.Meta.UnknownTags, name)
+			continue
+		}
+		addSegment(tag)
+	}
+	for _, id := range requestedIDs {
+		tag, ok := tagsByID[id]
+		if !ok {
+			report.Meta.UnknownTags = append(report.Meta.UnknownTags, strconv.FormatUint(uint64(id), 10))
+			continue
+		}
+		addSegment(tag)
+	}
+	untagged := newSegmentStats("untagged", nil)
+
+	// Tags of one exclusive group never share a customer
+	if len(report.Segments) > 0 && report.Segments[0].GroupExclusive {
+		exclusive := true
+		for _, segment := range report.Segments {
+			exclusive = exclusive && segment.GroupExclusive && segment.Group == report.Segments[0].Group
+		}
+		if exclusive {
+			report.Meta.NonExclusive = false
+			report.Meta.Note = "Segments are exclusive: they are tags of the exclusive group " + report.Segments[0].Group
+		}
+	}
+
+	closedStages := []string{string(models.DealStageClosedWon), string(models.DealStageClosedLost)}
+	noTags := "NOT EXISTS (SELECT 1 FROM customer_tags WHERE customer_tags.customer_id = customers.id)"
+
+	var statusRows, untaggedStatusRows []segmentStatusRow
+	var dealRows, untaggedDealRows []segmentDealRow
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		coverage, err := reportrollup.Load(tx)
+		if err != nil {
+			return err
+		}
+		// Deals won on snapshot days are read from the snapshots; customers
+		// and open pipeline are current and always computed live
+		window := coverage.Window(&from, &to)
+		report.Meta.Snapshot = window.Freshness()
+
+		won := clause.Expr{
+			SQL:  "deals.stage = ? AND deals.actual_close_date BETWEEN ? AND ? AND ?",
+			Vars: []interface{}{models.DealStageClosedWon, from, to, window.Live("deals.actual_close_date")},
+		}
+		dealSelect := "COALESCE(SUM(CASE WHEN deals.stage NOT IN ? THEN deals.amount ELSE 0 END), 0) as open_value, " +
+			"COALESCE(SUM(CASE WHEN ? THEN deals.amount ELSE 0 END), 0) as won_value, " +
+			"COUNT(CASE WHEN ? THEN 1 END) as won_count"
+		dealArgs := []interface{}{closedStages, won, won}
+		snapshotSelect := "0 AS open_value, COALESCE(SUM(won_total), 0) AS won_value, COALESCE(SUM(won_count), 0)::bigint AS won_count"
+		sumSelect := "SUM(open_value) AS open_value, SUM(won_value) AS won_value, SUM(won_count)::bigint AS won_count"
+
+		queries := []*gorm.DB{
+			tx.Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
+				Select("customer_tags.tag_id, customers.status, COUNT(*) as count").
+				Joins("JOIN customer_tags ON customer_tags.customer_id = customers.id").
+				Where("customer_tags.tag_id IN ?", tagIDs).
+				Group("customer_tags.tag_id, customers.status").
+				Scan(&statusRows),
+			tx.Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
+				Select("customers.status, COUNT(*) as count").
+				Where(noTags).
+				Group("customers.status").
+				Scan(&untaggedStatusRows),
+			withSnapshot(tx,
+				tx.Model(&models.Deal{}).Scopes(models.NotArchived("deals")).
+					Select("customer_tags.tag_id, "+dealSelect, dealArgs...).
+					Joins("JOIN customers ON customers.id = deals.customer_id AND customers.deleted_at IS NULL").
+					Joins("JOIN customer_tags ON customer_tags.customer_id = customers.id").
+					Where("customer_tags.tag_id IN ?", tagIDs).
+					Group("customer_tags.tag_id"),
+				tx.Model(&models.ReportSegmentDay{}).
+					Select("tag_id, "+snapshotSelect).
+					Where(window.Snapshot()).
+					Where("tag_id IN ?", tagIDs).
+					Group("tag_id"),
+			).Select("tag_id, " + sumSelect).Group("tag_id").Scan(&dealRows),
+			withSnapshot(tx,
+				tx.Model(&models.Deal{}).Scopes(models.NotArchived("deals")).
+					Select(dealSelect, dealArgs...).
+					Joins("JOIN customers ON customers.id = deals.customer_id AND customers.deleted_at IS NULL").
+					Where(noTags),
+				tx.Model(&models.ReportSegmentDay{}).
+					Select(snapshotSelect).
+					Where(window.Snapshot()).
+					Where("tag_id IS NULL"),
+			).Select(sumSelect).Scan(&untaggedDealRows),
+		}
+		for _, q := range queries {
+			if q.Error != nil {
+				return q.Error
+			}
+		}
+		return nil
+	}, snapshotRead)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute segments"),
+		})
+		return
+	}
+
+	for _, row := range statusRows {
+		if stats, ok := segments[row.TagID]; ok {
+			stats.CustomersByStatus[row.Status] += row.Count
+			stats.Customers += row.Count
+		}
+	}
+	for _, row := range untaggedStatusRows {
+		untagged.CustomersByStatus[row.Status] += row.Count
+		untagged.Customers += row.Count
+	}
+	for _, row := range dealRows {
+		if stats, ok := segments[row.TagID]; ok {
+			stats.applyDeals(row)
+		}
+	}
+	for _, row := range untaggedDealRows {
+		untagged.applyDeals(row)
+	}
+
+	// Copy the aggregated values back in request order
+	for i := range report.Segments {
+		report.Segments[i] = *segments[*report.Segments[i].TagID]
+	}
+	report.Segments = append(report.Segments, *untagged)
+
+	if c.Query("format") == "csv" {
+		h.writeSegmentsCSV(c, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
 
-	return results
+// newSegmentStats creates an empty segment with every customer status present
+func newSegmentStats(name string, tagID *uint) *SegmentStats {
+	stats := &SegmentStats{
+		Tag:               name,
+		TagID:             tagID,
+		CustomersByStatus: make(map[string]int64),
+	}
+	for _, status := range customerStatuses {
+		stats.CustomersByStatus[string(status)] = 0
+	}
+	return stats
+}
+
+// applyDeals adds a deal aggregate row to the segment
+func (s *SegmentStats) applyDeals(row segmentDealRow) {
+	s.OpenPipelineValue += row.OpenValue
+	s.WonValue += row.WonValue
+	s.WonCount += row.WonCount
+	if s.WonCount > 0 {
+		s.AverageDealSize = s.WonValue / float64(s.WonCount)
+	}
+}
+
+// writeSegmentsCSV writes the segments report as a CSV attachment
+func (h *ReportHandler) writeSegmentsCSV(c *gin.Context, report SegmentReport) {
+	locale := i18n.LocaleFromContext(c)
+
+	keys := []string{"tag", "tag_group", "customers"}
+	for _, status := range customerStatuses {
+		keys = append(keys, string(status))
+	}
+	keys = append(keys, "open_pipeline_value", "won_value", "won_count", "average_deal_size")
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="segments.csv"`)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(i18n.CSVHeaders(locale, keys))
+	for _, segment := range report.Segments {
+		name := segment.Tag
+		if segment.TagID == nil {
+			name = i18n.ReportLabel(locale, "untagged")
+		}
+		record := []string{name, segment.Group, strconv.FormatInt(segment.Customers, 10)}
+		for _, status := range customerStatuses {
+			record = append(record, strconv.FormatInt(segment.CustomersByStatus[string(status)], 10))
+		}
+		record = append(record,
+			i18n.FormatNumber(locale, segment.OpenPipelineValue),
+			i18n.FormatNumber(locale, segment.WonValue),
+			strconv.FormatInt(segment.WonCount, 10),
+			i18n.FormatNumber(locale, segment.AverageDealSize),
+		)
+		writer.Write(record)
+	}
+	writer.Flush()
+}
+
+// snapshotRead reads a report's snapshot days and live records from one
+// snapshot, so a rollup or a change is seen by both parts or by neither
+var snapshotRead = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+// withSnapshot returns a query over the rows of a live query and a snapshot
+// query selecting the same columns, for summing them per group
+func withSnapshot(tx, live, snapshot *gorm.DB) *gorm.DB {
+	return tx.Table("((?) UNION ALL (?)) AS report_rows", live, snapshot)
+}
+
+// ReportPeriod is the period a report covers. Range echoes the relative
+// token the period was resolved from, with the time zone it was resolved in.
+type ReportPeriod struct {
+	Range    string    `json:"range,omitempty"`
+	Timezone string    `json:"timezone,omitempty"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+}
+
+// reportPeriod reads the period of a report: a relative ?range= token such
+// as this_month, resolved now in the X-Timezone time zone or the calendar's,
+// or the from/to RFC 3339 query parameters, defaulting to the last 30 days.
+// It writes the error response for an unknown token or time zone, a
+// malformed from or to, or a from after to.
+func (h *ReportHandler) reportPeriod(c *gin.Context) (ReportPeriod, bool) {
+	if token := c.Query("range"); token != "" {
+		location := h.calendar.Calendar("").Location()
+		if name := c.GetHeader("X-Timezone"); name != "" {
+			var err error
+			if location, err = time.LoadLocation(name); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "validation_error",
+					"code":    "INVALID_TIMEZONE",
+					"message": i18n.Message(c, "INVALID_TIMEZONE", "X-Timezone must be an IANA time zone such as Asia/Riyadh"),
+				})
+				return ReportPeriod{}, false
+			}
+		}
+		resolved, err := businesstime.ResolveRange(token, time.Now(), location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "UNKNOWN_DATE_RANGE",
+				"message": i18n.Message(c, "UNKNOWN_DATE_RANGE", "range must be one of: "+strings.Join(businesstime.RangeTokens(), ", ")),
+			})
+			return ReportPeriod{}, false
+		}
+		return ReportPeriod{Range: token, Timezone: location.String(), From: resolved.From, To: resolved.To}, true
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	for name, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_DATE",
+				"message": i18n.Message(c, "INVALID_DATE", name+" must be an RFC 3339 timestamp"),
+			})
+			return ReportPeriod{}, false
+		}
+		*target = t
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_DATE_RANGE",
+			"message": i18n.Message(c, "INVALID_DATE_RANGE", "from must not be after to"),
+		})
+		return ReportPeriod{}, false
+	}
+	return ReportPeriod{From: from, To: to}, true
+}
+
+// EmailEngagementReport represents email engagement per template
+type EmailEngagementReport struct {
+	ReportPeriod
+	Templates []TemplateEngagementStats `json:"templates"`
+}
+
+// TemplateEngagementStats represents engagement for emails sent from one
+// template. Emails without a template are grouped under an empty name.
+type TemplateEngagementStats struct {
+	Template     string  `json:"template"`
+	Sent         int64   `json:"sent"`
+	Opened       int64   `json:"opened"` // Emails opened at least once
+	Opens        int64   `json:"opens"`
+	Clicks       int64   `json:"clicks"`
+	Unsubscribes int64   `json:"unsubscribes"`
+	OpenRate     float64 `json:"open_rate"`
+}
+
+// GetEmailEngagement returns open, click and unsubscribe counts per email
+// template for emails sent in the period
+// GET /admin/reports/email-engagement?from=&to=
+func (h *ReportHandler) GetEmailEngagement(c *gin.Context) {
+	period, ok := h.reportPeriod(c)
+	if !ok {
+		return
+	}
+	from, to := period.From, period.To
+
+	var sentRows []struct {
+		Template string
+		Count    int64
+	}
+	var eventRows []struct {
+		Template   string
+		Type       models.EmailEventType
+		Count      int64
+		Activities int64
+	}
+	queries := []*gorm.DB{
+		h.db.WithContext(c).Model(&models.Activity{}).
+			Select("template, COUNT(*) as count").
+			Where("type = ? AND created_at BETWEEN ? AND ?", models.ActivityTypeEmail, from, to).
+			Group("template").
+			Scan(&sentRows),
+		h.db.WithContext(c).Model(&models.EmailEvent{}).
+			Select("activities.template, email_events.type, COUNT(*) as count, COUNT(DISTINCT email_events.activity_id) as activities").
+			Joins("JOIN activities ON activities.id = email_events.activity_id AND activities.deleted_at IS NULL").
+			Where("activities.type = ? AND activities.created_at BETWEEN ? AND ?", models.ActivityTypeEmail, from, to).
+			Group("activities.template, email_events.type").
+			Scan(&eventRows),
+	}
+	for _, q := range queries {
+		if q.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute email engagement"),
+			})
+			return
+		}
+	}
+
+	report := EmailEngagementReport{ReportPeriod: period, Templates: []TemplateEngagementStats{}}
+	index := make(map[string]int, len(sentRows))
+	for _, row := range sentRows {
+		index[row.Template] = len(report.Templates)
+		report.Templates = append(report.Templates, TemplateEngagementStats{Template: row.Template, Sent: row.Count})
+	}
+	for _, row := range eventRows {
+		i, ok := index[row.Template]
+		if !ok {
+			continue
+		}
+		stats := &report.Templates[i]
+		switch row.Type {
+		case models.EmailEventOpen:
+			stats.Opens = row.Count
+			stats.Opened = row.Activities
+		case models.EmailEventClick:
+			stats.Clicks = row.Count
+		case models.EmailEventUnsubscribe:
+			stats.Unsubscribes = row.Count
+		}
+	}
+	for i := range report.Templates {
+		if report.Templates[i].Sent > 0 {
+			report.Templates[i].OpenRate = float64(report.Templates[i].Opened) / float64(report.Templates[i].Sent)
+		}
+	}
+	sort.Slice(report.Templates, func(i, j int) bool {
+		return report.Templates[i].Template < report.Templates[j].Template
+	})
+
+	c.JSON(http.StatusOK, report)
+}
+
+// EmailDeliverabilityReport represents email delivery outcomes per template
+// and per recipient domain
+type EmailDeliverabilityReport struct {
+	ReportPeriod
+	Templates []TemplateDeliverabilityStats `json:"templates"`
+	Domains   []DomainDeliverabilityStats   `json:"domains"`
+}
+
+// DeliverabilityStats counts sent emails by their latest delivery status.
+// Emails count as sent once a message ID was recorded for them or the
+// provider reported on them.
+type DeliverabilityStats struct {
+	Sent        int64   `json:"sent"`
+	Delivered   int64   `json:"delivered"`
+	Deferred    int64   `json:"deferred"`
+	Bounced     int64   `json:"bounced"` // Hard bounces
+	SoftBounced int64   `json:"soft_bounced"`
+	Dropped     int64   `json:"dropped"`
+	Complaints  int64   `json:"complaints"`
+	BounceRate  float64 `json:"bounce_rate"` // Hard bounces over sent
+}
+
+// add counts count emails whose latest delivery status is status
+func (s *DeliverabilityStats) add(status models.EmailEventType, count int64) {
+	s.Sent += count
+	switch status {
+	case models.EmailEventDelivered:
+		s.Delivered += count
+	case models.EmailEventDeferred:
+		s.Deferred += count
+	case models.EmailEventBounce:
+		s.Bounced += count
+	case models.EmailEventSoftBounce:
+		s.SoftBounced += count
+	case models.EmailEventDropped:
+		s.Dropped += count
+	case models.EmailEventComplaint:
+		s.Complaints += count
+	}
+	if s.Sent > 0 {
+		s.BounceRate = float64(s.Bounced) / float64(s.Sent)
+	}
+}
+
+// TemplateDeliverabilityStats represents delivery outcomes for emails sent
+// from one template. Emails without a template are grouped under an empty
+// name.
+type TemplateDeliverabilityStats struct {
+	Template string `json:"template"`
+	DeliverabilityStats
+}
+
+// DomainDeliverabilityStats represents delivery outcomes for emails sent to
+// one recipient domain
+type DomainDeliverabilityStats struct {
+	Domain string `json:"domain"`
+	DeliverabilityStats
+}
+
+// GetEmailDeliverability returns delivery and bounce counts per email
+// template and per recipient domain for emails sent in the period
+// GET /admin/reports/email-deliverability?from=&to=
+func (h *ReportHandler) GetEmailDeliverability(c *gin.Context) {
+	period, ok := h.reportPeriod(c)
+	if !ok {
+		return
+	}
+	from, to := period.From, period.To
+
+	sent := func() *gorm.DB {
+		return h.db.WithContext(c).Model(&models.Activity{}).
+			Where("activities.type = ? AND activities.created_at BETWEEN ? AND ?", models.ActivityTypeEmail, from, to).
+			Where("activities.message_id <> '' OR activities.delivery_status <> ''")
+	}
+
+	var templateRows []struct {
+		Template       string
+		DeliveryStatus models.EmailEventType
+		Count          int64
+	}
+	var domainRows []struct {
+		Domain         string
+		DeliveryStatus models.EmailEventType
+		Count          int64
+	}
+	queries := []*gorm.DB{
+		sent().
+			Select("activities.template, COALESCE(activities.delivery_status, '') as delivery_status, COUNT(*) as count").
+			Group("activities.template, activities.delivery_status").
+			Scan(&templateRows),
+		sent().
+			Select("LOWER(SPLIT_PART(COALESCE(NULLIF(contacts.email, ''), customers.email, ''), '@', 2)) as domain, COALESCE(activities.delivery_status, '') as delivery_status, COUNT(*) as count").
+			Joins("LEFT JOIN contacts ON contacts.id = activities.contact_id").
+			Joins("LEFT JOIN customers ON customers.id = activities.customer_id").
+			Group("1, activities.delivery_status").
+			Scan(&domainRows),
+	}
+	for _, q := range queries {
+		if q.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute email deliverability"),
+			})
+			return
+		}
+	}
+
+	report := EmailDeliverabilityReport{
+		ReportPeriod: period,
+		Templates:    []TemplateDeliverabilityStats{},
+		Domains:      []DomainDeliverabilityStats{},
+	}
+	templates := make(map[string]int)
+	for _, row := range templateRows {
+		i, ok := templates[row.Template]
+		if !ok {
+			i = len(report.Templates)
+			templates[row.Template] = i
+			report.Templates = append(report.Templates, TemplateDeliverabilityStats{Template: row.Template})
+		}
+		report.Templates[i].add(row.DeliveryStatus, row.Count)
+	}
+	domains := make(map[string]int)
+	for _, row := range domainRows {
+		i, ok := domains[row.Domain]
+		if !ok {
+			i = len(report.Domains)
+			domains[row.Domain] = i
+			report.Domains = append(report.Domains, DomainDeliverabilityStats{Domain: row.Domain})
+		}
+		report.Domains[i].add(row.DeliveryStatus, row.Count)
+	}
+	sort.Slice(report.Templates, func(i, j int) bool {
+		return report.Templates[i].Template < report.Templates[j].Template
+	})
+	sort.Slice(report.Domains, func(i, j int) bool {
+		if report.Domains[i].Sent != report.Domains[j].Sent {
+			return report.Domains[i].Sent > report.Domains[j].Sent
+		}
+		return report.Domains[i].Domain < report.Domains[j].Domain
+	})
+
+	c.JSON(http.StatusOK, report)
+}
+
+// FunnelReport represents the sales funnel report response
+type FunnelReport struct {
+	CreatedFrom    *time.Time              `json:"created_from,omitempty"`
+	CreatedTo      *time.Time              `json:"created_to,omitempty"`
+	Customers      Funnel                  `json:"customers"`
+	Deals          Funnel                  `json:"deals"`
+	WinRate        WinRateStats            `json:"win_rate"`
+	WinRateByOwner []OwnerWinRate          `json:"win_rate_by_owner"`
+	Interval       string                  `json:"interval,omitempty"`
+	*BucketPage                            // With an interval
+	Buckets        []FunnelBucket          `json:"buckets,omitempty"` // The requested page of them
+	Snapshot       *reportrollup.Freshness `json:"snapshot"`          // Nil when computed live
+}
+
+// FunnelBucket represents the funnels of the records created in one month
+// or week
+type FunnelBucket struct {
+	Start     time.Time `json:"start"` // Midnight starting the month, or the week on Monday
+	Customers Funnel    `json:"customers"`
+	Deals     Funnel    `json:"deals"`
+}
+
+// Funnel represents record counts per status or stage, and the conversion
+// between each pair of adjacent funnel steps
+type Funnel struct {
+	Counts      []FunnelCount      `json:"counts"`
+	Conversions []FunnelConversion `json:"conversions"`
+}
+
+// FunnelCount represents the records currently in one status or stage
+type FunnelCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// FunnelConversion represents how many records that reached one step went
+// on to reach the next. A record reached a step when it is at that step or
+// a later one.
+type FunnelConversion struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Reached   int64   `json:"reached"`   // Records that reached From
+	Converted int64   `json:"converted"` // Records that reached To
+	Rate      float64 `json:"rate"`      // Converted over reached, 0 to 1
+}
+
+// WinRateStats represents closed deal outcomes
+type WinRateStats struct {
+	Won  int64   `json:"won"`
+	Lost int64   `json:"lost"`
+	Rate float64 `json:"rate"` // Won over won and lost, 0 to 1
+}
+
+// OwnerWinRate represents closed deal outcomes for one owner. Deals without
+// an owner are grouped under a null owner_id.
+type OwnerWinRate struct {
+	OwnerID *uint `json:"owner_id"`
+	WinRateStats
+}
+
+// newWinRate computes the win rate of won and lost deal counts
+func newWinRate(won, lost int64) WinRateStats {
+	stats := WinRateStats{Won: won, Lost: lost}
+	if won+lost > 0 {
+		stats.Rate = float64(won) / float64(won+lost)
+	}
+	return stats
+}
+
+// newFunnel builds a funnel from counts per status or stage. steps lists the
+// funnel steps in order; reachedStep maps every counted status or stage to
+// the last step records in it reached, or -1 for none.
+func newFunnel(names []string, counts map[string]int64, steps []string, reachedStep func(name string) int) Funnel {
+	funnel := Funnel{
+		Counts:      make([]FunnelCount, 0, len(names)),
+		Conversions: make([]FunnelConversion, 0, len(steps)),
+	}
+	reached := make([]int64, len(steps))
+	for _, name := range names {
+		funnel.Counts = append(funnel.Counts, FunnelCount{Name: name, Count: counts[name]})
+		for step := reachedStep(name); step >= 0; step-- {
+			reached[step] += counts[name]
+		}
+	}
+	for i := 1; i < len(steps); i++ {
+		conversion := FunnelConversion{From: steps[i-1], To: steps[i], Reached: reached[i-1], Converted: reached[i]}
+		if conversion.Reached > 0 {
+			conversion.Rate = float64(conversion.Converted) / float64(conversion.Reached)
+		}
+		funnel.Conversions = append(funnel.Conversions, conversion)
+	}
+	return funnel
+}
+
+// GetFunnel returns customers per status and deals per stage created in the
+// window, with conversions between adjacent steps and win rates overall and
+// per owner. Stage changes are not recorded, so a deal reached every stage
+// up to its current one; won deals reached every stage and lost deals only
+// the first. Inactive and churned customers were active before. Records
+// created on days before the last rollup are counted from the snapshots.
+// With an interval, which needs both ends of the window, the funnels are
+// also returned per month or week of creation, paged like the revenue
+// report's buckets.
+// GET /admin/reports/funnel?created_from=&created_to=&interval=&page=&page_size=
+func (h *ReportHandler) GetFunnel(c *gin.Context) {
+	var report FunnelReport
+	for name, target := range map[string]**time.Time{"created_from": &report.CreatedFrom, "created_to": &report.CreatedTo} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_DATE",
+				"message": i18n.Message(c, "INVALID_DATE", name+" must be an RFC 3339 timestamp"),
+			})
+			return
+		}
+		*target = &t
+	}
+	if report.CreatedFrom != nil && report.CreatedTo != nil && report.CreatedFrom.After(*report.CreatedTo) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_DATE",
+			"message": i18n.Message(c, "INVALID_DATE", "created_from must not be after created_to"),
+		})
+		return
+	}
+
+	// Buckets of creation, for the funnels over time
+	var buckets reportBuckets
+	location := h.calendar.Calendar("").Location()
+	if report.Interval = c.Query("interval"); report.Interval != "" {
+		if !isValidReportInterval(report.Interval) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_INTERVAL",
+				"message": i18n.Message(c, "INVALID_INTERVAL", "interval must be month or week"),
+			})
+			return
+		}
+		if report.CreatedFrom == nil || report.CreatedTo == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_DATE",
+				"message": i18n.Message(c, "INVALID_DATE", "interval needs both created_from and created_to"),
+			})
+			return
+		}
+		var ok bool
+		if buckets, ok = bucketPage(c, *report.CreatedFrom, *report.CreatedTo, report.Interval, location); !ok {
+			return
+		}
+		report.BucketPage = &buckets.BucketPage
+	}
+
+	created := func(table string) func(*gorm.DB) *gorm.DB {
+		return func(db *gorm.DB) *gorm.DB {
+			if report.CreatedFrom != nil {
+				db = db.Where(table+".created_at >= ?", *report.CreatedFrom)
+			}
+			if report.CreatedTo != nil {
+				db = db.Where(table+".created_at <= ?", *report.CreatedTo)
+			}
+			return db
+		}
+	}
+
+	var statusRows, stageRows []struct {
+		Name  string
+		Count int64
+	}
+	var statusBucketRows, stageBucketRows []struct {
+		Bucket time.Time
+		Name   string
+		Count  int64
+	}
+	var ownerRows []struct {
+		OwnerID *uint
+		Won     int64
+		Lost    int64
+	}
+	closed := []models.DealStage{models.DealStageClosedWon, models.DealStageClosedLost}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		coverage, err := reportrollup.Load(tx)
+		if err != nil {
+			return err
+		}
+		window := coverage.Window(report.CreatedFrom, report.CreatedTo)
+		report.Snapshot = window.Freshness()
+
+		// Records created on snapshot days are read from the snapshots
+		liveCustomers := tx.Model(&models.Customer{}).Scopes(models.NotArchived("customers"), created("customers")).
+			Where(window.Live("customers.created_at"))
+		liveDeals := tx.Model(&models.Deal{}).Scopes(models.NotArchived("deals"), created("deals")).
+			Where(window.Live("deals.created_at")).Session(&gorm.Session{})
+		customerDays := tx.Model(&models.ReportCustomerDay{}).Where(window.Snapshot())
+		dealDays := tx.Model(&models.ReportDealDay{}).Where(window.Snapshot()).Session(&gorm.Session{})
+		queries := []*gorm.DB{
+			withSnapshot(tx,
+				liveCustomers.Select("status AS name, COUNT(*) AS count").Group("status"),
+				customerDays.Select("status AS name, SUM(customers)::bigint AS count").Group("status"),
+			).Select("name, SUM(count)::bigint AS count").Group("name").Scan(&statusRows),
+			withSnapshot(tx,
+				liveDeals.Select("stage AS name, COUNT(*) AS count").Group("stage"),
+				dealDays.Select("stage AS name, SUM(deals)::bigint AS count").Group("stage"),
+			).Select("name, SUM(count)::bigint AS count").Group("name").Scan(&stageRows),
+			withSnapshot(tx,
+				liveDeals.Select("owner_id, COUNT(*) FILTER (WHERE stage = ?) AS won, COUNT(*) FILTER (WHERE stage = ?) AS lost", closed[0], closed[1]).
+					Where("stage IN ?", closed).Group("owner_id"),
+				dealDays.Select("owner_id, COALESCE(SUM(deals) FILTER (WHERE stage = ?), 0)::bigint AS won, COALESCE(SUM(deals) FILTER (WHERE stage = ?), 0)::bigint AS lost", closed[0], closed[1]).
+					Where("stage IN ?", closed).Group("owner_id"),
+			).Select("owner_id, SUM(won)::bigint AS won, SUM(lost)::bigint AS lost").Group("owner_id").Order("owner_id NULLS LAST").Scan(&ownerRows),
+		}
+
+		// Only the page's buckets are counted. Snapshot days only fall into
+		// buckets in their own time zone.
+		if len(buckets.starts) > 0 {
+			var bucketWindow reportrollup.Window
+			if coverage.Timezone() == location.String() {
+				bucketWindow = coverage.Window(&buckets.from, &buckets.to)
+			}
+			live := "DATE_TRUNC(?, %s.created_at AT TIME ZONE ?) AS bucket, %s AS name, COUNT(*) AS count"
+			snapshot := "DATE_TRUNC(?, day::timestamp) AS bucket, %s AS name, SUM(%s)::bigint AS count"
+			queries = append(queries,
+				withSnapshot(tx,
+					tx.Model(&models.Customer{}).Scopes(models.NotArchived("customers")).
+						Select(fmt.Sprintf(live, "customers", "status"), report.Interval, location.String()).
+						Where("customers.created_at BETWEEN ? AND ?", buckets.from, buckets.to).
+						Where(bucketWindow.Live("customers.created_at")).Group("bucket, status"),
+					tx.Model(&models.ReportCustomerDay{}).Select(fmt.Sprintf(snapshot, "status", "customers"), report.Interval).
+						Where(bucketWindow.Snapshot()).Group("bucket, status"),
+				).Select("bucket, name, SUM(count)::bigint AS count").Group("bucket, name").Scan(&statusBucketRows),
+				withSnapshot(tx,
+					tx.Model(&models.Deal{}).Scopes(models.NotArchived("deals")).
+						Select(fmt.Sprintf(live, "deals", "stage"), report.Interval, location.String()).
+						Where("deals.created_at BETWEEN ? AND ?", buckets.from, buckets.to).
+						Where(bucketWindow.Live("deals.created_at")).Group("bucket, stage"),
+					tx.Model(&models.ReportDealDay{}).Select(fmt.Sprintf(snapshot, "stage", "deals"), report.Interval).
+						Where(bucketWindow.Snapshot()).Group("bucket, stage"),
+				).Select("bucket, name, SUM(count)::bigint AS count").Group("bucket, name").Scan(&stageBucketRows),
+			)
+		}
+		for _, q := range queries {
+			if q.Error != nil {
+				return q.Error
+			}
+		}
+		return nil
+	}, snapshotRead)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute funnel report"),
+		})
+		return
+	}
+
+	statusCounts := make(map[string]int64, len(statusRows))
+	for _, row := range statusRows {
+		statusCounts[row.Name] = row.Count
+	}
+	stageCounts := make(map[string]int64, len(stageRows))
+	for _, row := range stageRows {
+		stageCounts[row.Name] = row.Count
+	}
+	report.Customers = customerFunnel(statusCounts)
+	report.Deals = dealFunnel(stageCounts)
+
+	report.WinRate = newWinRate(stageCounts[string(models.DealStageClosedWon)], stageCounts[string(models.DealStageClosedLost)])
+	report.WinRateByOwner = make([]OwnerWinRate, 0, len(ownerRows))
+	for _, row := range ownerRows {
+		report.WinRateByOwner = append(report.WinRateByOwner, OwnerWinRate{OwnerID: row.OwnerID, WinRateStats: newWinRate(row.Won, row.Lost)})
+	}
+
+	if report.Interval != "" {
+		bucketStatuses := make(map[string]map[string]int64, len(buckets.starts))
+		bucketStages := make(map[string]map[string]int64, len(buckets.starts))
+		for _, start := range buckets.starts {
+			bucketStatuses[start.Format("2006-01-02")] = map[string]int64{}
+			bucketStages[start.Format("2006-01-02")] = map[string]int64{}
+		}
+		for _, row := range statusBucketRows {
+			if counts, ok := bucketStatuses[row.Bucket.Format("2006-01-02")]; ok {
+				counts[row.Name] = row.Count
+			}
+		}
+		for _, row := range stageBucketRows {
+			if counts, ok := bucketStages[row.Bucket.Format("2006-01-02")]; ok {
+				counts[row.Name] = row.Count
+			}
+		}
+		report.Buckets = make([]FunnelBucket, 0, len(buckets.starts))
+		for _, start := range buckets.starts {
+			report.Buckets = append(report.Buckets, FunnelBucket{
+				Start:     start,
+				Customers: customerFunnel(bucketStatuses[start.Format("2006-01-02")]),
+				Deals:     dealFunnel(bucketStages[start.Format("2006-01-02")]),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// customerFunnel builds the customer funnel, lead, prospect, active, from
+// counts per status
+func customerFunnel(counts map[string]int64) Funnel {
+	statuses := make([]string, len(customerStatuses))
+	for i, status := range customerStatuses {
+		statuses[i] = string(status)
+	}
+	steps := []string{string(models.CustomerStatusLead), string(models.CustomerStatusProspect), string(models.CustomerStatusActive)}
+	return newFunnel(statuses, counts, steps, func(name string) int {
+		switch models.CustomerStatus(name) {
+		case models.CustomerStatusInactive, models.CustomerStatusChurned:
+			return len(steps) - 1
+		}
+		return slices.Index(steps, name)
+	})
+}
+
+// dealFunnel builds the deal funnel, the open stages in pipeline order then
+// closed_won, from counts per stage
+func dealFunnel(counts map[string]int64) Funnel {
+	var stages, steps []string
+	for _, stage := range models.DealStages() {
+		stages = append(stages, string(stage))
+		if stage != models.DealStageClosedLost {
+			steps = append(steps, string(stage))
+		}
+	}
+	return newFunnel(stages, counts, steps, func(name string) int {
+		if models.DealStage(name) == models.DealStageClosedLost {
+			return 0
+		}
+		return slices.Index(steps, name)
+	})
+}
+
+// RevenueReport represents won revenue over time
+type RevenueReport struct {
+	ReportPeriod
+	Interval string `json:"interval"`
+	BucketPage
+	OwnerID  *uint                   `json:"owner_id,omitempty"`
+	Currency string                  `json:"currency,omitempty"`
+	Buckets  []RevenueBucket         `json:"buckets"`  // The requested page of them
+	Snapshot *reportrollup.Freshness `json:"snapshot"` // Nil when computed live
+}
+
+// RevenueBucket represents the deals won in one month or week
+type RevenueBucket struct {
+	Start           time.Time `json:"start"` // Midnight starting the month, or the week on Monday
+	Count           int64     `json:"count"`
+	TotalAmount     float64   `json:"total_amount"`
+	AverageDealSize float64   `json:"average_deal_size"`
+}
+
+// GetRevenue returns the count, total amount and average size of deals won
+// per month or week of the period, by actual close date. Every bucket of
+// the period is returned, empty ones with zeros, unless page_size pages
+// them; the first and last count only deals closed within the period.
+// Buckets start at midnight in the period's time zone, or the calendar's.
+// Archived deals are included, since won deals are archived once they age.
+// Without a currency amounts are summed as stored, as in the overview
+// report. Days before the last rollup are read from the snapshots when the
+// period's time zone is theirs.
+// GET /admin/reports/revenue?interval=month&from=&to=&owner_id=&currency=&page=&page_size=
+func (h *ReportHandler) GetRevenue(c *gin.Context) {
+	period, ok := h.reportPeriod(c)
+	if !ok {
+		return
+	}
+
+	report := RevenueReport{
+		ReportPeriod: period,
+		Interval:     c.DefaultQuery("interval", reportIntervalMonth),
+		Currency:     strings.ToUpper(c.Query("currency")),
+		Buckets:      []RevenueBucket{},
+	}
+	if !isValidReportInterval(report.Interval) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_INTERVAL",
+			"message": i18n.Message(c, "INVALID_INTERVAL", "interval must be month or week"),
+		})
+		return
+	}
+	if value := c.Query("owner_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_ID",
+				"message": i18n.Message(c, "INVALID_ID", "owner_id must be a user ID"),
+			})
+			return
+		}
+		ownerID := uint(id)
+		report.OwnerID = &ownerID
+	}
+
+	location := h.calendar.Calendar("").Location()
+	if report.Timezone != "" {
+		location, _ = time.LoadLocation(report.Timezone)
+	}
+	report.Timezone = location.String()
+
+	// Every bucket of the page, so charts do not skip empty ones
+	buckets, ok := bucketPage(c, report.From, report.To, report.Interval, location)
+	if !ok {
+		return
+	}
+	report.BucketPage = buckets.BucketPage
+	index := make(map[string]int, len(buckets.starts))
+	for _, start := range buckets.starts {
+		index[start.Format("2006-01-02")] = len(report.Buckets)
+		report.Buckets = append(report.Buckets, RevenueBucket{Start: start})
+	}
+	if len(buckets.starts) == 0 {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var rows []struct {
+		Bucket time.Time
+		Count  int64
+		Total  float64
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		coverage, err := reportrollup.Load(tx)
+		if err != nil {
+			return err
+		}
+		// Snapshot days only fall into buckets in their own time zone
+		var window reportrollup.Window
+		if coverage.Timezone() == report.Timezone {
+			window = coverage.Window(&buckets.from, &buckets.to)
+		}
+		report.Snapshot = window.Freshness()
+
+		live := tx.Model(&models.Deal{}).
+			Select("DATE_TRUNC(?, actual_close_date AT TIME ZONE ?) AS bucket, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total", report.Interval, report.Timezone).
+			Where("stage = ? AND actual_close_date BETWEEN ? AND ?", models.DealStageClosedWon, buckets.from, buckets.to).
+			Where(window.Live("actual_close_date"))
+		snapshot := tx.Model(&models.ReportRevenueDay{}).
+			Select("DATE_TRUNC(?, day::timestamp) AS bucket, SUM(won_count)::bigint AS count, SUM(won_total) AS total", report.Interval).
+			Where(window.Snapshot())
+		if report.OwnerID != nil {
+			live = live.Where("owner_id = ?", *report.OwnerID)
+			snapshot = snapshot.Where("owner_id = ?", *report.OwnerID)
+		}
+		if report.Currency != "" {
+			live = live.Where("currency = ?", report.Currency)
+			snapshot = snapshot.Where("currency = ?", report.Currency)
+		}
+		return withSnapshot(tx, live.Group("bucket"), snapshot.Group("bucket")).
+			Select("bucket, SUM(count)::bigint AS count, SUM(total) AS total").Group("bucket").Scan(&rows).Error
+	}, snapshotRead)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute revenue report"),
+		})
+		return
+	}
+
+	for _, row := range rows {
+		i, ok := index[row.Bucket.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		bucket := &report.Buckets[i]
+		bucket.Count = row.Count
+		bucket.TotalAmount = row.Total
+		if row.Count > 0 {
+			bucket.AverageDealSize = row.Total / float64(row.Count)
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// OutcomeReport represents activity completions per type and outcome code
+type OutcomeReport struct {
+	ReportPeriod
+	Types []OutcomeTypeStats `json:"types"`
+}
+
+// OutcomeTypeStats represents the completions of one activity type, with
+// every outcome of its taxonomy. Activities completed without a code are
+// counted under an empty code.
+type OutcomeTypeStats struct {
+	Type      models.ActivityType `json:"type"`
+	Completed int64               `json:"completed"`
+	Outcomes  []OutcomeCount      `json:"outcomes"`
+	Users     []UserOutcomeStats  `json:"users"`
+}
+
+// OutcomeCount represents the completions with one outcome code
+type OutcomeCount struct {
+	Code  string  `json:"code"`
+	Label string  `json:"label,omitempty"` // Empty for codes no longer in the taxonomy
+	Count int64   `json:"count"`
+	Rate  float64 `json:"rate"` // Share of the completions, 0 to 1
+}
+
+// UserOutcomeStats represents the completions of one activity type by one
+// assignee, with the outcomes they recorded. Unassigned activities are
+// grouped under a null user_id.
+type UserOutcomeStats struct {
+	UserID    *uint          `json:"user_id"`
+	Completed int64          `json:"completed"`
+	Outcomes  []OutcomeCount `json:"outcomes"`
+}
+
+// newOutcomeCounts builds the outcome counts of completions by code in
+// taxonomy order, then other codes alphabetically and no code last. codes
+// lists the codes reported even without completions.
+func newOutcomeCounts(taxonomy models.OutcomeTaxonomy, activityType models.ActivityType, counts map[string]int64, codes []string) []OutcomeCount {
+	var total int64
+	for code, count := range counts {
+		total += count
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+
+	order := taxonomy.Codes(activityType)
+	slices.SortStableFunc(codes, func(a, b string) int {
+		i, j := slices.Index(order, a), slices.Index(order, b)
+		switch {
+		case (a == "") != (b == ""):
+			if a == "" {
+				return 1
+			}
+			return -1
+		case i >= 0 && j >= 0:
+			return i - j
+		case i >= 0 || j >= 0:
+			return j - i
+		}
+		return strings.Compare(a, b)
+	})
+
+	outcomes := make([]OutcomeCount, len(codes))
+	for k, code := range codes {
+		outcomes[k] = OutcomeCount{Code: code, Label: taxonomy.Label(activityType, code), Count: counts[code]}
+		if total > 0 {
+			outcomes[k].Rate = float64(counts[code]) / float64(total)
+		}
+	}
+	return outcomes
+}
+
+// GetOutcomes returns the activities completed in the period per type and
+// outcome code, with a breakdown per assignee
+// GET /admin/reports/outcomes?from=&to=&type=
+func (h *ReportHandler) GetOutcomes(c *gin.Context) {
+	period, ok := h.reportPeriod(c)
+	if !ok {
+		return
+	}
+	activityType := models.ActivityType(c.Query("type"))
+	if activityType != "" && !models.IsValidActivityType(activityType) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ACTIVITY_TYPE",
+			"message": i18n.Message(c, "INVALID_ACTIVITY_TYPE", "Invalid activity type"),
+		})
+		return
+	}
+
+	taxonomy, err := loadOutcomeTaxonomy(h.db.WithContext(c))
+	var rows []struct {
+		Type        models.ActivityType
+		OutcomeCode string
+		AssignedTo  *uint
+		Count       int64
+	}
+	if err == nil {
+		db := h.db.WithContext(c).Model(&models.Activity{}).
+			Where("status = ? AND completed_at BETWEEN ? AND ?", models.ActivityStatusCompleted, period.From, period.To)
+		if activityType != "" {
+			db = db.Where("type = ?", activityType)
+		}
+		err = db.Select("type, outcome_code, assigned_to, COUNT(*) AS count").
+			Group("type, outcome_code, assigned_to").
+			Scan(&rows).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to compute outcome report"),
+		})
+		return
+	}
+
+	typeCounts := make(map[models.ActivityType]map[string]int64)
+	userCounts := make(map[models.ActivityType]map[uint]map[string]int64) // Unassigned under 0
+	for _, row := range rows {
+		if typeCounts[row.Type] == nil {
+			typeCounts[row.Type] = make(map[string]int64)
+			userCounts[row.Type] = make(map[uint]map[string]int64)
+		}
+		typeCounts[row.Type][row.OutcomeCode] += row.Count
+
+		var userID uint
+		if row.AssignedTo != nil {
+			userID = *row.AssignedTo
+		}
+		if userCounts[row.Type][userID] == nil {
+			userCounts[row.Type][userID] = make(map[string]int64)
+		}
+		userCounts[row.Type][userID][row.OutcomeCode] += row.Count
+	}
+
+	report := OutcomeReport{ReportPeriod: period, Types: []OutcomeTypeStats{}}
+	for _, t := range models.ValidActivityTypes {
+		if (activityType != "" && t != activityType) || (typeCounts[t] == nil && len(taxonomy[t]) == 0) {
+			continue
+		}
+
+		stats := OutcomeTypeStats{Type: t, Outcomes: newOutcomeCounts(taxonomy, t, typeCounts[t], taxonomy.Codes(t)), Users: []UserOutcomeStats{}}
+		for _, count := range typeCounts[t] {
+			stats.Completed += count
+		}
+		userIDs := slices.Sorted(maps.Keys(userCounts[t]))
+		// Unassigned activities go last
+		if len(userIDs) > 0 && userIDs[0] == 0 {
+			userIDs = append(userIDs[1:], 0)
+		}
+		for _, userID := range userIDs {
+			user := UserOutcomeStats{Outcomes: newOutcomeCounts(taxonomy, t, userCounts[t][userID], nil)}
+			if userID != 0 {
+				user.UserID = &userID
+			}
+			for _, count := range userCounts[t][userID] {
+				user.Completed += count
+			}
+			stats.Users = append(stats.Users, user)
+		}
+		report.Types = append(report.Types, stats)
+	}
+
+	c.JSON(http.StatusOK, report)
 }
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/roles"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RoleHandler handles role definition endpoints
+type RoleHandler struct {
+	db    *gorm.DB
+	roles *roles.Service
+}
+
+// NewRoleHandler creates a new RoleHandler
+func NewRoleHandler(db *gorm.DB, roles *roles.Service) *RoleHandler {
+	return &RoleHandler{db: db, roles: roles}
+}
+
+// RoleCreateRequest represents the request body for creating a role
+type RoleCreateRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description,omitempty" binding:"max=1000"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// RoleUpdateRequest represents the request body for updating a role. Names
+// are fixed because JWTs and service accounts refer to roles by name.
+type RoleUpdateRequest struct {
+	Description string   `json:"description,omitempty" binding:"max=1000"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// ListRoles returns every role ordered by name
+// GET /admin/roles
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := h.db.WithContext(c).Order("name ASC").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch roles"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RoleListResponse{Data: roles})
+}
+
+// GetRole returns a role
+// GET /admin/roles/:id
+func (h *RoleHandler) GetRole(c *gin.Context) {
+	role, ok := h.findRole(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// CreateRole defines a new role
+// POST /admin/roles
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req RoleCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if err := models.ValidateRoleName(req.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ROLE",
+			"message": i18n.Message(c, "INVALID_ROLE", err.Error()),
+		})
+		return
+	}
+	if !validatePermissions(c, req.Permissions) {
+		return
+	}
+
+	var existing int64
+	h.db.WithContext(c).Model(&models.Role{}).Where("name = ?", req.Name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "ROLE_EXISTS",
+			"message": i18n.Message(c, "ROLE_EXISTS", "A role with this name already exists"),
+		})
+		return
+	}
+
+	role := models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: compactPermissions(req.Permissions),
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "role", role.ID, models.AuditActionCreate, nil, &role)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create role"),
+		})
+		return
+	}
+	h.reloadRoles(c)
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole replaces a role's description and permissions. The admin role
+// may gain permissions but never lose its built-in ones, so it cannot lock
+// every administrator out.
+// PUT /admin/roles/:id
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	role, ok := h.findRole(c)
+	if !ok {
+		return
+	}
+	oldRole := *role
+
+	var req RoleUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if !validatePermissions(c, req.Permissions) {
+		return
+	}
+	if role.Name == models.RoleAdmin {
+		for _, permission := range models.DefaultRolePermissions[models.RoleAdmin] {
+			if !slices.Contains(req.Permissions, permission) {
+				h.roleProtected(c)
+				return
+			}
+		}
+	}
+
+	role.Description = req.Description
+	role.Permissions = compactPermissions(req.Permissions)
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(role).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "role", role.ID, models.AuditActionUpdate, &oldRole, role)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update role"),
+		})
+		return
+	}
+	h.reloadRoles(c)
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole removes a role. Built-in roles and roles held by active
+// service accounts cannot be deleted. Users are not stored, so a JWT still
+// carrying a deleted role is rejected with UNKNOWN_ROLE.
+// DELETE /admin/roles/:id
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	role, ok := h.findRole(c)
+	if !ok {
+		return
+	}
+
+	if models.IsBuiltInRole(role.Name) {
+		h.roleProtected(c)
+		return
+	}
+
+	var inUse int64
+	h.db.WithContext(c).Model(&models.ServiceAccount{}).
+		Where("role = ? AND revoked_at IS NULL", role.Name).
+		Count(&inUse)
+	if inUse > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "ROLE_IN_USE",
+			"message": i18n.Message(c, "ROLE_IN_USE", "The role is still assigned to service accounts"),
+		})
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(role).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "role", role.ID, models.AuditActionDelete, role, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete role"),
+		})
+		return
+	}
+	h.reloadRoles(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role deleted successfully",
+	})
+}
+
+// validatePermissions responds with 400 when a permission is unknown
+func validatePermissions(c *gin.Context, permissions []string) bool {
+	if err := models.ValidatePermissions(permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_PERMISSION",
+			"message": i18n.Message(c, "INVALID_PERMISSION", err.Error()),
+		})
+		return false
+	}
+	return true
+}
+
+// compactPermissions sorts permissions and drops duplicates
+func compactPermissions(permissions []string) []string {
+	permissions = slices.Clone(permissions)
+	slices.Sort(permissions)
+	return slices.Compact(permissions)
+}
+
+// roleProtected responds with 409 for changes to built-in role guarantees
+func (h *RoleHandler) roleProtected(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "conflict",
+		"code":    "ROLE_PROTECTED",
+		"message": i18n.Message(c, "ROLE_PROTECTED", "Built-in roles cannot be deleted and the admin role must keep its core permissions"),
+	})
+}
+
+// reloadRoles applies a role change to permission checks immediately. A
+// failure leaves the previous roles in effect until the next refresh.
+func (h *RoleHandler) reloadRoles(c *gin.Context) {
+	if err := h.roles.Reload(c); err != nil {
+		middleware.Logger.Warn("Failed to reload roles: " + err.Error())
+	}
+}
+
+// findRole loads the role identified by the :id route parameter, writing
+// the error response when it cannot be found
+func (h *RoleHandler) findRole(c *gin.Context) (*models.Role, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid role ID"),
+		})
+		return nil, false
+	}
+
+	var role models.Role
+	if err := h.db.WithContext(c).First(&role, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "ROLE_NOT_FOUND",
+				"message": i18n.Message(c, "ROLE_NOT_FOUND", "Role not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch role"),
+		})
+		return nil, false
+	}
+
+	return &role, true
+}
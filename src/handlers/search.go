@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/SalehAlobaylan/CRM-Service/src/search"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Global search limits
+const (
+	minSearchQueryLength    = 2
+	defaultSearchLimit      = 20
+	maxSearchLimit          = 50
+	maxSearchOffset         = 1000
+	defaultSearchGroupLimit = 5 // Per type, when grouped
+	maxSearchGroupLimit     = 20
+)
+
+// SearchHandler handles the global search endpoints
+type SearchHandler struct {
+	db         *gorm.DB
+	weights    search.Weights
+	external   search.Indexer // Configured search engine; nil searches Postgres
+	exports    *exports.Manager
+	showScores bool
+}
+
+// NewSearchHandler creates a new SearchHandler. Searches go to external when
+// set, falling back to Postgres when it fails; reindexes of it run as jobs
+// of the export manager. showScores includes each result's score in
+// responses, for tuning the weights.
+func NewSearchHandler(db *gorm.DB, weights search.Weights, external search.Indexer, exportManager *exports.Manager, showScores bool) *SearchHandler {
+	return &SearchHandler{db: db, weights: weights, external: external, exports: exportManager, showScores: showScores}
+}
+
+// Search returns customers, contacts, deals and activities matching a
+// query, ranked by text relevance with boosts for exact email/phone
+// matches, the requesting agent's own records and recently active records.
+// Users who cannot manage all records only find their own. With
+// grouped=true the best limit matches of each type are returned in one
+// group per type with the type's total. Searches are answered by the
+// configured engine; when it fails, Postgres answers and the response meta
+// is marked degraded.
+// GET /admin/search?q=acme&types=customer,deal&limit=20&offset=0&grouped=false
+func (h *SearchHandler) Search(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if len([]rune(text)) < minSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "SEARCH_QUERY_TOO_SHORT",
+			"message": i18n.Message(c, "SEARCH_QUERY_TOO_SHORT", "Search query must be at least "+strconv.Itoa(minSearchQueryLength)+" characters"),
+		})
+		return
+	}
+
+	var types []string
+	if value := c.Query("types"); value != "" {
+		for _, t := range strings.Split(value, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(models.SearchTypes, t) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "validation_error",
+					"code":    "INVALID_SEARCH_TYPE",
+					"message": i18n.Message(c, "INVALID_SEARCH_TYPE", "types must be a list of customer, contact, deal and activity"),
+				})
+				return
+			}
+			if !slices.Contains(types, t) {
+				types = append(types, t)
+			}
+		}
+	}
+
+	grouped := c.Query("grouped") == "true"
+	defaultLimit, maxLimit := defaultSearchLimit, maxSearchLimit
+	if grouped {
+		defaultLimit, maxLimit = defaultSearchGroupLimit, maxSearchGroupLimit
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 || grouped {
+		offset = 0
+	}
+	if offset > maxSearchOffset {
+		offset = maxSearchOffset
+	}
+
+	q := search.Query{Text: text, Types: types, Limit: limit, Offset: offset}
+	if user, ok := middleware.GetUserFromContext(c); ok {
+		if user.Role == models.RoleAgent {
+			q.OwnerID = &user.ID
+		}
+		if !models.CanManageAll(user.Role) {
+			q.OwnedBy = &user.ID
+		}
+	}
+
+	meta := models.SearchMeta{Offset: offset, Limit: limit}
+	if grouped {
+		var groups []models.SearchGroup
+		err = h.query(c, &meta, func(indexer search.Indexer) (err error) {
+			groups, err = indexer.QueryGroups(c, q)
+			return err
+		})
+		if err != nil {
+			respondSearchFailed(c)
+			return
+		}
+		for i := range groups {
+			h.hideScores(groups[i].Data)
+		}
+		c.JSON(http.StatusOK, models.SearchGroupsResponse{Data: groups, Query: text, Meta: meta})
+		return
+	}
+
+	var results []models.SearchResult
+	err = h.query(c, &meta, func(indexer search.Indexer) (err error) {
+		results, err = indexer.Query(c, q)
+		return err
+	})
+	if err != nil {
+		respondSearchFailed(c)
+		return
+	}
+	h.hideScores(results)
+	if results == nil {
+		results = []models.SearchResult{}
+	}
+
+	c.JSON(http.StatusOK, models.SearchResponse{Data: results, Query: text, Meta: meta})
+}
+
+// query runs a search on the configured engine, falling back to Postgres
+// when it fails, and records the engine that answered in meta. The external
+// index holds live records only, so sandboxed requests search their own
+// database.
+func (h *SearchHandler) query(c *gin.Context, meta *models.SearchMeta, run func(search.Indexer) error) error {
+	if h.external != nil && !sandbox.Active(c) {
+		err := run(h.external)
+		if err == nil {
+			meta.Engine = h.external.Name()
+			return nil
+		}
+		middleware.Logger.Warn("Search engine failed, falling back to Postgres: " + err.Error())
+		meta.Degraded = true
+	}
+
+	indexer := search.NewPostgresIndexer(middleware.GetReadDB(c, h.db), h.weights)
+	meta.Engine = indexer.Name()
+	return run(indexer)
+}
+
+// hideScores drops the scores of results unless they are shown for tuning
+func (h *SearchHandler) hideScores(results []models.SearchResult) {
+	if h.showScores {
+		return
+	}
+	for i := range results {
+		results[i].Score = nil
+	}
+}
+
+// respondSearchFailed writes the error response of a failed search
+func respondSearchFailed(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "internal_error",
+		"code":    "DATABASE_ERROR",
+		"message": i18n.Message(c, "DATABASE_ERROR", "Failed to search"),
+	})
+}
+
+// Reindex starts an async job rebuilding the search engine's index from
+// every customer, contact, deal and activity. The job's checkpoint reports the
+// records indexed so far, and a failed reindex can be resumed.
+// POST /admin/maintenance/search/reindex
+func (h *SearchHandler) Reindex(c *gin.Context) {
+	if h.external == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "SEARCH_ENGINE_NOT_INDEXED",
+			"message": i18n.Message(c, "SEARCH_ENGINE_NOT_INDEXED", "Search uses Postgres directly, there is no index to rebuild"),
+		})
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	job := models.Job{
+		Type:          search.ReindexJobType,
+		CreatedBy:     user.ID,
+		CreatedByName: user.Name,
+	}
+
+	if err := h.exports.EnqueueInternal(c, &job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create job"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SecurityHandler handles security activity and alert endpoints
+type SecurityHandler struct {
+	db      *gorm.DB
+	monitor *security.Monitor
+}
+
+// NewSecurityHandler creates a new SecurityHandler
+func NewSecurityHandler(db *gorm.DB, monitor *security.Monitor) *SecurityHandler {
+	return &SecurityHandler{db: db, monitor: monitor}
+}
+
+// AcknowledgeSecurityAlertRequest represents the request body for
+// acknowledging a security alert
+type AcknowledgeSecurityAlertRequest struct {
+	Note          string `json:"note,omitempty"`
+	FalsePositive bool   `json:"false_positive,omitempty"` // Lifts the token revocation the alert applied
+}
+
+// securityActivityListQuery defines the filters and sorting of ListSecurityActivity
+var securityActivityListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("user_id", "user_id"),
+		query.AtLeast("from", "bucket_start", query.KindTime),
+		query.AtMost("to", "bucket_start", query.KindTime),
+	},
+	Sort: query.Sort{Fixed: "bucket_start DESC, user_id ASC"},
+}
+
+// securityAlertListQuery defines the filters and sorting of ListSecurityAlerts
+var securityAlertListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("status", "status"),
+		query.Equal("metric", "metric"),
+		query.Equal("user_id", "user_id"),
+		query.AtLeast("from", "bucket_start", query.KindTime),
+		query.AtMost("to", "bucket_start", query.KindTime),
+	},
+	Sort: query.Sort{Fixed: "id DESC"},
+}
+
+// securityWebhookDeliveryListQuery defines the filters and sorting of
+// ListSecurityWebhookDeliveries
+var securityWebhookDeliveryListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("event_id", "event_id"),
+		query.Equal("alert_id", "alert_id"),
+		query.Equal("status", "status"),
+	},
+	Sort: query.Sort{Fixed: "id DESC"},
+}
+
+// ListSecurityActivity returns hourly per-user operation counts, latest
+// first, with their totals, for investigating a user's activity
+// GET /admin/security/activity?user_id=&from=&to=
+func (h *SecurityHandler) ListSecurityActivity(c *gin.Context) {
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db, _ := securityActivityListQuery.Filter(h.db.WithContext(c).Model(&models.SecurityActivity{}), c.Request.URL.Query())
+
+	var total int64
+	db.Session(&gorm.Session{}).Count(&total)
+
+	var totals models.SecurityCounts
+	if err := db.Session(&gorm.Session{}).Select(
+		"COALESCE(SUM(reads), 0) AS reads, COALESCE(SUM(writes), 0) AS writes, " +
+			"COALESCE(SUM(deletes), 0) AS deletes, COALESCE(SUM(exports), 0) AS exports, " +
+			"COALESCE(SUM(records_changed), 0) AS records_changed, COALESCE(SUM(records_deleted), 0) AS records_deleted",
+	).Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch security activity"),
+		})
+		return
+	}
+
+	var activity []models.SecurityActivity
+	if err := db.Order(securityActivityListQuery.Order(c.Request.URL.Query())).
+		Offset(page.Offset()).Limit(page.PageSize).Find(&activity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch security activity"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.SecurityActivityListResponse{
+		Data:       activity,
+		Totals:     totals,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
+
+// ListSecurityAlerts returns security alerts, newest first
+// GET /admin/security/alerts?status=&metric=&user_id=&from=&to=
+func (h *SecurityHandler) ListSecurityAlerts(c *gin.Context) {
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db, _ := securityAlertListQuery.Apply(h.db.WithContext(c).Model(&models.SecurityAlert{}), c.Request.URL.Query())
+
+	var total int64
+	db.Count(&total)
+
+	var alerts []models.SecurityAlert
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch security alerts"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.SecurityAlertListResponse{
+		Data:       alerts,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
+
+// AcknowledgeSecurityAlert closes an open security alert after review. A
+// false positive also lifts the token revocation the alert applied.
+// POST /admin/security/alerts/:id/acknowledge
+func (h *SecurityHandler) AcknowledgeSecurityAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid security alert ID"),
+		})
+		return
+	}
+
+	var req AcknowledgeSecurityAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	var alert models.SecurityAlert
+	if err := h.db.WithContext(c).First(&alert, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "SECURITY_ALERT_NOT_FOUND",
+				"message": i18n.Message(c, "SECURITY_ALERT_NOT_FOUND", "Security alert not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch security alert"),
+		})
+		return
+	}
+	oldAlert := alert
+
+	user, _ := middleware.GetUserFromContext(c)
+	err = h.monitor.Acknowledge(c, &alert, security.Reviewer{UserID: user.ID, UserName: user.Name}, req.Note, req.FalsePositive, func(tx *gorm.DB) error {
+		// Log audit
+		return logAudit(c, tx, "security_alert", alert.ID, models.AuditActionUpdate, &oldAlert, &alert)
+	})
+	if errors.Is(err, security.ErrAlreadyAcknowledged) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "ALERT_ALREADY_ACKNOWLEDGED",
+			"message": i18n.Message(c, "ALERT_ALREADY_ACKNOWLEDGED", "Security alert has already been acknowledged"),
+		})
+		return
+	}
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to acknowledge security alert"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// SecurityWebhookResponse describes the alert webhook's delivery contract
+type SecurityWebhookResponse struct {
+	Event            string                     `json:"event"`
+	Mode             models.SecurityWebhookMode `json:"mode"`
+	EventIDHeader    string                     `json:"event_id_header"`    // The alert's event ID, the same on every retry
+	DeliveryIDHeader string                     `json:"delivery_id_header"` // New on every attempt
+	Retries          string                     `json:"retries"`
+	Deliveries       string                     `json:"deliveries"` // Where attempts are listed, by event ID
+}
+
+// securityWebhookContract describes how webhook delivers alerts
+func securityWebhookContract(webhook *security.Webhook) SecurityWebhookResponse {
+	retries := "Deliveries that fail are retried on later runs for a day until a 2xx response; a retry has the same event ID and a new delivery ID"
+	if webhook.Mode() == models.SecurityWebhookAtMostOnce {
+		retries = "Each alert is sent once; when the response is not 2xx the alert is marked failed and not retried"
+	}
+	return SecurityWebhookResponse{
+		Event:            "security.alert",
+		Mode:             webhook.Mode(),
+		EventIDHeader:    security.EventIDHeader,
+		DeliveryIDHeader: security.DeliveryIDHeader,
+		Retries:          retries,
+		Deliveries:       "/admin/security/webhook/deliveries?event_id={event_id}",
+	}
+}
+
+// GetSecurityWebhook describes how alerts are delivered to the alert
+// webhook, so consumers can deduplicate and reconcile them
+// GET /admin/security/webhook
+func (h *SecurityHandler) GetSecurityWebhook(c *gin.Context) {
+	webhook := h.monitor.Webhook()
+	if webhook == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "SECURITY_WEBHOOK_NOT_CONFIGURED",
+			"message": i18n.Message(c, "SECURITY_WEBHOOK_NOT_CONFIGURED", "The security alert webhook is not configured"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, securityWebhookContract(webhook))
+}
+
+// ListSecurityWebhookDeliveries returns the attempts to deliver alerts to
+// the alert webhook, newest first, with the start of each response
+// GET /admin/security/webhook/deliveries?event_id=&alert_id=&status=
+func (h *SecurityHandler) ListSecurityWebhookDeliveries(c *gin.Context) {
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db, _ := securityWebhookDeliveryListQuery.Apply(h.db.WithContext(c).Model(&models.SecurityWebhookDelivery{}), c.Request.URL.Query())
+
+	var total int64
+	db.Count(&total)
+
+	var deliveries []models.SecurityWebhookDelivery
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch webhook deliveries"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.SecurityWebhookDeliveryListResponse{
+		Data:       deliveries,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultRotationGraceHours is how long the previous token stays valid after rotation
+const defaultRotationGraceHours = 24
+
+// ServiceAccountHandler handles service account endpoints
+type ServiceAccountHandler struct {
+	db *gorm.DB
+}
+
+// NewServiceAccountHandler creates a new ServiceAccountHandler
+func NewServiceAccountHandler(db *gorm.DB) *ServiceAccountHandler {
+	return &ServiceAccountHandler{db: db}
+}
+
+// ServiceAccountCreateRequest represents the request body for creating a service account
+type ServiceAccountCreateRequest struct {
+	Name               string   `json:"name" binding:"required,min=1,max=100"`
+	Description        string   `json:"description,omitempty"`
+	Role               string   `json:"role" binding:"required"`
+	Scopes             []string `json:"scopes" binding:"required,min=1"`
+	RateLimitPerMinute int      `json:"rate_limit_per_minute,omitempty" binding:"omitempty,min=0"`
+	Sandbox            bool     `json:"sandbox,omitempty"`
+}
+
+// ServiceAccountRotateRequest represents the request body for rotating a token
+type ServiceAccountRotateRequest struct {
+	GracePeriodHours *int `json:"grace_period_hours,omitempty" binding:"omitempty,min=0,max=168"`
+}
+
+// ListServiceAccounts returns all service accounts with their token metadata
+// GET /admin/service-accounts
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	var accounts []models.ServiceAccount
+	if err := h.db.WithContext(c).Preload("Tokens", "revoked_at IS NULL").Order("name ASC").Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch service accounts"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ServiceAccountListResponse{Data: accounts})
+}
+
+// CreateServiceAccount creates a service account and issues its first token
+// POST /admin/service-accounts
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	var req ServiceAccountCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	if !models.IsKnownRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ROLE",
+			"message": i18n.Message(c, "INVALID_ROLE", "Role is not defined; see GET /admin/roles"),
+		})
+		return
+	}
+	if err := models.ValidateScopes(req.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_SCOPE",
+			"message": i18n.Message(c, "INVALID_SCOPE", err.Error()),
+		})
+		return
+	}
+
+	if req.Sandbox && !sandbox.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "SANDBOX_UNAVAILABLE",
+			"message": i18n.Message(c, "SANDBOX_UNAVAILABLE", "The API sandbox is not enabled on this server"),
+		})
+		return
+	}
+
+	var existing int64
+	h.db.WithContext(c).Model(&models.ServiceAccount{}).Where("name = ?", req.Name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "SERVICE_ACCOUNT_EXISTS",
+			"message": i18n.Message(c, "SERVICE_ACCOUNT_EXISTS", "A service account with this name already exists"),
+		})
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	account := models.ServiceAccount{
+		Name:               req.Name,
+		Description:        req.Description,
+		Role:               req.Role,
+		Scopes:             req.Scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		Sandbox:            req.Sandbox,
+		CreatedBy:          user.ID,
+	}
+
+	var tokenString string
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+		var err error
+		if tokenString, err = issueServiceAccountToken(tx, account.ID); err != nil {
+			return err
+		}
+		tx.Preload("Tokens").First(&account, account.ID)
+
+		// Log audit
+		return logAudit(c, tx, "service_account", account.ID, models.AuditActionCreate, nil, &account)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create service account"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.ServiceAccountCreateResponse{
+		ServiceAccount: account,
+		Token:          tokenString,
+	})
+}
+
+// RotateServiceAccountToken issues a new token. The most recent previous
+// token stays valid for the grace period so integrations can roll over;
+// any older tokens are revoked immediately.
+// POST /admin/service-accounts/:id/rotate
+func (h *ServiceAccountHandler) RotateServiceAccountToken(c *gin.Context) {
+	account, ok := h.findServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	var req ServiceAccountRotateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "validation_error",
+				"code":    "INVALID_REQUEST",
+				"message": i18n.ValidationMessage(c, err),
+			})
+			return
+		}
+	}
+	graceHours := defaultRotationGraceHours
+	if req.GracePeriodHours != nil {
+		graceHours = *req.GracePeriodHours
+	}
+
+	now := time.Now()
+	graceUntil := now.Add(time.Duration(graceHours) * time.Hour)
+
+	var tokenString string
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var active []models.ServiceAccountToken
+		if err := tx.Where("service_account_id = ? AND revoked_at IS NULL", account.ID).
+			Order("created_at DESC").Find(&active).Error; err != nil {
+			return err
+		}
+		for i, token := range active {
+			if !token.IsActive(now) {
+				continue
+			}
+			if i == 0 && graceHours > 0 {
+				if token.ExpiresAt == nil || token.ExpiresAt.After(graceUntil) {
+					if err := tx.Model(&token).Update("expires_at", graceUntil).Error; err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := tx.Model(&token).Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if tokenString, err = issueServiceAccountToken(tx, account.ID); err != nil {
+			return err
+		}
+		tx.Preload("Tokens", "revoked_at IS NULL").First(account, account.ID)
+
+		// Log audit
+		return logAudit(c, tx, "service_account", account.ID, models.AuditActionUpdate, nil, account)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to rotate service account token"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ServiceAccountCreateResponse{
+		ServiceAccount: *account,
+		Token:          tokenString,
+	})
+}
+
+// RevokeServiceAccount revokes a service account and all of its tokens
+// DELETE /admin/service-accounts/:id
+func (h *ServiceAccountHandler) RevokeServiceAccount(c *gin.Context) {
+	account, ok := h.findServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ServiceAccountToken{}).
+			Where("service_account_id = ? AND revoked_at IS NULL", account.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(account).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "service_account", account.ID, models.AuditActionDelete, account, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to revoke service account"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Service account revoked successfully",
+	})
+}
+
+// findServiceAccount loads the active service account identified by the :id
+// route parameter, writing the error response when it cannot be found
+func (h *ServiceAccountHandler) findServiceAccount(c *gin.Context) (*models.ServiceAccount, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid service account ID"),
+		})
+		return nil, false
+	}
+
+	var account models.ServiceAccount
+	if err := h.db.WithContext(c).Where("revoked_at IS NULL").First(&account, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "SERVICE_ACCOUNT_NOT_FOUND",
+				"message": i18n.Message(c, "SERVICE_ACCOUNT_NOT_FOUND", "Service account not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch service account"),
+		})
+		return nil, false
+	}
+
+	return &account, true
+}
+
+// issueServiceAccountToken stores a new hashed token and returns the plaintext
+func issueServiceAccountToken(tx *gorm.DB, accountID uint) (string, error) {
+	tokenString, hash, err := models.GenerateServiceAccountToken()
+	if err != nil {
+		return "", err
+	}
+
+	token := models.ServiceAccountToken{
+		ServiceAccountID: accountID,
+		TokenHash:        hash,
+		Prefix:           tokenString[:len(models.ServiceAccountTokenPrefix)+6],
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/smoketest"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SmokeTestHandler runs post-deploy smoke tests through the service's own
+// router
+type SmokeTestHandler struct {
+	db      *gorm.DB
+	router  http.Handler
+	enabled bool
+}
+
+// NewSmokeTestHandler creates a new SmokeTestHandler sending the scenario's
+// requests to router. Runs are refused unless enabled.
+func NewSmokeTestHandler(db *gorm.DB, router http.Handler, enabled bool) *SmokeTestHandler {
+	return &SmokeTestHandler{db: db, router: router, enabled: enabled}
+}
+
+// SmokeTestResponse is the outcome of a smoke test run
+type SmokeTestResponse struct {
+	JobID uint   `json:"job_id"`
+	Tag   string `json:"tag"` // Name of the records the run created
+	smoketest.Report
+}
+
+// RunSmokeTest runs the post-deploy scenario with the caller's credentials:
+// it creates a customer, contact, deal and activity, advances and completes
+// them, loads the reports and deletes the records again, reporting each
+// step's result and timing. The run is recorded as a job.
+// POST /admin/maintenance/smoke-test
+func (h *SmokeTestHandler) RunSmokeTest(c *gin.Context) {
+	if !h.enabled {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"code":    "SMOKE_TEST_DISABLED",
+			"message": i18n.Message(c, "SMOKE_TEST_DISABLED", "Smoke tests are disabled in production unless SMOKE_TEST_ENABLED is set"),
+		})
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	started := time.Now()
+	job := models.Job{
+		Type:          smoketest.JobType,
+		Status:        models.JobStatusRunning,
+		CreatedBy:     user.ID,
+		CreatedByName: user.Name,
+		StartedAt:     &started,
+	}
+	if err := h.db.WithContext(c).Create(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create job"),
+		})
+		return
+	}
+
+	tag := fmt.Sprintf("smoke-test-%d", job.ID)
+	header := http.Header{}
+	header.Set("Authorization", c.GetHeader("Authorization"))
+	report := smoketest.Run(c.Request.Context(), h.router, header, map[string]string{
+		"tag":        tag,
+		"next_stage": string(smokeTestNextStage()),
+	}, smoketest.PostDeploy)
+
+	result, _ := json.Marshal(report)
+	finished := time.Now()
+	job.Status = models.JobStatusCompleted
+	job.Result = string(result)
+	job.FinishedAt = &finished
+	for _, step := range report.Steps {
+		if step.Result == smoketest.StepFailed {
+			job.Status = models.JobStatusFailed
+			job.Error = step.Name + ": " + step.Error
+			break
+		}
+	}
+	// The report is returned even when it cannot be recorded
+	if err := h.db.Model(&job).Select("status", "result", "error", "finished_at").Updates(&job).Error; err != nil {
+		middleware.Logger.Warn(fmt.Sprintf("Failed to record smoke test job %d: %s", job.ID, err.Error()))
+	}
+
+	c.JSON(http.StatusOK, SmokeTestResponse{JobID: job.ID, Tag: tag, Report: report})
+}
+
+// smokeTestNextStage returns the first open stage a new deal can advance
+// to: the first active stage the service does not reserve
+func smokeTestNextStage() models.DealStage {
+	for _, stage := range models.DealStages() {
+		if !models.IsReservedDealStage(stage) {
+			return stage
+		}
+	}
+	return models.DealStageClosedWon
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/buildinfo"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+)
+
+// StatusHandler serves the public status endpoint
+type StatusHandler struct {
+	db     *gorm.DB
+	stats  *middleware.RequestStats
+	apiKey string
+}
+
+// NewStatusHandler creates a new StatusHandler. With a non-empty apiKey
+// callers must send it in the X-API-Key header.
+func NewStatusHandler(db *gorm.DB, stats *middleware.RequestStats, apiKey string) *StatusHandler {
+	return &StatusHandler{db: db, stats: stats, apiKey: apiKey}
+}
+
+// StatusResponse is the public service status. Every field is an aggregate;
+// add nothing here that identifies records, users or infrastructure.
+type StatusResponse struct {
+	Status        string  `json:"status"`
+	Version       string  `json:"version"`
+	Commit        string  `json:"commit,omitempty"`
+	BuildTime     string  `json:"build_time,omitempty"`
+	UptimeSeconds int64   `json:"uptime_seconds"`
+	WindowSeconds int64   `json:"window_seconds"`
+	Requests      int64   `json:"requests"`
+	RequestRate   float64 `json:"request_rate"` // Requests per second over the window
+	ErrorRate     float64 `json:"error_rate"`   // Share of requests answered with a 5xx status
+	Database      string  `json:"database"`
+}
+
+// Status returns sanitized aggregate service metrics for uptime pages
+// GET /status
+func (h *StatusHandler) Status(c *gin.Context) {
+	if h.apiKey != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("X-API-Key")), []byte(h.apiKey)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "INVALID_API_KEY",
+			"message": i18n.Message(c, "INVALID_API_KEY", "Invalid or missing API key"),
+		})
+		return
+	}
+
+	snapshot := h.stats.Snapshot()
+	build := buildinfo.Get()
+	response := StatusResponse{
+		Status:        "ok",
+		Version:       build.Version,
+		Commit:        build.Commit,
+		BuildTime:     build.BuildTime,
+		UptimeSeconds: int64(time.Since(h.stats.Started()).Seconds()),
+		WindowSeconds: int64(snapshot.Window.Seconds()),
+		Requests:      snapshot.Requests,
+		Database:      "ok",
+	}
+	if seconds := snapshot.Covered.Seconds(); seconds > 0 {
+		response.RequestRate = round(float64(snapshot.Requests)/seconds, 3)
+	}
+	if snapshot.Requests > 0 {
+		response.ErrorRate = round(float64(snapshot.Errors)/float64(snapshot.Requests), 4)
+	}
+
+	// Reachability only; driver errors may name hosts and are not exposed
+	if sqlDB, err := h.db.DB(); err != nil || sqlDB.PingContext(c) != nil {
+		response.Status = "degraded"
+		response.Database = "unavailable"
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// round rounds a value to the given number of decimal places
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
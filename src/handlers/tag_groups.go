@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// customersInTagGroups selects the customers carrying any tag of the groups
+// bound to its placeholder by name
+const customersInTagGroups = "SELECT customer_tags.customer_id FROM customer_tags " +
+	"JOIN tags ON tags.id = customer_tags.tag_id AND tags.deleted_at IS NULL " +
+	"JOIN tag_groups ON tag_groups.id = tags.group_id AND tag_groups.deleted_at IS NULL " +
+	"WHERE tag_groups.name IN ?"
+
+// maxGroupConflicts caps the customer IDs listed in a tag group conflict
+const maxGroupConflicts = 20
+
+// TagGroupHandler handles tag group endpoints
+type TagGroupHandler struct {
+	db *gorm.DB
+}
+
+// NewTagGroupHandler creates a new TagGroupHandler
+func NewTagGroupHandler(db *gorm.DB) *TagGroupHandler {
+	return &TagGroupHandler{db: db}
+}
+
+// TagGroupCreateRequest represents the request body for creating a tag group
+type TagGroupCreateRequest struct {
+	Name      string `json:"name" binding:"required,min=1,max=100"`
+	Exclusive bool   `json:"exclusive"`
+}
+
+// TagGroupUpdateRequest represents the request body for updating a tag group
+type TagGroupUpdateRequest struct {
+	Name      string `json:"name,omitempty" binding:"omitempty,max=100"`
+	Exclusive *bool  `json:"exclusive,omitempty"`
+}
+
+// ListTagGroups returns all tag groups with their tags
+// GET /admin/tag-groups
+func (h *TagGroupHandler) ListTagGroups(c *gin.Context) {
+	var groups []models.TagGroup
+	if err := h.db.WithContext(c).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") }).
+		Order("name ASC").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tag groups"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TagGroupListResponse{
+		Data:  groups,
+		Total: int64(len(groups)),
+	})
+}
+
+// CreateTagGroup creates a new tag group
+// POST /admin/tag-groups
+func (h *TagGroupHandler) CreateTagGroup(c *gin.Context) {
+	var req TagGroupCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	var existing models.TagGroup
+	if err := h.db.WithContext(c).Where("name = ?", req.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "TAG_GROUP_EXISTS",
+			"message": i18n.Message(c, "TAG_GROUP_EXISTS", "A tag group with this name already exists"),
+		})
+		return
+	}
+
+	group := models.TagGroup{
+		Name:      req.Name,
+		Exclusive: req.Exclusive,
+	}
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "tag_group", group.ID, models.AuditActionCreate, nil, &group)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create tag group"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// UpdateTagGroup updates a tag group. A group can only be made exclusive
+// while no customer carries more than one of its tags.
+// PUT /admin/tag-groups/:id
+func (h *TagGroupHandler) UpdateTagGroup(c *gin.Context) {
+	group, ok := h.findTagGroup(c)
+	if !ok {
+		return
+	}
+	oldGroup := group
+
+	var req TagGroupUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+
+	// Check uniqueness if name is being changed
+	if req.Name != "" && req.Name != group.Name {
+		var existing models.TagGroup
+		if err := h.db.WithContext(c).Where("name = ? AND id != ?", req.Name, group.ID).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"code":    "TAG_GROUP_EXISTS",
+				"message": i18n.Message(c, "TAG_GROUP_EXISTS", "A tag group with this name already exists"),
+			})
+			return
+		}
+		group.Name = req.Name
+	}
+
+	if req.Exclusive != nil {
+		if *req.Exclusive && !group.Exclusive {
+			customerIDs, err := exclusiveGroupViolations(h.db.WithContext(c), group.ID, nil)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "internal_error",
+					"code":    "DATABASE_ERROR",
+					"message": i18n.Message(c, "DATABASE_ERROR", "Failed to check tag group"),
+				})
+				return
+			}
+			if len(customerIDs) > 0 {
+				respondGroupConflict(c, customerIDs)
+				return
+			}
+		}
+		group.Exclusive = *req.Exclusive
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&group).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "tag_group", group.ID, models.AuditActionUpdate, &oldGroup, &group)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update tag group"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteTagGroup deletes a tag group. Its tags are kept, ungrouped.
+// DELETE /admin/tag-groups/:id
+func (h *TagGroupHandler) DeleteTagGroup(c *gin.Context) {
+	group, ok := h.findTagGroup(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Tag{}).Where("group_id = ?", group.ID).Update("group_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&group).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "tag_group", group.ID, models.AuditActionDelete, &group, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete tag group"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag group deleted successfully",
+	})
+}
+
+// TagGroupMigrationRequest represents the request body for moving
+// prefix-encoded tag names, such as "industry:fintech", into groups
+type TagGroupMigrationRequest struct {
+	Separator string `json:"separator,omitempty" binding:"omitempty,max=5"` // Defaults to ":"
+	KeepNames bool   `json:"keep_names"`                                    // Group tags without stripping the prefix
+	Apply     bool   `json:"apply"`                                         // Preview only unless set
+}
+
+// TagGroupMigration describes how ungrouped tags map onto groups
+type TagGroupMigration struct {
+	Separator string                   `json:"separator"`
+	Applied   bool                     `json:"applied"`
+	NewGroups []string                 `json:"new_groups"`
+	Tags      []TagGroupMigrationEntry `json:"tags"`
+}
+
+// TagGroupMigrationEntry maps one tag onto its group. Tags with a conflict
+// are left unchanged.
+type TagGroupMigrationEntry struct {
+	TagID    uint   `json:"tag_id"`
+	Tag      string `json:"tag"`
+	Group    string `json:"group"`
+	NewName  string `json:"new_name"`
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// MigrateTagGroups moves ungrouped tags whose names carry a group prefix
+// into groups named after the prefix, creating missing groups as
+// non-exclusive. Without apply it only returns the mapping.
+// POST /admin/maintenance/tag-groups/migrate
+func (h *TagGroupHandler) MigrateTagGroups(c *gin.Context) {
+	var req TagGroupMigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if req.Separator == "" {
+		req.Separator = ":"
+	}
+
+	finish := func(string) {}
+	if req.Apply {
+		finish = annotateOperation(c, h.db, models.AnnotationTypeMaintenance, "Tag group migration")
+	}
+
+	var migration TagGroupMigration
+	var groups map[string]*models.TagGroup
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		migration, groups, err = planTagGroupMigration(tx, req.Separator, req.KeepNames)
+		if err != nil || !req.Apply {
+			return err
+		}
+
+		for _, name := range migration.NewGroups {
+			group := groups[name]
+			if err := tx.Create(group).Error; err != nil {
+				return err
+			}
+			audit := auditEntry(c, "tag_group", group.ID, models.AuditActionCreate, nil, group)
+			if err := audittrail.Record(c, tx, &audit); err != nil {
+				return err
+			}
+		}
+		for _, entry := range migration.Tags {
+			if entry.Conflict != "" {
+				continue
+			}
+			var tag models.Tag
+			if err := tx.First(&tag, entry.TagID).Error; err != nil {
+				return err
+			}
+			oldTag := tag
+			tag.Name = entry.NewName
+			tag.GroupID = &groups[entry.Group].ID
+			if err := tx.Save(&tag).Error; err != nil {
+				return err
+			}
+			audit := auditEntry(c, "tag", tag.ID, models.AuditActionUpdate, &oldTag, &tag)
+			if err := audittrail.Record(c, tx, &audit); err != nil {
+				return err
+			}
+		}
+		migration.Applied = true
+		return nil
+	})
+	if err != nil {
+		finish("Failed: no tags were moved")
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to migrate tag groups"),
+		})
+		return
+	}
+	finish("Created " + strconv.Itoa(len(migration.NewGroups)) + " tag groups")
+
+	c.JSON(http.StatusOK, migration)
+}
+
+// planTagGroupMigration maps every ungrouped tag named prefix+separator+name
+// onto the group named prefix. It returns the groups by name; new ones are
+// not yet created.
+func planTagGroupMigration(tx *gorm.DB, separator string, keepNames bool) (TagGroupMigration, map[string]*models.TagGroup, error) {
+	migration := TagGroupMigration{Separator: separator, NewGroups: []string{}, Tags: []TagGroupMigrationEntry{}}
+
+	var tags []models.Tag
+	if err := tx.Where("group_id IS NULL").Order("name ASC").Find(&tags).Error; err != nil {
+		return migration, nil, err
+	}
+	var existingGroups []models.TagGroup
+	if err := tx.Find(&existingGroups).Error; err != nil {
+		return migration, nil, err
+	}
+	groups := make(map[string]*models.TagGroup, len(existingGroups))
+	for i := range existingGroups {
+		groups[existingGroups[i].Name] = &existingGroups[i]
+	}
+
+	newNames := make(map[string]int)
+	for _, tag := range tags {
+		prefix, name, ok := strings.Cut(tag.Name, separator)
+		prefix, name = strings.TrimSpace(prefix), strings.TrimSpace(name)
+		if !ok || prefix == "" || name == "" {
+			continue
+		}
+		if keepNames {
+			name = tag.Name
+		}
+		if groups[prefix] == nil {
+			groups[prefix] = &models.TagGroup{Name: prefix}
+		}
+		migration.Tags = append(migration.Tags, TagGroupMigrationEntry{
+			TagID:   tag.ID,
+			Tag:     tag.Name,
+			Group:   prefix,
+			NewName: name,
+		})
+		newNames[name]++
+	}
+	// Tag names are unique, including deleted tags
+	if !keepNames && len(migration.Tags) > 0 {
+		names := make([]string, 0, len(newNames))
+		for name := range newNames {
+			names = append(names, name)
+		}
+		var taken []models.Tag
+		if err := tx.Unscoped().Select("id", "name").Where("name IN ?", names).Find(&taken).Error; err != nil {
+			return migration, nil, err
+		}
+		takenBy := make(map[string]uint, len(taken))
+		for _, tag := range taken {
+			takenBy[tag.Name] = tag.ID
+		}
+		for i := range migration.Tags {
+			entry := &migration.Tags[i]
+			if id, ok := takenBy[entry.NewName]; ok && id != entry.TagID {
+				entry.Conflict = "name_taken"
+			} else if newNames[entry.NewName] > 1 {
+				entry.Conflict = "duplicate_name"
+			}
+		}
+	}
+
+	// Exclusive groups may not end up with several tags on one customer;
+	// leave the tags that would for an admin to sort out
+	moving := make(map[uint][]uint)
+	for _, entry := range migration.Tags {
+		if group := groups[entry.Group]; entry.Conflict == "" && group.ID != 0 && group.Exclusive {
+			moving[group.ID] = append(moving[group.ID], entry.TagID)
+		}
+	}
+	conflicting := make(map[uint]bool)
+	for groupID, tagIDs := range moving {
+		inGroup := tx.Model(&models.Tag{}).Select("id").Where("group_id = ?", groupID)
+		violators := tx.Model(&models.CustomerTag{}).Select("customer_id").
+			Where("tag_id IN (?) OR tag_id IN ?", inGroup, tagIDs).
+			Group("customer_id").
+			Having("COUNT(*) > 1")
+		var ids []uint
+		if err := tx.Model(&models.CustomerTag{}).
+			Where("tag_id IN ? AND customer_id IN (?)", tagIDs, violators).
+			Distinct().Pluck("tag_id", &ids).Error; err != nil {
+			return migration, nil, err
+		}
+		for _, id := range ids {
+			conflicting[id] = true
+		}
+	}
+	for i := range migration.Tags {
+		if conflicting[migration.Tags[i].TagID] {
+			migration.Tags[i].Conflict = "exclusive_group"
+		}
+	}
+
+	// Only create groups that receive a tag
+	created := make(map[string]bool)
+	for _, entry := range migration.Tags {
+		if entry.Conflict == "" && groups[entry.Group].ID == 0 && !created[entry.Group] {
+			created[entry.Group] = true
+			migration.NewGroups = append(migration.NewGroups, entry.Group)
+		}
+	}
+	sort.Strings(migration.NewGroups)
+	return migration, groups, nil
+}
+
+// findTagGroup loads the tag group named by the :id parameter, responding
+// with an error when there is none
+func (h *TagGroupHandler) findTagGroup(c *gin.Context) (models.TagGroup, bool) {
+	var group models.TagGroup
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid tag group ID"),
+		})
+		return group, false
+	}
+
+	if err := h.db.WithContext(c).First(&group, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "TAG_GROUP_NOT_FOUND",
+				"message": i18n.Message(c, "TAG_GROUP_NOT_FOUND", "Tag group not found"),
+			})
+			return group, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tag group"),
+		})
+		return group, false
+	}
+	return group, true
+}
+
+// exclusiveGroupViolations returns customers that would carry more than one
+// tag of a group: among the group's tags, plus extraTagID when a tag is
+// being moved into the group
+func exclusiveGroupViolations(db *gorm.DB, groupID uint, extraTagID *uint) ([]uint, error) {
+	inGroup := db.Model(&models.Tag{}).Select("id").Where("group_id = ?", groupID)
+	if extraTagID != nil {
+		inGroup = inGroup.Or("id = ?", *extraTagID)
+	}
+
+	var customerIDs []uint
+	err := db.Model(&models.CustomerTag{}).
+		Select("customer_id").
+		Where("tag_id IN (?)", inGroup).
+		Group("customer_id").
+		Having("COUNT(*) > 1").
+		Order("customer_id").
+		Limit(maxGroupConflicts).
+		Pluck("customer_id", &customerIDs).Error
+	return customerIDs, err
+}
+
+// respondGroupConflict rejects a change that would leave customers with
+// several tags of an exclusive group
+func respondGroupConflict(c *gin.Context, customerIDs []uint) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":        "conflict",
+		"code":         "TAG_GROUP_CONFLICT",
+		"message":      i18n.Message(c, "TAG_GROUP_CONFLICT", "Some customers carry more than one tag of this group"),
+		"customer_ids": customerIDs,
+	})
+}
+
+// exclusiveGroupTag returns the tag of an exclusive group that a customer
+// already carries and that would conflict with assigning tag, or nil
+func exclusiveGroupTag(db *gorm.DB, customerID uint, tag models.Tag) (*models.Tag, error) {
+	if tag.GroupID == nil {
+		return nil, nil
+	}
+	var conflicting []models.Tag
+	err := db.Joins("JOIN tag_groups ON tag_groups.id = tags.group_id AND tag_groups.deleted_at IS NULL AND tag_groups.exclusive").
+		Joins("JOIN customer_tags ON customer_tags.tag_id = tags.id AND customer_tags.customer_id = ?", customerID).
+		Where("tags.group_id = ? AND tags.id <> ?", *tag.GroupID, tag.ID).
+		Limit(1).Find(&conflicting).Error
+	if err != nil || len(conflicting) == 0 {
+		return nil, err
+	}
+	return &conflicting[0], nil
+}
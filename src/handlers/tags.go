@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
-	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TagHandler handles tag-related endpoints
@@ -22,25 +25,33 @@ func NewTagHandler(db *gorm.DB) *TagHandler {
 
 // TagCreateRequest represents the request body for creating a tag
 type TagCreateRequest struct {
-	Name  string `json:"name" binding:"required,min=1,max=100"`
-	Color string `json:"color,omitempty"`
+	Name    string `json:"name" binding:"required,min=1,max=100"`
+	Color   string `json:"color,omitempty"`
+	GroupID *uint  `json:"group_id,omitempty"`
 }
 
 // TagUpdateRequest represents the request body for updating a tag
 type TagUpdateRequest struct {
-	Name  string `json:"name,omitempty"`
-	Color string `json:"color,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Color   string `json:"color,omitempty"`
+	GroupID *uint  `json:"group_id,omitempty"` // 0 removes the tag from its group
 }
 
-// ListTags returns all tags
-// GET /admin/tags
+// ListTags returns all tags with their groups, optionally only those of
+// the groups named in tag_group
+// GET /admin/tags?tag_group=industry,region
 func (h *TagHandler) ListTags(c *gin.Context) {
+	db := h.db.WithContext(c).Preload("Group")
+	if value := c.Query("tag_group"); value != "" {
+		db = db.Where("group_id IN (?)", h.db.Model(&models.TagGroup{}).Select("id").Where("name IN ?", strings.Split(value, ",")))
+	}
+
 	var tags []models.Tag
-	if err := h.db.Order("name ASC").Find(&tags).Error; err != nil {
+	if err := db.Order("name ASC").Find(&tags).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch tags",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tags"),
 		})
 		return
 	}
@@ -59,69 +70,97 @@ func (h *TagHandler) CreateTag(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
 
 	// Check uniqueness
 	var existing models.Tag
-	if err := h.db.Where("name = ?", req.Name).First(&existing).Error; err == nil {
+	if err := h.db.WithContext(c).Where("name = ?", req.Name).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{
 			"error":   "conflict",
 			"code":    "TAG_EXISTS",
-			"message": "A tag with this name already exists",
+			"message": i18n.Message(c, "TAG_EXISTS", "A tag with this name already exists"),
 		})
 		return
 	}
 
+	if req.GroupID != nil {
+		if _, ok := h.findGroup(c, *req.GroupID); !ok {
+			return
+		}
+	}
+
 	tag := models.Tag{
-		Name:  req.Name,
-		Color: req.Color,
+		Name:    req.Name,
+		Color:   req.Color,
+		GroupID: req.GroupID,
 	}
 
-	if err := h.db.Create(&tag).Error; err != nil {
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tag).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "tag", tag.ID, models.AuditActionCreate, nil, &tag)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to create tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create tag"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "tag", tag.ID, models.AuditActionCreate, nil, &tag)
-
 	c.JSON(http.StatusCreated, tag)
 }
 
-// UpdateTag updates a tag
-// PUT /admin/tags/:id
+// TagRenameReport describes how a tag update affects the names the tag is
+// found by. It is returned instead of the tag with ?dry_run=true, when
+// nothing is saved.
+type TagRenameReport struct {
+	DryRun     bool              `json:"dry_run"`
+	TagID      uint              `json:"tag_id"`
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Customers  int64             `json:"customers"`            // Customers carrying the tag
+	Aliases    []string          `json:"aliases"`              // Former names that still find the tag
+	Redirected []models.TagAlias `json:"redirected,omitempty"` // Former names of other tags that will find this one instead
+}
+
+// UpdateTag updates a tag. Renaming keeps the old name as an alias of the
+// tag, so reports and links selecting it by name keep working; a former
+// name of another tag it is renamed to finds this tag from then on.
+// PUT /admin/tags/:id?dry_run=true
 func (h *TagHandler) UpdateTag(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid tag ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid tag ID"),
 		})
 		return
 	}
 
 	var tag models.Tag
-	if err := h.db.First(&tag, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&tag, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "TAG_NOT_FOUND",
-				"message": "Tag not found",
+				"message": i18n.Message(c, "TAG_NOT_FOUND", "Tag not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tag"),
 		})
 		return
 	}
@@ -133,7 +172,7 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+			"message": i18n.ValidationMessage(c, err),
 		})
 		return
 	}
@@ -141,11 +180,11 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 	// Check uniqueness if name is being changed
 	if req.Name != "" && req.Name != tag.Name {
 		var existing models.Tag
-		if err := h.db.Where("name = ? AND id != ?", req.Name, id).First(&existing).Error; err == nil {
+		if err := h.db.WithContext(c).Where("name = ? AND id != ?", req.Name, id).First(&existing).Error; err == nil {
 			c.JSON(http.StatusConflict, gin.H{
 				"error":   "conflict",
 				"code":    "TAG_EXISTS",
-				"message": "A tag with this name already exists",
+				"message": i18n.Message(c, "TAG_EXISTS", "A tag with this name already exists"),
 			})
 			return
 		}
@@ -156,21 +195,112 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 		tag.Color = req.Color
 	}
 
-	if err := h.db.Save(&tag).Error; err != nil {
+	// Moving into an exclusive group must not leave a customer with two of
+	// its tags
+	if req.GroupID != nil && *req.GroupID == 0 {
+		tag.GroupID = nil
+	} else if req.GroupID != nil && (tag.GroupID == nil || *tag.GroupID != *req.GroupID) {
+		group, ok := h.findGroup(c, *req.GroupID)
+		if !ok {
+			return
+		}
+		if group.Exclusive {
+			customerIDs, err := exclusiveGroupViolations(h.db.WithContext(c), group.ID, &tag.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "internal_error",
+					"code":    "DATABASE_ERROR",
+					"message": i18n.Message(c, "DATABASE_ERROR", "Failed to check tag group"),
+				})
+				return
+			}
+			if len(customerIDs) > 0 {
+				respondGroupConflict(c, customerIDs)
+				return
+			}
+		}
+		tag.GroupID = req.GroupID
+	}
+
+	if isDryRun(c) {
+		report, err := tagRenameReport(h.db.WithContext(c), oldTag, tag.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"code":    "DATABASE_ERROR",
+				"message": i18n.Message(c, "DATABASE_ERROR", "Failed to check tag references"),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&tag).Error; err != nil {
+			return err
+		}
+		if tag.Name != oldTag.Name {
+			if err := renameTagAliases(tx, tag.ID, oldTag.Name, tag.Name); err != nil {
+				return err
+			}
+		}
+		return logAudit(c, tx, "tag", tag.ID, models.AuditActionUpdate, &oldTag, &tag)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to update tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update tag"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "tag", tag.ID, models.AuditActionUpdate, &oldTag, &tag)
-
 	c.JSON(http.StatusOK, tag)
 }
 
+// renameTagAliases keeps a renamed tag's old name as its alias, taking it
+// over from any tag that had it before, and drops the aliases of the new
+// name, which the tag's name now takes precedence over
+func renameTagAliases(tx *gorm.DB, tagID uint, oldName, newName string) error {
+	if err := tx.Where("name = ?", newName).Delete(&models.TagAlias{}).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tag_id"}),
+	}).Create(&models.TagAlias{TagID: tagID, Name: oldName}).Error
+}
+
+// tagRenameReport returns what giving the tag a new name would change
+func tagRenameReport(db *gorm.DB, tag models.Tag, newName string) (TagRenameReport, error) {
+	report := TagRenameReport{DryRun: true, TagID: tag.ID, From: tag.Name, To: newName, Aliases: []string{}}
+	if err := db.Model(&models.CustomerTag{}).Where("tag_id = ?", tag.ID).Count(&report.Customers).Error; err != nil {
+		return report, err
+	}
+
+	var aliases []models.TagAlias
+	if err := db.Where("tag_id = ? OR name = ?", tag.ID, newName).Order("name").Find(&aliases).Error; err != nil {
+		return report, err
+	}
+	for _, alias := range aliases {
+		switch {
+		case alias.Name == newName && alias.TagID != tag.ID:
+			report.Redirected = append(report.Redirected, alias)
+		case alias.Name != newName:
+			report.Aliases = append(report.Aliases, alias.Name)
+		}
+	}
+	if newName != tag.Name {
+		report.Aliases = append(report.Aliases, tag.Name)
+		sort.Strings(report.Aliases)
+	}
+	return report, nil
+}
+
 // DeleteTag deletes a tag
 // DELETE /admin/tags/:id
 func (h *TagHandler) DeleteTag(c *gin.Context) {
@@ -179,45 +309,51 @@ func (h *TagHandler) DeleteTag(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid tag ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid tag ID"),
 		})
 		return
 	}
 
 	var tag models.Tag
-	if err := h.db.First(&tag, id).Error; err != nil {
+	if err := h.db.WithContext(c).First(&tag, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "TAG_NOT_FOUND",
-				"message": "Tag not found",
+				"message": i18n.Message(c, "TAG_NOT_FOUND", "Tag not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tag"),
 		})
 		return
 	}
 
 	// Remove associations
-	h.db.Model(&tag).Association("Customers").Clear()
+	h.db.WithContext(c).Model(&tag).Association("Customers").Clear()
 
 	// Delete tag
-	if err := h.db.Delete(&tag).Error; err != nil {
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&tag).Error; err != nil {
+			return err
+		}
+		return logAudit(c, tx, "tag", tag.ID, models.AuditActionDelete, &tag, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to delete tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete tag"),
 		})
 		return
 	}
 
-	// Log audit
-	h.logAudit(c, "tag", tag.ID, models.AuditActionDelete, &tag, nil)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Tag deleted successfully",
 	})
@@ -231,7 +367,7 @@ func (h *TagHandler) AssignTagToCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
@@ -241,55 +377,79 @@ func (h *TagHandler) AssignTagToCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid tag ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid tag ID"),
 		})
 		return
 	}
 
 	// Verify customer exists
 	var customer models.Customer
-	if err := h.db.First(&customer, customerID).Error; err != nil {
+	if err := h.db.WithContext(c).First(&customer, customerID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
+	if rejectArchived(c, customer.ArchivedAt) {
+		return
+	}
+
 	// Verify tag exists
 	var tag models.Tag
-	if err := h.db.First(&tag, tagID).Error; err != nil {
+	if err := h.db.WithContext(c).First(&tag, tagID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "TAG_NOT_FOUND",
-				"message": "Tag not found",
+				"message": i18n.Message(c, "TAG_NOT_FOUND", "Tag not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tag"),
+		})
+		return
+	}
+
+	// A customer carries at most one tag of an exclusive group
+	conflicting, err := exclusiveGroupTag(h.db.WithContext(c), customer.ID, tag)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to check tag group"),
+		})
+		return
+	}
+	if conflicting != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           "conflict",
+			"code":            "TAG_GROUP_EXCLUSIVE",
+			"message":         i18n.Message(c, "TAG_GROUP_EXCLUSIVE", "The customer already has a tag from this exclusive group"),
+			"conflicting_tag": conflicting,
 		})
 		return
 	}
 
 	// Add association
-	if err := h.db.Model(&customer).Association("Tags").Append(&tag); err != nil {
+	if err := h.db.WithContext(c).Model(&customer).Association("Tags").Append(&tag); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to assign tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to assign tag"),
 		})
 		return
 	}
@@ -307,7 +467,7 @@ func (h *TagHandler) RemoveTagFromCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid customer ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid customer ID"),
 		})
 		return
 	}
@@ -317,55 +477,59 @@ func (h *TagHandler) RemoveTagFromCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "validation_error",
 			"code":    "INVALID_ID",
-			"message": "Invalid tag ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid tag ID"),
 		})
 		return
 	}
 
 	// Verify customer exists
 	var customer models.Customer
-	if err := h.db.First(&customer, customerID).Error; err != nil {
+	if err := h.db.WithContext(c).First(&customer, customerID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "CUSTOMER_NOT_FOUND",
-				"message": "Customer not found",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch customer",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch customer"),
 		})
 		return
 	}
 
+	if rejectArchived(c, customer.ArchivedAt) {
+		return
+	}
+
 	// Verify tag exists
 	var tag models.Tag
-	if err := h.db.First(&tag, tagID).Error; err != nil {
+	if err := h.db.WithContext(c).First(&tag, tagID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"code":    "TAG_NOT_FOUND",
-				"message": "Tag not found",
+				"message": i18n.Message(c, "TAG_NOT_FOUND", "Tag not found"),
 			})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to fetch tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tag"),
 		})
 		return
 	}
 
 	// Remove association
-	if err := h.db.Model(&customer).Association("Tags").Delete(&tag); err != nil {
+	if err := h.db.WithContext(c).Model(&customer).Association("Tags").Delete(&tag); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"code":    "DATABASE_ERROR",
-			"message": "Failed to remove tag",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to remove tag"),
 		})
 		return
 	}
@@ -375,20 +539,24 @@ func (h *TagHandler) RemoveTagFromCustomer(c *gin.Context) {
 	})
 }
 
-// logAudit creates an audit log entry
-func (h *TagHandler) logAudit(c *gin.Context, resourceType string, resourceID uint, action models.AuditAction, oldValue, newValue interface{}) {
-	user, _ := middleware.GetUserFromContext(c)
-
-	audit := models.AuditLog{
-		ResourceType: resourceType,
-		ResourceID:   resourceID,
-		Action:       action,
-		UserID:       user.ID,
-		UserName:     user.Name,
-		UserRole:     user.Role,
-		IPAddress:    c.ClientIP(),
-		UserAgent:    c.Request.UserAgent(),
+// findGroup loads a tag group, responding with an error when there is none
+func (h *TagHandler) findGroup(c *gin.Context, id uint) (models.TagGroup, bool) {
+	var group models.TagGroup
+	if err := h.db.WithContext(c).First(&group, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "TAG_GROUP_NOT_FOUND",
+				"message": i18n.Message(c, "TAG_GROUP_NOT_FOUND", "Tag group not found"),
+			})
+			return group, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch tag group"),
+		})
+		return group, false
 	}
-
-	h.db.Create(&audit)
+	return group, true
 }
@@ -0,0 +1,468 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/telephony"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxTelephonyCallBody bounds the size of one call webhook
+const maxTelephonyCallBody = 64 << 10
+
+// TelephonyHandler handles the telephony provider's call webhook and the
+// queue of calls it could not match
+type TelephonyHandler struct {
+	db         *gorm.DB
+	verifier   *telephony.Verifier
+	extensions map[string]uint
+}
+
+// NewTelephonyHandler creates a new TelephonyHandler. verifier is nil when
+// the webhook is not configured; extensions maps rep extensions to user IDs.
+func NewTelephonyHandler(db *gorm.DB, verifier *telephony.Verifier, extensions map[string]uint) *TelephonyHandler {
+	return &TelephonyHandler{db: db, verifier: verifier, extensions: extensions}
+}
+
+// TelephonyCallResult reports what a call webhook did: "logged" as an
+// activity, queued as "unmatched", or ignored as a "duplicate" event
+type TelephonyCallResult struct {
+	Result string               `json:"result"`
+	Call   models.TelephonyCall `json:"call"`
+}
+
+// MatchTelephonyCallRequest represents the request body for matching a
+// queued call to a customer or one of its contacts
+type MatchTelephonyCallRequest struct {
+	CustomerID *uint `json:"customer_id,omitempty"`
+	ContactID  *uint `json:"contact_id,omitempty"` // Takes precedence; the customer is the contact's
+}
+
+// callParty is the customer, and contact when known, on the other end of a
+// call
+type callParty struct {
+	CustomerID uint
+	ContactID  *uint
+	AssignedTo *uint // The customer's assigned rep
+}
+
+// telephonyCallListQuery defines the filters and sorting of ListCalls
+var telephonyCallListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("status", "status"),
+		query.Equal("direction", "direction"),
+		query.Equal("activity_id", "activity_id"),
+		query.AtLeast("from", "started_at", query.KindTime),
+		query.AtMost("to", "started_at", query.KindTime),
+	},
+	Sort: query.Sort{Fixed: "started_at DESC, id DESC"},
+}
+
+// IngestCall logs a call the telephony provider reports as a completed call
+// activity on the customer or contact whose phone number matches the other
+// end. Calls without a match are queued as unmatched for matching by hand.
+// Redelivered events are acknowledged without logging the call again.
+// POST /integrations/telephony/calls
+func (h *TelephonyHandler) IngestCall(c *gin.Context) {
+	if h.verifier == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "TELEPHONY_NOT_CONFIGURED",
+			"message": i18n.Message(c, "TELEPHONY_NOT_CONFIGURED", "The telephony webhook is not configured"),
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTelephonyCallBody+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_WEBHOOK_PAYLOAD",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_PAYLOAD", "Invalid webhook payload"),
+		})
+		return
+	}
+	if len(body) > maxTelephonyCallBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "validation_error",
+			"code":    "WEBHOOK_PAYLOAD_TOO_LARGE",
+			"message": i18n.Message(c, "WEBHOOK_PAYLOAD_TOO_LARGE", "Webhook payload is too large"),
+		})
+		return
+	}
+
+	now := time.Now()
+	if err := h.verifier.Verify(c.Request.Header, body, now); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"code":    "INVALID_WEBHOOK_SIGNATURE",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_SIGNATURE", "Invalid webhook signature"),
+		})
+		return
+	}
+	parsed, err := telephony.Parse(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_WEBHOOK_PAYLOAD",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_PAYLOAD", "Invalid webhook payload"),
+		})
+		return
+	}
+	if parsed.StartedAt.IsZero() || parsed.StartedAt.After(now) {
+		parsed.StartedAt = now
+	}
+
+	call := models.TelephonyCall{
+		EventID:         parsed.EventID,
+		Direction:       parsed.Direction,
+		FromNumber:      parsed.From,
+		ToNumber:        parsed.To,
+		ExternalNumber:  parsed.ExternalNumber(),
+		Extension:       parsed.Extension,
+		DurationSeconds: parsed.DurationSeconds,
+		RecordingURL:    parsed.RecordingURL,
+		StartedAt:       parsed.StartedAt,
+		Status:          models.TelephonyCallUnmatched,
+	}
+
+	result := "unmatched"
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		create := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&call)
+		if create.Error != nil {
+			return create.Error
+		}
+		if create.RowsAffected == 0 {
+			result = "duplicate"
+			return tx.Where("event_id = ?", call.EventID).First(&call).Error
+		}
+
+		party, err := findCallParty(tx, call.ExternalNumber)
+		if err != nil || party == nil {
+			return err
+		}
+		activity, err := logCallActivity(tx, &call, *party, h.callAssignee(call, *party))
+		if err != nil {
+			return err
+		}
+		result = "logged"
+
+		audit := models.AuditLog{
+			ResourceType: "activity",
+			ResourceID:   activity.ID,
+			Action:       models.AuditActionCreate,
+			UserName:     "system:telephony",
+			UserRole:     "system",
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+		}
+		audit.OldValues, audit.NewValues = models.AuditDiff(nil, activity)
+		return audittrail.Record(c, tx, &audit)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to log call"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TelephonyCallResult{Result: result, Call: call})
+}
+
+// ListCalls returns the calls reported by the telephony provider, latest
+// first; status=unmatched lists the queue waiting to be matched by hand
+// GET /admin/telephony/calls?status=&direction=&activity_id=&from=&to=
+func (h *TelephonyHandler) ListCalls(c *gin.Context) {
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db, _ := telephonyCallListQuery.Apply(h.db.WithContext(c).Model(&models.TelephonyCall{}), c.Request.URL.Query())
+
+	var total int64
+	db.Count(&total)
+
+	var calls []models.TelephonyCall
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&calls).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch calls"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.TelephonyCallListResponse{
+		Data:       calls,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
+
+// MatchCall logs an unmatched call as a completed call activity on the
+// given customer or contact, taking it off the queue
+// POST /admin/telephony/calls/:id/match
+func (h *TelephonyHandler) MatchCall(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid call ID"),
+		})
+		return
+	}
+
+	var req MatchTelephonyCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if req.CustomerID == nil && req.ContactID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "MISSING_CALL_PARTY",
+			"message": i18n.Message(c, "MISSING_CALL_PARTY", "A customer or contact is required to match a call"),
+		})
+		return
+	}
+
+	var call models.TelephonyCall
+	if err := h.db.WithContext(c).First(&call, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "TELEPHONY_CALL_NOT_FOUND",
+				"message": i18n.Message(c, "TELEPHONY_CALL_NOT_FOUND", "Call not found"),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch call"),
+		})
+		return
+	}
+	if call.Status != models.TelephonyCallUnmatched {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "CALL_ALREADY_LOGGED",
+			"message": i18n.Message(c, "CALL_ALREADY_LOGGED", "Call has already been logged as an activity"),
+		})
+		return
+	}
+
+	var party callParty
+	if req.ContactID != nil {
+		var contact models.Contact
+		if err := h.db.WithContext(c).Preload("Customer").First(&contact, *req.ContactID).Error; err != nil || contact.Customer.ID == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CONTACT_NOT_FOUND",
+				"message": i18n.Message(c, "CONTACT_NOT_FOUND", "Contact not found"),
+			})
+			return
+		}
+		party = callParty{CustomerID: contact.CustomerID, ContactID: &contact.ID, AssignedTo: contact.Customer.AssignedTo}
+	} else {
+		var customer models.Customer
+		if err := h.db.WithContext(c).First(&customer, *req.CustomerID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "CUSTOMER_NOT_FOUND",
+				"message": i18n.Message(c, "CUSTOMER_NOT_FOUND", "Customer not found"),
+			})
+			return
+		}
+		party = callParty{CustomerID: customer.ID, AssignedTo: customer.AssignedTo}
+	}
+
+	oldCall := call
+	var activity *models.Activity
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Claim the call so concurrent matches log it once
+		result := tx.Model(&models.TelephonyCall{}).Where("id = ? AND status = ?", call.ID, models.TelephonyCallUnmatched).
+			Update("updated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCallAlreadyLogged
+		}
+
+		var err error
+		if activity, err = logCallActivity(tx, &call, party, h.callAssignee(call, party)); err != nil {
+			return err
+		}
+
+		// Log audit
+		if err := logAudit(c, tx, "activity", activity.ID, models.AuditActionCreate, nil, activity); err != nil {
+			return err
+		}
+		return logAudit(c, tx, "telephony_call", call.ID, models.AuditActionUpdate, &oldCall, &call)
+	})
+	if errors.Is(err, errCallAlreadyLogged) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"code":    "CALL_ALREADY_LOGGED",
+			"message": i18n.Message(c, "CALL_ALREADY_LOGGED", "Call has already been logged as an activity"),
+		})
+		return
+	}
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to log call"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TelephonyCallResult{Result: "logged", Call: call})
+}
+
+// errCallAlreadyLogged aborts matching a call another request logged first
+var errCallAlreadyLogged = errors.New("call already logged")
+
+// callAssignee returns the rep a call activity is assigned to: the rep
+// whose extension placed an outbound call, or the customer's assigned rep
+func (h *TelephonyHandler) callAssignee(call models.TelephonyCall, party callParty) *uint {
+	if call.Direction == models.CallOutbound {
+		if userID, ok := h.extensions[call.Extension]; ok && call.Extension != "" {
+			return &userID
+		}
+	}
+	return party.AssignedTo
+}
+
+// findCallParty returns the contact, or customer otherwise, whose phone
+// number matches a call's external number, or nil when there is none. When
+// several match, a primary contact and then the most recently updated
+// record wins.
+func findCallParty(tx *gorm.DB, number string) (*callParty, error) {
+	key := models.PhoneKey(number)
+	if key == "" {
+		return nil, nil
+	}
+
+	var contacts []models.Contact
+	if err := tx.Joins("Customer").
+		Where(models.PhoneKeySQL("contacts.phone")+" = ?", key).
+		Order("contacts.is_primary DESC, contacts.updated_at DESC, contacts.id DESC").
+		Limit(1).Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	if len(contacts) > 0 && contacts[0].Customer.ID != 0 {
+		contact := contacts[0]
+		return &callParty{CustomerID: contact.CustomerID, ContactID: &contact.ID, AssignedTo: contact.Customer.AssignedTo}, nil
+	}
+
+	var customers []models.Customer
+	if err := tx.Where(models.PhoneKeySQL("phone")+" = ?", key).
+		Order("updated_at DESC, id DESC").
+		Limit(1).Find(&customers).Error; err != nil {
+		return nil, err
+	}
+	if len(customers) == 0 {
+		return nil, nil
+	}
+	return &callParty{CustomerID: customers[0].ID, AssignedTo: customers[0].AssignedTo}, nil
+}
+
+// logCallActivity creates the completed call activity for a call, with its
+// recording URL in the description, marks the customer contacted and links
+// the call to the activity
+func logCallActivity(tx *gorm.DB, call *models.TelephonyCall, party callParty, assignee *uint) (*models.Activity, error) {
+	startedAt := call.StartedAt
+	completedAt := startedAt.Add(time.Duration(call.DurationSeconds) * time.Second)
+
+	title := fmt.Sprintf("Inbound call from %s", call.ExternalNumber)
+	if call.Direction == models.CallOutbound {
+		title = fmt.Sprintf("Outbound call to %s", call.ExternalNumber)
+	}
+	outcome := "Connected"
+	if call.DurationSeconds == 0 {
+		outcome = "No answer"
+	}
+	var description string
+	if call.RecordingURL != "" {
+		description = "Recording: " + call.RecordingURL
+	}
+
+	customerID := party.CustomerID
+	activity := models.Activity{
+		Title:       title,
+		Description: description,
+		Type:        models.ActivityTypeCall,
+		Status:      models.ActivityStatusCompleted,
+		CustomerID:  &customerID,
+		ContactID:   party.ContactID,
+		AssignedTo:  assignee,
+		DueDate:     &startedAt,
+		CompletedAt: &completedAt,
+		Duration:    (call.DurationSeconds + 59) / 60,
+		Outcome:     outcome,
+		Priority:    "normal",
+	}
+	// Logged calls take the outcome code whose keywords match, e.g. a
+	// no_answer outcome with the keyword "no answer"
+	taxonomy, err := loadOutcomeTaxonomy(tx.Where("type = ?", models.ActivityTypeCall))
+	if err != nil {
+		return nil, err
+	}
+	if codes := models.ClassifyOutcome(taxonomy[models.ActivityTypeCall], outcome); len(codes) == 1 {
+		activity.OutcomeCode = codes[0]
+	}
+	if err := tx.Create(&activity).Error; err != nil {
+		return nil, err
+	}
+	if err := markCustomerContacted(tx, &activity, nil); err != nil {
+		return nil, err
+	}
+
+	call.Status = models.TelephonyCallLogged
+	call.ActivityID = &activity.ID
+	if err := tx.Model(call).Updates(map[string]interface{}{
+		"status":      call.Status,
+		"activity_id": activity.ID,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &activity, nil
+}
+
+// loadActivityCall returns the telephony call a call activity was logged
+// from, or nil when it was logged by hand
+func loadActivityCall(db *gorm.DB, activityID uint) *models.TelephonyCall {
+	var calls []models.TelephonyCall
+	db.Where("activity_id = ?", activityID).Limit(1).Find(&calls)
+	if len(calls) == 0 {
+		return nil
+	}
+	return &calls[0]
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler handles record quota usage endpoints
+type UsageHandler struct {
+	quotas *quota.Tracker
+}
+
+// NewUsageHandler creates a new UsageHandler
+func NewUsageHandler(quotas *quota.Tracker) *UsageHandler {
+	return &UsageHandler{quotas: quotas}
+}
+
+// GetUsage returns record usage and quota limits per entity type
+// GET /admin/usage
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, h.quotas.Usage())
+}
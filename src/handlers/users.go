@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// topEndpointsPerUser is the number of most-used endpoints returned per user
+const topEndpointsPerUser = 5
+
+// UserActivityHandler handles user activity (last-seen) endpoints
+type UserActivityHandler struct {
+	db *gorm.DB
+}
+
+// NewUserActivityHandler creates a new UserActivityHandler
+func NewUserActivityHandler(db *gorm.DB) *UserActivityHandler {
+	return &UserActivityHandler{db: db}
+}
+
+// ListUserActivity returns last seen, today's request count, and most-used
+// endpoints for every user with recorded activity
+// GET /admin/users/activity
+func (h *UserActivityHandler) ListUserActivity(c *gin.Context) {
+	windowDays, _ := strconv.Atoi(c.DefaultQuery("window_days", "7"))
+	if windowDays < 1 || windowDays > 90 {
+		windowDays = 7
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	windowStart := time.Now().UTC().AddDate(0, 0, -(windowDays - 1)).Format("2006-01-02")
+
+	var summaries []models.UserActivitySummary
+	if err := h.db.WithContext(c).Model(&models.UserActivity{}).
+		Select("user_id, MAX(last_seen_at) as last_seen_at, COALESCE(SUM(CASE WHEN bucket_date = ? THEN request_count ELSE 0 END), 0) as requests_today", today).
+		Group("user_id").
+		Order("last_seen_at DESC").
+		Scan(&summaries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch user activity"),
+		})
+		return
+	}
+
+	var usage []struct {
+		UserID   uint
+		Endpoint string
+		Count    int64
+	}
+	if err := h.db.WithContext(c).Model(&models.UserActivity{}).
+		Select("user_id, endpoint, SUM(request_count) as count").
+		Where("bucket_date >= ?", windowStart).
+		Group("user_id, endpoint").
+		Order("user_id, count DESC").
+		Scan(&usage).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch endpoint usage"),
+		})
+		return
+	}
+
+	topEndpoints := make(map[uint][]models.EndpointUsage)
+	for _, u := range usage {
+		if len(topEndpoints[u.UserID]) < topEndpointsPerUser {
+			topEndpoints[u.UserID] = append(topEndpoints[u.UserID], models.EndpointUsage{
+				Endpoint: u.Endpoint,
+				Count:    u.Count,
+			})
+		}
+	}
+
+	for i := range summaries {
+		summaries[i].TopEndpoints = topEndpoints[summaries[i].UserID]
+		if summaries[i].TopEndpoints == nil {
+			summaries[i].TopEndpoints = []models.EndpointUsage{}
+		}
+	}
+	if summaries == nil {
+		summaries = []models.UserActivitySummary{}
+	}
+
+	c.JSON(http.StatusOK, models.UserActivityListResponse{
+		Data:       summaries,
+		WindowDays: windowDays,
+	})
+}
+
+// UserUnavailabilityHandler handles rep unavailability windows used by lead assignment
+type UserUnavailabilityHandler struct {
+	db *gorm.DB
+}
+
+// NewUserUnavailabilityHandler creates a new UserUnavailabilityHandler
+func NewUserUnavailabilityHandler(db *gorm.DB) *UserUnavailabilityHandler {
+	return &UserUnavailabilityHandler{db: db}
+}
+
+// UserUnavailabilityRequest represents the request body for adding an unavailability window
+type UserUnavailabilityRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Reason   string    `json:"reason,omitempty" binding:"max=255"`
+}
+
+// ListUserUnavailability returns a user's current and upcoming unavailability windows
+// GET /admin/users/:id/unavailability
+func (h *UserUnavailabilityHandler) ListUserUnavailability(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid user ID"),
+		})
+		return
+	}
+
+	var windows []models.UserUnavailability
+	if err := h.db.WithContext(c).
+		Where("user_id = ? AND ends_at > ?", userID, time.Now()).
+		Order("starts_at ASC").
+		Find(&windows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch unavailability"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": windows})
+}
+
+// CreateUserUnavailability adds an unavailability window for a user
+// POST /admin/users/:id/unavailability
+func (h *UserUnavailabilityHandler) CreateUserUnavailability(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid user ID"),
+		})
+		return
+	}
+
+	var req UserUnavailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_DATE_RANGE",
+			"message": i18n.Message(c, "INVALID_DATE_RANGE", "ends_at must be after starts_at"),
+		})
+		return
+	}
+
+	window := models.UserUnavailability{
+		UserID:   uint(userID),
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Reason:   req.Reason,
+	}
+	if err := h.db.WithContext(c).Create(&window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create unavailability"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+// DeleteUserUnavailability removes an unavailability window
+// DELETE /admin/users/:id/unavailability/:windowId
+func (h *UserUnavailabilityHandler) DeleteUserUnavailability(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid user ID"),
+		})
+		return
+	}
+	windowID, err := strconv.ParseUint(c.Param("windowId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid unavailability ID"),
+		})
+		return
+	}
+
+	result := h.db.WithContext(c).Where("id = ? AND user_id = ?", windowID, userID).Delete(&models.UserUnavailability{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete unavailability"),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"code":    "UNAVAILABILITY_NOT_FOUND",
+			"message": i18n.Message(c, "UNAVAILABILITY_NOT_FOUND", "Unavailability window not found"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Unavailability deleted successfully",
+	})
+}
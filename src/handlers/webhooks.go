@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/webhooks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler handles webhook subscriptions and their deliveries
+type WebhookHandler struct {
+	db         *gorm.DB
+	dispatcher *webhooks.Dispatcher
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(db *gorm.DB, dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{db: db, dispatcher: dispatcher}
+}
+
+// WebhookSubscriptionRequest represents the request body for creating or
+// updating a webhook subscription
+type WebhookSubscriptionRequest struct {
+	Name       string             `json:"name" binding:"required,max=100"`
+	URL        string             `json:"url" binding:"required,url"`
+	Events     []string           `json:"events" binding:"required,min=1"`
+	Filter     string             `json:"filter" binding:"max=1000"` // See webhooks.Filter; empty sends every event
+	Mode       models.WebhookMode `json:"mode"`                      // Defaults to at_least_once
+	BulkEvents bool               `json:"bulk_events"`               // Take the events of records changed by bulk operations
+	Active     *bool              `json:"active"`                    // Defaults to true
+}
+
+// WebhookTestRequest represents the request body for test-firing a
+// subscription with a sample event
+type WebhookTestRequest struct {
+	Event    string                 `json:"event" binding:"required"`
+	Data     map[string]interface{} `json:"data" binding:"required"` // The sample record
+	Previous map[string]interface{} `json:"previous"`                // Values of the changed fields before the update
+	Send     bool                   `json:"send"`                    // Post the event to the subscription when it matches
+}
+
+// WebhookTestResponse is the outcome of test-firing a subscription
+type WebhookTestResponse struct {
+	Subscribed bool                    `json:"subscribed"` // The subscription subscribes to the event
+	Matched    bool                    `json:"matched"`    // The sample matches its filter
+	Delivery   *models.WebhookDelivery `json:"delivery,omitempty"`
+}
+
+// WebhookContractResponse describes how subscriptions are delivered to
+type WebhookContractResponse struct {
+	Events           []string                      `json:"events"`
+	Modes            map[models.WebhookMode]string `json:"modes"` // How each subscription mode handles failed deliveries
+	DefaultMode      models.WebhookMode            `json:"default_mode"`
+	EventHeader      string                        `json:"event_header"`
+	EventIDHeader    string                        `json:"event_id_header"`    // The event's ID, the same on every retry
+	DeliveryIDHeader string                        `json:"delivery_id_header"` // New on every attempt
+	TimestampHeader  string                        `json:"timestamp_header"`
+	SignatureHeader  string                        `json:"signature_header"`
+	Signature        string                        `json:"signature"`
+	Filters          string                        `json:"filters"`
+	Bulk             string                        `json:"bulk"`
+	Deliveries       string                        `json:"deliveries"` // Where attempts are listed, by event ID
+}
+
+// webhookContract is the delivery contract of every subscription
+var webhookContract = WebhookContractResponse{
+	Events: models.WebhookEvents,
+	Modes: map[models.WebhookMode]string{
+		models.WebhookModeAtLeastOnce: "Deliveries that fail are retried with a doubling delay for a day until a 2xx response, then moved to the dead-letter queue; a retry has the same event ID and a new delivery ID",
+		models.WebhookModeAtMostOnce:  "Each event is sent once; when the response is not 2xx, or the attempt is cut short, the delivery is marked failed and not retried",
+	},
+	DefaultMode:      models.WebhookModeAtLeastOnce,
+	EventHeader:      webhooks.EventHeader,
+	EventIDHeader:    webhooks.EventIDHeader,
+	DeliveryIDHeader: webhooks.DeliveryIDHeader,
+	TimestampHeader:  webhooks.TimestampHeader,
+	SignatureHeader:  webhooks.SignatureHeader,
+	Signature:        "Hex HMAC-SHA256 of \"<timestamp>.<body>\" with the subscription's secret",
+	Filters:          "Events that do not match the subscription's filter are recorded as skipped and not sent",
+	Bulk:             "Events of records changed by imports and other bulk operations are recorded as suppressed, unless the subscription has bulk_events, and not sent or counted; each operation raises one bulk.completed event with its counts",
+	Deliveries:       "/admin/webhooks/{id}/deliveries?event_id={event_id}",
+}
+
+// webhookDeliveryListQuery defines the filters and sorting of
+// ListWebhookDeliveries
+var webhookDeliveryListQuery = query.Definition{
+	Filters: []query.Filter{
+		query.Equal("event_id", "event_id"),
+		query.Equal("event_type", "event_type"),
+		query.Equal("status", "status"),
+	},
+	Sort: query.Sort{Fixed: "id DESC"},
+}
+
+// ListWebhookSubscriptions returns the subscriptions with their delivery
+// counts
+// GET /admin/webhooks
+func (h *WebhookHandler) ListWebhookSubscriptions(c *gin.Context) {
+	var subscriptions []models.WebhookSubscription
+	if err := h.db.WithContext(c).Order("id").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch webhook subscriptions"),
+		})
+		return
+	}
+
+	stats, err := h.stats(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch webhook subscriptions"),
+		})
+		return
+	}
+
+	response := models.WebhookSubscriptionListResponse{Data: make([]models.WebhookSubscriptionResponse, len(subscriptions))}
+	for i, sub := range subscriptions {
+		response.Data[i] = models.WebhookSubscriptionResponse{WebhookSubscription: sub, Stats: stats[sub.ID]}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWebhookSubscription returns a subscription with its delivery counts
+// GET /admin/webhooks/:id
+func (h *WebhookHandler) GetWebhookSubscription(c *gin.Context) {
+	sub, ok := h.findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.stats(c, sub.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch webhook subscription"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WebhookSubscriptionResponse{WebhookSubscription: *sub, Stats: stats[sub.ID]})
+}
+
+// CreateWebhookSubscription subscribes a URL to events. The secret that
+// signs its deliveries is generated and only returned here.
+// POST /admin/webhooks
+func (h *WebhookHandler) CreateWebhookSubscription(c *gin.Context) {
+	var req WebhookSubscriptionRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "INTERNAL_ERROR",
+			"message": i18n.Message(c, "INTERNAL_ERROR", "Failed to generate webhook secret"),
+		})
+		return
+	}
+	sub := models.WebhookSubscription{Secret: secret}
+	applyWebhookSubscriptionRequest(&sub, req)
+
+	err = h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sub).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "webhook_subscription", sub.ID, models.AuditActionCreate, nil, &sub)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to create webhook subscription"),
+		})
+		return
+	}
+	h.reloadSubscriptions(c)
+
+	c.JSON(http.StatusCreated, models.WebhookSubscriptionCreateResponse{WebhookSubscription: sub, Secret: sub.Secret})
+}
+
+// UpdateWebhookSubscription replaces a subscription, keeping its secret.
+// Deliveries already queued are sent as they are.
+// PUT /admin/webhooks/:id
+func (h *WebhookHandler) UpdateWebhookSubscription(c *gin.Context) {
+	sub, ok := h.findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+	oldSub := *sub
+
+	var req WebhookSubscriptionRequest
+	if !h.bind(c, &req) {
+		return
+	}
+	applyWebhookSubscriptionRequest(sub, req)
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(sub).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "webhook_subscription", sub.ID, models.AuditActionUpdate, &oldSub, sub)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to update webhook subscription"),
+		})
+		return
+	}
+	h.reloadSubscriptions(c)
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhookSubscription removes a subscription with its deliveries
+// DELETE /admin/webhooks/:id
+func (h *WebhookHandler) DeleteWebhookSubscription(c *gin.Context) {
+	sub, ok := h.findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(sub).Error; err != nil {
+			return err
+		}
+
+		// Log audit
+		return logAudit(c, tx, "webhook_subscription", sub.ID, models.AuditActionDelete, sub, nil)
+	})
+	if err != nil {
+		if respondAuditFailure(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to delete webhook subscription"),
+		})
+		return
+	}
+	h.reloadSubscriptions(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook subscription deleted successfully",
+	})
+}
+
+// ListWebhookDeliveries returns a subscription's deliveries, newest first,
+// with the start of each latest response
+// GET /admin/webhooks/:id/deliveries?event_id=&event_type=&status=
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	sub, ok := h.findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+	page := query.ParsePage(c.Request.URL.Query())
+
+	db, _ := webhookDeliveryListQuery.Apply(h.db.WithContext(c).Model(&models.WebhookDelivery{}).Where("subscription_id = ?", sub.ID), c.Request.URL.Query())
+
+	var total int64
+	db.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	if err := db.Offset(page.Offset()).Limit(page.PageSize).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch webhook deliveries"),
+		})
+		return
+	}
+
+	setPageHeaders(c, total, "")
+	c.JSON(http.StatusOK, models.WebhookDeliveryListResponse{
+		Data:       deliveries,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages(total),
+	})
+}
+
+// TestWebhookSubscription evaluates a subscription against a sample event
+// and, when send is set and it matches, posts it to the subscription as a
+// real delivery would be, returning the response. Nothing is stored.
+// POST /admin/webhooks/:id/test
+func (h *WebhookHandler) TestWebhookSubscription(c *gin.Context) {
+	sub, ok := h.findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+
+	var req WebhookTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return
+	}
+	if !slices.Contains(models.WebhookEvents, req.Event) {
+		invalidWebhookEvent(c)
+		return
+	}
+
+	// Filters are validated when saved, so this only fails for
+	// subscriptions saved before a grammar change
+	filter, err := webhooks.ParseFilter(sub.Filter)
+	if err != nil {
+		invalidWebhookFilter(c, err)
+		return
+	}
+
+	event := webhooks.Event{Event: req.Event, EventID: uuid.NewString(), OccurredAt: time.Now(), Data: req.Data, Previous: req.Previous}
+	response := WebhookTestResponse{
+		Subscribed: slices.Contains(sub.Events, req.Event),
+		Matched:    filter.Match(event.Values()),
+	}
+	if req.Send && response.Matched {
+		// The sample was decoded from JSON, so it encodes
+		payload, _ := json.Marshal(event)
+		response.Delivery = &models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        event.EventID,
+			EventType:      event.Event,
+			DeliveryID:     uuid.NewString(),
+			Status:         models.WebhookDeliveryDelivered,
+			Attempts:       1,
+			Payload:        string(payload),
+			CreatedAt:      event.OccurredAt,
+		}
+		if err := h.dispatcher.Send(c, *sub, response.Delivery); err != nil {
+			response.Delivery.Status = models.WebhookDeliveryFailed
+		}
+		now := time.Now()
+		response.Delivery.CompletedAt = &now
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// bind parses the request body, writing the error response when it is
+// invalid, names an unknown event, or has a filter that does not parse
+func (h *WebhookHandler) bind(c *gin.Context, req *WebhookSubscriptionRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_REQUEST",
+			"message": i18n.ValidationMessage(c, err),
+		})
+		return false
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_WEBHOOK_URL",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_URL", "The webhook URL must be an http or https URL"),
+		})
+		return false
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			invalidWebhookEvent(c)
+			return false
+		}
+	}
+	if _, err := webhooks.ParseFilter(req.Filter); err != nil {
+		invalidWebhookFilter(c, err)
+		return false
+	}
+	if req.Mode != "" && !slices.Contains(models.WebhookModes, req.Mode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_WEBHOOK_MODE",
+			"message": i18n.Message(c, "INVALID_WEBHOOK_MODE", "The webhook mode must be at_least_once or at_most_once"),
+		})
+		return false
+	}
+	return true
+}
+
+// invalidWebhookEvent responds with 400 for an unknown event type
+func invalidWebhookEvent(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "validation_error",
+		"code":    "INVALID_WEBHOOK_EVENT",
+		"message": i18n.Message(c, "INVALID_WEBHOOK_EVENT", "Unknown webhook event; see GET /admin/meta/webhooks for the events"),
+	})
+}
+
+// invalidWebhookFilter responds with 400 for a filter that does not parse,
+// saying where it fails in English
+func invalidWebhookFilter(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "validation_error",
+		"code":    "INVALID_WEBHOOK_FILTER",
+		"message": i18n.Message(c, "INVALID_WEBHOOK_FILTER", err.Error()),
+	})
+}
+
+// applyWebhookSubscriptionRequest copies request fields onto a
+// subscription, dropping repeated events
+func applyWebhookSubscriptionRequest(sub *models.WebhookSubscription, req WebhookSubscriptionRequest) {
+	events := slices.Clone(req.Events)
+	slices.Sort(events)
+	sub.Name = req.Name
+	sub.URL = req.URL
+	sub.Events = slices.Compact(events)
+	sub.Filter = req.Filter
+	sub.Mode = req.Mode
+	sub.BulkEvents = req.BulkEvents
+	if sub.Mode == "" {
+		sub.Mode = models.WebhookModeAtLeastOnce
+	}
+	sub.Active = req.Active == nil || *req.Active
+}
+
+// stats counts deliveries by subscription and status, for the given
+// subscriptions or all of them
+func (h *WebhookHandler) stats(c *gin.Context, ids ...uint) (map[uint]models.WebhookDeliveryStats, error) {
+	var rows []struct {
+		SubscriptionID uint
+		Status         models.WebhookDeliveryStatus
+		Count          int64
+	}
+	db := h.db.WithContext(c).Model(&models.WebhookDelivery{})
+	if len(ids) > 0 {
+		db = db.Where("subscription_id IN ?", ids)
+	}
+	if err := db.Select("subscription_id, status, COUNT(*) AS count").Group("subscription_id, status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uint]models.WebhookDeliveryStats)
+	for _, row := range rows {
+		s := stats[row.SubscriptionID]
+		s.Add(row.Status, row.Count)
+		stats[row.SubscriptionID] = s
+	}
+	return stats, nil
+}
+
+// reloadSubscriptions applies a subscription change to the events queued
+// from now on. A failure leaves the previous subscriptions in effect until
+// the next delivery run.
+func (h *WebhookHandler) reloadSubscriptions(c *gin.Context) {
+	if err := h.dispatcher.Reload(c); err != nil {
+		middleware.Logger.Warn("Failed to reload webhook subscriptions: " + err.Error())
+	}
+}
+
+// findWebhookSubscription loads the subscription identified by the :id
+// route parameter, writing the error response when it cannot be found
+func (h *WebhookHandler) findWebhookSubscription(c *gin.Context) (*models.WebhookSubscription, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"code":    "INVALID_ID",
+			"message": i18n.Message(c, "INVALID_ID", "Invalid webhook subscription ID"),
+		})
+		return nil, false
+	}
+
+	var sub models.WebhookSubscription
+	if err := h.db.WithContext(c).First(&sub, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"code":    "WEBHOOK_NOT_FOUND",
+				"message": i18n.Message(c, "WEBHOOK_NOT_FOUND", "Webhook subscription not found"),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"code":    "DATABASE_ERROR",
+			"message": i18n.Message(c, "DATABASE_ERROR", "Failed to fetch webhook subscription"),
+		})
+		return nil, false
+	}
+
+	return &sub, true
+}
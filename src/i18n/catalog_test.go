@@ -0,0 +1,126 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestCatalogsCoverEveryErrorCode checks that both catalogs translate every
+// registered error code, validation message and report label, and register
+// nothing the default catalog does not
+func TestCatalogsCoverEveryErrorCode(t *testing.T) {
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	base := catalogs[DefaultLocale]
+	if len(ErrorCodes()) != len(base.Errors) || len(base.Errors) == 0 {
+		t.Fatalf("%d error codes registered, catalog has %d", len(ErrorCodes()), len(base.Errors))
+	}
+	for _, locale := range SupportedLocales() {
+		catalog := catalogs[locale]
+		for section, keys := range map[string][2]map[string]string{
+			"errors":     {base.Errors, catalog.Errors},
+			"validation": {base.Validation, catalog.Validation},
+			"report":     {base.Report, catalog.Report},
+		} {
+			if missing := missingKeys(keys[0], keys[1]); len(missing) > 0 {
+				t.Errorf("%s %s: missing %v", locale, section, missing)
+			}
+			if extra := missingKeys(keys[1], keys[0]); len(extra) > 0 {
+				t.Errorf("%s %s: not registered in %s: %v", locale, section, DefaultLocale, extra)
+			}
+			for key, message := range keys[1] {
+				if strings.TrimSpace(message) == "" {
+					t.Errorf("%s %s: %s is empty", locale, section, key)
+				}
+			}
+		}
+		for _, code := range ErrorCodes() {
+			if got := MessageFor(locale, code, ""); got == code {
+				t.Errorf("%s: %s has no message", locale, code)
+			}
+		}
+	}
+}
+
+// TestHandlersUseRegisteredCodes checks that every error code the service
+// responds with, as a "code" value or a message lookup, is registered
+func TestHandlersUseRegisteredCodes(t *testing.T) {
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	used := map[string]string{} // Code to the first place it is used
+	record := func(fset *token.FileSet, e ast.Expr) {
+		if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if code, err := strconv.Unquote(lit.Value); err == nil && code != "" {
+				if _, seen := used[code]; !seen {
+					used[code] = fset.Position(lit.Pos()).String()
+				}
+			}
+		}
+	}
+
+	fset := token.NewFileSet()
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "testdata" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				// i18n.Message(c, code, fallback) and MessageFor(locale, code, fallback)
+				name := ""
+				switch fn := n.Fun.(type) {
+				case *ast.SelectorExpr:
+					if pkg, ok := fn.X.(*ast.Ident); ok && pkg.Name == "i18n" {
+						name = fn.Sel.Name
+					}
+				case *ast.Ident:
+					if file.Name.Name == "i18n" {
+						name = fn.Name
+					}
+				}
+				if (name == "Message" || name == "MessageFor") && len(n.Args) == 3 {
+					record(fset, n.Args[1])
+				}
+			case *ast.KeyValueExpr:
+				// "code": "..." in response bodies
+				if key, ok := n.Key.(*ast.BasicLit); ok && key.Value == `"code"` {
+					record(fset, n.Value)
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(used) < 100 {
+		t.Fatalf("found only %d codes; is the source walk broken?", len(used))
+	}
+	registered := ErrorCodes()
+	for code, at := range used {
+		if !slices.Contains(registered, code) {
+			t.Errorf("%s: %s is not registered in the catalogs", at, code)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Supported locales
+const (
+	LocaleEN      = "en"
+	LocaleAR      = "ar"
+	DefaultLocale = LocaleEN
+)
+
+// ContextKeyLocale is the Gin context key holding the negotiated locale
+const ContextKeyLocale = "locale"
+
+// Unicode isolate marks used to keep LTR values readable inside RTL text
+const (
+	leftToRightIsolate  = "\u2066"
+	rightToLeftIsolate  = "\u2067"
+	popDirectionIsolate = "\u2069"
+)
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Catalog holds the translated messages for a single locale
+type Catalog struct {
+	Errors     map[string]string `json:"errors"`
+	Validation map[string]string `json:"validation"`
+	Report     map[string]string `json:"report"`
+}
+
+var catalogs = map[string]*Catalog{}
+
+// Load reads the embedded message catalogs and verifies that every locale
+// covers every error code registered in the default (en) catalog
+func Load() error {
+	loaded := make(map[string]*Catalog)
+	for _, locale := range SupportedLocales() {
+		data, err := localeFS.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			return fmt.Errorf("failed to read %s catalog: %w", locale, err)
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("failed to parse %s catalog: %w", locale, err)
+		}
+		loaded[locale] = &catalog
+	}
+
+	base := loaded[DefaultLocale]
+	for locale, catalog := range loaded {
+		if missing := missingKeys(base.Errors, catalog.Errors); len(missing) > 0 {
+			return fmt.Errorf("%s catalog is missing error codes: %s", locale, strings.Join(missing, ", "))
+		}
+		if missing := missingKeys(base.Validation, catalog.Validation); len(missing) > 0 {
+			return fmt.Errorf("%s catalog is missing validation messages: %s", locale, strings.Join(missing, ", "))
+		}
+		if missing := missingKeys(base.Report, catalog.Report); len(missing) > 0 {
+			return fmt.Errorf("%s catalog is missing report labels: %s", locale, strings.Join(missing, ", "))
+		}
+	}
+
+	catalogs = loaded
+	return registerValidatorFieldNames()
+}
+
+// SupportedLocales returns all locales with a message catalog
+func SupportedLocales() []string {
+	return []string{LocaleEN, LocaleAR}
+}
+
+// ErrorCodes returns every registered error code in sorted order
+func ErrorCodes() []string {
+	base, ok := catalogs[DefaultLocale]
+	if !ok {
+		return nil
+	}
+	codes := make([]string, 0, len(base.Errors))
+	for code := range base.Errors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Negotiate picks the best supported locale from an Accept-Language header,
+// falling back to the default locale
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		// Match on the primary subtag so "ar-SA" resolves to "ar"
+		primary := strings.SplitN(tag, "-", 2)[0]
+		candidates = append(candidates, candidate{locale: primary, q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, cand := range candidates {
+		if cand.q <= 0 {
+			continue
+		}
+		if _, ok := catalogs[cand.locale]; ok {
+			return cand.locale
+		}
+	}
+	return DefaultLocale
+}
+
+// LocaleFromContext returns the locale negotiated for the current request
+func LocaleFromContext(c *gin.Context) string {
+	if locale, ok := c.Get(ContextKeyLocale); ok {
+		if s, ok := locale.(string); ok {
+			return s
+		}
+	}
+	return DefaultLocale
+}
+
+// Message returns the localized message for an error code. The English
+// fallback is kept as-is for the default locale so existing, more specific
+// messages are not replaced.
+func Message(c *gin.Context, code, fallback string) string {
+	return MessageFor(LocaleFromContext(c), code, fallback)
+}
+
+// MessageFor returns the message for an error code in the given locale
+func MessageFor(locale, code, fallback string) string {
+	if locale != DefaultLocale {
+		if catalog, ok := catalogs[locale]; ok {
+			if msg, ok := catalog.Errors[code]; ok {
+				return msg
+			}
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	if catalog, ok := catalogs[DefaultLocale]; ok {
+		if msg, ok := catalog.Errors[code]; ok {
+			return msg
+		}
+	}
+	return code
+}
+
+// IsRTL reports whether a locale is written right-to-left
+func IsRTL(locale string) bool {
+	return locale == LocaleAR
+}
+
+// ReportLabel returns the localized label for a report column key
+func ReportLabel(locale, key string) string {
+	if catalog, ok := catalogs[locale]; ok {
+		if label, ok := catalog.Report[key]; ok {
+			return label
+		}
+	}
+	if catalog, ok := catalogs[DefaultLocale]; ok {
+		if label, ok := catalog.Report[key]; ok {
+			return label
+		}
+	}
+	return key
+}
+
+// CSVHeaders returns localized CSV header labels. For RTL locales each label
+// is wrapped in an RTL isolate so spreadsheet tools keep column order intact.
+func CSVHeaders(locale string, keys []string) []string {
+	headers := make([]string, len(keys))
+	for i, key := range keys {
+		label := ReportLabel(locale, key)
+		if IsRTL(locale) {
+			label = rightToLeftIsolate + label + popDirectionIsolate
+		}
+		headers[i] = label
+	}
+	return headers
+}
+
+// FormatNumber formats a number for CSV output, isolating it as LTR text
+// for RTL locales so signs and separators are not reordered
+func FormatNumber(locale string, value float64) string {
+	formatted := strconv.FormatFloat(value, 'f', 2, 64)
+	if IsRTL(locale) {
+		return leftToRightIsolate + formatted + popDirectionIsolate
+	}
+	return formatted
+}
+
+// FormatDate formats a date for CSV output, isolating it as LTR text for
+// RTL locales
+func FormatDate(locale string, t time.Time) string {
+	formatted := t.Format("2006-01-02")
+	if IsRTL(locale) {
+		return leftToRightIsolate + formatted + popDirectionIsolate
+	}
+	return formatted
+}
+
+// missingKeys returns the keys present in base but absent from other
+func missingKeys(base, other map[string]string) []string {
+	var missing []string
+	for key := range base {
+		if _, ok := other[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
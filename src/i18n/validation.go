@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// registerValidatorFieldNames makes validation errors report JSON field
+// names instead of Go struct field names
+func registerValidatorFieldNames() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected validator engine")
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return nil
+}
+
+// ValidationMessage converts a request binding error into a localized,
+// human-readable message. Non-validation errors (e.g. malformed JSON) keep
+// their original text for the default locale.
+func ValidationMessage(c *gin.Context, err error) string {
+	return ValidationMessageFor(LocaleFromContext(c), err)
+}
+
+// ValidationMessageFor converts a binding error into a message in the given locale
+func ValidationMessageFor(locale string, err error) string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return MessageFor(locale, "INVALID_REQUEST", err.Error())
+	}
+
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog, ok = catalogs[DefaultLocale]
+		if !ok {
+			return err.Error()
+		}
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		template, ok := catalog.Validation[fe.Tag()]
+		if !ok {
+			template = catalog.Validation["default"]
+		}
+		messages = append(messages, strings.NewReplacer(
+			"{field}", fe.Field(),
+			"{param}", fe.Param(),
+		).Replace(template))
+	}
+	return strings.Join(messages, "; ")
+}
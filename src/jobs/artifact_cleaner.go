@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+)
+
+// ArtifactCleaner periodically deletes expired export artifacts
+type ArtifactCleaner struct {
+	manager  *exports.Manager
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewArtifactCleaner creates a new ArtifactCleaner
+func NewArtifactCleaner(manager *exports.Manager, interval time.Duration, onErr func(error)) *ArtifactCleaner {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &ArtifactCleaner{
+		manager:  manager,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background cleanup loop
+func (a *ArtifactCleaner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := a.manager.CleanupExpired(ctx, time.Now()); err != nil {
+					a.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the cleanup loop
+func (a *ArtifactCleaner) Stop() {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+	}
+}
@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// AuditPurger periodically deletes audit log entries older than the
+// retention period. Each purge records a checkpoint with the hash of the
+// last deleted entry so the remaining hash chain can still be verified.
+type AuditPurger struct {
+	db            *gorm.DB
+	retentionDays int
+	interval      time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewAuditPurger creates a new AuditPurger. retentionDays <= 0 disables it.
+func NewAuditPurger(db *gorm.DB, retentionDays int, interval time.Duration, onErr func(error)) *AuditPurger {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &AuditPurger{
+		db:            db,
+		retentionDays: retentionDays,
+		interval:      interval,
+		onErr:         onErr,
+	}
+}
+
+// Start launches the background purge loop
+func (p *AuditPurger) Start() {
+	if p.retentionDays <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := p.Run(ctx); err != nil {
+					p.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the purge loop
+func (p *AuditPurger) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+	}
+}
+
+// Run deletes the oldest audit entries up to the last one created before
+// the cutoff and records a checkpoint. Entries are purged as an ID prefix,
+// so the chain stays contiguous. It returns the number of entries deleted.
+func (p *AuditPurger) Run(ctx context.Context) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -p.retentionDays)
+
+	var purged int64
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var last models.AuditLog
+		err := tx.Select("id", "hash").Where("created_at < ?", cutoff).Order("id DESC").Limit(1).Take(&last).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Keep new entries from linking to rows being purged
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", models.AuditChainLockKey).Error; err != nil {
+			return err
+		}
+		purged, err = models.PurgeAuditLogs(tx, last.ID)
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.AuditCheckpoint{
+			LastAuditID:  last.ID,
+			LastHash:     last.Hash,
+			PurgedCount:  purged,
+			PurgedBefore: cutoff,
+		}).Error
+	})
+	return purged, err
+}
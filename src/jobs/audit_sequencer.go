@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"gorm.io/gorm"
+)
+
+// AuditSequencer periodically moves queued audit entries into the audit
+// log. Changes have their entries moved as they commit; this picks up
+// those left queued by a failed move or a restart.
+type AuditSequencer struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewAuditSequencer creates a new AuditSequencer
+func NewAuditSequencer(db *gorm.DB, interval time.Duration, onErr func(error)) *AuditSequencer {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &AuditSequencer{
+		db:       db,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background loop, moving what is queued right away
+func (s *AuditSequencer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := audittrail.Sequence(ctx, s.db); err != nil && ctx.Err() == nil {
+				s.onErr(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop halts the sequencing loop
+func (s *AuditSequencer) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
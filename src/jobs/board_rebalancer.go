@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// BoardRebalancer periodically renumbers deal board positions in columns
+// where repeated moves have left gaps too small to split further
+type BoardRebalancer struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewBoardRebalancer creates a new BoardRebalancer
+func NewBoardRebalancer(db *gorm.DB, interval time.Duration, onErr func(error)) *BoardRebalancer {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &BoardRebalancer{
+		db:       db,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background rebalance loop
+func (r *BoardRebalancer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Run(ctx); err != nil {
+					r.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the rebalance loop
+func (r *BoardRebalancer) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// Run renumbers every board column, a stage on one owner's board or on
+// the global board, whose smallest gap between neighbors is below the
+// minimum. It returns the stages with a column that was rebalanced.
+func (r *BoardRebalancer) Run(ctx context.Context) ([]models.DealStage, error) {
+	var rebalanced []models.DealStage
+	for _, stage := range models.DealStages() {
+		var columns []struct{ OwnerID *uint }
+		if err := r.db.WithContext(ctx).Model(&models.Deal{}).Where("stage = ?", stage).
+			Distinct("owner_id").Find(&columns).Error; err != nil {
+			return rebalanced, err
+		}
+		stageDone := false
+		for _, column := range columns {
+			done, err := r.rebalanceColumn(ctx, stage, column.OwnerID)
+			if err != nil {
+				return rebalanced, err
+			}
+			stageDone = stageDone || done
+		}
+		if stageDone {
+			rebalanced = append(rebalanced, stage)
+		}
+	}
+	return rebalanced, nil
+}
+
+// rebalanceColumn renumbers one board column in board order if needed. It
+// holds the column's lock, so deals appended meanwhile land below the
+// renumbered ones.
+func (r *BoardRebalancer) rebalanceColumn(ctx context.Context, stage models.DealStage, ownerID *uint) (bool, error) {
+	rebalanced := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := models.LockBoardColumn(tx, stage, ownerID); err != nil {
+			return err
+		}
+		var deals []struct {
+			ID            uint
+			BoardPosition float64
+		}
+		if err := models.BoardColumn(tx.Model(&models.Deal{}), stage, ownerID).
+			Order("board_position ASC, id ASC").
+			Find(&deals).Error; err != nil {
+			return err
+		}
+
+		needed := false
+		for i := 1; i < len(deals); i++ {
+			if deals[i].BoardPosition-deals[i-1].BoardPosition < models.MinBoardPositionGap {
+				needed = true
+				break
+			}
+		}
+		if !needed {
+			return nil
+		}
+
+		for i, deal := range deals {
+			position := float64(i+1) * models.BoardPositionStep
+			if err := tx.Model(&models.Deal{}).Where("id = ?", deal.ID).
+				UpdateColumn("board_position", position).Error; err != nil {
+				return err
+			}
+		}
+		rebalanced = true
+		return nil
+	})
+	return rebalanced, err
+}
@@ -0,0 +1,54 @@
+package jobs_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/jobs"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+)
+
+// TestBoardRebalancerPerColumn checks that only the board column whose
+// gaps are too small is renumbered, keeping its order
+func TestBoardRebalancerPerColumn(t *testing.T) {
+	testDB := testdb.New(t, factory.Epoch)
+	f := factory.New(testDB.DB)
+	customer := f.Customer(t)
+	two, three := uint(2), uint(3)
+	deal := func(owner *uint, position float64) models.Deal {
+		return f.Deal(t, customer, func(d *models.Deal) { d.OwnerID, d.BoardPosition = owner, position })
+	}
+	// Owner 2's column is crowded; owner 3's and the global column interleave
+	// with it but have room
+	crowded := []models.Deal{deal(&two, 1), deal(&two, 1+1e-7), deal(&two, 1+2e-7)}
+	roomy := []models.Deal{deal(&three, 1+5e-8), deal(nil, 1+1.5e-7), deal(&three, 2)}
+
+	stages, err := jobs.NewBoardRebalancer(testDB.DB, 0, nil).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stages, []models.DealStage{models.DealStageProspecting}) {
+		t.Errorf("rebalanced stages = %v", stages)
+	}
+
+	position := func(id uint) float64 {
+		var d models.Deal
+		if err := testDB.DB.First(&d, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return d.BoardPosition
+	}
+	for i, d := range crowded {
+		if got, want := position(d.ID), float64(i+1)*models.BoardPositionStep; got != want {
+			t.Errorf("crowded deal %d: board_position = %v, want %v", d.ID, got, want)
+		}
+	}
+	for _, d := range roomy {
+		if got := position(d.ID); got != d.BoardPosition {
+			t.Errorf("deal %d: board_position = %v, want it unchanged at %v", d.ID, got, d.BoardPosition)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/consistency"
+)
+
+// ConsistencySweeper periodically runs the registered consistency checks
+type ConsistencySweeper struct {
+	runner   *consistency.Runner
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewConsistencySweeper creates a new ConsistencySweeper. interval <= 0 disables it.
+func NewConsistencySweeper(runner *consistency.Runner, interval time.Duration, onErr func(error)) *ConsistencySweeper {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &ConsistencySweeper{
+		runner:   runner,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background sweep loop
+func (s *ConsistencySweeper) Start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				summary, err := s.runner.Run(ctx)
+				if err != nil {
+					s.onErr(err)
+					continue
+				}
+				for _, check := range summary.Checks {
+					if check.Error != "" {
+						s.onErr(&checkError{name: check.Name, message: check.Error})
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the sweep loop
+func (s *ConsistencySweeper) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+// checkError reports a consistency check that failed to run
+type checkError struct {
+	name    string
+	message string
+}
+
+func (e *checkError) Error() string {
+	return "consistency check " + e.name + " failed: " + e.message
+}
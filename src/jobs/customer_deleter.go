@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/deletion"
+)
+
+// CustomerDeleter finishes background customer deletions. It runs at start,
+// resuming deletions interrupted by a restart, whenever a deletion is
+// requested, and on an interval to retry failed ones.
+type CustomerDeleter struct {
+	runner   *deletion.Runner
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewCustomerDeleter creates a new CustomerDeleter. interval <= 0 disables
+// the retries but not the runs on request.
+func NewCustomerDeleter(runner *deletion.Runner, interval time.Duration, onErr func(error)) *CustomerDeleter {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &CustomerDeleter{
+		runner:   runner,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background deletion loop
+func (d *CustomerDeleter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+
+		var retry <-chan time.Time
+		if d.interval > 0 {
+			ticker := time.NewTicker(d.interval)
+			defer ticker.Stop()
+			retry = ticker.C
+		}
+
+		d.run(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-d.runner.Wakeups():
+				d.run(ctx)
+			case <-retry:
+				d.run(ctx)
+			}
+		}
+	}()
+}
+
+// Stop halts the deletion loop. An interrupted deletion resumes on the next start.
+func (d *CustomerDeleter) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+		<-d.done
+	}
+}
+
+// run processes the unfinished deletions
+func (d *CustomerDeleter) run(ctx context.Context) {
+	if _, err := d.runner.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		d.onErr(err)
+	}
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// DealArchiver periodically archives deals that have been closed for longer
+// than the configured number of days
+type DealArchiver struct {
+	db        *gorm.DB
+	afterDays int
+	interval  time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewDealArchiver creates a new DealArchiver. afterDays <= 0 disables it.
+func NewDealArchiver(db *gorm.DB, afterDays int, interval time.Duration, onErr func(error)) *DealArchiver {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &DealArchiver{
+		db:        db,
+		afterDays: afterDays,
+		interval:  interval,
+		onErr:     onErr,
+	}
+}
+
+// Start launches the background archive loop
+func (a *DealArchiver) Start() {
+	if a.afterDays <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := a.Run(ctx); err != nil {
+					a.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the archive loop
+func (a *DealArchiver) Stop() {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+	}
+}
+
+// Run archives closed deals past the cutoff and audits each one. It returns
+// the number of deals archived.
+func (a *DealArchiver) Run(ctx context.Context) (int, error) {
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -a.afterDays)
+
+	var ids []uint
+	if err := a.db.WithContext(ctx).Model(&models.Deal{}).
+		Where("archived_at IS NULL AND stage IN ?", []models.DealStage{models.DealStageClosedWon, models.DealStageClosedLost}).
+		Where("COALESCE(actual_close_date, updated_at) < ?", cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Deal{}).Where("id IN ?", ids).Update("archived_at", now).Error; err != nil {
+			return err
+		}
+
+		newValues, _ := json.Marshal(map[string]time.Time{"archived_at": now})
+		for _, id := range ids {
+			if err := audittrail.Record(ctx, tx, &models.AuditLog{
+				ResourceType: "deal",
+				ResourceID:   id,
+				Action:       models.AuditActionArchive,
+				UserName:     "system:auto-archive",
+				UserRole:     "system",
+				OldValues:    `{"archived_at":null}`,
+				NewValues:    string(newValues),
+				CreatedAt:    now,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
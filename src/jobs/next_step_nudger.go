@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// NextStepNudger periodically reminds deal owners about open deals whose
+// next step is past due or that have gone without a next step for too long.
+// A nudge is a task activity assigned to the owner; each deal is nudged once
+// until its next step or due date changes, or, for a missing next step, once
+// every missingDays.
+type NextStepNudger struct {
+	db          *gorm.DB
+	missingDays int
+	interval    time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewNextStepNudger creates a new NextStepNudger. interval <= 0 disables it;
+// missingDays <= 0 only nudges about overdue next steps.
+func NewNextStepNudger(db *gorm.DB, missingDays int, interval time.Duration, onErr func(error)) *NextStepNudger {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &NextStepNudger{
+		db:          db,
+		missingDays: missingDays,
+		interval:    interval,
+		onErr:       onErr,
+	}
+}
+
+// Start launches the background nudge loop
+func (n *NextStepNudger) Start() {
+	if n.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+	n.done = make(chan struct{})
+
+	go func() {
+		defer close(n.done)
+
+		ticker := time.NewTicker(n.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := n.Run(ctx); err != nil {
+					n.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the nudge loop
+func (n *NextStepNudger) Stop() {
+	if n.cancel != nil {
+		n.cancel()
+		<-n.done
+	}
+}
+
+// Run creates a task for the owner of every deal due a nudge and marks the
+// deal nudged. It returns the number of deals nudged.
+func (n *NextStepNudger) Run(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	stale := n.db.Where("next_step <> '' AND next_step_due < ? AND next_step_nudged_at IS NULL", now)
+	if n.missingDays > 0 {
+		cutoff := now.AddDate(0, 0, -n.missingDays)
+		stale = stale.Or("next_step = '' AND COALESCE(next_step_set_at, created_at) < ? AND (next_step_nudged_at IS NULL OR next_step_nudged_at < ?)", cutoff, cutoff)
+	}
+
+	var deals []models.Deal
+	if err := n.db.WithContext(ctx).
+		Where("archived_at IS NULL AND owner_id IS NOT NULL AND stage NOT IN ?", []models.DealStage{models.DealStageClosedWon, models.DealStageClosedLost}).
+		Where(stale).
+		Find(&deals).Error; err != nil {
+		return 0, err
+	}
+	if len(deals) == 0 {
+		return 0, nil
+	}
+
+	err := n.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, deal := range deals {
+			// An owner who has not acted on the last nudge gets it refreshed
+			// rather than a second task
+			task := nextStepTask(deal, now)
+			var previous models.Activity
+			if err := tx.Where("automation_fingerprint = ?", task.AutomationFingerprint).
+				Where("status NOT IN ?", []models.ActivityStatus{models.ActivityStatusCompleted, models.ActivityStatusCancelled}).
+				Limit(1).Find(&previous).Error; err != nil {
+				return err
+			}
+			path, err := models.CreateAutomatedActivity(tx, &task, true)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Deal{}).Where("id = ?", deal.ID).Update("next_step_nudged_at", now).Error; err != nil {
+				return err
+			}
+
+			audit := models.AuditLog{
+				ResourceType: "activity",
+				ResourceID:   task.ID,
+				Action:       models.AuditActionCreate,
+				UserName:     "system:next-step-nudge",
+				UserRole:     "system",
+				CreatedAt:    now,
+			}
+			if path == models.AutomationUpdated {
+				audit.Action = models.AuditActionUpdate
+				audit.OldValues, audit.NewValues = models.AuditDiff(previous, task)
+			} else {
+				newValues, _ := json.Marshal(task)
+				audit.NewValues = string(newValues)
+			}
+			if err := audittrail.Record(ctx, tx, &audit); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(deals), nil
+}
+
+// nextStepTask builds the reminder task for a deal's owner
+func nextStepTask(deal models.Deal, now time.Time) models.Activity {
+	task := models.Activity{
+		Title:      "Record a next step: " + deal.Title,
+		Type:       models.ActivityTypeTask,
+		Status:     models.ActivityStatusScheduled,
+		CustomerID: &deal.CustomerID,
+		DealID:     &deal.ID,
+		AssignedTo: deal.OwnerID,
+		DueDate:    &now,
+		Priority:   "high",
+	}
+	if deal.NextStep != "" {
+		task.Title = "Next step overdue: " + deal.Title
+		task.Description = deal.NextStep
+	}
+	task.AutomationFingerprint = models.AutomationFingerprint("next-step-nudge", deal.ID, task.Title, now, 0)
+	return task
+}
@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+)
+
+// QuotaReconciler periodically recounts quota usage to correct drift in the
+// incremental counters
+type QuotaReconciler struct {
+	tracker  *quota.Tracker
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewQuotaReconciler creates a new QuotaReconciler. interval <= 0 disables it.
+func NewQuotaReconciler(tracker *quota.Tracker, interval time.Duration, onErr func(error)) *QuotaReconciler {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &QuotaReconciler{
+		tracker:  tracker,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background reconcile loop
+func (r *QuotaReconciler) Start() {
+	if r.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.tracker.Reconcile(ctx); err != nil {
+					r.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the reconcile loop
+func (r *QuotaReconciler) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
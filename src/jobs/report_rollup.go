@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/reportrollup"
+)
+
+// ReportRollup periodically brings the report snapshots up to date
+type ReportRollup struct {
+	runner   *reportrollup.Runner
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewReportRollup creates a new ReportRollup. interval <= 0 disables it.
+func NewReportRollup(runner *reportrollup.Runner, interval time.Duration, onErr func(error)) *ReportRollup {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &ReportRollup{
+		runner:   runner,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background rollup loop
+func (r *ReportRollup) Start() {
+	if r.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Another replica rolling up does the work of this tick
+				if _, err := r.runner.Run(ctx, false); err != nil && !errors.Is(err, reportrollup.ErrRollupInProgress) {
+					r.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the rollup loop
+func (r *ReportRollup) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/roles"
+)
+
+// RoleRefresher periodically reloads role definitions so changes made through
+// other instances take effect here
+type RoleRefresher struct {
+	roles    *roles.Service
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewRoleRefresher creates a new RoleRefresher. interval <= 0 disables it.
+func NewRoleRefresher(service *roles.Service, interval time.Duration, onErr func(error)) *RoleRefresher {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &RoleRefresher{
+		roles:    service,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background refresh loop
+func (r *RoleRefresher) Start() {
+	if r.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.roles.Reload(ctx); err != nil {
+					r.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the refresh loop
+func (r *RoleRefresher) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"gorm.io/gorm"
+)
+
+// SandboxResetter periodically restores the sandbox database to its demo
+// fixtures, discarding whatever sandbox callers changed
+type SandboxResetter struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewSandboxResetter creates a new SandboxResetter for the sandbox database.
+// A nil db or interval <= 0 disables it.
+func NewSandboxResetter(db *gorm.DB, interval time.Duration, onErr func(error)) *SandboxResetter {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &SandboxResetter{
+		db:       db,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background reset loop
+func (r *SandboxResetter) Start() {
+	if r.db == nil || r.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := database.ResetSandbox(ctx, r.db); err != nil {
+					r.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the reset loop
+func (r *SandboxResetter) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
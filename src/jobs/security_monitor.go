@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+)
+
+// SecurityMonitor periodically evaluates the security alert rules against
+// per-user activity
+type SecurityMonitor struct {
+	monitor  *security.Monitor
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewSecurityMonitor creates a new SecurityMonitor. interval <= 0 disables it.
+func NewSecurityMonitor(monitor *security.Monitor, interval time.Duration, onErr func(error)) *SecurityMonitor {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &SecurityMonitor{
+		monitor:  monitor,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background evaluation loop
+func (s *SecurityMonitor) Start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.monitor.Evaluate(ctx); err != nil {
+					s.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the evaluation loop
+func (s *SecurityMonitor) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
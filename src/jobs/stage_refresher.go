@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/stages"
+)
+
+// StageRefresher periodically reloads the pipeline stages so changes made
+// through other instances take effect here
+type StageRefresher struct {
+	stages   *stages.Service
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewStageRefresher creates a new StageRefresher. interval <= 0 disables it.
+func NewStageRefresher(service *stages.Service, interval time.Duration, onErr func(error)) *StageRefresher {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &StageRefresher{
+		stages:   service,
+		interval: interval,
+		onErr:    onErr,
+	}
+}
+
+// Start launches the background refresh loop
+func (r *StageRefresher) Start() {
+	if r.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.stages.Reload(ctx); err != nil {
+					r.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the refresh loop
+func (r *StageRefresher) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/webhooks"
+)
+
+// WebhookDispatcher periodically sends the webhook deliveries that are due
+type WebhookDispatcher struct {
+	dispatcher *webhooks.Dispatcher
+	interval   time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewWebhookDispatcher creates a new WebhookDispatcher. interval <= 0
+// disables it.
+func NewWebhookDispatcher(dispatcher *webhooks.Dispatcher, interval time.Duration, onErr func(error)) *WebhookDispatcher {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &WebhookDispatcher{
+		dispatcher: dispatcher,
+		interval:   interval,
+		onErr:      onErr,
+	}
+}
+
+// Start launches the background delivery loop
+func (w *WebhookDispatcher) Start() {
+	if w.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := w.dispatcher.Run(ctx); err != nil {
+					w.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the delivery loop
+func (w *WebhookDispatcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+		<-w.done
+	}
+}
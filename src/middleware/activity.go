@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"slices"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/SalehAlobaylan/CRM-Service/src/tracking"
+	"github.com/gin-gonic/gin"
+)
+
+// TrackUserActivity records the authenticated user's request in the
+// last-seen tracker. Must run after Authenticate. Sandbox requests are not
+// tracked; the tracker writes to the primary database.
+func TrackUserActivity(tracker *tracking.UserActivityTracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		userID, ok := GetUserIDFromContext(c)
+		if !ok || c.FullPath() == "" || sandbox.Active(c) {
+			return
+		}
+		endpoint := c.Request.Method + " " + c.FullPath()
+		// Downloads asked for with a format are told apart from the
+		// endpoint's other reads
+		if format := c.Query("format"); format != "" && slices.Contains(models.SecurityExportEndpoints, endpoint+"?format="+format) {
+			endpoint += "?format=" + format
+		}
+		tracker.Record(userID, endpoint, time.Now())
+	}
+}
@@ -5,21 +5,15 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-// JWTClaims represents the JWT claims structure
-type JWTClaims struct {
-	UserID uint   `json:"user_id,omitempty"`
-	Sub    string `json:"sub,omitempty"`
-	Email  string `json:"email,omitempty"`
-	Name   string `json:"name,omitempty"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
-}
-
 // Context keys for user information
 const (
 	ContextKeyUser     = "user"
@@ -28,15 +22,23 @@ const (
 	ContextKeyClaims   = "claims"
 )
 
-// ErrorResponse represents a standard error response
+// ErrorResponse represents a standard error response. Refused tokens also
+// name the authentication provider and its reason for refusing them.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Error    string `json:"error"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Provider string `json:"provider,omitempty"`
+	Reason   string `json:"reason,omitempty"`
 }
 
-// JWTAuth creates a JWT authentication middleware
-func JWTAuth(jwtSecret string) gin.HandlerFunc {
+// Authenticate authenticates the bearer token of a request with the first
+// provider of chain that accepts it. Users act with the role of their
+// token; service accounts are rate-limited per account and restricted to
+// their scopes in addition to the permissions of their role.
+func Authenticate(chain auth.Chain, db *gorm.DB, defaultRateLimit int) gin.HandlerFunc {
+	limiter := newFixedWindowLimiter[uint](defaultRateLimit)
+
 	return func(c *gin.Context) {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -44,7 +46,7 @@ func JWTAuth(jwtSecret string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
 				Error:   "unauthorized",
 				Code:    "MISSING_TOKEN",
-				Message: "Authorization header is required",
+				Message: i18n.Message(c, "MISSING_TOKEN", "Authorization header is required"),
 			})
 			return
 		}
@@ -55,87 +57,113 @@ func JWTAuth(jwtSecret string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
 				Error:   "unauthorized",
 				Code:    "INVALID_TOKEN_FORMAT",
-				Message: "Authorization header must be in 'Bearer <token>' format",
+				Message: i18n.Message(c, "INVALID_TOKEN_FORMAT", "Authorization header must be in 'Bearer <token>' format"),
 			})
 			return
 		}
 
-		tokenString := parts[1]
-
-		// Parse and validate token
-		claims := &JWTClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(jwtSecret), nil
-		})
-
+		identity, err := chain.Authenticate(c, parts[1])
 		if err != nil {
-			var message string
-			if errors.Is(err, jwt.ErrTokenExpired) {
-				message = "Token has expired"
-			} else if errors.Is(err, jwt.ErrTokenMalformed) {
-				message = "Token is malformed"
-			} else {
-				message = "Invalid token"
-			}
-
-			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
-				Error:   "unauthorized",
-				Code:    "INVALID_TOKEN",
-				Message: message,
-			})
+			abortInvalidToken(c, err)
 			return
 		}
-
-		if !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
-				Error:   "unauthorized",
-				Code:    "INVALID_TOKEN",
-				Message: "Token is not valid",
-			})
+		if identity.Account != nil {
+			serviceAccountAuth(c, db, limiter, identity)
 			return
 		}
+		userAuth(c, identity)
+	}
+}
 
-		// Extract user ID (could be in 'sub' or 'user_id')
-		userID := claims.UserID
-		if userID == 0 && claims.Sub != "" {
-			// Sub claim might contain user ID as string - we'll just use 0 for now
-			// In production, you might parse this from the sub field
-		}
+// abortInvalidToken rejects a token no provider accepted with
+// INVALID_TOKEN, the provider that refused it and its reason
+func abortInvalidToken(c *gin.Context, err error) {
+	response := ErrorResponse{
+		Error:   "unauthorized",
+		Code:    "INVALID_TOKEN",
+		Message: i18n.Message(c, "INVALID_TOKEN", "Invalid token"),
+	}
+	var refused *auth.Error
+	if errors.As(err, &refused) {
+		response.Message = i18n.Message(c, "INVALID_TOKEN", refused.Message)
+		response.Provider = refused.Provider
+		response.Reason = refused.Reason
+	}
+	if Logger != nil {
+		Logger.Debug("Token refused: " + err.Error())
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+}
 
-		// Validate role is present
-		if claims.Role == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
-				Error:   "unauthorized",
-				Code:    "MISSING_ROLE",
-				Message: "Token must contain a role claim",
-			})
-			return
-		}
+// userAuth admits a user authenticated by a token
+func userAuth(c *gin.Context, identity *auth.Identity) {
+	userID := identity.UserID
+
+	// Tokens issued before a security alert revoked them are rejected;
+	// tokens without an issue time cannot be told apart and are too
+	if revokedAt, revoked := security.TokensRevokedAt(userID); revoked && userID != 0 &&
+		(identity.IssuedAt == nil || !identity.IssuedAt.After(revokedAt)) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Code:    "TOKEN_REVOKED",
+			Message: i18n.Message(c, "TOKEN_REVOKED", "Token has been revoked, please sign in again"),
+		})
+		return
+	}
 
-		// Create user object from claims
-		user := models.User{
-			ID:       userID,
-			Email:    claims.Email,
-			Name:     claims.Name,
-			Role:     claims.Role,
-			IsActive: true,
-		}
+	// Validate role is present
+	if identity.Role == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+			Error:    "unauthorized",
+			Code:     "MISSING_ROLE",
+			Message:  i18n.Message(c, "MISSING_ROLE", "Token must contain a role claim"),
+			Provider: identity.Provider,
+		})
+		return
+	}
 
-		// Store user info in context
-		c.Set(ContextKeyUser, user)
-		c.Set(ContextKeyUserID, userID)
-		c.Set(ContextKeyUserRole, claims.Role)
-		c.Set(ContextKeyClaims, claims)
+	// Reject roles without a definition rather than granting nothing
+	// silently, so a mistyped or deleted role is easy to diagnose
+	if !models.IsKnownRole(identity.Role) {
+		abortUnknownRole(c)
+		return
+	}
 
-		c.Next()
+	if identity.Sandbox && !enterSandbox(c) {
+		return
+	}
+
+	// Create user object from claims
+	user := models.User{
+		ID:       userID,
+		Email:    identity.Email,
+		Name:     identity.Name,
+		Role:     identity.Role,
+		Org:      identity.Org,
+		IsActive: true,
 	}
+
+	// Store user info in context
+	c.Set(ContextKeyUser, user)
+	c.Set(ContextKeyUserID, userID)
+	c.Set(ContextKeyUserRole, identity.Role)
+	c.Set(ContextKeyClaims, identity.Claims)
+	c.Set(redaction.ContextKey, redaction.Viewer{UserID: userID, Role: identity.Role})
+
+	c.Next()
+}
+
+// abortUnknownRole rejects a caller whose role is not defined
+func abortUnknownRole(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+		Error:   "forbidden",
+		Code:    "UNKNOWN_ROLE",
+		Message: i18n.Message(c, "UNKNOWN_ROLE", "Your role is not defined in this CRM"),
+	})
 }
 
-// RequireRole creates middleware that requires specific roles
+// RequireRole creates middleware that requires the permissions of one of
+// the built-in roles, so a custom role holding them passes too
 func RequireRole(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get(ContextKeyUserRole)
@@ -143,14 +171,14 @@ func RequireRole(allowedRoles ...string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
 				Error:   "unauthorized",
 				Code:    "NO_USER_CONTEXT",
-				Message: "User context not found",
+				Message: i18n.Message(c, "NO_USER_CONTEXT", "User context not found"),
 			})
 			return
 		}
 
 		userRole := role.(string)
 		for _, allowed := range allowedRoles {
-			if userRole == allowed {
+			if models.HoldsRole(userRole, allowed) {
 				c.Next()
 				return
 			}
@@ -159,7 +187,7 @@ func RequireRole(allowedRoles ...string) gin.HandlerFunc {
 		c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
 			Error:   "forbidden",
 			Code:    "INSUFFICIENT_PERMISSIONS",
-			Message: "You do not have permission to access this resource",
+			Message: i18n.Message(c, "INSUFFICIENT_PERMISSIONS", "You do not have permission to access this resource"),
 		})
 	}
 }
@@ -172,7 +200,7 @@ func RequirePermission(permission string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
 				Error:   "unauthorized",
 				Code:    "NO_USER_CONTEXT",
-				Message: "User context not found",
+				Message: i18n.Message(c, "NO_USER_CONTEXT", "User context not found"),
 			})
 			return
 		}
@@ -182,7 +210,7 @@ func RequirePermission(permission string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
 				Error:   "forbidden",
 				Code:    "INSUFFICIENT_PERMISSIONS",
-				Message: "You do not have permission to perform this action",
+				Message: i18n.Message(c, "INSUFFICIENT_PERMISSIONS", "You do not have permission to perform this action"),
 			})
 			return
 		}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/SalehAlobaylan/CRM-Service/src/calendarfeed"
+	"github.com/gin-gonic/gin"
+)
+
+// ProviderCalendarFeed names calendar feed tokens in refusals
+const ProviderCalendarFeed = "calendar_feed"
+
+// CalendarFeedAuth authenticates calendar subscriptions by the feed token
+// in the token query parameter, for clients that cannot send an
+// Authorization header. Requests without one are authenticated by
+// authenticate as usual. Feed tokens act as the user and role they were
+// issued to and are refused once the user's tokens are revoked.
+func CalendarFeedAuth(signer *calendarfeed.Signer, authenticate gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			authenticate(c)
+			return
+		}
+
+		feedToken, err := signer.Verify(token)
+		if err != nil {
+			abortInvalidToken(c, &auth.Error{
+				Provider: ProviderCalendarFeed,
+				Reason:   auth.ReasonBadSignature,
+				Message:  "Invalid calendar feed token",
+				Err:      err,
+			})
+			return
+		}
+
+		issuedAt := time.Unix(feedToken.IssuedAt, 0)
+		userAuth(c, &auth.Identity{
+			Provider: ProviderCalendarFeed,
+			UserID:   feedToken.UserID,
+			Role:     feedToken.Role,
+			IssuedAt: &issuedAt,
+		})
+	}
+}
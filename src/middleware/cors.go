@@ -1,39 +1,216 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/publicsuffix"
 )
 
-// CORS creates a CORS middleware with the specified allowed origins
-func CORS(allowedOrigins []string) gin.HandlerFunc {
+// CORSAllowedMethods lists the HTTP methods accepted in preflight requests
+var CORSAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// CORSAllowedHeaders is the single list of request headers accepted in
+// preflight requests. Add new custom headers here so browsers are not
+// silently blocked when a feature starts sending them.
+var CORSAllowedHeaders = []string{
+	"Origin",
+	"Content-Type",
+	"Accept",
+	"Accept-Language",
+	"Authorization",
+	"X-Request-ID",
+	"X-Timezone",
+	"X-Impersonate-User-ID",
+	"Idempotency-Key",
+	"If-Match",
+}
+
+// Pagination headers set by list endpoints alongside the response body
+const (
+	HeaderTotalCount = "X-Total-Count"
+	HeaderNextCursor = "X-Next-Cursor"
+)
+
+// CORSExposedHeaders lists the response headers readable by browser clients
+var CORSExposedHeaders = []string{
+	"Content-Length",
+	"Content-Language",
+	"X-Request-ID",
+	HeaderSyncToken,
+	HeaderTotalCount,
+	HeaderNextCursor,
+	"Retry-After",
+	"ETag",
+}
+
+// originPattern is a parsed wildcard origin such as https://*.example.com
+type originPattern struct {
+	scheme string
+	suffix string // host suffix including the leading dot, e.g. ".example.com"
+	port   string
+}
+
+// CORS creates a CORS middleware with the specified allowed origins.
+// Origins may be exact (https://app.example.com) or wildcard subdomain
+// patterns (https://*.example.com). An empty list or "*" allows all origins,
+// which is refused when credentials are enabled.
+func CORS(allowedOrigins []string, allowCredentials bool) (gin.HandlerFunc, error) {
+	config, err := NewCORSConfig(allowedOrigins, allowCredentials)
+	if err != nil {
+		return nil, err
+	}
+	return cors.New(config), nil
+}
+
+// NewCORSConfig builds and validates the CORS configuration
+func NewCORSConfig(allowedOrigins []string, allowCredentials bool) (cors.Config, error) {
 	config := cors.Config{
-		AllowOrigins:     allowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
-		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
-		AllowCredentials: true,
+		AllowMethods:     CORSAllowedMethods,
+		AllowHeaders:     CORSAllowedHeaders,
+		ExposeHeaders:    CORSExposedHeaders,
+		AllowCredentials: allowCredentials,
 		MaxAge:           12 * time.Hour,
 	}
 
-	// If no origins specified, allow all in development
-	if len(allowedOrigins) == 0 {
+	var exact []string
+	var patterns []originPattern
+	allowAll := false
+	for _, origin := range allowedOrigins {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == "":
+			continue
+		case origin == "*":
+			allowAll = true
+		case strings.Contains(origin, "*"):
+			pattern, err := parseOriginPattern(origin)
+			if err != nil {
+				return cors.Config{}, err
+			}
+			patterns = append(patterns, pattern)
+		default:
+			exact = append(exact, strings.TrimSuffix(origin, "/"))
+		}
+	}
+
+	// If no origins specified, allow all
+	if allowAll || (len(exact) == 0 && len(patterns) == 0) {
+		if allowCredentials {
+			return cors.Config{}, errors.New("CORS: allowing all origins together with credentials is unsafe; " +
+				"list explicit or wildcard origins in CORS_ALLOWED_ORIGINS or set CORS_ALLOW_CREDENTIALS=false")
+		}
 		config.AllowAllOrigins = true
+		return config, nil
+	}
+
+	config.AllowOrigins = exact
+	if len(patterns) > 0 {
+		config.AllowOriginFunc = func(origin string) bool {
+			return matchOriginPatterns(patterns, origin)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return cors.Config{}, fmt.Errorf("CORS: %w", err)
 	}
 
-	return cors.New(config)
+	return config, nil
+}
+
+// Preflight answers OPTIONS requests routed to a group whose CORS
+// middleware did not already answer them, such as requests without an
+// Origin. Register it as the group's OPTIONS catch-all, right after the
+// group's CORS middleware, so preflights never reach authentication.
+func Preflight(c *gin.Context) {
+	c.AbortWithStatus(http.StatusNoContent)
 }
 
-// CORSDefault creates a permissive CORS middleware for development
+// CORSDefault creates a permissive CORS middleware for development.
+// Credentials are not allowed since every origin is accepted.
 func CORSDefault() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowAllOrigins:  true,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
-		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
-		AllowCredentials: true,
+		AllowMethods:     CORSAllowedMethods,
+		AllowHeaders:     CORSAllowedHeaders,
+		ExposeHeaders:    CORSExposedHeaders,
+		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	})
 }
+
+// parseOriginPattern parses a wildcard origin. Only a single leading
+// subdomain wildcard is supported, e.g. https://*.example.com[:port].
+func parseOriginPattern(origin string) (originPattern, error) {
+	invalid := fmt.Errorf("CORS: invalid wildcard origin %q, expected scheme://*.domain", origin)
+
+	scheme, rest, ok := strings.Cut(origin, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return originPattern{}, invalid
+	}
+	if strings.Count(rest, "*") != 1 || !strings.HasPrefix(rest, "*.") {
+		return originPattern{}, invalid
+	}
+
+	host := strings.TrimPrefix(rest, "*")
+	port := ""
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host, port = host[:i], host[i+1:]
+	}
+	if strings.Count(host, ".") < 2 || strings.ContainsAny(host, "/?#@") {
+		return originPattern{}, invalid
+	}
+	// Require a registrable domain after the wildcard: *.co.uk or *.github.io
+	// would admit every site under a public suffix
+	if _, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host[1:])); err != nil {
+		return originPattern{}, fmt.Errorf("CORS: wildcard origin %q covers a public suffix, expected scheme://*.domain", origin)
+	}
+
+	return originPattern{scheme: scheme, suffix: strings.ToLower(host), port: port}, nil
+}
+
+// matchOriginPatterns reports whether an origin matches any wildcard pattern
+func matchOriginPatterns(patterns []originPattern, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, p := range patterns {
+		if u.Scheme != p.scheme || u.Port() != p.port {
+			continue
+		}
+		if !strings.HasSuffix(host, p.suffix) {
+			continue
+		}
+		if isValidSubdomain(strings.TrimSuffix(host, p.suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidSubdomain checks the wildcard part contains only DNS label characters
+func isValidSubdomain(sub string) bool {
+	if sub == "" {
+		return false
+	}
+	for _, label := range strings.Split(sub, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// corsRouter serves one GET route behind the CORS middleware, with the
+// OPTIONS catch-all the route groups register
+func corsRouter(t *testing.T, origins []string, credentials bool) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	handler, err := middleware.CORS(origins, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	group := router.Group("/admin", handler)
+	group.OPTIONS("/*path", middleware.Preflight)
+	group.GET("/customers", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// preflight sends an OPTIONS preflight for a GET that sends every custom
+// header
+func preflight(router http.Handler, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/admin/customers", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", strings.Join(middleware.CORSAllowedHeaders, ", "))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := corsRouter(t, []string{"https://crm.example.com", "https://*.crm-ui.example.com"}, true)
+
+	for _, origin := range []string{
+		"https://crm.example.com",
+		"https://pr-123.crm-ui.example.com",
+		"https://a.pr-123.crm-ui.example.com",
+		"https://PR-7.crm-ui.example.com",
+	} {
+		t.Run("allowed "+origin, func(t *testing.T) {
+			rec := preflight(router, origin)
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != origin {
+				t.Errorf("Access-Control-Allow-Origin = %q", got)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+				t.Errorf("Access-Control-Allow-Credentials = %q", got)
+			}
+			allowed := strings.ToLower(rec.Header().Get("Access-Control-Allow-Headers"))
+			for _, header := range middleware.CORSAllowedHeaders {
+				if !strings.Contains(allowed, strings.ToLower(header)) {
+					t.Errorf("Access-Control-Allow-Headers = %q, missing %s", allowed, header)
+				}
+			}
+			if methods := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "PATCH") {
+				t.Errorf("Access-Control-Allow-Methods = %q", methods)
+			}
+		})
+	}
+
+	for _, origin := range []string{
+		"https://evil.com",
+		"https://crm-ui.example.com",                 // The wildcard needs a subdomain
+		"http://pr-123.crm-ui.example.com",           // Scheme differs
+		"https://pr-123.crm-ui.example.com:8443",     // Port differs
+		"https://pr-123.crm-ui.example.com.evil.com", // Suffix is not the end
+		"https://evilcrm-ui.example.com",             // Not a subdomain
+		"https://user@pr-123.crm-ui.example.com",     // Credentials in the origin
+		"https://pr_123.crm-ui.example.com",          // Not a DNS label
+		"https://crm.example.com.evil.com",           // Exact origins match exactly
+		"null",
+	} {
+		t.Run("rejected "+origin, func(t *testing.T) {
+			rec := preflight(router, origin)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q", got)
+			}
+		})
+	}
+
+	t.Run("without an origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/admin/customers", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("status = %d, headers %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("request from a rejected origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/customers", nil)
+		req.Header.Set("Origin", "https://evil.com")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("status = %d, headers %v", rec.Code, rec.Header())
+		}
+	})
+}
+
+func TestCORSAllowAll(t *testing.T) {
+	router := corsRouter(t, []string{"*"}, false)
+	rec := preflight(router, "https://anywhere.example.org")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("status = %d, headers %v", rec.Code, rec.Header())
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q", got)
+	}
+}
+
+func TestNewCORSConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		origins     []string
+		credentials bool
+		ok          bool
+	}{
+		{"exact with credentials", []string{"https://crm.example.com"}, true, true},
+		{"wildcard with credentials", []string{"https://*.crm-ui.example.com:8443"}, true, true},
+		{"all without credentials", []string{"*"}, false, true},
+		{"none without credentials", nil, false, true},
+		{"all with credentials", []string{"https://crm.example.com", "*"}, true, false},
+		{"none with credentials", []string{" "}, true, false},
+		{"wildcard top-level domain", []string{"https://*.com"}, false, false},
+		{"wildcard public suffix", []string{"https://*.co.uk"}, false, false},
+		{"wildcard private public suffix", []string{"https://*.github.io"}, false, false},
+		{"wildcard under a public suffix", []string{"https://*.example.co.uk"}, true, true},
+		{"wildcard in the middle", []string{"https://pr-*.example.com"}, false, false},
+		{"two wildcards", []string{"https://*.*.example.com"}, false, false},
+		{"wildcard scheme", []string{"ftp://*.example.com"}, false, false},
+		{"wildcard with a path", []string{"https://*.example.com/app"}, false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := middleware.NewCORSConfig(tc.origins, tc.credentials)
+			if (err == nil) != tc.ok {
+				t.Errorf("err = %v, want ok = %v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureFlagOverrides applies X-Feature-Flags header overrides for the
+// request. The header is honored for admins, or for everyone in
+// development, and ignored otherwise. Must run after authentication.
+func FeatureFlagOverrides(development bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(flags.Header)
+		if header == "" || (!development && !models.HoldsRole(c.GetString(ContextKeyUserRole), models.RoleAdmin)) {
+			c.Next()
+			return
+		}
+
+		overrides, err := flags.Parse(header)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Code:    "INVALID_FEATURE_FLAGS",
+				Message: i18n.Message(c, "INVALID_FEATURE_FLAGS", err.Error()),
+			})
+			return
+		}
+
+		c.Set(flags.ContextKey, overrides)
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// Locale negotiates the response language from the Accept-Language header
+// and stores it in the request context
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.Negotiate(c.GetHeader("Accept-Language"))
+
+		c.Set(i18n.ContextKeyLocale, locale)
+		c.Header("Content-Language", locale)
+
+		c.Next()
+	}
+}
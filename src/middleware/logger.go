@@ -3,6 +3,7 @@ package middleware
 import (
 	"time"
 
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -34,7 +35,7 @@ func InitLogger(isDevelopment bool) error {
 	return nil
 }
 
-// RequestID adds a unique request ID to each request
+// RequestID adds a unique request ID and the matched route to each request
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if request ID already exists in header
@@ -45,6 +46,7 @@ func RequestID() gin.HandlerFunc {
 
 		// Set request ID in context and response header
 		c.Set("request_id", requestID)
+		c.Set("route", c.FullPath())
 		c.Header("X-Request-ID", requestID)
 
 		c.Next()
@@ -125,7 +127,7 @@ func Recovery() gin.HandlerFunc {
 				c.AbortWithStatusJSON(500, ErrorResponse{
 					Error:   "internal_server_error",
 					Code:    "INTERNAL_ERROR",
-					Message: "An unexpected error occurred",
+					Message: i18n.Message(c, "INTERNAL_ERROR", "An unexpected error occurred"),
 				})
 			}
 		}()
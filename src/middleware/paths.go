@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// originalPathKey holds the request path as sent, before NormalizePath
+type originalPathKey struct{}
+
+// NormalizePath serves trailing-slash and case variants of the engine's
+// routes, such as /Admin/customers/, as the route itself. The path is
+// matched against the route templates, preferring fixed segments over
+// parameters as gin does, and the fixed segments of the matching template
+// are written as registered; segments a parameter matches, like IDs and
+// tokens, are left as sent. The request is rewritten before routing, so
+// clients get the resource instead of a redirect that would break CORS
+// preflights and drop POST bodies. Wrap the engine once every route is
+// registered.
+func NormalizePath(engine *gin.Engine) http.Handler {
+	root := &routeNode{}
+	for _, route := range engine.Routes() {
+		root.add(strings.Split(strings.Trim(route.Path, "/"), "/"))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), originalPathKey{}, r.URL.Path))
+		r.URL.Path = normalizePath(r.URL.Path, root)
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = normalizePath(r.URL.RawPath, root)
+		}
+		engine.ServeHTTP(w, r)
+	})
+}
+
+// routeNode is a segment of the route templates. Fixed segments are keyed
+// by their lower-cased form.
+type routeNode struct {
+	segment  string // The fixed segment as registered
+	fixed    map[string]*routeNode
+	param    *routeNode
+	catchAll bool // A *param matches the rest of the path
+	end      bool // A route ends here
+}
+
+// add adds the segments of a route template below the node
+func (n *routeNode) add(segments []string) {
+	if len(segments) == 0 || (len(segments) == 1 && segments[0] == "") {
+		n.end = true
+		return
+	}
+	segment := segments[0]
+	switch {
+	case strings.HasPrefix(segment, "*"):
+		n.catchAll = true
+	case strings.HasPrefix(segment, ":"):
+		if n.param == nil {
+			n.param = &routeNode{}
+		}
+		n.param.add(segments[1:])
+	default:
+		if n.fixed == nil {
+			n.fixed = make(map[string]*routeNode)
+		}
+		key := strings.ToLower(segment)
+		child, ok := n.fixed[key]
+		if !ok {
+			child = &routeNode{segment: segment}
+			n.fixed[key] = child
+		}
+		child.add(segments[1:])
+	}
+}
+
+// match matches parts against the templates below the node, rewriting the
+// parts that match fixed segments as registered. It reports whether a
+// template matched; parts are only rewritten when one did.
+func (n *routeNode) match(parts []string) bool {
+	if len(parts) == 0 {
+		return n.end || n.catchAll
+	}
+	if child, ok := n.fixed[strings.ToLower(parts[0])]; ok && child.match(parts[1:]) {
+		parts[0] = child.segment
+		return true
+	}
+	if n.param != nil && parts[0] != "" && n.param.match(parts[1:]) {
+		return true
+	}
+	return n.catchAll
+}
+
+// normalizePath drops trailing slashes and writes the fixed segments of the
+// route template the path matches as registered. A path matching no
+// template keeps its case.
+func normalizePath(path string, root *routeNode) string {
+	if trimmed := strings.TrimRight(path, "/"); trimmed != "" {
+		path = trimmed
+	}
+	if !strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	parts := strings.Split(path[1:], "/")
+	if len(parts) == 1 && parts[0] == "" {
+		parts = nil
+	}
+	if !root.match(parts) {
+		return path
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// requestedPath returns the path the client sent, before normalization
+func requestedPath(c *gin.Context) string {
+	if path, ok := c.Request.Context().Value(originalPathKey{}).(string); ok {
+		return path
+	}
+	return c.Request.URL.Path
+}
+
+// RouteNotFound answers requests for paths no route serves
+func RouteNotFound() gin.HandlerFunc {
+	return routeNotFound
+}
+
+func routeNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":   "not_found",
+		"code":    "ROUTE_NOT_FOUND",
+		"message": i18n.Message(c, "ROUTE_NOT_FOUND", "No endpoint matches this path"),
+		"path":    requestedPath(c),
+		"hint":    "Check the path against the API documentation; admin endpoints are under /admin",
+	})
+}
+
+// MethodNotAllowed answers requests whose path exists only for other
+// methods. Gin has already set the Allow header listing them. The preflight
+// catch-alls of the route groups match every path under them, so a path
+// allowing only OPTIONS has no route and is not found instead.
+func MethodNotAllowed() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Writer.Header().Get("Allow") == http.MethodOptions {
+			c.Writer.Header().Del("Allow")
+			routeNotFound(c)
+			return
+		}
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error":   "method_not_allowed",
+			"code":    "METHOD_NOT_ALLOWED",
+			"message": i18n.Message(c, "METHOD_NOT_ALLOWED", "This endpoint does not support the request method"),
+			"path":    requestedPath(c),
+			"method":  c.Request.Method,
+			"allowed": strings.Split(c.Writer.Header().Get("Allow"), ", "),
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// pathsRouter answers each route with its template and parameter values
+func pathsRouter() http.Handler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	echo := func(c *gin.Context) {
+		c.String(http.StatusOK, c.FullPath()+" "+c.Param("id")+c.Param("token"))
+	}
+	admin := router.Group("/admin")
+	admin.OPTIONS("/*path", middleware.Preflight)
+	admin.GET("/customers", echo)
+	admin.GET("/customers/export", echo)
+	admin.GET("/customers/:id", echo)
+	admin.GET("/tags/:id", echo)
+	router.GET("/public/email/unsubscribe/:token", echo)
+	router.NoRoute(func(c *gin.Context) { c.String(http.StatusNotFound, c.Request.URL.Path) })
+	return middleware.NormalizePath(router)
+}
+
+func TestNormalizePath(t *testing.T) {
+	router := pathsRouter()
+	for _, tc := range []struct {
+		path, want string
+	}{
+		{"/admin/customers", "/admin/customers "},
+		{"/Admin/Customers/", "/admin/customers "},
+		{"/ADMIN/CUSTOMERS/EXPORT", "/admin/customers/export "},
+		{"/admin/customers/42/", "/admin/customers/:id 42"},
+		// Parameter values that spell a fixed segment keep their case
+		{"/admin/tags/Customers", "/admin/tags/:id Customers"},
+		{"/admin/customers/Admin", "/admin/customers/:id Admin"},
+		{"/Public/Email/Unsubscribe/Export", "/public/email/unsubscribe/:token Export"},
+		{"/public/email/unsubscribe/aBc.DeF", "/public/email/unsubscribe/:token aBc.DeF"},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != tc.want {
+			t.Errorf("%s: status = %d, body %q, want %q", tc.path, rec.Code, rec.Body, tc.want)
+		}
+	}
+
+	// A path no template matches keeps its case
+	for _, path := range []string{"/Nowhere/Customers", "/Public/Nope"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound || rec.Body.String() != path {
+			t.Errorf("%s: status = %d, routed as %q", path, rec.Code, rec.Body)
+		}
+	}
+}
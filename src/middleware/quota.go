@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireQuota rejects a create request when one more record of entity
+// would exceed its quota
+func RequireQuota(tracker *quota.Tracker, entity string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CheckQuota(c, tracker, entity, 1) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckQuota reports whether n more records of entity fit within its quota,
+// writing a 403 QUOTA_EXCEEDED response with current usage and the limit
+// when they do not. A nil tracker allows everything, as does the sandbox,
+// whose records do not count against the quotas.
+func CheckQuota(c *gin.Context, tracker *quota.Tracker, entity string, n int64) bool {
+	if tracker == nil || sandbox.Active(c) {
+		return true
+	}
+
+	var exceeded *quota.ExceededError
+	if err := tracker.Check(entity, n); errors.As(err, &exceeded) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     "forbidden",
+			"code":      "QUOTA_EXCEEDED",
+			"message":   i18n.Message(c, "QUOTA_EXCEEDED", "Record quota exceeded"),
+			"entity":    exceeded.Entity,
+			"usage":     exceeded.Usage,
+			"limit":     exceeded.Limit,
+			"requested": exceeded.Requested,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitByIP limits requests per client IP to perMinute requests in a
+// fixed one-minute window. perMinute <= 0 disables the limit.
+func RateLimitByIP(perMinute int) gin.HandlerFunc {
+	limiter := newFixedWindowLimiter[string](perMinute)
+
+	return func(c *gin.Context) {
+		if retryAfter, ok := limiter.Allow(c.ClientIP(), 0, time.Now()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{
+				Error:   "rate_limited",
+				Code:    "RATE_LIMITED",
+				Message: i18n.Message(c, "RATE_LIMITED", "Too many requests, please retry later"),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// fixedWindowLimiter is a fixed-window per-minute rate limiter keyed by
+// caller (service account, client IP, ...)
+type fixedWindowLimiter[K comparable] struct {
+	defaultLimit int
+
+	mu      sync.Mutex
+	windows map[K]*rateWindow
+}
+
+// rateWindow counts requests in the current one-minute window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// newFixedWindowLimiter creates a new fixedWindowLimiter
+func newFixedWindowLimiter[K comparable](defaultLimit int) *fixedWindowLimiter[K] {
+	return &fixedWindowLimiter[K]{
+		defaultLimit: defaultLimit,
+		windows:      make(map[K]*rateWindow),
+	}
+}
+
+// Allow records a request and reports whether it is within the limit. When
+// it is not, the time until the window resets is returned. A limit <= 0
+// falls back to the limiter's default.
+func (l *fixedWindowLimiter[K]) Allow(key K, limit int, now time.Time) (time.Duration, bool) {
+	if limit <= 0 {
+		limit = l.defaultLimit
+	}
+	if limit <= 0 {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop expired windows so keys seen once do not accumulate
+	if len(l.windows) > 10000 {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= time.Minute {
+				delete(l.windows, k)
+			}
+		}
+	}
+
+	window, ok := l.windows[key]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		l.windows[key] = window
+	}
+	if window.count >= limit {
+		return window.start.Add(time.Minute).Sub(now), false
+	}
+	window.count++
+	return 0, true
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HeaderSyncToken carries the sync token of a successful mutation
+const HeaderSyncToken = "X-Sync-Token"
+
+// ContextKeyReadDB holds the database list reads should use
+const ContextKeyReadDB = "read_db"
+
+// ReadConsistency gives clients read-your-writes consistency when list reads
+// are served by a replica. Successful mutations return an X-Sync-Token
+// header; GET requests passing it back as min_sync_token read from the
+// replica only once it has caught up, and from the primary otherwise. Without
+// a replica, and for sandbox requests, which never use it, this is a no-op.
+func ReadConsistency(router *database.ReadRouter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !router.Enabled() || sandbox.Active(c) {
+			c.Next()
+			return
+		}
+
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Set(ContextKeyReadDB, router.Reader(c, c.Query("min_sync_token")))
+			c.Next()
+			return
+		}
+
+		c.Writer = &syncTokenWriter{ResponseWriter: c.Writer, router: router, c: c}
+		c.Next()
+	}
+}
+
+// GetReadDB returns the database list reads should use, falling back to db
+// when no read routing applies to the request
+func GetReadDB(c *gin.Context, db *gorm.DB) *gorm.DB {
+	if readDB, ok := c.Get(ContextKeyReadDB); ok {
+		return readDB.(*gorm.DB)
+	}
+	return db
+}
+
+// syncTokenWriter adds the sync token header to successful mutation
+// responses just before the headers are sent, after the handler's writes
+// have been committed
+type syncTokenWriter struct {
+	gin.ResponseWriter
+	router *database.ReadRouter
+	c      *gin.Context
+	done   bool
+}
+
+// setToken adds the header once, for responses below 400
+func (w *syncTokenWriter) setToken() {
+	if w.done {
+		return
+	}
+	w.done = true
+	if w.Status() >= http.StatusBadRequest {
+		return
+	}
+	token, err := w.router.SyncToken(w.c)
+	if err != nil {
+		if Logger != nil {
+			Logger.Warn("Failed to issue sync token: " + err.Error())
+		}
+		return
+	}
+	w.Header().Set(HeaderSyncToken, token)
+}
+
+func (w *syncTokenWriter) WriteHeaderNow() {
+	w.setToken()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *syncTokenWriter) Write(data []byte) (int, error) {
+	w.setToken()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *syncTokenWriter) WriteString(s string) (int, error) {
+	w.setToken()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *syncTokenWriter) Flush() {
+	w.setToken()
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/SalehAlobaylan/CRM-Service/src/tracking"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderImpersonateUserID is sent when an admin acts on behalf of another user
+const HeaderImpersonateUserID = "X-Impersonate-User-ID"
+
+// RecordView records a successful detail view of an entity identified by
+// the :id route parameter. The write is queued after the response so it
+// never delays the request; impersonated and sandbox sessions are not
+// tracked.
+func RecordView(recorder *tracking.RecentViewRecorder, entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Writer.Status() != http.StatusOK || c.GetHeader(HeaderImpersonateUserID) != "" || sandbox.Active(c) {
+			return
+		}
+		userID, ok := GetUserIDFromContext(c)
+		if !ok {
+			return
+		}
+		entityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return
+		}
+		recorder.Record(userID, entityType, uint(entityID))
+	}
+}
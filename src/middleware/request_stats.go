@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestStats counts requests and server errors over a rolling window.
+// The window is a ring of fixed-width buckets; a bucket is reset when the
+// clock moves into a slot it held for an older interval, so counts age out
+// without a background sweep.
+type RequestStats struct {
+	mu      sync.Mutex
+	width   time.Duration
+	buckets []requestBucket
+	started time.Time
+	now     func() time.Time
+}
+
+// requestBucket holds the counts of one interval of the window
+type requestBucket struct {
+	interval int64
+	requests int64
+	errors   int64
+}
+
+// RequestSnapshot is the rolling-window view of RequestStats
+type RequestSnapshot struct {
+	Window   time.Duration
+	Covered  time.Duration // Part of the window the process has been up for
+	Requests int64
+	Errors   int64
+}
+
+// NewRequestStats creates RequestStats over window split into the given
+// number of buckets
+func NewRequestStats(window time.Duration, buckets int) *RequestStats {
+	if buckets < 1 {
+		buckets = 1
+	}
+	width := window / time.Duration(buckets)
+	if width <= 0 {
+		width = time.Second
+	}
+	return &RequestStats{
+		width:   width,
+		buckets: make([]requestBucket, buckets),
+		started: time.Now(),
+		now:     time.Now,
+	}
+}
+
+// Started returns when counting began
+func (s *RequestStats) Started() time.Time {
+	return s.started
+}
+
+// Record counts a finished request with its response status
+func (s *RequestStats) Record(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	interval := s.now().UnixNano() / int64(s.width)
+	bucket := &s.buckets[interval%int64(len(s.buckets))]
+	if bucket.interval != interval {
+		*bucket = requestBucket{interval: interval}
+	}
+	bucket.requests++
+	if status >= http.StatusInternalServerError {
+		bucket.errors++
+	}
+}
+
+// Snapshot sums the buckets still inside the window
+func (s *RequestStats) Snapshot() RequestSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	window := s.width * time.Duration(len(s.buckets))
+	snapshot := RequestSnapshot{Window: window, Covered: min(now.Sub(s.started), window)}
+
+	oldest := now.UnixNano()/int64(s.width) - int64(len(s.buckets)) + 1
+	for _, bucket := range s.buckets {
+		if bucket.interval >= oldest {
+			snapshot.Requests += bucket.requests
+			snapshot.Errors += bucket.errors
+		}
+	}
+	return snapshot
+}
+
+// CountRequests records every request in stats once it has been served
+func CountRequests(stats *RequestStats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		stats.Record(c.Writer.Status())
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestStatsWindow(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	// step moves the clock by after, then records a request per status
+	type step struct {
+		after    time.Duration
+		statuses []int
+	}
+	ok := func(n int) []int {
+		statuses := make([]int, n)
+		for i := range statuses {
+			statuses[i] = http.StatusOK
+		}
+		return statuses
+	}
+	for _, tc := range []struct {
+		name     string
+		buckets  int // Over a 5s window
+		steps    []step
+		wait     time.Duration // Clock move before the snapshot
+		requests int64
+		errors   int64
+		covered  time.Duration
+	}{
+		{
+			name:     "within the window",
+			buckets:  5,
+			steps:    []step{{0, ok(2)}, {time.Second, ok(3)}},
+			requests: 5,
+			covered:  time.Second,
+		},
+		{
+			name:     "server errors",
+			buckets:  5,
+			steps:    []step{{0, []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable}}},
+			requests: 4,
+			errors:   2,
+		},
+		{
+			// Seven seconds of 1 to 7 requests: the first two seconds age out
+			// and the ring has wrapped into their slots
+			name:    "wraparound keeps the newest buckets",
+			buckets: 5,
+			steps: []step{
+				{0, ok(1)}, {time.Second, ok(2)}, {time.Second, ok(3)}, {time.Second, ok(4)},
+				{time.Second, ok(5)}, {time.Second, ok(6)}, {time.Second, ok(7)},
+			},
+			requests: 3 + 4 + 5 + 6 + 7,
+			covered:  5 * time.Second,
+		},
+		{
+			name:     "a reused slot starts empty",
+			buckets:  5,
+			steps:    []step{{0, ok(3)}, {5 * time.Second, ok(1)}},
+			requests: 1,
+			covered:  5 * time.Second,
+		},
+		{
+			name:     "buckets age out while idle",
+			buckets:  5,
+			steps:    []step{{0, ok(3)}, {time.Second, ok(2)}},
+			wait:     4 * time.Second,
+			requests: 2,
+			covered:  5 * time.Second,
+		},
+		{
+			name:    "idle longer than the window",
+			buckets: 5,
+			steps:   []step{{0, ok(3)}, {time.Second, ok(2)}},
+			wait:    20 * time.Second,
+			covered: 5 * time.Second,
+		},
+		{
+			name:     "capacity 1 within its interval",
+			buckets:  1,
+			steps:    []step{{0, ok(2)}, {4 * time.Second, ok(1)}},
+			requests: 3,
+			covered:  4 * time.Second,
+		},
+		{
+			name:     "capacity 1 after its interval",
+			buckets:  1,
+			steps:    []step{{0, ok(2)}, {4 * time.Second, ok(1)}, {time.Second, ok(1)}},
+			requests: 1,
+			covered:  5 * time.Second,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			now := start
+			stats := NewRequestStats(5*time.Second, tc.buckets)
+			stats.started, stats.now = start, func() time.Time { return now }
+			for _, step := range tc.steps {
+				now = now.Add(step.after)
+				for _, status := range step.statuses {
+					stats.Record(status)
+				}
+			}
+			now = now.Add(tc.wait)
+
+			got := stats.Snapshot()
+			want := RequestSnapshot{Window: 5 * time.Second, Covered: tc.covered, Requests: tc.requests, Errors: tc.errors}
+			if got != want {
+				t.Errorf("snapshot = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
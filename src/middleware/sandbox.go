@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderSandbox marks responses served from the sandbox database
+const HeaderSandbox = "X-Sandbox"
+
+// enterSandbox routes the rest of the request to the sandbox database. The
+// marker is set on both the gin context and the request context, so
+// queries see it whichever one they are given. A sandbox credential is
+// refused when no sandbox is configured rather than served real data.
+func enterSandbox(c *gin.Context) bool {
+	if !sandbox.Enabled() {
+		c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Code:    "SANDBOX_UNAVAILABLE",
+			Message: i18n.Message(c, "SANDBOX_UNAVAILABLE", "The API sandbox is not enabled on this server"),
+		})
+		return false
+	}
+
+	c.Set(sandbox.ContextKey, true)
+	c.Request = c.Request.WithContext(sandbox.With(c.Request.Context()))
+	c.Header(HeaderSandbox, "true")
+	return true
+}
+
+// NotInSandbox rejects sandbox requests to endpoints that change
+// process-wide state or hand work to background workers outside the request
+func NotInSandbox() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sandbox.Active(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+				Error:   "forbidden",
+				Code:    "SANDBOX_UNSUPPORTED",
+				Message: i18n.Message(c, "SANDBOX_UNSUPPORTED", "This endpoint is not available in the API sandbox"),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/SalehAlobaylan/CRM-Service/src/preview"
+	"github.com/gin-gonic/gin"
+)
+
+// TextPreview serializes the long text of the rows the route loads, such as
+// deal descriptions and notes, as a preview of length characters. 0 leaves
+// them in full.
+func TextPreview(length int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if length > 0 {
+			c.Set(preview.ContextKey, length)
+		}
+		c.Next()
+	}
+}
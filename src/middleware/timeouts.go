@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WriteDeadline replaces the server-wide write timeout for the routes it is
+// applied to, so slow endpoints such as large reports are not cut off
+// mid-response. d <= 0 removes the deadline entirely.
+func WriteDeadline(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var deadline time.Time
+		if d > 0 {
+			deadline = time.Now().Add(d)
+		}
+
+		// Writers that cannot change deadlines keep the server timeout
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(deadline); err != nil && Logger != nil {
+			Logger.Debug("Write deadline not supported: " + err.Error())
+		}
+
+		c.Next()
+	}
+}
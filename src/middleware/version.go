@@ -0,0 +1,15 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// HeaderVersion names the response header carrying the service version
+const HeaderVersion = "X-CRM-Version"
+
+// VersionHeader sets the service version on every response, so a response
+// captured during an incident tells which build answered it
+func VersionHeader(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(HeaderVersion, version)
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/i18n"
+	"github.com/SalehAlobaylan/CRM-Service/src/workload"
+	"github.com/gin-gonic/gin"
+)
+
+// LimitWorkload holds a slot of a workload class for the duration of the
+// request. When every slot of the class is taken the request is rejected
+// with 429 RESOURCE_BUSY and a Retry-After estimate, independently of the
+// per-caller rate limits. A nil limiter admits everything.
+func LimitWorkload(limiter *workload.Limiter, class string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, ok := limiter.TryAcquire(class)
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limiter.RetryAfter(class).Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "rate_limited",
+				"code":    "RESOURCE_BUSY",
+				"message": i18n.Message(c, "RESOURCE_BUSY", "Too many expensive requests of this kind are running, please retry later"),
+				"class":   class,
+			})
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
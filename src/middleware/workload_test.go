@@ -0,0 +1,168 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/workload"
+	"github.com/gin-gonic/gin"
+)
+
+// workloadRouter serves an export route limited to exports slots, and a
+// customer route that is not. Both take a connection of a shared pool, as
+// they would of the database's. Exports signal started once they hold a
+// slot and keep their connection until done closes.
+func workloadRouter(limiter *workload.Limiter, pool, started chan struct{}, done <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/customers/export", middleware.LimitWorkload(limiter, workload.ClassExports), func(c *gin.Context) {
+		pool <- struct{}{}
+		defer func() { <-pool }()
+		started <- struct{}{}
+		<-done
+		c.Status(http.StatusOK)
+	})
+	router.GET("/admin/customers", func(c *gin.Context) {
+		pool <- struct{}{}
+		defer func() { <-pool }()
+		time.Sleep(2 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestLimitWorkloadRejects(t *testing.T) {
+	limiter := workload.NewLimiter(map[string]int{workload.ClassExports: 1})
+	done := make(chan struct{})
+	started := make(chan struct{}, 1)
+	router := workloadRouter(limiter, make(chan struct{}, 10), started, done)
+
+	held := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/customers/export", nil))
+		held <- rec
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/customers/export", nil))
+	var body struct {
+		Code  string
+		Class string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusTooManyRequests || body.Code != "RESOURCE_BUSY" || body.Class != workload.ClassExports || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("saturated: status = %d, Retry-After %q: %s", rec.Code, rec.Header().Get("Retry-After"), rec.Body)
+	}
+
+	// The slot is released when the request finishes
+	close(done)
+	if rec := <-held; rec.Code != http.StatusOK {
+		t.Fatalf("held export: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/customers/export", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("after release: status = %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestLimitWorkloadLoad checks that CRUD latency stays flat while exports
+// are saturated: exports hold every slot of their class and a burst of
+// more is turned away, so they cannot take the connections CRUD requests
+// need
+func TestLimitWorkloadLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test")
+	}
+	const pool, exportSlots, crud, exports = 4, 2, 200, 100
+	limiter := workload.NewLimiter(map[string]int{workload.ClassExports: exportSlots})
+	done := make(chan struct{})
+	started := make(chan struct{}, exportSlots)
+	router := workloadRouter(limiter, make(chan struct{}, pool), started, done)
+
+	// p95 returns the 95th percentile latency of CRUD requests sent 8 at a
+	// time
+	p95 := func() time.Duration {
+		t.Helper()
+		latencies := make([]time.Duration, crud)
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, 8)
+		for i := range crud {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				start := time.Now()
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/customers", nil))
+				latencies[i] = time.Since(start)
+				if rec.Code != http.StatusOK {
+					t.Errorf("crud: status = %d", rec.Code)
+				}
+			}()
+		}
+		wg.Wait()
+		slices.Sort(latencies)
+		return latencies[crud*95/100]
+	}
+	baseline := p95()
+
+	// Saturate exports, then keep sending more while CRUD runs
+	var exportsDone sync.WaitGroup
+	var mu sync.Mutex
+	statuses := map[int]int{}
+	export := func() {
+		defer exportsDone.Done()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/customers/export", nil))
+		mu.Lock()
+		statuses[rec.Code]++
+		mu.Unlock()
+	}
+	for range exportSlots {
+		exportsDone.Add(1)
+		go export()
+	}
+	for range exportSlots {
+		<-started
+	}
+	stop := make(chan struct{})
+	burst := make(chan struct{})
+	go func() {
+		defer close(burst)
+		for range exports {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			exportsDone.Add(1)
+			go export()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	saturated := p95()
+	close(stop)
+	<-burst
+	close(done)
+	exportsDone.Wait()
+
+	if statuses[http.StatusOK] != exportSlots || statuses[http.StatusTooManyRequests] == 0 {
+		t.Errorf("export statuses = %v", statuses)
+	}
+	if limit := 2*baseline + 20*time.Millisecond; saturated > limit {
+		t.Errorf("CRUD p95 = %v while exports were saturated, %v before", saturated, baseline)
+	}
+	t.Logf("CRUD p95: %v idle, %v with exports saturated", baseline, saturated)
+}
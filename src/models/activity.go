@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // ActivityType represents the type of activity
@@ -25,6 +27,46 @@ const (
 	ActivityStatusOverdue   ActivityStatus = "overdue"
 )
 
+// ValidActivityTypes contains all valid activity types for validation
+var ValidActivityTypes = []ActivityType{
+	ActivityTypeCall,
+	ActivityTypeEmail,
+	ActivityTypeMeeting,
+	ActivityTypeTask,
+	ActivityTypeNote,
+}
+
+// ValidActivityStatuses contains all valid activity statuses for validation
+var ValidActivityStatuses = []ActivityStatus{
+	ActivityStatusScheduled,
+	ActivityStatusCompleted,
+	ActivityStatusCancelled,
+	ActivityStatusOverdue,
+}
+
+// ValidActivityPriorities contains all valid activity priorities for validation
+var ValidActivityPriorities = []string{"low", "normal", "high"}
+
+// IsValidActivityType checks if a type is valid
+func IsValidActivityType(activityType ActivityType) bool {
+	for _, t := range ValidActivityTypes {
+		if t == activityType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidActivityStatus checks if a status is valid
+func IsValidActivityStatus(status ActivityStatus) bool {
+	for _, s := range ValidActivityStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 // Activity represents a CRM activity (call, email, meeting, task)
 type Activity struct {
 	BaseModel
@@ -40,7 +82,37 @@ type Activity struct {
 	CompletedAt *time.Time     `json:"completed_at,omitempty"`
 	Duration    int            `json:"duration,omitempty"` // Duration in minutes
 	Outcome     string         `gorm:"type:text" json:"outcome,omitempty"`
-	Priority    string         `gorm:"size:20;default:'normal'" json:"priority"` // low, normal, high
+	OutcomeCode string         `gorm:"size:50;not null;default:'';index" json:"outcome_code,omitempty"` // Code from the outcome taxonomy of the type
+	Priority    string         `gorm:"size:20;default:'normal'" json:"priority"`                        // low, normal, high
+	Template    string         `gorm:"size:100;index" json:"template,omitempty"`                        // Email template key, for email activities
+
+	// Delivery tracking, for email activities. MessageID is the provider's
+	// message ID, recorded when the email is sent; DeliveryStatus is the type
+	// of the latest delivery event the provider reported.
+	MessageID        string         `gorm:"size:255;index" json:"message_id,omitempty"`
+	DeliveryStatus   EmailEventType `gorm:"size:20" json:"delivery_status,omitempty"`
+	DeliveryStatusAt *time.Time     `json:"delivery_status_at,omitempty"`
+
+	// PreviousActivityID links a follow-up to the activity it was scheduled from
+	PreviousActivityID *uint `gorm:"index" json:"previous_activity_id,omitempty"`
+
+	// AutomationFingerprint is set on activities created by automations; at
+	// most one open activity carries each fingerprint (see
+	// CreateAutomatedActivity)
+	AutomationFingerprint string `gorm:"size:64;uniqueIndex:idx_activities_automation_open,where:automation_fingerprint <> '' AND status <> 'completed' AND status <> 'cancelled' AND deleted_at IS NULL" json:"-"`
+
+	// EffectiveStatus is derived at read time by WithEffectiveStatus
+	EffectiveStatus ActivityStatus `gorm:"->;-:migration" json:"effective_status,omitempty"`
+
+	// Engagement is loaded on the detail view of email activities
+	Engagement *EmailEngagement `gorm:"-" json:"engagement,omitempty"`
+
+	// Call is loaded on the detail view of call activities logged from the
+	// telephony provider, with the call's numbers and recording URL
+	Call *TelephonyCall `gorm:"-" json:"call,omitempty"`
+
+	// Characters long text is serialized with, 0 for in full (see LongText)
+	PreviewLength int `gorm:"-" json:"-"`
 
 	// Relations
 	Customer *Customer `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
@@ -53,11 +125,37 @@ func (Activity) TableName() string {
 	return "activities"
 }
 
+// EffectiveStatusSQL derives an activity's status at read time: scheduled
+// activities past their due date are overdue even before the stored status
+// is updated. An activity due exactly now is not yet overdue.
+const EffectiveStatusSQL = "CASE WHEN activities.status = 'scheduled' AND activities.due_date < NOW() " +
+	"THEN 'overdue' ELSE activities.status END"
+
+// WithEffectiveStatus is a query scope selecting activities together with
+// their effective status
+func WithEffectiveStatus(db *gorm.DB) *gorm.DB {
+	return db.Select("activities.*, " + EffectiveStatusSQL + " AS effective_status")
+}
+
+// IsClosed reports whether the activity is completed or cancelled
+func (a Activity) IsClosed() bool {
+	return IsClosedActivityStatus(a.Status)
+}
+
+// ActivityCompletionResponse is returned when an activity is completed,
+// with the follow-up activity scheduled in the same step, if any
+type ActivityCompletionResponse struct {
+	Completed    Activity  `json:"completed"`
+	NextActivity *Activity `json:"next_activity,omitempty"`
+}
+
 // ActivityListResponse is used for paginated activity lists
 type ActivityListResponse struct {
-	Data       []Activity `json:"data"`
-	Total      int64      `json:"total"`
-	Page       int        `json:"page"`
-	PageSize   int        `json:"page_size"`
-	TotalPages int        `json:"total_pages"`
+	Data       []Activity        `json:"data"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Pass as cursor to fetch the rows after this page
+	Filters    map[string]string `json:"filters,omitempty"`     // Filter parameters that were applied
 }
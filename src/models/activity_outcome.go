@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ActivityOutcome is an outcome activities of a type are completed with,
+// such as voicemail for calls. Activities record the outcome by code, so a
+// code keeps its meaning when the outcome is amended.
+type ActivityOutcome struct {
+	BaseModel
+	Type     ActivityType `gorm:"size:50;not null;index" json:"type"`
+	Code     string       `gorm:"size:50;not null" json:"code"`
+	Label    string       `gorm:"size:255;not null" json:"label"`
+	Keywords []string     `gorm:"type:jsonb;serializer:json;not null" json:"keywords"` // Classify free-text outcomes (see ClassifyOutcome)
+	Position int          `gorm:"not null;default:0" json:"position"`
+}
+
+// TableName specifies the table name for ActivityOutcome
+func (ActivityOutcome) TableName() string {
+	return "activity_outcomes"
+}
+
+// OutcomeTaxonomy lists the outcomes of each activity type in order. Types
+// without outcomes are completed without an outcome code.
+type OutcomeTaxonomy map[ActivityType][]ActivityOutcome
+
+// NewOutcomeTaxonomy groups outcomes in order by activity type
+func NewOutcomeTaxonomy(outcomes []ActivityOutcome) OutcomeTaxonomy {
+	taxonomy := make(OutcomeTaxonomy)
+	for _, outcome := range outcomes {
+		taxonomy[outcome.Type] = append(taxonomy[outcome.Type], outcome)
+	}
+	return taxonomy
+}
+
+// Codes returns the outcome codes of an activity type in order
+func (t OutcomeTaxonomy) Codes(activityType ActivityType) []string {
+	codes := make([]string, len(t[activityType]))
+	for i, outcome := range t[activityType] {
+		codes[i] = outcome.Code
+	}
+	return codes
+}
+
+// Label returns the label of an outcome code of an activity type, or ""
+// when the type has no such outcome
+func (t OutcomeTaxonomy) Label(activityType ActivityType, code string) string {
+	for _, outcome := range t[activityType] {
+		if outcome.Code == code {
+			return outcome.Label
+		}
+	}
+	return ""
+}
+
+// outcomeCode matches the codes an activity outcome may have
+var outcomeCode = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidateOutcomeCode checks that an outcome code is lowercase letters,
+// digits and underscores, starting with a letter
+func ValidateOutcomeCode(code string) error {
+	if !outcomeCode.MatchString(code) {
+		return fmt.Errorf("outcome code %q must be 1-50 lowercase letters, digits or underscores, starting with a letter", code)
+	}
+	return nil
+}
+
+// NormalizeOutcomeKeywords lowercases keywords and strips their
+// punctuation as ClassifyOutcome does, dropping empty and repeated ones
+func NormalizeOutcomeKeywords(keywords []string) []string {
+	normalized := []string{}
+	for _, keyword := range keywords {
+		keyword = strings.Join(outcomeWords(keyword), " ")
+		if keyword != "" && !slices.Contains(normalized, keyword) {
+			normalized = append(normalized, keyword)
+		}
+	}
+	return normalized
+}
+
+// ClassifyOutcome returns the codes of the outcomes whose keywords a
+// free-text outcome mentions, in taxonomy order. Keywords match whole
+// words or phrases regardless of case and punctuation, so "no answer"
+// matches "No answer, left no message" but "connect" does not match
+// "connected".
+func ClassifyOutcome(outcomes []ActivityOutcome, text string) []string {
+	padded := " " + strings.Join(outcomeWords(text), " ") + " "
+
+	var codes []string
+	for _, outcome := range outcomes {
+		for _, keyword := range outcome.Keywords {
+			if keyword != "" && strings.Contains(padded, " "+keyword+" ") {
+				codes = append(codes, outcome.Code)
+				break
+			}
+		}
+	}
+	return codes
+}
+
+// outcomeWords splits text into lowercase words, keeping hyphenated words
+// such as no-show whole
+func outcomeWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '\''
+	})
+}
+
+// ActivityOutcomeListResponse is the response listing activity outcomes
+type ActivityOutcomeListResponse struct {
+	Data []ActivityOutcome `json:"data"`
+}
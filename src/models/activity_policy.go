@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ActivityPolicy lists the fields each activity type requires in a status
+type ActivityPolicy map[ActivityType]map[ActivityStatus][]string
+
+// ActivityRequirableFields lists the activity fields a policy can require
+var ActivityRequirableFields = []string{"description", "contact_id", "assigned_to", "due_date", "duration", "outcome"}
+
+// DefaultActivityPolicy requires completed calls to record how long they
+// took and how they went, and scheduled meetings to have a date
+var DefaultActivityPolicy = ActivityPolicy{
+	ActivityTypeCall: {
+		ActivityStatusCompleted: {"duration", "outcome"},
+	},
+	ActivityTypeMeeting: {
+		ActivityStatusScheduled: {"due_date"},
+	},
+}
+
+// ActivityRequirements is the active activity policy
+var ActivityRequirements = DefaultActivityPolicy
+
+// ActivityPolicyStrict rejects activities created before
+// ActivityPolicyEffectiveFrom that miss required fields. When unset they
+// are saved with a warning instead.
+var ActivityPolicyStrict = false
+
+// ActivityPolicyEffectiveFrom is when the activity policy started to apply
+var ActivityPolicyEffectiveFrom time.Time
+
+// ParseActivityPolicy parses a comma-separated list of rules in the form
+// type.status=field|field, e.g. "call.completed=duration|outcome". A rule
+// with no fields lifts the requirements of that type and status. An empty
+// string yields the default policy.
+func ParseActivityPolicy(spec string) (ActivityPolicy, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return DefaultActivityPolicy, nil
+	}
+
+	policy := make(ActivityPolicy)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, list, ok := strings.Cut(entry, "=")
+		activityType, status, ok2 := strings.Cut(target, ".")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid activity rule %q, expected type.status=field|field", entry)
+		}
+		if !IsValidActivityType(ActivityType(activityType)) {
+			return nil, fmt.Errorf("unknown activity type %q in activity rule %q", activityType, entry)
+		}
+		if !IsValidActivityStatus(ActivityStatus(status)) {
+			return nil, fmt.Errorf("unknown activity status %q in activity rule %q", status, entry)
+		}
+
+		fields := []string{}
+		for _, field := range strings.Split(list, "|") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !slices.Contains(ActivityRequirableFields, field) {
+				return nil, fmt.Errorf("unknown field %q in activity rule %q", field, entry)
+			}
+			fields = append(fields, field)
+		}
+
+		if policy[ActivityType(activityType)] == nil {
+			policy[ActivityType(activityType)] = make(map[ActivityStatus][]string)
+		}
+		policy[ActivityType(activityType)][ActivityStatus(status)] = fields
+	}
+
+	return policy, nil
+}
+
+// MissingFields returns the fields the policy requires for the activity's
+// type and status that it leaves empty, in policy order
+func (p ActivityPolicy) MissingFields(a Activity) []string {
+	var missing []string
+	for _, field := range p[a.Type][a.Status] {
+		if !a.hasField(field) {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// hasField reports whether a requirable field is set
+func (a Activity) hasField(field string) bool {
+	switch field {
+	case "description":
+		return strings.TrimSpace(a.Description) != ""
+	case "contact_id":
+		return a.ContactID != nil
+	case "assigned_to":
+		return a.AssignedTo != nil
+	case "due_date":
+		return a.DueDate != nil
+	case "duration":
+		return a.Duration > 0
+	case "outcome":
+		return strings.TrimSpace(a.Outcome) != ""
+	}
+	return true
+}
+
+// PredatesActivityPolicy reports whether the activity was created before
+// the activity policy started to apply
+func (a Activity) PredatesActivityPolicy() bool {
+	return !a.CreatedAt.IsZero() && a.CreatedAt.Before(ActivityPolicyEffectiveFrom)
+}
+
+// ActivityPolicyCapabilities exposes the activity policy so clients can
+// mark required inputs
+type ActivityPolicyCapabilities struct {
+	Required      ActivityPolicy `json:"required"`
+	Strict        bool           `json:"strict"`
+	EffectiveFrom *time.Time     `json:"effective_from,omitempty"`
+}
+
+// BuildActivityPolicyCapabilities describes the active activity policy
+func BuildActivityPolicyCapabilities() ActivityPolicyCapabilities {
+	caps := ActivityPolicyCapabilities{Required: ActivityRequirements, Strict: ActivityPolicyStrict}
+	if !ActivityPolicyEffectiveFrom.IsZero() {
+		from := ActivityPolicyEffectiveFrom
+		caps.EffectiveFrom = &from
+	}
+	return caps
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// ActivityTransitions lists the statuses an activity may move to from each
+// status. Completed and cancelled activities are closed and only leave
+// their status when reopened.
+var ActivityTransitions = map[ActivityStatus][]ActivityStatus{
+	ActivityStatusScheduled: {ActivityStatusOverdue, ActivityStatusCompleted, ActivityStatusCancelled},
+	ActivityStatusOverdue:   {ActivityStatusScheduled, ActivityStatusCompleted, ActivityStatusCancelled},
+	ActivityStatusCompleted: {},
+	ActivityStatusCancelled: {},
+}
+
+// ActivityReopenStatuses are the statuses a closed activity may be reopened to
+var ActivityReopenStatuses = []ActivityStatus{ActivityStatusScheduled, ActivityStatusOverdue}
+
+// AllowedActivityTransitions returns the statuses an activity may move to
+// from a status; reopen adds the reopen statuses for closed activities
+func AllowedActivityTransitions(from ActivityStatus, reopen bool) []ActivityStatus {
+	allowed := slices.Clone(ActivityTransitions[from])
+	if reopen && IsClosedActivityStatus(from) {
+		allowed = append(allowed, ActivityReopenStatuses...)
+	}
+	return allowed
+}
+
+// CanTransitionActivity reports whether an activity may move between two
+// statuses. Keeping the same status is always allowed.
+func CanTransitionActivity(from, to ActivityStatus, reopen bool) bool {
+	return from == to || slices.Contains(AllowedActivityTransitions(from, reopen), to)
+}
+
+// IsClosedActivityStatus reports whether a status is completed or cancelled
+func IsClosedActivityStatus(status ActivityStatus) bool {
+	return status == ActivityStatusCompleted || status == ActivityStatusCancelled
+}
+
+// IsActivityReopen reports whether moving between two statuses reopens a
+// closed activity
+func IsActivityReopen(from, to ActivityStatus) bool {
+	return IsClosedActivityStatus(from) && !IsClosedActivityStatus(to)
+}
+
+// SyncCompletedAt keeps completed_at consistent with the status after a
+// change from previous: only completed activities have it. Completing an
+// activity stamps now unless a new completion time was given, and staying
+// completed keeps the previous one unless replaced. It reports whether
+// completed_at changed.
+func (a *Activity) SyncCompletedAt(previous Activity, now time.Time) bool {
+	switch {
+	case a.Status != ActivityStatusCompleted:
+		a.CompletedAt = nil
+	case previous.Status != ActivityStatusCompleted:
+		if a.CompletedAt == nil || sameTime(a.CompletedAt, previous.CompletedAt) {
+			a.CompletedAt = &now
+		}
+	case a.CompletedAt == nil:
+		a.CompletedAt = previous.CompletedAt
+		if a.CompletedAt == nil {
+			a.CompletedAt = &now
+		}
+	}
+	return !sameTime(a.CompletedAt, previous.CompletedAt)
+}
+
+// sameTime reports whether two optional times are equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,99 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+func TestCanTransitionActivity(t *testing.T) {
+	const (
+		scheduled = models.ActivityStatusScheduled
+		overdue   = models.ActivityStatusOverdue
+		completed = models.ActivityStatusCompleted
+		cancelled = models.ActivityStatusCancelled
+	)
+	// Every pair of statuses, allowed without and with reopen=true
+	for _, tc := range []struct {
+		from, to        models.ActivityStatus
+		allowed, reopen bool
+	}{
+		{scheduled, scheduled, true, true},
+		{scheduled, overdue, true, true},
+		{scheduled, completed, true, true},
+		{scheduled, cancelled, true, true},
+		{overdue, scheduled, true, true},
+		{overdue, overdue, true, true},
+		{overdue, completed, true, true},
+		{overdue, cancelled, true, true},
+		{completed, scheduled, false, true},
+		{completed, overdue, false, true},
+		{completed, completed, true, true},
+		{completed, cancelled, false, false},
+		{cancelled, scheduled, false, true},
+		{cancelled, overdue, false, true},
+		{cancelled, completed, false, false},
+		{cancelled, cancelled, true, true},
+	} {
+		t.Run(string(tc.from)+"_to_"+string(tc.to), func(t *testing.T) {
+			if got := models.CanTransitionActivity(tc.from, tc.to, false); got != tc.allowed {
+				t.Errorf("allowed = %v, want %v", got, tc.allowed)
+			}
+			if got := models.CanTransitionActivity(tc.from, tc.to, true); got != tc.reopen {
+				t.Errorf("allowed with reopen = %v, want %v", got, tc.reopen)
+			}
+			if got, want := models.IsActivityReopen(tc.from, tc.to), models.IsClosedActivityStatus(tc.from) && tc.reopen && !tc.allowed; got != want {
+				t.Errorf("reopen = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// The table above covers every status
+	if n := len(models.ValidActivityStatuses); n != 4 {
+		t.Errorf("%d statuses, the table covers 4", n)
+	}
+}
+
+func TestSyncCompletedAt(t *testing.T) {
+	now := time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC)
+	earlier, given := now.Add(-24*time.Hour), now.Add(-time.Hour)
+
+	for _, tc := range []struct {
+		name     string
+		previous models.Activity
+		current  models.Activity
+		want     *time.Time
+		changed  bool
+	}{
+		{"completing stamps now",
+			models.Activity{Status: models.ActivityStatusScheduled},
+			models.Activity{Status: models.ActivityStatusCompleted}, &now, true},
+		{"completing keeps a given time",
+			models.Activity{Status: models.ActivityStatusScheduled},
+			models.Activity{Status: models.ActivityStatusCompleted, CompletedAt: &given}, &given, true},
+		{"staying completed keeps the time",
+			models.Activity{Status: models.ActivityStatusCompleted, CompletedAt: &earlier},
+			models.Activity{Status: models.ActivityStatusCompleted}, &earlier, false},
+		{"staying completed takes a new time",
+			models.Activity{Status: models.ActivityStatusCompleted, CompletedAt: &earlier},
+			models.Activity{Status: models.ActivityStatusCompleted, CompletedAt: &given}, &given, true},
+		{"reopening clears it",
+			models.Activity{Status: models.ActivityStatusCompleted, CompletedAt: &earlier},
+			models.Activity{Status: models.ActivityStatusScheduled, CompletedAt: &earlier}, nil, true},
+		{"cancelling leaves none",
+			models.Activity{Status: models.ActivityStatusScheduled},
+			models.Activity{Status: models.ActivityStatusCancelled, CompletedAt: &given}, nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			changed := tc.current.SyncCompletedAt(tc.previous, now)
+			got := tc.current.CompletedAt
+			if (got == nil) != (tc.want == nil) || (got != nil && !got.Equal(*tc.want)) {
+				t.Errorf("completed_at = %v, want %v", got, tc.want)
+			}
+			if changed != tc.changed {
+				t.Errorf("changed = %v, want %v", changed, tc.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AnnotationType represents the kind of operation an annotation marks
+type AnnotationType string
+
+const (
+	AnnotationTypeDeploy      AnnotationType = "deploy"
+	AnnotationTypeImport      AnnotationType = "import"
+	AnnotationTypeMaintenance AnnotationType = "maintenance"
+)
+
+// ValidAnnotationTypes contains all valid annotation types for validation
+var ValidAnnotationTypes = []AnnotationType{
+	AnnotationTypeDeploy,
+	AnnotationTypeImport,
+	AnnotationTypeMaintenance,
+}
+
+// IsValidAnnotationType checks if an annotation type is valid
+func IsValidAnnotationType(t AnnotationType) bool {
+	for _, valid := range ValidAnnotationTypes {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotation marks a window of operational work, such as a deploy, an import
+// or a maintenance run, so dashboards and auditors can explain what happened
+// in it. An annotation is open until it has an end; closed annotations are
+// immutable.
+type Annotation struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Type          AnnotationType `gorm:"size:20;not null;index" json:"type"`
+	Title         string         `gorm:"size:255;not null" json:"title"`
+	Description   string         `gorm:"type:text" json:"description,omitempty"`
+	StartsAt      time.Time      `gorm:"not null;index" json:"starts_at"`
+	EndsAt        *time.Time     `gorm:"index" json:"ends_at,omitempty"`
+	CreatedBy     *uint          `gorm:"index" json:"created_by,omitempty"` // Unset for annotations the service creates itself
+	CreatedByName string         `gorm:"size:255" json:"created_by_name,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for Annotation
+func (Annotation) TableName() string {
+	return "annotations"
+}
+
+// IsOpen reports whether the annotation has no end yet
+func (a Annotation) IsOpen() bool {
+	return a.EndsAt == nil
+}
+
+// AnnotationListResponse is used for paginated annotation lists
+type AnnotationListResponse struct {
+	Data       []Annotation `json:"data"`
+	Total      int64        `json:"total"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalPages int          `json:"total_pages"`
+}
+
+// OpenAnnotation records the start of an operation run by the service
+// itself or on behalf of a user
+func OpenAnnotation(db *gorm.DB, annotationType AnnotationType, title string, userID *uint, userName string) (*Annotation, error) {
+	annotation := Annotation{
+		Type:          annotationType,
+		Title:         title,
+		StartsAt:      time.Now(),
+		CreatedBy:     userID,
+		CreatedByName: userName,
+	}
+	if err := db.Create(&annotation).Error; err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+// CloseAnnotation ends an open annotation now, recording the outcome of the
+// operation as its description
+func CloseAnnotation(db *gorm.DB, annotation *Annotation, outcome string) error {
+	now := time.Now()
+	result := db.Model(annotation).Where("ends_at IS NULL").Updates(map[string]interface{}{
+		"ends_at":     now,
+		"description": outcome,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	annotation.EndsAt = &now
+	annotation.Description = outcome
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// AnonymizationSummary counts the records touched when a customer is
+// anonymized. Deals keep their amounts and stages; only text is scrubbed.
+type AnonymizationSummary struct {
+	Contacts    int64 `json:"contacts"`
+	Deals       int64 `json:"deals"`
+	Activities  int64 `json:"activities"`
+	Notes       int64 `json:"notes"`
+	AuditLogs   int64 `json:"audit_logs"`
+	EmailEvents int64 `json:"email_events"`
+}
+
+// AnonymizationPreview is returned by the first step of anonymizing a
+// customer. Repeating the request with the confirmation token performs it.
+type AnonymizationPreview struct {
+	CustomerID        uint                 `json:"customer_id"`
+	Affected          AnonymizationSummary `json:"affected"`
+	ConfirmationToken string               `json:"confirmation_token"`
+	ExpiresAt         time.Time            `json:"expires_at"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AssignmentStrategy represents how an assignment rule picks a rep
+type AssignmentStrategy string
+
+const (
+	AssignmentStrategyRoundRobin         AssignmentStrategy = "round_robin"
+	AssignmentStrategyWeightedRoundRobin AssignmentStrategy = "weighted_round_robin"
+	AssignmentStrategyLeastOpenLeads     AssignmentStrategy = "least_open_leads"
+)
+
+// ValidAssignmentStrategies contains all valid assignment strategies
+var ValidAssignmentStrategies = []AssignmentStrategy{
+	AssignmentStrategyRoundRobin,
+	AssignmentStrategyWeightedRoundRobin,
+	AssignmentStrategyLeastOpenLeads,
+}
+
+// OpenLeadStatuses are the customer statuses counted as open leads
+var OpenLeadStatuses = []CustomerStatus{CustomerStatusLead, CustomerStatusProspect}
+
+// AssignmentMember is a rep taking part in an assignment rule
+type AssignmentMember struct {
+	UserID uint `json:"user_id"`
+	Weight int  `json:"weight,omitempty"` // Used by weighted_round_robin, defaults to 1
+}
+
+// AssignmentRule assigns new leads to reps using a strategy
+type AssignmentRule struct {
+	BaseModel
+	Name     string             `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Strategy AssignmentStrategy `gorm:"size:50;not null" json:"strategy"`
+	Members  []AssignmentMember `gorm:"type:jsonb;serializer:json;not null" json:"members"`
+	Priority int                `gorm:"not null;default:0" json:"priority"` // Lower runs first
+	IsActive bool               `gorm:"default:true" json:"is_active"`
+	Position int                `gorm:"not null;default:0" json:"position"` // Round-robin cursor
+}
+
+// TableName specifies the table name for AssignmentRule
+func (AssignmentRule) TableName() string {
+	return "assignment_rules"
+}
+
+// Validate checks the rule's strategy and members
+func (r AssignmentRule) Validate() error {
+	valid := false
+	for _, s := range ValidAssignmentStrategies {
+		if r.Strategy == s {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid strategy %q", r.Strategy)
+	}
+	if len(r.Members) == 0 {
+		return fmt.Errorf("at least one member is required")
+	}
+	seen := make(map[uint]bool, len(r.Members))
+	for _, m := range r.Members {
+		if m.UserID == 0 {
+			return fmt.Errorf("member user_id is required")
+		}
+		if seen[m.UserID] {
+			return fmt.Errorf("user %d is listed more than once", m.UserID)
+		}
+		seen[m.UserID] = true
+		if m.Weight < 0 {
+			return fmt.Errorf("weight for user %d must not be negative", m.UserID)
+		}
+	}
+	return nil
+}
+
+// UserUnavailability is a period during which a rep receives no assignments,
+// e.g. vacation. CRM keeps this alongside the users it learns from JWTs.
+type UserUnavailability struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	StartsAt  time.Time `gorm:"not null" json:"starts_at"`
+	EndsAt    time.Time `gorm:"not null" json:"ends_at"`
+	Reason    string    `gorm:"size:255" json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for UserUnavailability
+func (UserUnavailability) TableName() string {
+	return "user_unavailability"
+}
+
+// AssignmentSimulation shows how upcoming leads would be distributed
+type AssignmentSimulation struct {
+	RuleID       uint               `json:"rule_id"`
+	Strategy     AssignmentStrategy `json:"strategy"`
+	Count        int                `json:"count"`
+	Assignments  []uint             `json:"assignments"`
+	Distribution map[uint]int       `json:"distribution"`
+	Unavailable  []uint             `json:"unavailable"`
+}
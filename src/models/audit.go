@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"reflect"
+	"strings"
 	"time"
 )
 
@@ -8,12 +11,23 @@ import (
 type AuditAction string
 
 const (
-	AuditActionCreate AuditAction = "create"
-	AuditActionUpdate AuditAction = "update"
-	AuditActionDelete AuditAction = "delete"
+	AuditActionCreate     AuditAction = "create"
+	AuditActionUpdate     AuditAction = "update"
+	AuditActionDelete     AuditAction = "delete"
+	AuditActionArchive    AuditAction = "archive"
+	AuditActionUnarchive  AuditAction = "unarchive"
+	AuditActionAnonymize  AuditAction = "anonymize"
+	AuditActionClaim      AuditAction = "claim"       // An agent assigned an unassigned record to themselves
+	AuditActionRestore    AuditAction = "restore"     // The database was replaced by a backup
+	AuditActionBulkUpsert AuditAction = "bulk_upsert" // Summary of records created or updated by one bulk upsert
+	AuditActionImport     AuditAction = "import"      // Summary of records created or updated by one CSV import
+	AuditActionReopen     AuditAction = "reopen"      // A completed or cancelled activity was moved back to an open status
+	AuditActionConvert    AuditAction = "convert"     // A lead or prospect was converted to an active customer
+	AuditActionClassify   AuditAction = "classify"    // Summary of activity outcomes classified by one mapping pass
 )
 
-// AuditLog represents an immutable audit trail entry
+// AuditLog represents an immutable audit trail entry. Entries are
+// hash-chained and the table is append-only outside the retention purge.
 type AuditLog struct {
 	ID           uint        `gorm:"primaryKey" json:"id"`
 	ResourceType string      `gorm:"size:100;not null;index" json:"resource_type"` // customer, deal, activity, etc.
@@ -22,11 +36,24 @@ type AuditLog struct {
 	UserID       uint        `gorm:"not null;index" json:"user_id"`
 	UserName     string      `gorm:"size:255" json:"user_name,omitempty"`
 	UserRole     string      `gorm:"size:50" json:"user_role,omitempty"`
-	OldValues    string      `gorm:"type:jsonb" json:"old_values,omitempty"`
-	NewValues    string      `gorm:"type:jsonb" json:"new_values,omitempty"`
+	OldValues    string      `gorm:"type:jsonb;default:null" json:"old_values,omitempty"`
+	NewValues    string      `gorm:"type:jsonb;default:null" json:"new_values,omitempty"`
 	IPAddress    string      `gorm:"size:45" json:"ip_address,omitempty"`
 	UserAgent    string      `gorm:"size:500" json:"user_agent,omitempty"`
+	Reason       string      `gorm:"type:text;not null;default:''" json:"reason,omitempty"` // Justification given for the change (see ChangeReasonRule)
 	CreatedAt    time.Time   `gorm:"not null" json:"created_at"`
+
+	// Every column of the record after the change, or before a delete (see
+	// AuditSnapshot). Not stored; the change's events are built from it.
+	Snapshot string `gorm:"-" json:"-"`
+
+	// Hash chain (see audit_chain.go). Entries written before chaining have
+	// empty hashes.
+	Hash               string     `gorm:"size:64;not null;default:''" json:"hash,omitempty"`
+	PrevHash           string     `gorm:"size:64;not null;default:''" json:"prev_hash,omitempty"`
+	ValuesHash         string     `gorm:"size:64;not null;default:''" json:"-"`
+	ValuesRedactedAt   *time.Time `json:"values_redacted_at,omitempty"`         // Values scrubbed by anonymization
+	RedactedValuesHash string     `gorm:"size:64;not null;default:''" json:"-"` // Hash of the scrubbed values
 }
 
 // TableName specifies the table name for AuditLog
@@ -36,9 +63,138 @@ func (AuditLog) TableName() string {
 
 // AuditLogListResponse is used for paginated audit log lists
 type AuditLogListResponse struct {
-	Data       []AuditLog `json:"data"`
-	Total      int64      `json:"total"`
-	Page       int        `json:"page"`
-	PageSize   int        `json:"page_size"`
-	TotalPages int        `json:"total_pages"`
+	Data        []AuditLog   `json:"data"`
+	Annotations []Annotation `json:"annotations"` // Operational annotations overlapping the listed entries
+	Total       int64        `json:"total"`
+	Page        int          `json:"page"`
+	PageSize    int          `json:"page_size"`
+	TotalPages  int          `json:"total_pages"`
+}
+
+// auditIgnoredFields are columns left out of update diffs because they
+// change on every write
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// AuditDiff returns the old and new values to store on an audit entry as
+// JSON objects keyed by column. Updates keep only the changed columns;
+// creates and deletes keep every column of the side that exists. A side
+// is empty only when its value is nil, so entries without values can be
+// told apart from no-op updates.
+func AuditDiff(oldValue, newValue interface{}) (string, string) {
+	oldFields := auditFields(oldValue)
+	newFields := auditFields(newValue)
+
+	if oldFields != nil && newFields != nil {
+		for key, value := range newFields {
+			if auditIgnoredFields[key] || string(oldFields[key]) == string(value) {
+				delete(oldFields, key)
+				delete(newFields, key)
+			}
+		}
+	}
+
+	return marshalAuditFields(oldFields), marshalAuditFields(newFields)
+}
+
+// AuditSnapshot returns every column of a record after a change, or before
+// it when the record was deleted, as a JSON object keyed by column
+func AuditSnapshot(oldValue, newValue interface{}) string {
+	if fields := auditFields(newValue); fields != nil {
+		return marshalAuditFields(fields)
+	}
+	return marshalAuditFields(auditFields(oldValue))
+}
+
+// auditFields flattens a model into its column values keyed by JSON name,
+// skipping relations and hidden fields. Non-struct values yield nil.
+func auditFields(value interface{}) map[string]json.RawMessage {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	collectAuditFields(v, fields)
+	return fields
+}
+
+// collectAuditFields adds the column fields of a struct, descending into
+// embedded structs such as BaseModel
+func collectAuditFields(v reflect.Value, fields map[string]json.RawMessage) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectAuditFields(v.Field(i), fields)
+			continue
+		}
+
+		gormTag := field.Tag.Get("gorm")
+		if gormTag == "-" || strings.HasPrefix(gormTag, "->") ||
+			strings.Contains(gormTag, "foreignKey") || strings.Contains(gormTag, "many2many") {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		raw, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			continue
+		}
+		fields[name] = raw
+	}
+}
+
+// marshalAuditFields encodes audit fields, returning "" for nil
+func marshalAuditFields(fields map[string]json.RawMessage) string {
+	if fields == nil {
+		return ""
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// FieldChange is one change of a single field taken from the audit trail
+type FieldChange struct {
+	AuditID   uint            `json:"audit_id"`
+	Action    AuditAction     `json:"action"`
+	OldValue  json.RawMessage `json:"old_value"`
+	NewValue  json.RawMessage `json:"new_value"`
+	UserID    uint            `json:"user_id"`
+	UserName  string          `json:"user_name,omitempty"`
+	UserRole  string          `json:"user_role,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// FieldHistoryResponse is used for paginated field histories. Truncated is
+// set when the resource has audit entries recorded before field values were
+// stored, so earlier changes of the field cannot be shown.
+type FieldHistoryResponse struct {
+	Field      string        `json:"field"`
+	Data       []FieldChange `json:"data"`
+	Truncated  bool          `json:"truncated"`
+	Total      int64         `json:"total"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"page_size"`
+	TotalPages int           `json:"total_pages"`
 }
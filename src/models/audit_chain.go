@@ -0,0 +1,225 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditChainLockKey is the Postgres advisory lock serializing audit log
+// inserts, so every entry links to the one written before it. It is held
+// until the inserting transaction ends, which is why changes queue their
+// entries (see AuditQueueEntry) rather than insert them.
+const AuditChainLockKey = 0x61756474 // "audt"
+
+// auditChainPrevKey carries the last hash between the entries of one
+// batch insert, which are hashed before any of them is written
+const auditChainPrevKey = "audit_chain:prev_hash"
+
+// AuditCheckpoint records the last hash of audit history removed by the
+// retention purge, so the remaining chain can still be verified
+type AuditCheckpoint struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	LastAuditID  uint      `gorm:"not null;index" json:"last_audit_id"` // Highest purged audit log ID
+	LastHash     string    `gorm:"size:64;not null" json:"last_hash"`   // Hash of that entry
+	PurgedCount  int64     `gorm:"not null" json:"purged_count"`
+	PurgedBefore time.Time `gorm:"not null" json:"purged_before"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for AuditCheckpoint
+func (AuditCheckpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+// AuditQueueEntry is an audit entry saved with its change but not yet
+// chained. Changes queue their entries without the chain lock, and the
+// audit trail's sequencer moves them into the log once they commit.
+type AuditQueueEntry struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	Entry     string    `gorm:"type:jsonb;not null" json:"entry"` // The AuditLog, without ID or hashes
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for AuditQueueEntry
+func (AuditQueueEntry) TableName() string {
+	return "audit_log_queue"
+}
+
+// BeforeCreate links a new audit entry to the previous one. It takes a
+// transaction-scoped advisory lock so concurrent inserts chain in ID order,
+// and stamps the entry only once the lock is held, so creation times
+// follow IDs and a time window of the log is a contiguous ID range.
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	prev, ok := tx.InstanceGet(auditChainPrevKey)
+	if !ok {
+		db := tx.Session(&gorm.Session{NewDB: true})
+		if err := db.Exec("SELECT pg_advisory_xact_lock(?)", AuditChainLockKey).Error; err != nil {
+			return err
+		}
+		hash, err := LastAuditHash(db)
+		if err != nil {
+			return err
+		}
+		prev = hash
+	}
+
+	// Postgres keeps microseconds; hash what will be read back
+	a.CreatedAt = tx.NowFunc().Truncate(time.Microsecond)
+	a.PrevHash = prev.(string)
+	a.ValuesHash = AuditValuesHash(a.OldValues, a.NewValues, a.Reason)
+	a.Hash = a.ChainHash(a.PrevHash)
+	tx.InstanceSet(auditChainPrevKey, a.Hash)
+	return nil
+}
+
+// LastAuditHash returns the hash new audit entries link to: that of the
+// latest entry, or of the latest purge checkpoint once all are purged
+func LastAuditHash(db *gorm.DB) (string, error) {
+	var hashes []string
+	if err := db.Model(&AuditLog{}).Order("id DESC").Limit(1).Pluck("hash", &hashes).Error; err != nil {
+		return "", err
+	}
+	if len(hashes) == 0 {
+		if err := db.Model(&AuditCheckpoint{}).Order("id DESC").Limit(1).Pluck("last_hash", &hashes).Error; err != nil {
+			return "", err
+		}
+	}
+	if len(hashes) == 0 {
+		return "", nil
+	}
+	return hashes[0], nil
+}
+
+// AuditValuesHash returns the SHA-256 of an entry's old and new values and
+// its reason, which anonymization may redact like the values. Values are
+// re-encoded first, since jsonb does not keep key order or whitespace. An
+// empty reason is left out, so entries without one hash as before reasons
+// were recorded.
+func AuditValuesHash(oldValues, newValues, reason string) string {
+	content := canonicalAuditJSON(oldValues) + "\x00" + canonicalAuditJSON(newValues)
+	if reason != "" {
+		content += "\x00" + reason
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainHash returns the SHA-256 of the entry's content and the previous
+// entry's hash. Values and the reason are covered through ValuesHash, so
+// redacting them leaves the chain intact.
+func (a AuditLog) ChainHash(prevHash string) string {
+	content, _ := json.Marshal(struct {
+		ResourceType string      `json:"resource_type"`
+		ResourceID   uint        `json:"resource_id"`
+		Action       AuditAction `json:"action"`
+		UserID       uint        `json:"user_id"`
+		UserName     string      `json:"user_name"`
+		UserRole     string      `json:"user_role"`
+		ValuesHash   string      `json:"values_hash"`
+		IPAddress    string      `json:"ip_address"`
+		UserAgent    string      `json:"user_agent"`
+		CreatedAt    string      `json:"created_at"`
+	}{
+		ResourceType: a.ResourceType,
+		ResourceID:   a.ResourceID,
+		Action:       a.Action,
+		UserID:       a.UserID,
+		UserName:     a.UserName,
+		UserRole:     a.UserRole,
+		ValuesHash:   a.ValuesHash,
+		IPAddress:    a.IPAddress,
+		UserAgent:    a.UserAgent,
+		CreatedAt:    a.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(append([]byte(prevHash+"\n"), content...))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalAuditJSON re-encodes a JSON document with sorted keys and no
+// whitespace. Documents that cannot be parsed are returned unchanged.
+func canonicalAuditJSON(document string) string {
+	if document == "" {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		return document
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return document
+	}
+	return string(encoded)
+}
+
+// auditMaintained reports whether audit_logs is guarded by the
+// crm_audit_maintainer role of migration 000041. Schemas created by
+// AutoMigrate have neither the guard nor the role's functions, and
+// audit_logs is changed directly there.
+func auditMaintained(tx *gorm.DB) (bool, error) {
+	var function *string
+	err := tx.Raw("SELECT to_regprocedure('crm_audit_purge(bigint)')::text").Scan(&function).Error
+	return function != nil, err
+}
+
+// RedactAuditLog replaces the values and reason of an audit entry with
+// their redacted form. The hash of the redacted values is recorded so
+// verification keeps checking them.
+func RedactAuditLog(tx *gorm.DB, id uint, oldValues, newValues, reason string, at time.Time) error {
+	hash := AuditValuesHash(oldValues, newValues, reason)
+	maintained, err := auditMaintained(tx)
+	if err != nil {
+		return err
+	}
+	if maintained {
+		return tx.Exec("SELECT crm_audit_redact(?, ?, ?, ?, ?, ?)",
+			id, nullableJSON(oldValues), nullableJSON(newValues), reason, hash, at).Error
+	}
+	return tx.Model(&AuditLog{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"old_values":           nullableJSON(oldValues),
+		"new_values":           nullableJSON(newValues),
+		"reason":               reason,
+		"redacted_values_hash": hash,
+		"values_redacted_at":   at,
+	}).Error
+}
+
+// PurgeAuditLogs deletes the audit entries up to and including lastID and
+// returns how many were deleted
+func PurgeAuditLogs(tx *gorm.DB, lastID uint) (int64, error) {
+	maintained, err := auditMaintained(tx)
+	if err != nil {
+		return 0, err
+	}
+	if !maintained {
+		result := tx.Where("id <= ?", lastID).Delete(&AuditLog{})
+		return result.RowsAffected, result.Error
+	}
+	var purged int64
+	err = tx.Raw("SELECT crm_audit_purge(?)", lastID).Scan(&purged).Error
+	return purged, err
+}
+
+// TruncateAuditLogs empties the audit log for a backup restore
+func TruncateAuditLogs(tx *gorm.DB) error {
+	maintained, err := auditMaintained(tx)
+	if err != nil {
+		return err
+	}
+	if maintained {
+		return tx.Exec("SELECT crm_audit_truncate()").Error
+	}
+	return tx.Exec("TRUNCATE TABLE audit_logs RESTART IDENTITY").Error
+}
+
+// nullableJSON maps empty JSON values to NULL
+func nullableJSON(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AutomationPath tells what an automated activity creation did
+type AutomationPath string
+
+const (
+	AutomationCreated AutomationPath = "created" // No open duplicate; the activity was inserted
+	AutomationUpdated AutomationPath = "updated" // An open duplicate was refreshed instead
+	AutomationSkipped AutomationPath = "skipped" // An open duplicate was left as it is
+)
+
+// automationOpenPredicate selects the open automated activities covered by
+// the partial unique index on automation_fingerprint. It must match the
+// index predicate exactly for ON CONFLICT to use the index.
+const automationOpenPredicate = "automation_fingerprint <> '' AND status <> 'completed' AND status <> 'cancelled' AND deleted_at IS NULL"
+
+// AutomationFingerprint identifies an automated activity by the automation
+// that creates it, its deal and title, and the window its due date falls
+// in. With window <= 0 the due date is ignored, so the automation keeps at
+// most one open copy of the activity per deal.
+func AutomationFingerprint(source string, dealID uint, title string, due time.Time, window time.Duration) string {
+	parts := []string{source, strconv.FormatUint(uint64(dealID), 10), strings.ToLower(strings.TrimSpace(title))}
+	if window > 0 {
+		parts = append(parts, strconv.FormatInt(due.UTC().Truncate(window).Unix(), 10))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAutomatedActivity inserts an activity carrying an automation
+// fingerprint unless an open activity with the same fingerprint exists.
+// With update, that duplicate takes the new description, due date,
+// priority and assignee; otherwise it is left alone. Either way activity
+// ends up holding the stored activity. Manual activities, which have no
+// fingerprint, are not affected.
+func CreateAutomatedActivity(tx *gorm.DB, activity *Activity, update bool) (AutomationPath, error) {
+	result := tx.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "automation_fingerprint"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: automationOpenPredicate}}},
+		DoNothing:   true,
+	}).Create(activity)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected > 0 {
+		return AutomationCreated, nil
+	}
+
+	incoming := *activity
+	var existing Activity
+	if err := tx.Where(automationOpenPredicate).
+		Where("automation_fingerprint = ?", incoming.AutomationFingerprint).
+		First(&existing).Error; err != nil {
+		return "", err
+	}
+	*activity = existing
+	if !update {
+		return AutomationSkipped, nil
+	}
+
+	activity.Description = incoming.Description
+	activity.DueDate = incoming.DueDate
+	activity.Priority = incoming.Priority
+	activity.AssignedTo = incoming.AssignedTo
+	if err := tx.Model(activity).Select("description", "due_date", "priority", "assigned_to").Updates(activity).Error; err != nil {
+		return "", err
+	}
+	return AutomationUpdated, nil
+}
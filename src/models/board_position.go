@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"gorm.io/gorm"
+)
+
+// BoardLockKey namespaces the Postgres advisory locks taken per board
+// column, the second key of each lock naming the column
+const BoardLockKey = 0x62726464 // "brdd"
+
+// BoardColumn scopes a query to the deals of one board column: a stage on
+// the owner's board, or on the global board for unowned deals
+func BoardColumn(db *gorm.DB, stage DealStage, ownerID *uint) *gorm.DB {
+	if ownerID == nil {
+		return db.Where("stage = ? AND owner_id IS NULL", stage)
+	}
+	return db.Where("stage = ? AND owner_id = ?", stage, *ownerID)
+}
+
+// LockBoardColumn takes a transaction-scoped advisory lock on a board
+// column, so concurrent creates and moves append to it one at a time
+func LockBoardColumn(tx *gorm.DB, stage DealStage, ownerID *uint) error {
+	var owner uint
+	if ownerID != nil {
+		owner = *ownerID
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%d", stage, owner)
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", BoardLockKey, int32(h.Sum32())).Error
+}
+
+// BottomBoardPosition locks a board column and returns the position below
+// its last deal other than exceptID. It must run in the transaction that
+// writes the position, which holds the lock until it commits.
+func BottomBoardPosition(tx *gorm.DB, stage DealStage, ownerID *uint, exceptID uint) (float64, error) {
+	if err := LockBoardColumn(tx, stage, ownerID); err != nil {
+		return 0, err
+	}
+	var last float64
+	err := BoardColumn(tx.Model(&Deal{}), stage, ownerID).
+		Where("id <> ?", exceptID).
+		Select("COALESCE(MAX(board_position), 0)").Scan(&last).Error
+	return last + BoardPositionStep, err
+}
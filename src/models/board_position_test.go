@@ -0,0 +1,69 @@
+package models_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// TestBottomBoardPositionConcurrent checks on Postgres that deals appended
+// to the same board column at once get distinct positions, while columns
+// of other owners are numbered on their own
+func TestBottomBoardPositionConcurrent(t *testing.T) {
+	db := testdb.Open(t)
+	customer := factory.New(db).Customer(t)
+	owner := uint(2)
+
+	const perColumn = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*perColumn)
+	for i := 0; i < 2*perColumn; i++ {
+		var ownerID *uint
+		if i%2 == 0 {
+			ownerID = &owner
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- db.Transaction(func(tx *gorm.DB) error {
+				position, err := models.BottomBoardPosition(tx, models.DealStageProspecting, ownerID, 0)
+				if err != nil {
+					return err
+				}
+				// Widen the window between reading the bottom and writing below it
+				time.Sleep(10 * time.Millisecond)
+				return tx.Create(&models.Deal{Title: "Renewal", CustomerID: customer.ID, Stage: models.DealStageProspecting,
+					Currency: "USD", OwnerID: ownerID, BoardPosition: position}).Error
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, ownerID := range []*uint{&owner, nil} {
+		var positions []float64
+		if err := models.BoardColumn(db.Model(&models.Deal{}), models.DealStageProspecting, ownerID).
+			Order("board_position").Pluck("board_position", &positions).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(positions) != perColumn {
+			t.Fatalf("owner %v: %d deals, want %d", ownerID, len(positions), perColumn)
+		}
+		for i, position := range positions {
+			if want := float64(i+1) * models.BoardPositionStep; position != want {
+				t.Errorf("owner %v: positions = %v, want steps of %v", ownerID, positions, models.BoardPositionStep)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MaxChangeReasonLength is the longest reason a change may be given
+const MaxChangeReasonLength = 500
+
+// ChangeReasonRule requires edits of a field of an entity to give a reason,
+// which is kept on the audit entry of the change. ClosedOnly limits a deal
+// rule to deals that were closed before the edit.
+type ChangeReasonRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Entity     string    `gorm:"size:50;not null;uniqueIndex:idx_change_reason_rules_entity_field" json:"entity"`
+	Field      string    `gorm:"size:100;not null;uniqueIndex:idx_change_reason_rules_entity_field" json:"field"`
+	ClosedOnly bool      `gorm:"not null;default:false" json:"closed_only"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for ChangeReasonRule
+func (ChangeReasonRule) TableName() string {
+	return "change_reason_rules"
+}
+
+// Validate checks that a rule names an editable field of an entity and that
+// only deal rules are limited to closed records
+func (r ChangeReasonRule) Validate() error {
+	fields, ok := EditableFields[r.Entity]
+	if !ok {
+		return fmt.Errorf("unknown entity %q", r.Entity)
+	}
+	if !slices.Contains(fields, r.Field) {
+		return fmt.Errorf("unknown %s field %q", r.Entity, r.Field)
+	}
+	if r.ClosedOnly && r.Entity != EntityDeal {
+		return fmt.Errorf("closed_only applies to deal rules only")
+	}
+	return nil
+}
+
+// ChangeReasonPolicy is the set of fields whose edits require a reason
+type ChangeReasonPolicy []ChangeReasonRule
+
+// RequiredFields returns the changed fields of an entity that the policy
+// requires a reason for, in the order they were changed. closed reports
+// whether the record was closed before the edit.
+func (p ChangeReasonPolicy) RequiredFields(entity string, closed bool, changed []string) []string {
+	var required []string
+	for _, field := range changed {
+		if slices.ContainsFunc(p, func(rule ChangeReasonRule) bool {
+			return rule.Entity == entity && rule.Field == field && (closed || !rule.ClosedOnly)
+		}) && !slices.Contains(required, field) {
+			required = append(required, field)
+		}
+	}
+	return required
+}
+
+// ChangeReasonPolicyResponse is the response listing the change reason policy
+type ChangeReasonPolicyResponse struct {
+	Data []ChangeReasonRule `json:"data"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Company aggregates customers sharing an email domain
+type Company struct {
+	Domain         string  `json:"domain"`
+	CompanyName    string  `json:"company_name,omitempty"` // Most common company name among its customers
+	CustomersCount int64   `json:"customers_count"`
+	OpenDealsCount int64   `json:"open_deals_count"`
+	PipelineValue  float64 `json:"pipeline_value"` // Total amount of open deals
+}
+
+// CompanyListResponse is used for paginated company lists
+type CompanyListResponse struct {
+	Data       []Company         `json:"data"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+	Filters    map[string]string `json:"filters,omitempty"` // Filter parameters that were applied
+}
+
+// CompanyMember is a customer listed alongside others at the same company
+type CompanyMember struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Company string `json:"company,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// FindingSeverity represents how serious a consistency finding is
+type FindingSeverity string
+
+const (
+	FindingSeverityInfo    FindingSeverity = "info"
+	FindingSeverityWarning FindingSeverity = "warning"
+	FindingSeverityError   FindingSeverity = "error"
+)
+
+// ConsistencyFinding records a broken data invariant found by the
+// consistency checker. Findings are deduplicated per check and resource and
+// resolved automatically once a run no longer reports them.
+type ConsistencyFinding struct {
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	CheckName    string          `gorm:"size:100;not null;uniqueIndex:idx_consistency_findings_key" json:"check_name"`
+	ResourceType string          `gorm:"size:100;not null;uniqueIndex:idx_consistency_findings_key" json:"resource_type"`
+	ResourceID   uint            `gorm:"not null;uniqueIndex:idx_consistency_findings_key" json:"resource_id"`
+	Severity     FindingSeverity `gorm:"size:20;not null;index" json:"severity"`
+	Message      string          `gorm:"type:text" json:"message"`
+	FirstSeenAt  time.Time       `gorm:"not null" json:"first_seen_at"`
+	LastSeenAt   time.Time       `gorm:"not null" json:"last_seen_at"`
+	ResolvedAt   *time.Time      `gorm:"index" json:"resolved_at,omitempty"`
+}
+
+// TableName specifies the table name for ConsistencyFinding
+func (ConsistencyFinding) TableName() string {
+	return "consistency_findings"
+}
+
+// ConsistencyFindingListResponse is used for paginated finding lists
+type ConsistencyFindingListResponse struct {
+	Data       []ConsistencyFinding `json:"data"`
+	Total      int64                `json:"total"`
+	Page       int                  `json:"page"`
+	PageSize   int                  `json:"page_size"`
+	TotalPages int                  `json:"total_pages"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Contact represents a contact person for a customer
 type Contact struct {
 	BaseModel
@@ -12,6 +14,12 @@ type Contact struct {
 	IsPrimary  bool   `gorm:"default:false" json:"is_primary"`
 	Notes      string `gorm:"type:text" json:"notes,omitempty"`
 
+	EmailOptOutAt  *time.Time `json:"email_opt_out_at,omitempty"`
+	EmailInvalidAt *time.Time `json:"email_invalid_at,omitempty"` // The email hard-bounced; cleared when it changes
+
+	// Characters long text is serialized with, 0 for in full (see LongText)
+	PreviewLength int `gorm:"-" json:"-"`
+
 	// Relations
 	Customer Customer `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
 }
@@ -23,9 +31,10 @@ func (Contact) TableName() string {
 
 // ContactListResponse is used for paginated contact lists
 type ContactListResponse struct {
-	Data       []Contact `json:"data"`
-	Total      int64     `json:"total"`
-	Page       int       `json:"page"`
-	PageSize   int       `json:"page_size"`
-	TotalPages int       `json:"total_pages"`
+	Data       []Contact         `json:"data"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+	Filters    map[string]string `json:"filters,omitempty"` // Filter parameters that were applied
 }
@@ -25,11 +25,42 @@ const (
 	CustomerStatusChurned   CustomerStatus = "churned"
 )
 
+// ValidCustomerStatuses contains all valid customer statuses for validation
+var ValidCustomerStatuses = []CustomerStatus{
+	CustomerStatusLead,
+	CustomerStatusProspect,
+	CustomerStatusActive,
+	CustomerStatusInactive,
+	CustomerStatusChurned,
+}
+
+// IsValidCustomerStatus checks if a status is valid
+func IsValidCustomerStatus(status CustomerStatus) bool {
+	for _, s := range ValidCustomerStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLeadStatus reports whether a customer with the status has not converted
+// to an account yet
+func IsLeadStatus(status CustomerStatus) bool {
+	return status == CustomerStatusLead || status == CustomerStatusProspect
+}
+
+// IsReverseConversion reports whether a status change moves a converted
+// customer back to lead or prospect
+func IsReverseConversion(from, to CustomerStatus) bool {
+	return !IsLeadStatus(from) && IsLeadStatus(to)
+}
+
 // Customer represents a customer in the CRM
 type Customer struct {
 	BaseModel
 	Name           string         `gorm:"size:255;not null" json:"name"`
-	Email          string         `gorm:"size:255;uniqueIndex;not null" json:"email"`
+	Email          string         `gorm:"size:255;uniqueIndex;index:idx_customers_email_lower,expression:LOWER(email);not null" json:"email"`
 	Phone          string         `gorm:"size:50" json:"phone,omitempty"`
 	Company        string         `gorm:"size:255" json:"company,omitempty"`
 	Role           string         `gorm:"size:100" json:"role,omitempty"`
@@ -38,6 +69,20 @@ type Customer struct {
 	Contacted      bool           `gorm:"default:false" json:"contacted"`
 	NextFollowUpAt *time.Time     `json:"next_follow_up_at,omitempty"`
 	Notes          string         `gorm:"type:text" json:"notes,omitempty"`
+	ArchivedAt     *time.Time     `gorm:"index" json:"archived_at,omitempty"`
+	EmailOptOutAt  *time.Time     `json:"email_opt_out_at,omitempty"`
+	EmailInvalidAt *time.Time     `json:"email_invalid_at,omitempty"` // The email hard-bounced; cleared when it changes
+	EmailDomain    string         `gorm:"size:255;not null;default:'';index" json:"email_domain,omitempty"` // Company domain of the email, empty for free providers
+	AnonymizedAt   *time.Time     `json:"anonymized_at,omitempty"` // Personal data erased; the record can no longer be edited
+	ExternalID     string         `gorm:"size:100;not null;default:'';uniqueIndex:idx_customers_external_id,where:external_id <> '' AND deleted_at IS NULL" json:"external_id,omitempty"` // ID in the system the customer is synced from
+	ConvertedAt    *time.Time     `gorm:"index" json:"converted_at,omitempty"` // Moved from lead or prospect to active
+	ConvertedBy    *uint          `json:"converted_by,omitempty"` // User who converted the customer
+
+	// Fields hidden from the requesting user, serialized as null
+	RedactedFields []string `gorm:"-" json:"redacted_fields,omitempty"`
+
+	// Characters long text is serialized with, 0 for in full (see LongText)
+	PreviewLength int `gorm:"-" json:"-"`
 
 	// Relations
 	Contacts   []Contact   `gorm:"foreignKey:CustomerID" json:"contacts,omitempty"`
@@ -46,6 +91,13 @@ type Customer struct {
 	Tags       []Tag       `gorm:"many2many:customer_tags;" json:"tags,omitempty"`
 }
 
+// NotArchived is a query scope excluding archived rows of the given table
+func NotArchived(table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(table + ".archived_at IS NULL")
+	}
+}
+
 // TableName specifies the table name for Customer
 func (Customer) TableName() string {
 	return "customers"
@@ -53,11 +105,14 @@ func (Customer) TableName() string {
 
 // CustomerListResponse is used for paginated customer lists
 type CustomerListResponse struct {
-	Data       []Customer `json:"data"`
-	Total      int64      `json:"total"`
-	Page       int        `json:"page"`
-	PageSize   int        `json:"page_size"`
-	TotalPages int        `json:"total_pages"`
+	Data          []Customer        `json:"data"`
+	Total         int64             `json:"total"`
+	Page          int               `json:"page"`
+	PageSize      int               `json:"page_size"`
+	TotalPages    int               `json:"total_pages"`
+	NextPageToken string            `json:"next_page_token,omitempty"` // Pass as page_token to fetch the next page
+	NextCursor    string            `json:"next_cursor,omitempty"`     // Pass as cursor to fetch the rows after this page
+	Filters       map[string]string `json:"filters,omitempty"`         // Filter parameters that were applied
 }
 
 // CustomerDetailResponse includes customer with related entities summary
@@ -67,4 +122,6 @@ type CustomerDetailResponse struct {
 	OpenDealsCount         int        `json:"open_deals_count"`
 	UpcomingActivitiesCount int       `json:"upcoming_activities_count"`
 	RecentActivities       []Activity `json:"recent_activities,omitempty"`
+	DomainMatesCount       int64           `json:"domain_mates_count"` // Other customers at the same email domain
+	DomainMates            []CompanyMember `json:"domain_mates,omitempty"`
 }
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// CustomerDeletionStatus represents the state of a background customer deletion
+type CustomerDeletionStatus string
+
+const (
+	CustomerDeletionPending   CustomerDeletionStatus = "pending"
+	CustomerDeletionRunning   CustomerDeletionStatus = "running"
+	CustomerDeletionCompleted CustomerDeletionStatus = "completed"
+	CustomerDeletionFailed    CustomerDeletionStatus = "failed" // Retried on the next run
+)
+
+// CustomerDeletionSummary counts the records deleted along with a customer
+type CustomerDeletionSummary struct {
+	Contacts   int64 `json:"contacts"`
+	Deals      int64 `json:"deals"`
+	Activities int64 `json:"activities"`
+	Notes      int64 `json:"notes"`
+}
+
+// Total returns the number of records counted
+func (s CustomerDeletionSummary) Total() int64 {
+	return s.Contacts + s.Deals + s.Activities + s.Notes
+}
+
+// Add adds the counts of other
+func (s *CustomerDeletionSummary) Add(other CustomerDeletionSummary) {
+	s.Contacts += other.Contacts
+	s.Deals += other.Deals
+	s.Activities += other.Activities
+	s.Notes += other.Notes
+}
+
+// CustomerDeletion tracks the background deletion of a customer with too
+// many dependent records to delete within a request. The customer is
+// soft-deleted when the deletion is requested, hiding it at once; its
+// contacts, deals, activities and notes follow in batches.
+type CustomerDeletion struct {
+	ID              uint                    `gorm:"primaryKey" json:"id"`
+	CustomerID      uint                    `gorm:"not null;uniqueIndex" json:"customer_id"`
+	Status          CustomerDeletionStatus  `gorm:"size:20;not null;index" json:"status"`
+	Expected        CustomerDeletionSummary `gorm:"embedded;embeddedPrefix:expected_" json:"expected"` // Dependents found when requested
+	Deleted         CustomerDeletionSummary `gorm:"embedded;embeddedPrefix:deleted_" json:"deleted"`
+	RequestedBy     uint                    `gorm:"not null" json:"requested_by"`
+	RequestedByName string                  `gorm:"size:255" json:"requested_by_name,omitempty"`
+	RequestedByRole string                  `gorm:"size:50" json:"-"`
+	IPAddress       string                  `gorm:"size:45" json:"-"`
+	UserAgent       string                  `gorm:"size:500" json:"-"`
+	Error           string                  `gorm:"type:text" json:"error,omitempty"`
+	Attempts        int                     `gorm:"not null;default:0" json:"attempts"`
+	StartedAt       *time.Time              `json:"started_at,omitempty"`
+	FinishedAt      *time.Time              `json:"finished_at,omitempty"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// TableName specifies the table name for CustomerDeletion
+func (CustomerDeletion) TableName() string {
+	return "customer_deletions"
+}
+
+// IsFinished reports whether the deletion has completed
+func (d CustomerDeletion) IsFinished() bool {
+	return d.Status == CustomerDeletionCompleted
+}
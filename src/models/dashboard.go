@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// Dashboard widget sizes
+const (
+	WidgetSizeSmall  = "small"
+	WidgetSizeMedium = "medium"
+	WidgetSizeLarge  = "large"
+)
+
+// ValidWidgetSizes contains all valid dashboard widget sizes
+var ValidWidgetSizes = []string{WidgetSizeSmall, WidgetSizeMedium, WidgetSizeLarge}
+
+// DashboardWidget is one widget on a user's dashboard. Type names an entry
+// of the widget catalog and Params holds its integer parameters.
+type DashboardWidget struct {
+	Type   string         `json:"type"`
+	Params map[string]int `json:"params,omitempty"`
+	Size   string         `json:"size"`
+}
+
+// UserDashboard stores a user's dashboard layout. Widgets are shown in order.
+type UserDashboard struct {
+	ID        uint              `gorm:"primaryKey" json:"-"`
+	UserID    uint              `gorm:"not null;uniqueIndex" json:"user_id"`
+	Widgets   []DashboardWidget `gorm:"type:jsonb;serializer:json;not null" json:"widgets"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// TableName specifies the table name for UserDashboard
+func (UserDashboard) TableName() string {
+	return "user_dashboards"
+}
+
+// DashboardResponse is a user's dashboard layout. IsDefault is set when the
+// user has not saved one and the default layout for their role is returned.
+type DashboardResponse struct {
+	Widgets   []DashboardWidget `json:"widgets"`
+	IsDefault bool              `json:"is_default"`
+	UpdatedAt *time.Time        `json:"updated_at,omitempty"`
+}
+
+// DashboardWidgetData is the resolved data of one dashboard widget. Widgets
+// that cannot be resolved carry an error code instead of data.
+type DashboardWidgetData struct {
+	Type  string      `json:"type"`
+	Size  string      `json:"size"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// DeadLetterStatus represents the state of a dead-lettered item
+type DeadLetterStatus string
+
+const (
+	DeadLetterStatusPending  DeadLetterStatus = "pending"
+	DeadLetterStatusRequeued DeadLetterStatus = "requeued"
+)
+
+// DeadLetter stores async work whose retries were exhausted
+type DeadLetter struct {
+	ID            uint             `gorm:"primaryKey" json:"id"`
+	Component     string           `gorm:"size:100;not null;index" json:"component"` // webhook, notification, job, ...
+	Payload       string           `gorm:"type:jsonb;not null" json:"payload"`
+	Error         string           `gorm:"type:text" json:"error"`
+	Attempts      int              `gorm:"not null;default:0" json:"attempts"`
+	Status        DeadLetterStatus `gorm:"size:20;not null;default:'pending';index" json:"status"`
+	FirstFailedAt time.Time        `gorm:"not null" json:"first_failed_at"`
+	LastFailedAt  time.Time        `gorm:"not null" json:"last_failed_at"`
+	RequeuedAt    *time.Time       `json:"requeued_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// TableName specifies the table name for DeadLetter
+func (DeadLetter) TableName() string {
+	return "dead_letters"
+}
+
+// DeadLetterListResponse is used for paginated dead letter lists
+type DeadLetterListResponse struct {
+	Data       []DeadLetter `json:"data"`
+	Total      int64        `json:"total"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalPages int          `json:"total_pages"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -16,7 +18,8 @@ const (
 	DealStageClosedLost   DealStage = "closed_lost"
 )
 
-// ValidDealStages contains all valid deal stages for validation
+// ValidDealStages contains the built-in deal stages, in pipeline order.
+// DealStages returns the stages in effect.
 var ValidDealStages = []DealStage{
 	DealStageProspecting,
 	DealStageQualification,
@@ -26,14 +29,66 @@ var ValidDealStages = []DealStage{
 	DealStageClosedLost,
 }
 
-// IsValidDealStage checks if a stage is valid
+// IsValidDealStage checks if a stage is one of the active pipeline stages
 func IsValidDealStage(stage DealStage) bool {
-	for _, s := range ValidDealStages {
-		if s == stage {
-			return true
-		}
+	return slices.Contains(DealStages(), stage)
+}
+
+// IsClosedDealStage checks if a stage is a terminal (won/lost) stage
+func IsClosedDealStage(stage DealStage) bool {
+	return stage == DealStageClosedWon || stage == DealStageClosedLost
+}
+
+// IsPastQualification checks if a stage comes after qualification in the
+// pipeline, or after the second stage once qualification is renamed or
+// removed
+func IsPastQualification(stage DealStage) bool {
+	stages := DealStages()
+	qualification := slices.Index(stages, DealStageQualification)
+	if qualification < 0 {
+		qualification = 1
+	}
+	return slices.Index(stages, stage) > qualification
+}
+
+// DealNextStepRequired makes moving an open deal to a later open stage
+// require a next step
+var DealNextStepRequired = false
+
+// IsForwardStageMove reports whether a deal moves from an open stage to a
+// later open stage. Closing a deal is not a forward move.
+func IsForwardStageMove(from, to DealStage) bool {
+	if IsClosedDealStage(from) || IsClosedDealStage(to) {
+		return false
 	}
-	return false
+	stages := DealStages()
+	return slices.Index(stages, to) > slices.Index(stages, from)
+}
+
+// NeedsNextStep reports whether moving the deal from a stage to its current
+// stage is refused for lack of a next step
+func (d Deal) NeedsNextStep(from DealStage) bool {
+	return DealNextStepRequired && strings.TrimSpace(d.NextStep) == "" && IsForwardStageMove(from, d.Stage)
+}
+
+// TrackNextStep stamps when the next step changed since previous and
+// re-arms the nudge when the step or its due date changed
+func (d *Deal) TrackNextStep(previous Deal, now time.Time) {
+	if d.NextStep != previous.NextStep {
+		d.NextStepSetAt = &now
+		d.NextStepNudgedAt = nil
+	}
+	if !timesEqual(d.NextStepDue, previous.NextStepDue) {
+		d.NextStepNudgedAt = nil
+	}
+}
+
+// timesEqual reports whether two optional times are both unset or equal
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
 }
 
 // Deal represents a sales opportunity
@@ -51,6 +106,22 @@ type Deal struct {
 	ActualCloseDate   *time.Time `json:"actual_close_date,omitempty"`
 	OwnerID           *uint      `json:"owner_id,omitempty"`
 	LostReason        string     `gorm:"size:255" json:"lost_reason,omitempty"`
+	ExternalID        string     `gorm:"size:100;index" json:"external_id,omitempty"` // ID in the system the deal was migrated from
+	ArchivedAt        *time.Time `gorm:"index" json:"archived_at,omitempty"`
+	BoardPosition     float64    `gorm:"not null;default:0" json:"board_position"` // Manual order within a stage
+	NextStep          string     `gorm:"size:255;not null;default:''" json:"next_step,omitempty"`
+	NextStepDue       *time.Time `gorm:"index" json:"next_step_due,omitempty"`
+	NextStepSetAt     *time.Time `json:"next_step_set_at,omitempty"`    // When next_step last changed
+	NextStepNudgedAt  *time.Time `json:"next_step_nudged_at,omitempty"` // When the owner was last nudged about the next step
+
+	// Fields hidden from the requesting user, serialized as null
+	RedactedFields []string `gorm:"-" json:"redacted_fields,omitempty"`
+
+	// Characters long text is serialized with, 0 for in full (see LongText)
+	PreviewLength int `gorm:"-" json:"-"`
+
+	// Close checklist, loaded for deal details
+	Checklist *DealChecklist `gorm:"-" json:"checklist,omitempty"`
 
 	// Relations
 	Customer   Customer   `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
@@ -59,6 +130,13 @@ type Deal struct {
 	Notes      []Note     `gorm:"foreignKey:DealID" json:"notes,omitempty"`
 }
 
+// Board positions are fractional so moving a deal never renumbers its
+// neighbors. Stages are renumbered in steps once gaps become too small.
+const (
+	BoardPositionStep   = 1024.0
+	MinBoardPositionGap = 1e-6
+)
+
 // TableName specifies the table name for Deal
 func (Deal) TableName() string {
 	return "deals"
@@ -66,11 +144,13 @@ func (Deal) TableName() string {
 
 // DealListResponse is used for paginated deal lists
 type DealListResponse struct {
-	Data       []Deal `json:"data"`
-	Total      int64  `json:"total"`
-	Page       int    `json:"page"`
-	PageSize   int    `json:"page_size"`
-	TotalPages int    `json:"total_pages"`
+	Data          []Deal            `json:"data"`
+	Total         int64             `json:"total"`
+	Page          int               `json:"page"`
+	PageSize      int               `json:"page_size"`
+	TotalPages    int               `json:"total_pages"`
+	NextPageToken string            `json:"next_page_token,omitempty"` // Pass as page_token to fetch the next page
+	Filters       map[string]string `json:"filters,omitempty"`         // Filter parameters that were applied
 }
 
 // PipelineStage represents a configurable pipeline stage
@@ -83,6 +163,34 @@ type PipelineStage struct {
 	IsActive    bool   `gorm:"default:true" json:"is_active"`
 }
 
+// PipelineColumn is one stage of the deals board
+type PipelineColumn struct {
+	Stage       DealStage `json:"stage"`
+	DisplayName string    `json:"display_name"`
+	Color       string    `json:"color,omitempty"`
+	Count       int       `json:"count"`
+	TotalAmount float64   `json:"total_amount"`
+	Deals       []Deal    `json:"deals"`
+}
+
+// PipelineViewResponse is the response for the deals board
+type PipelineViewResponse struct {
+	Stages []PipelineColumn `json:"stages"`
+}
+
+// DealBoardColumn is one stage of the deals board with its first deals.
+// Count and TotalAmount cover every deal in the stage.
+type DealBoardColumn struct {
+	PipelineColumn
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as cursor to fetch the stage's next deals
+}
+
+// DealBoardResponse is the response for the limited deals board
+type DealBoardResponse struct {
+	Stages  []DealBoardColumn `json:"stages"`
+	Filters map[string]string `json:"filters,omitempty"` // Filter parameters that were applied
+}
+
 // TableName specifies the table name for PipelineStage
 func (PipelineStage) TableName() string {
 	return "pipeline_stages"
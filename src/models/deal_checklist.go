@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// ChecklistDefinition is an item of the checklist deals must complete before
+// they can be closed won, such as a recorded PO number. Deals record checked
+// items by key, so a key keeps its meaning when the item is amended.
+type ChecklistDefinition struct {
+	BaseModel
+	Key      string `gorm:"size:50;not null;index" json:"key"`
+	Label    string `gorm:"size:255;not null" json:"label"`
+	Required bool   `gorm:"not null;default:true" json:"required"` // Blocks closing won until checked
+	Position int    `gorm:"not null;default:0" json:"position"`
+}
+
+// TableName specifies the table name for ChecklistDefinition
+func (ChecklistDefinition) TableName() string {
+	return "deal_checklist_definitions"
+}
+
+// DealChecklistItem records that a checklist item was checked on a deal,
+// and by whom. Unchecking the item deletes it.
+type DealChecklistItem struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	DealID        uint      `gorm:"not null;uniqueIndex:idx_deal_checklist_items_deal_key" json:"deal_id"`
+	Key           string    `gorm:"size:50;not null;uniqueIndex:idx_deal_checklist_items_deal_key" json:"key"`
+	CheckedBy     uint      `json:"checked_by"` // 0 for service accounts
+	CheckedByName string    `gorm:"size:255" json:"checked_by_name"`
+	CheckedAt     time.Time `gorm:"not null" json:"checked_at"`
+}
+
+// TableName specifies the table name for DealChecklistItem
+func (DealChecklistItem) TableName() string {
+	return "deal_checklist_items"
+}
+
+// DealChecklist is the checklist of one deal
+type DealChecklist struct {
+	Items    []DealChecklistEntry `json:"items"`
+	Missing  []string             `json:"missing"`  // Keys of unchecked required items
+	Complete bool                 `json:"complete"` // Whether the deal may be closed won
+}
+
+// DealChecklistEntry is a checklist item and whether a deal has checked it
+type DealChecklistEntry struct {
+	Key           string     `json:"key"`
+	Label         string     `json:"label"`
+	Required      bool       `json:"required"`
+	Checked       bool       `json:"checked"`
+	CheckedBy     *uint      `json:"checked_by,omitempty"`
+	CheckedByName string     `json:"checked_by_name,omitempty"`
+	CheckedAt     *time.Time `json:"checked_at,omitempty"`
+}
+
+// ChecklistBlockedSQL matches deals with an unchecked required checklist item
+const ChecklistBlockedSQL = `EXISTS (SELECT 1 FROM deal_checklist_definitions
+	WHERE deal_checklist_definitions.deleted_at IS NULL AND deal_checklist_definitions.required
+	AND NOT EXISTS (SELECT 1 FROM deal_checklist_items
+		WHERE deal_checklist_items.deal_id = deals.id AND deal_checklist_items.key = deal_checklist_definitions.key))`
+
+// checklistKey matches the keys a checklist item may have
+var checklistKey = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidateChecklistKey checks that a checklist key is lowercase letters,
+// digits and underscores, starting with a letter
+func ValidateChecklistKey(key string) error {
+	if !checklistKey.MatchString(key) {
+		return fmt.Errorf("checklist key %q must be 1-50 lowercase letters, digits or underscores, starting with a letter", key)
+	}
+	return nil
+}
+
+// NewDealChecklist builds the checklist of a deal from the checklist
+// definitions in order and the items checked on the deal. A deal closed
+// before an item was defined is not held to it, so amending the checklist
+// leaves closed deals complete.
+func NewDealChecklist(deal Deal, definitions []ChecklistDefinition, checked []DealChecklistItem) DealChecklist {
+	checklist := DealChecklist{Items: []DealChecklistEntry{}, Missing: []string{}}
+	for _, definition := range definitions {
+		if IsClosedDealStage(deal.Stage) && deal.ActualCloseDate != nil && definition.CreatedAt.After(*deal.ActualCloseDate) {
+			continue
+		}
+
+		entry := DealChecklistEntry{Key: definition.Key, Label: definition.Label, Required: definition.Required}
+		if i := slices.IndexFunc(checked, func(item DealChecklistItem) bool { return item.Key == definition.Key }); i >= 0 {
+			entry.Checked = true
+			entry.CheckedBy = &checked[i].CheckedBy
+			entry.CheckedByName = checked[i].CheckedByName
+			entry.CheckedAt = &checked[i].CheckedAt
+		} else if definition.Required {
+			checklist.Missing = append(checklist.Missing, definition.Key)
+		}
+		checklist.Items = append(checklist.Items, entry)
+	}
+	checklist.Complete = len(checklist.Missing) == 0
+	return checklist
+}
+
+// NeedsChecklist reports whether moving the deal from a stage to its
+// current stage is refused because the checklist is incomplete: deals are
+// closed won only once every required item is checked
+func (d Deal) NeedsChecklist(from DealStage, checklist DealChecklist) bool {
+	return d.Stage == DealStageClosedWon && from != DealStageClosedWon && !checklist.Complete
+}
+
+// ChecklistListResponse is the response listing checklist definitions
+type ChecklistListResponse struct {
+	Data []ChecklistDefinition `json:"data"`
+}
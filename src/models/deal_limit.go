@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// OpenDealLimitMode is how exceeding the open deal limit is handled
+type OpenDealLimitMode string
+
+const (
+	OpenDealLimitEnforce OpenDealLimitMode = "enforce" // Refuse the change
+	OpenDealLimitWarn    OpenDealLimitMode = "warn"    // Make the change with a warning
+)
+
+// ParseOpenDealLimitMode parses an open deal limit mode; "" is enforce
+func ParseOpenDealLimitMode(s string) (OpenDealLimitMode, error) {
+	switch mode := OpenDealLimitMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return OpenDealLimitEnforce, nil
+	case OpenDealLimitEnforce, OpenDealLimitWarn:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown open deal limit mode %q: want enforce or warn", s)
+	}
+}
+
+// MaxOpenDealsPerCustomer caps how many open deals a customer may have;
+// 0 means no limit
+var MaxOpenDealsPerCustomer = 0
+
+// OpenDealLimitPolicy is how exceeding MaxOpenDealsPerCustomer is handled
+var OpenDealLimitPolicy = OpenDealLimitEnforce
+
+// IsOpen reports whether a deal counts toward its customer's open deals:
+// it is in an open stage and not archived
+func (d Deal) IsOpen() bool {
+	return !IsClosedDealStage(d.Stage) && d.ArchivedAt == nil
+}
+
+// AddsOpenDeal reports whether changing a deal from previous makes it a new
+// open deal of its customer, by creating, reopening or unarchiving it or by
+// moving it to another customer
+func (d Deal) AddsOpenDeal(previous Deal) bool {
+	return d.IsOpen() && (!previous.IsOpen() || previous.CustomerID != d.CustomerID)
+}
+
+// OpenDeals is a query scope selecting the deals that count toward their
+// customer's open deals
+func OpenDeals(db *gorm.DB) *gorm.DB {
+	return db.Where("deals.archived_at IS NULL AND deals.stage NOT IN ?",
+		[]DealStage{DealStageClosedWon, DealStageClosedLost})
+}
+
+// OpenDealLimitCapabilities exposes the open deal limit so clients can
+// warn before creating a deal
+type OpenDealLimitCapabilities struct {
+	Max  int               `json:"max"` // 0 means no limit
+	Mode OpenDealLimitMode `json:"mode"`
+}
+
+// BuildOpenDealLimitCapabilities describes the active open deal limit
+func BuildOpenDealLimitCapabilities() OpenDealLimitCapabilities {
+	return OpenDealLimitCapabilities{Max: MaxOpenDealsPerCustomer, Mode: OpenDealLimitPolicy}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// AllowedDealTransitions returns the stages a deal may move to from a
+// stage: an open deal may move to a later open stage or close. reopen, for
+// admins, adds every other stage, so closed deals can be reopened or
+// switched between won and lost and open deals moved back.
+func AllowedDealTransitions(from DealStage, reopen bool) []DealStage {
+	var allowed []DealStage
+	for _, stage := range DealStages() {
+		if stage == from {
+			continue
+		}
+		if reopen || IsForwardStageMove(from, stage) || (!IsClosedDealStage(from) && IsClosedDealStage(stage)) {
+			allowed = append(allowed, stage)
+		}
+	}
+	return allowed
+}
+
+// CanTransitionDeal reports whether a deal may move between two stages.
+// Keeping the same stage is always allowed.
+func CanTransitionDeal(from, to DealStage, reopen bool) bool {
+	return from == to || slices.Contains(AllowedDealTransitions(from, reopen), to)
+}
+
+// IsDealReopen reports whether moving between two stages reopens a closed
+// deal
+func IsDealReopen(from, to DealStage) bool {
+	return IsClosedDealStage(from) && !IsClosedDealStage(to)
+}
+
+// NeedsLostReason reports whether moving the deal from a stage to its
+// current stage is refused for lack of a lost reason: deals closed as lost
+// must say why
+func (d Deal) NeedsLostReason(from DealStage) bool {
+	return d.Stage == DealStageClosedLost && from != DealStageClosedLost && strings.TrimSpace(d.LostReason) == ""
+}
+
+// SyncCloseFields keeps the actual close date and lost reason consistent
+// with the stage after a change from previous. Closing a deal stamps now
+// unless a new close date was given, and only lost deals keep a lost
+// reason; reopening a deal clears both.
+func (d *Deal) SyncCloseFields(previous Deal, now time.Time) {
+	if d.Stage == previous.Stage {
+		return
+	}
+
+	switch {
+	case IsClosedDealStage(d.Stage):
+		if d.ActualCloseDate == nil || timesEqual(d.ActualCloseDate, previous.ActualCloseDate) {
+			d.ActualCloseDate = &now
+		}
+		if d.Stage != DealStageClosedLost {
+			d.LostReason = ""
+		}
+	case IsDealReopen(previous.Stage, d.Stage):
+		d.ActualCloseDate = nil
+		d.LostReason = ""
+	}
+}
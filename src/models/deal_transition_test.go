@@ -0,0 +1,130 @@
+package models_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+func TestCanTransitionDeal(t *testing.T) {
+	const (
+		prospecting   = models.DealStageProspecting
+		qualification = models.DealStageQualification
+		proposal      = models.DealStageProposal
+		negotiation   = models.DealStageNegotiation
+		won           = models.DealStageClosedWon
+		lost          = models.DealStageClosedLost
+	)
+	// Every pair of stages, allowed without and with reopen=true. Open deals
+	// move forward or close; only reopen moves them back, reopens closed
+	// deals or switches them between won and lost.
+	for _, tc := range []struct {
+		from, to        models.DealStage
+		allowed, reopen bool
+	}{
+		{prospecting, prospecting, true, true},
+		{prospecting, qualification, true, true},
+		{prospecting, proposal, true, true},
+		{prospecting, negotiation, true, true},
+		{prospecting, won, true, true},
+		{prospecting, lost, true, true},
+
+		{qualification, prospecting, false, true},
+		{qualification, qualification, true, true},
+		{qualification, proposal, true, true},
+		{qualification, negotiation, true, true},
+		{qualification, won, true, true},
+		{qualification, lost, true, true},
+
+		{proposal, prospecting, false, true},
+		{proposal, qualification, false, true},
+		{proposal, proposal, true, true},
+		{proposal, negotiation, true, true},
+		{proposal, won, true, true},
+		{proposal, lost, true, true},
+
+		{negotiation, prospecting, false, true},
+		{negotiation, qualification, false, true},
+		{negotiation, proposal, false, true},
+		{negotiation, negotiation, true, true},
+		{negotiation, won, true, true},
+		{negotiation, lost, true, true},
+
+		{won, prospecting, false, true},
+		{won, qualification, false, true},
+		{won, proposal, false, true},
+		{won, negotiation, false, true},
+		{won, won, true, true},
+		{won, lost, false, true},
+
+		{lost, prospecting, false, true},
+		{lost, qualification, false, true},
+		{lost, proposal, false, true},
+		{lost, negotiation, false, true},
+		{lost, won, false, true},
+		{lost, lost, true, true},
+	} {
+		t.Run(string(tc.from)+"_to_"+string(tc.to), func(t *testing.T) {
+			if got := models.CanTransitionDeal(tc.from, tc.to, false); got != tc.allowed {
+				t.Errorf("allowed = %v, want %v", got, tc.allowed)
+			}
+			if got := models.CanTransitionDeal(tc.from, tc.to, true); got != tc.reopen {
+				t.Errorf("allowed with reopen = %v, want %v", got, tc.reopen)
+			}
+			// The stages offered in errors agree, without the current stage
+			if got := slices.Contains(models.AllowedDealTransitions(tc.from, false), tc.to); got != (tc.allowed && tc.from != tc.to) {
+				t.Errorf("listed as allowed = %v", got)
+			}
+			if got := slices.Contains(models.AllowedDealTransitions(tc.from, true), tc.to); got != (tc.reopen && tc.from != tc.to) {
+				t.Errorf("listed as allowed with reopen = %v", got)
+			}
+			if got, want := models.IsDealReopen(tc.from, tc.to), models.IsClosedDealStage(tc.from) && !models.IsClosedDealStage(tc.to); got != want {
+				t.Errorf("reopen = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// The table above covers every stage
+	if n := len(models.DealStages()); n != 6 {
+		t.Errorf("%d stages, the table covers 6", n)
+	}
+
+	t.Run("unknown stage", func(t *testing.T) {
+		for _, reopen := range []bool{false, true} {
+			if models.CanTransitionDeal(prospecting, "archived", reopen) {
+				t.Errorf("moving to an unknown stage allowed with reopen=%v", reopen)
+			}
+		}
+	})
+}
+
+// TestCanTransitionDealCustomPipeline checks that transitions follow the
+// active pipeline's order once stages are added
+func TestCanTransitionDealCustomPipeline(t *testing.T) {
+	models.SetDealStages([]models.DealStage{
+		models.DealStageProspecting, "discovery", models.DealStageProposal,
+		models.DealStageClosedWon, models.DealStageClosedLost,
+	})
+	t.Cleanup(func() { models.SetDealStages(nil) })
+
+	for _, tc := range []struct {
+		from, to models.DealStage
+		allowed  bool
+	}{
+		{models.DealStageProspecting, "discovery", true},
+		{"discovery", models.DealStageProposal, true},
+		{"discovery", models.DealStageClosedLost, true},
+		{models.DealStageProposal, "discovery", false},
+		{models.DealStageClosedWon, "discovery", false},
+		// Removed from the pipeline
+		{models.DealStageProspecting, models.DealStageQualification, false},
+	} {
+		if got := models.CanTransitionDeal(tc.from, tc.to, false); got != tc.allowed {
+			t.Errorf("%s to %s: allowed = %v, want %v", tc.from, tc.to, got, tc.allowed)
+		}
+		if want := tc.to != models.DealStageQualification; models.CanTransitionDeal(tc.from, tc.to, true) != want {
+			t.Errorf("%s to %s: allowed with reopen = %v, want %v", tc.from, tc.to, !want, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// TestEffectiveStatusAtDueTime pins on Postgres the boundary of the overdue
+// derivation. NOW() stands still within a transaction, so there an activity
+// due at NOW() is still scheduled while one due a microsecond before is
+// overdue; a later transaction finds both overdue.
+func TestEffectiveStatusAtDueTime(t *testing.T) {
+	db := testdb.Open(t)
+	customer := factory.New(db).Customer(t)
+
+	statuses := func(tx *gorm.DB) map[string]models.ActivityStatus {
+		t.Helper()
+		var activities []models.Activity
+		if err := tx.Scopes(models.WithEffectiveStatus).Find(&activities).Error; err != nil {
+			t.Fatal(err)
+		}
+		statuses := map[string]models.ActivityStatus{}
+		for _, a := range activities {
+			statuses[a.Title] = a.EffectiveStatus
+		}
+		return statuses
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		activities := factory.New(tx)
+		for title, due := range map[string]string{
+			"due now":    "NOW()",
+			"just due":   "NOW() - interval '1 microsecond'",
+			"due later":  "NOW() + interval '1 microsecond'",
+			"closed due": "NOW() - interval '1 hour'",
+		} {
+			status := models.ActivityStatusScheduled
+			if title == "closed due" {
+				status = models.ActivityStatusCompleted
+			}
+			activity := activities.Activity(t, customer, func(a *models.Activity) { a.Title, a.Status = title, status })
+			if err := tx.Exec("UPDATE activities SET due_date = "+due+" WHERE id = ?", activity.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		for title, want := range map[string]models.ActivityStatus{
+			"due now":    models.ActivityStatusScheduled,
+			"just due":   models.ActivityStatusOverdue,
+			"due later":  models.ActivityStatusScheduled,
+			"closed due": models.ActivityStatusCompleted,
+		} {
+			if got := statuses(tx)[title]; got != want {
+				t.Errorf("%s: effective_status = %q, want %q", title, got, want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A moment later the activity due now is overdue too, without its row
+	// being written
+	if got := statuses(db)["due now"]; got != models.ActivityStatusOverdue {
+		t.Errorf("due now, a moment later: effective_status = %q", got)
+	}
+	var stored models.Activity
+	if err := db.Where("title = ?", "due now").First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.ActivityStatusScheduled {
+		t.Errorf("due now: stored status = %q", stored.Status)
+	}
+}
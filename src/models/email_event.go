@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// EmailEventType represents an engagement or delivery event on an outbound
+// email
+type EmailEventType string
+
+const (
+	EmailEventOpen        EmailEventType = "open"
+	EmailEventClick       EmailEventType = "click"
+	EmailEventUnsubscribe EmailEventType = "unsubscribe"
+
+	// Delivery events, reported by the email provider
+	EmailEventDelivered  EmailEventType = "delivered"
+	EmailEventDeferred   EmailEventType = "deferred"
+	EmailEventBounce     EmailEventType = "bounce" // Hard bounce: the address does not accept mail
+	EmailEventSoftBounce EmailEventType = "soft_bounce"
+	EmailEventDropped    EmailEventType = "dropped"
+	EmailEventComplaint  EmailEventType = "complaint"
+)
+
+// DeliveryEventTypes lists the delivery event types
+var DeliveryEventTypes = []EmailEventType{
+	EmailEventDelivered,
+	EmailEventDeferred,
+	EmailEventBounce,
+	EmailEventSoftBounce,
+	EmailEventDropped,
+	EmailEventComplaint,
+}
+
+// IsDeliveryEvent checks if an event type is a delivery event
+func IsDeliveryEvent(eventType EmailEventType) bool {
+	for _, t := range DeliveryEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailEvent records one engagement or delivery event against an email
+// activity. Each tracking token or provider event records at most one
+// event, so replays are ignored.
+type EmailEvent struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	ActivityID      uint           `gorm:"not null;index" json:"activity_id"`
+	Type            EmailEventType `gorm:"size:20;not null;uniqueIndex:idx_email_events_token" json:"type"`
+	Nonce           string         `gorm:"size:64;not null;uniqueIndex:idx_email_events_token" json:"-"`
+	IPAddress       string         `gorm:"size:50" json:"ip_address,omitempty"`
+	UserAgent       string         `gorm:"size:500" json:"user_agent,omitempty"`
+	Reason          string         `gorm:"size:500" json:"reason,omitempty"`                 // Provider reason, for delivery events
+	RecipientDomain string         `gorm:"size:255;index" json:"recipient_domain,omitempty"` // For delivery events
+	CreatedAt       time.Time      `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for EmailEvent
+func (EmailEvent) TableName() string {
+	return "email_events"
+}
+
+// EmailEngagement summarizes engagement on an email activity
+type EmailEngagement struct {
+	Opens         int64      `json:"opens"`
+	Clicks        int64      `json:"clicks"`
+	Unsubscribed  bool       `json:"unsubscribed"`
+	FirstOpenedAt *time.Time `json:"first_opened_at,omitempty"`
+	LastOpenedAt  *time.Time `json:"last_opened_at,omitempty"`
+}
+
+// EmailTrackingTokens are the tracking links to embed in an outbound email
+type EmailTrackingTokens struct {
+	ActivityID       uint               `json:"activity_id"`
+	OpenToken        string             `json:"open_token"`
+	OpenURL          string             `json:"open_url"`
+	UnsubscribeToken string             `json:"unsubscribe_token"`
+	UnsubscribeURL   string             `json:"unsubscribe_url"`
+	Links            []EmailTrackedLink `json:"links,omitempty"`
+}
+
+// EmailTrackedLink is a link of an outbound email rewritten to record a
+// click before redirecting to it
+type EmailTrackedLink struct {
+	URL         string `json:"url"`
+	Token       string `json:"token"`
+	TrackingURL string `json:"tracking_url"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// ExchangeRate represents a currency conversion rate effective from a date
+type ExchangeRate struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	BaseCurrency  string    `gorm:"size:3;not null;index:idx_exchange_rates_pair" json:"base_currency"`
+	QuoteCurrency string    `gorm:"size:3;not null;index:idx_exchange_rates_pair" json:"quote_currency"`
+	Rate          float64   `gorm:"type:decimal(18,8);not null" json:"rate"` // 1 base = rate quote
+	EffectiveDate time.Time `gorm:"type:date;not null" json:"effective_date"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ExchangeRate
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+// ConvertAmount converts an amount using a rate, rounding half away from
+// zero to two decimal places to match the deals.amount column precision.
+// The product is first rounded to millionths, so binary noise such as
+// 1.005 being stored as 1.00499999... does not decide the rounding.
+func ConvertAmount(amount, rate float64) float64 {
+	micros := math.Round(amount * rate * 1e6)
+	return math.Round(micros/1e4) / 100
+}
@@ -0,0 +1,34 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+func TestConvertAmount(t *testing.T) {
+	for _, tc := range []struct {
+		amount, rate, want float64
+	}{
+		{100, 3.75, 375},
+		{1000, 0.26666667, 266.67},
+		{10, 1.0 / 3, 3.33},
+		{20, 1.0 / 3, 6.67},
+		// Halves round away from zero, even when the product is stored
+		// just below them
+		{1.005, 1, 1.01},
+		{2.675, 1, 2.68},
+		{1234.565, 1, 1234.57},
+		{0.125, 1, 0.13},
+		{-1.005, 1, -1.01},
+		{-0.125, 1, -0.13},
+		{0.5, 0.01, 0.01},
+		{0.4, 0.01, 0},
+		{0, 3.75, 0},
+		{9999999.99, 3.75, 37499999.96},
+	} {
+		if got := models.ConvertAmount(tc.amount, tc.rate); got != tc.want {
+			t.Errorf("ConvertAmount(%v, %v) = %v, want %v", tc.amount, tc.rate, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// Export template entities
+const (
+	ExportEntityCustomer = "customer"
+	ExportEntityDeal     = "deal"
+	ExportEntityNote     = "note"
+)
+
+// ValidExportEntities contains all entities export templates can target
+var ValidExportEntities = []string{ExportEntityCustomer, ExportEntityDeal, ExportEntityNote}
+
+// ExportDateFormats maps the date format names accepted by export templates
+// to Go time layouts. Dates are always written in UTC.
+var ExportDateFormats = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"date":     "2006-01-02",
+	"datetime": "2006-01-02 15:04:05",
+	"us":       "01/02/2006",
+	"eu":       "02/01/2006",
+}
+
+// DefaultExportDateFormat is used when a template does not set a date format
+const DefaultExportDateFormat = "rfc3339"
+
+// ExportColumn is one output column of an export template. Key names a
+// column of the entity, such as "amount" or "customer.name"; Label is the
+// CSV header.
+type ExportColumn struct {
+	Key   string `json:"key" binding:"required"`
+	Label string `json:"label,omitempty"`
+}
+
+// ExportTemplate defines the columns, headers and date format of a CSV
+// export. At most one template per entity is the default, used by exports
+// started without a template_id.
+type ExportTemplate struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	Name       string         `gorm:"size:100;not null;uniqueIndex:idx_export_templates_entity_name" json:"name"`
+	Entity     string         `gorm:"size:50;not null;uniqueIndex:idx_export_templates_entity_name" json:"entity"`
+	Columns    []ExportColumn `gorm:"type:jsonb;serializer:json;not null" json:"columns"`
+	DateFormat string         `gorm:"size:20;not null;default:'rfc3339'" json:"date_format"`
+	IsDefault  bool           `gorm:"not null;default:false" json:"is_default"`
+	CreatedBy  uint           `json:"created_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for ExportTemplate
+func (ExportTemplate) TableName() string {
+	return "export_templates"
+}
+
+// Snapshot returns the template as recorded on export jobs
+func (t ExportTemplate) Snapshot() *ExportTemplateSnapshot {
+	return &ExportTemplateSnapshot{
+		ID:         t.ID,
+		Name:       t.Name,
+		Entity:     t.Entity,
+		Columns:    t.Columns,
+		DateFormat: t.DateFormat,
+		UpdatedAt:  t.UpdatedAt,
+	}
+}
+
+// ExportTemplateSnapshot is a copy of a template stored in an export job's
+// params, so the job can be reproduced after the template changes
+type ExportTemplateSnapshot struct {
+	ID         uint           `json:"id"`
+	Name       string         `json:"name"`
+	Entity     string         `json:"entity"`
+	Columns    []ExportColumn `json:"columns"`
+	DateFormat string         `json:"date_format"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// ExportTemplateListResponse is used for export template lists
+type ExportTemplateListResponse struct {
+	Data  []ExportTemplate `json:"data"`
+	Total int64            `json:"total"`
+}
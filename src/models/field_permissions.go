@@ -0,0 +1,161 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Entity names used by field-level permissions
+const (
+	EntityCustomer = "customer"
+	EntityDeal     = "deal"
+)
+
+// FieldRule restricts edits of a single field to roles holding a permission.
+// When OwnerExempt is set, the record's owner may edit the field regardless.
+type FieldRule struct {
+	Permission  string `json:"permission"`
+	OwnerExempt bool   `json:"owner_exempt"`
+}
+
+// EditableFields lists the editable fields of each entity
+var EditableFields = map[string][]string{
+	EntityCustomer: {
+		"name", "email", "phone", "company", "role", "status",
+		"assigned_to", "contacted", "notes", "next_follow_up_at",
+	},
+	EntityDeal: {
+		"title", "description", "customer_id", "contact_id", "stage", "amount",
+		"currency", "probability", "expected_close_date", "actual_close_date",
+		"owner_id", "lost_reason", "external_id",
+	},
+}
+
+// DefaultFieldPermissions only lets managers and admins reassign or change
+// the status/stage of records owned by someone else
+var DefaultFieldPermissions = map[string]map[string]FieldRule{
+	EntityCustomer: {
+		"assigned_to": {Permission: PermissionManageAll, OwnerExempt: true},
+		"status":      {Permission: PermissionManageAll, OwnerExempt: true},
+	},
+	EntityDeal: {
+		"owner_id": {Permission: PermissionManageAll, OwnerExempt: true},
+		"stage":    {Permission: PermissionManageAll, OwnerExempt: true},
+	},
+}
+
+// FieldPermissions is the active field permission map
+var FieldPermissions = DefaultFieldPermissions
+
+// ParseFieldPermissions parses a comma-separated list of rules in the form
+// entity.field=permission[:owner], e.g. "customer.status=manage_all:owner".
+// An empty string yields the default rules.
+func ParseFieldPermissions(spec string) (map[string]map[string]FieldRule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return DefaultFieldPermissions, nil
+	}
+
+	rules := make(map[string]map[string]FieldRule)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, permission, ok := strings.Cut(entry, "=")
+		entity, field, ok2 := strings.Cut(target, ".")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid field permission %q, expected entity.field=permission[:owner]", entry)
+		}
+		if !isEditableField(entity, field) {
+			return nil, fmt.Errorf("unknown field %q in field permission %q", target, entry)
+		}
+
+		rule := FieldRule{Permission: permission}
+		if p, flag, found := strings.Cut(permission, ":"); found {
+			if flag != "owner" {
+				return nil, fmt.Errorf("invalid field permission flag %q in %q", flag, entry)
+			}
+			rule = FieldRule{Permission: p, OwnerExempt: true}
+		}
+
+		if rules[entity] == nil {
+			rules[entity] = make(map[string]FieldRule)
+		}
+		rules[entity][field] = rule
+	}
+
+	return rules, nil
+}
+
+// ForbiddenFields returns the changed fields the role may not edit on a record
+func ForbiddenFields(entity, role string, isOwner bool, changed []string) []string {
+	var forbidden []string
+	for _, field := range changed {
+		if !CanEditField(entity, field, role, isOwner) {
+			forbidden = append(forbidden, field)
+		}
+	}
+	sort.Strings(forbidden)
+	return forbidden
+}
+
+// CanEditField checks whether a role may edit a field on a record
+func CanEditField(entity, field, role string, isOwner bool) bool {
+	rule, restricted := FieldPermissions[entity][field]
+	if !restricted {
+		return true
+	}
+	if rule.OwnerExempt && isOwner {
+		return true
+	}
+	return HasPermission(role, rule.Permission)
+}
+
+// FieldCapability describes whether the current user can edit a field
+type FieldCapability struct {
+	Editable          bool `json:"editable"`
+	EditableWhenOwner bool `json:"editable_when_owner"`
+}
+
+// EntityCapabilities lists per-field capabilities for an entity
+type EntityCapabilities struct {
+	Fields map[string]FieldCapability `json:"fields"`
+}
+
+// CapabilitiesResponse is the response for GET /admin/me/capabilities
+type CapabilitiesResponse struct {
+	Role           string                        `json:"role"`
+	Permissions    []string                      `json:"permissions"`
+	Entities       map[string]EntityCapabilities `json:"entities"`
+	ActivityPolicy ActivityPolicyCapabilities    `json:"activity_policy"`
+	OpenDealLimit  OpenDealLimitCapabilities     `json:"open_deal_limit"`
+	ChangeReasons  []ChangeReasonRule            `json:"change_reasons"`
+}
+
+// BuildCapabilities computes field capabilities for a role
+func BuildCapabilities(role string) map[string]EntityCapabilities {
+	entities := make(map[string]EntityCapabilities, len(EditableFields))
+	for entity, fields := range EditableFields {
+		caps := EntityCapabilities{Fields: make(map[string]FieldCapability, len(fields))}
+		for _, field := range fields {
+			caps.Fields[field] = FieldCapability{
+				Editable:          CanEditField(entity, field, role, false),
+				EditableWhenOwner: CanEditField(entity, field, role, true),
+			}
+		}
+		entities[entity] = caps
+	}
+	return entities
+}
+
+// isEditableField checks whether a field is a known editable field of an entity
+func isEditableField(entity, field string) bool {
+	for _, f := range EditableFields[entity] {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Holiday is a non-working date in the business calendar. Holidays without a
+// region apply to every region.
+type Holiday struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex:idx_holidays_date_region" json:"date"`
+	Region    string    `gorm:"size:50;not null;default:'';uniqueIndex:idx_holidays_date_region" json:"region"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Holiday
+func (Holiday) TableName() string {
+	return "holidays"
+}
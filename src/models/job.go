@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// JobStatus represents the state of an async job
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// Job is an async unit of work, such as a CSV export, run in the background
+// on behalf of a user
+type Job struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	Type          string        `gorm:"size:100;not null;index" json:"type"`
+	Status        JobStatus     `gorm:"size:20;not null;index" json:"status"`
+	Params        string        `gorm:"type:jsonb;default:null" json:"params,omitempty"`
+	Error         string        `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy     uint          `gorm:"not null;index" json:"created_by"`
+	CreatedByName string        `gorm:"size:255" json:"created_by_name,omitempty"`
+	TemplateID    *uint         `gorm:"index" json:"template_id,omitempty"`              // Export template; its snapshot is kept in Params
+	Result        string        `gorm:"type:jsonb;default:null" json:"result,omitempty"` // Outcome of jobs without a file, such as smoke tests
+	Artifact      JobArtifact   `gorm:"embedded;embeddedPrefix:artifact_" json:"artifact"`
+	Checkpoint    JobCheckpoint `gorm:"embedded;embeddedPrefix:checkpoint_" json:"checkpoint"`
+	Resumes       int           `gorm:"not null;default:0" json:"resumes"` // Times the job was resumed after failing
+	StartedAt     *time.Time    `json:"started_at,omitempty"`
+	FinishedAt    *time.Time    `json:"finished_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// JobArtifact describes the file produced by a job. Clients can verify a
+// download against Bytes and Checksum.
+type JobArtifact struct {
+	Key         string     `gorm:"size:255" json:"-"`
+	FileName    string     `gorm:"size:255" json:"file_name,omitempty"`
+	ContentType string     `gorm:"size:100" json:"content_type,omitempty"`
+	Rows        int64      `json:"rows"`
+	Bytes       int64      `json:"bytes"`
+	Checksum    string     `gorm:"size:64" json:"checksum,omitempty"` // hex-encoded SHA-256
+	Resumes     int        `gorm:"not null;default:0" json:"resumes"` // 0 when the file was produced in one pass
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // Set once the expired file is removed
+}
+
+// JobCheckpoint records how far a resumable export got. The rows written up
+// to Cursor are stored in Parts files whose concatenation is Bytes long and
+// hashes to Checksum, so a resumed job verifies them and continues after
+// Cursor instead of starting over.
+type JobCheckpoint struct {
+	Parts    int        `gorm:"not null;default:0" json:"-"`
+	Rows     int64      `gorm:"not null;default:0" json:"rows"`
+	Bytes    int64      `gorm:"not null;default:0" json:"bytes"`
+	Checksum string     `gorm:"size:64" json:"checksum,omitempty"` // hex-encoded SHA-256 of the stored parts
+	Cursor   string     `gorm:"type:jsonb;default:null" json:"-"`  // Keyset of the last row written
+	At       *time.Time `json:"at,omitempty"`
+}
+
+// TableName specifies the table name for Job
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// IsFinished reports whether the job has completed or failed
+func (j Job) IsFinished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed
+}
+
+// JobListResponse is used for paginated job lists
+type JobListResponse struct {
+	Data       []Job `json:"data"`
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	TotalPages int   `json:"total_pages"`
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LongTextSoftLimit is the size in bytes above which a long text field is
+// still written but the response carries a Warning header; 0 disables the
+// warning
+var LongTextSoftLimit = 0
+
+// LongTextHardLimit is the size in bytes above which writing a long text
+// field is refused; 0 means no limit
+var LongTextHardLimit = 0
+
+// LongText is a model with free-text fields, such as descriptions and
+// notes, whose size is limited on write and which lists serialize as a
+// preview
+type LongText interface {
+	// LongTextFields returns the long text fields by JSON name
+	LongTextFields() map[string]string
+	// SetPreviewLength sets the number of characters the long text fields
+	// are serialized with; 0 serializes them in full
+	SetPreviewLength(n int)
+}
+
+// OversizedFields returns the fields of a model longer than limit bytes,
+// sorted. A limit of 0 allows any size.
+func OversizedFields(model LongText, limit int) []string {
+	if limit <= 0 {
+		return nil
+	}
+	var fields []string
+	for field, value := range model.LongTextFields() {
+		if len(value) > limit {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// TruncateText returns the first n characters of s, cut back to the last
+// word boundary when one falls in their second half, and whether s was
+// shortened. Characters are runes, so multi-byte text is never split, and
+// the cut moves back before a letter whose marks, such as Arabic
+// diacritics, would otherwise be dropped.
+func TruncateText(s string, n int) (string, bool) {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+
+	cut, count := len(s), 0
+	for i := range s {
+		if count == n {
+			cut = i
+			break
+		}
+		count++
+	}
+
+	for cut > 0 && cut < len(s) {
+		mark, _ := utf8.DecodeRuneInString(s[cut:])
+		if !unicode.Is(unicode.Mn, mark) {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(s[:cut])
+		cut -= size
+	}
+
+	preview := s[:cut]
+	next, _ := utf8.DecodeRuneInString(s[cut:])
+	if !unicode.IsSpace(next) {
+		if i := strings.LastIndexFunc(preview, unicode.IsSpace); i >= 0 && utf8.RuneCountInString(preview[:i]) >= n/2 {
+			preview = preview[:i]
+		}
+	}
+	return strings.TrimRightFunc(preview, unicode.IsSpace), true
+}
+
+// marshalModel serializes value, replacing the redacted fields with null.
+// With a preview length, each long text field longer than it is replaced
+// by its preview and followed by <field>_truncated and <field>_length, the
+// full length in characters.
+func marshalModel(value interface{}, redacted []string, long map[string]string, previewLength int) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil || (len(redacted) == 0 && previewLength <= 0) {
+		return data, err
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	if previewLength > 0 {
+		for field, text := range long {
+			preview, truncated := TruncateText(text, previewLength)
+			if !truncated {
+				continue
+			}
+			if object[field], err = json.Marshal(preview); err != nil {
+				return nil, err
+			}
+			object[field+"_truncated"] = json.RawMessage("true")
+			object[field+"_length"] = json.RawMessage(strconv.Itoa(utf8.RuneCountInString(text)))
+		}
+	}
+	for _, field := range redacted {
+		object[field] = json.RawMessage("null")
+		delete(object, field+"_truncated")
+		delete(object, field+"_length")
+	}
+	return json.Marshal(object)
+}
+
+// LongTextFields returns the customer's notes
+func (c Customer) LongTextFields() map[string]string {
+	return map[string]string{"notes": c.Notes}
+}
+
+// SetPreviewLength sets the length the customer's notes are serialized with
+func (c *Customer) SetPreviewLength(n int) {
+	c.PreviewLength = n
+}
+
+// LongTextFields returns the contact's notes
+func (c Contact) LongTextFields() map[string]string {
+	return map[string]string{"notes": c.Notes}
+}
+
+// SetPreviewLength sets the length the contact's notes are serialized with
+func (c *Contact) SetPreviewLength(n int) {
+	c.PreviewLength = n
+}
+
+// MarshalJSON serializes the contact, writing its notes as a preview when a
+// preview length is set
+func (c Contact) MarshalJSON() ([]byte, error) {
+	type contact Contact
+	return marshalModel(contact(c), nil, c.LongTextFields(), c.PreviewLength)
+}
+
+// LongTextFields returns the deal's description
+func (d Deal) LongTextFields() map[string]string {
+	return map[string]string{"description": d.Description}
+}
+
+// SetPreviewLength sets the length the deal's description is serialized with
+func (d *Deal) SetPreviewLength(n int) {
+	d.PreviewLength = n
+}
+
+// LongTextFields returns the activity's description and outcome
+func (a Activity) LongTextFields() map[string]string {
+	return map[string]string{"description": a.Description, "outcome": a.Outcome}
+}
+
+// SetPreviewLength sets the length the activity's description and outcome
+// are serialized with
+func (a *Activity) SetPreviewLength(n int) {
+	a.PreviewLength = n
+}
+
+// MarshalJSON serializes the activity, writing its description and outcome
+// as a preview when a preview length is set
+func (a Activity) MarshalJSON() ([]byte, error) {
+	type activity Activity
+	return marshalModel(activity(a), nil, a.LongTextFields(), a.PreviewLength)
+}
+
+// LongTextFields returns the note's content
+func (n Note) LongTextFields() map[string]string {
+	return map[string]string{"content": n.Content}
+}
+
+// SetPreviewLength sets the length the note's content is serialized with
+func (n *Note) SetPreviewLength(length int) {
+	n.PreviewLength = length
+}
+
+// MarshalJSON serializes the note, writing its content as a preview when a
+// preview length is set
+func (n Note) MarshalJSON() ([]byte, error) {
+	type note Note
+	return marshalModel(note(n), nil, n.LongTextFields(), n.PreviewLength)
+}
@@ -0,0 +1,109 @@
+package models_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// arabic is 31 characters of two-byte runes: "Welcome to the customer
+// relationship management system"
+const arabic = "مرحبا بكم في نظام إدارة العملاء"
+
+func TestTruncateText(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		text      string
+		n         int
+		want      string
+		truncated bool
+	}{
+		{"shorter", arabic, 40, arabic, false},
+		{"exact length", arabic, 31, arabic, false},
+		{"no limit", arabic, 0, arabic, false},
+		{"back to a word boundary", arabic, 15, "مرحبا بكم في", true},
+		{"at a word boundary", arabic, 12, "مرحبا بكم في", true},
+		{"boundary in the second half", arabic, 8, "مرحبا", true},
+		{"no boundary in the second half", arabic, 3, "مرح", true},
+		{"single word", "العملاءالعملاء", 9, "العملاءال", true},
+		{"diacritics kept with their letter", "مُحَمَّدٌ رَسُولٌ", 6, "مُحَ", true},
+		{"four-byte runes", "🚀🚀🚀🚀 عرض", 3, "🚀🚀🚀", true},
+		{"four-byte runes to a boundary", "عرض 🚀🚀🚀🚀", 6, "عرض", true},
+		{"mixed scripts", "Deal صفقة كبيرة", 11, "Deal صفقة", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, truncated := models.TruncateText(tc.text, tc.n)
+			if got != tc.want || truncated != tc.truncated {
+				t.Errorf("TruncateText(%q, %d) = %q, %v, want %q, %v", tc.text, tc.n, got, truncated, tc.want, tc.truncated)
+			}
+		})
+	}
+}
+
+// TestTruncateTextNeverSplitsRunes truncates at every length, checking
+// that no rune is split and no letter loses its marks
+func TestTruncateTextNeverSplitsRunes(t *testing.T) {
+	for _, text := range []string{arabic, "مُحَمَّدٌ رَسُولٌ", "عرض 🚀🚀🚀🚀", strings.Repeat("ب", 50)} {
+		for n := 1; n <= utf8.RuneCountInString(text); n++ {
+			got, _ := models.TruncateText(text, n)
+			next, _ := utf8.DecodeRuneInString(strings.TrimPrefix(text, got))
+			if !utf8.ValidString(got) || !strings.HasPrefix(text, got) || utf8.RuneCountInString(got) > n || unicode.Is(unicode.Mn, next) {
+				t.Errorf("TruncateText(%q, %d) = %q", text, n, got)
+			}
+		}
+	}
+}
+
+func TestLongTextPreviewJSON(t *testing.T) {
+	activity := models.Activity{Title: "مكالمة", Description: arabic, Outcome: "تم"}
+	activity.SetPreviewLength(15)
+	data, err := json.Marshal(activity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.Valid(data) {
+		t.Fatalf("invalid UTF-8: %q", data)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["description"] != "مرحبا بكم في" || got["description_truncated"] != true || got["description_length"] != float64(31) {
+		t.Errorf("description = %q, truncated %v, length %v", got["description"], got["description_truncated"], got["description_length"])
+	}
+	if got["outcome"] != "تم" || got["outcome_truncated"] != nil || got["title"] != "مكالمة" {
+		t.Errorf("short fields = %v", got)
+	}
+
+	// Without a preview length the fields are serialized in full
+	activity.SetPreviewLength(0)
+	if data, err = json.Marshal(activity); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"description":"`+arabic+`"`) || strings.Contains(string(data), "_truncated") {
+		t.Errorf("full activity = %s", data)
+	}
+}
+
+func TestOversizedFields(t *testing.T) {
+	note := models.Note{Content: arabic} // 57 bytes
+	for _, tc := range []struct {
+		limit int
+		want  []string
+	}{
+		{0, nil},
+		{57, nil},
+		{56, []string{"content"}},
+		{31, []string{"content"}}, // The limit counts bytes, not characters
+	} {
+		got := models.OversizedFields(&note, tc.limit)
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("limit %d: %v, want %v", tc.limit, got, tc.want)
+		}
+	}
+}
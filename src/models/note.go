@@ -1,14 +1,26 @@
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+)
+
 // Note represents a note/comment attached to a customer or deal
 type Note struct {
 	BaseModel
-	Content    string `gorm:"type:text;not null" json:"content"`
-	CustomerID *uint  `gorm:"index" json:"customer_id,omitempty"`
-	DealID     *uint  `gorm:"index" json:"deal_id,omitempty"`
-	ActivityID *uint  `gorm:"index" json:"activity_id,omitempty"`
-	AuthorID   uint   `gorm:"not null" json:"author_id"`
-	AuthorName string `gorm:"size:255" json:"author_name,omitempty"`
+	Content     string `gorm:"type:text;not null" json:"content"`
+	ContentHash string `gorm:"size:64;not null;default:'';index" json:"-"` // SHA-256 of Content, for duplicate detection
+	CustomerID  *uint  `gorm:"index" json:"customer_id,omitempty"`
+	DealID      *uint  `gorm:"index" json:"deal_id,omitempty"`
+	ActivityID  *uint  `gorm:"index" json:"activity_id,omitempty"`
+	AuthorID    uint   `gorm:"not null" json:"author_id"` // 0 for imported notes
+	AuthorName  string `gorm:"size:255" json:"author_name,omitempty"`
+	Imported    bool   `gorm:"not null;default:false" json:"imported"` // Migrated from another system; never triggers automations
+
+	// Characters long text is serialized with, 0 for in full (see LongText)
+	PreviewLength int `gorm:"-" json:"-"`
 
 	// Relations
 	Customer *Customer `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
@@ -20,6 +32,18 @@ func (Note) TableName() string {
 	return "notes"
 }
 
+// NoteContentHash returns the hex-encoded SHA-256 of note content
+func NoteContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// NotImportedNotes is a query scope excluding notes migrated from another
+// system. Automations reacting to recent notes must use it.
+func NotImportedNotes(db *gorm.DB) *gorm.DB {
+	return db.Where("notes.imported = ?", false)
+}
+
 // NoteListResponse is used for paginated note lists
 type NoteListResponse struct {
 	Data       []Note `json:"data"`
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PhoneKeyDigits is how many trailing digits identify a phone number. Only
+// the trailing digits are compared, so a number stored in national form
+// matches the same number dialed with its country code.
+const PhoneKeyDigits = 9
+
+// PhoneKeyMinDigits is the fewest digits a number needs to be matched;
+// shorter numbers are extensions or short codes
+const PhoneKeyMinDigits = 7
+
+// PhoneKey returns the key a phone number is matched on: its trailing
+// digits, ignoring spaces, punctuation and prefixes. It returns "" for
+// numbers too short to match.
+func PhoneKey(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+
+	key := digits.String()
+	if len(key) < PhoneKeyMinDigits {
+		return ""
+	}
+	if len(key) > PhoneKeyDigits {
+		key = key[len(key)-PhoneKeyDigits:]
+	}
+	return key
+}
+
+// PhoneKeySQL returns the SQL expression computing the phone key of a
+// column, as indexed on customers and contacts
+func PhoneKeySQL(column string) string {
+	return fmt.Sprintf("RIGHT(REGEXP_REPLACE(%s, '[^0-9]', '', 'g'), %d)", column, PhoneKeyDigits)
+}
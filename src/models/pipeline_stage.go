@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// dealStages holds the active pipeline stages in board order
+var (
+	dealStagesMu sync.RWMutex
+	dealStages   = ValidDealStages
+)
+
+// ReservedDealStages are the stages the service relies on: new deals start
+// in prospecting and close as won or lost. They cannot be renamed,
+// deactivated or deleted.
+var ReservedDealStages = []DealStage{DealStageProspecting, DealStageClosedWon, DealStageClosedLost}
+
+// stageName matches the names a pipeline stage may have
+var stageName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// SetDealStages replaces the active pipeline stages, in board order. An
+// empty list restores the built-in stages.
+func SetDealStages(stages []DealStage) {
+	if len(stages) == 0 {
+		stages = ValidDealStages
+	}
+	dealStagesMu.Lock()
+	defer dealStagesMu.Unlock()
+	dealStages = stages
+}
+
+// DealStages returns the active pipeline stages in board order
+func DealStages() []DealStage {
+	dealStagesMu.RLock()
+	defer dealStagesMu.RUnlock()
+	return slices.Clone(dealStages)
+}
+
+// IsReservedDealStage reports whether a stage is one of ReservedDealStages
+func IsReservedDealStage(stage DealStage) bool {
+	return slices.Contains(ReservedDealStages, stage)
+}
+
+// ValidateStageName checks that a pipeline stage name is lowercase letters,
+// digits and underscores, starting with a letter, as stored on deals
+func ValidateStageName(name string) error {
+	if !stageName.MatchString(name) {
+		return fmt.Errorf("stage name %q must be 1-50 lowercase letters, digits or underscores, starting with a letter", name)
+	}
+	return nil
+}
+
+// PipelineStageListResponse is the response listing pipeline stages
+type PipelineStageListResponse struct {
+	Data []PipelineStage `json:"data"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// QuotaUsage reports record usage of one entity against its limit. Limit and
+// Remaining are omitted when the entity is unlimited.
+type QuotaUsage struct {
+	Entity    string `json:"entity"`
+	Usage     int64  `json:"usage"`
+	Limit     *int64 `json:"limit,omitempty"`
+	Remaining *int64 `json:"remaining,omitempty"`
+}
+
+// UsageResponse is used for the usage endpoint
+type UsageResponse struct {
+	Data         []QuotaUsage `json:"data"`
+	ReconciledAt *time.Time   `json:"reconciled_at,omitempty"`
+}
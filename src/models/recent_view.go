@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// Recent view entity types
+const (
+	RecentViewCustomer = "customer"
+	RecentViewDeal     = "deal"
+)
+
+// RecentView records the last time a user opened a customer or deal.
+// Each user/entity pair keeps a single row.
+type RecentView struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_recent_views_entity" json:"user_id"`
+	EntityType string    `gorm:"size:50;not null;uniqueIndex:idx_recent_views_entity" json:"entity_type"`
+	EntityID   uint      `gorm:"not null;uniqueIndex:idx_recent_views_entity" json:"entity_id"`
+	ViewedAt   time.Time `gorm:"not null;index" json:"viewed_at"`
+}
+
+// TableName specifies the table name for RecentView
+func (RecentView) TableName() string {
+	return "recent_views"
+}
+
+// RecentViewItem is a recently viewed entity with a lightweight summary
+type RecentViewItem struct {
+	EntityType string      `json:"entity_type"`
+	EntityID   uint        `json:"entity_id"`
+	ViewedAt   time.Time   `json:"viewed_at"`
+	Summary    interface{} `json:"summary"`
+}
+
+// RecentCustomerSummary is the summary shown for a recently viewed customer
+type RecentCustomerSummary struct {
+	ID      uint           `json:"id"`
+	Name    string         `json:"name"`
+	Email   string         `json:"email"`
+	Company string         `json:"company,omitempty"`
+	Status  CustomerStatus `json:"status"`
+}
+
+// RecentDealSummary is the summary shown for a recently viewed deal
+type RecentDealSummary struct {
+	ID         uint      `json:"id"`
+	Title      string    `json:"title"`
+	CustomerID uint      `json:"customer_id"`
+	Stage      DealStage `json:"stage"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	OwnerID    *uint     `json:"-"`
+
+	// Fields hidden from the requesting user, serialized as null
+	RedactedFields []string `json:"redacted_fields,omitempty"`
+}
+
+// RecentViewListResponse is the response for GET /admin/me/recent
+type RecentViewListResponse struct {
+	Data []RecentViewItem `json:"data"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import "encoding/json"
+
+// RedactionEntity names the customer for field redaction rules
+func (Customer) RedactionEntity() string {
+	return EntityCustomer
+}
+
+// RedactionOwner returns the user the customer is assigned to
+func (c Customer) RedactionOwner() *uint {
+	return c.AssignedTo
+}
+
+// SetRedactedFields sets the fields hidden from the requesting user
+func (c *Customer) SetRedactedFields(fields []string) {
+	c.RedactedFields = fields
+}
+
+// MarshalJSON serializes the customer, writing redacted fields as null
+// and long text as a preview when a preview length is set
+func (c Customer) MarshalJSON() ([]byte, error) {
+	type customer Customer
+	return marshalModel(customer(c), c.RedactedFields, c.LongTextFields(), c.PreviewLength)
+}
+
+// RedactionEntity names the deal for field redaction rules
+func (Deal) RedactionEntity() string {
+	return EntityDeal
+}
+
+// RedactionOwner returns the deal's owner
+func (d Deal) RedactionOwner() *uint {
+	return d.OwnerID
+}
+
+// SetRedactedFields sets the fields hidden from the requesting user
+func (d *Deal) SetRedactedFields(fields []string) {
+	d.RedactedFields = fields
+}
+
+// MarshalJSON serializes the deal, writing redacted fields as null and
+// long text as a preview when a preview length is set
+func (d Deal) MarshalJSON() ([]byte, error) {
+	type deal Deal
+	return marshalModel(deal(d), d.RedactedFields, d.LongTextFields(), d.PreviewLength)
+}
+
+// RedactionEntity names the deal summary for field redaction rules
+func (RecentDealSummary) RedactionEntity() string {
+	return EntityDeal
+}
+
+// RedactionOwner returns the deal's owner
+func (s RecentDealSummary) RedactionOwner() *uint {
+	return s.OwnerID
+}
+
+// SetRedactedFields sets the fields hidden from the requesting user
+func (s *RecentDealSummary) SetRedactedFields(fields []string) {
+	s.RedactedFields = fields
+}
+
+// MarshalJSON serializes the deal summary, writing redacted fields as null
+func (s RecentDealSummary) MarshalJSON() ([]byte, error) {
+	type summary RecentDealSummary
+	return marshalModel(summary(s), s.RedactedFields, nil, 0)
+}
+
+// MarshalJSON serializes the customer with its related summaries. It is
+// needed because the embedded customer's MarshalJSON would otherwise
+// serialize the customer alone.
+func (r CustomerDetailResponse) MarshalJSON() ([]byte, error) {
+	return MarshalExtended(r.Customer, struct {
+		ContactsCount           int             `json:"contacts_count"`
+		OpenDealsCount          int             `json:"open_deals_count"`
+		UpcomingActivitiesCount int             `json:"upcoming_activities_count"`
+		RecentActivities        []Activity      `json:"recent_activities,omitempty"`
+		DomainMatesCount        int64           `json:"domain_mates_count"`
+		DomainMates             []CompanyMember `json:"domain_mates,omitempty"`
+	}{r.ContactsCount, r.OpenDealsCount, r.UpcomingActivitiesCount, r.RecentActivities, r.DomainMatesCount, r.DomainMates})
+}
+
+// MarshalExtended serializes a model with its own MarshalJSON followed by
+// the fields of extra, for responses that embed the model
+func MarshalExtended(model json.Marshaler, extra interface{}) ([]byte, error) {
+	base, err := model.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	more, err := json.Marshal(extra)
+	if err != nil {
+		return nil, err
+	}
+	if len(more) <= 2 {
+		return base, nil
+	}
+	if len(base) <= 2 {
+		return more, nil
+	}
+	extended := append(base[:len(base)-1:len(base)-1], ',')
+	return append(extended, more[1:]...), nil
+}
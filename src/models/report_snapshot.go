@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// ReportRollupState records the last rollup of the report snapshots; there
+// is one row. Snapshot days are dates in Timezone and cover every day
+// before Through.
+type ReportRollupState struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Timezone   string    `gorm:"size:64;not null" json:"timezone"`
+	Through    time.Time `gorm:"type:date;not null" json:"through"` // First day not in the snapshots, the day of the rollup
+	RolledUpAt time.Time `gorm:"not null" json:"rolled_up_at"`
+}
+
+// TableName specifies the table name for ReportRollupState
+func (ReportRollupState) TableName() string {
+	return "report_rollup_state"
+}
+
+// ReportRollupDay is a snapshot day whose records changed since the last
+// rollup. Triggers on deals, customers and customer_tags queue the days a
+// change affects, each day once until a rollup claims it; the rollup
+// recomputes the days it claimed.
+type ReportRollupDay struct {
+	ID      uint64    `gorm:"primaryKey"`
+	Day     time.Time `gorm:"type:date;not null;index;uniqueIndex:idx_report_rollup_days_unclaimed,where:NOT claimed"`
+	Claimed bool      `gorm:"not null;default:false"`
+}
+
+// TableName specifies the table name for ReportRollupDay
+func (ReportRollupDay) TableName() string {
+	return "report_rollup_days"
+}
+
+// ReportCustomerDay counts the unarchived customers created on a day by
+// their current status, for the funnel report
+type ReportCustomerDay struct {
+	ID        uint      `gorm:"primaryKey"`
+	Day       time.Time `gorm:"type:date;not null;index"`
+	Status    string    `gorm:"size:20;not null"`
+	Customers int64     `gorm:"not null"`
+}
+
+// TableName specifies the table name for ReportCustomerDay
+func (ReportCustomerDay) TableName() string {
+	return "report_customer_days"
+}
+
+// ReportDealDay counts the unarchived deals created on a day by their
+// owner and current stage, for the funnel report
+type ReportDealDay struct {
+	ID      uint      `gorm:"primaryKey"`
+	Day     time.Time `gorm:"type:date;not null;index"`
+	OwnerID *uint
+	Stage   string `gorm:"size:50;not null"`
+	Deals   int64  `gorm:"not null"`
+}
+
+// TableName specifies the table name for ReportDealDay
+func (ReportDealDay) TableName() string {
+	return "report_deal_days"
+}
+
+// ReportRevenueDay sums the deals won on a day, archived ones included, by
+// owner and currency, for the revenue report
+type ReportRevenueDay struct {
+	ID       uint      `gorm:"primaryKey"`
+	Day      time.Time `gorm:"type:date;not null;index"`
+	OwnerID  *uint
+	Currency string  `gorm:"size:3"`
+	WonCount int64   `gorm:"not null"`
+	WonTotal float64 `gorm:"type:numeric(20,2);not null"`
+}
+
+// TableName specifies the table name for ReportRevenueDay
+func (ReportRevenueDay) TableName() string {
+	return "report_revenue_days"
+}
+
+// ReportSegmentDay sums the unarchived deals won on a day by the tags of
+// their customer, for the segments report. A nil TagID is the untagged
+// segment.
+type ReportSegmentDay struct {
+	ID       uint      `gorm:"primaryKey"`
+	Day      time.Time `gorm:"type:date;not null;index"`
+	TagID    *uint
+	WonCount int64   `gorm:"not null"`
+	WonTotal float64 `gorm:"type:numeric(20,2);not null"`
+}
+
+// TableName specifies the table name for ReportSegmentDay
+func (ReportSegmentDay) TableName() string {
+	return "report_segment_days"
+}
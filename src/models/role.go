@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Permissions lists every permission a role can be granted
+var Permissions = []string{PermissionRead, PermissionWrite, PermissionDelete, PermissionManageAll, PermissionManageOwn, PermissionAdminister}
+
+// roleNamePattern restricts role names to the form used in JWT role claims
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// Role is a named permission set. Users get their role from the JWT role
+// claim, service accounts from their definition.
+type Role struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:50;uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	Permissions []string  `gorm:"type:jsonb;serializer:json;not null" json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Role
+func (Role) TableName() string {
+	return "roles"
+}
+
+// IsBuiltInRole reports whether a role is one the service relies on by name
+func IsBuiltInRole(name string) bool {
+	_, exists := DefaultRolePermissions[name]
+	return exists
+}
+
+// ValidateRoleName checks that a role name can be used in a role claim
+func ValidateRoleName(name string) error {
+	if !roleNamePattern.MatchString(name) {
+		return fmt.Errorf("role name %q must start with a lowercase letter and contain only lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// ValidatePermissions checks that every permission is known
+func ValidatePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		if !slices.Contains(Permissions, permission) {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+	return nil
+}
+
+// RoleListResponse is used for the role list
+type RoleListResponse struct {
+	Data []Role `json:"data"`
+}
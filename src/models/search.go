@@ -0,0 +1,52 @@
+package models
+
+// Search result types
+const (
+	SearchTypeCustomer = "customer"
+	SearchTypeContact  = "contact"
+	SearchTypeDeal     = "deal"
+	SearchTypeActivity = "activity"
+)
+
+// SearchTypes lists the searchable record types. Reindex checkpoints refer
+// to their order, so new types go last.
+var SearchTypes = []string{SearchTypeCustomer, SearchTypeContact, SearchTypeDeal, SearchTypeActivity}
+
+// SearchResult is one ranked match of the global search
+type SearchResult struct {
+	Type     string   `json:"type"`
+	ID       uint     `json:"id"`
+	Title    string   `json:"title"`
+	Subtitle string   `json:"subtitle,omitempty"`
+	Score    *float64 `json:"score,omitempty"` // Only returned in development, for tuning weights
+}
+
+// SearchResponse is the response of the global search
+type SearchResponse struct {
+	Data  []SearchResult `json:"data"`
+	Query string         `json:"query"`
+	Meta  SearchMeta     `json:"meta"`
+}
+
+// SearchGroup is the best matches of one record type and how many records
+// of the type match in total
+type SearchGroup struct {
+	Type  string         `json:"type"`
+	Total int64          `json:"total"`
+	Data  []SearchResult `json:"data"`
+}
+
+// SearchGroupsResponse is the response of the global search grouped by type
+type SearchGroupsResponse struct {
+	Data  []SearchGroup `json:"data"`
+	Query string        `json:"query"`
+	Meta  SearchMeta    `json:"meta"`
+}
+
+// SearchMeta describes how a search was answered
+type SearchMeta struct {
+	Engine   string `json:"engine"`             // Engine that answered: postgres or opensearch
+	Degraded bool   `json:"degraded,omitempty"` // The configured engine failed and Postgres answered instead
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"` // Per type when grouped
+}
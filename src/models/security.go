@@ -0,0 +1,302 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecurityMetric names a per-user operation counter watched for anomalies
+type SecurityMetric string
+
+const (
+	SecurityMetricReads          SecurityMetric = "reads"           // GET requests
+	SecurityMetricWrites         SecurityMetric = "writes"          // POST, PUT and PATCH requests other than exports
+	SecurityMetricDeletes        SecurityMetric = "deletes"         // DELETE requests
+	SecurityMetricExports        SecurityMetric = "exports"         // Export requests and artifact downloads
+	SecurityMetricRecordsChanged SecurityMetric = "records_changed" // Audited creates, updates and other changes
+	SecurityMetricRecordsDeleted SecurityMetric = "records_deleted" // Audited deletions, one per record
+)
+
+// ValidSecurityMetrics contains all security metrics for validation
+var ValidSecurityMetrics = []SecurityMetric{
+	SecurityMetricReads,
+	SecurityMetricWrites,
+	SecurityMetricDeletes,
+	SecurityMetricExports,
+	SecurityMetricRecordsChanged,
+	SecurityMetricRecordsDeleted,
+}
+
+// SecurityExportEndpoints are the endpoints counted as exports rather than
+// reads or writes. Endpoints that download only when asked for a format are
+// listed with their format query, as the activity tracker records them.
+var SecurityExportEndpoints = []string{
+	"POST /admin/jobs/exports",
+	"POST /admin/jobs/:id/resume",
+	"GET /admin/jobs/:id/download",
+	"GET /admin/customers/export",
+	"GET /admin/notes/export",
+	"GET /admin/reports/segments?format=csv",
+}
+
+// SecurityMetricForEndpoint returns the request counter an endpoint such as
+// "DELETE /admin/customers/:id" adds to
+func SecurityMetricForEndpoint(endpoint string) (SecurityMetric, bool) {
+	if slices.Contains(SecurityExportEndpoints, endpoint) {
+		return SecurityMetricExports, true
+	}
+	method, _, _ := strings.Cut(endpoint, " ")
+	switch method {
+	case "GET":
+		return SecurityMetricReads, true
+	case "POST", "PUT", "PATCH":
+		return SecurityMetricWrites, true
+	case "DELETE":
+		return SecurityMetricDeletes, true
+	}
+	return "", false
+}
+
+// SecurityCounts holds one count per security metric
+type SecurityCounts struct {
+	Reads          int64 `gorm:"not null;default:0" json:"reads"`
+	Writes         int64 `gorm:"not null;default:0" json:"writes"`
+	Deletes        int64 `gorm:"not null;default:0" json:"deletes"`
+	Exports        int64 `gorm:"not null;default:0" json:"exports"`
+	RecordsChanged int64 `gorm:"not null;default:0" json:"records_changed"`
+	RecordsDeleted int64 `gorm:"not null;default:0" json:"records_deleted"`
+}
+
+// Count returns the value of a metric
+func (c SecurityCounts) Count(metric SecurityMetric) int64 {
+	switch metric {
+	case SecurityMetricReads:
+		return c.Reads
+	case SecurityMetricWrites:
+		return c.Writes
+	case SecurityMetricDeletes:
+		return c.Deletes
+	case SecurityMetricExports:
+		return c.Exports
+	case SecurityMetricRecordsChanged:
+		return c.RecordsChanged
+	case SecurityMetricRecordsDeleted:
+		return c.RecordsDeleted
+	}
+	return 0
+}
+
+// Add adds one to a metric
+func (c *SecurityCounts) Add(metric SecurityMetric) {
+	switch metric {
+	case SecurityMetricReads:
+		c.Reads++
+	case SecurityMetricWrites:
+		c.Writes++
+	case SecurityMetricDeletes:
+		c.Deletes++
+	case SecurityMetricExports:
+		c.Exports++
+	case SecurityMetricRecordsChanged:
+		c.RecordsChanged++
+	case SecurityMetricRecordsDeleted:
+		c.RecordsDeleted++
+	}
+}
+
+// SecurityActivity counts a user's operations in one hour. Request counts
+// are added as requests are tracked; record counts are recomputed from the
+// audit log by the security monitor.
+type SecurityActivity struct {
+	ID             uint      `gorm:"primaryKey" json:"-"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_security_activity_bucket" json:"user_id"`
+	BucketStart    time.Time `gorm:"not null;uniqueIndex:idx_security_activity_bucket;index" json:"bucket_start"` // Start of the hour, UTC
+	SecurityCounts `gorm:"embedded"`
+}
+
+// TableName specifies the table name for SecurityActivity
+func (SecurityActivity) TableName() string {
+	return "security_activity"
+}
+
+// SecurityAlertRule raises an alert when a user's hourly count of a metric
+// exceeds the threshold
+type SecurityAlertRule struct {
+	Metric    SecurityMetric `json:"metric"`
+	Threshold int64          `json:"threshold"`
+}
+
+// DefaultSecurityAlertRules flag users deleting more than 500 records or
+// exporting more than 50 times in an hour
+var DefaultSecurityAlertRules = []SecurityAlertRule{
+	{Metric: SecurityMetricDeletes, Threshold: 500},
+	{Metric: SecurityMetricRecordsDeleted, Threshold: 500},
+	{Metric: SecurityMetricExports, Threshold: 50},
+}
+
+// ParseSecurityAlertRules parses a comma-separated list of rules in the form
+// metric>threshold, e.g. "deletes>500,exports>50". An empty string yields
+// the default rules and "none" disables alerting.
+func ParseSecurityAlertRules(spec string) ([]SecurityAlertRule, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "":
+		return DefaultSecurityAlertRules, nil
+	case "none":
+		return []SecurityAlertRule{}, nil
+	}
+
+	var rules []SecurityAlertRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		metric, limit, ok := strings.Cut(entry, ">")
+		if !ok {
+			return nil, fmt.Errorf("invalid security alert rule %q, expected metric>threshold", entry)
+		}
+		if !slices.Contains(ValidSecurityMetrics, SecurityMetric(metric)) {
+			return nil, fmt.Errorf("unknown metric %q in security alert rule %q", metric, entry)
+		}
+		threshold, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid threshold %q in security alert rule %q", limit, entry)
+		}
+		rules = append(rules, SecurityAlertRule{Metric: SecurityMetric(metric), Threshold: threshold})
+	}
+	return rules, nil
+}
+
+// SecurityAlertStatus represents the review state of a security alert
+type SecurityAlertStatus string
+
+const (
+	SecurityAlertOpen         SecurityAlertStatus = "open"
+	SecurityAlertAcknowledged SecurityAlertStatus = "acknowledged"
+)
+
+// SecurityAlert records a user exceeding a security alert rule in one hour.
+// Each rule raises at most one alert per user and hour.
+type SecurityAlert struct {
+	ID                 uint                `gorm:"primaryKey" json:"id"`
+	UserID             uint                `gorm:"not null;uniqueIndex:idx_security_alerts_bucket" json:"user_id"`
+	BucketStart        time.Time           `gorm:"not null;uniqueIndex:idx_security_alerts_bucket" json:"bucket_start"`
+	Metric             SecurityMetric      `gorm:"size:30;not null;uniqueIndex:idx_security_alerts_bucket" json:"metric"`
+	Threshold          int64               `gorm:"not null" json:"threshold"`
+	Value              int64               `gorm:"not null" json:"value"` // Count when the alert was raised
+	Status             SecurityAlertStatus `gorm:"size:20;not null;index" json:"status"`
+	TokensRevoked      bool                `gorm:"not null;default:false" json:"tokens_revoked"` // The user's tokens were revoked pending review
+	EventID            string              `gorm:"size:36;not null;uniqueIndex" json:"event_id"` // Sent with every webhook delivery of the alert
+	NotifiedAt         *time.Time          `json:"notified_at,omitempty"`                        // When the alert webhook accepted the alert
+	NotifyFailedAt     *time.Time          `json:"notify_failed_at,omitempty"`                   // When an at-most-once webhook refused the alert
+	FalsePositive      bool                `gorm:"not null;default:false" json:"false_positive"`
+	Note               string              `gorm:"type:text" json:"note,omitempty"`
+	AcknowledgedBy     *uint               `json:"acknowledged_by,omitempty"`
+	AcknowledgedByName string              `gorm:"size:255" json:"acknowledged_by_name,omitempty"`
+	AcknowledgedAt     *time.Time          `json:"acknowledged_at,omitempty"`
+	CreatedAt          time.Time           `json:"created_at"`
+	UpdatedAt          time.Time           `json:"updated_at"`
+}
+
+// TableName specifies the table name for SecurityAlert
+func (SecurityAlert) TableName() string {
+	return "security_alerts"
+}
+
+// SecurityWebhookMode is how the alert webhook handles deliveries that fail
+type SecurityWebhookMode string
+
+const (
+	SecurityWebhookAtLeastOnce SecurityWebhookMode = "at_least_once" // Retry on later runs until accepted
+	SecurityWebhookAtMostOnce  SecurityWebhookMode = "at_most_once"  // Send once; an alert not accepted is marked failed
+)
+
+// ParseSecurityWebhookMode parses an alert webhook mode; "" is at_least_once
+func ParseSecurityWebhookMode(s string) (SecurityWebhookMode, error) {
+	switch mode := SecurityWebhookMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return SecurityWebhookAtLeastOnce, nil
+	case SecurityWebhookAtLeastOnce, SecurityWebhookAtMostOnce:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown security alert webhook mode %q: want at_least_once or at_most_once", s)
+	}
+}
+
+// SecurityWebhookDeliveryStatus represents the outcome of a webhook delivery
+type SecurityWebhookDeliveryStatus string
+
+const (
+	SecurityWebhookDeliveryPending   SecurityWebhookDeliveryStatus = "pending" // Sent without a response yet, or interrupted
+	SecurityWebhookDeliveryDelivered SecurityWebhookDeliveryStatus = "delivered"
+	SecurityWebhookDeliveryFailed    SecurityWebhookDeliveryStatus = "failed"
+)
+
+// SecurityWebhookDelivery records one attempt to post an alert to the alert
+// webhook. Every attempt has its own delivery ID and carries the alert's
+// event ID, so consumers can tell a retry from a new alert.
+type SecurityWebhookDelivery struct {
+	ID             uint                          `gorm:"primaryKey" json:"id"`
+	DeliveryID     string                        `gorm:"size:36;not null;uniqueIndex" json:"delivery_id"`
+	EventID        string                        `gorm:"size:36;not null;index" json:"event_id"`
+	AlertID        uint                          `gorm:"not null;index" json:"alert_id"`
+	Attempt        int                           `gorm:"not null" json:"attempt"` // 1 for the first delivery of the alert
+	Status         SecurityWebhookDeliveryStatus `gorm:"size:20;not null" json:"status"`
+	ResponseStatus int                           `json:"response_status,omitempty"`
+	ResponseBody   string                        `gorm:"type:text" json:"response_body,omitempty"` // The start of the response, for diagnosis
+	Error          string                        `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt      time.Time                     `json:"created_at"`
+	CompletedAt    *time.Time                    `json:"completed_at,omitempty"`
+}
+
+// TableName specifies the table name for SecurityWebhookDelivery
+func (SecurityWebhookDelivery) TableName() string {
+	return "security_webhook_deliveries"
+}
+
+// UserTokenRevocation rejects a user's tokens issued up to RevokedAt. New
+// tokens are accepted, so signing in again restores access.
+type UserTokenRevocation struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	RevokedAt time.Time `gorm:"not null" json:"revoked_at"`
+	AlertID   *uint     `json:"alert_id,omitempty"` // Alert that revoked the tokens
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for UserTokenRevocation
+func (UserTokenRevocation) TableName() string {
+	return "user_token_revocations"
+}
+
+// SecurityActivityListResponse lists hourly activity with its totals
+type SecurityActivityListResponse struct {
+	Data       []SecurityActivity `json:"data"`
+	Totals     SecurityCounts     `json:"totals"` // Sums over every matching hour, not just this page
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+	TotalPages int                `json:"total_pages"`
+}
+
+// SecurityAlertListResponse is used for paginated security alert lists
+type SecurityAlertListResponse struct {
+	Data       []SecurityAlert `json:"data"`
+	Total      int64           `json:"total"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"page_size"`
+	TotalPages int             `json:"total_pages"`
+}
+
+// SecurityWebhookDeliveryListResponse is used for paginated webhook
+// delivery lists
+type SecurityWebhookDeliveryListResponse struct {
+	Data       []SecurityWebhookDelivery `json:"data"`
+	Total      int64                     `json:"total"`
+	Page       int                       `json:"page"`
+	PageSize   int                       `json:"page_size"`
+	TotalPages int                       `json:"total_pages"`
+}
@@ -0,0 +1,32 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+func TestSecurityMetricForEndpoint(t *testing.T) {
+	for _, tc := range []struct {
+		endpoint string
+		want     models.SecurityMetric
+	}{
+		{"GET /admin/customers", models.SecurityMetricReads},
+		{"GET /admin/customers/export", models.SecurityMetricExports},
+		{"GET /admin/notes/export", models.SecurityMetricExports},
+		{"GET /admin/jobs/:id/download", models.SecurityMetricExports},
+		{"POST /admin/jobs/exports", models.SecurityMetricExports},
+		{"GET /admin/reports/segments", models.SecurityMetricReads},
+		{"GET /admin/reports/segments?format=csv", models.SecurityMetricExports},
+		{"POST /admin/customers", models.SecurityMetricWrites},
+		{"PATCH /admin/deals/:id", models.SecurityMetricWrites},
+		{"DELETE /admin/customers/:id", models.SecurityMetricDeletes},
+	} {
+		if got, ok := models.SecurityMetricForEndpoint(tc.endpoint); !ok || got != tc.want {
+			t.Errorf("%s: metric = %q, %v, want %q", tc.endpoint, got, ok, tc.want)
+		}
+	}
+	if metric, ok := models.SecurityMetricForEndpoint("OPTIONS /admin/customers"); ok {
+		t.Errorf("OPTIONS counted as %q", metric)
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServiceAccountTokenPrefix marks bearer tokens issued to service accounts
+const ServiceAccountTokenPrefix = "crm_sa_"
+
+// Service account scope actions. Write also grants read.
+const (
+	ScopeActionRead  = "read"
+	ScopeActionWrite = "write"
+)
+
+// ServiceAccountResources lists the resources a service account can be scoped to
+var ServiceAccountResources = []string{"customers", "contacts", "deals", "activities", "tags", "reports", "jobs", "maintenance", "service-accounts", "audit-logs", "annotations", "claims", "notes", "roles"}
+
+// ServiceAccount is a non-human identity used by integrations
+type ServiceAccount struct {
+	BaseModel
+	Name               string     `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description        string     `gorm:"type:text" json:"description,omitempty"`
+	Role               string     `gorm:"size:50;not null" json:"role"`
+	Scopes             []string   `gorm:"type:jsonb;serializer:json;not null" json:"scopes"`
+	RateLimitPerMinute int        `gorm:"not null;default:0" json:"rate_limit_per_minute"` // 0 uses the default limit
+	Sandbox            bool       `gorm:"not null;default:false" json:"sandbox"`           // Requests run against the API sandbox database
+	CreatedBy          uint       `json:"created_by"`
+	LastUsedAt         *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+
+	// Relations
+	Tokens []ServiceAccountToken `gorm:"foreignKey:ServiceAccountID" json:"tokens,omitempty"`
+}
+
+// TableName specifies the table name for ServiceAccount
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// HasScope checks whether the account may perform an action on a resource
+func (a ServiceAccount) HasScope(resource, action string) bool {
+	for _, scope := range a.Scopes {
+		r, act, _ := strings.Cut(scope, ":")
+		if r != resource {
+			continue
+		}
+		if act == action || act == ScopeActionWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceAccountToken is a hashed bearer token belonging to a service account.
+// During rotation the previous token stays valid until ExpiresAt.
+type ServiceAccountToken struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ServiceAccountID uint       `gorm:"not null;index" json:"service_account_id"`
+	TokenHash        string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Prefix           string     `gorm:"size:20;not null" json:"prefix"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for ServiceAccountToken
+func (ServiceAccountToken) TableName() string {
+	return "service_account_tokens"
+}
+
+// IsActive reports whether the token can still authenticate
+func (t ServiceAccountToken) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// GenerateServiceAccountToken returns a new random token and its hash
+func GenerateServiceAccountToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = ServiceAccountTokenPrefix + hex.EncodeToString(buf)
+	return token, HashServiceAccountToken(token), nil
+}
+
+// HashServiceAccountToken hashes a token for storage and lookup
+func HashServiceAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsServiceAccountToken reports whether a bearer token was issued to a service account
+func IsServiceAccountToken(token string) bool {
+	return strings.HasPrefix(token, ServiceAccountTokenPrefix)
+}
+
+// ValidateScopes checks that every scope is a known resource:action pair
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		resource, action, ok := strings.Cut(scope, ":")
+		if !ok || (action != ScopeActionRead && action != ScopeActionWrite) {
+			return fmt.Errorf("invalid scope %q, expected resource:read or resource:write", scope)
+		}
+		known := false
+		for _, r := range ServiceAccountResources {
+			if r == resource {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown scope resource %q", resource)
+		}
+	}
+	return nil
+}
+
+// ServiceAccountListResponse is used for service account lists
+type ServiceAccountListResponse struct {
+	Data []ServiceAccount `json:"data"`
+}
+
+// ServiceAccountCreateResponse is returned when an account is created or its
+// token is rotated. The token is only ever shown in this response.
+type ServiceAccountCreateResponse struct {
+	ServiceAccount ServiceAccount `json:"service_account"`
+	Token          string         `json:"token"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SideEffectFallback stores a side effect, such as an audit entry, whose
+// write failed after the change it belongs to was saved, until it is
+// replayed
+type SideEffectFallback struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Kind          string    `gorm:"size:50;not null;index" json:"kind"` // audit, user_activity, security_activity
+	Payload       string    `gorm:"type:jsonb;not null" json:"payload"`
+	Error         string    `gorm:"type:text" json:"error"`
+	Attempts      int       `gorm:"not null;default:0" json:"attempts"`
+	FirstFailedAt time.Time `gorm:"not null" json:"first_failed_at"`
+	LastFailedAt  time.Time `gorm:"not null" json:"last_failed_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for SideEffectFallback
+func (SideEffectFallback) TableName() string {
+	return "side_effect_fallbacks"
+}
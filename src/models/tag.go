@@ -1,13 +1,17 @@
 package models
 
+import "time"
+
 // Tag represents a tag/label for categorization
 type Tag struct {
 	BaseModel
-	Name  string `gorm:"size:100;not null;uniqueIndex" json:"name"`
-	Color string `gorm:"size:7" json:"color,omitempty"` // Hex color like #FF5733
+	Name    string `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Color   string `gorm:"size:7" json:"color,omitempty"` // Hex color like #FF5733
+	GroupID *uint  `gorm:"index" json:"group_id,omitempty"`
 
 	// Relations (many-to-many with customers)
 	Customers []Customer `gorm:"many2many:customer_tags;" json:"customers,omitempty"`
+	Group     *TagGroup  `gorm:"foreignKey:GroupID" json:"group,omitempty"`
 }
 
 // TableName specifies the table name for Tag
@@ -15,6 +19,43 @@ func (Tag) TableName() string {
 	return "tags"
 }
 
+// TagAlias is a former name of a renamed tag. Tags selected by name, such
+// as in the segments report, are found by their aliases when no live tag
+// has the name.
+type TagAlias struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	TagID     uint      `gorm:"not null;index" json:"tag_id"`
+	Name      string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for TagAlias
+func (TagAlias) TableName() string {
+	return "tag_aliases"
+}
+
+// TagGroup organizes related tags, such as industries or regions. A record
+// carries at most one tag of an exclusive group.
+type TagGroup struct {
+	BaseModel
+	Name      string `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Exclusive bool   `gorm:"not null;default:false" json:"exclusive"`
+
+	// Relations
+	Tags []Tag `gorm:"foreignKey:GroupID" json:"tags,omitempty"`
+}
+
+// TableName specifies the table name for TagGroup
+func (TagGroup) TableName() string {
+	return "tag_groups"
+}
+
+// TagGroupListResponse is used for tag group lists
+type TagGroupListResponse struct {
+	Data  []TagGroup `json:"data"`
+	Total int64      `json:"total"`
+}
+
 // CustomerTag represents the join table for customer-tag relationship
 type CustomerTag struct {
 	CustomerID uint `gorm:"primaryKey" json:"customer_id"`
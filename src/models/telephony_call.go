@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// CallDirection represents which side placed a phone call
+type CallDirection string
+
+const (
+	CallInbound  CallDirection = "inbound"  // The customer called in
+	CallOutbound CallDirection = "outbound" // A rep called out
+)
+
+// IsValidCallDirection checks if a call direction is valid
+func IsValidCallDirection(direction CallDirection) bool {
+	return direction == CallInbound || direction == CallOutbound
+}
+
+// TelephonyCallStatus represents whether a call was logged as an activity
+type TelephonyCallStatus string
+
+const (
+	TelephonyCallLogged    TelephonyCallStatus = "logged"
+	TelephonyCallUnmatched TelephonyCallStatus = "unmatched" // No customer or contact has the number; waiting to be matched by hand
+)
+
+// TelephonyCall records a call reported by the telephony provider. Each
+// provider event ID records at most one call, so redelivered webhooks are
+// ignored. Matched calls are logged as a completed call activity.
+type TelephonyCall struct {
+	ID              uint                `gorm:"primaryKey" json:"id"`
+	EventID         string              `gorm:"size:255;not null;uniqueIndex" json:"event_id"`
+	Direction       CallDirection       `gorm:"size:10;not null" json:"direction"`
+	FromNumber      string              `gorm:"size:50" json:"from_number"`
+	ToNumber        string              `gorm:"size:50" json:"to_number"`
+	ExternalNumber  string              `gorm:"size:50;index" json:"external_number"` // The customer's side of the call
+	Extension       string              `gorm:"size:20" json:"extension,omitempty"`   // The rep's side of the call
+	DurationSeconds int                 `gorm:"not null;default:0" json:"duration_seconds"`
+	RecordingURL    string              `gorm:"size:1000" json:"recording_url,omitempty"`
+	StartedAt       time.Time           `gorm:"not null;index" json:"started_at"`
+	Status          TelephonyCallStatus `gorm:"size:20;not null;index" json:"status"`
+	ActivityID      *uint               `gorm:"index" json:"activity_id,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// TableName specifies the table name for TelephonyCall
+func (TelephonyCall) TableName() string {
+	return "telephony_calls"
+}
+
+// TelephonyCallListResponse is used for paginated telephony call lists
+type TelephonyCallListResponse struct {
+	Data       []TelephonyCall `json:"data"`
+	Total      int64           `json:"total"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"page_size"`
+	TotalPages int             `json:"total_pages"`
+}
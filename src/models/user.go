@@ -1,11 +1,17 @@
 package models
 
+import (
+	"slices"
+	"sync"
+)
+
 // User represents user information extracted from JWT (CRM doesn't store users)
 type User struct {
 	ID       uint   `json:"id"`
 	Email    string `json:"email,omitempty"`
 	Name     string `json:"name,omitempty"`
 	Role     string `json:"role"`
+	Org      string `json:"org,omitempty"` // Organization claim of the token, when its provider maps one
 	IsActive bool   `json:"is_active"`
 }
 
@@ -18,20 +24,23 @@ const (
 
 // Permission constants
 const (
-	PermissionRead      = "read"
-	PermissionWrite     = "write"
-	PermissionDelete    = "delete"
-	PermissionManageAll = "manage_all"
-	PermissionManageOwn = "manage_own"
+	PermissionRead       = "read"
+	PermissionWrite      = "write"
+	PermissionDelete     = "delete"
+	PermissionManageAll  = "manage_all"
+	PermissionManageOwn  = "manage_own"
+	PermissionAdminister = "administer" // Settings and other Admin only routes
 )
 
-// RolePermissions defines what each role can do
-var RolePermissions = map[string][]string{
+// DefaultRolePermissions are the built-in roles. They seed the roles table
+// and apply until it has been loaded.
+var DefaultRolePermissions = map[string][]string{
 	RoleAdmin: {
 		PermissionRead,
 		PermissionWrite,
 		PermissionDelete,
 		PermissionManageAll,
+		PermissionAdminister,
 	},
 	RoleManager: {
 		PermissionRead,
@@ -46,18 +55,55 @@ var RolePermissions = map[string][]string{
 	},
 }
 
+// rolePermissions holds the permissions of every defined role
+var (
+	rolePermissionsMu sync.RWMutex
+	rolePermissions   = DefaultRolePermissions
+)
+
+// SetRolePermissions replaces the permissions of every defined role
+func SetRolePermissions(roles map[string][]string) {
+	rolePermissionsMu.Lock()
+	defer rolePermissionsMu.Unlock()
+	rolePermissions = roles
+}
+
+// RolePermissions returns the permissions of a role and whether it is defined
+func RolePermissions(role string) ([]string, bool) {
+	rolePermissionsMu.RLock()
+	defer rolePermissionsMu.RUnlock()
+	permissions, exists := rolePermissions[role]
+	return slices.Clone(permissions), exists
+}
+
+// IsKnownRole reports whether a role is defined
+func IsKnownRole(role string) bool {
+	_, exists := RolePermissions(role)
+	return exists
+}
+
 // HasPermission checks if a role has a specific permission
 func HasPermission(role, permission string) bool {
-	permissions, exists := RolePermissions[role]
+	rolePermissionsMu.RLock()
+	defer rolePermissionsMu.RUnlock()
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// HoldsRole reports whether a role holds every permission of a built-in
+// role, so custom roles pass the checks made for the built-in one
+func HoldsRole(role, builtIn string) bool {
+	required, exists := DefaultRolePermissions[builtIn]
 	if !exists {
 		return false
 	}
-	for _, p := range permissions {
-		if p == permission {
-			return true
+	rolePermissionsMu.RLock()
+	defer rolePermissionsMu.RUnlock()
+	for _, permission := range required {
+		if !slices.Contains(rolePermissions[role], permission) {
+			return false
 		}
 	}
-	return false
+	return true
 }
 
 // CanManageAll checks if a role can manage all records
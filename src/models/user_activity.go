@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// UserActivity aggregates a user's requests to one endpoint on one day
+type UserActivity struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_user_activity_bucket" json:"user_id"`
+	Endpoint     string    `gorm:"size:255;not null;uniqueIndex:idx_user_activity_bucket" json:"endpoint"` // e.g. "GET /admin/customers/:id"
+	BucketDate   time.Time `gorm:"type:date;not null;uniqueIndex:idx_user_activity_bucket" json:"bucket_date"`
+	RequestCount int64     `gorm:"not null;default:0" json:"request_count"`
+	LastSeenAt   time.Time `gorm:"not null" json:"last_seen_at"`
+}
+
+// TableName specifies the table name for UserActivity
+func (UserActivity) TableName() string {
+	return "user_activity"
+}
+
+// EndpointUsage represents how often a user called an endpoint
+type EndpointUsage struct {
+	Endpoint string `json:"endpoint"`
+	Count    int64  `json:"count"`
+}
+
+// UserActivitySummary summarizes a user's recent CRM usage
+type UserActivitySummary struct {
+	UserID        uint            `json:"user_id"`
+	LastSeenAt    time.Time       `json:"last_seen_at"`
+	RequestsToday int64           `json:"requests_today"`
+	TopEndpoints  []EndpointUsage `json:"top_endpoints"`
+}
+
+// UserActivityListResponse is used for the user activity list
+type UserActivityListResponse struct {
+	Data       []UserActivitySummary `json:"data"`
+	WindowDays int                   `json:"window_days"`
+}
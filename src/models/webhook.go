@@ -0,0 +1,174 @@
+package models
+
+import "time"
+
+// Webhook event types. A stage or status change is sent as its own event
+// as well as the generic update, so consumers can subscribe to it alone.
+const (
+	WebhookEventCustomerCreated       = "customer.created"
+	WebhookEventCustomerUpdated       = "customer.updated"
+	WebhookEventCustomerStatusChanged = "customer.status_changed"
+	WebhookEventCustomerDeleted       = "customer.deleted"
+	WebhookEventDealCreated           = "deal.created"
+	WebhookEventDealUpdated           = "deal.updated"
+	WebhookEventDealStageChanged      = "deal.stage_changed"
+	WebhookEventDealDeleted           = "deal.deleted"
+	WebhookEventBulkCompleted         = "bulk.completed" // An import or other bulk operation finished; its data is a BulkOperationSummary
+)
+
+// WebhookEvents contains every event a subscription can subscribe to
+var WebhookEvents = []string{
+	WebhookEventCustomerCreated,
+	WebhookEventCustomerUpdated,
+	WebhookEventCustomerStatusChanged,
+	WebhookEventCustomerDeleted,
+	WebhookEventDealCreated,
+	WebhookEventDealUpdated,
+	WebhookEventDealStageChanged,
+	WebhookEventDealDeleted,
+	WebhookEventBulkCompleted,
+}
+
+// WebhookMode is how a subscription handles deliveries that fail
+type WebhookMode string
+
+const (
+	WebhookModeAtLeastOnce WebhookMode = "at_least_once" // Retry for a day until accepted, then dead-letter
+	WebhookModeAtMostOnce  WebhookMode = "at_most_once"  // Send once; a delivery not accepted is marked failed
+)
+
+// WebhookModes contains every subscription mode
+var WebhookModes = []WebhookMode{WebhookModeAtLeastOnce, WebhookModeAtMostOnce}
+
+// WebhookSubscription sends the events it subscribes to, when they match
+// its filter, to a URL
+type WebhookSubscription struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	Name       string      `gorm:"size:100;not null" json:"name"`
+	URL        string      `gorm:"type:text;not null" json:"url"`
+	Secret     string      `gorm:"size:64;not null" json:"-"` // Signs deliveries; only shown when the subscription is created
+	Events     []string    `gorm:"type:jsonb;serializer:json;not null" json:"events"`
+	Filter     string      `gorm:"type:text" json:"filter,omitempty"` // Filter expression over the event's record, empty to send every event
+	Mode       WebhookMode `gorm:"size:20;not null;default:'at_least_once'" json:"mode"`
+	BulkEvents bool        `gorm:"not null" json:"bulk_events"` // Send the events of records changed by bulk operations rather than suppress them
+	Active     bool        `gorm:"not null" json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// TableName specifies the table name for WebhookSubscription
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookDeliveryStatus represents the outcome of sending an event to a
+// subscription
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending" // Not accepted yet; retried until it is or a day has passed, unless at most once
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+	WebhookDeliverySkipped   WebhookDeliveryStatus = "skipped" // The event did not match the filter and was not sent
+	// A bulk operation's event the subscription does not take; not sent
+	// and not counted in the delivery stats
+	WebhookDeliverySuppressed WebhookDeliveryStatus = "suppressed"
+)
+
+// WebhookDelivery records sending one event to one subscription. Every
+// attempt gets a new delivery ID and carries the event's ID.
+type WebhookDelivery struct {
+	ID             uint                  `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint                  `gorm:"not null;index" json:"subscription_id"`
+	EventID        string                `gorm:"size:36;not null;index" json:"event_id"`
+	EventType      string                `gorm:"size:50;not null" json:"event_type"`
+	DeliveryID     string                `gorm:"size:36" json:"delivery_id,omitempty"` // Of the latest attempt
+	Status         WebhookDeliveryStatus `gorm:"size:20;not null" json:"status"`
+	Attempts       int                   `gorm:"not null" json:"attempts"`
+	Payload        string                `gorm:"type:jsonb;not null" json:"payload"` // The event as sent, without its delivery ID
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	ResponseBody   string                `gorm:"type:text" json:"response_body,omitempty"` // The start of the latest response, for diagnosis
+	Error          string                `gorm:"type:text" json:"error,omitempty"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	CompletedAt    *time.Time            `json:"completed_at,omitempty"`
+}
+
+// TableName specifies the table name for WebhookDelivery
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryStats counts a subscription's deliveries by status,
+// leaving out suppressed ones
+type WebhookDeliveryStats struct {
+	Pending   int64 `json:"pending"`
+	Delivered int64 `json:"delivered"`
+	Failed    int64 `json:"failed"`
+	Skipped   int64 `json:"skipped"`
+}
+
+// Add counts n deliveries with a status
+func (s *WebhookDeliveryStats) Add(status WebhookDeliveryStatus, n int64) {
+	switch status {
+	case WebhookDeliveryPending:
+		s.Pending += n
+	case WebhookDeliveryDelivered:
+		s.Delivered += n
+	case WebhookDeliveryFailed:
+		s.Failed += n
+	case WebhookDeliverySkipped:
+		s.Skipped += n
+	}
+}
+
+// Bulk operations, whose records' events are suppressed for subscriptions
+// that do not take bulk events
+const (
+	BulkOperationCustomerImport = "customer_import"
+	BulkOperationCustomerUpsert = "customer_bulk_upsert"
+	BulkOperationDealStage      = "deal_bulk_stage"
+)
+
+// BulkOperationSummary is the data of the bulk.completed event of a bulk
+// operation. Consumers that do not take bulk events resync the records
+// changed between its start and completion.
+type BulkOperationSummary struct {
+	Operation    string    `json:"operation"`
+	ResourceType string    `json:"resource_type"`
+	Total        int       `json:"total"`
+	Created      int       `json:"created"`
+	Updated      int       `json:"updated"`
+	Skipped      int       `json:"skipped"` // Records left as they were
+	Failed       int       `json:"failed"`
+	AnnotationID *uint     `json:"annotation_id,omitempty"` // The operation's annotation, see GET /admin/annotations
+	StartedAt    time.Time `json:"started_at"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+// WebhookSubscriptionResponse is a subscription with its delivery counts
+type WebhookSubscriptionResponse struct {
+	WebhookSubscription
+	Stats WebhookDeliveryStats `json:"stats"`
+}
+
+// WebhookSubscriptionCreateResponse is returned when a subscription is
+// created. The secret is only ever shown in this response.
+type WebhookSubscriptionCreateResponse struct {
+	WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// WebhookSubscriptionListResponse is used for the subscription list
+type WebhookSubscriptionListResponse struct {
+	Data []WebhookSubscriptionResponse `json:"data"`
+}
+
+// WebhookDeliveryListResponse is used for paginated webhook delivery lists
+type WebhookDeliveryListResponse struct {
+	Data       []WebhookDelivery `json:"data"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+}
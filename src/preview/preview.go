@@ -0,0 +1,147 @@
+// Package preview shortens long text fields, such as deal descriptions and
+// notes, in list responses. Rows loaded for a request with a preview length
+// are marked with it, and the models serialize their long text as a preview
+// of that many characters.
+package preview
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// ContextKey holds the preview length of a context's queries. It is a
+// plain string so the length set on a gin.Context is visible through its
+// Value method.
+const ContextKey = "text_preview_length"
+
+// Endpoints that serialize long text as a preview
+const (
+	EndpointCustomers  = "customers"  // Customer lists
+	EndpointContacts   = "contacts"   // Contact lists
+	EndpointDeals      = "deals"      // Deal lists and the pipeline board
+	EndpointActivities = "activities" // Activity lists
+	EndpointTimeline   = "timeline"   // Activities and notes shown on customer and deal details
+)
+
+// endpoints lists the endpoint names a preview length can be set for
+var endpoints = []string{EndpointCustomers, EndpointContacts, EndpointDeals, EndpointActivities, EndpointTimeline}
+
+// With returns a copy of ctx whose loaded rows are previewed with length
+// characters
+func With(ctx context.Context, length int) context.Context {
+	return context.WithValue(ctx, ContextKey, length)
+}
+
+// From returns the preview length of ctx, 0 when rows are loaded in full
+func From(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	length, _ := ctx.Value(ContextKey).(int)
+	return length
+}
+
+// Copy returns to carrying the preview length of from, for work detached
+// from the request
+func Copy(from, to context.Context) context.Context {
+	if length := From(from); length > 0 {
+		return With(to, length)
+	}
+	return to
+}
+
+// Lengths is the preview length of each endpoint
+type Lengths struct {
+	Default   int            // Used by endpoints without their own length
+	Endpoints map[string]int // By endpoint name
+}
+
+// For returns the preview length of an endpoint
+func (l Lengths) For(endpoint string) int {
+	if length, ok := l.Endpoints[endpoint]; ok {
+		return length
+	}
+	return l.Default
+}
+
+// ParseLengths parses a comma-separated list of endpoint=length overrides
+// of the default length, e.g. "timeline=200,deals=500". A length of 0
+// serializes the endpoint's long text in full.
+func ParseLengths(defaultLength int, spec string) (Lengths, error) {
+	if defaultLength < 0 {
+		return Lengths{}, fmt.Errorf("text preview length must not be negative, got %d", defaultLength)
+	}
+
+	lengths := Lengths{Default: defaultLength, Endpoints: make(map[string]int)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		endpoint, value, ok := strings.Cut(entry, "=")
+		endpoint = strings.TrimSpace(endpoint)
+		if !ok {
+			return Lengths{}, fmt.Errorf("invalid text preview length %q, expected endpoint=length", entry)
+		}
+		if !slices.Contains(endpoints, endpoint) {
+			return Lengths{}, fmt.Errorf("unknown endpoint %q in text preview length %q, want one of %s", endpoint, entry, strings.Join(endpoints, ", "))
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || length < 0 {
+			return Lengths{}, fmt.Errorf("invalid length in text preview length %q", entry)
+		}
+		lengths.Endpoints[endpoint] = length
+	}
+	return lengths, nil
+}
+
+// Mark sets the preview length of every model with long text in value,
+// which may be a model, a slice of models or pointers to them
+func Mark(value interface{}, length int) {
+	mark(reflect.ValueOf(value), length)
+}
+
+// mark walks a loaded value, marking the models with long text in it
+func mark(v reflect.Value, length int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			mark(v.Index(i), length)
+		}
+	case reflect.Struct:
+		if !v.CanAddr() {
+			return
+		}
+		if model, ok := v.Addr().Interface().(models.LongText); ok {
+			model.SetPreviewLength(length)
+		}
+	}
+}
+
+// Register marks the rows of every query made with a preview length in its
+// context, including preloaded associations, so no list serializes long
+// text in full
+func Register(db *gorm.DB) error {
+	return db.Callback().Query().After("gorm:query").Register("preview:mark", func(db *gorm.DB) {
+		if db.Error != nil || !db.Statement.ReflectValue.IsValid() {
+			return
+		}
+		if length := From(db.Statement.Context); length > 0 {
+			mark(db.Statement.ReflectValue, length)
+		}
+	})
+}
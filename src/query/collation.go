@@ -0,0 +1,40 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// NameCollation is the collation that sort fields marked Collated are
+// ordered with, such as "und-x-icu" for ICU's language-neutral rules, so
+// Arabic and other non-Latin names sort the way readers expect. Empty uses
+// the database default.
+var NameCollation = ""
+
+// collationName matches the collation names Collate may quote
+var collationName = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+
+// ParseCollation validates a collation name; "" disables collated ordering
+func ParseCollation(s string) (string, error) {
+	if s != "" && !collationName.MatchString(s) {
+		return "", fmt.Errorf("invalid collation name %q", s)
+	}
+	return s, nil
+}
+
+// Collate returns a text expression ordered with NameCollation
+func Collate(expr string) string {
+	if NameCollation == "" {
+		return expr
+	}
+	return expr + ` COLLATE "` + NameCollation + `"`
+}
+
+// FoldedSearch filters rows whose folded search column contains the
+// parameter. The column holds crm_fold_text of the searchable text, which
+// drops case, Arabic diacritics and the common orthographic variants of
+// alef, ta marbuta, alef maqsura and hamza, and the parameter is folded the
+// same way.
+func FoldedSearch(param, column string) Filter {
+	return Filter{Param: param, Kind: KindSearch, Where: column + " LIKE crm_fold_text(?)"}
+}
@@ -0,0 +1,199 @@
+package query
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was made
+// for another sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a keyset page: its sort key and ID, and the
+// sort the page was in. Clients only see it encoded, as an opaque token.
+type Cursor struct {
+	SortBy string      `json:"sort_by"`
+	Desc   bool        `json:"desc"`
+	Key    interface{} `json:"key"` // nil when the row's sort key is NULL
+	ID     uint        `json:"id"`
+}
+
+// encode returns the cursor as an opaque token
+func (cur Cursor) encode() (string, error) {
+	raw, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// sortKey returns the sort column and direction of the request
+func (s Sort) sortKey(values url.Values) (string, bool) {
+	sortOrder := values.Get("sort_order")
+	if sortOrder != "asc" && sortOrder != "desc" {
+		sortOrder = s.DefaultOrder
+	}
+	sortBy := values.Get("sort_by")
+	if !slices.Contains(s.Fields, sortBy) {
+		sortBy = s.DefaultField
+	}
+	return sortBy, sortOrder == "desc"
+}
+
+// column returns the sort expression of a field of table
+func (s Sort) column(table, field string) string {
+	if slices.Contains(s.Collated, field) {
+		return Collate(table + "." + field)
+	}
+	return table + "." + field
+}
+
+// KeysetOrder returns the ORDER BY of the request's sort on table followed
+// by the ID in the same direction, so rows with equal sort keys keep one
+// order across pages and keyset pages continue offset pages exactly.
+// Definitions with a Fixed sort are not supported.
+func (d Definition) KeysetOrder(table string, values url.Values) string {
+	sortBy, desc := d.Sort.sortKey(values)
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	return d.Sort.column(table, sortBy) + direction + ", " + table + ".id" + direction
+}
+
+// ParseCursor decodes a cursor token made by NextCursor. It returns nil
+// without a token, and ErrInvalidCursor when the token is malformed or the
+// request asks for another sort than the one the cursor was made in.
+func (d Definition) ParseCursor(token string, values url.Values) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	if sortBy, desc := d.Sort.sortKey(values); cursor.SortBy != sortBy || cursor.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := cursor.Key.(map[string]interface{}); ok {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := cursor.Key.([]interface{}); ok {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// after restricts db to the rows of table after the cursor in KeysetOrder.
+// NULL sort keys sort last ascending and first descending, as Postgres
+// orders them.
+func (d Definition) after(db *gorm.DB, table string, cursor Cursor) *gorm.DB {
+	column := d.Sort.column(table, cursor.SortBy)
+	id := table + ".id"
+	switch {
+	case cursor.Key == nil && !cursor.Desc:
+		return db.Where(column+" IS NULL AND "+id+" > ?", cursor.ID)
+	case cursor.Key == nil:
+		return db.Where("("+column+" IS NULL AND "+id+" < ?) OR "+column+" IS NOT NULL", cursor.ID)
+	case !cursor.Desc:
+		return db.Where("("+column+" > ? OR ("+column+" = ? AND "+id+" > ?) OR "+column+" IS NULL)",
+			cursor.Key, cursor.Key, cursor.ID)
+	default:
+		return db.Where("("+column+" < ? OR ("+column+" = ? AND "+id+" < ?))", cursor.Key, cursor.Key, cursor.ID)
+	}
+}
+
+// NextCursor returns the token of the cursor after row, the last row of a
+// page in the request's sort. row is a pointer to a model of db.
+func (d Definition) NextCursor(ctx context.Context, db *gorm.DB, values url.Values, row interface{}) (string, error) {
+	sortBy, desc := d.Sort.sortKey(values)
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(row); err != nil {
+		return "", err
+	}
+	sortField := stmt.Schema.LookUpField(sortBy)
+	idField := stmt.Schema.PrioritizedPrimaryField
+	if sortField == nil || idField == nil {
+		return "", fmt.Errorf("cannot page %s by %s", stmt.Schema.Table, sortBy)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(row))
+	id, _ := idField.ValueOf(ctx, value)
+	key, _ := sortField.ValueOf(ctx, value)
+	if v := reflect.ValueOf(key); v.Kind() == reflect.Ptr {
+		key = nil
+		if !v.IsNil() {
+			key = v.Elem().Interface()
+		}
+	}
+
+	cursor := Cursor{SortBy: sortBy, Desc: desc, Key: key}
+	if cursor.ID, _ = id.(uint); cursor.ID == 0 {
+		return "", fmt.Errorf("cannot page %s rows without an ID", stmt.Schema.Table)
+	}
+	return cursor.encode()
+}
+
+// typedKey converts the cursor's key back from JSON to the type of its sort
+// field on model, so it binds as a time or a number rather than text
+func typedKey(db *gorm.DB, model interface{}, cursor Cursor) (interface{}, error) {
+	if cursor.Key == nil {
+		return nil, nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	field := stmt.Schema.LookUpField(cursor.SortBy)
+	if field == nil {
+		return nil, ErrInvalidCursor
+	}
+
+	fieldType := field.FieldType
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	raw, err := json.Marshal(cursor.Key)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	key := reflect.New(fieldType)
+	if err := json.Unmarshal(raw, key.Interface()); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return key.Elem().Interface(), nil
+}
+
+// FindAfter loads up to pageSize rows of db after cursor in the keyset order
+// of table, reporting whether more rows follow. A cursor whose key does not
+// fit the sort field yields ErrInvalidCursor.
+func FindAfter[T any](db *gorm.DB, d Definition, table string, values url.Values, cursor Cursor, pageSize int) ([]T, bool, error) {
+	var rows []T
+	key, err := typedKey(db, &rows, cursor)
+	if err != nil {
+		return nil, false, err
+	}
+	cursor.Key = key
+	if err := d.after(db, table, cursor).
+		Order(d.KeysetOrder(table, values)).
+		Limit(pageSize + 1).
+		Find(&rows).Error; err != nil {
+		return nil, false, err
+	}
+	if len(rows) > pageSize {
+		return rows[:pageSize], true, nil
+	}
+	return rows, false, nil
+}
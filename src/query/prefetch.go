@@ -0,0 +1,325 @@
+package query
+
+import (
+	"container/list"
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/preview"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxEntriesPerScope caps how many primed pages one user may hold
+	maxEntriesPerScope = 4
+
+	// maxConcurrentPrimes caps background page loads; extra primes are skipped
+	maxConcurrentPrimes = 4
+
+	// primeTimeout bounds a background page load
+	primeTimeout = 10 * time.Second
+)
+
+var listCacheRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "crm_list_cache_requests_total",
+		Help: "Total number of list page lookups in the prefetch cache by result",
+	},
+	[]string{"table", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(listCacheRequestsTotal)
+}
+
+// Prefetcher caches list pages primed ahead of the client. Entries are
+// partitioned per user and bounded in number. A cached page is only served
+// while the list's row count and newest updated_at are unchanged and no
+// write has touched the tables the list depends on since it was loaded.
+type Prefetcher struct {
+	maxEntries int
+	ttl        time.Duration
+	priming    chan struct{}
+
+	mu          sync.Mutex
+	entries     map[string]*list.Element
+	lru         *list.List
+	perScope    map[string]int
+	generations map[string]uint64
+	global      uint64
+}
+
+// cacheEntry is one primed page
+type cacheEntry struct {
+	key       string
+	scope     string
+	rows      interface{}
+	stamp     listStamp
+	expiresAt time.Time
+}
+
+// listStamp identifies the state of a list when a page was loaded
+type listStamp struct {
+	Total        int64
+	MaxUpdatedAt *time.Time
+	generation   uint64
+}
+
+// matches reports whether two stamps describe the same list state
+func (s listStamp) matches(other listStamp) bool {
+	if s.Total != other.Total || s.generation != other.generation {
+		return false
+	}
+	if s.MaxUpdatedAt == nil || other.MaxUpdatedAt == nil {
+		return s.MaxUpdatedAt == nil && other.MaxUpdatedAt == nil
+	}
+	return s.MaxUpdatedAt.Equal(*other.MaxUpdatedAt)
+}
+
+// NewPrefetcher creates a Prefetcher holding at most maxEntries pages, each
+// for at most ttl
+func NewPrefetcher(maxEntries int, ttl time.Duration) *Prefetcher {
+	return &Prefetcher{
+		maxEntries:  maxEntries,
+		ttl:         ttl,
+		priming:     make(chan struct{}, maxConcurrentPrimes),
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		perScope:    make(map[string]int),
+		generations: make(map[string]uint64),
+	}
+}
+
+// Register hooks the Prefetcher into db so that every create, update,
+// delete and raw statement invalidates cached pages of the tables it
+// touches. Raw statements invalidate everything.
+func (p *Prefetcher) Register(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("query:invalidate_prefetch", p.invalidate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("query:invalidate_prefetch", p.invalidate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("query:invalidate_prefetch", p.invalidate); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("query:invalidate_prefetch", p.invalidate)
+}
+
+// invalidate bumps the generation of the table written by db
+func (p *Prefetcher) invalidate(db *gorm.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if table := db.Statement.Table; table != "" {
+		p.generations[table]++
+	} else {
+		p.global++
+	}
+}
+
+// generation returns a counter that changes whenever any of tables is written
+func (p *Prefetcher) generation(tables []string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.generationLocked(tables)
+}
+
+// generationLocked is generation for callers holding p.mu
+func (p *Prefetcher) generationLocked(tables []string) uint64 {
+	generation := p.global
+	for _, table := range tables {
+		generation += p.generations[table]
+	}
+	return generation
+}
+
+// get returns the cached rows for key when they were loaded at stamp
+func (p *Prefetcher) get(key string, stamp listStamp) (interface{}, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	element, ok := p.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) || !entry.stamp.matches(stamp) {
+		p.remove(element)
+		return nil, false
+	}
+	p.lru.MoveToFront(element)
+	return entry.rows, true
+}
+
+// has reports whether a valid entry exists for key without touching it
+func (p *Prefetcher) has(key string, stamp listStamp) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	element, ok := p.entries[key]
+	if !ok {
+		return false
+	}
+	entry := element.Value.(*cacheEntry)
+	return time.Now().Before(entry.expiresAt) && entry.stamp.matches(stamp)
+}
+
+// put stores rows for key, evicting the scope's and then the cache's least
+// recently used pages when over their limits
+func (p *Prefetcher) put(key, scope string, rows interface{}, stamp listStamp, tables []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A write landed while the page was loading
+	if p.generationLocked(tables) != stamp.generation {
+		return
+	}
+
+	if element, ok := p.entries[key]; ok {
+		p.remove(element)
+	}
+	for p.perScope[scope] >= maxEntriesPerScope {
+		p.removeOldest(scope)
+	}
+	for p.lru.Len() >= p.maxEntries && p.lru.Len() > 0 {
+		p.remove(p.lru.Back())
+	}
+
+	p.entries[key] = p.lru.PushFront(&cacheEntry{
+		key:       key,
+		scope:     scope,
+		rows:      rows,
+		stamp:     stamp,
+		expiresAt: time.Now().Add(p.ttl),
+	})
+	p.perScope[scope]++
+}
+
+// removeOldest evicts the least recently used page of scope
+func (p *Prefetcher) removeOldest(scope string) {
+	for element := p.lru.Back(); element != nil; element = element.Prev() {
+		if element.Value.(*cacheEntry).scope == scope {
+			p.remove(element)
+			return
+		}
+	}
+}
+
+// remove evicts one entry; the caller holds p.mu
+func (p *Prefetcher) remove(element *list.Element) {
+	entry := p.lru.Remove(element).(*cacheEntry)
+	delete(p.entries, entry.key)
+	if p.perScope[entry.scope]--; p.perScope[entry.scope] <= 0 {
+		delete(p.perScope, entry.scope)
+	}
+}
+
+// PageRequest describes one page of a list endpoint
+type PageRequest struct {
+	Query    *gorm.DB   // filtered query without ordering or pagination
+	Table    string     // listed table; its updated_at column validates cached pages
+	Depends  []string   // other tables whose writes invalidate cached pages
+	Scope    string     // cache partition, normally the requesting user
+	Values   url.Values // list parameters, see Values
+	Page     Page
+	Order    string
+	Prefetch bool // prime the next page in the background
+}
+
+// FindPage counts the rows matched by req.Query and loads the requested
+// page, serving it from p when a primed copy is still valid. With
+// req.Prefetch the next page is primed after this one is loaded. p may be
+// nil, in which case nothing is cached; sandbox pages are never cached, as
+// primes run outside the request.
+func FindPage[T any](ctx context.Context, p *Prefetcher, req PageRequest) ([]T, int64, error) {
+	if sandbox.Active(ctx) {
+		p = nil
+	}
+
+	var generation uint64
+	if p != nil {
+		generation = p.generation(append([]string{req.Table}, req.Depends...))
+	}
+
+	stamp := listStamp{generation: generation}
+	if err := req.Query.WithContext(ctx).
+		Select("COUNT(*) AS total, MAX(" + req.Table + ".updated_at) AS max_updated_at").
+		Scan(&stamp).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []T
+	cached := false
+	if p != nil {
+		var value interface{}
+		value, cached = p.get(cacheKey(req, req.Page), stamp)
+		if cached {
+			rows = value.([]T)
+			listCacheRequestsTotal.WithLabelValues(req.Table, "hit").Inc()
+		} else {
+			listCacheRequestsTotal.WithLabelValues(req.Table, "miss").Inc()
+		}
+	}
+	if !cached {
+		if err := findRows(ctx, req, req.Page, &rows); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if p != nil && req.Prefetch && req.Page.Page < req.Page.TotalPages(stamp.Total) {
+		prime[T](ctx, p, req, stamp)
+	}
+	return rows, stamp.Total, nil
+}
+
+// prime loads the page after req.Page in the background unless it is
+// already cached or too many primes are running. The page is redacted for
+// the viewer of ctx and previewed with its preview length, as the request's
+// own page is.
+func prime[T any](ctx context.Context, p *Prefetcher, req PageRequest, stamp listStamp) {
+	next := req.Page
+	next.Page++
+	key := cacheKey(req, next)
+	if p.has(key, stamp) {
+		return
+	}
+
+	select {
+	case p.priming <- struct{}{}:
+	default:
+		return
+	}
+	detached := preview.Copy(ctx, redaction.Detach(ctx))
+	go func() {
+		defer func() { <-p.priming }()
+
+		ctx, cancel := context.WithTimeout(detached, primeTimeout)
+		defer cancel()
+
+		var rows []T
+		if err := findRows(ctx, req, next, &rows); err != nil {
+			return
+		}
+		p.put(key, req.Scope, rows, stamp, append([]string{req.Table}, req.Depends...))
+	}()
+}
+
+// findRows loads one page of the request's query into dest
+func findRows(ctx context.Context, req PageRequest, page Page, dest interface{}) error {
+	return req.Query.WithContext(ctx).
+		Order(req.Order).
+		Offset(page.Offset()).
+		Limit(page.PageSize).
+		Find(dest).Error
+}
+
+// cacheKey identifies a page of a list for one scope
+func cacheKey(req PageRequest, page Page) string {
+	return req.Scope + "\x00" + req.Table + "\x00" + pageValues(req.Values, page).Encode()
+}
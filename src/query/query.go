@@ -0,0 +1,291 @@
+// Package query turns declarative per-entity filter and sort definitions into
+// GORM scopes for list endpoints.
+package query
+
+import (
+	"encoding/base64"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Pagination defaults shared by list endpoints
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Kind describes how a filter's raw query value is parsed
+type Kind int
+
+const (
+	KindString Kind = iota // used as-is
+	KindSearch             // lower-cased and wrapped in % for LIKE
+	KindFloat              // parsed as a float; invalid values are ignored
+	KindTime               // parsed as RFC 3339; invalid values are ignored
+	KindList               // split on commas
+	KindBool               // parsed as a boolean; invalid values are ignored
+)
+
+// String names the kind of value a filter parameter takes, as described to
+// API clients
+func (k Kind) String() string {
+	switch k {
+	case KindSearch:
+		return "search"
+	case KindFloat:
+		return "number"
+	case KindTime:
+		return "datetime"
+	case KindList:
+		return "list"
+	case KindBool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// Filter maps one query parameter onto a condition. Every ? placeholder in
+// Where is bound to the parsed value; Join is added when the filter applies.
+// Column is set on equality filters, whose rows can be counted per value.
+type Filter struct {
+	Param  string
+	Kind   Kind
+	Where  string
+	Join   string
+	Column string
+}
+
+// Equal filters rows whose column equals the parameter
+func Equal(param, column string) Filter {
+	return Filter{Param: param, Kind: KindString, Where: column + " = ?", Column: column}
+}
+
+// Search filters rows where any of the columns contains the parameter,
+// case-insensitively
+func Search(param string, columns ...string) Filter {
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = "LOWER(" + column + ") LIKE ?"
+	}
+	return Filter{Param: param, Kind: KindSearch, Where: strings.Join(conditions, " OR ")}
+}
+
+// AtLeast filters rows whose column is >= the parameter
+func AtLeast(param, column string, kind Kind) Filter {
+	return Filter{Param: param, Kind: kind, Where: column + " >= ?"}
+}
+
+// AtMost filters rows whose column is <= the parameter
+func AtMost(param, column string, kind Kind) Filter {
+	return Filter{Param: param, Kind: kind, Where: column + " <= ?"}
+}
+
+// AnyOf filters with a comma-separated list bound to an IN condition,
+// optionally joining another table
+func AnyOf(param, where, join string) Filter {
+	return Filter{Param: param, Kind: KindList, Where: where, Join: join}
+}
+
+// Condition filters rows where a boolean SQL condition has the truth value
+// of the parameter, e.g. ?missing_next_step=false selects rows where the
+// condition is false
+func Condition(param, condition string) Filter {
+	return Filter{Param: param, Kind: KindBool, Where: "(" + condition + ") = ?"}
+}
+
+// Sort whitelists sortable columns for the sort_by/sort_order parameters.
+// When Fixed is set the parameters are ignored and Fixed is used instead.
+// Collated fields are text ordered with NameCollation.
+type Sort struct {
+	Fields       []string
+	DefaultField string
+	DefaultOrder string
+	Fixed        string
+	Collated     []string
+}
+
+// Definition describes the filters and sorting of one list endpoint
+type Definition struct {
+	Filters []Filter
+	Sort    Sort
+}
+
+// Apply adds the filters present in values and the ordering to db. It
+// returns the query and the filter parameters that were applied.
+func (d Definition) Apply(db *gorm.DB, values url.Values) (*gorm.DB, map[string]string) {
+	db, applied := d.Filter(db, values)
+	return db.Order(d.Order(values)), applied
+}
+
+// Filter adds the filters present in values to db, without ordering. It
+// returns the query and the filter parameters that were applied.
+func (d Definition) Filter(db *gorm.DB, values url.Values) (*gorm.DB, map[string]string) {
+	applied := make(map[string]string)
+	for _, filter := range d.Filters {
+		raw := values.Get(filter.Param)
+		if raw == "" {
+			continue
+		}
+		value, ok := parse(filter.Kind, raw)
+		if !ok {
+			continue
+		}
+
+		if filter.Join != "" {
+			db = db.Joins(filter.Join)
+		}
+		args := make([]interface{}, strings.Count(filter.Where, "?"))
+		for i := range args {
+			args[i] = value
+		}
+		db = db.Where(filter.Where, args...)
+		applied[filter.Param] = raw
+	}
+	return db, applied
+}
+
+// Without returns the definition minus the filter on param, e.g. to count
+// a field's values under every other filter of the request
+func (d Definition) Without(param string) Definition {
+	filters := make([]Filter, 0, len(d.Filters))
+	for _, filter := range d.Filters {
+		if filter.Param != param {
+			filters = append(filters, filter)
+		}
+	}
+	return Definition{Filters: filters, Sort: d.Sort}
+}
+
+// Lookup returns the filter on param
+func (d Definition) Lookup(param string) (Filter, bool) {
+	for _, filter := range d.Filters {
+		if filter.Param == param {
+			return filter, true
+		}
+	}
+	return Filter{}, false
+}
+
+// Order returns the ORDER BY expression for the request
+func (d Definition) Order(values url.Values) string {
+	return d.Sort.order(values)
+}
+
+// order returns the ORDER BY expression for the request
+func (s Sort) order(values url.Values) string {
+	if s.Fixed != "" {
+		return s.Fixed
+	}
+
+	sortBy, desc := s.sortKey(values)
+	sortOrder := "asc"
+	if desc {
+		sortOrder = "desc"
+	}
+	if slices.Contains(s.Collated, sortBy) {
+		return Collate(sortBy) + " " + sortOrder
+	}
+	return sortBy + " " + sortOrder
+}
+
+// parse converts a raw parameter according to its kind
+func parse(kind Kind, raw string) (interface{}, bool) {
+	switch kind {
+	case KindSearch:
+		return "%" + strings.ToLower(raw) + "%", true
+	case KindFloat:
+		value, err := strconv.ParseFloat(raw, 64)
+		return value, err == nil
+	case KindTime:
+		value, err := time.Parse(time.RFC3339, raw)
+		return value, err == nil
+	case KindList:
+		return strings.Split(raw, ","), true
+	case KindBool:
+		value, err := strconv.ParseBool(raw)
+		return value, err == nil
+	default:
+		return raw, true
+	}
+}
+
+// Page holds pagination parameters
+type Page struct {
+	Page     int
+	PageSize int
+}
+
+// ParsePage reads page and page_size, falling back to the defaults for
+// missing or out-of-range values
+func ParsePage(values url.Values) Page {
+	page, _ := strconv.Atoi(values.Get("page"))
+	pageSize, _ := strconv.Atoi(values.Get("page_size"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > MaxPageSize {
+		pageSize = DefaultPageSize
+	}
+	return Page{Page: page, PageSize: pageSize}
+}
+
+// Offset returns the number of rows to skip
+func (p Page) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// TotalPages returns the page count for total rows
+func (p Page) TotalPages(total int64) int {
+	return int(math.Ceil(float64(total) / float64(p.PageSize)))
+}
+
+// Values returns the list parameters of a request. A page_token from a
+// previous response replaces them with the parameters of the page it points
+// to; an invalid token is ignored.
+func Values(values url.Values) url.Values {
+	token := values.Get("page_token")
+	if token == "" {
+		return values
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return values
+	}
+	decoded, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return values
+	}
+	return decoded
+}
+
+// NextPageToken returns an opaque token for the page after p, or "" when p
+// is the last page. The token carries the filters and sorting of values.
+func NextPageToken(values url.Values, p Page, total int64) string {
+	if p.Page >= p.TotalPages(total) {
+		return ""
+	}
+	next := p
+	next.Page++
+	return base64.RawURLEncoding.EncodeToString([]byte(pageValues(values, next).Encode()))
+}
+
+// pageValues returns a copy of values pointing at page p, without the
+// parameters that only control how the request is served
+func pageValues(values url.Values, p Page) url.Values {
+	result := make(url.Values, len(values)+2)
+	for key, value := range values {
+		result[key] = value
+	}
+	result.Del("page_token")
+	result.Del("prefetch")
+	result.Set("page", strconv.Itoa(p.Page))
+	result.Set("page_size", strconv.Itoa(p.PageSize))
+	return result
+}
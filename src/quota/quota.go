@@ -0,0 +1,143 @@
+// Package quota enforces soft limits on record counts. Usage is counted
+// incrementally from database writes and periodically reconciled.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"gorm.io/gorm"
+)
+
+// Limited entities, named after their tables
+const (
+	Customers  = "customers"
+	Contacts   = "contacts"
+	Deals      = "deals"
+	Activities = "activities"
+)
+
+// Entities lists the entities whose usage is tracked
+var Entities = []string{Customers, Contacts, Deals, Activities}
+
+// ExceededError is returned when a create would take an entity over its limit
+type ExceededError struct {
+	Entity    string
+	Usage     int64
+	Limit     int64
+	Requested int64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: %d of %d used, %d requested", e.Entity, e.Usage, e.Limit, e.Requested)
+}
+
+// Tracker keeps live record counts and checks them against limits. Counts
+// follow creates and deletes made through the registered database, so they
+// can drift on raw statements or rolled-back transactions until the next
+// Reconcile.
+type Tracker struct {
+	db     *gorm.DB
+	limits map[string]int64
+
+	mu           sync.Mutex
+	usage        map[string]int64
+	reconciledAt *time.Time
+}
+
+// NewTracker creates a Tracker. A limit <= 0 means unlimited.
+func NewTracker(db *gorm.DB, limits map[string]int64) *Tracker {
+	return &Tracker{
+		db:     db,
+		limits: limits,
+		usage:  make(map[string]int64),
+	}
+}
+
+// Register hooks the Tracker into db so creates and deletes of tracked
+// tables adjust usage
+func (t *Tracker) Register(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("quota:count_create", t.counter(1)); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("quota:count_delete", t.counter(-1))
+}
+
+// counter returns a callback adding sign * rows affected to the written table
+func (t *Tracker) counter(sign int64) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.RowsAffected == 0 || sandbox.Active(db.Statement.Context) {
+			return
+		}
+		table := db.Statement.Table
+		if !slices.Contains(Entities, table) {
+			return
+		}
+		t.mu.Lock()
+		t.usage[table] += sign * db.RowsAffected
+		t.mu.Unlock()
+	}
+}
+
+// Reconcile recounts every tracked entity from the database
+func (t *Tracker) Reconcile(ctx context.Context) error {
+	counts := make(map[string]int64, len(Entities))
+	for _, entity := range Entities {
+		var count int64
+		if err := t.db.WithContext(ctx).Table(entity).Where("deleted_at IS NULL").Count(&count).Error; err != nil {
+			return err
+		}
+		counts[entity] = count
+	}
+
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage = counts
+	t.reconciledAt = &now
+	return nil
+}
+
+// Check returns an *ExceededError when creating n more records of entity
+// would exceed its limit
+func (t *Tracker) Check(entity string, n int64) error {
+	limit := t.limits[entity]
+	if limit <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	usage := t.usage[entity]
+	t.mu.Unlock()
+	if usage+n > limit {
+		return &ExceededError{Entity: entity, Usage: usage, Limit: limit, Requested: n}
+	}
+	return nil
+}
+
+// Usage reports current usage and limits of every tracked entity
+func (t *Tracker) Usage() models.UsageResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	response := models.UsageResponse{
+		Data:         make([]models.QuotaUsage, 0, len(Entities)),
+		ReconciledAt: t.reconciledAt,
+	}
+	for _, entity := range Entities {
+		usage := models.QuotaUsage{Entity: entity, Usage: t.usage[entity]}
+		if limit := t.limits[entity]; limit > 0 {
+			remaining := max(limit-usage.Usage, 0)
+			usage.Limit = &limit
+			usage.Remaining = &remaining
+		}
+		response.Data = append(response.Data, usage)
+	}
+	return response
+}
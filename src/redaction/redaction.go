@@ -0,0 +1,192 @@
+// Package redaction hides sensitive customer and deal fields from users
+// whose role may not read them. Rows loaded for a request are marked with
+// the fields to hide, and the models serialize those fields as null.
+package redaction
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// ContextKey holds the Viewer a context's queries are redacted for. It is a
+// plain string so the viewer set on a gin.Context is visible through its
+// Value method.
+const ContextKey = "redaction_viewer"
+
+// Viewer is the user responses are redacted for
+type Viewer struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// With returns a copy of ctx whose loaded rows are redacted for viewer
+func With(ctx context.Context, viewer Viewer) context.Context {
+	return context.WithValue(ctx, ContextKey, viewer)
+}
+
+// From returns the viewer of ctx, if any
+func From(ctx context.Context) (Viewer, bool) {
+	if ctx == nil {
+		return Viewer{}, false
+	}
+	viewer, ok := ctx.Value(ContextKey).(Viewer)
+	return viewer, ok
+}
+
+// Detach returns a background context carrying the viewer of ctx, for work
+// that outlives the request
+func Detach(ctx context.Context) context.Context {
+	detached := context.Background()
+	if viewer, ok := From(ctx); ok {
+		detached = With(detached, viewer)
+	}
+	return detached
+}
+
+// DefaultRules hide deal amounts, customer notes and the notes embedded in
+// deal details from users without manage_all on records they do not own
+var DefaultRules = map[string]map[string]models.FieldRule{
+	models.EntityCustomer: {
+		"notes": {Permission: models.PermissionManageAll, OwnerExempt: true},
+	},
+	models.EntityDeal: {
+		"amount": {Permission: models.PermissionManageAll, OwnerExempt: true},
+		"notes":  {Permission: models.PermissionManageAll, OwnerExempt: true},
+	},
+}
+
+// relatedFields lists the fields that can be redacted without being
+// editable, such as the notes a deal detail embeds
+var relatedFields = map[string][]string{
+	models.EntityDeal: {"notes"},
+}
+
+// Rules is the active redaction rule map. A field with a rule is readable
+// by roles holding its permission and, when OwnerExempt is set, by the
+// record's owner; unowned records have no owner.
+var Rules = DefaultRules
+
+// ParseRules parses a comma-separated list of rules in the form
+// entity.field=permission[:owner], e.g. "deal.amount=manage_all:owner".
+// An empty string yields the default rules and "none" disables redaction.
+func ParseRules(spec string) (map[string]map[string]models.FieldRule, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "":
+		return DefaultRules, nil
+	case "none":
+		return map[string]map[string]models.FieldRule{}, nil
+	}
+
+	rules := make(map[string]map[string]models.FieldRule)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, permission, ok := strings.Cut(entry, "=")
+		entity, field, ok2 := strings.Cut(target, ".")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid field redaction %q, expected entity.field=permission[:owner]", entry)
+		}
+		if !redactable(entity, field) {
+			return nil, fmt.Errorf("unknown field %q in field redaction %q", target, entry)
+		}
+
+		rule := models.FieldRule{Permission: permission}
+		if p, flag, found := strings.Cut(permission, ":"); found {
+			if flag != "owner" {
+				return nil, fmt.Errorf("invalid field redaction flag %q in %q", flag, entry)
+			}
+			rule = models.FieldRule{Permission: p, OwnerExempt: true}
+		}
+		if err := models.ValidatePermissions([]string{rule.Permission}); err != nil {
+			return nil, fmt.Errorf("field redaction %q: %w", entry, err)
+		}
+
+		if rules[entity] == nil {
+			rules[entity] = make(map[string]models.FieldRule)
+		}
+		rules[entity][field] = rule
+	}
+
+	return rules, nil
+}
+
+// Fields returns the fields of an entity hidden from viewer on a record
+// owned by ownerID, sorted
+func Fields(entity string, viewer Viewer, ownerID *uint) []string {
+	var fields []string
+	for field, rule := range Rules[entity] {
+		if rule.OwnerExempt && ownerID != nil && *ownerID == viewer.UserID {
+			continue
+		}
+		if !models.HasPermission(viewer.Role, rule.Permission) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Redactable is a model whose fields can be hidden
+type Redactable interface {
+	RedactionEntity() string
+	RedactionOwner() *uint
+	SetRedactedFields(fields []string)
+}
+
+// Mark sets the fields hidden from viewer on every redactable model in
+// value, which may be a model, a slice of models or pointers to them
+func Mark(value interface{}, viewer Viewer) {
+	mark(reflect.ValueOf(value), viewer)
+}
+
+// mark walks a loaded value, marking the redactable models in it
+func mark(v reflect.Value, viewer Viewer) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			mark(v.Index(i), viewer)
+		}
+	case reflect.Struct:
+		if !v.CanAddr() {
+			return
+		}
+		if model, ok := v.Addr().Interface().(Redactable); ok {
+			model.SetRedactedFields(Fields(model.RedactionEntity(), viewer, model.RedactionOwner()))
+		}
+	}
+}
+
+// Register marks the rows of every query made with a viewer in its context,
+// including preloaded associations, so no endpoint serializes a hidden field
+func Register(db *gorm.DB) error {
+	return db.Callback().Query().After("gorm:query").Register("redaction:mark", func(db *gorm.DB) {
+		if db.Error != nil || !db.Statement.ReflectValue.IsValid() {
+			return
+		}
+		if viewer, ok := From(db.Statement.Context); ok {
+			mark(db.Statement.ReflectValue, viewer)
+		}
+	})
+}
+
+// redactable checks whether a field can be given a redaction rule
+func redactable(entity, field string) bool {
+	return slices.Contains(models.EditableFields[entity], field) || slices.Contains(relatedFields[entity], field)
+}
@@ -0,0 +1,111 @@
+package redaction_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+)
+
+func TestFields(t *testing.T) {
+	self, other := uint(3), uint(4)
+	for _, tc := range []struct {
+		role     string
+		owner    *uint
+		customer []string
+		deal     []string
+	}{
+		{models.RoleAdmin, &self, nil, nil},
+		{models.RoleAdmin, &other, nil, nil},
+		{models.RoleAdmin, nil, nil, nil},
+		{models.RoleManager, &self, nil, nil},
+		{models.RoleManager, &other, nil, nil},
+		{models.RoleManager, nil, nil, nil},
+		{models.RoleAgent, &self, nil, nil},
+		{models.RoleAgent, &other, []string{"notes"}, []string{"amount", "notes"}},
+		{models.RoleAgent, nil, []string{"notes"}, []string{"amount", "notes"}},
+		{"unknown_role", &other, []string{"notes"}, []string{"amount", "notes"}},
+	} {
+		owner := "unowned"
+		if tc.owner != nil {
+			owner = map[uint]string{self: "own", other: "other's"}[*tc.owner]
+		}
+		t.Run(tc.role+"/"+owner, func(t *testing.T) {
+			viewer := redaction.Viewer{UserID: self, Role: tc.role}
+			if got := redaction.Fields(models.EntityCustomer, viewer, tc.owner); !slices.Equal(got, tc.customer) {
+				t.Errorf("customer fields = %v, want %v", got, tc.customer)
+			}
+			if got := redaction.Fields(models.EntityDeal, viewer, tc.owner); !slices.Equal(got, tc.deal) {
+				t.Errorf("deal fields = %v, want %v", got, tc.deal)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	for _, tc := range []struct {
+		spec  string
+		rules map[string]map[string]models.FieldRule
+		err   bool
+	}{
+		{spec: "", rules: redaction.DefaultRules},
+		{spec: "none", rules: map[string]map[string]models.FieldRule{}},
+		{spec: "deal.amount=manage_all:owner, customer.phone=manage_all", rules: map[string]map[string]models.FieldRule{
+			models.EntityDeal:     {"amount": {Permission: models.PermissionManageAll, OwnerExempt: true}},
+			models.EntityCustomer: {"phone": {Permission: models.PermissionManageAll}},
+		}},
+		{spec: "deal.notes=manage_all", rules: map[string]map[string]models.FieldRule{
+			models.EntityDeal: {"notes": {Permission: models.PermissionManageAll}},
+		}},
+		{spec: "deal.amount", err: true},
+		{spec: "deal.password=manage_all", err: true},
+		{spec: "customer.notes=manage_all:team", err: true},
+		{spec: "customer.notes=fly", err: true},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			rules, err := redaction.ParseRules(tc.spec)
+			if tc.err {
+				if err == nil {
+					t.Errorf("rules = %v, want an error", rules)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rules) != len(tc.rules) {
+				t.Fatalf("rules = %v, want %v", rules, tc.rules)
+			}
+			for entity, fields := range tc.rules {
+				if len(rules[entity]) != len(fields) {
+					t.Errorf("%s rules = %v, want %v", entity, rules[entity], fields)
+				}
+				for field, rule := range fields {
+					if rules[entity][field] != rule {
+						t.Errorf("%s.%s = %+v, want %+v", entity, field, rules[entity][field], rule)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMark(t *testing.T) {
+	agentID, otherID := uint(3), uint(4)
+	own := models.Deal{OwnerID: &agentID, Customer: models.Customer{AssignedTo: &otherID}}
+	others := &models.Deal{OwnerID: &otherID}
+	loaded := []interface{}{&own, []*models.Deal{others, nil}}
+
+	redaction.Mark(loaded, redaction.Viewer{UserID: agentID, Role: models.RoleAgent})
+	if own.RedactedFields != nil {
+		t.Errorf("own deal redacted %v", own.RedactedFields)
+	}
+	if !slices.Equal(others.RedactedFields, []string{"amount", "notes"}) {
+		t.Errorf("other's deal redacted %v", others.RedactedFields)
+	}
+	// Associations are marked by the queries that preload them
+	if own.Customer.RedactedFields != nil {
+		t.Errorf("customer of the own deal redacted %v", own.Customer.RedactedFields)
+	}
+}
@@ -0,0 +1,137 @@
+package reportrollup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Freshness tells report consumers how stale the snapshot days they read
+// are
+type Freshness struct {
+	RolledUpAt time.Time `json:"rolled_up_at"`
+	Through    string    `json:"through"`  // First day not in the snapshots
+	Timezone   string    `json:"timezone"` // Time zone of the snapshot days
+}
+
+// Coverage is the state of the snapshots as a report reads them. The zero
+// Coverage, before the first rollup, covers nothing.
+type Coverage struct {
+	state    *models.ReportRollupState
+	location *time.Location
+}
+
+// Load reads the coverage of the snapshots. Reports load it in the
+// repeatable read transaction they read the snapshots and the records in,
+// so a rollup or a queued change is seen by every part or by none.
+func Load(db *gorm.DB) (Coverage, error) {
+	state, err := loadState(db)
+	if err != nil || state == nil {
+		return Coverage{}, err
+	}
+	location, err := time.LoadLocation(state.Timezone)
+	if err != nil {
+		return Coverage{}, fmt.Errorf("report rollup time zone: %w", err)
+	}
+	return Coverage{state: state, location: location}, nil
+}
+
+// Timezone returns the time zone of the snapshot days, or "" before the
+// first rollup
+func (c Coverage) Timezone() string {
+	if c.state == nil {
+		return ""
+	}
+	return c.state.Timezone
+}
+
+// Window returns the snapshot days a report over the records stamped from
+// from to to, both inclusive, reads: the whole days of the window before
+// the last rollup. A nil from or to leaves the window open on that side.
+func (c Coverage) Window(from, to *time.Time) Window {
+	if c.state == nil {
+		return Window{}
+	}
+	year, month, day := c.state.Through.Date()
+	end := time.Date(year, month, day, 0, 0, 0, 0, c.location)
+	if to != nil {
+		// Days ending by the microsecond after to, the precision of Postgres
+		if last := midnight(to.Add(time.Microsecond).In(c.location)); last.Before(end) {
+			end = last
+		}
+	}
+	var first *time.Time
+	if from != nil {
+		start := midnight(from.In(c.location))
+		if start.Before(*from) {
+			start = start.AddDate(0, 0, 1)
+		}
+		if !start.Before(end) {
+			return Window{}
+		}
+		first = &start
+	}
+	return Window{coverage: c, first: first, end: end, ok: true}
+}
+
+// midnight returns the start of the day holding t in its location
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Window is the run of snapshot days a report reads, except the days queued
+// since the last rollup. The zero Window reads no snapshot day.
+type Window struct {
+	coverage Coverage
+	first    *time.Time // Midnight starting the first day, nil for every day before end
+	end      time.Time  // Midnight after the last day
+	ok       bool
+}
+
+// Live returns the condition that a record stamped at column is not in a
+// snapshot day the report reads, so the report computes it from the record
+func (w Window) Live(column string) clause.Expr {
+	if !w.ok {
+		return clause.Expr{SQL: "TRUE"}
+	}
+	queued := "(" + column + " AT TIME ZONE ?)::date IN (SELECT day FROM report_rollup_days)"
+	if w.first == nil {
+		return clause.Expr{SQL: "(" + column + " >= ? OR " + queued + ")", Vars: []interface{}{w.end, w.coverage.state.Timezone}}
+	}
+	return clause.Expr{
+		SQL:  "(" + column + " < ? OR " + column + " >= ? OR " + queued + ")",
+		Vars: []interface{}{*w.first, w.end, w.coverage.state.Timezone},
+	}
+}
+
+// Snapshot returns the condition on the day column of a snapshot table that
+// the report reads the row
+func (w Window) Snapshot() clause.Expr {
+	if !w.ok {
+		return clause.Expr{SQL: "FALSE"}
+	}
+	days := "day < ? AND day NOT IN (SELECT day FROM report_rollup_days)"
+	vars := []interface{}{w.end.Format(time.DateOnly)}
+	if w.first != nil {
+		days = "day >= ? AND " + days
+		vars = append([]interface{}{w.first.Format(time.DateOnly)}, vars...)
+	}
+	return clause.Expr{SQL: "(" + days + ")", Vars: vars}
+}
+
+// Freshness returns the freshness of the snapshots the report reads, or nil
+// when it reads none and is computed live
+func (w Window) Freshness() *Freshness {
+	if !w.ok {
+		return nil
+	}
+	return &Freshness{
+		RolledUpAt: w.coverage.state.RolledUpAt,
+		Through:    w.coverage.state.Through.Format(time.DateOnly),
+		Timezone:   w.coverage.state.Timezone,
+	}
+}
@@ -0,0 +1,227 @@
+// Package reportrollup keeps the daily snapshots the funnel, revenue and
+// segments reports read for days before the last rollup, so only the rest
+// of their window is computed from the records on each request.
+//
+// Snapshot days are dates in the time zone of the rollup. Triggers on
+// deals, customers and customer_tags queue the days a change affects; the
+// reports compute queued days live until the next rollup recomputes them.
+package reportrollup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// ErrRollupInProgress is returned when a rollup is requested while one is
+// running here or on another replica
+var ErrRollupInProgress = errors.New("report rollup already in progress")
+
+// rollupLockKey is the Postgres advisory lock held while a replica rolls up
+// the snapshots
+const rollupLockKey int64 = 0x43524d5f524f4c4c // "CRM_ROLL"
+
+// queueLockKey is the Postgres advisory lock whose shared side a change
+// holds once it queued a day, until it commits (see migration 000046). A
+// rollup takes it exclusively to claim the queued days.
+const queueLockKey int64 = 0x43524d5f51554555 // "CRM_QUEU"
+
+// claimLockTimeout bounds how long claiming the queued days waits for the
+// changes queueing them; writes queueing days wait behind it meanwhile
+const claimLockTimeout = "10s"
+
+// Summary summarizes a rollup
+type Summary struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Full       bool      `json:"full"`     // Every day was recomputed
+	Timezone   string    `json:"timezone"` // Time zone of the snapshot days
+	Through    string    `json:"through"`  // First day not in the snapshots, the day of the rollup
+}
+
+// Runner rolls up the report snapshots
+type Runner struct {
+	db       *gorm.DB
+	location *time.Location
+}
+
+// NewRunner creates a new Runner keeping snapshot days in location
+func NewRunner(db *gorm.DB, location *time.Location) *Runner {
+	return &Runner{db: db, location: location}
+}
+
+// Run brings the snapshots up to the day before today. It recomputes the
+// days queued since the last rollup and the days since then, or every day
+// when full is set, when nothing was rolled up yet or when the time zone
+// changed.
+//
+// The rollup reads the records from one snapshot, taken once it claimed the
+// queued days. Changes committed while it runs stay queued, except on a first rollup or a change of time zone,
+// which holds off writes to the source tables until it is done.
+func (r *Runner) Run(ctx context.Context, full bool) (Summary, error) {
+	summary := Summary{StartedAt: time.Now(), Timezone: r.location.String()}
+	summary.Through = summary.StartedAt.In(r.location).Format(time.DateOnly)
+
+	err := r.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// A new session, so the queries below do not add up their clauses
+		conn = conn.Session(&gorm.Session{})
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", rollupLockKey).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to take report rollup lock: %w", err)
+		}
+		if !locked {
+			return ErrRollupInProgress
+		}
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(?)", rollupLockKey)
+
+		state, err := loadState(conn)
+		if err != nil {
+			return err
+		}
+		reset := state == nil || state.Timezone != summary.Timezone
+		if err := claimDays(conn); err != nil {
+			return fmt.Errorf("failed to claim queued days: %w", err)
+		}
+		err = r.rollup(conn, &summary, full, reset)
+		if errors.Is(err, errStateChanged) {
+			// A truncate dropped the state since it was read
+			err = r.rollup(conn, &summary, full, true)
+		}
+		return err
+	})
+	summary.FinishedAt = time.Now()
+	return summary, err
+}
+
+// claimDays claims the queued days for the rollup. Changes queue a day
+// only while it is unclaimed and not yet queued, so the claim first waits
+// for those still queueing to commit: a change that found its day queued is
+// then in the rollup's snapshot, taken after the claim. Changes queue
+// claimed days anew, so they stay queued past the rollup.
+func claimDays(conn *gorm.DB) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL lock_timeout = '" + claimLockTimeout + "'").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", queueLockKey).Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE report_rollup_days SET claimed = TRUE WHERE NOT claimed").Error
+	})
+}
+
+// errStateChanged is returned when the rollup state differs in the rollup's
+// snapshot from the state the rollup was planned on
+var errStateChanged = errors.New("report rollup state changed")
+
+// rollup recomputes the snapshot days in one repeatable read transaction.
+// A reset first locks the state every trigger reads, so writes that read no
+// state or another time zone finish before the snapshot is taken and later
+// ones queue their days in the new time zone.
+func (r *Runner) rollup(conn *gorm.DB, summary *Summary, full, reset bool) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if reset {
+			if err := tx.Exec("LOCK TABLE report_rollup_state IN ACCESS EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		}
+		state, err := loadState(tx)
+		if err != nil {
+			return err
+		}
+		if !reset && (state == nil || state.Timezone != summary.Timezone) {
+			return errStateChanged
+		}
+		summary.Full = full || reset
+
+		args := map[string]interface{}{
+			"tz":      summary.Timezone,
+			"through": summary.Through,
+			"won":     models.DealStageClosedWon,
+		}
+		// The days recomputed, as a condition on a day expression
+		recompute := func(day string) string {
+			return day + " < @through"
+		}
+		if !summary.Full {
+			args["since"] = state.Through.Format(time.DateOnly)
+			recompute = func(day string) string {
+				return day + " < @through AND (" + day + " >= @since OR " + day + " IN (SELECT day FROM report_rollup_days WHERE claimed))"
+			}
+		}
+
+		for _, table := range []string{"report_customer_days", "report_deal_days", "report_revenue_days", "report_segment_days"} {
+			var err error
+			if summary.Full {
+				err = tx.Exec("DELETE FROM " + table).Error
+			} else {
+				err = tx.Exec("DELETE FROM "+table+" WHERE "+recompute("day"), args).Error
+			}
+			if err != nil {
+				return fmt.Errorf("failed to clear %s: %w", table, err)
+			}
+		}
+
+		created := "(created_at AT TIME ZONE @tz)::date"
+		closed := "(deals.actual_close_date AT TIME ZONE @tz)::date"
+		wonDeals := "deals.deleted_at IS NULL AND deals.stage = @won AND deals.actual_close_date IS NOT NULL AND " + recompute(closed)
+		for _, insert := range []struct {
+			table string
+			sql   string
+		}{
+			{"report_customer_days", `INSERT INTO report_customer_days (day, status, customers)
+				SELECT ` + created + `, status, COUNT(*) FROM customers
+				WHERE deleted_at IS NULL AND archived_at IS NULL AND ` + recompute(created) + `
+				GROUP BY 1, 2`},
+			{"report_deal_days", `INSERT INTO report_deal_days (day, owner_id, stage, deals)
+				SELECT ` + created + `, owner_id, stage, COUNT(*) FROM deals
+				WHERE deleted_at IS NULL AND archived_at IS NULL AND ` + recompute(created) + `
+				GROUP BY 1, 2, 3`},
+			{"report_revenue_days", `INSERT INTO report_revenue_days (day, owner_id, currency, won_count, won_total)
+				SELECT ` + closed + `, owner_id, currency, COUNT(*), COALESCE(SUM(amount), 0) FROM deals
+				WHERE ` + wonDeals + `
+				GROUP BY 1, 2, 3`},
+			{"report_segment_days", `INSERT INTO report_segment_days (day, tag_id, won_count, won_total)
+				SELECT ` + closed + `, customer_tags.tag_id, COUNT(*), COALESCE(SUM(deals.amount), 0) FROM deals
+				JOIN customers ON customers.id = deals.customer_id AND customers.deleted_at IS NULL
+				JOIN customer_tags ON customer_tags.customer_id = customers.id
+				WHERE deals.archived_at IS NULL AND ` + wonDeals + `
+				GROUP BY 1, 2`},
+			{"report_segment_days", `INSERT INTO report_segment_days (day, tag_id, won_count, won_total)
+				SELECT ` + closed + `, NULL, COUNT(*), COALESCE(SUM(deals.amount), 0) FROM deals
+				JOIN customers ON customers.id = deals.customer_id AND customers.deleted_at IS NULL
+				WHERE deals.archived_at IS NULL AND ` + wonDeals + `
+				AND NOT EXISTS (SELECT 1 FROM customer_tags WHERE customer_tags.customer_id = customers.id)
+				GROUP BY 1`},
+		} {
+			if err := tx.Exec(insert.sql, args).Error; err != nil {
+				return fmt.Errorf("failed to roll up %s: %w", insert.table, err)
+			}
+		}
+
+		// Days queued since the claim stay
+		if err := tx.Exec("DELETE FROM report_rollup_days WHERE claimed").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO report_rollup_state (id, timezone, through, rolled_up_at) VALUES (1, @tz, @through, @now)
+			ON CONFLICT (id) DO UPDATE SET timezone = EXCLUDED.timezone, through = EXCLUDED.through, rolled_up_at = EXCLUDED.rolled_up_at`,
+			map[string]interface{}{"tz": summary.Timezone, "through": summary.Through, "now": summary.StartedAt}).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+}
+
+// loadState returns the rollup state, or nil before the first rollup
+func loadState(db *gorm.DB) (*models.ReportRollupState, error) {
+	var states []models.ReportRollupState
+	if err := db.Where("id = ?", 1).Limit(1).Find(&states).Error; err != nil {
+		return nil, fmt.Errorf("failed to load report rollup state: %w", err)
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return &states[0], nil
+}
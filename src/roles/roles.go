@@ -0,0 +1,41 @@
+// Package roles loads the role definitions used for permission checks from
+// the database.
+package roles
+
+import (
+	"context"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Service keeps the permission checks of models in step with the roles table
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a Service. Call Reload to load the roles.
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Reload reads every role from the database and makes it the set used by
+// models.HasPermission. An empty table leaves the built-in roles in effect
+// so a database without the roles migration keeps working.
+func (s *Service) Reload(ctx context.Context) error {
+	var roles []models.Role
+	if err := s.db.WithContext(ctx).Find(&roles).Error; err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		models.SetRolePermissions(models.DefaultRolePermissions)
+		return nil
+	}
+
+	permissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		permissions[role.Name] = role.Permissions
+	}
+	models.SetRolePermissions(permissions)
+	return nil
+}
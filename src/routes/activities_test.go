@@ -0,0 +1,201 @@
+package routes_test
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+func TestCompleteActivity(t *testing.T) {
+	s := newServer(t)
+	activity := s.Factory.Activity(t, s.Factory.Customer(t))
+
+	rec := s.do(t, agent, http.MethodPost, "/admin/activities/1/complete", map[string]interface{}{"outcome": "Agreed on a demo", "duration": 15})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// Only the completion columns are written
+	var update string
+	for _, statement := range s.Statements() {
+		if strings.HasPrefix(statement, `UPDATE "activities"`) {
+			update = statement
+		}
+	}
+	for _, column := range []string{"status", "completed_at", "outcome", "outcome_code", "duration", "updated_at"} {
+		if !strings.Contains(update, `"`+column+`"=`) {
+			t.Errorf("update does not set %s: %s", column, update)
+		}
+	}
+	for _, column := range []string{"title", "description", "assigned_to", "due_date", "priority"} {
+		if strings.Contains(update, `"`+column+`"=`) {
+			t.Errorf("update sets %s: %s", column, update)
+		}
+	}
+
+	var completed models.Activity
+	if err := s.DB.First(&completed, activity.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if completed.Status != models.ActivityStatusCompleted || completed.CompletedAt == nil || completed.Duration != 15 || completed.Title != activity.Title {
+		t.Errorf("completed = %+v", completed)
+	}
+
+	rec = s.do(t, agent, http.MethodPost, "/admin/activities/1/complete", map[string]interface{}{"outcome": "Again"})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("second completion: status = %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCompleteActivityClosedConcurrently(t *testing.T) {
+	s := newServer(t)
+	s.Factory.Activity(t, s.Factory.Customer(t))
+
+	// The activity is cancelled between the handler's read and its write.
+	// The cancellation runs on the handler's connection, so it is rolled
+	// back with the refused completion.
+	err := s.DB.Callback().Update().Before("gorm:update").Register("test:cancel_activity", func(tx *gorm.DB) {
+		if tx.Statement.Table == "activities" {
+			tx.Statement.ConnPool.ExecContext(tx.Statement.Context, `UPDATE "activities" SET "status" = 'cancelled' WHERE "id" = 1`)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := s.do(t, agent, http.MethodPost, "/admin/activities/1/complete", map[string]interface{}{
+		"outcome":       "Agreed on a demo",
+		"next_activity": map[string]interface{}{"title": "Demo"},
+	})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var activities []models.Activity
+	if err := s.DB.Find(&activities).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 1 || activities[0].Status == models.ActivityStatusCompleted || activities[0].Outcome != "" {
+		t.Errorf("activities = %+v, want the activity alone and not completed", activities)
+	}
+	if n := s.Count("audit_logs"); n != 0 {
+		t.Errorf("%d audit entries", n)
+	}
+}
+
+// TestEffectiveStatus checks the overdue derivation where activities are
+// read: lists, their status filters and the overview. The derivation
+// compares with the database's NOW(), so due dates are set from the real
+// time; its boundary is pinned in the models tests.
+func TestEffectiveStatus(t *testing.T) {
+	s := newServer(t)
+	now := time.Now()
+	customer := s.Factory.Customer(t)
+	activity := func(title string, status models.ActivityStatus, due time.Time) {
+		s.Factory.Activity(t, customer, func(a *models.Activity) {
+			a.Title, a.Status, a.DueDate, a.AssignedTo = title, status, &due, &agent.ID
+		})
+	}
+	activity("past due", models.ActivityStatusScheduled, now.Add(-time.Hour))
+	activity("due soon", models.ActivityStatusScheduled, now.Add(time.Hour))
+	activity("due later", models.ActivityStatusScheduled, now.Add(24*time.Hour))
+	activity("stored overdue", models.ActivityStatusOverdue, now.Add(time.Hour))
+	activity("completed late", models.ActivityStatusCompleted, now.Add(-time.Hour))
+
+	// list returns the titles and effective statuses of the listed activities
+	list := func(as caller, path string) map[string]models.ActivityStatus {
+		t.Helper()
+		var body struct{ Data []models.Activity }
+		decode(t, s.get(t, as, path), &body)
+		statuses := map[string]models.ActivityStatus{}
+		for _, a := range body.Data {
+			statuses[a.Title] = a.EffectiveStatus
+		}
+		return statuses
+	}
+	titles := func(statuses map[string]models.ActivityStatus) []string {
+		var titles []string
+		for title := range statuses {
+			titles = append(titles, title)
+		}
+		slices.Sort(titles)
+		return titles
+	}
+	overview := func() (scheduled, overdue int64) {
+		t.Helper()
+		var report struct {
+			Activities    struct{ Scheduled, Overdue int64 }
+			PartialErrors []string `json:"partial_errors"`
+		}
+		decode(t, s.get(t, admin, "/admin/reports/overview"), &report)
+		if len(report.PartialErrors) > 0 {
+			t.Fatalf("overview: partial_errors = %v", report.PartialErrors)
+		}
+		return report.Activities.Scheduled, report.Activities.Overdue
+	}
+
+	all := list(admin, "/admin/activities")
+	for title, want := range map[string]models.ActivityStatus{
+		"past due":       models.ActivityStatusOverdue,
+		"due soon":       models.ActivityStatusScheduled,
+		"due later":      models.ActivityStatusScheduled,
+		"stored overdue": models.ActivityStatusOverdue,
+		"completed late": models.ActivityStatusCompleted,
+	} {
+		if all[title] != want {
+			t.Errorf("%s: effective_status = %q, want %q", title, all[title], want)
+		}
+	}
+	for path, want := range map[string][]string{
+		"/admin/activities?status=overdue":      {"past due", "stored overdue"},
+		"/admin/activities?status=scheduled":    {"due later", "due soon"},
+		"/admin/me/activities?status=overdue":   {"past due", "stored overdue"},
+		"/admin/me/activities?status=scheduled": {"due later", "due soon"},
+		"/admin/me/activities":                  {"due later", "due soon", "past due", "stored overdue"},
+	} {
+		if got := titles(list(agent, path)); !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", path, got, want)
+		}
+	}
+	if scheduled, overdue := overview(); scheduled != 2 || overdue != 2 {
+		t.Errorf("overview: scheduled = %d, overdue = %d, want 2 and 2", scheduled, overdue)
+	}
+
+	// Reading never writes the derived status back
+	for _, statement := range s.Statements() {
+		if strings.HasPrefix(statement, `UPDATE "activities"`) {
+			t.Errorf("reads wrote an activity: %s", statement)
+		}
+	}
+}
+
+// TestActivityStatusTransition checks that a completed activity is only
+// moved back to an open status when reopened explicitly
+func TestActivityStatusTransition(t *testing.T) {
+	s := newServer(t)
+	activity := s.Factory.Activity(t, s.Factory.Customer(t), func(a *models.Activity) { a.Status = models.ActivityStatusCompleted })
+	path := "/admin/activities/" + strconv.FormatUint(uint64(activity.ID), 10)
+
+	rec := s.do(t, manager, http.MethodPatch, path, map[string]interface{}{"status": models.ActivityStatusScheduled})
+	var refused struct {
+		Code    string
+		From    models.ActivityStatus
+		Allowed []models.ActivityStatus
+	}
+	decode(t, rec, &refused)
+	if rec.Code != http.StatusUnprocessableEntity || refused.Code != "INVALID_TRANSITION" || refused.From != models.ActivityStatusCompleted ||
+		slices.Contains(refused.Allowed, models.ActivityStatusScheduled) {
+		t.Errorf("without reopen: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = s.do(t, manager, http.MethodPatch, path, map[string]interface{}{"status": models.ActivityStatusScheduled, "reopen": true})
+	if rec.Code != http.StatusOK {
+		t.Errorf("reopen: status = %d: %s", rec.Code, rec.Body)
+	}
+}
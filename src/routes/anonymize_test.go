@@ -0,0 +1,84 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestAnonymizationKeepsReportTotals anonymizes a customer with won, lost
+// and open deals and checks that every report over their records answers
+// as it did before
+func TestAnonymizationKeepsReportTotals(t *testing.T) {
+	s := newServer(t)
+	vip := s.Factory.Tag(t)
+	var customers []models.Customer
+	for i := 0; i < 3; i++ {
+		customer := s.Factory.Customer(t, func(c *models.Customer) { c.Status = models.CustomerStatusActive })
+		s.Factory.TagCustomer(t, customer, vip)
+		s.Factory.Contact(t, customer)
+		s.Factory.Note(t, customer)
+		s.Factory.Activity(t, customer)
+		for j, stage := range []models.DealStage{models.DealStageClosedWon, models.DealStageClosedLost, models.DealStageNegotiation} {
+			closed := factory.Epoch.AddDate(0, 0, 7*i+j)
+			s.Factory.Deal(t, customer, func(d *models.Deal) {
+				d.Stage, d.Amount, d.Currency, d.OwnerID = stage, float64(1000*(i+1)+100*j), "USD", &agent.ID
+				if stage != models.DealStageNegotiation {
+					d.ActualCloseDate = &closed
+				}
+			})
+		}
+		customers = append(customers, customer)
+	}
+
+	const period = "from=2025-01-01T00:00:00Z&to=2025-03-01T00:00:00Z"
+	reports := []string{
+		"/admin/reports/overview",
+		"/admin/reports/revenue?" + period,
+		"/admin/reports/funnel?" + period,
+		"/admin/reports/outcomes?" + period,
+		fmt.Sprintf("/admin/reports/segments?tag_ids=%d&%s", vip.ID, period),
+	}
+	before := make(map[string]string)
+	for _, report := range reports {
+		rec := s.get(t, admin, report)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", report, rec.Code, rec.Body)
+		}
+		before[report] = rec.Body.String()
+	}
+
+	path := fmt.Sprintf("/admin/customers/%d/anonymize", customers[1].ID)
+	var preview models.AnonymizationPreview
+	decode(t, s.do(t, admin, http.MethodPost, path, nil), &preview)
+	if preview.ConfirmationToken == "" {
+		t.Fatal("no confirmation token")
+	}
+	rec := s.do(t, admin, http.MethodPost, path, map[string]string{"confirmation_token": preview.ConfirmationToken})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("anonymize: status = %d: %s", rec.Code, rec.Body)
+	}
+	var anonymized models.Customer
+	if err := s.DB.First(&anonymized, customers[1].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if anonymized.AnonymizedAt == nil || anonymized.Name == customers[1].Name || anonymized.Email == customers[1].Email {
+		t.Fatalf("customer not anonymized: %+v", anonymized)
+	}
+
+	for _, report := range reports {
+		rec := s.get(t, admin, report)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", report, rec.Code, rec.Body)
+		}
+		if after := rec.Body.String(); after != before[report] {
+			t.Errorf("%s changed after anonymization:\nbefore %s\nafter  %s", report, before[report], after)
+		}
+	}
+	if n := s.Count("deals"); n != 9 {
+		t.Errorf("%d deals, want all 9 kept", n)
+	}
+}
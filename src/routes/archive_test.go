@@ -0,0 +1,73 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestArchivedCustomerChildren checks that the contacts and tags of an
+// archived customer cannot change until the customer is unarchived
+func TestArchivedCustomerChildren(t *testing.T) {
+	s := newServer(t)
+	archivedAt := s.Now()
+	customer := s.Factory.Customer(t, func(c *models.Customer) { c.ArchivedAt = &archivedAt })
+	contact := s.Factory.Contact(t, customer)
+	assigned, other := s.Factory.Tag(t), s.Factory.Tag(t)
+	s.Factory.TagCustomer(t, customer, assigned)
+
+	importContacts := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/customers/%d/contacts/import", customer.ID),
+			strings.NewReader("first_name,last_name,email\nOmar,Nakheel,omar@nakheel.sa\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		return s.serve(t, manager, req)
+	}
+	for _, tc := range []struct {
+		name string
+		do   func() *httptest.ResponseRecorder
+	}{
+		{"create contact", func() *httptest.ResponseRecorder {
+			return s.do(t, manager, http.MethodPost, fmt.Sprintf("/admin/customers/%d/contacts", customer.ID), map[string]interface{}{"first_name": "Omar"})
+		}},
+		{"import contacts", importContacts},
+		{"update contact", func() *httptest.ResponseRecorder {
+			return s.do(t, manager, http.MethodPut, fmt.Sprintf("/admin/contacts/%d", contact.ID), map[string]interface{}{"first_name": "Omar"})
+		}},
+		{"delete contact", func() *httptest.ResponseRecorder {
+			return s.do(t, admin, http.MethodDelete, fmt.Sprintf("/admin/contacts/%d", contact.ID), nil)
+		}},
+		{"assign tag", func() *httptest.ResponseRecorder {
+			return s.do(t, manager, http.MethodPost, fmt.Sprintf("/admin/customers/%d/tags/%d", customer.ID, other.ID), nil)
+		}},
+		{"remove tag", func() *httptest.ResponseRecorder {
+			return s.do(t, manager, http.MethodDelete, fmt.Sprintf("/admin/customers/%d/tags/%d", customer.ID, assigned.ID), nil)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := tc.do()
+			if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"code":"ARCHIVED"`) {
+				t.Errorf("status = %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+	if n := s.Count("contacts"); n != 1 {
+		t.Errorf("%d contacts, want the one created before archiving", n)
+	}
+	if n := s.Count("customer_tags"); n != 1 {
+		t.Errorf("%d customer tags, want 1", n)
+	}
+
+	if rec := s.do(t, manager, http.MethodPost, fmt.Sprintf("/admin/customers/%d/unarchive", customer.ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("unarchive: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, manager, http.MethodPost, fmt.Sprintf("/admin/customers/%d/contacts", customer.ID), map[string]interface{}{"first_name": "Omar"}); rec.Code != http.StatusCreated {
+		t.Errorf("create contact after unarchive: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, manager, http.MethodPost, fmt.Sprintf("/admin/customers/%d/tags/%d", customer.ID, other.ID), nil); rec.Code != http.StatusOK {
+		t.Errorf("assign tag after unarchive: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,77 @@
+package routes_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestConcurrentAuditedWrites runs bulk stage changes and single deal
+// updates on the same deals at once. A bulk change locks its deals one by
+// one while recording their entries, so holding the audit chain lock from
+// its first entry until commit deadlocked with an update of a later deal
+// waiting to record its own.
+func TestConcurrentAuditedWrites(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	ids := make([]uint, 8)
+	for i := range ids {
+		ids[i] = s.Factory.Deal(t, customer).ID
+	}
+
+	var wg sync.WaitGroup
+	failures := make(chan string, 100)
+	check := func(what string, code int, body fmt.Stringer) {
+		if code != http.StatusOK {
+			failures <- fmt.Sprintf("%s: status = %d: %s", what, code, body)
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, stage := range []models.DealStage{models.DealStageQualification, models.DealStageProposal, models.DealStageNegotiation} {
+			rec := s.do(t, admin, http.MethodPost, "/admin/deals/bulk-stage", map[string]interface{}{"ids": ids, "stage": stage})
+			check("bulk stage "+string(stage), rec.Code, rec.Body)
+		}
+	}()
+	for writer := 1; writer <= 4; writer++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 0; round < 3; round++ {
+				// Against the bulk change's order, so each waits on the other
+				for i := len(ids) - 1; i >= 0; i-- {
+					path := fmt.Sprintf("/admin/deals/%d", ids[i])
+					rec := s.do(t, admin, http.MethodPatch, path, map[string]interface{}{"amount": writer*1000 + round})
+					check("patch "+path, rec.Code, rec.Body)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(failures)
+	for failure := range failures {
+		t.Error(failure)
+	}
+
+	if n := s.Count("audit_log_queue"); n != 0 {
+		t.Errorf("%d audit entries left queued", n)
+	}
+	entries := s.Count("audit_logs")
+	if entries < 4*3*len(ids) {
+		t.Errorf("%d audit entries, want one per change", entries)
+	}
+	result, err := audittrail.Verify(context.Background(), s.DB, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checked != int64(entries) {
+		t.Errorf("audit chain: %+v, want %d valid entries", result, entries)
+	}
+}
@@ -0,0 +1,209 @@
+package routes_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/fallback"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// auditPolicy sets the audit write policy and fallback writer for a test
+func auditPolicy(t *testing.T, policy fallback.Policy, writer *fallback.Writer) {
+	t.Helper()
+	oldPolicy, oldFallback := audittrail.WritePolicy, audittrail.Fallback
+	audittrail.WritePolicy, audittrail.Fallback = policy, writer
+	t.Cleanup(func() {
+		audittrail.WritePolicy, audittrail.Fallback = oldPolicy, oldFallback
+	})
+}
+
+func TestStrictAuditFailureRollsBackTheChange(t *testing.T) {
+	s := newServer(t)
+	auditPolicy(t, fallback.PolicyStrict, fallback.NewWriter(s.DB, time.Minute, 10, nil))
+	customer := s.Factory.Customer(t)
+	deal := s.Factory.Deal(t, customer)
+	s.FailInserts(t, "audit_log_queue")
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		body   map[string]interface{}
+	}{
+		{"create customer", http.MethodPost, "/admin/customers", map[string]interface{}{"name": "Huda", "email": "huda@nakheel.sa"}},
+		{"update customer", http.MethodPut, "/admin/customers/1", map[string]interface{}{"company": "Nakheel"}},
+		{"create deal", http.MethodPost, "/admin/deals", map[string]interface{}{"title": "Renewal", "customer_id": customer.ID}},
+		{"update deal", http.MethodPut, "/admin/deals/1", map[string]interface{}{"amount": 7500}},
+		{"create contact", http.MethodPost, "/admin/customers/1/contacts", map[string]interface{}{"first_name": "Sara", "last_name": "Ali", "email": "sara@nakheel.sa"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, admin, tc.method, tc.path, tc.body)
+			var body struct {
+				Code string `json:"code"`
+			}
+			decode(t, rec, &body)
+			if rec.Code != http.StatusInternalServerError || body.Code != "AUDIT_WRITE_FAILED" {
+				t.Errorf("status = %d, code = %q: %s", rec.Code, body.Code, rec.Body)
+			}
+		})
+	}
+
+	if n := s.Count("customers"); n != 1 {
+		t.Errorf("%d customers, want only the seeded one", n)
+	}
+	if n := s.Count("deals"); n != 1 {
+		t.Errorf("%d deals, want only the seeded one", n)
+	}
+	if n := s.Count("contacts"); n != 0 {
+		t.Errorf("%d contacts, want none", n)
+	}
+	var stored models.Customer
+	if err := s.DB.First(&stored, customer.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Company != customer.Company {
+		t.Errorf("company = %q, the update was kept", stored.Company)
+	}
+	var storedDeal models.Deal
+	if err := s.DB.First(&storedDeal, deal.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if storedDeal.Amount != deal.Amount {
+		t.Errorf("amount = %v, the update was kept", storedDeal.Amount)
+	}
+	if n := s.Count("audit_logs"); n != 0 {
+		t.Errorf("%d audit entries", n)
+	}
+}
+
+func TestResilientAuditFailureKeepsTheChange(t *testing.T) {
+	s := newServer(t)
+	writer := fallback.NewWriter(s.DB, time.Minute, 10, nil)
+	writer.Register(audittrail.FallbackKind, audittrail.Replay)
+	auditPolicy(t, fallback.PolicyResilient, writer)
+	restore := s.FailInserts(t, "audit_log_queue")
+
+	rec := s.do(t, admin, http.MethodPost, "/admin/customers", map[string]interface{}{"name": "Huda", "email": "huda@nakheel.sa"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if n := s.Count("customers"); n != 1 {
+		t.Errorf("%d customers, want the created one", n)
+	}
+	if n := s.Count("audit_logs"); n != 0 {
+		t.Errorf("%d audit entries were written", n)
+	}
+	pending, err := writer.Pending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending[audittrail.FallbackKind] != 1 {
+		t.Fatalf("pending = %v, want the audit entry", pending)
+	}
+
+	// The kept entry is written once the audit log accepts it again
+	restore()
+	if replayed, err := writer.Replay(context.Background()); err != nil || replayed != 1 {
+		t.Fatalf("replayed %d: %v", replayed, err)
+	}
+	var entry models.AuditLog
+	if err := s.DB.First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if entry.ResourceType != "customer" || entry.ResourceID != 1 || entry.Action != models.AuditActionCreate {
+		t.Errorf("replayed entry = %+v", entry)
+	}
+}
+
+// TestResilientAuditFailureWaitsForCommit checks that an audit entry that
+// failed under the resilient policy is kept for replay only once the
+// change's transaction commits, not when it or its savepoint rolls back
+func TestResilientAuditFailureWaitsForCommit(t *testing.T) {
+	s := newServer(t)
+	writer := fallback.NewWriter(s.DB, time.Minute, 10, nil)
+	writer.Register(audittrail.FallbackKind, audittrail.Replay)
+	auditPolicy(t, fallback.PolicyResilient, writer)
+	restore := s.FailInserts(t, "audit_log_queue")
+	defer restore()
+
+	errRolledBack := errors.New("rolled back")
+	change := func(tx *gorm.DB, name string) error {
+		customer := models.Customer{Name: name, Email: strings.ToLower(name) + "@nakheel.sa", Status: models.CustomerStatusLead}
+		if err := tx.Create(&customer).Error; err != nil {
+			return err
+		}
+		return audittrail.Record(context.Background(), tx, &models.AuditLog{
+			ResourceType: "customer", ResourceID: customer.ID, Action: models.AuditActionCreate, UserID: admin.ID,
+		})
+	}
+	pending := func() int64 {
+		t.Helper()
+		counts, err := writer.Pending(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return counts[audittrail.FallbackKind]
+	}
+
+	// The change fails after its audit entry did
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx, "Huda"); err != nil {
+			return err
+		}
+		return errRolledBack
+	})
+	if !errors.Is(err, errRolledBack) {
+		t.Fatalf("err = %v", err)
+	}
+	if n := pending(); n != 0 {
+		t.Errorf("%d entries kept for a rolled back change", n)
+	}
+
+	// A nested change is rolled back to its savepoint, the outer one commits
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx, "Sara"); err != nil {
+			return err
+		}
+		if err := tx.Transaction(func(tx *gorm.DB) error {
+			if err := change(tx, "Omar"); err != nil {
+				return err
+			}
+			return errRolledBack
+		}); !errors.Is(err, errRolledBack) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := pending(); n != 1 {
+		t.Fatalf("%d entries kept, want the committed change's", n)
+	}
+
+	restore()
+	if replayed, err := writer.Replay(context.Background()); err != nil || replayed != 1 {
+		t.Fatalf("replayed %d: %v", replayed, err)
+	}
+	var entries []models.AuditLog
+	if err := s.DB.Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	var sara models.Customer
+	if err := s.DB.Where("name = ?", "Sara").First(&sara).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ResourceID != sara.ID {
+		t.Errorf("replayed entries = %+v, want Sara's", entries)
+	}
+	if n := s.Count("customers"); n != 1 {
+		t.Errorf("%d customers, want only Sara", n)
+	}
+}
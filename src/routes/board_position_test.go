@@ -0,0 +1,108 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestBoardPositionPerOwner checks that new and moved deals go to the
+// bottom of their stage on their owner's board, or on the global board
+// when unowned, and that the bottom is read under the column's lock
+func TestBoardPositionPerOwner(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	two, three := uint(2), uint(3)
+	column := func(owner *uint, stage models.DealStage, position float64) models.Deal {
+		return s.Factory.Deal(t, customer, func(d *models.Deal) { d.OwnerID, d.Stage, d.BoardPosition = owner, stage, position })
+	}
+	twos := column(&two, models.DealStageProspecting, 5000)
+	threes := column(&three, models.DealStageProspecting, 100)
+	unowned := column(nil, models.DealStageProspecting, 9000)
+	column(&two, models.DealStageProposal, 7000)
+
+	position := func(id uint) float64 {
+		t.Helper()
+		var deal models.Deal
+		if err := s.DB.First(&deal, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return deal.BoardPosition
+	}
+
+	for _, tc := range []struct {
+		name  string
+		owner *uint
+		want  float64
+	}{
+		{"owner 2", &two, 5000 + models.BoardPositionStep},
+		{"owner 3", &three, 100 + models.BoardPositionStep},
+		{"unowned", nil, 9000 + models.BoardPositionStep},
+	} {
+		t.Run("create/"+tc.name, func(t *testing.T) {
+			before := len(s.Statements())
+			body := map[string]interface{}{"title": "Renewal", "customer_id": customer.ID}
+			if tc.owner != nil {
+				body["owner_id"] = *tc.owner
+			}
+			rec := s.do(t, manager, http.MethodPost, "/admin/deals", body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var created models.Deal
+			decode(t, rec, &created)
+			if got := position(created.ID); got != tc.want {
+				t.Errorf("board_position = %v, want %v", got, tc.want)
+			}
+
+			// The column is locked before its bottom is read
+			locked := false
+			for _, statement := range s.Statements()[before:] {
+				if strings.Contains(statement, "pg_advisory_xact_lock") {
+					locked = true
+				}
+				if strings.Contains(statement, "MAX(board_position)") && !locked {
+					t.Errorf("bottom read before the lock: %s", statement)
+				}
+			}
+			if !locked {
+				t.Error("no lock taken")
+			}
+		})
+	}
+
+	move := func(id uint, body map[string]interface{}) *httptest.ResponseRecorder {
+		return s.do(t, manager, http.MethodPatch, fmt.Sprintf("/admin/deals/%d/position", id), body)
+	}
+
+	t.Run("move to the bottom of the owner's column", func(t *testing.T) {
+		rec := move(twos.ID, map[string]interface{}{"stage": "proposal"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		if got, want := position(twos.ID), 7000+models.BoardPositionStep; got != want {
+			t.Errorf("board_position = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("move to the bottom of the global column", func(t *testing.T) {
+		rec := move(unowned.ID, map[string]interface{}{"stage": "proposal"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		if got, want := position(unowned.ID), models.BoardPositionStep; got != want {
+			t.Errorf("board_position = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("neighbor on another board", func(t *testing.T) {
+		rec := move(threes.ID, map[string]interface{}{"stage": "proposal", "prev_id": twos.ID})
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "INVALID_NEIGHBOR") {
+			t.Errorf("status = %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
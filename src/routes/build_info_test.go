@@ -0,0 +1,87 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+)
+
+// Build details linked into the test binary TestBuildInfoLdflags builds
+const (
+	linkedVersion   = "9.8.7-test"
+	linkedCommit    = "0123456789abcdef0123456789abcdef01234567"
+	linkedBuildTime = "2026-01-02T03:04:05Z"
+)
+
+// TestBuildInfoLdflags rebuilds this test with the build details set by
+// -ldflags -X, as the dockerfile does, and checks that they reach every
+// place reporting the build
+func TestBuildInfoLdflags(t *testing.T) {
+	if os.Getenv("CRM_TEST_LINKED_BUILD") == "" {
+		if testing.Short() {
+			t.Skip("rebuilds the test binary")
+		}
+		const pkg = "github.com/SalehAlobaylan/CRM-Service/src/buildinfo"
+		ldflags := fmt.Sprintf("-X %s.Version=%s -X %s.Commit=%s -X %s.BuildTime=%s", pkg, linkedVersion, pkg, linkedCommit, pkg, linkedBuildTime)
+		cmd := exec.Command("go", "test", "-count=1", "-run", "^TestBuildInfoLdflags$", "-ldflags", ldflags, ".")
+		cmd.Env = append(os.Environ(), "CRM_TEST_LINKED_BUILD=1")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v\n%s", err, output)
+		}
+		return
+	}
+
+	s := newServer(t)
+	rec := s.do(t, admin, http.MethodGet, "/admin/meta/build", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var build struct {
+		Version      string `json:"version"`
+		Commit       string `json:"commit"`
+		BuildTime    string `json:"build_time"`
+		GoVersion    string `json:"go_version"`
+		Dependencies []struct {
+			Path    string `json:"path"`
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	decode(t, rec, &build)
+	if build.Version != linkedVersion || build.Commit != linkedCommit || build.BuildTime != linkedBuildTime || build.GoVersion == "" {
+		t.Errorf("/admin/meta/build = %+v", build)
+	}
+	if rec.Header().Get(middleware.HeaderVersion) != linkedVersion {
+		t.Errorf("%s = %q", middleware.HeaderVersion, rec.Header().Get(middleware.HeaderVersion))
+	}
+	var gorm string
+	for _, dep := range build.Dependencies {
+		if dep.Path == "gorm.io/gorm" {
+			gorm = dep.Version
+		}
+	}
+	if !strings.HasPrefix(gorm, "v1.") {
+		t.Errorf("gorm.io/gorm = %q, want its module version", gorm)
+	}
+
+	rec = s.do(t, caller{}, http.MethodGet, "/health", nil)
+	var health struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		BuildTime string `json:"build_time"`
+	}
+	decode(t, rec, &health)
+	if health.Version != linkedVersion || health.Commit != linkedCommit || health.BuildTime != linkedBuildTime {
+		t.Errorf("/health = %+v", health)
+	}
+
+	rec = s.do(t, caller{}, http.MethodGet, "/metrics", nil)
+	gauge := fmt.Sprintf(`crm_build_info{commit="%s",go_version=`, linkedCommit)
+	if !strings.Contains(rec.Body.String(), gauge) || !strings.Contains(rec.Body.String(), `version="`+linkedVersion+`"} 1`) {
+		t.Errorf("/metrics has no %s...version=%q} 1 series", gauge, linkedVersion)
+	}
+}
@@ -0,0 +1,54 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// TestClaimCustomer checks that of concurrent claims of an unassigned
+// customer one wins and the others get 409 naming the winner
+func TestClaimCustomer(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	path := fmt.Sprintf("/admin/customers/%d/claim", customer.ID)
+
+	callers := []caller{agent, manager, {ID: 5, Role: agent.Role}}
+	codes := make([]int, len(callers))
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, as := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			codes[i] = s.do(t, as, http.MethodPost, path, nil).Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var winner uint
+	for i, code := range codes {
+		if code == http.StatusOK {
+			if winner != 0 {
+				t.Fatalf("statuses = %v, want one claim to win", codes)
+			}
+			winner = callers[i].ID
+		}
+	}
+	if winner == 0 {
+		t.Fatalf("statuses = %v, want one claim to win", codes)
+	}
+
+	rec := s.do(t, agent, http.MethodPost, path, nil)
+	var refused struct {
+		Code       string
+		AssignedTo uint `json:"assigned_to"`
+	}
+	decode(t, rec, &refused)
+	if rec.Code != http.StatusConflict || refused.Code != "ALREADY_CLAIMED" || refused.AssignedTo != winner {
+		t.Errorf("claim of a claimed customer: status = %d: %s, want 409 naming %d", rec.Code, rec.Body, winner)
+	}
+}
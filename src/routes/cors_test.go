@@ -0,0 +1,147 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+)
+
+const (
+	consoleOrigin = "https://console.crm.sa"
+	siteOrigin    = "https://www.nakheel.sa"
+)
+
+// preflight sends a CORS preflight for a POST from origin
+func (s *server) preflight(t *testing.T, path, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+	return s.serve(t, caller{}, req)
+}
+
+// TestCORSPerRouteGroup answers preflights for /admin from the console
+// origins only, with credentials, and for /public from the public origins,
+// without them
+func TestCORSPerRouteGroup(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) {
+		cfg.CORSAllowedOrigins = []string{consoleOrigin}
+		cfg.CORSAllowCredentials = true
+		cfg.CORSPublicAllowedOrigins = []string{"https://*.nakheel.sa"}
+	})
+
+	for _, tc := range []struct {
+		name, path, origin string
+		want               int
+		credentials        bool
+	}{
+		{"admin from the console", "/admin/customers", consoleOrigin, http.StatusNoContent, true},
+		{"admin from a public site", "/admin/customers", siteOrigin, http.StatusForbidden, false},
+		{"admin variant from the console", "/Admin/Customers/", consoleOrigin, http.StatusNoContent, true},
+		{"calendar feed from the console", "/admin/me/activities.ics", consoleOrigin, http.StatusNoContent, true},
+		{"public from a public site", "/public/email/click/token", siteOrigin, http.StatusNoContent, false},
+		{"public from the console", "/public/email/click/token", consoleOrigin, http.StatusForbidden, false},
+		{"public from a lookalike", "/public/email/click/token", "https://www.nakheel.sa.evil.com", http.StatusForbidden, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.preflight(t, tc.path, tc.origin)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			header := rec.Header()
+			if tc.want != http.StatusNoContent {
+				if origin := header.Get("Access-Control-Allow-Origin"); origin != "" {
+					t.Errorf("Allow-Origin = %q on a refused preflight", origin)
+				}
+				return
+			}
+			if origin := header.Get("Access-Control-Allow-Origin"); origin != tc.origin {
+				t.Errorf("Allow-Origin = %q, want %q", origin, tc.origin)
+			}
+			if credentials := header.Get("Access-Control-Allow-Credentials") == "true"; credentials != tc.credentials {
+				t.Errorf("Allow-Credentials = %v, want %v", credentials, tc.credentials)
+			}
+			if allowed := header.Get("Access-Control-Allow-Headers"); !strings.Contains(allowed, "Authorization") {
+				t.Errorf("Allow-Headers = %q", allowed)
+			}
+		})
+	}
+
+	// Preflights never reach authentication, even without an Origin
+	for _, path := range []string{"/admin/customers", "/public/email/click/token"} {
+		if rec := s.preflight(t, path, ""); rec.Code != http.StatusNoContent {
+			t.Errorf("%s without an origin: status = %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+}
+
+// TestCORSPublicDefault opens /public to every origin by default, without
+// credentials, while /admin stays limited to its origins
+func TestCORSPublicDefault(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) {
+		cfg.CORSAllowedOrigins = []string{consoleOrigin}
+		cfg.CORSAllowCredentials = true
+	})
+
+	rec := s.preflight(t, "/public/email/click/token", "https://anywhere.example")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("public: status = %d, headers = %v", rec.Code, rec.Header())
+	}
+	if rec := s.preflight(t, "/admin/customers", "https://anywhere.example"); rec.Code != http.StatusForbidden {
+		t.Errorf("admin: status = %d", rec.Code)
+	}
+}
+
+// TestCORSExposedHeaders lets browsers read the pagination, rate limit and
+// ETag headers of actual responses
+func TestCORSExposedHeaders(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) {
+		cfg.CORSAllowedOrigins = []string{consoleOrigin}
+		cfg.CORSAllowCredentials = true
+		cfg.CORSPublicAllowedOrigins = []string{siteOrigin}
+		cfg.PublicRateLimitPerMinute = 1
+	})
+	s.Factory.Customer(t)
+
+	exposed := func(rec *httptest.ResponseRecorder) []string {
+		var names []string
+		for _, name := range strings.Split(rec.Header().Get("Access-Control-Expose-Headers"), ",") {
+			names = append(names, http.CanonicalHeaderKey(strings.TrimSpace(name)))
+		}
+		return names
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/customers", nil)
+	req.Header.Set("Origin", consoleOrigin)
+	rec := s.serve(t, admin, req)
+	if rec.Code != http.StatusOK || rec.Header().Get(middleware.HeaderTotalCount) != "1" {
+		t.Fatalf("list: status = %d, %s = %q", rec.Code, middleware.HeaderTotalCount, rec.Header().Get(middleware.HeaderTotalCount))
+	}
+	for _, name := range []string{middleware.HeaderTotalCount, middleware.HeaderNextCursor, "Retry-After", "Etag"} {
+		if !slices.Contains(exposed(rec), name) {
+			t.Errorf("admin exposes %v, missing %s", exposed(rec), name)
+		}
+	}
+
+	// Rate-limited public responses carry a readable Retry-After
+	var limited *httptest.ResponseRecorder
+	for i := 0; i < 3 && (limited == nil || limited.Code != http.StatusTooManyRequests); i++ {
+		req := httptest.NewRequest(http.MethodGet, "/public/email/open/token", nil)
+		req.Header.Set("Origin", siteOrigin)
+		limited = s.serve(t, caller{}, req)
+	}
+	if limited.Code != http.StatusTooManyRequests || limited.Header().Get("Retry-After") == "" {
+		t.Fatalf("public: status = %d, Retry-After = %q", limited.Code, limited.Header().Get("Retry-After"))
+	}
+	if limited.Header().Get("Access-Control-Allow-Origin") != siteOrigin || !slices.Contains(exposed(limited), "Retry-After") {
+		t.Errorf("public: headers = %v", limited.Header())
+	}
+}
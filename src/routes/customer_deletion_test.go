@@ -0,0 +1,123 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/deletion"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// customerGraph creates a customer with a contact, a deal, two activities
+// and a note: five dependents
+func customerGraph(t *testing.T, s *server) models.Customer {
+	t.Helper()
+	customer := s.Factory.Customer(t)
+	s.Factory.Contact(t, customer)
+	s.Factory.Deal(t, customer)
+	s.Factory.Activity(t, customer)
+	s.Factory.Activity(t, customer)
+	s.Factory.Note(t, customer)
+	return customer
+}
+
+func syncLimit(limit int) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.CustomerDeleteSyncLimit = limit
+	}
+}
+
+func TestDeleteCustomerSync(t *testing.T) {
+	s := newServer(t, syncLimit(5))
+	customerGraph(t, s)
+
+	rec := s.do(t, admin, http.MethodDelete, "/admin/customers/1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Deleted models.CustomerDeletionSummary `json:"deleted"`
+	}
+	decode(t, rec, &body)
+	if body.Deleted != (models.CustomerDeletionSummary{Contacts: 1, Deals: 1, Activities: 2, Notes: 1}) {
+		t.Errorf("deleted = %+v", body.Deleted)
+	}
+
+	for _, as := range []caller{admin, agent} {
+		if rec := s.do(t, as, http.MethodGet, "/admin/customers/1", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("GET as %s: status = %d: %s", as.Role, rec.Code, rec.Body)
+		}
+	}
+	if n := s.Count("customer_deletions"); n != 0 {
+		t.Errorf("%d background deletions", n)
+	}
+	if n := s.Count("audit_logs"); n != 1 {
+		t.Errorf("%d audit entries, want the delete", n)
+	}
+}
+
+func TestDeleteCustomerInBackground(t *testing.T) {
+	s := newServer(t, syncLimit(4))
+	customerGraph(t, s)
+	s.Factory.Customer(t)
+
+	rec := s.do(t, admin, http.MethodDelete, "/admin/customers/1", nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// Until the job runs the customer is gone from lists and from everyone
+	// but admins, who see the deletion's progress
+	rec = s.do(t, agent, http.MethodGet, "/admin/customers", nil)
+	var page struct {
+		Data []models.Customer `json:"data"`
+	}
+	decode(t, rec, &page)
+	if len(page.Data) != 1 || page.Data[0].ID != 2 {
+		t.Errorf("list = %+v, want only the other customer", page.Data)
+	}
+	for _, as := range []caller{manager, agent} {
+		if rec := s.do(t, as, http.MethodGet, "/admin/customers/1", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("GET as %s: status = %d: %s", as.Role, rec.Code, rec.Body)
+		}
+	}
+	rec = s.do(t, admin, http.MethodGet, "/admin/customers/1", nil)
+	var status struct {
+		Customer models.Customer         `json:"customer"`
+		Deletion models.CustomerDeletion `json:"deletion"`
+	}
+	decode(t, rec, &status)
+	if rec.Code != http.StatusOK || status.Customer.ID != 1 || status.Deletion.Status != models.CustomerDeletionPending || status.Deletion.Expected.Total() != 5 {
+		t.Errorf("GET as admin: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, admin, http.MethodDelete, "/admin/customers/1", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d: %s", rec.Code, rec.Body)
+	}
+	var activities int64
+	if err := s.DB.Model(&models.Activity{}).Count(&activities).Error; err != nil || activities != 2 {
+		t.Errorf("%d live activities before the job, %v", activities, err)
+	}
+	if n := s.Count("audit_logs"); n != 0 {
+		t.Errorf("%d audit entries before the job", n)
+	}
+
+	// The job finishes the deletion and records it
+	if completed, err := deletion.NewRunner(s.DB, 4).Run(context.Background()); err != nil || completed != 1 {
+		t.Fatalf("run: %d, %v", completed, err)
+	}
+	if rec := s.do(t, admin, http.MethodGet, "/admin/customers/1", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GET as admin after the job: status = %d: %s", rec.Code, rec.Body)
+	}
+	if err := s.DB.Model(&models.Activity{}).Count(&activities).Error; err != nil || activities != 0 {
+		t.Errorf("%d live activities after the job, %v", activities, err)
+	}
+	var entry models.AuditLog
+	if err := s.DB.First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if entry.ResourceID != 1 || entry.Action != models.AuditActionDelete || entry.UserID != admin.ID {
+		t.Errorf("audit entry = %+v", entry)
+	}
+}
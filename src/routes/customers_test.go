@@ -0,0 +1,132 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+func TestCreateCustomer(t *testing.T) {
+	s := newServer(t)
+	s.Factory.Customer(t, func(c *models.Customer) { c.Email = "taken@example.com" })
+
+	for _, tc := range []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"created", map[string]interface{}{"name": "Huda", "email": "huda@nakheel.sa", "company": "Nakheel"}, http.StatusCreated},
+		{"missing email", map[string]interface{}{"name": "Huda"}, http.StatusBadRequest},
+		{"invalid email", map[string]interface{}{"name": "Huda", "email": "huda"}, http.StatusBadRequest},
+		{"duplicate email", map[string]interface{}{"name": "Huda", "email": "taken@example.com"}, http.StatusConflict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, admin, http.MethodPost, "/admin/customers", tc.body)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+
+	var created models.Customer
+	if err := s.DB.Where("email = ?", "huda@nakheel.sa").First(&created).Error; err != nil {
+		t.Fatal(err)
+	}
+	if created.Status != models.CustomerStatusLead || created.EmailDomain != "nakheel.sa" {
+		t.Errorf("created = %+v", created)
+	}
+}
+
+func TestGetCustomer(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	s.Factory.Contact(t, customer)
+	s.Factory.Deal(t, customer)
+	s.Factory.Deal(t, customer, func(d *models.Deal) { d.Stage = models.DealStageClosedWon })
+
+	var got struct {
+		ID             uint   `json:"id"`
+		Name           string `json:"name"`
+		ContactsCount  int    `json:"contacts_count"`
+		OpenDealsCount int    `json:"open_deals_count"`
+	}
+	rec := s.do(t, agent, http.MethodGet, "/admin/customers/1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	decode(t, rec, &got)
+	if got.ID != customer.ID || got.Name != customer.Name || got.ContactsCount != 1 || got.OpenDealsCount != 1 {
+		t.Errorf("customer = %+v", got)
+	}
+
+	if rec := s.do(t, agent, http.MethodGet, "/admin/customers/99", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing customer: status = %d", rec.Code)
+	}
+}
+
+func TestListCustomersFilters(t *testing.T) {
+	s := newServer(t)
+	s.Factory.Customer(t)
+	s.Factory.Customer(t, func(c *models.Customer) { c.Status = models.CustomerStatusActive })
+	s.Factory.Customer(t, func(c *models.Customer) {
+		c.Status = models.CustomerStatusActive
+		c.Email = "sara@nakheel.sa"
+	})
+
+	for _, tc := range []struct {
+		query string
+		want  []uint
+	}{
+		{"", []uint{3, 2, 1}},
+		{"?status=active", []uint{3, 2}},
+		{"?domain=nakheel.sa", []uint{3}},
+		{"?status=inactive", []uint{}},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			var page struct {
+				Data []models.Customer `json:"data"`
+			}
+			rec := s.do(t, manager, http.MethodGet, "/admin/customers"+tc.query, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			decode(t, rec, &page)
+			if len(page.Data) != len(tc.want) {
+				t.Fatalf("got %d customers, want %v", len(page.Data), tc.want)
+			}
+			for i, customer := range page.Data {
+				if customer.ID != tc.want[i] {
+					t.Errorf("customer %d = %d, want %d", i, customer.ID, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateAndDeleteCustomer(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+
+	rec := s.do(t, manager, http.MethodPut, "/admin/customers/1", map[string]interface{}{"company": "Nakheel"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+	var updated models.Customer
+	if err := s.DB.First(&updated, customer.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if updated.Company != "Nakheel" || updated.Name != customer.Name {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if rec := s.do(t, agent, http.MethodDelete, "/admin/customers/1", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("agent delete: status = %d", rec.Code)
+	}
+	if rec := s.do(t, admin, http.MethodDelete, "/admin/customers/1", nil); rec.Code/100 != 2 {
+		t.Fatalf("delete: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, admin, http.MethodGet, "/admin/customers/1", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("deleted customer: status = %d", rec.Code)
+	}
+}
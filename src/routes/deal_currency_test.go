@@ -0,0 +1,160 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestDealCurrencyChange covers converting a deal's amount on a currency
+// change, and the changes refused because they would alter its value
+func TestDealCurrencyChange(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	for _, rate := range []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "SAR", Rate: 3.75, EffectiveDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{BaseCurrency: "USD", QuoteCurrency: "SAR", Rate: 3.7512, EffectiveDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: 1.08, EffectiveDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		if err := s.DB.Create(&rate).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	deal := func(stage models.DealStage, amount float64) models.Deal {
+		return s.Factory.Deal(t, customer, func(d *models.Deal) { d.Stage, d.Amount, d.Currency = stage, amount, "USD" })
+	}
+	stored := func(id uint) models.Deal {
+		t.Helper()
+		var d models.Deal
+		if err := s.DB.First(&d, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	for _, tc := range []struct {
+		name       string
+		stage      models.DealStage
+		amount     float64
+		query      string
+		currency   string
+		want       int
+		code       string
+		wantAmount float64
+	}{
+		{"open deal relabels", models.DealStageProspecting, 1000, "", "SAR", http.StatusOK, "", 1000},
+		{"same currency in another case", models.DealStageProposal, 1000, "", "usd", http.StatusOK, "", 1000},
+		{"past qualification", models.DealStageProposal, 1000, "", "SAR", http.StatusConflict, "CURRENCY_CHANGE_FORBIDDEN", 1000},
+		{"past qualification converted", models.DealStageProposal, 1000, "?convert=true", "SAR", http.StatusOK, "", 3751.2},
+		{"converted at the effective date", models.DealStageProposal, 1000.01, "?convert=true&effective_date=2024-06-30", "SAR", http.StatusOK, "", 3750.04},
+		{"converted by the inverse rate", models.DealStageNegotiation, 1000, "?convert=true", "EUR", http.StatusOK, "", 925.93},
+		{"rounded half away from zero", models.DealStageProspecting, 1.02, "?convert=true&effective_date=2024-06-30", "SAR", http.StatusOK, "", 3.83},
+		{"no rate", models.DealStageProposal, 1000, "?convert=true", "GBP", http.StatusBadRequest, "EXCHANGE_RATE_NOT_FOUND", 1000},
+		{"no rate yet on the date", models.DealStageProposal, 1000, "?convert=true&effective_date=2023-12-31", "SAR", http.StatusBadRequest, "EXCHANGE_RATE_NOT_FOUND", 1000},
+		{"malformed effective date", models.DealStageProposal, 1000, "?convert=true&effective_date=30/06/2024", "SAR", http.StatusBadRequest, "INVALID_DATE", 1000},
+		{"closed won", models.DealStageClosedWon, 1000, "", "SAR", http.StatusConflict, "CURRENCY_IMMUTABLE", 1000},
+		{"closed lost converted", models.DealStageClosedLost, 1000, "?convert=true", "SAR", http.StatusConflict, "CURRENCY_IMMUTABLE", 1000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := deal(tc.stage, tc.amount)
+			rec := s.do(t, admin, http.MethodPut, fmt.Sprintf("/admin/deals/%d%s", d.ID, tc.query), map[string]interface{}{"currency": tc.currency})
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+			if tc.code != "" && !strings.Contains(rec.Body.String(), `"code":"`+tc.code+`"`) {
+				t.Errorf("body = %s, want code %s", rec.Body, tc.code)
+			}
+			got := stored(d.ID)
+			if got.Amount != tc.wantAmount {
+				t.Errorf("amount = %v, want %v", got.Amount, tc.wantAmount)
+			}
+			wantCurrency := "USD"
+			if tc.want == http.StatusOK {
+				wantCurrency = strings.ToUpper(tc.currency)
+			}
+			if got.Currency != wantCurrency {
+				t.Errorf("currency = %q, want %q", got.Currency, wantCurrency)
+			}
+
+			// Conversions are recorded with their rate and both amounts
+			converted := false
+			for _, row := range s.Rows("audit_logs") {
+				if row["resource_id"] == int64(d.ID) && strings.Contains(fmt.Sprint(row["new_values"]), "currency_conversion") {
+					converted = true
+				}
+			}
+			if want := tc.want == http.StatusOK && tc.query != ""; converted != want {
+				t.Errorf("conversion audited = %v, want %v", converted, want)
+			}
+		})
+	}
+
+	// The guards apply to the stage a deal moves to in the same request, and
+	// a converted amount cannot be overridden by one given alongside
+	for _, tc := range []struct {
+		name         string
+		stage        models.DealStage
+		query        string
+		body         map[string]interface{}
+		want         int
+		code         string
+		wantStage    models.DealStage
+		wantAmount   float64
+		wantCurrency string
+	}{
+		{"moved past qualification", models.DealStageProspecting, "", map[string]interface{}{"stage": "proposal", "currency": "SAR"},
+			http.StatusConflict, "CURRENCY_CHANGE_FORBIDDEN", models.DealStageProspecting, 1000, "USD"},
+		{"moved past qualification converted", models.DealStageProspecting, "?convert=true", map[string]interface{}{"stage": "proposal", "currency": "SAR"},
+			http.StatusOK, "", models.DealStageProposal, 3751.2, "SAR"},
+		{"moved back to qualification", models.DealStageProposal, "", map[string]interface{}{"stage": "qualification", "currency": "SAR"},
+			http.StatusOK, "", models.DealStageQualification, 1000, "SAR"},
+		{"closed in the same request", models.DealStageNegotiation, "?convert=true", map[string]interface{}{"stage": "closed_won", "currency": "SAR"},
+			http.StatusConflict, "CURRENCY_IMMUTABLE", models.DealStageNegotiation, 1000, "USD"},
+		{"amount with conversion", models.DealStageProposal, "?convert=true", map[string]interface{}{"currency": "SAR", "amount": 5000},
+			http.StatusUnprocessableEntity, "AMOUNT_WITH_CONVERSION", models.DealStageProposal, 1000, "USD"},
+		{"amount with relabelling", models.DealStageProspecting, "", map[string]interface{}{"currency": "SAR", "amount": 5000},
+			http.StatusOK, "", models.DealStageProspecting, 5000, "SAR"},
+	} {
+		t.Run("combined/"+tc.name, func(t *testing.T) {
+			d := deal(tc.stage, 1000)
+			rec := s.do(t, admin, http.MethodPut, fmt.Sprintf("/admin/deals/%d%s", d.ID, tc.query), tc.body)
+			if rec.Code != tc.want || tc.code != "" && !strings.Contains(rec.Body.String(), `"code":"`+tc.code+`"`) {
+				t.Fatalf("status = %d, want %d %s: %s", rec.Code, tc.want, tc.code, rec.Body)
+			}
+			if got := stored(d.ID); got.Stage != tc.wantStage || got.Amount != tc.wantAmount || got.Currency != tc.wantCurrency {
+				t.Errorf("deal = %s %v %s, want %s %v %s", got.Stage, got.Amount, got.Currency, tc.wantStage, tc.wantAmount, tc.wantCurrency)
+			}
+		})
+	}
+
+	// Merge patches never convert, so they follow the forbidden paths only,
+	// judged by the stage the patch leaves the deal in
+	for _, tc := range []struct {
+		stage models.DealStage
+		patch string
+		want  int
+		code  string
+	}{
+		{models.DealStageQualification, `{"currency":"sar"}`, http.StatusOK, ""},
+		{models.DealStageProposal, `{"currency":"sar"}`, http.StatusConflict, "CURRENCY_CHANGE_FORBIDDEN"},
+		{models.DealStageClosedWon, `{"currency":"sar"}`, http.StatusConflict, "CURRENCY_IMMUTABLE"},
+		{models.DealStageQualification, `{"currency":"sar","stage":"negotiation"}`, http.StatusConflict, "CURRENCY_CHANGE_FORBIDDEN"},
+		{models.DealStageNegotiation, `{"currency":"sar","stage":"closed_won"}`, http.StatusConflict, "CURRENCY_IMMUTABLE"},
+		{models.DealStageProposal, `{"currency":"sar","stage":"prospecting"}`, http.StatusOK, ""},
+	} {
+		t.Run("merge patch/"+string(tc.stage)+tc.patch, func(t *testing.T) {
+			d := deal(tc.stage, 1000)
+			rec := s.patch(t, fmt.Sprintf("/admin/deals/%d", d.ID), "application/merge-patch+json", tc.patch)
+			if rec.Code != tc.want || tc.code != "" && !strings.Contains(rec.Body.String(), `"code":"`+tc.code+`"`) {
+				t.Fatalf("status = %d, want %d %s: %s", rec.Code, tc.want, tc.code, rec.Body)
+			}
+			if got := stored(d.ID); got.Amount != 1000 {
+				t.Errorf("amount = %v", got.Amount)
+			}
+		})
+	}
+}
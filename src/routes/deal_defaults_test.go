@@ -0,0 +1,114 @@
+package routes_test
+
+import (
+	"maps"
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestDealDefaultsPrecedence creates deals with apply_defaults=true and
+// checks where each field's value comes from: the request when it sets the
+// field, then the customer's history, then the settings
+func TestDealDefaultsPrecedence(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) {
+		cfg.DealDefaultCurrency, cfg.DealDefaultStage, cfg.DealDefaultProbability = "EUR", "qualification", 25
+	})
+
+	fresh := s.Factory.Customer(t)
+	assigned := s.Factory.Customer(t, func(c *models.Customer) { c.AssignedTo = &agent.ID })
+	for _, deal := range []struct {
+		stage  models.DealStage
+		amount float64
+	}{
+		{models.DealStageClosedWon, 2000},
+		{models.DealStageClosedWon, 4000},
+		{models.DealStageClosedLost, 3000},
+	} {
+		s.Factory.Deal(t, assigned, func(d *models.Deal) { d.Stage, d.Amount, d.Currency = deal.stage, deal.amount, "SAR" })
+	}
+	unassigned := s.Factory.Customer(t)
+	s.Factory.Deal(t, unassigned, func(d *models.Deal) { d.Amount, d.OwnerID = 1200, &manager.ID })
+
+	type deal struct {
+		Currency    string
+		Amount      float64
+		Probability int
+		Stage       models.DealStage
+		OwnerID     *uint `json:"owner_id"`
+	}
+	for _, tc := range []struct {
+		name      string
+		body      map[string]interface{}
+		want      deal
+		defaulted map[string]string
+	}{
+		{
+			name: "settings without history",
+			body: map[string]interface{}{"customer_id": fresh.ID},
+			want: deal{Currency: "EUR", Probability: 25, Stage: models.DealStageQualification},
+			defaulted: map[string]string{
+				"title": "settings", "currency": "settings", "probability": "settings", "stage": "settings",
+			},
+		},
+		{
+			name: "history over settings",
+			body: map[string]interface{}{"customer_id": assigned.ID},
+			want: deal{Currency: "SAR", Amount: 3000, Probability: 67, Stage: models.DealStageQualification, OwnerID: &agent.ID},
+			defaulted: map[string]string{
+				"title": "settings", "currency": "history", "amount": "history", "probability": "history",
+				"stage": "settings", "owner_id": "customer",
+			},
+		},
+		{
+			name: "owner from history, too few closed deals for a win rate",
+			body: map[string]interface{}{"customer_id": unassigned.ID},
+			want: deal{Currency: "USD", Amount: 1200, Probability: 25, Stage: models.DealStageQualification, OwnerID: &manager.ID},
+			defaulted: map[string]string{
+				"title": "settings", "currency": "history", "amount": "history", "probability": "settings",
+				"stage": "settings", "owner_id": "history",
+			},
+		},
+		{
+			name: "request over history",
+			body: map[string]interface{}{
+				"customer_id": assigned.ID, "title": "Renewal", "currency": "GBP", "amount": 100,
+				"probability": 90, "stage": "proposal", "owner_id": manager.ID,
+			},
+			want:      deal{Currency: "GBP", Amount: 100, Probability: 90, Stage: models.DealStageProposal, OwnerID: &manager.ID},
+			defaulted: map[string]string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, admin, http.MethodPost, "/admin/deals?apply_defaults=true", tc.body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var created struct {
+				deal
+				Meta struct {
+					Defaulted map[string]string
+				}
+			}
+			decode(t, rec, &created)
+			got := created.deal
+			if got.Currency != tc.want.Currency || got.Amount != tc.want.Amount || got.Probability != tc.want.Probability ||
+				got.Stage != tc.want.Stage || !sameOwner(got.OwnerID, tc.want.OwnerID) {
+				t.Errorf("deal = %+v, want %+v", got, tc.want)
+			}
+			if !maps.Equal(created.Meta.Defaulted, tc.defaulted) {
+				t.Errorf("defaulted = %v, want %v", created.Meta.Defaulted, tc.defaulted)
+			}
+		})
+	}
+}
+
+// sameOwner reports whether two optional owner IDs are equal
+func sameOwner(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
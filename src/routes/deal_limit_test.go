@@ -0,0 +1,105 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// limitOpenDeals sets the open deal limit for the test
+func limitOpenDeals(t *testing.T, limit int, mode models.OpenDealLimitMode) {
+	t.Helper()
+	previous, policy := models.MaxOpenDealsPerCustomer, models.OpenDealLimitPolicy
+	models.MaxOpenDealsPerCustomer, models.OpenDealLimitPolicy = limit, mode
+	t.Cleanup(func() { models.MaxOpenDealsPerCustomer, models.OpenDealLimitPolicy = previous, policy })
+}
+
+// TestOpenDealLimit checks that a new open deal over the limit is refused
+// with the customer's open deals, or made with a warning in warn mode
+func TestOpenDealLimit(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	first := s.Factory.Deal(t, customer)
+	second := s.Factory.Deal(t, customer)
+	s.Factory.Deal(t, customer, func(d *models.Deal) { d.Stage = models.DealStageClosedWon })
+	body := map[string]interface{}{"title": "Expansion", "customer_id": customer.ID}
+
+	t.Run("enforce", func(t *testing.T) {
+		limitOpenDeals(t, 2, models.OpenDealLimitEnforce)
+		rec := s.do(t, manager, http.MethodPost, "/admin/deals", body)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var refused struct {
+			Code      string
+			Limit     int
+			OpenDeals []struct{ ID uint } `json:"open_deals"`
+		}
+		decode(t, rec, &refused)
+		if refused.Code != "OPEN_DEAL_LIMIT" || refused.Limit != 2 || len(refused.OpenDeals) != 2 ||
+			refused.OpenDeals[0].ID != first.ID || refused.OpenDeals[1].ID != second.ID {
+			t.Errorf("response = %+v", refused)
+		}
+		if n := s.Count("deals"); n != 3 {
+			t.Errorf("%d deals, want the refused one not created", n)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		limitOpenDeals(t, 2, models.OpenDealLimitWarn)
+		rec := s.do(t, manager, http.MethodPost, "/admin/deals", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		if warning := rec.Header().Get("Warning"); !strings.HasPrefix(warning, "299 - ") || !strings.Contains(warning, "the limit is 2") {
+			t.Errorf("Warning = %q", warning)
+		}
+	})
+}
+
+// TestOpenDealLimitConcurrent checks that two concurrent creates for a
+// customer one deal under the limit cannot both get under it
+func TestOpenDealLimitConcurrent(t *testing.T) {
+	s := newServer(t)
+	limitOpenDeals(t, 2, models.OpenDealLimitEnforce)
+	customer := s.Factory.Customer(t)
+	s.Factory.Deal(t, customer)
+
+	const creates = 2
+	codes := make([]int, creates)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range creates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			codes[i] = s.do(t, manager, http.MethodPost, "/admin/deals", map[string]interface{}{"title": "Expansion", "customer_id": customer.ID}).Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	created, refused := 0, 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusUnprocessableEntity:
+			refused++
+		}
+	}
+	if created != 1 || refused != 1 {
+		t.Errorf("statuses = %v, want one created and one refused", codes)
+	}
+	var open int64
+	if err := s.DB.Model(&models.Deal{}).Scopes(models.OpenDeals).Where("customer_id = ?", customer.ID).Count(&open).Error; err != nil {
+		t.Fatal(err)
+	}
+	if open != 2 {
+		t.Errorf("%d open deals, want the limit", open)
+	}
+}
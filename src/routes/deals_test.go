@@ -0,0 +1,227 @@
+package routes_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/jobs"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+func TestCreateDeal(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+
+	for _, tc := range []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"created", map[string]interface{}{"title": "Renewal", "customer_id": customer.ID, "amount": 5000}, http.StatusCreated},
+		{"missing customer", map[string]interface{}{"title": "Renewal"}, http.StatusBadRequest},
+		{"unknown customer", map[string]interface{}{"title": "Renewal", "customer_id": 99}, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, manager, http.MethodPost, "/admin/deals", tc.body)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+
+	var deals []models.Deal
+	if err := s.DB.Find(&deals).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(deals) != 1 || deals[0].Title != "Renewal" || deals[0].Stage != models.DealStageProspecting || deals[0].Amount != 5000 {
+		t.Errorf("deals = %+v", deals)
+	}
+}
+
+func TestGetDeal(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	deal := s.Factory.Deal(t, customer)
+
+	var got models.Deal
+	rec := s.do(t, agent, http.MethodGet, "/admin/deals/1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	decode(t, rec, &got)
+	if got.ID != deal.ID || got.Title != deal.Title || got.Customer.Name != customer.Name {
+		t.Errorf("deal = %+v", got)
+	}
+	if rec := s.do(t, agent, http.MethodGet, "/admin/deals/99", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing deal: status = %d", rec.Code)
+	}
+}
+
+func TestListDealsFilters(t *testing.T) {
+	s := newServer(t)
+	first := s.Factory.Customer(t)
+	second := s.Factory.Customer(t)
+	s.Factory.Deal(t, first)
+	s.Factory.Deal(t, first, func(d *models.Deal) { d.Stage = models.DealStageProposal })
+	s.Factory.Deal(t, second, func(d *models.Deal) { d.Stage = models.DealStageProposal })
+
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"?stage=proposal", 2},
+		{"?customer_id=1", 2},
+		{"?customer_id=2&stage=prospecting", 0},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			var page struct {
+				Data []models.Deal `json:"data"`
+			}
+			rec := s.do(t, manager, http.MethodGet, "/admin/deals"+tc.query, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			decode(t, rec, &page)
+			if len(page.Data) != tc.want {
+				t.Errorf("got %d deals, want %d", len(page.Data), tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateDeal(t *testing.T) {
+	s := newServer(t)
+	deal := s.Factory.Deal(t, s.Factory.Customer(t))
+
+	rec := s.do(t, manager, http.MethodPut, "/admin/deals/1", map[string]interface{}{"amount": 7500, "probability": 40})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var updated models.Deal
+	if err := s.DB.First(&updated, deal.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if updated.Amount != 7500 || updated.Probability != 40 || updated.Title != deal.Title {
+		t.Errorf("updated = %+v", updated)
+	}
+}
+
+// TestStageBouncingKeepsOneNudge moves a deal with an overdue next step
+// back and forth between two stages, pushing the due date each time so the
+// nudge re-arms, and checks that its owner ends up with one open nudge
+func TestStageBouncingKeepsOneNudge(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	overdue := time.Now().Add(-time.Hour).Truncate(time.Second)
+	deal := s.Factory.Deal(t, customer, func(d *models.Deal) {
+		d.OwnerID, d.NextStep, d.NextStepDue = &agent.ID, "Send the proposal", &overdue
+	})
+	nudger := jobs.NewNextStepNudger(s.DB, 0, 0, nil)
+
+	path := fmt.Sprintf("/admin/deals/%d", deal.ID)
+	for i, stage := range []models.DealStage{
+		models.DealStageQualification, models.DealStageProspecting,
+		models.DealStageQualification, models.DealStageProspecting,
+	} {
+		due := overdue.Add(time.Duration(i+1) * time.Minute)
+		rec := s.do(t, admin, http.MethodPatch, path, map[string]interface{}{"stage": stage, "next_step_due": due})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("move %d to %s: status = %d: %s", i, stage, rec.Code, rec.Body)
+		}
+		if nudged, err := nudger.Run(context.Background()); err != nil || nudged != 1 {
+			t.Fatalf("move %d: nudged %d deals: %v", i, nudged, err)
+		}
+	}
+	if nudged, err := nudger.Run(context.Background()); err != nil || nudged != 0 {
+		t.Fatalf("nudged %d deals again without a change: %v", nudged, err)
+	}
+
+	var nudges []models.Activity
+	if err := s.DB.Where("deal_id = ?", deal.ID).Find(&nudges).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(nudges) != 1 {
+		t.Fatalf("%d nudges, want one", len(nudges))
+	}
+	if nudge := nudges[0]; nudge.Status != models.ActivityStatusScheduled || nudge.AssignedTo == nil || *nudge.AssignedTo != agent.ID {
+		t.Errorf("nudge = %+v, want an open task for the owner", nudge)
+	}
+}
+
+// TestDealStageRules checks the rules a stage change must pass: forward
+// moves only unless an admin reopens, a lost reason to close as lost, a
+// complete checklist to close as won and a reason to edit a closed deal's
+// amount
+func TestDealStageRules(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	deal := s.Factory.Deal(t, customer, func(d *models.Deal) { d.Stage = models.DealStageProposal })
+	path := fmt.Sprintf("/admin/deals/%d", deal.ID)
+
+	refused := func(as caller, body map[string]interface{}, code string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := s.do(t, as, http.MethodPatch, path, body)
+		if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"`+code+`"`) {
+			t.Fatalf("%v: status = %d, want 422 %s: %s", body, rec.Code, code, rec.Body)
+		}
+		return rec
+	}
+	moved := func(as caller, body map[string]interface{}) {
+		t.Helper()
+		if rec := s.do(t, as, http.MethodPatch, path, body); rec.Code != http.StatusOK {
+			t.Fatalf("%v: status = %d: %s", body, rec.Code, rec.Body)
+		}
+	}
+
+	rec := refused(manager, map[string]interface{}{"stage": models.DealStageQualification}, "INVALID_TRANSITION")
+	var transition struct {
+		From    models.DealStage
+		Allowed []models.DealStage
+	}
+	decode(t, rec, &transition)
+	if transition.From != models.DealStageProposal || slices.Contains(transition.Allowed, models.DealStageQualification) {
+		t.Errorf("transition = %+v", transition)
+	}
+	moved(admin, map[string]interface{}{"stage": models.DealStageQualification})
+
+	refused(manager, map[string]interface{}{"stage": models.DealStageClosedLost}, "MISSING_LOST_REASON")
+
+	rec = s.do(t, admin, http.MethodPost, "/admin/deal-checklist", map[string]interface{}{"key": "legal_review", "label": "Legal review"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("checklist item: status = %d: %s", rec.Code, rec.Body)
+	}
+	rec = refused(manager, map[string]interface{}{"stage": models.DealStageClosedWon}, "CHECKLIST_INCOMPLETE")
+	var incomplete struct {
+		Missing []struct{ Key string }
+	}
+	decode(t, rec, &incomplete)
+	if len(incomplete.Missing) != 1 || incomplete.Missing[0].Key != "legal_review" {
+		t.Errorf("missing = %+v", incomplete.Missing)
+	}
+	if rec := s.do(t, manager, http.MethodPut, path+"/checklist/legal_review", nil); rec.Code != http.StatusOK {
+		t.Fatalf("check item: status = %d: %s", rec.Code, rec.Body)
+	}
+	moved(manager, map[string]interface{}{"stage": models.DealStageClosedWon})
+
+	// The default policy asks a reason for the amount of closed deals
+	rec = s.do(t, manager, http.MethodPut, path, map[string]interface{}{"amount": 9000})
+	var reason struct {
+		Code   string
+		Fields []string
+	}
+	decode(t, rec, &reason)
+	if rec.Code != http.StatusUnprocessableEntity || reason.Code != "REASON_REQUIRED" || !slices.Equal(reason.Fields, []string{"amount"}) {
+		t.Errorf("amount without a reason: status = %d: %s", rec.Code, rec.Body)
+	}
+	rec = s.do(t, manager, http.MethodPut, path, map[string]interface{}{"amount": 9000, "change_reason": "Signed at a discount"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("amount with a reason: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,116 @@
+package routes_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestEmailClickTracking checks that issued click links record a click
+// once and redirect to the signed link, and that expired tracking links
+// are not recorded
+func TestEmailClickTracking(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	email := s.Factory.Activity(t, customer, func(a *models.Activity) { a.Type = models.ActivityTypeEmail })
+	issuePath := fmt.Sprintf("/admin/activities/%d/email-tracking", email.ID)
+	clicks := func() int {
+		n := 0
+		for _, row := range s.Rows("email_events") {
+			if row["type"] == string(models.EmailEventClick) {
+				n++
+			}
+		}
+		return n
+	}
+
+	rec := s.do(t, manager, http.MethodPost, issuePath, map[string]interface{}{"links": []string{"https://nakheel.sa/pricing?plan=pro"}})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("issue: status = %d: %s", rec.Code, rec.Body)
+	}
+	var issued models.EmailTrackingTokens
+	decode(t, rec, &issued)
+	if len(issued.Links) != 1 || issued.Links[0].URL != "https://nakheel.sa/pricing?plan=pro" {
+		t.Fatalf("links = %+v", issued.Links)
+	}
+	clickPath := "/public/email/click/" + issued.Links[0].Token
+	if !strings.HasSuffix(issued.Links[0].TrackingURL, clickPath) {
+		t.Errorf("tracking_url = %q", issued.Links[0].TrackingURL)
+	}
+
+	// Replays redirect again but count once
+	for i := 0; i < 2; i++ {
+		rec := s.do(t, caller{}, http.MethodGet, clickPath, nil)
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://nakheel.sa/pricing?plan=pro" {
+			t.Fatalf("click %d: status = %d, location %q", i, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+	if n := clicks(); n != 1 {
+		t.Errorf("%d clicks recorded, want 1", n)
+	}
+
+	t.Run("invalid links are refused", func(t *testing.T) {
+		rec := s.do(t, manager, http.MethodPost, issuePath, map[string]interface{}{"links": []string{"https://nakheel.sa", "javascript:alert(1)"}})
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"INVALID_LINK"`) {
+			t.Errorf("status = %d: %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("invalid click tokens do not redirect", func(t *testing.T) {
+		for _, token := range []string{"garbage", issued.OpenToken, issued.Links[0].Token + "x"} {
+			rec := s.do(t, caller{}, http.MethodGet, "/public/email/click/"+token, nil)
+			if rec.Code != http.StatusBadRequest || rec.Header().Get("Location") != "" {
+				t.Errorf("%s: status = %d, location %q", token, rec.Code, rec.Header().Get("Location"))
+			}
+		}
+	})
+
+	t.Run("expired links", func(t *testing.T) {
+		before := len(s.Rows("email_events"))
+		issuedAt := time.Now().AddDate(-2, 0, 0)
+		expired := func(purpose, link string) string {
+			return signTrackingToken(t, map[string]interface{}{"a": email.ID, "p": purpose, "n": "expired-" + purpose, "t": issuedAt.Unix(), "u": link})
+		}
+
+		rec := s.do(t, caller{}, http.MethodGet, "/public/email/click/"+expired("click", "https://nakheel.sa/old"), nil)
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://nakheel.sa/old" {
+			t.Errorf("click: status = %d, location %q", rec.Code, rec.Header().Get("Location"))
+		}
+		rec = s.do(t, caller{}, http.MethodGet, "/public/email/open/"+expired("open", ""), nil)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/gif" {
+			t.Errorf("open: status = %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		rec = s.do(t, caller{}, http.MethodGet, "/public/email/unsubscribe/"+expired("unsubscribe", ""), nil)
+		if rec.Code != http.StatusGone || !strings.Contains(rec.Body.String(), `"code":"TRACKING_TOKEN_EXPIRED"`) {
+			t.Errorf("unsubscribe: status = %d: %s", rec.Code, rec.Body)
+		}
+		if after := len(s.Rows("email_events")); after != before {
+			t.Errorf("%d events recorded for expired links", after-before)
+		}
+	})
+}
+
+// signTrackingToken signs a tracking token payload with the key the server
+// signs with, which falls back to the JWT secret
+func signTrackingToken(t *testing.T, payload map[string]interface{}) string {
+	t.Helper()
+	if payload["u"] == "" {
+		delete(payload, "u")
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(raw)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
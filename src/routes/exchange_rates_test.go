@@ -0,0 +1,105 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestExchangeRates manages rates through the API and checks that deal
+// conversions fail without one, use it once created and fail again once it
+// is deleted
+func TestExchangeRates(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	convert := func(t *testing.T, want int, wantAmount float64) {
+		t.Helper()
+		deal := s.Factory.Deal(t, customer, func(d *models.Deal) { d.Stage, d.Amount, d.Currency = models.DealStageProposal, 1000, "USD" })
+		rec := s.do(t, admin, http.MethodPut, fmt.Sprintf("/admin/deals/%d?convert=true&effective_date=2025-06-30", deal.ID),
+			map[string]interface{}{"currency": "SAR"})
+		if rec.Code != want {
+			t.Fatalf("convert: status = %d, want %d: %s", rec.Code, want, rec.Body)
+		}
+		if want != http.StatusOK && !strings.Contains(rec.Body.String(), `"code":"EXCHANGE_RATE_NOT_FOUND"`) {
+			t.Errorf("convert: body = %s", rec.Body)
+		}
+		var stored models.Deal
+		if err := s.DB.First(&stored, deal.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.Amount != wantAmount {
+			t.Errorf("amount = %v, want %v", stored.Amount, wantAmount)
+		}
+	}
+
+	// No rates are seeded
+	convert(t, http.StatusBadRequest, 1000)
+
+	body := map[string]interface{}{"base_currency": "usd", "quote_currency": "sar", "rate": 3.75, "effective_date": "2025-01-01"}
+	if rec := s.do(t, manager, http.MethodPost, "/admin/exchange-rates", body); rec.Code != http.StatusForbidden {
+		t.Errorf("manager: status = %d", rec.Code)
+	}
+	rec := s.do(t, admin, http.MethodPost, "/admin/exchange-rates", body)
+	var rate models.ExchangeRate
+	decode(t, rec, &rate)
+	if rec.Code != http.StatusCreated || rate.BaseCurrency != "USD" || rate.QuoteCurrency != "SAR" {
+		t.Fatalf("create: status = %d: %s", rec.Code, rec.Body)
+	}
+	convert(t, http.StatusOK, 3750)
+
+	for _, tc := range []struct {
+		name string
+		body map[string]interface{}
+		want int
+		code string
+	}{
+		{"same pair and date", map[string]interface{}{"base_currency": "USD", "quote_currency": "SAR", "rate": 3.76, "effective_date": "2025-01-01"},
+			http.StatusConflict, "EXCHANGE_RATE_EXISTS"},
+		{"one currency", map[string]interface{}{"base_currency": "USD", "quote_currency": "usd", "rate": 1, "effective_date": "2025-01-01"},
+			http.StatusBadRequest, "EXCHANGE_RATE_SAME_CURRENCY"},
+		{"zero rate", map[string]interface{}{"base_currency": "USD", "quote_currency": "EUR", "rate": 0, "effective_date": "2025-01-01"},
+			http.StatusBadRequest, "INVALID_REQUEST"},
+		{"malformed date", map[string]interface{}{"base_currency": "USD", "quote_currency": "EUR", "rate": 0.92, "effective_date": "01/01/2025"},
+			http.StatusBadRequest, "INVALID_REQUEST"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, admin, http.MethodPost, "/admin/exchange-rates", tc.body)
+			if rec.Code != tc.want || !strings.Contains(rec.Body.String(), `"code":"`+tc.code+`"`) {
+				t.Errorf("status = %d, want %d %s: %s", rec.Code, tc.want, tc.code, rec.Body)
+			}
+		})
+	}
+
+	body["rate"] = 3.8
+	if rec := s.do(t, admin, http.MethodPut, fmt.Sprintf("/admin/exchange-rates/%d", rate.ID), body); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+	var list struct{ Data []models.ExchangeRate }
+	decode(t, s.get(t, agent, "/admin/exchange-rates?base=usd"), &list)
+	if len(list.Data) != 1 || list.Data[0].Rate != 3.8 {
+		t.Errorf("rates = %+v", list.Data)
+	}
+	convert(t, http.StatusOK, 3800)
+
+	if rec := s.do(t, admin, http.MethodDelete, fmt.Sprintf("/admin/exchange-rates/%d", rate.ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d: %s", rec.Code, rec.Body)
+	}
+	convert(t, http.StatusBadRequest, 1000)
+	if rec := s.do(t, admin, http.MethodDelete, fmt.Sprintf("/admin/exchange-rates/%d", rate.ID), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("delete again: status = %d", rec.Code)
+	}
+
+	// Each change is audited
+	changes := 0
+	for _, row := range s.Rows("audit_logs") {
+		if row["resource_type"] == "exchange_rate" && row["resource_id"] == int64(rate.ID) {
+			changes++
+		}
+	}
+	if changes != 3 {
+		t.Errorf("%d audited changes, want 3", changes)
+	}
+}
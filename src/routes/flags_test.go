@@ -0,0 +1,99 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+)
+
+// flagState returns a flag's value and source from /admin/meta/flags, sent
+// with the given X-Feature-Flags header
+func (s *server) flagState(t *testing.T, as caller, name, header string) (bool, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/admin/meta/flags", nil)
+	if header != "" {
+		req.Header.Set(flags.Header, header)
+	}
+	rec := s.serve(t, as, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct{ Data []flags.State }
+	decode(t, rec, &body)
+	for _, state := range body.Data {
+		if state.Name == name {
+			return state.Enabled, state.Source
+		}
+	}
+	t.Fatalf("%s missing from %s", name, rec.Body)
+	return false, ""
+}
+
+// TestFeatureFlagPrecedence resolves a flag from its default, the
+// FEATURE_FLAGS settings and the X-Feature-Flags header of the callers
+// allowed to send it
+func TestFeatureFlagPrecedence(t *testing.T) {
+	settings := flags.Settings
+	t.Cleanup(func() { flags.Settings = settings })
+	production := newServer(t)
+	development := newServer(t, func(cfg *config.Config) { cfg.Environment = "development" })
+
+	for _, tc := range []struct {
+		name     string
+		s        *server
+		as       caller
+		settings string
+		header   string
+		enabled  bool
+		source   string
+	}{
+		{"default", production, admin, "", "", true, flags.SourceDefault},
+		{"settings off", production, admin, "-merge_patch", "", false, flags.SourceSettings},
+		{"settings on", production, admin, "merge_patch=on", "", true, flags.SourceSettings},
+		{"admin header over settings", production, admin, "-merge_patch", "merge_patch", true, flags.SourceHeader},
+		{"admin header over the default", production, admin, "", "merge_patch=off", false, flags.SourceHeader},
+		{"header for another flag", production, admin, "-merge_patch", "list_prefetch=off", false, flags.SourceSettings},
+		{"manager header ignored", production, manager, "-merge_patch", "merge_patch", false, flags.SourceSettings},
+		{"agent header ignored", production, agent, "", "merge_patch=off", true, flags.SourceDefault},
+		{"manager header in development", development, manager, "-merge_patch", "merge_patch", true, flags.SourceHeader},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if flags.Settings, err = flags.Parse(tc.settings); err != nil {
+				t.Fatal(err)
+			}
+			enabled, source := tc.s.flagState(t, tc.as, flags.MergePatch, tc.header)
+			if enabled != tc.enabled || source != tc.source {
+				t.Errorf("merge_patch = %v from %s, want %v from %s", enabled, source, tc.enabled, tc.source)
+			}
+		})
+	}
+
+	t.Run("invalid header", func(t *testing.T) {
+		flags.Settings = map[string]bool{}
+		req := httptest.NewRequest(http.MethodGet, "/admin/meta/flags", nil)
+		req.Header.Set(flags.Header, "merge_patch,no_such_flag")
+		rec := production.serve(t, admin, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"INVALID_FEATURE_FLAGS"`) {
+			t.Errorf("status = %d: %s", rec.Code, rec.Body)
+		}
+	})
+
+	// The resolved value drives the behavior: merge patches are applied only
+	// while the flag is on
+	t.Run("behavior follows the flag", func(t *testing.T) {
+		s := newServer(t)
+		s.Factory.Customer(t)
+		flags.Settings = map[string]bool{flags.MergePatch: false}
+		if rec := s.patch(t, "/admin/customers/1", mergePatch, `{"name":"Huda"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("settings off: status = %d: %s", rec.Code, rec.Body)
+		}
+		if rec := s.patch(t, "/admin/customers/1", mergePatch, `{"name":"Huda"}`, flags.Header, flags.MergePatch); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"Huda"`) {
+			t.Errorf("header on over settings off: status = %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/golden"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// seedGolden creates the records the golden responses are built from: two
+// customers, one tagged, with contacts, deals, activities and notes
+func seedGolden(t *testing.T, s *server) {
+	t.Helper()
+	f := s.Factory
+	vip := f.Tag(t, func(tag *models.Tag) { tag.Name = "vip" })
+	first := f.Customer(t, func(c *models.Customer) { c.Notes = "Prefers calls in the morning" })
+	second := f.Customer(t, func(c *models.Customer) { c.Status = models.CustomerStatusActive })
+	f.TagCustomer(t, first, vip)
+	f.Contact(t, first, func(c *models.Contact) { c.IsPrimary = true })
+	f.Contact(t, first)
+	deal := f.Deal(t, first)
+	f.Deal(t, second, func(d *models.Deal) { d.Stage = models.DealStageProposal })
+	f.Activity(t, first, func(a *models.Activity) { a.DealID = &deal.ID })
+	f.Activity(t, second, func(a *models.Activity) { a.Type = models.ActivityTypeCall })
+	f.Note(t, first, func(n *models.Note) { n.DealID = &deal.ID })
+}
+
+func TestGoldenResponses(t *testing.T) {
+	s := newServer(t)
+	seedGolden(t, s)
+
+	for _, tc := range []struct {
+		name string
+		path string
+	}{
+		{"customers_list", "/admin/customers?page_size=1"},
+		{"customer_detail", "/admin/customers/1"},
+		{"contacts_list", "/admin/customers/1/contacts"},
+		{"deals_list", "/admin/deals?page_size=1"},
+		{"deal_detail", "/admin/deals/1"},
+		{"activities_list", "/admin/activities?page_size=1"},
+		{"activity_detail", "/admin/activities/1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, admin, http.MethodGet, tc.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET %s: status %d: %s", tc.path, rec.Code, rec.Body)
+			}
+			golden.JSON(t, tc.name, rec.Body.Bytes())
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/golden"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// seedLists creates records that every list filter both matches and
+// excludes: three customers of different statuses, owners, domains and
+// tags, with contacts, deals and activities spread over them. The
+// activities fall due in 2025, before the database's NOW(), so the open
+// ones all read as overdue.
+func seedLists(t *testing.T, s *server) {
+	t.Helper()
+	f := s.Factory
+	one, two, three := uint(1), uint(2), uint(3)
+	epoch := factory.Epoch
+
+	industry := models.TagGroup{Name: "industry"}
+	if err := s.DB.Create(&industry).Error; err != nil {
+		t.Fatal(err)
+	}
+	vip := f.Tag(t, func(tag *models.Tag) { tag.Name = "vip" })
+	retail := f.Tag(t, func(tag *models.Tag) { tag.Name = "retail"; tag.GroupID = &industry.ID })
+
+	huda := f.Customer(t, func(c *models.Customer) {
+		c.Name, c.Email, c.Company = "Huda Al-Nakheel", "huda@nakheel.sa", "Nakheel"
+		c.AssignedTo, c.ExternalID = &two, "ext-1"
+	})
+	ahmed := f.Customer(t, func(c *models.Customer) { c.Name, c.Status = "Ahmed Saleh", models.CustomerStatusActive })
+	sara := f.Customer(t, func(c *models.Customer) {
+		c.Name, c.Email, c.Status, c.AssignedTo = "Sara Ali", "sara@nakheel.sa", models.CustomerStatusInactive, &three
+	})
+	f.TagCustomer(t, huda, vip)
+	f.TagCustomer(t, ahmed, retail)
+	f.TagCustomer(t, sara, vip)
+
+	f.Contact(t, huda, func(c *models.Contact) { c.FirstName, c.LastName = "Omar", "Nakheel" })
+	f.Contact(t, huda, func(c *models.Contact) { c.IsPrimary = true })
+
+	closeSoon, closeLater := epoch.AddDate(0, 0, 10), epoch.AddDate(0, 1, 0)
+	f.Deal(t, huda, func(d *models.Deal) {
+		d.Title, d.Amount, d.OwnerID, d.ExpectedCloseDate, d.NextStep = "Nakheel renewal", 1000, &two, &closeSoon, "Send the quote"
+	})
+	f.Deal(t, ahmed, func(d *models.Deal) {
+		d.Title, d.Amount, d.OwnerID, d.ExpectedCloseDate = "Branch rollout", 5000, &three, &closeLater
+		d.Stage, d.ExternalID = models.DealStageProposal, "legacy-7"
+	})
+	f.Deal(t, sara, func(d *models.Deal) { d.Title, d.Amount, d.NextStep = "Support plan", 2500, "Book a demo" })
+
+	overdue := epoch.Add(time.Hour)
+	f.Activity(t, huda, func(a *models.Activity) { a.Title, a.AssignedTo, a.Priority = "Call Huda", &one, "high" })
+	f.Activity(t, huda, func(a *models.Activity) {
+		a.Title, a.Type, a.AssignedTo, a.DueDate = "Quarterly review", models.ActivityTypeMeeting, &one, &overdue
+	})
+	f.Activity(t, ahmed, func(a *models.Activity) { a.Title, a.DealID = "Rollout plan", &two })
+	f.Activity(t, sara, func(a *models.Activity) {
+		a.Title, a.AssignedTo, a.Status, a.Priority = "Demo follow-up", &three, models.ActivityStatusCompleted, "low"
+	})
+}
+
+// TestListParameterGoldens pins the responses of the list endpoints to every
+// filter, sort and paging parameter they take, one golden file per endpoint
+// keyed by request
+func TestListParameterGoldens(t *testing.T) {
+	s := newServer(t)
+	seedLists(t, s)
+
+	for _, tc := range []struct {
+		name  string
+		paths []string
+	}{
+		{"list_params_customers", []string{
+			"/admin/customers",
+			"/admin/customers?status=active",
+			"/admin/customers?assigned_to=2",
+			"/admin/customers?domain=nakheel.sa",
+			"/admin/customers?external_id=ext-1",
+			"/admin/customers?search=NAKHEEL",
+			"/admin/customers?created_from=2025-01-06T09:03:00Z",
+			"/admin/customers?created_to=2025-01-06T09:03:00Z",
+			"/admin/customers?created_from=yesterday",
+			"/admin/customers?tags=1",
+			"/admin/customers?tags=1,2",
+			"/admin/customers?tag_group=industry",
+			"/admin/customers?claimable=true",
+			"/admin/customers?claimable=maybe",
+			"/admin/customers?sort_by=name&sort_order=asc",
+			"/admin/customers?sort_by=password&sort_order=sideways",
+			"/admin/customers?page=2&page_size=2",
+			"/admin/customers?page=0&page_size=500",
+			"/admin/customers?status=lead&tags=1&sort_by=email",
+		}},
+		{"list_params_contacts", []string{
+			"/admin/customers/1/contacts",
+			"/admin/customers/1/contacts?search=omar",
+			"/admin/customers/1/contacts?search=nobody",
+		}},
+		{"list_params_deals", []string{
+			"/admin/deals",
+			"/admin/deals?stage=proposal",
+			"/admin/deals?owner_id=2",
+			"/admin/deals?customer_id=3",
+			"/admin/deals?external_id=legacy-7",
+			"/admin/deals?search=renewal",
+			"/admin/deals?amount_min=2000",
+			"/admin/deals?amount_max=2500",
+			"/admin/deals?amount_min=lots",
+			"/admin/deals?expected_close_from=2025-01-20T00:00:00Z",
+			"/admin/deals?expected_close_to=2025-01-20T00:00:00Z",
+			"/admin/deals?tags=1",
+			"/admin/deals?tag_group=industry",
+			"/admin/deals?missing_next_step=true",
+			"/admin/deals?missing_next_step=false",
+			"/admin/deals?sort_by=amount&sort_order=asc",
+			"/admin/deals?sort_by=title",
+			"/admin/deals?page=2&page_size=2",
+		}},
+		{"list_params_activities", []string{
+			"/admin/activities",
+			"/admin/activities?type=meeting",
+			"/admin/activities?status=overdue",
+			"/admin/activities?status=completed",
+			"/admin/activities?assigned_to=1",
+			"/admin/activities?customer_id=1",
+			"/admin/activities?deal_id=2",
+			"/admin/activities?search=demo",
+			"/admin/activities?due_date_from=2025-01-07T09:10:00Z",
+			"/admin/activities?due_date_to=2025-01-07T09:10:00Z",
+			"/admin/activities?priority=high",
+			"/admin/activities?claimable=true",
+			"/admin/activities?sort_by=title&sort_order=desc",
+			"/admin/activities?sort_by=unknown",
+			"/admin/activities?page=2&page_size=3",
+		}},
+		{"list_params_my_activities", []string{
+			"/admin/me/activities",
+			"/admin/me/activities?status=overdue",
+			"/admin/me/activities?status=scheduled",
+			"/admin/me/activities?page=2&page_size=1",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			responses := make(map[string]interface{}, len(tc.paths))
+			for _, path := range tc.paths {
+				rec := s.do(t, admin, http.MethodGet, path, nil)
+				var body interface{}
+				decode(t, rec, &body)
+				responses[path] = map[string]interface{}{"status": rec.Code, "body": body}
+			}
+			raw, err := json.Marshal(responses)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden.JSON(t, tc.name, raw)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// description is 31 characters, 57 bytes, of Arabic text
+const description = "مرحبا بكم في نظام إدارة العملاء"
+
+func TestDealDescriptionPreview(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) {
+		cfg.TextPreviewLengths = "deals=15"
+	})
+	s.Factory.Deal(t, s.Factory.Customer(t), func(d *models.Deal) { d.Description = description })
+
+	rec := s.do(t, agent, http.MethodGet, "/admin/deals", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var page struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decode(t, rec, &page)
+	if len(page.Data) != 1 {
+		t.Fatalf("%d deals", len(page.Data))
+	}
+	deal := page.Data[0]
+	if deal["description"] != "مرحبا بكم في" || deal["description_truncated"] != true || deal["description_length"] != float64(31) {
+		t.Errorf("list: description = %q, truncated %v, length %v", deal["description"], deal["description_truncated"], deal["description_length"])
+	}
+
+	// The detail returns the description in full
+	rec = s.do(t, agent, http.MethodGet, "/admin/deals/1", nil)
+	var detail map[string]interface{}
+	decode(t, rec, &detail)
+	if detail["description"] != description || detail["description_truncated"] != nil {
+		t.Errorf("detail: description = %q, truncated %v", detail["description"], detail["description_truncated"])
+	}
+}
+
+func TestLongTextLimits(t *testing.T) {
+	s := newServer(t)
+	oldSoft, oldHard := models.LongTextSoftLimit, models.LongTextHardLimit
+	models.LongTextSoftLimit, models.LongTextHardLimit = 40, 60
+	t.Cleanup(func() {
+		models.LongTextSoftLimit, models.LongTextHardLimit = oldSoft, oldHard
+	})
+	customer := s.Factory.Customer(t)
+
+	// 57 bytes: over the soft limit only
+	rec := s.do(t, agent, http.MethodPost, "/admin/deals", map[string]interface{}{"title": "Renewal", "customer_id": customer.ID, "description": description})
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Header().Get("Warning"), "description") {
+		t.Errorf("over the soft limit: status = %d, Warning = %q: %s", rec.Code, rec.Header().Get("Warning"), rec.Body)
+	}
+
+	// 63 bytes: over the hard limit
+	rec = s.do(t, agent, http.MethodPost, "/admin/deals", map[string]interface{}{"title": "Renewal", "customer_id": customer.ID, "description": description + " جدا"})
+	var body struct {
+		Code   string   `json:"code"`
+		Fields []string `json:"fields"`
+	}
+	decode(t, rec, &body)
+	if rec.Code != http.StatusRequestEntityTooLarge || body.Code != "TEXT_TOO_LONG" || len(body.Fields) != 1 || body.Fields[0] != "description" {
+		t.Errorf("over the hard limit: status = %d: %s", rec.Code, rec.Body)
+	}
+	if n := s.Count("deals"); n != 1 {
+		t.Errorf("%d deals, want the one under the hard limit", n)
+	}
+}
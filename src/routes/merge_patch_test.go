@@ -0,0 +1,269 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/flags"
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+const mergePatch = "application/merge-patch+json"
+
+// patch sends a raw PATCH body with a content type as the admin
+func (s *server) patch(t testing.TB, path, contentType, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return s.serve(t, admin, req)
+}
+
+// row returns the stored row of a table with the given ID
+func (s *server) row(t testing.TB, table string, id uint) map[string]interface{} {
+	t.Helper()
+	for _, r := range s.Rows(table) {
+		if fmt.Sprint(r["id"]) == fmt.Sprint(id) {
+			return r
+		}
+	}
+	t.Fatalf("%s %d not found", table, id)
+	return nil
+}
+
+// jsonValue returns a stored value as JSON, so it compares with the value
+// a patch sent
+func jsonValue(t testing.TB, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+// sameJSON reports whether two JSON texts hold the same value
+func sameJSON(a, b string) bool {
+	var x, y interface{}
+	return json.Unmarshal([]byte(a), &x) == nil && json.Unmarshal([]byte(b), &y) == nil && reflect.DeepEqual(x, y)
+}
+
+// patchField is a field of a merge patch matrix: the value the patch sets,
+// whether null clears it, and the other columns setting it also changes
+type patchField struct {
+	name     string
+	value    string
+	nullable bool
+	derived  []string
+}
+
+// patchMatrix patches one field at a time of a fresh record, with a value
+// and with null, and checks the stored row: the field takes the value or
+// is cleared, null on a required field is refused, and every field absent
+// from the patch keeps its value. create makes the record a field is
+// patched on.
+func patchMatrix(t *testing.T, s *server, table string, create func(field string) uint, fields []patchField) {
+	for _, field := range fields {
+		for _, value := range []string{field.value, "null"} {
+			t.Run(field.name+"="+value, func(t *testing.T) {
+				id := create(field.name)
+				before := s.row(t, table, id)
+				// The reason covers the fields the seeded policy requires one for
+				rec := s.patch(t, fmt.Sprintf("/admin/%s/%d", table, id), mergePatch, `{"`+field.name+`":`+value+`}`, handlers.HeaderChangeReason, "Matrix")
+				after := s.row(t, table, id)
+
+				if value == "null" && !field.nullable {
+					var body struct {
+						Code   string   `json:"code"`
+						Fields []string `json:"fields"`
+					}
+					decode(t, rec, &body)
+					if rec.Code != http.StatusBadRequest || body.Code != "FIELD_NOT_NULLABLE" || !slices.Equal(body.Fields, []string{field.name}) {
+						t.Errorf("status = %d, body = %s, want FIELD_NOT_NULLABLE", rec.Code, rec.Body)
+					}
+					if !reflect.DeepEqual(before, after) {
+						t.Errorf("refused patch changed the row:\n%v\n%v", before, after)
+					}
+					return
+				}
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+				}
+
+				got := jsonValue(t, after[field.name])
+				switch {
+				case value == "null" && !slices.Contains([]string{"null", `""`, "0", "false"}, got):
+					t.Errorf("%s = %s, want it cleared", field.name, got)
+				case value != "null" && !sameJSON(got, value):
+					t.Errorf("%s = %s, want %s", field.name, got, value)
+				}
+
+				// Absent fields are untouched
+				for column, old := range before {
+					if column == field.name || column == "updated_at" || slices.Contains(field.derived, column) {
+						continue
+					}
+					if !reflect.DeepEqual(old, after[column]) {
+						t.Errorf("absent %s changed from %v to %v", column, old, after[column])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCustomerMergePatchMatrix(t *testing.T) {
+	s := newServer(t)
+	agentID := agent.ID
+	followUp := s.Now().Add(48 * time.Hour)
+	create := func(string) uint {
+		return s.Factory.Customer(t, func(c *models.Customer) {
+			c.Role, c.Notes, c.AssignedTo, c.NextFollowUpAt = "Buyer", "Met at the expo", &agentID, &followUp
+		}).ID
+	}
+
+	patchMatrix(t, s, "customers", create, []patchField{
+		{name: "name", value: `"Huda Al-Nakheel"`, derived: []string{"search_text"}},
+		{name: "email", value: `"huda@nakheel.sa"`, derived: []string{"email_domain", "search_text"}},
+		{name: "phone", value: `"+966500001234"`, nullable: true},
+		{name: "company", value: `"Nakheel"`, nullable: true, derived: []string{"search_text"}},
+		{name: "role", value: `"Director"`, nullable: true},
+		{name: "status", value: `"prospect"`},
+		{name: "assigned_to", value: `2`, nullable: true},
+		{name: "contacted", value: `true`},
+		{name: "notes", value: `"Prefers email"`, nullable: true},
+		{name: "next_follow_up_at", value: `"2025-02-03T09:00:00Z"`, nullable: true},
+	})
+}
+
+func TestDealMergePatchMatrix(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	other := s.Factory.Customer(t)
+	contact, otherContact := s.Factory.Contact(t, customer), s.Factory.Contact(t, customer)
+	agentID := agent.ID
+	closeOn, due := s.Now().AddDate(0, 1, 0), s.Now().AddDate(0, 0, 3)
+	create := func(string) uint {
+		return s.Factory.Deal(t, customer, func(d *models.Deal) {
+			d.Description, d.ContactID, d.OwnerID, d.ExpectedCloseDate = "Two branches", &contact.ID, &agentID, &closeOn
+			d.ActualCloseDate, d.LostReason, d.ExternalID = &closeOn, "Budget", "legacy-1"
+			d.NextStep, d.NextStepDue = "Send the quote", &due
+		}).ID
+	}
+
+	patchMatrix(t, s, "deals", create, []patchField{
+		{name: "title", value: `"Nakheel renewal"`, derived: []string{"search_text"}},
+		{name: "description", value: `"Three branches"`, nullable: true},
+		{name: "customer_id", value: fmt.Sprint(other.ID)},
+		{name: "contact_id", value: fmt.Sprint(otherContact.ID), nullable: true},
+		{name: "stage", value: `"qualification"`, derived: []string{"board_position"}},
+		{name: "amount", value: `7500`},
+		{name: "currency", value: `"EUR"`},
+		{name: "probability", value: `40`},
+		{name: "expected_close_date", value: `"2025-03-31T00:00:00Z"`, nullable: true},
+		{name: "actual_close_date", value: `"2025-03-30T00:00:00Z"`, nullable: true},
+		{name: "owner_id", value: `2`, nullable: true},
+		{name: "lost_reason", value: `"Timing"`, nullable: true},
+		{name: "external_id", value: `"legacy-2"`, nullable: true},
+		{name: "next_step", value: `"Book a demo"`, nullable: true, derived: []string{"next_step_set_at"}},
+		{name: "next_step_due", value: `"2025-01-20T09:00:00Z"`, nullable: true},
+	})
+}
+
+func TestActivityMergePatchMatrix(t *testing.T) {
+	s := newServer(t)
+	customer, other := s.Factory.Customer(t), s.Factory.Customer(t)
+	deal, otherDeal := s.Factory.Deal(t, customer), s.Factory.Deal(t, customer)
+	contact, otherContact := s.Factory.Contact(t, customer), s.Factory.Contact(t, customer)
+	agentID := agent.ID
+	completedAt := s.Now().Add(-time.Hour)
+	create := func(field string) uint {
+		return s.Factory.Activity(t, customer, func(a *models.Activity) {
+			a.Description, a.DealID, a.ContactID, a.AssignedTo = "Agenda attached", &deal.ID, &contact.ID, &agentID
+			a.Duration, a.Outcome, a.Template = 30, "Left a message", "intro"
+			// Only completed activities have a completion time
+			if field == "completed_at" {
+				a.Status, a.CompletedAt = models.ActivityStatusCompleted, &completedAt
+			}
+		}).ID
+	}
+
+	patchMatrix(t, s, "activities", create, []patchField{
+		{name: "title", value: `"Call Huda"`, derived: []string{"search_text"}},
+		{name: "description", value: `"No agenda"`, nullable: true},
+		{name: "type", value: `"call"`},
+		{name: "status", value: `"cancelled"`},
+		{name: "customer_id", value: fmt.Sprint(other.ID), nullable: true},
+		{name: "deal_id", value: fmt.Sprint(otherDeal.ID), nullable: true},
+		{name: "contact_id", value: fmt.Sprint(otherContact.ID), nullable: true},
+		{name: "assigned_to", value: `2`, nullable: true},
+		{name: "due_date", value: `"2025-01-09T09:00:00Z"`, nullable: true},
+		{name: "completed_at", value: `"2025-01-06T12:00:00Z"`},
+		{name: "duration", value: `45`, nullable: true},
+		{name: "outcome", value: `"Agreed on a demo"`, nullable: true},
+		{name: "priority", value: `"high"`},
+		{name: "template", value: `"follow_up"`, nullable: true},
+	})
+}
+
+func TestPatchContentTypes(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	s.Factory.Deal(t, customer)
+	s.Factory.Activity(t, customer)
+
+	for _, tc := range []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		header      []string
+		status      int
+		code        string
+		column      string // Column of the patched row to check
+		want        string // Its stored value, as JSON
+	}{
+		{"merge patch clears", "/admin/customers/1", mergePatch, `{"phone":null}`, nil, http.StatusOK, "", "phone", `""`},
+		{"merge patch with charset", "/admin/customers/1", mergePatch + "; charset=utf-8", `{"company":null}`, nil, http.StatusOK, "", "company", `""`},
+		{"plain json ignores other fields", "/admin/customers/1", "application/json", `{"role":"Buyer"}`, nil, http.StatusBadRequest, "NO_UPDATES", "role", `""`},
+		{"plain json status patch", "/admin/customers/1", "application/json", `{"status":"prospect"}`, nil, http.StatusOK, "", "status", `"prospect"`},
+		{"merge patch flag off", "/admin/customers/1", mergePatch, `{"name":"Huda"}`, []string{flags.Header, flags.MergePatch + "=off"}, http.StatusBadRequest, "NO_UPDATES", "name", `"Customer 1"`},
+		{"unknown field", "/admin/customers/1", mergePatch, `{"name":"Huda","password":"x"}`, nil, http.StatusBadRequest, "UNKNOWN_FIELDS", "name", `"Customer 1"`},
+		{"array body", "/admin/customers/1", mergePatch, `["name"]`, nil, http.StatusBadRequest, "INVALID_MERGE_PATCH", "", ""},
+		{"null body", "/admin/customers/1", mergePatch, `null`, nil, http.StatusBadRequest, "INVALID_MERGE_PATCH", "", ""},
+		{"wrong type", "/admin/deals/1", mergePatch, `{"amount":"lots"}`, nil, http.StatusBadRequest, "INVALID_REQUEST", "amount", "1000"},
+		{"invalid enum", "/admin/deals/1", mergePatch, `{"stage":"won"}`, nil, http.StatusBadRequest, "INVALID_STAGE", "stage", `"prospecting"`},
+		{"plain json stage transition", "/admin/deals/1", "application/json", `{"stage":"qualification"}`, nil, http.StatusOK, "", "stage", `"qualification"`},
+		{"plain json needs a stage", "/admin/deals/1", "application/json", `{"description":null}`, nil, http.StatusBadRequest, "INVALID_REQUEST", "", ""},
+		{"invalid priority", "/admin/activities/1", mergePatch, `{"priority":"urgent"}`, nil, http.StatusBadRequest, "INVALID_PRIORITY", "priority", `"normal"`},
+		{"plain json status update", "/admin/activities/1", "application/json", `{"status":"cancelled"}`, nil, http.StatusOK, "", "status", `"cancelled"`},
+		{"completion time of an open activity", "/admin/activities/1", mergePatch, `{"completed_at":"2025-01-06T12:00:00Z"}`, nil, http.StatusBadRequest, "INVALID_REQUEST", "completed_at", "null"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.patch(t, tc.path, tc.contentType, tc.body, tc.header...)
+			var body struct {
+				Code string `json:"code"`
+			}
+			decode(t, rec, &body)
+			if rec.Code != tc.status || body.Code != tc.code {
+				t.Errorf("status = %d, code = %q, want %d %q: %s", rec.Code, body.Code, tc.status, tc.code, rec.Body)
+			}
+			if tc.column != "" {
+				table := strings.Split(tc.path, "/")[2]
+				if got := jsonValue(t, s.row(t, table, 1)[tc.column]); !sameJSON(got, tc.want) {
+					t.Errorf("%s = %s, want %s", tc.column, got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package routes_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestDealStagesMatchTheSeed checks that the stage constants the service
+// validates deals with are the pipeline stages the migrations seed, in
+// board order, and that the deal schema offers exactly those stages
+func TestDealStagesMatchTheSeed(t *testing.T) {
+	s := newServer(t)
+
+	var seeded []models.PipelineStage
+	if err := s.DB.Order(`"order"`).Find(&seeded).Error; err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, stage := range seeded {
+		names = append(names, stage.Name)
+	}
+	var constants []string
+	for _, stage := range models.ValidDealStages {
+		constants = append(constants, string(stage))
+	}
+	if !slices.Equal(names, constants) {
+		t.Errorf("seeded stages = %v, models.ValidDealStages = %v", names, constants)
+	}
+	for _, reserved := range models.ReservedDealStages {
+		if !slices.Contains(names, string(reserved)) {
+			t.Errorf("reserved stage %s is not seeded", reserved)
+		}
+	}
+
+	var schema handlers.EntitySchema
+	decode(t, s.get(t, admin, "/admin/meta/entities/deals"), &schema)
+	var enum []string
+	for _, field := range schema.Fields {
+		if field.Name != "stage" {
+			continue
+		}
+		for _, value := range field.Rules.Enum {
+			enum = append(enum, value.Value)
+		}
+	}
+	if !slices.Equal(enum, names) {
+		t.Errorf("stage enum = %v, want the seeded stages %v", enum, names)
+	}
+
+	for _, entity := range []string{"customers", "contacts", "deals", "activities"} {
+		if rec := s.get(t, admin, "/admin/meta/entities/"+entity); rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d: %s", entity, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// listPage is the part of a list response paging needs
+type listPage struct {
+	Data []struct {
+		ID uint `json:"id"`
+	} `json:"data"`
+	Page       int    `json:"page"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// pageThrough lists path with the given parameters page by page, following
+// next_cursor from the first offset page, and returns the IDs in order.
+// Offset pages carry a page token in X-Next-Cursor, cursor pages their
+// cursor.
+// between runs after each page.
+func pageThrough(t *testing.T, s *server, path string, params url.Values, between func()) []uint {
+	t.Helper()
+	var ids []uint
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatalf("%s?%s does not end", path, params.Encode())
+		}
+		rec := s.do(t, admin, http.MethodGet, path+"?"+params.Encode(), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s?%s: status = %d: %s", path, params.Encode(), rec.Code, rec.Body)
+		}
+		var page listPage
+		decode(t, rec, &page)
+		if pages > 0 && page.Page != 0 {
+			t.Errorf("cursor page numbered %d", page.Page)
+		}
+		for _, row := range page.Data {
+			ids = append(ids, row.ID)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+		if pages > 0 && rec.Header().Get("X-Next-Cursor") != page.NextCursor {
+			t.Errorf("X-Next-Cursor = %q, want %q", rec.Header().Get("X-Next-Cursor"), page.NextCursor)
+		}
+		if between != nil {
+			between()
+		}
+		params.Set("cursor", page.NextCursor)
+	}
+}
+
+// activitiesDue creates activities due the given number of days after the
+// epoch, -1 for no due date
+func activitiesDue(t *testing.T, s *server, days ...int) {
+	t.Helper()
+	customer := s.Factory.Customer(t)
+	for _, day := range days {
+		s.Factory.Activity(t, customer, func(a *models.Activity) {
+			a.DueDate = nil
+			if day >= 0 {
+				due := factory.Epoch.Add(time.Duration(day) * 24 * time.Hour)
+				a.DueDate = &due
+			}
+		})
+	}
+}
+
+func TestActivityCursorPaging(t *testing.T) {
+	s := newServer(t)
+	// IDs 1 to 7; 3 and 5 have no due date, 2 and 4 and 7 share one
+	activitiesDue(t, s, 3, 2, -1, 2, -1, 4, 2)
+
+	for _, tc := range []struct {
+		order string
+		want  []uint
+	}{
+		// No due date sorts last ascending and first descending
+		{"asc", []uint{2, 4, 7, 1, 6, 3, 5}},
+		{"desc", []uint{5, 3, 6, 1, 7, 4, 2}},
+	} {
+		for size := 1; size <= 4; size++ {
+			t.Run(fmt.Sprintf("%s by %d", tc.order, size), func(t *testing.T) {
+				params := url.Values{"sort_by": {"due_date"}, "sort_order": {tc.order}, "page_size": {fmt.Sprint(size)}}
+				if got := pageThrough(t, s, "/admin/activities", params, nil); !slices.Equal(got, tc.want) {
+					t.Errorf("IDs = %v, want %v", got, tc.want)
+				}
+			})
+		}
+	}
+}
+
+// TestActivityCursorPagingDuringInserts inserts a row before the cursor
+// after every page: cursor pages neither repeat nor skip rows
+func TestActivityCursorPagingDuringInserts(t *testing.T) {
+	s := newServer(t)
+	activitiesDue(t, s, 1, 2, 3, 4, 5, 6)
+	customer := s.Factory.Customer(t)
+
+	for _, order := range []string{"asc", "desc"} {
+		t.Run(order, func(t *testing.T) {
+			before := s.Count("activities")
+			insert := func() {
+				s.Factory.Activity(t, customer, func(a *models.Activity) {
+					due := factory.Epoch
+					if order == "desc" {
+						due = factory.Epoch.Add(7 * 24 * time.Hour)
+					}
+					a.DueDate = &due
+				})
+			}
+			params := url.Values{"sort_by": {"due_date"}, "sort_order": {order}, "page_size": {"2"}}
+			got := pageThrough(t, s, "/admin/activities", params, insert)
+			if len(got) != before {
+				t.Errorf("IDs = %v, want the %d rows there at the start", got, before)
+			}
+			seen := map[uint]bool{}
+			for _, id := range got {
+				if seen[id] {
+					t.Errorf("ID %d listed twice: %v", id, got)
+				}
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func TestCustomerCursorPaging(t *testing.T) {
+	s := newServer(t)
+	for _, name := range []string{"Omar", "Huda", "Sara", "Huda", "Ali", "Omar"} {
+		s.Factory.Customer(t, func(c *models.Customer) { c.Name = name })
+	}
+
+	for _, tc := range []struct {
+		sortBy, order string
+		want          []uint
+	}{
+		{"name", "asc", []uint{5, 2, 4, 1, 6, 3}},
+		{"name", "desc", []uint{3, 6, 1, 4, 2, 5}},
+		{"created_at", "asc", []uint{1, 2, 3, 4, 5, 6}},
+		{"created_at", "desc", []uint{6, 5, 4, 3, 2, 1}},
+	} {
+		for _, size := range []int{1, 2, 4} {
+			t.Run(fmt.Sprintf("%s %s by %d", tc.sortBy, tc.order, size), func(t *testing.T) {
+				params := url.Values{"sort_by": {tc.sortBy}, "sort_order": {tc.order}, "page_size": {fmt.Sprint(size)}}
+				if got := pageThrough(t, s, "/admin/customers", params, nil); !slices.Equal(got, tc.want) {
+					t.Errorf("IDs = %v, want %v", got, tc.want)
+				}
+			})
+		}
+	}
+
+	// Numbered pages keep working without a cursor
+	rec := s.do(t, admin, http.MethodGet, "/admin/customers?sort_by=name&sort_order=asc&page=2&page_size=2", nil)
+	var page listPage
+	decode(t, rec, &page)
+	if page.Page != 2 || len(page.Data) != 2 || page.Data[0].ID != 4 || page.Data[1].ID != 1 {
+		t.Errorf("page 2 = %+v", page)
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	s := newServer(t)
+	activitiesDue(t, s, 1, 2, 3)
+	rec := s.do(t, admin, http.MethodGet, "/admin/activities?sort_by=due_date&sort_order=asc&page_size=1", nil)
+	var page listPage
+	decode(t, rec, &page)
+
+	for _, tc := range []struct{ name, query string }{
+		{"not base64", "cursor=%25%25"},
+		{"not a cursor", "cursor=bm90LWpzb24"},
+		{"other direction", "sort_by=due_date&sort_order=desc&cursor=" + page.NextCursor},
+		{"other field", "sort_by=title&sort_order=asc&cursor=" + page.NextCursor},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, admin, http.MethodGet, "/admin/activities?"+tc.query, nil)
+			var body struct {
+				Code string `json:"code"`
+			}
+			decode(t, rec, &body)
+			if rec.Code != http.StatusBadRequest || body.Code != "INVALID_CURSOR" {
+				t.Errorf("status = %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
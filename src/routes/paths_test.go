@@ -0,0 +1,134 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestPathVariants serves trailing-slash and case variants of a route as the
+// route itself, without redirecting
+func TestPathVariants(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t, func(c *models.Customer) { c.Name = "Nakheel" })
+
+	for _, path := range []string{
+		"/admin/customers",
+		"/admin/customers/",
+		"/admin/customers//",
+		"/Admin/Customers",
+		"/ADMIN/CUSTOMERS/",
+		"/admin/customers?search=Nakheel",
+		"/admin/customers/?search=Nakheel",
+	} {
+		t.Run(path, func(t *testing.T) {
+			var body struct{ Data []models.Customer }
+			decode(t, s.get(t, admin, path), &body)
+			if len(body.Data) != 1 || body.Data[0].ID != customer.ID {
+				t.Errorf("customers = %+v", body.Data)
+			}
+		})
+	}
+
+	for _, path := range []string{fmt.Sprintf("/admin/customers/%d/", customer.ID), fmt.Sprintf("/Admin/Customers/%d", customer.ID)} {
+		var got models.Customer
+		decode(t, s.get(t, admin, path), &got)
+		if got.ID != customer.ID {
+			t.Errorf("%s: customer %d", path, got.ID)
+		}
+	}
+
+	// A POST keeps its body, which a redirect would drop
+	rec := s.do(t, admin, http.MethodPost, "/Admin/Customers/", map[string]interface{}{"name": "Huda", "email": "huda@nakheel.sa"})
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"name":"Huda"`) {
+		t.Errorf("POST to a variant: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// A preflight of a variant is answered, not redirected
+	req := httptest.NewRequest(http.MethodOptions, "/Admin/customers/", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec = s.serve(t, caller{}, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Errorf("preflight of a variant: status = %d, headers = %v", rec.Code, rec.Header())
+	}
+}
+
+// TestPathVariantsKeepParameters leaves segments that are not part of any
+// route, like tokens, as sent
+func TestPathVariantsKeepParameters(t *testing.T) {
+	s := newServer(t)
+	email := s.Factory.Activity(t, s.Factory.Customer(t), func(a *models.Activity) { a.Type = models.ActivityTypeEmail })
+	// Tokens are case-sensitive, so lower-casing one would break its
+	// signature
+	token := signTrackingToken(t, map[string]interface{}{"a": email.ID, "p": "unsubscribe", "n": "Nonce", "t": time.Now().Unix()})
+	for _, path := range []string{"/public/email/unsubscribe/" + token, "/Public/Email/Unsubscribe/" + token + "/"} {
+		rec := s.do(t, caller{}, http.MethodGet, path, nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+}
+
+// TestUnmatchedRoutes answers unknown paths with a structured 404, and known
+// paths with another method with a 405 listing the allowed ones
+func TestUnmatchedRoutes(t *testing.T) {
+	s := newServer(t)
+
+	for _, path := range []string{"/admin/customerz", "/Admin/Nope/", "/public/nope", "/nowhere"} {
+		t.Run("404 "+path, func(t *testing.T) {
+			rec := s.do(t, admin, http.MethodGet, path, nil)
+			if rec.Code != http.StatusNotFound || rec.Header().Get("Allow") != "" {
+				t.Fatalf("status = %d, Allow = %q: %s", rec.Code, rec.Header().Get("Allow"), rec.Body)
+			}
+			var body struct{ Error, Code, Message, Path, Hint string }
+			decode(t, rec, &body)
+			if body.Error != "not_found" || body.Code != "ROUTE_NOT_FOUND" || body.Message == "" || body.Hint == "" {
+				t.Errorf("body = %+v", body)
+			}
+			// The path is reported as sent, before normalization
+			if body.Path != path {
+				t.Errorf("path = %q, want %q", body.Path, path)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		allowed      []string
+	}{
+		{http.MethodDelete, "/admin/customers", []string{http.MethodGet, http.MethodPost}},
+		{http.MethodPatch, "/Admin/Customers/", []string{http.MethodGet, http.MethodPost}},
+		{http.MethodPost, "/admin/customers/1", []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete}},
+	} {
+		t.Run("405 "+tc.method+" "+tc.path, func(t *testing.T) {
+			rec := s.do(t, admin, tc.method, tc.path, nil)
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			allow := strings.Split(rec.Header().Get("Allow"), ", ")
+			for _, method := range tc.allowed {
+				if !slices.Contains(allow, method) {
+					t.Errorf("Allow = %v, missing %s", allow, method)
+				}
+			}
+			if slices.Contains(allow, tc.method) {
+				t.Errorf("Allow = %v lists the request method", allow)
+			}
+			var body struct {
+				Code, Path, Method string
+				Allowed            []string
+			}
+			decode(t, rec, &body)
+			if body.Code != "METHOD_NOT_ALLOWED" || body.Path != tc.path || body.Method != tc.method || !slices.Equal(body.Allowed, allow) {
+				t.Errorf("body = %+v, Allow = %v", body, allow)
+			}
+		})
+	}
+}
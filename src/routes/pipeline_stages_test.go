@@ -0,0 +1,47 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestDeleteStageWithDeals checks that a stage still holding deals cannot
+// be deleted or deactivated
+func TestDeleteStageWithDeals(t *testing.T) {
+	s := newServer(t)
+	var stage models.PipelineStage
+	if err := s.DB.Where("name = ?", models.DealStageNegotiation).First(&stage).Error; err != nil {
+		t.Fatal(err)
+	}
+	deal := s.Factory.Deal(t, s.Factory.Customer(t), func(d *models.Deal) { d.Stage = models.DealStageNegotiation })
+	path := fmt.Sprintf("/admin/pipeline-stages/%d", stage.ID)
+
+	inactive := false
+	for _, tc := range []struct {
+		method string
+		body   interface{}
+	}{
+		{http.MethodDelete, nil},
+		{http.MethodPut, map[string]interface{}{"name": stage.Name, "display_name": stage.DisplayName, "order": stage.Order, "is_active": &inactive}},
+	} {
+		rec := s.do(t, admin, tc.method, path, tc.body)
+		var refused struct {
+			Code      string
+			DealCount int64 `json:"deal_count"`
+		}
+		decode(t, rec, &refused)
+		if rec.Code != http.StatusConflict || refused.Code != "DEAL_REFERENCES_STAGE" || refused.DealCount != 1 {
+			t.Errorf("%s: status = %d: %s", tc.method, rec.Code, rec.Body)
+		}
+	}
+
+	if err := s.DB.Model(&deal).Update("stage", models.DealStageProposal).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rec := s.do(t, admin, http.MethodDelete, path, nil); rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+		t.Errorf("empty stage: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,34 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+)
+
+// TestRecordQuota checks that a create over the record quota is refused
+// with the usage and limit
+func TestRecordQuota(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) { cfg.QuotaDeals = 1 })
+	customer := s.Factory.Customer(t)
+	s.Factory.Deal(t, customer)
+
+	rec := s.do(t, manager, http.MethodPost, "/admin/deals", map[string]interface{}{"title": "Expansion", "customer_id": customer.ID})
+	var refused struct {
+		Code   string
+		Entity string
+		Usage  int64
+		Limit  int64
+	}
+	decode(t, rec, &refused)
+	if rec.Code != http.StatusForbidden || refused.Code != "QUOTA_EXCEEDED" || refused.Entity != "deals" || refused.Usage != 1 || refused.Limit != 1 {
+		t.Errorf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// Other entities have their own quotas
+	rec = s.do(t, manager, http.MethodPost, "/admin/customers", map[string]interface{}{"name": "Another", "email": "another@example.com"})
+	if rec.Code != http.StatusCreated {
+		t.Errorf("customer: status = %d: %s", rec.Code, rec.Body)
+	}
+}
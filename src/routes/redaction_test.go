@@ -0,0 +1,114 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// redactedRecord is the part of a customer or deal response redaction
+// changes
+type redactedRecord struct {
+	ID             uint            `json:"id"`
+	Notes          interface{}     `json:"notes"`
+	Amount         *float64        `json:"amount"`
+	RedactedFields []string        `json:"redacted_fields"`
+	Customer       *redactedRecord `json:"customer"`
+}
+
+// TestRedactionByRoleAndOwnership reads a customer and a deal owned by the
+// agent, by another agent and by nobody as every role, through the detail,
+// and list endpoints
+func TestRedactionByRoleAndOwnership(t *testing.T) {
+	s := newServer(t)
+	customerFields, dealFields := []string{"notes"}, []string{"amount", "notes"}
+	other := agent.ID + 1
+	owners := map[string]*uint{"own": &agent.ID, "other's": &other, "unowned": nil}
+	customers := map[string]models.Customer{}
+	deals := map[string]models.Deal{}
+	for _, ownership := range []string{"own", "other's", "unowned"} {
+		owner := owners[ownership]
+		customer := s.Factory.Customer(t, func(c *models.Customer) {
+			c.Name, c.Notes, c.AssignedTo = "Nakheel "+ownership, "Discount agreed off the record", owner
+		})
+		deal := s.Factory.Deal(t, customer, func(d *models.Deal) { d.Title, d.OwnerID = "Nakheel "+ownership, owner })
+		s.Factory.Note(t, customer, func(n *models.Note) { n.DealID = &deal.ID })
+		customers[ownership], deals[ownership] = customer, deal
+	}
+
+	for _, tc := range []struct {
+		as       caller
+		redacted map[string]bool // By ownership
+	}{
+		{admin, map[string]bool{}},
+		{manager, map[string]bool{}},
+		{agent, map[string]bool{"other's": true, "unowned": true}},
+	} {
+		for _, ownership := range []string{"own", "other's", "unowned"} {
+			hidden := tc.redacted[ownership]
+			customer, deal := customers[ownership], deals[ownership]
+			t.Run(fmt.Sprintf("%s/%s", tc.as.Role, ownership), func(t *testing.T) {
+				// check compares the redacted fields of a record and the values
+				// of those the endpoint serializes
+				check := func(endpoint string, record redactedRecord, fields []string, values ...string) {
+					t.Helper()
+					var want []string
+					if hidden {
+						want = fields
+					}
+					if !slices.Equal(record.RedactedFields, want) {
+						t.Errorf("%s: redacted_fields = %v, want %v", endpoint, record.RedactedFields, want)
+					}
+					if slices.Contains(values, "amount") && (record.Amount == nil) != hidden {
+						t.Errorf("%s: amount = %v, hidden = %v", endpoint, record.Amount, hidden)
+					}
+					if slices.Contains(values, "notes") && (record.Notes == nil) != hidden {
+						t.Errorf("%s: notes = %v, hidden = %v", endpoint, record.Notes, hidden)
+					}
+				}
+
+				var detail redactedRecord
+				decode(t, s.get(t, tc.as, fmt.Sprintf("/admin/customers/%d", customer.ID)), &detail)
+				check("customer detail", detail, customerFields, "notes")
+
+				var customerList struct{ Data []redactedRecord }
+				decode(t, s.get(t, tc.as, "/admin/customers?search="+url.QueryEscape(customer.Name)), &customerList)
+				if len(customerList.Data) != 1 {
+					t.Fatalf("customer list: %d customers", len(customerList.Data))
+				}
+				check("customer list", customerList.Data[0], customerFields, "notes")
+
+				// The deal detail embeds its notes and its customer
+				var dealDetail redactedRecord
+				decode(t, s.get(t, tc.as, fmt.Sprintf("/admin/deals/%d", deal.ID)), &dealDetail)
+				check("deal detail", dealDetail, dealFields, "amount", "notes")
+				if dealDetail.Customer == nil {
+					t.Fatal("deal detail has no customer")
+				}
+				check("deal customer", *dealDetail.Customer, customerFields, "notes")
+
+				var dealList struct{ Data []redactedRecord }
+				decode(t, s.get(t, tc.as, fmt.Sprintf("/admin/deals?customer_id=%d", customer.ID)), &dealList)
+				if len(dealList.Data) != 1 {
+					t.Fatalf("deal list: %d deals", len(dealList.Data))
+				}
+				check("deal list", dealList.Data[0], dealFields, "amount")
+			})
+		}
+	}
+}
+
+// get serves a GET as the caller, failing the test unless it succeeds
+func (s *server) get(t testing.TB, as caller, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := s.do(t, as, http.MethodGet, path, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s as %s: status %d: %s", path, as.Role, rec.Code, rec.Body)
+	}
+	return rec
+}
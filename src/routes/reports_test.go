@@ -0,0 +1,624 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/reportrollup"
+	"gorm.io/gorm"
+)
+
+// TestReportPeriodValidation checks that every report with a period
+// refuses a malformed from or to and a from after to, as it refuses an
+// unknown range token
+func TestReportPeriodValidation(t *testing.T) {
+	s := newServer(t)
+	for _, report := range []string{
+		"/admin/reports/segments?tags=vip&",
+		"/admin/reports/revenue?",
+		"/admin/reports/outcomes?",
+		"/admin/reports/email-engagement?",
+		"/admin/reports/email-deliverability?",
+	} {
+		for _, tc := range []struct {
+			query string
+			want  int
+			code  string
+		}{
+			{"", http.StatusOK, ""},
+			{"from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z", http.StatusOK, ""},
+			{"from=2025-01-01T00:00:00Z&to=2025-01-01T00:00:00Z", http.StatusOK, ""},
+			{"from=2025-01-01", http.StatusBadRequest, "INVALID_DATE"},
+			{"to=tomorrow", http.StatusBadRequest, "INVALID_DATE"},
+			{"from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z", http.StatusBadRequest, "INVALID_DATE_RANGE"},
+			{"from=2999-01-01T00:00:00Z", http.StatusBadRequest, "INVALID_DATE_RANGE"},
+			{"range=last_century", http.StatusBadRequest, "UNKNOWN_DATE_RANGE"},
+		} {
+			path := report + tc.query
+			t.Run(path, func(t *testing.T) {
+				rec := s.do(t, admin, http.MethodGet, path, nil)
+				if rec.Code != tc.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+				}
+				if tc.code != "" && !strings.Contains(rec.Body.String(), `"code":"`+tc.code+`"`) {
+					t.Errorf("body = %s, want code %s", rec.Body, tc.code)
+				}
+			})
+		}
+	}
+}
+
+// TestRevenueReport buckets won deals by close date at midnight in the
+// calendar's time zone, Asia/Riyadh (UTC+3), so deals closed late on the
+// last day of a month in UTC count toward the next month
+func TestRevenueReport(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	riyadh, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(value string) time.Time {
+		closed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return closed
+	}
+	owner, other := agent.ID, manager.ID
+	won := func(closed string, amount float64, overrides ...func(*models.Deal)) models.Deal {
+		closedAt := utc(closed)
+		return s.Factory.Deal(t, customer, append([]func(*models.Deal){func(d *models.Deal) {
+			d.Stage, d.Amount, d.Currency, d.OwnerID, d.ActualCloseDate = models.DealStageClosedWon, amount, "USD", &owner, &closedAt
+		}}, overrides...)...)
+	}
+	won("2025-01-31T20:59:00Z", 1000) // 23:59 on 31 January in Riyadh
+	won("2025-01-31T21:00:00Z", 3000) // Midnight starting 1 February
+	won("2025-02-28T22:00:00Z", 500)  // 01:00 on 1 March
+	won("2024-12-31T20:59:00Z", 9000) // Still December, before the period
+	won("2025-03-31T21:00:00Z", 9000) // Already April, after the period
+	won("2025-02-14T09:00:00Z", 1500, func(d *models.Deal) { d.OwnerID, d.Currency = &other, "SAR" })
+	won("2025-03-20T09:00:00Z", 700, func(d *models.Deal) { archived := utc("2025-03-25T09:00:00Z"); d.ArchivedAt = &archived })
+	won("2025-01-15T09:00:00Z", 8000, func(d *models.Deal) { d.Stage = models.DealStageClosedLost })
+	deleted := won("2025-02-10T09:00:00Z", 2000)
+	if err := s.DB.Delete(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	type bucket struct {
+		start      string
+		count      int64
+		total, avg float64
+	}
+	const period = "from=2024-12-31T21:00:00Z&to=2025-03-31T20:59:59Z"
+	for _, tc := range []struct {
+		name  string
+		query string
+		want  []bucket
+	}{
+		{"monthly", period, []bucket{
+			{"2025-01-01", 1, 1000, 1000},
+			{"2025-02-01", 2, 4500, 2250},
+			{"2025-03-01", 2, 1200, 600},
+		}},
+		{"by owner", period + fmt.Sprintf("&owner_id=%d", other), []bucket{
+			{"2025-01-01", 0, 0, 0},
+			{"2025-02-01", 1, 1500, 1500},
+			{"2025-03-01", 0, 0, 0},
+		}},
+		{"by currency", period + "&currency=usd", []bucket{
+			{"2025-01-01", 1, 1000, 1000},
+			{"2025-02-01", 1, 3000, 3000},
+			{"2025-03-01", 2, 1200, 600},
+		}},
+		// Weeks start on Monday; the second week has no deals
+		{"weekly", "interval=week&from=2025-01-26T21:00:00Z&to=2025-02-09T20:59:59Z", []bucket{
+			{"2025-01-27", 2, 4000, 2000},
+			{"2025-02-03", 0, 0, 0},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var report struct {
+				Timezone string
+				Buckets  []struct {
+					Start           time.Time
+					Count           int64
+					TotalAmount     float64 `json:"total_amount"`
+					AverageDealSize float64 `json:"average_deal_size"`
+				}
+			}
+			decode(t, s.get(t, admin, "/admin/reports/revenue?"+tc.query), &report)
+			if report.Timezone != "Asia/Riyadh" {
+				t.Errorf("timezone = %q", report.Timezone)
+			}
+			if len(report.Buckets) != len(tc.want) {
+				t.Fatalf("buckets = %+v, want %+v", report.Buckets, tc.want)
+			}
+			for i, want := range tc.want {
+				got := report.Buckets[i]
+				start, err := time.ParseInLocation(time.DateOnly, want.start, riyadh)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.Start.Equal(start) || got.Count != want.count || got.TotalAmount != want.total || got.AverageDealSize != want.avg {
+					t.Errorf("bucket %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestReportBuckets checks that every bucketed report refuses a period of
+// too many buckets with 422, and returns a page of its buckets equal to
+// that part of the unpaged report
+func TestReportBuckets(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t, func(c *models.Customer) { c.CreatedAt = time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC) })
+	for i, stage := range []models.DealStage{models.DealStageClosedWon, models.DealStageProposal, models.DealStageClosedWon, models.DealStageClosedLost} {
+		at := time.Date(2025, 1, 6+9*i, 9, 0, 0, 0, time.UTC)
+		s.Factory.Deal(t, customer, func(d *models.Deal) {
+			d.Stage, d.Amount, d.Currency, d.CreatedAt = stage, float64(1000*(i+1)), "USD", at
+			if stage == models.DealStageClosedWon {
+				d.ActualCloseDate = &at
+			}
+		})
+		s.Factory.Customer(t, func(c *models.Customer) { c.Status, c.CreatedAt = models.CustomerStatusProspect, at })
+	}
+
+	t.Run("too many buckets", func(t *testing.T) {
+		for _, path := range []string{
+			"/admin/reports/revenue?interval=week&from=2015-01-01T00:00:00Z&to=2025-12-31T00:00:00Z",
+			"/admin/reports/funnel?interval=week&created_from=2015-01-01T00:00:00Z&created_to=2025-12-31T00:00:00Z",
+		} {
+			rec := s.get(t, admin, path)
+			if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "TOO_MANY_BUCKETS") {
+				t.Errorf("%s: status = %d: %s", path, rec.Code, rec.Body)
+			}
+		}
+		// The longest period allowed is within the cap
+		rec := s.get(t, admin, "/admin/reports/revenue?interval=month&from=2000-01-15T00:00:00Z&to=2025-12-31T00:00:00Z")
+		if rec.Code != http.StatusOK {
+			t.Errorf("312 months: status = %d: %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("funnel needs a window", func(t *testing.T) {
+		for path, code := range map[string]string{
+			"/admin/reports/funnel?interval=week&created_from=2025-01-01T00:00:00Z":                                "INVALID_DATE",
+			"/admin/reports/funnel?interval=day&created_from=2025-01-01T00:00:00Z&created_to=2025-02-01T00:00:00Z": "INVALID_INTERVAL",
+		} {
+			rec := s.get(t, admin, path)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), code) {
+				t.Errorf("%s: status = %d: %s", path, rec.Code, rec.Body)
+			}
+		}
+	})
+
+	type page struct {
+		Page         int
+		PageSize     int `json:"page_size"`
+		TotalBuckets int `json:"total_buckets"`
+		TotalPages   int `json:"total_pages"`
+	}
+	t.Run("revenue pages", func(t *testing.T) {
+		const period = "/admin/reports/revenue?from=2024-12-31T21:00:00Z&to=2025-03-31T20:59:59Z"
+		var all struct {
+			page
+			Buckets []json.RawMessage
+		}
+		decode(t, s.get(t, admin, period), &all)
+		if len(all.Buckets) != 3 || all.page != (page{1, 520, 3, 1}) {
+			t.Fatalf("unpaged = %+v", all)
+		}
+		var paged []json.RawMessage
+		for n, want := range []page{{1, 2, 3, 2}, {2, 2, 3, 2}, {3, 2, 3, 2}} {
+			var report struct {
+				page
+				Buckets []json.RawMessage
+			}
+			rec := s.get(t, admin, fmt.Sprintf("%s&page=%d&page_size=2", period, n+1))
+			decode(t, rec, &report)
+			if report.page != want || rec.Header().Get("X-Total-Count") != "3" {
+				t.Errorf("page %d = %+v, X-Total-Count %q", n+1, report.page, rec.Header().Get("X-Total-Count"))
+			}
+			paged = append(paged, report.Buckets...)
+		}
+		if !reflect.DeepEqual(paged, all.Buckets) {
+			t.Errorf("pages = %s, want %s", paged, all.Buckets)
+		}
+	})
+
+	t.Run("funnel buckets", func(t *testing.T) {
+		const window = "/admin/reports/funnel?interval=week&created_from=2024-12-31T21:00:00Z&created_to=2025-02-28T20:59:59Z"
+		type funnel struct {
+			Counts []struct {
+				Name  string
+				Count int64
+			}
+		}
+		type bucket struct {
+			Start     time.Time
+			Customers funnel
+			Deals     funnel
+		}
+		var all struct {
+			page
+			Customers funnel
+			Deals     funnel
+			Buckets   []bucket
+		}
+		decode(t, s.get(t, admin, window), &all)
+		if all.TotalBuckets != 9 || len(all.Buckets) != 9 {
+			t.Fatalf("weeks = %d, buckets = %d", all.TotalBuckets, len(all.Buckets))
+		}
+
+		// Every record falls into one week
+		sum := func(pick func(bucket) funnel, overall funnel) {
+			t.Helper()
+			for i, count := range overall.Counts {
+				var total int64
+				for _, b := range all.Buckets {
+					total += pick(b).Counts[i].Count
+				}
+				if total != count.Count {
+					t.Errorf("%s: weeks sum to %d, want %d", count.Name, total, count.Count)
+				}
+			}
+		}
+		sum(func(b bucket) funnel { return b.Customers }, all.Customers)
+		sum(func(b bucket) funnel { return b.Deals }, all.Deals)
+
+		var paged struct {
+			page
+			Buckets []bucket
+		}
+		decode(t, s.get(t, admin, window+"&page=2&page_size=4"), &paged)
+		if paged.page != (page{2, 4, 9, 3}) || !reflect.DeepEqual(paged.Buckets, all.Buckets[4:8]) {
+			t.Errorf("page 2 = %+v, want %+v", paged, all.Buckets[4:8])
+		}
+	})
+}
+
+// TestReportSnapshots checks that the funnel, revenue and segments reports
+// read from the snapshots equal the reports computed from the records,
+// after a rebuild, after changes to snapshot days and after the
+// incremental rollup that recomputes those days
+func TestReportSnapshots(t *testing.T) {
+	s := newServer(t)
+	riyadh, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Records over three weeks, some created and closed after 21:00 UTC,
+	// on the next day in Riyadh
+	vip, enterprise := s.Factory.Tag(t), s.Factory.Tag(t)
+	statuses := []models.CustomerStatus{models.CustomerStatusLead, models.CustomerStatusActive, models.CustomerStatusChurned}
+	owners := []*uint{&agent.ID, &manager.ID, nil}
+	var customers []models.Customer
+	var deals []models.Deal
+	for i := 0; i < 21; i++ {
+		at := factory.Epoch.AddDate(0, 0, i).Add(time.Duration(i%4) * 4 * time.Hour)
+		customer := s.Factory.Customer(t, func(c *models.Customer) { c.Status, c.CreatedAt = statuses[i%len(statuses)], at })
+		switch i % 3 {
+		case 0:
+			s.Factory.TagCustomer(t, customer, vip)
+		case 1:
+			s.Factory.TagCustomer(t, customer, vip)
+			s.Factory.TagCustomer(t, customer, enterprise)
+		}
+		customers = append(customers, customer)
+		for j, stage := range []models.DealStage{models.DealStageClosedWon, models.DealStageClosedLost, models.DealStageProposal} {
+			closed := at.Add(time.Duration(j+1) * 30 * time.Hour)
+			deals = append(deals, s.Factory.Deal(t, customer, func(d *models.Deal) {
+				d.Stage, d.OwnerID, d.CreatedAt = stage, owners[(i+j)%len(owners)], at
+				d.Amount = float64(100*i + 10*j + 5)
+				if i%5 == 0 {
+					d.Currency = "SAR"
+				}
+				if stage != models.DealStageProposal {
+					d.ActualCloseDate = &closed
+				}
+			}))
+		}
+	}
+
+	paths := []string{
+		"/admin/reports/funnel",
+		// Bounds inside days, so their days are computed from the records
+		"/admin/reports/funnel?created_from=2025-01-08T10:00:00Z&created_to=2025-01-20T22:30:00Z",
+		"/admin/reports/funnel?created_from=2025-01-09T21:00:00Z&created_to=2025-01-19T20:59:59Z",
+		"/admin/reports/funnel?interval=week&created_from=2025-01-05T21:00:00Z&created_to=2025-01-26T20:59:59Z",
+		"/admin/reports/revenue?from=2024-12-31T21:00:00Z&to=2025-03-31T20:59:59Z",
+		"/admin/reports/revenue?interval=week&from=2025-01-05T21:00:00Z&to=2025-02-09T20:59:59Z&currency=usd",
+		fmt.Sprintf("/admin/reports/revenue?from=2024-12-31T21:00:00Z&to=2025-03-31T20:59:59Z&owner_id=%d", manager.ID),
+		fmt.Sprintf("/admin/reports/segments?tag_ids=%d,%d&from=2025-01-07T12:00:00Z&to=2025-01-25T12:00:00Z", vip.ID, enterprise.ID),
+		fmt.Sprintf("/admin/reports/segments?tag_ids=%d&from=2024-12-31T21:00:00Z&to=2025-03-31T20:59:59Z", vip.ID),
+	}
+	// fetch returns the reports and whether each read the snapshots
+	fetch := func() ([]map[string]interface{}, []bool) {
+		t.Helper()
+		reports := make([]map[string]interface{}, len(paths))
+		snapshots := make([]bool, len(paths))
+		for i, path := range paths {
+			rec := s.get(t, admin, path)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d: %s", path, rec.Code, rec.Body)
+			}
+			decode(t, rec, &reports[i])
+			holder := reports[i]
+			if meta, ok := holder["meta"].(map[string]interface{}); ok {
+				holder = meta
+			}
+			snapshots[i] = holder["snapshot"] != nil
+			delete(holder, "snapshot")
+		}
+		return reports, snapshots
+	}
+	compare := func(stage string, got, want []map[string]interface{}) {
+		t.Helper()
+		for i := range paths {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Errorf("%s: %s\n got = %v\nwant = %v", stage, paths[i], got[i], want[i])
+			}
+		}
+	}
+	queued := func() int64 {
+		t.Helper()
+		var n int64
+		if err := s.DB.Model(&models.ReportRollupDay{}).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	live, snapshots := fetch()
+	for i, snapshot := range snapshots {
+		if snapshot {
+			t.Errorf("%s read snapshots before the first rollup", paths[i])
+		}
+	}
+
+	rec := s.do(t, admin, http.MethodPost, "/admin/maintenance/rollup", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rollup: status = %d: %s", rec.Code, rec.Body)
+	}
+	var summary reportrollup.Summary
+	decode(t, rec, &summary)
+	if !summary.Full || summary.Timezone != "Asia/Riyadh" {
+		t.Errorf("summary = %+v", summary)
+	}
+	if n := s.Count("report_deal_days"); n == 0 {
+		t.Fatal("no deal days rolled up")
+	}
+
+	rolled, snapshots := fetch()
+	for i, snapshot := range snapshots {
+		if !snapshot {
+			t.Errorf("%s did not read snapshots after the rollup", paths[i])
+		}
+	}
+	compare("rolled up", rolled, live)
+
+	// Changes to snapshot days queue the days until the next rollup
+	closed := time.Date(2025, 1, 12, 22, 0, 0, 0, time.UTC)
+	if err := s.DB.Model(&deals[4]).Updates(map[string]interface{}{"stage": models.DealStageClosedWon, "actual_close_date": closed}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Model(&deals[9]).Update("owner_id", manager.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Model(&customers[6]).Update("status", models.CustomerStatusActive).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Delete(&customers[10]).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Exec("DELETE FROM customer_tags WHERE customer_id = ? AND tag_id = ?", customers[12].ID, vip.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	s.Factory.TagCustomer(t, customers[14], enterprise)
+	s.Factory.Deal(t, customers[2], func(d *models.Deal) {
+		won := time.Date(2025, 1, 16, 8, 0, 0, 0, time.UTC)
+		d.Stage, d.Amount, d.OwnerID, d.CreatedAt, d.ActualCloseDate = models.DealStageClosedWon, 777, &agent.ID, time.Date(2025, 1, 9, 23, 0, 0, 0, time.UTC), &won
+	})
+	if queued() == 0 {
+		t.Fatal("changes queued no days")
+	}
+	changed, _ := fetch()
+
+	if _, err := reportrollup.NewRunner(s.DB, riyadh).Run(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if n := queued(); n != 0 {
+		t.Errorf("%d days still queued after the rollup", n)
+	}
+	incremental, _ := fetch()
+
+	// Without a rollup state the reports are computed from the records
+	if err := s.DB.Exec("DELETE FROM report_rollup_state").Error; err != nil {
+		t.Fatal(err)
+	}
+	direct, snapshots := fetch()
+	for i, snapshot := range snapshots {
+		if snapshot {
+			t.Errorf("%s read snapshots without a rollup state", paths[i])
+		}
+	}
+	compare("changed", changed, direct)
+	compare("incremental", incremental, direct)
+}
+
+// TestRollupQueuesEachDayOnce checks that many changes to one snapshot day
+// queue it once, and that changes made once a rollup claimed the day queue
+// it again
+func TestRollupQueuesEachDayOnce(t *testing.T) {
+	s := newServer(t)
+	riyadh, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deal := s.Factory.Deal(t, s.Factory.Customer(t))
+	runner := reportrollup.NewRunner(s.DB, riyadh)
+	if _, err := runner.Run(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	days := func() []models.ReportRollupDay {
+		t.Helper()
+		var days []models.ReportRollupDay
+		if err := s.DB.Order("id").Find(&days).Error; err != nil {
+			t.Fatal(err)
+		}
+		return days
+	}
+
+	for i := 1; i <= 20; i++ {
+		path := fmt.Sprintf("/admin/deals/%d", deal.ID)
+		if rec := s.do(t, admin, http.MethodPatch, path, map[string]interface{}{"amount": i * 100}); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+	}
+	queued := days()
+	if len(queued) != 1 || queued[0].Claimed {
+		t.Fatalf("queued days = %+v, want the deal's day once", queued)
+	}
+
+	// As if a rollup claimed the day and is still running
+	if err := s.DB.Exec("UPDATE report_rollup_days SET claimed = TRUE").Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := s.DB.Model(&deal).Update("amount", i).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	queued = days()
+	if len(queued) != 2 || !queued[0].Claimed || queued[1].Claimed || !queued[1].Day.Equal(queued[0].Day) {
+		t.Fatalf("queued days = %+v, want the claimed day and the day queued again", queued)
+	}
+
+	if _, err := runner.Run(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if queued := days(); len(queued) != 0 {
+		t.Errorf("queued days after the rollup = %+v", queued)
+	}
+}
+
+// TestOverviewPartialResults holds a lock on one table while the overview
+// report is computed under a short deadline. A locked optional section
+// times out and is named in partial_errors without holding up the others;
+// a locked critical section fails the report.
+func TestOverviewPartialResults(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	s.Factory.Deal(t, customer)
+	s.Factory.Activity(t, customer)
+
+	const deadline = 500 * time.Millisecond
+	for _, tc := range []struct {
+		locked  string
+		status  int
+		partial []string
+	}{
+		{"", http.StatusOK, nil},
+		{"activities", http.StatusOK, []string{"activities"}},
+		{"customers", http.StatusInternalServerError, nil},
+	} {
+		t.Run("locked "+tc.locked, func(t *testing.T) {
+			if tc.locked != "" {
+				lock := s.DB.Begin()
+				defer lock.Rollback()
+				if err := lock.Exec("LOCK TABLE " + tc.locked + " IN ACCESS EXCLUSIVE MODE").Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), deadline)
+			defer cancel()
+			req := httptest.NewRequest(http.MethodGet, "/admin/reports/overview", nil).WithContext(ctx)
+			start := time.Now()
+			rec := s.serve(t, admin, req)
+			if elapsed := time.Since(start); elapsed > 2*deadline {
+				t.Errorf("answered after %v, want the deadline to stop waiting sections", elapsed)
+			}
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var report struct {
+				Customers     struct{ Total int64 }
+				Deals         struct{ Total int64 }
+				PartialErrors []string `json:"partial_errors"`
+			}
+			decode(t, rec, &report)
+			if !reflect.DeepEqual(report.PartialErrors, tc.partial) {
+				t.Errorf("partial_errors = %v, want %v", report.PartialErrors, tc.partial)
+			}
+			if report.Customers.Total != 1 || report.Deals.Total != 1 {
+				t.Errorf("customers = %d, deals = %d, want the sections that were not locked", report.Customers.Total, report.Deals.Total)
+			}
+		})
+	}
+}
+
+// slowQueries marks the context of a request whose queries the callback
+// registered by TestOverviewConcurrency delays
+type slowQueries struct{}
+
+// TestOverviewConcurrency checks that computing the overview sections
+// concurrently answers faster than computing them one after another, with
+// every query of the request delayed so the sections dominate its latency
+func TestOverviewConcurrency(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	elapsed := func(concurrency int) time.Duration {
+		t.Helper()
+		s := newServer(t, func(cfg *config.Config) { cfg.ReportConcurrency = concurrency })
+		customer := s.Factory.Customer(t)
+		s.Factory.Deal(t, customer)
+		s.Factory.Activity(t, customer)
+		slow := func(db *gorm.DB) {
+			if db.Statement.Context.Value(slowQueries{}) != nil {
+				time.Sleep(delay)
+			}
+		}
+		if err := s.DB.Callback().Query().Before("gorm:query").Register("test:slow_query", slow); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DB.Callback().Row().Before("gorm:row").Register("test:slow_row", slow); err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/reports/overview", nil)
+		req = req.WithContext(context.WithValue(req.Context(), slowQueries{}, true))
+		start := time.Now()
+		rec := s.serve(t, admin, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("concurrency %d: status = %d: %s", concurrency, rec.Code, rec.Body)
+		}
+		return time.Since(start)
+	}
+
+	sequential := elapsed(1)
+	concurrent := elapsed(5)
+	if sequential < 5*delay {
+		t.Fatalf("sequential overview took %v, less than a delayed query per section", sequential)
+	}
+	if concurrent > sequential*6/10 {
+		t.Errorf("concurrent overview took %v, sequential %v", concurrent, sequential)
+	}
+	t.Logf("overview: %v sequential, %v concurrent", sequential, concurrent)
+}
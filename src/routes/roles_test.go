@@ -0,0 +1,71 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestCustomRoleAccess checks that routes restricted to built-in roles
+// admit a custom role holding their permissions, and refuse it otherwise
+func TestCustomRoleAccess(t *testing.T) {
+	s := newServer(t)
+	ops := caller{ID: 4, Role: "ops"}
+
+	rec := s.do(t, admin, http.MethodPost, "/admin/roles", map[string]interface{}{
+		"name":        ops.Role,
+		"permissions": []string{models.PermissionRead, models.PermissionWrite, models.PermissionDelete, models.PermissionManageAll},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create role: status = %d: %s", rec.Code, rec.Body)
+	}
+	var role models.Role
+	decode(t, rec, &role)
+
+	check := func(stage string, path string, want int) {
+		t.Helper()
+		if rec := s.get(t, ops, path); rec.Code != want {
+			t.Errorf("%s: %s: status = %d, want %d: %s", stage, path, rec.Code, want, rec.Body)
+		}
+	}
+	// Manager permissions pass admin and manager routes, not admin only ones
+	check("manager permissions", "/admin/assignment-rules", http.StatusOK)
+	check("manager permissions", "/admin/audit-logs", http.StatusForbidden)
+	if rec := s.get(t, manager, "/admin/audit-logs"); rec.Code != http.StatusForbidden {
+		t.Errorf("manager: /admin/audit-logs: status = %d", rec.Code)
+	}
+
+	rec = s.do(t, admin, http.MethodPut, fmt.Sprintf("/admin/roles/%d", role.ID), map[string]interface{}{
+		"permissions": append(role.Permissions, models.PermissionAdminister),
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update role: status = %d: %s", rec.Code, rec.Body)
+	}
+	check("admin permissions", "/admin/audit-logs", http.StatusOK)
+
+	// Without manage_all it passes neither
+	rec = s.do(t, admin, http.MethodPut, fmt.Sprintf("/admin/roles/%d", role.ID), map[string]interface{}{
+		"permissions": []string{models.PermissionRead, models.PermissionWrite, models.PermissionManageOwn, models.PermissionAdminister},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update role: status = %d: %s", rec.Code, rec.Body)
+	}
+	check("agent permissions", "/admin/assignment-rules", http.StatusForbidden)
+	check("agent permissions", "/admin/audit-logs", http.StatusForbidden)
+}
+
+// TestUnknownRole checks that a token whose role is not defined is
+// refused rather than granted nothing
+func TestUnknownRole(t *testing.T) {
+	s := newServer(t)
+	for _, role := range []string{"superuser", "Admin"} {
+		rec := s.do(t, caller{ID: 6, Role: role}, http.MethodGet, "/admin/customers", nil)
+		var refused struct{ Code string }
+		decode(t, rec, &refused)
+		if rec.Code != http.StatusForbidden || refused.Code != "UNKNOWN_ROLE" {
+			t.Errorf("%s: status = %d: %s", role, rec.Code, rec.Body)
+		}
+	}
+}
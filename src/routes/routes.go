@@ -1,16 +1,70 @@
 package routes
 
 import (
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/anonymize"
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/SalehAlobaylan/CRM-Service/src/buildinfo"
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
+	"github.com/SalehAlobaylan/CRM-Service/src/calendarfeed"
+	"github.com/SalehAlobaylan/CRM-Service/src/companies"
 	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/consistency"
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/dealdefaults"
+	"github.com/SalehAlobaylan/CRM-Service/src/deletion"
+	"github.com/SalehAlobaylan/CRM-Service/src/emaildelivery"
+	"github.com/SalehAlobaylan/CRM-Service/src/emailtracking"
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/fallback"
 	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
 	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
 	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/preview"
+	"github.com/SalehAlobaylan/CRM-Service/src/query"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/SalehAlobaylan/CRM-Service/src/reportrollup"
+	"github.com/SalehAlobaylan/CRM-Service/src/roles"
+	"github.com/SalehAlobaylan/CRM-Service/src/search"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+	"github.com/SalehAlobaylan/CRM-Service/src/stages"
+	"github.com/SalehAlobaylan/CRM-Service/src/telephony"
+	"github.com/SalehAlobaylan/CRM-Service/src/tracking"
+	"github.com/SalehAlobaylan/CRM-Service/src/webhooks"
+	"github.com/SalehAlobaylan/CRM-Service/src/workload"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// Services holds long-lived background services used by middleware and handlers
+type Services struct {
+	Authenticators  auth.Chain // Bearer token providers of the admin API, tried in order
+	ActivityTracker *tracking.UserActivityTracker
+	RecentViews     *tracking.RecentViewRecorder
+	DeadLetters     *deadletter.Queue
+	Fallbacks       *fallback.Writer
+	SlowQueries     *database.SlowQueryLog
+	Consistency     *consistency.Runner
+	Rollups         *reportrollup.Runner
+	Deletions       *deletion.Runner
+	Exports         *exports.Manager
+	ListPrefetch    *query.Prefetcher
+	Calendar        *businesstime.Service
+	ReadRouter      *database.ReadRouter
+	Quotas          *quota.Tracker
+	Roles           *roles.Service
+	Stages          *stages.Service
+	Security        *security.Monitor
+	Webhooks        *webhooks.Dispatcher
+	Search          search.Indexer // External search engine, nil to search Postgres
+	Previews        preview.Lengths
+	Workloads       *workload.Limiter // Slots of expensive endpoint classes, shared with async jobs
+}
+
 // SetupRouter creates and configures the Gin router
-func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
+func SetupRouter(db *gorm.DB, cfg *config.Config, services *Services) (*gin.Engine, error) {
 	// Set Gin mode
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
@@ -18,54 +72,251 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 
 	router := gin.New()
 
+	// Trailing-slash and case variants are served by middleware.NormalizePath
+	// rather than redirected; unmatched paths and methods get JSON errors
+	router.RedirectTrailingSlash = false
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(middleware.RouteNotFound())
+	router.NoMethod(middleware.MethodNotAllowed())
+
+	// Each route group gets its own CORS policy: the admin API is limited to
+	// the console origins, the public endpoints are open to any listed site
+	adminCORS, err := middleware.CORS(cfg.CORSAllowedOrigins, cfg.CORSAllowCredentials)
+	if err != nil {
+		return nil, err
+	}
+	publicCORS, err := middleware.CORS(cfg.CORSPublicAllowedOrigins, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rolling request counts for /status, taken outside Recovery so
+	// panics count as errors
+	requestStats := middleware.NewRequestStats(5*time.Minute, 60)
+
 	// Global middleware
 	router.Use(middleware.RequestID())
+	router.Use(middleware.VersionHeader(buildinfo.Get().Version))
+	if cfg.StatusEnabled {
+		router.Use(middleware.CountRequests(requestStats))
+	}
 	router.Use(middleware.Recovery())
 	router.Use(middleware.StructuredLogger())
-	router.Use(middleware.CORS(cfg.CORSAllowedOrigins))
+	router.Use(middleware.Locale())
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler()
-	customerHandler := handlers.NewCustomerHandler(db)
-	contactHandler := handlers.NewContactHandler(db)
-	dealHandler := handlers.NewDealHandler(db)
-	activityHandler := handlers.NewActivityHandler(db)
+	authHandler := handlers.NewAuthHandler(db)
+	emailDomains := companies.NewDomains(cfg.FreeEmailProviders)
+	customerHandler := handlers.NewCustomerHandler(db, services.ListPrefetch, emailDomains, services.Deletions, services.Quotas, services.Previews.For(preview.EndpointTimeline))
+	contactHandler := handlers.NewContactHandler(db, services.Quotas)
+	dealDefaults := dealdefaults.New(db, dealdefaults.Settings{
+		Currency:     cfg.DealDefaultCurrency,
+		Stage:        models.DealStage(cfg.DealDefaultStage),
+		Probability:  cfg.DealDefaultProbability,
+		TitlePattern: cfg.DealDefaultTitlePattern,
+	})
+	dealHandler := handlers.NewDealHandler(db, services.ListPrefetch, dealDefaults, services.Previews.For(preview.EndpointTimeline))
+	conversionHandler := handlers.NewConversionHandler(db, dealDefaults, services.Quotas)
+	activityHandler := handlers.NewActivityHandler(db, services.Calendar, services.Quotas)
+	activityStreamHandler := handlers.NewActivityStreamHandler(db, services.Quotas, handlers.ActivityStreamLimits{
+		BatchSize:   cfg.ActivityStreamBatchSize,
+		MaxBytes:    int64(cfg.ActivityStreamMaxMB) << 20,
+		MaxDuration: time.Duration(cfg.ActivityStreamMaxSeconds) * time.Second,
+	})
 	tagHandler := handlers.NewTagHandler(db)
-	reportHandler := handlers.NewReportHandler(db)
-	healthHandler := handlers.NewHealthHandler(db)
+	tagGroupHandler := handlers.NewTagGroupHandler(db)
+	noteHandler := handlers.NewNoteHandler(db)
+	reportHandler := handlers.NewReportHandler(db, services.Calendar, cfg.ReportConcurrency)
+	dashboardHandler := handlers.NewDashboardHandler(db, cfg.DashboardConcurrency)
+	companyHandler := handlers.NewCompanyHandler(db, emailDomains)
+	searchHandler := handlers.NewSearchHandler(db, search.Weights{
+		Text:       cfg.SearchWeightText,
+		Exact:      cfg.SearchWeightExact,
+		Prefix:     cfg.SearchWeightPrefix,
+		Owned:      cfg.SearchWeightOwned,
+		Recent:     cfg.SearchWeightRecent,
+		RecentDays: cfg.SearchRecentDays,
+	}, services.Search, services.Exports, cfg.IsDevelopment())
+	healthHandler := handlers.NewHealthHandler(db, services.Fallbacks)
+	statusHandler := handlers.NewStatusHandler(db, requestStats, cfg.StatusAPIKey)
+	userActivityHandler := handlers.NewUserActivityHandler(db)
+	recentViewHandler := handlers.NewRecentViewHandler(db)
+	deadLetterHandler := handlers.NewDeadLetterHandler(db, services.DeadLetters)
+	maintenanceHandler := handlers.NewMaintenanceHandler(services.SlowQueries)
+	smokeTestHandler := handlers.NewSmokeTestHandler(db, router, cfg.SmokeTestEnabled)
+	metaHandler := handlers.NewMetaHandler(db, services.Security)
+	consistencyHandler := handlers.NewConsistencyHandler(db, services.Consistency)
+	reportRollupHandler := handlers.NewReportRollupHandler(db, services.Rollups)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(db)
+	roleHandler := handlers.NewRoleHandler(db, services.Roles)
+	pipelineStageHandler := handlers.NewPipelineStageHandler(db, services.Stages)
+	checklistHandler := handlers.NewChecklistHandler(db)
+	activityOutcomeHandler := handlers.NewActivityOutcomeHandler(db)
+	changeReasonHandler := handlers.NewChangeReasonHandler(db)
+	securityHandler := handlers.NewSecurityHandler(db, services.Security)
+	assignmentRuleHandler := handlers.NewAssignmentRuleHandler(db)
+	userUnavailabilityHandler := handlers.NewUserUnavailabilityHandler(db)
+	jobHandler := handlers.NewJobHandler(db, services.Exports)
+	backupHandler := handlers.NewBackupHandler(db, services.Exports)
+	exportTemplateHandler := handlers.NewExportTemplateHandler(db)
+	auditLogHandler := handlers.NewAuditLogHandler(db)
+	annotationHandler := handlers.NewAnnotationHandler(db)
+	claimHandler := handlers.NewClaimHandler(db, cfg.ClaimDailyLimit)
+	holidayHandler := handlers.NewHolidayHandler(db, services.Calendar)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(db)
+	webhookHandler := handlers.NewWebhookHandler(db, services.Webhooks)
+	usageHandler := handlers.NewUsageHandler(services.Quotas)
+	emailTrackingHandler := handlers.NewEmailTrackingHandler(db, emailtracking.NewSigner(cfg.TrackingSecret(), time.Duration(cfg.EmailTrackingTokenTTLDays)*24*time.Hour), cfg.PublicBaseURL)
+	anonymizationHandler := handlers.NewAnonymizationHandler(db, anonymize.New(cfg.AnonymizationKey()))
+	calendarFeedSigner := calendarfeed.NewSigner(cfg.CalendarFeedKey())
+	calendarFeedHandler := handlers.NewCalendarFeedHandler(db, calendarFeedSigner, cfg.PublicBaseURL)
+
+	// Email delivery webhook providers, enabled by their signing keys
+	deliveryProviders := make(map[string]emaildelivery.Provider)
+	if cfg.EmailEventsSecret != "" {
+		deliveryProviders["generic"] = emaildelivery.NewGeneric(cfg.EmailEventsSecret)
+	}
+	if cfg.SendGridWebhookPublicKey != "" {
+		sendGrid, err := emaildelivery.NewSendGrid(cfg.SendGridWebhookPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		deliveryProviders["sendgrid"] = sendGrid
+	}
+	emailDeliveryHandler := handlers.NewEmailDeliveryHandler(db, deliveryProviders)
+
+	// Telephony call webhook, enabled by its signing secret or API key
+	extensions, err := telephony.ParseExtensions(cfg.TelephonyExtensions)
+	if err != nil {
+		return nil, err
+	}
+	telephonyHandler := handlers.NewTelephonyHandler(db, telephony.NewVerifier(cfg.TelephonyWebhookSecret, cfg.TelephonyAPIKey), extensions)
 
 	// Public routes (no auth required)
-	router.GET("/health", healthHandler.Health)
-	router.GET("/ready", healthHandler.Ready)
-	router.GET("/metrics", healthHandler.Metrics())
+	probes := router.Group("", adminCORS)
+	probes.GET("/health", healthHandler.Health)
+	probes.GET("/ready", healthHandler.Ready)
+	probes.GET("/metrics", healthHandler.Metrics())
+	if cfg.StatusEnabled {
+		probes.GET("/status", statusHandler.Status)
+	}
+
+	// Public email tracking links (signed tokens, rate-limited per IP)
+	public := router.Group("/public")
+	public.Use(publicCORS)
+	public.OPTIONS("/*path", middleware.Preflight)
+	public.Use(middleware.RateLimitByIP(cfg.PublicRateLimitPerMinute))
+	{
+		public.GET("/email/open/:token", emailTrackingHandler.TrackOpen)
+		public.GET("/email/click/:token", emailTrackingHandler.TrackClick)
+		public.GET("/email/unsubscribe/:token", emailTrackingHandler.Unsubscribe)
+	}
+
+	// Email and telephony provider webhooks (provider signatures, not
+	// rate-limited since providers batch and retry from few addresses)
+	integrations := router.Group("/integrations")
+	{
+		integrations.POST("/email/events", emailDeliveryHandler.IngestEvents)
+		integrations.POST("/telephony/calls", telephonyHandler.IngestCall)
+	}
+
+	// Admin routes (user JWT or service-account token required)
+	authenticate := middleware.Authenticate(services.Authenticators, db, cfg.ServiceAccountRateLimitPerMinute)
+
+	// Calendar feed; subscribed calendar clients pass a feed token as
+	// ?token= since they cannot send an Authorization header
+	router.GET("/admin/me/activities.ics", adminCORS, middleware.RateLimitByIP(cfg.PublicRateLimitPerMinute),
+		middleware.CalendarFeedAuth(calendarFeedSigner, authenticate), calendarFeedHandler.GetMyActivitiesCalendar)
 
-	// Admin routes (JWT auth required)
 	admin := router.Group("/admin")
-	admin.Use(middleware.JWTAuth(cfg.JWTSecret))
+	admin.Use(adminCORS)
+	admin.OPTIONS("/*path", middleware.Preflight)
+	admin.Use(authenticate)
+	admin.Use(middleware.TrackUserActivity(services.ActivityTracker))
+	admin.Use(middleware.ReadConsistency(services.ReadRouter))
+	admin.Use(middleware.FeatureFlagOverrides(cfg.IsDevelopment()))
 	{
 		// Auth endpoints
 		admin.GET("/me", authHandler.GetMe)
-		admin.GET("/me/activities", activityHandler.GetMyActivities)
+		admin.GET("/me/capabilities", authHandler.GetCapabilities)
+		admin.GET("/me/activities", middleware.TextPreview(services.Previews.For(preview.EndpointActivities)), activityHandler.GetMyActivities)
+		admin.POST("/me/calendar-token", middleware.NotInSandbox(), calendarFeedHandler.IssueCalendarToken)
+		admin.GET("/me/recent", recentViewHandler.GetMyRecent)
+		admin.GET("/me/dashboard", dashboardHandler.GetMyDashboard)
+		admin.PUT("/me/dashboard", dashboardHandler.UpdateMyDashboard)
+		admin.GET("/me/dashboard/data", middleware.WriteDeadline(time.Duration(cfg.ReportWriteTimeoutSeconds)*time.Second), middleware.LimitWorkload(services.Workloads, workload.ClassReports), dashboardHandler.GetMyDashboardData)
+
+		// Feature flags as they apply to the request
+		admin.GET("/meta/flags", metaHandler.ListFlags)
+		admin.GET("/meta/build", middleware.RequireRole(models.RoleAdmin), metaHandler.GetBuildInfo)
+		admin.GET("/meta/webhooks", middleware.RequireRole(models.RoleAdmin), metaHandler.ListWebhooks)
+		admin.GET("/meta/entities/:entity", metaHandler.GetEntitySchema)
+		admin.GET("/meta/filter-options", metaHandler.GetFilterOptions)
+
+		// Global search
+		admin.GET("/search", middleware.LimitWorkload(services.Workloads, workload.ClassSearch), searchHandler.Search)
+
+		// Record quota usage
+		admin.GET("/usage", middleware.RequireRole(models.RoleAdmin, models.RoleManager), middleware.NotInSandbox(), usageHandler.GetUsage)
+
+		// Audit log browsing and hash chain verification (admin only)
+		admin.GET("/audit-logs", middleware.RequireRole(models.RoleAdmin), auditLogHandler.ListAuditLogs)
+		admin.GET("/audit-logs/verify", middleware.RequireRole(models.RoleAdmin), auditLogHandler.VerifyAuditLogs)
+
+		// Daily digest of claimed records for managers
+		admin.GET("/claims/digest", middleware.RequireRole(models.RoleAdmin, models.RoleManager), claimHandler.GetClaimDigest)
+
+		// Operational annotations for dashboards and audits (admin only)
+		annotations := admin.Group("/annotations")
+		annotations.Use(middleware.RequireRole(models.RoleAdmin))
+		{
+			annotations.GET("", annotationHandler.ListAnnotations)
+			annotations.POST("", annotationHandler.CreateAnnotation)
+			annotations.PATCH("/:id", annotationHandler.UpdateAnnotation)
+		}
+
+		// User activity (last-seen) endpoints
+		admin.GET("/users/activity", middleware.RequireRole(models.RoleAdmin, models.RoleManager), userActivityHandler.ListUserActivity)
+
+		// User unavailability (skipped by lead assignment)
+		admin.GET("/users/:id/unavailability", userUnavailabilityHandler.ListUserUnavailability)
+		admin.POST("/users/:id/unavailability", middleware.RequireRole(models.RoleAdmin, models.RoleManager), userUnavailabilityHandler.CreateUserUnavailability)
+		admin.DELETE("/users/:id/unavailability/:windowId", middleware.RequireRole(models.RoleAdmin, models.RoleManager), userUnavailabilityHandler.DeleteUserUnavailability)
 
 		// Customer endpoints
 		customers := admin.Group("/customers")
 		{
-			customers.GET("", customerHandler.ListCustomers)
-			customers.POST("", middleware.RequirePermission(models.PermissionWrite), customerHandler.CreateCustomer)
-			customers.GET("/:id", customerHandler.GetCustomer)
+			customers.GET("", middleware.TextPreview(services.Previews.For(preview.EndpointCustomers)), customerHandler.ListCustomers)
+			customers.POST("", middleware.RequirePermission(models.PermissionWrite), middleware.RequireQuota(services.Quotas, quota.Customers), customerHandler.CreateCustomer)
+			customers.POST("/bulk-upsert", middleware.RequirePermission(models.PermissionWrite), middleware.LimitWorkload(services.Workloads, workload.ClassImports), customerHandler.BulkUpsertCustomers)
+			customers.POST("/import", middleware.RequirePermission(models.PermissionWrite), middleware.LimitWorkload(services.Workloads, workload.ClassImports), customerHandler.ImportCustomers)
+			customers.GET("/export", middleware.WriteDeadline(time.Duration(cfg.ReportWriteTimeoutSeconds)*time.Second), middleware.LimitWorkload(services.Workloads, workload.ClassExports), customerHandler.ExportCustomers)
+			customers.GET("/:id", middleware.RecordView(services.RecentViews, models.RecentViewCustomer), customerHandler.GetCustomer)
 			customers.PUT("/:id", middleware.RequirePermission(models.PermissionWrite), customerHandler.UpdateCustomer)
 			customers.PATCH("/:id", middleware.RequirePermission(models.PermissionWrite), customerHandler.PatchCustomer)
 			customers.DELETE("/:id", middleware.RequirePermission(models.PermissionDelete), customerHandler.DeleteCustomer)
+			customers.POST("/:id/archive", middleware.RequirePermission(models.PermissionWrite), customerHandler.ArchiveCustomer)
+			customers.POST("/:id/unarchive", middleware.RequirePermission(models.PermissionWrite), customerHandler.UnarchiveCustomer)
+			customers.POST("/:id/claim", middleware.RequirePermission(models.PermissionWrite), claimHandler.ClaimCustomer)
+			customers.POST("/:id/convert", middleware.RequirePermission(models.PermissionWrite), conversionHandler.ConvertCustomer)
+			customers.POST("/:id/anonymize", middleware.RequireRole(models.RoleAdmin), anonymizationHandler.AnonymizeCustomer)
+			customers.GET("/:id/history", customerHandler.GetCustomerHistory)
+			customers.GET("/:id/deal-defaults", dealHandler.GetDealDefaults)
 
 			// Nested contacts under customers
-			customers.GET("/:id/contacts", contactHandler.ListContacts)
-			customers.POST("/:id/contacts", middleware.RequirePermission(models.PermissionWrite), contactHandler.CreateContact)
+			customers.GET("/:id/contacts", middleware.TextPreview(services.Previews.For(preview.EndpointContacts)), contactHandler.ListContacts)
+			customers.POST("/:id/contacts", middleware.RequirePermission(models.PermissionWrite), middleware.RequireQuota(services.Quotas, quota.Contacts), contactHandler.CreateContact)
+			customers.POST("/:id/contacts/import", middleware.RequirePermission(models.PermissionWrite), middleware.LimitWorkload(services.Workloads, workload.ClassImports), contactHandler.ImportContacts)
 
 			// Customer tags
 			customers.POST("/:id/tags/:tagId", middleware.RequirePermission(models.PermissionWrite), tagHandler.AssignTagToCustomer)
 			customers.DELETE("/:id/tags/:tagId", middleware.RequirePermission(models.PermissionWrite), tagHandler.RemoveTagFromCustomer)
 		}
 
+		// Company endpoints (customers grouped by email domain)
+		admin.GET("/companies", companyHandler.ListCompanies)
+		admin.GET("/companies/:domain/customers", middleware.TextPreview(services.Previews.For(preview.EndpointCustomers)), companyHandler.ListCompanyCustomers)
+
 		// Contact endpoints (for update/delete by contact ID)
 		contacts := admin.Group("/contacts")
 		{
@@ -76,25 +327,46 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 		// Deal endpoints
 		deals := admin.Group("/deals")
 		{
-			deals.GET("", dealHandler.ListDeals)
-			deals.POST("", middleware.RequirePermission(models.PermissionWrite), dealHandler.CreateDeal)
-			deals.GET("/:id", dealHandler.GetDeal)
+			deals.GET("", middleware.TextPreview(services.Previews.For(preview.EndpointDeals)), dealHandler.ListDeals)
+			deals.POST("", middleware.RequirePermission(models.PermissionWrite), middleware.RequireQuota(services.Quotas, quota.Deals), dealHandler.CreateDeal)
+			deals.GET("/pipeline", middleware.TextPreview(services.Previews.For(preview.EndpointDeals)), dealHandler.GetPipeline)
+			deals.GET("/board", middleware.TextPreview(services.Previews.For(preview.EndpointDeals)), dealHandler.GetBoard)
+			deals.POST("/bulk-stage", middleware.RequirePermission(models.PermissionWrite), dealHandler.BulkUpdateStage)
+			deals.GET("/:id", middleware.RecordView(services.RecentViews, models.RecentViewDeal), dealHandler.GetDeal)
 			deals.PUT("/:id", middleware.RequirePermission(models.PermissionWrite), dealHandler.UpdateDeal)
 			deals.PATCH("/:id", middleware.RequirePermission(models.PermissionWrite), dealHandler.PatchDeal)
+			deals.PATCH("/:id/position", middleware.RequirePermission(models.PermissionWrite), dealHandler.MoveDeal)
 			deals.DELETE("/:id", middleware.RequirePermission(models.PermissionDelete), dealHandler.DeleteDeal)
+			deals.POST("/:id/archive", middleware.RequirePermission(models.PermissionWrite), dealHandler.ArchiveDeal)
+			deals.POST("/:id/unarchive", middleware.RequirePermission(models.PermissionWrite), dealHandler.UnarchiveDeal)
+			deals.GET("/:id/history", dealHandler.GetDealHistory)
+			deals.GET("/:id/checklist", dealHandler.GetDealChecklist)
+			deals.PUT("/:id/checklist/:key", middleware.RequirePermission(models.PermissionWrite), dealHandler.CheckDealChecklistItem)
+			deals.DELETE("/:id/checklist/:key", middleware.RequirePermission(models.PermissionWrite), dealHandler.UncheckDealChecklistItem)
 		}
 
 		// Activity endpoints
 		activities := admin.Group("/activities")
 		{
-			activities.GET("", activityHandler.ListActivities)
-			activities.POST("", middleware.RequirePermission(models.PermissionWrite), activityHandler.CreateActivity)
+			activities.GET("", middleware.TextPreview(services.Previews.For(preview.EndpointActivities)), activityHandler.ListActivities)
+			activities.POST("", middleware.RequirePermission(models.PermissionWrite), middleware.RequireQuota(services.Quotas, quota.Activities), activityHandler.CreateActivity)
+			activities.POST("/stream", middleware.RequirePermission(models.PermissionWrite), middleware.LimitWorkload(services.Workloads, workload.ClassImports), activityStreamHandler.StreamActivities)
 			activities.GET("/:id", activityHandler.GetActivity)
 			activities.PUT("/:id", middleware.RequirePermission(models.PermissionWrite), activityHandler.UpdateActivity)
+			activities.POST("/:id/claim", middleware.RequirePermission(models.PermissionWrite), claimHandler.ClaimActivity)
 			activities.PATCH("/:id", middleware.RequirePermission(models.PermissionWrite), activityHandler.PatchActivity)
+			activities.POST("/:id/complete", middleware.RequirePermission(models.PermissionWrite), activityHandler.CompleteActivity)
+			activities.POST("/:id/email-tracking", middleware.RequirePermission(models.PermissionWrite), emailTrackingHandler.IssueTrackingTokens)
 			activities.DELETE("/:id", middleware.RequirePermission(models.PermissionDelete), activityHandler.DeleteActivity)
 		}
 
+		// Telephony calls, including the queue of unmatched calls
+		telephonyCalls := admin.Group("/telephony/calls")
+		{
+			telephonyCalls.GET("", telephonyHandler.ListCalls)
+			telephonyCalls.POST("/:id/match", middleware.RequirePermission(models.PermissionWrite), telephonyHandler.MatchCall)
+		}
+
 		// Tag endpoints
 		tags := admin.Group("/tags")
 		{
@@ -104,12 +376,218 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			tags.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), tagHandler.DeleteTag)
 		}
 
+		// Tag group endpoints
+		tagGroups := admin.Group("/tag-groups")
+		{
+			tagGroups.GET("", tagGroupHandler.ListTagGroups)
+			tagGroups.POST("", middleware.RequireRole(models.RoleAdmin), tagGroupHandler.CreateTagGroup)
+			tagGroups.PUT("/:id", middleware.RequireRole(models.RoleAdmin), tagGroupHandler.UpdateTagGroup)
+			tagGroups.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), tagGroupHandler.DeleteTagGroup)
+		}
+
+		// Pipeline stage endpoints. Stages are process-wide, so sandbox
+		// requests cannot change them.
+		pipelineStages := admin.Group("/pipeline-stages")
+		{
+			pipelineStages.GET("", pipelineStageHandler.ListPipelineStages)
+			pipelineStages.POST("", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), pipelineStageHandler.CreatePipelineStage)
+			pipelineStages.PUT("/:id", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), pipelineStageHandler.UpdatePipelineStage)
+			pipelineStages.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), pipelineStageHandler.DeletePipelineStage)
+		}
+
+		// Close checklist deals complete before they are closed won
+		dealChecklist := admin.Group("/deal-checklist")
+		{
+			dealChecklist.GET("", checklistHandler.ListChecklist)
+			dealChecklist.POST("", middleware.RequireRole(models.RoleAdmin), checklistHandler.CreateChecklistItem)
+			dealChecklist.PUT("/:id", middleware.RequireRole(models.RoleAdmin), checklistHandler.UpdateChecklistItem)
+			dealChecklist.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), checklistHandler.DeleteChecklistItem)
+		}
+
+		// Outcome taxonomy activities of each type are completed with
+		activityOutcomes := admin.Group("/activity-outcomes")
+		{
+			activityOutcomes.GET("", activityOutcomeHandler.ListActivityOutcomes)
+			activityOutcomes.POST("", middleware.RequireRole(models.RoleAdmin), activityOutcomeHandler.CreateActivityOutcome)
+			activityOutcomes.PUT("/:id", middleware.RequireRole(models.RoleAdmin), activityOutcomeHandler.UpdateActivityOutcome)
+			activityOutcomes.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), activityOutcomeHandler.DeleteActivityOutcome)
+		}
+
+		// Fields whose changes must give a reason
+		admin.GET("/change-reasons", changeReasonHandler.GetChangeReasonPolicy)
+		admin.PUT("/change-reasons", middleware.RequireRole(models.RoleAdmin), changeReasonHandler.UpdateChangeReasonPolicy)
+
+		// Business calendar holidays
+		holidays := admin.Group("/holidays")
+		{
+			holidays.GET("", holidayHandler.ListHolidays)
+			holidays.POST("", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), holidayHandler.CreateHoliday)
+			holidays.PUT("/:id", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), holidayHandler.UpdateHoliday)
+			holidays.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), holidayHandler.DeleteHoliday)
+		}
+
+		// Exchange rates for deal currency conversions
+		exchangeRates := admin.Group("/exchange-rates")
+		{
+			exchangeRates.GET("", exchangeRateHandler.ListExchangeRates)
+			exchangeRates.POST("", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), exchangeRateHandler.CreateExchangeRate)
+			exchangeRates.PUT("/:id", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), exchangeRateHandler.UpdateExchangeRate)
+			exchangeRates.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox(), exchangeRateHandler.DeleteExchangeRate)
+		}
+
+		// Webhook subscriptions to deal and customer events
+		webhookSubscriptions := admin.Group("/webhooks")
+		webhookSubscriptions.Use(middleware.RequireRole(models.RoleAdmin))
+		{
+			webhookSubscriptions.GET("", webhookHandler.ListWebhookSubscriptions)
+			webhookSubscriptions.POST("", middleware.NotInSandbox(), webhookHandler.CreateWebhookSubscription)
+			webhookSubscriptions.GET("/:id", webhookHandler.GetWebhookSubscription)
+			webhookSubscriptions.PUT("/:id", middleware.NotInSandbox(), webhookHandler.UpdateWebhookSubscription)
+			webhookSubscriptions.DELETE("/:id", middleware.NotInSandbox(), webhookHandler.DeleteWebhookSubscription)
+			webhookSubscriptions.GET("/:id/deliveries", webhookHandler.ListWebhookDeliveries)
+			webhookSubscriptions.POST("/:id/test", middleware.NotInSandbox(), webhookHandler.TestWebhookSubscription)
+		}
+
 		// Report endpoints
 		reports := admin.Group("/reports")
+		reports.Use(middleware.WriteDeadline(time.Duration(cfg.ReportWriteTimeoutSeconds)*time.Second), middleware.LimitWorkload(services.Workloads, workload.ClassReports))
 		{
 			reports.GET("/overview", reportHandler.GetOverview)
+			reports.GET("/segments", reportHandler.GetSegments)
+			reports.GET("/funnel", reportHandler.GetFunnel)
+			reports.GET("/revenue", reportHandler.GetRevenue)
+			reports.GET("/outcomes", reportHandler.GetOutcomes)
+			reports.GET("/email-engagement", reportHandler.GetEmailEngagement)
+			reports.GET("/email-deliverability", reportHandler.GetEmailDeliverability)
+		}
+
+		// Async job endpoints
+		jobs := admin.Group("/jobs")
+		{
+			jobs.GET("", jobHandler.ListJobs)
+			jobs.POST("/exports", middleware.NotInSandbox(), jobHandler.CreateExportJob)
+			jobs.GET("/:id", jobHandler.GetJob)
+			jobs.POST("/:id/resume", middleware.NotInSandbox(), jobHandler.ResumeJob)
+			jobs.GET("/:id/download", middleware.WriteDeadline(time.Duration(cfg.ReportWriteTimeoutSeconds)*time.Second), jobHandler.DownloadJobArtifact)
+		}
+
+		// Bulk note endpoints
+		notes := admin.Group("/notes")
+		{
+			notes.POST("/import", middleware.RequirePermission(models.PermissionWrite), middleware.LimitWorkload(services.Workloads, workload.ClassImports), noteHandler.ImportNotes)
+			notes.GET("/export", middleware.WriteDeadline(time.Duration(cfg.ReportWriteTimeoutSeconds)*time.Second), middleware.LimitWorkload(services.Workloads, workload.ClassExports), noteHandler.ExportNotes)
+		}
+
+		// Export template endpoints
+		exportTemplates := admin.Group("/export-templates")
+		{
+			exportTemplates.GET("", exportTemplateHandler.ListExportTemplates)
+			exportTemplates.GET("/columns", exportTemplateHandler.ListExportColumns)
+			exportTemplates.GET("/:id", exportTemplateHandler.GetExportTemplate)
+			exportTemplates.POST("", middleware.RequireRole(models.RoleAdmin, models.RoleManager), exportTemplateHandler.CreateExportTemplate)
+			exportTemplates.PUT("/:id", middleware.RequireRole(models.RoleAdmin, models.RoleManager), exportTemplateHandler.UpdateExportTemplate)
+			exportTemplates.DELETE("/:id", middleware.RequireRole(models.RoleAdmin, models.RoleManager), exportTemplateHandler.DeleteExportTemplate)
+		}
+
+		// Dead-letter queue endpoints (admin only)
+		deadLetters := admin.Group("/dead-letters")
+		deadLetters.Use(middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox())
+		{
+			deadLetters.GET("", deadLetterHandler.ListDeadLetters)
+			deadLetters.POST("/:id/retry", deadLetterHandler.RetryDeadLetter)
+			deadLetters.DELETE("/:id", deadLetterHandler.DeleteDeadLetter)
+		}
+
+		// Lead assignment rule endpoints
+		assignmentRules := admin.Group("/assignment-rules")
+		{
+			assignmentRules.GET("", middleware.RequireRole(models.RoleAdmin, models.RoleManager), assignmentRuleHandler.ListAssignmentRules)
+			assignmentRules.POST("", middleware.RequireRole(models.RoleAdmin), assignmentRuleHandler.CreateAssignmentRule)
+			assignmentRules.PUT("/:id", middleware.RequireRole(models.RoleAdmin), assignmentRuleHandler.UpdateAssignmentRule)
+			assignmentRules.DELETE("/:id", middleware.RequireRole(models.RoleAdmin), assignmentRuleHandler.DeleteAssignmentRule)
+			assignmentRules.GET("/:id/simulate", middleware.RequireRole(models.RoleAdmin, models.RoleManager), assignmentRuleHandler.SimulateAssignmentRule)
+		}
+
+		// Service account endpoints (admin only)
+		serviceAccounts := admin.Group("/service-accounts")
+		serviceAccounts.Use(middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox())
+		{
+			serviceAccounts.GET("", serviceAccountHandler.ListServiceAccounts)
+			serviceAccounts.POST("", serviceAccountHandler.CreateServiceAccount)
+			serviceAccounts.POST("/:id/rotate", serviceAccountHandler.RotateServiceAccountToken)
+			serviceAccounts.DELETE("/:id", serviceAccountHandler.RevokeServiceAccount)
+		}
+
+		// Security activity, alert and alert webhook endpoints (admin only)
+		securityGroup := admin.Group("/security")
+		securityGroup.Use(middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox())
+		{
+			securityGroup.GET("/activity", securityHandler.ListSecurityActivity)
+			securityGroup.GET("/alerts", securityHandler.ListSecurityAlerts)
+			securityGroup.POST("/alerts/:id/acknowledge", securityHandler.AcknowledgeSecurityAlert)
+			securityGroup.GET("/webhook", securityHandler.GetSecurityWebhook)
+			securityGroup.GET("/webhook/deliveries", securityHandler.ListSecurityWebhookDeliveries)
+		}
+
+		// Role endpoints (admin only)
+		roles := admin.Group("/roles")
+		roles.Use(middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox())
+		{
+			roles.GET("", roleHandler.ListRoles)
+			roles.POST("", roleHandler.CreateRole)
+			roles.GET("/:id", roleHandler.GetRole)
+			roles.PUT("/:id", roleHandler.UpdateRole)
+			roles.DELETE("/:id", roleHandler.DeleteRole)
+		}
+
+		// Maintenance endpoints (admin only)
+		maintenance := admin.Group("/maintenance")
+		maintenance.Use(middleware.RequireRole(models.RoleAdmin), middleware.NotInSandbox())
+		{
+			maintenance.GET("/slow-queries", maintenanceHandler.GetSlowQueries)
+			maintenance.DELETE("/slow-queries", maintenanceHandler.ResetSlowQueries)
+			maintenance.GET("/consistency", consistencyHandler.ListFindings)
+			maintenance.POST("/consistency/run", consistencyHandler.RunChecks)
+			maintenance.POST("/rollup", reportRollupHandler.RebuildSnapshots)
+			maintenance.POST("/email-domains/backfill", companyHandler.BackfillEmailDomains)
+			maintenance.POST("/tag-groups/migrate", tagGroupHandler.MigrateTagGroups)
+			maintenance.POST("/activity-outcomes/classify", activityOutcomeHandler.ClassifyActivityOutcomes)
+			maintenance.POST("/backup", backupHandler.CreateBackup)
+			maintenance.POST("/restore", backupHandler.RestoreBackup)
+			maintenance.POST("/search/reindex", searchHandler.Reindex)
+			maintenance.POST("/smoke-test", smokeTestHandler.RunSmokeTest)
+		}
+	}
+
+	// Server-rendered debugging UI. Pages call the admin API above
+	// in-process with the token kept in the session cookie.
+	if cfg.AdminUIEnabled {
+		adminUIHandler := handlers.NewAdminUIHandler(router, cfg.IsProduction())
+
+		ui := router.Group("/admin/ui", adminCORS)
+		ui.GET("/login", adminUIHandler.Login)
+		ui.POST("/session", adminUIHandler.CreateSession)
+		ui.POST("/logout", adminUIHandler.Logout)
+
+		session := ui.Group("", adminUIHandler.RequireSession)
+		{
+			session.GET("", adminUIHandler.Home)
+			session.GET("/customers", adminUIHandler.ListCustomers)
+			session.GET("/customers/:id", adminUIHandler.GetCustomer)
+			session.GET("/deals", adminUIHandler.ListDeals)
+			session.GET("/deals/:id", adminUIHandler.GetDeal)
+			session.GET("/activities", adminUIHandler.ListActivities)
+			session.GET("/activities/:id", adminUIHandler.GetActivity)
+			session.GET("/audit-logs", adminUIHandler.ListAuditLogs)
+			session.GET("/jobs", adminUIHandler.ListJobs)
+			session.GET("/jobs/:id", adminUIHandler.GetJob)
+			session.GET("/dead-letters", adminUIHandler.ListDeadLetters)
+			session.POST("/dead-letters/:id/retry", adminUIHandler.RetryDeadLetter)
+			session.GET("/maintenance", adminUIHandler.Maintenance)
+			session.POST("/maintenance/consistency/run", adminUIHandler.RunConsistencyChecks)
+			session.POST("/maintenance/email-domains/backfill", adminUIHandler.BackfillEmailDomains)
 		}
 	}
 
-	return router
+	return router, nil
 }
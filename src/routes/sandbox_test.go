@@ -0,0 +1,51 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+)
+
+func TestSandboxWritesStayInTheSandbox(t *testing.T) {
+	s := newServer(t)
+	demo := testdb.New(t, factory.Epoch)
+	if err := sandbox.Route(s.DB, demo.DB); err != nil {
+		t.Fatal(err)
+	}
+	integrator := caller{ID: 1, Role: models.RoleAdmin, Sandbox: true}
+
+	rec := s.do(t, integrator, http.MethodPost, "/admin/customers", map[string]interface{}{"name": "Demo", "email": "demo@example.com"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("sandbox create: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get(middleware.HeaderSandbox) != "true" {
+		t.Errorf("sandbox response is not marked")
+	}
+	if demo.Count("customers") != 1 || s.Count("customers") != 0 {
+		t.Fatalf("customers: sandbox %d, primary %d; want 1, 0", demo.Count("customers"), s.Count("customers"))
+	}
+
+	// A normal token reads the primary, which never saw the write
+	var page struct {
+		Data []models.Customer `json:"data"`
+	}
+	rec = s.do(t, admin, http.MethodGet, "/admin/customers", nil)
+	decode(t, rec, &page)
+	if rec.Code != http.StatusOK || len(page.Data) != 0 {
+		t.Errorf("primary list: status %d, %d customers", rec.Code, len(page.Data))
+	}
+	if rec := s.do(t, admin, http.MethodGet, "/admin/customers/1", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("primary read of the sandbox customer: status %d", rec.Code)
+	}
+
+	// The sandbox token reads it back
+	rec = s.do(t, integrator, http.MethodGet, "/admin/customers/1", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("sandbox read: status %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,37 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// TestExportsRaiseTheExportCounter checks that streamed customer exports
+// and segment reports downloaded as CSV count as exports, while the same
+// report read as JSON counts as a read
+func TestExportsRaiseTheExportCounter(t *testing.T) {
+	s := newServer(t)
+	customer := s.Factory.Customer(t)
+	tag := s.Factory.Tag(t)
+	s.Factory.TagCustomer(t, customer, tag)
+
+	segments := fmt.Sprintf("/admin/reports/segments?tag_ids=%d", tag.ID)
+	for _, path := range []string{"/admin/customers/export", segments + "&format=csv", segments} {
+		if rec := s.do(t, admin, http.MethodGet, path, nil); rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+	if err := s.Services.ActivityTracker.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var activity models.SecurityActivity
+	if err := s.DB.Where("user_id = ?", admin.ID).First(&activity).Error; err != nil {
+		t.Fatal(err)
+	}
+	if activity.Exports != 2 || activity.Reads != 1 {
+		t.Errorf("exports = %d, reads = %d, want 2 and 1", activity.Exports, activity.Reads)
+	}
+}
@@ -0,0 +1,103 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+)
+
+// TestSecurityWebhookDeliveries checks that the alert webhook's contract
+// is described and that its deliveries can be reconciled by event ID
+func TestSecurityWebhookDeliveries(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) {
+		cfg.SecurityAlertWebhookURL = "https://hooks.example.com/security"
+		cfg.SecurityAlertWebhookMode = "at_most_once"
+	})
+
+	rec := s.get(t, admin, "/admin/security/webhook")
+	var contract handlers.SecurityWebhookResponse
+	decode(t, rec, &contract)
+	if rec.Code != http.StatusOK || contract.Mode != models.SecurityWebhookAtMostOnce || contract.Event != "security.alert" ||
+		contract.EventIDHeader != security.EventIDHeader || contract.DeliveryIDHeader != security.DeliveryIDHeader ||
+		!strings.Contains(contract.Retries, "not retried") ||
+		contract.Deliveries != "/admin/security/webhook/deliveries?event_id={event_id}" {
+		t.Errorf("contract: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// The meta endpoint publishes the same contract
+	rec = s.get(t, admin, "/admin/meta/webhooks")
+	var webhooks struct {
+		Data []handlers.SecurityWebhookResponse
+	}
+	decode(t, rec, &webhooks)
+	if rec.Code != http.StatusOK || len(webhooks.Data) != 1 || webhooks.Data[0] != contract {
+		t.Errorf("meta: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	alerts := make([]models.SecurityAlert, 2)
+	for i := range alerts {
+		alerts[i] = models.SecurityAlert{
+			UserID: uint(10 + i), BucketStart: s.Now().Truncate(time.Hour), Metric: models.SecurityMetricExports,
+			Threshold: 50, Value: 51, Status: models.SecurityAlertOpen, EventID: []string{"event-a", "event-b"}[i],
+		}
+		if err := s.DB.Create(&alerts[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, delivery := range []models.SecurityWebhookDelivery{
+		{DeliveryID: "delivery-1", Attempt: 1, Status: models.SecurityWebhookDeliveryFailed, ResponseStatus: 502, ResponseBody: "bad gateway"},
+		{DeliveryID: "delivery-2", Attempt: 2, Status: models.SecurityWebhookDeliveryDelivered, ResponseStatus: 200},
+		{DeliveryID: "delivery-3", Attempt: 1, Status: models.SecurityWebhookDeliveryDelivered, ResponseStatus: 204},
+	} {
+		alert := alerts[i/2]
+		delivery.AlertID, delivery.EventID = alert.ID, alert.EventID
+		if err := s.DB.Create(&delivery).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for query, want := range map[string][]string{
+		"":                                {"delivery-3", "delivery-2", "delivery-1"},
+		"?event_id=event-a":               {"delivery-2", "delivery-1"},
+		"?event_id=event-a&status=failed": {"delivery-1"},
+		"?event_id=unknown":               {},
+	} {
+		rec := s.get(t, admin, "/admin/security/webhook/deliveries"+query)
+		var list models.SecurityWebhookDeliveryListResponse
+		decode(t, rec, &list)
+		got := []string{}
+		for _, delivery := range list.Data {
+			got = append(got, delivery.DeliveryID)
+		}
+		if rec.Code != http.StatusOK || strings.Join(got, ",") != strings.Join(want, ",") || list.Total != int64(len(want)) {
+			t.Errorf("%q: status = %d, deliveries %v, want %v", query, rec.Code, got, want)
+		}
+		if query == "?event_id=event-a&status=failed" && len(list.Data) == 1 && list.Data[0].ResponseBody != "bad gateway" {
+			t.Errorf("response_body = %q", list.Data[0].ResponseBody)
+		}
+	}
+
+	if rec := s.do(t, manager, http.MethodGet, "/admin/security/webhook/deliveries", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("manager: status = %d", rec.Code)
+	}
+}
+
+// TestSecurityWebhookNotConfigured checks that the contract is not
+// described without a webhook and that the meta endpoint lists none
+func TestSecurityWebhookNotConfigured(t *testing.T) {
+	s := newServer(t)
+	rec := s.do(t, admin, http.MethodGet, "/admin/security/webhook", nil)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"code":"SECURITY_WEBHOOK_NOT_CONFIGURED"`) {
+		t.Errorf("status = %d: %s", rec.Code, rec.Body)
+	}
+	rec = s.get(t, admin, "/admin/meta/webhooks")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), `{"data":[],`) {
+		t.Errorf("meta: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,74 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// serviceAccount creates a service account with scopes and returns its
+// token
+func (s *server) serviceAccount(t *testing.T, name, role string, scopes ...string) string {
+	t.Helper()
+	rec := s.do(t, admin, http.MethodPost, "/admin/service-accounts", map[string]interface{}{"name": name, "role": role, "scopes": scopes})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create service account: status = %d: %s", rec.Code, rec.Body)
+	}
+	var created models.ServiceAccountCreateResponse
+	decode(t, rec, &created)
+	return created.Token
+}
+
+// asServiceAccount serves a request with a service account token
+func (s *server) asServiceAccount(t *testing.T, token, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return s.serve(t, caller{}, req)
+}
+
+// TestServiceAccountScopes checks that a service account reaches only the
+// resources its scopes cover, reading with a read scope and writing with a
+// write scope. Routes nested under a customer need the scope of what they
+// serve, and paths no route serves are not found.
+func TestServiceAccountScopes(t *testing.T) {
+	s := newServer(t)
+	token := s.serviceAccount(t, "sync", models.RoleManager, "customers:read", "deals:write")
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/admin/customers", http.StatusOK},
+		{http.MethodGet, "/admin/deals", http.StatusOK},
+		{http.MethodGet, "/admin/activities", http.StatusForbidden},
+		{http.MethodDelete, "/admin/customers/1", http.StatusForbidden},
+		{http.MethodGet, "/admin/customers/1/contacts", http.StatusForbidden},
+		{http.MethodGet, "/admin/nowhere", http.StatusNotFound},
+	} {
+		rec := s.asServiceAccount(t, token, tc.method, tc.path)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: status = %d, want %d: %s", tc.method, tc.path, rec.Code, tc.want, rec.Body)
+			continue
+		}
+		if tc.want == http.StatusForbidden {
+			var refused struct{ Code string }
+			decode(t, rec, &refused)
+			if refused.Code != "INSUFFICIENT_SCOPE" {
+				t.Errorf("%s %s: code = %s", tc.method, tc.path, refused.Code)
+			}
+		}
+	}
+
+	// customers:write does not cover a customer's contacts
+	writer := s.serviceAccount(t, "importer", models.RoleManager, "customers:write")
+	if rec := s.asServiceAccount(t, writer, http.MethodPost, "/admin/customers/1/contacts/import"); rec.Code != http.StatusForbidden {
+		t.Errorf("import contacts with customers:write: status = %d: %s", rec.Code, rec.Body)
+	}
+	contacts := s.serviceAccount(t, "contacts", models.RoleManager, "contacts:read")
+	if rec := s.asServiceAccount(t, contacts, http.MethodGet, "/admin/customers/1/contacts"); rec.Code == http.StatusForbidden {
+		t.Errorf("list contacts with contacts:read: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,81 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// segments returns the segments report for a query, failing on any error
+func segments(t *testing.T, s *server, query string) handlers.SegmentReport {
+	t.Helper()
+	rec := s.do(t, admin, http.MethodGet, "/admin/reports/segments?"+query, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("segments?%s: status = %d: %s", query, rec.Code, rec.Body)
+	}
+	var report handlers.SegmentReport
+	decode(t, rec, &report)
+	return report
+}
+
+// TestRenamedTagKeepsSegmentsWorking checks that a segments report link
+// naming a tag, or giving its ID, finds the tag after it is renamed
+func TestRenamedTagKeepsSegmentsWorking(t *testing.T) {
+	s := newServer(t)
+	vip := s.Factory.Tag(t, func(tag *models.Tag) { tag.Name = "vip" })
+	for i := 0; i < 2; i++ {
+		s.Factory.TagCustomer(t, s.Factory.Customer(t), vip)
+	}
+
+	// The dry run reports and saves nothing
+	path := fmt.Sprintf("/admin/tags/%d", vip.ID)
+	rec := s.do(t, admin, http.MethodPut, path+"?dry_run=true", map[string]interface{}{"name": "premium"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dry run: status = %d: %s", rec.Code, rec.Body)
+	}
+	var report handlers.TagRenameReport
+	decode(t, rec, &report)
+	want := handlers.TagRenameReport{DryRun: true, TagID: vip.ID, From: "vip", To: "premium", Customers: 2, Aliases: []string{"vip"}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("dry run = %+v, want %+v", report, want)
+	}
+	if n := s.Count("tag_aliases"); n != 0 {
+		t.Errorf("dry run saved %d aliases", n)
+	}
+
+	if rec := s.do(t, admin, http.MethodPut, path, map[string]interface{}{"name": "premium"}); rec.Code != http.StatusOK {
+		t.Fatalf("rename: status = %d: %s", rec.Code, rec.Body)
+	}
+	for _, query := range []string{"tags=vip", "tags=premium", fmt.Sprintf("tag_ids=%d", vip.ID), fmt.Sprintf("tags=vip&tag_ids=%d", vip.ID)} {
+		report := segments(t, s, query)
+		if len(report.Segments) != 1 || report.Segments[0].Tag != "premium" || report.Segments[0].Customers != 2 || len(report.Meta.UnknownTags) != 0 {
+			t.Errorf("segments?%s = %+v", query, report)
+		}
+	}
+	if report := segments(t, s, "tags=vip"); report.Meta.RenamedTags["vip"] != "premium" {
+		t.Errorf("renamed tags = %v", report.Meta.RenamedTags)
+	}
+
+	// Another tag renamed to the former name takes it over
+	gold := s.Factory.Tag(t, func(tag *models.Tag) { tag.Name = "gold" })
+	s.Factory.TagCustomer(t, s.Factory.Customer(t), gold)
+	goldPath := fmt.Sprintf("/admin/tags/%d", gold.ID)
+	rec = s.do(t, admin, http.MethodPut, goldPath+"?dry_run=true", map[string]interface{}{"name": "vip"})
+	decode(t, rec, &report)
+	if len(report.Redirected) != 1 || report.Redirected[0].Name != "vip" || report.Redirected[0].TagID != vip.ID {
+		t.Errorf("redirected = %+v, want vip from tag %d", report.Redirected, vip.ID)
+	}
+	if rec := s.do(t, admin, http.MethodPut, goldPath, map[string]interface{}{"name": "vip"}); rec.Code != http.StatusOK {
+		t.Fatalf("rename: status = %d: %s", rec.Code, rec.Body)
+	}
+	if report := segments(t, s, "tags=vip"); len(report.Segments) != 1 || report.Segments[0].TagID == nil || *report.Segments[0].TagID != gold.ID {
+		t.Errorf("segments?tags=vip = %+v, want the renamed gold tag", report.Segments)
+	}
+	if report := segments(t, s, "tags=gold"); len(report.Segments) != 1 || report.Segments[0].Customers != 1 {
+		t.Errorf("segments?tags=gold = %+v", report.Segments)
+	}
+}
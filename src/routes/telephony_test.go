@@ -0,0 +1,211 @@
+package routes_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/telephony"
+)
+
+const (
+	telephonySecret = "telephony-secret"
+	telephonyAPIKey = "telephony-key"
+)
+
+// postCall posts a call webhook, signed with the secret unless headers are
+// given
+func (s *server) postCall(t *testing.T, call map[string]interface{}, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/integrations/telephony/calls", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if len(header) == 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(telephonySecret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		header = []string{telephony.TimestampHeader, timestamp, telephony.SignatureHeader, hex.EncodeToString(mac.Sum(nil))}
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return s.serve(t, caller{}, req)
+}
+
+// TestTelephonyWebhook checks that calls are authenticated, logged against
+// the customer or contact whose number matches and the rep who placed them,
+// queued when nothing matches, and logged once per event
+func TestTelephonyWebhook(t *testing.T) {
+	s := newServer(t, func(cfg *config.Config) {
+		cfg.TelephonyWebhookSecret = telephonySecret
+		cfg.TelephonyAPIKey = telephonyAPIKey
+		cfg.TelephonyExtensions = "101=5"
+	})
+	rep := uint(2)
+	customer := s.Factory.Customer(t, func(c *models.Customer) { c.Phone, c.AssignedTo = "+966 11 200 1234", &rep })
+	contact := s.Factory.Contact(t, customer, func(c *models.Contact) { c.Phone, c.IsPrimary = "00966551234567", true })
+	startedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	ingest := func(t *testing.T, call map[string]interface{}, wantResult string) handlers.TelephonyCallResult {
+		t.Helper()
+		rec := s.postCall(t, call)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var result handlers.TelephonyCallResult
+		decode(t, rec, &result)
+		if result.Result != wantResult {
+			t.Fatalf("result = %q, want %q: %s", result.Result, wantResult, rec.Body)
+		}
+		return result
+	}
+	activity := func(t *testing.T, id *uint) models.Activity {
+		t.Helper()
+		if id == nil {
+			t.Fatal("call has no activity")
+		}
+		var activity models.Activity
+		if err := s.DB.First(&activity, *id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return activity
+	}
+
+	t.Run("inbound call matched by the contact's number", func(t *testing.T) {
+		result := ingest(t, map[string]interface{}{
+			"id": "call_1", "direction": "inbound", "from": "+966 55 123 4567", "to": "+966 11 200 0000",
+			"duration": 61, "recording_url": "https://pbx.example.com/rec/call_1.mp3", "timestamp": startedAt,
+		}, "logged")
+		if result.Call.Status != models.TelephonyCallLogged || result.Call.ExternalNumber != "+966 55 123 4567" {
+			t.Errorf("call = %+v", result.Call)
+		}
+		logged := activity(t, result.Call.ActivityID)
+		if logged.Type != models.ActivityTypeCall || logged.Status != models.ActivityStatusCompleted ||
+			logged.CustomerID == nil || *logged.CustomerID != customer.ID || logged.ContactID == nil || *logged.ContactID != contact.ID ||
+			logged.AssignedTo == nil || *logged.AssignedTo != rep || logged.Duration != 2 || logged.Outcome != "Connected" ||
+			logged.Title != "Inbound call from +966 55 123 4567" || logged.Description != "Recording: https://pbx.example.com/rec/call_1.mp3" ||
+			logged.DueDate == nil || !logged.DueDate.Equal(startedAt) {
+			t.Errorf("activity = %+v", logged)
+		}
+	})
+
+	t.Run("redelivered event is logged once", func(t *testing.T) {
+		calls, activities := s.Count("telephony_calls"), s.Count("activities")
+		// The redelivery may authenticate differently and carry other fields
+		rec := s.postCall(t, map[string]interface{}{"id": "call_1", "direction": "inbound", "from": "+966 11 200 1234"},
+			telephony.APIKeyHeader, telephonyAPIKey)
+		var result handlers.TelephonyCallResult
+		decode(t, rec, &result)
+		if rec.Code != http.StatusOK || result.Result != "duplicate" || result.Call.ExternalNumber != "+966 55 123 4567" {
+			t.Errorf("status = %d: %s", rec.Code, rec.Body)
+		}
+		if s.Count("telephony_calls") != calls || s.Count("activities") != activities {
+			t.Error("redelivered event was logged again")
+		}
+	})
+
+	t.Run("outbound call is attributed to the rep's extension", func(t *testing.T) {
+		result := ingest(t, map[string]interface{}{
+			"id": "call_2", "direction": "outbound", "from": "+966 11 200 0000", "to": "0112001234",
+			"extension": "101", "duration": 0, "timestamp": startedAt,
+		}, "logged")
+		logged := activity(t, result.Call.ActivityID)
+		if logged.AssignedTo == nil || *logged.AssignedTo != 5 || logged.ContactID != nil ||
+			logged.CustomerID == nil || *logged.CustomerID != customer.ID || logged.Outcome != "No answer" {
+			t.Errorf("activity = %+v", logged)
+		}
+
+		// An extension without a rep falls back to the customer's
+		result = ingest(t, map[string]interface{}{
+			"id": "call_3", "direction": "outbound", "from": "+966 11 200 0000", "to": "0112001234", "extension": "199",
+		}, "logged")
+		if logged := activity(t, result.Call.ActivityID); logged.AssignedTo == nil || *logged.AssignedTo != rep {
+			t.Errorf("unknown extension: activity = %+v", logged)
+		}
+	})
+
+	t.Run("unmatched call is queued and matched by hand", func(t *testing.T) {
+		activities := s.Count("activities")
+		result := ingest(t, map[string]interface{}{
+			"id": "call_4", "direction": "inbound", "from": "+966 50 999 8888", "to": "+966 11 200 0000", "duration": 30,
+		}, "unmatched")
+		if result.Call.Status != models.TelephonyCallUnmatched || result.Call.ActivityID != nil || s.Count("activities") != activities {
+			t.Errorf("call = %+v", result.Call)
+		}
+
+		rec := s.get(t, admin, "/admin/telephony/calls?status=unmatched")
+		var queue models.TelephonyCallListResponse
+		decode(t, rec, &queue)
+		if rec.Code != http.StatusOK || queue.Total != 1 || queue.Data[0].EventID != "call_4" {
+			t.Fatalf("queue: status = %d: %s", rec.Code, rec.Body)
+		}
+
+		matchPath := fmt.Sprintf("/admin/telephony/calls/%d/match", result.Call.ID)
+		rec = s.do(t, admin, http.MethodPost, matchPath, map[string]interface{}{"customer_id": customer.ID})
+		var matched handlers.TelephonyCallResult
+		decode(t, rec, &matched)
+		if rec.Code != http.StatusOK || matched.Call.Status != models.TelephonyCallLogged {
+			t.Fatalf("match: status = %d: %s", rec.Code, rec.Body)
+		}
+		if logged := activity(t, matched.Call.ActivityID); logged.CustomerID == nil || *logged.CustomerID != customer.ID ||
+			logged.AssignedTo == nil || *logged.AssignedTo != rep {
+			t.Errorf("activity = %+v", logged)
+		}
+
+		rec = s.do(t, admin, http.MethodPost, matchPath, map[string]interface{}{"customer_id": customer.ID})
+		if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"code":"CALL_ALREADY_LOGGED"`) {
+			t.Errorf("second match: status = %d: %s", rec.Code, rec.Body)
+		}
+		rec = s.get(t, admin, "/admin/telephony/calls?status=unmatched")
+		decode(t, rec, &queue)
+		if queue.Total != 0 {
+			t.Errorf("queue after matching = %+v", queue)
+		}
+	})
+
+	t.Run("unauthenticated calls are refused", func(t *testing.T) {
+		call := map[string]interface{}{"id": "call_5", "direction": "inbound", "from": "+966 55 123 4567"}
+		stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		for name, header := range map[string][]string{
+			"no credentials":  {"X-Unused", ""},
+			"wrong API key":   {telephony.APIKeyHeader, "guess"},
+			"bad signature":   {telephony.TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10), telephony.SignatureHeader, "00"},
+			"stale signature": {telephony.TimestampHeader, stale, telephony.SignatureHeader, "00"},
+		} {
+			rec := s.postCall(t, call, header...)
+			if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"code":"INVALID_WEBHOOK_SIGNATURE"`) {
+				t.Errorf("%s: status = %d: %s", name, rec.Code, rec.Body)
+			}
+		}
+		if n := s.Count("telephony_calls"); n != 4 {
+			t.Errorf("%d calls recorded, want 4", n)
+		}
+	})
+}
+
+// TestTelephonyWebhookNotConfigured checks that the webhook is off until a
+// secret or API key is set
+func TestTelephonyWebhookNotConfigured(t *testing.T) {
+	s := newServer(t)
+	rec := s.postCall(t, map[string]interface{}{"id": "call_1", "direction": "inbound", "from": "+966 55 123 4567"},
+		telephony.APIKeyHeader, telephonyAPIKey)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"code":"TELEPHONY_NOT_CONFIGURED"`) {
+		t.Errorf("status = %d: %s", rec.Code, rec.Body)
+	}
+}
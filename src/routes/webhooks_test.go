@@ -0,0 +1,522 @@
+package routes_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/handlers"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/webhooks"
+)
+
+// webhookReceiver records the events posted to it, checking their
+// signatures, and answers with status
+type webhookReceiver struct {
+	*httptest.Server
+	secret string
+
+	mu       sync.Mutex
+	status   int
+	received []webhooks.Event
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	r := &webhookReceiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mac := hmac.New(sha256.New, []byte(r.secret))
+		mac.Write([]byte(req.Header.Get(webhooks.TimestampHeader) + "."))
+		mac.Write(body)
+		if req.Header.Get(webhooks.SignatureHeader) != hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("bad signature for %s", body)
+		}
+		var event webhooks.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("decode %s: %v", body, err)
+		}
+		if req.Header.Get(webhooks.EventHeader) != event.Event || req.Header.Get(webhooks.EventIDHeader) != event.EventID ||
+			req.Header.Get(webhooks.DeliveryIDHeader) != event.DeliveryID {
+			t.Errorf("headers %v do not match %s", req.Header, body)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.received = append(r.received, event)
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *webhookReceiver) respond(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// take returns the events received since the last call
+func (r *webhookReceiver) take() []webhooks.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	received := r.received
+	r.received = nil
+	return received
+}
+
+// TestWebhookSubscriptions subscribes to stage and status changes, with a
+// filter on won deals, and checks that matching events are sent signed,
+// that the others are recorded as skipped, and that failures are retried
+// with the same event ID and dead-lettered past the retry window
+func TestWebhookSubscriptions(t *testing.T) {
+	s := newServer(t)
+	receiver, statusReceiver := newWebhookReceiver(t), newWebhookReceiver(t)
+	ctx := context.Background()
+
+	won := map[string]interface{}{"name": "Big wins", "url": receiver.URL, "events": []string{"deal.stage_changed"},
+		"filter": `stage == "closed_won" and amount > 10000`}
+	if rec := s.do(t, manager, http.MethodPost, "/admin/webhooks", won); rec.Code != http.StatusForbidden {
+		t.Errorf("manager: status = %d", rec.Code)
+	}
+	for _, tc := range []struct {
+		name string
+		body map[string]interface{}
+		code string
+	}{
+		{"bad filter", map[string]interface{}{"name": "x", "url": receiver.URL, "events": []string{"deal.updated"}, "filter": `stage = "closed_won"`}, "INVALID_WEBHOOK_FILTER"},
+		{"unknown event", map[string]interface{}{"name": "x", "url": receiver.URL, "events": []string{"deal.won"}}, "INVALID_WEBHOOK_EVENT"},
+		{"not http", map[string]interface{}{"name": "x", "url": "ftp://example.com/hook", "events": []string{"deal.updated"}}, "INVALID_WEBHOOK_URL"},
+		{"no events", map[string]interface{}{"name": "x", "url": receiver.URL, "events": []string{}}, "INVALID_REQUEST"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := s.do(t, admin, http.MethodPost, "/admin/webhooks", tc.body)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"`+tc.code+`"`) {
+				t.Errorf("status = %d, want 400 %s: %s", rec.Code, tc.code, rec.Body)
+			}
+		})
+	}
+
+	var wonSub models.WebhookSubscriptionCreateResponse
+	rec := s.do(t, admin, http.MethodPost, "/admin/webhooks", won)
+	decode(t, rec, &wonSub)
+	if rec.Code != http.StatusCreated || len(wonSub.Secret) != 64 || !wonSub.Active {
+		t.Fatalf("create: status = %d: %s", rec.Code, rec.Body)
+	}
+	var statusSub models.WebhookSubscriptionCreateResponse
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "Statuses", "url": statusReceiver.URL, "events": []string{"customer.status_changed"},
+	}), &statusSub)
+	receiver.secret, statusReceiver.secret = wonSub.Secret, statusSub.Secret
+	if rec := s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d", wonSub.ID)); strings.Contains(rec.Body.String(), wonSub.Secret) {
+		t.Errorf("secret shown after creation: %s", rec.Body)
+	}
+
+	customer := s.Factory.Customer(t)
+	deal := func(amount float64) models.Deal {
+		return s.Factory.Deal(t, customer, func(d *models.Deal) { d.Stage, d.Amount = models.DealStageNegotiation, amount })
+	}
+	update := func(path string, id uint, body map[string]interface{}) {
+		t.Helper()
+		if rec := s.do(t, admin, http.MethodPut, fmt.Sprintf(path, id), body); rec.Code != http.StatusOK {
+			t.Fatalf("update %d: status = %d: %s", id, rec.Code, rec.Body)
+		}
+	}
+	run := func(want int) {
+		t.Helper()
+		attempts, err := s.Services.Webhooks.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempts != want {
+			t.Errorf("%d attempts, want %d", attempts, want)
+		}
+	}
+
+	big, small := deal(25000), deal(5000)
+	update("/admin/deals/%d", big.ID, map[string]interface{}{"stage": "closed_won"})
+	update("/admin/deals/%d", small.ID, map[string]interface{}{"stage": "closed_won"})
+	update("/admin/deals/%d", big.ID, map[string]interface{}{"title": "Renamed"}) // A generic update only
+	update("/admin/customers/%d", customer.ID, map[string]interface{}{"status": "prospect"})
+	run(2)
+
+	received := receiver.take()
+	if len(received) != 1 || received[0].Event != models.WebhookEventDealStageChanged || received[0].Data["id"] != float64(big.ID) ||
+		received[0].Data["stage"] != "closed_won" || received[0].Previous["stage"] != "negotiation" {
+		t.Fatalf("stage changes = %+v", received)
+	}
+	received = statusReceiver.take()
+	if len(received) != 1 || received[0].Event != models.WebhookEventCustomerStatusChanged || received[0].Data["id"] != float64(customer.ID) ||
+		received[0].Data["status"] != "prospect" || received[0].Previous["status"] != "lead" {
+		t.Errorf("status changes = %+v", received)
+	}
+
+	// The small win was matched against the filter and skipped
+	var got models.WebhookSubscriptionResponse
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d", wonSub.ID)), &got)
+	if got.Stats != (models.WebhookDeliveryStats{Delivered: 1, Skipped: 1}) {
+		t.Errorf("stats = %+v", got.Stats)
+	}
+	var skipped models.WebhookDeliveryListResponse
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d/deliveries?status=skipped", wonSub.ID)), &skipped)
+	if skipped.Total != 1 || skipped.Data[0].Attempts != 0 {
+		t.Fatalf("skipped = %+v", skipped.Data)
+	}
+	var skippedEvent webhooks.Event
+	if err := json.Unmarshal([]byte(skipped.Data[0].Payload), &skippedEvent); err != nil || skippedEvent.Data["id"] != float64(small.ID) {
+		t.Errorf("skipped event = %s", skipped.Data[0].Payload)
+	}
+
+	// A failed delivery waits and is retried with the same event ID
+	receiver.respond(http.StatusBadGateway)
+	update("/admin/deals/%d", deal(20000).ID, map[string]interface{}{"stage": "closed_won"})
+	run(1)
+	run(0)
+	var pending models.WebhookDeliveryListResponse
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d/deliveries?status=pending", wonSub.ID)), &pending)
+	if pending.Total != 1 || pending.Data[0].ResponseStatus != http.StatusBadGateway || pending.Data[0].NextAttemptAt == nil {
+		t.Fatalf("pending = %+v", pending.Data)
+	}
+	if err := s.DB.Model(&models.WebhookDelivery{}).Where("id = ?", pending.Data[0].ID).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	receiver.respond(http.StatusOK)
+	run(1)
+	retried := receiver.take()
+	if len(retried) != 2 || retried[0].EventID != retried[1].EventID || retried[0].DeliveryID == retried[1].DeliveryID {
+		t.Errorf("attempts = %+v", retried)
+	}
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d", wonSub.ID)), &got)
+	if got.Stats != (models.WebhookDeliveryStats{Delivered: 2, Skipped: 1}) {
+		t.Errorf("stats = %+v", got.Stats)
+	}
+
+	// A delivery failing past the retry window is dead-lettered, and a retry
+	// from the dead-letter queue sends it again
+	receiver.respond(http.StatusBadGateway)
+	update("/admin/deals/%d", deal(30000).ID, map[string]interface{}{"stage": "closed_won"})
+	if err := s.DB.Model(&models.WebhookDelivery{}).Where("status = ?", models.WebhookDeliveryPending).
+		Update("created_at", time.Now().Add(-25*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	run(1)
+	var letters models.DeadLetterListResponse
+	decode(t, s.get(t, admin, "/admin/dead-letters?component="+webhooks.DeadLetterComponent), &letters)
+	if letters.Total != 1 || !strings.Contains(letters.Data[0].Error, "502") {
+		t.Fatalf("dead letters = %+v", letters.Data)
+	}
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d", wonSub.ID)), &got)
+	if got.Stats != (models.WebhookDeliveryStats{Delivered: 2, Failed: 1, Skipped: 1}) {
+		t.Errorf("stats = %+v", got.Stats)
+	}
+	receiver.respond(http.StatusOK)
+	if rec := s.do(t, admin, http.MethodPost, fmt.Sprintf("/admin/dead-letters/%d/retry", letters.Data[0].ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("retry: status = %d: %s", rec.Code, rec.Body)
+	}
+	receiver.take()
+	run(1)
+	if received := receiver.take(); len(received) != 1 {
+		t.Errorf("%d events after the retry, want 1", len(received))
+	}
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d", wonSub.ID)), &got)
+	if got.Stats != (models.WebhookDeliveryStats{Delivered: 3, Skipped: 1}) {
+		t.Errorf("stats after retry = %+v", got.Stats)
+	}
+
+	// A paused subscription queues nothing
+	update("/admin/webhooks/%d", statusSub.ID, map[string]interface{}{
+		"name": "Statuses", "url": statusReceiver.URL, "events": []string{"customer.status_changed"}, "active": false,
+	})
+	update("/admin/customers/%d", customer.ID, map[string]interface{}{"status": "active"})
+	var statusDeliveries models.WebhookDeliveryListResponse
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d/deliveries", statusSub.ID)), &statusDeliveries)
+	if statusDeliveries.Total != 1 {
+		t.Errorf("%d status deliveries, want 1", statusDeliveries.Total)
+	}
+}
+
+// TestWebhookTestFire matches sample events against a subscription and
+// sends a matching one without storing it
+func TestWebhookTestFire(t *testing.T) {
+	s := newServer(t)
+	receiver := newWebhookReceiver(t)
+	var sub models.WebhookSubscriptionCreateResponse
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "Big wins", "url": receiver.URL, "events": []string{"deal.stage_changed"},
+		"filter": `stage == "closed_won" and amount > 10000 and previous.stage != "closed_won"`,
+	}), &sub)
+	receiver.secret = sub.Secret
+	path := fmt.Sprintf("/admin/webhooks/%d/test", sub.ID)
+
+	for _, tc := range []struct {
+		name           string
+		body           map[string]interface{}
+		wantSubscribed bool
+		wantMatched    bool
+	}{
+		{"match", map[string]interface{}{"event": "deal.stage_changed", "data": map[string]interface{}{"stage": "closed_won", "amount": 50000},
+			"previous": map[string]interface{}{"stage": "negotiation"}}, true, true},
+		{"too small", map[string]interface{}{"event": "deal.stage_changed", "data": map[string]interface{}{"stage": "closed_won", "amount": 100}}, true, false},
+		{"not subscribed", map[string]interface{}{"event": "deal.updated", "data": map[string]interface{}{"stage": "closed_won", "amount": 50000}}, false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var result handlers.WebhookTestResponse
+			decode(t, s.do(t, admin, http.MethodPost, path, tc.body), &result)
+			if result.Subscribed != tc.wantSubscribed || result.Matched != tc.wantMatched || result.Delivery != nil {
+				t.Errorf("result = %+v", result)
+			}
+		})
+	}
+	if rec := s.do(t, admin, http.MethodPost, path, map[string]interface{}{"event": "deal.won", "data": map[string]interface{}{}}); rec.Code != http.StatusBadRequest ||
+		!strings.Contains(rec.Body.String(), `"code":"INVALID_WEBHOOK_EVENT"`) {
+		t.Errorf("unknown event: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var result handlers.WebhookTestResponse
+	decode(t, s.do(t, admin, http.MethodPost, path, map[string]interface{}{
+		"event": "deal.stage_changed", "data": map[string]interface{}{"stage": "closed_won", "amount": 50000}, "send": true,
+	}), &result)
+	if result.Delivery == nil || result.Delivery.Status != models.WebhookDeliveryDelivered || result.Delivery.ResponseStatus != http.StatusOK {
+		t.Fatalf("result = %+v", result)
+	}
+	received := receiver.take()
+	if len(received) != 1 || received[0].EventID != result.Delivery.EventID || received[0].Data["amount"] != float64(50000) {
+		t.Errorf("received = %+v", received)
+	}
+	if rows := s.Rows("webhook_deliveries"); len(rows) != 0 {
+		t.Errorf("%d deliveries stored", len(rows))
+	}
+}
+
+// TestWebhookModes checks that an at-least-once subscription retries a
+// refused event while an at-most-once one fails it after the first attempt,
+// that both are reconciled by event ID, and that the meta endpoint
+// describes both modes
+func TestWebhookModes(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	retrying, once := newWebhookReceiver(t), newWebhookReceiver(t)
+
+	rec := s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "x", "url": once.URL, "events": []string{"deal.updated"}, "mode": "exactly_once",
+	})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"INVALID_WEBHOOK_MODE"`) {
+		t.Errorf("unknown mode: status = %d: %s", rec.Code, rec.Body)
+	}
+	var retryingSub, onceSub models.WebhookSubscriptionCreateResponse
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "Retrying", "url": retrying.URL, "events": []string{"deal.updated"},
+	}), &retryingSub)
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "Once", "url": once.URL, "events": []string{"deal.updated"}, "mode": "at_most_once",
+	}), &onceSub)
+	if retryingSub.Mode != models.WebhookModeAtLeastOnce || onceSub.Mode != models.WebhookModeAtMostOnce {
+		t.Fatalf("modes = %q, %q", retryingSub.Mode, onceSub.Mode)
+	}
+	retrying.secret, once.secret = retryingSub.Secret, onceSub.Secret
+	retrying.respond(http.StatusServiceUnavailable)
+	once.respond(http.StatusServiceUnavailable)
+
+	deal := s.Factory.Deal(t, s.Factory.Customer(t))
+	if rec := s.do(t, admin, http.MethodPut, fmt.Sprintf("/admin/deals/%d", deal.ID), map[string]interface{}{"title": "Renamed"}); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+	if _, err := s.Services.Webhooks.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Model(&models.WebhookDelivery{}).Where("status = ?", models.WebhookDeliveryPending).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	attempts, err := s.Services.Webhooks.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 1 || len(retrying.take()) != 2 || len(once.take()) != 1 {
+		t.Errorf("%d attempts on the second run", attempts)
+	}
+
+	deliveries := func(sub uint, eventID string) models.WebhookDeliveryListResponse {
+		t.Helper()
+		var list models.WebhookDeliveryListResponse
+		decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d/deliveries?event_id=%s", sub, eventID)), &list)
+		return list
+	}
+	failed := deliveries(onceSub.ID, "")
+	if failed.Total != 1 || failed.Data[0].Status != models.WebhookDeliveryFailed || failed.Data[0].Attempts != 1 ||
+		failed.Data[0].ResponseStatus != http.StatusServiceUnavailable {
+		t.Fatalf("at most once = %+v", failed.Data)
+	}
+	// The event has one ID across subscriptions
+	pending := deliveries(retryingSub.ID, failed.Data[0].EventID)
+	if pending.Total != 1 || pending.Data[0].Status != models.WebhookDeliveryPending || pending.Data[0].Attempts != 2 {
+		t.Errorf("at least once = %+v", pending.Data)
+	}
+	if other := deliveries(retryingSub.ID, "unknown"); other.Total != 0 {
+		t.Errorf("unknown event = %+v", other.Data)
+	}
+	if rows := s.Rows("dead_letters"); len(rows) != 0 {
+		t.Errorf("%d dead letters for an at-most-once failure", len(rows))
+	}
+
+	// An at-most-once attempt cut short is failed rather than sent again
+	interrupted := models.WebhookDelivery{SubscriptionID: onceSub.ID, EventID: "interrupted", EventType: models.WebhookEventDealUpdated,
+		Status: models.WebhookDeliveryPending, Attempts: 1, Payload: `{}`, NextAttemptAt: &time.Time{}}
+	if err := s.DB.Create(&interrupted).Error; err != nil {
+		t.Fatal(err)
+	}
+	once.respond(http.StatusOK)
+	if _, err := s.Services.Webhooks.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := deliveries(onceSub.ID, "interrupted"); got.Total != 1 || got.Data[0].Status != models.WebhookDeliveryFailed || len(once.take()) != 0 {
+		t.Errorf("interrupted = %+v", got.Data)
+	}
+
+	var meta struct {
+		Subscriptions handlers.WebhookContractResponse
+	}
+	decode(t, s.get(t, admin, "/admin/meta/webhooks"), &meta)
+	if meta.Subscriptions.DefaultMode != models.WebhookModeAtLeastOnce || len(meta.Subscriptions.Modes) != len(models.WebhookModes) ||
+		!strings.Contains(meta.Subscriptions.Modes[models.WebhookModeAtMostOnce], "not retried") ||
+		meta.Subscriptions.Deliveries != "/admin/webhooks/{id}/deliveries?event_id={event_id}" {
+		t.Errorf("meta = %+v", meta.Subscriptions)
+	}
+}
+
+// TestWebhookBulkEvents imports and upserts customers and checks that the
+// records' events are suppressed for a subscription that does not take bulk
+// events, and left out of its stats, while one that does is sent them, and
+// that each operation raises one bulk.completed event with its counts
+func TestWebhookBulkEvents(t *testing.T) {
+	s := newServer(t)
+	var quiet, bulk models.WebhookSubscriptionCreateResponse
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "Quiet", "url": "https://example.com/quiet", "events": []string{"customer.created", "customer.updated", "bulk.completed"},
+	}), &quiet)
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "Bulk", "url": "https://example.com/bulk", "events": []string{"customer.created", "customer.updated"}, "bulk_events": true,
+	}), &bulk)
+	if quiet.BulkEvents || !bulk.BulkEvents {
+		t.Fatalf("bulk_events = %t, %t", quiet.BulkEvents, bulk.BulkEvents)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/customers/import", strings.NewReader(
+		"name,email\nAcme,ops@acme.example\nGlobex,ops@globex.example\nbroken,not-an-email\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	if rec := s.serve(t, admin, req); rec.Code != http.StatusOK {
+		t.Fatalf("import: status = %d: %s", rec.Code, rec.Body)
+	}
+	rec := s.do(t, admin, http.MethodPost, "/admin/customers/bulk-upsert", map[string]interface{}{"records": []map[string]interface{}{
+		{"email": "ops@acme.example", "phone": "+966500000000"},
+		{"email": "ops@acme.example", "phone": "+966500000000"},
+	}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upsert: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	deliveries := func(sub uint, filter string) []models.WebhookDelivery {
+		t.Helper()
+		var list models.WebhookDeliveryListResponse
+		decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d/deliveries?%s", sub, filter)), &list)
+		return list.Data
+	}
+	if suppressed := deliveries(quiet.ID, "status=suppressed"); len(suppressed) != 3 {
+		t.Errorf("%d suppressed deliveries, want 3 (2 created, 1 updated)", len(suppressed))
+	}
+	completed := deliveries(quiet.ID, "event_type=bulk.completed")
+	if len(completed) != 2 {
+		t.Fatalf("%d bulk.completed events, want 2", len(completed))
+	}
+	var upsert, imported webhooks.Event
+	if err := json.Unmarshal([]byte(completed[0].Payload), &upsert); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(completed[1].Payload), &imported); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Data["operation"] != models.BulkOperationCustomerImport || imported.Data["total"] != float64(3) ||
+		imported.Data["created"] != float64(2) || imported.Data["failed"] != float64(1) || imported.Data["annotation_id"] == nil {
+		t.Errorf("import summary = %+v", imported.Data)
+	}
+	if upsert.Data["operation"] != models.BulkOperationCustomerUpsert || upsert.Data["updated"] != float64(1) || upsert.Data["skipped"] != float64(1) {
+		t.Errorf("upsert summary = %+v", upsert.Data)
+	}
+	var got models.WebhookSubscriptionResponse
+	decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d", quiet.ID)), &got)
+	if got.Stats != (models.WebhookDeliveryStats{Pending: 2}) {
+		t.Errorf("stats = %+v", got.Stats)
+	}
+
+	sent := deliveries(bulk.ID, "status=pending")
+	if len(sent) != 3 {
+		t.Fatalf("%d deliveries with bulk events, want 3", len(sent))
+	}
+	var event webhooks.Event
+	if err := json.Unmarshal([]byte(sent[0].Payload), &event); err != nil || event.Bulk != models.BulkOperationCustomerUpsert ||
+		event.Event != models.WebhookEventCustomerUpdated || event.Data["phone"] != "+966500000000" {
+		t.Errorf("bulk event = %s", sent[0].Payload)
+	}
+
+	// A change outside a bulk operation is sent as usual
+	if rec := s.do(t, admin, http.MethodPost, "/admin/customers", map[string]interface{}{"name": "Initech", "email": "ops@initech.example"}); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", rec.Code, rec.Body)
+	}
+	if pending := deliveries(quiet.ID, "status=pending&event_type=customer.created"); len(pending) != 1 {
+		t.Errorf("%d created events outside a bulk operation, want 1", len(pending))
+	}
+}
+
+// TestWebhookBulkStage moves deals in bulk and checks that their stage
+// changes are suppressed in favor of one bulk.completed event, unless the
+// request turns suppression off
+func TestWebhookBulkStage(t *testing.T) {
+	s := newServer(t)
+	var sub models.WebhookSubscriptionCreateResponse
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/webhooks", map[string]interface{}{
+		"name": "Stages", "url": "https://example.com/stages", "events": []string{"deal.stage_changed", "bulk.completed"},
+	}), &sub)
+	customer := s.Factory.Customer(t)
+	deals := func() []uint {
+		ids := make([]uint, 2)
+		for i := range ids {
+			ids[i] = s.Factory.Deal(t, customer, func(d *models.Deal) { d.Stage, d.NextStep = models.DealStageQualification, "Send the proposal" }).ID
+		}
+		return ids
+	}
+	count := func(filter string) int64 {
+		t.Helper()
+		var list models.WebhookDeliveryListResponse
+		decode(t, s.get(t, admin, fmt.Sprintf("/admin/webhooks/%d/deliveries?%s", sub.ID, filter)), &list)
+		return list.Total
+	}
+
+	var report handlers.BulkStageReport
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/deals/bulk-stage", map[string]interface{}{"ids": deals(), "stage": "proposal"}), &report)
+	if report.Updated != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if suppressed, completed := count("status=suppressed"), count("status=pending&event_type=bulk.completed"); suppressed != 2 || completed != 1 {
+		t.Errorf("%d suppressed and %d bulk.completed events, want 2 and 1", suppressed, completed)
+	}
+
+	decode(t, s.do(t, admin, http.MethodPost, "/admin/deals/bulk-stage", map[string]interface{}{
+		"ids": deals(), "stage": "proposal", "suppress_events": false,
+	}), &report)
+	if report.Updated != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if sent, completed := count("status=pending&event_type=deal.stage_changed"), count("event_type=bulk.completed"); sent != 2 || completed != 1 {
+		t.Errorf("%d stage changes and %d bulk.completed events without suppression, want 2 and 1", sent, completed)
+	}
+}
@@ -0,0 +1,104 @@
+// Package sandbox routes requests made with sandbox credentials to a
+// separate database of demo data, so integrators can try the API without
+// touching real records.
+package sandbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ContextKey marks a request context as sandboxed. It is a plain string so
+// the marker set on a gin.Context is visible through its Value method.
+const ContextKey = "sandbox"
+
+// enabled is set once the sandbox database is routed, before serving
+var enabled bool
+
+// With returns a copy of ctx whose queries run on the sandbox database
+func With(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ContextKey, true)
+}
+
+// Active reports whether ctx is sandboxed
+func Active(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	active, _ := ctx.Value(ContextKey).(bool)
+	return active
+}
+
+// Enabled reports whether a sandbox database is routed
+func Enabled() bool {
+	return enabled
+}
+
+// Route makes primary run every statement and transaction whose context is
+// sandboxed on the sandbox database instead. Routing happens below GORM, at
+// the connection pool, so handlers, callbacks and transactions need no
+// changes and a sandboxed statement can never reach the primary pool.
+// Statements without a sandboxed context, such as background jobs, keep
+// using the primary.
+func Route(primary, sandboxDB *gorm.DB) error {
+	primaryPool, ok := primary.ConnPool.(*sql.DB)
+	if !ok {
+		return errors.New("sandbox: primary connection pool is not a *sql.DB")
+	}
+	sandboxPool, ok := sandboxDB.ConnPool.(*sql.DB)
+	if !ok {
+		return errors.New("sandbox: sandbox connection pool is not a *sql.DB")
+	}
+
+	router := &pool{primary: primaryPool, sandbox: sandboxPool}
+	primary.ConnPool = router
+	primary.Statement.ConnPool = router
+	enabled = true
+	return nil
+}
+
+// pool picks the primary or sandbox connection pool from the context of
+// each call
+type pool struct {
+	primary *sql.DB
+	sandbox *sql.DB
+}
+
+// conn returns the pool serving ctx
+func (p *pool) conn(ctx context.Context) *sql.DB {
+	if Active(ctx) {
+		return p.sandbox
+	}
+	return p.primary
+}
+
+func (p *pool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.conn(ctx).PrepareContext(ctx, query)
+}
+
+func (p *pool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.conn(ctx).ExecContext(ctx, query, args...)
+}
+
+func (p *pool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.conn(ctx).QueryContext(ctx, query, args...)
+}
+
+func (p *pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.conn(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on the pool serving ctx; the statements of
+// the transaction then stay on its connection
+func (p *pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.conn(ctx).BeginTx(ctx, opts)
+}
+
+// GetDBConn returns the primary pool, so pings, pool settings and Close
+// keep applying to the primary database
+func (p *pool) GetDBConn() (*sql.DB, error) {
+	return p.primary, nil
+}
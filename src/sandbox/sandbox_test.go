@@ -0,0 +1,46 @@
+package sandbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+func TestRoute(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	primary, demo := testdb.New(t, now), testdb.New(t, now)
+	if err := sandbox.Route(primary.DB, demo.DB); err != nil {
+		t.Fatal(err)
+	}
+	ctx := sandbox.With(context.Background())
+
+	if err := primary.DB.WithContext(ctx).Create(&models.Customer{Name: "a", Email: "a@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Transactions stay on the pool they began on
+	err := primary.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&models.Customer{Name: "b", Email: "b@example.com"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.DB.Create(&models.Customer{Name: "c", Email: "c@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if demo.Count("customers") != 2 || primary.Count("customers") != 1 {
+		t.Errorf("customers: sandbox %d, primary %d; want 2, 1", demo.Count("customers"), primary.Count("customers"))
+	}
+	var names []string
+	if err := primary.DB.Model(&models.Customer{}).Order("name").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "c" {
+		t.Errorf("primary read = %v, want [c]", names)
+	}
+}
@@ -0,0 +1,159 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Engine names
+const (
+	EnginePostgres   = "postgres"
+	EngineOpenSearch = "opensearch"
+)
+
+// Document is the searchable form of a customer, contact, deal or activity
+type Document struct {
+	Type           string     `json:"type"`
+	ID             uint       `json:"id"`
+	Title          string     `json:"title"`
+	Subtitle       string     `json:"subtitle,omitempty"`
+	Text           string     `json:"text"`
+	Email          string     `json:"email,omitempty"` // Lowercased
+	Phone          string     `json:"phone,omitempty"` // Digits only
+	OwnerID        *uint      `json:"owner_id,omitempty"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+}
+
+// Ref identifies an indexed record
+type Ref struct {
+	Type string
+	ID   uint
+}
+
+// Indexer keeps documents searchable and answers search queries
+type Indexer interface {
+	// Name is the engine name reported with search results
+	Name() string
+	// IndexDocument adds or replaces a document
+	IndexDocument(ctx context.Context, doc Document) error
+	// Delete removes a document; deleting a missing document is not an error
+	Delete(ctx context.Context, ref Ref) error
+	// Query returns a page of the best matches, highest score first
+	Query(ctx context.Context, q Query) ([]models.SearchResult, error)
+	// QueryGroups returns the best q.Limit matches of each type and the
+	// number of matches of the type
+	QueryGroups(ctx context.Context, q Query) ([]models.SearchGroup, error)
+}
+
+// PostgresIndexer searches the database tables directly. There is no
+// separate index to maintain, so IndexDocument and Delete do nothing.
+type PostgresIndexer struct {
+	db      *gorm.DB
+	weights Weights
+}
+
+// NewPostgresIndexer creates a new PostgresIndexer searching db
+func NewPostgresIndexer(db *gorm.DB, weights Weights) *PostgresIndexer {
+	return &PostgresIndexer{db: db, weights: weights}
+}
+
+// Name returns the engine name
+func (p *PostgresIndexer) Name() string {
+	return EnginePostgres
+}
+
+// IndexDocument does nothing, the tables are the index
+func (p *PostgresIndexer) IndexDocument(ctx context.Context, doc Document) error {
+	return nil
+}
+
+// Delete does nothing, the tables are the index
+func (p *PostgresIndexer) Delete(ctx context.Context, ref Ref) error {
+	return nil
+}
+
+// Query runs Search against the database
+func (p *PostgresIndexer) Query(ctx context.Context, q Query) ([]models.SearchResult, error) {
+	return Search(ctx, p.db, p.weights, q)
+}
+
+// QueryGroups runs SearchGroups against the database
+func (p *PostgresIndexer) QueryGroups(ctx context.Context, q Query) ([]models.SearchGroup, error) {
+	return SearchGroups(ctx, p.db, p.weights, q)
+}
+
+// LoadDocuments loads the searchable documents of a record type ordered by
+// ID. With ids set only those records are loaded; otherwise up to limit
+// records after afterID. Deleted and archived records are left out.
+func LoadDocuments(ctx context.Context, db *gorm.DB, docType string, ids []uint, afterID uint, limit int) ([]Document, error) {
+	e, ok := entities[docType]
+	if !ok {
+		return nil, fmt.Errorf("unknown search type %q", docType)
+	}
+
+	where := "t.deleted_at IS NULL"
+	if e.archived {
+		where += " AND t.archived_at IS NULL"
+	}
+	args := map[string]interface{}{"after_id": afterID, "limit": limit, "ids": ids}
+	if ids != nil {
+		where += " AND t.id IN @ids"
+	} else {
+		where += " AND t.id > @after_id"
+	}
+
+	var document []string
+	for _, column := range e.document {
+		document = append(document, "NULLIF("+column+", '')")
+	}
+	lastActivity := "GREATEST(t.created_at, t.completed_at)"
+	if e.activity != "" {
+		lastActivity = "(SELECT MAX(GREATEST(a.created_at, a.completed_at)) FROM activities a WHERE a." + e.activity + " = t.id" +
+			" AND a.deleted_at IS NULL)"
+	}
+	email, phone := "''", "''"
+	if e.email != "" {
+		email = "LOWER(COALESCE(" + e.email + ", ''))"
+	}
+	if e.phone != "" {
+		phone = "REGEXP_REPLACE(COALESCE(" + e.phone + ", ''), '[^0-9]', '', 'g')"
+	}
+	sql := "SELECT t.id, " + e.title + " AS title, COALESCE(" + e.subtitle + ", '') AS subtitle, " +
+		"CONCAT_WS(' ', " + strings.Join(document, ", ") + ") AS text, " +
+		email + " AS email, " + phone + " AS phone, " + e.owner + " AS owner_id, " +
+		lastActivity + " AS last_activity_at" +
+		" FROM " + e.table + " t WHERE " + where + " ORDER BY t.id"
+	if ids == nil {
+		sql += " LIMIT @limit"
+	}
+
+	var docs []Document
+	if err := db.WithContext(ctx).Raw(sql, args).Scan(&docs).Error; err != nil {
+		return nil, err
+	}
+	for i := range docs {
+		docs[i].Type = docType
+	}
+	return docs, nil
+}
+
+// indexDocuments indexes docs with a single request when the indexer
+// supports bulk indexing, and one by one otherwise
+func indexDocuments(ctx context.Context, indexer Indexer, docs []Document) error {
+	if bulk, ok := indexer.(interface {
+		IndexDocuments(context.Context, []Document) error
+	}); ok {
+		return bulk.IndexDocuments(ctx, docs)
+	}
+	for _, doc := range docs {
+		if err := indexer.IndexDocument(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+func TestPostgresIndexer(t *testing.T) {
+	testDB := testdb.New(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	testIndexer(t, testDB.DB, NewPostgresIndexer(testDB.DB, testWeights))
+}
+
+// TestOpenSearchIndexer runs against the OpenSearch node at
+// TEST_OPENSEARCH_URL, in an index of its own that it drops afterwards
+func TestOpenSearchIndexer(t *testing.T) {
+	url := os.Getenv("TEST_OPENSEARCH_URL")
+	if url == "" {
+		t.Skip("TEST_OPENSEARCH_URL is not set")
+	}
+	testDB := testdb.New(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	indexer, err := NewOpenSearchIndexer(OpenSearchConfig{
+		URL:   url,
+		Index: "crm-test-" + strconv.FormatInt(time.Now().UnixNano(), 10),
+	}, testWeights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.EnsureIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, _, err := indexer.doOK(context.Background(), http.MethodDelete, "/"+indexer.cfg.Index, "", nil); err != nil {
+			t.Errorf("drop test index: %v", err)
+		}
+	})
+	testIndexer(t, testDB.DB, indexer)
+}
+
+// testIndexer checks an indexer kept up to date by a Sync on db. Both
+// engines must answer these the same way; ranking beyond the exact match
+// boost is left to each engine.
+func testIndexer(t *testing.T, db *gorm.DB, indexer Indexer) {
+	ctx := context.Background()
+	sync := NewSync(db, indexer, time.Second, nil)
+	if err := sync.Register(db); err != nil {
+		t.Fatal(err)
+	}
+	// flush indexes the queued writes and makes them searchable
+	flush := func(t *testing.T) {
+		t.Helper()
+		if err := sync.flush(ctx, true); err != nil {
+			t.Fatal(err)
+		}
+		if o, ok := indexer.(*OpenSearchIndexer); ok {
+			if _, _, err := o.doOK(ctx, http.MethodPost, "/"+o.cfg.Index+"/_refresh", "", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	agentID := uint(3)
+	f := factory.New(db)
+	huda := f.Customer(t, func(c *models.Customer) { c.Name, c.AssignedTo = "Huda Nasser", &agentID })
+	omar := f.Customer(t, func(c *models.Customer) { c.Name, c.Email = "Omar Saleh", "omar@nakheel.sa" })
+	f.Contact(t, omar, func(c *models.Contact) { c.FirstName, c.LastName = "Huda", "Ali" })
+	deal := f.Deal(t, huda, func(d *models.Deal) { d.Title, d.OwnerID = "Huda renewal", &agentID })
+	f.Activity(t, omar, func(a *models.Activity) { a.Title = "Call Omar" })
+	flush(t)
+
+	type hit struct {
+		Type  string
+		Title string
+	}
+	query := func(t *testing.T, q Query) []hit {
+		t.Helper()
+		if q.Limit == 0 {
+			q.Limit = 10
+		}
+		results, err := indexer.Query(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits := []hit{}
+		for _, r := range results {
+			hits = append(hits, hit{r.Type, r.Title})
+		}
+		return hits
+	}
+	// sameHits compares results regardless of their order
+	sameHits := func(got, want []hit) bool {
+		byKey := func(a, b hit) int {
+			return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Title, b.Title))
+		}
+		got, want = slices.Clone(got), slices.Clone(want)
+		slices.SortFunc(got, byKey)
+		slices.SortFunc(want, byKey)
+		return slices.Equal(got, want)
+	}
+
+	t.Run("matches every type", func(t *testing.T) {
+		want := []hit{
+			{models.SearchTypeCustomer, "Huda Nasser"},
+			{models.SearchTypeContact, "Huda Ali"},
+			{models.SearchTypeDeal, "Huda renewal"},
+		}
+		if got := query(t, Query{Text: "huda"}); !sameHits(got, want) {
+			t.Errorf("results = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("types filter", func(t *testing.T) {
+		want := []hit{{models.SearchTypeDeal, "Huda renewal"}}
+		if got := query(t, Query{Text: "huda", Types: []string{models.SearchTypeDeal}}); !sameHits(got, want) {
+			t.Errorf("results = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("exact email first", func(t *testing.T) {
+		got := query(t, Query{Text: "omar@nakheel.sa"})
+		if len(got) == 0 || got[0] != (hit{models.SearchTypeCustomer, "Omar Saleh"}) {
+			t.Errorf("results = %v, want Omar Saleh first", got)
+		}
+	})
+
+	t.Run("owned by", func(t *testing.T) {
+		want := []hit{
+			{models.SearchTypeCustomer, "Huda Nasser"},
+			{models.SearchTypeDeal, "Huda renewal"},
+		}
+		if got := query(t, Query{Text: "huda", OwnedBy: &agentID}); !sameHits(got, want) {
+			t.Errorf("results = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("paging", func(t *testing.T) {
+		all := query(t, Query{Text: "huda", Limit: 3})
+		var paged []hit
+		for offset := 0; offset < 4; offset++ {
+			paged = append(paged, query(t, Query{Text: "huda", Limit: 1, Offset: offset})...)
+		}
+		if len(all) != 3 || !slices.Equal(paged, all) {
+			t.Errorf("pages = %v, want %v", paged, all)
+		}
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		groups, err := indexer.QueryGroups(ctx, Query{Text: "huda", Limit: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		totals := make(map[string]int64)
+		for _, group := range groups {
+			if int64(len(group.Data)) != group.Total {
+				t.Errorf("%s: %d results for a total of %d", group.Type, len(group.Data), group.Total)
+			}
+			totals[group.Type] = group.Total
+		}
+		want := map[string]int64{
+			models.SearchTypeCustomer: 1, models.SearchTypeContact: 1,
+			models.SearchTypeDeal: 1, models.SearchTypeActivity: 0,
+		}
+		if !maps.Equal(totals, want) {
+			t.Errorf("totals = %v, want %v", totals, want)
+		}
+	})
+
+	t.Run("updates and deletes", func(t *testing.T) {
+		if err := db.Model(&omar).Update("name", "Omar Khalid").Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Delete(&deal).Error; err != nil {
+			t.Fatal(err)
+		}
+		flush(t)
+
+		want := []hit{{models.SearchTypeCustomer, "Omar Khalid"}}
+		if got := query(t, Query{Text: "khalid"}); !sameHits(got, want) {
+			t.Errorf("renamed: results = %v, want %v", got, want)
+		}
+		if got := query(t, Query{Text: "renewal"}); len(got) != 0 {
+			t.Errorf("deleted: results = %v, want none", got)
+		}
+	})
+}
@@ -0,0 +1,399 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// openSearchTimeout bounds each request to the engine, so an unreachable
+// engine falls back to Postgres quickly
+const openSearchTimeout = 5 * time.Second
+
+// openSearchIndexSettings creates the index with text fields analyzed the
+// way the Postgres search folds them: case, accents and Arabic variants
+const openSearchIndexSettings = `{
+  "settings": {
+    "analysis": {
+      "analyzer": {
+        "folded": {
+          "type": "custom",
+          "tokenizer": "standard",
+          "filter": ["lowercase", "asciifolding", "arabic_normalization"]
+        }
+      }
+    }
+  },
+  "mappings": {
+    "properties": {
+      "type":             {"type": "keyword"},
+      "id":               {"type": "long"},
+      "title":            {"type": "text", "analyzer": "folded"},
+      "subtitle":         {"type": "text", "analyzer": "folded"},
+      "text":             {"type": "text", "analyzer": "folded"},
+      "email":            {"type": "keyword"},
+      "phone":            {"type": "keyword"},
+      "owner_id":         {"type": "long"},
+      "last_activity_at": {"type": "date"},
+      "indexed_at":       {"type": "date"}
+    }
+  }
+}`
+
+// OpenSearchConfig configures an OpenSearchIndexer
+type OpenSearchConfig struct {
+	URL      string // Base URL of the cluster, such as http://localhost:9200
+	Index    string
+	Username string // Basic auth, when set
+	Password string
+}
+
+// OpenSearchIndexer keeps documents in an OpenSearch (or Elasticsearch)
+// index and searches it over the REST API
+type OpenSearchIndexer struct {
+	cfg     OpenSearchConfig
+	weights Weights
+	client  *http.Client
+}
+
+// NewOpenSearchIndexer creates a new OpenSearchIndexer
+func NewOpenSearchIndexer(cfg OpenSearchConfig, weights Weights) (*OpenSearchIndexer, error) {
+	base, err := url.Parse(cfg.URL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid OpenSearch URL %q", cfg.URL)
+	}
+	if cfg.Index == "" || strings.ContainsAny(cfg.Index, `/\*?"<>| ,#`) {
+		return nil, fmt.Errorf("invalid OpenSearch index name %q", cfg.Index)
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	return &OpenSearchIndexer{cfg: cfg, weights: weights, client: &http.Client{Timeout: openSearchTimeout}}, nil
+}
+
+// Name returns the engine name
+func (o *OpenSearchIndexer) Name() string {
+	return EngineOpenSearch
+}
+
+// indexedDocument is a Document as stored in the index
+type indexedDocument struct {
+	Document
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// documentID is the index ID of a record
+func documentID(ref Ref) string {
+	return ref.Type + "-" + strconv.FormatUint(uint64(ref.ID), 10)
+}
+
+// EnsureIndex creates the index when it does not exist yet
+func (o *OpenSearchIndexer) EnsureIndex(ctx context.Context) error {
+	status, _, err := o.do(ctx, http.MethodHead, "/"+o.cfg.Index, "", nil)
+	if err != nil || status == http.StatusOK {
+		return err
+	}
+	_, _, err = o.doOK(ctx, http.MethodPut, "/"+o.cfg.Index, "application/json", strings.NewReader(openSearchIndexSettings))
+	return err
+}
+
+// IndexDocument adds or replaces a document
+func (o *OpenSearchIndexer) IndexDocument(ctx context.Context, doc Document) error {
+	body, err := json.Marshal(indexedDocument{Document: doc, IndexedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, _, err = o.doOK(ctx, http.MethodPut, "/"+o.cfg.Index+"/_doc/"+documentID(Ref{doc.Type, doc.ID}),
+		"application/json", bytes.NewReader(body))
+	return err
+}
+
+// IndexDocuments adds or replaces documents with a single bulk request
+func (o *OpenSearchIndexer) IndexDocuments(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	now := time.Now()
+	for _, doc := range docs {
+		action := map[string]map[string]string{"index": {"_id": documentID(Ref{doc.Type, doc.ID})}}
+		if err := encoder.Encode(action); err != nil {
+			return err
+		}
+		if err := encoder.Encode(indexedDocument{Document: doc, IndexedAt: now}); err != nil {
+			return err
+		}
+	}
+
+	_, data, err := o.doOK(ctx, http.MethodPost, "/"+o.cfg.Index+"/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID    string          `json:"_id"`
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid OpenSearch bulk response: %w", err)
+	}
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, op := range item {
+				if len(op.Error) > 0 {
+					return fmt.Errorf("OpenSearch failed to index %s: %s", op.ID, op.Error)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Delete removes a document
+func (o *OpenSearchIndexer) Delete(ctx context.Context, ref Ref) error {
+	status, data, err := o.do(ctx, http.MethodDelete, "/"+o.cfg.Index+"/_doc/"+documentID(ref), "", nil)
+	if err != nil || status == http.StatusNotFound {
+		return err
+	}
+	return statusError(http.MethodDelete, status, data)
+}
+
+// DeleteIndexedBefore removes the documents last indexed before t, which a
+// full reindex started at t did not see again
+func (o *OpenSearchIndexer) DeleteIndexedBefore(ctx context.Context, t time.Time) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{"indexed_at": map[string]interface{}{"lt": t.Format(time.RFC3339Nano)}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, _, err = o.doOK(ctx, http.MethodPost, "/"+o.cfg.Index+"/_delete_by_query?conflicts=proceed",
+		"application/json", bytes.NewReader(body))
+	return err
+}
+
+// searchHit is a matching document with its score
+type searchHit struct {
+	Score  float64  `json:"_score"`
+	Source Document `json:"_source"`
+}
+
+// result converts a hit to a search result
+func (hit searchHit) result() models.SearchResult {
+	score := hit.Score
+	return models.SearchResult{
+		Type:     hit.Source.Type,
+		ID:       hit.Source.ID,
+		Title:    hit.Source.Title,
+		Subtitle: hit.Source.Subtitle,
+		Score:    &score,
+	}
+}
+
+// hitSort orders hits like Search orders results
+var hitSort = []interface{}{
+	map[string]string{"_score": "desc"},
+	map[string]string{"type": "asc"},
+	map[string]string{"id": "desc"},
+}
+
+// Query searches the index. Matches are scored like Search: text relevance
+// scaled by the text weight, plus the exact, prefix, owned and recent boosts.
+func (o *OpenSearchIndexer) Query(ctx context.Context, q Query) ([]models.SearchResult, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"from":  q.Offset,
+		"size":  q.Limit,
+		"sort":  hitSort,
+		"query": o.query(q),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, data, err := o.doOK(ctx, http.MethodPost, "/"+o.cfg.Index+"/_search", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Hits struct {
+			Hits []searchHit `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("invalid OpenSearch search response: %w", err)
+	}
+
+	results := make([]models.SearchResult, 0, len(response.Hits.Hits))
+	for _, hit := range response.Hits.Hits {
+		results = append(results, hit.result())
+	}
+	return results, nil
+}
+
+// QueryGroups searches the index like Query, returning the best q.Limit
+// matches of each type with the number of matches of the type
+func (o *OpenSearchIndexer) QueryGroups(ctx context.Context, q Query) ([]models.SearchGroup, error) {
+	types := queryTypes(q)
+	body, err := json.Marshal(map[string]interface{}{
+		"size":  0,
+		"query": o.query(q),
+		"aggs": map[string]interface{}{
+			"types": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "type", "size": len(types)},
+				"aggs": map[string]interface{}{
+					"top": map[string]interface{}{"top_hits": map[string]interface{}{"size": q.Limit, "sort": hitSort}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, data, err := o.doOK(ctx, http.MethodPost, "/"+o.cfg.Index+"/_search", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Aggregations struct {
+			Types struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int64  `json:"doc_count"`
+					Top      struct {
+						Hits struct {
+							Hits []searchHit `json:"hits"`
+						} `json:"hits"`
+					} `json:"top"`
+				} `json:"buckets"`
+			} `json:"types"`
+		} `json:"aggregations"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("invalid OpenSearch search response: %w", err)
+	}
+
+	// Types without matches have no bucket
+	groups := make([]models.SearchGroup, len(types))
+	for i, docType := range types {
+		groups[i] = models.SearchGroup{Type: docType, Data: []models.SearchResult{}}
+		for _, bucket := range response.Aggregations.Types.Buckets {
+			if bucket.Key != docType {
+				continue
+			}
+			groups[i].Total = bucket.DocCount
+			for _, hit := range bucket.Top.Hits.Hits {
+				groups[i].Data = append(groups[i].Data, hit.result())
+			}
+		}
+	}
+	return groups, nil
+}
+
+// query builds the scored query of a search
+func (o *OpenSearchIndexer) query(q Query) map[string]interface{} {
+	match := []interface{}{
+		map[string]interface{}{"multi_match": map[string]interface{}{
+			"query":  q.Text,
+			"type":   "bool_prefix",
+			"fields": []string{"title^2", "subtitle", "text"},
+			"boost":  o.weights.Text,
+		}},
+		map[string]interface{}{"term": map[string]interface{}{
+			"email": map[string]interface{}{"value": strings.ToLower(q.Text), "boost": o.weights.Exact},
+		}},
+		map[string]interface{}{"match_phrase_prefix": map[string]interface{}{
+			"title": map[string]interface{}{"query": q.Text, "boost": o.weights.Prefix},
+		}},
+	}
+	if digits := phoneDigits(q.Text); digits != "" {
+		match = append(match, map[string]interface{}{"term": map[string]interface{}{
+			"phone": map[string]interface{}{"value": digits, "boost": o.weights.Exact},
+		}})
+	}
+
+	// Boosts only add to the score of records that already match
+	boosts := []interface{}{
+		map[string]interface{}{"range": map[string]interface{}{
+			"last_activity_at": map[string]interface{}{
+				"gte":   time.Now().AddDate(0, 0, -o.weights.RecentDays).Format(time.RFC3339),
+				"boost": o.weights.Recent,
+			},
+		}},
+	}
+	if q.OwnerID != nil {
+		boosts = append(boosts, map[string]interface{}{"term": map[string]interface{}{
+			"owner_id": map[string]interface{}{"value": *q.OwnerID, "boost": o.weights.Owned},
+		}})
+	}
+
+	filter := []interface{}{map[string]interface{}{"terms": map[string]interface{}{"type": queryTypes(q)}}}
+	if q.OwnedBy != nil {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"owner_id": *q.OwnedBy}})
+	}
+
+	return map[string]interface{}{"bool": map[string]interface{}{
+		"filter": filter,
+		"must":   []interface{}{map[string]interface{}{"bool": map[string]interface{}{"should": match, "minimum_should_match": 1}}},
+		"should": boosts,
+	}}
+}
+
+// doOK sends a request and fails on any status other than 2xx
+func (o *OpenSearchIndexer) doOK(ctx context.Context, method, path, contentType string, body io.Reader) (int, []byte, error) {
+	status, data, err := o.do(ctx, method, path, contentType, body)
+	if err != nil {
+		return status, data, err
+	}
+	return status, data, statusError(method, status, data)
+}
+
+// do sends a request to the cluster and reads the response body
+func (o *OpenSearchIndexer) do(ctx context.Context, method, path, contentType string, body io.Reader) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, o.cfg.URL+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if o.cfg.Username != "" {
+		req.SetBasicAuth(o.cfg.Username, o.cfg.Password)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("OpenSearch unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read OpenSearch response: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// statusError returns an error for a response status other than 2xx
+func statusError(method string, status int, data []byte) error {
+	if status >= 200 && status < 300 {
+		return nil
+	}
+	const maxBody = 512
+	if len(data) > maxBody {
+		data = data[:maxBody]
+	}
+	return fmt.Errorf("OpenSearch %s returned %d: %s", method, status, data)
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// ReindexJobType is the job type of full reindexes
+const ReindexJobType = "search_reindex"
+
+// reindexBatchSize is how many records are indexed per batch
+const reindexBatchSize = 500
+
+// Reindex returns the job rebuilding the index from every searchable
+// record. Each batch adds a line to the job's report and checkpoints the
+// type and ID it reached, so the job's checkpoint shows its progress and a
+// failed reindex resumes where it stopped. A reindex that ran from the start
+// finally deletes the documents it did not see, which belong to records
+// removed without the sync noticing.
+func Reindex(indexer Indexer) exports.ResumableExporter {
+	return func(ctx context.Context, db *gorm.DB, params json.RawMessage, w io.Writer, resume exports.Resume) (int64, error) {
+		started := time.Now()
+		if ensure, ok := indexer.(interface{ EnsureIndex(context.Context) error }); ok {
+			if err := ensure.EnsureIndex(ctx); err != nil {
+				return 0, err
+			}
+		}
+
+		types := models.SearchTypes
+		afterID := uint(0)
+		if resume.From != nil {
+			index := slices.Index(types, resume.From.Key)
+			if index < 0 {
+				return 0, fmt.Errorf("unknown search type %q in reindex checkpoint", resume.From.Key)
+			}
+			types = types[index:]
+			afterID = uint(resume.From.ID)
+		} else if _, err := io.WriteString(w, "type,indexed,last_id\n"); err != nil {
+			return 0, err
+		}
+
+		var indexed int64
+		for _, docType := range types {
+			for {
+				docs, err := LoadDocuments(ctx, db, docType, nil, afterID, reindexBatchSize)
+				if err != nil {
+					return indexed, err
+				}
+				if len(docs) == 0 {
+					break
+				}
+
+				if err := indexDocuments(ctx, indexer, docs); err != nil {
+					return indexed, err
+				}
+
+				indexed += int64(len(docs))
+				afterID = docs[len(docs)-1].ID
+				if _, err := fmt.Fprintf(w, "%s,%d,%d\n", docType, len(docs), afterID); err != nil {
+					return indexed, err
+				}
+				if resume.Checkpoint != nil {
+					if err := resume.Checkpoint(exports.Cursor{Key: docType, ID: int64(afterID)}, indexed); err != nil {
+						return indexed, err
+					}
+				}
+				if len(docs) < reindexBatchSize {
+					break
+				}
+			}
+			afterID = 0
+		}
+
+		if resume.From == nil {
+			if pruner, ok := indexer.(interface {
+				DeleteIndexedBefore(context.Context, time.Time) error
+			}); ok {
+				if err := pruner.DeleteIndexedBefore(ctx, started); err != nil {
+					return indexed, err
+				}
+			}
+		}
+		return indexed, nil
+	}
+}
@@ -0,0 +1,339 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Weights scales each component of a search result's score. Text relevance
+// from ts_rank is usually well below 1, so a boost weight of 1 outweighs
+// most differences in wording.
+type Weights struct {
+	Text       float64 // Full-text relevance (ts_rank)
+	Exact      float64 // Exact email or phone match
+	Prefix     float64 // A word of the title starting with the query
+	Owned      float64 // Record owned by the searching agent
+	Recent     float64 // Activity on the record within RecentDays
+	RecentDays int
+}
+
+// Query is a search request
+type Query struct {
+	Text   string
+	Types  []string // Record types to search, all when empty
+	Limit  int
+	Offset int // Results to skip, for paging
+
+	// OwnerID boosts records owned by this user when set. Only agents get
+	// the ownership boost; managers and admins search the whole book evenly.
+	OwnerID *uint
+
+	// OwnedBy limits the results to records owned by this user when set,
+	// for users who cannot manage all records
+	OwnedBy *uint
+}
+
+// entity describes how one record type is matched and scored. Expressions
+// refer to the entity's table as t.
+type entity struct {
+	table    string
+	title    string   // Display title expression
+	subtitle string   // Display subtitle expression
+	document []string // Columns indexed for full-text relevance
+	folded   string   // Folded search column matched like the list search filters, empty when none
+	email    string   // Email column for exact matches, empty when none
+	phone    string   // Phone column for exact matches, empty when none
+	owner    string   // Owner expression
+	activity string   // activities column referencing the record, empty for activities themselves
+	archived bool     // Whether the table has archived_at
+}
+
+// entities maps each search type to its definition
+var entities = map[string]entity{
+	models.SearchTypeCustomer: {
+		table:    "customers",
+		title:    "t.name",
+		subtitle: "CONCAT_WS(' · ', NULLIF(t.company, ''), t.email)",
+		document: []string{"t.name", "t.email", "t.company"},
+		folded:   "t.search_text",
+		email:    "t.email",
+		phone:    "t.phone",
+		owner:    "t.assigned_to",
+		activity: "customer_id",
+		archived: true,
+	},
+	models.SearchTypeContact: {
+		table:    "contacts",
+		title:    "CONCAT_WS(' ', t.first_name, NULLIF(t.last_name, ''))",
+		subtitle: "NULLIF(t.email, '')",
+		document: []string{"t.first_name", "t.last_name", "t.email"},
+		folded:   "t.search_text",
+		email:    "t.email",
+		phone:    "t.phone",
+		owner:    "(SELECT c.assigned_to FROM customers c WHERE c.id = t.customer_id)",
+		activity: "contact_id",
+	},
+	models.SearchTypeDeal: {
+		table:    "deals",
+		title:    "t.title",
+		subtitle: "t.stage",
+		document: []string{"t.title", "t.description"},
+		folded:   "t.search_text",
+		owner:    "t.owner_id",
+		activity: "deal_id",
+		archived: true,
+	},
+	models.SearchTypeActivity: {
+		table:    "activities",
+		title:    "t.title",
+		subtitle: "CONCAT_WS(' · ', t.type, t.status)",
+		document: []string{"t.title"},
+		folded:   "t.search_text",
+		owner:    "t.assigned_to",
+	},
+}
+
+// minPhoneDigits is the number of digits a query needs to match phones exactly
+const minPhoneDigits = 6
+
+// Search returns the best matches across the requested record types, highest
+// score first. Each type is ranked by its own scoring expression:
+//
+//	text   * ts_rank(document, query)
+//	+ exact  when the email or phone equals the query
+//	+ prefix when a word of the title starts with the query
+//	+ owned  when the record belongs to the searching agent
+//	+ recent when the record had activity within the recent window
+func Search(ctx context.Context, db *gorm.DB, weights Weights, q Query) ([]models.SearchResult, error) {
+	args := queryArgs(weights, q)
+	args["limit"] = q.Offset + q.Limit
+
+	var results []models.SearchResult
+	for _, name := range queryTypes(q) {
+		e, ok := entities[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown search type %q", name)
+		}
+
+		rows, err := e.search(ctx, db, name, q, args)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rows...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if *results[i].Score != *results[j].Score {
+			return *results[i].Score > *results[j].Score
+		}
+		if results[i].Type != results[j].Type {
+			return results[i].Type < results[j].Type
+		}
+		return results[i].ID > results[j].ID
+	})
+	if q.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[q.Offset:]
+	if len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results, nil
+}
+
+// SearchGroups returns the best q.Limit matches of each requested record
+// type, ranked as Search ranks them, with the number of matches of the type.
+// q.Offset is ignored.
+func SearchGroups(ctx context.Context, db *gorm.DB, weights Weights, q Query) ([]models.SearchGroup, error) {
+	args := queryArgs(weights, q)
+	args["limit"] = q.Limit
+
+	var groups []models.SearchGroup
+	for _, name := range queryTypes(q) {
+		e, ok := entities[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown search type %q", name)
+		}
+
+		group := models.SearchGroup{Type: name}
+		if err := db.WithContext(ctx).Raw(e.countSQL(q), args).Scan(&group.Total).Error; err != nil {
+			return nil, err
+		}
+		var err error
+		if group.Data, err = e.search(ctx, db, name, q, args); err != nil {
+			return nil, err
+		}
+		if group.Data == nil {
+			group.Data = []models.SearchResult{}
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// queryTypes returns the record types a query searches
+func queryTypes(q Query) []string {
+	if len(q.Types) == 0 {
+		return models.SearchTypes
+	}
+	return q.Types
+}
+
+// queryArgs returns the named arguments of the search queries, but for the
+// row limit
+func queryArgs(weights Weights, q Query) map[string]interface{} {
+	args := map[string]interface{}{
+		"q":             q.Text,
+		"lower_q":       strings.ToLower(q.Text),
+		"like":          "%" + strings.ToLower(q.Text) + "%",
+		"word_prefix":   "% " + strings.ToLower(q.Text) + "%",
+		"digits":        phoneDigits(q.Text),
+		"recent_since":  time.Now().AddDate(0, 0, -weights.RecentDays),
+		"w_text":        weights.Text,
+		"w_exact":       weights.Exact,
+		"w_prefix":      weights.Prefix,
+		"w_owned":       weights.Owned,
+		"w_recent":      weights.Recent,
+		"owner_id":      uint(0),
+		"owner_boosted": q.OwnerID != nil,
+		"owned_by":      uint(0),
+	}
+	if q.OwnerID != nil {
+		args["owner_id"] = *q.OwnerID
+	}
+	if q.OwnedBy != nil {
+		args["owned_by"] = *q.OwnedBy
+	}
+	return args
+}
+
+// search runs the ranked query of the entity, whose search type is name
+func (e entity) search(ctx context.Context, db *gorm.DB, name string, q Query, args map[string]interface{}) ([]models.SearchResult, error) {
+	var rows []struct {
+		ID       uint
+		Title    string
+		Subtitle string
+		Score    float64
+	}
+	if err := db.WithContext(ctx).Raw(e.sql(q), args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var results []models.SearchResult
+	for _, row := range rows {
+		score := row.Score
+		results = append(results, models.SearchResult{
+			Type:     name,
+			ID:       row.ID,
+			Title:    row.Title,
+			Subtitle: row.Subtitle,
+			Score:    &score,
+		})
+	}
+	return results, nil
+}
+
+// tsvector is the full-text document of the entity. Migration 000040
+// indexes the same expression, so the two must stay in step.
+func (e entity) tsvector() string {
+	var document []string
+	for _, column := range e.document {
+		document = append(document, "COALESCE("+column+", '')")
+	}
+	return "to_tsvector('simple', " + strings.Join(document, " || ' ' || ") + ")"
+}
+
+// exact returns the conditions of an exact email or phone match
+func (e entity) exact() []string {
+	var exact []string
+	if e.email != "" {
+		exact = append(exact, "LOWER("+e.email+") = @lower_q")
+	}
+	if e.phone != "" {
+		exact = append(exact, "(@digits <> '' AND REGEXP_REPLACE("+e.phone+", '[^0-9]', '', 'g') = @digits)")
+	}
+	return exact
+}
+
+// where builds the condition selecting the live records matching the query
+// that the searching user may see
+func (e entity) where(q Query) string {
+	tsquery := "plainto_tsquery('simple', @q)"
+	match := []string{e.tsvector() + " @@ " + tsquery}
+
+	// Folding matches names typed with other Arabic orthographic variants.
+	// Substrings are matched on the folded column alone, so its trigram
+	// index serves the match.
+	if e.folded != "" {
+		match = append(match, e.folded+" LIKE crm_fold_text(@like)")
+	} else {
+		for _, column := range e.document {
+			match = append(match, "LOWER("+column+") LIKE @like")
+		}
+	}
+	match = append(match, e.exact()...)
+
+	where := "t.deleted_at IS NULL"
+	if e.archived {
+		where += " AND t.archived_at IS NULL"
+	}
+	if q.OwnedBy != nil {
+		where += " AND " + e.owner + " = @owned_by"
+	}
+	return where + " AND (" + strings.Join(match, " OR ") + ")"
+}
+
+// sql builds the ranked query for the entity
+func (e entity) sql(q Query) string {
+	score := []string{"@w_text * ts_rank(" + e.tsvector() + ", plainto_tsquery('simple', @q))"}
+	if exact := e.exact(); len(exact) > 0 {
+		score = append(score, "CASE WHEN "+strings.Join(exact, " OR ")+" THEN @w_exact ELSE 0 END")
+	}
+	// A word starting with the query ranks above the same letters inside a
+	// word, which ts_rank does not match at all
+	score = append(score, "CASE WHEN ' ' || crm_fold_text("+e.title+") LIKE crm_fold_text(@word_prefix) THEN @w_prefix ELSE 0 END")
+	score = append(score, "CASE WHEN @owner_boosted AND "+e.owner+" = @owner_id THEN @w_owned ELSE 0 END")
+	if e.activity != "" {
+		score = append(score, "CASE WHEN EXISTS (SELECT 1 FROM activities a WHERE a."+e.activity+" = t.id"+
+			" AND a.deleted_at IS NULL AND (a.created_at >= @recent_since OR a.completed_at >= @recent_since))"+
+			" THEN @w_recent ELSE 0 END")
+	} else {
+		score = append(score, "CASE WHEN t.created_at >= @recent_since OR t.completed_at >= @recent_since THEN @w_recent ELSE 0 END")
+	}
+
+	return "SELECT t.id, " + e.title + " AS title, COALESCE(" + e.subtitle + ", '') AS subtitle, " +
+		strings.Join(score, " + ") + " AS score" +
+		" FROM " + e.table + " t" +
+		" WHERE " + e.where(q) +
+		" ORDER BY score DESC, t.id DESC LIMIT @limit"
+}
+
+// countSQL builds the query counting the entity's matches
+func (e entity) countSQL(q Query) string {
+	return "SELECT COUNT(*) FROM " + e.table + " t WHERE " + e.where(q)
+}
+
+// phoneDigits returns the digits of a query that looks like a phone number,
+// or an empty string
+func phoneDigits(text string) string {
+	var digits strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune("+-() .", r):
+		default:
+			return ""
+		}
+	}
+	if digits.Len() < minPhoneDigits {
+		return ""
+	}
+	return digits.String()
+}
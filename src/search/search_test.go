@@ -0,0 +1,109 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+)
+
+// testWeights are the default search weights
+var testWeights = Weights{Text: 1, Exact: 5, Prefix: 0.5, Owned: 1, Recent: 0.5, RecentDays: 30}
+
+func TestSearchRanking(t *testing.T) {
+	testDB := testdb.New(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	customers := []models.Customer{
+		{Name: "Alhuda Foods", Email: "orders@alhudafoods.sa"},
+		{Name: "Hudaib Ali", Email: "hudaib@nakheel.sa"},
+		{Name: "Huda Nasser", Email: "huda@nakheel.sa"},
+		{Name: "Alhuda Trading", Email: "info@alhudatrading.sa"},
+		{Name: "Nasser Omar", Email: "nasser@nakheel.sa"},
+		{Name: "Sara Ali", Email: "sara.nasser@nakheel.sa"},
+		{Name: "Rawda Trading", Email: "info@rt.sa"},
+	}
+	for i := range customers {
+		customers[i].Status = models.CustomerStatusLead
+		if err := testDB.DB.Create(&customers[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	contact := models.Contact{CustomerID: customers[6].ID, FirstName: "Rawda", LastName: "Saleh", Email: "rs@rt.sa"}
+	if err := testDB.DB.Create(&contact).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	type hit struct {
+		Type  string
+		Title string
+	}
+	for _, tc := range []struct {
+		name string
+		text string
+		want []hit
+	}{
+		{
+			// A whole word outranks a word starting with the query, which
+			// outranks the query inside a word; the infix matches tie and
+			// the newer comes first
+			name: "word, prefix, infix",
+			text: "huda",
+			want: []hit{
+				{models.SearchTypeCustomer, "Huda Nasser"},
+				{models.SearchTypeCustomer, "Hudaib Ali"},
+				{models.SearchTypeCustomer, "Alhuda Trading"},
+				{models.SearchTypeCustomer, "Alhuda Foods"},
+			},
+		},
+		{
+			name: "prefix, infix",
+			text: "hud",
+			want: []hit{
+				{models.SearchTypeCustomer, "Huda Nasser"},
+				{models.SearchTypeCustomer, "Hudaib Ali"},
+				{models.SearchTypeCustomer, "Alhuda Trading"},
+				{models.SearchTypeCustomer, "Alhuda Foods"},
+			},
+		},
+		{
+			name: "exact email above a fuzzy one",
+			text: "nasser@nakheel.sa",
+			want: []hit{
+				{models.SearchTypeCustomer, "Nasser Omar"},
+				{models.SearchTypeCustomer, "Sara Ali"},
+			},
+		},
+		{
+			name: "equal scores ordered by type",
+			text: "rawda",
+			want: []hit{
+				{models.SearchTypeContact, "Rawda Saleh"},
+				{models.SearchTypeCustomer, "Rawda Trading"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q := Query{Text: tc.text, Types: []string{models.SearchTypeCustomer, models.SearchTypeContact}, Limit: 10}
+			// Ties must come back in the same order every time
+			for run := 0; run < 3; run++ {
+				results, err := Search(context.Background(), testDB.DB, testWeights, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []hit
+				for _, result := range results {
+					got = append(got, hit{result.Type, result.Title})
+				}
+				if len(got) != len(tc.want) {
+					t.Fatalf("results = %v, want %v", got, tc.want)
+				}
+				for i := range got {
+					if got[i] != tc.want[i] {
+						t.Fatalf("results = %v, want %v", got, tc.want)
+					}
+				}
+			}
+		})
+	}
+}
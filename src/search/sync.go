@@ -0,0 +1,318 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// syncBatchSize is how many records of a type are loaded per query when
+// syncing
+const syncBatchSize = 500
+
+// syncMaxAttempts is how many ticks a record may fail to index before it
+// is handed to the dead-letter queue
+const syncMaxAttempts = 5
+
+// SyncDeadLetterComponent is the dead-letter component of records the
+// sync could not index
+const SyncDeadLetterComponent = "search_sync"
+
+// syncDeadLetter lists records of one type the sync gave up on
+type syncDeadLetter struct {
+	Type string `json:"type"`
+	IDs  []uint `json:"ids"`
+}
+
+// trackedFields maps each table whose writes change search documents to the
+// fields referencing them. Activities are documents themselves and change
+// the recency of their records.
+var trackedFields = map[string][]struct {
+	field   string
+	docType string
+}{
+	"customers": {{"ID", models.SearchTypeCustomer}},
+	"contacts":  {{"ID", models.SearchTypeContact}},
+	"deals":     {{"ID", models.SearchTypeDeal}},
+	"activities": {
+		{"ID", models.SearchTypeActivity},
+		{"CustomerID", models.SearchTypeCustomer},
+		{"ContactID", models.SearchTypeContact},
+		{"DealID", models.SearchTypeDeal},
+	},
+}
+
+// Sync keeps an external index in step with the database. Writes through
+// GORM queue the records they touch, and every interval the records queued
+// before the previous tick are loaded and indexed, or deleted when they are
+// gone, so transactions that queued them have committed by then.
+type Sync struct {
+	db       *gorm.DB
+	indexer  Indexer
+	interval time.Duration
+	onErr    func(error)
+
+	mu          sync.Mutex
+	queued      map[Ref]struct{} // Queued since the last tick
+	settled     map[Ref]struct{} // Queued before the last tick, indexed next
+	failures    map[Ref]int      // Failed attempts of records still queued
+	deadLetters *deadletter.Queue
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSync creates a new Sync indexing into indexer every interval
+func NewSync(db *gorm.DB, indexer Indexer, interval time.Duration, onErr func(error)) *Sync {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &Sync{
+		db:       db,
+		indexer:  indexer,
+		interval: interval,
+		onErr:    onErr,
+		queued:   make(map[Ref]struct{}),
+		settled:  make(map[Ref]struct{}),
+		failures: make(map[Ref]int),
+	}
+}
+
+// DeadLetterTo hands records that failed to index syncMaxAttempts times to
+// queue instead of retrying them every tick, and lets the queue requeue
+// them for the next tick
+func (s *Sync) DeadLetterTo(queue *deadletter.Queue) {
+	s.mu.Lock()
+	s.deadLetters = queue
+	s.mu.Unlock()
+	queue.RegisterRetrier(SyncDeadLetterComponent, func(payload json.RawMessage) error {
+		var letter syncDeadLetter
+		if err := json.Unmarshal(payload, &letter); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, id := range letter.IDs {
+			s.queued[Ref{Type: letter.Type, ID: id}] = struct{}{}
+		}
+		return nil
+	})
+}
+
+// Register adds the callbacks queueing the records written through db.
+// Statements without the record's ID, such as bulk updates by condition,
+// are not seen; a full reindex catches up with them.
+func (s *Sync) Register(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("search:sync_create", s.track); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("search:sync_update", s.track); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("search:sync_delete", s.track)
+}
+
+// track queues the records a statement wrote
+func (s *Sync) track(db *gorm.DB) {
+	if db.Error != nil || db.RowsAffected == 0 || db.Statement.Schema == nil || sandbox.Active(db.Statement.Context) {
+		return
+	}
+	tracked, ok := trackedFields[db.Statement.Table]
+	if !ok {
+		return
+	}
+
+	ctx := db.Statement.Context
+	value := reflect.Indirect(db.Statement.ReflectValue)
+	var rows []reflect.Value
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			rows = append(rows, reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		rows = append(rows, value)
+	default:
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tracked {
+		field := db.Statement.Schema.LookUpField(t.field)
+		if field == nil {
+			continue
+		}
+		for _, row := range rows {
+			if id, ok := fieldID(ctx, field, row); ok {
+				s.queued[Ref{Type: t.docType, ID: id}] = struct{}{}
+			}
+		}
+	}
+}
+
+// fieldID reads a uint or *uint ID field of a row, reporting false when it
+// is unset
+func fieldID(ctx context.Context, field *schema.Field, row reflect.Value) (uint, bool) {
+	if row.Kind() != reflect.Struct {
+		return 0, false
+	}
+	value, zero := field.ValueOf(ctx, row)
+	if zero {
+		return 0, false
+	}
+	switch id := value.(type) {
+	case uint:
+		return id, true
+	case *uint:
+		if id != nil {
+			return *id, true
+		}
+	}
+	return 0, false
+}
+
+// Start launches the background loop
+func (s *Sync) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.flush(ctx, false); err != nil && ctx.Err() == nil {
+					s.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the background loop and indexes everything still queued
+func (s *Sync) Stop() error {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+	return s.flush(context.Background(), true)
+}
+
+// flush indexes the settled records and settles the ones queued since the
+// last tick, or indexes both when all is set. Records that fail stay queued
+// for the next tick.
+func (s *Sync) flush(ctx context.Context, all bool) error {
+	s.mu.Lock()
+	pending := s.settled
+	if all {
+		for ref := range s.queued {
+			pending[ref] = struct{}{}
+		}
+		s.queued = make(map[Ref]struct{})
+	}
+	s.settled = s.queued
+	s.queued = make(map[Ref]struct{})
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	byType := make(map[string][]uint)
+	for ref := range pending {
+		byType[ref.Type] = append(byType[ref.Type], ref.ID)
+	}
+
+	var errs []error
+	for docType, ids := range byType {
+		for start := 0; start < len(ids); start += syncBatchSize {
+			batch := ids[start:min(start+syncBatchSize, len(ids))]
+			err := s.index(ctx, docType, batch)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to index %d %s records: %w", len(batch), docType, err))
+			}
+			if err := s.settle(docType, batch, err); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// settle records the outcome of indexing a batch. Failed records are
+// queued for the next tick until they have failed syncMaxAttempts times,
+// when they go to the dead-letter queue if one is set.
+func (s *Sync) settle(docType string, ids []uint, cause error) error {
+	s.mu.Lock()
+	var exhausted []uint
+	attempts := 0
+	for _, id := range ids {
+		ref := Ref{Type: docType, ID: id}
+		if cause == nil {
+			delete(s.failures, ref)
+			continue
+		}
+		s.failures[ref]++
+		if s.deadLetters != nil && s.failures[ref] >= syncMaxAttempts {
+			attempts = max(attempts, s.failures[ref])
+			delete(s.failures, ref)
+			exhausted = append(exhausted, id)
+			continue
+		}
+		s.settled[ref] = struct{}{}
+	}
+	queue := s.deadLetters
+	s.mu.Unlock()
+
+	if len(exhausted) == 0 {
+		return nil
+	}
+	return queue.Add(SyncDeadLetterComponent, syncDeadLetter{Type: docType, IDs: exhausted}, cause, attempts)
+}
+
+// index indexes the records of a type that still exist and deletes the rest
+func (s *Sync) index(ctx context.Context, docType string, ids []uint) error {
+	docs, err := LoadDocuments(ctx, s.db, docType, ids, 0, 0)
+	if err != nil {
+		return err
+	}
+	found := make(map[uint]bool, len(docs))
+	for _, doc := range docs {
+		found[doc.ID] = true
+	}
+
+	if err := indexDocuments(ctx, s.indexer, docs); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			if err := s.indexer.Delete(ctx, Ref{Type: docType, ID: id}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+)
+
+// flakyIndexer fails while err is set and records the deletes it serves
+type flakyIndexer struct {
+	err     error
+	deleted []Ref
+}
+
+func (f *flakyIndexer) Name() string { return "flaky" }
+
+func (f *flakyIndexer) IndexDocument(context.Context, Document) error { return f.err }
+
+func (f *flakyIndexer) Delete(_ context.Context, ref Ref) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.deleted = append(f.deleted, ref)
+	return nil
+}
+
+func (f *flakyIndexer) Query(context.Context, Query) ([]models.SearchResult, error) { return nil, nil }
+
+func (f *flakyIndexer) QueryGroups(context.Context, Query) ([]models.SearchGroup, error) {
+	return nil, nil
+}
+
+func TestSyncDeadLettersExhaustedRecords(t *testing.T) {
+	testDB := testdb.New(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	indexer := &flakyIndexer{err: errors.New("index unavailable")}
+	queue := deadletter.NewQueue(testDB.DB)
+	s := NewSync(testDB.DB, indexer, time.Second, nil)
+	s.DeadLetterTo(queue)
+
+	gone := Ref{Type: models.SearchTypeCustomer, ID: 7}
+	s.queued[gone] = struct{}{}
+	for attempt := 1; attempt <= syncMaxAttempts; attempt++ {
+		if err := s.flush(context.Background(), true); err == nil {
+			t.Fatalf("attempt %d: flush succeeded against a failing index", attempt)
+		}
+		if letters := testDB.Count("dead_letters"); attempt < syncMaxAttempts && letters != 0 {
+			t.Fatalf("attempt %d: dead-lettered before retries were exhausted", attempt)
+		}
+	}
+
+	var letter models.DeadLetter
+	if err := testDB.DB.First(&letter).Error; err != nil {
+		t.Fatal(err)
+	}
+	if letter.Component != SyncDeadLetterComponent || letter.Attempts != syncMaxAttempts ||
+		letter.Payload != `{"type":"customer","ids":[7]}` || letter.Error != "index unavailable" {
+		t.Errorf("dead letter = %+v", letter)
+	}
+	if len(s.settled)+len(s.queued)+len(s.failures) != 0 {
+		t.Errorf("dead-lettered record is still queued")
+	}
+
+	// Retrying the letter queues the record for the next tick
+	indexer.err = nil
+	if err := queue.Retry(testDB.DB, &letter); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.flush(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	if len(indexer.deleted) != 1 || indexer.deleted[0] != gone {
+		t.Errorf("deleted = %v, want %v", indexer.deleted, gone)
+	}
+}
@@ -0,0 +1,58 @@
+package security
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// revocations holds when each revoked user's tokens stopped being accepted
+var (
+	revocationsMu sync.RWMutex
+	revocations   = map[uint]time.Time{}
+)
+
+// TokensRevokedAt returns the time up to which a user's tokens are revoked
+func TokensRevokedAt(userID uint) (time.Time, bool) {
+	revocationsMu.RLock()
+	defer revocationsMu.RUnlock()
+	revokedAt, ok := revocations[userID]
+	return revokedAt, ok
+}
+
+// LoadRevocations reads every token revocation from the database and makes
+// it the set checked by TokensRevokedAt
+func LoadRevocations(ctx context.Context, db *gorm.DB) error {
+	var rows []models.UserTokenRevocation
+	if err := db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	loaded := make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		loaded[row.UserID] = row.RevokedAt
+	}
+
+	revocationsMu.Lock()
+	revocations = loaded
+	revocationsMu.Unlock()
+	return nil
+}
+
+// setRevocation applies a revocation on this instance without waiting for
+// the next load
+func setRevocation(userID uint, revokedAt time.Time) {
+	revocationsMu.Lock()
+	defer revocationsMu.Unlock()
+	revocations[userID] = revokedAt
+}
+
+// clearRevocation lifts a revocation on this instance
+func clearRevocation(userID uint) {
+	revocationsMu.Lock()
+	defer revocationsMu.Unlock()
+	delete(revocations, userID)
+}
@@ -0,0 +1,299 @@
+// Package security watches per-user operation counts for signs of a
+// compromised token, such as mass deletions or exports, and raises alerts
+// that can revoke the user's tokens until an admin reviews them.
+package security
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAlreadyAcknowledged is returned when acknowledging a reviewed alert
+var ErrAlreadyAcknowledged = errors.New("security alert already acknowledged")
+
+// Reviewer identifies the admin acknowledging an alert
+type Reviewer struct {
+	UserID   uint
+	UserName string
+}
+
+// Monitor evaluates the alert rules against hourly security activity
+type Monitor struct {
+	db         *gorm.DB
+	rules      []models.SecurityAlertRule
+	autoRevoke bool
+	webhook    *Webhook
+	onAlert    func(models.SecurityAlert)
+}
+
+// NewMonitor creates a new Monitor. With autoRevoke an alert also revokes
+// the user's tokens; webhook may be nil. onAlert is called for every alert
+// raised, e.g. to log it.
+func NewMonitor(db *gorm.DB, rules []models.SecurityAlertRule, autoRevoke bool, webhook *Webhook, onAlert func(models.SecurityAlert)) *Monitor {
+	if onAlert == nil {
+		onAlert = func(models.SecurityAlert) {}
+	}
+	return &Monitor{
+		db:         db,
+		rules:      rules,
+		autoRevoke: autoRevoke,
+		webhook:    webhook,
+		onAlert:    onAlert,
+	}
+}
+
+// Rules returns the alert rules in effect
+func (m *Monitor) Rules() []models.SecurityAlertRule {
+	return m.rules
+}
+
+// Webhook returns the alert webhook, or nil when none is configured
+func (m *Monitor) Webhook() *Webhook {
+	return m.webhook
+}
+
+// Evaluate brings the record counts of the current and previous hour up to
+// date from the audit log, raises an alert for every rule a user exceeded
+// in those hours and, unless the webhook is at-most-once, retries alert
+// notifications that failed. It also
+// reloads token revocations made on other instances. It returns the alerts
+// raised.
+func (m *Monitor) Evaluate(ctx context.Context) ([]models.SecurityAlert, error) {
+	since := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	if err := m.rollup(ctx, since); err != nil {
+		return nil, err
+	}
+
+	var raised []models.SecurityAlert
+	var errs []error
+	if len(m.rules) > 0 {
+		var activity []models.SecurityActivity
+		if err := m.db.WithContext(ctx).Where("bucket_start >= ?", since).Find(&activity).Error; err != nil {
+			return nil, err
+		}
+		for _, hour := range activity {
+			for _, rule := range m.rules {
+				if hour.Count(rule.Metric) <= rule.Threshold {
+					continue
+				}
+				alert, err := m.raise(ctx, hour, rule)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if alert != nil {
+					raised = append(raised, *alert)
+					m.onAlert(*alert)
+				}
+			}
+		}
+	}
+
+	if err := m.notifyPending(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := LoadRevocations(ctx, m.db); err != nil {
+		errs = append(errs, err)
+	}
+	return raised, errors.Join(errs...)
+}
+
+// rollup recomputes the hourly record counts since a time from the audit
+// log. Entries without a user, such as those of service accounts, are left
+// out.
+func (m *Monitor) rollup(ctx context.Context, since time.Time) error {
+	return m.db.WithContext(ctx).Exec(`
+		INSERT INTO security_activity (user_id, bucket_start, records_changed, records_deleted)
+		SELECT user_id,
+		       TO_TIMESTAMP(FLOOR(EXTRACT(EPOCH FROM created_at) / 3600) * 3600) AS bucket_start,
+		       COUNT(*) FILTER (WHERE action <> ?),
+		       COUNT(*) FILTER (WHERE action = ?)
+		FROM audit_logs
+		WHERE created_at >= ? AND user_id <> 0
+		GROUP BY 1, 2
+		ON CONFLICT (user_id, bucket_start) DO UPDATE
+		SET records_changed = EXCLUDED.records_changed,
+		    records_deleted = EXCLUDED.records_deleted`,
+		models.AuditActionDelete, models.AuditActionDelete, since,
+	).Error
+}
+
+// raise records an alert for a user exceeding a rule in an hour, revoking
+// the user's tokens when configured. It returns nil when the alert was
+// already raised.
+func (m *Monitor) raise(ctx context.Context, hour models.SecurityActivity, rule models.SecurityAlertRule) (*models.SecurityAlert, error) {
+	alert := models.SecurityAlert{
+		UserID:      hour.UserID,
+		BucketStart: hour.BucketStart,
+		Metric:      rule.Metric,
+		Threshold:   rule.Threshold,
+		Value:       hour.Count(rule.Metric),
+		Status:      models.SecurityAlertOpen,
+		EventID:     uuid.NewString(),
+	}
+
+	var created bool
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		created = true
+		if !m.autoRevoke {
+			return nil
+		}
+
+		revocation := models.UserTokenRevocation{UserID: alert.UserID, RevokedAt: alert.CreatedAt, AlertID: &alert.ID}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&revocation).Error; err != nil {
+			return err
+		}
+		alert.TokensRevoked = true
+		return tx.Model(&alert).Update("tokens_revoked", true).Error
+	})
+	if err != nil || !created {
+		return nil, err
+	}
+
+	if alert.TokensRevoked {
+		setRevocation(alert.UserID, alert.CreatedAt)
+	}
+	// A failed delivery is retried by the next evaluation, unless the
+	// webhook is at-most-once
+	if m.webhook != nil {
+		m.notify(ctx, &alert)
+	}
+	return &alert, nil
+}
+
+// notifyPending resends open alerts of the last day the webhook has not
+// accepted yet. An at-most-once webhook only gets alerts never sent to it,
+// such as those raised while the database was unreachable.
+func (m *Monitor) notifyPending(ctx context.Context) error {
+	if m.webhook == nil {
+		return nil
+	}
+
+	db := m.db.WithContext(ctx).
+		Where("status = ? AND notified_at IS NULL AND created_at >= ?", models.SecurityAlertOpen, time.Now().Add(-24*time.Hour))
+	if m.webhook.Mode() == models.SecurityWebhookAtMostOnce {
+		db = db.Where("NOT EXISTS (SELECT 1 FROM security_webhook_deliveries d WHERE d.alert_id = security_alerts.id)")
+	}
+	var pending []models.SecurityAlert
+	if err := db.Order("id ASC").Find(&pending).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range pending {
+		if err := m.notify(ctx, &pending[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notify posts an alert to the webhook as a new delivery, recording it
+// with its response. The delivery is recorded before it is sent, so an
+// at-most-once webhook never gets an alert twice; when it does not accept
+// the alert, the alert is marked failed instead of retried.
+func (m *Monitor) notify(ctx context.Context, alert *models.SecurityAlert) error {
+	db := m.db.WithContext(ctx)
+	var attempts int64
+	if err := db.Model(&models.SecurityWebhookDelivery{}).Where("alert_id = ?", alert.ID).Count(&attempts).Error; err != nil {
+		return err
+	}
+	delivery := models.SecurityWebhookDelivery{
+		DeliveryID: uuid.NewString(),
+		EventID:    alert.EventID,
+		AlertID:    alert.ID,
+		Attempt:    int(attempts) + 1,
+		Status:     models.SecurityWebhookDeliveryPending,
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return err
+	}
+
+	sendErr := m.webhook.Deliver(ctx, *alert, &delivery)
+	now := time.Now()
+	delivery.Status, delivery.CompletedAt = models.SecurityWebhookDeliveryDelivered, &now
+	if sendErr != nil {
+		delivery.Status = models.SecurityWebhookDeliveryFailed
+	}
+	err := db.Model(&delivery).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"response_status": delivery.ResponseStatus,
+		"response_body":   delivery.ResponseBody,
+		"error":           delivery.Error,
+		"completed_at":    now,
+	}).Error
+
+	switch {
+	case sendErr == nil:
+		alert.NotifiedAt = &now
+		err = errors.Join(err, db.Model(alert).Update("notified_at", now).Error)
+	case m.webhook.Mode() == models.SecurityWebhookAtMostOnce:
+		alert.NotifyFailedAt = &now
+		err = errors.Join(err, db.Model(alert).Update("notify_failed_at", now).Error)
+	}
+	return errors.Join(sendErr, err)
+}
+
+// Acknowledge closes an open alert after review. Marking it a false
+// positive lifts the token revocation it applied, so the user's existing
+// tokens work again. record, when set, runs in the acknowledging
+// transaction once alert is updated, to write its audit entry.
+func (m *Monitor) Acknowledge(ctx context.Context, alert *models.SecurityAlert, by Reviewer, note string, falsePositive bool, record func(tx *gorm.DB) error) error {
+	if alert.Status == models.SecurityAlertAcknowledged {
+		return ErrAlreadyAcknowledged
+	}
+
+	now := time.Now()
+	var lifted bool
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(alert).Where("status = ?", models.SecurityAlertOpen).Updates(map[string]interface{}{
+			"status":               models.SecurityAlertAcknowledged,
+			"false_positive":       falsePositive,
+			"note":                 note,
+			"acknowledged_by":      by.UserID,
+			"acknowledged_by_name": by.UserName,
+			"acknowledged_at":      now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyAcknowledged
+		}
+		if falsePositive && alert.TokensRevoked {
+			// Only the revocation this alert applied is lifted; a later
+			// alert's revocation stays until that alert is reviewed
+			result = tx.Where("user_id = ? AND alert_id = ?", alert.UserID, alert.ID).Delete(&models.UserTokenRevocation{})
+			if result.Error != nil {
+				return result.Error
+			}
+			lifted = result.RowsAffected > 0
+		}
+
+		if err := tx.First(alert, alert.ID).Error; err != nil {
+			return err
+		}
+		if record == nil {
+			return nil
+		}
+		return record(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	if lifted {
+		clearRevocation(alert.UserID)
+	}
+	return nil
+}
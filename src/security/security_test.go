@@ -0,0 +1,136 @@
+package security_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// deletions writes n audited deletions by a user
+func deletions(t *testing.T, db *gorm.DB, userID uint, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := db.Create(&models.AuditLog{ResourceType: "customer", ResourceID: uint(i + 1), Action: models.AuditActionDelete, UserID: userID}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEvaluateRaisesAlertsOverThreshold(t *testing.T) {
+	db := testdb.Open(t)
+	rules := []models.SecurityAlertRule{{Metric: models.SecurityMetricRecordsDeleted, Threshold: 2}}
+	monitor := security.NewMonitor(db, rules, false, nil, nil)
+	deletions(t, db, 11, 3)
+	deletions(t, db, 12, 2) // At the threshold, not over it
+
+	raised, err := monitor.Evaluate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raised) != 1 {
+		t.Fatalf("raised %+v, want one alert", raised)
+	}
+	alert := raised[0]
+	if alert.UserID != 11 || alert.Metric != models.SecurityMetricRecordsDeleted || alert.Value != 3 || alert.Threshold != 2 ||
+		alert.Status != models.SecurityAlertOpen || alert.TokensRevoked {
+		t.Errorf("alert = %+v", alert)
+	}
+	if _, revoked := security.TokensRevokedAt(11); revoked {
+		t.Error("tokens revoked without auto-revocation")
+	}
+
+	// The hour's alert is raised once, however often it is evaluated
+	deletions(t, db, 11, 1)
+	if raised, err := monitor.Evaluate(context.Background()); err != nil || len(raised) != 0 {
+		t.Errorf("second evaluation raised %+v, %v", raised, err)
+	}
+	var activity models.SecurityActivity
+	if err := db.Where("user_id = ?", 11).First(&activity).Error; err != nil {
+		t.Fatal(err)
+	}
+	if activity.RecordsDeleted != 4 {
+		t.Errorf("records_deleted = %d, want the rollup brought up to 4", activity.RecordsDeleted)
+	}
+}
+
+func TestAutoRevocationAndAcknowledge(t *testing.T) {
+	db := testdb.Open(t)
+	rules := []models.SecurityAlertRule{{Metric: models.SecurityMetricRecordsDeleted, Threshold: 0}}
+	monitor := security.NewMonitor(db, rules, true, nil, nil)
+	deletions(t, db, 21, 1)
+	deletions(t, db, 22, 1)
+
+	raised, err := monitor.Evaluate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts := map[uint]models.SecurityAlert{}
+	for _, alert := range raised {
+		alerts[alert.UserID] = alert
+	}
+	for _, userID := range []uint{21, 22} {
+		if !alerts[userID].TokensRevoked {
+			t.Errorf("user %d: alert = %+v, want the tokens revoked", userID, alerts[userID])
+		}
+		var revocation models.UserTokenRevocation
+		if err := db.First(&revocation, "user_id = ?", userID).Error; err != nil {
+			t.Fatalf("user %d: %v", userID, err)
+		}
+		if revocation.AlertID == nil || *revocation.AlertID != alerts[userID].ID {
+			t.Errorf("user %d: revocation = %+v", userID, revocation)
+		}
+		if _, revoked := security.TokensRevokedAt(userID); !revoked {
+			t.Errorf("user %d: tokens still accepted", userID)
+		}
+	}
+
+	// A confirmed alert keeps the revocation; a false positive lifts it
+	reviewer := security.Reviewer{UserID: 1, UserName: "Admin"}
+	confirmed, falsePositive := alerts[21], alerts[22]
+	var recorded int
+	record := func(*gorm.DB) error { recorded++; return nil }
+	if err := monitor.Acknowledge(context.Background(), &confirmed, reviewer, "Bulk cleanup was not approved", false, record); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor.Acknowledge(context.Background(), &falsePositive, reviewer, "Planned cleanup", true, record); err != nil {
+		t.Fatal(err)
+	}
+	if recorded != 2 {
+		t.Errorf("audit recorded %d times, want 2", recorded)
+	}
+	if confirmed.Status != models.SecurityAlertAcknowledged || confirmed.AcknowledgedBy == nil || *confirmed.AcknowledgedBy != 1 ||
+		confirmed.AcknowledgedByName != "Admin" || confirmed.AcknowledgedAt == nil || confirmed.FalsePositive {
+		t.Errorf("confirmed = %+v", confirmed)
+	}
+	if _, revoked := security.TokensRevokedAt(21); !revoked {
+		t.Error("confirmed alert lifted the revocation")
+	}
+	if _, revoked := security.TokensRevokedAt(22); revoked {
+		t.Error("false positive kept the revocation")
+	}
+	var left []models.UserTokenRevocation
+	if err := db.Find(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].UserID != 21 {
+		t.Errorf("revocations = %+v, want user 21's alone", left)
+	}
+
+	// An alert is acknowledged once, even from a stale copy
+	stale := alerts[21]
+	if err := monitor.Acknowledge(context.Background(), &confirmed, reviewer, "", false, nil); !errors.Is(err, security.ErrAlreadyAcknowledged) {
+		t.Errorf("second acknowledgement: err = %v", err)
+	}
+	if err := monitor.Acknowledge(context.Background(), &stale, reviewer, "", true, nil); !errors.Is(err, security.ErrAlreadyAcknowledged) {
+		t.Errorf("stale acknowledgement: err = %v", err)
+	}
+	if _, revoked := security.TokensRevokedAt(21); !revoked {
+		t.Error("refused acknowledgement lifted the revocation")
+	}
+}
@@ -0,0 +1,98 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// webhookTimeout bounds one alert delivery
+const webhookTimeout = 10 * time.Second
+
+// maxResponseSnippet bounds how much of a webhook response is kept with the
+// delivery
+const maxResponseSnippet = 1024
+
+// Alert webhook headers. The event ID is the alert's and is the same on
+// every retry; the delivery ID is new on every attempt.
+const (
+	EventIDHeader    = "X-Webhook-Event-ID"
+	DeliveryIDHeader = "X-Webhook-Delivery-ID"
+)
+
+// WebhookEvent is the body posted to the alert webhook
+type WebhookEvent struct {
+	Event      string               `json:"event"` // Always "security.alert"
+	EventID    string               `json:"event_id"`
+	DeliveryID string               `json:"delivery_id"`
+	Alert      models.SecurityAlert `json:"alert"`
+}
+
+// Webhook posts security alerts to an external URL, such as a chat or
+// incident tool integration
+type Webhook struct {
+	url    string
+	mode   models.SecurityWebhookMode
+	client *http.Client
+}
+
+// NewWebhook creates a Webhook posting to url, or returns nil when url is
+// empty. mode decides whether failed deliveries are retried.
+func NewWebhook(url string, mode models.SecurityWebhookMode) *Webhook {
+	if url == "" {
+		return nil
+	}
+	return &Webhook{url: url, mode: mode, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Mode returns how the webhook handles deliveries that fail
+func (w *Webhook) Mode() models.SecurityWebhookMode {
+	return w.mode
+}
+
+// Deliver posts an alert as delivery, recording the response status and
+// the start of its body on it; any 2xx response counts as delivered
+func (w *Webhook) Deliver(ctx context.Context, alert models.SecurityAlert, delivery *models.SecurityWebhookDelivery) error {
+	body, err := json.Marshal(WebhookEvent{
+		Event:      "security.alert",
+		EventID:    delivery.EventID,
+		DeliveryID: delivery.DeliveryID,
+		Alert:      alert,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(DeliveryIDHeader, delivery.DeliveryID)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	delivery.ResponseStatus = resp.StatusCode
+	// Text columns hold neither invalid UTF-8 nor NUL
+	delivery.ResponseBody = strings.ReplaceAll(strings.ToValidUTF8(string(snippet), ""), "\x00", "")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("security alert webhook responded with status %d", resp.StatusCode)
+		delivery.Error = err.Error()
+		return err
+	}
+	return nil
+}
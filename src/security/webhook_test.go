@@ -0,0 +1,162 @@
+package security_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"gorm.io/gorm"
+)
+
+// receiver is an alert webhook answering with the queued responses, then
+// 200, and recording what it receives
+type receiver struct {
+	mu        sync.Mutex
+	responses []int
+	body      string
+	received  []security.WebhookEvent
+	headers   []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	raw, _ := io.ReadAll(req.Body)
+	var event security.WebhookEvent
+	json.Unmarshal(raw, &event)
+	r.received = append(r.received, event)
+	r.headers = append(r.headers, req.Header.Clone())
+
+	status := http.StatusOK
+	if len(r.responses) > 0 {
+		status, r.responses = r.responses[0], r.responses[1:]
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, r.body)
+}
+
+// deliveries returns the recorded deliveries in the order they were made
+func deliveries(t *testing.T, db *gorm.DB) []models.SecurityWebhookDelivery {
+	t.Helper()
+	var deliveries []models.SecurityWebhookDelivery
+	if err := db.Order("id ASC").Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func TestWebhookRetriesWithTheSameEventID(t *testing.T) {
+	db := testdb.Open(t)
+	hook := &receiver{responses: []int{http.StatusServiceUnavailable}, body: "busy " + strings.Repeat("x", 2000)}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+	rules := []models.SecurityAlertRule{{Metric: models.SecurityMetricRecordsDeleted, Threshold: 0}}
+	monitor := security.NewMonitor(db, rules, false, security.NewWebhook(server.URL, models.SecurityWebhookAtLeastOnce), nil)
+	deletions(t, db, 31, 1)
+
+	// The first delivery is refused and retried
+	raised, err := monitor.Evaluate(context.Background())
+	if err != nil || len(raised) != 1 {
+		t.Fatalf("raised %+v, %v", raised, err)
+	}
+	alert := raised[0]
+	if alert.EventID == "" || alert.NotifiedAt != nil || alert.NotifyFailedAt != nil {
+		t.Fatalf("alert = %+v", alert)
+	}
+	if _, err := monitor.Evaluate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	records := deliveries(t, db)
+	if len(records) != 2 || len(hook.received) != 2 {
+		t.Fatalf("%d deliveries recorded, %d received, want 2", len(records), len(hook.received))
+	}
+	failed, delivered := records[0], records[1]
+	if failed.Attempt != 1 || failed.Status != models.SecurityWebhookDeliveryFailed || failed.ResponseStatus != http.StatusServiceUnavailable ||
+		len(failed.ResponseBody) != 1024 || !strings.HasPrefix(failed.ResponseBody, "busy x") || failed.Error == "" || failed.CompletedAt == nil {
+		t.Errorf("first delivery = %+v", failed)
+	}
+	if delivered.Attempt != 2 || delivered.Status != models.SecurityWebhookDeliveryDelivered || delivered.ResponseStatus != http.StatusOK || delivered.Error != "" {
+		t.Errorf("second delivery = %+v", delivered)
+	}
+	for i, record := range records {
+		header, event := hook.headers[i], hook.received[i]
+		if record.EventID != alert.EventID || record.AlertID != alert.ID ||
+			header.Get(security.EventIDHeader) != alert.EventID || event.EventID != alert.EventID ||
+			header.Get(security.DeliveryIDHeader) != record.DeliveryID || event.DeliveryID != record.DeliveryID ||
+			event.Event != "security.alert" || event.Alert.ID != alert.ID {
+			t.Errorf("delivery %d: record %+v, headers %v, event %+v", i+1, record, header, event)
+		}
+	}
+	if failed.DeliveryID == delivered.DeliveryID {
+		t.Error("retry reused the delivery ID")
+	}
+
+	// Once accepted, the alert is not sent again
+	if _, err := monitor.Evaluate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(deliveries(t, db)); n != 2 {
+		t.Errorf("%d deliveries after acceptance, want 2", n)
+	}
+	if err := db.First(&alert, alert.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if alert.NotifiedAt == nil || alert.NotifyFailedAt != nil {
+		t.Errorf("alert = %+v", alert)
+	}
+}
+
+func TestWebhookAtMostOnce(t *testing.T) {
+	db := testdb.Open(t)
+	hook := &receiver{responses: []int{http.StatusInternalServerError}, body: `{"error":"down"}`}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+	rules := []models.SecurityAlertRule{{Metric: models.SecurityMetricRecordsDeleted, Threshold: 0}}
+	monitor := security.NewMonitor(db, rules, false, security.NewWebhook(server.URL, models.SecurityWebhookAtMostOnce), nil)
+	deletions(t, db, 41, 1)
+
+	raised, err := monitor.Evaluate(context.Background())
+	if err != nil || len(raised) != 1 {
+		t.Fatalf("raised %+v, %v", raised, err)
+	}
+	// The refused alert is marked failed and never sent again
+	for i := 0; i < 2; i++ {
+		if _, err := monitor.Evaluate(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := deliveries(t, db)
+	if len(records) != 1 || len(hook.received) != 1 {
+		t.Fatalf("%d deliveries recorded, %d received, want 1", len(records), len(hook.received))
+	}
+	if record := records[0]; record.Status != models.SecurityWebhookDeliveryFailed || record.ResponseStatus != http.StatusInternalServerError ||
+		record.ResponseBody != `{"error":"down"}` || record.EventID != raised[0].EventID {
+		t.Errorf("delivery = %+v", record)
+	}
+	var alert models.SecurityAlert
+	if err := db.First(&alert, raised[0].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if alert.NotifyFailedAt == nil || alert.NotifiedAt != nil {
+		t.Errorf("alert = %+v", alert)
+	}
+
+	// An accepted alert is marked notified as usual
+	deletions(t, db, 42, 1)
+	if raised, err := monitor.Evaluate(context.Background()); err != nil || len(raised) != 1 || raised[0].NotifiedAt == nil || raised[0].NotifyFailedAt != nil {
+		t.Errorf("raised %+v, %v", raised, err)
+	}
+	if len(hook.received) != 2 {
+		t.Errorf("%d alerts received, want 2", len(hook.received))
+	}
+}
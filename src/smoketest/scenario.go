@@ -0,0 +1,94 @@
+package smoketest
+
+import "net/http"
+
+// JobType is the job type smoke test runs are recorded under
+const JobType = "smoke_test"
+
+// PostDeploy walks the critical sales flow: it creates a customer with a
+// contact and a deal, advances the deal, logs and completes an activity,
+// loads the reports and then deletes what it created. Records are named
+// after the {tag} variable, and it expects {next_stage} to be an open stage
+// the deal can advance to.
+var PostDeploy = []Step{
+	{
+		Name:    "create_customer",
+		Method:  http.MethodPost,
+		Path:    "/admin/customers",
+		Body:    `{"name": "{tag}", "email": "{tag}@smoke-test.invalid", "company": "Smoke Test"}`,
+		Expect:  []int{http.StatusCreated},
+		Capture: map[string]string{"customer_id": "id"},
+	},
+	{
+		Name:    "add_contact",
+		Method:  http.MethodPost,
+		Path:    "/admin/customers/{customer_id}/contacts",
+		Body:    `{"first_name": "Smoke", "last_name": "{tag}", "email": "contact-{tag}@smoke-test.invalid"}`,
+		Expect:  []int{http.StatusCreated},
+		Capture: map[string]string{"contact_id": "id"},
+	},
+	{
+		Name:    "create_deal",
+		Method:  http.MethodPost,
+		Path:    "/admin/deals",
+		Body:    `{"title": "{tag}", "customer_id": {customer_id}, "contact_id": {contact_id}, "amount": 1000}`,
+		Expect:  []int{http.StatusCreated},
+		Capture: map[string]string{"deal_id": "id"},
+	},
+	{
+		Name:   "advance_stage",
+		Method: http.MethodPatch,
+		Path:   "/admin/deals/{deal_id}",
+		Body:   `{"stage": "{next_stage}", "next_step": "Smoke test follow-up"}`,
+		Expect: []int{http.StatusOK},
+	},
+	{
+		Name:    "log_activity",
+		Method:  http.MethodPost,
+		Path:    "/admin/activities",
+		Body:    `{"title": "{tag}", "type": "call", "customer_id": {customer_id}, "contact_id": {contact_id}, "deal_id": {deal_id}}`,
+		Expect:  []int{http.StatusCreated},
+		Capture: map[string]string{"activity_id": "id"},
+	},
+	{
+		Name:   "complete_activity",
+		Method: http.MethodPost,
+		Path:   "/admin/activities/{activity_id}/complete",
+		Body:   `{"outcome": "Smoke test"}`,
+		Expect: []int{http.StatusOK},
+	},
+	{
+		Name:   "query_reports",
+		Method: http.MethodGet,
+		Path:   "/admin/reports/overview",
+		Expect: []int{http.StatusOK},
+	},
+	{
+		Name:    "delete_activity",
+		Method:  http.MethodDelete,
+		Path:    "/admin/activities/{activity_id}",
+		Expect:  []int{http.StatusOK},
+		Cleanup: true,
+	},
+	{
+		Name:    "delete_deal",
+		Method:  http.MethodDelete,
+		Path:    "/admin/deals/{deal_id}",
+		Expect:  []int{http.StatusOK},
+		Cleanup: true,
+	},
+	{
+		Name:    "delete_contact",
+		Method:  http.MethodDelete,
+		Path:    "/admin/contacts/{contact_id}",
+		Expect:  []int{http.StatusOK},
+		Cleanup: true,
+	},
+	{
+		Name:    "delete_customer",
+		Method:  http.MethodDelete,
+		Path:    "/admin/customers/{customer_id}",
+		Expect:  []int{http.StatusOK, http.StatusAccepted},
+		Cleanup: true,
+	},
+}
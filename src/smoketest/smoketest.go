@@ -0,0 +1,190 @@
+// Package smoketest runs scripted request scenarios against the service's
+// own router, so a deploy can be checked end to end through the same
+// middleware, validation and automation real clients go through.
+package smoketest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Step results
+const (
+	StepPassed  = "passed"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// Step is one request of a scenario. Path and Body may reference variables
+// as {name}: the ones passed to Run and the ones captured by earlier steps.
+type Step struct {
+	Name    string
+	Method  string
+	Path    string
+	Body    string            // JSON request body, empty for none
+	Expect  []int             // Accepted response statuses
+	Capture map[string]string // Variable name to the dotted field of the JSON response it is read from
+	Cleanup bool              // Run even after a step failed, as long as its variables are set
+}
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Name       string  `json:"name"`
+	Result     string  `json:"result"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a scenario
+type Report struct {
+	Passed     bool         `json:"passed"`
+	DurationMs float64      `json:"duration_ms"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// Run executes steps in order against handler, sending header with every
+// request. After a step fails the remaining steps are skipped, except
+// cleanup steps whose variables were captured.
+func Run(ctx context.Context, handler http.Handler, header http.Header, vars map[string]string, steps []Step) Report {
+	values := make(map[string]string, len(vars))
+	for name, value := range vars {
+		values[name] = value
+	}
+
+	started := time.Now()
+	report := Report{Passed: true, Steps: make([]StepResult, 0, len(steps))}
+	for _, step := range steps {
+		result := StepResult{Name: step.Name, Method: step.Method, Path: step.Path}
+
+		path, pathErr := expand(step.Path, values)
+		body, bodyErr := expand(step.Body, values)
+		switch {
+		case !report.Passed && !step.Cleanup:
+			result.Result, result.Error = StepSkipped, "an earlier step failed"
+		case pathErr != nil || bodyErr != nil:
+			// A missing variable means the step capturing it did not pass
+			result.Result, result.Error = StepSkipped, errors.Join(pathErr, bodyErr).Error()
+		default:
+			result.Path = path
+			run(ctx, handler, header, step, path, body, values, &result)
+			if result.Result == StepFailed {
+				report.Passed = false
+			}
+		}
+		report.Steps = append(report.Steps, result)
+	}
+	report.DurationMs = milliseconds(time.Since(started))
+	return report
+}
+
+// run sends one step's request and checks its response
+func run(ctx context.Context, handler http.Handler, header http.Header, step Step, path, body string, values map[string]string, result *StepResult) {
+	started := time.Now()
+	req, err := http.NewRequestWithContext(ctx, step.Method, path, strings.NewReader(body))
+	if err != nil {
+		result.Result, result.Error = StepFailed, err.Error()
+		return
+	}
+	req.Header = header.Clone()
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	result.DurationMs = milliseconds(time.Since(started))
+	result.Status = recorder.Code
+
+	if !slices.Contains(step.Expect, recorder.Code) {
+		result.Result = StepFailed
+		result.Error = fmt.Sprintf("expected status %v, got %d: %s", step.Expect, recorder.Code, truncate(recorder.Body.String(), 500))
+		return
+	}
+
+	if len(step.Capture) > 0 {
+		var response interface{}
+		decoder := json.NewDecoder(bytes.NewReader(recorder.Body.Bytes()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&response); err != nil {
+			result.Result, result.Error = StepFailed, "response is not JSON: "+err.Error()
+			return
+		}
+		for name, field := range step.Capture {
+			value, ok := lookup(response, field)
+			if !ok {
+				result.Result, result.Error = StepFailed, "response has no "+field
+				return
+			}
+			values[name] = value
+		}
+	}
+	result.Result = StepPassed
+}
+
+// variable matches a {name} reference in a step's path or body
+var variable = regexp.MustCompile(`\{([a-z_][a-z0-9_]*)\}`)
+
+// expand replaces the {name} variables of s
+func expand(s string, values map[string]string) (string, error) {
+	var missing string
+	expanded := variable.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[1 : len(ref)-1]
+		value, ok := values[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("variable %q is not set", missing)
+	}
+	return expanded, nil
+}
+
+// lookup reads a dotted field of a decoded JSON value as a string
+func lookup(value interface{}, field string) (string, bool) {
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = object[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// milliseconds converts d to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,35 @@
+// Package stages loads the pipeline stages deals may be in from the
+// database.
+package stages
+
+import (
+	"context"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Service keeps the deal stage checks of models in step with the
+// pipeline_stages table
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a Service. Call Reload to load the stages.
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Reload reads the active pipeline stages in board order and makes them the
+// stages accepted by models.IsValidDealStage. An empty table leaves the
+// built-in stages in effect.
+func (s *Service) Reload(ctx context.Context) error {
+	var names []models.DealStage
+	if err := s.db.WithContext(ctx).Model(&models.PipelineStage{}).
+		Where("is_active = ?", true).Order(`"order" ASC, id ASC`).
+		Pluck("name", &names).Error; err != nil {
+		return err
+	}
+	models.SetDealStages(names)
+	return nil
+}
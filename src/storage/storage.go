@@ -0,0 +1,126 @@
+// Package storage stores generated files such as export artifacts.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when a stored object does not exist
+var ErrNotFound = errors.New("storage object not found")
+
+// ErrTooLarge is returned by Put when an object exceeds the size limit
+var ErrTooLarge = errors.New("storage object exceeds size limit")
+
+// Object describes a stored object
+type Object struct {
+	Key      string
+	Size     int64
+	Checksum string // hex-encoded SHA-256
+}
+
+// Storage stores objects by key
+type Storage interface {
+	// Put stores the contents of r under key. maxBytes > 0 limits the size;
+	// larger objects are discarded and ErrTooLarge is returned.
+	Put(ctx context.Context, key string, r io.Reader, maxBytes int64) (Object, error)
+	// Open returns a reader for the object stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Missing objects are not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Local stores objects as files under a directory
+type Local struct {
+	dir string
+}
+
+// NewLocal creates a Local storage rooted at dir, creating it if needed
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir}, nil
+}
+
+// Put implements Storage
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, maxBytes int64) (Object, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return Object{}, err
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return Object{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	src := r
+	if maxBytes > 0 {
+		src = io.LimitReader(r, maxBytes+1)
+	}
+	size, err := io.Copy(io.MultiWriter(tmp, hash), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	if maxBytes > 0 && size > maxBytes {
+		return Object{}, ErrTooLarge
+	}
+	if err := ctx.Err(); err != nil {
+		return Object{}, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Object{}, err
+	}
+	return Object{Key: key, Size: size, Checksum: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// Open implements Storage
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements Storage
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file path, rejecting keys escaping the directory
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(l.dir, clean), nil
+}
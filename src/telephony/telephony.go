@@ -0,0 +1,181 @@
+// Package telephony verifies and parses the call webhooks of the telephony
+// provider, which report every inbound and outbound call so it can be
+// logged against the customer on the other end.
+package telephony
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+var (
+	// ErrUnauthorized is returned when a webhook carries neither a valid
+	// signature nor the API key
+	ErrUnauthorized = errors.New("invalid webhook signature or API key")
+
+	// ErrInvalidPayload is returned when a webhook body cannot be parsed
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+)
+
+// Webhook authentication headers
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	APIKeyHeader    = "X-API-Key"
+)
+
+// MaxSkew bounds how old a signed webhook may be
+const MaxSkew = 5 * time.Minute
+
+// Call is a call normalized from a webhook payload
+type Call struct {
+	EventID         string // Provider event ID, used to ignore redelivered webhooks
+	Direction       models.CallDirection
+	From            string
+	To              string
+	Extension       string // The rep's extension, when the provider reports it
+	DurationSeconds int
+	RecordingURL    string
+	StartedAt       time.Time
+}
+
+// ExternalNumber returns the customer's side of the call: the caller of an
+// inbound call and the callee of an outbound one
+func (c Call) ExternalNumber() string {
+	if c.Direction == models.CallOutbound {
+		return c.To
+	}
+	return c.From
+}
+
+// Verifier authenticates call webhooks, which are either signed like the
+// generic email delivery webhook, with a hex HMAC-SHA256 of
+// "<timestamp>.<body>" in X-Webhook-Signature where timestamp is the Unix
+// time in X-Webhook-Timestamp, or carry the API key in X-API-Key for
+// providers that cannot sign.
+type Verifier struct {
+	secret []byte
+	apiKey string
+}
+
+// NewVerifier creates a Verifier accepting signatures made with secret and
+// requests carrying apiKey; either may be empty to disable it. It returns
+// nil when both are empty.
+func NewVerifier(secret, apiKey string) *Verifier {
+	if secret == "" && apiKey == "" {
+		return nil
+	}
+	return &Verifier{secret: []byte(secret), apiKey: apiKey}
+}
+
+// Verify checks that body was signed recently with the secret, or that the
+// request carries the API key
+func (v *Verifier) Verify(header http.Header, body []byte, now time.Time) error {
+	if len(v.secret) > 0 && header.Get(SignatureHeader) != "" {
+		return v.verifySignature(header, body, now)
+	}
+	if v.apiKey != "" && subtle.ConstantTimeCompare([]byte(header.Get(APIKeyHeader)), []byte(v.apiKey)) == 1 {
+		return nil
+	}
+	return ErrUnauthorized
+}
+
+// verifySignature checks the HMAC signature and that it is recent
+func (v *Verifier) verifySignature(header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(TimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrUnauthorized
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > MaxSkew || skew < -MaxSkew {
+		return ErrUnauthorized
+	}
+
+	signature, err := hex.DecodeString(header.Get(SignatureHeader))
+	if err != nil {
+		return ErrUnauthorized
+	}
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// payload is the body of a call webhook:
+//
+//	{"id": "call_1", "direction": "inbound", "from": "+966 55 123 4567", "to": "+966 11 200 0000",
+//	  "extension": "101", "duration": 185, "recording_url": "https://pbx.example.com/rec/call_1.mp3",
+//	  "timestamp": "2026-10-16T09:30:00Z"}
+//
+// duration is in seconds and timestamp is when the call started.
+type payload struct {
+	ID           string               `json:"id"`
+	Direction    models.CallDirection `json:"direction"`
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	Extension    string               `json:"extension"`
+	Duration     int                  `json:"duration"`
+	RecordingURL string               `json:"recording_url"`
+	Timestamp    time.Time            `json:"timestamp"`
+}
+
+// Parse returns the call a webhook reports. The event ID, a valid direction
+// and the customer's number are required, and fields must fit the columns
+// they are stored in.
+func Parse(body []byte) (Call, error) {
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return Call{}, ErrInvalidPayload
+	}
+
+	call := Call{
+		EventID:         strings.TrimSpace(p.ID),
+		Direction:       models.CallDirection(strings.ToLower(string(p.Direction))),
+		From:            strings.TrimSpace(p.From),
+		To:              strings.TrimSpace(p.To),
+		Extension:       strings.TrimSpace(p.Extension),
+		DurationSeconds: p.Duration,
+		RecordingURL:    strings.TrimSpace(p.RecordingURL),
+		StartedAt:       p.Timestamp,
+	}
+	if call.EventID == "" || len(call.EventID) > 255 || !models.IsValidCallDirection(call.Direction) ||
+		call.ExternalNumber() == "" || len(call.From) > 50 || len(call.To) > 50 || len(call.Extension) > 20 ||
+		len(call.RecordingURL) > 1000 || call.DurationSeconds < 0 {
+		return Call{}, ErrInvalidPayload
+	}
+	return call, nil
+}
+
+// ParseExtensions parses the extension to user mapping, a comma-separated
+// list of extension=user_id pairs such as "101=5,102=7"
+func ParseExtensions(s string) (map[string]uint, error) {
+	extensions := make(map[string]uint)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		extension, user, ok := strings.Cut(pair, "=")
+		extension = strings.TrimSpace(extension)
+		userID, err := strconv.ParseUint(strings.TrimSpace(user), 10, 32)
+		if !ok || extension == "" || err != nil || userID == 0 {
+			return nil, fmt.Errorf("invalid telephony extension mapping %q: want extension=user_id", pair)
+		}
+		extensions[extension] = uint(userID)
+	}
+	return extensions, nil
+}
@@ -0,0 +1,134 @@
+package telephony
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+// sign returns the headers of a webhook signed with secret at a time
+func sign(secret string, body []byte, at time.Time) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	header := http.Header{}
+	header.Set(TimestampHeader, timestamp)
+	header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	body := []byte(`{"id":"call_1"}`)
+	apiKey := http.Header{}
+	apiKey.Set(APIKeyHeader, "key")
+	wrongKey := http.Header{}
+	wrongKey.Set(APIKeyHeader, "other")
+	badTimestamp := sign("secret", body, now)
+	badTimestamp.Set(TimestampHeader, "yesterday")
+	signedWithKey := sign("other", body, now)
+	signedWithKey.Set(APIKeyHeader, "key")
+
+	for _, tc := range []struct {
+		name     string
+		verifier *Verifier
+		header   http.Header
+		body     []byte
+		ok       bool
+	}{
+		{"signed", NewVerifier("secret", ""), sign("secret", body, now), body, true},
+		{"signed within the skew", NewVerifier("secret", ""), sign("secret", body, now.Add(-MaxSkew)), body, true},
+		{"signed too long ago", NewVerifier("secret", ""), sign("secret", body, now.Add(-MaxSkew-time.Second)), body, false},
+		{"signed in the future", NewVerifier("secret", ""), sign("secret", body, now.Add(MaxSkew+time.Second)), body, false},
+		{"signed with another secret", NewVerifier("secret", ""), sign("other", body, now), body, false},
+		{"body changed after signing", NewVerifier("secret", ""), sign("secret", body, now), []byte(`{"id":"call_2"}`), false},
+		{"unparsable timestamp", NewVerifier("secret", ""), badTimestamp, body, false},
+		{"unsigned", NewVerifier("secret", ""), http.Header{}, body, false},
+		{"API key", NewVerifier("", "key"), apiKey, body, true},
+		{"wrong API key", NewVerifier("", "key"), wrongKey, body, false},
+		{"API key when signatures are expected", NewVerifier("secret", ""), apiKey, body, false},
+		{"bad signature is not rescued by the API key", NewVerifier("secret", "key"), signedWithKey, body, false},
+		{"API key alongside a secret", NewVerifier("secret", "key"), apiKey, body, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.verifier.Verify(tc.header, tc.body, now)
+			if tc.ok && err != nil {
+				t.Errorf("err = %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("err = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+
+	if NewVerifier("", "") != nil {
+		t.Error("verifier without a secret or API key is enabled")
+	}
+}
+
+func TestParse(t *testing.T) {
+	call, err := Parse([]byte(`{"id": " call_1 ", "direction": "Outbound", "from": "+966 11 200 0000", "to": "+966 55 123 4567",
+		"extension": "101", "duration": 185, "recording_url": "https://pbx.example.com/rec/call_1.mp3", "timestamp": "2026-10-16T09:30:00Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Call{
+		EventID:         "call_1",
+		Direction:       models.CallOutbound,
+		From:            "+966 11 200 0000",
+		To:              "+966 55 123 4567",
+		Extension:       "101",
+		DurationSeconds: 185,
+		RecordingURL:    "https://pbx.example.com/rec/call_1.mp3",
+		StartedAt:       time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+	}
+	if call != want {
+		t.Errorf("call = %+v, want %+v", call, want)
+	}
+	if call.ExternalNumber() != "+966 55 123 4567" {
+		t.Errorf("outbound external number = %q", call.ExternalNumber())
+	}
+	call.Direction = models.CallInbound
+	if call.ExternalNumber() != "+966 11 200 0000" {
+		t.Errorf("inbound external number = %q", call.ExternalNumber())
+	}
+
+	for name, body := range map[string]string{
+		"not JSON":           `call`,
+		"missing event ID":   `{"direction": "inbound", "from": "+966551234567"}`,
+		"unknown direction":  `{"id": "call_1", "direction": "sideways", "from": "+966551234567"}`,
+		"no customer number": `{"id": "call_1", "direction": "outbound", "from": "+966551234567"}`,
+		"negative duration":  `{"id": "call_1", "direction": "inbound", "from": "+966551234567", "duration": -1}`,
+		"oversized number":   `{"id": "call_1", "direction": "inbound", "from": "+96655123456700000000000000000000000000000000000000000"}`,
+	} {
+		if _, err := Parse([]byte(body)); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	extensions, err := ParseExtensions(" 101=5, 102 = 7 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extensions) != 2 || extensions["101"] != 5 || extensions["102"] != 7 {
+		t.Errorf("extensions = %v", extensions)
+	}
+	if extensions, err := ParseExtensions(""); err != nil || len(extensions) != 0 {
+		t.Errorf("empty mapping = %v, %v", extensions, err)
+	}
+	for _, s := range []string{"101", "=5", "101=0", "101=rep"} {
+		if _, err := ParseExtensions(s); err == nil {
+			t.Errorf("%q parsed", s)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Database is a fresh Postgres database with the migrations applied, for
+// handler tests. Its GORM handle stamps records from a clock the test
+// controls and records the statements it runs.
+//
+// The clock is GORM's: NOW() in SQL is the server's, so tests of what SQL
+// compares with NOW() build their records relative to the real time.
+type Database struct {
+	DB *gorm.DB
+
+	mu  sync.Mutex
+	now time.Time
+	log []string
+}
+
+// New creates a database for the test and opens it with GORM's clock
+// standing at now until moved with SetNow. The test is skipped when no
+// server is configured.
+func New(t testing.TB, now time.Time) *Database {
+	t.Helper()
+	d := &Database{now: now}
+	config, err := pgx.ParseConfig(URL(t))
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	config.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	pool := sql.OpenDB(connector{stdlib.GetConnector(*config), d})
+	t.Cleanup(func() { pool.Close() })
+
+	migrator, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	migrate(t, migrator)
+	d.mu.Lock()
+	d.log = nil
+	d.mu.Unlock()
+
+	if d.DB, err = gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{
+		Logger:  logger.Discard,
+		NowFunc: d.Now,
+	}); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	return d
+}
+
+// Now returns GORM's clock
+func (d *Database) Now() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.now
+}
+
+// SetNow moves GORM's clock
+func (d *Database) SetNow(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.now = now
+}
+
+// FailInserts makes Postgres refuse inserts into table, aborting the
+// transaction they run in as a real failure does, until the returned
+// function is called
+func (d *Database) FailInserts(t testing.TB, table string) (restore func()) {
+	t.Helper()
+	trigger := "testdb_fail_" + table
+	err := d.DB.Exec(`CREATE OR REPLACE FUNCTION testdb_fail() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+	RAISE EXCEPTION 'testdb: inserts into % fail', TG_TABLE_NAME;
+END
+$$;
+CREATE TRIGGER ` + trigger + ` BEFORE INSERT ON ` + table + ` FOR EACH ROW EXECUTE FUNCTION testdb_fail()`).Error
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	return func() {
+		t.Helper()
+		if err := d.DB.Exec("DROP TRIGGER IF EXISTS " + trigger + " ON " + table).Error; err != nil {
+			t.Fatalf("testdb: %v", err)
+		}
+	}
+}
+
+// Statements returns the statements run so far, in order, with their
+// parameters as placeholders
+func (d *Database) Statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.log...)
+}
+
+// Rows returns the rows of a table in the order of their first column.
+// Integers are int64 and text and JSON are strings.
+func (d *Database) Rows(name string) []map[string]interface{} {
+	var rows []map[string]interface{}
+	if err := d.DB.Table(name).Order("1").Find(&rows).Error; err != nil {
+		panic(fmt.Sprintf("testdb: rows of %s: %v", name, err))
+	}
+	for _, row := range rows {
+		for column, value := range row {
+			switch v := value.(type) {
+			case []byte:
+				row[column] = string(v)
+			case int32:
+				row[column] = int64(v)
+			case int16:
+				row[column] = int64(v)
+			}
+		}
+	}
+	return rows
+}
+
+// Count returns the number of rows of a table
+func (d *Database) Count(name string) int {
+	var n int64
+	if err := d.DB.Table(name).Count(&n).Error; err != nil {
+		panic(fmt.Sprintf("testdb: count of %s: %v", name, err))
+	}
+	return int(n)
+}
+
+// record appends a statement to the log
+func (d *Database) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, query)
+}
+
+// connector opens pgx connections recording the statements they run
+type connector struct {
+	driver.Connector
+	d *Database
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	inner, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{inner.(*stdlib.Conn), c.d}, nil
+}
+
+type conn struct {
+	*stdlib.Conn
+	d *Database
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.d.record(query)
+	return c.Conn.PrepareContext(ctx, query)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(query)
+	return c.Conn.ExecContext(ctx, query, args)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(query)
+	return c.Conn.QueryContext(ctx, query, args)
+}
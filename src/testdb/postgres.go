@@ -0,0 +1,154 @@
+// Package testdb provides fresh Postgres databases for tests, created on
+// the server named by TEST_DATABASE_URL with every migration applied: New
+// for handler tests, with a controlled clock and recorded statements, and
+// Open for a plain GORM handle. Tests are skipped when no server is set.
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// URLVariable names the Postgres server tests create their databases on,
+// as a URL to a database the user may CREATE DATABASE from
+const URLVariable = "TEST_DATABASE_URL"
+
+var databases atomic.Int64
+
+// URL creates an empty database for the test and returns its URL. The
+// database is dropped when the test ends; the test is skipped when no
+// server is configured.
+func URL(t testing.TB) string {
+	t.Helper()
+	server := os.Getenv(URLVariable)
+	if server == "" {
+		t.Skipf("%s is not set", URLVariable)
+	}
+	admin, err := sql.Open("pgx", server)
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("crm_test_%d_%d_%d", os.Getpid(), time.Now().UnixNano()%1e9, databases.Add(1))
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)"); err != nil {
+			t.Errorf("testdb: %v", err)
+		}
+	})
+
+	u, err := url.Parse(server)
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	u.Path = "/" + name
+	return u.String()
+}
+
+// Connect opens a GORM handle on the database at dsn, closed when the test
+// ends
+func Connect(t testing.TB, dsn string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: dsn, PreferSimpleProtocol: true}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// Open creates a database for the test with every migration applied, as
+// golang-migrate leaves it, and opens it
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	db := Connect(t, URL(t))
+	migrate(t, db)
+	return db
+}
+
+// migrationLock is the advisory lock held on the server's database while
+// a test database is migrated
+const migrationLock = 7_264_001
+
+// migrate applies the migrations to a test database. Migrations create
+// roles, which belong to the whole server, so test databases are migrated
+// one at a time even across test binaries.
+func migrate(t testing.TB, db *gorm.DB) {
+	t.Helper()
+	admin, err := sql.Open("pgx", os.Getenv(URLVariable))
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	defer admin.Close()
+	ctx := context.Background()
+	conn, err := admin.Conn(ctx)
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+}
+
+// Migrate applies the up migrations in order and records the last version
+// in schema_migrations as golang-migrate does
+func Migrate(db *gorm.DB) error {
+	_, file, _, _ := runtime.Caller(0)
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(file), "..", "..", "migrations", "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations found")
+	}
+	sort.Strings(files)
+
+	var version int64
+	for _, path := range files {
+		migration, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := db.Exec(string(migration)).Error; err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		prefix, _, _ := strings.Cut(filepath.Base(path), "_")
+		if version, err = strconv.ParseInt(prefix, 10, 64); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)").Error; err != nil {
+		return err
+	}
+	return db.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (?, false)", version).Error
+}
@@ -0,0 +1,184 @@
+// Package testserver runs the service's router on a fresh test database,
+// for tests that drive the API over HTTP: the handler tests and the tests
+// of clients such as crmctl. Tokens are HMAC JWTs signed with Secret.
+package testserver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/auth"
+	"github.com/SalehAlobaylan/CRM-Service/src/businesstime"
+	"github.com/SalehAlobaylan/CRM-Service/src/config"
+	"github.com/SalehAlobaylan/CRM-Service/src/consistency"
+	"github.com/SalehAlobaylan/CRM-Service/src/database"
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/deletion"
+	"github.com/SalehAlobaylan/CRM-Service/src/exports"
+	"github.com/SalehAlobaylan/CRM-Service/src/factory"
+	"github.com/SalehAlobaylan/CRM-Service/src/fallback"
+	"github.com/SalehAlobaylan/CRM-Service/src/middleware"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/preview"
+	"github.com/SalehAlobaylan/CRM-Service/src/quota"
+	"github.com/SalehAlobaylan/CRM-Service/src/redaction"
+	"github.com/SalehAlobaylan/CRM-Service/src/reportrollup"
+	"github.com/SalehAlobaylan/CRM-Service/src/roles"
+	"github.com/SalehAlobaylan/CRM-Service/src/routes"
+	"github.com/SalehAlobaylan/CRM-Service/src/security"
+	"github.com/SalehAlobaylan/CRM-Service/src/stages"
+	"github.com/SalehAlobaylan/CRM-Service/src/storage"
+	"github.com/SalehAlobaylan/CRM-Service/src/testdb"
+	"github.com/SalehAlobaylan/CRM-Service/src/tracking"
+	"github.com/SalehAlobaylan/CRM-Service/src/webhooks"
+	"github.com/SalehAlobaylan/CRM-Service/src/workload"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Secret is the JWT secret of the server's HMAC tokens
+const Secret = "routes-test-secret"
+
+// Server is the router of the service on a test database
+type Server struct {
+	*testdb.Database
+	Factory  *factory.Factory
+	Services *routes.Services
+	Router   *gin.Engine // The routes, served through Handler
+	Handler  http.Handler
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	middleware.Logger = zap.NewNop()
+}
+
+// New starts the router on a freshly migrated database whose GORM clock
+// stands a day after the factory epoch. It authenticates HMAC tokens only
+// unless configure picks other providers.
+func New(t *testing.T, configure ...func(*config.Config)) *Server {
+	t.Helper()
+	testDB := testdb.New(t, factory.Epoch.Add(24*time.Hour))
+	database.EnableCommitHooks(testDB.DB)
+
+	cfg := config.Load()
+	cfg.Environment = "test"
+	cfg.JWTSecret = Secret
+	cfg.AuthProviders = auth.ProviderHMAC
+	for _, fn := range configure {
+		fn(cfg)
+	}
+	services, err := newServices(testDB.DB, cfg, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(services.Exports.Stop)
+	events := audittrail.Events
+	audittrail.Events = services.Webhooks
+	t.Cleanup(func() { audittrail.Events = events })
+	router, err := routes.SetupRouter(testDB.DB, cfg, services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{Database: testDB, Factory: factory.New(testDB.DB), Services: services, Router: router, Handler: middleware.NormalizePath(router)}
+}
+
+// newServices builds the services of the router as the server does, with
+// background jobs left stopped so tests stay deterministic
+func newServices(db *gorm.DB, cfg *config.Config, exportDir string) (*routes.Services, error) {
+	chain, err := auth.NewChain(cfg.AuthProviders, auth.Config{HMACSecret: cfg.JWTSecret, HMACClaims: auth.DefaultClaimsMapping}, db)
+	if err != nil {
+		return nil, err
+	}
+	previews, err := preview.ParseLengths(cfg.TextPreviewLength, cfg.TextPreviewLengths)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := workload.ParseLimits(cfg.WorkloadLimits)
+	if err != nil {
+		return nil, err
+	}
+	workloads := workload.NewLimiter(limits)
+	workdays, err := businesstime.ParseWorkdays(cfg.BusinessWorkdays)
+	if err != nil {
+		return nil, err
+	}
+	workStart, workEnd, err := businesstime.ParseHours(cfg.BusinessHours)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(cfg.BusinessTimezone)
+	if err != nil {
+		return nil, err
+	}
+	baseCalendar, err := businesstime.New(workdays, workStart, workEnd, location)
+	if err != nil {
+		return nil, err
+	}
+	fallbacks := fallback.NewWriter(db, time.Minute, cfg.SideEffectBufferSize, func(error) {})
+	exportStorage, err := storage.NewLocal(exportDir)
+	if err != nil {
+		return nil, err
+	}
+	quotas := quota.NewTracker(db, map[string]int64{
+		quota.Customers:  int64(cfg.QuotaCustomers),
+		quota.Contacts:   int64(cfg.QuotaContacts),
+		quota.Deals:      int64(cfg.QuotaDeals),
+		quota.Activities: int64(cfg.QuotaActivities),
+	})
+	if err := quotas.Register(db); err != nil {
+		return nil, err
+	}
+	deadLetters := deadletter.NewQueue(db)
+	exportManager := exports.NewManager(db, exportStorage, time.Hour, int64(cfg.ExportMaxBytes), cfg.ExportCheckpointBatches, workloads, func(error) {})
+	exportManager.DeadLetterTo(deadLetters)
+	exportManager.Register("customers_csv", models.ExportEntityCustomer, "customers.csv", "text/csv; charset=utf-8", exports.CustomersCSV)
+	exportManager.Register("deals_csv", models.ExportEntityDeal, "deals.csv", "text/csv; charset=utf-8", exports.DealsCSV)
+	exportManager.Register("notes_csv", models.ExportEntityNote, "notes.csv", "text/csv; charset=utf-8", exports.NotesCSV)
+	if err := redaction.Register(db); err != nil {
+		return nil, err
+	}
+	if err := preview.Register(db); err != nil {
+		return nil, err
+	}
+	webhookDispatcher := webhooks.NewDispatcher(db, nil)
+	webhookDispatcher.DeadLetterTo(deadLetters)
+	webhookMode, err := models.ParseSecurityWebhookMode(cfg.SecurityAlertWebhookMode)
+	if err != nil {
+		return nil, err
+	}
+	return &routes.Services{
+		Authenticators:  chain,
+		ActivityTracker: tracking.NewUserActivityTracker(db, time.Minute, cfg.UserActivityRetentionDays, fallbacks, func(error) {}),
+		RecentViews:     tracking.NewRecentViewRecorder(db, false, cfg.RecentViewsRetentionDays, func(error) {}),
+		DeadLetters:     deadLetters,
+		Fallbacks:       fallbacks,
+		Consistency:     consistency.NewRunner(db),
+		Rollups:         reportrollup.NewRunner(db, location),
+		Deletions:       deletion.NewRunner(db, cfg.CustomerDeleteSyncLimit),
+		Exports:         exportManager,
+		Calendar:        businesstime.NewService(db, baseCalendar, cfg.BusinessRegion),
+		ReadRouter:      database.NewReadRouter(db, nil, 0),
+		Quotas:          quotas,
+		Roles:           roles.NewService(db),
+		Stages:          stages.NewService(db),
+		Previews:        previews,
+		Workloads:       workloads,
+		Security:        security.NewMonitor(db, nil, false, security.NewWebhook(cfg.SecurityAlertWebhookURL, webhookMode), nil),
+		Webhooks:        webhookDispatcher,
+	}, nil
+}
+
+// Sign signs a token with Secret
+func Sign(t testing.TB, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
@@ -0,0 +1,38 @@
+package tracking
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+)
+
+// Side effect kinds of the activity counts kept for replay
+const (
+	ActivityFallbackKind = "user_activity"
+	SecurityFallbackKind = "security_activity"
+)
+
+// ReplayActivity adds request counts kept by the fallback writer to the
+// user activity
+func ReplayActivity(ctx context.Context, tx *gorm.DB, payload json.RawMessage) error {
+	var rows []models.UserActivity
+	if err := json.Unmarshal(payload, &rows); err != nil {
+		return err
+	}
+	for i := range rows {
+		rows[i].ID = 0 // Set when an earlier batch of the failed flush was written
+	}
+	return addActivity(tx.WithContext(ctx), rows)
+}
+
+// ReplaySecurityActivity adds hourly request counts kept by the fallback
+// writer to the security activity
+func ReplaySecurityActivity(ctx context.Context, tx *gorm.DB, payload json.RawMessage) error {
+	var rows []models.SecurityActivity
+	if err := json.Unmarshal(payload, &rows); err != nil {
+		return err
+	}
+	return addSecurityActivity(tx.WithContext(ctx), rows)
+}
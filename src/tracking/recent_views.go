@@ -0,0 +1,108 @@
+package tracking
+
+import (
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recentViewQueueSize bounds the number of view writes waiting to be stored
+const recentViewQueueSize = 1024
+
+// RecentViewRecorder stores "recently viewed" entries off the request path.
+// Views are queued without blocking and written by a single worker.
+type RecentViewRecorder struct {
+	db            *gorm.DB
+	enabled       bool
+	retentionDays int
+
+	queue chan models.RecentView
+	done  chan struct{}
+	onErr func(error)
+}
+
+// NewRecentViewRecorder creates a new RecentViewRecorder
+func NewRecentViewRecorder(db *gorm.DB, enabled bool, retentionDays int, onErr func(error)) *RecentViewRecorder {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &RecentViewRecorder{
+		db:            db,
+		enabled:       enabled,
+		retentionDays: retentionDays,
+		queue:         make(chan models.RecentView, recentViewQueueSize),
+		onErr:         onErr,
+	}
+}
+
+// Enabled reports whether view tracking is turned on
+func (r *RecentViewRecorder) Enabled() bool {
+	return r.enabled
+}
+
+// Record queues a view. It never blocks; views are dropped if the queue is full.
+func (r *RecentViewRecorder) Record(userID uint, entityType string, entityID uint) {
+	if !r.enabled || userID == 0 || entityID == 0 {
+		return
+	}
+	select {
+	case r.queue <- models.RecentView{UserID: userID, EntityType: entityType, EntityID: entityID, ViewedAt: time.Now()}:
+	default:
+	}
+}
+
+// Start launches the background writer and hourly retention cleanup
+func (r *RecentViewRecorder) Start() {
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case view, ok := <-r.queue:
+				if !ok {
+					return
+				}
+				if err := r.store(view); err != nil {
+					r.onErr(err)
+				}
+			case <-ticker.C:
+				if err := r.Cleanup(); err != nil {
+					r.onErr(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop drains queued views and stops the writer. Record must not be
+// called after Stop.
+func (r *RecentViewRecorder) Stop() {
+	close(r.queue)
+	if r.done != nil {
+		<-r.done
+	}
+}
+
+// Cleanup deletes views older than the retention period
+func (r *RecentViewRecorder) Cleanup() error {
+	if r.retentionDays <= 0 {
+		return nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -r.retentionDays)
+	return r.db.Where("viewed_at < ?", cutoff).Delete(&models.RecentView{}).Error
+}
+
+// store upserts a view so each user/entity pair keeps one row
+func (r *RecentViewRecorder) store(view models.RecentView) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "entity_type"}, {Name: "entity_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"viewed_at"}),
+	}).Create(&view).Error
+}
@@ -0,0 +1,281 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/fallback"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// activityKey identifies one aggregation bucket
+type activityKey struct {
+	userID   uint
+	endpoint string
+	day      string
+}
+
+// activityBucket holds the pending counters for one bucket
+type activityBucket struct {
+	count    int64
+	lastSeen time.Time
+}
+
+// securityKey identifies one hourly security activity bucket
+type securityKey struct {
+	userID uint
+	hour   time.Time
+}
+
+// UserActivityTracker records per-user request activity in memory and
+// periodically flushes it in batches to the user_activity table and, as
+// hourly reads, writes, deletes and exports, to the security_activity table
+type UserActivityTracker struct {
+	db            *gorm.DB
+	flushInterval time.Duration
+	retentionDays int
+	fallback      *fallback.Writer // Keeps counts whose flush failed; nil keeps them pending
+
+	mu       sync.Mutex
+	pending  map[activityKey]*activityBucket
+	security map[securityKey]*models.SecurityCounts
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	onErr  func(error)
+}
+
+// NewUserActivityTracker creates a new UserActivityTracker
+func NewUserActivityTracker(db *gorm.DB, flushInterval time.Duration, retentionDays int, fallbacks *fallback.Writer, onErr func(error)) *UserActivityTracker {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Minute
+	}
+	return &UserActivityTracker{
+		db:            db,
+		flushInterval: flushInterval,
+		retentionDays: retentionDays,
+		fallback:      fallbacks,
+		pending:       make(map[activityKey]*activityBucket),
+		security:      make(map[securityKey]*models.SecurityCounts),
+		onErr:         onErr,
+	}
+}
+
+// Record notes a request made by a user. It never touches the database.
+func (t *UserActivityTracker) Record(userID uint, endpoint string, at time.Time) {
+	if userID == 0 || endpoint == "" {
+		return
+	}
+
+	key := activityKey{userID: userID, endpoint: endpoint, day: at.UTC().Format("2006-01-02")}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bucket, ok := t.pending[key]
+	if !ok {
+		bucket = &activityBucket{}
+		t.pending[key] = bucket
+	}
+	bucket.count++
+	if at.After(bucket.lastSeen) {
+		bucket.lastSeen = at
+	}
+
+	if metric, ok := models.SecurityMetricForEndpoint(endpoint); ok {
+		key := securityKey{userID: userID, hour: at.UTC().Truncate(time.Hour)}
+		counts, ok := t.security[key]
+		if !ok {
+			counts = &models.SecurityCounts{}
+			t.security[key] = counts
+		}
+		counts.Add(metric)
+	}
+}
+
+// Start launches the background flush loop
+func (t *UserActivityTracker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(t.flushInterval)
+		defer ticker.Stop()
+
+		lastCleanup := time.Time{}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Flush(); err != nil {
+					t.onErr(err)
+				}
+				if time.Since(lastCleanup) >= time.Hour {
+					if err := t.Cleanup(); err != nil {
+						t.onErr(err)
+					}
+					lastCleanup = time.Now()
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the flush loop and writes any pending activity
+func (t *UserActivityTracker) Stop() error {
+	if t.cancel != nil {
+		t.cancel()
+		<-t.done
+	}
+	return t.Flush()
+}
+
+// Flush writes all pending activity to the database in batches. On
+// failure the counts are handed to the fallback writer, or merged back
+// into the pending counters without one, so nothing is lost.
+func (t *UserActivityTracker) Flush() error {
+	t.mu.Lock()
+	pending, security := t.pending, t.security
+	t.pending = make(map[activityKey]*activityBucket)
+	t.security = make(map[securityKey]*models.SecurityCounts)
+	t.mu.Unlock()
+
+	return errors.Join(t.flushActivity(pending), t.flushSecurity(security))
+}
+
+// flushActivity writes per-endpoint request counts
+func (t *UserActivityTracker) flushActivity(pending map[activityKey]*activityBucket) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rows := make([]models.UserActivity, 0, len(pending))
+	for key, bucket := range pending {
+		day, _ := time.Parse("2006-01-02", key.day)
+		rows = append(rows, models.UserActivity{
+			UserID:       key.userID,
+			Endpoint:     key.endpoint,
+			BucketDate:   day,
+			RequestCount: bucket.count,
+			LastSeenAt:   bucket.lastSeen,
+		})
+	}
+
+	if err := addActivity(t.db, rows); err != nil {
+		if t.fallback != nil {
+			return t.fallback.Add(ActivityFallbackKind, rows, err)
+		}
+		t.restore(pending)
+		return err
+	}
+	return nil
+}
+
+// addActivity adds request counts to the user activity
+func addActivity(db *gorm.DB, rows []models.UserActivity) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "endpoint"}, {Name: "bucket_date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"request_count": gorm.Expr("user_activity.request_count + EXCLUDED.request_count"),
+			"last_seen_at":  gorm.Expr("GREATEST(user_activity.last_seen_at, EXCLUDED.last_seen_at)"),
+		}),
+	}).CreateInBatches(&rows, 500).Error
+}
+
+// flushSecurity adds hourly request counts to the security activity;
+// record counts are left to the security monitor
+func (t *UserActivityTracker) flushSecurity(pending map[securityKey]*models.SecurityCounts) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rows := make([]models.SecurityActivity, 0, len(pending))
+	for key, counts := range pending {
+		rows = append(rows, models.SecurityActivity{
+			UserID:         key.userID,
+			BucketStart:    key.hour,
+			SecurityCounts: *counts,
+		})
+	}
+
+	if err := addSecurityActivity(t.db, rows); err != nil {
+		if t.fallback != nil {
+			return t.fallback.Add(SecurityFallbackKind, rows, err)
+		}
+		t.restoreSecurity(pending)
+		return err
+	}
+	return nil
+}
+
+// addSecurityActivity adds hourly request counts to the security activity
+func addSecurityActivity(db *gorm.DB, rows []models.SecurityActivity) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "bucket_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"reads":   gorm.Expr("security_activity.reads + EXCLUDED.reads"),
+			"writes":  gorm.Expr("security_activity.writes + EXCLUDED.writes"),
+			"deletes": gorm.Expr("security_activity.deletes + EXCLUDED.deletes"),
+			"exports": gorm.Expr("security_activity.exports + EXCLUDED.exports"),
+		}),
+	}).CreateInBatches(&rows, 500).Error
+}
+
+// Cleanup deletes activity rows older than the retention period
+func (t *UserActivityTracker) Cleanup() error {
+	if t.retentionDays <= 0 {
+		return nil
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -t.retentionDays)
+	if err := t.db.Where("bucket_date < ?", cutoff.Format("2006-01-02")).Delete(&models.UserActivity{}).Error; err != nil {
+		return err
+	}
+	return t.db.Where("bucket_start < ?", cutoff).Delete(&models.SecurityActivity{}).Error
+}
+
+// restore merges unflushed buckets back into the pending map
+func (t *UserActivityTracker) restore(buckets map[activityKey]*activityBucket) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, bucket := range buckets {
+		existing, ok := t.pending[key]
+		if !ok {
+			t.pending[key] = bucket
+			continue
+		}
+		existing.count += bucket.count
+		if bucket.lastSeen.After(existing.lastSeen) {
+			existing.lastSeen = bucket.lastSeen
+		}
+	}
+}
+
+// restoreSecurity merges unflushed security counts back into the pending map
+func (t *UserActivityTracker) restoreSecurity(buckets map[securityKey]*models.SecurityCounts) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, counts := range buckets {
+		existing, ok := t.security[key]
+		if !ok {
+			t.security[key] = counts
+			continue
+		}
+		existing.Reads += counts.Reads
+		existing.Writes += counts.Writes
+		existing.Deletes += counts.Deletes
+		existing.Exports += counts.Exports
+	}
+}
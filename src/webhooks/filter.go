@@ -0,0 +1,431 @@
+package webhooks
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter limits, so an expression cannot make matching expensive
+const (
+	maxFilterLength = 1000
+	maxFilterDepth  = 20
+)
+
+// Filter is a parsed filter expression. It compares fields of an event's
+// record with literals:
+//
+//	stage == "closed_won" and amount > 10000
+//	status in ["active", "churned"] and not (previous.status == "lead")
+//
+// Fields are names of the record's JSON fields, or previous.<field> for a
+// changed field's value before the update. Literals are double-quoted
+// strings, numbers, true, false and null. The operators are ==, !=, <, <=,
+// >, >= and in [...], combined with and, or, not and parentheses. Numbers
+// compare as numbers and strings, such as timestamps, compare as text; a
+// comparison between different types, or with a missing field, is false,
+// except that a missing field equals null. Nothing but the record is read,
+// so matching needs no queries.
+type Filter struct {
+	root node
+}
+
+// node is a part of a filter expression
+type node interface {
+	match(values map[string]interface{}) bool
+}
+
+// ParseFilter parses a filter expression. An empty expression returns a
+// nil Filter, which matches every event.
+func ParseFilter(expr string) (*Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	if len(expr) > maxFilterLength {
+		return nil, fmt.Errorf("filter is longer than %d characters", maxFilterLength)
+	}
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return &Filter{root: root}, nil
+}
+
+// Match reports whether values, an event's record with its previous
+// values, match the filter
+func (f *Filter) Match(values map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.match(values)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenField
+	tokenString
+	tokenNumber
+	tokenKeyword // and, or, not, in, true, false, null
+	tokenOperator
+	tokenPunct // ( ) [ ] ,
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+	return strconv.Quote(t.text)
+}
+
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "true": true, "false": true, "null": true}
+
+// lex splits a filter expression into tokens
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case strings.ContainsRune(" \t\r\n", c):
+			i++
+		case strings.ContainsRune("()[],", c):
+			tokens = append(tokens, token{tokenPunct, string(c), i})
+			i++
+		case strings.ContainsRune("=!<>", c):
+			op := string(c)
+			if i+1 < len(expr) && expr[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("unknown operator %q at position %d", op, i)
+			}
+			tokens = append(tokens, token{tokenOperator, op, i})
+			i += len(op)
+		case c == '"':
+			end := i + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d", i)
+			}
+			tokens = append(tokens, token{tokenString, text, i})
+			i = end + 1
+		case c == '-' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(expr) && strings.ContainsRune("0123456789.eE+-", rune(expr[end])) {
+				end++
+			}
+			if _, err := strconv.ParseFloat(expr[i:end], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", expr[i:end], i)
+			}
+			tokens = append(tokens, token{tokenNumber, expr[i:end], i})
+			i = end
+		case isFieldChar(c) && !strings.ContainsRune("0123456789.", c):
+			end := i + 1
+			for end < len(expr) && isFieldChar(rune(expr[end])) {
+				end++
+			}
+			word := expr[i:end]
+			kind := tokenField
+			if keywords[strings.ToLower(word)] {
+				kind, word = tokenKeyword, strings.ToLower(word)
+			} else if strings.HasPrefix(word, ".") || strings.HasSuffix(word, ".") || strings.Contains(word, "..") {
+				return nil, fmt.Errorf("invalid field %q at position %d", word, i)
+			}
+			tokens = append(tokens, token{kind, word, i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(expr)}), nil
+}
+
+// isFieldChar reports whether c can be part of a field path
+func isFieldChar(c rune) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parser builds a filter from tokens by recursive descent:
+//
+//	or         = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | primary
+//	primary    = "(" or ")" | field operator literal | field "in" "[" literal { "," literal } "]"
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) is(kind tokenKind, text string) bool {
+	tok := p.peek()
+	return tok.kind == kind && tok.text == text
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if tok := p.take(); tok.kind != kind || tok.text != text {
+		return fmt.Errorf("expected %q at position %d, found %s", text, tok.pos, tok)
+	}
+	return nil
+}
+
+func (p *parser) or(depth int) (node, error) {
+	left, err := p.and(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokenKeyword, "or") {
+		p.take()
+		right, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and(depth int) (node, error) {
+	left, err := p.not(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokenKeyword, "and") {
+		p.take()
+		right, err := p.not(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) not(depth int) (node, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("filter is nested deeper than %d levels", maxFilterDepth)
+	}
+	if p.is(tokenKeyword, "not") {
+		p.take()
+		inner, err := p.not(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.primary(depth)
+}
+
+func (p *parser) primary(depth int) (node, error) {
+	if p.is(tokenPunct, "(") {
+		p.take()
+		inner, err := p.or(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	field := p.take()
+	if field.kind != tokenField {
+		return nil, fmt.Errorf("expected a field at position %d, found %s", field.pos, field)
+	}
+	path := strings.Split(field.text, ".")
+
+	if p.is(tokenKeyword, "in") {
+		p.take()
+		if err := p.expect(tokenPunct, "["); err != nil {
+			return nil, err
+		}
+		var values []interface{}
+		for {
+			value, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if !p.is(tokenPunct, ",") {
+				break
+			}
+			p.take()
+		}
+		if err := p.expect(tokenPunct, "]"); err != nil {
+			return nil, err
+		}
+		return inNode{path, values}, nil
+	}
+
+	op := p.take()
+	if op.kind != tokenOperator {
+		return nil, fmt.Errorf("expected an operator after %s at position %d, found %s", field, op.pos, op)
+	}
+	value, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{path, op.text, value}, nil
+}
+
+// literal parses a string, number, boolean or null
+func (p *parser) literal() (interface{}, error) {
+	tok := p.take()
+	switch {
+	case tok.kind == tokenString:
+		return tok.text, nil
+	case tok.kind == tokenNumber:
+		return strconv.ParseFloat(tok.text, 64)
+	case tok.kind == tokenKeyword && tok.text == "true":
+		return true, nil
+	case tok.kind == tokenKeyword && tok.text == "false":
+		return false, nil
+	case tok.kind == tokenKeyword && tok.text == "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("expected a value at position %d, found %s", tok.pos, tok)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) match(values map[string]interface{}) bool {
+	return n.left.match(values) || n.right.match(values)
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) match(values map[string]interface{}) bool {
+	return n.left.match(values) && n.right.match(values)
+}
+
+type notNode struct{ inner node }
+
+func (n notNode) match(values map[string]interface{}) bool {
+	return !n.inner.match(values)
+}
+
+type compareNode struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (n compareNode) match(values map[string]interface{}) bool {
+	actual := lookup(values, n.path)
+	switch n.op {
+	case "==":
+		return equal(actual, n.value)
+	case "!=":
+		return !equal(actual, n.value)
+	}
+
+	var order int
+	switch a := actual.(type) {
+	case float64:
+		b, ok := n.value.(float64)
+		if !ok {
+			return false
+		}
+		order = cmp.Compare(a, b)
+	case string:
+		b, ok := n.value.(string)
+		if !ok {
+			return false
+		}
+		order = strings.Compare(a, b)
+	default:
+		return false
+	}
+	switch n.op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default:
+		return order >= 0
+	}
+}
+
+type inNode struct {
+	path   []string
+	values []interface{}
+}
+
+func (n inNode) match(values map[string]interface{}) bool {
+	actual := lookup(values, n.path)
+	for _, value := range n.values {
+		if equal(actual, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the value at a field path, nil when it is missing
+func lookup(values map[string]interface{}, path []string) interface{} {
+	var current interface{} = values
+	for _, name := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[name]
+	}
+	return current
+}
+
+// equal compares a JSON value with a literal of the same type
+func equal(actual, literal interface{}) bool {
+	switch a := actual.(type) {
+	case nil:
+		return literal == nil
+	case float64:
+		b, ok := literal.(float64)
+		return ok && a == b
+	case string:
+		b, ok := literal.(string)
+		return ok && a == b
+	case bool:
+		b, ok := literal.(bool)
+		return ok && a == b
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	var values map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"stage": "closed_won", "amount": 25000, "currency": "SAR", "owner_id": 3,
+		"contact_id": null, "archived": false, "expected_close_date": "2025-06-30T00:00:00Z",
+		"previous": {"stage": "negotiation"}
+	}`), &values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		expr string
+		want bool
+	}{
+		{``, true},
+		{`stage == "closed_won"`, true},
+		{`stage != "closed_won"`, false},
+		{`stage == "closed_won" and amount > 10000`, true},
+		{`stage == "closed_won" and amount > 30000`, false},
+		{`amount >= 25000 and amount <= 25000 and amount < 25000.5`, true},
+		{`amount > -1e3`, true},
+		{`stage == "closed_lost" or currency == "SAR"`, true},
+		{`not stage == "closed_won"`, false},
+		{`not (stage == "closed_lost" or amount < 100)`, true},
+		{`stage in ["closed_won", "closed_lost"]`, true},
+		{`owner_id in [1, 2]`, false},
+		{`previous.stage == "negotiation"`, true},
+		{`previous.amount == null`, true},
+		{`previous.stage.name == null`, true},
+		{`contact_id == null and archived == false`, true},
+		{`missing != null`, false},
+		{`expected_close_date < "2025-07-01"`, true},
+		{`amount > "10000"`, false},
+		{`stage > 1`, false},
+		{`amount == "25000"`, false},
+		{`STAGE == "closed_won"`, false},
+		{`stage == "closed_won" AND Amount > 0 OR currency == "SAR"`, true},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filter.Match(values); got != tc.want {
+				t.Errorf("Match = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{`stage = "closed_won"`, `unknown operator "="`},
+		{`stage == "closed_won`, "unterminated string"},
+		{`stage == closed_won`, `expected a value at position 9, found "closed_won"`},
+		{`stage ==`, "found end of filter"},
+		{`stage`, "expected an operator"},
+		{`== "closed_won"`, "expected a field"},
+		{`stage == "x" and`, "expected a field"},
+		{`(stage == "x"`, `expected ")"`},
+		{`stage == "x")`, `unexpected ")"`},
+		{`stage in []`, "expected a value"},
+		{`stage in ["a" "b"]`, `expected "]"`},
+		{`amount > 1.2.3`, "invalid number"},
+		{`.stage == 1`, "unexpected character"},
+		{`previous..stage == 1`, "invalid field"},
+		{`stage == 'closed_won'`, "unexpected character"},
+		{`stage ~= "x"`, "unexpected character"},
+		{`stage == "x" and` + strings.Repeat(" ", maxFilterLength), "longer than"},
+		{strings.Repeat("(", maxFilterDepth+1) + `a == 1` + strings.Repeat(")", maxFilterDepth+1), "nested deeper"},
+		{strings.Repeat("not ", maxFilterDepth+1) + `a == 1`, "nested deeper"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := ParseFilter(tc.expr)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,570 @@
+// Package webhooks sends customer and deal events to subscribed URLs. An
+// event is queued as a delivery for every subscription to its type in the
+// transaction of the change that raised it, and sent by Run until the
+// subscriber accepts it or a day has passed, so delivery is at least once,
+// and then handed to the dead-letter queue, if set. Subscriptions in the
+// at-most-once mode are sent each event once instead, and a delivery they
+// do not accept is failed. Events that do not match a subscription's
+// filter are recorded as skipped. Events of records changed by imports and
+// other bulk operations are recorded as suppressed, unless the subscription
+// takes bulk events, and the operation raises one bulk.completed event.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/audittrail"
+	"github.com/SalehAlobaylan/CRM-Service/src/deadletter"
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+	"github.com/SalehAlobaylan/CRM-Service/src/sandbox"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Delivery headers. Deliveries are signed like the inbound call and email
+// webhooks: a hex HMAC-SHA256 of "<timestamp>.<body>" with the
+// subscription's secret, where timestamp is the Unix time sent in
+// X-Webhook-Timestamp.
+const (
+	EventHeader      = "X-Webhook-Event"
+	EventIDHeader    = "X-Webhook-Event-ID"
+	DeliveryIDHeader = "X-Webhook-Delivery-ID"
+	TimestampHeader  = "X-Webhook-Timestamp"
+	SignatureHeader  = "X-Webhook-Signature"
+)
+
+// DeadLetterComponent is the dead-letter component of failed deliveries
+const DeadLetterComponent = "webhooks"
+
+// deadLetterPayload identifies a failed delivery in the dead-letter queue
+type deadLetterPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+const (
+	// deliveryTimeout bounds one attempt
+	deliveryTimeout = 10 * time.Second
+
+	// retryWindow is how long a delivery is retried before it fails
+	retryWindow = 24 * time.Hour
+
+	// maxRetryDelay caps the doubling delay between attempts
+	maxRetryDelay = time.Hour
+
+	// runBatchSize bounds the deliveries one Run attempts
+	runBatchSize = 100
+
+	// deliveryRetention is how long finished deliveries are kept
+	deliveryRetention = 30 * 24 * time.Hour
+
+	// maxResponseSnippet bounds how much of a response is kept
+	maxResponseSnippet = 1024
+)
+
+// Event is the body posted to a subscription
+type Event struct {
+	Event      string                 `json:"event"`
+	EventID    string                 `json:"event_id"`
+	DeliveryID string                 `json:"delivery_id,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Bulk       string                 `json:"bulk,omitempty"`     // The bulk operation that changed the record, if any
+	Data       map[string]interface{} `json:"data"`               // The record after the change, or before a delete
+	Previous   map[string]interface{} `json:"previous,omitempty"` // Values of the fields an update changed, before it
+}
+
+// Values returns what filters match against: the record's fields, with
+// the previous values under "previous"
+func (e Event) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(e.Data)+1)
+	for name, value := range e.Data {
+		values[name] = value
+	}
+	values["previous"] = e.Previous
+	return values
+}
+
+// subscription is an active subscription with its parsed filter
+type subscription struct {
+	models.WebhookSubscription
+	filter *Filter
+}
+
+// Dispatcher queues events for the active subscriptions and sends them
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+	onErr  func(error)
+
+	mu            sync.RWMutex
+	subscriptions []subscription
+	deadLetters   *deadletter.Queue // Receives failed deliveries, if set
+}
+
+// NewDispatcher creates a new Dispatcher. onErr is called with errors
+// queueing events, which do not fail their change, e.g. to log them.
+func NewDispatcher(db *gorm.DB, onErr func(error)) *Dispatcher {
+	if onErr == nil {
+		onErr = func(error) {}
+	}
+	return &Dispatcher{db: db, client: &http.Client{Timeout: deliveryTimeout}, onErr: onErr}
+}
+
+// DeadLetterTo hands deliveries that fail from now on to queue and lets the
+// queue retry them by making them pending again. A retried delivery is sent
+// on the next run; it is past its retry window, so if that attempt fails it
+// is failed and dead-lettered again.
+func (d *Dispatcher) DeadLetterTo(queue *deadletter.Queue) {
+	d.mu.Lock()
+	d.deadLetters = queue
+	d.mu.Unlock()
+	queue.RegisterRetrier(DeadLetterComponent, func(payload json.RawMessage) error {
+		var letter deadLetterPayload
+		if err := json.Unmarshal(payload, &letter); err != nil {
+			return err
+		}
+		result := d.db.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ?", letter.DeliveryID, models.WebhookDeliveryFailed).
+			Updates(map[string]interface{}{
+				"status":          models.WebhookDeliveryPending,
+				"next_attempt_at": nil,
+				"completed_at":    nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("webhook delivery %d is not failed", letter.DeliveryID)
+		}
+		return nil
+	})
+}
+
+// NewSecret returns a random secret for signing a subscription's
+// deliveries
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Reload loads the active subscriptions. Subscriptions with a filter that
+// no longer parses are left out.
+func (d *Dispatcher) Reload(ctx context.Context) error {
+	var active []models.WebhookSubscription
+	if err := d.db.WithContext(ctx).Where("active").Order("id").Find(&active).Error; err != nil {
+		return err
+	}
+	subscriptions := make([]subscription, 0, len(active))
+	for _, sub := range active {
+		filter, err := ParseFilter(sub.Filter)
+		if err != nil {
+			continue
+		}
+		subscriptions = append(subscriptions, subscription{WebhookSubscription: sub, filter: filter})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscriptions = subscriptions
+	return nil
+}
+
+// subscribed returns the active subscriptions to an event type
+func (d *Dispatcher) subscribed(eventType string) []subscription {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var subscribed []subscription
+	for _, sub := range d.subscriptions {
+		if slices.Contains(sub.Events, eventType) {
+			subscribed = append(subscribed, sub)
+		}
+	}
+	return subscribed
+}
+
+// EventTypes returns the events a customer or deal change raises: its
+// creation, update or deletion, and a stage or status change as its own
+// event as well. Other changes, and summaries of many records, raise none.
+func EventTypes(audit *models.AuditLog) []string {
+	if audit.ResourceID == 0 || (audit.ResourceType != "customer" && audit.ResourceType != "deal") {
+		return nil
+	}
+	switch audit.Action {
+	case models.AuditActionCreate:
+		return []string{audit.ResourceType + ".created"}
+	case models.AuditActionDelete:
+		return []string{audit.ResourceType + ".deleted"}
+	case models.AuditActionUpdate, models.AuditActionArchive, models.AuditActionUnarchive,
+		models.AuditActionClaim, models.AuditActionConvert, models.AuditActionAnonymize:
+		types := []string{audit.ResourceType + ".updated"}
+		changed := changedFields(audit.NewValues)
+		if audit.ResourceType == "deal" && changed["stage"] {
+			types = append(types, models.WebhookEventDealStageChanged)
+		}
+		if audit.ResourceType == "customer" && changed["status"] {
+			types = append(types, models.WebhookEventCustomerStatusChanged)
+		}
+		return types
+	}
+	return nil
+}
+
+// changedFields returns the fields of an audit entry's values
+func changedFields(values string) map[string]bool {
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(values), &fields)
+	changed := make(map[string]bool, len(fields))
+	for name := range fields {
+		changed[name] = true
+	}
+	return changed
+}
+
+// eventSavepoint guards the delivery inserts inside the change's
+// transaction
+const eventSavepoint = "webhook_events"
+
+// Publish queues the events of an audited change for the subscriptions to
+// them. The deliveries are inserted in the transaction saving the change,
+// so they commit with it and a change rolled back sends nothing. They are
+// built from the audit entry alone and filters are matched here, against
+// it; events that do not match are recorded as skipped. Events of a record
+// changed by a bulk operation (see audittrail.BulkContextKey) are recorded
+// as suppressed for subscriptions that do not take bulk events. A failure
+// is reported to onErr and never fails the change: in a transaction the
+// inserts run in a savepoint that is rolled back.
+func (d *Dispatcher) Publish(ctx context.Context, tx *gorm.DB, audit *models.AuditLog) {
+	if sandbox.Active(ctx) {
+		return
+	}
+	err := d.queue(tx.WithContext(ctx), EventTypes(audit), audittrail.BulkOperation(ctx), func() (map[string]interface{}, map[string]interface{}, error) {
+		return eventValues(audit)
+	})
+	if err != nil {
+		d.onErr(fmt.Errorf("failed to queue webhook events of %s %d: %w", audit.ResourceType, audit.ResourceID, err))
+	}
+}
+
+// PublishBulk queues the bulk.completed event of a bulk operation, whose
+// data is the summary, for the subscriptions to it
+func (d *Dispatcher) PublishBulk(ctx context.Context, db *gorm.DB, summary models.BulkOperationSummary) {
+	if sandbox.Active(ctx) {
+		return
+	}
+	err := d.queue(db.WithContext(ctx), []string{models.WebhookEventBulkCompleted}, "", func() (map[string]interface{}, map[string]interface{}, error) {
+		var data map[string]interface{}
+		raw, err := json.Marshal(summary)
+		if err == nil {
+			err = json.Unmarshal(raw, &data)
+		}
+		return data, nil, err
+	})
+	if err != nil {
+		d.onErr(fmt.Errorf("failed to queue the bulk.completed event of %s: %w", summary.Operation, err))
+	}
+}
+
+// queue inserts the deliveries of events of the given types to the
+// subscriptions to them, in a savepoint when tx is a transaction so a
+// failure leaves it usable. The events' record and previous values are
+// built by values, only when there is a subscription. bulk is the bulk
+// operation raising the events, if any.
+func (d *Dispatcher) queue(tx *gorm.DB, eventTypes []string, bulk string, values func() (data, previous map[string]interface{}, err error)) error {
+	type queued struct {
+		eventType     string
+		subscriptions []subscription
+	}
+	var events []queued
+	for _, eventType := range eventTypes {
+		if subscriptions := d.subscribed(eventType); len(subscriptions) > 0 {
+			events = append(events, queued{eventType, subscriptions})
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	data, previous, err := values()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, queued := range events {
+		event := Event{Event: queued.eventType, EventID: uuid.NewString(), OccurredAt: now, Bulk: bulk, Data: data, Previous: previous}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		values := event.Values()
+		for _, sub := range queued.subscriptions {
+			delivery := models.WebhookDelivery{
+				SubscriptionID: sub.ID,
+				EventID:        event.EventID,
+				EventType:      event.Event,
+				Status:         models.WebhookDeliveryPending,
+				Payload:        string(payload),
+			}
+			switch {
+			case bulk != "" && !sub.BulkEvents:
+				delivery.Status, delivery.CompletedAt = models.WebhookDeliverySuppressed, &now
+			case !sub.filter.Match(values):
+				delivery.Status, delivery.CompletedAt = models.WebhookDeliverySkipped, &now
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return insert(tx, deliveries)
+}
+
+// eventValues returns an event's record and, for an update, the changed
+// fields' previous values, from its audit entry. The record is the entry's
+// snapshot; entries written without one, outside the handlers, give the
+// values they hold, with the new over the old.
+func eventValues(audit *models.AuditLog) (data, previous map[string]interface{}, err error) {
+	data = map[string]interface{}{}
+	sources := []string{audit.OldValues, audit.NewValues}
+	if audit.Snapshot != "" {
+		sources = []string{audit.Snapshot}
+	}
+	for _, raw := range sources {
+		if raw == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(raw), &data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if audit.Action != models.AuditActionCreate && audit.Action != models.AuditActionDelete && audit.OldValues != "" {
+		if err := json.Unmarshal([]byte(audit.OldValues), &previous); err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, previous, nil
+}
+
+// insert creates deliveries, in a savepoint when tx is a transaction so a
+// failure leaves the transaction usable
+func insert(tx *gorm.DB, deliveries []models.WebhookDelivery) error {
+	// A failed statement aborts the whole transaction in Postgres
+	if _, inTransaction := tx.Statement.ConnPool.(gorm.TxCommitter); !inTransaction {
+		return tx.Create(&deliveries).Error
+	}
+	if err := tx.SavePoint(eventSavepoint).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&deliveries).Error; err != nil {
+		if rollbackErr := tx.RollbackTo(eventSavepoint).Error; rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Run sends the pending deliveries that are due, retrying those that fail
+// with a doubling delay until retryWindow has passed, and removes finished
+// deliveries past deliveryRetention. It reloads the subscriptions first, so
+// changes made on other instances apply. Deliveries of paused subscriptions
+// wait. It returns the number of attempts made.
+func (d *Dispatcher) Run(ctx context.Context) (int, error) {
+	if err := d.Reload(ctx); err != nil {
+		return 0, err
+	}
+
+	// Deliveries are claimed by pushing back their next attempt, so other
+	// instances do not send them at the same time
+	var due []models.WebhookDelivery
+	now := time.Now()
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// An at-most-once delivery still pending after an attempt was cut
+		// short, e.g. by a restart, may have been sent, so it is failed
+		// rather than sent again
+		err := tx.Model(&models.WebhookDelivery{}).
+			Where("status = ? AND attempts > 0 AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Where("subscription_id IN (?)", tx.Model(&models.WebhookSubscription{}).Select("id").Where("mode = ?", models.WebhookModeAtMostOnce)).
+			Updates(map[string]interface{}{
+				"status":          models.WebhookDeliveryFailed,
+				"error":           "attempt interrupted",
+				"next_attempt_at": nil,
+				"completed_at":    now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", models.WebhookDeliveryPending, now).
+			Where("subscription_id IN (?)", tx.Model(&models.WebhookSubscription{}).Select("id").Where("active")).
+			Order("id").Limit(runBatchSize).Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		claimed := now.Add(2 * deliveryTimeout)
+		ids := make([]uint, len(due))
+		for i := range due {
+			ids[i], due[i].NextAttemptAt = due[i].ID, &claimed
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", claimed).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for i := range due {
+		sub, ok := d.subscription(due[i].SubscriptionID)
+		if !ok {
+			continue
+		}
+		if err := d.attempt(ctx, sub, &due[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = d.db.WithContext(ctx).
+		Where("status <> ? AND completed_at < ?", models.WebhookDeliveryPending, now.Add(-deliveryRetention)).
+		Delete(&models.WebhookDelivery{}).Error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return len(due), errors.Join(errs...)
+}
+
+// subscription returns an active subscription by ID
+func (d *Dispatcher) subscription(id uint) (models.WebhookSubscription, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, sub := range d.subscriptions {
+		if sub.ID == id {
+			return sub.WebhookSubscription, true
+		}
+	}
+	return models.WebhookSubscription{}, false
+}
+
+// attempt sends a delivery once and records the outcome. A delivery that
+// fails past the retry window is handed to the dead-letter queue; one to an
+// at-most-once subscription fails at once and is not, since retrying it
+// would send it twice.
+func (d *Dispatcher) attempt(ctx context.Context, sub models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	delivery.Attempts++
+	delivery.DeliveryID = uuid.NewString()
+	if sub.Mode == models.WebhookModeAtMostOnce {
+		// Recorded before sending, so an attempt cut short is not repeated
+		if err := d.record(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	sendErr := d.Send(ctx, sub, delivery)
+
+	now := time.Now()
+	switch {
+	case sendErr == nil:
+		delivery.Status, delivery.CompletedAt, delivery.NextAttemptAt = models.WebhookDeliveryDelivered, &now, nil
+	case sub.Mode == models.WebhookModeAtMostOnce:
+		delivery.Status, delivery.CompletedAt, delivery.NextAttemptAt = models.WebhookDeliveryFailed, &now, nil
+		return d.record(ctx, delivery)
+	case now.Sub(delivery.CreatedAt) >= retryWindow:
+		delivery.Status, delivery.CompletedAt, delivery.NextAttemptAt = models.WebhookDeliveryFailed, &now, nil
+	default:
+		next := now.Add(min(time.Minute<<(delivery.Attempts-1), maxRetryDelay))
+		delivery.NextAttemptAt = &next
+	}
+	if err := d.record(ctx, delivery); err != nil || delivery.Status != models.WebhookDeliveryFailed {
+		return err
+	}
+
+	d.mu.RLock()
+	queue := d.deadLetters
+	d.mu.RUnlock()
+	if queue == nil {
+		return nil
+	}
+	return queue.Add(DeadLetterComponent, deadLetterPayload{DeliveryID: delivery.ID}, sendErr, delivery.Attempts)
+}
+
+// record saves the outcome of a delivery's latest attempt
+func (d *Dispatcher) record(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return d.db.WithContext(ctx).Model(delivery).Updates(map[string]interface{}{
+		"attempts":        delivery.Attempts,
+		"delivery_id":     delivery.DeliveryID,
+		"status":          delivery.Status,
+		"response_status": delivery.ResponseStatus,
+		"response_body":   delivery.ResponseBody,
+		"error":           delivery.Error,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"completed_at":    delivery.CompletedAt,
+	}).Error
+}
+
+// Send posts a delivery's event to a subscription with the delivery's ID,
+// recording the response status and the start of its body on it; any 2xx
+// response counts as delivered
+func (d *Dispatcher) Send(ctx context.Context, sub models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	var event Event
+	if err := json.Unmarshal([]byte(delivery.Payload), &event); err != nil {
+		return err
+	}
+	event.DeliveryID = delivery.DeliveryID
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(sub.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Event)
+	req.Header.Set(EventIDHeader, event.EventID)
+	req.Header.Set(DeliveryIDHeader, delivery.DeliveryID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	delivery.ResponseStatus = resp.StatusCode
+	// Text columns hold neither invalid UTF-8 nor NUL
+	delivery.ResponseBody = strings.ReplaceAll(strings.ToValidUTF8(string(snippet), ""), "\x00", "")
+	delivery.Error = ""
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+		delivery.Error = err.Error()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package webhooks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/models"
+)
+
+func TestEventTypes(t *testing.T) {
+	for _, tc := range []struct {
+		resource  string
+		action    string
+		newValues string
+		want      string
+	}{
+		{"deal", "create", `{"stage":"prospecting"}`, "deal.created"},
+		{"deal", "update", `{"amount":10}`, "deal.updated"},
+		{"deal", "update", `{"stage":"closed_won","amount":10}`, "deal.updated deal.stage_changed"},
+		{"deal", "archive", `{"archived_at":"2025-01-01T00:00:00Z"}`, "deal.updated"},
+		{"deal", "delete", ``, "deal.deleted"},
+		{"customer", "update", `{"status":"active"}`, "customer.updated customer.status_changed"},
+		{"customer", "convert", `{"status":"active","converted_at":"2025-01-01T00:00:00Z"}`, "customer.updated customer.status_changed"},
+		{"customer", "update", `{"stage":"x"}`, "customer.updated"},
+		{"customer", "bulk_upsert", `{}`, ""},
+		{"contact", "update", `{"status":"active"}`, ""},
+	} {
+		t.Run(tc.resource+" "+tc.action+" "+tc.newValues, func(t *testing.T) {
+			audit := models.AuditLog{ResourceType: tc.resource, ResourceID: 1, Action: models.AuditAction(tc.action), NewValues: tc.newValues}
+			if got := strings.Join(EventTypes(&audit), " "); got != tc.want {
+				t.Errorf("EventTypes = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	// Summaries of many records raise nothing
+	summary := models.AuditLog{ResourceType: "deal", Action: models.AuditActionUpdate, NewValues: `{"stage":"closed_won"}`}
+	if types := EventTypes(&summary); len(types) != 0 {
+		t.Errorf("summary: EventTypes = %v", types)
+	}
+}
@@ -0,0 +1,209 @@
+// Package workload caps how many expensive operations, such as exports,
+// reports and imports, run at the same time. Each class of operation has
+// its own slots, shared by its HTTP endpoints and its async jobs, so one
+// class saturating the database does not hold up the others.
+package workload
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Classes of expensive operations
+const (
+	ClassExports     = "exports"     // CSV exports, streamed or run as jobs
+	ClassReports     = "reports"     // Report endpoints and dashboard data
+	ClassImports     = "imports"     // CSV imports and bulk upserts
+	ClassSearch      = "search"      // Global search
+	ClassMaintenance = "maintenance" // Internal jobs such as backups and search reindexes
+)
+
+// classes lists the class names a limit can be set for
+var classes = []string{ClassExports, ClassReports, ClassImports, ClassSearch, ClassMaintenance}
+
+// DefaultLimits is the number of slots of each class
+var DefaultLimits = map[string]int{
+	ClassExports:     2,
+	ClassReports:     5,
+	ClassImports:     1,
+	ClassSearch:      10,
+	ClassMaintenance: 1,
+}
+
+// minRetryAfter is the shortest wait suggested to a rejected caller
+const minRetryAfter = time.Second
+
+var (
+	workloadInUse = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "crm_workload_in_use",
+			Help: "Number of slots of each workload class currently held",
+		},
+		[]string{"class"},
+	)
+	workloadQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "crm_workload_queued",
+			Help: "Number of jobs waiting for a slot of each workload class",
+		},
+		[]string{"class"},
+	)
+	workloadRejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "crm_workload_rejected_total",
+			Help: "Total number of requests rejected because their workload class was saturated",
+		},
+		[]string{"class"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(workloadInUse, workloadQueued, workloadRejectedTotal)
+}
+
+// ParseLimits parses a comma-separated list of class=slots overrides of
+// DefaultLimits, e.g. "exports=4,imports=2". A limit of 0 leaves the class
+// unlimited.
+func ParseLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int, len(DefaultLimits))
+	for class, limit := range DefaultLimits {
+		limits[class] = limit
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		class, value, ok := strings.Cut(entry, "=")
+		class = strings.TrimSpace(class)
+		if !ok {
+			return nil, fmt.Errorf("invalid workload limit %q, expected class=slots", entry)
+		}
+		if !slices.Contains(classes, class) {
+			return nil, fmt.Errorf("unknown class %q in workload limit %q, want one of %s", class, entry, strings.Join(classes, ", "))
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid slots in workload limit %q", entry)
+		}
+		limits[class] = limit
+	}
+	return limits, nil
+}
+
+// Limiter holds the slots of each workload class. A nil Limiter admits
+// everything.
+type Limiter struct {
+	classes map[string]*class
+}
+
+// class is the slots of one workload class
+type class struct {
+	name  string
+	slots chan struct{} // nil when the class is unlimited
+
+	mu      sync.Mutex
+	avgHold time.Duration // Moving average of how long a slot is held
+}
+
+// NewLimiter creates a Limiter with the given slots per class. Classes
+// missing from limits or with a limit of 0 are unlimited.
+func NewLimiter(limits map[string]int) *Limiter {
+	l := &Limiter{classes: make(map[string]*class, len(classes))}
+	for _, name := range classes {
+		c := &class{name: name}
+		if limit := limits[name]; limit > 0 {
+			c.slots = make(chan struct{}, limit)
+		}
+		l.classes[name] = c
+	}
+	return l
+}
+
+// TryAcquire takes a slot of a class without waiting. It returns the
+// function releasing the slot, or false when every slot is taken.
+func (l *Limiter) TryAcquire(name string) (func(), bool) {
+	c := l.class(name)
+	if c == nil || c.slots == nil {
+		return func() {}, true
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return c.hold(), true
+	default:
+		workloadRejectedTotal.WithLabelValues(c.name).Inc()
+		return nil, false
+	}
+}
+
+// Acquire waits for a slot of a class, for jobs that queue rather than
+// fail. It returns the function releasing the slot, or the context's error
+// when ctx is done first.
+func (l *Limiter) Acquire(ctx context.Context, name string) (func(), error) {
+	c := l.class(name)
+	if c == nil || c.slots == nil {
+		return func() {}, nil
+	}
+
+	workloadQueued.WithLabelValues(c.name).Inc()
+	defer workloadQueued.WithLabelValues(c.name).Dec()
+	select {
+	case c.slots <- struct{}{}:
+		return c.hold(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// RetryAfter estimates how long until a slot of a class frees up: the
+// average time its slots are held, at least a second
+func (l *Limiter) RetryAfter(name string) time.Duration {
+	c := l.class(name)
+	if c == nil {
+		return minRetryAfter
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return max(c.avgHold, minRetryAfter)
+}
+
+// class returns the named class, nil when l is nil or the class unknown
+func (l *Limiter) class(name string) *class {
+	if l == nil {
+		return nil
+	}
+	return l.classes[name]
+}
+
+// hold records a taken slot and returns the function releasing it
+func (c *class) hold() func() {
+	workloadInUse.WithLabelValues(c.name).Inc()
+	start := time.Now()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			held := time.Since(start)
+			c.mu.Lock()
+			if c.avgHold == 0 {
+				c.avgHold = held
+			} else {
+				c.avgHold = (c.avgHold*7 + held) / 8
+			}
+			c.mu.Unlock()
+
+			<-c.slots
+			workloadInUse.WithLabelValues(c.name).Dec()
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package workload_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SalehAlobaylan/CRM-Service/src/workload"
+)
+
+func TestParseLimits(t *testing.T) {
+	limits, err := workload.ParseLimits(" exports = 4, imports=0 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits[workload.ClassExports] != 4 || limits[workload.ClassImports] != 0 || limits[workload.ClassReports] != workload.DefaultLimits[workload.ClassReports] {
+		t.Errorf("limits = %v", limits)
+	}
+	if workload.DefaultLimits[workload.ClassExports] == 4 {
+		t.Error("ParseLimits changed DefaultLimits")
+	}
+
+	for _, spec := range []string{"exports", "unknown=1", "exports=-1", "exports=many"} {
+		if _, err := workload.ParseLimits(spec); err == nil {
+			t.Errorf("%q: no error", spec)
+		}
+	}
+}
+
+func TestTryAcquire(t *testing.T) {
+	limiter := workload.NewLimiter(map[string]int{workload.ClassExports: 2})
+
+	first, ok := limiter.TryAcquire(workload.ClassExports)
+	if !ok {
+		t.Fatal("first slot refused")
+	}
+	second, ok := limiter.TryAcquire(workload.ClassExports)
+	if !ok {
+		t.Fatal("second slot refused")
+	}
+	if _, ok := limiter.TryAcquire(workload.ClassExports); ok {
+		t.Fatal("third slot taken past the limit")
+	}
+
+	// Other classes have their own slots, and missing ones are unlimited
+	for range 10 {
+		if _, ok := limiter.TryAcquire(workload.ClassReports); !ok {
+			t.Fatal("saturated exports held up reports")
+		}
+	}
+
+	// Releasing twice frees one slot
+	first()
+	first()
+	if _, ok := limiter.TryAcquire(workload.ClassExports); !ok {
+		t.Fatal("released slot refused")
+	}
+	if _, ok := limiter.TryAcquire(workload.ClassExports); ok {
+		t.Fatal("a second release freed another slot")
+	}
+	second()
+	if _, ok := limiter.TryAcquire(workload.ClassExports); !ok {
+		t.Fatal("released slot refused")
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	limiter := workload.NewLimiter(map[string]int{workload.ClassImports: 1})
+	release, err := limiter.Acquire(context.Background(), workload.ClassImports)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A job waits for the slot rather than failing
+	acquired := make(chan func())
+	go func() {
+		next, err := limiter.Acquire(context.Background(), workload.ClassImports)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired a slot still held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case next := <-acquired:
+		next()
+	case <-time.After(time.Second):
+		t.Fatal("waiting job did not get the released slot")
+	}
+
+	// A job stops waiting when its context is done
+	release, _ = limiter.Acquire(context.Background(), workload.ClassImports)
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, workload.ClassImports); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestSaturation(t *testing.T) {
+	const slots, callers = 3, 50
+	limiter := workload.NewLimiter(map[string]int{workload.ClassExports: slots})
+
+	// Callers race for the slots; never more than slots hold one at once
+	var mu sync.Mutex
+	var held, peak, admitted int
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			release, ok := limiter.TryAcquire(workload.ClassExports)
+			if !ok {
+				return
+			}
+			mu.Lock()
+			held++
+			admitted++
+			peak = max(peak, held)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			held--
+			mu.Unlock()
+			release()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if peak > slots || admitted == 0 || admitted == callers {
+		t.Errorf("peak = %d, admitted = %d of %d", peak, admitted, callers)
+	}
+
+	// Every slot is free again
+	for range slots {
+		if _, ok := limiter.TryAcquire(workload.ClassExports); !ok {
+			t.Fatal("slot leaked")
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	limiter := workload.NewLimiter(map[string]int{workload.ClassReports: 1})
+	if got := limiter.RetryAfter(workload.ClassReports); got != time.Second {
+		t.Errorf("before any hold = %v, want 1s", got)
+	}
+	release, _ := limiter.TryAcquire(workload.ClassReports)
+	time.Sleep(1200 * time.Millisecond)
+	release()
+	if got := limiter.RetryAfter(workload.ClassReports); got < 1200*time.Millisecond || got > 2*time.Second {
+		t.Errorf("after a 1.2s hold = %v", got)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var limiter *workload.Limiter
+	release, ok := limiter.TryAcquire(workload.ClassExports)
+	if !ok {
+		t.Fatal("nil limiter refused")
+	}
+	release()
+	if release, err := limiter.Acquire(context.Background(), workload.ClassExports); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+	if got := limiter.RetryAfter(workload.ClassExports); got != time.Second {
+		t.Errorf("RetryAfter = %v", got)
+	}
+}